@@ -58,3 +58,14 @@ Create the name of the service account to use
 {{- default "default" .Values.serviceAccount.name }}
 {{- end }}
 {{- end }}
+
+{{/*
+Name of the ConfigMap holding the custom CA bundle (empty when none is configured)
+*/}}
+{{- define "supacontrol.caBundleConfigMap" -}}
+{{- if .Values.config.caBundle.existingConfigMap }}
+{{- .Values.config.caBundle.existingConfigMap }}
+{{- else if .Values.config.caBundle.pem }}
+{{- include "supacontrol.fullname" . }}-ca-bundle
+{{- end }}
+{{- end }}
@@ -1,6 +1,9 @@
 package apitypes
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 // UserInfo represents user information
 type UserInfo struct {
@@ -8,8 +11,85 @@ type UserInfo struct {
 	Username  string    `json:"username"`
 	Role      string    `json:"role"`
 	CreatedAt time.Time `json:"created_at"`
+	// MustChangePassword is set while the user can do nothing but change their password
+	MustChangePassword bool `json:"must_change_password,omitempty"`
+}
+
+// FieldError is a problem with one field of a request. Field is the field's JSON
+// name; elements of lists are addressed as name[index].
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ErrorCode is the machine-readable reason of an ErrorResponse. Clients should branch on
+// codes rather than on messages, which are translated into the request's locale.
+type ErrorCode string
+
+// Error codes of every status, returned unless an error has a more specific code
+const (
+	ErrorCodeBadRequest         ErrorCode = "bad_request"
+	ErrorCodeUnauthorized       ErrorCode = "unauthorized"
+	ErrorCodeForbidden          ErrorCode = "forbidden"
+	ErrorCodeNotFound           ErrorCode = "not_found"
+	ErrorCodeMethodNotAllowed   ErrorCode = "method_not_allowed"
+	ErrorCodeConflict           ErrorCode = "conflict"
+	ErrorCodeGone               ErrorCode = "gone"
+	ErrorCodePayloadTooLarge    ErrorCode = "payload_too_large"
+	ErrorCodeUnsupportedMedia   ErrorCode = "unsupported_media_type"
+	ErrorCodeTooManyRequests    ErrorCode = "too_many_requests"
+	ErrorCodeInternal           ErrorCode = "internal_error"
+	ErrorCodeBadGateway         ErrorCode = "bad_gateway"
+	ErrorCodeServiceUnavailable ErrorCode = "service_unavailable"
+	ErrorCodeTimeout            ErrorCode = "timeout"
+)
+
+// Specific error codes
+const (
+	// ErrorCodeValidationFailed means fields of the request are invalid; Errors lists them
+	ErrorCodeValidationFailed ErrorCode = "validation_failed"
+
+	// ErrorCodeInvalidBody means the request body is not valid JSON of the expected shape
+	ErrorCodeInvalidBody ErrorCode = "invalid_body"
+
+	// ErrorCodeInstanceNotFound means the named instance does not exist or is not visible
+	// to the caller
+	ErrorCodeInstanceNotFound ErrorCode = "instance_not_found"
+
+	// ErrorCodeInstanceExists means an instance with the requested name already exists
+	ErrorCodeInstanceExists ErrorCode = "instance_exists"
+
+	// ErrorCodeDeletionProtected means the instance cannot be deleted until its deletion
+	// protection is disabled
+	ErrorCodeDeletionProtected ErrorCode = "deletion_protected"
+
+	// ErrorCodeQuotaExceeded means the caller's quota does not allow the request; Details
+	// holds the limit
+	ErrorCodeQuotaExceeded ErrorCode = "quota_exceeded"
+
+	// ErrorCodeServerBusy means the request was shed under load and can be retried later
+	ErrorCodeServerBusy ErrorCode = "server_busy"
+)
+
+// ErrorResponse is the body of every error response. Code is stable and
+// machine-readable, Message is translated into the request's locale, Details holds
+// values specific to the error, Errors lists the invalid fields of a request that
+// failed validation, and RequestID matches the X-Request-ID response header for
+// finding the request in the server logs.
+type ErrorResponse struct {
+	Code      ErrorCode         `json:"code"`
+	Message   string            `json:"message"`
+	Details   map[string]string `json:"details,omitempty"`
+	Errors    []FieldError      `json:"errors,omitempty"`
+	RequestID string            `json:"request_id,omitempty"`
 }
 
+// ValidationErrorResponse is the ErrorResponse returned with 400 Bad Request when
+// fields of a request are invalid.
+//
+// Deprecated: use ErrorResponse.
+type ValidationErrorResponse = ErrorResponse
+
 // LoginRequest represents a login request
 type LoginRequest struct {
 	Username string `json:"username" binding:"required"`
@@ -22,12 +102,22 @@ type LoginResponse struct {
 	User  *UserInfo `json:"user"`
 }
 
+// ChangePasswordRequest represents a request to change the caller's password
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
 // AuthMeResponse represents an auth/me response
 type AuthMeResponse struct {
 	User *UserInfo `json:"user"`
 }
 
-// CreateAPIKeyRequest represents an API key creation request
+// MaxAPIKeyNameLength is the longest name an API key can have, in characters
+const MaxAPIKeyNameLength = 255
+
+// CreateAPIKeyRequest represents an API key creation request. ExpiresAt, when
+// set, must be in the future.
 type CreateAPIKeyRequest struct {
 	Name      string     `json:"name" binding:"required"`
 	ExpiresAt *time.Time `json:"expires_at,omitempty"`
@@ -49,6 +139,20 @@ type APIKey struct {
 	CreatedAt time.Time  `json:"created_at" db:"created_at"`
 	ExpiresAt *time.Time `json:"expires_at" db:"expires_at"`
 	LastUsed  *time.Time `json:"last_used" db:"last_used"`
+	// PreviousKeyHash is the hash the key had before it was last rotated, which keeps
+	// authenticating until PreviousKeyExpiresAt
+	PreviousKeyHash      *string    `json:"-" db:"previous_key_hash"`
+	PreviousKeyExpiresAt *time.Time `json:"previous_key_expires_at,omitempty" db:"previous_key_expires_at"`
+}
+
+// MaxAPIKeyRotationOverlap is the longest a rotated API key's previous value can stay valid
+const MaxAPIKeyRotationOverlap = 30 * 24 * time.Hour
+
+// RotateAPIKeyRequest represents an API key rotation request. Overlap is a duration such
+// as "1h" during which the previous key keeps working; "0s" revokes it immediately and
+// an empty overlap uses the server's default.
+type RotateAPIKeyRequest struct {
+	Overlap string `json:"overlap,omitempty"`
 }
 
 // ListAPIKeysResponse represents a list API keys response
@@ -61,12 +165,32 @@ type ListAPIKeysResponse struct {
 type InstanceStatus string
 
 const (
-	StatusProvisioning InstanceStatus = "provisioning"
-	StatusRunning      InstanceStatus = "running"
-	StatusDeleting     InstanceStatus = "deleting"
-	StatusFailed       InstanceStatus = "failed"
+	StatusQueued          InstanceStatus = "queued"
+	StatusProvisioning    InstanceStatus = "provisioning"
+	StatusRunning         InstanceStatus = "running"
+	StatusUpgrading       InstanceStatus = "upgrading"
+	StatusStopped         InstanceStatus = "stopped"
+	StatusSuspended       InstanceStatus = "suspended"
+	StatusPendingDeletion InstanceStatus = "pending_deletion"
+	StatusDeleting        InstanceStatus = "deleting"
+	StatusFailed          InstanceStatus = "failed"
 )
 
+// InstanceStatuses returns every instance status in lifecycle order
+func InstanceStatuses() []InstanceStatus {
+	return []InstanceStatus{
+		StatusQueued,
+		StatusProvisioning,
+		StatusRunning,
+		StatusUpgrading,
+		StatusStopped,
+		StatusSuspended,
+		StatusPendingDeletion,
+		StatusDeleting,
+		StatusFailed,
+	}
+}
+
 // Instance represents a Supabase instance
 type Instance struct {
 	ProjectName  string         `json:"project_name"`
@@ -77,23 +201,962 @@ type Instance struct {
 	CreatedAt    time.Time      `json:"created_at"`
 	UpdatedAt    time.Time      `json:"updated_at,omitempty"`
 	ErrorMessage *string        `json:"error_message,omitempty"`
+	ChartVersion string         `json:"chart_version,omitempty"`
+
+	// UpgradeAvailable reports whether a newer chart version than ChartVersion has been
+	// indexed from the chart repository
+	UpgradeAvailable bool `json:"upgrade_available,omitempty"`
+
+	// HelmRevision and HelmReleaseStatus describe the latest revision of the instance's
+	// Helm release, e.g. 3 and failed after an upgrade that did not complete
+	HelmRevision      int32  `json:"helm_revision,omitempty"`
+	HelmReleaseStatus string `json:"helm_release_status,omitempty"`
+
+	// Profiles names the shared service profiles attached to the instance
+	Profiles []string `json:"profiles,omitempty"`
+
+	// CustomDomains lists the customer-owned hostnames configured for the instance
+	CustomDomains *CustomDomains `json:"custom_domains,omitempty"`
+
+	// Ingress holds the instance's ingress annotations and TLS settings, omitted when it
+	// uses the controller's defaults
+	Ingress *InstanceIngress `json:"ingress,omitempty"`
+
+	// DNS holds the instance's external-dns hints, omitted when it has none
+	DNS *InstanceDNS `json:"dns,omitempty"`
+
+	// Advisories lists security advisories affecting the running components
+	Advisories []SecurityAdvisory `json:"advisories,omitempty"`
+
+	// Placement is the instance's node placement, omitted for shared placement
+	Placement *InstancePlacement `json:"placement,omitempty"`
+
+	// DedicatedNode names the node reserved for a dedicated instance
+	DedicatedNode string `json:"dedicated_node,omitempty"`
+
+	// Isolation is the requested isolation, omitted for namespace isolation
+	Isolation *InstanceIsolation `json:"isolation,omitempty"`
+
+	// IsolationLevel is the isolation the instance runs with, which differs from the
+	// requested level when it fell back to namespace isolation
+	IsolationLevel string `json:"isolation_level,omitempty"`
+
+	// NetworkIsolation reports whether traffic into the instance namespace is restricted
+	// to its own pods and the ingress controller
+	NetworkIsolation bool `json:"network_isolation,omitempty"`
+
+	// NamespaceLabels are the labels set on the instance namespace, e.g. a cost center
+	NamespaceLabels map[string]string `json:"namespace_labels,omitempty"`
+
+	// Tags are the key/value pairs the instance is organized by
+	Tags map[string]string `json:"tags,omitempty"`
+
+	// ConnectionPooler is the instance's PgBouncer pool, omitted when it has none
+	ConnectionPooler *ConnectionPooler `json:"connection_pooler,omitempty"`
+
+	// Monitoring reports whether a Postgres exporter and ServiceMonitor are deployed for
+	// the instance's database
+	Monitoring bool `json:"monitoring,omitempty"`
+
+	// Suspension is set while an administrator or the billing system has suspended the instance
+	Suspension *InstanceSuspension `json:"suspension,omitempty"`
+
+	// Maintenance is set while the instance's ingresses serve its maintenance page
+	Maintenance *InstanceMaintenance `json:"maintenance,omitempty"`
+
+	// PublicStatusBadge reports whether the instance's status badge is published
+	PublicStatusBadge bool `json:"public_status_badge,omitempty"`
+
+	// Storage is the instance's Postgres volume configuration, omitted when it uses the chart defaults
+	Storage *InstanceStorage `json:"storage,omitempty"`
+
+	// Database is the instance's database configuration, omitted when it has no read
+	// replicas and runs the chart's default Postgres version
+	Database *InstanceDatabase `json:"database,omitempty"`
+
+	// DatabaseVersion is the Postgres major version the database runs, omitted when it
+	// runs the chart's default
+	DatabaseVersion string `json:"database_version,omitempty"`
+
+	// ReadyReadReplicas is the number of the instance's read replicas that are ready
+	ReadyReadReplicas int32 `json:"ready_read_replicas,omitempty"`
+
+	// Resources is the requested CPU and memory of the instance's database, omitted when
+	// it uses the chart defaults
+	Resources *InstanceResources `json:"resources,omitempty"`
+
+	// HighAvailability is the instance's high availability configuration, omitted when
+	// its services run a single replica
+	HighAvailability *InstanceHighAvailability `json:"high_availability,omitempty"`
+
+	// DeletionProtection reports whether deleting the instance is refused
+	DeletionProtection bool `json:"deletion_protection,omitempty"`
+
+	// TTL is how long after its creation an ephemeral instance is deleted, as a
+	// duration such as "72h", and ExpiresAt is when that happens; both are omitted for
+	// instances that don't expire
+	TTL       string     `json:"ttl,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+
+	// Schedule is when the instance is stopped and started automatically, omitted when
+	// it has no schedule
+	Schedule *InstanceSchedule `json:"schedule,omitempty"`
+
+	// Env holds the additional environment variables of the instance's components
+	Env map[string]string `json:"env,omitempty"`
+
+	// ProvisionerImage is the image running the instance's provisioning, upgrade and
+	// cleanup Jobs, omitted when it uses the controller's image
+	ProvisionerImage string `json:"provisioner_image,omitempty"`
+
+	// Addons names the add-ons enabled for the instance
+	Addons []string `json:"addons,omitempty"`
+
+	// Template names the template the instance was created from, omitted when it was
+	// configured by hand
+	Template string `json:"template,omitempty"`
+
+	// AllowedConnections names the instances whose workloads may read the instance's
+	// database through an approved connection
+	AllowedConnections []string `json:"allowed_connections,omitempty"`
+
+	// Sandbox is true for developer sandbox instances, which are confined to a small
+	// tier and expire automatically
+	Sandbox bool `json:"sandbox,omitempty"`
+
+	// Imported is true for instances adopted from a Helm release installed outside of
+	// SupaControl rather than provisioned by it
+	Imported bool `json:"imported,omitempty"`
+
+	// SMTP is the mail server the instance's auth service sends email through, omitted
+	// when it uses the chart defaults or an SMTP profile
+	SMTP *InstanceSMTP `json:"smtp,omitempty"`
+
+	// PhaseHistory lists the instance's most recent phase transitions, oldest first. It
+	// is only returned when getting a single instance.
+	PhaseHistory []PhaseTransition `json:"phase_history,omitempty"`
+
+	// PendingDeletion is set while the instance is in the trash
+	PendingDeletion *InstancePendingDeletion `json:"pending_deletion,omitempty"`
+
+	// Notes are free-form notes kept about the instance
+	Notes string `json:"notes,omitempty"`
+
+	// Favorite reports whether the caller has marked the instance as a favorite
+	Favorite bool `json:"favorite,omitempty"`
+}
+
+// InstancePendingDeletion describes a deleted instance waiting in the trash.
+// It can be recovered with the undelete endpoint until PurgeAfter, when its
+// resources are cleaned up for good.
+type InstancePendingDeletion struct {
+	RequestedAt time.Time `json:"requested_at"`
+	PurgeAfter  time.Time `json:"purge_after"`
+}
+
+// SecurityAdvisory is a known vulnerability affecting a running component
+type SecurityAdvisory struct {
+	ID             string `json:"id"`
+	Component      string `json:"component"`
+	Severity       string `json:"severity"`
+	Summary        string `json:"summary"`
+	URL            string `json:"url,omitempty"`
+	CurrentVersion string `json:"current_version"`
+	FixedVersion   string `json:"fixed_version,omitempty"`
+}
+
+// Supabase components reported by the version and health endpoints
+const (
+	ComponentPostgres  = "postgres"
+	ComponentGoTrue    = "gotrue"
+	ComponentPostgREST = "postgrest"
+	ComponentKong      = "kong"
+	ComponentStudio    = "studio"
+	ComponentRealtime  = "realtime"
+	ComponentStorage   = "storage"
+)
+
+// ComponentVersion reports the running and target version of one Supabase component
+type ComponentVersion struct {
+	Component       string `json:"component"`
+	Image           string `json:"image,omitempty"`
+	CurrentVersion  string `json:"current_version,omitempty"`
+	TargetVersion   string `json:"target_version,omitempty"`
+	UpdateAvailable bool   `json:"update_available"`
+
+	Advisories []SecurityAdvisory `json:"advisories,omitempty"`
+}
+
+// InstanceVersionsResponse represents an instance component version report
+type InstanceVersionsResponse struct {
+	InstanceName     string             `json:"instance_name"`
+	ChartVersion     string             `json:"chart_version,omitempty"`
+	Components       []ComponentVersion `json:"components"`
+	UpdatesAvailable bool               `json:"updates_available"`
+	Warning          string             `json:"warning,omitempty"`
+}
+
+// Kinds of instance secrets
+const (
+	SecretKindJWTSecret        = "jwt-secret"
+	SecretKindAnonKey          = "anon-key"
+	SecretKindServiceRoleKey   = "service-role-key"
+	SecretKindPostgresPassword = "postgres-password"
+	SecretKindTLSCertificate   = "tls-certificate"
+)
+
+// InstanceSecretAge reports when one of an instance's keys, passwords or TLS
+// certificates was last rotated. Overdue secrets are older than the server's maximum
+// secret age, or are certificates that have expired.
+type InstanceSecretAge struct {
+	Name       string     `json:"name"`
+	Kind       string     `json:"kind"`
+	SecretName string     `json:"secret_name"`
+	RotatedAt  time.Time  `json:"rotated_at"`
+	AgeDays    int        `json:"age_days"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	Overdue    bool       `json:"overdue"`
+}
+
+// InstanceSecurityReport lists the security advisories affecting an instance's running
+// components and the ages of its secrets
+type InstanceSecurityReport struct {
+	InstanceName   string              `json:"instance_name"`
+	Advisories     []SecurityAdvisory  `json:"advisories"`
+	Secrets        []InstanceSecretAge `json:"secrets"`
+	SecretsOverdue bool                `json:"secrets_overdue"`
+}
+
+// Health states of an instance and its components
+const (
+	// HealthHealthy means every replica is ready
+	HealthHealthy = "healthy"
+
+	// HealthDegraded means some but not all replicas are ready, or for an instance, that
+	// at least one component is not healthy
+	HealthDegraded = "degraded"
+
+	// HealthUnavailable means no replica is ready
+	HealthUnavailable = "unavailable"
+
+	// HealthStopped means the workload is scaled to zero, e.g. while the instance is suspended
+	HealthStopped = "stopped"
+
+	// HealthMissing means the instance namespace has no workload for the component
+	HealthMissing = "missing"
+)
+
+// ComponentHealth reports the readiness of the Deployment or StatefulSet running one
+// Supabase component
+type ComponentHealth struct {
+	Component     string `json:"component"`
+	Status        string `json:"status"`
+	Workload      string `json:"workload,omitempty"`
+	ReadyReplicas int32  `json:"ready_replicas"`
+	Replicas      int32  `json:"replicas"`
+	Message       string `json:"message,omitempty"`
+}
+
+// InstanceHealthResponse reports the health of each component of an instance. Status is
+// healthy when every component is, stopped when every component is scaled to zero and
+// degraded otherwise.
+type InstanceHealthResponse struct {
+	InstanceName string            `json:"instance_name"`
+	Status       string            `json:"status"`
+	Components   []ComponentHealth `json:"components"`
+}
+
+// PreviewInstanceRequest selects the chart version to preview; empty previews the
+// instance's configured chart version
+type PreviewInstanceRequest struct {
+	ChartVersion string `json:"chart_version,omitempty"`
+}
+
+// Manifest change actions
+const (
+	ManifestAdded   = "added"
+	ManifestRemoved = "removed"
+	ManifestChanged = "changed"
+)
+
+// ManifestChange is a resource an upgrade would add, remove or change. Diff is a unified
+// diff of the resource's YAML; Secret values are redacted.
+type ManifestChange struct {
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	Namespace string `json:"namespace,omitempty"`
+	Action    string `json:"action"`
+	Diff      string `json:"diff"`
+}
+
+// InstancePreviewResponse lists the manifest changes an upgrade of an instance would apply
+type InstancePreviewResponse struct {
+	InstanceName        string           `json:"instance_name"`
+	CurrentChartVersion string           `json:"current_chart_version"`
+	TargetChartVersion  string           `json:"target_chart_version"`
+	Changes             []ManifestChange `json:"changes"`
+	Unchanged           int              `json:"unchanged"`
+}
+
+// Usage metrics sources
+const (
+	MetricsSourceMetricsServer = "metrics-server"
+	MetricsSourcePrometheus    = "prometheus"
+)
+
+// PodMetrics reports the current resource usage of one pod
+type PodMetrics struct {
+	Name          string `json:"name"`
+	CPUMillicores int64  `json:"cpu_millicores"`
+	MemoryBytes   int64  `json:"memory_bytes"`
+}
+
+// VolumeMetrics reports the capacity and usage of one persistent volume claim.
+// UsedBytes is omitted when the metrics source does not report volume usage.
+type VolumeMetrics struct {
+	Name          string `json:"name"`
+	CapacityBytes int64  `json:"capacity_bytes"`
+	UsedBytes     *int64 `json:"used_bytes,omitempty"`
+}
+
+// InstanceMetrics reports the CPU, memory and storage usage of an instance
+type InstanceMetrics struct {
+	InstanceName         string          `json:"instance_name"`
+	Source               string          `json:"source"`
+	CPUMillicores        int64           `json:"cpu_millicores"`
+	MemoryBytes          int64           `json:"memory_bytes"`
+	StorageCapacityBytes int64           `json:"storage_capacity_bytes"`
+	StorageUsedBytes     int64           `json:"storage_used_bytes"`
+	Pods                 []PodMetrics    `json:"pods"`
+	Volumes              []VolumeMetrics `json:"volumes"`
+	CollectedAt          time.Time       `json:"collected_at"`
+}
+
+// Kinds of errors found in component logs
+const (
+	ErrorKindHTTP5xx = "http_5xx"
+	ErrorKindError   = "error"
+	ErrorKindFatal   = "fatal"
+)
+
+// ErrorSample is a log line recognized as an error
+type ErrorSample struct {
+	Time time.Time `json:"time"`
+	Pod  string    `json:"pod"`
+	Line string    `json:"line"`
+}
+
+// ComponentErrors counts one kind of error logged by a component, with the
+// latest sample lines
+type ComponentErrors struct {
+	Component string        `json:"component"`
+	Kind      string        `json:"kind"`
+	Count     int           `json:"count"`
+	LastSeen  *time.Time    `json:"last_seen,omitempty"`
+	Samples   []ErrorSample `json:"samples,omitempty"`
 }
 
+// InstanceErrorSummary reports the errors an instance's Kong, GoTrue and
+// Postgres containers logged within a rolling window, most frequent first
+type InstanceErrorSummary struct {
+	Instance      string            `json:"instance"`
+	WindowSeconds int               `json:"window_seconds"`
+	TotalErrors   int               `json:"total_errors"`
+	Components    []ComponentErrors `json:"components"`
+	UpdatedAt     *time.Time        `json:"updated_at,omitempty"`
+}
+
+// MaxInstanceNameLength is the longest instance name. Names are DNS-1123 labels
+// and become part of the instance namespace, supa-<name>.
+const MaxInstanceNameLength = 58
+
 // CreateInstanceRequest represents an instance creation request
 type CreateInstanceRequest struct {
 	Name string `json:"name" binding:"required"`
+
+	// Profiles names shared service profiles (SMTP, S3, OAuth) to attach
+	Profiles []string `json:"profiles,omitempty"`
+
+	// CustomDomains serves the instance on customer-owned hostnames
+	CustomDomains *CustomDomains `json:"custom_domains,omitempty"`
+
+	// Ingress overrides the annotations and TLS settings of the instance's ingresses
+	Ingress *InstanceIngress `json:"ingress,omitempty"`
+
+	// DNS passes hints to external-dns when the server publishes instance hostnames
+	// through it
+	DNS *InstanceDNS `json:"dns,omitempty"`
+
+	// Placement controls which nodes run the instance's workloads
+	Placement *InstancePlacement `json:"placement,omitempty"`
+
+	// Isolation selects how strongly the instance is isolated from other tenants
+	Isolation *InstanceIsolation `json:"isolation,omitempty"`
+
+	// NetworkIsolation only admits traffic from the instance's own pods and the ingress
+	// controller into its namespace
+	NetworkIsolation bool `json:"network_isolation,omitempty"`
+
+	// NamespaceLabels are set on the instance namespace, e.g. to attribute its costs to a
+	// team. Keys in the kubernetes.io, k8s.io and supacontrol.io domains are refused.
+	NamespaceLabels map[string]string `json:"namespace_labels,omitempty"`
+
+	// Tags organize instances, e.g. {"env": "staging"}, and can be filtered on when
+	// listing them. Keys and values follow the rules of Kubernetes label values.
+	Tags map[string]string `json:"tags,omitempty"`
+
+	// ConnectionPooler deploys PgBouncer in front of the instance database
+	ConnectionPooler *ConnectionPooler `json:"connection_pooler,omitempty"`
+
+	// Monitoring deploys a Postgres exporter and a ServiceMonitor for the platform's
+	// Prometheus
+	Monitoring bool `json:"monitoring,omitempty"`
+
+	// PublicStatusBadge publishes an unauthenticated status badge for the instance
+	PublicStatusBadge bool `json:"public_status_badge,omitempty"`
+
+	// Storage sizes the instance's Postgres volume and selects its StorageClass
+	Storage *InstanceStorage `json:"storage,omitempty"`
+
+	// Database configures the instance's database, such as its read replicas
+	Database *InstanceDatabase `json:"database,omitempty"`
+
+	// Resources sizes the CPU and memory of the instance's database
+	Resources *InstanceResources `json:"resources,omitempty"`
+
+	// HighAvailability runs the instance's API gateway, auth and realtime services with
+	// several replicas spread across nodes
+	HighAvailability *InstanceHighAvailability `json:"high_availability,omitempty"`
+
+	// DeletionProtection refuses deletion of the instance until it is disabled
+	DeletionProtection bool `json:"deletion_protection,omitempty"`
+
+	// TTL makes the instance ephemeral: it is deleted this long after its creation,
+	// given as a duration such as "72h" of at most MaxInstanceTTL
+	TTL string `json:"ttl,omitempty"`
+
+	// Schedule stops and starts the instance automatically, e.g. at night and on weekends
+	Schedule *InstanceSchedule `json:"schedule,omitempty"`
+
+	// Env sets additional environment variables of the instance's components, such as
+	// GOTRUE_DISABLE_SIGNUP. Only the variables listed in MetaEnums.EnvVariables are accepted.
+	Env map[string]string `json:"env,omitempty"`
+
+	// ProvisionerImage overrides the image running the instance's provisioning, upgrade
+	// and cleanup Jobs, e.g. a mirror in an internal registry (admins only)
+	ProvisionerImage string `json:"provisioner_image,omitempty"`
+
+	// Addons names the add-ons to enable, as listed by GET /api/v1/addons
+	Addons []string `json:"addons,omitempty"`
+
+	// Template names the template to create the instance from, as listed by
+	// GET /api/v1/templates. Its chart version, resources and storage apply unless the
+	// request sets them; its chart values apply beneath the instance's own settings.
+	Template string `json:"template,omitempty"`
+}
+
+// ImportInstanceRequest adopts a Supabase Helm release installed outside of SupaControl
+// as an instance. The controller verifies that the release is deployed in Namespace and
+// takes it over without provisioning anything; ReleaseName defaults to Name.
+type ImportInstanceRequest struct {
+	Name        string `json:"name"`
+	Namespace   string `json:"namespace"`
+	ReleaseName string `json:"release_name,omitempty"`
+}
+
+// MaxReleaseNameLength is the longest Helm release name
+const MaxReleaseNameLength = 53
+
+// InstanceSchedule stops and starts an instance at the times given by five-field
+// cron expressions, evaluated in TimeZone (UTC by default). NextStop and NextStart
+// are reported by the API and ignored in requests.
+type InstanceSchedule struct {
+	Stop      string     `json:"stop"`
+	Start     string     `json:"start"`
+	TimeZone  string     `json:"time_zone,omitempty"`
+	NextStop  *time.Time `json:"next_stop,omitempty"`
+	NextStart *time.Time `json:"next_start,omitempty"`
+}
+
+// InstanceStorage configures an instance's Postgres volume. Size is a
+// Kubernetes quantity such as "20Gi"; an empty StorageClass uses the cluster
+// default.
+type InstanceStorage struct {
+	Size         string `json:"size,omitempty"`
+	StorageClass string `json:"storage_class,omitempty"`
+}
+
+// InstanceDatabase configures an instance's Postgres database. ReadReplicas
+// streaming replicas, at most MaxReadReplicas, serve read-only queries through
+// a separate connection string. Version selects the Postgres major version, one of
+// MetaEnums.PostgresVersions; empty runs the chart's default. Audit turns on pgAudit
+// statement logging.
+type InstanceDatabase struct {
+	ReadReplicas int32                  `json:"read_replicas"`
+	Version      string                 `json:"version,omitempty"`
+	Audit        *InstanceDatabaseAudit `json:"audit,omitempty"`
+}
+
+// InstanceDatabaseAudit configures pgAudit statement logging of an instance database.
+// When enabled, DDL, role changes and writes are logged with the "AUDIT:" prefix to the
+// Postgres log, which GET /instances/{name}/logs?container=postgres&grep=AUDIT returns.
+type InstanceDatabaseAudit struct {
+	Enabled bool `json:"enabled"`
+}
+
+// UpdateDatabaseAuditRequest turns pgAudit statement logging of an instance on or off
+type UpdateDatabaseAuditRequest struct {
+	Enabled bool `json:"enabled"`
+}
+
+// UpgradePostgresRequest upgrades an instance's database in place to a newer Postgres
+// major version
+type UpgradePostgresRequest struct {
+	Version string `json:"version"`
+}
+
+// MaxReadReplicas is the most read replicas an instance database can have
+const MaxReadReplicas = 5
+
+// InstanceHighAvailability configures the replicas of an instance's stateless services.
+// Kong, GoTrue and Realtime each run Replicas pods, between MinHighAvailabilityReplicas
+// and MaxHighAvailabilityReplicas, and keep at least one of them through node drains.
+type InstanceHighAvailability struct {
+	Replicas int32 `json:"replicas"`
+}
+
+const (
+	// MinHighAvailabilityReplicas and MaxHighAvailabilityReplicas bound the replicas
+	// of a highly available instance's services
+	MinHighAvailabilityReplicas = 2
+	MaxHighAvailabilityReplicas = 10
+)
+
+// MaxInstanceTTL is the furthest in the future an ephemeral instance can expire
+const MaxInstanceTTL = 30 * 24 * time.Hour
+
+// ExtendInstanceRequest postpones the expiry of an ephemeral instance by Duration,
+// given as a duration such as "24h"
+type ExtendInstanceRequest struct {
+	Duration string `json:"duration"`
+}
+
+// UpdateReadReplicasRequest changes the number of an instance's read replicas;
+// zero removes them
+type UpdateReadReplicasRequest struct {
+	ReadReplicas int32 `json:"read_replicas"`
+}
+
+// InstanceResources sizes an instance's Postgres database. Tier is one of small,
+// medium, large and xlarge; CPU and Memory are Kubernetes quantities such as
+// "1500m" and "3Gi" that override the tier.
+type InstanceResources struct {
+	Tier   string `json:"tier,omitempty"`
+	CPU    string `json:"cpu,omitempty"`
+	Memory string `json:"memory,omitempty"`
+}
+
+// MaxInstanceTags is the most tags an instance can have
+const MaxInstanceTags = 20
+
+// MaxTagKeyLength is the longest a tag key can be, in characters
+const MaxTagKeyLength = 59
+
+// UpdateInstanceRequest changes an instance. Changed resources resize the database of a
+// running instance with a restart, which the Resizing condition reports on. Tags are
+// merged into the instance's tags; a null value removes a tag.
+type UpdateInstanceRequest struct {
+	Resources *InstanceResources `json:"resources,omitempty"`
+	Tags      map[string]*string `json:"tags,omitempty"`
+}
+
+// ResizeStorageRequest grows an instance's Postgres volume to Size. The
+// StorageClass must allow volume expansion, and volumes cannot shrink.
+type ResizeStorageRequest struct {
+	Size string `json:"size"`
+}
+
+// PhaseTransition records an instance moving from one phase to another. From
+// is empty for the instance's first phase.
+type PhaseTransition struct {
+	From   string    `json:"from,omitempty"`
+	To     string    `json:"to"`
+	At     time.Time `json:"at"`
+	Reason string    `json:"reason,omitempty"`
+}
+
+// InstanceSMTP describes the mail server of an instance's auth service. The
+// password is never returned; PasswordSet reports whether one is stored.
+type InstanceSMTP struct {
+	Host        string `json:"host"`
+	Port        int32  `json:"port"`
+	User        string `json:"user,omitempty"`
+	SenderEmail string `json:"sender_email,omitempty"`
+	SenderName  string `json:"sender_name,omitempty"`
+	PasswordSet bool   `json:"password_set"`
+}
+
+// UpdateSMTPRequest sets the mail server of an instance's auth service. An
+// empty Password keeps the stored one.
+type UpdateSMTPRequest struct {
+	Host        string `json:"host"`
+	Port        int32  `json:"port"`
+	User        string `json:"user,omitempty"`
+	Password    string `json:"password,omitempty"`
+	SenderEmail string `json:"sender_email,omitempty"`
+	SenderName  string `json:"sender_name,omitempty"`
+}
+
+// Addon describes an add-on instances can enable
+type Addon struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+
+	// HelmValues reports whether the add-on sets chart values, which take effect when
+	// the instance is provisioned or upgraded
+	HelmValues bool `json:"helm_values"`
+
+	// Manifests reports whether the add-on deploys extra objects, which are applied in
+	// the instance namespace as soon as the instance runs
+	Manifests bool `json:"manifests"`
+}
+
+// ListAddonsResponse is the response for listing the available add-ons
+type ListAddonsResponse struct {
+	Addons []Addon `json:"addons"`
+	Count  int     `json:"count"`
+}
+
+// InstanceTemplate is a standard configuration instances can be created from, such as a
+// plan offered to users. Its chart values are applied by the controller and not listed.
+type InstanceTemplate struct {
+	Name         string             `json:"name"`
+	DisplayName  string             `json:"display_name"`
+	Description  string             `json:"description,omitempty"`
+	ChartVersion string             `json:"chart_version,omitempty"`
+	Resources    *InstanceResources `json:"resources,omitempty"`
+	Storage      *InstanceStorage   `json:"storage,omitempty"`
+
+	// Quota caps the instances created from the template, omitted when unlimited
+	Quota *TemplateQuota `json:"quota,omitempty"`
+}
+
+// TemplateQuota caps the instances created from a template across the installation and
+// per user; zero means unlimited
+type TemplateQuota struct {
+	MaxInstances        int `json:"max_instances,omitempty"`
+	MaxInstancesPerUser int `json:"max_instances_per_user,omitempty"`
+}
+
+// ListTemplatesResponse is the response for listing the instance templates
+type ListTemplatesResponse struct {
+	Templates []InstanceTemplate `json:"templates"`
+	Count     int                `json:"count"`
+}
+
+// EnableAddonRequest enables an add-on for an instance
+type EnableAddonRequest struct {
+	Name string `json:"name"`
+}
+
+// Connection statuses
+const (
+	ConnectionStatusPending = "pending"
+	ConnectionStatusActive  = "active"
+	ConnectionStatusRevoked = "revoked"
+)
+
+// Connection lets the workloads of the source instance read the database of the target
+// instance. It takes effect once the owners of both instances approved it; read-only
+// credentials, rotated regularly, are then published as Secret connection-<target> in
+// the source instance's namespace.
+type Connection struct {
+	ID               int64      `json:"id" db:"id"`
+	SourceInstance   string     `json:"source_instance" db:"source_instance"`
+	TargetInstance   string     `json:"target_instance" db:"target_instance"`
+	Status           string     `json:"status" db:"status"`
+	RequestedBy      *int64     `json:"requested_by" db:"requested_by"`
+	SourceApprovedAt *time.Time `json:"source_approved_at,omitempty" db:"source_approved_at"`
+	TargetApprovedAt *time.Time `json:"target_approved_at,omitempty" db:"target_approved_at"`
+	CreatedAt        time.Time  `json:"created_at" db:"created_at"`
+	ActivatedAt      *time.Time `json:"activated_at,omitempty" db:"activated_at"`
+	RevokedAt        *time.Time `json:"revoked_at,omitempty" db:"revoked_at"`
+}
+
+// Approved reports whether the owners of both instances approved the connection
+func (c *Connection) Approved() bool {
+	return c.SourceApprovedAt != nil && c.TargetApprovedAt != nil
+}
+
+// CreateConnectionRequest requests a connection from the source to the target instance.
+// The sides the caller owns are approved right away.
+type CreateConnectionRequest struct {
+	SourceInstance string `json:"source_instance"`
+	TargetInstance string `json:"target_instance"`
+}
+
+// ListConnectionsResponse represents a list of connections between instances
+type ListConnectionsResponse struct {
+	Connections []*Connection `json:"connections"`
+	Count       int           `json:"count"`
+}
+
+// Instance creation steps reported by the progress stream, in order
+const (
+	ProgressStepNamespace = "namespace_created"
+	ProgressStepSecrets   = "secrets_created"
+	ProgressStepChart     = "chart_installed"
+	ProgressStepIngress   = "ingress_ready"
+	ProgressStepDNS       = "dns_ready"
+)
+
+// Progress step states
+const (
+	ProgressPending    = "pending"
+	ProgressInProgress = "in_progress"
+	ProgressDone       = "done"
+	ProgressFailed     = "failed"
+)
+
+// ProgressStep reports the state of one instance creation step
+type ProgressStep struct {
+	Step    string `json:"step"`
+	State   string `json:"state"`
+	Message string `json:"message,omitempty"`
+}
+
+// InstanceProgress reports how far an instance's creation has got. Percent
+// counts finished steps; Complete is set once every step is done, and Error
+// once provisioning has failed.
+type InstanceProgress struct {
+	Instance string         `json:"instance"`
+	Status   InstanceStatus `json:"status"`
+	Steps    []ProgressStep `json:"steps"`
+	Percent  int            `json:"percent"`
+	Complete bool           `json:"complete"`
+	Error    string         `json:"error,omitempty"`
+}
+
+// ClusterClass is an IngressClass or StorageClass available in the cluster
+type ClusterClass struct {
+	Name    string `json:"name"`
+	Default bool   `json:"default,omitempty"`
+}
+
+// MetaEnums lists the values the API accepts and reports, so clients can build
+// pickers without hardcoding them. Lists read from the cluster or the chart
+// repository are empty when they cannot be read.
+type MetaEnums struct {
+	Statuses          []InstanceStatus `json:"statuses"`
+	Phases            []string         `json:"phases"`
+	PlacementModes    []string         `json:"placement_modes"`
+	IsolationLevels   []string         `json:"isolation_levels"`
+	PoolModes         []string         `json:"pool_modes"`
+	SuspensionReasons []string         `json:"suspension_reasons"`
+	ProfileTypes      []string         `json:"profile_types"`
+	Components        []string         `json:"components"`
+	EnvVariables      []string         `json:"env_variables"`
+
+	// ChartVersions are the Supabase chart versions published in the chart
+	// repository, newest first
+	ChartVersions  []string       `json:"chart_versions"`
+	IngressClasses []ClusterClass `json:"ingress_classes"`
+	StorageClasses []ClusterClass `json:"storage_classes"`
+
+	// PostgresVersions are the Postgres major versions instances of the default
+	// chart version can run, oldest first
+	PostgresVersions []string `json:"postgres_versions"`
+}
+
+// UpdateDeletionProtectionRequest enables or disables an instance's deletion protection
+type UpdateDeletionProtectionRequest struct {
+	Enabled bool `json:"enabled"`
+}
+
+// UpdateStatusBadgeRequest publishes or unpublishes an instance's status badge
+type UpdateStatusBadgeRequest struct {
+	Public bool `json:"public"`
+}
+
+// UpdateInstanceNotesRequest replaces the notes of an instance; empty notes are removed
+type UpdateInstanceNotesRequest struct {
+	Notes string `json:"notes"`
+}
+
+// UpdateInstanceFavoriteRequest adds an instance to or removes it from the caller's favorites
+type UpdateInstanceFavoriteRequest struct {
+	Favorite bool `json:"favorite"`
+}
+
+// Statuses shown on instance status badges
+const (
+	BadgeStatusRunning  = "running"
+	BadgeStatusDegraded = "degraded"
+	BadgeStatusDown     = "down"
+)
+
+// Placement modes for an instance's workloads
+const (
+	PlacementShared    = "shared"
+	PlacementDedicated = "dedicated"
+)
+
+// InstancePlacement selects shared or dedicated nodes for an instance. In
+// dedicated mode the instance gets a node of its own, chosen among the nodes
+// matching NodeSelector, which is tainted so no other workload runs there.
+type InstancePlacement struct {
+	Mode         string            `json:"mode"`
+	NodeSelector map[string]string `json:"node_selector,omitempty"`
+}
+
+// Isolation levels and fallbacks for an instance
+const (
+	IsolationNamespace   = "namespace"
+	IsolationVCluster    = "vcluster"
+	IsolationKataRuntime = "kata-runtime"
+
+	IsolationFallbackFail      = "fail"
+	IsolationFallbackNamespace = "namespace"
+)
+
+// InstanceIsolation selects how an instance is isolated from other tenants:
+// in its own namespace (the default), in a virtual cluster inside that
+// namespace, or in Kata Containers micro-VMs. When the cluster cannot provide
+// the level, Fallback decides whether provisioning fails or continues with
+// namespace isolation.
+type InstanceIsolation struct {
+	Level    string `json:"level"`
+	Fallback string `json:"fallback,omitempty"`
+}
+
+// Connection pool modes
+const (
+	PoolModeSession     = "session"
+	PoolModeTransaction = "transaction"
+	PoolModeStatement   = "statement"
+)
+
+// ConnectionPooler configures the PgBouncer pool in front of an instance
+// database. Unset settings use the defaults: transaction pooling, 20 server
+// connections per user and database, and 1000 client connections.
+type ConnectionPooler struct {
+	Enabled              bool   `json:"enabled"`
+	PoolMode             string `json:"pool_mode,omitempty"`
+	DefaultPoolSize      int    `json:"default_pool_size,omitempty"`
+	MaxClientConnections int    `json:"max_client_connections,omitempty"`
+}
+
+// Suspension reasons
+const (
+	SuspensionReasonBilling        = "billing"
+	SuspensionReasonQuota          = "quota"
+	SuspensionReasonAdministrative = "administrative"
+)
+
+// InstanceSuspension describes an administrative suspension of an instance.
+// A suspended instance is scaled to zero and its owner cannot start it until
+// an administrator or the billing system lifts the suspension.
+type InstanceSuspension struct {
+	Reason      string    `json:"reason"`
+	Message     string    `json:"message,omitempty"`
+	SuspendedAt time.Time `json:"suspended_at"`
+}
+
+// InstanceMaintenance describes a maintenance window of an instance. Its ingresses serve a
+// maintenance page with status 503 while its workloads keep running; Active reports
+// whether the controller has switched them over yet.
+type InstanceMaintenance struct {
+	Message   string    `json:"message,omitempty"`
+	StartedAt time.Time `json:"started_at"`
+	Active    bool      `json:"active"`
+}
+
+// UpdateMaintenanceRequest puts an instance into maintenance or takes it out again. The
+// message is shown on the maintenance page.
+type UpdateMaintenanceRequest struct {
+	Enabled bool   `json:"enabled"`
+	Message string `json:"message,omitempty"`
+}
+
+// MaxMaintenanceMessageLength matches the CRD limit on maintenance messages
+const MaxMaintenanceMessageLength = 500
+
+// SuspendInstanceRequest represents a request to suspend an instance
+type SuspendInstanceRequest struct {
+	Reason  string `json:"reason"`
+	Message string `json:"message,omitempty"`
+}
+
+// Billing webhook events
+const (
+	BillingEventSuspend = "suspend"
+	BillingEventResume  = "resume"
+)
+
+// BillingWebhookEvent is sent by a billing system to suspend or resume an
+// instance, or every instance owned by a user. Requests carry an
+// X-SupaControl-Signature header: "sha256=" followed by the hex HMAC-SHA256 of
+// the body keyed with the webhook secret.
+type BillingWebhookEvent struct {
+	Event    string `json:"event"`
+	Instance string `json:"instance,omitempty"`
+	UserID   *int64 `json:"user_id,omitempty"`
+	Reason   string `json:"reason,omitempty"`
+	Message  string `json:"message,omitempty"`
+}
+
+// BillingWebhookResponse lists the instances a billing webhook event changed
+type BillingWebhookResponse struct {
+	Instances []string `json:"instances"`
+}
+
+// CustomDomains holds customer-owned hostnames for an instance. Each one left
+// empty falls back to the generated <name>-api / <name>-studio hostname.
+type CustomDomains struct {
+	API    string `json:"api,omitempty"`
+	Studio string `json:"studio,omitempty"`
+}
+
+// InstanceIngress configures an instance's ingresses. Annotations are merged over the
+// controller's defaults (admins only); TLSIssuer names the cert-manager ClusterIssuer
+// used instead of the controller's, and DisableTLS serves the instance over plain HTTP,
+// e.g. on bare-metal development clusters without cert-manager.
+type InstanceIngress struct {
+	Annotations map[string]string `json:"annotations,omitempty"`
+	TLSIssuer   string            `json:"tls_issuer,omitempty"`
+	DisableTLS  bool              `json:"disable_tls,omitempty"`
 }
 
+// InstanceDNS holds external-dns hints for an instance's hostnames. Provider selects the
+// external-dns deployment publishing them, e.g. route53, and TTL is the time to live of
+// their records in seconds (0 uses the external-dns default).
+type InstanceDNS struct {
+	Provider string `json:"provider,omitempty"`
+	TTL      int32  `json:"ttl,omitempty"`
+}
+
+// MaxDNSTTL is the longest TTL of an instance's DNS records in seconds
+const MaxDNSTTL = 86400
+
 // CreateInstanceResponse represents an instance creation response
 type CreateInstanceResponse struct {
 	Instance *Instance `json:"instance"`
 	Message  string    `json:"message"`
+
+	// OperationID identifies the provisioning operation at GET /operations/{id};
+	// it is omitted when the operation could not be recorded
+	OperationID int64 `json:"operation_id,omitempty"`
 }
 
 // ListInstancesResponse represents a list instances response
 type ListInstancesResponse struct {
-	Instances []*Instance `json:"instances"`
-	Count     int         `json:"count"`
+	Instances         []*Instance `json:"instances"`
+	Count             int         `json:"count"`
+	AffectedInstances int         `json:"affected_instances"`
+
+	// Stale reports that the Kubernetes API could not be reached and the instances were
+	// read from the database mirror, which lags them by up to the sync interval
+	Stale bool `json:"stale,omitempty"`
 }
 
 // GetInstanceResponse represents a get instance response
@@ -104,4 +1167,608 @@ type GetInstanceResponse struct {
 // DeleteInstanceResponse represents a delete instance response
 type DeleteInstanceResponse struct {
 	Message string `json:"message"`
+
+	// PurgeAfter is when an instance moved to the trash is deleted for good;
+	// it is omitted when the instance is deleted immediately
+	PurgeAfter *time.Time `json:"purge_after,omitempty"`
+
+	// OperationID identifies the deletion operation at GET /operations/{id}; it is
+	// omitted when the operation could not be recorded
+	OperationID int64 `json:"operation_id,omitempty"`
+}
+
+// InstanceCredentials holds connection details and keys for a Supabase instance
+type InstanceCredentials struct {
+	APIURL           string `json:"api_url,omitempty"`
+	DatabaseHost     string `json:"database_host"`
+	DatabasePort     int    `json:"database_port"`
+	DatabaseName     string `json:"database_name"`
+	DatabaseUser     string `json:"database_user"`
+	DatabasePassword string `json:"database_password"`
+	DatabaseURL      string `json:"database_url"`
+	AnonKey          string `json:"anon_key"`
+	ServiceRoleKey   string `json:"service_role_key"`
+
+	// Pooled connection details, set when the instance has a connection pooler
+	PooledDatabaseHost string `json:"pooled_database_host,omitempty"`
+	PooledDatabasePort int    `json:"pooled_database_port,omitempty"`
+	PooledDatabaseURL  string `json:"pooled_database_url,omitempty"`
+
+	// Read-only connection details, set when the instance has read replicas
+	ReadOnlyDatabaseHost string `json:"read_only_database_host,omitempty"`
+	ReadOnlyDatabasePort int    `json:"read_only_database_port,omitempty"`
+	ReadOnlyDatabaseURL  string `json:"read_only_database_url,omitempty"`
+}
+
+// GetInstanceCredentialsResponse represents a get instance credentials response
+type GetInstanceCredentialsResponse struct {
+	Credentials *InstanceCredentials `json:"credentials"`
+}
+
+// CreateTunnelRequest opens a tunnel to an instance database for TTL, given as a
+// duration such as "30m" of at most MaxTunnelTTL; empty uses DefaultTunnelTTL
+type CreateTunnelRequest struct {
+	TTL string `json:"ttl,omitempty"`
+}
+
+const (
+	// DefaultTunnelTTL and MaxTunnelTTL are how long a database tunnel stays usable by
+	// default and at most
+	DefaultTunnelTTL = time.Hour
+	MaxTunnelTTL     = 8 * time.Hour
+)
+
+// CreateTunnelResponse describes an opened database tunnel. Each WebSocket connected to
+// Path on the API server, with the same credentials, carries one TCP connection to the
+// instance's Postgres port and is closed at ExpiresAt. Token is the secret part of Path.
+type CreateTunnelResponse struct {
+	Path      string    `json:"path"`
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// StudioSessionTTL is how long a Studio link stays valid. Links are meant to be opened
+// right away, and each can only be opened once.
+const StudioSessionTTL = time.Minute
+
+// StudioProxySessionTTL is how long Studio stays signed in after a link was opened.
+// Afterwards opening Studio again takes a new link.
+const StudioProxySessionTTL = time.Hour
+
+// CreateStudioSessionResponse is a one-time link that opens an instance's Studio signed
+// in. Opening URL in a browser signs it in to Studio, which SupaControl serves under
+// /api/v1/studio/{name}/ with the instance's dashboard credentials.
+type CreateStudioSessionResponse struct {
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// Team roles
+const (
+	TeamRoleAdmin  = "admin"
+	TeamRoleMember = "member"
+)
+
+// Team represents a group of users
+type Team struct {
+	ID        int64     `json:"id" db:"id"`
+	Name      string    `json:"name" db:"name"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// TeamMember represents a user's membership in a team
+type TeamMember struct {
+	TeamID    int64     `json:"team_id" db:"team_id"`
+	UserID    int64     `json:"user_id" db:"user_id"`
+	Role      string    `json:"role" db:"role"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// TeamInvitation represents a pending, accepted, or revoked team invitation
+type TeamInvitation struct {
+	ID         int64      `json:"id" db:"id"`
+	TeamID     int64      `json:"team_id" db:"team_id"`
+	Email      string     `json:"email" db:"email"`
+	Role       string     `json:"role" db:"role"`
+	InvitedBy  *int64     `json:"invited_by" db:"invited_by"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+	ExpiresAt  time.Time  `json:"expires_at" db:"expires_at"`
+	AcceptedAt *time.Time `json:"accepted_at" db:"accepted_at"`
+	AcceptedBy *int64     `json:"accepted_by" db:"accepted_by"`
+	RevokedAt  *time.Time `json:"revoked_at" db:"revoked_at"`
+}
+
+// MaxTeamNameLength is the longest name a team can have, in characters
+const MaxTeamNameLength = 255
+
+// CreateTeamRequest represents a team creation request
+type CreateTeamRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+// ListTeamsResponse represents a list teams response
+type ListTeamsResponse struct {
+	Teams []*Team `json:"teams"`
+	Count int     `json:"count"`
+}
+
+// CreateInvitationRequest represents a team invitation request
+type CreateInvitationRequest struct {
+	Email          string `json:"email" binding:"required"`
+	Role           string `json:"role,omitempty"`
+	ExpiresInHours int    `json:"expires_in_hours,omitempty"`
+}
+
+// ResendInvitationRequest represents a request to extend and resend a team invitation
+type ResendInvitationRequest struct {
+	ExpiresInHours int `json:"expires_in_hours,omitempty"`
+}
+
+// CreateInvitationResponse represents a team invitation response. Notified reports
+// whether the invitation was delivered to the notification webhook.
+type CreateInvitationResponse struct {
+	Invitation *TeamInvitation `json:"invitation"`
+	Token      string          `json:"token"`
+	InviteURL  string          `json:"invite_url"`
+	Notified   bool            `json:"notified"`
+}
+
+// ListInvitationsResponse represents a list team invitations response
+type ListInvitationsResponse struct {
+	Invitations []*TeamInvitation `json:"invitations"`
+	Count       int               `json:"count"`
+}
+
+// AcceptInvitationRequest represents an invitation acceptance request.
+// Username and password are ignored for a signed-in user. Otherwise, if the
+// username exists the password must match and the account is linked, else a
+// new account is created with these credentials.
+type AcceptInvitationRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+}
+
+// AcceptInvitationResponse represents an invitation acceptance response
+type AcceptInvitationResponse struct {
+	Token string    `json:"token"`
+	User  *UserInfo `json:"user"`
+	Team  *Team     `json:"team"`
+	Role  string    `json:"role"`
+}
+
+// UserPreferences holds a user's UI preferences (theme, default filters,
+// table columns, ...) as an opaque JSON object owned by the client
+type UserPreferences struct {
+	Preferences json.RawMessage `json:"preferences" db:"preferences"`
+	UpdatedAt   *time.Time      `json:"updated_at,omitempty" db:"updated_at"`
+}
+
+// UpdatePreferencesRequest replaces the caller's preferences document
+type UpdatePreferencesRequest struct {
+	Preferences json.RawMessage `json:"preferences"`
+}
+
+// Quota is a stored override of the configured default quotas for one user, or
+// for the whole installation when UserID is nil. A nil limit keeps the default.
+type Quota struct {
+	UserID       *int64    `json:"user_id,omitempty" db:"user_id"`
+	MaxInstances *int      `json:"max_instances" db:"max_instances"`
+	MaxStorageGB *int      `json:"max_storage_gb" db:"max_storage_gb"`
+	UpdatedAt    time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// UpdateQuotaRequest sets quota overrides. A null limit restores the configured
+// default and 0 removes the limit.
+type UpdateQuotaRequest struct {
+	MaxInstances *int `json:"max_instances"`
+	MaxStorageGB *int `json:"max_storage_gb"`
+}
+
+// QuotaLimits are the limits in effect for a user or the installation (0 means unlimited)
+type QuotaLimits struct {
+	MaxInstances int `json:"max_instances"`
+	MaxStorageGB int `json:"max_storage_gb"`
+}
+
+// QuotaUsage is the usage counted against quota limits. Storage is the
+// capacity of the persistent volume claims of the counted instances.
+type QuotaUsage struct {
+	Instances    int   `json:"instances"`
+	StorageBytes int64 `json:"storage_bytes"`
+}
+
+// QuotaStatus reports the limits in effect and current usage
+type QuotaStatus struct {
+	Limits QuotaLimits `json:"limits"`
+	Usage  QuotaUsage  `json:"usage"`
+}
+
+// QuotasResponse reports a user's quota and the installation-wide quota
+type QuotasResponse struct {
+	User   QuotaStatus `json:"user"`
+	Global QuotaStatus `json:"global"`
+
+	// Sandbox holds the limits of the caller's developer sandbox, omitted when the
+	// caller is not sandboxed
+	Sandbox *SandboxQuota `json:"sandbox,omitempty"`
+}
+
+// SandboxQuota reports the limits of a developer sandbox and how many of its
+// instances are in use. Sandbox instances are deleted TTL after their creation.
+type SandboxQuota struct {
+	MaxInstances int    `json:"max_instances"`
+	MaxStorageGB int    `json:"max_storage_gb"`
+	TTL          string `json:"ttl"`
+	Instances    int    `json:"instances"`
+}
+
+// Upgrade run statuses
+const (
+	UpgradeStatusRunning   = "running"
+	UpgradeStatusCompleted = "completed"
+	UpgradeStatusHalted    = "halted"
+)
+
+// Upgrade target statuses
+const (
+	UpgradeTargetPending    = "pending"
+	UpgradeTargetInProgress = "in_progress"
+	UpgradeTargetSucceeded  = "succeeded"
+	UpgradeTargetFailed     = "failed"
+	UpgradeTargetSkipped    = "skipped"
+)
+
+// Upgrade represents a chart version rollout across a set of instances
+type Upgrade struct {
+	ID           int64      `json:"id" db:"id"`
+	ChartVersion string     `json:"chart_version" db:"chart_version"`
+	Status       string     `json:"status" db:"status"`
+	Concurrency  int        `json:"concurrency" db:"concurrency"`
+	MaxFailures  int        `json:"max_failures" db:"max_failures"`
+	HaltReason   *string    `json:"halt_reason,omitempty" db:"halt_reason"`
+	CreatedBy    *int64     `json:"created_by" db:"created_by"`
+	CreatedAt    time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at" db:"updated_at"`
+	CompletedAt  *time.Time `json:"completed_at,omitempty" db:"completed_at"`
+}
+
+// UpgradeTarget tracks the upgrade of one instance within an upgrade run
+type UpgradeTarget struct {
+	ID           int64      `json:"-" db:"id"`
+	UpgradeID    int64      `json:"-" db:"upgrade_id"`
+	InstanceName string     `json:"instance_name" db:"instance_name"`
+	Position     int        `json:"position" db:"position"`
+	Canary       bool       `json:"canary" db:"canary"`
+	Status       string     `json:"status" db:"status"`
+	FromVersion  *string    `json:"from_version,omitempty" db:"from_version"`
+	ErrorMessage *string    `json:"error_message,omitempty" db:"error_message"`
+	StartedAt    *time.Time `json:"started_at,omitempty" db:"started_at"`
+	CompletedAt  *time.Time `json:"completed_at,omitempty" db:"completed_at"`
+}
+
+// UpgradeProgress summarizes target statuses of an upgrade run
+type UpgradeProgress struct {
+	Total      int `json:"total"`
+	Pending    int `json:"pending"`
+	InProgress int `json:"in_progress"`
+	Succeeded  int `json:"succeeded"`
+	Failed     int `json:"failed"`
+	Skipped    int `json:"skipped"`
+}
+
+// CreateUpgradeRequest represents a bulk upgrade request. The first CanaryCount
+// instances (default 1) are upgraded one at a time before the rest.
+type CreateUpgradeRequest struct {
+	ChartVersion string   `json:"chart_version" binding:"required"`
+	Instances    []string `json:"instances" binding:"required"`
+	CanaryCount  *int     `json:"canary_count,omitempty"`
+	Concurrency  int      `json:"concurrency,omitempty"`
+	MaxFailures  int      `json:"max_failures,omitempty"`
+}
+
+// UpgradeResponse represents an upgrade run with per-instance progress
+type UpgradeResponse struct {
+	Upgrade  *Upgrade         `json:"upgrade"`
+	Targets  []*UpgradeTarget `json:"targets"`
+	Progress UpgradeProgress  `json:"progress"`
+}
+
+// ListUpgradesResponse represents a list upgrades response
+type ListUpgradesResponse struct {
+	Upgrades []*Upgrade `json:"upgrades"`
+	Count    int        `json:"count"`
+}
+
+// Benchmark statuses
+const (
+	BenchmarkStatusRunning   = "running"
+	BenchmarkStatusSucceeded = "succeeded"
+	BenchmarkStatusFailed    = "failed"
+)
+
+// Benchmark is a pgbench load test run against an instance's database.
+// StorageClass records the class of the instance's Postgres volume when the
+// benchmark started, so results of different classes can be compared.
+type Benchmark struct {
+	ID                    int64      `json:"id" db:"id"`
+	InstanceName          string     `json:"instance_name" db:"instance_name"`
+	Namespace             string     `json:"-" db:"namespace"`
+	JobName               string     `json:"-" db:"job_name"`
+	Status                string     `json:"status" db:"status"`
+	Clients               int        `json:"clients" db:"clients"`
+	DurationSeconds       int        `json:"duration_seconds" db:"duration_seconds"`
+	Scale                 int        `json:"scale" db:"scale"`
+	StorageClass          string     `json:"storage_class,omitempty" db:"storage_class"`
+	Transactions          *int64     `json:"transactions,omitempty" db:"transactions"`
+	TransactionsPerSecond *float64   `json:"transactions_per_second,omitempty" db:"transactions_per_second"`
+	LatencyAverageMS      *float64   `json:"latency_average_ms,omitempty" db:"latency_average_ms"`
+	ErrorMessage          *string    `json:"error_message,omitempty" db:"error_message"`
+	CreatedBy             *int64     `json:"created_by" db:"created_by"`
+	CreatedAt             time.Time  `json:"created_at" db:"created_at"`
+	CompletedAt           *time.Time `json:"completed_at,omitempty" db:"completed_at"`
+}
+
+// CreateBenchmarkRequest starts a benchmark. Clients is the number of
+// concurrent database sessions (default 10), DurationSeconds how long the load
+// runs (default 60) and Scale the pgbench scale factor of the test data
+// (default 10, about 16 MB per unit).
+type CreateBenchmarkRequest struct {
+	Clients         int `json:"clients,omitempty"`
+	DurationSeconds int `json:"duration_seconds,omitempty"`
+	Scale           int `json:"scale,omitempty"`
+}
+
+// ListBenchmarksResponse represents a list benchmarks response
+type ListBenchmarksResponse struct {
+	Benchmarks []*Benchmark `json:"benchmarks"`
+	Count      int          `json:"count"`
+}
+
+// Operation types
+const (
+	OperationTypeCreate  = "create"
+	OperationTypeDelete  = "delete"
+	OperationTypeRestart = "restart"
+)
+
+// Operation statuses
+const (
+	OperationStatusRunning   = "running"
+	OperationStatusSucceeded = "succeeded"
+	OperationStatusFailed    = "failed"
+)
+
+// Operation is a create, delete or restart request on an instance, returned by
+// those endpoints as operation_id. Progress (0-100), Step, Phase and Job are
+// derived from the instance when the operation is read; Phase and Job are
+// omitted once it has finished.
+type Operation struct {
+	ID           int64         `json:"id" db:"id"`
+	Type         string        `json:"type" db:"type"`
+	InstanceName string        `json:"instance_name" db:"instance_name"`
+	InstanceUID  string        `json:"-" db:"instance_uid"`
+	Status       string        `json:"status" db:"status"`
+	Progress     int           `json:"progress" db:"-"`
+	Step         string        `json:"step,omitempty" db:"-"`
+	Phase        string        `json:"phase,omitempty" db:"-"`
+	Job          *OperationJob `json:"job,omitempty" db:"-"`
+	ErrorMessage *string       `json:"error_message,omitempty" db:"error_message"`
+	CreatedBy    *int64        `json:"created_by" db:"created_by"`
+	CreatedAt    time.Time     `json:"created_at" db:"created_at"`
+	CompletedAt  *time.Time    `json:"completed_at,omitempty" db:"completed_at"`
+}
+
+// OperationJob is the Kubernetes Job currently carrying out an operation, such as
+// the provisioning Job of a create operation, with its pod counts
+type OperationJob struct {
+	Name      string `json:"name"`
+	Active    int32  `json:"active"`
+	Succeeded int32  `json:"succeeded"`
+	Failed    int32  `json:"failed"`
+}
+
+// CronJob is a scheduled job of an instance database, run by the pg_cron
+// extension. LastRunStatus and LastRunAt describe the job's latest run and are
+// omitted until it has run.
+type CronJob struct {
+	ID            int64      `json:"id"`
+	Name          string     `json:"name"`
+	Schedule      string     `json:"schedule"`
+	Command       string     `json:"command"`
+	Active        bool       `json:"active"`
+	LastRunStatus string     `json:"last_run_status,omitempty"`
+	LastRunAt     *time.Time `json:"last_run_at,omitempty"`
+}
+
+// MaxCronCommandLength is the longest SQL command a cron job can run, in bytes
+const MaxCronCommandLength = 4096
+
+// CreateCronJobRequest schedules Command, an SQL statement, to run in the
+// instance's postgres database. Schedule is a five-field cron expression such as
+// "0 3 * * *" or an interval of 1-59 seconds such as "30 seconds".
+type CreateCronJobRequest struct {
+	Name     string `json:"name"`
+	Schedule string `json:"schedule"`
+	Command  string `json:"command"`
+}
+
+// ListCronJobsResponse lists an instance database's cron jobs. Enabled reports
+// whether the pg_cron extension is installed; creating the first job installs it.
+type ListCronJobsResponse struct {
+	Enabled bool       `json:"enabled"`
+	Jobs    []*CronJob `json:"jobs"`
+	Count   int        `json:"count"`
+}
+
+// CreateCronJobResponse represents a create cron job response
+type CreateCronJobResponse struct {
+	Job *CronJob `json:"job"`
+}
+
+// StorageBucket is a bucket of an instance's storage service. Public buckets serve
+// their files without authentication. FileSizeLimit, in bytes, and
+// AllowedMimeTypes restrict uploads and are omitted when unrestricted.
+type StorageBucket struct {
+	ID               string     `json:"id"`
+	Name             string     `json:"name"`
+	Public           bool       `json:"public"`
+	FileSizeLimit    *int64     `json:"file_size_limit,omitempty"`
+	AllowedMimeTypes []string   `json:"allowed_mime_types,omitempty"`
+	CreatedAt        *time.Time `json:"created_at,omitempty"`
+	UpdatedAt        *time.Time `json:"updated_at,omitempty"`
+}
+
+// MaxBucketNameLength is the longest name of a storage bucket
+const MaxBucketNameLength = 100
+
+// CreateBucketRequest creates a storage bucket, whose ID is its Name
+type CreateBucketRequest struct {
+	Name             string   `json:"name"`
+	Public           bool     `json:"public,omitempty"`
+	FileSizeLimit    *int64   `json:"file_size_limit,omitempty"`
+	AllowedMimeTypes []string `json:"allowed_mime_types,omitempty"`
+}
+
+// ListBucketsResponse lists the buckets of an instance's storage service
+type ListBucketsResponse struct {
+	Buckets []*StorageBucket `json:"buckets"`
+	Count   int              `json:"count"`
+}
+
+// CreateBucketResponse represents a create bucket response
+type CreateBucketResponse struct {
+	Bucket *StorageBucket `json:"bucket"`
+}
+
+// ChartVersion is a Supabase chart version published in the configured chart repository
+type ChartVersion struct {
+	Version     string    `json:"version" db:"version"`
+	Position    int       `json:"-" db:"position"`
+	Latest      bool      `json:"latest"`
+	FirstSeenAt time.Time `json:"first_seen_at" db:"first_seen_at"`
+	LastSeenAt  time.Time `json:"last_seen_at" db:"last_seen_at"`
+}
+
+// ListChartVersionsResponse lists the indexed chart versions, newest first
+type ListChartVersionsResponse struct {
+	ChartVersions []*ChartVersion `json:"chart_versions"`
+	Count         int             `json:"count"`
+}
+
+// InstanceRecord is an instance as last mirrored into the database. Records are kept
+// after their instances are deleted, so they also form the history of every instance.
+type InstanceRecord struct {
+	UID          string         `json:"uid" db:"uid"`
+	Name         string         `json:"name" db:"name"`
+	Namespace    string         `json:"namespace,omitempty" db:"namespace"`
+	OwnerID      *int64         `json:"owner_id,omitempty" db:"owner_id"`
+	Phase        string         `json:"-" db:"phase"`
+	Status       InstanceStatus `json:"status"`
+	ChartVersion string         `json:"chart_version,omitempty" db:"chart_version"`
+	StudioURL    string         `json:"studio_url,omitempty" db:"studio_url"`
+	APIURL       string         `json:"api_url,omitempty" db:"api_url"`
+	ErrorMessage *string        `json:"error_message,omitempty" db:"error_message"`
+	CreatedAt    time.Time      `json:"created_at" db:"created_at"`
+	DeletedAt    *time.Time     `json:"deleted_at,omitempty" db:"deleted_at"`
+	SyncedAt     time.Time      `json:"synced_at" db:"synced_at"`
+}
+
+// ListInstanceHistoryResponse lists instance records, newest first
+type ListInstanceHistoryResponse struct {
+	Instances []*InstanceRecord `json:"instances"`
+	Count     int               `json:"count"`
+}
+
+// ServiceProfile is an admin-managed shared service configuration (SMTP relay,
+// S3 bucket, OAuth app) that instances attach by name. Credentials are write-only:
+// responses list their keys but never their values.
+type ServiceProfile struct {
+	Name       string            `json:"name"`
+	Type       string            `json:"type"`
+	Settings   map[string]string `json:"settings"`
+	SecretKeys []string          `json:"secret_keys"`
+	Instances  []string          `json:"instances"`
+	CreatedAt  time.Time         `json:"created_at"`
+}
+
+// ServiceProfileRequest creates or updates a shared service profile. On update,
+// credentials omitted from Secrets keep their stored values.
+type ServiceProfileRequest struct {
+	Name     string            `json:"name"`
+	Type     string            `json:"type"`
+	Settings map[string]string `json:"settings"`
+	Secrets  map[string]string `json:"secrets"`
+}
+
+// ListServiceProfilesResponse represents a list of shared service profiles
+type ListServiceProfilesResponse struct {
+	Profiles []*ServiceProfile `json:"profiles"`
+	Count    int               `json:"count"`
+}
+
+// SMTPTestRequest requests a test email through an SMTP profile. To defaults to
+// the profile's sender address.
+type SMTPTestRequest struct {
+	To string `json:"to,omitempty"`
+}
+
+// SMTPTestResponse reports the outcome of an SMTP profile test with the SMTP
+// transcript (credentials redacted)
+type SMTPTestResponse struct {
+	Success    bool     `json:"success"`
+	Recipient  string   `json:"recipient"`
+	Transcript []string `json:"transcript"`
+	Error      string   `json:"error,omitempty"`
+}
+
+// Backup is an encrypted logical backup of an instance database in object storage.
+// KeyID names the encryption key, which has to be configured to restore it. A held
+// backup is never pruned by the retention policy; PrunedAt is set once the pruner has
+// deleted the artifact.
+type Backup struct {
+	ID           int64      `json:"id" db:"id"`
+	InstanceName string     `json:"instance_name" db:"instance_name"`
+	ObjectKey    string     `json:"-" db:"object_key"`
+	KeyID        string     `json:"key_id" db:"key_id"`
+	SizeBytes    int64      `json:"size_bytes" db:"size_bytes"`
+	Held         bool       `json:"held" db:"held"`
+	CreatedBy    *int64     `json:"created_by" db:"created_by"`
+	CreatedAt    time.Time  `json:"created_at" db:"created_at"`
+	PrunedAt     *time.Time `json:"-" db:"pruned_at"`
+}
+
+// ListBackupsResponse represents a list of instance backups, newest first
+type ListBackupsResponse struct {
+	Backups []*Backup `json:"backups"`
+	Count   int       `json:"count"`
+}
+
+// UpdateBackupHoldRequest places or releases a hold that protects a backup from pruning
+type UpdateBackupHoldRequest struct {
+	Held bool `json:"held"`
+}
+
+// MaxBackupRetention is the most periods a retention rule can keep backups for
+const MaxBackupRetention = 1000
+
+// BackupRetentionPolicy keeps the newest backup of each of the last KeepDaily days,
+// KeepWeekly ISO weeks and KeepMonthly months (UTC) that have a backup. Backups no
+// rule keeps are pruned unless they are held; a policy whose rules are all zero keeps
+// every backup.
+type BackupRetentionPolicy struct {
+	InstanceName string     `json:"instance_name" db:"instance_name"`
+	KeepDaily    int        `json:"keep_daily" db:"keep_daily"`
+	KeepWeekly   int        `json:"keep_weekly" db:"keep_weekly"`
+	KeepMonthly  int        `json:"keep_monthly" db:"keep_monthly"`
+	UpdatedAt    *time.Time `json:"updated_at,omitempty" db:"updated_at"`
+}
+
+// UpdateBackupRetentionRequest replaces the retention policy of an instance; all zeros
+// turn pruning off
+type UpdateBackupRetentionRequest struct {
+	KeepDaily   int `json:"keep_daily"`
+	KeepWeekly  int `json:"keep_weekly"`
+	KeepMonthly int `json:"keep_monthly"`
 }
@@ -0,0 +1,64 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+)
+
+// codedMessage is the message of an HTTP error with a more specific code than its
+// status implies. Message is a catalog message or an *i18n.Message, and Details is
+// returned as is.
+type codedMessage struct {
+	Code    apitypes.ErrorCode
+	Message interface{}
+	Details map[string]string
+}
+
+// newAPIError returns an HTTP error with a specific error code
+func newAPIError(status int, code apitypes.ErrorCode, message interface{}) *echo.HTTPError {
+	return echo.NewHTTPError(status, &codedMessage{Code: code, Message: message})
+}
+
+// newAPIErrorWithDetails returns an HTTP error with a specific error code and details
+func newAPIErrorWithDetails(status int, code apitypes.ErrorCode, message interface{}, details map[string]string) *echo.HTTPError {
+	return echo.NewHTTPError(status, &codedMessage{Code: code, Message: message, Details: details})
+}
+
+// errorCodeForStatus returns the error code of HTTP errors without a specific one
+func errorCodeForStatus(status int) apitypes.ErrorCode {
+	switch status {
+	case http.StatusBadRequest:
+		return apitypes.ErrorCodeBadRequest
+	case http.StatusUnauthorized:
+		return apitypes.ErrorCodeUnauthorized
+	case http.StatusForbidden:
+		return apitypes.ErrorCodeForbidden
+	case http.StatusNotFound:
+		return apitypes.ErrorCodeNotFound
+	case http.StatusMethodNotAllowed:
+		return apitypes.ErrorCodeMethodNotAllowed
+	case http.StatusConflict:
+		return apitypes.ErrorCodeConflict
+	case http.StatusGone:
+		return apitypes.ErrorCodeGone
+	case http.StatusRequestEntityTooLarge:
+		return apitypes.ErrorCodePayloadTooLarge
+	case http.StatusUnsupportedMediaType:
+		return apitypes.ErrorCodeUnsupportedMedia
+	case http.StatusTooManyRequests:
+		return apitypes.ErrorCodeTooManyRequests
+	case http.StatusBadGateway:
+		return apitypes.ErrorCodeBadGateway
+	case http.StatusServiceUnavailable:
+		return apitypes.ErrorCodeServiceUnavailable
+	case http.StatusGatewayTimeout, StatusClientClosedRequest:
+		return apitypes.ErrorCodeTimeout
+	}
+	if status >= http.StatusInternalServerError {
+		return apitypes.ErrorCodeInternal
+	}
+	return apitypes.ErrorCodeBadRequest
+}
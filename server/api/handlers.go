@@ -4,8 +4,10 @@ package api
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"strconv"
 	"strings"
@@ -15,11 +17,20 @@ import (
 	"github.com/labstack/echo/v4"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
 	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+	"github.com/qubitquilt/supacontrol/server/controllers"
 	"github.com/qubitquilt/supacontrol/server/internal/auth"
+	"github.com/qubitquilt/supacontrol/server/internal/backup"
+	"github.com/qubitquilt/supacontrol/server/internal/db"
+	"github.com/qubitquilt/supacontrol/server/internal/i18n"
+	"github.com/qubitquilt/supacontrol/server/internal/k8s"
+	"github.com/qubitquilt/supacontrol/server/internal/notify"
+	"github.com/qubitquilt/supacontrol/server/internal/objectstore"
+	"github.com/qubitquilt/supacontrol/server/internal/profiles"
 )
 
 // Handler holds dependencies for API handlers
@@ -28,16 +39,244 @@ type Handler struct {
 	dbClient    DBClient
 	crClient    CRClient
 	k8sClient   K8sClient
+
+	// publicURL is the externally reachable base URL of SupaControl, used to build links
+	publicURL string
+
+	// chartResolver looks up component versions of the target Supabase chart
+	chartResolver ChartVersionResolver
+
+	// advisorySource provides security advisories matched against running components
+	advisorySource AdvisorySource
+
+	// usageSource reports instance CPU, memory and storage usage
+	usageSource UsageMetricsSource
+
+	// errorSource summarizes errors found in instance logs
+	errorSource ErrorSummarySource
+
+	// apiKeyUsage batches the last-used updates of API keys authenticating requests
+	apiKeyUsage APIKeyUsageRecorder
+
+	// chartCatalog reports the newest indexed chart version, used to flag outdated instances
+	chartCatalog ChartCatalog
+
+	// releasePreviewer renders chart upgrades of instance releases without applying them
+	releasePreviewer ReleasePreviewer
+
+	// deletionGracePeriod is how long deleted instances stay in the trash (0 deletes them immediately)
+	deletionGracePeriod time.Duration
+
+	// apiKeyRotationOverlap is how long rotated API keys' previous values keep working by default
+	apiKeyRotationOverlap time.Duration
+
+	// requireImageDigest rejects provisioner image overrides not pinned by digest
+	requireImageDigest bool
+
+	// namespaceTemplate names instance namespaces (empty uses the default template)
+	namespaceTemplate string
+
+	// postgresImageTags maps the Postgres major versions instances can select besides the
+	// chart's default to the tags of their images
+	postgresImageTags map[string]string
+
+	// userQuotaDefaults and globalQuotaDefaults are the configured quotas that stored
+	// overrides take precedence over
+	userQuotaDefaults   apitypes.QuotaLimits
+	globalQuotaDefaults apitypes.QuotaLimits
+
+	// sandbox confines a designated role or team to developer sandbox instances
+	sandbox SandboxPolicy
+
+	// billingWebhookSecret authenticates billing webhook calls (empty disables the webhook)
+	billingWebhookSecret string
+
+	// samlProvider authenticates single sign-on users (nil disables SAML)
+	samlProvider SAMLServiceProvider
+
+	// samlDefaultRole is given to new single sign-on users when the IdP does not manage roles
+	samlDefaultRole string
+
+	// podExecutor runs psql in instance databases (nil disables cron job management)
+	podExecutor PodExecutor
+
+	// portForwarder carries database tunnels (nil disables them)
+	portForwarder PortForwarder
+
+	// backupStore keeps instance backups and backupKeys encrypts them (nil disables backups)
+	backupStore objectstore.Backend
+	backupKeys  *backup.Keyring
+
+	// storageAPI manages instance storage buckets (nil disables bucket management)
+	storageAPI StorageAPI
+
+	// studioTransport carries proxied Studio requests (nil uses http.DefaultTransport)
+	studioTransport http.RoundTripper
+
+	// logClient fetches pod logs with its own rate limit (nil uses k8sClient)
+	logClient K8sClient
+
+	// badges caches the statuses shown on public status badges
+	badges *badgeCache
+
+	// readinessChecks are run by /readyz to verify dependencies such as the database
+	readinessChecks []readinessCheck
+
+	// leaderElected is closed once this replica leads the controller (nil without leader election)
+	leaderElected <-chan struct{}
+
+	// sendTestEmail delivers SMTP profile test emails; replaced in tests
+	sendTestEmail func(ctx context.Context, profile *profiles.Profile, recipient string) ([]string, error)
+
+	// progressInterval is how often instance progress streams re-check an instance
+	progressInterval time.Duration
+
+	// lookupHost resolves instance hostnames for progress streams; replaced in tests
+	lookupHost func(ctx context.Context, host string) ([]string, error)
+
+	// requestTimeout bounds the Kubernetes and database calls of authenticated requests
+	requestTimeout time.Duration
+
+	// loadShedding caps the authenticated requests handled at once (zero caps are unlimited)
+	loadShedding LoadSheddingConfig
+
+	// notifier delivers team invitation links to invitees (nil leaves delivery to the inviter)
+	notifier notify.Notifier
+}
+
+// HandlerOption configures optional Handler settings
+type HandlerOption func(*Handler)
+
+// WithPublicURL sets the externally reachable base URL used when building links (e.g. invitations)
+func WithPublicURL(publicURL string) HandlerOption {
+	return func(h *Handler) {
+		h.publicURL = strings.TrimSuffix(publicURL, "/")
+	}
+}
+
+// WithNotifier sets the notifier team invitation links are delivered through
+func WithNotifier(notifier notify.Notifier) HandlerOption {
+	return func(h *Handler) {
+		h.notifier = notifier
+	}
+}
+
+// WithLogClient sets the Kubernetes client pod logs are fetched with, so heavy log fetching
+// is throttled separately from the API calls of other requests
+func WithLogClient(client K8sClient) HandlerOption {
+	return func(h *Handler) {
+		h.logClient = client
+	}
+}
+
+// WithChartResolver sets the resolver used to compare running component versions with the target chart
+func WithChartResolver(resolver ChartVersionResolver) HandlerOption {
+	return func(h *Handler) {
+		h.chartResolver = resolver
+	}
+}
+
+// WithAdvisorySource sets the security advisory feed used to flag affected instances
+func WithAdvisorySource(source AdvisorySource) HandlerOption {
+	return func(h *Handler) {
+		h.advisorySource = source
+	}
+}
+
+// WithUsageMetricsSource sets the source of instance usage metrics (metrics-server or Prometheus)
+func WithUsageMetricsSource(source UsageMetricsSource) HandlerOption {
+	return func(h *Handler) {
+		h.usageSource = source
+	}
+}
+
+// WithErrorSummarySource sets the analyzer whose log error summaries are served per instance
+func WithErrorSummarySource(source ErrorSummarySource) HandlerOption {
+	return func(h *Handler) {
+		h.errorSource = source
+	}
+}
+
+// WithAPIKeyUsageRecorder sets the recorder API key uses are batched in. Without one,
+// every request authenticated with an API key updates its last-used time right away.
+func WithAPIKeyUsageRecorder(recorder APIKeyUsageRecorder) HandlerOption {
+	return func(h *Handler) {
+		h.apiKeyUsage = recorder
+	}
+}
+
+// WithChartCatalog sets the catalog used to flag instances with a newer chart version available
+func WithChartCatalog(catalog ChartCatalog) HandlerOption {
+	return func(h *Handler) {
+		h.chartCatalog = catalog
+	}
+}
+
+// WithReleasePreviewer sets the renderer used to preview the manifest changes of upgrades
+func WithReleasePreviewer(previewer ReleasePreviewer) HandlerOption {
+	return func(h *Handler) {
+		h.releasePreviewer = previewer
+	}
+}
+
+// WithRequestTimeout sets how long authenticated requests may take. The expensiveRoutes,
+// such as log fetches and release previews, get slowRequestTimeout instead, and progress
+// streams are unbounded.
+func WithRequestTimeout(timeout time.Duration) HandlerOption {
+	return func(h *Handler) {
+		h.requestTimeout = timeout
+	}
+}
+
+// WithLoadShedding caps the authenticated requests handled at once, rejecting requests
+// that find no free slot within the queue timeout
+func WithLoadShedding(cfg LoadSheddingConfig) HandlerOption {
+	return func(h *Handler) {
+		h.loadShedding = cfg
+	}
+}
+
+// readinessCheck is a named dependency check reported by /readyz
+type readinessCheck struct {
+	name  string
+	check func(ctx context.Context) error
+}
+
+// WithReadinessCheck adds a dependency check to /readyz; the server is not ready while it fails
+func WithReadinessCheck(name string, check func(ctx context.Context) error) HandlerOption {
+	return func(h *Handler) {
+		h.readinessChecks = append(h.readinessChecks, readinessCheck{name: name, check: check})
+	}
+}
+
+// WithLeaderElection reports on /readyz whether this replica is the elected controller leader.
+// Standby replicas still serve the API, so they stay ready.
+func WithLeaderElection(elected <-chan struct{}) HandlerOption {
+	return func(h *Handler) {
+		h.leaderElected = elected
+	}
 }
 
 // NewHandler creates a new API handler
-func NewHandler(authService *auth.Service, dbClient DBClient, crClient CRClient, k8sClient K8sClient) *Handler {
-	return &Handler{
+func NewHandler(authService *auth.Service, dbClient DBClient, crClient CRClient, k8sClient K8sClient, opts ...HandlerOption) *Handler {
+	h := &Handler{
 		authService: authService,
 		dbClient:    dbClient,
 		crClient:    crClient,
 		k8sClient:   k8sClient,
+
+		badges:           newBadgeCache(),
+		sendTestEmail:    profiles.SendTestEmail,
+		progressInterval: defaultProgressInterval,
+		lookupHost:       net.DefaultResolver.LookupHost,
+		requestTimeout:   defaultRequestTimeout,
+
+		apiKeyRotationOverlap: defaultAPIKeyRotationOverlap,
+	}
+	for _, opt := range opts {
+		opt(h)
 	}
+	return h
 }
 
 // getInstanceNamespace returns the namespace for an instance
@@ -49,6 +288,31 @@ func getInstanceNamespace(instance *supacontrolv1alpha1.SupabaseInstance) string
 	return fmt.Sprintf("supa-%s", instance.Spec.ProjectName)
 }
 
+// isAdminOrOwner reports whether the authenticated user is an admin or the user who created the instance
+func isAdminOrOwner(authCtx *AuthContext, instance *supacontrolv1alpha1.SupabaseInstance) bool {
+	if authCtx == nil {
+		return false
+	}
+	if authCtx.IsAdmin() {
+		return true
+	}
+	ownerID, ok := instance.Annotations[supacontrolv1alpha1.AnnotationOwnerID]
+	return ok && ownerID == strconv.FormatInt(authCtx.UserID, 10)
+}
+
+// recordAudit writes an audit log entry for the authenticated user.
+// Failures are logged but do not fail the request; callers that must not proceed
+// without an audit trail should call CreateAuditLog directly.
+func (h *Handler) recordAudit(c echo.Context, action, resourceType, resourceID string, details map[string]string) {
+	authCtx := GetAuthContext(c)
+	if authCtx == nil {
+		return
+	}
+	if err := h.dbClient.CreateAuditLog(authCtx.UserID, action, resourceType, resourceID, details); err != nil {
+		GetLogger(c).Error("Failed to record audit log", "action", action, "resource_id", resourceID, "error", err)
+	}
+}
+
 // containerLogResult holds the result of fetching logs from a container
 type containerLogResult struct {
 	podName       string
@@ -102,11 +366,77 @@ func (h *Handler) HealthCheck(c echo.Context) error {
 	})
 }
 
+// LivenessCheck handles liveness probe requests. It does not check dependencies, so a
+// database outage makes replicas unready instead of restarting all of them.
+func (h *Handler) LivenessCheck(c echo.Context) error {
+	return c.JSON(http.StatusOK, map[string]string{
+		"status": "alive",
+		"time":   time.Now().Format(time.RFC3339),
+	})
+}
+
+// readinessCheckTimeout bounds each dependency check so a hung backend cannot stall the probe
+const readinessCheckTimeout = 5 * time.Second
+
+const (
+	// defaultRequestTimeout bounds authenticated requests, so a slow API server cannot
+	// pile up handler goroutines
+	defaultRequestTimeout = 30 * time.Second
+
+	// slowRequestTimeout bounds the expensiveRoutes, e.g. requests that stream every
+	// container's logs or render a chart
+	slowRequestTimeout = 2 * time.Minute
+
+	// maxLogLines caps the lines tailed per container by log fetches
+	maxLogLines = 10000
+
+	// maxLogGrepLength caps the length of the text log fetches are filtered by
+	maxLogGrepLength = 256
+)
+
+// ReadinessCheck handles readiness probe requests by running the registered dependency checks.
+// Failure details are logged rather than returned, as the endpoint is unauthenticated.
+func (h *Handler) ReadinessCheck(c echo.Context) error {
+	status := http.StatusOK
+	checks := make(map[string]string, len(h.readinessChecks))
+	for _, rc := range h.readinessChecks {
+		ctx, cancel := context.WithTimeout(c.Request().Context(), readinessCheckTimeout)
+		err := rc.check(ctx)
+		cancel()
+		if err != nil {
+			GetLogger(c).Warn("Readiness check failed", "check", rc.name, "error", err)
+			checks[rc.name] = "failed"
+			status = http.StatusServiceUnavailable
+			continue
+		}
+		checks[rc.name] = "ok"
+	}
+
+	result := "ready"
+	if status != http.StatusOK {
+		result = "not ready"
+	}
+	resp := map[string]interface{}{
+		"status": result,
+		"checks": checks,
+		"time":   time.Now().Format(time.RFC3339),
+	}
+	if h.leaderElected != nil {
+		select {
+		case <-h.leaderElected:
+			resp["leader"] = true
+		default:
+			resp["leader"] = false
+		}
+	}
+	return c.JSON(status, resp)
+}
+
 // Login handles user login
 func (h *Handler) Login(c echo.Context) error {
 	var req apitypes.LoginRequest
-	if err := c.Bind(&req); err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, "invalid request body")
+	if err := bindRequest(c, &req); err != nil {
+		return err
 	}
 
 	// Get user
@@ -118,6 +448,9 @@ func (h *Handler) Login(c echo.Context) error {
 	if user == nil {
 		return echo.NewHTTPError(http.StatusUnauthorized, "invalid credentials")
 	}
+	if user.AuthProvider == db.AuthProviderSAML {
+		return echo.NewHTTPError(http.StatusUnauthorized, "this account signs in through single sign-on")
+	}
 
 	// Verify password
 	valid, err := h.authService.VerifyPassword(req.Password, user.PasswordHash)
@@ -138,9 +471,10 @@ func (h *Handler) Login(c echo.Context) error {
 	return c.JSON(http.StatusOK, apitypes.LoginResponse{
 		Token: token,
 		User: &apitypes.UserInfo{
-			ID:       user.ID,
-			Username: user.Username,
-			Role:     user.Role,
+			ID:                 user.ID,
+			Username:           user.Username,
+			Role:               user.Role,
+			MustChangePassword: user.MustChangePassword,
 		},
 	})
 }
@@ -163,13 +497,63 @@ func (h *Handler) GetAuthMe(c echo.Context) error {
 
 	return c.JSON(http.StatusOK, apitypes.AuthMeResponse{
 		User: &apitypes.UserInfo{
-			ID:       user.ID,
-			Username: user.Username,
-			Role:     user.Role,
+			ID:                 user.ID,
+			Username:           user.Username,
+			Role:               user.Role,
+			MustChangePassword: user.MustChangePassword,
 		},
 	})
 }
 
+// ChangePassword changes the password of the authenticated user after verifying their
+// current one, and lifts any requirement to change it
+func (h *Handler) ChangePassword(c echo.Context) error {
+	authCtx := GetAuthContext(c)
+	if authCtx == nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "not authenticated")
+	}
+
+	var req apitypes.ChangePasswordRequest
+	if err := bindRequest(c, &req); err != nil {
+		return err
+	}
+	if req.NewPassword == req.CurrentPassword {
+		return echo.NewHTTPError(http.StatusBadRequest, "new password must differ from the current password")
+	}
+
+	user, err := h.dbClient.GetUserByID(authCtx.UserID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to get user")
+	}
+	if user == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "user not found")
+	}
+	if user.AuthProvider == db.AuthProviderSAML {
+		return echo.NewHTTPError(http.StatusBadRequest, "this account signs in through single sign-on")
+	}
+
+	valid, err := h.authService.VerifyPassword(req.CurrentPassword, user.PasswordHash)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to verify password")
+	}
+	if !valid {
+		return echo.NewHTTPError(http.StatusUnauthorized, "current password is incorrect")
+	}
+
+	hash, err := h.authService.HashPassword(req.NewPassword)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to hash password")
+	}
+	if err := h.dbClient.UpdateUserPassword(user.ID, hash); err != nil {
+		GetLogger(c).Error("Failed to update password", "user_id", user.ID, "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to change password")
+	}
+
+	h.recordAudit(c, "user.password_change", "user", strconv.FormatInt(user.ID, 10), nil)
+
+	return c.NoContent(http.StatusNoContent)
+}
+
 // CreateAPIKey generates a new API key
 func (h *Handler) CreateAPIKey(c echo.Context) error {
 	authCtx := GetAuthContext(c)
@@ -178,8 +562,8 @@ func (h *Handler) CreateAPIKey(c echo.Context) error {
 	}
 
 	var req apitypes.CreateAPIKeyRequest
-	if err := c.Bind(&req); err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, "invalid request body")
+	if err := bindRequest(c, &req); err != nil {
+		return err
 	}
 
 	// Generate new API key
@@ -203,7 +587,7 @@ func (h *Handler) CreateAPIKey(c echo.Context) error {
 	return c.JSON(http.StatusCreated, apitypes.CreateAPIKeyResponse{
 		Key:     apiKey,
 		APIKey:  apiKeyRecord,
-		Message: "API key created successfully. Save this key securely - it won't be shown again!",
+		Message: localize(c, "API key created successfully. Save this key securely - it won't be shown again!"),
 	})
 }
 
@@ -218,7 +602,7 @@ func (h *Handler) ListAPIKeys(c echo.Context) error {
 	var err error
 
 	// Admins can see all keys
-	if authCtx.Role == "admin" {
+	if authCtx.IsAdmin() {
 		apiKeys, err = h.dbClient.ListAllAPIKeys()
 	} else {
 		apiKeys, err = h.dbClient.ListAPIKeysByUser(authCtx.UserID)
@@ -258,7 +642,7 @@ func (h *Handler) DeleteAPIKey(c echo.Context) error {
 	}
 
 	// Users can only delete their own keys, admins can delete any
-	if authCtx.Role != "admin" && apiKey.UserID != authCtx.UserID {
+	if !authCtx.IsAdmin() && apiKey.UserID != authCtx.UserID {
 		return echo.NewHTTPError(http.StatusForbidden, "cannot delete other users' API keys")
 	}
 
@@ -267,20 +651,15 @@ func (h *Handler) DeleteAPIKey(c echo.Context) error {
 	}
 
 	return c.JSON(http.StatusOK, map[string]string{
-		"message": "API key deleted successfully",
+		"message": localize(c, "API key deleted successfully"),
 	})
 }
 
 // CreateInstance creates a new Supabase instance
 func (h *Handler) CreateInstance(c echo.Context) error {
 	var req apitypes.CreateInstanceRequest
-	if err := c.Bind(&req); err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, "invalid request body")
-	}
-
-	// Validate project name
-	if req.Name == "" {
-		return echo.NewHTTPError(http.StatusBadRequest, "project name is required")
+	if err := bindRequest(c, &req); err != nil {
+		return err
 	}
 
 	ctx := c.Request().Context()
@@ -288,13 +667,106 @@ func (h *Handler) CreateInstance(c echo.Context) error {
 	// Check if instance already exists in K8s
 	_, err := h.crClient.GetSupabaseInstance(ctx, req.Name)
 	if err == nil {
-		return echo.NewHTTPError(http.StatusConflict, "instance with this name already exists")
+		return newAPIError(http.StatusConflict, apitypes.ErrorCodeInstanceExists, "instance with this name already exists")
 	}
-	if !apierrors.IsNotFound(err) {
+	if !errors.Is(err, k8s.ErrInstanceNotFound) {
 		GetLogger(c).Error("Failed to check instance existence", "error", err)
 		return echo.NewHTTPError(http.StatusInternalServerError, "failed to check instance existence")
 	}
 
+	if err := h.checkQuota(c); err != nil {
+		return err
+	}
+	template, err := h.resolveTemplate(c, req.Template)
+	if err != nil {
+		return err
+	}
+	if template != nil {
+		if err := h.checkTemplateQuota(c, template); err != nil {
+			return err
+		}
+	}
+
+	if err := h.resolveInstanceProfiles(c, req.Profiles); err != nil {
+		return err
+	}
+
+	customDomains, err := normalizeCustomDomains(req.CustomDomains)
+	if err != nil {
+		return err
+	}
+	if err := h.checkDomainsAvailable(c, req.Name, customDomains); err != nil {
+		return err
+	}
+
+	ingress, err := normalizeIngress(c, req.Ingress)
+	if err != nil {
+		return err
+	}
+	dns, err := normalizeDNS(req.DNS)
+	if err != nil {
+		return err
+	}
+	placement, err := normalizePlacement(req.Placement)
+	if err != nil {
+		return err
+	}
+	isolation, err := normalizeIsolation(req.Isolation)
+	if err != nil {
+		return err
+	}
+	pooler, err := normalizeConnectionPooler(req.ConnectionPooler)
+	if err != nil {
+		return err
+	}
+	storage, err := normalizeStorage(req.Storage)
+	if err != nil {
+		return err
+	}
+	database, err := normalizeDatabase(req.Database)
+	if err != nil {
+		return err
+	}
+	if database != nil && database.Version != "" {
+		if err := h.checkPostgresVersion(c, database.Version, ""); err != nil {
+			return err
+		}
+	}
+	resources, err := normalizeResources(req.Resources)
+	if err != nil {
+		return err
+	}
+	highAvailability, err := normalizeHighAvailability(req.HighAvailability)
+	if err != nil {
+		return err
+	}
+	env, err := normalizeEnv(req.Env)
+	if err != nil {
+		return err
+	}
+	if err := h.checkProvisionerImage(c, req.ProvisionerImage); err != nil {
+		return err
+	}
+	if err := h.checkNamespace(req.Name, req.NamespaceLabels); err != nil {
+		return err
+	}
+	addonNames, err := normalizeAddons(req.Addons)
+	if err != nil {
+		return err
+	}
+	ttl, err := normalizeTTL(req.TTL)
+	if err != nil {
+		return err
+	}
+	instanceSchedule, err := normalizeSchedule(req.Schedule)
+	if err != nil {
+		return err
+	}
+	var monitoring *supacontrolv1alpha1.Monitoring
+	if req.Monitoring {
+		monitoring = &supacontrolv1alpha1.Monitoring{Enabled: true}
+	}
+
 	// Create SupabaseInstance CR
 	instance := &supacontrolv1alpha1.SupabaseInstance{
 		ObjectMeta: metav1.ObjectMeta{
@@ -304,11 +776,48 @@ func (h *Handler) CreateInstance(c echo.Context) error {
 			},
 		},
 		Spec: supacontrolv1alpha1.SupabaseInstanceSpec{
-			ProjectName: req.Name,
+			ProjectName:        req.Name,
+			Profiles:           req.Profiles,
+			CustomDomains:      customDomains,
+			Ingress:            ingress,
+			DNS:                dns,
+			Placement:          placement,
+			Isolation:          isolation,
+			NetworkIsolation:   req.NetworkIsolation,
+			NamespaceLabels:    req.NamespaceLabels,
+			ConnectionPooler:   pooler,
+			Monitoring:         monitoring,
+			PublicStatusBadge:  req.PublicStatusBadge,
+			Storage:            storage,
+			Database:           database,
+			Resources:          resources,
+			HighAvailability:   highAvailability,
+			DeletionProtection: req.DeletionProtection,
+			TTL:                ttl,
+			Schedule:           instanceSchedule,
+			Env:                env,
+			ProvisionerImage:   req.ProvisionerImage,
+			Addons:             addonNames,
 		},
 	}
 
+	// Record the creating user so owner-only operations can be authorized later
+	if authCtx := GetAuthContext(c); authCtx != nil {
+		instance.Annotations = map[string]string{
+			supacontrolv1alpha1.AnnotationOwnerID: strconv.FormatInt(authCtx.UserID, 10),
+		}
+	}
+	setInstanceTags(instance, req.Tags)
+	applyTemplate(instance, template)
+	if err := h.applySandbox(c, instance); err != nil {
+		return err
+	}
+
 	if err := h.crClient.CreateSupabaseInstance(ctx, instance); err != nil {
+		// Another request may have created the instance since the existence check
+		if errors.Is(err, k8s.ErrAlreadyExists) {
+			return newAPIError(http.StatusConflict, apitypes.ErrorCodeInstanceExists, "instance with this name already exists")
+		}
 		GetLogger(c).Error("Failed to create SupabaseInstance CR", "error", err)
 		return echo.NewHTTPError(http.StatusInternalServerError, "failed to create instance")
 	}
@@ -317,30 +826,52 @@ func (h *Handler) CreateInstance(c echo.Context) error {
 	apiInstance := h.convertCRToAPIType(c, instance)
 
 	return c.JSON(http.StatusAccepted, apitypes.CreateInstanceResponse{
-		Instance: apiInstance,
-		Message:  "Instance provisioning started",
+		Instance:    apiInstance,
+		Message:     localize(c, "Instance provisioning started"),
+		OperationID: h.recordOperation(c, apitypes.OperationTypeCreate, instance),
 	})
 }
 
 // ListInstances lists all Supabase instances
 func (h *Handler) ListInstances(c echo.Context) error {
 	ctx := c.Request().Context()
+	tagFilters, err := parseTagFilters(c)
+	if err != nil {
+		return err
+	}
 
 	crList, err := h.crClient.ListSupabaseInstances(ctx)
 	if err != nil {
 		GetLogger(c).Error("Failed to list instances", "error", err)
+		// The mirror doesn't record tags, so it cannot answer filtered listings
+		if len(tagFilters) == 0 {
+			if resp, ok := h.listMirroredInstances(c); ok {
+				return c.JSON(http.StatusOK, resp)
+			}
+		}
 		return echo.NewHTTPError(http.StatusInternalServerError, "failed to list instances")
 	}
+	if len(tagFilters) > 0 {
+		matching := crList.Items[:0]
+		for _, instance := range crList.Items {
+			if matchesTags(&instance, tagFilters) {
+				matching = append(matching, instance)
+			}
+		}
+		crList.Items = matching
+	}
 
 	// Convert CRs to API types
 	instances := make([]*apitypes.Instance, 0, len(crList.Items))
 	for i := range crList.Items {
 		instances = append(instances, h.convertCRToAPIType(c, &crList.Items[i]))
 	}
+	h.mergeInstanceMetadata(c, crList.Items, instances)
 
 	return c.JSON(http.StatusOK, apitypes.ListInstancesResponse{
-		Instances: instances,
-		Count:     len(instances),
+		Instances:         instances,
+		Count:             len(instances),
+		AffectedInstances: h.flagInstanceAdvisories(c, crList.Items, instances),
 	})
 }
 
@@ -351,15 +882,20 @@ func (h *Handler) GetInstance(c echo.Context) error {
 
 	instance, err := h.crClient.GetSupabaseInstance(ctx, name)
 	if err != nil {
-		if apierrors.IsNotFound(err) {
-			return echo.NewHTTPError(http.StatusNotFound, "instance not found")
+		if errors.Is(err, k8s.ErrInstanceNotFound) {
+			return newAPIError(http.StatusNotFound, apitypes.ErrorCodeInstanceNotFound, "instance not found")
 		}
 		GetLogger(c).Error("Failed to get instance", "error", err)
 		return echo.NewHTTPError(http.StatusInternalServerError, "failed to get instance")
 	}
 
+	apiInstance := h.convertCRToAPIType(c, instance)
+	h.mergeInstanceMetadata(c, []supacontrolv1alpha1.SupabaseInstance{*instance}, []*apitypes.Instance{apiInstance})
+	h.flagInstanceAdvisories(c, []supacontrolv1alpha1.SupabaseInstance{*instance}, []*apitypes.Instance{apiInstance})
+	apiInstance.PhaseHistory = phaseHistoryToAPIType(controllers.PhaseHistory(instance))
+
 	return c.JSON(http.StatusOK, apitypes.GetInstanceResponse{
-		Instance: h.convertCRToAPIType(c, instance),
+		Instance: apiInstance,
 	})
 }
 
@@ -369,14 +905,59 @@ func (h *Handler) DeleteInstance(c echo.Context) error {
 	ctx := c.Request().Context()
 
 	// Check if instance exists
-	_, err := h.crClient.GetSupabaseInstance(ctx, name)
+	instance, err := h.crClient.GetSupabaseInstance(ctx, name)
 	if err != nil {
-		if apierrors.IsNotFound(err) {
-			return echo.NewHTTPError(http.StatusNotFound, "instance not found")
+		if errors.Is(err, k8s.ErrInstanceNotFound) {
+			return newAPIError(http.StatusNotFound, apitypes.ErrorCodeInstanceNotFound, "instance not found")
 		}
 		GetLogger(c).Error("Failed to get instance", "error", err)
 		return echo.NewHTTPError(http.StatusInternalServerError, "failed to get instance")
 	}
+	if !isAdminOrOwner(GetAuthContext(c), instance) {
+		return echo.NewHTTPError(http.StatusForbidden, "only admins and the instance owner can delete an instance")
+	}
+
+	// Forcing skips the cleanup of an instance whose deletion already started
+	force, err := forceDeletionRequested(c)
+	if err != nil {
+		return err
+	}
+	if force {
+		return h.forceDeleteInstance(c, instance)
+	}
+
+	if instance.Spec.DeletionProtection {
+		return newAPIError(http.StatusConflict, apitypes.ErrorCodeDeletionProtected, "instance has deletion protection enabled")
+	}
+
+	// With a grace period the instance goes to the trash and the controller purges it later
+	if h.deletionGracePeriod > 0 {
+		instance, err = h.patchInstance(c, name, func(instance *supacontrolv1alpha1.SupabaseInstance) error {
+			if instance.Spec.DeletionProtection {
+				return newAPIError(http.StatusConflict, apitypes.ErrorCodeDeletionProtected, "instance has deletion protection enabled")
+			}
+			if instance.Spec.PendingDeletion != nil {
+				return echo.NewHTTPError(http.StatusConflict, "instance is already pending deletion")
+			}
+			now := metav1.Now()
+			instance.Spec.PendingDeletion = &supacontrolv1alpha1.PendingDeletion{
+				RequestedAt: now,
+				PurgeAfter:  metav1.NewTime(now.Add(h.deletionGracePeriod)),
+			}
+			return nil
+		}, "failed to delete instance")
+		if err != nil {
+			return err
+		}
+
+		purgeAfter := instance.Spec.PendingDeletion.PurgeAfter.Time
+		h.recordAudit(c, "instance.delete", "instance", name, map[string]string{"purge_after": purgeAfter.Format(time.RFC3339)})
+		return c.JSON(http.StatusAccepted, apitypes.DeleteInstanceResponse{
+			Message:     localize(c, "Instance scheduled for deletion"),
+			PurgeAfter:  &purgeAfter,
+			OperationID: h.recordOperation(c, apitypes.OperationTypeDelete, instance),
+		})
+	}
 
 	// Delete SupabaseInstance CR (controller will handle cleanup via finalizer)
 	if err := h.crClient.DeleteSupabaseInstance(ctx, name); err != nil {
@@ -385,73 +966,138 @@ func (h *Handler) DeleteInstance(c echo.Context) error {
 	}
 
 	return c.JSON(http.StatusAccepted, apitypes.DeleteInstanceResponse{
-		Message: "Instance deletion started",
+		Message:     localize(c, "Instance deletion started"),
+		OperationID: h.recordOperation(c, apitypes.OperationTypeDelete, instance),
 	})
 }
 
+// patchInstance applies mutate to the latest version of an instance, re-applying it when
+// the write conflicts with the controller. HTTP errors returned by mutate are passed
+// through; other failures are logged and reported with failure.
+func (h *Handler) patchInstance(c echo.Context, name string, mutate func(*supacontrolv1alpha1.SupabaseInstance) error, failure string) (*supacontrolv1alpha1.SupabaseInstance, error) {
+	instance, err := h.crClient.PatchSupabaseInstance(c.Request().Context(), name, mutate)
+	if err != nil {
+		var httpErr *echo.HTTPError
+		if errors.As(err, &httpErr) {
+			return nil, httpErr
+		}
+		if errors.Is(err, k8s.ErrInstanceNotFound) {
+			return nil, newAPIError(http.StatusNotFound, apitypes.ErrorCodeInstanceNotFound, "instance not found")
+		}
+		GetLogger(c).Error("Failed to patch instance", "instance", name, "error", err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, failure)
+	}
+	return instance, nil
+}
+
 // StartInstance starts a stopped instance by setting Paused=false
 func (h *Handler) StartInstance(c echo.Context) error {
-	name := c.Param("name")
-	ctx := c.Request().Context()
+	_, err := h.patchInstance(c, c.Param("name"), func(instance *supacontrolv1alpha1.SupabaseInstance) error {
+		// Instances in the trash must be recovered first, through the undelete endpoint
+		if instance.Spec.PendingDeletion != nil {
+			return echo.NewHTTPError(http.StatusConflict, "instance is pending deletion and must be recovered first")
+		}
 
-	// Get the instance
-	instance, err := h.crClient.GetSupabaseInstance(ctx, name)
-	if err != nil {
-		if apierrors.IsNotFound(err) {
-			return echo.NewHTTPError(http.StatusNotFound, "instance not found")
+		// Only administrators can lift a suspension, through the unsuspend endpoint
+		if instance.Spec.Suspension != nil {
+			return echo.NewHTTPError(http.StatusForbidden, "instance is suspended and can only be resumed by an administrator")
 		}
-		GetLogger(c).Error("Failed to get instance", "error", err)
-		return echo.NewHTTPError(http.StatusInternalServerError, "failed to get instance")
-	}
 
-	// Check if already running
-	if !instance.Spec.Paused {
-		return echo.NewHTTPError(http.StatusConflict, "instance is already running")
-	}
+		// Check if already running
+		if !instance.Spec.Paused {
+			return echo.NewHTTPError(http.StatusConflict, "instance is already running")
+		}
 
-	// Update the instance to set Paused=false
-	instance.Spec.Paused = false
-	if err := h.crClient.UpdateSupabaseInstance(ctx, instance); err != nil {
-		GetLogger(c).Error("Failed to start instance", "error", err)
-		return echo.NewHTTPError(http.StatusInternalServerError, "failed to start instance")
+		instance.Spec.Paused = false
+		return nil
+	}, "failed to start instance")
+	if err != nil {
+		return err
 	}
 
 	return c.JSON(http.StatusOK, map[string]string{
-		"message": "Instance start initiated",
+		"message": localize(c, "Instance start initiated"),
 		"status":  "Starting",
 	})
 }
 
-// StopInstance stops a running instance by setting Paused=true
+// StopInstance stops a running instance by setting Paused=true.
+// The controller then scales the instance's workloads to zero.
 func (h *Handler) StopInstance(c echo.Context) error {
+	_, err := h.patchInstance(c, c.Param("name"), func(instance *supacontrolv1alpha1.SupabaseInstance) error {
+		// Check if already stopped
+		if instance.Spec.Paused {
+			return echo.NewHTTPError(http.StatusConflict, "instance is already stopped")
+		}
+
+		instance.Spec.Paused = true
+		return nil
+	}, "failed to stop instance")
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": localize(c, "Instance stop initiated"),
+		"status":  "Stopping",
+	})
+}
+
+// RetryInstance retries provisioning of a Failed instance. It deletes the failed provisioning
+// Job and resets the instance to Pending, so the controller provisions it from scratch.
+func (h *Handler) RetryInstance(c echo.Context) error {
 	name := c.Param("name")
 	ctx := c.Request().Context()
 
-	// Get the instance
 	instance, err := h.crClient.GetSupabaseInstance(ctx, name)
 	if err != nil {
-		if apierrors.IsNotFound(err) {
-			return echo.NewHTTPError(http.StatusNotFound, "instance not found")
+		if errors.Is(err, k8s.ErrInstanceNotFound) {
+			return newAPIError(http.StatusNotFound, apitypes.ErrorCodeInstanceNotFound, "instance not found")
 		}
 		GetLogger(c).Error("Failed to get instance", "error", err)
 		return echo.NewHTTPError(http.StatusInternalServerError, "failed to get instance")
 	}
 
-	// Check if already stopped
-	if instance.Spec.Paused {
-		return echo.NewHTTPError(http.StatusConflict, "instance is already stopped")
+	if instance.Status.Phase != supacontrolv1alpha1.PhaseFailed {
+		return echo.NewHTTPError(http.StatusConflict, "only failed instances can be retried")
 	}
 
-	// Update the instance to set Paused=true
-	instance.Spec.Paused = true
-	if err := h.crClient.UpdateSupabaseInstance(ctx, instance); err != nil {
-		GetLogger(c).Error("Failed to stop instance", "error", err)
-		return echo.NewHTTPError(http.StatusInternalServerError, "failed to stop instance")
+	if jobName := instance.Status.ProvisioningJobName; jobName != "" {
+		propagation := metav1.DeletePropagationBackground
+		err := h.k8sClient.GetClientset().BatchV1().Jobs(controllers.ControllerNamespace).Delete(ctx, jobName, metav1.DeleteOptions{
+			PropagationPolicy: &propagation,
+		})
+		if err != nil && !apierrors.IsNotFound(err) {
+			GetLogger(c).Error("Failed to delete provisioning Job", "job", jobName, "error", err)
+			return echo.NewHTTPError(http.StatusInternalServerError, "failed to retry instance")
+		}
 	}
 
-	return c.JSON(http.StatusOK, map[string]string{
-		"message": "Instance stopped successfully",
-		"status":  "Stopped",
+	instance.Status.Phase = supacontrolv1alpha1.PhasePending
+	instance.Status.ErrorMessage = ""
+	instance.Status.ProvisioningJobName = ""
+	instance.Status.RetryCount = 0
+	instance.Status.FailureType = ""
+	instance.Status.NextRetryAt = nil
+	now := metav1.Now()
+	instance.Status.LastTransitionTime = &now
+	meta.SetStatusCondition(&instance.Status.Conditions, metav1.Condition{
+		Type:               supacontrolv1alpha1.ConditionTypeReady,
+		Status:             metav1.ConditionFalse,
+		ObservedGeneration: instance.Generation,
+		Reason:             "RetryRequested",
+		Message:            "Provisioning retry requested",
+	})
+	if err := h.crClient.UpdateSupabaseInstanceStatus(ctx, instance); err != nil {
+		GetLogger(c).Error("Failed to reset instance status", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to retry instance")
+	}
+
+	h.recordAudit(c, "instance.retry", "instance", name, nil)
+
+	return c.JSON(http.StatusAccepted, map[string]string{
+		"message": localize(c, "Instance provisioning retry started"),
+		"status":  "Retrying",
 	})
 }
 
@@ -463,8 +1109,8 @@ func (h *Handler) RestartInstance(c echo.Context) error {
 	// Get the instance to verify it exists
 	instance, err := h.crClient.GetSupabaseInstance(ctx, name)
 	if err != nil {
-		if apierrors.IsNotFound(err) {
-			return echo.NewHTTPError(http.StatusNotFound, "instance not found")
+		if errors.Is(err, k8s.ErrInstanceNotFound) {
+			return newAPIError(http.StatusNotFound, apitypes.ErrorCodeInstanceNotFound, "instance not found")
 		}
 		GetLogger(c).Error("Failed to get instance", "error", err)
 		return echo.NewHTTPError(http.StatusInternalServerError, "failed to get instance")
@@ -502,14 +1148,40 @@ func (h *Handler) RestartInstance(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusInternalServerError, "no deployments found or failed to restart")
 	}
 
-	return c.JSON(http.StatusOK, map[string]interface{}{
-		"message":   "Instance restart initiated",
+	response := map[string]interface{}{
+		"message":   localize(c, "Instance restart initiated"),
 		"status":    "Restarting",
 		"restarted": restartedCount,
-	})
+	}
+	if operationID := h.recordOperation(c, apitypes.OperationTypeRestart, instance); operationID != 0 {
+		response["operation_id"] = operationID
+	}
+	return c.JSON(http.StatusOK, response)
+}
+
+// matchesLogContainer reports whether a container is selected by the container filter of a
+// log fetch, which names either the container or the component its pod runs, so
+// "postgres" selects the database container whatever the chart calls it
+func matchesLogContainer(pod corev1.Pod, container, filter string) bool {
+	return container == filter || componentWorkloads[pod.Labels["app.kubernetes.io/name"]] == filter
 }
 
-// GetLogs retrieves logs from instance pods using concurrent fetching for better performance
+// filterLogLines keeps the log lines containing text
+func filterLogLines(logs, text string) string {
+	var filtered strings.Builder
+	for _, line := range strings.Split(strings.TrimSuffix(logs, "\n"), "\n") {
+		if strings.Contains(line, text) {
+			filtered.WriteString(line)
+			filtered.WriteString("\n")
+		}
+	}
+	return strings.TrimSuffix(filtered.String(), "\n")
+}
+
+// GetLogs retrieves logs from instance pods using concurrent fetching for better performance.
+// The container query parameter limits them to the containers with that name or running
+// that component, and grep to the lines containing the given text, e.g.
+// ?container=postgres&grep=AUDIT returns the pgAudit log. grep applies to the tailed lines.
 func (h *Handler) GetLogs(c echo.Context) error {
 	name := c.Param("name")
 	ctx := c.Request().Context()
@@ -520,15 +1192,20 @@ func (h *Handler) GetLogs(c echo.Context) error {
 	if linesParam != "" {
 		parsed, err := strconv.ParseInt(linesParam, 10, 64)
 		if err == nil && parsed > 0 {
-			lines = parsed
+			lines = min(parsed, maxLogLines)
 		}
 	}
+	containerFilter := c.QueryParam("container")
+	grep := c.QueryParam("grep")
+	if len(grep) > maxLogGrepLength {
+		return echo.NewHTTPError(http.StatusBadRequest, i18n.Msg("grep must be at most %d characters", maxLogGrepLength))
+	}
 
 	// Get the instance to verify it exists
 	instance, err := h.crClient.GetSupabaseInstance(ctx, name)
 	if err != nil {
-		if apierrors.IsNotFound(err) {
-			return echo.NewHTTPError(http.StatusNotFound, "instance not found")
+		if errors.Is(err, k8s.ErrInstanceNotFound) {
+			return newAPIError(http.StatusNotFound, apitypes.ErrorCodeInstanceNotFound, "instance not found")
 		}
 		GetLogger(c).Error("Failed to get instance", "error", err)
 		return echo.NewHTTPError(http.StatusInternalServerError, "failed to get instance")
@@ -538,8 +1215,11 @@ func (h *Handler) GetLogs(c echo.Context) error {
 	namespace := getInstanceNamespace(instance)
 
 	// Get all pods in the namespace
-	clientset := h.k8sClient.GetClientset()
-	pods, err := clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{})
+	logClient := h.k8sClient
+	if h.logClient != nil {
+		logClient = h.logClient
+	}
+	pods, err := logClient.GetClientset().CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{})
 	if err != nil {
 		GetLogger(c).Error("Failed to list pods", "error", err)
 		return echo.NewHTTPError(http.StatusInternalServerError, "failed to get logs")
@@ -552,7 +1232,14 @@ func (h *Handler) GetLogs(c echo.Context) error {
 	// Count total containers to fetch logs from
 	totalContainers := 0
 	for _, pod := range pods.Items {
-		totalContainers += len(pod.Spec.Containers)
+		for _, container := range pod.Spec.Containers {
+			if containerFilter == "" || matchesLogContainer(pod, container.Name, containerFilter) {
+				totalContainers++
+			}
+		}
+	}
+	if totalContainers == 0 {
+		return c.String(http.StatusOK, fmt.Sprintf("No containers matching %q found for this instance\n", containerFilter))
 	}
 
 	// Fetch logs concurrently from all containers
@@ -562,10 +1249,13 @@ func (h *Handler) GetLogs(c echo.Context) error {
 	index := 0
 	for _, pod := range pods.Items {
 		for _, container := range pod.Spec.Containers {
+			if containerFilter != "" && !matchesLogContainer(pod, container.Name, containerFilter) {
+				continue
+			}
 			wg.Add(1)
 			go func(p corev1.Pod, c corev1.Container, idx int) {
 				defer wg.Done()
-				result := fetchContainerLogs(ctx, h.k8sClient, namespace, p.Name, c.Name, lines, idx)
+				result := fetchContainerLogs(ctx, logClient, namespace, p.Name, c.Name, lines, idx)
 				resultsChan <- result
 			}(pod, container, index)
 			index++
@@ -609,7 +1299,11 @@ func (h *Handler) GetLogs(c echo.Context) error {
 		if result.err != nil {
 			aggregatedLogs.WriteString(fmt.Sprintf("Error getting logs: %v\n", result.err))
 		} else {
-			aggregatedLogs.WriteString(result.logs)
+			logs := result.logs
+			if grep != "" {
+				logs = filterLogLines(logs, grep)
+			}
+			aggregatedLogs.WriteString(logs)
 			aggregatedLogs.WriteString("\n")
 		}
 	}
@@ -617,36 +1311,94 @@ func (h *Handler) GetLogs(c echo.Context) error {
 	return c.String(http.StatusOK, aggregatedLogs.String())
 }
 
-// convertCRToAPIType converts a SupabaseInstance CR to API type
-func (h *Handler) convertCRToAPIType(c echo.Context, cr *supacontrolv1alpha1.SupabaseInstance) *apitypes.Instance {
-	// Map CR phase to API status
-	var status apitypes.InstanceStatus
-	switch cr.Status.Phase {
-	case supacontrolv1alpha1.PhasePending:
-		status = apitypes.StatusProvisioning
-	case supacontrolv1alpha1.PhaseProvisioning:
-		status = apitypes.StatusProvisioning
+// instanceStatus maps an instance's phase to its API status. Unknown phases map to
+// StatusProvisioning and are reported as not ok.
+func instanceStatus(phase supacontrolv1alpha1.SupabaseInstancePhase) (apitypes.InstanceStatus, bool) {
+	switch phase {
+	case supacontrolv1alpha1.PhaseQueued:
+		return apitypes.StatusQueued, true
+	case supacontrolv1alpha1.PhasePending, supacontrolv1alpha1.PhaseProvisioning:
+		return apitypes.StatusProvisioning, true
 	case supacontrolv1alpha1.PhaseRunning:
-		status = apitypes.StatusRunning
+		return apitypes.StatusRunning, true
+	case supacontrolv1alpha1.PhaseUpgrading, supacontrolv1alpha1.PhaseBackingUpDatabase, supacontrolv1alpha1.PhaseUpgradingDatabase,
+		supacontrolv1alpha1.PhaseVerifyingDatabase, supacontrolv1alpha1.PhaseRollingBackDatabase:
+		return apitypes.StatusUpgrading, true
+	case supacontrolv1alpha1.PhaseStopped:
+		return apitypes.StatusStopped, true
+	case supacontrolv1alpha1.PhaseSuspended:
+		return apitypes.StatusSuspended, true
+	case supacontrolv1alpha1.PhasePendingDeletion:
+		return apitypes.StatusPendingDeletion, true
 	case supacontrolv1alpha1.PhaseDeleting:
-		status = apitypes.StatusDeleting
+		return apitypes.StatusDeleting, true
 	case supacontrolv1alpha1.PhaseFailed:
-		status = apitypes.StatusFailed
+		return apitypes.StatusFailed, true
 	default:
+		return apitypes.StatusProvisioning, false
+	}
+}
+
+// convertCRToAPIType converts a SupabaseInstance CR to API type
+func (h *Handler) convertCRToAPIType(c echo.Context, cr *supacontrolv1alpha1.SupabaseInstance) *apitypes.Instance {
+	// Map CR phase to API status
+	status, ok := instanceStatus(cr.Status.Phase)
+	if !ok {
 		// Unknown phase - log warning and default to Provisioning
 		GetLogger(c).Warn("Unknown SupabaseInstance phase encountered",
 			"projectName", cr.Spec.ProjectName,
 			"phase", cr.Status.Phase,
 			"defaulting_to", apitypes.StatusProvisioning)
-		status = apitypes.StatusProvisioning
 	}
 
 	instance := &apitypes.Instance{
-		ProjectName: cr.Spec.ProjectName,
-		Namespace:   cr.Status.Namespace,
-		Status:      status,
-		StudioURL:   cr.Status.StudioURL,
-		APIURL:      cr.Status.APIURL,
+		ProjectName:        cr.Spec.ProjectName,
+		Namespace:          cr.Status.Namespace,
+		Status:             status,
+		StudioURL:          cr.Status.StudioURL,
+		APIURL:             cr.Status.APIURL,
+		ChartVersion:       cr.Status.ChartVersion,
+		HelmRevision:       cr.Status.HelmRevision,
+		HelmReleaseStatus:  cr.Status.HelmReleaseStatus,
+		Profiles:           cr.Spec.Profiles,
+		Ingress:            ingressToAPIType(cr.Spec.Ingress),
+		DNS:                dnsToAPIType(cr.Spec.DNS),
+		Placement:          placementToAPIType(cr.Spec.Placement),
+		DedicatedNode:      cr.Status.DedicatedNode,
+		Isolation:          isolationToAPIType(cr.Spec.Isolation),
+		IsolationLevel:     isolationLevels[cr.Status.IsolationLevel],
+		NetworkIsolation:   cr.Spec.NetworkIsolation,
+		NamespaceLabels:    cr.Spec.NamespaceLabels,
+		Tags:               instanceTags(cr),
+		ConnectionPooler:   connectionPoolerToAPIType(cr.Spec.ConnectionPooler),
+		Monitoring:         controllers.HasMonitoring(cr),
+		Suspension:         suspensionToAPIType(cr.Spec.Suspension),
+		Maintenance:        maintenanceToAPIType(cr),
+		PublicStatusBadge:  cr.Spec.PublicStatusBadge,
+		Storage:            storageToAPIType(cr.Spec.Storage),
+		Database:           databaseToAPIType(cr.Spec.Database),
+		DatabaseVersion:    cr.Status.DatabaseVersion,
+		ReadyReadReplicas:  cr.Status.ReadyReadReplicas,
+		Resources:          resourcesToAPIType(cr.Spec.Resources),
+		HighAvailability:   highAvailabilityToAPIType(cr.Spec.HighAvailability),
+		DeletionProtection: cr.Spec.DeletionProtection,
+		PendingDeletion:    pendingDeletionToAPIType(cr.Spec.PendingDeletion),
+		Env:                cr.Spec.Env,
+		ProvisionerImage:   cr.Spec.ProvisionerImage,
+		Addons:             cr.Spec.Addons,
+		Template:           cr.Spec.TemplateRef,
+		AllowedConnections: cr.Spec.AllowedConnections,
+		SMTP:               smtpToAPIType(cr.Spec.Auth),
+		Sandbox:            isSandboxInstance(cr),
+		Imported:           cr.Spec.Adoption != nil,
+	}
+	if domains := cr.Spec.CustomDomains; domains != nil {
+		instance.CustomDomains = &apitypes.CustomDomains{API: domains.API, Studio: domains.Studio}
+	}
+	instance.TTL, instance.ExpiresAt = expiryToAPIType(cr)
+	instance.Schedule = scheduleToAPIType(cr.Spec.Schedule, time.Now())
+	if h.chartCatalog != nil {
+		instance.UpgradeAvailable = k8s.IsUpdateAvailable(cr.Status.ChartVersion, h.chartCatalog.LatestChartVersion())
 	}
 
 	// Set error message if present
@@ -664,3 +1416,20 @@ func (h *Handler) convertCRToAPIType(c echo.Context, cr *supacontrolv1alpha1.Sup
 
 	return instance
 }
+
+// phaseHistoryToAPIType converts an instance's phase transitions to their API form
+func phaseHistoryToAPIType(history []supacontrolv1alpha1.PhaseTransition) []apitypes.PhaseTransition {
+	if len(history) == 0 {
+		return nil
+	}
+	result := make([]apitypes.PhaseTransition, 0, len(history))
+	for _, transition := range history {
+		result = append(result, apitypes.PhaseTransition{
+			From:   string(transition.From),
+			To:     string(transition.To),
+			At:     transition.At.Time,
+			Reason: transition.Reason,
+		})
+	}
+	return result
+}
@@ -0,0 +1,130 @@
+package api
+
+import (
+	"net/http"
+	"slices"
+
+	"github.com/labstack/echo/v4"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+	"github.com/qubitquilt/supacontrol/server/internal/addons"
+	"github.com/qubitquilt/supacontrol/server/internal/i18n"
+)
+
+// maxAddons bounds how many add-ons an instance can enable, matching the CRD
+const maxAddons = 20
+
+// normalizeAddons validates the add-ons requested for a new instance
+func normalizeAddons(names []string) ([]string, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+	if len(names) > maxAddons {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, i18n.Msg("at most %d add-ons can be enabled", maxAddons))
+	}
+	result := make([]string, 0, len(names))
+	for _, name := range names {
+		if _, ok := addons.Get(name); !ok {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, i18n.Msg("unknown add-on %s", name))
+		}
+		if !slices.Contains(result, name) {
+			result = append(result, name)
+		}
+	}
+	return result, nil
+}
+
+// ListAddons lists the add-ons instances can enable
+func (h *Handler) ListAddons(c echo.Context) error {
+	catalog := addons.List()
+	result := make([]apitypes.Addon, 0, len(catalog))
+	for _, addon := range catalog {
+		result = append(result, apitypes.Addon{
+			Name:        addon.Name,
+			Description: addon.Description,
+			HelmValues:  addon.HasValues(),
+			Manifests:   addon.HasManifests(),
+		})
+	}
+	return c.JSON(http.StatusOK, apitypes.ListAddonsResponse{
+		Addons: result,
+		Count:  len(result),
+	})
+}
+
+// EnableInstanceAddon enables an add-on for an instance (admins and the instance owner
+// only). The controller applies its manifests right away; its chart values take effect
+// on the next upgrade.
+func (h *Handler) EnableInstanceAddon(c echo.Context) error {
+	var req apitypes.EnableAddonRequest
+	if err := bindRequest(c, &req); err != nil {
+		return err
+	}
+	if _, ok := addons.Get(req.Name); !ok {
+		return echo.NewHTTPError(http.StatusBadRequest, i18n.Msg("unknown add-on %s", req.Name))
+	}
+
+	name := c.Param("name")
+	instance, err := h.getInstanceOrError(c, name)
+	if err != nil {
+		return err
+	}
+	if !isAdminOrOwner(GetAuthContext(c), instance) {
+		return echo.NewHTTPError(http.StatusForbidden, "only admins and the instance owner can manage add-ons")
+	}
+	if instance.Status.IsolationLevel == supacontrolv1alpha1.IsolationVCluster {
+		return echo.NewHTTPError(http.StatusConflict, "add-ons are not supported for vcluster instances")
+	}
+
+	instance, err = h.patchInstance(c, name, func(instance *supacontrolv1alpha1.SupabaseInstance) error {
+		if slices.Contains(instance.Spec.Addons, req.Name) {
+			return echo.NewHTTPError(http.StatusConflict, "add-on is already enabled")
+		}
+		if len(instance.Spec.Addons) >= maxAddons {
+			return echo.NewHTTPError(http.StatusBadRequest, i18n.Msg("at most %d add-ons can be enabled", maxAddons))
+		}
+		instance.Spec.Addons = append(instance.Spec.Addons, req.Name)
+		return nil
+	}, "failed to enable add-on")
+	if err != nil {
+		return err
+	}
+
+	h.recordAudit(c, "instance.addon.enable", "instance", name, map[string]string{"addon": req.Name})
+	return c.JSON(http.StatusOK, apitypes.GetInstanceResponse{
+		Instance: h.convertCRToAPIType(c, instance),
+	})
+}
+
+// DisableInstanceAddon disables an add-on of an instance (admins and the instance owner
+// only). The controller deletes its manifests; its chart values are dropped on the next
+// upgrade.
+func (h *Handler) DisableInstanceAddon(c echo.Context) error {
+	name := c.Param("name")
+	addon := c.Param("addon")
+	instance, err := h.getInstanceOrError(c, name)
+	if err != nil {
+		return err
+	}
+	if !isAdminOrOwner(GetAuthContext(c), instance) {
+		return echo.NewHTTPError(http.StatusForbidden, "only admins and the instance owner can manage add-ons")
+	}
+
+	instance, err = h.patchInstance(c, name, func(instance *supacontrolv1alpha1.SupabaseInstance) error {
+		index := slices.Index(instance.Spec.Addons, addon)
+		if index < 0 {
+			return echo.NewHTTPError(http.StatusNotFound, "add-on is not enabled")
+		}
+		instance.Spec.Addons = slices.Delete(instance.Spec.Addons, index, index+1)
+		return nil
+	}, "failed to disable add-on")
+	if err != nil {
+		return err
+	}
+
+	h.recordAudit(c, "instance.addon.disable", "instance", name, map[string]string{"addon": addon})
+	return c.JSON(http.StatusOK, apitypes.GetInstanceResponse{
+		Instance: h.convertCRToAPIType(c, instance),
+	})
+}
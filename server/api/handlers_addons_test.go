@@ -0,0 +1,124 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+)
+
+// TestEnableInstanceAddon tests the EnableInstanceAddon handler
+func TestEnableInstanceAddon(t *testing.T) {
+	tests := []struct {
+		name           string
+		userID         int64
+		role           string
+		existing       []string
+		isolation      supacontrolv1alpha1.IsolationLevel
+		body           string
+		conflicts      int
+		expectedStatus int
+		expectedAddons []string
+	}{
+		{name: "owner enables add-on", userID: 7, role: RoleUser, body: `{"name":"pgvector"}`, expectedStatus: http.StatusOK, expectedAddons: []string{"pgvector"}},
+		{name: "admin adds second add-on", userID: 1, role: RoleAdmin, existing: []string{"pgvector"}, body: `{"name":"metabase"}`, expectedStatus: http.StatusOK, expectedAddons: []string{"pgvector", "metabase"}},
+		{name: "retried after a conflicting controller write", userID: 7, role: RoleUser, existing: []string{"pgvector"}, body: `{"name":"metabase"}`, conflicts: 2, expectedStatus: http.StatusOK, expectedAddons: []string{"pgvector", "metabase"}},
+		{name: "unknown add-on", userID: 7, role: RoleUser, body: `{"name":"wordpress"}`, expectedStatus: http.StatusBadRequest},
+		{name: "already enabled", userID: 7, role: RoleUser, existing: []string{"pgvector"}, body: `{"name":"pgvector"}`, expectedStatus: http.StatusConflict},
+		{name: "vcluster instance", userID: 7, role: RoleUser, isolation: supacontrolv1alpha1.IsolationVCluster, body: `{"name":"pgvector"}`, expectedStatus: http.StatusConflict},
+		{name: "other user", userID: 8, role: RoleUser, body: `{"name":"pgvector"}`, expectedStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			instance := newOwnedInstance("my-app", "7")
+			instance.Spec.Addons = tt.existing
+			instance.Status.IsolationLevel = tt.isolation
+			var updated []string
+			cr := newSuspensionCRClient(nil, instance)
+			cr.updateSupabaseInstanceFunc = conflictFirst(tt.conflicts, func(_ context.Context, instance *supacontrolv1alpha1.SupabaseInstance) error {
+				updated = instance.Spec.Addons
+				return nil
+			})
+			handler := NewHandler(nil, &mockDBClient{}, cr, nil)
+			c, _ := newTestContext(http.MethodPost, "/api/v1/instances/my-app/addons", tt.body)
+			c.SetParamNames("name")
+			c.SetParamValues("my-app")
+			setAuthContext(c, tt.userID, "someone", tt.role)
+
+			err := handler.EnableInstanceAddon(c)
+			if tt.expectedStatus != http.StatusOK {
+				assertHTTPError(t, err, tt.expectedStatus)
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(updated, tt.expectedAddons) {
+				t.Errorf("expected add-ons %v, got %v", tt.expectedAddons, updated)
+			}
+		})
+	}
+}
+
+// TestDisableInstanceAddon tests the DisableInstanceAddon handler
+func TestDisableInstanceAddon(t *testing.T) {
+	tests := []struct {
+		name           string
+		userID         int64
+		role           string
+		addon          string
+		expectedStatus int
+		expectedAddons []string
+	}{
+		{name: "owner disables add-on", userID: 7, role: RoleUser, addon: "pgvector", expectedStatus: http.StatusOK, expectedAddons: []string{"metabase"}},
+		{name: "not enabled", userID: 7, role: RoleUser, addon: "imgproxy", expectedStatus: http.StatusNotFound},
+		{name: "other user", userID: 8, role: RoleUser, addon: "pgvector", expectedStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			instance := newOwnedInstance("my-app", "7")
+			instance.Spec.Addons = []string{"pgvector", "metabase"}
+			var updated []string
+			cr := newSuspensionCRClient(nil, instance)
+			cr.updateSupabaseInstanceFunc = func(_ context.Context, instance *supacontrolv1alpha1.SupabaseInstance) error {
+				updated = instance.Spec.Addons
+				return nil
+			}
+			handler := NewHandler(nil, &mockDBClient{}, cr, nil)
+			c, _ := newTestContext(http.MethodDelete, "/api/v1/instances/my-app/addons/"+tt.addon, "")
+			c.SetParamNames("name", "addon")
+			c.SetParamValues("my-app", tt.addon)
+			setAuthContext(c, tt.userID, "someone", tt.role)
+
+			err := handler.DisableInstanceAddon(c)
+			if tt.expectedStatus != http.StatusOK {
+				assertHTTPError(t, err, tt.expectedStatus)
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(updated, tt.expectedAddons) {
+				t.Errorf("expected add-ons %v, got %v", tt.expectedAddons, updated)
+			}
+		})
+	}
+}
+
+// TestNormalizeAddons tests validation of the add-ons requested for a new instance
+func TestNormalizeAddons(t *testing.T) {
+	got, err := normalizeAddons([]string{"pgvector", "imgproxy", "pgvector"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(got, []string{"pgvector", "imgproxy"}) {
+		t.Errorf("expected duplicates to be dropped, got %v", got)
+	}
+
+	_, err = normalizeAddons([]string{"pgvector", "wordpress"})
+	assertHTTPError(t, err, http.StatusBadRequest)
+}
@@ -0,0 +1,78 @@
+package api
+
+import (
+	"github.com/labstack/echo/v4"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+	"github.com/qubitquilt/supacontrol/server/internal/advisories"
+	"github.com/qubitquilt/supacontrol/server/internal/k8s"
+)
+
+// loadAdvisories returns the current advisory feed. It returns nil when no feed is
+// configured; a feed that fails to reload is logged and its last good copy is used.
+func (h *Handler) loadAdvisories(c echo.Context) []advisories.Advisory {
+	if h.advisorySource == nil {
+		return nil
+	}
+	feed, err := h.advisorySource.Advisories(c.Request().Context())
+	if err != nil {
+		GetLogger(c).Warn("Failed to load security advisories", "error", err)
+	}
+	return feed
+}
+
+// matchAdvisories returns the advisories affecting a component at the given image
+func matchAdvisories(feed []advisories.Advisory, component, image string) []apitypes.SecurityAdvisory {
+	if image == "" {
+		return nil
+	}
+	_, version := k8s.ParseImage(image)
+
+	var matched []apitypes.SecurityAdvisory
+	for _, advisory := range advisories.Match(feed, component, version) {
+		matched = append(matched, apitypes.SecurityAdvisory{
+			ID:             advisory.ID,
+			Component:      advisory.Component,
+			Severity:       advisory.Severity,
+			Summary:        advisory.Summary,
+			URL:            advisory.URL,
+			CurrentVersion: version,
+			FixedVersion:   advisory.Fixed,
+		})
+	}
+	return matched
+}
+
+// flagInstanceAdvisories sets the advisories affecting each instance's running components
+// and returns how many instances are affected. crs and instances must be index-aligned.
+// Advisory matching is best-effort: pod listing failures are logged and leave that instance
+// unflagged. Pods are listed per instance by release label rather than across the cluster.
+func (h *Handler) flagInstanceAdvisories(c echo.Context, crs []supacontrolv1alpha1.SupabaseInstance, instances []*apitypes.Instance) int {
+	feed := h.loadAdvisories(c)
+	if len(feed) == 0 || len(crs) == 0 {
+		return 0
+	}
+
+	affected := 0
+	for i := range crs {
+		namespace := getInstanceNamespace(&crs[i])
+		pods, err := h.k8sClient.GetClientset().CoreV1().Pods(namespace).List(c.Request().Context(), metav1.ListOptions{
+			LabelSelector: "app.kubernetes.io/instance=" + getInstanceReleaseName(&crs[i]),
+		})
+		if err != nil {
+			GetLogger(c).Warn("Failed to list pods for advisory matching", "namespace", namespace, "error", err)
+			continue
+		}
+
+		images := runningImages(pods.Items)
+		for _, component := range k8s.Components() {
+			instances[i].Advisories = append(instances[i].Advisories, matchAdvisories(feed, component, images[component])...)
+		}
+		if len(instances[i].Advisories) > 0 {
+			affected++
+		}
+	}
+	return affected
+}
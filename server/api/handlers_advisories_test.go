@@ -0,0 +1,159 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+	"github.com/qubitquilt/supacontrol/server/internal/advisories"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// testAdvisories is a feed with one postgres advisory fixed in 15.1.0.150
+var testAdvisories = []advisories.Advisory{
+	{
+		ID:        "SA-2024-01",
+		Component: apitypes.ComponentPostgres,
+		Severity:  "high",
+		Summary:   "privilege escalation",
+		Fixed:     "15.1.0.150",
+	},
+}
+
+// newPostgresPod returns a postgres pod of the named instance's release running the given tag
+func newPostgresPod(projectName, tag string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "db",
+			Namespace: "supa-" + projectName,
+			Labels:    map[string]string{"app.kubernetes.io/instance": projectName},
+		},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{Name: "db", Image: "supabase/postgres:" + tag}},
+		},
+	}
+}
+
+// TestListInstances_FlagsAdvisories tests that affected instances are flagged in list responses
+func TestListInstances_FlagsAdvisories(t *testing.T) {
+	instances := &supacontrolv1alpha1.SupabaseInstanceList{
+		Items: []supacontrolv1alpha1.SupabaseInstance{
+			*newOwnedInstance("old-app", "1"),
+			*newOwnedInstance("new-app", "1"),
+		},
+	}
+	clientset := fake.NewSimpleClientset(
+		newPostgresPod("old-app", "15.1.0.147"),
+		newPostgresPod("new-app", "15.1.0.150"),
+	)
+
+	tests := []struct {
+		name             string
+		source           AdvisorySource
+		expectedAffected int
+		expectedFlagged  map[string]bool
+	}{
+		{
+			name: "flags instances running affected versions",
+			source: &mockAdvisorySource{
+				advisoriesFunc: func(context.Context) ([]advisories.Advisory, error) {
+					return testAdvisories, nil
+				},
+			},
+			expectedAffected: 1,
+			expectedFlagged:  map[string]bool{"old-app": true, "new-app": false},
+		},
+		{
+			name: "feed failure leaves instances unflagged",
+			source: &mockAdvisorySource{
+				advisoriesFunc: func(context.Context) ([]advisories.Advisory, error) {
+					return nil, fmt.Errorf("feed unreachable")
+				},
+			},
+			expectedFlagged: map[string]bool{"old-app": false, "new-app": false},
+		},
+		{
+			name:            "no feed configured",
+			expectedFlagged: map[string]bool{"old-app": false, "new-app": false},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockCR := &mockCRClient{
+				listSupabaseInstancesFunc: func(context.Context) (*supacontrolv1alpha1.SupabaseInstanceList, error) {
+					return instances.DeepCopy(), nil
+				},
+			}
+
+			var opts []HandlerOption
+			if tt.source != nil {
+				opts = append(opts, WithAdvisorySource(tt.source))
+			}
+			handler := NewHandler(nil, &mockDBClient{}, mockCR, &mockK8sClient{clientset: clientset}, opts...)
+
+			c, rec := newTestContext(http.MethodGet, "/api/v1/instances", "")
+			if err := handler.ListInstances(c); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var resp apitypes.ListInstancesResponse
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+
+			if resp.AffectedInstances != tt.expectedAffected {
+				t.Errorf("expected affected_instances=%d, got %d", tt.expectedAffected, resp.AffectedInstances)
+			}
+			for _, instance := range resp.Instances {
+				flagged := len(instance.Advisories) > 0
+				if flagged != tt.expectedFlagged[instance.ProjectName] {
+					t.Errorf("instance %s: expected flagged=%t, got %t", instance.ProjectName, tt.expectedFlagged[instance.ProjectName], flagged)
+				}
+			}
+		})
+	}
+}
+
+// TestGetInstance_IncludesAdvisories tests that a single instance reports matched advisory details
+func TestGetInstance_IncludesAdvisories(t *testing.T) {
+	mockCR := &mockCRClient{
+		getSupabaseInstanceFunc: func(_ context.Context, name string) (*supacontrolv1alpha1.SupabaseInstance, error) {
+			return newOwnedInstance(name, "1"), nil
+		},
+	}
+	clientset := fake.NewSimpleClientset(newPostgresPod("old-app", "15.1.0.147"))
+	source := &mockAdvisorySource{
+		advisoriesFunc: func(context.Context) ([]advisories.Advisory, error) {
+			return testAdvisories, nil
+		},
+	}
+	handler := NewHandler(nil, &mockDBClient{}, mockCR, &mockK8sClient{clientset: clientset}, WithAdvisorySource(source))
+
+	c, rec := newTestContext(http.MethodGet, "/api/v1/instances/old-app", "")
+	c.SetParamNames("name")
+	c.SetParamValues("old-app")
+
+	if err := handler.GetInstance(c); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var resp apitypes.GetInstanceResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if len(resp.Instance.Advisories) != 1 {
+		t.Fatalf("expected 1 advisory, got %d", len(resp.Instance.Advisories))
+	}
+	advisory := resp.Instance.Advisories[0]
+	if advisory.ID != "SA-2024-01" || advisory.CurrentVersion != "15.1.0.147" || advisory.FixedVersion != "15.1.0.150" {
+		t.Errorf("unexpected advisory: %+v", advisory)
+	}
+}
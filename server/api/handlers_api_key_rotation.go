@@ -0,0 +1,112 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+	"github.com/qubitquilt/supacontrol/server/internal/i18n"
+)
+
+// defaultAPIKeyRotationOverlap is how long a rotated API key's previous value keeps
+// working when neither the request nor WithAPIKeyRotationOverlap sets an overlap
+const defaultAPIKeyRotationOverlap = 24 * time.Hour
+
+// WithAPIKeyRotationOverlap sets how long the previous value of a rotated API key keeps
+// working when the rotation request doesn't choose an overlap (0 revokes it immediately)
+func WithAPIKeyRotationOverlap(overlap time.Duration) HandlerOption {
+	return func(h *Handler) {
+		h.apiKeyRotationOverlap = overlap
+	}
+}
+
+// rotationOverlap returns the overlap a rotation request asks for, or the default
+func (h *Handler) rotationOverlap(req apitypes.RotateAPIKeyRequest) (time.Duration, error) {
+	if req.Overlap == "" {
+		return h.apiKeyRotationOverlap, nil
+	}
+	overlap, err := time.ParseDuration(req.Overlap)
+	if err != nil || overlap < 0 {
+		return 0, echo.NewHTTPError(http.StatusBadRequest, "overlap must be a duration such as 1h")
+	}
+	if overlap > apitypes.MaxAPIKeyRotationOverlap {
+		return 0, echo.NewHTTPError(http.StatusBadRequest, i18n.Msg("the previous API key can stay valid for at most %d hours", int(apitypes.MaxAPIKeyRotationOverlap.Hours())))
+	}
+	return overlap, nil
+}
+
+// RotateAPIKey replaces an API key with a newly generated one. The previous key keeps
+// working for the requested overlap, so automation can switch to the new key without
+// downtime. Users can only rotate their own keys, admins can rotate any.
+func (h *Handler) RotateAPIKey(c echo.Context) error {
+	authCtx := GetAuthContext(c)
+	if authCtx == nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "not authenticated")
+	}
+
+	var apiKeyID int64
+	if _, err := fmt.Sscanf(c.Param("id"), "%d", &apiKeyID); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid API key ID")
+	}
+
+	var req apitypes.RotateAPIKeyRequest
+	if err := bindRequest(c, &req); err != nil {
+		return err
+	}
+	overlap, err := h.rotationOverlap(req)
+	if err != nil {
+		return err
+	}
+
+	apiKey, err := h.dbClient.GetAPIKeyByID(apiKeyID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to get API key")
+	}
+	if apiKey == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "API key not found")
+	}
+	if !authCtx.IsAdmin() && apiKey.UserID != authCtx.UserID {
+		return echo.NewHTTPError(http.StatusForbidden, "cannot rotate other users' API keys")
+	}
+
+	newKey, err := h.authService.GenerateAPIKey()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to generate API key")
+	}
+	keyHash, err := h.authService.HashAPIKey(newKey)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to hash API key")
+	}
+
+	// The previous key never outlives the key itself
+	var previousValidUntil *time.Time
+	if overlap > 0 {
+		until := time.Now().Add(overlap)
+		if apiKey.ExpiresAt != nil && apiKey.ExpiresAt.Before(until) {
+			until = *apiKey.ExpiresAt
+		}
+		previousValidUntil = &until
+	}
+
+	rotated, err := h.dbClient.RotateAPIKey(apiKeyID, keyHash, previousValidUntil)
+	if err != nil {
+		GetLogger(c).Error("Failed to rotate API key", "api_key_id", apiKeyID, "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to rotate API key")
+	}
+	if rotated == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "API key not found")
+	}
+
+	h.recordAudit(c, "api_key.rotate", "api_key", strconv.FormatInt(apiKeyID, 10), map[string]string{
+		"overlap": overlap.String(),
+	})
+	return c.JSON(http.StatusOK, apitypes.CreateAPIKeyResponse{
+		Key:     newKey,
+		APIKey:  rotated,
+		Message: localize(c, "API key rotated successfully. Save this key securely - it won't be shown again!"),
+	})
+}
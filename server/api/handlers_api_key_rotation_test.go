@@ -0,0 +1,95 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+	"github.com/qubitquilt/supacontrol/server/internal/auth"
+)
+
+// TestRotateAPIKey tests ownership checks and overlap handling of the RotateAPIKey handler
+func TestRotateAPIKey(t *testing.T) {
+	keyExpiry := time.Now().Add(30 * time.Minute)
+
+	tests := []struct {
+		name           string
+		apiKeyID       string
+		body           string
+		userRole       string
+		key            *apitypes.APIKey
+		expectedStatus int
+		// expectedOverlap is the expected validity of the previous key, 0 if it is revoked
+		expectedOverlap time.Duration
+	}{
+		{name: "default overlap", apiKeyID: "1", userRole: "user", key: &apitypes.APIKey{ID: 1, UserID: 1}, expectedStatus: http.StatusOK, expectedOverlap: time.Hour},
+		{name: "requested overlap", apiKeyID: "1", body: `{"overlap":"2h"}`, userRole: "user", key: &apitypes.APIKey{ID: 1, UserID: 1}, expectedStatus: http.StatusOK, expectedOverlap: 2 * time.Hour},
+		{name: "no overlap", apiKeyID: "1", body: `{"overlap":"0s"}`, userRole: "user", key: &apitypes.APIKey{ID: 1, UserID: 1}, expectedStatus: http.StatusOK},
+		{name: "overlap capped by key expiry", apiKeyID: "1", userRole: "user", key: &apitypes.APIKey{ID: 1, UserID: 1, ExpiresAt: &keyExpiry}, expectedStatus: http.StatusOK, expectedOverlap: 30 * time.Minute},
+		{name: "other user's key as admin", apiKeyID: "2", userRole: "admin", key: &apitypes.APIKey{ID: 2, UserID: 999}, expectedStatus: http.StatusOK, expectedOverlap: time.Hour},
+		{name: "other user's key as user", apiKeyID: "2", userRole: "user", key: &apitypes.APIKey{ID: 2, UserID: 999}, expectedStatus: http.StatusForbidden},
+		{name: "invalid overlap", apiKeyID: "1", body: `{"overlap":"soon"}`, userRole: "user", key: &apitypes.APIKey{ID: 1, UserID: 1}, expectedStatus: http.StatusBadRequest},
+		{name: "overlap too long", apiKeyID: "1", body: `{"overlap":"8760h"}`, userRole: "user", key: &apitypes.APIKey{ID: 1, UserID: 1}, expectedStatus: http.StatusBadRequest},
+		{name: "invalid API key ID", apiKeyID: "invalid", userRole: "user", expectedStatus: http.StatusBadRequest},
+		{name: "API key not found", apiKeyID: "999", userRole: "user", expectedStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var rotatedHash string
+			var previousValidUntil *time.Time
+			rotated := false
+			mockDB := &mockDBClient{
+				getAPIKeyByIDFunc: func(_ int64) (*apitypes.APIKey, error) {
+					return tt.key, nil
+				},
+				rotateAPIKeyFunc: func(id int64, keyHash string, validUntil *time.Time) (*apitypes.APIKey, error) {
+					rotated = true
+					rotatedHash = keyHash
+					previousValidUntil = validUntil
+					return &apitypes.APIKey{ID: id, UserID: tt.key.UserID, KeyHash: keyHash, PreviousKeyExpiresAt: validUntil}, nil
+				},
+			}
+			handler := NewHandler(auth.NewService("test-secret-key"), mockDB, nil, nil, WithAPIKeyRotationOverlap(time.Hour))
+			c, rec := newTestContext(http.MethodPost, "/api/v1/auth/api-keys/"+tt.apiKeyID+"/rotate", tt.body)
+			c.SetParamNames("id")
+			c.SetParamValues(tt.apiKeyID)
+			setAuthContext(c, 1, "testuser", tt.userRole)
+
+			err := handler.RotateAPIKey(c)
+			if tt.expectedStatus != http.StatusOK {
+				assertHTTPError(t, err, tt.expectedStatus)
+				if rotated {
+					t.Error("expected the key not to be rotated")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var resp apitypes.CreateAPIKeyResponse
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if resp.Key == "" || rotatedHash == "" || rotatedHash == resp.Key {
+				t.Errorf("expected a new key stored by its hash, got key %q and hash %q", resp.Key, rotatedHash)
+			}
+
+			if tt.expectedOverlap == 0 {
+				if previousValidUntil != nil {
+					t.Errorf("expected the previous key to be revoked, got it valid until %v", previousValidUntil)
+				}
+				return
+			}
+			if previousValidUntil == nil {
+				t.Fatal("expected the previous key to stay valid")
+			}
+			if overlap := time.Until(*previousValidUntil); overlap > tt.expectedOverlap || overlap < tt.expectedOverlap-time.Minute {
+				t.Errorf("expected the previous key to stay valid for %v, got %v", tt.expectedOverlap, overlap)
+			}
+		})
+	}
+}
@@ -87,6 +87,17 @@ func TestLogin(t *testing.T) {
 			expectedStatus: http.StatusUnauthorized,
 			expectedError:  true,
 		},
+		{
+			name:        "single sign-on account",
+			requestBody: `{"username":"jane","password":"secret"}`,
+			setupMock: func(mockDB *mockDBClient, _ *auth.Service) {
+				mockDB.getUserByUsernameFunc = func(_ string) (*db.User, error) {
+					return &db.User{ID: 2, Username: "jane", Role: "user", AuthProvider: db.AuthProviderSAML}, nil
+				}
+			},
+			expectedStatus: http.StatusUnauthorized,
+			expectedError:  true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -222,6 +233,128 @@ func TestGetAuthMe(t *testing.T) {
 	}
 }
 
+// TestChangePassword tests the password change endpoint
+func TestChangePassword(t *testing.T) {
+	authSvc := auth.NewService("test-secret")
+	hash, err := authSvc.HashPassword("one-time-password")
+	if err != nil {
+		t.Fatalf("failed to hash password: %v", err)
+	}
+
+	tests := []struct {
+		name           string
+		requestBody    string
+		authProvider   string
+		expectedStatus int
+		expectUpdate   bool
+	}{
+		{
+			name:           "successful change",
+			requestBody:    `{"current_password":"one-time-password","new_password":"a-much-better-password"}`,
+			expectedStatus: http.StatusNoContent,
+			expectUpdate:   true,
+		},
+		{
+			name:           "wrong current password",
+			requestBody:    `{"current_password":"guess","new_password":"a-much-better-password"}`,
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "new password too short",
+			requestBody:    `{"current_password":"one-time-password","new_password":"short"}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "new password unchanged",
+			requestBody:    `{"current_password":"one-time-password","new_password":"one-time-password"}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "single sign-on account",
+			requestBody:    `{"current_password":"one-time-password","new_password":"a-much-better-password"}`,
+			authProvider:   db.AuthProviderSAML,
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var updatedHash string
+			mockDB := &mockDBClient{
+				getUserByIDFunc: func(id int64) (*db.User, error) {
+					return &db.User{ID: id, Username: "admin", Role: "admin", PasswordHash: hash,
+						AuthProvider: tt.authProvider, MustChangePassword: true}, nil
+				},
+				updateUserPasswordFunc: func(_ int64, passwordHash string) error {
+					updatedHash = passwordHash
+					return nil
+				},
+				createAuditLogFunc: func(_ int64, _, _, _ string, _ map[string]string) error {
+					return nil
+				},
+			}
+
+			handler := NewHandler(authSvc, mockDB, nil, nil)
+			c, rec := newTestContext(http.MethodPut, "/api/v1/auth/password", tt.requestBody)
+			setAuthContext(c, 1, "admin", "admin")
+
+			err := handler.ChangePassword(c)
+			if tt.expectedStatus != http.StatusNoContent {
+				assertHTTPError(t, err, tt.expectedStatus)
+			} else {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if rec.Code != tt.expectedStatus {
+					t.Errorf("expected status %d, got %d", tt.expectedStatus, rec.Code)
+				}
+			}
+
+			if (updatedHash != "") != tt.expectUpdate {
+				t.Fatalf("expected update %v, got hash %q", tt.expectUpdate, updatedHash)
+			}
+			if tt.expectUpdate {
+				if valid, _ := authSvc.VerifyPassword("a-much-better-password", updatedHash); !valid {
+					t.Error("stored hash does not match the new password")
+				}
+			}
+		})
+	}
+}
+
+// TestRequirePasswordChange tests that users who must change their password can only do so
+func TestRequirePasswordChange(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		mustChange bool
+		allowed    bool
+	}{
+		{name: "flagged user changing password", method: http.MethodPut, path: "/api/v1/auth/password", mustChange: true, allowed: true},
+		{name: "flagged user reading their account", method: http.MethodGet, path: "/api/v1/auth/me", mustChange: true, allowed: true},
+		{name: "flagged user listing instances", method: http.MethodGet, path: "/api/v1/instances", mustChange: true},
+		{name: "flagged user creating an API key", method: http.MethodPost, path: "/api/v1/auth/api-keys", mustChange: true},
+		{name: "regular user", method: http.MethodGet, path: "/api/v1/instances", allowed: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := newTestContext(tt.method, tt.path, "")
+			c.SetPath(tt.path)
+
+			err := requirePasswordChange(c, &db.User{ID: 1, MustChangePassword: tt.mustChange})
+			if tt.allowed {
+				if err != nil {
+					t.Errorf("expected request to be allowed, got %v", err)
+				}
+				return
+			}
+			assertHTTPError(t, err, http.StatusForbidden)
+		})
+	}
+}
+
 // TestCreateAPIKey tests the API key creation endpoint
 func TestCreateAPIKey(t *testing.T) {
 	tests := []struct {
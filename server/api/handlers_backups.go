@@ -0,0 +1,264 @@
+package api
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+	"github.com/qubitquilt/supacontrol/server/internal/backup"
+	"github.com/qubitquilt/supacontrol/server/internal/i18n"
+	"github.com/qubitquilt/supacontrol/server/internal/objectstore"
+)
+
+// WithBackups sets the object storage backups are kept in and the keys they are encrypted
+// with, which enables instance backups. Backups also need the pod executor.
+func WithBackups(store objectstore.Backend, keys *backup.Keyring) HandlerOption {
+	return func(h *Handler) {
+		h.backupStore = store
+		h.backupKeys = keys
+	}
+}
+
+// backupInstance returns the instance an admin or the owner backs up or restores
+func (h *Handler) backupInstance(c echo.Context) (*supacontrolv1alpha1.SupabaseInstance, error) {
+	if h.backupStore == nil || h.backupKeys == nil || h.podExecutor == nil {
+		return nil, echo.NewHTTPError(http.StatusServiceUnavailable, "backups are not enabled")
+	}
+	instance, err := h.getInstanceOrError(c, c.Param("name"))
+	if err != nil {
+		return nil, err
+	}
+	if !isAdminOrOwner(GetAuthContext(c), instance) {
+		return nil, echo.NewHTTPError(http.StatusForbidden, "only admins and the instance owner can manage backups")
+	}
+	return instance, nil
+}
+
+// CreateBackup dumps an instance database, encrypts the dump with the active backup key
+// and stores it in object storage (admins and the instance owner only). The dump never
+// leaves the API unencrypted.
+func (h *Handler) CreateBackup(c echo.Context) error {
+	instance, err := h.backupInstance(c)
+	if err != nil {
+		return err
+	}
+	namespace, pod, container, err := h.databasePod(c, instance)
+	if err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	dump, err := h.podExecutor.Exec(ctx, namespace, pod, container, backup.DumpCommand(), "")
+	if err != nil {
+		return cronError(c, err, "failed to dump the instance database")
+	}
+	artifact, err := h.backupKeys.Encrypt([]byte(dump))
+	if err != nil {
+		GetLogger(c).Error("Failed to encrypt backup", "instance", instance.Name, "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to encrypt backup")
+	}
+
+	createdAt := time.Now().UTC()
+	objectKey := backup.ObjectKey(instance.Name, createdAt)
+	if err := h.backupStore.Put(ctx, objectKey, bytes.NewReader(artifact), int64(len(artifact))); err != nil {
+		GetLogger(c).Error("Failed to store backup", "instance", instance.Name, "error", err)
+		return echo.NewHTTPError(http.StatusBadGateway, "failed to store backup")
+	}
+
+	authCtx := GetAuthContext(c)
+	record, err := h.dbClient.CreateBackup(instance.Name, objectKey, h.backupKeys.ActiveKeyID(), int64(len(artifact)), &authCtx.UserID, createdAt)
+	if err != nil {
+		GetLogger(c).Error("Failed to record backup", "instance", instance.Name, "error", err)
+		// Don't leave an artifact behind that no record points to
+		if err := h.backupStore.Delete(ctx, objectKey); err != nil {
+			GetLogger(c).Error("Failed to delete unrecorded backup", "key", objectKey, "error", err)
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to record backup")
+	}
+
+	h.recordAudit(c, "instance.backup.create", "instance", instance.Name, map[string]string{
+		"backup_id": strconv.FormatInt(record.ID, 10),
+		"key_id":    record.KeyID,
+	})
+	return c.JSON(http.StatusCreated, record)
+}
+
+// ListBackups lists the backups of an instance, newest first (admins and the instance
+// owner only)
+func (h *Handler) ListBackups(c echo.Context) error {
+	instance, err := h.backupInstance(c)
+	if err != nil {
+		return err
+	}
+	backups, err := h.dbClient.ListBackups(instance.Name)
+	if err != nil {
+		GetLogger(c).Error("Failed to list backups", "instance", instance.Name, "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to list backups")
+	}
+	return c.JSON(http.StatusOK, apitypes.ListBackupsResponse{Backups: backups, Count: len(backups)})
+}
+
+// backupRecord returns the backup named by the id parameter, which has to belong to
+// instance and not have been pruned
+func (h *Handler) backupRecord(c echo.Context, instance *supacontrolv1alpha1.SupabaseInstance) (*apitypes.Backup, error) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "invalid backup ID")
+	}
+	record, err := h.dbClient.GetBackup(id)
+	if err != nil {
+		GetLogger(c).Error("Failed to get backup", "backup_id", id, "error", err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "failed to get backup")
+	}
+	if record == nil || record.InstanceName != instance.Name {
+		return nil, echo.NewHTTPError(http.StatusNotFound, "backup not found")
+	}
+	return record, nil
+}
+
+// RestoreBackup restores a backup over its instance's database (admins and the instance
+// owner only). The backup's key has to be configured; a backup that fails to decrypt is
+// never applied.
+func (h *Handler) RestoreBackup(c echo.Context) error {
+	instance, err := h.backupInstance(c)
+	if err != nil {
+		return err
+	}
+	record, err := h.backupRecord(c, instance)
+	if err != nil {
+		return err
+	}
+	id := record.ID
+
+	ctx := c.Request().Context()
+	body, err := h.backupStore.Get(ctx, record.ObjectKey)
+	if err != nil {
+		if errors.Is(err, objectstore.ErrNotFound) {
+			return echo.NewHTTPError(http.StatusConflict, "backup artifact is missing from object storage")
+		}
+		GetLogger(c).Error("Failed to fetch backup", "backup_id", id, "error", err)
+		return echo.NewHTTPError(http.StatusBadGateway, "failed to fetch backup")
+	}
+	defer func() { _ = body.Close() }()
+	artifact, err := io.ReadAll(body)
+	if err != nil {
+		GetLogger(c).Error("Failed to read backup", "backup_id", id, "error", err)
+		return echo.NewHTTPError(http.StatusBadGateway, "failed to fetch backup")
+	}
+
+	// The artifact must have been encrypted with the key its record names
+	if keyID, err := backup.KeyID(artifact); err != nil || keyID != record.KeyID {
+		return echo.NewHTTPError(http.StatusConflict, "backup is corrupt or was modified")
+	}
+	dump, err := h.backupKeys.Decrypt(artifact)
+	if errors.Is(err, backup.ErrUnknownKey) {
+		return echo.NewHTTPError(http.StatusConflict, i18n.Msg("backup encryption key %s is not configured", record.KeyID))
+	}
+	if err != nil {
+		return echo.NewHTTPError(http.StatusConflict, "backup is corrupt or was modified")
+	}
+
+	namespace, pod, container, err := h.databasePod(c, instance)
+	if err != nil {
+		return err
+	}
+	if _, err := h.podExecutor.Exec(ctx, namespace, pod, container, backup.RestoreCommand(), string(dump)); err != nil {
+		return cronError(c, err, "failed to restore backup")
+	}
+
+	h.recordAudit(c, "instance.backup.restore", "instance", instance.Name, map[string]string{
+		"backup_id": strconv.FormatInt(record.ID, 10),
+	})
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": localize(c, "Backup restored successfully"),
+	})
+}
+
+// UpdateBackupHold places or releases the hold that protects a backup from the retention
+// policy (admins and the instance owner only)
+func (h *Handler) UpdateBackupHold(c echo.Context) error {
+	var req apitypes.UpdateBackupHoldRequest
+	if err := bindRequest(c, &req); err != nil {
+		return err
+	}
+
+	instance, err := h.backupInstance(c)
+	if err != nil {
+		return err
+	}
+	record, err := h.backupRecord(c, instance)
+	if err != nil {
+		return err
+	}
+	updated, err := h.dbClient.SetBackupHeld(record.ID, req.Held)
+	if err != nil {
+		GetLogger(c).Error("Failed to set backup hold", "backup_id", record.ID, "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to update backup hold")
+	}
+	if updated == nil {
+		// Pruned since it was looked up
+		return echo.NewHTTPError(http.StatusNotFound, "backup not found")
+	}
+
+	h.recordAudit(c, "instance.backup.hold", "instance", instance.Name, map[string]string{
+		"backup_id": strconv.FormatInt(updated.ID, 10),
+		"held":      strconv.FormatBool(updated.Held),
+	})
+	return c.JSON(http.StatusOK, updated)
+}
+
+// GetBackupRetention returns the retention policy of an instance's backups (admins and
+// the instance owner only). Without a policy every backup is kept.
+func (h *Handler) GetBackupRetention(c echo.Context) error {
+	instance, err := h.backupInstance(c)
+	if err != nil {
+		return err
+	}
+	policy, err := h.dbClient.GetBackupRetentionPolicy(instance.Name)
+	if err != nil {
+		GetLogger(c).Error("Failed to get backup retention policy", "instance", instance.Name, "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to get backup retention policy")
+	}
+	if policy == nil {
+		policy = &apitypes.BackupRetentionPolicy{InstanceName: instance.Name}
+	}
+	return c.JSON(http.StatusOK, policy)
+}
+
+// UpdateBackupRetention replaces the retention policy of an instance's backups (admins
+// and the instance owner only). The pruner applies it on its next pass.
+func (h *Handler) UpdateBackupRetention(c echo.Context) error {
+	var req apitypes.UpdateBackupRetentionRequest
+	if err := bindRequest(c, &req); err != nil {
+		return err
+	}
+	for _, keep := range []int{req.KeepDaily, req.KeepWeekly, req.KeepMonthly} {
+		if keep < 0 || keep > apitypes.MaxBackupRetention {
+			return echo.NewHTTPError(http.StatusBadRequest, i18n.Msg("keep_daily, keep_weekly and keep_monthly must be between 0 and %d", apitypes.MaxBackupRetention))
+		}
+	}
+
+	instance, err := h.backupInstance(c)
+	if err != nil {
+		return err
+	}
+	policy, err := h.dbClient.SetBackupRetentionPolicy(instance.Name, req.KeepDaily, req.KeepWeekly, req.KeepMonthly)
+	if err != nil {
+		GetLogger(c).Error("Failed to set backup retention policy", "instance", instance.Name, "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to update backup retention policy")
+	}
+
+	h.recordAudit(c, "instance.backup.retention.update", "instance", instance.Name, map[string]string{
+		"keep_daily":   strconv.Itoa(policy.KeepDaily),
+		"keep_weekly":  strconv.Itoa(policy.KeepWeekly),
+		"keep_monthly": strconv.Itoa(policy.KeepMonthly),
+	})
+	return c.JSON(http.StatusOK, policy)
+}
@@ -0,0 +1,336 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"k8s.io/client-go/kubernetes/fake"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+	"github.com/qubitquilt/supacontrol/server/internal/backup"
+	"github.com/qubitquilt/supacontrol/server/internal/objectstore"
+)
+
+// newBackupKeyring returns a keyring holding the key id, derived from it
+func newBackupKeyring(t *testing.T, id string) *backup.Keyring {
+	t.Helper()
+	key := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte(id[:1]), backup.KeySize))
+	keys, err := backup.NewKeyring(map[string]string{id: key}, "")
+	if err != nil {
+		t.Fatalf("failed to build keyring: %v", err)
+	}
+	return keys
+}
+
+// backupFixture serves backups of the my-app instance owned by user 7 from a local object
+// store, recording the backups and the commands run in its database
+type backupFixture struct {
+	store    objectstore.Backend
+	records  map[int64]*apitypes.Backup
+	policy   *apitypes.BackupRetentionPolicy
+	commands [][]string
+	stdin    []string
+}
+
+func newBackupFixture(t *testing.T) *backupFixture {
+	t.Helper()
+	store, err := objectstore.New(objectstore.Config{Provider: objectstore.ProviderLocal, Path: t.TempDir()})
+	if err != nil {
+		t.Fatalf("failed to create object store: %v", err)
+	}
+	return &backupFixture{store: store, records: map[int64]*apitypes.Backup{}}
+}
+
+// handler returns a handler encrypting backups with keys
+func (f *backupFixture) handler(keys *backup.Keyring) *Handler {
+	mockDB := &mockDBClient{
+		createBackupFunc: func(instanceName, objectKey, keyID string, sizeBytes int64, createdBy *int64, createdAt time.Time) (*apitypes.Backup, error) {
+			record := &apitypes.Backup{ID: int64(len(f.records) + 1), InstanceName: instanceName, ObjectKey: objectKey,
+				KeyID: keyID, SizeBytes: sizeBytes, CreatedBy: createdBy, CreatedAt: createdAt}
+			f.records[record.ID] = record
+			return record, nil
+		},
+		getBackupFunc: func(id int64) (*apitypes.Backup, error) {
+			return f.records[id], nil
+		},
+		listBackupsFunc: func(instanceName string) ([]*apitypes.Backup, error) {
+			backups := []*apitypes.Backup{}
+			for _, record := range f.records {
+				if record.InstanceName == instanceName {
+					backups = append(backups, record)
+				}
+			}
+			return backups, nil
+		},
+		setBackupHeldFunc: func(id int64, held bool) (*apitypes.Backup, error) {
+			record := f.records[id]
+			if record != nil {
+				record.Held = held
+			}
+			return record, nil
+		},
+		getBackupRetentionFunc: func(string) (*apitypes.BackupRetentionPolicy, error) {
+			return f.policy, nil
+		},
+		setBackupRetentionFunc: func(instanceName string, keepDaily, keepWeekly, keepMonthly int) (*apitypes.BackupRetentionPolicy, error) {
+			f.policy = &apitypes.BackupRetentionPolicy{InstanceName: instanceName, KeepDaily: keepDaily,
+				KeepWeekly: keepWeekly, KeepMonthly: keepMonthly}
+			return f.policy, nil
+		},
+		createAuditLogFunc: func(int64, string, string, string, map[string]string) error {
+			return nil
+		},
+	}
+	exec := &mockPodExecutor{execFunc: func(_ context.Context, _, _, _ string, command []string, stdin string) (string, error) {
+		f.commands = append(f.commands, command)
+		f.stdin = append(f.stdin, stdin)
+		return "CREATE TABLE todos (id bigint);\n", nil
+	}}
+	return NewHandler(nil, mockDB, newSuspensionCRClient(nil, newOwnedInstance("my-app", "7")),
+		&mockK8sClient{clientset: fake.NewSimpleClientset(newDatabasePod())}, WithPodExecutor(exec), WithBackups(f.store, keys))
+}
+
+// backupContext returns a request context for a backup route of the my-app instance
+func backupContext(method, path string, userID int64, params ...string) (echo.Context, *httptest.ResponseRecorder) {
+	c, rec := newTestContext(method, path, "")
+	c.SetParamNames(append([]string{"name"}, params[:len(params)/2]...)...)
+	c.SetParamValues(append([]string{"my-app"}, params[len(params)/2:]...)...)
+	setAuthContext(c, userID, "owner", RoleUser)
+	return c, rec
+}
+
+// TestBackups tests that backups are stored encrypted and restored with their key
+func TestBackups(t *testing.T) {
+	fixture := newBackupFixture(t)
+	handler := fixture.handler(newBackupKeyring(t, "2025"))
+
+	c, rec := backupContext(http.MethodPost, "/api/v1/instances/my-app/backups", 7)
+	if err := handler.CreateBackup(c); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var created apitypes.Backup
+	if err := json.Unmarshal(rec.Body.Bytes(), &created); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if created.KeyID != "2025" || created.InstanceName != "my-app" || created.SizeBytes == 0 {
+		t.Errorf("unexpected backup %+v", created)
+	}
+	if !slices.Equal(fixture.commands[0], backup.DumpCommand()) {
+		t.Errorf("expected the database to be dumped, ran %v", fixture.commands[0])
+	}
+	object, err := fixture.store.Get(context.Background(), fixture.records[created.ID].ObjectKey)
+	if err != nil {
+		t.Fatalf("expected the backup in object storage: %v", err)
+	}
+	artifact, _ := io.ReadAll(object)
+	_ = object.Close()
+	if bytes.Contains(artifact, []byte("CREATE TABLE")) || int64(len(artifact)) != created.SizeBytes {
+		t.Error("expected the stored backup to be encrypted")
+	}
+
+	c, rec = backupContext(http.MethodGet, "/api/v1/instances/my-app/backups", 7)
+	if err := handler.ListBackups(c); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var list apitypes.ListBackupsResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &list); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if list.Count != 1 || list.Backups[0].ID != created.ID || strings.Contains(rec.Body.String(), "backups/my-app") {
+		t.Errorf("unexpected list %s", rec.Body.String())
+	}
+
+	// After a key rotation the old key is still needed, and enough, to restore
+	id := strconv.FormatInt(created.ID, 10)
+	rotated := fixture.handler(newBackupKeyring(t, "2026"))
+	c, _ = backupContext(http.MethodPost, "/api/v1/instances/my-app/backups/"+id+"/restore", 7, "id", id)
+	assertHTTPError(t, rotated.RestoreBackup(c), http.StatusConflict)
+	if len(fixture.commands) != 1 {
+		t.Fatal("expected nothing to be restored without the key")
+	}
+
+	c, _ = backupContext(http.MethodPost, "/api/v1/instances/my-app/backups/"+id+"/restore", 7, "id", id)
+	if err := handler.RestoreBackup(c); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !slices.Equal(fixture.commands[1], backup.RestoreCommand()) || fixture.stdin[1] != "CREATE TABLE todos (id bigint);\n" {
+		t.Errorf("expected the decrypted dump to be restored, ran %v with %q", fixture.commands[1], fixture.stdin[1])
+	}
+}
+
+// TestRestoreBackup_Denied tests that backups are only restored intact, into their own
+// instance, by admins and the owner
+func TestRestoreBackup_Denied(t *testing.T) {
+	fixture := newBackupFixture(t)
+	keys := newBackupKeyring(t, "2025")
+	handler := fixture.handler(keys)
+	artifact, err := keys.Encrypt([]byte("SELECT 1;"))
+	if err != nil {
+		t.Fatalf("failed to encrypt: %v", err)
+	}
+	tampered := bytes.Clone(artifact)
+	tampered[len(tampered)-1] ^= 1
+	for key, body := range map[string][]byte{"intact": artifact, "tampered": tampered} {
+		if err := fixture.store.Put(context.Background(), key, bytes.NewReader(body), int64(len(body))); err != nil {
+			t.Fatalf("failed to store backup: %v", err)
+		}
+	}
+	fixture.records[1] = &apitypes.Backup{ID: 1, InstanceName: "other-app", ObjectKey: "intact", KeyID: "2025"}
+	fixture.records[2] = &apitypes.Backup{ID: 2, InstanceName: "my-app", ObjectKey: "tampered", KeyID: "2025"}
+	fixture.records[3] = &apitypes.Backup{ID: 3, InstanceName: "my-app", ObjectKey: "intact", KeyID: "2024"}
+	fixture.records[4] = &apitypes.Backup{ID: 4, InstanceName: "my-app", ObjectKey: "missing", KeyID: "2025"}
+	fixture.records[5] = &apitypes.Backup{ID: 5, InstanceName: "my-app", ObjectKey: "intact", KeyID: "2025"}
+
+	tests := []struct {
+		name           string
+		id             string
+		userID         int64
+		expectedStatus int
+	}{
+		{name: "backup of another instance", id: "1", userID: 7, expectedStatus: http.StatusNotFound},
+		{name: "modified artifact", id: "2", userID: 7, expectedStatus: http.StatusConflict},
+		{name: "artifact does not match its record", id: "3", userID: 7, expectedStatus: http.StatusConflict},
+		{name: "artifact missing", id: "4", userID: 7, expectedStatus: http.StatusConflict},
+		{name: "not the owner", id: "5", userID: 8, expectedStatus: http.StatusForbidden},
+		{name: "invalid ID", id: "latest", userID: 7, expectedStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := backupContext(http.MethodPost, "/api/v1/instances/my-app/backups/"+tt.id+"/restore", tt.userID, "id", tt.id)
+			assertHTTPError(t, handler.RestoreBackup(c), tt.expectedStatus)
+			if len(fixture.commands) != 0 {
+				t.Errorf("expected nothing to be restored, ran %v", fixture.commands)
+			}
+		})
+	}
+}
+
+// TestUpdateBackupHold tests that an admin or the owner can hold a backup of their instance
+func TestUpdateBackupHold(t *testing.T) {
+	fixture := newBackupFixture(t)
+	handler := fixture.handler(newBackupKeyring(t, "2025"))
+	fixture.records[1] = &apitypes.Backup{ID: 1, InstanceName: "my-app", KeyID: "2025"}
+	fixture.records[2] = &apitypes.Backup{ID: 2, InstanceName: "other-app", KeyID: "2025"}
+
+	tests := []struct {
+		name           string
+		id             string
+		userID         int64
+		body           string
+		expectedStatus int
+	}{
+		{name: "hold", id: "1", userID: 7, body: `{"held": true}`, expectedStatus: http.StatusOK},
+		{name: "backup of another instance", id: "2", userID: 7, body: `{"held": true}`, expectedStatus: http.StatusNotFound},
+		{name: "missing backup", id: "3", userID: 7, body: `{"held": true}`, expectedStatus: http.StatusNotFound},
+		{name: "not the owner", id: "1", userID: 8, body: `{"held": false}`, expectedStatus: http.StatusForbidden},
+		{name: "invalid body", id: "1", userID: 7, body: `{"held": "yes"}`, expectedStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, rec := newTestContext(http.MethodPut, "/api/v1/instances/my-app/backups/"+tt.id+"/hold", tt.body)
+			c.SetParamNames("name", "id")
+			c.SetParamValues("my-app", tt.id)
+			setAuthContext(c, tt.userID, "owner", RoleUser)
+
+			err := handler.UpdateBackupHold(c)
+			if tt.expectedStatus != http.StatusOK {
+				assertHTTPError(t, err, tt.expectedStatus)
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var updated apitypes.Backup
+			if err := json.Unmarshal(rec.Body.Bytes(), &updated); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if !updated.Held || !fixture.records[1].Held {
+				t.Errorf("expected the backup to be held, got %s", rec.Body.String())
+			}
+		})
+	}
+	if fixture.records[2].Held {
+		t.Error("expected the other instance's backup to stay unheld")
+	}
+}
+
+// TestBackupRetention tests reading and replacing an instance's retention policy
+func TestBackupRetention(t *testing.T) {
+	fixture := newBackupFixture(t)
+	handler := fixture.handler(newBackupKeyring(t, "2025"))
+
+	// Without a policy every backup is kept
+	c, rec := backupContext(http.MethodGet, "/api/v1/instances/my-app/backups/retention", 7)
+	if err := handler.GetBackupRetention(c); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var policy apitypes.BackupRetentionPolicy
+	if err := json.Unmarshal(rec.Body.Bytes(), &policy); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if policy.InstanceName != "my-app" || policy.KeepDaily != 0 || policy.KeepWeekly != 0 || policy.KeepMonthly != 0 {
+		t.Errorf("expected an empty policy, got %s", rec.Body.String())
+	}
+
+	tests := []struct {
+		name           string
+		userID         int64
+		body           string
+		expectedStatus int
+	}{
+		{name: "negative", userID: 7, body: `{"keep_daily": -1}`, expectedStatus: http.StatusBadRequest},
+		{name: "too many", userID: 7, body: `{"keep_monthly": 1001}`, expectedStatus: http.StatusBadRequest},
+		{name: "not the owner", userID: 8, body: `{"keep_daily": 7}`, expectedStatus: http.StatusForbidden},
+		{name: "update", userID: 7, body: `{"keep_daily": 7, "keep_weekly": 4, "keep_monthly": 12}`, expectedStatus: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := newTestContext(http.MethodPut, "/api/v1/instances/my-app/backups/retention", tt.body)
+			c.SetParamNames("name")
+			c.SetParamValues("my-app")
+			setAuthContext(c, tt.userID, "owner", RoleUser)
+
+			err := handler.UpdateBackupRetention(c)
+			if tt.expectedStatus != http.StatusOK {
+				assertHTTPError(t, err, tt.expectedStatus)
+				if fixture.policy != nil {
+					t.Errorf("expected no policy to be stored, got %+v", fixture.policy)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+
+	c, rec = backupContext(http.MethodGet, "/api/v1/instances/my-app/backups/retention", 7)
+	if err := handler.GetBackupRetention(c); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &policy); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if policy.KeepDaily != 7 || policy.KeepWeekly != 4 || policy.KeepMonthly != 12 {
+		t.Errorf("expected the updated policy, got %s", rec.Body.String())
+	}
+}
+
+// TestBackupsDisabled tests that backups need object storage and keys
+func TestBackupsDisabled(t *testing.T) {
+	handler := NewHandler(nil, &mockDBClient{}, newSuspensionCRClient(nil, newOwnedInstance("my-app", "7")), nil,
+		WithPodExecutor(&mockPodExecutor{}))
+	c, _ := backupContext(http.MethodPost, "/api/v1/instances/my-app/backups", 7)
+	assertHTTPError(t, handler.CreateBackup(c), http.StatusServiceUnavailable)
+}
@@ -0,0 +1,214 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+	"github.com/qubitquilt/supacontrol/server/internal/k8s"
+)
+
+// badgeTTL is how long a badge's status is cached, bounding the load anonymous
+// badge requests put on the Kubernetes API
+const badgeTTL = time.Minute
+
+// badgeColors maps badge statuses to shields.io-style colors
+var badgeColors = map[string]string{
+	apitypes.BadgeStatusRunning:  "#4c1",
+	apitypes.BadgeStatusDegraded: "#dfb317",
+	apitypes.BadgeStatusDown:     "#e05d44",
+}
+
+// badgeEntry is a cached badge status
+type badgeEntry struct {
+	status  string
+	expires time.Time
+}
+
+// badgeCache caches badge statuses per instance
+type badgeCache struct {
+	mu      sync.Mutex
+	entries map[string]badgeEntry
+	now     func() time.Time
+}
+
+// newBadgeCache creates an empty badge cache
+func newBadgeCache() *badgeCache {
+	return &badgeCache{entries: map[string]badgeEntry{}, now: time.Now}
+}
+
+// get returns the cached status of an instance, if it has not expired
+func (b *badgeCache) get(name string) (string, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	entry, ok := b.entries[name]
+	if !ok || b.now().After(entry.expires) {
+		return "", false
+	}
+	return entry.status, true
+}
+
+// put caches the status of an instance
+func (b *badgeCache) put(name, status string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.entries[name] = badgeEntry{status: status, expires: b.now().Add(badgeTTL)}
+}
+
+// forget drops the cached status of an instance
+func (b *badgeCache) forget(name string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.entries, name)
+}
+
+// podsReady counts the instance's serving pods and how many of them are ready. Pods of
+// finished Jobs are not counted.
+func (h *Handler) podsReady(ctx context.Context, namespace string) (total, ready int, err error) {
+	pods, err := h.k8sClient.GetClientset().CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return 0, 0, err
+	}
+	for _, pod := range pods.Items {
+		if pod.Status.Phase == corev1.PodSucceeded {
+			continue
+		}
+		total++
+		for _, condition := range pod.Status.Conditions {
+			if condition.Type == corev1.PodReady && condition.Status == corev1.ConditionTrue {
+				ready++
+				break
+			}
+		}
+	}
+	return total, ready, nil
+}
+
+// badgeStatus derives an instance's badge status from its phase and the readiness of its
+// pods: running when all of them are ready, degraded while some are not or the instance
+// is changing, and down when it serves nothing
+func (h *Handler) badgeStatus(ctx context.Context, instance *supacontrolv1alpha1.SupabaseInstance) string {
+	switch instance.Status.Phase {
+	case supacontrolv1alpha1.PhaseRunning:
+	case supacontrolv1alpha1.PhaseUpgrading:
+		return apitypes.BadgeStatusDegraded
+	default:
+		if supacontrolv1alpha1.IsDatabaseUpgradePhase(instance.Status.Phase) {
+			return apitypes.BadgeStatusDegraded
+		}
+		return apitypes.BadgeStatusDown
+	}
+
+	if h.k8sClient == nil {
+		if meta.IsStatusConditionTrue(instance.Status.Conditions, supacontrolv1alpha1.ConditionTypeReady) {
+			return apitypes.BadgeStatusRunning
+		}
+		return apitypes.BadgeStatusDegraded
+	}
+	total, ready, err := h.podsReady(ctx, getInstanceNamespace(instance))
+	switch {
+	case err != nil:
+		return apitypes.BadgeStatusDegraded
+	case ready == 0:
+		return apitypes.BadgeStatusDown
+	case ready < total:
+		return apitypes.BadgeStatusDegraded
+	default:
+		return apitypes.BadgeStatusRunning
+	}
+}
+
+// renderBadge renders a flat SVG badge with the label on the left and the status on the right
+func renderBadge(label, status string) string {
+	// Approximate Verdana 11px glyph widths, as badge services do
+	labelWidth := 10 + 7*len(label)
+	statusWidth := 10 + 7*len(status)
+	width := labelWidth + statusWidth
+	label = html.EscapeString(label)
+
+	return fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" width="%[1]d" height="20" role="img" aria-label="%[2]s: %[3]s">
+<title>%[2]s: %[3]s</title>
+<linearGradient id="s" x2="0" y2="100%%"><stop offset="0" stop-color="#bbb" stop-opacity=".1"/><stop offset="1" stop-opacity=".1"/></linearGradient>
+<clipPath id="r"><rect width="%[1]d" height="20" rx="3" fill="#fff"/></clipPath>
+<g clip-path="url(#r)">
+<rect width="%[4]d" height="20" fill="#555"/>
+<rect x="%[4]d" width="%[5]d" height="20" fill="%[6]s"/>
+<rect width="%[1]d" height="20" fill="url(#s)"/>
+</g>
+<g fill="#fff" text-anchor="middle" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" font-size="11">
+<text x="%[7]d" y="14">%[2]s</text>
+<text x="%[8]d" y="14">%[3]s</text>
+</g>
+</svg>
+`, width, label, status, labelWidth, statusWidth, badgeColors[status], labelWidth/2, labelWidth+statusWidth/2)
+}
+
+// GetStatusBadge serves an instance's status badge as SVG, for embedding in READMEs and
+// wikis. It is public, so only instances that publish their badge have one, and statuses
+// are cached for a minute.
+func (h *Handler) GetStatusBadge(c echo.Context) error {
+	name := c.Param("name")
+	ctx := c.Request().Context()
+
+	status, ok := h.badges.get(name)
+	if !ok {
+		instance, err := h.crClient.GetSupabaseInstance(ctx, name)
+		if err != nil && !errors.Is(err, k8s.ErrInstanceNotFound) {
+			GetLogger(c).Error("Failed to get instance", "error", err)
+			return echo.NewHTTPError(http.StatusInternalServerError, "failed to get instance")
+		}
+		// Private and missing instances look the same, so badges don't reveal instance names
+		if err != nil || !instance.Spec.PublicStatusBadge {
+			return echo.NewHTTPError(http.StatusNotFound, "badge not found")
+		}
+		status = h.badgeStatus(ctx, instance)
+		h.badges.put(name, status)
+	}
+
+	c.Response().Header().Set(echo.HeaderCacheControl, fmt.Sprintf("public, max-age=%d", int(badgeTTL.Seconds())))
+	return c.Blob(http.StatusOK, "image/svg+xml", []byte(renderBadge(name, status)))
+}
+
+// UpdateStatusBadge publishes or unpublishes an instance's status badge (admins and the
+// instance owner only)
+func (h *Handler) UpdateStatusBadge(c echo.Context) error {
+	var req apitypes.UpdateStatusBadgeRequest
+	if err := bindRequest(c, &req); err != nil {
+		return err
+	}
+
+	name := c.Param("name")
+	instance, err := h.getInstanceOrError(c, name)
+	if err != nil {
+		return err
+	}
+	if !isAdminOrOwner(GetAuthContext(c), instance) {
+		return echo.NewHTTPError(http.StatusForbidden, "only admins and the instance owner can change the status badge")
+	}
+
+	instance, err = h.patchInstance(c, name, func(instance *supacontrolv1alpha1.SupabaseInstance) error {
+		instance.Spec.PublicStatusBadge = req.Public
+		return nil
+	}, "failed to update status badge")
+	if err != nil {
+		return err
+	}
+	// Unpublishing takes effect immediately instead of after the cache expires
+	h.badges.forget(name)
+
+	h.recordAudit(c, "instance.badge.update", "instance", name, map[string]string{"public": fmt.Sprint(req.Public)})
+	return c.JSON(http.StatusOK, apitypes.GetInstanceResponse{
+		Instance: h.convertCRToAPIType(c, instance),
+	})
+}
@@ -0,0 +1,231 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+)
+
+// newBadgePod returns a pod in the given namespace with the given readiness
+func newBadgePod(namespace, name string, ready bool) *corev1.Pod {
+	status := corev1.ConditionFalse
+	if ready {
+		status = corev1.ConditionTrue
+	}
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Status: corev1.PodStatus{
+			Phase:      corev1.PodRunning,
+			Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: status}},
+		},
+	}
+}
+
+// TestGetStatusBadge tests the GetStatusBadge handler
+func TestGetStatusBadge(t *testing.T) {
+	tests := []struct {
+		name           string
+		public         bool
+		phase          supacontrolv1alpha1.SupabaseInstancePhase
+		pods           []*corev1.Pod
+		expectedStatus int
+		expectedBadge  string
+	}{
+		{
+			name:           "all pods ready",
+			public:         true,
+			phase:          supacontrolv1alpha1.PhaseRunning,
+			pods:           []*corev1.Pod{newBadgePod("supa-my-app", "kong", true), newBadgePod("supa-my-app", "db", true)},
+			expectedStatus: http.StatusOK,
+			expectedBadge:  apitypes.BadgeStatusRunning,
+		},
+		{
+			name:           "some pods not ready",
+			public:         true,
+			phase:          supacontrolv1alpha1.PhaseRunning,
+			pods:           []*corev1.Pod{newBadgePod("supa-my-app", "kong", true), newBadgePod("supa-my-app", "db", false)},
+			expectedStatus: http.StatusOK,
+			expectedBadge:  apitypes.BadgeStatusDegraded,
+		},
+		{
+			name:           "no pods ready",
+			public:         true,
+			phase:          supacontrolv1alpha1.PhaseRunning,
+			pods:           []*corev1.Pod{newBadgePod("supa-my-app", "kong", false)},
+			expectedStatus: http.StatusOK,
+			expectedBadge:  apitypes.BadgeStatusDown,
+		},
+		{
+			name:           "stopped",
+			public:         true,
+			phase:          supacontrolv1alpha1.PhaseStopped,
+			expectedStatus: http.StatusOK,
+			expectedBadge:  apitypes.BadgeStatusDown,
+		},
+		{
+			name:           "badge not published",
+			phase:          supacontrolv1alpha1.PhaseRunning,
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			instance := newOwnedInstance("my-app", "7")
+			instance.Spec.PublicStatusBadge = tt.public
+			instance.Status.Phase = tt.phase
+			clientset := fake.NewSimpleClientset()
+			for _, pod := range tt.pods {
+				if _, err := clientset.CoreV1().Pods(pod.Namespace).Create(context.Background(), pod, metav1.CreateOptions{}); err != nil {
+					t.Fatalf("failed to create pod: %v", err)
+				}
+			}
+
+			handler := NewHandler(nil, nil, newSuspensionCRClient(nil, instance), &mockK8sClient{clientset: clientset})
+			c, rec := newTestContext(http.MethodGet, "/badges/my-app/status.svg", "")
+			c.SetParamNames("name")
+			c.SetParamValues("my-app")
+
+			err := handler.GetStatusBadge(c)
+			if tt.expectedStatus != http.StatusOK {
+				assertHTTPError(t, err, tt.expectedStatus)
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if ct := rec.Header().Get(echo.HeaderContentType); ct != "image/svg+xml" {
+				t.Errorf("expected SVG content type, got %q", ct)
+			}
+			if !strings.Contains(rec.Body.String(), "my-app: "+tt.expectedBadge) {
+				t.Errorf("expected badge %q, got %s", tt.expectedBadge, rec.Body.String())
+			}
+		})
+	}
+}
+
+// TestGetStatusBadge_Cached tests that badge statuses are served from the cache until they expire
+func TestGetStatusBadge_Cached(t *testing.T) {
+	instance := newOwnedInstance("my-app", "7")
+	instance.Spec.PublicStatusBadge = true
+	instance.Status.Phase = supacontrolv1alpha1.PhaseStopped
+
+	lookups := 0
+	cr := newSuspensionCRClient(nil, instance)
+	cr.getSupabaseInstanceFunc = func(_ context.Context, _ string) (*supacontrolv1alpha1.SupabaseInstance, error) {
+		lookups++
+		return instance, nil
+	}
+	handler := NewHandler(nil, nil, cr, nil)
+	now := time.Now()
+	handler.badges.now = func() time.Time { return now }
+
+	serve := func() {
+		c, _ := newTestContext(http.MethodGet, "/badges/my-app/status.svg", "")
+		c.SetParamNames("name")
+		c.SetParamValues("my-app")
+		if err := handler.GetStatusBadge(c); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	serve()
+	serve()
+	if lookups != 1 {
+		t.Errorf("expected the second request to be cached, got %d lookups", lookups)
+	}
+
+	now = now.Add(badgeTTL + time.Second)
+	serve()
+	if lookups != 2 {
+		t.Errorf("expected the expired entry to be refreshed, got %d lookups", lookups)
+	}
+}
+
+// TestUpdateStatusBadge tests the UpdateStatusBadge handler
+func TestUpdateStatusBadge(t *testing.T) {
+	tests := []struct {
+		name           string
+		userID         int64
+		role           string
+		conflicts      int
+		expectedStatus int
+	}{
+		{name: "owner publishes", userID: 7, role: "user", expectedStatus: http.StatusOK},
+		{name: "retried after a conflicting controller write", userID: 7, role: "user", conflicts: 2, expectedStatus: http.StatusOK},
+		{name: "admin publishes", userID: 1, role: "admin", expectedStatus: http.StatusOK},
+		{name: "other user", userID: 8, role: "user", expectedStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			instance := newOwnedInstance("my-app", "7")
+			updated := false
+			cr := newSuspensionCRClient(nil, instance)
+			cr.updateSupabaseInstanceFunc = conflictFirst(tt.conflicts, func(_ context.Context, instance *supacontrolv1alpha1.SupabaseInstance) error {
+				updated = instance.Spec.PublicStatusBadge
+				return nil
+			})
+			handler := NewHandler(nil, &mockDBClient{}, cr, nil)
+			handler.badges.put("my-app", apitypes.BadgeStatusDown)
+			c, _ := newTestContext(http.MethodPut, "/api/v1/instances/my-app/badge", `{"public":true}`)
+			c.SetParamNames("name")
+			c.SetParamValues("my-app")
+			setAuthContext(c, tt.userID, "someone", tt.role)
+
+			err := handler.UpdateStatusBadge(c)
+			if tt.expectedStatus != http.StatusOK {
+				assertHTTPError(t, err, tt.expectedStatus)
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !updated {
+				t.Error("expected the badge to be published")
+			}
+			if _, ok := handler.badges.get("my-app"); ok {
+				t.Error("expected the cached status to be dropped")
+			}
+		})
+	}
+}
+
+// TestRenderBadge tests that badge labels are escaped
+func TestRenderBadge(t *testing.T) {
+	svg := renderBadge(`<a&b>`, apitypes.BadgeStatusRunning)
+	if strings.Contains(svg, "<a&b>") || !strings.Contains(svg, "&lt;a&amp;b&gt;") {
+		t.Errorf("expected the label to be escaped, got %s", svg)
+	}
+	if !strings.Contains(svg, badgeColors[apitypes.BadgeStatusRunning]) {
+		t.Error("expected the running color")
+	}
+}
+
+// TestBadgeStatus_ListError tests that a failed pod lookup degrades the badge instead of failing it
+func TestBadgeStatus_ListError(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	clientset.PrependReactor("list", "pods", func(k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, errors.New("api unavailable")
+	})
+	handler := NewHandler(nil, nil, nil, &mockK8sClient{clientset: clientset})
+	instance := newOwnedInstance("my-app", "7")
+	instance.Status.Phase = supacontrolv1alpha1.PhaseRunning
+
+	if status := handler.badgeStatus(context.Background(), instance); status != apitypes.BadgeStatusDegraded {
+		t.Errorf("expected %q, got %q", apitypes.BadgeStatusDegraded, status)
+	}
+}
@@ -0,0 +1,149 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+	"github.com/qubitquilt/supacontrol/server/internal/benchmarks"
+	"github.com/qubitquilt/supacontrol/server/internal/db"
+	"github.com/qubitquilt/supacontrol/server/internal/i18n"
+)
+
+// normalizeBenchmarkRequest applies the defaults of a benchmark request and validates it
+func normalizeBenchmarkRequest(req *apitypes.CreateBenchmarkRequest) error {
+	if req.Clients == 0 {
+		req.Clients = benchmarks.DefaultClients
+	}
+	if req.DurationSeconds == 0 {
+		req.DurationSeconds = benchmarks.DefaultDurationSeconds
+	}
+	if req.Scale == 0 {
+		req.Scale = benchmarks.DefaultScale
+	}
+
+	if req.Clients < 1 || req.Clients > benchmarks.MaxClients {
+		return echo.NewHTTPError(http.StatusBadRequest, i18n.Msg("clients must be between 1 and %d", benchmarks.MaxClients))
+	}
+	if req.DurationSeconds < benchmarks.MinDurationSeconds || req.DurationSeconds > benchmarks.MaxDurationSeconds {
+		return echo.NewHTTPError(http.StatusBadRequest, i18n.Msg("duration must be between %d and %d seconds",
+			benchmarks.MinDurationSeconds, benchmarks.MaxDurationSeconds))
+	}
+	if req.Scale < 1 || req.Scale > benchmarks.MaxScale {
+		return echo.NewHTTPError(http.StatusBadRequest, i18n.Msg("scale must be between 1 and %d", benchmarks.MaxScale))
+	}
+	return nil
+}
+
+// CreateBenchmark runs a pgbench load test against an instance's database (admins only).
+// The test data lives in a scratch database that is dropped afterwards; the results are
+// recorded by the benchmark runner once the Job finishes.
+func (h *Handler) CreateBenchmark(c echo.Context) error {
+	authCtx := GetAuthContext(c)
+
+	var req apitypes.CreateBenchmarkRequest
+	if err := bindRequest(c, &req); err != nil {
+		return err
+	}
+	if err := normalizeBenchmarkRequest(&req); err != nil {
+		return err
+	}
+
+	name := c.Param("name")
+	instance, err := h.getInstanceOrError(c, name)
+	if err != nil {
+		return err
+	}
+	if instance.Status.Phase != supacontrolv1alpha1.PhaseRunning {
+		return echo.NewHTTPError(http.StatusConflict, "benchmarks can only run against running instances")
+	}
+	if instance.Status.IsolationLevel == supacontrolv1alpha1.IsolationVCluster {
+		return echo.NewHTTPError(http.StatusConflict, "benchmarks are not supported for vcluster instances")
+	}
+
+	namespace := getInstanceNamespace(instance)
+	storageClass := ""
+	if instance.Spec.Storage != nil {
+		storageClass = instance.Spec.Storage.ClassName
+	}
+	benchmark, err := h.dbClient.CreateBenchmark(&apitypes.Benchmark{
+		InstanceName:    name,
+		Namespace:       namespace,
+		JobName:         fmt.Sprintf("supacontrol-benchmark-%d", time.Now().Unix()),
+		Clients:         req.Clients,
+		DurationSeconds: req.DurationSeconds,
+		Scale:           req.Scale,
+		StorageClass:    storageClass,
+		CreatedBy:       &authCtx.UserID,
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrBenchmarkInProgress) {
+			return echo.NewHTTPError(http.StatusConflict, "a benchmark is already running for this instance")
+		}
+		GetLogger(c).Error("Failed to create benchmark", "instance", name, "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to create benchmark")
+	}
+
+	job := benchmarks.NewJob(benchmarks.Params{
+		Name:              benchmark.JobName,
+		Namespace:         namespace,
+		Instance:          instance.Spec.ProjectName,
+		Host:              fmt.Sprintf("%s-db.%s.svc.cluster.local", getInstanceReleaseName(instance), namespace),
+		PasswordSecret:    getInstanceSecretName(instance),
+		PasswordSecretKey: "postgres-password",
+		Clients:           benchmark.Clients,
+		DurationSeconds:   benchmark.DurationSeconds,
+		Scale:             benchmark.Scale,
+	})
+	if _, err := h.k8sClient.GetClientset().BatchV1().Jobs(namespace).Create(c.Request().Context(), job, metav1.CreateOptions{}); err != nil {
+		GetLogger(c).Error("Failed to create benchmark Job", "instance", name, "error", err)
+		message := "failed to create benchmark Job"
+		benchmark.Status = apitypes.BenchmarkStatusFailed
+		benchmark.ErrorMessage = &message
+		if err := h.dbClient.FinishBenchmark(benchmark); err != nil {
+			GetLogger(c).Error("Failed to record benchmark failure", "benchmark_id", benchmark.ID, "error", err)
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to start benchmark")
+	}
+
+	h.recordAudit(c, "instance.benchmark.create", "instance", name, map[string]string{
+		"benchmark_id":     strconv.FormatInt(benchmark.ID, 10),
+		"clients":          strconv.Itoa(benchmark.Clients),
+		"duration_seconds": strconv.Itoa(benchmark.DurationSeconds),
+	})
+	return c.JSON(http.StatusAccepted, benchmark)
+}
+
+// ListInstanceBenchmarks lists the benchmarks of an instance, newest first (admins and
+// the instance owner only)
+func (h *Handler) ListInstanceBenchmarks(c echo.Context) error {
+	name := c.Param("name")
+	instance, err := h.getInstanceOrError(c, name)
+	if err != nil {
+		return err
+	}
+	if !isAdminOrOwner(GetAuthContext(c), instance) {
+		return echo.NewHTTPError(http.StatusForbidden, "only admins and the instance owner can view benchmarks")
+	}
+
+	results, err := h.dbClient.ListBenchmarks(name)
+	if err != nil {
+		GetLogger(c).Error("Failed to list benchmarks", "instance", name, "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to list benchmarks")
+	}
+	if results == nil {
+		results = []*apitypes.Benchmark{}
+	}
+
+	return c.JSON(http.StatusOK, apitypes.ListBenchmarksResponse{
+		Benchmarks: results,
+		Count:      len(results),
+	})
+}
@@ -0,0 +1,127 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+	"github.com/qubitquilt/supacontrol/server/internal/db"
+)
+
+// TestCreateBenchmark tests the CreateBenchmark handler
+func TestCreateBenchmark(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           string
+		phase          supacontrolv1alpha1.SupabaseInstancePhase
+		createErr      error
+		expectedStatus int
+		expectClients  int
+	}{
+		{name: "defaults", body: `{}`, phase: supacontrolv1alpha1.PhaseRunning, expectedStatus: http.StatusAccepted, expectClients: 10},
+		{name: "custom load", body: `{"clients":32,"duration_seconds":120,"scale":50}`, phase: supacontrolv1alpha1.PhaseRunning, expectedStatus: http.StatusAccepted, expectClients: 32},
+		{name: "too many clients", body: `{"clients":500}`, phase: supacontrolv1alpha1.PhaseRunning, expectedStatus: http.StatusBadRequest},
+		{name: "too short", body: `{"duration_seconds":5}`, phase: supacontrolv1alpha1.PhaseRunning, expectedStatus: http.StatusBadRequest},
+		{name: "stopped instance", body: `{}`, phase: supacontrolv1alpha1.PhaseStopped, expectedStatus: http.StatusConflict},
+		{name: "benchmark already running", body: `{}`, phase: supacontrolv1alpha1.PhaseRunning, createErr: db.ErrBenchmarkInProgress, expectedStatus: http.StatusConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			instance := newOwnedInstance("my-app", "7")
+			instance.Spec.ProjectName = "my-app"
+			instance.Status.Phase = tt.phase
+			instance.Status.Namespace = "supa-my-app"
+			clientset := fake.NewSimpleClientset()
+			mockDB := &mockDBClient{
+				createBenchmarkFunc: func(benchmark *apitypes.Benchmark) (*apitypes.Benchmark, error) {
+					if tt.createErr != nil {
+						return nil, tt.createErr
+					}
+					created := *benchmark
+					created.ID = 3
+					created.Status = apitypes.BenchmarkStatusRunning
+					return &created, nil
+				},
+			}
+			handler := NewHandler(nil, mockDB, newSuspensionCRClient(nil, instance), &mockK8sClient{clientset: clientset})
+			c, _ := newTestContext(http.MethodPost, "/api/v1/instances/my-app/benchmark", tt.body)
+			c.SetParamNames("name")
+			c.SetParamValues("my-app")
+			setAuthContext(c, 1, "admin", RoleAdmin)
+
+			err := handler.CreateBenchmark(c)
+			if tt.expectedStatus != http.StatusAccepted {
+				assertHTTPError(t, err, tt.expectedStatus)
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			jobs, err := clientset.BatchV1().Jobs("supa-my-app").List(context.Background(), metav1.ListOptions{})
+			if err != nil {
+				t.Fatalf("failed to list jobs: %v", err)
+			}
+			if len(jobs.Items) != 1 {
+				t.Fatalf("expected one benchmark Job, got %d", len(jobs.Items))
+			}
+			found := false
+			for _, env := range jobs.Items[0].Spec.Template.Spec.Containers[0].Env {
+				if env.Name == "CLIENTS" {
+					found = env.Value == strconv.Itoa(tt.expectClients)
+				}
+			}
+			if !found {
+				t.Errorf("expected the Job to run %d clients", tt.expectClients)
+			}
+		})
+	}
+}
+
+// TestListInstanceBenchmarks tests that only admins and the owner see an instance's benchmarks
+func TestListInstanceBenchmarks(t *testing.T) {
+	tests := []struct {
+		name           string
+		userID         int64
+		role           string
+		expectedStatus int
+	}{
+		{name: "owner", userID: 7, role: RoleUser, expectedStatus: http.StatusOK},
+		{name: "admin", userID: 1, role: RoleAdmin, expectedStatus: http.StatusOK},
+		{name: "other user", userID: 8, role: RoleUser, expectedStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockDB := &mockDBClient{
+				listBenchmarksFunc: func(instanceName string) ([]*apitypes.Benchmark, error) {
+					return []*apitypes.Benchmark{{ID: 1, InstanceName: instanceName, Status: apitypes.BenchmarkStatusSucceeded}}, nil
+				},
+			}
+			handler := NewHandler(nil, mockDB, newSuspensionCRClient(nil, newOwnedInstance("my-app", "7")), nil)
+			c, rec := newTestContext(http.MethodGet, "/api/v1/instances/my-app/benchmarks", "")
+			c.SetParamNames("name")
+			c.SetParamValues("my-app")
+			setAuthContext(c, tt.userID, "someone", tt.role)
+
+			err := handler.ListInstanceBenchmarks(c)
+			if tt.expectedStatus != http.StatusOK {
+				assertHTTPError(t, err, tt.expectedStatus)
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if rec.Code != http.StatusOK {
+				t.Errorf("expected status 200, got %d", rec.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,165 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+	"github.com/qubitquilt/supacontrol/server/controllers"
+	"github.com/qubitquilt/supacontrol/server/internal/i18n"
+	"github.com/qubitquilt/supacontrol/server/internal/storageapi"
+)
+
+// WithStorageAPI sets the client calling the storage services of instances, which enables
+// bucket management
+func WithStorageAPI(client StorageAPI) HandlerOption {
+	return func(h *Handler) {
+		h.storageAPI = client
+	}
+}
+
+// storageEndpoint returns the in-cluster URL of an instance's storage service. Network
+// isolation admits calls from the controller namespace on its port.
+func storageEndpoint(instance *supacontrolv1alpha1.SupabaseInstance) string {
+	return fmt.Sprintf("http://%s-storage.%s.svc.cluster.local:%d", getInstanceReleaseName(instance), getInstanceNamespace(instance), controllers.StorageAPIPort)
+}
+
+// bucketInstance returns the running instance an admin or the owner manages buckets of,
+// with the service-role key its storage service accepts
+func (h *Handler) bucketInstance(c echo.Context) (*supacontrolv1alpha1.SupabaseInstance, string, error) {
+	if h.storageAPI == nil {
+		return nil, "", echo.NewHTTPError(http.StatusServiceUnavailable, "storage API access is not available")
+	}
+	instance, err := h.getInstanceOrError(c, c.Param("name"))
+	if err != nil {
+		return nil, "", err
+	}
+	if !isAdminOrOwner(GetAuthContext(c), instance) {
+		return nil, "", echo.NewHTTPError(http.StatusForbidden, "only admins and the instance owner can manage storage buckets")
+	}
+	if instance.Status.Phase != supacontrolv1alpha1.PhaseRunning {
+		return nil, "", echo.NewHTTPError(http.StatusConflict, "instance storage service is not running")
+	}
+	// The storage service and credentials of vcluster instances live inside the vcluster
+	if instance.Status.IsolationLevel == supacontrolv1alpha1.IsolationVCluster {
+		return nil, "", echo.NewHTTPError(http.StatusConflict, "storage buckets cannot be managed for vcluster instances")
+	}
+
+	namespace := getInstanceNamespace(instance)
+	secret, err := h.k8sClient.GetClientset().CoreV1().Secrets(namespace).Get(c.Request().Context(), getInstanceSecretName(instance), metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, "", echo.NewHTTPError(http.StatusConflict, "instance credentials not available yet")
+		}
+		GetLogger(c).Error("Failed to get instance secret", "namespace", namespace, "error", err)
+		return nil, "", echo.NewHTTPError(http.StatusInternalServerError, "failed to get instance credentials")
+	}
+	key := string(secret.Data["service-role-key"])
+	if key == "" {
+		return nil, "", echo.NewHTTPError(http.StatusConflict, "instance credentials not available yet")
+	}
+	return instance, key, nil
+}
+
+// bucketToAPIType converts a storage API bucket to its API form
+func bucketToAPIType(bucket storageapi.Bucket) *apitypes.StorageBucket {
+	return &apitypes.StorageBucket{
+		ID:               bucket.ID,
+		Name:             bucket.Name,
+		Public:           bucket.Public,
+		FileSizeLimit:    bucket.FileSizeLimit,
+		AllowedMimeTypes: bucket.AllowedMimeTypes,
+		CreatedAt:        bucket.CreatedAt,
+		UpdatedAt:        bucket.UpdatedAt,
+	}
+}
+
+// ListBuckets lists the storage buckets of an instance (admins and the instance owner only)
+func (h *Handler) ListBuckets(c echo.Context) error {
+	instance, key, err := h.bucketInstance(c)
+	if err != nil {
+		return err
+	}
+	buckets, err := h.storageAPI.ListBuckets(c.Request().Context(), storageEndpoint(instance), key)
+	if err != nil {
+		return bucketError(c, err, "failed to list storage buckets", "")
+	}
+	result := make([]*apitypes.StorageBucket, len(buckets))
+	for i, bucket := range buckets {
+		result[i] = bucketToAPIType(bucket)
+	}
+	return c.JSON(http.StatusOK, apitypes.ListBucketsResponse{Buckets: result, Count: len(result)})
+}
+
+// CreateBucket creates a storage bucket in an instance (admins and the instance owner only)
+func (h *Handler) CreateBucket(c echo.Context) error {
+	var req apitypes.CreateBucketRequest
+	if err := bindRequest(c, &req); err != nil {
+		return err
+	}
+	instance, key, err := h.bucketInstance(c)
+	if err != nil {
+		return err
+	}
+
+	bucket := storageapi.Bucket{
+		Name:             req.Name,
+		Public:           req.Public,
+		FileSizeLimit:    req.FileSizeLimit,
+		AllowedMimeTypes: req.AllowedMimeTypes,
+	}
+	if err := h.storageAPI.CreateBucket(c.Request().Context(), storageEndpoint(instance), key, bucket); err != nil {
+		return bucketError(c, err, "failed to create storage bucket", "storage bucket already exists")
+	}
+	bucket.ID = bucket.Name
+
+	h.recordAudit(c, "instance.bucket.create", "instance", instance.Name, map[string]string{
+		"bucket": req.Name,
+		"public": fmt.Sprintf("%t", req.Public),
+	})
+	return c.JSON(http.StatusCreated, apitypes.CreateBucketResponse{Bucket: bucketToAPIType(bucket)})
+}
+
+// DeleteBucket deletes an empty storage bucket of an instance (admins and the instance
+// owner only)
+func (h *Handler) DeleteBucket(c echo.Context) error {
+	id := c.Param("bucket")
+	instance, key, err := h.bucketInstance(c)
+	if err != nil {
+		return err
+	}
+	if err := h.storageAPI.DeleteBucket(c.Request().Context(), storageEndpoint(instance), key, id); err != nil {
+		return bucketError(c, err, "failed to delete storage bucket", "storage bucket is not empty")
+	}
+
+	h.recordAudit(c, "instance.bucket.delete", "instance", instance.Name, map[string]string{"bucket": id})
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": localize(c, "storage bucket deleted successfully"),
+	})
+}
+
+// bucketError reports a failed storage API call. Missing buckets and conflicts keep their
+// status, conflict explaining the latter; other rejections carry the storage API's message,
+// and unreachable storage services are reported as a bad gateway.
+func bucketError(c echo.Context, err error, message, conflict string) error {
+	var apiErr *storageapi.Error
+	switch {
+	case errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound:
+		return echo.NewHTTPError(http.StatusNotFound, "storage bucket not found")
+	case errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusConflict && conflict != "":
+		return echo.NewHTTPError(http.StatusConflict, conflict)
+	case errors.As(err, &apiErr) && apiErr.StatusCode < http.StatusInternalServerError:
+		return echo.NewHTTPError(http.StatusBadRequest, i18n.Msg("storage API rejected the request: %s", apiErr.Message))
+	case errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded):
+		return echo.NewHTTPError(http.StatusGatewayTimeout, "instance storage service did not respond in time")
+	}
+	GetLogger(c).Error("Storage API call failed", "error", err)
+	return echo.NewHTTPError(http.StatusBadGateway, message)
+}
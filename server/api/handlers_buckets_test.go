@@ -0,0 +1,223 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"k8s.io/client-go/kubernetes/fake"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+	"github.com/qubitquilt/supacontrol/server/internal/storageapi"
+)
+
+// mockStorageAPI records the storage API calls of the bucket handlers
+type mockStorageAPI struct {
+	endpoint string
+	key      string
+	buckets  []storageapi.Bucket
+	created  *storageapi.Bucket
+	deleted  string
+	err      error
+}
+
+func (m *mockStorageAPI) ListBuckets(_ context.Context, endpoint, serviceRoleKey string) ([]storageapi.Bucket, error) {
+	m.endpoint, m.key = endpoint, serviceRoleKey
+	return m.buckets, m.err
+}
+
+func (m *mockStorageAPI) CreateBucket(_ context.Context, endpoint, serviceRoleKey string, bucket storageapi.Bucket) error {
+	m.endpoint, m.key = endpoint, serviceRoleKey
+	if m.err == nil {
+		m.created = &bucket
+	}
+	return m.err
+}
+
+func (m *mockStorageAPI) DeleteBucket(_ context.Context, endpoint, serviceRoleKey, id string) error {
+	m.endpoint, m.key = endpoint, serviceRoleKey
+	if m.err == nil {
+		m.deleted = id
+	}
+	return m.err
+}
+
+// newBucketHandler returns a handler managing the buckets of a running my-app instance
+// owned by user 7
+func newBucketHandler(storage StorageAPI, phase supacontrolv1alpha1.SupabaseInstancePhase, withSecret bool) *Handler {
+	instance := newOwnedInstance("my-app", "7")
+	instance.Status.Phase = phase
+	clientset := fake.NewSimpleClientset()
+	if withSecret {
+		clientset = fake.NewSimpleClientset(newInstanceSecret("my-app"))
+	}
+	return NewHandler(nil, &mockDBClient{}, newSuspensionCRClient(nil, instance), &mockK8sClient{clientset: clientset},
+		WithStorageAPI(storage))
+}
+
+// TestListBuckets tests access checks and the response of the ListBuckets handler
+func TestListBuckets(t *testing.T) {
+	tests := []struct {
+		name           string
+		userID         int64
+		role           string
+		phase          supacontrolv1alpha1.SupabaseInstancePhase
+		withSecret     bool
+		err            error
+		expectedStatus int
+	}{
+		{name: "owner", userID: 7, role: "user", phase: supacontrolv1alpha1.PhaseRunning, withSecret: true, expectedStatus: http.StatusOK},
+		{name: "admin", userID: 1, role: "admin", phase: supacontrolv1alpha1.PhaseRunning, withSecret: true, expectedStatus: http.StatusOK},
+		{name: "other user", userID: 8, role: "user", phase: supacontrolv1alpha1.PhaseRunning, withSecret: true, expectedStatus: http.StatusForbidden},
+		{name: "not running", userID: 7, role: "user", phase: supacontrolv1alpha1.PhaseStopped, withSecret: true, expectedStatus: http.StatusConflict},
+		{name: "no credentials", userID: 7, role: "user", phase: supacontrolv1alpha1.PhaseRunning, expectedStatus: http.StatusConflict},
+		{name: "storage unreachable", userID: 7, role: "user", phase: supacontrolv1alpha1.PhaseRunning, withSecret: true, err: errors.New("connection refused"), expectedStatus: http.StatusBadGateway},
+		{name: "storage timed out", userID: 7, role: "user", phase: supacontrolv1alpha1.PhaseRunning, withSecret: true, err: context.DeadlineExceeded, expectedStatus: http.StatusGatewayTimeout},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := &mockStorageAPI{buckets: []storageapi.Bucket{{ID: "avatars", Name: "avatars", Public: true}}, err: tt.err}
+			handler := newBucketHandler(storage, tt.phase, tt.withSecret)
+			c, rec := newTestContext(http.MethodGet, "/api/v1/instances/my-app/storage/buckets", "")
+			c.SetParamNames("name")
+			c.SetParamValues("my-app")
+			setAuthContext(c, tt.userID, "someone", tt.role)
+
+			err := handler.ListBuckets(c)
+			if tt.expectedStatus != http.StatusOK {
+				assertHTTPError(t, err, tt.expectedStatus)
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if storage.key != "service" || storage.endpoint != "http://my-app-storage.supa-my-app.svc.cluster.local:5000" {
+				t.Errorf("unexpected storage API call to %s with key %q", storage.endpoint, storage.key)
+			}
+			var resp apitypes.ListBucketsResponse
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if resp.Count != 1 || resp.Buckets[0].Name != "avatars" || !resp.Buckets[0].Public {
+				t.Errorf("unexpected response %+v", resp)
+			}
+		})
+	}
+}
+
+// TestCreateBucket tests validation and storage API errors of the CreateBucket handler
+func TestCreateBucket(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           string
+		err            error
+		expectedStatus int
+	}{
+		{name: "created", body: `{"name":"docs","public":false,"file_size_limit":1048576,"allowed_mime_types":["application/pdf"]}`, expectedStatus: http.StatusCreated},
+		{name: "missing name", body: `{"public":true}`, expectedStatus: http.StatusBadRequest},
+		{name: "negative size limit", body: `{"name":"docs","file_size_limit":-1}`, expectedStatus: http.StatusBadRequest},
+		{name: "already exists", body: `{"name":"docs"}`, err: &storageapi.Error{StatusCode: http.StatusConflict, Message: "The resource already exists"}, expectedStatus: http.StatusConflict},
+		{name: "rejected", body: `{"name":"bad/name"}`, err: &storageapi.Error{StatusCode: http.StatusBadRequest, Message: "Invalid bucket name"}, expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := &mockStorageAPI{err: tt.err}
+			handler := newBucketHandler(storage, supacontrolv1alpha1.PhaseRunning, true)
+			c, rec := newTestContext(http.MethodPost, "/api/v1/instances/my-app/storage/buckets", tt.body)
+			c.SetParamNames("name")
+			c.SetParamValues("my-app")
+			setAuthContext(c, 7, "someone", "user")
+
+			err := handler.CreateBucket(c)
+			if tt.expectedStatus != http.StatusCreated {
+				assertHTTPError(t, err, tt.expectedStatus)
+				if storage.created != nil {
+					t.Errorf("expected no bucket to be created, got %+v", storage.created)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if rec.Code != http.StatusCreated {
+				t.Errorf("expected status 201, got %d", rec.Code)
+			}
+			if storage.created == nil || storage.created.Name != "docs" || storage.created.FileSizeLimit == nil ||
+				*storage.created.FileSizeLimit != 1048576 || len(storage.created.AllowedMimeTypes) != 1 {
+				t.Errorf("unexpected bucket created: %+v", storage.created)
+			}
+		})
+	}
+}
+
+// TestDeleteBucket tests the DeleteBucket handler
+func TestDeleteBucket(t *testing.T) {
+	tests := []struct {
+		name           string
+		err            error
+		expectedStatus int
+	}{
+		{name: "deleted", expectedStatus: http.StatusOK},
+		{name: "not found", err: &storageapi.Error{StatusCode: http.StatusNotFound, Message: "Bucket not found"}, expectedStatus: http.StatusNotFound},
+		{name: "not empty", err: &storageapi.Error{StatusCode: http.StatusConflict, Message: "The bucket you tried to delete is not empty"}, expectedStatus: http.StatusConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := &mockStorageAPI{err: tt.err}
+			handler := newBucketHandler(storage, supacontrolv1alpha1.PhaseRunning, true)
+			c, _ := newTestContext(http.MethodDelete, "/api/v1/instances/my-app/storage/buckets/docs", "")
+			c.SetParamNames("name", "bucket")
+			c.SetParamValues("my-app", "docs")
+			setAuthContext(c, 7, "someone", "user")
+
+			err := handler.DeleteBucket(c)
+			if tt.expectedStatus != http.StatusOK {
+				assertHTTPError(t, err, tt.expectedStatus)
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if storage.deleted != "docs" {
+				t.Errorf("expected bucket docs to be deleted, got %q", storage.deleted)
+			}
+		})
+	}
+}
+
+// TestBucketsUnavailable tests that bucket management is disabled without a storage API client
+func TestBucketsUnavailable(t *testing.T) {
+	handler := NewHandler(nil, &mockDBClient{}, newSuspensionCRClient(nil, newOwnedInstance("my-app", "7")), nil)
+	c, _ := newTestContext(http.MethodGet, "/api/v1/instances/my-app/storage/buckets", "")
+	c.SetParamNames("name")
+	c.SetParamValues("my-app")
+	setAuthContext(c, 7, "someone", "user")
+
+	assertHTTPError(t, handler.ListBuckets(c), http.StatusServiceUnavailable)
+}
+
+// TestBuckets_VCluster tests that buckets of vcluster instances are not managed, as their
+// storage service is not reachable from the host cluster
+func TestBuckets_VCluster(t *testing.T) {
+	instance := newOwnedInstance("my-app", "7")
+	instance.Status.Phase = supacontrolv1alpha1.PhaseRunning
+	instance.Status.IsolationLevel = supacontrolv1alpha1.IsolationVCluster
+	storage := &mockStorageAPI{}
+	handler := NewHandler(nil, &mockDBClient{}, newSuspensionCRClient(nil, instance),
+		&mockK8sClient{clientset: fake.NewSimpleClientset(newInstanceSecret("my-app"))}, WithStorageAPI(storage))
+	c, _ := newTestContext(http.MethodGet, "/api/v1/instances/my-app/storage/buckets", "")
+	c.SetParamNames("name")
+	c.SetParamValues("my-app")
+	setAuthContext(c, 7, "someone", "user")
+
+	assertHTTPError(t, handler.ListBuckets(c), http.StatusConflict)
+	if storage.endpoint != "" {
+		t.Errorf("expected no storage API call, got one to %s", storage.endpoint)
+	}
+}
@@ -0,0 +1,292 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+	"github.com/qubitquilt/supacontrol/server/internal/db"
+	"github.com/qubitquilt/supacontrol/server/internal/i18n"
+	"github.com/qubitquilt/supacontrol/server/internal/k8s"
+)
+
+// maxConnections bounds how many instances may connect to one instance, matching the CRD
+const maxConnections = 20
+
+// parseConnectionID parses the :id path parameter
+func parseConnectionID(c echo.Context) (int64, error) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return 0, echo.NewHTTPError(http.StatusBadRequest, "invalid connection ID")
+	}
+	return id, nil
+}
+
+// getConnectionOrError loads a connection, mapping a missing one to 404
+func (h *Handler) getConnectionOrError(c echo.Context, id int64) (*apitypes.Connection, error) {
+	connection, err := h.dbClient.GetConnection(id)
+	if err != nil {
+		GetLogger(c).Error("Failed to get connection", "connection_id", id, "error", err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "failed to get connection")
+	}
+	if connection == nil {
+		return nil, echo.NewHTTPError(http.StatusNotFound, "connection not found")
+	}
+	return connection, nil
+}
+
+// connectionSides reports which sides of a connection the caller may act for: admins act
+// for both, owners for their own instance. An instance that no longer exists has no owner.
+func (h *Handler) connectionSides(c echo.Context, connection *apitypes.Connection) (source, target bool, err error) {
+	authCtx := GetAuthContext(c)
+	owns := func(name string) (bool, error) {
+		instance, err := h.crClient.GetSupabaseInstance(c.Request().Context(), name)
+		if errors.Is(err, k8s.ErrInstanceNotFound) {
+			return authCtx != nil && authCtx.IsAdmin(), nil
+		}
+		if err != nil {
+			GetLogger(c).Error("Failed to get instance", "instance", name, "error", err)
+			return false, echo.NewHTTPError(http.StatusInternalServerError, "failed to get instance")
+		}
+		return isAdminOrOwner(authCtx, instance), nil
+	}
+	if source, err = owns(connection.SourceInstance); err != nil {
+		return false, false, err
+	}
+	if target, err = owns(connection.TargetInstance); err != nil {
+		return false, false, err
+	}
+	return source, target, nil
+}
+
+// activateConnection allows an approved connection on its target instance, which makes
+// the controller materialize it, and marks it active
+func (h *Handler) activateConnection(c echo.Context, connection *apitypes.Connection) error {
+	_, err := h.crClient.PatchSupabaseInstance(c.Request().Context(), connection.TargetInstance, func(instance *supacontrolv1alpha1.SupabaseInstance) error {
+		if !slices.Contains(instance.Spec.AllowedConnections, connection.SourceInstance) {
+			instance.Spec.AllowedConnections = append(instance.Spec.AllowedConnections, connection.SourceInstance)
+		}
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, k8s.ErrInstanceNotFound) {
+			return newAPIError(http.StatusNotFound, apitypes.ErrorCodeInstanceNotFound, "instance not found")
+		}
+		GetLogger(c).Error("Failed to allow connection", "connection_id", connection.ID, "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to activate connection")
+	}
+	if err := h.dbClient.ActivateConnection(connection.ID); err != nil {
+		GetLogger(c).Error("Failed to activate connection", "connection_id", connection.ID, "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to activate connection")
+	}
+
+	now := time.Now()
+	connection.Status = apitypes.ConnectionStatusActive
+	connection.ActivatedAt = &now
+	h.recordAudit(c, "connection.activate", "instance", connection.TargetInstance, map[string]string{
+		"connection_id": strconv.FormatInt(connection.ID, 10),
+		"source":        connection.SourceInstance,
+	})
+	return nil
+}
+
+// ListConnections lists the connections of the caller's instances (all connections for admins)
+func (h *Handler) ListConnections(c echo.Context) error {
+	authCtx := GetAuthContext(c)
+	if authCtx == nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "not authenticated")
+	}
+
+	connections, err := h.dbClient.ListConnections()
+	if err != nil {
+		GetLogger(c).Error("Failed to list connections", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to list connections")
+	}
+
+	if !authCtx.IsAdmin() {
+		list, err := h.crClient.ListSupabaseInstances(c.Request().Context())
+		if err != nil {
+			GetLogger(c).Error("Failed to list instances", "error", err)
+			return echo.NewHTTPError(http.StatusInternalServerError, "failed to list instances")
+		}
+		owned := map[string]bool{}
+		for i := range list.Items {
+			if isAdminOrOwner(authCtx, &list.Items[i]) {
+				owned[list.Items[i].Name] = true
+			}
+		}
+		connections = slices.DeleteFunc(connections, func(connection *apitypes.Connection) bool {
+			return !owned[connection.SourceInstance] && !owned[connection.TargetInstance]
+		})
+	}
+	if connections == nil {
+		connections = []*apitypes.Connection{}
+	}
+
+	return c.JSON(http.StatusOK, apitypes.ListConnectionsResponse{
+		Connections: connections,
+		Count:       len(connections),
+	})
+}
+
+// CreateConnection requests a connection from the source to the target instance. The
+// sides the caller owns are approved right away; once the owners of both instances
+// approved it, the target allows the connection.
+func (h *Handler) CreateConnection(c echo.Context) error {
+	authCtx := GetAuthContext(c)
+	if authCtx == nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "not authenticated")
+	}
+
+	var req apitypes.CreateConnectionRequest
+	if err := bindRequest(c, &req); err != nil {
+		return err
+	}
+	req.SourceInstance = strings.TrimSpace(req.SourceInstance)
+	req.TargetInstance = strings.TrimSpace(req.TargetInstance)
+	if req.SourceInstance == req.TargetInstance {
+		return echo.NewHTTPError(http.StatusBadRequest, "an instance cannot connect to itself")
+	}
+
+	source, err := h.getInstanceOrError(c, req.SourceInstance)
+	if err != nil {
+		return err
+	}
+	target, err := h.getInstanceOrError(c, req.TargetInstance)
+	if err != nil {
+		return err
+	}
+	approveSource, approveTarget := isAdminOrOwner(authCtx, source), isAdminOrOwner(authCtx, target)
+	if !approveSource && !approveTarget {
+		return echo.NewHTTPError(http.StatusForbidden, "only the owners of the connected instances can manage connections")
+	}
+	if target.Status.IsolationLevel == supacontrolv1alpha1.IsolationVCluster {
+		return echo.NewHTTPError(http.StatusConflict, "connections to vcluster instances are not supported")
+	}
+	if len(target.Spec.AllowedConnections) >= maxConnections {
+		return echo.NewHTTPError(http.StatusBadRequest, i18n.Msg("at most %d instances can connect to an instance", maxConnections))
+	}
+
+	connection, err := h.dbClient.CreateConnection(req.SourceInstance, req.TargetInstance, authCtx.UserID, approveSource, approveTarget)
+	if err != nil {
+		if errors.Is(err, db.ErrConnectionExists) {
+			return echo.NewHTTPError(http.StatusConflict, "a connection between these instances already exists")
+		}
+		GetLogger(c).Error("Failed to create connection", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to create connection")
+	}
+
+	h.recordAudit(c, "connection.create", "instance", connection.TargetInstance, map[string]string{
+		"connection_id": strconv.FormatInt(connection.ID, 10),
+		"source":        connection.SourceInstance,
+	})
+
+	if connection.Approved() {
+		if err := h.activateConnection(c, connection); err != nil {
+			return err
+		}
+	}
+	return c.JSON(http.StatusCreated, connection)
+}
+
+// ApproveConnection approves a pending connection on behalf of the instances the caller owns
+func (h *Handler) ApproveConnection(c echo.Context) error {
+	id, err := parseConnectionID(c)
+	if err != nil {
+		return err
+	}
+	connection, err := h.getConnectionOrError(c, id)
+	if err != nil {
+		return err
+	}
+	if connection.Status != apitypes.ConnectionStatusPending {
+		return echo.NewHTTPError(http.StatusConflict, "connection is no longer pending")
+	}
+
+	approveSource, approveTarget, err := h.connectionSides(c, connection)
+	if err != nil {
+		return err
+	}
+	if !approveSource && !approveTarget {
+		return echo.NewHTTPError(http.StatusForbidden, "only the owners of the connected instances can manage connections")
+	}
+
+	connection, err = h.dbClient.ApproveConnection(id, approveSource, approveTarget)
+	if err != nil {
+		if errors.Is(err, db.ErrConnectionNotPending) {
+			return echo.NewHTTPError(http.StatusConflict, "connection is no longer pending")
+		}
+		GetLogger(c).Error("Failed to approve connection", "connection_id", id, "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to approve connection")
+	}
+
+	h.recordAudit(c, "connection.approve", "instance", connection.TargetInstance, map[string]string{
+		"connection_id": strconv.FormatInt(id, 10),
+		"source":        connection.SourceInstance,
+	})
+
+	if connection.Approved() {
+		if err := h.activateConnection(c, connection); err != nil {
+			return err
+		}
+	}
+	return c.JSON(http.StatusOK, connection)
+}
+
+// RevokeConnection rejects a pending connection or revokes an active one on behalf of
+// either instance's owner. The controller deletes the connection's NetworkPolicy and
+// credentials and disables its database role.
+func (h *Handler) RevokeConnection(c echo.Context) error {
+	id, err := parseConnectionID(c)
+	if err != nil {
+		return err
+	}
+	connection, err := h.getConnectionOrError(c, id)
+	if err != nil {
+		return err
+	}
+	if connection.Status == apitypes.ConnectionStatusRevoked {
+		return echo.NewHTTPError(http.StatusConflict, "connection is already revoked")
+	}
+
+	source, target, err := h.connectionSides(c, connection)
+	if err != nil {
+		return err
+	}
+	if !source && !target {
+		return echo.NewHTTPError(http.StatusForbidden, "only the owners of the connected instances can manage connections")
+	}
+
+	if connection.Status == apitypes.ConnectionStatusActive {
+		_, err := h.crClient.PatchSupabaseInstance(c.Request().Context(), connection.TargetInstance, func(instance *supacontrolv1alpha1.SupabaseInstance) error {
+			instance.Spec.AllowedConnections = slices.DeleteFunc(instance.Spec.AllowedConnections, func(name string) bool {
+				return name == connection.SourceInstance
+			})
+			return nil
+		})
+		if err != nil && !errors.Is(err, k8s.ErrInstanceNotFound) {
+			GetLogger(c).Error("Failed to remove connection", "connection_id", id, "error", err)
+			return echo.NewHTTPError(http.StatusInternalServerError, "failed to revoke connection")
+		}
+	}
+	if err := h.dbClient.RevokeConnection(id); err != nil {
+		GetLogger(c).Error("Failed to revoke connection", "connection_id", id, "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to revoke connection")
+	}
+
+	h.recordAudit(c, "connection.revoke", "instance", connection.TargetInstance, map[string]string{
+		"connection_id": strconv.FormatInt(id, 10),
+		"source":        connection.SourceInstance,
+	})
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": localize(c, "Connection revoked successfully"),
+	})
+}
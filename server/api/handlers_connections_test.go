@@ -0,0 +1,261 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+	"github.com/qubitquilt/supacontrol/server/internal/db"
+)
+
+// newConnectionCRClient serves an analytics instance owned by user 7 and an app instance
+// owned by user 8, recording the connections allowed on updated instances
+func newConnectionCRClient(allowed map[string][]string) (*mockCRClient, *supacontrolv1alpha1.SupabaseInstance) {
+	target := newOwnedInstance("app", "8")
+	cr := newSuspensionCRClient(nil, newOwnedInstance("analytics", "7"), target)
+	cr.updateSupabaseInstanceFunc = func(_ context.Context, instance *supacontrolv1alpha1.SupabaseInstance) error {
+		allowed[instance.Name] = instance.Spec.AllowedConnections
+		return nil
+	}
+	return cr, target
+}
+
+// TestCreateConnection tests the CreateConnection handler
+func TestCreateConnection(t *testing.T) {
+	tests := []struct {
+		name            string
+		userID          int64
+		role            string
+		body            string
+		isolation       supacontrolv1alpha1.IsolationLevel
+		createErr       error
+		expectedStatus  int
+		expectedApprove [2]bool
+		expectedAllowed []string
+	}{
+		{name: "source owner requests", userID: 7, role: RoleUser, body: `{"source_instance":"analytics","target_instance":"app"}`, expectedStatus: http.StatusCreated, expectedApprove: [2]bool{true, false}},
+		{name: "target owner requests", userID: 8, role: RoleUser, body: `{"source_instance":"analytics","target_instance":"app"}`, expectedStatus: http.StatusCreated, expectedApprove: [2]bool{false, true}},
+		{name: "admin approves both sides", userID: 1, role: RoleAdmin, body: `{"source_instance":"analytics","target_instance":"app"}`, expectedStatus: http.StatusCreated, expectedApprove: [2]bool{true, true}, expectedAllowed: []string{"analytics"}},
+		{name: "missing target", userID: 7, role: RoleUser, body: `{"source_instance":"analytics"}`, expectedStatus: http.StatusBadRequest},
+		{name: "self connection", userID: 7, role: RoleUser, body: `{"source_instance":"analytics","target_instance":"analytics"}`, expectedStatus: http.StatusBadRequest},
+		{name: "unknown instance", userID: 7, role: RoleUser, body: `{"source_instance":"analytics","target_instance":"blog"}`, expectedStatus: http.StatusNotFound},
+		{name: "other user", userID: 9, role: RoleUser, body: `{"source_instance":"analytics","target_instance":"app"}`, expectedStatus: http.StatusForbidden},
+		{name: "vcluster target", userID: 7, role: RoleUser, body: `{"source_instance":"analytics","target_instance":"app"}`, isolation: supacontrolv1alpha1.IsolationVCluster, expectedStatus: http.StatusConflict},
+		{name: "already requested", userID: 7, role: RoleUser, body: `{"source_instance":"analytics","target_instance":"app"}`, createErr: db.ErrConnectionExists, expectedStatus: http.StatusConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			allowed := map[string][]string{}
+			cr, target := newConnectionCRClient(allowed)
+			target.Status.IsolationLevel = tt.isolation
+			var approve [2]bool
+			var activated bool
+			dbClient := &mockDBClient{
+				createConnectionFunc: func(source, target string, requestedBy int64, approveSource, approveTarget bool) (*apitypes.Connection, error) {
+					if tt.createErr != nil {
+						return nil, tt.createErr
+					}
+					approve = [2]bool{approveSource, approveTarget}
+					connection := &apitypes.Connection{ID: 3, SourceInstance: source, TargetInstance: target, Status: apitypes.ConnectionStatusPending}
+					now := time.Now()
+					if approveSource {
+						connection.SourceApprovedAt = &now
+					}
+					if approveTarget {
+						connection.TargetApprovedAt = &now
+					}
+					return connection, nil
+				},
+				activateConnectionFunc: func(id int64) error {
+					activated = true
+					return nil
+				},
+			}
+			handler := NewHandler(nil, dbClient, cr, nil)
+			c, rec := newTestContext(http.MethodPost, "/api/v1/connections", tt.body)
+			setAuthContext(c, tt.userID, "someone", tt.role)
+
+			err := handler.CreateConnection(c)
+			if tt.expectedStatus != http.StatusCreated {
+				assertHTTPError(t, err, tt.expectedStatus)
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if approve != tt.expectedApprove {
+				t.Errorf("expected approvals %v, got %v", tt.expectedApprove, approve)
+			}
+			if !reflect.DeepEqual(allowed["app"], tt.expectedAllowed) {
+				t.Errorf("expected allowed connections %v, got %v", tt.expectedAllowed, allowed["app"])
+			}
+
+			var connection apitypes.Connection
+			if err := json.Unmarshal(rec.Body.Bytes(), &connection); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			expectedStatus := apitypes.ConnectionStatusPending
+			if tt.expectedAllowed != nil {
+				expectedStatus = apitypes.ConnectionStatusActive
+			}
+			if connection.Status != expectedStatus || activated != (tt.expectedAllowed != nil) {
+				t.Errorf("expected status %q, got %q (activated %v)", expectedStatus, connection.Status, activated)
+			}
+		})
+	}
+}
+
+// TestApproveConnection tests the ApproveConnection handler
+func TestApproveConnection(t *testing.T) {
+	tests := []struct {
+		name            string
+		userID          int64
+		status          string
+		expectedStatus  int
+		expectedAllowed []string
+	}{
+		{name: "target owner completes approval", userID: 8, status: apitypes.ConnectionStatusPending, expectedStatus: http.StatusOK, expectedAllowed: []string{"analytics"}},
+		{name: "source owner approves again", userID: 7, status: apitypes.ConnectionStatusPending, expectedStatus: http.StatusOK},
+		{name: "other user", userID: 9, status: apitypes.ConnectionStatusPending, expectedStatus: http.StatusForbidden},
+		{name: "already active", userID: 8, status: apitypes.ConnectionStatusActive, expectedStatus: http.StatusConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			allowed := map[string][]string{}
+			cr, _ := newConnectionCRClient(allowed)
+			approvedAt := time.Now()
+			connection := &apitypes.Connection{ID: 3, SourceInstance: "analytics", TargetInstance: "app", Status: tt.status, SourceApprovedAt: &approvedAt}
+			dbClient := &mockDBClient{
+				getConnectionFunc: func(id int64) (*apitypes.Connection, error) {
+					return connection, nil
+				},
+				approveConnectionFunc: func(id int64, approveSource, approveTarget bool) (*apitypes.Connection, error) {
+					approved := *connection
+					if approveTarget {
+						approved.TargetApprovedAt = &approvedAt
+					}
+					return &approved, nil
+				},
+				activateConnectionFunc: func(id int64) error { return nil },
+			}
+			handler := NewHandler(nil, dbClient, cr, nil)
+			c, _ := newTestContext(http.MethodPost, "/api/v1/connections/3/approve", "")
+			c.SetParamNames("id")
+			c.SetParamValues("3")
+			setAuthContext(c, tt.userID, "someone", RoleUser)
+
+			err := handler.ApproveConnection(c)
+			if tt.expectedStatus != http.StatusOK {
+				assertHTTPError(t, err, tt.expectedStatus)
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(allowed["app"], tt.expectedAllowed) {
+				t.Errorf("expected allowed connections %v, got %v", tt.expectedAllowed, allowed["app"])
+			}
+		})
+	}
+}
+
+// TestRevokeConnection tests the RevokeConnection handler
+func TestRevokeConnection(t *testing.T) {
+	tests := []struct {
+		name            string
+		userID          int64
+		status          string
+		expectedStatus  int
+		expectedAllowed []string
+		expectPatched   bool
+	}{
+		{name: "source owner revokes active connection", userID: 7, status: apitypes.ConnectionStatusActive, expectedStatus: http.StatusOK, expectedAllowed: []string{"reports"}, expectPatched: true},
+		{name: "target owner rejects pending connection", userID: 8, status: apitypes.ConnectionStatusPending, expectedStatus: http.StatusOK},
+		{name: "other user", userID: 9, status: apitypes.ConnectionStatusActive, expectedStatus: http.StatusForbidden},
+		{name: "already revoked", userID: 7, status: apitypes.ConnectionStatusRevoked, expectedStatus: http.StatusConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			allowed := map[string][]string{}
+			cr, target := newConnectionCRClient(allowed)
+			target.Spec.AllowedConnections = []string{"analytics", "reports"}
+			var revoked bool
+			dbClient := &mockDBClient{
+				getConnectionFunc: func(id int64) (*apitypes.Connection, error) {
+					return &apitypes.Connection{ID: id, SourceInstance: "analytics", TargetInstance: "app", Status: tt.status}, nil
+				},
+				revokeConnectionFunc: func(id int64) error {
+					revoked = true
+					return nil
+				},
+			}
+			handler := NewHandler(nil, dbClient, cr, nil)
+			c, _ := newTestContext(http.MethodDelete, "/api/v1/connections/3", "")
+			c.SetParamNames("id")
+			c.SetParamValues("3")
+			setAuthContext(c, tt.userID, "someone", RoleUser)
+
+			err := handler.RevokeConnection(c)
+			if tt.expectedStatus != http.StatusOK {
+				assertHTTPError(t, err, tt.expectedStatus)
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !revoked {
+				t.Error("expected the connection to be revoked")
+			}
+			patched, ok := allowed["app"]
+			if ok != tt.expectPatched || !reflect.DeepEqual(patched, tt.expectedAllowed) {
+				t.Errorf("expected allowed connections %v, got %v", tt.expectedAllowed, patched)
+			}
+		})
+	}
+}
+
+// TestListConnections tests that users only see connections of their own instances
+func TestListConnections(t *testing.T) {
+	cr, _ := newConnectionCRClient(map[string][]string{})
+	dbClient := &mockDBClient{
+		listConnectionsFunc: func() ([]*apitypes.Connection, error) {
+			return []*apitypes.Connection{
+				{ID: 1, SourceInstance: "analytics", TargetInstance: "app"},
+				{ID: 2, SourceInstance: "blog", TargetInstance: "shop"},
+			}, nil
+		},
+	}
+	handler := NewHandler(nil, dbClient, cr, nil)
+
+	for _, tc := range []struct {
+		userID        int64
+		role          string
+		expectedCount int
+	}{
+		{userID: 7, role: RoleUser, expectedCount: 1},
+		{userID: 9, role: RoleUser, expectedCount: 0},
+		{userID: 1, role: RoleAdmin, expectedCount: 2},
+	} {
+		c, rec := newTestContext(http.MethodGet, "/api/v1/connections", "")
+		setAuthContext(c, tc.userID, "someone", tc.role)
+
+		if err := handler.ListConnections(c); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		var resp apitypes.ListConnectionsResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if resp.Count != tc.expectedCount || len(resp.Connections) != tc.expectedCount {
+			t.Errorf("user %d: expected %d connections, got %d", tc.userID, tc.expectedCount, resp.Count)
+		}
+	}
+}
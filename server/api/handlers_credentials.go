@@ -0,0 +1,128 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/labstack/echo/v4"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+	"github.com/qubitquilt/supacontrol/server/controllers"
+	"github.com/qubitquilt/supacontrol/server/internal/k8s"
+)
+
+const (
+	// postgresPort is the port exposed by the instance database service
+	postgresPort = 5432
+
+	// postgresUser is the superuser created by the Supabase chart
+	postgresUser = "postgres"
+
+	// postgresDatabase is the default database created by the Supabase chart
+	postgresDatabase = "postgres"
+)
+
+// getInstanceSecretName returns the name of the Secret created by the provisioning Job
+func getInstanceSecretName(instance *supacontrolv1alpha1.SupabaseInstance) string {
+	return controllers.InstanceSecretName(instance.Spec.ProjectName)
+}
+
+// getInstanceReleaseName returns the Helm release name for an instance
+func getInstanceReleaseName(instance *supacontrolv1alpha1.SupabaseInstance) string {
+	if instance.Status.HelmReleaseName != "" {
+		return instance.Status.HelmReleaseName
+	}
+	return instance.Spec.ProjectName
+}
+
+// GetInstanceCredentials returns connection strings and keys for an instance.
+// Only admins and the instance owner may read credentials, and every read is audited.
+func (h *Handler) GetInstanceCredentials(c echo.Context) error {
+	authCtx := GetAuthContext(c)
+	if authCtx == nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "not authenticated")
+	}
+
+	name := c.Param("name")
+	ctx := c.Request().Context()
+
+	instance, err := h.crClient.GetSupabaseInstance(ctx, name)
+	if err != nil {
+		if errors.Is(err, k8s.ErrInstanceNotFound) {
+			return newAPIError(http.StatusNotFound, apitypes.ErrorCodeInstanceNotFound, "instance not found")
+		}
+		GetLogger(c).Error("Failed to get instance", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to get instance")
+	}
+
+	if !isAdminOrOwner(authCtx, instance) {
+		return echo.NewHTTPError(http.StatusForbidden, "only admins and the instance owner can view credentials")
+	}
+
+	namespace := getInstanceNamespace(instance)
+	secret, err := h.k8sClient.GetClientset().CoreV1().Secrets(namespace).Get(ctx, getInstanceSecretName(instance), metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return echo.NewHTTPError(http.StatusNotFound, "instance credentials not available yet")
+		}
+		GetLogger(c).Error("Failed to get instance secret", "namespace", namespace, "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to get instance credentials")
+	}
+
+	// Record the access before disclosing anything; refuse to serve unaudited reads
+	if err := h.dbClient.CreateAuditLog(authCtx.UserID, "instance.credentials.read", "instance", name, map[string]string{
+		"username": authCtx.Username,
+		"api_key":  fmt.Sprintf("%t", authCtx.IsAPIKey),
+	}); err != nil {
+		GetLogger(c).Error("Failed to record credentials access", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to record audit log")
+	}
+
+	password := string(secret.Data["postgres-password"])
+	host := fmt.Sprintf("%s-db.%s.svc.cluster.local", getInstanceReleaseName(instance), namespace)
+	databaseURL := url.URL{
+		Scheme: "postgresql",
+		User:   url.UserPassword(postgresUser, password),
+		Host:   fmt.Sprintf("%s:%d", host, postgresPort),
+		Path:   "/" + postgresDatabase,
+	}
+
+	credentials := &apitypes.InstanceCredentials{
+		APIURL:           instance.Status.APIURL,
+		DatabaseHost:     host,
+		DatabasePort:     postgresPort,
+		DatabaseName:     postgresDatabase,
+		DatabaseUser:     postgresUser,
+		DatabasePassword: password,
+		DatabaseURL:      databaseURL.String(),
+		AnonKey:          string(secret.Data["anon-key"]),
+		ServiceRoleKey:   string(secret.Data["service-role-key"]),
+	}
+
+	// Instances with a connection pooler also get the pooled connection string
+	if controllers.HasConnectionPooler(instance) {
+		poolerHost := fmt.Sprintf("%s.%s.svc.cluster.local", controllers.PoolerServiceName(instance.Spec.ProjectName), namespace)
+		pooledURL := databaseURL
+		pooledURL.Host = fmt.Sprintf("%s:%d", poolerHost, controllers.PoolerPort)
+		credentials.PooledDatabaseHost = poolerHost
+		credentials.PooledDatabasePort = controllers.PoolerPort
+		credentials.PooledDatabaseURL = pooledURL.String()
+	}
+
+	// Instances with read replicas also get the read-only connection string
+	if controllers.ReadReplicaCount(instance) > 0 {
+		readHost := fmt.Sprintf("%s.%s.svc.cluster.local", controllers.ReadReplicaServiceName(instance.Spec.ProjectName), namespace)
+		readURL := databaseURL
+		readURL.Host = fmt.Sprintf("%s:%d", readHost, postgresPort)
+		credentials.ReadOnlyDatabaseHost = readHost
+		credentials.ReadOnlyDatabasePort = postgresPort
+		credentials.ReadOnlyDatabaseURL = readURL.String()
+	}
+
+	return c.JSON(http.StatusOK, apitypes.GetInstanceCredentialsResponse{Credentials: credentials})
+}
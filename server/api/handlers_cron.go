@@ -0,0 +1,210 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+	"github.com/qubitquilt/supacontrol/server/internal/i18n"
+	"github.com/qubitquilt/supacontrol/server/internal/k8s"
+	"github.com/qubitquilt/supacontrol/server/internal/pgcron"
+)
+
+// databasePodSelector selects the primary database pod of the Supabase chart, which
+// read replicas are not labeled as
+const databasePodSelector = "app.kubernetes.io/name=supabase-db"
+
+// WithPodExecutor sets the executor running psql in instance databases, which enables
+// cron job management
+func WithPodExecutor(executor PodExecutor) HandlerOption {
+	return func(h *Handler) {
+		h.podExecutor = executor
+	}
+}
+
+// cronInstance returns the instance an admin or the owner manages cron jobs of
+func (h *Handler) cronInstance(c echo.Context) (*supacontrolv1alpha1.SupabaseInstance, error) {
+	if h.podExecutor == nil {
+		return nil, echo.NewHTTPError(http.StatusServiceUnavailable, "database access is not available")
+	}
+	instance, err := h.getInstanceOrError(c, c.Param("name"))
+	if err != nil {
+		return nil, err
+	}
+	if !isAdminOrOwner(GetAuthContext(c), instance) {
+		return nil, echo.NewHTTPError(http.StatusForbidden, "only admins and the instance owner can manage cron jobs")
+	}
+	return instance, nil
+}
+
+// runInDatabase runs a psql invocation in the instance's primary database container
+func (h *Handler) runInDatabase(c echo.Context, instance *supacontrolv1alpha1.SupabaseInstance, invocation pgcron.Invocation) (string, error) {
+	namespace, pod, container, err := h.databasePod(c, instance)
+	if err != nil {
+		return "", err
+	}
+	return h.podExecutor.Exec(c.Request().Context(), namespace, pod, container, invocation.Command, invocation.Script)
+}
+
+// databasePod returns the namespace, name and Postgres container of the instance's ready
+// primary database pod
+func (h *Handler) databasePod(c echo.Context, instance *supacontrolv1alpha1.SupabaseInstance) (string, string, string, error) {
+	namespace := getInstanceNamespace(instance)
+	pods, err := h.k8sClient.GetClientset().CoreV1().Pods(namespace).List(c.Request().Context(), metav1.ListOptions{
+		LabelSelector: databasePodSelector + ",app.kubernetes.io/instance=" + getInstanceReleaseName(instance),
+	})
+	if err != nil {
+		GetLogger(c).Error("Failed to list database pods", "namespace", namespace, "error", err)
+		return "", "", "", echo.NewHTTPError(http.StatusInternalServerError, "failed to reach the instance database")
+	}
+	pod, container := readyDatabaseContainer(pods.Items)
+	if pod == "" {
+		return "", "", "", echo.NewHTTPError(http.StatusConflict, "instance database is not running")
+	}
+	return namespace, pod, container, nil
+}
+
+// readyDatabaseContainer returns a ready database pod and its Postgres container
+func readyDatabaseContainer(pods []corev1.Pod) (string, string) {
+	for _, pod := range pods {
+		if pod.Status.Phase != corev1.PodRunning || pod.DeletionTimestamp != nil {
+			continue
+		}
+		ready := false
+		for _, condition := range pod.Status.Conditions {
+			if condition.Type == corev1.PodReady && condition.Status == corev1.ConditionTrue {
+				ready = true
+			}
+		}
+		if !ready {
+			continue
+		}
+		for _, container := range pod.Spec.Containers {
+			if component, ok := k8s.ComponentForImage(container.Image); ok && component == apitypes.ComponentPostgres {
+				return pod.Name, container.Name
+			}
+		}
+	}
+	return "", ""
+}
+
+// ListCronJobs lists the pg_cron jobs of an instance database (admins and the instance
+// owner only)
+func (h *Handler) ListCronJobs(c echo.Context) error {
+	instance, err := h.cronInstance(c)
+	if err != nil {
+		return err
+	}
+	output, err := h.runInDatabase(c, instance, pgcron.List())
+	if err != nil {
+		return cronError(c, err, "failed to list cron jobs")
+	}
+	enabled, jobs, err := pgcron.ParseList(output)
+	if err != nil {
+		GetLogger(c).Error("Failed to parse cron jobs", "instance", instance.Name, "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to list cron jobs")
+	}
+	return c.JSON(http.StatusOK, apitypes.ListCronJobsResponse{Enabled: enabled, Jobs: jobs, Count: len(jobs)})
+}
+
+// CreateCronJob schedules a pg_cron job in an instance database, installing the extension
+// first if needed (admins and the instance owner only)
+func (h *Handler) CreateCronJob(c echo.Context) error {
+	var req apitypes.CreateCronJobRequest
+	if err := bindRequest(c, &req); err != nil {
+		return err
+	}
+	if !pgcron.ValidName(req.Name) {
+		return echo.NewHTTPError(http.StatusBadRequest, "job name must be up to 63 lowercase letters, digits, hyphens and underscores")
+	}
+	if !pgcron.ValidSchedule(req.Schedule) {
+		return echo.NewHTTPError(http.StatusBadRequest, "schedule must be five cron fields or an interval of 1-59 seconds")
+	}
+	if strings.TrimSpace(req.Command) == "" || strings.ContainsRune(req.Command, 0) {
+		return echo.NewHTTPError(http.StatusBadRequest, "command is required")
+	}
+	if len(req.Command) > apitypes.MaxCronCommandLength {
+		return echo.NewHTTPError(http.StatusBadRequest, i18n.Msg("command must be at most %d bytes", apitypes.MaxCronCommandLength))
+	}
+
+	instance, err := h.cronInstance(c)
+	if err != nil {
+		return err
+	}
+	output, err := h.runInDatabase(c, instance, pgcron.Schedule(req))
+	if err != nil {
+		return cronError(c, err, "failed to create cron job")
+	}
+	id, err := pgcron.ParseSchedule(output)
+	if errors.Is(err, pgcron.ErrJobExists) {
+		return echo.NewHTTPError(http.StatusConflict, "cron job already exists")
+	}
+	if err != nil {
+		GetLogger(c).Error("Failed to parse scheduled cron job", "instance", instance.Name, "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to create cron job")
+	}
+
+	h.recordAudit(c, "instance.cron_job.create", "instance", instance.Name, map[string]string{
+		"job":      req.Name,
+		"schedule": req.Schedule,
+	})
+	return c.JSON(http.StatusCreated, apitypes.CreateCronJobResponse{Job: &apitypes.CronJob{
+		ID:       id,
+		Name:     req.Name,
+		Schedule: req.Schedule,
+		Command:  req.Command,
+		Active:   true,
+	}})
+}
+
+// DeleteCronJob removes a pg_cron job from an instance database (admins and the instance
+// owner only)
+func (h *Handler) DeleteCronJob(c echo.Context) error {
+	job := c.Param("job")
+	if !pgcron.ValidName(job) {
+		return echo.NewHTTPError(http.StatusNotFound, "cron job not found")
+	}
+
+	instance, err := h.cronInstance(c)
+	if err != nil {
+		return err
+	}
+	output, err := h.runInDatabase(c, instance, pgcron.Unschedule(job))
+	if err != nil {
+		return cronError(c, err, "failed to delete cron job")
+	}
+	removed, err := pgcron.ParseUnschedule(output)
+	if err != nil {
+		GetLogger(c).Error("Failed to parse unscheduled cron job", "instance", instance.Name, "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to delete cron job")
+	}
+	if !removed {
+		return echo.NewHTTPError(http.StatusNotFound, "cron job not found")
+	}
+
+	h.recordAudit(c, "instance.cron_job.delete", "instance", instance.Name, map[string]string{"job": job})
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": localize(c, "cron job deleted successfully"),
+	})
+}
+
+// cronError passes HTTP errors through and reports failures of psql, which carry the
+// database's error message, as a bad gateway
+func cronError(c echo.Context, err error, message string) error {
+	var httpErr *echo.HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return echo.NewHTTPError(http.StatusGatewayTimeout, "instance database did not respond in time")
+	}
+	GetLogger(c).Error("psql failed in instance database", "error", err)
+	return echo.NewHTTPError(http.StatusBadGateway, message)
+}
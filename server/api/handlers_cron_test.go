@@ -0,0 +1,189 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// newDatabasePod returns the ready primary database pod of the my-app instance
+func newDatabasePod() *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "my-app-supabase-db-0",
+			Namespace: "supa-my-app",
+			Labels: map[string]string{
+				"app.kubernetes.io/name":     "supabase-db",
+				"app.kubernetes.io/instance": "my-app",
+			},
+		},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{
+				{Name: "exporter", Image: "prometheuscommunity/postgres-exporter:v0.15.0"},
+				{Name: "supabase-db", Image: "supabase/postgres:15.1.0.147"},
+			},
+		},
+		Status: corev1.PodStatus{
+			Phase:      corev1.PodRunning,
+			Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}},
+		},
+	}
+}
+
+// newCronHandler returns a handler for the my-app instance owned by user 7, whose
+// database runs psql through exec
+func newCronHandler(pod *corev1.Pod, exec func(ctx context.Context, namespace, pod, container string, command []string, stdin string) (string, error)) *Handler {
+	clientset := fake.NewSimpleClientset()
+	if pod != nil {
+		clientset = fake.NewSimpleClientset(pod)
+	}
+	return NewHandler(nil, &mockDBClient{}, newSuspensionCRClient(nil, newOwnedInstance("my-app", "7")),
+		&mockK8sClient{clientset: clientset}, WithPodExecutor(&mockPodExecutor{execFunc: exec}))
+}
+
+// TestListCronJobs tests the ListCronJobs handler
+func TestListCronJobs(t *testing.T) {
+	var container string
+	handler := newCronHandler(newDatabasePod(), func(_ context.Context, namespace, pod, c string, command []string, _ string) (string, error) {
+		if namespace != "supa-my-app" || pod != "my-app-supabase-db-0" || command[0] != "psql" {
+			t.Errorf("unexpected exec of %v in %s/%s", command, namespace, pod)
+		}
+		container = c
+		return `[{"id":1,"name":"cleanup","schedule":"0 3 * * *","command":"VACUUM","active":true,"last_run_status":"succeeded","last_run_at":"2026-01-02T03:00:00+00:00"}]` + "\n", nil
+	})
+	c, rec := newTestContext(http.MethodGet, "/api/v1/instances/my-app/database/cron-jobs", "")
+	c.SetParamNames("name")
+	c.SetParamValues("my-app")
+	setAuthContext(c, 7, "owner", RoleUser)
+
+	if err := handler.ListCronJobs(c); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if container != "supabase-db" {
+		t.Errorf("expected psql to run in the postgres container, got %q", container)
+	}
+	var resp apitypes.ListCronJobsResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if !resp.Enabled || resp.Count != 1 || resp.Jobs[0].Name != "cleanup" || resp.Jobs[0].LastRunStatus != "succeeded" {
+		t.Errorf("unexpected response %+v", resp)
+	}
+}
+
+// TestCreateCronJob tests the CreateCronJob handler
+func TestCreateCronJob(t *testing.T) {
+	tests := []struct {
+		name           string
+		userID         int64
+		role           string
+		body           string
+		pod            *corev1.Pod
+		output         string
+		execErr        error
+		expectedStatus int
+	}{
+		{name: "owner schedules", userID: 7, role: RoleUser, body: `{"name":"cleanup","schedule":"0 3 * * *","command":"VACUUM"}`, pod: newDatabasePod(), output: "12\n", expectedStatus: http.StatusCreated},
+		{name: "admin schedules", userID: 1, role: RoleAdmin, body: `{"name":"ping","schedule":"30 seconds","command":"SELECT 1"}`, pod: newDatabasePod(), output: "13\n", expectedStatus: http.StatusCreated},
+		{name: "invalid name", userID: 7, role: RoleUser, body: `{"name":"Clean Up","schedule":"0 3 * * *","command":"VACUUM"}`, pod: newDatabasePod(), expectedStatus: http.StatusBadRequest},
+		{name: "invalid schedule", userID: 7, role: RoleUser, body: `{"name":"cleanup","schedule":"@daily","command":"VACUUM"}`, pod: newDatabasePod(), expectedStatus: http.StatusBadRequest},
+		{name: "missing command", userID: 7, role: RoleUser, body: `{"name":"cleanup","schedule":"0 3 * * *","command":" "}`, pod: newDatabasePod(), expectedStatus: http.StatusBadRequest},
+		{name: "command too long", userID: 7, role: RoleUser, body: `{"name":"cleanup","schedule":"0 3 * * *","command":"` + strings.Repeat("x", apitypes.MaxCronCommandLength+1) + `"}`, pod: newDatabasePod(), expectedStatus: http.StatusBadRequest},
+		{name: "other user", userID: 8, role: RoleUser, body: `{"name":"cleanup","schedule":"0 3 * * *","command":"VACUUM"}`, pod: newDatabasePod(), expectedStatus: http.StatusForbidden},
+		{name: "database not running", userID: 7, role: RoleUser, body: `{"name":"cleanup","schedule":"0 3 * * *","command":"VACUUM"}`, expectedStatus: http.StatusConflict},
+		{name: "job exists", userID: 7, role: RoleUser, body: `{"name":"cleanup","schedule":"0 3 * * *","command":"VACUUM"}`, pod: newDatabasePod(), output: "exists\n", expectedStatus: http.StatusConflict},
+		{name: "database rejects command", userID: 7, role: RoleUser, body: `{"name":"cleanup","schedule":"0 3 * * *","command":"VACUM"}`, pod: newDatabasePod(), execErr: errors.New("command terminated with exit code 3"), expectedStatus: http.StatusBadGateway},
+		{name: "database times out", userID: 7, role: RoleUser, body: `{"name":"cleanup","schedule":"0 3 * * *","command":"VACUUM"}`, pod: newDatabasePod(), execErr: context.DeadlineExceeded, expectedStatus: http.StatusGatewayTimeout},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var command []string
+			handler := newCronHandler(tt.pod, func(_ context.Context, _, _, _ string, cmd []string, _ string) (string, error) {
+				command = cmd
+				return tt.output, tt.execErr
+			})
+			c, rec := newTestContext(http.MethodPost, "/api/v1/instances/my-app/database/cron-jobs", tt.body)
+			c.SetParamNames("name")
+			c.SetParamValues("my-app")
+			setAuthContext(c, tt.userID, "someone", tt.role)
+
+			err := handler.CreateCronJob(c)
+			if tt.expectedStatus != http.StatusCreated {
+				assertHTTPError(t, err, tt.expectedStatus)
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var req apitypes.CreateCronJobRequest
+			_ = json.Unmarshal([]byte(tt.body), &req)
+			if !slices.Contains(command, "command="+req.Command) {
+				t.Errorf("expected the command to be passed as a psql variable, got %v", command)
+			}
+			var resp apitypes.CreateCronJobResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if resp.Job == nil || resp.Job.Name != req.Name || resp.Job.ID == 0 {
+				t.Errorf("unexpected response %+v", resp.Job)
+			}
+		})
+	}
+}
+
+// TestDeleteCronJob tests the DeleteCronJob handler
+func TestDeleteCronJob(t *testing.T) {
+	tests := []struct {
+		name           string
+		job            string
+		output         string
+		expectedStatus int
+	}{
+		{name: "removes job", job: "cleanup", output: "1\n", expectedStatus: http.StatusOK},
+		{name: "unknown job", job: "missing", output: "0\n", expectedStatus: http.StatusNotFound},
+		{name: "invalid name", job: "Clean Up", expectedStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := newCronHandler(newDatabasePod(), func(_ context.Context, _, _, _ string, _ []string, _ string) (string, error) {
+				return tt.output, nil
+			})
+			c, _ := newTestContext(http.MethodDelete, "/api/v1/instances/my-app/database/cron-jobs/"+url.PathEscape(tt.job), "")
+			c.SetParamNames("name", "job")
+			c.SetParamValues("my-app", tt.job)
+			setAuthContext(c, 7, "owner", RoleUser)
+
+			err := handler.DeleteCronJob(c)
+			if tt.expectedStatus != http.StatusOK {
+				assertHTTPError(t, err, tt.expectedStatus)
+				return
+			}
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+// TestCronJobsWithoutPodExecutor tests that cron job management requires database access
+func TestCronJobsWithoutPodExecutor(t *testing.T) {
+	handler := NewHandler(nil, &mockDBClient{}, newSuspensionCRClient(nil, newOwnedInstance("my-app", "7")), &mockK8sClient{})
+	c, _ := newTestContext(http.MethodGet, "/api/v1/instances/my-app/database/cron-jobs", "")
+	c.SetParamNames("name")
+	c.SetParamValues("my-app")
+	setAuthContext(c, 7, "owner", RoleUser)
+
+	assertHTTPError(t, handler.ListCronJobs(c), http.StatusServiceUnavailable)
+}
@@ -0,0 +1,60 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+)
+
+// UpdateInstanceDatabaseAudit turns pgAudit statement logging of an instance's database
+// on or off (admins and the instance owner only). The controller applies the setting once
+// the instance is running; audit lines then appear in the Postgres logs.
+func (h *Handler) UpdateInstanceDatabaseAudit(c echo.Context) error {
+	var req apitypes.UpdateDatabaseAuditRequest
+	if err := bindRequest(c, &req); err != nil {
+		return err
+	}
+
+	name := c.Param("name")
+	instance, err := h.getInstanceOrError(c, name)
+	if err != nil {
+		return err
+	}
+	if !isAdminOrOwner(GetAuthContext(c), instance) {
+		return echo.NewHTTPError(http.StatusForbidden, "only admins and the instance owner can change database auditing")
+	}
+	if req.Enabled && instance.Status.IsolationLevel == supacontrolv1alpha1.IsolationVCluster {
+		return echo.NewHTTPError(http.StatusConflict, "database auditing is not supported for vcluster instances")
+	}
+
+	instance, err = h.patchInstance(c, name, func(instance *supacontrolv1alpha1.SupabaseInstance) error {
+		database := &supacontrolv1alpha1.Database{}
+		if instance.Spec.Database != nil {
+			database = instance.Spec.Database.DeepCopy()
+		}
+		database.Audit = nil
+		if req.Enabled {
+			database.Audit = &supacontrolv1alpha1.DatabaseAudit{Enabled: true}
+		}
+		if isDefaultDatabase(database) {
+			instance.Spec.Database = nil
+		} else {
+			instance.Spec.Database = database
+		}
+		return nil
+	}, "failed to update database auditing")
+	if err != nil {
+		return err
+	}
+
+	h.recordAudit(c, "instance.database_audit.update", "instance", name, map[string]string{
+		"enabled": strconv.FormatBool(req.Enabled),
+	})
+	return c.JSON(http.StatusOK, apitypes.GetInstanceResponse{
+		Instance: h.convertCRToAPIType(c, instance),
+	})
+}
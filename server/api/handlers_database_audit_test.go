@@ -0,0 +1,88 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+)
+
+// TestUpdateInstanceDatabaseAudit tests the UpdateInstanceDatabaseAudit handler
+func TestUpdateInstanceDatabaseAudit(t *testing.T) {
+	tests := []struct {
+		name           string
+		userID         int64
+		role           string
+		isolation      supacontrolv1alpha1.IsolationLevel
+		database       *supacontrolv1alpha1.Database
+		body           string
+		expectedStatus int
+		expectedAudit  bool
+	}{
+		{name: "owner enables", userID: 7, role: RoleUser, body: `{"enabled":true}`, expectedStatus: http.StatusOK, expectedAudit: true},
+		{name: "admin disables", userID: 1, role: RoleAdmin, database: &supacontrolv1alpha1.Database{Audit: &supacontrolv1alpha1.DatabaseAudit{Enabled: true}}, body: `{"enabled":false}`, expectedStatus: http.StatusOK},
+		{name: "keeps read replicas", userID: 7, role: RoleUser, database: &supacontrolv1alpha1.Database{ReadReplicas: 2, Version: "17"}, body: `{"enabled":true}`, expectedStatus: http.StatusOK, expectedAudit: true},
+		{name: "vcluster instance", userID: 7, role: RoleUser, isolation: supacontrolv1alpha1.IsolationVCluster, body: `{"enabled":true}`, expectedStatus: http.StatusConflict},
+		{name: "other user", userID: 8, role: RoleUser, body: `{"enabled":true}`, expectedStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			instance := newOwnedInstance("my-app", "7")
+			instance.Spec.Database = tt.database
+			instance.Status.IsolationLevel = tt.isolation
+			var updated *supacontrolv1alpha1.SupabaseInstance
+			cr := newSuspensionCRClient(nil, instance)
+			cr.updateSupabaseInstanceFunc = func(_ context.Context, instance *supacontrolv1alpha1.SupabaseInstance) error {
+				updated = instance
+				return nil
+			}
+			handler := NewHandler(nil, &mockDBClient{}, cr, nil)
+			c, _ := newTestContext(http.MethodPut, "/api/v1/instances/my-app/database-audit", tt.body)
+			c.SetParamNames("name")
+			c.SetParamValues("my-app")
+			setAuthContext(c, tt.userID, "someone", tt.role)
+
+			err := handler.UpdateInstanceDatabaseAudit(c)
+			if tt.expectedStatus != http.StatusOK {
+				assertHTTPError(t, err, tt.expectedStatus)
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if updated == nil {
+				t.Fatal("expected the instance to be updated")
+			}
+			got := databaseToAPIType(updated.Spec.Database)
+			if enabled := got != nil && got.Audit != nil && got.Audit.Enabled; enabled != tt.expectedAudit {
+				t.Errorf("expected audit enabled %v, got %+v", tt.expectedAudit, updated.Spec.Database)
+			}
+			if tt.database != nil && tt.database.ReadReplicas > 0 &&
+				(got == nil || got.ReadReplicas != tt.database.ReadReplicas || got.Version != tt.database.Version) {
+				t.Errorf("expected the other database settings to be kept, got %+v", updated.Spec.Database)
+			}
+		})
+	}
+}
+
+// TestUpdateInstanceReadReplicasKeepsAudit tests that changing read replicas keeps auditing on
+func TestUpdateInstanceReadReplicasKeepsAudit(t *testing.T) {
+	instance := newOwnedInstance("my-app", "7")
+	instance.Spec.Database = &supacontrolv1alpha1.Database{ReadReplicas: 1, Audit: &supacontrolv1alpha1.DatabaseAudit{Enabled: true}}
+	cr := newSuspensionCRClient(nil, instance)
+	handler := NewHandler(nil, &mockDBClient{}, cr, nil)
+	c, _ := newTestContext(http.MethodPut, "/api/v1/instances/my-app/read-replicas", `{"read_replicas":0}`)
+	c.SetParamNames("name")
+	c.SetParamValues("my-app")
+	setAuthContext(c, 7, "someone", RoleUser)
+
+	if err := handler.UpdateInstanceReadReplicas(c); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if database := instance.Spec.Database; database == nil || database.ReadReplicas != 0 ||
+		database.Audit == nil || !database.Audit.Enabled {
+		t.Errorf("expected auditing kept without replicas, got %+v", database)
+	}
+}
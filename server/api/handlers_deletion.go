@@ -0,0 +1,145 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+	"github.com/qubitquilt/supacontrol/server/controllers"
+)
+
+// WithDeletionGracePeriod moves deleted instances to the trash for period before they are
+// purged (0 deletes them immediately)
+func WithDeletionGracePeriod(period time.Duration) HandlerOption {
+	return func(h *Handler) {
+		h.deletionGracePeriod = period
+	}
+}
+
+// pendingDeletionToAPIType converts an instance's pending deletion to its API form
+func pendingDeletionToAPIType(pending *supacontrolv1alpha1.PendingDeletion) *apitypes.InstancePendingDeletion {
+	if pending == nil {
+		return nil
+	}
+	return &apitypes.InstancePendingDeletion{
+		RequestedAt: pending.RequestedAt.Time,
+		PurgeAfter:  pending.PurgeAfter.Time,
+	}
+}
+
+// forceDeletionRequested reports whether a delete request asks to skip cleanup with force=true
+func forceDeletionRequested(c echo.Context) (bool, error) {
+	value := c.QueryParam("force")
+	if value == "" {
+		return false, nil
+	}
+	force, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, echo.NewHTTPError(http.StatusBadRequest, "force must be true or false")
+	}
+	return force, nil
+}
+
+// forceDeleteInstance removes the finalizer of an instance whose deletion is stuck, e.g.
+// because its cleanup Job keeps failing, so the instance goes away without being cleaned
+// up (admins only). Its namespace and Helm release may be left behind for an admin to
+// remove by hand.
+func (h *Handler) forceDeleteInstance(c echo.Context, instance *supacontrolv1alpha1.SupabaseInstance) error {
+	if !GetAuthContext(c).IsAdmin() {
+		return echo.NewHTTPError(http.StatusForbidden, "only admins can force the deletion of an instance")
+	}
+	if instance.DeletionTimestamp.IsZero() {
+		return echo.NewHTTPError(http.StatusConflict, "only instances that are already being deleted can be force deleted")
+	}
+
+	name := instance.Name
+	_, err := h.patchInstance(c, name, func(instance *supacontrolv1alpha1.SupabaseInstance) error {
+		controllerutil.RemoveFinalizer(instance, controllers.FinalizerName)
+		return nil
+	}, "failed to delete instance")
+	if err != nil {
+		return err
+	}
+
+	h.recordAudit(c, "instance.delete", "instance", name, map[string]string{
+		"force":       "true",
+		"phase":       string(instance.Status.Phase),
+		"cleanup_job": instance.Status.CleanupJobName,
+	})
+	return c.JSON(http.StatusOK, apitypes.DeleteInstanceResponse{
+		Message: localize(c, "Instance deleted without cleanup; its namespace and Helm release may remain"),
+	})
+}
+
+// UndeleteInstance recovers an instance from the trash before it is purged (admins and
+// the instance owner only). The controller scales its workloads back up unless it is
+// paused or suspended.
+func (h *Handler) UndeleteInstance(c echo.Context) error {
+	name := c.Param("name")
+	instance, err := h.getInstanceOrError(c, name)
+	if err != nil {
+		return err
+	}
+	if !isAdminOrOwner(GetAuthContext(c), instance) {
+		return echo.NewHTTPError(http.StatusForbidden, "only admins and the instance owner can recover an instance")
+	}
+
+	instance, err = h.patchInstance(c, name, func(instance *supacontrolv1alpha1.SupabaseInstance) error {
+		if !instance.DeletionTimestamp.IsZero() {
+			return echo.NewHTTPError(http.StatusConflict, "instance is already being purged")
+		}
+		if instance.Spec.PendingDeletion == nil {
+			return echo.NewHTTPError(http.StatusConflict, "instance is not pending deletion")
+		}
+		instance.Spec.PendingDeletion = nil
+		return nil
+	}, "failed to recover instance")
+	if err != nil {
+		return err
+	}
+
+	h.recordAudit(c, "instance.undelete", "instance", name, nil)
+	return c.JSON(http.StatusOK, apitypes.GetInstanceResponse{
+		Instance: h.convertCRToAPIType(c, instance),
+	})
+}
+
+// UpdateDeletionProtection enables or disables an instance's deletion protection (admins
+// and the instance owner only)
+func (h *Handler) UpdateDeletionProtection(c echo.Context) error {
+	var req apitypes.UpdateDeletionProtectionRequest
+	if err := bindRequest(c, &req); err != nil {
+		return err
+	}
+
+	name := c.Param("name")
+	instance, err := h.getInstanceOrError(c, name)
+	if err != nil {
+		return err
+	}
+	if !isAdminOrOwner(GetAuthContext(c), instance) {
+		return echo.NewHTTPError(http.StatusForbidden, "only admins and the instance owner can change deletion protection")
+	}
+	if req.Enabled && isSandboxInstance(instance) && !GetAuthContext(c).IsAdmin() {
+		return echo.NewHTTPError(http.StatusForbidden, "sandbox instances cannot be protected from deletion")
+	}
+
+	instance, err = h.patchInstance(c, name, func(instance *supacontrolv1alpha1.SupabaseInstance) error {
+		instance.Spec.DeletionProtection = req.Enabled
+		return nil
+	}, "failed to update deletion protection")
+	if err != nil {
+		return err
+	}
+
+	h.recordAudit(c, "instance.deletion_protection.update", "instance", name, map[string]string{"enabled": fmt.Sprint(req.Enabled)})
+	return c.JSON(http.StatusOK, apitypes.GetInstanceResponse{
+		Instance: h.convertCRToAPIType(c, instance),
+	})
+}
@@ -0,0 +1,223 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+	"github.com/qubitquilt/supacontrol/server/controllers"
+)
+
+// TestDeleteInstance_GracePeriod tests that deleted instances go to the trash when a grace
+// period is configured, and that protected instances are not deleted at all
+func TestDeleteInstance_GracePeriod(t *testing.T) {
+	tests := []struct {
+		name           string
+		protected      bool
+		pending        bool
+		expectedStatus int
+	}{
+		{name: "moved to the trash", expectedStatus: http.StatusAccepted},
+		{name: "already pending deletion", pending: true, expectedStatus: http.StatusConflict},
+		{name: "deletion protection", protected: true, expectedStatus: http.StatusConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			instance := newOwnedInstance("my-app", "7")
+			instance.Spec.DeletionProtection = tt.protected
+			if tt.pending {
+				instance.Spec.PendingDeletion = &supacontrolv1alpha1.PendingDeletion{}
+			}
+			var updated *supacontrolv1alpha1.PendingDeletion
+			cr := newSuspensionCRClient(nil, instance)
+			cr.updateSupabaseInstanceFunc = func(_ context.Context, instance *supacontrolv1alpha1.SupabaseInstance) error {
+				updated = instance.Spec.PendingDeletion
+				return nil
+			}
+			cr.deleteSupabaseInstanceFunc = func(context.Context, string) error {
+				t.Fatal("expected the instance not to be deleted immediately")
+				return nil
+			}
+			handler := NewHandler(nil, &mockDBClient{}, cr, nil, WithDeletionGracePeriod(72*time.Hour))
+			c, rec := newTestContext(http.MethodDelete, "/api/v1/instances/my-app", "")
+			c.SetParamNames("name")
+			c.SetParamValues("my-app")
+			setAuthContext(c, 7, "owner", "user")
+
+			err := handler.DeleteInstance(c)
+			if tt.expectedStatus != http.StatusAccepted {
+				assertHTTPError(t, err, tt.expectedStatus)
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if updated == nil {
+				t.Fatal("expected a pending deletion to be recorded")
+			}
+			if grace := updated.PurgeAfter.Sub(updated.RequestedAt.Time); grace != 72*time.Hour {
+				t.Errorf("expected a 72h grace period, got %v", grace)
+			}
+
+			var resp apitypes.DeleteInstanceResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if resp.PurgeAfter == nil || !resp.PurgeAfter.Equal(updated.PurgeAfter.Time) {
+				t.Errorf("expected purge_after %v, got %v", updated.PurgeAfter.Time, resp.PurgeAfter)
+			}
+		})
+	}
+}
+
+// TestDeleteInstance_Force tests that admins can remove the finalizer of an instance
+// whose deletion is stuck, and only of such an instance
+func TestDeleteInstance_Force(t *testing.T) {
+	tests := []struct {
+		name           string
+		role           string
+		force          string
+		deleting       bool
+		expectedStatus int
+	}{
+		{name: "admin forces stuck deletion", role: "admin", force: "true", deleting: true, expectedStatus: http.StatusOK},
+		{name: "owner cannot force", role: "user", force: "true", deleting: true, expectedStatus: http.StatusForbidden},
+		{name: "not being deleted", role: "admin", force: "true", expectedStatus: http.StatusConflict},
+		{name: "invalid force", role: "admin", force: "always", deleting: true, expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			instance := newOwnedInstance("my-app", "7")
+			instance.Finalizers = []string{controllers.FinalizerName}
+			instance.Status.Phase = supacontrolv1alpha1.PhaseDeletingInProgress
+			if tt.deleting {
+				now := metav1.Now()
+				instance.DeletionTimestamp = &now
+			}
+			var finalizers []string
+			updated := false
+			cr := newSuspensionCRClient(nil, instance)
+			cr.updateSupabaseInstanceFunc = func(_ context.Context, instance *supacontrolv1alpha1.SupabaseInstance) error {
+				finalizers = instance.Finalizers
+				updated = true
+				return nil
+			}
+			cr.deleteSupabaseInstanceFunc = func(context.Context, string) error {
+				t.Fatal("expected the instance not to be deleted again")
+				return nil
+			}
+			handler := NewHandler(nil, &mockDBClient{}, cr, nil)
+			c, _ := newTestContext(http.MethodDelete, "/api/v1/instances/my-app?force="+tt.force, "")
+			c.SetParamNames("name")
+			c.SetParamValues("my-app")
+			setAuthContext(c, 7, "someone", tt.role)
+
+			err := handler.DeleteInstance(c)
+			if tt.expectedStatus != http.StatusOK {
+				assertHTTPError(t, err, tt.expectedStatus)
+				if updated {
+					t.Error("expected the instance not to be updated")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !updated || len(finalizers) != 0 {
+				t.Errorf("expected the finalizer to be removed, got %v", finalizers)
+			}
+		})
+	}
+}
+
+// TestUndeleteInstance tests the UndeleteInstance handler
+func TestUndeleteInstance(t *testing.T) {
+	tests := []struct {
+		name           string
+		userID         int64
+		role           string
+		pending        bool
+		purging        bool
+		conflicts      int
+		expectedStatus int
+	}{
+		{name: "owner recovers", userID: 7, role: "user", pending: true, expectedStatus: http.StatusOK},
+		{name: "retried after a conflicting controller write", userID: 7, role: "user", pending: true, conflicts: 2, expectedStatus: http.StatusOK},
+		{name: "admin recovers", userID: 1, role: "admin", pending: true, expectedStatus: http.StatusOK},
+		{name: "other user", userID: 8, role: "user", pending: true, expectedStatus: http.StatusForbidden},
+		{name: "not pending deletion", userID: 7, role: "user", expectedStatus: http.StatusConflict},
+		{name: "already purging", userID: 7, role: "user", pending: true, purging: true, expectedStatus: http.StatusConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			instance := newOwnedInstance("my-app", "7")
+			if tt.pending {
+				now := metav1.Now()
+				instance.Spec.PendingDeletion = &supacontrolv1alpha1.PendingDeletion{RequestedAt: now, PurgeAfter: now}
+			}
+			if tt.purging {
+				now := metav1.Now()
+				instance.DeletionTimestamp = &now
+			}
+			updated := false
+			cr := newSuspensionCRClient(nil, instance)
+			cr.updateSupabaseInstanceFunc = conflictFirst(tt.conflicts, func(_ context.Context, instance *supacontrolv1alpha1.SupabaseInstance) error {
+				if instance.Spec.PendingDeletion != nil {
+					t.Error("expected the pending deletion to be cleared")
+				}
+				updated = true
+				return nil
+			})
+			handler := NewHandler(nil, &mockDBClient{}, cr, nil)
+			c, _ := newTestContext(http.MethodPost, "/api/v1/instances/my-app/undelete", "")
+			c.SetParamNames("name")
+			c.SetParamValues("my-app")
+			setAuthContext(c, tt.userID, "someone", tt.role)
+
+			err := handler.UndeleteInstance(c)
+			if tt.expectedStatus != http.StatusOK {
+				assertHTTPError(t, err, tt.expectedStatus)
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !updated {
+				t.Error("expected the instance to be updated")
+			}
+		})
+	}
+}
+
+// TestUpdateDeletionProtection tests the UpdateDeletionProtection handler
+func TestUpdateDeletionProtection(t *testing.T) {
+	instance := newOwnedInstance("my-app", "7")
+	cr := newSuspensionCRClient(nil, instance)
+	handler := NewHandler(nil, &mockDBClient{}, cr, nil)
+
+	c, _ := newTestContext(http.MethodPut, "/api/v1/instances/my-app/deletion-protection", `{"enabled":true}`)
+	c.SetParamNames("name")
+	c.SetParamValues("my-app")
+	setAuthContext(c, 8, "someone", "user")
+	assertHTTPError(t, handler.UpdateDeletionProtection(c), http.StatusForbidden)
+
+	c, _ = newTestContext(http.MethodPut, "/api/v1/instances/my-app/deletion-protection", `{"enabled":true}`)
+	c.SetParamNames("name")
+	c.SetParamValues("my-app")
+	setAuthContext(c, 7, "owner", "user")
+	if err := handler.UpdateDeletionProtection(c); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !instance.Spec.DeletionProtection {
+		t.Error("expected deletion protection to be enabled")
+	}
+}
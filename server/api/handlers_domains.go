@@ -0,0 +1,131 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+	"github.com/qubitquilt/supacontrol/server/internal/i18n"
+	"github.com/qubitquilt/supacontrol/server/internal/k8s"
+)
+
+// normalizeCustomDomains validates requested custom domains and converts them to their CR form.
+// Hostnames are lowercased and stripped of a trailing dot; nil means no custom domains.
+func normalizeCustomDomains(req *apitypes.CustomDomains) (*supacontrolv1alpha1.CustomDomains, error) {
+	if req == nil {
+		return nil, nil
+	}
+	domains := &supacontrolv1alpha1.CustomDomains{
+		API:    strings.TrimSuffix(strings.ToLower(strings.TrimSpace(req.API)), "."),
+		Studio: strings.TrimSuffix(strings.ToLower(strings.TrimSpace(req.Studio)), "."),
+	}
+	for _, host := range []string{domains.API, domains.Studio} {
+		if host == "" {
+			continue
+		}
+		if errs := validation.IsDNS1123Subdomain(host); len(errs) > 0 || !strings.Contains(host, ".") {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, i18n.Msg("%s is not a valid DNS hostname", host))
+		}
+	}
+	if domains.API == "" && domains.Studio == "" {
+		return nil, nil
+	}
+	if domains.API == domains.Studio {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "API and Studio domains must differ")
+	}
+	return domains, nil
+}
+
+// instanceHostnames returns the hostnames an instance currently claims: its custom domains
+// and the hosts of its published URLs
+func instanceHostnames(instance *supacontrolv1alpha1.SupabaseInstance) []string {
+	var hosts []string
+	if domains := instance.Spec.CustomDomains; domains != nil {
+		hosts = append(hosts, domains.API, domains.Studio)
+	}
+	for _, url := range []string{instance.Status.APIURL, instance.Status.StudioURL} {
+		hosts = append(hosts, strings.TrimPrefix(strings.TrimPrefix(url, "https://"), "http://"))
+	}
+	return hosts
+}
+
+// checkDomainsAvailable rejects custom domains already claimed by another instance
+func (h *Handler) checkDomainsAvailable(c echo.Context, name string, domains *supacontrolv1alpha1.CustomDomains) error {
+	if domains == nil {
+		return nil
+	}
+	list, err := h.crClient.ListSupabaseInstances(c.Request().Context())
+	if err != nil {
+		GetLogger(c).Error("Failed to list instances", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to list instances")
+	}
+	for i := range list.Items {
+		other := &list.Items[i]
+		if other.Name == name {
+			continue
+		}
+		for _, host := range instanceHostnames(other) {
+			if host != "" && (host == domains.API || host == domains.Studio) {
+				return echo.NewHTTPError(http.StatusConflict, i18n.Msg("domain %s is already used by instance %s", host, other.Name))
+			}
+		}
+	}
+	return nil
+}
+
+// UpdateInstanceDomains replaces the custom domains of an instance. The controller moves the
+// instance's ingresses to the new hostnames in place; empty fields restore the generated ones.
+func (h *Handler) UpdateInstanceDomains(c echo.Context) error {
+	name := c.Param("name")
+	ctx := c.Request().Context()
+
+	var req apitypes.CustomDomains
+	if err := bindRequest(c, &req); err != nil {
+		return err
+	}
+
+	instance, err := h.crClient.GetSupabaseInstance(ctx, name)
+	if err != nil {
+		if errors.Is(err, k8s.ErrInstanceNotFound) {
+			return newAPIError(http.StatusNotFound, apitypes.ErrorCodeInstanceNotFound, "instance not found")
+		}
+		GetLogger(c).Error("Failed to get instance", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to get instance")
+	}
+
+	if !isAdminOrOwner(GetAuthContext(c), instance) {
+		return echo.NewHTTPError(http.StatusForbidden, "only admins and the instance owner can change custom domains")
+	}
+
+	domains, err := normalizeCustomDomains(&req)
+	if err != nil {
+		return err
+	}
+	if err := h.checkDomainsAvailable(c, name, domains); err != nil {
+		return err
+	}
+
+	instance, err = h.patchInstance(c, name, func(instance *supacontrolv1alpha1.SupabaseInstance) error {
+		instance.Spec.CustomDomains = domains.DeepCopy()
+		return nil
+	}, "failed to update custom domains")
+	if err != nil {
+		return err
+	}
+
+	details := map[string]string{}
+	if domains != nil {
+		details["api"] = domains.API
+		details["studio"] = domains.Studio
+	}
+	h.recordAudit(c, "instance.domains.update", "instance", name, details)
+
+	return c.JSON(http.StatusOK, apitypes.GetInstanceResponse{
+		Instance: h.convertCRToAPIType(c, instance),
+	})
+}
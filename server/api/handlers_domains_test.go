@@ -0,0 +1,201 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+	"github.com/qubitquilt/supacontrol/server/internal/k8s"
+)
+
+// TestNormalizeCustomDomains tests custom domain validation and normalization
+func TestNormalizeCustomDomains(t *testing.T) {
+	tests := []struct {
+		name      string
+		req       *apitypes.CustomDomains
+		expected  *supacontrolv1alpha1.CustomDomains
+		expectErr bool
+	}{
+		{name: "nil request", req: nil, expected: nil},
+		{name: "empty request", req: &apitypes.CustomDomains{}, expected: nil},
+		{
+			name:     "normalizes case and trailing dot",
+			req:      &apitypes.CustomDomains{API: "API.Acme.io.", Studio: " studio.acme.io"},
+			expected: &supacontrolv1alpha1.CustomDomains{API: "api.acme.io", Studio: "studio.acme.io"},
+		},
+		{
+			name:     "API only",
+			req:      &apitypes.CustomDomains{API: "api.acme.io"},
+			expected: &supacontrolv1alpha1.CustomDomains{API: "api.acme.io"},
+		},
+		{name: "single label", req: &apitypes.CustomDomains{API: "localhost"}, expectErr: true},
+		{name: "wildcard", req: &apitypes.CustomDomains{API: "*.acme.io"}, expectErr: true},
+		{name: "underscore", req: &apitypes.CustomDomains{Studio: "my_studio.acme.io"}, expectErr: true},
+		{name: "url instead of hostname", req: &apitypes.CustomDomains{API: "https://api.acme.io"}, expectErr: true},
+		{name: "same hostname twice", req: &apitypes.CustomDomains{API: "app.acme.io", Studio: "app.acme.io"}, expectErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := normalizeCustomDomains(tt.req)
+			if tt.expectErr {
+				assertHTTPError(t, err, http.StatusBadRequest)
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if (got == nil) != (tt.expected == nil) || (got != nil && *got != *tt.expected) {
+				t.Errorf("expected %+v, got %+v", tt.expected, got)
+			}
+		})
+	}
+}
+
+// TestUpdateInstanceDomains tests the UpdateInstanceDomains handler
+func TestUpdateInstanceDomains(t *testing.T) {
+	tests := []struct {
+		name           string
+		userID         int64
+		role           string
+		body           string
+		getErr         error
+		conflicts      int
+		expectedStatus int
+		expected       *supacontrolv1alpha1.CustomDomains
+	}{
+		{
+			name:           "owner sets custom domains",
+			userID:         7,
+			role:           "user",
+			body:           `{"api":"api.acme.io","studio":"studio.acme.io"}`,
+			expectedStatus: http.StatusOK,
+			expected:       &supacontrolv1alpha1.CustomDomains{API: "api.acme.io", Studio: "studio.acme.io"},
+		},
+		{
+			name:           "retried after a conflicting controller write",
+			userID:         7,
+			role:           "user",
+			body:           `{"api":"api.acme.io"}`,
+			conflicts:      2,
+			expectedStatus: http.StatusOK,
+			expected:       &supacontrolv1alpha1.CustomDomains{API: "api.acme.io"},
+		},
+		{
+			name:           "admin clears custom domains",
+			userID:         1,
+			role:           "admin",
+			body:           `{}`,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "other user is forbidden",
+			userID:         8,
+			role:           "user",
+			body:           `{"api":"api.acme.io"}`,
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "invalid hostname",
+			userID:         7,
+			role:           "user",
+			body:           `{"api":"not a host"}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "domain used by another instance",
+			userID:         7,
+			role:           "user",
+			body:           `{"api":"api.taken.io"}`,
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			name:           "generated hostname of another instance",
+			userID:         7,
+			role:           "user",
+			body:           `{"studio":"other-api.example.com"}`,
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			name:           "instance not found",
+			userID:         1,
+			role:           "admin",
+			body:           `{}`,
+			getErr:         k8s.ErrInstanceNotFound,
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			current := newOwnedInstance("my-app", "7")
+			current.Spec.CustomDomains = &supacontrolv1alpha1.CustomDomains{API: "api.old.io"}
+			other := newOwnedInstance("other", "9")
+			other.Spec.CustomDomains = &supacontrolv1alpha1.CustomDomains{API: "api.taken.io"}
+
+			var updated *supacontrolv1alpha1.SupabaseInstance
+			mockCR := &mockCRClient{
+				getSupabaseInstanceFunc: func(_ context.Context, _ string) (*supacontrolv1alpha1.SupabaseInstance, error) {
+					if tt.getErr != nil {
+						return nil, tt.getErr
+					}
+					return current, nil
+				},
+				listSupabaseInstancesFunc: func(_ context.Context) (*supacontrolv1alpha1.SupabaseInstanceList, error) {
+					return &supacontrolv1alpha1.SupabaseInstanceList{
+						Items: []supacontrolv1alpha1.SupabaseInstance{*current, *other},
+					}, nil
+				},
+				updateSupabaseInstanceFunc: conflictFirst(tt.conflicts, func(_ context.Context, instance *supacontrolv1alpha1.SupabaseInstance) error {
+					updated = instance
+					return nil
+				}),
+			}
+			mockDB := &mockDBClient{
+				createAuditLogFunc: func(_ int64, action, _, _ string, _ map[string]string) error {
+					if action != "instance.domains.update" {
+						t.Errorf("unexpected audit action %s", action)
+					}
+					return nil
+				},
+			}
+
+			handler := NewHandler(nil, mockDB, mockCR, nil)
+			c, rec := newTestContext(http.MethodPut, "/api/v1/instances/my-app/domains", tt.body)
+			c.SetParamNames("name")
+			c.SetParamValues("my-app")
+			setAuthContext(c, tt.userID, "tester", tt.role)
+
+			err := handler.UpdateInstanceDomains(c)
+			if tt.expectedStatus != http.StatusOK {
+				assertHTTPError(t, err, tt.expectedStatus)
+				if updated != nil {
+					t.Error("instance should not be updated")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if updated == nil {
+				t.Fatal("expected instance to be updated")
+			}
+			got := updated.Spec.CustomDomains
+			if (got == nil) != (tt.expected == nil) || (got != nil && *got != *tt.expected) {
+				t.Errorf("expected custom domains %+v, got %+v", tt.expected, got)
+			}
+
+			var resp apitypes.GetInstanceResponse
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if tt.expected != nil && (resp.Instance.CustomDomains == nil || resp.Instance.CustomDomains.API != tt.expected.API) {
+				t.Errorf("expected custom domains in response, got %+v", resp.Instance.CustomDomains)
+			}
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package api
+
+import (
+	"net/http"
+	"sort"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/qubitquilt/supacontrol/server/controllers"
+	"github.com/qubitquilt/supacontrol/server/internal/i18n"
+)
+
+// maxEnvVariables limits how many environment variables an instance may set
+const maxEnvVariables = 50
+
+// normalizeEnv validates the environment variables requested for an instance against the
+// controller's allowlist
+func normalizeEnv(env map[string]string) (map[string]string, error) {
+	if len(env) == 0 {
+		return nil, nil
+	}
+	if len(env) > maxEnvVariables {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, i18n.Msg("at most %d environment variables can be set", maxEnvVariables))
+	}
+
+	names := make([]string, 0, len(env))
+	for name := range env {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if !controllers.EnvAllowed(name) {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, i18n.Msg("environment variable %s is not allowed", name))
+		}
+		if len(env[name]) > controllers.MaxEnvValueLength {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, i18n.Msg("environment variable %s must be at most %d characters", name, controllers.MaxEnvValueLength))
+		}
+	}
+	return env, nil
+}
@@ -0,0 +1,54 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/qubitquilt/supacontrol/server/controllers"
+)
+
+// TestNormalizeEnv tests instance environment validation against the allowlist
+func TestNormalizeEnv(t *testing.T) {
+	tooMany := make(map[string]string, maxEnvVariables+1)
+	for i := 0; i <= maxEnvVariables; i++ {
+		tooMany[fmt.Sprintf("VAR_%d", i)] = "x"
+	}
+
+	tests := []struct {
+		name      string
+		env       map[string]string
+		expected  map[string]string
+		expectErr bool
+	}{
+		{name: "no variables", env: nil, expected: nil},
+		{name: "empty map", env: map[string]string{}, expected: nil},
+		{
+			name:     "allowed feature flags",
+			env:      map[string]string{"GOTRUE_DISABLE_SIGNUP": "true", "PGRST_DB_MAX_ROWS": "500"},
+			expected: map[string]string{"GOTRUE_DISABLE_SIGNUP": "true", "PGRST_DB_MAX_ROWS": "500"},
+		},
+		{name: "secret is not allowed", env: map[string]string{"SMTP_PASS": "hunter2"}, expectErr: true},
+		{name: "unknown variable", env: map[string]string{"GOTRUE_DISABLE_SIGNUP": "true", "LD_PRELOAD": "/tmp/x.so"}, expectErr: true},
+		{name: "value too long", env: map[string]string{"GOTRUE_URI_ALLOW_LIST": strings.Repeat("x", controllers.MaxEnvValueLength+1)}, expectErr: true},
+		{name: "too many variables", env: tooMany, expectErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := normalizeEnv(tt.env)
+			if tt.expectErr {
+				assertHTTPError(t, err, http.StatusBadRequest)
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("expected %+v, got %+v", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,29 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+)
+
+// GetInstanceErrors returns the errors recently found in an instance's Kong, GoTrue and
+// Postgres logs. Instances whose logs have not been analyzed yet, e.g. because they are
+// not running, report no errors and no update time.
+func (h *Handler) GetInstanceErrors(c echo.Context) error {
+	if h.errorSource == nil {
+		return echo.NewHTTPError(http.StatusServiceUnavailable, "log error analysis is not enabled")
+	}
+
+	name := c.Param("name")
+	if _, err := h.getInstanceOrError(c, name); err != nil {
+		return err
+	}
+
+	summary := h.errorSource.Summary(name)
+	if summary == nil {
+		summary = &apitypes.InstanceErrorSummary{Instance: name, Components: []apitypes.ComponentErrors{}}
+	}
+	return c.JSON(http.StatusOK, summary)
+}
@@ -0,0 +1,72 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+)
+
+// mockErrorSource serves fixed error summaries
+type mockErrorSource map[string]*apitypes.InstanceErrorSummary
+
+func (m mockErrorSource) Summary(instance string) *apitypes.InstanceErrorSummary {
+	return m[instance]
+}
+
+// TestGetInstanceErrors tests the GetInstanceErrors handler
+func TestGetInstanceErrors(t *testing.T) {
+	summaries := mockErrorSource{
+		"my-app": {
+			Instance:      "my-app",
+			WindowSeconds: 3600,
+			TotalErrors:   3,
+			Components:    []apitypes.ComponentErrors{{Component: apitypes.ComponentKong, Kind: apitypes.ErrorKindHTTP5xx, Count: 3}},
+		},
+	}
+
+	tests := []struct {
+		name           string
+		instance       string
+		source         ErrorSummarySource
+		expectedStatus int
+		expectedTotal  int
+	}{
+		{name: "errors found", instance: "my-app", source: summaries, expectedStatus: http.StatusOK, expectedTotal: 3},
+		{name: "not analyzed yet", instance: "new-app", source: summaries, expectedStatus: http.StatusOK},
+		{name: "missing instance", instance: "gone", source: summaries, expectedStatus: http.StatusNotFound},
+		{name: "analysis disabled", instance: "my-app", expectedStatus: http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var opts []HandlerOption
+			if tt.source != nil {
+				opts = append(opts, WithErrorSummarySource(tt.source))
+			}
+			cr := newSuspensionCRClient(nil, newOwnedInstance("my-app", "7"), newOwnedInstance("new-app", "7"))
+			handler := NewHandler(nil, nil, cr, nil, opts...)
+			c, rec := newTestContext(http.MethodGet, "/api/v1/instances/"+tt.instance+"/errors", "")
+			c.SetParamNames("name")
+			c.SetParamValues(tt.instance)
+			setAuthContext(c, 7, "owner", "user")
+
+			err := handler.GetInstanceErrors(c)
+			if tt.expectedStatus != http.StatusOK {
+				assertHTTPError(t, err, tt.expectedStatus)
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var summary apitypes.InstanceErrorSummary
+			if err := json.Unmarshal(rec.Body.Bytes(), &summary); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if summary.Instance != tt.instance || summary.TotalErrors != tt.expectedTotal || summary.Components == nil {
+				t.Errorf("unexpected summary %+v", summary)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+	"github.com/qubitquilt/supacontrol/server/internal/i18n"
+)
+
+// maxTTLError rejects an expiry further away than apitypes.MaxInstanceTTL
+func maxTTLError() error {
+	return echo.NewHTTPError(http.StatusBadRequest, i18n.Msg("instances cannot expire more than %d hours from now", int(apitypes.MaxInstanceTTL.Hours())))
+}
+
+// normalizeTTL validates a requested TTL and converts it to its CR form. No TTL keeps
+// the instance from expiring, so nil is returned for it.
+func normalizeTTL(ttl string) (*metav1.Duration, error) {
+	if ttl == "" {
+		return nil, nil
+	}
+	duration, err := time.ParseDuration(ttl)
+	if err != nil || duration <= 0 {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "ttl must be a positive duration such as 72h")
+	}
+	if duration > apitypes.MaxInstanceTTL {
+		return nil, maxTTLError()
+	}
+	return &metav1.Duration{Duration: duration}, nil
+}
+
+// expiryToAPIType returns an instance's TTL and expiry in their API form. Instances not
+// stored yet have no creation time, so their expiry is counted from now.
+func expiryToAPIType(cr *supacontrolv1alpha1.SupabaseInstance) (string, *time.Time) {
+	if cr.Spec.TTL == nil {
+		return "", nil
+	}
+	created := cr.CreationTimestamp.Time
+	if created.IsZero() {
+		created = time.Now()
+	}
+	expiresAt := created.Add(cr.Spec.TTL.Duration).UTC()
+	return cr.Spec.TTL.Duration.String(), &expiresAt
+}
+
+// ExtendInstance postpones the expiry of an ephemeral instance (admins and the instance
+// owner only). The duration is added to the expiry, or to the current time for an
+// instance whose deletion protection held it back after it expired.
+func (h *Handler) ExtendInstance(c echo.Context) error {
+	var req apitypes.ExtendInstanceRequest
+	if err := bindRequest(c, &req); err != nil {
+		return err
+	}
+	duration, err := time.ParseDuration(req.Duration)
+	if err != nil || duration <= 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "duration must be a positive duration such as 24h")
+	}
+
+	name := c.Param("name")
+	instance, err := h.getInstanceOrError(c, name)
+	if err != nil {
+		return err
+	}
+	if !isAdminOrOwner(GetAuthContext(c), instance) {
+		return echo.NewHTTPError(http.StatusForbidden, "only admins and the instance owner can extend the instance")
+	}
+	if isSandboxInstance(instance) && !GetAuthContext(c).IsAdmin() {
+		return echo.NewHTTPError(http.StatusForbidden, "only admins can extend sandbox instances")
+	}
+
+	// The expiry is computed from the latest TTL, so concurrent extensions add up
+	var previous time.Duration
+	instance, err = h.patchInstance(c, name, func(instance *supacontrolv1alpha1.SupabaseInstance) error {
+		if instance.Spec.TTL == nil {
+			return echo.NewHTTPError(http.StatusConflict, "instance does not expire")
+		}
+
+		now := time.Now()
+		expiresAt := instance.CreationTimestamp.Add(instance.Spec.TTL.Duration)
+		if expiresAt.Before(now) {
+			expiresAt = now
+		}
+		expiresAt = expiresAt.Add(duration)
+		if expiresAt.Sub(now) > apitypes.MaxInstanceTTL {
+			return maxTTLError()
+		}
+
+		previous = instance.Spec.TTL.Duration
+		instance.Spec.TTL = &metav1.Duration{Duration: expiresAt.Sub(instance.CreationTimestamp.Time).Round(time.Second)}
+		return nil
+	}, "failed to extend instance")
+	if err != nil {
+		return err
+	}
+
+	h.recordAudit(c, "instance.extend", "instance", name, map[string]string{
+		"duration":     duration.String(),
+		"previous_ttl": previous.String(),
+		"ttl":          instance.Spec.TTL.Duration.String(),
+	})
+	return c.JSON(http.StatusOK, apitypes.GetInstanceResponse{
+		Instance: h.convertCRToAPIType(c, instance),
+	})
+}
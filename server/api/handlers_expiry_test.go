@@ -0,0 +1,87 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+)
+
+// TestNormalizeTTL tests validation of requested TTLs
+func TestNormalizeTTL(t *testing.T) {
+	ttl, err := normalizeTTL("72h")
+	if err != nil || ttl == nil || ttl.Duration != 72*time.Hour {
+		t.Errorf("normalizeTTL(72h) = %v, %v", ttl, err)
+	}
+	if ttl, err := normalizeTTL(""); ttl != nil || err != nil {
+		t.Errorf("normalizeTTL() = %v, %v; want no TTL", ttl, err)
+	}
+	for _, invalid := range []string{"soon", "-1h", "0s", "721h"} {
+		_, err := normalizeTTL(invalid)
+		assertHTTPError(t, err, http.StatusBadRequest)
+	}
+}
+
+// TestExtendInstance tests the ExtendInstance handler
+func TestExtendInstance(t *testing.T) {
+	created := time.Now().Add(-10 * time.Hour).Truncate(time.Second)
+
+	tests := []struct {
+		name           string
+		userID         int64
+		role           string
+		ttl            time.Duration
+		body           string
+		conflicts      int
+		expectedStatus int
+		expectedExpiry time.Time
+	}{
+		{name: "owner extends", userID: 7, role: RoleUser, ttl: 24 * time.Hour, body: `{"duration":"24h"}`, expectedStatus: http.StatusOK, expectedExpiry: created.Add(48 * time.Hour)},
+		{name: "retried after a conflicting controller write", userID: 7, role: RoleUser, ttl: 24 * time.Hour, body: `{"duration":"24h"}`, conflicts: 2, expectedStatus: http.StatusOK, expectedExpiry: created.Add(48 * time.Hour)},
+		{name: "admin extends expired instance", userID: 1, role: RoleAdmin, ttl: 5 * time.Hour, body: `{"duration":"2h"}`, expectedStatus: http.StatusOK, expectedExpiry: time.Now().Add(2 * time.Hour)},
+		{name: "beyond the maximum", userID: 7, role: RoleUser, ttl: 24 * time.Hour, body: `{"duration":"720h"}`, expectedStatus: http.StatusBadRequest},
+		{name: "invalid duration", userID: 7, role: RoleUser, ttl: 24 * time.Hour, body: `{"duration":"tomorrow"}`, expectedStatus: http.StatusBadRequest},
+		{name: "instance without TTL", userID: 7, role: RoleUser, body: `{"duration":"24h"}`, expectedStatus: http.StatusConflict},
+		{name: "other user", userID: 8, role: RoleUser, ttl: 24 * time.Hour, body: `{"duration":"24h"}`, expectedStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			instance := newOwnedInstance("my-app", "7")
+			instance.CreationTimestamp = metav1.NewTime(created)
+			if tt.ttl > 0 {
+				instance.Spec.TTL = &metav1.Duration{Duration: tt.ttl}
+			}
+			var updated *supacontrolv1alpha1.SupabaseInstance
+			cr := newSuspensionCRClient(nil, instance)
+			cr.updateSupabaseInstanceFunc = conflictFirst(tt.conflicts, func(_ context.Context, instance *supacontrolv1alpha1.SupabaseInstance) error {
+				updated = instance
+				return nil
+			})
+			handler := NewHandler(nil, &mockDBClient{}, cr, nil)
+			c, _ := newTestContext(http.MethodPost, "/api/v1/instances/my-app/extend", tt.body)
+			c.SetParamNames("name")
+			c.SetParamValues("my-app")
+			setAuthContext(c, tt.userID, "someone", tt.role)
+
+			err := handler.ExtendInstance(c)
+			if tt.expectedStatus != http.StatusOK {
+				assertHTTPError(t, err, tt.expectedStatus)
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if updated == nil {
+				t.Fatal("expected the instance to be updated")
+			}
+			if expiry := updated.ExpiryTime(); expiry.Sub(tt.expectedExpiry).Abs() > 2*time.Second {
+				t.Errorf("expected expiry at %s, got %s", tt.expectedExpiry, expiry.Time)
+			}
+		})
+	}
+}
@@ -0,0 +1,180 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+)
+
+// Formats instances can be exported in
+const (
+	// exportFormatYAML exports the SupabaseInstance resource and the instance's ingresses
+	exportFormatYAML = "yaml"
+
+	// exportFormatHelm exports the chart values the controller applies to the release
+	exportFormatHelm = "helm"
+)
+
+// exportMIMEType is the content type of exported manifests
+const exportMIMEType = "application/yaml"
+
+// lastAppliedAnnotation is the annotation kubectl apply records the applied object in
+const lastAppliedAnnotation = "kubectl.kubernetes.io/last-applied-configuration"
+
+// exportedAnnotations returns annotations without the ones that record the state of the
+// control plane rather than the desired state, or nil when none remain
+func exportedAnnotations(annotations map[string]string) map[string]string {
+	exported := map[string]string{}
+	for key, value := range annotations {
+		switch key {
+		case supacontrolv1alpha1.AnnotationPhaseHistory, supacontrolv1alpha1.AnnotationScheduleAppliedAt, lastAppliedAnnotation:
+			continue
+		}
+		exported[key] = value
+	}
+	if len(exported) == 0 {
+		return nil
+	}
+	return exported
+}
+
+// exportedMetadata returns the metadata of an exported object: its name, namespace,
+// labels and annotations, but none of the fields the API server sets
+func exportedMetadata(meta metav1.ObjectMeta) map[string]interface{} {
+	metadata := map[string]interface{}{"name": meta.Name}
+	if meta.Namespace != "" {
+		metadata["namespace"] = meta.Namespace
+	}
+	if len(meta.Labels) > 0 {
+		metadata["labels"] = meta.Labels
+	}
+	if annotations := exportedAnnotations(meta.Annotations); annotations != nil {
+		metadata["annotations"] = annotations
+	}
+	return metadata
+}
+
+// appendDocument appends object to a multi-document YAML stream
+func appendDocument(buf *bytes.Buffer, object interface{}) error {
+	data, err := yaml.Marshal(object)
+	if err != nil {
+		return err
+	}
+	if buf.Len() > 0 {
+		buf.WriteString("---\n")
+	}
+	buf.Write(data)
+	return nil
+}
+
+// exportManifests renders an instance's SupabaseInstance resource, without its status, and
+// the ingresses of its namespace as a multi-document YAML stream
+func (h *Handler) exportManifests(c echo.Context, instance *supacontrolv1alpha1.SupabaseInstance) ([]byte, error) {
+	var buf bytes.Buffer
+	err := appendDocument(&buf, map[string]interface{}{
+		"apiVersion": supacontrolv1alpha1.GroupVersion.String(),
+		"kind":       "SupabaseInstance",
+		"metadata":   exportedMetadata(metav1.ObjectMeta{Name: instance.Name, Labels: instance.Labels, Annotations: instance.Annotations}),
+		"spec":       instance.Spec,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if h.k8sClient == nil || instance.Status.Namespace == "" {
+		return buf.Bytes(), nil
+	}
+	ingresses, err := h.k8sClient.GetClientset().NetworkingV1().Ingresses(instance.Status.Namespace).List(c.Request().Context(), metav1.ListOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return buf.Bytes(), nil
+		}
+		return nil, fmt.Errorf("failed to list ingresses: %w", err)
+	}
+	for _, ingress := range ingresses.Items {
+		err := appendDocument(&buf, map[string]interface{}{
+			"apiVersion": "networking.k8s.io/v1",
+			"kind":       "Ingress",
+			"metadata":   exportedMetadata(ingress.ObjectMeta),
+			"spec":       ingress.Spec,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// exportValues renders the chart values the controller applies to an instance's release.
+// The instance's credentials are set separately by its provisioning Job and are not
+// exported; a header names the Secret holding them.
+func (h *Handler) exportValues(c echo.Context, instance *supacontrolv1alpha1.SupabaseInstance) ([]byte, error) {
+	values, err := h.profileValues(c.Request().Context(), instance)
+	if err != nil {
+		return nil, err
+	}
+	data, err := yaml.Marshal(values)
+	if err != nil {
+		return nil, err
+	}
+
+	namespace := getInstanceNamespace(instance)
+	chartVersion := instance.Status.ChartVersion
+	if chartVersion == "" {
+		chartVersion = instance.Spec.ChartVersion
+	}
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "# Supabase chart values of instance %s (release %s in namespace %s", instance.Name, instance.Spec.ProjectName, namespace)
+	if chartVersion != "" {
+		fmt.Fprintf(&buf, ", chart version %s", chartVersion)
+	}
+	buf.WriteString(")\n")
+	fmt.Fprintf(&buf, "# Credentials are not included: set postgresql.auth.postgresPassword, jwt.secret,\n")
+	fmt.Fprintf(&buf, "# jwt.anonKey and jwt.serviceRoleKey from Secret %s-secrets in namespace %s.\n", instance.Spec.ProjectName, namespace)
+	buf.Write(data)
+	return buf.Bytes(), nil
+}
+
+// ExportInstance returns an instance as manifests to commit to Git or apply with a GitOps
+// tool such as Argo CD (operators and admins). The yaml format returns the
+// SupabaseInstance resource and its ingresses; the helm format returns the values the
+// controller installs the Supabase chart with.
+func (h *Handler) ExportInstance(c echo.Context) error {
+	format := c.QueryParam("format")
+	if format == "" {
+		format = exportFormatYAML
+	}
+	if format != exportFormatYAML && format != exportFormatHelm {
+		return echo.NewHTTPError(http.StatusBadRequest, "format must be yaml or helm")
+	}
+
+	name := c.Param("name")
+	instance, err := h.getInstanceOrError(c, name)
+	if err != nil {
+		return err
+	}
+
+	var data []byte
+	filename := name + ".yaml"
+	if format == exportFormatHelm {
+		data, err = h.exportValues(c, instance)
+		filename = name + "-values.yaml"
+	} else {
+		data, err = h.exportManifests(c, instance)
+	}
+	if err != nil {
+		GetLogger(c).Error("Failed to export instance", "instance", name, "format", format, "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to export instance")
+	}
+
+	h.recordAudit(c, "instance.export", "instance", name, map[string]string{"format": format})
+	c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", filename))
+	return c.Blob(http.StatusOK, exportMIMEType, data)
+}
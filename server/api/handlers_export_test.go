@@ -0,0 +1,149 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"sigs.k8s.io/yaml"
+
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+	"github.com/qubitquilt/supacontrol/server/controllers"
+)
+
+// newExportHandler returns a handler exporting a running my-app instance with one ingress
+// and profile values
+func newExportHandler() *Handler {
+	instance := newOwnedInstance("my-app", "7")
+	instance.Annotations[supacontrolv1alpha1.AnnotationPhaseHistory] = `[{"to":"Running"}]`
+	instance.Spec.NetworkIsolation = true
+	instance.Status.Phase = supacontrolv1alpha1.PhaseRunning
+	instance.Status.ChartVersion = "0.1.3"
+
+	pathType := networkingv1.PathTypePrefix
+	ingress := &networkingv1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "my-app-kong",
+			Namespace:       "supa-my-app",
+			ResourceVersion: "42",
+			UID:             "0b1c",
+			Annotations:     map[string]string{"cert-manager.io/cluster-issuer": "letsencrypt"},
+		},
+		Spec: networkingv1.IngressSpec{
+			Rules: []networkingv1.IngressRule{{
+				Host: "my-app-api.example.com",
+				IngressRuleValue: networkingv1.IngressRuleValue{HTTP: &networkingv1.HTTPIngressRuleValue{
+					Paths: []networkingv1.HTTPIngressPath{{
+						Path:     "/",
+						PathType: &pathType,
+						Backend: networkingv1.IngressBackend{Service: &networkingv1.IngressServiceBackend{
+							Name: "my-app-kong",
+							Port: networkingv1.ServiceBackendPort{Number: 8000},
+						}},
+					}},
+				}},
+			}},
+		},
+	}
+	values := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: controllers.ProfileValuesSecretName(instance), Namespace: controllers.ControllerNamespace},
+		Data:       map[string][]byte{controllers.ProfileValuesKey: []byte("studio:\n  replicaCount: 2\n")},
+	}
+
+	clientset := fake.NewSimpleClientset(ingress, values)
+	return NewHandler(nil, &mockDBClient{}, newSuspensionCRClient(nil, instance), &mockK8sClient{clientset: clientset})
+}
+
+// TestExportInstance tests both export formats of the ExportInstance handler
+func TestExportInstance(t *testing.T) {
+	tests := []struct {
+		name           string
+		format         string
+		expectedStatus int
+		filename       string
+		contains       []string
+		excludes       []string
+	}{
+		{
+			name:           "manifests",
+			expectedStatus: http.StatusOK,
+			filename:       "my-app.yaml",
+			contains:       []string{"kind: SupabaseInstance", "networkIsolation: true", "supacontrol.io/owner-id", "kind: Ingress", "host: my-app-api.example.com"},
+			excludes:       []string{"status:", "phase-history", "resourceVersion", "uid:", "creationTimestamp"},
+		},
+		{
+			name:           "helm values",
+			format:         "helm",
+			expectedStatus: http.StatusOK,
+			filename:       "my-app-values.yaml",
+			contains:       []string{"replicaCount: 2", "chart version 0.1.3", "Secret my-app-secrets in namespace supa-my-app"},
+			excludes:       []string{"kind:"},
+		},
+		{name: "unknown format", format: "json", expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := newExportHandler()
+			c, rec := newTestContext(http.MethodGet, "/api/v1/instances/my-app/export?format="+tt.format, "")
+			c.SetParamNames("name")
+			c.SetParamValues("my-app")
+			setAuthContext(c, 1, "admin", "admin")
+
+			err := handler.ExportInstance(c)
+			if tt.expectedStatus != http.StatusOK {
+				assertHTTPError(t, err, tt.expectedStatus)
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if disposition := rec.Header().Get("Content-Disposition"); !strings.Contains(disposition, tt.filename) {
+				t.Errorf("expected attachment %s, got %q", tt.filename, disposition)
+			}
+			body := rec.Body.String()
+			for _, s := range tt.contains {
+				if !strings.Contains(body, s) {
+					t.Errorf("expected export to contain %q:\n%s", s, body)
+				}
+			}
+			for _, s := range tt.excludes {
+				if strings.Contains(body, s) {
+					t.Errorf("expected export not to contain %q:\n%s", s, body)
+				}
+			}
+		})
+	}
+}
+
+// TestExportInstanceRoundTrip tests that an exported instance parses back into its spec
+func TestExportInstanceRoundTrip(t *testing.T) {
+	handler := newExportHandler()
+	c, rec := newTestContext(http.MethodGet, "/api/v1/instances/my-app/export", "")
+	c.SetParamNames("name")
+	c.SetParamValues("my-app")
+	setAuthContext(c, 1, "admin", "admin")
+
+	if err := handler.ExportInstance(c); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	documents := strings.Split(rec.Body.String(), "---\n")
+	if len(documents) != 2 {
+		t.Fatalf("expected the instance and its ingress, got %d documents", len(documents))
+	}
+
+	var instance supacontrolv1alpha1.SupabaseInstance
+	if err := yaml.UnmarshalStrict([]byte(documents[0]), &instance); err != nil {
+		t.Fatalf("failed to parse exported instance: %v", err)
+	}
+	if instance.Name != "my-app" || instance.Spec.ProjectName != "my-app" || !instance.Spec.NetworkIsolation {
+		t.Errorf("unexpected exported instance %+v", instance)
+	}
+	if instance.APIVersion != supacontrolv1alpha1.GroupVersion.String() {
+		t.Errorf("unexpected apiVersion %q", instance.APIVersion)
+	}
+}
@@ -1,7 +1,9 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"testing"
 )
@@ -33,3 +35,114 @@ func TestHealthCheck(t *testing.T) {
 		t.Error("expected non-empty time field")
 	}
 }
+
+// TestReadinessCheck tests that /readyz reports each dependency check and fails when one does
+func TestReadinessCheck(t *testing.T) {
+	tests := []struct {
+		name       string
+		storageErr error
+		wantStatus int
+		wantResult string
+	}{
+		{name: "all checks pass", wantStatus: http.StatusOK, wantResult: "ready"},
+		{name: "object storage down", storageErr: errors.New("bucket missing"), wantStatus: http.StatusServiceUnavailable, wantResult: "not ready"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewHandler(nil, nil, nil, nil,
+				WithReadinessCheck("database", func(ctx context.Context) error { return nil }),
+				WithReadinessCheck("object_storage", func(ctx context.Context) error { return tt.storageErr }),
+			)
+			c, rec := newTestContext(http.MethodGet, "/readyz", "")
+
+			if err := handler.ReadinessCheck(c); err != nil {
+				t.Fatalf("ReadinessCheck() error = %v", err)
+			}
+			if rec.Code != tt.wantStatus {
+				t.Errorf("expected status %d, got %d", tt.wantStatus, rec.Code)
+			}
+
+			var resp struct {
+				Status string            `json:"status"`
+				Checks map[string]string `json:"checks"`
+			}
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if resp.Status != tt.wantResult {
+				t.Errorf("expected status '%s', got '%s'", tt.wantResult, resp.Status)
+			}
+			if resp.Checks["database"] != "ok" {
+				t.Errorf("expected database check 'ok', got '%s'", resp.Checks["database"])
+			}
+			wantStorage := "ok"
+			if tt.storageErr != nil {
+				wantStorage = "failed"
+			}
+			if resp.Checks["object_storage"] != wantStorage {
+				t.Errorf("expected object_storage check '%s', got '%s'", wantStorage, resp.Checks["object_storage"])
+			}
+		})
+	}
+}
+
+// TestLivenessCheck tests that /livez reports the process alive without checking dependencies
+func TestLivenessCheck(t *testing.T) {
+	handler := NewHandler(nil, nil, nil, nil,
+		WithReadinessCheck("database", func(ctx context.Context) error { return errors.New("connection refused") }),
+	)
+	c, rec := newTestContext(http.MethodGet, "/livez", "")
+
+	if err := handler.LivenessCheck(c); err != nil {
+		t.Fatalf("LivenessCheck() error = %v", err)
+	}
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected status 200, got %d", rec.Code)
+	}
+
+	var resp map[string]string
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp["status"] != "alive" {
+		t.Errorf("expected status 'alive', got '%s'", resp["status"])
+	}
+}
+
+// TestReadinessCheckLeaderElection tests that /readyz reports leadership without failing standby replicas
+func TestReadinessCheckLeaderElection(t *testing.T) {
+	elected := make(chan struct{})
+	handler := NewHandler(nil, nil, nil, nil, WithLeaderElection(elected))
+
+	readyz := func() (int, *bool) {
+		c, rec := newTestContext(http.MethodGet, "/readyz", "")
+		if err := handler.ReadinessCheck(c); err != nil {
+			t.Fatalf("ReadinessCheck() error = %v", err)
+		}
+		var resp struct {
+			Leader *bool `json:"leader"`
+		}
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		return rec.Code, resp.Leader
+	}
+
+	code, leader := readyz()
+	if code != http.StatusOK {
+		t.Errorf("expected standby replica to be ready, got status %d", code)
+	}
+	if leader == nil || *leader {
+		t.Errorf("expected leader false before election, got %v", leader)
+	}
+
+	close(elected)
+	code, leader = readyz()
+	if code != http.StatusOK {
+		t.Errorf("expected status 200, got %d", code)
+	}
+	if leader == nil || !*leader {
+		t.Errorf("expected leader true after election, got %v", leader)
+	}
+}
@@ -0,0 +1,32 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+	"github.com/qubitquilt/supacontrol/server/internal/i18n"
+)
+
+// normalizeHighAvailability validates requested high availability settings and converts
+// them to their CR form. Single replicas are the default, so nil is returned for them.
+func normalizeHighAvailability(req *apitypes.InstanceHighAvailability) (*supacontrolv1alpha1.HighAvailability, error) {
+	if req == nil || req.Replicas == 0 || req.Replicas == 1 {
+		return nil, nil
+	}
+	if req.Replicas < apitypes.MinHighAvailabilityReplicas || req.Replicas > apitypes.MaxHighAvailabilityReplicas {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, i18n.Msg("high availability replicas must be between %d and %d",
+			apitypes.MinHighAvailabilityReplicas, apitypes.MaxHighAvailabilityReplicas))
+	}
+	return &supacontrolv1alpha1.HighAvailability{Replicas: req.Replicas}, nil
+}
+
+// highAvailabilityToAPIType converts an instance's high availability settings to their API form
+func highAvailabilityToAPIType(highAvailability *supacontrolv1alpha1.HighAvailability) *apitypes.InstanceHighAvailability {
+	if highAvailability == nil {
+		return nil
+	}
+	return &apitypes.InstanceHighAvailability{Replicas: highAvailability.Replicas}
+}
@@ -0,0 +1,42 @@
+package api
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+)
+
+// TestNormalizeHighAvailability tests high availability validation and conversion
+func TestNormalizeHighAvailability(t *testing.T) {
+	tests := []struct {
+		name      string
+		req       *apitypes.InstanceHighAvailability
+		expected  *supacontrolv1alpha1.HighAvailability
+		expectErr bool
+	}{
+		{name: "nil request", req: nil, expected: nil},
+		{name: "single replica", req: &apitypes.InstanceHighAvailability{Replicas: 1}, expected: nil},
+		{name: "three replicas", req: &apitypes.InstanceHighAvailability{Replicas: 3}, expected: &supacontrolv1alpha1.HighAvailability{Replicas: 3}},
+		{name: "negative", req: &apitypes.InstanceHighAvailability{Replicas: -2}, expectErr: true},
+		{name: "too many", req: &apitypes.InstanceHighAvailability{Replicas: apitypes.MaxHighAvailabilityReplicas + 1}, expectErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := normalizeHighAvailability(tt.req)
+			if tt.expectErr {
+				assertHTTPError(t, err, http.StatusBadRequest)
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("expected %+v, got %+v", tt.expected, got)
+			}
+		})
+	}
+}
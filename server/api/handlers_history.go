@@ -0,0 +1,127 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+	"github.com/qubitquilt/supacontrol/server/internal/db"
+	"github.com/qubitquilt/supacontrol/server/internal/i18n"
+)
+
+const (
+	// defaultInstanceHistory and maxInstanceHistory are how many instance records a
+	// history request returns by default and at most
+	defaultInstanceHistory = 100
+	maxInstanceHistory     = 1000
+)
+
+// ListInstanceHistory lists the instances mirrored into the database, newest first.
+// Deleted instances keep their last status and have deleted_at set. The q parameter
+// searches instance names, created_after and created_before bound the creation time
+// (dates or RFC 3339 times), deleted selects deleted (true) or existing (false)
+// instances and limit caps the results.
+func (h *Handler) ListInstanceHistory(c echo.Context) error {
+	filter := db.InstanceRecordFilter{
+		Query: c.QueryParam("q"),
+		Limit: defaultInstanceHistory,
+	}
+
+	for _, bound := range []struct {
+		param  string
+		target **time.Time
+	}{
+		{"created_after", &filter.CreatedAfter},
+		{"created_before", &filter.CreatedBefore},
+	} {
+		value := c.QueryParam(bound.param)
+		if value == "" {
+			continue
+		}
+		t, err := parseHistoryTime(value)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, i18n.Msg("%s must be a date or an RFC 3339 time", bound.param))
+		}
+		*bound.target = &t
+	}
+
+	if value := c.QueryParam("deleted"); value != "" {
+		deleted, err := strconv.ParseBool(value)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "deleted must be true or false")
+		}
+		filter.Deleted = &deleted
+	}
+
+	if value := c.QueryParam("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > maxInstanceHistory {
+			return echo.NewHTTPError(http.StatusBadRequest, i18n.Msg("limit must be between 1 and %d", maxInstanceHistory))
+		}
+		filter.Limit = limit
+	}
+
+	records, err := h.dbClient.ListInstanceRecords(filter)
+	if err != nil {
+		GetLogger(c).Error("Failed to list instance records", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to list instance history")
+	}
+	if records == nil {
+		records = []*apitypes.InstanceRecord{}
+	}
+	for _, record := range records {
+		record.Status, _ = instanceStatus(supacontrolv1alpha1.SupabaseInstancePhase(record.Phase))
+	}
+
+	return c.JSON(http.StatusOK, apitypes.ListInstanceHistoryResponse{
+		Instances: records,
+		Count:     len(records),
+	})
+}
+
+// parseHistoryTime parses a date, taken as midnight UTC, or an RFC 3339 time
+func parseHistoryTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.DateOnly, value); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+// listMirroredInstances lists the existing instances from the database mirror, for when
+// the Kubernetes API cannot be reached. It reports false when the mirror cannot be read
+// or holds no instances yet.
+func (h *Handler) listMirroredInstances(c echo.Context) (*apitypes.ListInstancesResponse, bool) {
+	deleted := false
+	records, err := h.dbClient.ListInstanceRecords(db.InstanceRecordFilter{Deleted: &deleted})
+	if err != nil {
+		GetLogger(c).Warn("Failed to list mirrored instances", "error", err)
+		return nil, false
+	}
+	if len(records) == 0 {
+		return nil, false
+	}
+
+	instances := make([]*apitypes.Instance, 0, len(records))
+	for _, record := range records {
+		status, _ := instanceStatus(supacontrolv1alpha1.SupabaseInstancePhase(record.Phase))
+		instances = append(instances, &apitypes.Instance{
+			ProjectName:  record.Name,
+			Namespace:    record.Namespace,
+			Status:       status,
+			StudioURL:    record.StudioURL,
+			APIURL:       record.APIURL,
+			ChartVersion: record.ChartVersion,
+			ErrorMessage: record.ErrorMessage,
+			CreatedAt:    record.CreatedAt,
+		})
+	}
+	return &apitypes.ListInstancesResponse{
+		Instances: instances,
+		Count:     len(instances),
+		Stale:     true,
+	}, true
+}
@@ -0,0 +1,140 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+	"github.com/qubitquilt/supacontrol/server/internal/db"
+)
+
+// TestListInstanceHistory tests that query parameters become record filters and that
+// records get their API status
+func TestListInstanceHistory(t *testing.T) {
+	deletedAt := time.Date(2026, 9, 30, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name           string
+		query          string
+		expectedStatus int
+		check          func(t *testing.T, filter db.InstanceRecordFilter)
+	}{
+		{
+			name:           "defaults",
+			expectedStatus: http.StatusOK,
+			check: func(t *testing.T, filter db.InstanceRecordFilter) {
+				if filter.Limit != defaultInstanceHistory || filter.Deleted != nil || filter.CreatedAfter != nil {
+					t.Errorf("unexpected filter %+v", filter)
+				}
+			},
+		},
+		{
+			name:           "created last month",
+			query:          "?created_after=2026-09-01&created_before=2026-10-01T00:00:00Z",
+			expectedStatus: http.StatusOK,
+			check: func(t *testing.T, filter db.InstanceRecordFilter) {
+				if filter.CreatedAfter == nil || !filter.CreatedAfter.Equal(time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC)) {
+					t.Errorf("unexpected created_after %v", filter.CreatedAfter)
+				}
+				if filter.CreatedBefore == nil || !filter.CreatedBefore.Equal(time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)) {
+					t.Errorf("unexpected created_before %v", filter.CreatedBefore)
+				}
+			},
+		},
+		{
+			name:           "deleted search",
+			query:          "?q=app&deleted=true&limit=10",
+			expectedStatus: http.StatusOK,
+			check: func(t *testing.T, filter db.InstanceRecordFilter) {
+				if filter.Query != "app" || filter.Deleted == nil || !*filter.Deleted || filter.Limit != 10 {
+					t.Errorf("unexpected filter %+v", filter)
+				}
+			},
+		},
+		{name: "invalid date", query: "?created_after=last-month", expectedStatus: http.StatusBadRequest},
+		{name: "invalid deleted", query: "?deleted=maybe", expectedStatus: http.StatusBadRequest},
+		{name: "limit too high", query: "?limit=5000", expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var filter db.InstanceRecordFilter
+			mockDB := &mockDBClient{
+				listInstanceRecordsFunc: func(f db.InstanceRecordFilter) ([]*apitypes.InstanceRecord, error) {
+					filter = f
+					return []*apitypes.InstanceRecord{
+						{UID: "uid-1", Name: "my-app", Phase: string(supacontrolv1alpha1.PhaseRunning), DeletedAt: &deletedAt},
+					}, nil
+				},
+			}
+			handler := NewHandler(nil, mockDB, &mockCRClient{}, nil)
+			c, rec := newTestContext(http.MethodGet, "/api/v1/instance-history"+tt.query, "")
+
+			err := handler.ListInstanceHistory(c)
+			if tt.expectedStatus != http.StatusOK {
+				assertHTTPError(t, err, tt.expectedStatus)
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			tt.check(t, filter)
+
+			var resp apitypes.ListInstanceHistoryResponse
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if resp.Count != 1 || resp.Instances[0].Status != apitypes.StatusRunning || resp.Instances[0].DeletedAt == nil {
+				t.Errorf("unexpected response %+v", resp.Instances)
+			}
+		})
+	}
+}
+
+// TestListInstances_FallsBackToMirror tests that existing instances are served from the
+// database mirror when the Kubernetes API fails, and that an empty mirror is not served
+func TestListInstances_FallsBackToMirror(t *testing.T) {
+	for _, records := range [][]*apitypes.InstanceRecord{
+		{{UID: "uid-1", Name: "my-app", Namespace: "supa-my-app", Phase: string(supacontrolv1alpha1.PhaseFailed)}},
+		nil,
+	} {
+		mockCR := &mockCRClient{
+			listSupabaseInstancesFunc: func(context.Context) (*supacontrolv1alpha1.SupabaseInstanceList, error) {
+				return nil, fmt.Errorf("kubernetes api error")
+			},
+		}
+		var filter db.InstanceRecordFilter
+		mockDB := &mockDBClient{
+			listInstanceRecordsFunc: func(f db.InstanceRecordFilter) ([]*apitypes.InstanceRecord, error) {
+				filter = f
+				return records, nil
+			},
+		}
+		handler := NewHandler(nil, mockDB, mockCR, nil)
+		c, rec := newTestContext(http.MethodGet, "/api/v1/instances", "")
+
+		err := handler.ListInstances(c)
+		if records == nil {
+			assertHTTPError(t, err, http.StatusInternalServerError)
+			continue
+		}
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if filter.Deleted == nil || *filter.Deleted {
+			t.Errorf("expected only existing instances to be listed, got %+v", filter)
+		}
+
+		var resp apitypes.ListInstancesResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if !resp.Stale || resp.Count != 1 || resp.Instances[0].ProjectName != "my-app" || resp.Instances[0].Status != apitypes.StatusFailed {
+			t.Errorf("unexpected response %+v", resp)
+		}
+	}
+}
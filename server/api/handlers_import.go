@@ -0,0 +1,124 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+	"github.com/qubitquilt/supacontrol/server/controllers"
+	"github.com/qubitquilt/supacontrol/server/internal/i18n"
+	"github.com/qubitquilt/supacontrol/server/internal/k8s"
+	"github.com/qubitquilt/supacontrol/server/internal/namespaces"
+)
+
+// checkImportNamespace checks that the namespace of a release to import exists, is not a
+// system namespace and does not already hold an instance
+func (h *Handler) checkImportNamespace(c echo.Context, namespace string) error {
+	if err := namespaces.Validate(namespace, controllers.ControllerNamespace); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, i18n.Msg("namespace %s is reserved for the system", namespace))
+	}
+
+	ctx := c.Request().Context()
+	if h.k8sClient != nil {
+		_, err := h.k8sClient.GetClientset().CoreV1().Namespaces().Get(ctx, namespace, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			return echo.NewHTTPError(http.StatusNotFound, i18n.Msg("namespace %s not found", namespace))
+		}
+		if err != nil {
+			GetLogger(c).Error("Failed to get namespace", "namespace", namespace, "error", err)
+			return echo.NewHTTPError(http.StatusInternalServerError, "failed to import instance")
+		}
+	}
+
+	instances, err := h.crClient.ListSupabaseInstances(ctx)
+	if err != nil {
+		GetLogger(c).Error("Failed to list instances", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to import instance")
+	}
+	for i := range instances.Items {
+		instance := &instances.Items[i]
+		used := instance.Status.Namespace
+		if used == "" {
+			used = namespaces.Name(h.namespaceTemplate, instance.Spec.ProjectName)
+			if instance.Spec.Adoption != nil {
+				used = instance.Spec.Adoption.Namespace
+			}
+		}
+		if used == namespace {
+			return echo.NewHTTPError(http.StatusConflict, i18n.Msg("namespace %s already belongs to instance %s", namespace, instance.Name))
+		}
+	}
+	return nil
+}
+
+// ImportInstance adopts a Supabase Helm release installed outside of SupaControl, e.g. by
+// hand with helm install, as an instance (admins only). The controller verifies that the
+// release is deployed, labels its namespace and workloads and moves the instance to
+// Running without provisioning anything; from then on it is managed like any other.
+func (h *Handler) ImportInstance(c echo.Context) error {
+	var req apitypes.ImportInstanceRequest
+	if err := bindRequest(c, &req); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	_, err := h.crClient.GetSupabaseInstance(ctx, req.Name)
+	if err == nil {
+		return newAPIError(http.StatusConflict, apitypes.ErrorCodeInstanceExists, "instance with this name already exists")
+	}
+	if !errors.Is(err, k8s.ErrInstanceNotFound) {
+		GetLogger(c).Error("Failed to check instance existence", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to check instance existence")
+	}
+	if err := h.checkImportNamespace(c, req.Namespace); err != nil {
+		return err
+	}
+
+	instance := &supacontrolv1alpha1.SupabaseInstance{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: req.Name,
+			Labels: map[string]string{
+				"app.kubernetes.io/managed-by": "supacontrol-api",
+			},
+		},
+		Spec: supacontrolv1alpha1.SupabaseInstanceSpec{
+			ProjectName: req.Name,
+			Adoption: &supacontrolv1alpha1.Adoption{
+				Namespace:   req.Namespace,
+				ReleaseName: req.ReleaseName,
+			},
+		},
+	}
+	if authCtx := GetAuthContext(c); authCtx != nil {
+		instance.Annotations = map[string]string{
+			supacontrolv1alpha1.AnnotationOwnerID: strconv.FormatInt(authCtx.UserID, 10),
+		}
+	}
+
+	if err := h.crClient.CreateSupabaseInstance(ctx, instance); err != nil {
+		if errors.Is(err, k8s.ErrAlreadyExists) {
+			return newAPIError(http.StatusConflict, apitypes.ErrorCodeInstanceExists, "instance with this name already exists")
+		}
+		GetLogger(c).Error("Failed to create SupabaseInstance CR", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to import instance")
+	}
+
+	releaseName := req.ReleaseName
+	if releaseName == "" {
+		releaseName = req.Name
+	}
+	h.recordAudit(c, "instance.import", "instance", req.Name, map[string]string{
+		"namespace": req.Namespace,
+		"release":   releaseName,
+	})
+	return c.JSON(http.StatusAccepted, apitypes.CreateInstanceResponse{
+		Instance: h.convertCRToAPIType(c, instance),
+		Message:  localize(c, "Instance import started"),
+	})
+}
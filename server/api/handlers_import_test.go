@@ -0,0 +1,85 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+)
+
+// TestImportInstance tests validation and conflict checks of the ImportInstance handler
+func TestImportInstance(t *testing.T) {
+	tests := []struct {
+		name            string
+		body            string
+		expectedStatus  int
+		expectedRelease string
+	}{
+		{name: "imports release", body: `{"name":"legacy","namespace":"supabase","release_name":"supabase"}`, expectedStatus: http.StatusAccepted, expectedRelease: "supabase"},
+		{name: "release defaults to name", body: `{"name":"legacy","namespace":"supabase"}`, expectedStatus: http.StatusAccepted},
+		{name: "missing namespace", body: `{"name":"legacy"}`, expectedStatus: http.StatusBadRequest},
+		{name: "invalid release name", body: `{"name":"legacy","namespace":"supabase","release_name":"Supabase"}`, expectedStatus: http.StatusBadRequest},
+		{name: "system namespace", body: `{"name":"legacy","namespace":"kube-system"}`, expectedStatus: http.StatusBadRequest},
+		{name: "unknown namespace", body: `{"name":"legacy","namespace":"nowhere"}`, expectedStatus: http.StatusNotFound},
+		{name: "namespace of another instance", body: `{"name":"legacy","namespace":"supa-my-app"}`, expectedStatus: http.StatusConflict},
+		{name: "existing instance", body: `{"name":"my-app","namespace":"supabase"}`, expectedStatus: http.StatusConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var created *supacontrolv1alpha1.SupabaseInstance
+			cr := newSuspensionCRClient(nil, newOwnedInstance("my-app", "7"))
+			cr.createSupabaseInstanceFunc = func(_ context.Context, instance *supacontrolv1alpha1.SupabaseInstance) error {
+				created = instance
+				return nil
+			}
+			clientset := fake.NewSimpleClientset(
+				&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "supabase"}},
+				&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "supa-my-app"}},
+				&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system"}},
+			)
+			handler := NewHandler(nil, &mockDBClient{}, cr, &mockK8sClient{clientset: clientset})
+			c, rec := newTestContext(http.MethodPost, "/api/v1/instances/import", tt.body)
+			setAuthContext(c, 1, "admin", "admin")
+
+			err := handler.ImportInstance(c)
+			if tt.expectedStatus != http.StatusAccepted {
+				assertHTTPError(t, err, tt.expectedStatus)
+				if created != nil {
+					t.Errorf("expected no instance to be created, got %+v", created)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if rec.Code != http.StatusAccepted {
+				t.Fatalf("expected status %d, got %d", http.StatusAccepted, rec.Code)
+			}
+			if created == nil || created.Spec.Adoption == nil {
+				t.Fatalf("expected an instance adopting the release, got %+v", created)
+			}
+			if created.Spec.Adoption.Namespace != "supabase" || created.Spec.Adoption.ReleaseName != tt.expectedRelease {
+				t.Errorf("unexpected adoption %+v", created.Spec.Adoption)
+			}
+			if created.Annotations[supacontrolv1alpha1.AnnotationOwnerID] != "1" {
+				t.Errorf("expected the importing user to own the instance, got %v", created.Annotations)
+			}
+
+			var resp apitypes.CreateInstanceResponse
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if !resp.Instance.Imported {
+				t.Error("expected the instance to be reported as imported")
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package api
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+	"github.com/qubitquilt/supacontrol/server/internal/i18n"
+)
+
+// maxIngressAnnotations limits how many annotations an instance's ingresses may set
+const maxIngressAnnotations = 50
+
+// normalizeIngress validates requested ingress settings and converts them to their CR
+// form. Only admins may set annotations, as they can configure the shared ingress
+// controller, e.g. through nginx configuration snippets.
+func normalizeIngress(c echo.Context, req *apitypes.InstanceIngress) (*supacontrolv1alpha1.IngressSettings, error) {
+	if req == nil {
+		return nil, nil
+	}
+	settings := &supacontrolv1alpha1.IngressSettings{
+		TLSIssuer:  strings.TrimSpace(req.TLSIssuer),
+		DisableTLS: req.DisableTLS,
+	}
+
+	if len(req.Annotations) > 0 {
+		if authCtx := GetAuthContext(c); authCtx == nil || !authCtx.IsAdmin() {
+			return nil, echo.NewHTTPError(http.StatusForbidden, "only admins can set ingress annotations")
+		}
+		if len(req.Annotations) > maxIngressAnnotations {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, i18n.Msg("at most %d ingress annotations can be set", maxIngressAnnotations))
+		}
+		keys := make([]string, 0, len(req.Annotations))
+		for key := range req.Annotations {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+				return nil, echo.NewHTTPError(http.StatusBadRequest, i18n.Msg("%s is not a valid annotation name", key))
+			}
+		}
+		settings.Annotations = req.Annotations
+	}
+
+	if settings.TLSIssuer != "" {
+		if settings.DisableTLS {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, "a TLS issuer cannot be set when TLS is disabled")
+		}
+		if errs := validation.IsDNS1123Subdomain(settings.TLSIssuer); len(errs) > 0 {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, i18n.Msg("%s is not a valid issuer name", settings.TLSIssuer))
+		}
+	}
+
+	if settings.Annotations == nil && settings.TLSIssuer == "" && !settings.DisableTLS {
+		return nil, nil
+	}
+	return settings, nil
+}
+
+// ingressToAPIType converts an instance's ingress settings to their API form
+func ingressToAPIType(settings *supacontrolv1alpha1.IngressSettings) *apitypes.InstanceIngress {
+	if settings == nil {
+		return nil
+	}
+	return &apitypes.InstanceIngress{
+		Annotations: settings.Annotations,
+		TLSIssuer:   settings.TLSIssuer,
+		DisableTLS:  settings.DisableTLS,
+	}
+}
+
+// normalizeDNS validates requested external-dns hints and converts them to their CR form
+func normalizeDNS(req *apitypes.InstanceDNS) (*supacontrolv1alpha1.DNSSettings, error) {
+	if req == nil {
+		return nil, nil
+	}
+	settings := &supacontrolv1alpha1.DNSSettings{
+		Provider: strings.TrimSpace(req.Provider),
+		TTL:      req.TTL,
+	}
+	if settings.Provider != "" {
+		if errs := validation.IsDNS1123Label(settings.Provider); len(errs) > 0 {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, i18n.Msg("%s is not a valid DNS provider", settings.Provider))
+		}
+	}
+	if settings.TTL < 0 || settings.TTL > apitypes.MaxDNSTTL {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, i18n.Msg("DNS TTL must be between 1 and %d seconds", apitypes.MaxDNSTTL))
+	}
+
+	if settings.Provider == "" && settings.TTL == 0 {
+		return nil, nil
+	}
+	return settings, nil
+}
+
+// dnsToAPIType converts an instance's external-dns hints to their API form
+func dnsToAPIType(settings *supacontrolv1alpha1.DNSSettings) *apitypes.InstanceDNS {
+	if settings == nil {
+		return nil
+	}
+	return &apitypes.InstanceDNS{
+		Provider: settings.Provider,
+		TTL:      settings.TTL,
+	}
+}
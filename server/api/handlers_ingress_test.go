@@ -0,0 +1,90 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+)
+
+// TestNormalizeIngress tests that only admins may set ingress annotations, and that
+// issuers and annotation names are validated
+func TestNormalizeIngress(t *testing.T) {
+	tests := []struct {
+		name           string
+		req            *apitypes.InstanceIngress
+		role           string
+		expectedStatus int
+		expectNil      bool
+	}{
+		{name: "nil", req: nil, role: RoleUser, expectedStatus: http.StatusOK, expectNil: true},
+		{name: "empty", req: &apitypes.InstanceIngress{}, role: RoleUser, expectedStatus: http.StatusOK, expectNil: true},
+		{name: "issuer", req: &apitypes.InstanceIngress{TLSIssuer: "letsencrypt-staging"}, role: RoleUser, expectedStatus: http.StatusOK},
+		{name: "TLS disabled", req: &apitypes.InstanceIngress{DisableTLS: true}, role: RoleUser, expectedStatus: http.StatusOK},
+		{name: "admin annotations", req: &apitypes.InstanceIngress{Annotations: map[string]string{"nginx.ingress.kubernetes.io/proxy-body-size": "50m"}}, role: RoleAdmin, expectedStatus: http.StatusOK},
+		{name: "user annotations", req: &apitypes.InstanceIngress{Annotations: map[string]string{"nginx.ingress.kubernetes.io/proxy-body-size": "50m"}}, role: RoleUser, expectedStatus: http.StatusForbidden},
+		{name: "invalid annotation name", req: &apitypes.InstanceIngress{Annotations: map[string]string{"not valid": "x"}}, role: RoleAdmin, expectedStatus: http.StatusBadRequest},
+		{name: "invalid issuer", req: &apitypes.InstanceIngress{TLSIssuer: "Lets_Encrypt"}, role: RoleUser, expectedStatus: http.StatusBadRequest},
+		{name: "issuer with TLS disabled", req: &apitypes.InstanceIngress{TLSIssuer: "letsencrypt", DisableTLS: true}, role: RoleUser, expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := newTestContext(http.MethodPost, "/api/v1/instances", "")
+			setAuthContext(c, 1, "someone", tt.role)
+
+			settings, err := normalizeIngress(c, tt.req)
+			if tt.expectedStatus != http.StatusOK {
+				assertHTTPError(t, err, tt.expectedStatus)
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if (settings == nil) != tt.expectNil {
+				t.Fatalf("unexpected settings: %+v", settings)
+			}
+			if settings != nil && (settings.TLSIssuer != tt.req.TLSIssuer || settings.DisableTLS != tt.req.DisableTLS ||
+				len(settings.Annotations) != len(tt.req.Annotations)) {
+				t.Errorf("settings %+v do not match request %+v", settings, tt.req)
+			}
+		})
+	}
+}
+
+// TestNormalizeDNS tests that DNS provider hints and TTLs are validated
+func TestNormalizeDNS(t *testing.T) {
+	tests := []struct {
+		name           string
+		req            *apitypes.InstanceDNS
+		expectedStatus int
+		expectNil      bool
+	}{
+		{name: "nil", req: nil, expectedStatus: http.StatusOK, expectNil: true},
+		{name: "empty", req: &apitypes.InstanceDNS{}, expectedStatus: http.StatusOK, expectNil: true},
+		{name: "provider", req: &apitypes.InstanceDNS{Provider: "route53"}, expectedStatus: http.StatusOK},
+		{name: "TTL", req: &apitypes.InstanceDNS{TTL: 300}, expectedStatus: http.StatusOK},
+		{name: "invalid provider", req: &apitypes.InstanceDNS{Provider: "Route 53"}, expectedStatus: http.StatusBadRequest},
+		{name: "negative TTL", req: &apitypes.InstanceDNS{TTL: -1}, expectedStatus: http.StatusBadRequest},
+		{name: "TTL too long", req: &apitypes.InstanceDNS{TTL: apitypes.MaxDNSTTL + 1}, expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			settings, err := normalizeDNS(tt.req)
+			if tt.expectedStatus != http.StatusOK {
+				assertHTTPError(t, err, tt.expectedStatus)
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if (settings == nil) != tt.expectNil {
+				t.Fatalf("unexpected settings: %+v", settings)
+			}
+			if settings != nil && (settings.Provider != tt.req.Provider || settings.TTL != tt.req.TTL) {
+				t.Errorf("settings %+v do not match request %+v", settings, tt.req)
+			}
+		})
+	}
+}
@@ -0,0 +1,153 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+)
+
+// healthComponents are the components reported by the health endpoint in display order
+var healthComponents = []string{
+	apitypes.ComponentPostgres,
+	apitypes.ComponentKong,
+	apitypes.ComponentGoTrue,
+	apitypes.ComponentRealtime,
+	apitypes.ComponentStorage,
+	apitypes.ComponentStudio,
+}
+
+// componentWorkloads maps the app.kubernetes.io/name label the Supabase chart gives each
+// workload to the component it runs
+var componentWorkloads = map[string]string{
+	"supabase-db":       apitypes.ComponentPostgres,
+	"supabase-kong":     apitypes.ComponentKong,
+	"supabase-auth":     apitypes.ComponentGoTrue,
+	"supabase-realtime": apitypes.ComponentRealtime,
+	"supabase-storage":  apitypes.ComponentStorage,
+	"supabase-studio":   apitypes.ComponentStudio,
+}
+
+// workloadHealth derives the health of a component from its desired and ready replicas
+func workloadHealth(health *apitypes.ComponentHealth) {
+	switch {
+	case health.Replicas == 0:
+		health.Status = apitypes.HealthStopped
+	case health.ReadyReplicas >= health.Replicas:
+		health.Status = apitypes.HealthHealthy
+	case health.ReadyReplicas == 0:
+		health.Status = apitypes.HealthUnavailable
+	default:
+		health.Status = apitypes.HealthDegraded
+	}
+}
+
+// deploymentHealth reports the health of a Deployment. Unless it is healthy, the message
+// of its failing Available or Progressing condition explains why.
+func deploymentHealth(component string, deployment *appsv1.Deployment) apitypes.ComponentHealth {
+	health := apitypes.ComponentHealth{
+		Component:     component,
+		Workload:      deployment.Name,
+		ReadyReplicas: deployment.Status.ReadyReplicas,
+		Replicas:      1,
+	}
+	if deployment.Spec.Replicas != nil {
+		health.Replicas = *deployment.Spec.Replicas
+	}
+	workloadHealth(&health)
+	if health.Status != apitypes.HealthHealthy && health.Status != apitypes.HealthStopped {
+		for _, condition := range deployment.Status.Conditions {
+			if condition.Status != corev1.ConditionTrue && condition.Message != "" &&
+				(condition.Type == appsv1.DeploymentAvailable || condition.Type == appsv1.DeploymentProgressing) {
+				health.Message = condition.Message
+				break
+			}
+		}
+	}
+	return health
+}
+
+// statefulSetHealth reports the health of a StatefulSet
+func statefulSetHealth(component string, statefulSet *appsv1.StatefulSet) apitypes.ComponentHealth {
+	health := apitypes.ComponentHealth{
+		Component:     component,
+		Workload:      statefulSet.Name,
+		ReadyReplicas: statefulSet.Status.ReadyReplicas,
+		Replicas:      1,
+	}
+	if statefulSet.Spec.Replicas != nil {
+		health.Replicas = *statefulSet.Spec.Replicas
+	}
+	workloadHealth(&health)
+	return health
+}
+
+// GetInstanceHealth reports the health of each component of an instance, derived from the
+// readiness of the Deployments and StatefulSets in its namespace
+func (h *Handler) GetInstanceHealth(c echo.Context) error {
+	name := c.Param("name")
+	ctx := c.Request().Context()
+
+	instance, err := h.getInstanceOrError(c, name)
+	if err != nil {
+		return err
+	}
+
+	namespace := getInstanceNamespace(instance)
+	clientset := h.k8sClient.GetClientset()
+	deployments, err := clientset.AppsV1().Deployments(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		GetLogger(c).Error("Failed to list deployments", "namespace", namespace, "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to get instance health")
+	}
+	statefulSets, err := clientset.AppsV1().StatefulSets(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		GetLogger(c).Error("Failed to list statefulsets", "namespace", namespace, "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to get instance health")
+	}
+
+	found := make(map[string]apitypes.ComponentHealth)
+	for i := range deployments.Items {
+		if component, ok := componentWorkloads[deployments.Items[i].Labels["app.kubernetes.io/name"]]; ok {
+			found[component] = deploymentHealth(component, &deployments.Items[i])
+		}
+	}
+	for i := range statefulSets.Items {
+		if component, ok := componentWorkloads[statefulSets.Items[i].Labels["app.kubernetes.io/name"]]; ok {
+			found[component] = statefulSetHealth(component, &statefulSets.Items[i])
+		}
+	}
+
+	resp := apitypes.InstanceHealthResponse{
+		InstanceName: instance.Spec.ProjectName,
+		Components:   make([]apitypes.ComponentHealth, 0, len(healthComponents)),
+	}
+	healthy, stopped := 0, 0
+	for _, component := range healthComponents {
+		health, ok := found[component]
+		if !ok {
+			health = apitypes.ComponentHealth{Component: component, Status: apitypes.HealthMissing}
+		}
+		switch health.Status {
+		case apitypes.HealthHealthy:
+			healthy++
+		case apitypes.HealthStopped:
+			stopped++
+		}
+		resp.Components = append(resp.Components, health)
+	}
+	switch {
+	case healthy == len(healthComponents):
+		resp.Status = apitypes.HealthHealthy
+	case stopped == len(healthComponents):
+		resp.Status = apitypes.HealthStopped
+	default:
+		resp.Status = apitypes.HealthDegraded
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,149 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/utils/ptr"
+)
+
+// newComponentDeployment returns a Deployment of the my-app instance labeled as the chart
+// labels the workload of a component
+func newComponentDeployment(name string, replicas, ready int32) *appsv1.Deployment {
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "my-app-" + name,
+			Namespace: "supa-my-app",
+			Labels:    map[string]string{"app.kubernetes.io/name": name},
+		},
+		Spec:   appsv1.DeploymentSpec{Replicas: ptr.To(replicas)},
+		Status: appsv1.DeploymentStatus{ReadyReplicas: ready},
+	}
+}
+
+// TestGetInstanceHealth tests the GetInstanceHealth handler
+func TestGetInstanceHealth(t *testing.T) {
+	database := &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "my-app-supabase-db",
+			Namespace: "supa-my-app",
+			Labels:    map[string]string{"app.kubernetes.io/name": "supabase-db"},
+		},
+		Spec:   appsv1.StatefulSetSpec{Replicas: ptr.To(int32(1))},
+		Status: appsv1.StatefulSetStatus{ReadyReplicas: 1},
+	}
+	unavailableRealtime := newComponentDeployment("supabase-realtime", 1, 0)
+	unavailableRealtime.Status.Conditions = []appsv1.DeploymentCondition{{
+		Type:    appsv1.DeploymentAvailable,
+		Status:  corev1.ConditionFalse,
+		Message: "Deployment does not have minimum availability.",
+	}}
+
+	tests := []struct {
+		name             string
+		objects          []runtime.Object
+		expectedStatus   string
+		expectedStatuses map[string]string
+		expectedMessage  string
+	}{
+		{
+			name: "all components ready",
+			objects: []runtime.Object{
+				database,
+				newComponentDeployment("supabase-kong", 1, 1),
+				newComponentDeployment("supabase-auth", 2, 2),
+				newComponentDeployment("supabase-realtime", 1, 1),
+				newComponentDeployment("supabase-storage", 1, 1),
+				newComponentDeployment("supabase-studio", 1, 1),
+			},
+			expectedStatus: apitypes.HealthHealthy,
+			expectedStatuses: map[string]string{
+				apitypes.ComponentPostgres: apitypes.HealthHealthy,
+				apitypes.ComponentGoTrue:   apitypes.HealthHealthy,
+			},
+		},
+		{
+			name: "degraded and unavailable components",
+			objects: []runtime.Object{
+				database,
+				newComponentDeployment("supabase-kong", 1, 1),
+				newComponentDeployment("supabase-auth", 2, 1),
+				unavailableRealtime,
+				newComponentDeployment("supabase-storage", 1, 1),
+				newComponentDeployment("supabase-meta", 1, 0),
+			},
+			expectedStatus: apitypes.HealthDegraded,
+			expectedStatuses: map[string]string{
+				apitypes.ComponentKong:     apitypes.HealthHealthy,
+				apitypes.ComponentGoTrue:   apitypes.HealthDegraded,
+				apitypes.ComponentRealtime: apitypes.HealthUnavailable,
+				apitypes.ComponentStudio:   apitypes.HealthMissing,
+			},
+			expectedMessage: "Deployment does not have minimum availability.",
+		},
+		{
+			name: "suspended instance",
+			objects: []runtime.Object{
+				newComponentDeployment("supabase-db", 0, 0),
+				newComponentDeployment("supabase-kong", 0, 0),
+				newComponentDeployment("supabase-auth", 0, 0),
+				newComponentDeployment("supabase-realtime", 0, 0),
+				newComponentDeployment("supabase-storage", 0, 0),
+				newComponentDeployment("supabase-studio", 0, 0),
+			},
+			expectedStatus: apitypes.HealthStopped,
+			expectedStatuses: map[string]string{
+				apitypes.ComponentPostgres: apitypes.HealthStopped,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewHandler(nil, &mockDBClient{}, newSuspensionCRClient(nil, newOwnedInstance("my-app", "7")),
+				&mockK8sClient{clientset: fake.NewSimpleClientset(tt.objects...)})
+			c, rec := newTestContext(http.MethodGet, "/api/v1/instances/my-app/health", "")
+			c.SetParamNames("name")
+			c.SetParamValues("my-app")
+
+			if err := handler.GetInstanceHealth(c); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var resp apitypes.InstanceHealthResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if resp.Status != tt.expectedStatus {
+				t.Errorf("expected status %q, got %q", tt.expectedStatus, resp.Status)
+			}
+			if len(resp.Components) != 6 {
+				t.Fatalf("expected 6 components, got %+v", resp.Components)
+			}
+			for _, health := range resp.Components {
+				if expected, ok := tt.expectedStatuses[health.Component]; ok && health.Status != expected {
+					t.Errorf("expected %s to be %q, got %q", health.Component, expected, health.Status)
+				}
+				if health.Component == apitypes.ComponentRealtime && health.Message != tt.expectedMessage {
+					t.Errorf("expected realtime message %q, got %q", tt.expectedMessage, health.Message)
+				}
+			}
+		})
+	}
+}
+
+// TestGetInstanceHealthNotFound tests that the health of an unknown instance is not found
+func TestGetInstanceHealthNotFound(t *testing.T) {
+	handler := NewHandler(nil, &mockDBClient{}, newSuspensionCRClient(nil), &mockK8sClient{clientset: fake.NewSimpleClientset()})
+	c, _ := newTestContext(http.MethodGet, "/api/v1/instances/missing/health", "")
+	c.SetParamNames("name")
+	c.SetParamValues("missing")
+
+	assertHTTPError(t, handler.GetInstanceHealth(c), http.StatusNotFound)
+}
@@ -10,9 +10,8 @@ import (
 	"github.com/labstack/echo/v4"
 	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
 	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
-	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"github.com/qubitquilt/supacontrol/server/internal/k8s"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/runtime/schema"
 )
 
 // TestCreateInstance tests creating a Supabase instance
@@ -30,7 +29,7 @@ func TestCreateInstance(t *testing.T) {
 			setupMock: func(cr *mockCRClient) {
 				cr.getSupabaseInstanceFunc = func(_ context.Context, _ string) (*supacontrolv1alpha1.SupabaseInstance, error) {
 					// Return NotFound to indicate instance doesn't exist
-					return nil, apierrors.NewNotFound(schema.GroupResource{}, "")
+					return nil, k8s.ErrInstanceNotFound
 				}
 				cr.createSupabaseInstanceFunc = func(_ context.Context, _ *supacontrolv1alpha1.SupabaseInstance) error {
 					return nil
@@ -55,6 +54,141 @@ func TestCreateInstance(t *testing.T) {
 			expectedStatus: http.StatusConflict,
 			expectedError:  true,
 		},
+		{
+			name:        "instance created concurrently",
+			requestBody: `{"name":"test-app"}`,
+			setupMock: func(cr *mockCRClient) {
+				cr.getSupabaseInstanceFunc = func(_ context.Context, _ string) (*supacontrolv1alpha1.SupabaseInstance, error) {
+					return nil, k8s.ErrInstanceNotFound
+				}
+				cr.createSupabaseInstanceFunc = func(_ context.Context, _ *supacontrolv1alpha1.SupabaseInstance) error {
+					return k8s.ErrAlreadyExists
+				}
+			},
+			expectedStatus: http.StatusConflict,
+			expectedError:  true,
+		},
+		{
+			name:        "instance with custom domains",
+			requestBody: `{"name":"test-app","custom_domains":{"api":"api.acme.io","studio":"studio.acme.io"}}`,
+			setupMock: func(cr *mockCRClient) {
+				cr.getSupabaseInstanceFunc = func(_ context.Context, _ string) (*supacontrolv1alpha1.SupabaseInstance, error) {
+					return nil, k8s.ErrInstanceNotFound
+				}
+				cr.listSupabaseInstancesFunc = func(_ context.Context) (*supacontrolv1alpha1.SupabaseInstanceList, error) {
+					return &supacontrolv1alpha1.SupabaseInstanceList{}, nil
+				}
+				cr.createSupabaseInstanceFunc = func(_ context.Context, instance *supacontrolv1alpha1.SupabaseInstance) error {
+					if instance.Spec.CustomDomains == nil || instance.Spec.CustomDomains.API != "api.acme.io" {
+						return fmt.Errorf("custom domains not set: %+v", instance.Spec.CustomDomains)
+					}
+					return nil
+				}
+			},
+			expectedStatus: http.StatusAccepted,
+			expectedError:  false,
+		},
+		{
+			name:        "instance with network isolation",
+			requestBody: `{"name":"test-app","network_isolation":true}`,
+			setupMock: func(cr *mockCRClient) {
+				cr.getSupabaseInstanceFunc = func(_ context.Context, _ string) (*supacontrolv1alpha1.SupabaseInstance, error) {
+					return nil, k8s.ErrInstanceNotFound
+				}
+				cr.createSupabaseInstanceFunc = func(_ context.Context, instance *supacontrolv1alpha1.SupabaseInstance) error {
+					if !instance.Spec.NetworkIsolation {
+						return fmt.Errorf("network isolation not set")
+					}
+					return nil
+				}
+			},
+			expectedStatus: http.StatusAccepted,
+			expectedError:  false,
+		},
+		{
+			name:        "instance with namespace labels",
+			requestBody: `{"name":"test-app","namespace_labels":{"cost-center":"cc-1234"}}`,
+			setupMock: func(cr *mockCRClient) {
+				cr.getSupabaseInstanceFunc = func(_ context.Context, _ string) (*supacontrolv1alpha1.SupabaseInstance, error) {
+					return nil, k8s.ErrInstanceNotFound
+				}
+				cr.createSupabaseInstanceFunc = func(_ context.Context, instance *supacontrolv1alpha1.SupabaseInstance) error {
+					if instance.Spec.NamespaceLabels["cost-center"] != "cc-1234" {
+						return fmt.Errorf("namespace labels not set: %v", instance.Spec.NamespaceLabels)
+					}
+					return nil
+				}
+			},
+			expectedStatus: http.StatusAccepted,
+			expectedError:  false,
+		},
+		{
+			name:        "reserved namespace label",
+			requestBody: `{"name":"test-app","namespace_labels":{"pod-security.kubernetes.io/enforce":"privileged"}}`,
+			setupMock: func(cr *mockCRClient) {
+				cr.getSupabaseInstanceFunc = func(_ context.Context, _ string) (*supacontrolv1alpha1.SupabaseInstance, error) {
+					return nil, k8s.ErrInstanceNotFound
+				}
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  true,
+		},
+		{
+			name:        "instance with schedule",
+			requestBody: `{"name":"test-app","schedule":{"stop":"0 19 * * 1-5","start":"0 7 * * 1-5"}}`,
+			setupMock: func(cr *mockCRClient) {
+				cr.getSupabaseInstanceFunc = func(_ context.Context, _ string) (*supacontrolv1alpha1.SupabaseInstance, error) {
+					return nil, k8s.ErrInstanceNotFound
+				}
+				cr.createSupabaseInstanceFunc = func(_ context.Context, instance *supacontrolv1alpha1.SupabaseInstance) error {
+					if instance.Spec.Schedule == nil || instance.Spec.Schedule.Stop != "0 19 * * 1-5" {
+						return fmt.Errorf("schedule not set: %+v", instance.Spec.Schedule)
+					}
+					return nil
+				}
+			},
+			expectedStatus: http.StatusAccepted,
+			expectedError:  false,
+		},
+		{
+			name:        "invalid schedule",
+			requestBody: `{"name":"test-app","schedule":{"stop":"evenings","start":"0 7 * * 1-5"}}`,
+			setupMock: func(cr *mockCRClient) {
+				cr.getSupabaseInstanceFunc = func(_ context.Context, _ string) (*supacontrolv1alpha1.SupabaseInstance, error) {
+					return nil, k8s.ErrInstanceNotFound
+				}
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  true,
+		},
+		{
+			name:        "instance with monitoring",
+			requestBody: `{"name":"test-app","monitoring":true}`,
+			setupMock: func(cr *mockCRClient) {
+				cr.getSupabaseInstanceFunc = func(_ context.Context, _ string) (*supacontrolv1alpha1.SupabaseInstance, error) {
+					return nil, k8s.ErrInstanceNotFound
+				}
+				cr.createSupabaseInstanceFunc = func(_ context.Context, instance *supacontrolv1alpha1.SupabaseInstance) error {
+					if instance.Spec.Monitoring == nil || !instance.Spec.Monitoring.Enabled {
+						return fmt.Errorf("monitoring not enabled")
+					}
+					return nil
+				}
+			},
+			expectedStatus: http.StatusAccepted,
+			expectedError:  false,
+		},
+		{
+			name:        "invalid custom domain",
+			requestBody: `{"name":"test-app","custom_domains":{"api":"api_acme"}}`,
+			setupMock: func(cr *mockCRClient) {
+				cr.getSupabaseInstanceFunc = func(_ context.Context, _ string) (*supacontrolv1alpha1.SupabaseInstance, error) {
+					return nil, k8s.ErrInstanceNotFound
+				}
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  true,
+		},
 		{
 			name:           "empty instance name",
 			requestBody:    `{"name":""}`,
@@ -76,7 +210,7 @@ func TestCreateInstance(t *testing.T) {
 			mockCR := &mockCRClient{}
 			tt.setupMock(mockCR)
 
-			handler := NewHandler(nil, nil, mockCR, nil)
+			handler := NewHandler(nil, &mockDBClient{}, mockCR, nil)
 			c, rec := newTestContext(http.MethodPost, "/api/v1/instances", tt.requestBody)
 
 			err := handler.CreateInstance(c)
@@ -185,7 +319,7 @@ func TestListInstances(t *testing.T) {
 			mockCR := &mockCRClient{}
 			tt.setupMock(mockCR)
 
-			handler := NewHandler(nil, nil, mockCR, nil)
+			handler := NewHandler(nil, &mockDBClient{}, mockCR, nil)
 			c, rec := newTestContext(http.MethodGet, "/api/v1/instances", "")
 
 			err := handler.ListInstances(c)
@@ -254,7 +388,7 @@ func TestGetInstance(t *testing.T) {
 			instanceName: "nonexistent",
 			setupMock: func(cr *mockCRClient) {
 				cr.getSupabaseInstanceFunc = func(_ context.Context, _ string) (*supacontrolv1alpha1.SupabaseInstance, error) {
-					return nil, apierrors.NewNotFound(schema.GroupResource{}, "")
+					return nil, k8s.ErrInstanceNotFound
 				}
 			},
 			expectedStatus: http.StatusNotFound,
@@ -267,7 +401,7 @@ func TestGetInstance(t *testing.T) {
 			mockCR := &mockCRClient{}
 			tt.setupMock(mockCR)
 
-			handler := NewHandler(nil, nil, mockCR, nil)
+			handler := NewHandler(nil, &mockDBClient{}, mockCR, nil)
 			c, rec := newTestContext(http.MethodGet, "/api/v1/instances/"+tt.instanceName, "")
 			c.SetParamNames("name")
 			c.SetParamValues(tt.instanceName)
@@ -306,11 +440,52 @@ func TestGetInstance(t *testing.T) {
 	}
 }
 
+// TestGetInstance_PhaseHistory tests that getting an instance includes the phase
+// transitions recorded by the controller
+func TestGetInstance_PhaseHistory(t *testing.T) {
+	instance := &supacontrolv1alpha1.SupabaseInstance{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "test-app",
+			Annotations: map[string]string{
+				supacontrolv1alpha1.AnnotationPhaseHistory: `[{"to":"Pending","at":"2026-01-02T10:00:00Z"},` +
+					`{"from":"Pending","to":"Provisioning","at":"2026-01-02T10:00:05Z","reason":"ProvisioningJobCreated"}]`,
+			},
+		},
+		Spec:   supacontrolv1alpha1.SupabaseInstanceSpec{ProjectName: "test-app"},
+		Status: supacontrolv1alpha1.SupabaseInstanceStatus{Phase: supacontrolv1alpha1.PhaseProvisioning},
+	}
+	mockCR := &mockCRClient{
+		getSupabaseInstanceFunc: func(_ context.Context, _ string) (*supacontrolv1alpha1.SupabaseInstance, error) {
+			return instance, nil
+		},
+	}
+	handler := NewHandler(nil, &mockDBClient{}, mockCR, nil)
+	c, rec := newTestContext(http.MethodGet, "/api/v1/instances/test-app", "")
+	c.SetParamNames("name")
+	c.SetParamValues("test-app")
+
+	if err := handler.GetInstance(c); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var resp apitypes.GetInstanceResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	history := resp.Instance.PhaseHistory
+	if len(history) != 2 {
+		t.Fatalf("expected 2 transitions, got %+v", history)
+	}
+	if history[1].From != "Pending" || history[1].To != "Provisioning" || history[1].Reason != "ProvisioningJobCreated" {
+		t.Errorf("unexpected transition %+v", history[1])
+	}
+}
+
 // TestDeleteInstance tests deleting an instance
 func TestDeleteInstance(t *testing.T) {
 	tests := []struct {
 		name           string
 		instanceName   string
+		role           string
 		setupMock      func(*mockCRClient)
 		expectedStatus int
 		expectedError  bool
@@ -318,6 +493,7 @@ func TestDeleteInstance(t *testing.T) {
 		{
 			name:         "successful delete",
 			instanceName: "test-app",
+			role:         "admin",
 			setupMock: func(cr *mockCRClient) {
 				cr.getSupabaseInstanceFunc = func(_ context.Context, _ string) (*supacontrolv1alpha1.SupabaseInstance, error) {
 					return &supacontrolv1alpha1.SupabaseInstance{
@@ -335,14 +511,31 @@ func TestDeleteInstance(t *testing.T) {
 		{
 			name:         "instance not found",
 			instanceName: "nonexistent",
+			role:         "admin",
 			setupMock: func(cr *mockCRClient) {
 				cr.getSupabaseInstanceFunc = func(_ context.Context, _ string) (*supacontrolv1alpha1.SupabaseInstance, error) {
-					return nil, apierrors.NewNotFound(schema.GroupResource{}, "")
+					return nil, k8s.ErrInstanceNotFound
 				}
 			},
 			expectedStatus: http.StatusNotFound,
 			expectedError:  true,
 		},
+		{
+			name:         "another user's instance",
+			instanceName: "test-app",
+			role:         "user",
+			setupMock: func(cr *mockCRClient) {
+				cr.getSupabaseInstanceFunc = func(_ context.Context, _ string) (*supacontrolv1alpha1.SupabaseInstance, error) {
+					return newOwnedInstance("test-app", "9"), nil
+				}
+				cr.deleteSupabaseInstanceFunc = func(_ context.Context, _ string) error {
+					t.Fatal("expected the instance not to be deleted")
+					return nil
+				}
+			},
+			expectedStatus: http.StatusForbidden,
+			expectedError:  true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -350,10 +543,11 @@ func TestDeleteInstance(t *testing.T) {
 			mockCR := &mockCRClient{}
 			tt.setupMock(mockCR)
 
-			handler := NewHandler(nil, nil, mockCR, nil)
+			handler := NewHandler(nil, &mockDBClient{}, mockCR, nil)
 			c, rec := newTestContext(http.MethodDelete, "/api/v1/instances/"+tt.instanceName, "")
 			c.SetParamNames("name")
 			c.SetParamValues(tt.instanceName)
+			setAuthContext(c, 7, "someone", tt.role)
 
 			err := handler.DeleteInstance(c)
 
@@ -0,0 +1,63 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+)
+
+// isolationLevels maps the CR isolation levels to their API form
+var isolationLevels = map[supacontrolv1alpha1.IsolationLevel]string{
+	supacontrolv1alpha1.IsolationNamespace:   apitypes.IsolationNamespace,
+	supacontrolv1alpha1.IsolationVCluster:    apitypes.IsolationVCluster,
+	supacontrolv1alpha1.IsolationKataRuntime: apitypes.IsolationKataRuntime,
+}
+
+// normalizeIsolation validates a requested isolation and converts it to its CR form.
+// Namespace isolation is the default, so nil is returned for it.
+func normalizeIsolation(req *apitypes.InstanceIsolation) (*supacontrolv1alpha1.Isolation, error) {
+	if req == nil {
+		return nil, nil
+	}
+	isolation := &supacontrolv1alpha1.Isolation{}
+	switch req.Level {
+	case "", apitypes.IsolationNamespace:
+		if req.Fallback != "" {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, "isolation fallback requires vcluster or kata-runtime isolation")
+		}
+		return nil, nil
+	case apitypes.IsolationVCluster:
+		isolation.Level = supacontrolv1alpha1.IsolationVCluster
+	case apitypes.IsolationKataRuntime:
+		isolation.Level = supacontrolv1alpha1.IsolationKataRuntime
+	default:
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "isolation level must be 'namespace', 'vcluster' or 'kata-runtime'")
+	}
+	switch req.Fallback {
+	case "", apitypes.IsolationFallbackFail:
+		isolation.Fallback = supacontrolv1alpha1.IsolationFallbackFail
+	case apitypes.IsolationFallbackNamespace:
+		isolation.Fallback = supacontrolv1alpha1.IsolationFallbackNamespace
+	default:
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "isolation fallback must be 'fail' or 'namespace'")
+	}
+	return isolation, nil
+}
+
+// isolationToAPIType converts an instance's isolation to its API form, omitting namespace isolation
+func isolationToAPIType(isolation *supacontrolv1alpha1.Isolation) *apitypes.InstanceIsolation {
+	if isolation == nil || isolation.Level == "" || isolation.Level == supacontrolv1alpha1.IsolationNamespace {
+		return nil
+	}
+	fallback := apitypes.IsolationFallbackFail
+	if isolation.Fallback == supacontrolv1alpha1.IsolationFallbackNamespace {
+		fallback = apitypes.IsolationFallbackNamespace
+	}
+	return &apitypes.InstanceIsolation{
+		Level:    isolationLevels[isolation.Level],
+		Fallback: fallback,
+	}
+}
@@ -0,0 +1,61 @@
+package api
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+)
+
+// TestNormalizeIsolation tests isolation validation and conversion
+func TestNormalizeIsolation(t *testing.T) {
+	tests := []struct {
+		name      string
+		req       *apitypes.InstanceIsolation
+		expected  *supacontrolv1alpha1.Isolation
+		expectErr bool
+	}{
+		{name: "nil request", req: nil, expected: nil},
+		{name: "namespace", req: &apitypes.InstanceIsolation{Level: apitypes.IsolationNamespace}, expected: nil},
+		{
+			name: "vcluster fails by default",
+			req:  &apitypes.InstanceIsolation{Level: apitypes.IsolationVCluster},
+			expected: &supacontrolv1alpha1.Isolation{
+				Level:    supacontrolv1alpha1.IsolationVCluster,
+				Fallback: supacontrolv1alpha1.IsolationFallbackFail,
+			},
+		},
+		{
+			name: "kata-runtime with namespace fallback",
+			req:  &apitypes.InstanceIsolation{Level: apitypes.IsolationKataRuntime, Fallback: apitypes.IsolationFallbackNamespace},
+			expected: &supacontrolv1alpha1.Isolation{
+				Level:    supacontrolv1alpha1.IsolationKataRuntime,
+				Fallback: supacontrolv1alpha1.IsolationFallbackNamespace,
+			},
+		},
+		{name: "unknown level", req: &apitypes.InstanceIsolation{Level: "gvisor"}, expectErr: true},
+		{name: "unknown fallback", req: &apitypes.InstanceIsolation{Level: apitypes.IsolationVCluster, Fallback: "retry"}, expectErr: true},
+		{name: "fallback without stronger isolation", req: &apitypes.InstanceIsolation{Fallback: apitypes.IsolationFallbackNamespace}, expectErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := normalizeIsolation(tt.req)
+			if tt.expectErr {
+				assertHTTPError(t, err, http.StatusBadRequest)
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("expected %+v, got %+v", tt.expected, got)
+			}
+			if api := isolationToAPIType(got); tt.expected != nil && api.Level != tt.req.Level {
+				t.Errorf("round trip level = %q, want %q", api.Level, tt.req.Level)
+			}
+		})
+	}
+}
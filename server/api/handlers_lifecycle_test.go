@@ -6,11 +6,14 @@ import (
 	"net/http"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
 	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+	"github.com/qubitquilt/supacontrol/server/internal/k8s"
 	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -54,7 +57,7 @@ func TestStartInstance(t *testing.T) {
 			instanceName: "nonexistent",
 			setupMock: func(cr *mockCRClient) {
 				cr.getSupabaseInstanceFunc = func(_ context.Context, name string) (*supacontrolv1alpha1.SupabaseInstance, error) {
-					return nil, apierrors.NewNotFound(schema.GroupResource{}, name)
+					return nil, k8s.ErrInstanceNotFound
 				}
 			},
 			expectedStatus: http.StatusNotFound,
@@ -79,6 +82,28 @@ func TestStartInstance(t *testing.T) {
 			expectedStatus: http.StatusConflict,
 			expectedError:  true,
 		},
+		{
+			name:         "suspended instance",
+			instanceName: "suspended-instance",
+			setupMock: func(cr *mockCRClient) {
+				cr.getSupabaseInstanceFunc = func(_ context.Context, name string) (*supacontrolv1alpha1.SupabaseInstance, error) {
+					return &supacontrolv1alpha1.SupabaseInstance{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: name,
+						},
+						Spec: supacontrolv1alpha1.SupabaseInstanceSpec{
+							ProjectName: name,
+							Paused:      true,
+							Suspension: &supacontrolv1alpha1.Suspension{
+								Reason: supacontrolv1alpha1.SuspensionReasonBilling,
+							},
+						},
+					}, nil
+				}
+			},
+			expectedStatus: http.StatusForbidden,
+			expectedError:  true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -147,12 +172,38 @@ func TestStopInstance(t *testing.T) {
 			expectedStatus: http.StatusOK,
 			expectedError:  false,
 		},
+		{
+			name:         "retried after a conflicting controller write",
+			instanceName: "busy-instance",
+			setupMock: func(cr *mockCRClient) {
+				cr.getSupabaseInstanceFunc = func(_ context.Context, name string) (*supacontrolv1alpha1.SupabaseInstance, error) {
+					return &supacontrolv1alpha1.SupabaseInstance{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: name,
+						},
+						Spec: supacontrolv1alpha1.SupabaseInstanceSpec{
+							ProjectName: name,
+						},
+					}, nil
+				}
+				conflicts := 2
+				cr.updateSupabaseInstanceFunc = func(_ context.Context, instance *supacontrolv1alpha1.SupabaseInstance) error {
+					if conflicts > 0 {
+						conflicts--
+						return apierrors.NewConflict(schema.GroupResource{}, instance.Name, fmt.Errorf("object was modified"))
+					}
+					return nil
+				}
+			},
+			expectedStatus: http.StatusOK,
+			expectedError:  false,
+		},
 		{
 			name:         "instance not found",
 			instanceName: "nonexistent",
 			setupMock: func(cr *mockCRClient) {
 				cr.getSupabaseInstanceFunc = func(_ context.Context, name string) (*supacontrolv1alpha1.SupabaseInstance, error) {
-					return nil, apierrors.NewNotFound(schema.GroupResource{}, name)
+					return nil, k8s.ErrInstanceNotFound
 				}
 			},
 			expectedStatus: http.StatusNotFound,
@@ -214,6 +265,98 @@ func TestStopInstance(t *testing.T) {
 	}
 }
 
+// TestRetryInstance tests the RetryInstance handler
+func TestRetryInstance(t *testing.T) {
+	tests := []struct {
+		name           string
+		phase          supacontrolv1alpha1.SupabaseInstancePhase
+		getErr         error
+		updateErr      error
+		expectedStatus int
+	}{
+		{
+			name:           "failed instance is reset to pending",
+			phase:          supacontrolv1alpha1.PhaseFailed,
+			expectedStatus: http.StatusAccepted,
+		},
+		{
+			name:           "running instance cannot be retried",
+			phase:          supacontrolv1alpha1.PhaseRunning,
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			name:           "instance not found",
+			getErr:         k8s.ErrInstanceNotFound,
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "status update fails",
+			phase:          supacontrolv1alpha1.PhaseFailed,
+			updateErr:      fmt.Errorf("conflict"),
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var updated *supacontrolv1alpha1.SupabaseInstance
+			mockCR := &mockCRClient{
+				getSupabaseInstanceFunc: func(_ context.Context, name string) (*supacontrolv1alpha1.SupabaseInstance, error) {
+					if tt.getErr != nil {
+						return nil, tt.getErr
+					}
+					return &supacontrolv1alpha1.SupabaseInstance{
+						ObjectMeta: metav1.ObjectMeta{Name: name},
+						Spec:       supacontrolv1alpha1.SupabaseInstanceSpec{ProjectName: name},
+						Status: supacontrolv1alpha1.SupabaseInstanceStatus{
+							Phase:               tt.phase,
+							ErrorMessage:        "Provisioning Job failed after retries",
+							ProvisioningJobName: "supacontrol-provision-my-app",
+							RetryCount:          3,
+							FailureType:         supacontrolv1alpha1.FailureTransient,
+							NextRetryAt:         &metav1.Time{Time: time.Now().Add(time.Hour)},
+						},
+					}, nil
+				},
+				updateSupabaseInstanceStatusFunc: func(_ context.Context, instance *supacontrolv1alpha1.SupabaseInstance) error {
+					updated = instance
+					return tt.updateErr
+				},
+			}
+			clientset := fake.NewSimpleClientset(&batchv1.Job{
+				ObjectMeta: metav1.ObjectMeta{Name: "supacontrol-provision-my-app", Namespace: "supacontrol-system"},
+			})
+
+			handler := NewHandler(nil, nil, mockCR, &mockK8sClient{clientset: clientset})
+			c, rec := newTestContext(http.MethodPost, "/api/v1/instances/my-app/retry", "")
+			c.SetParamNames("name")
+			c.SetParamValues("my-app")
+
+			err := handler.RetryInstance(c)
+			if tt.expectedStatus != http.StatusAccepted {
+				assertHTTPError(t, err, tt.expectedStatus)
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if rec.Code != http.StatusAccepted {
+				t.Errorf("expected status %d, got %d", http.StatusAccepted, rec.Code)
+			}
+
+			status := updated.Status
+			if status.Phase != supacontrolv1alpha1.PhasePending || status.ErrorMessage != "" || status.ProvisioningJobName != "" || status.RetryCount != 0 ||
+				status.FailureType != "" || status.NextRetryAt != nil {
+				t.Errorf("expected cleared Pending status, got %+v", status)
+			}
+			jobs, _ := clientset.BatchV1().Jobs("supacontrol-system").List(context.Background(), metav1.ListOptions{})
+			if len(jobs.Items) != 0 {
+				t.Errorf("expected failed provisioning Job to be deleted, found %d", len(jobs.Items))
+			}
+		})
+	}
+}
+
 // TestGetInstanceNamespace tests the namespace helper function
 func TestGetInstanceNamespace(t *testing.T) {
 	tests := []struct {
@@ -290,6 +433,19 @@ func TestConvertCRToAPIType(t *testing.T) {
 			},
 			expected: apitypes.StatusRunning,
 		},
+		{
+			name: "stopped phase",
+			cr: &supacontrolv1alpha1.SupabaseInstance{
+				Spec: supacontrolv1alpha1.SupabaseInstanceSpec{
+					ProjectName: "test",
+					Paused:      true,
+				},
+				Status: supacontrolv1alpha1.SupabaseInstanceStatus{
+					Phase: supacontrolv1alpha1.PhaseStopped,
+				},
+			},
+			expected: apitypes.StatusStopped,
+		},
 		{
 			name: "failed phase",
 			cr: &supacontrolv1alpha1.SupabaseInstance{
@@ -318,6 +474,63 @@ func TestConvertCRToAPIType(t *testing.T) {
 	}
 }
 
+// TestConvertCRToAPIType_UpgradeAvailable tests that instances behind the latest indexed
+// chart version are flagged
+func TestConvertCRToAPIType_UpgradeAvailable(t *testing.T) {
+	tests := []struct {
+		name         string
+		catalog      ChartCatalog
+		chartVersion string
+		expected     bool
+	}{
+		{name: "older chart", catalog: staticChartCatalog("0.2.0"), chartVersion: "0.1.3", expected: true},
+		{name: "latest chart", catalog: staticChartCatalog("0.2.0"), chartVersion: "0.2.0"},
+		{name: "not installed yet", catalog: staticChartCatalog("0.2.0")},
+		{name: "nothing indexed yet", catalog: staticChartCatalog(""), chartVersion: "0.1.3"},
+		{name: "no catalog", chartVersion: "0.1.3"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var opts []HandlerOption
+			if tt.catalog != nil {
+				opts = append(opts, WithChartCatalog(tt.catalog))
+			}
+			handler := NewHandler(nil, nil, nil, nil, opts...)
+			cr := &supacontrolv1alpha1.SupabaseInstance{
+				Spec:   supacontrolv1alpha1.SupabaseInstanceSpec{ProjectName: "test"},
+				Status: supacontrolv1alpha1.SupabaseInstanceStatus{Phase: supacontrolv1alpha1.PhaseRunning, ChartVersion: tt.chartVersion},
+			}
+
+			c, _ := newTestContext(http.MethodGet, "/", "")
+			if got := handler.convertCRToAPIType(c, cr).UpgradeAvailable; got != tt.expected {
+				t.Errorf("expected upgrade available %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+// TestConvertCRToAPIType_HelmRelease tests that the Helm release revision and status are exposed
+func TestConvertCRToAPIType_HelmRelease(t *testing.T) {
+	handler := NewHandler(nil, nil, nil, nil)
+	cr := &supacontrolv1alpha1.SupabaseInstance{
+		Spec: supacontrolv1alpha1.SupabaseInstanceSpec{ProjectName: "test"},
+		Status: supacontrolv1alpha1.SupabaseInstanceStatus{
+			Phase:             supacontrolv1alpha1.PhaseRunning,
+			ChartVersion:      "0.1.3",
+			HelmRevision:      4,
+			HelmReleaseStatus: "failed",
+		},
+	}
+
+	c, _ := newTestContext(http.MethodGet, "/", "")
+	instance := handler.convertCRToAPIType(c, cr)
+	if instance.HelmRevision != 4 || instance.HelmReleaseStatus != "failed" || instance.ChartVersion != "0.1.3" {
+		t.Errorf("unexpected Helm release fields: revision %d, status %q, chart %q",
+			instance.HelmRevision, instance.HelmReleaseStatus, instance.ChartVersion)
+	}
+}
+
 // TestRestartInstance tests the RestartInstance handler
 func TestRestartInstance(t *testing.T) {
 	tests := []struct {
@@ -370,7 +583,7 @@ func TestRestartInstance(t *testing.T) {
 			instanceName: "nonexistent",
 			setupMock: func(cr *mockCRClient, _ *fake.Clientset) {
 				cr.getSupabaseInstanceFunc = func(_ context.Context, _ string) (*supacontrolv1alpha1.SupabaseInstance, error) {
-					return nil, apierrors.NewNotFound(schema.GroupResource{}, "")
+					return nil, k8s.ErrInstanceNotFound
 				}
 			},
 			expectedStatus: http.StatusNotFound,
@@ -385,7 +598,7 @@ func TestRestartInstance(t *testing.T) {
 			mockK8s := &mockK8sClient{clientset: fakeClientset}
 			tt.setupMock(mockCR, fakeClientset)
 
-			handler := NewHandler(nil, nil, mockCR, mockK8s)
+			handler := NewHandler(nil, &mockDBClient{}, mockCR, mockK8s)
 			c, rec := newTestContext(http.MethodPost, fmt.Sprintf("/api/v1/instances/%s/restart", tt.instanceName), "")
 			c.SetParamNames("name")
 			c.SetParamValues(tt.instanceName)
@@ -468,7 +681,7 @@ func TestGetLogs(t *testing.T) {
 			instanceName: "nonexistent",
 			setupMock: func(cr *mockCRClient, _ *fake.Clientset) {
 				cr.getSupabaseInstanceFunc = func(_ context.Context, _ string) (*supacontrolv1alpha1.SupabaseInstance, error) {
-					return nil, apierrors.NewNotFound(schema.GroupResource{}, "")
+					return nil, k8s.ErrInstanceNotFound
 				}
 			},
 			expectedStatus: http.StatusNotFound,
@@ -550,3 +763,87 @@ func TestGetLogs(t *testing.T) {
 		})
 	}
 }
+
+// TestGetLogsUsesLogClient tests that logs are fetched with the dedicated log client
+func TestGetLogsUsesLogClient(t *testing.T) {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "db-pod", Namespace: "supa-my-app"},
+		Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "postgres"}}},
+	}
+	handler := NewHandler(nil, &mockDBClient{}, newSuspensionCRClient(nil, newOwnedInstance("my-app", "7")),
+		&mockK8sClient{clientset: fake.NewSimpleClientset()},
+		WithLogClient(&mockK8sClient{clientset: fake.NewSimpleClientset(pod)}))
+
+	c, rec := newTestContext(http.MethodGet, "/api/v1/instances/my-app/logs", "")
+	c.SetParamNames("name")
+	c.SetParamValues("my-app")
+
+	if err := handler.GetLogs(c); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(rec.Body.String(), "=== Logs from pod: db-pod ===") {
+		t.Errorf("expected logs fetched with the log client, got %q", rec.Body.String())
+	}
+}
+
+// TestGetLogsFilters tests filtering logs by container and text
+func TestGetLogsFilters(t *testing.T) {
+	dbPod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "db-pod", Namespace: "supa-my-app",
+			Labels: map[string]string{"app.kubernetes.io/name": "supabase-db"}},
+		Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "supabase-db"}}},
+	}
+	kongPod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "kong-pod", Namespace: "supa-my-app"},
+		Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "kong"}}},
+	}
+	tests := []struct {
+		name           string
+		query          string
+		expectedStatus int
+		expected       []string
+		unexpected     []string
+	}{
+		{name: "component", query: "container=postgres", expectedStatus: http.StatusOK,
+			expected: []string{"=== Logs from pod: db-pod ===", "fake logs"}, unexpected: []string{"kong-pod"}},
+		{name: "container name", query: "container=kong", expectedStatus: http.StatusOK,
+			expected: []string{"--- Container: kong ---"}, unexpected: []string{"db-pod"}},
+		{name: "no matching container", query: "container=studio", expectedStatus: http.StatusOK,
+			expected: []string{`No containers matching "studio"`}},
+		{name: "grep drops other lines", query: "container=postgres&grep=AUDIT", expectedStatus: http.StatusOK,
+			expected: []string{"db-pod"}, unexpected: []string{"fake logs"}},
+		{name: "grep keeps matching lines", query: "grep=fake", expectedStatus: http.StatusOK,
+			expected: []string{"fake logs"}},
+		{name: "grep too long", query: "grep=" + strings.Repeat("a", maxLogGrepLength+1), expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewHandler(nil, &mockDBClient{}, newSuspensionCRClient(nil, newOwnedInstance("my-app", "7")),
+				&mockK8sClient{clientset: fake.NewSimpleClientset(dbPod, kongPod)})
+			c, rec := newTestContext(http.MethodGet, "/api/v1/instances/my-app/logs?"+tt.query, "")
+			c.SetParamNames("name")
+			c.SetParamValues("my-app")
+
+			err := handler.GetLogs(c)
+			if tt.expectedStatus != http.StatusOK {
+				assertHTTPError(t, err, tt.expectedStatus)
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			body := rec.Body.String()
+			for _, want := range tt.expected {
+				if !strings.Contains(body, want) {
+					t.Errorf("expected output to contain %q, got %q", want, body)
+				}
+			}
+			for _, unwanted := range tt.unexpected {
+				if strings.Contains(body, unwanted) {
+					t.Errorf("expected output not to contain %q, got %q", unwanted, body)
+				}
+			}
+		})
+	}
+}
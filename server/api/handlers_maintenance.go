@@ -0,0 +1,70 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+	"github.com/qubitquilt/supacontrol/server/internal/i18n"
+)
+
+// maintenanceToAPIType converts an instance's maintenance to its API form
+func maintenanceToAPIType(instance *supacontrolv1alpha1.SupabaseInstance) *apitypes.InstanceMaintenance {
+	maintenance := instance.Spec.Maintenance
+	if maintenance == nil {
+		return nil
+	}
+	return &apitypes.InstanceMaintenance{
+		Message:   maintenance.Message,
+		StartedAt: maintenance.StartedAt.Time,
+		Active:    meta.IsStatusConditionTrue(instance.Status.Conditions, supacontrolv1alpha1.ConditionTypeMaintenance),
+	}
+}
+
+// UpdateInstanceMaintenance puts an instance into maintenance, where the controller points
+// its ingresses at a maintenance page, or takes it out again (admins, operators and the
+// instance owner only). Routes and workloads are kept, so the instance can be upgraded by
+// hand and put back online without a redeploy.
+func (h *Handler) UpdateInstanceMaintenance(c echo.Context) error {
+	var req apitypes.UpdateMaintenanceRequest
+	if err := bindRequest(c, &req); err != nil {
+		return err
+	}
+	if len(req.Message) > apitypes.MaxMaintenanceMessageLength {
+		return echo.NewHTTPError(http.StatusBadRequest, i18n.Msg("maintenance message must be at most %d characters", apitypes.MaxMaintenanceMessageLength))
+	}
+
+	name := c.Param("name")
+	authCtx := GetAuthContext(c)
+	instance, err := h.patchInstance(c, name, func(instance *supacontrolv1alpha1.SupabaseInstance) error {
+		if !isAdminOrOwner(authCtx, instance) && authCtx.Role != RoleOperator {
+			return echo.NewHTTPError(http.StatusForbidden, "only admins, operators and the instance owner can change maintenance mode")
+		}
+		if !req.Enabled {
+			if instance.Spec.Maintenance == nil {
+				return echo.NewHTTPError(http.StatusConflict, "instance is not in maintenance")
+			}
+			instance.Spec.Maintenance = nil
+			return nil
+		}
+
+		if instance.Spec.Maintenance == nil {
+			instance.Spec.Maintenance = &supacontrolv1alpha1.Maintenance{StartedAt: metav1.Now()}
+		}
+		instance.Spec.Maintenance.Message = req.Message
+		return nil
+	}, "failed to update maintenance mode")
+	if err != nil {
+		return err
+	}
+
+	h.recordAudit(c, "instance.maintenance.update", "instance", name, map[string]string{"enabled": fmt.Sprint(req.Enabled)})
+	return c.JSON(http.StatusOK, apitypes.GetInstanceResponse{
+		Instance: h.convertCRToAPIType(c, instance),
+	})
+}
@@ -0,0 +1,98 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+)
+
+// TestUpdateInstanceMaintenance tests the UpdateInstanceMaintenance handler
+func TestUpdateInstanceMaintenance(t *testing.T) {
+	startedAt := metav1.NewTime(time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC))
+	tests := []struct {
+		name            string
+		userID          int64
+		role            string
+		inMaintenance   bool
+		body            string
+		expectedStatus  int
+		expectedMessage string
+		expectCleared   bool
+	}{
+		{name: "owner enables", userID: 7, role: "user", body: `{"enabled":true,"message":"upgrading"}`, expectedStatus: http.StatusOK, expectedMessage: "upgrading"},
+		{name: "operator enables", userID: 9, role: "operator", body: `{"enabled":true}`, expectedStatus: http.StatusOK},
+		{name: "message updated", userID: 1, role: "admin", inMaintenance: true, body: `{"enabled":true,"message":"nearly done"}`, expectedStatus: http.StatusOK, expectedMessage: "nearly done"},
+		{name: "owner disables", userID: 7, role: "user", inMaintenance: true, body: `{"enabled":false}`, expectedStatus: http.StatusOK, expectCleared: true},
+		{name: "not in maintenance", userID: 7, role: "user", body: `{"enabled":false}`, expectedStatus: http.StatusConflict},
+		{name: "other user", userID: 9, role: "user", body: `{"enabled":true}`, expectedStatus: http.StatusForbidden},
+		{name: "message too long", userID: 7, role: "user", body: `{"enabled":true,"message":"` + strings.Repeat("x", 501) + `"}`, expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			instance := newOwnedInstance("my-app", "7")
+			if tt.inMaintenance {
+				instance.Spec.Maintenance = &supacontrolv1alpha1.Maintenance{Message: "upgrading", StartedAt: startedAt}
+			}
+			var updated *supacontrolv1alpha1.SupabaseInstance
+			cr := newSuspensionCRClient(nil, instance)
+			cr.updateSupabaseInstanceFunc = func(_ context.Context, instance *supacontrolv1alpha1.SupabaseInstance) error {
+				updated = instance
+				return nil
+			}
+			handler := NewHandler(nil, &mockDBClient{}, cr, nil)
+			c, rec := newTestContext(http.MethodPost, "/api/v1/instances/my-app/maintenance", tt.body)
+			c.SetParamNames("name")
+			c.SetParamValues("my-app")
+			setAuthContext(c, tt.userID, "someone", tt.role)
+
+			err := handler.UpdateInstanceMaintenance(c)
+			if tt.expectedStatus != http.StatusOK {
+				assertHTTPError(t, err, tt.expectedStatus)
+				if updated != nil {
+					t.Error("expected the instance not to be updated")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if updated == nil {
+				t.Fatal("expected the instance to be updated")
+			}
+			if tt.expectCleared {
+				if updated.Spec.Maintenance != nil {
+					t.Errorf("expected the maintenance to be cleared, got %+v", updated.Spec.Maintenance)
+				}
+				return
+			}
+
+			maintenance := updated.Spec.Maintenance
+			if maintenance == nil || maintenance.Message != tt.expectedMessage {
+				t.Fatalf("expected maintenance with message %q, got %+v", tt.expectedMessage, maintenance)
+			}
+			if tt.inMaintenance && !maintenance.StartedAt.Equal(&startedAt) {
+				t.Errorf("expected the start of the maintenance to be kept, got %v", maintenance.StartedAt)
+			}
+			if maintenance.StartedAt.IsZero() {
+				t.Error("expected the start of the maintenance to be set")
+			}
+
+			var resp apitypes.GetInstanceResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if resp.Instance.Maintenance == nil || resp.Instance.Maintenance.Active {
+				t.Errorf("expected a pending maintenance in the response, got %+v", resp.Instance.Maintenance)
+			}
+		})
+	}
+}
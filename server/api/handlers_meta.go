@@ -0,0 +1,93 @@
+package api
+
+import (
+	"net/http"
+	"sort"
+
+	"github.com/labstack/echo/v4"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+	"github.com/qubitquilt/supacontrol/server/controllers"
+	"github.com/qubitquilt/supacontrol/server/internal/k8s"
+	"github.com/qubitquilt/supacontrol/server/internal/profiles"
+)
+
+// defaultStorageClassAnnotation marks the cluster's default StorageClass
+const defaultStorageClassAnnotation = "storageclass.kubernetes.io/is-default-class"
+
+// sortedKeys returns the keys of an enum map in sorted order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// GetMetaEnums returns the values the API accepts and reports, for clients building
+// pickers. Static values come from the same tables request validation uses; chart
+// versions, ingress classes and storage classes are read live and left empty when they
+// cannot be read.
+func (h *Handler) GetMetaEnums(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	isolation := make([]string, 0, len(isolationLevels))
+	for _, level := range isolationLevels {
+		isolation = append(isolation, level)
+	}
+	sort.Strings(isolation)
+
+	enums := apitypes.MetaEnums{
+		Statuses:          apitypes.InstanceStatuses(),
+		Phases:            supacontrolv1alpha1.AllPhases(),
+		PlacementModes:    []string{apitypes.PlacementShared, apitypes.PlacementDedicated},
+		IsolationLevels:   isolation,
+		PoolModes:         sortedKeys(poolModes),
+		SuspensionReasons: sortedKeys(suspensionReasons),
+		ProfileTypes:      profiles.Types(),
+		Components:        k8s.Components(),
+		EnvVariables:      controllers.AllowedEnv(),
+		ChartVersions:     []string{},
+		IngressClasses:    []apitypes.ClusterClass{},
+		StorageClasses:    []apitypes.ClusterClass{},
+		PostgresVersions:  h.postgresVersions(c, ""),
+	}
+
+	if h.chartResolver != nil {
+		if versions, err := h.chartResolver.ChartVersions(ctx); err != nil {
+			GetLogger(c).Warn("Failed to list chart versions", "error", err)
+		} else {
+			enums.ChartVersions = versions
+		}
+	}
+
+	if h.k8sClient != nil {
+		clientset := h.k8sClient.GetClientset()
+		if classes, err := clientset.NetworkingV1().IngressClasses().List(ctx, metav1.ListOptions{}); err != nil {
+			GetLogger(c).Warn("Failed to list ingress classes", "error", err)
+		} else {
+			for _, class := range classes.Items {
+				enums.IngressClasses = append(enums.IngressClasses, apitypes.ClusterClass{
+					Name:    class.Name,
+					Default: class.Annotations[networkingv1.AnnotationIsDefaultIngressClass] == "true",
+				})
+			}
+		}
+		if classes, err := clientset.StorageV1().StorageClasses().List(ctx, metav1.ListOptions{}); err != nil {
+			GetLogger(c).Warn("Failed to list storage classes", "error", err)
+		} else {
+			for _, class := range classes.Items {
+				enums.StorageClasses = append(enums.StorageClasses, apitypes.ClusterClass{
+					Name:    class.Name,
+					Default: class.Annotations[defaultStorageClassAnnotation] == "true",
+				})
+			}
+		}
+	}
+
+	return c.JSON(http.StatusOK, enums)
+}
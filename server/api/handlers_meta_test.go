@@ -0,0 +1,77 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+)
+
+// TestGetMetaEnums tests that static enums are listed and live lists are read from the
+// chart repository and the cluster
+func TestGetMetaEnums(t *testing.T) {
+	clientset := fake.NewSimpleClientset(
+		&networkingv1.IngressClass{ObjectMeta: metav1.ObjectMeta{
+			Name:        "nginx",
+			Annotations: map[string]string{networkingv1.AnnotationIsDefaultIngressClass: "true"},
+		}},
+		&storagev1.StorageClass{ObjectMeta: metav1.ObjectMeta{Name: "fast-ssd"}},
+	)
+	resolver := &mockChartResolver{chartVersionsFunc: func(context.Context) ([]string, error) {
+		return []string{"0.1.3", "0.1.2"}, nil
+	}}
+	handler := NewHandler(nil, nil, nil, &mockK8sClient{clientset: clientset}, WithChartResolver(resolver))
+
+	c, rec := newTestContext(http.MethodGet, "/api/v1/meta/enums", "")
+	if err := handler.GetMetaEnums(c); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var enums apitypes.MetaEnums
+	if err := json.Unmarshal(rec.Body.Bytes(), &enums); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(enums.Statuses) != len(apitypes.InstanceStatuses()) || len(enums.PoolModes) != 3 || len(enums.IsolationLevels) != 3 {
+		t.Errorf("unexpected static enums: %+v", enums)
+	}
+	if len(enums.ChartVersions) != 2 || enums.ChartVersions[0] != "0.1.3" {
+		t.Errorf("expected chart versions from the resolver, got %v", enums.ChartVersions)
+	}
+	if len(enums.IngressClasses) != 1 || !enums.IngressClasses[0].Default {
+		t.Errorf("expected the default nginx ingress class, got %+v", enums.IngressClasses)
+	}
+	if len(enums.StorageClasses) != 1 || enums.StorageClasses[0].Name != "fast-ssd" || enums.StorageClasses[0].Default {
+		t.Errorf("expected the fast-ssd storage class, got %+v", enums.StorageClasses)
+	}
+}
+
+// TestGetMetaEnums_Unavailable tests that live lists are empty when they cannot be read
+func TestGetMetaEnums_Unavailable(t *testing.T) {
+	resolver := &mockChartResolver{chartVersionsFunc: func(context.Context) ([]string, error) {
+		return nil, errors.New("repository unreachable")
+	}}
+	handler := NewHandler(nil, nil, nil, nil, WithChartResolver(resolver))
+
+	c, rec := newTestContext(http.MethodGet, "/api/v1/meta/enums", "")
+	if err := handler.GetMetaEnums(c); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var body map[string]json.RawMessage
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	for _, key := range []string{"chart_versions", "ingress_classes", "storage_classes"} {
+		if string(body[key]) != "[]" {
+			t.Errorf("expected %s to be an empty list, got %s", key, body[key])
+		}
+	}
+}
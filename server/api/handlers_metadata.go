@@ -0,0 +1,121 @@
+package api
+
+import (
+	"net/http"
+	"unicode/utf8"
+
+	"github.com/labstack/echo/v4"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+	"github.com/qubitquilt/supacontrol/server/internal/db"
+	"github.com/qubitquilt/supacontrol/server/internal/i18n"
+)
+
+// maxInstanceNotesLength limits the characters of notes kept about an instance
+const maxInstanceNotesLength = 10000
+
+// instanceRef identifies an instance for metadata stored in the database
+func instanceRef(instance *supacontrolv1alpha1.SupabaseInstance) db.InstanceRef {
+	return db.InstanceRef{Name: instance.Name, UID: string(instance.UID)}
+}
+
+// mergeInstanceMetadata adds the notes and favorites stored in the database to converted
+// instances, reading them for all instances in one query. Instances without stored
+// metadata are left as they are. Metadata is an addition to the listing, so it is
+// skipped when it cannot be read.
+func (h *Handler) mergeInstanceMetadata(c echo.Context, crs []supacontrolv1alpha1.SupabaseInstance, instances []*apitypes.Instance) {
+	if len(crs) == 0 {
+		return
+	}
+
+	var userID int64
+	if authCtx := GetAuthContext(c); authCtx != nil {
+		userID = authCtx.UserID
+	}
+
+	refs := make([]db.InstanceRef, 0, len(crs))
+	for i := range crs {
+		refs = append(refs, instanceRef(&crs[i]))
+	}
+
+	metadata, err := h.dbClient.ListInstanceMetadata(userID, refs)
+	if err != nil {
+		GetLogger(c).Warn("Failed to read instance metadata", "error", err)
+		return
+	}
+
+	// Rows are looked up by the listed names, so rows of other instances are never merged
+	for i := range crs {
+		if row, ok := metadata[crs[i].Name]; ok {
+			instances[i].Notes = row.Notes
+			instances[i].Favorite = row.Favorite
+		}
+	}
+}
+
+// UpdateInstanceNotes replaces the notes kept about an instance (admins and the instance
+// owner only)
+func (h *Handler) UpdateInstanceNotes(c echo.Context) error {
+	var req apitypes.UpdateInstanceNotesRequest
+	if err := bindRequest(c, &req); err != nil {
+		return err
+	}
+	if utf8.RuneCountInString(req.Notes) > maxInstanceNotesLength {
+		return echo.NewHTTPError(http.StatusBadRequest, i18n.Msg("notes must be at most %d characters", maxInstanceNotesLength))
+	}
+
+	name := c.Param("name")
+	instance, err := h.getInstanceOrError(c, name)
+	if err != nil {
+		return err
+	}
+	authCtx := GetAuthContext(c)
+	if !isAdminOrOwner(authCtx, instance) {
+		return echo.NewHTTPError(http.StatusForbidden, "only admins and the instance owner can change instance notes")
+	}
+
+	if err := h.dbClient.SetInstanceNotes(instanceRef(instance), req.Notes, authCtx.UserID); err != nil {
+		GetLogger(c).Error("Failed to update instance notes", "instance", name, "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to update instance notes")
+	}
+
+	h.recordAudit(c, "instance.notes.update", "instance", name, nil)
+	return h.instanceWithMetadata(c, instance)
+}
+
+// UpdateInstanceFavorite adds an instance to or removes it from the caller's favorites
+func (h *Handler) UpdateInstanceFavorite(c echo.Context) error {
+	var req apitypes.UpdateInstanceFavoriteRequest
+	if err := bindRequest(c, &req); err != nil {
+		return err
+	}
+
+	authCtx := GetAuthContext(c)
+	if authCtx == nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "not authenticated")
+	}
+
+	name := c.Param("name")
+	instance, err := h.getInstanceOrError(c, name)
+	if err != nil {
+		return err
+	}
+
+	if err := h.dbClient.SetInstanceFavorite(authCtx.UserID, instanceRef(instance), req.Favorite); err != nil {
+		GetLogger(c).Error("Failed to update favorite", "instance", name, "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to update favorite")
+	}
+
+	return h.instanceWithMetadata(c, instance)
+}
+
+// instanceWithMetadata responds with an instance and its stored metadata
+func (h *Handler) instanceWithMetadata(c echo.Context, instance *supacontrolv1alpha1.SupabaseInstance) error {
+	apiInstance := h.convertCRToAPIType(c, instance)
+	h.mergeInstanceMetadata(c, []supacontrolv1alpha1.SupabaseInstance{*instance}, []*apitypes.Instance{apiInstance})
+
+	return c.JSON(http.StatusOK, apitypes.GetInstanceResponse{
+		Instance: apiInstance,
+	})
+}
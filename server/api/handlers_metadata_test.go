@@ -0,0 +1,214 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/types"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+	"github.com/qubitquilt/supacontrol/server/internal/db"
+)
+
+// TestListInstances_MergesMetadata tests that metadata of all listed instances is read in
+// one query and merged by name, ignoring instances without rows and rows of instances
+// that are not listed
+func TestListInstances_MergesMetadata(t *testing.T) {
+	alpha := newOwnedInstance("alpha", "1")
+	alpha.UID = types.UID("uid-alpha")
+	beta := newOwnedInstance("beta", "1")
+	beta.UID = types.UID("uid-beta")
+	gamma := newOwnedInstance("gamma", "1")
+	gamma.UID = types.UID("uid-gamma")
+
+	mockCR := &mockCRClient{
+		listSupabaseInstancesFunc: func(context.Context) (*supacontrolv1alpha1.SupabaseInstanceList, error) {
+			return &supacontrolv1alpha1.SupabaseInstanceList{
+				Items: []supacontrolv1alpha1.SupabaseInstance{*alpha, *beta, *gamma},
+			}, nil
+		},
+	}
+
+	queries := 0
+	var gotUserID int64
+	var gotRefs []db.InstanceRef
+	mockDB := &mockDBClient{
+		listInstanceMetadataFunc: func(userID int64, instances []db.InstanceRef) (map[string]*db.InstanceMetadata, error) {
+			queries++
+			gotUserID, gotRefs = userID, instances
+			return map[string]*db.InstanceMetadata{
+				"alpha": {InstanceName: "alpha", Notes: "billing team", Favorite: true},
+				"beta":  {InstanceName: "beta", Notes: "staging"},
+				// Orphan row of an instance that no longer exists
+				"deleted": {InstanceName: "deleted", Notes: "old notes"},
+			}, nil
+		},
+	}
+
+	handler := NewHandler(nil, mockDB, mockCR, nil)
+	c, rec := newTestContext(http.MethodGet, "/api/v1/instances", "")
+	setAuthContext(c, 7, "tester", "user")
+
+	if err := handler.ListInstances(c); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if queries != 1 {
+		t.Fatalf("expected a single metadata query, got %d", queries)
+	}
+	if gotUserID != 7 || len(gotRefs) != 3 || gotRefs[1] != (db.InstanceRef{Name: "beta", UID: "uid-beta"}) {
+		t.Errorf("unexpected query for user %d: %+v", gotUserID, gotRefs)
+	}
+
+	var resp apitypes.ListInstancesResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Count != 3 {
+		t.Fatalf("expected the orphan row not to add an instance, got %d instances", resp.Count)
+	}
+	byName := make(map[string]*apitypes.Instance, len(resp.Instances))
+	for _, instance := range resp.Instances {
+		byName[instance.ProjectName] = instance
+	}
+	if got := byName["alpha"]; got.Notes != "billing team" || !got.Favorite {
+		t.Errorf("expected alpha to carry its metadata, got notes %q favorite %v", got.Notes, got.Favorite)
+	}
+	if got := byName["beta"]; got.Notes != "staging" || got.Favorite {
+		t.Errorf("expected beta notes without favorite, got notes %q favorite %v", got.Notes, got.Favorite)
+	}
+	if got := byName["gamma"]; got.Notes != "" || got.Favorite {
+		t.Errorf("expected gamma without metadata, got notes %q favorite %v", got.Notes, got.Favorite)
+	}
+}
+
+// TestListInstances_MetadataUnavailable tests that instances are still listed when their
+// metadata cannot be read
+func TestListInstances_MetadataUnavailable(t *testing.T) {
+	mockCR := &mockCRClient{
+		listSupabaseInstancesFunc: func(context.Context) (*supacontrolv1alpha1.SupabaseInstanceList, error) {
+			return &supacontrolv1alpha1.SupabaseInstanceList{
+				Items: []supacontrolv1alpha1.SupabaseInstance{*newOwnedInstance("alpha", "1")},
+			}, nil
+		},
+	}
+	mockDB := &mockDBClient{
+		listInstanceMetadataFunc: func(int64, []db.InstanceRef) (map[string]*db.InstanceMetadata, error) {
+			return nil, errors.New("connection refused")
+		},
+	}
+
+	handler := NewHandler(nil, mockDB, mockCR, nil)
+	c, rec := newTestContext(http.MethodGet, "/api/v1/instances", "")
+
+	if err := handler.ListInstances(c); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"alpha"`) {
+		t.Errorf("expected the instance to be listed, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+// TestUpdateInstanceNotes tests the UpdateInstanceNotes handler
+func TestUpdateInstanceNotes(t *testing.T) {
+	tests := []struct {
+		name           string
+		userID         int64
+		role           string
+		body           string
+		expectedStatus int
+	}{
+		{name: "owner sets notes", userID: 7, role: "user", body: `{"notes":"billing team"}`, expectedStatus: http.StatusOK},
+		{name: "admin clears notes", userID: 1, role: "admin", body: `{"notes":""}`, expectedStatus: http.StatusOK},
+		{name: "other user", userID: 8, role: "user", body: `{"notes":"mine"}`, expectedStatus: http.StatusForbidden},
+		{name: "notes too long", userID: 7, role: "user", body: `{"notes":"` + strings.Repeat("x", maxInstanceNotesLength+1) + `"}`, expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			instance := newOwnedInstance("my-app", "7")
+			instance.UID = types.UID("uid-my-app")
+
+			var saved *string
+			var savedRef db.InstanceRef
+			mockDB := &mockDBClient{
+				setInstanceNotesFunc: func(ref db.InstanceRef, notes string, _ int64) error {
+					saved, savedRef = &notes, ref
+					return nil
+				},
+				listInstanceMetadataFunc: func(int64, []db.InstanceRef) (map[string]*db.InstanceMetadata, error) {
+					if saved == nil || *saved == "" {
+						return map[string]*db.InstanceMetadata{}, nil
+					}
+					return map[string]*db.InstanceMetadata{"my-app": {InstanceName: "my-app", Notes: *saved}}, nil
+				},
+				createAuditLogFunc: func(int64, string, string, string, map[string]string) error {
+					return nil
+				},
+			}
+			handler := NewHandler(nil, mockDB, newSuspensionCRClient(nil, instance), nil)
+			c, rec := newTestContext(http.MethodPut, "/api/v1/instances/my-app/notes", tt.body)
+			c.SetParamNames("name")
+			c.SetParamValues("my-app")
+			setAuthContext(c, tt.userID, "someone", tt.role)
+
+			err := handler.UpdateInstanceNotes(c)
+			if tt.expectedStatus != http.StatusOK {
+				assertHTTPError(t, err, tt.expectedStatus)
+				if saved != nil {
+					t.Error("expected the notes not to be saved")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if savedRef.UID != "uid-my-app" {
+				t.Errorf("expected the notes to be saved for the instance UID, got %+v", savedRef)
+			}
+
+			var resp apitypes.GetInstanceResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if resp.Instance.Notes != *saved {
+				t.Errorf("expected notes %q in the response, got %q", *saved, resp.Instance.Notes)
+			}
+		})
+	}
+}
+
+// TestUpdateInstanceFavorite tests that any user can mark an instance as a favorite
+func TestUpdateInstanceFavorite(t *testing.T) {
+	instance := newOwnedInstance("my-app", "7")
+	var gotUserID int64
+	var gotFavorite bool
+	mockDB := &mockDBClient{
+		setInstanceFavoriteFunc: func(userID int64, _ db.InstanceRef, favorite bool) error {
+			gotUserID, gotFavorite = userID, favorite
+			return nil
+		},
+		listInstanceMetadataFunc: func(int64, []db.InstanceRef) (map[string]*db.InstanceMetadata, error) {
+			return map[string]*db.InstanceMetadata{"my-app": {InstanceName: "my-app", Favorite: gotFavorite}}, nil
+		},
+	}
+	handler := NewHandler(nil, mockDB, newSuspensionCRClient(nil, instance), nil)
+	c, rec := newTestContext(http.MethodPut, "/api/v1/instances/my-app/favorite", `{"favorite":true}`)
+	c.SetParamNames("name")
+	c.SetParamValues("my-app")
+	setAuthContext(c, 8, "someone", "user")
+
+	if err := handler.UpdateInstanceFavorite(c); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if gotUserID != 8 || !gotFavorite {
+		t.Errorf("expected user 8 to favorite the instance, got user %d favorite %v", gotUserID, gotFavorite)
+	}
+	if !strings.Contains(rec.Body.String(), `"favorite":true`) {
+		t.Errorf("expected the instance to be a favorite, got %s", rec.Body.String())
+	}
+}
@@ -0,0 +1,183 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+	"github.com/qubitquilt/supacontrol/server/internal/k8s"
+)
+
+// openMetricsContentType is the content type of the OpenMetrics text format
+const openMetricsContentType = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+
+// collectInstanceMetrics gathers the current CPU, memory and storage usage of an instance
+func (h *Handler) collectInstanceMetrics(c echo.Context, instance *supacontrolv1alpha1.SupabaseInstance) (*apitypes.InstanceMetrics, error) {
+	ctx := c.Request().Context()
+	if h.usageSource == nil {
+		return nil, echo.NewHTTPError(http.StatusServiceUnavailable, "usage metrics are not configured")
+	}
+
+	namespace := getInstanceNamespace(instance)
+	usage, err := h.usageSource.NamespaceUsage(ctx, namespace)
+	if err != nil {
+		GetLogger(c).Error("Failed to query usage metrics", "source", h.usageSource.Name(), "namespace", namespace, "error", err)
+		return nil, echo.NewHTTPError(http.StatusServiceUnavailable, "usage metrics are unavailable")
+	}
+
+	claims, err := h.k8sClient.GetClientset().CoreV1().PersistentVolumeClaims(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		GetLogger(c).Error("Failed to list persistent volume claims", "namespace", namespace, "error", err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "failed to get instance metrics")
+	}
+
+	resp := &apitypes.InstanceMetrics{
+		InstanceName: instance.Spec.ProjectName,
+		Source:       h.usageSource.Name(),
+		Pods:         []apitypes.PodMetrics{},
+		Volumes:      []apitypes.VolumeMetrics{},
+		CollectedAt:  time.Now().UTC(),
+	}
+	for _, pod := range usage.Pods {
+		resp.CPUMillicores += pod.CPUMillicores
+		resp.MemoryBytes += pod.MemoryBytes
+		resp.Pods = append(resp.Pods, pod)
+	}
+	for _, claim := range claims.Items {
+		volume := apitypes.VolumeMetrics{Name: claim.Name}
+		if capacity, ok := claim.Status.Capacity[corev1.ResourceStorage]; ok {
+			volume.CapacityBytes = capacity.Value()
+		}
+		if used, ok := usage.VolumeUsedBytes[claim.Name]; ok {
+			volume.UsedBytes = &used
+			resp.StorageUsedBytes += used
+		}
+		resp.StorageCapacityBytes += volume.CapacityBytes
+		resp.Volumes = append(resp.Volumes, volume)
+	}
+	sort.Slice(resp.Volumes, func(i, j int) bool { return resp.Volumes[i].Name < resp.Volumes[j].Name })
+	return resp, nil
+}
+
+// GetInstanceMetrics reports the current CPU, memory and storage usage of an instance
+func (h *Handler) GetInstanceMetrics(c echo.Context) error {
+	name := c.Param("name")
+	ctx := c.Request().Context()
+
+	instance, err := h.crClient.GetSupabaseInstance(ctx, name)
+	if err != nil {
+		if errors.Is(err, k8s.ErrInstanceNotFound) {
+			return newAPIError(http.StatusNotFound, apitypes.ErrorCodeInstanceNotFound, "instance not found")
+		}
+		GetLogger(c).Error("Failed to get instance", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to get instance")
+	}
+
+	resp, err := h.collectInstanceMetrics(c, instance)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+// GetInstancePrometheusMetrics serves an instance's usage metrics in the OpenMetrics text
+// format, so teams without access to the cluster's Prometheus can scrape their own
+// instances with an API key (admins and the instance owner only)
+func (h *Handler) GetInstancePrometheusMetrics(c echo.Context) error {
+	instance, err := h.getInstanceOrError(c, c.Param("name"))
+	if err != nil {
+		return err
+	}
+	if !isAdminOrOwner(GetAuthContext(c), instance) {
+		return echo.NewHTTPError(http.StatusForbidden, "only admins and the instance owner can scrape instance metrics")
+	}
+
+	metrics, err := h.collectInstanceMetrics(c, instance)
+	if err != nil {
+		return err
+	}
+	return c.Blob(http.StatusOK, openMetricsContentType, []byte(renderOpenMetrics(metrics)))
+}
+
+// openMetricsWriter writes metric families in the OpenMetrics text format
+type openMetricsWriter struct {
+	b strings.Builder
+}
+
+// family starts a metric family
+func (w *openMetricsWriter) family(name, metricType, unit, help string) {
+	fmt.Fprintf(&w.b, "# TYPE %s %s\n", name, metricType)
+	if unit != "" {
+		fmt.Fprintf(&w.b, "# UNIT %s %s\n", name, unit)
+	}
+	fmt.Fprintf(&w.b, "# HELP %s %s\n", name, help)
+}
+
+// sample writes one sample; labels alternate between names and values
+func (w *openMetricsWriter) sample(name string, value float64, labels ...string) {
+	w.b.WriteString(name)
+	w.b.WriteByte('{')
+	for i := 0; i+1 < len(labels); i += 2 {
+		if i > 0 {
+			w.b.WriteByte(',')
+		}
+		fmt.Fprintf(&w.b, "%s=\"%s\"", labels[i], escapeLabelValue(labels[i+1]))
+	}
+	w.b.WriteString("} ")
+	w.b.WriteString(strconv.FormatFloat(value, 'f', -1, 64))
+	w.b.WriteByte('\n')
+}
+
+// escapeLabelValue escapes backslashes, quotes and newlines in a label value
+func escapeLabelValue(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
+
+// renderOpenMetrics renders instance usage metrics in the OpenMetrics text format. Every
+// sample carries an instance label, so scrapes of several instances can be merged.
+func renderOpenMetrics(metrics *apitypes.InstanceMetrics) string {
+	w := &openMetricsWriter{}
+	instance := metrics.InstanceName
+
+	w.family("supacontrol_instance_cpu_usage_cores", "gauge", "", "CPU usage of the instance's pods")
+	w.sample("supacontrol_instance_cpu_usage_cores", float64(metrics.CPUMillicores)/1000, "instance", instance)
+	w.family("supacontrol_instance_memory_usage_bytes", "gauge", "bytes", "Memory usage of the instance's pods")
+	w.sample("supacontrol_instance_memory_usage_bytes", float64(metrics.MemoryBytes), "instance", instance)
+	w.family("supacontrol_instance_storage_capacity_bytes", "gauge", "bytes", "Capacity of the instance's persistent volume claims")
+	w.sample("supacontrol_instance_storage_capacity_bytes", float64(metrics.StorageCapacityBytes), "instance", instance)
+	w.family("supacontrol_instance_storage_used_bytes", "gauge", "bytes", "Used space of the instance's persistent volume claims that report usage")
+	w.sample("supacontrol_instance_storage_used_bytes", float64(metrics.StorageUsedBytes), "instance", instance)
+
+	w.family("supacontrol_instance_pod_cpu_usage_cores", "gauge", "", "CPU usage per pod")
+	for _, pod := range metrics.Pods {
+		w.sample("supacontrol_instance_pod_cpu_usage_cores", float64(pod.CPUMillicores)/1000, "instance", instance, "pod", pod.Name)
+	}
+	w.family("supacontrol_instance_pod_memory_usage_bytes", "gauge", "bytes", "Memory usage per pod")
+	for _, pod := range metrics.Pods {
+		w.sample("supacontrol_instance_pod_memory_usage_bytes", float64(pod.MemoryBytes), "instance", instance, "pod", pod.Name)
+	}
+
+	w.family("supacontrol_instance_volume_capacity_bytes", "gauge", "bytes", "Capacity per persistent volume claim")
+	for _, volume := range metrics.Volumes {
+		w.sample("supacontrol_instance_volume_capacity_bytes", float64(volume.CapacityBytes), "instance", instance, "volume", volume.Name)
+	}
+	w.family("supacontrol_instance_volume_used_bytes", "gauge", "bytes", "Used space per persistent volume claim, for claims that report usage")
+	for _, volume := range metrics.Volumes {
+		if volume.UsedBytes != nil {
+			w.sample("supacontrol_instance_volume_used_bytes", float64(*volume.UsedBytes), "instance", instance, "volume", volume.Name)
+		}
+	}
+
+	w.b.WriteString("# EOF\n")
+	return w.b.String()
+}
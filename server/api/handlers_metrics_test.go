@@ -0,0 +1,221 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+	"github.com/qubitquilt/supacontrol/server/internal/k8s"
+)
+
+// newInstanceClaim returns a bound persistent volume claim in the instance namespace
+func newInstanceClaim(name, capacity string) *corev1.PersistentVolumeClaim {
+	return &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "supa-my-app"},
+		Status: corev1.PersistentVolumeClaimStatus{
+			Capacity: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse(capacity)},
+		},
+	}
+}
+
+// TestGetInstanceMetrics tests the GetInstanceMetrics handler
+func TestGetInstanceMetrics(t *testing.T) {
+	source := &mockUsageSource{
+		namespaceUsageFunc: func(_ context.Context, namespace string) (*k8s.NamespaceUsage, error) {
+			if namespace != "supa-my-app" {
+				return nil, fmt.Errorf("unexpected namespace %s", namespace)
+			}
+			return &k8s.NamespaceUsage{
+				Pods: []apitypes.PodMetrics{
+					{Name: "db-0", CPUMillicores: 250, MemoryBytes: 512 << 20},
+					{Name: "kong-1", CPUMillicores: 5, MemoryBytes: 64 << 20},
+				},
+				VolumeUsedBytes: map[string]int64{"db-data": 1 << 30},
+			}, nil
+		},
+	}
+	mockCR := &mockCRClient{
+		getSupabaseInstanceFunc: func(_ context.Context, name string) (*supacontrolv1alpha1.SupabaseInstance, error) {
+			return newOwnedInstance(name, "7"), nil
+		},
+	}
+	clientset := fake.NewSimpleClientset(newInstanceClaim("db-data", "8Gi"), newInstanceClaim("storage-data", "2Gi"))
+	handler := NewHandler(nil, &mockDBClient{}, mockCR, &mockK8sClient{clientset: clientset}, WithUsageMetricsSource(source))
+
+	c, rec := newTestContext(http.MethodGet, "/api/v1/instances/my-app/metrics", "")
+	c.SetParamNames("name")
+	c.SetParamValues("my-app")
+	setAuthContext(c, 7, "tester", "user")
+
+	if err := handler.GetInstanceMetrics(c); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var resp apitypes.InstanceMetrics
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Source != apitypes.MetricsSourceMetricsServer {
+		t.Errorf("expected source %q, got %q", apitypes.MetricsSourceMetricsServer, resp.Source)
+	}
+	if resp.CPUMillicores != 255 || resp.MemoryBytes != 576<<20 {
+		t.Errorf("expected 255m CPU and 576Mi memory, got %dm and %d bytes", resp.CPUMillicores, resp.MemoryBytes)
+	}
+	if resp.StorageCapacityBytes != 10<<30 || resp.StorageUsedBytes != 1<<30 {
+		t.Errorf("expected 10Gi capacity and 1Gi used, got %d and %d", resp.StorageCapacityBytes, resp.StorageUsedBytes)
+	}
+	if len(resp.Volumes) != 2 {
+		t.Fatalf("expected 2 volumes, got %d", len(resp.Volumes))
+	}
+	if resp.Volumes[0].UsedBytes == nil || *resp.Volumes[0].UsedBytes != 1<<30 {
+		t.Errorf("expected db-data usage to be reported, got %+v", resp.Volumes[0])
+	}
+	if resp.Volumes[1].UsedBytes != nil {
+		t.Errorf("expected storage-data usage to be omitted, got %d", *resp.Volumes[1].UsedBytes)
+	}
+}
+
+// TestGetInstanceMetrics_Errors tests the GetInstanceMetrics error responses
+func TestGetInstanceMetrics_Errors(t *testing.T) {
+	tests := []struct {
+		name           string
+		source         UsageMetricsSource
+		instanceErr    error
+		expectedStatus int
+	}{
+		{
+			name:           "instance not found",
+			source:         &mockUsageSource{},
+			instanceErr:    k8s.ErrInstanceNotFound,
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "no metrics source configured",
+			expectedStatus: http.StatusServiceUnavailable,
+		},
+		{
+			name:           "metrics source unavailable",
+			source:         &mockUsageSource{},
+			expectedStatus: http.StatusServiceUnavailable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockCR := &mockCRClient{
+				getSupabaseInstanceFunc: func(_ context.Context, name string) (*supacontrolv1alpha1.SupabaseInstance, error) {
+					if tt.instanceErr != nil {
+						return nil, tt.instanceErr
+					}
+					return newOwnedInstance(name, "7"), nil
+				},
+			}
+			var opts []HandlerOption
+			if tt.source != nil {
+				opts = append(opts, WithUsageMetricsSource(tt.source))
+			}
+			handler := NewHandler(nil, &mockDBClient{}, mockCR, &mockK8sClient{}, opts...)
+
+			c, _ := newTestContext(http.MethodGet, "/api/v1/instances/my-app/metrics", "")
+			c.SetParamNames("name")
+			c.SetParamValues("my-app")
+			setAuthContext(c, 7, "tester", "user")
+
+			err := handler.GetInstanceMetrics(c)
+			he, ok := err.(*echo.HTTPError)
+			if !ok {
+				t.Fatalf("expected HTTPError, got %v", err)
+			}
+			if he.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, he.Code)
+			}
+		})
+	}
+}
+
+// TestGetInstancePrometheusMetrics tests the OpenMetrics rendering of instance metrics
+func TestGetInstancePrometheusMetrics(t *testing.T) {
+	source := &mockUsageSource{
+		namespaceUsageFunc: func(_ context.Context, _ string) (*k8s.NamespaceUsage, error) {
+			return &k8s.NamespaceUsage{
+				Pods:            []apitypes.PodMetrics{{Name: "db-0", CPUMillicores: 250, MemoryBytes: 512 << 20}},
+				VolumeUsedBytes: map[string]int64{"db-data": 1 << 30},
+			}, nil
+		},
+	}
+	mockCR := &mockCRClient{
+		getSupabaseInstanceFunc: func(_ context.Context, name string) (*supacontrolv1alpha1.SupabaseInstance, error) {
+			return newOwnedInstance(name, "7"), nil
+		},
+	}
+	clientset := fake.NewSimpleClientset(newInstanceClaim("db-data", "8Gi"), newInstanceClaim("storage-data", "2Gi"))
+	handler := NewHandler(nil, &mockDBClient{}, mockCR, &mockK8sClient{clientset: clientset}, WithUsageMetricsSource(source))
+
+	tests := []struct {
+		name           string
+		userID         int64
+		role           string
+		expectedStatus int
+	}{
+		{name: "owner", userID: 7, role: "user", expectedStatus: http.StatusOK},
+		{name: "admin", userID: 1, role: "admin", expectedStatus: http.StatusOK},
+		{name: "other user", userID: 8, role: "user", expectedStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, rec := newTestContext(http.MethodGet, "/api/v1/instances/my-app/metrics/prometheus", "")
+			c.SetParamNames("name")
+			c.SetParamValues("my-app")
+			setAuthContext(c, tt.userID, "tester", tt.role)
+
+			err := handler.GetInstancePrometheusMetrics(c)
+			if tt.expectedStatus != http.StatusOK {
+				assertHTTPError(t, err, tt.expectedStatus)
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if ct := rec.Header().Get(echo.HeaderContentType); !strings.HasPrefix(ct, "application/openmetrics-text") {
+				t.Errorf("expected OpenMetrics content type, got %q", ct)
+			}
+			body := rec.Body.String()
+			for _, line := range []string{
+				"# TYPE supacontrol_instance_cpu_usage_cores gauge",
+				`supacontrol_instance_cpu_usage_cores{instance="my-app"} 0.25`,
+				`supacontrol_instance_pod_memory_usage_bytes{instance="my-app",pod="db-0"} 536870912`,
+				`supacontrol_instance_storage_capacity_bytes{instance="my-app"} 10737418240`,
+				`supacontrol_instance_volume_used_bytes{instance="my-app",volume="db-data"} 1073741824`,
+			} {
+				if !strings.Contains(body, line+"\n") {
+					t.Errorf("expected line %q in:\n%s", line, body)
+				}
+			}
+			if strings.Contains(body, `volume_used_bytes{instance="my-app",volume="storage-data"}`) {
+				t.Error("expected volumes without usage to be omitted from volume_used_bytes")
+			}
+			if !strings.HasSuffix(body, "# EOF\n") {
+				t.Error("expected the exposition to end with # EOF")
+			}
+		})
+	}
+}
+
+// TestEscapeLabelValue tests OpenMetrics label value escaping
+func TestEscapeLabelValue(t *testing.T) {
+	if got := escapeLabelValue("a\"b\\c\nd"); got != `a\"b\\c\nd` {
+		t.Errorf("unexpected escaping %q", got)
+	}
+}
@@ -0,0 +1,45 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/qubitquilt/supacontrol/server/controllers"
+	"github.com/qubitquilt/supacontrol/server/internal/i18n"
+	"github.com/qubitquilt/supacontrol/server/internal/namespaces"
+)
+
+// maxNamespaceLabels limits how many namespace labels an instance may set, as the CRD does
+const maxNamespaceLabels = 20
+
+// WithNamespaceTemplate sets the template instance namespaces are named from, so instances
+// whose namespace would be a system namespace are refused before they are created
+func WithNamespaceTemplate(template string) HandlerOption {
+	return func(h *Handler) {
+		h.namespaceTemplate = template
+	}
+}
+
+// checkNamespace validates the namespace a new instance would get and the labels requested
+// for it. The controller checks both again, as instances may be created without the API.
+func (h *Handler) checkNamespace(name string, labels map[string]string) error {
+	namespace := namespaces.Name(h.namespaceTemplate, name)
+	if err := namespaces.Validate(namespace, controllers.ControllerNamespace); err != nil {
+		if errors.Is(err, namespaces.ErrSystemNamespace) {
+			return echo.NewHTTPError(http.StatusBadRequest, i18n.Msg("namespace %s is reserved for the system", namespace))
+		}
+		return echo.NewHTTPError(http.StatusBadRequest, i18n.Msg("instance name yields the invalid namespace %s", namespace))
+	}
+	if len(labels) > maxNamespaceLabels {
+		return echo.NewHTTPError(http.StatusBadRequest, i18n.Msg("at most %d namespace labels can be set", maxNamespaceLabels))
+	}
+	if err := namespaces.ValidateLabels(labels); err != nil {
+		if errors.Is(err, namespaces.ErrReservedKey) {
+			return echo.NewHTTPError(http.StatusBadRequest, "namespace labels must not use the kubernetes.io, k8s.io or supacontrol.io domains")
+		}
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid namespace labels")
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+)
+
+// TestCheckNamespace tests that instances are refused namespaces reserved for the system
+func TestCheckNamespace(t *testing.T) {
+	tests := []struct {
+		name           string
+		template       string
+		instance       string
+		labels         map[string]string
+		expectedStatus int
+	}{
+		{name: "default template", instance: "default", labels: map[string]string{"team": "payments"}},
+		{name: "custom template", template: "tenants-{name}", instance: "my-app"},
+		{name: "system namespace", template: "{name}", instance: "default", expectedStatus: http.StatusBadRequest},
+		{name: "kube prefix", template: "{name}", instance: "kube-system", expectedStatus: http.StatusBadRequest},
+		{name: "controller namespace", template: "{name}", instance: "supacontrol-system", expectedStatus: http.StatusBadRequest},
+		{name: "reserved label", instance: "my-app", labels: map[string]string{"supacontrol.io/instance": "other"}, expectedStatus: http.StatusBadRequest},
+		{name: "invalid label", instance: "my-app", labels: map[string]string{"team": "a team"}, expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewHandler(nil, &mockDBClient{}, nil, nil, WithNamespaceTemplate(tt.template))
+			err := handler.checkNamespace(tt.instance, tt.labels)
+			if tt.expectedStatus == 0 {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			assertHTTPError(t, err, tt.expectedStatus)
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package api
+
+import (
+	_ "embed"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/labstack/echo/v4"
+	"sigs.k8s.io/yaml"
+)
+
+// openAPISpec is the hand-maintained OpenAPI 3 description of the API.
+// Every route registered in SetupRouter must be documented in it.
+//
+//go:embed openapi.yaml
+var openAPISpec []byte
+
+var (
+	openAPIJSONOnce sync.Once
+	openAPIJSON     []byte
+	openAPIJSONErr  error
+)
+
+// swaggerUIPage renders Swagger UI against the served OpenAPI document
+const swaggerUIPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8" />
+  <title>SupaControl API</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css" />
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js" crossorigin></script>
+  <script>
+    window.onload = () => {
+      window.ui = SwaggerUIBundle({ url: "/api/v1/openapi.json", dom_id: "#swagger-ui" });
+    };
+  </script>
+</body>
+</html>
+`
+
+// loadOpenAPIJSON converts the embedded spec to JSON once
+func loadOpenAPIJSON() ([]byte, error) {
+	openAPIJSONOnce.Do(func() {
+		openAPIJSON, openAPIJSONErr = yaml.YAMLToJSON(openAPISpec)
+		if openAPIJSONErr != nil {
+			openAPIJSONErr = fmt.Errorf("failed to convert OpenAPI spec: %w", openAPIJSONErr)
+		}
+	})
+	return openAPIJSON, openAPIJSONErr
+}
+
+// GetOpenAPISpec serves the OpenAPI 3 document as JSON
+func (h *Handler) GetOpenAPISpec(c echo.Context) error {
+	spec, err := loadOpenAPIJSON()
+	if err != nil {
+		GetLogger(c).Error("Failed to load OpenAPI spec", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to load API specification")
+	}
+	return c.Blob(http.StatusOK, echo.MIMEApplicationJSON, spec)
+}
+
+// GetAPIDocs serves Swagger UI for browsing the OpenAPI document
+func (h *Handler) GetAPIDocs(c echo.Context) error {
+	return c.HTML(http.StatusOK, swaggerUIPage)
+}
@@ -0,0 +1,68 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// openAPIDocument is the subset of the OpenAPI document inspected by the tests
+type openAPIDocument struct {
+	OpenAPI string                                `json:"openapi"`
+	Paths   map[string]map[string]json.RawMessage `json:"paths"`
+}
+
+func TestGetOpenAPISpec(t *testing.T) {
+	handler := &Handler{}
+	c, rec := newTestContext(http.MethodGet, "/api/v1/openapi.json", "")
+
+	require.NoError(t, handler.GetOpenAPISpec(c))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Header().Get(echo.HeaderContentType), echo.MIMEApplicationJSON)
+
+	var doc openAPIDocument
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &doc))
+	assert.True(t, strings.HasPrefix(doc.OpenAPI, "3."), "unexpected openapi version %q", doc.OpenAPI)
+	assert.NotEmpty(t, doc.Paths)
+}
+
+func TestGetAPIDocs(t *testing.T) {
+	handler := &Handler{}
+	c, rec := newTestContext(http.MethodGet, "/api/docs", "")
+
+	require.NoError(t, handler.GetAPIDocs(c))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "/api/v1/openapi.json")
+}
+
+// TestOpenAPISpecCoversRoutes ensures every API route is documented in the OpenAPI spec
+func TestOpenAPISpecCoversRoutes(t *testing.T) {
+	spec, err := loadOpenAPIJSON()
+	require.NoError(t, err)
+	var doc openAPIDocument
+	require.NoError(t, json.Unmarshal(spec, &doc))
+
+	e := echo.New()
+	SetupRouter(e, &Handler{}, nil, nil)
+
+	param := regexp.MustCompile(`:(\w+)`)
+	for _, route := range e.Routes() {
+		// Groups register catch-all routes for their middleware
+		if !strings.HasPrefix(route.Path, "/api/v1/") || strings.HasSuffix(route.Path, "*") || route.Path == "/api/v1/openapi.json" {
+			continue
+		}
+		path := param.ReplaceAllString(route.Path, "{$1}")
+		operations, ok := doc.Paths[path]
+		if !assert.True(t, ok, "path %s is missing from the OpenAPI spec", path) {
+			continue
+		}
+		_, ok = operations[strings.ToLower(route.Method)]
+		assert.True(t, ok, "operation %s %s is missing from the OpenAPI spec", route.Method, path)
+	}
+}
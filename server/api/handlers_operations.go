@@ -0,0 +1,294 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+	"github.com/qubitquilt/supacontrol/server/controllers"
+	"github.com/qubitquilt/supacontrol/server/internal/k8s"
+)
+
+// Progress reported for the steps of create and delete operations
+const (
+	progressWaiting      = 10
+	progressStarting     = 25
+	progressProvisioning = 50
+	progressCleanup      = 40
+	progressRemoving     = 70
+	progressDone         = 100
+)
+
+// operationState is the progress of a running operation derived from its instance
+type operationState struct {
+	status   string
+	progress int
+	step     string
+	phase    string
+	job      string
+	errorMsg string
+}
+
+// operationRunning returns a state reporting the operation still in progress
+func operationRunning(progress int, step string, phase supacontrolv1alpha1.SupabaseInstancePhase) operationState {
+	return operationState{status: apitypes.OperationStatusRunning, progress: progress, step: step, phase: string(phase)}
+}
+
+// operationSucceeded returns a state reporting the operation finished
+func operationSucceeded() operationState {
+	return operationState{status: apitypes.OperationStatusSucceeded}
+}
+
+// operationFailed returns a state reporting the operation failed with message
+func operationFailed(message string) operationState {
+	return operationState{status: apitypes.OperationStatusFailed, errorMsg: message}
+}
+
+// recordOperation records a create, delete or restart of instance that was just started
+// and returns its ID. Failures are logged but do not fail the request, which has already
+// taken effect; the response then leaves out the operation ID.
+func (h *Handler) recordOperation(c echo.Context, operationType string, instance *supacontrolv1alpha1.SupabaseInstance) int64 {
+	var createdBy *int64
+	if authCtx := GetAuthContext(c); authCtx != nil {
+		createdBy = &authCtx.UserID
+	}
+	operation, err := h.dbClient.CreateOperation(operationType, instance.Name, string(instance.UID), createdBy)
+	if err != nil {
+		GetLogger(c).Error("Failed to record operation", "type", operationType, "instance", instance.Name, "error", err)
+		return 0
+	}
+	return operation.ID
+}
+
+// canViewOperation reports whether the authenticated user may follow an operation: admins,
+// the user who started it and the owner of its instance, which is nil once it is gone
+func canViewOperation(authCtx *AuthContext, operation *apitypes.Operation, instance *supacontrolv1alpha1.SupabaseInstance) bool {
+	if authCtx == nil {
+		return false
+	}
+	if authCtx.IsAdmin() || (operation.CreatedBy != nil && *operation.CreatedBy == authCtx.UserID) {
+		return true
+	}
+	return instance != nil && isAdminOrOwner(authCtx, instance)
+}
+
+// GetOperation reports the progress of a create, delete or restart operation. Progress of
+// running operations is derived from the instance's phase, Jobs and Deployments on every
+// read; the outcome is stored once the operation is seen to finish.
+func (h *Handler) GetOperation(c echo.Context) error {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid operation ID")
+	}
+
+	operation, err := h.dbClient.GetOperation(id)
+	if err != nil {
+		GetLogger(c).Error("Failed to get operation", "operation_id", id, "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to get operation")
+	}
+	if operation == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "operation not found")
+	}
+
+	ctx := c.Request().Context()
+	instance, err := h.operationInstance(ctx, operation)
+	if err != nil {
+		GetLogger(c).Error("Failed to get operation instance", "operation_id", id, "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to get operation")
+	}
+	if !canViewOperation(GetAuthContext(c), operation, instance) {
+		return echo.NewHTTPError(http.StatusForbidden, "only admins, the instance owner and the user who started an operation can view it")
+	}
+
+	if operation.Status != apitypes.OperationStatusRunning {
+		operation.Progress = progressDone
+		return c.JSON(http.StatusOK, operation)
+	}
+
+	state, err := h.operationState(ctx, operation, instance)
+	if err != nil {
+		GetLogger(c).Error("Failed to get operation progress", "operation_id", id, "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to get operation")
+	}
+
+	if state.status != apitypes.OperationStatusRunning {
+		operation.Status = state.status
+		if state.errorMsg != "" {
+			operation.ErrorMessage = &state.errorMsg
+		}
+		if err := h.dbClient.FinishOperation(operation); err != nil {
+			GetLogger(c).Error("Failed to record operation outcome", "operation_id", id, "error", err)
+		}
+		operation.Progress = progressDone
+		return c.JSON(http.StatusOK, operation)
+	}
+
+	operation.Progress = state.progress
+	operation.Step = localize(c, state.step)
+	operation.Phase = state.phase
+	if state.job != "" {
+		operation.Job = h.operationJob(c, state.job)
+	}
+	return c.JSON(http.StatusOK, operation)
+}
+
+// operationInstance returns the instance an operation was started on, or nil once it is
+// gone. An instance re-created under the same name is a different instance.
+func (h *Handler) operationInstance(ctx context.Context, operation *apitypes.Operation) (*supacontrolv1alpha1.SupabaseInstance, error) {
+	instance, err := h.crClient.GetSupabaseInstance(ctx, operation.InstanceName)
+	if errors.Is(err, k8s.ErrInstanceNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if operation.InstanceUID != "" && string(instance.UID) != operation.InstanceUID {
+		return nil, nil
+	}
+	return instance, nil
+}
+
+// operationState derives the progress of a running operation from its instance, nil once
+// the instance is gone
+func (h *Handler) operationState(ctx context.Context, operation *apitypes.Operation, instance *supacontrolv1alpha1.SupabaseInstance) (operationState, error) {
+	switch operation.Type {
+	case apitypes.OperationTypeCreate:
+		return createOperationState(instance), nil
+	case apitypes.OperationTypeDelete:
+		return deleteOperationState(instance), nil
+	case apitypes.OperationTypeRestart:
+		if instance == nil {
+			return operationFailed("instance was deleted during the restart"), nil
+		}
+		deployments, err := h.k8sClient.GetClientset().AppsV1().Deployments(getInstanceNamespace(instance)).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return operationState{}, err
+		}
+		return restartOperationState(deployments.Items), nil
+	}
+	return operationFailed("unknown operation type"), nil
+}
+
+// createOperationState derives the progress of provisioning an instance from its phase
+func createOperationState(instance *supacontrolv1alpha1.SupabaseInstance) operationState {
+	if instance == nil || instance.DeletionTimestamp != nil {
+		return operationFailed("instance was deleted before it was provisioned")
+	}
+
+	phase := instance.Status.Phase
+	switch phase {
+	case "", supacontrolv1alpha1.PhasePending:
+		return operationRunning(progressWaiting, "Waiting to be provisioned", phase)
+	case supacontrolv1alpha1.PhaseQueued:
+		return operationRunning(progressWaiting, "Waiting for a provisioning slot", phase)
+	case supacontrolv1alpha1.PhaseProvisioning:
+		state := operationRunning(progressStarting, "Starting the provisioning Job", phase)
+		state.job = instance.Status.ProvisioningJobName
+		return state
+	case supacontrolv1alpha1.PhaseProvisioningInProgress:
+		state := operationRunning(progressProvisioning, "Installing the Supabase chart", phase)
+		state.job = instance.Status.ProvisioningJobName
+		return state
+	case supacontrolv1alpha1.PhaseFailed:
+		if instance.Status.NextRetryAt != nil {
+			return operationRunning(progressWaiting, "Waiting to retry after a failure", phase)
+		}
+		if instance.Status.ErrorMessage == "" {
+			return operationFailed("instance provisioning failed")
+		}
+		return operationFailed(instance.Status.ErrorMessage)
+	case supacontrolv1alpha1.PhasePendingDeletion, supacontrolv1alpha1.PhaseDeleting, supacontrolv1alpha1.PhaseDeletingInProgress:
+		return operationFailed("instance was deleted before it was provisioned")
+	}
+	return operationSucceeded()
+}
+
+// deleteOperationState derives the progress of deleting an instance. Deleting into the
+// trash finishes once the instance is scheduled for deletion; it is purged later.
+func deleteOperationState(instance *supacontrolv1alpha1.SupabaseInstance) operationState {
+	if instance == nil {
+		return operationSucceeded()
+	}
+
+	phase := instance.Status.Phase
+	if instance.DeletionTimestamp != nil {
+		if phase == supacontrolv1alpha1.PhaseDeletingInProgress {
+			state := operationRunning(progressRemoving, "Removing the instance's resources", phase)
+			state.job = instance.Status.CleanupJobName
+			return state
+		}
+		return operationRunning(progressCleanup, "Waiting for cleanup", phase)
+	}
+	if instance.Spec.PendingDeletion != nil {
+		if phase == supacontrolv1alpha1.PhasePendingDeletion {
+			return operationSucceeded()
+		}
+		return operationRunning(progressCleanup, "Moving the instance to the trash", phase)
+	}
+	return operationFailed("instance deletion was cancelled")
+}
+
+// restartOperationState derives the progress of restarting an instance from the rollouts
+// of its Deployments
+func restartOperationState(deployments []appsv1.Deployment) operationState {
+	if len(deployments) == 0 {
+		return operationSucceeded()
+	}
+
+	done := 0
+	for i := range deployments {
+		deployment := &deployments[i]
+		for _, condition := range deployment.Status.Conditions {
+			if condition.Type == appsv1.DeploymentProgressing && condition.Status == corev1.ConditionFalse &&
+				condition.Reason == "ProgressDeadlineExceeded" {
+				return operationFailed(fmt.Sprintf("deployment %s did not finish restarting", deployment.Name))
+			}
+		}
+		if rolloutComplete(deployment) {
+			done++
+		}
+	}
+	if done == len(deployments) {
+		return operationSucceeded()
+	}
+	return operationRunning(done*progressDone/len(deployments), "Restarting deployments", "")
+}
+
+// rolloutComplete reports whether a Deployment has rolled out its latest template, the
+// check kubectl rollout status makes
+func rolloutComplete(deployment *appsv1.Deployment) bool {
+	desired := int32(1)
+	if deployment.Spec.Replicas != nil {
+		desired = *deployment.Spec.Replicas
+	}
+	status := deployment.Status
+	return status.ObservedGeneration >= deployment.Generation &&
+		status.UpdatedReplicas >= desired &&
+		status.Replicas <= status.UpdatedReplicas &&
+		status.AvailableReplicas >= desired
+}
+
+// operationJob returns the pod counts of the Job carrying out an operation, or nil when
+// it cannot be read, such as after it was cleaned up
+func (h *Handler) operationJob(c echo.Context, name string) *apitypes.OperationJob {
+	job, err := h.k8sClient.GetClientset().BatchV1().Jobs(controllers.ControllerNamespace).Get(c.Request().Context(), name, metav1.GetOptions{})
+	if err != nil {
+		GetLogger(c).Debug("Failed to get operation Job", "job", name, "error", err)
+		return nil
+	}
+	return &apitypes.OperationJob{
+		Name:      job.Name,
+		Active:    job.Status.Active,
+		Succeeded: job.Status.Succeeded,
+		Failed:    job.Status.Failed,
+	}
+}
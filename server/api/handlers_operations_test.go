@@ -0,0 +1,358 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+	"github.com/qubitquilt/supacontrol/server/controllers"
+)
+
+// newOperationDBClient returns a database client holding a single operation, recording the
+// operation it is asked to finish
+func newOperationDBClient(operation *apitypes.Operation, finished **apitypes.Operation) *mockDBClient {
+	return &mockDBClient{
+		getOperationFunc: func(id int64) (*apitypes.Operation, error) {
+			if id != operation.ID {
+				return nil, nil
+			}
+			copied := *operation
+			return &copied, nil
+		},
+		finishOperationFunc: func(op *apitypes.Operation) error {
+			*finished = op
+			return nil
+		},
+	}
+}
+
+// getOperation calls GetOperation for an operation ID and decodes the response
+func getOperation(t *testing.T, handler *Handler, id string) apitypes.Operation {
+	t.Helper()
+	c, rec := newTestContext(http.MethodGet, "/api/v1/operations/"+id, "")
+	c.SetParamNames("id")
+	c.SetParamValues(id)
+	setAuthContext(c, 7, "tester", RoleUser)
+
+	if err := handler.GetOperation(c); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var operation apitypes.Operation
+	if err := json.Unmarshal(rec.Body.Bytes(), &operation); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	return operation
+}
+
+// TestGetOperation_Create tests that the progress of a create operation follows the
+// instance's phase and is stored once it finishes
+func TestGetOperation_Create(t *testing.T) {
+	tests := []struct {
+		name             string
+		phase            supacontrolv1alpha1.SupabaseInstancePhase
+		retrying         bool
+		deleted          bool
+		expectedStatus   string
+		expectedProgress int
+		expectJob        bool
+	}{
+		{name: "queued", phase: supacontrolv1alpha1.PhaseQueued, expectedStatus: apitypes.OperationStatusRunning, expectedProgress: progressWaiting},
+		{name: "installing", phase: supacontrolv1alpha1.PhaseProvisioningInProgress, expectedStatus: apitypes.OperationStatusRunning, expectedProgress: progressProvisioning, expectJob: true},
+		{name: "waiting to retry", phase: supacontrolv1alpha1.PhaseFailed, retrying: true, expectedStatus: apitypes.OperationStatusRunning, expectedProgress: progressWaiting},
+		{name: "failed", phase: supacontrolv1alpha1.PhaseFailed, expectedStatus: apitypes.OperationStatusFailed, expectedProgress: progressDone},
+		{name: "running", phase: supacontrolv1alpha1.PhaseRunning, expectedStatus: apitypes.OperationStatusSucceeded, expectedProgress: progressDone},
+		{name: "deleted", deleted: true, expectedStatus: apitypes.OperationStatusFailed, expectedProgress: progressDone},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			instance := newOwnedInstance("my-app", "7")
+			instance.Status.Phase = tt.phase
+			instance.Status.ProvisioningJobName = "provision-my-app"
+			instance.Status.ErrorMessage = "helm install timed out"
+			if tt.retrying {
+				next := metav1.Now()
+				instance.Status.NextRetryAt = &next
+			}
+			var instances []*supacontrolv1alpha1.SupabaseInstance
+			if !tt.deleted {
+				instances = append(instances, instance)
+			}
+
+			var finished *apitypes.Operation
+			createdBy := int64(7)
+			dbClient := newOperationDBClient(&apitypes.Operation{
+				ID: 42, Type: apitypes.OperationTypeCreate, InstanceName: "my-app", Status: apitypes.OperationStatusRunning, CreatedBy: &createdBy,
+			}, &finished)
+			clientset := fake.NewSimpleClientset(&batchv1.Job{
+				ObjectMeta: metav1.ObjectMeta{Name: "provision-my-app", Namespace: controllers.ControllerNamespace},
+				Status:     batchv1.JobStatus{Active: 1},
+			})
+			handler := NewHandler(nil, dbClient, newSuspensionCRClient(nil, instances...), &mockK8sClient{clientset: clientset})
+
+			operation := getOperation(t, handler, "42")
+			if operation.Status != tt.expectedStatus || operation.Progress != tt.expectedProgress {
+				t.Errorf("expected %s at %d%%, got %s at %d%%", tt.expectedStatus, tt.expectedProgress, operation.Status, operation.Progress)
+			}
+			if tt.expectJob && (operation.Job == nil || operation.Job.Name != "provision-my-app" || operation.Job.Active != 1) {
+				t.Errorf("expected the active provisioning Job, got %+v", operation.Job)
+			}
+
+			if tt.expectedStatus == apitypes.OperationStatusRunning {
+				if finished != nil {
+					t.Errorf("expected a running operation not to be finished, got %+v", finished)
+				}
+				if operation.Step == "" || operation.Phase != string(tt.phase) {
+					t.Errorf("expected the step and phase %s, got %q and %q", tt.phase, operation.Step, operation.Phase)
+				}
+				return
+			}
+			if finished == nil || finished.Status != tt.expectedStatus {
+				t.Fatalf("expected the operation to be finished as %s, got %+v", tt.expectedStatus, finished)
+			}
+			if tt.phase == supacontrolv1alpha1.PhaseFailed && (finished.ErrorMessage == nil || *finished.ErrorMessage != "helm install timed out") {
+				t.Errorf("expected the instance's error to be recorded, got %v", finished.ErrorMessage)
+			}
+		})
+	}
+}
+
+// TestGetOperation_Finished tests that finished operations are reported as stored
+func TestGetOperation_Finished(t *testing.T) {
+	var finished *apitypes.Operation
+	createdBy := int64(7)
+	dbClient := newOperationDBClient(&apitypes.Operation{
+		ID: 42, Type: apitypes.OperationTypeDelete, InstanceName: "my-app", Status: apitypes.OperationStatusSucceeded, CreatedBy: &createdBy,
+	}, &finished)
+	handler := NewHandler(nil, dbClient, newSuspensionCRClient(nil), nil)
+
+	operation := getOperation(t, handler, "42")
+	if operation.Status != apitypes.OperationStatusSucceeded || operation.Progress != progressDone || finished != nil {
+		t.Errorf("expected the stored outcome at 100%%, got %+v", operation)
+	}
+}
+
+// TestGetOperation_Forbidden tests that only admins, the instance owner and the user who
+// started an operation can follow it
+func TestGetOperation_Forbidden(t *testing.T) {
+	createdBy := int64(8)
+	tests := []struct {
+		name           string
+		instance       *supacontrolv1alpha1.SupabaseInstance
+		role           string
+		expectedStatus int
+	}{
+		{name: "other user's instance", instance: newOwnedInstance("my-app", "8"), role: RoleUser, expectedStatus: http.StatusForbidden},
+		{name: "instance gone", role: RoleUser, expectedStatus: http.StatusForbidden},
+		{name: "instance owner", instance: newOwnedInstance("my-app", "7"), role: RoleUser, expectedStatus: http.StatusOK},
+		{name: "admin", instance: newOwnedInstance("my-app", "8"), role: RoleAdmin, expectedStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var finished *apitypes.Operation
+			dbClient := newOperationDBClient(&apitypes.Operation{
+				ID: 42, Type: apitypes.OperationTypeDelete, InstanceName: "my-app", Status: apitypes.OperationStatusSucceeded, CreatedBy: &createdBy,
+			}, &finished)
+			var instances []*supacontrolv1alpha1.SupabaseInstance
+			if tt.instance != nil {
+				instances = append(instances, tt.instance)
+			}
+			handler := NewHandler(nil, dbClient, newSuspensionCRClient(nil, instances...), nil)
+			c, rec := newTestContext(http.MethodGet, "/api/v1/operations/42", "")
+			c.SetParamNames("id")
+			c.SetParamValues("42")
+			setAuthContext(c, 7, "tester", tt.role)
+
+			err := handler.GetOperation(c)
+			if tt.expectedStatus != http.StatusOK {
+				assertHTTPError(t, err, tt.expectedStatus)
+				return
+			}
+			if err != nil || rec.Code != http.StatusOK {
+				t.Errorf("expected 200, got %d (%v)", rec.Code, err)
+			}
+		})
+	}
+}
+
+// TestGetOperation_RecreatedInstance tests that a create operation is not reported on an
+// instance re-created under the same name
+func TestGetOperation_RecreatedInstance(t *testing.T) {
+	instance := newOwnedInstance("my-app", "7")
+	instance.UID = "new-uid"
+	instance.Status.Phase = supacontrolv1alpha1.PhaseRunning
+
+	var finished *apitypes.Operation
+	dbClient := newOperationDBClient(&apitypes.Operation{
+		ID: 42, Type: apitypes.OperationTypeCreate, InstanceName: "my-app", InstanceUID: "old-uid", Status: apitypes.OperationStatusRunning,
+	}, &finished)
+	handler := NewHandler(nil, dbClient, newSuspensionCRClient(nil, instance), nil)
+	c, rec := newTestContext(http.MethodGet, "/api/v1/operations/42", "")
+	c.SetParamNames("id")
+	c.SetParamValues("42")
+	setAuthContext(c, 7, "tester", RoleAdmin)
+
+	if err := handler.GetOperation(c); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var operation apitypes.Operation
+	if err := json.Unmarshal(rec.Body.Bytes(), &operation); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if operation.Status != apitypes.OperationStatusFailed || finished == nil {
+		t.Errorf("expected the operation to fail as its instance was deleted, got %+v", operation)
+	}
+}
+
+// TestGetOperation_Errors tests the error responses of GetOperation
+func TestGetOperation_Errors(t *testing.T) {
+	tests := []struct {
+		name           string
+		id             string
+		getErr         error
+		expectedStatus int
+	}{
+		{name: "invalid ID", id: "abc", expectedStatus: http.StatusBadRequest},
+		{name: "not found", id: "7", expectedStatus: http.StatusNotFound},
+		{name: "database error", id: "7", getErr: fmt.Errorf("connection refused"), expectedStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dbClient := &mockDBClient{
+				getOperationFunc: func(_ int64) (*apitypes.Operation, error) {
+					return nil, tt.getErr
+				},
+			}
+			handler := NewHandler(nil, dbClient, nil, nil)
+			c, _ := newTestContext(http.MethodGet, "/api/v1/operations/"+tt.id, "")
+			c.SetParamNames("id")
+			c.SetParamValues(tt.id)
+
+			assertHTTPError(t, handler.GetOperation(c), tt.expectedStatus)
+		})
+	}
+}
+
+// TestDeleteOperationState tests the progress of deleting an instance, immediately or
+// into the trash
+func TestDeleteOperationState(t *testing.T) {
+	deleting := func(phase supacontrolv1alpha1.SupabaseInstancePhase) *supacontrolv1alpha1.SupabaseInstance {
+		instance := newOwnedInstance("my-app", "7")
+		now := metav1.Now()
+		instance.DeletionTimestamp = &now
+		instance.Status.Phase = phase
+		instance.Status.CleanupJobName = "cleanup-my-app"
+		return instance
+	}
+	trashed := newOwnedInstance("my-app", "7")
+	trashed.Spec.PendingDeletion = &supacontrolv1alpha1.PendingDeletion{}
+	trashed.Status.Phase = supacontrolv1alpha1.PhasePendingDeletion
+	restored := newOwnedInstance("my-app", "7")
+	restored.Status.Phase = supacontrolv1alpha1.PhaseRunning
+
+	tests := []struct {
+		name             string
+		instance         *supacontrolv1alpha1.SupabaseInstance
+		expectedStatus   string
+		expectedProgress int
+		expectedJob      string
+	}{
+		{name: "waiting for cleanup", instance: deleting(supacontrolv1alpha1.PhaseDeleting), expectedStatus: apitypes.OperationStatusRunning, expectedProgress: progressCleanup},
+		{name: "cleanup Job running", instance: deleting(supacontrolv1alpha1.PhaseDeletingInProgress), expectedStatus: apitypes.OperationStatusRunning, expectedProgress: progressRemoving, expectedJob: "cleanup-my-app"},
+		{name: "deleted", expectedStatus: apitypes.OperationStatusSucceeded},
+		{name: "moved to the trash", instance: trashed, expectedStatus: apitypes.OperationStatusSucceeded},
+		{name: "restored from the trash", instance: restored, expectedStatus: apitypes.OperationStatusFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state := deleteOperationState(tt.instance)
+			if state.status != tt.expectedStatus || state.progress != tt.expectedProgress || state.job != tt.expectedJob {
+				t.Errorf("expected %s at %d%% with Job %q, got %+v", tt.expectedStatus, tt.expectedProgress, tt.expectedJob, state)
+			}
+		})
+	}
+}
+
+// TestRestartOperationState tests that a restart finishes once every Deployment has
+// rolled out again
+func TestRestartOperationState(t *testing.T) {
+	deployment := func(name string, generation, observed int64, updated, available int32) appsv1.Deployment {
+		replicas := int32(2)
+		return appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Generation: generation},
+			Spec:       appsv1.DeploymentSpec{Replicas: &replicas},
+			Status: appsv1.DeploymentStatus{
+				ObservedGeneration: observed,
+				Replicas:           updated,
+				UpdatedReplicas:    updated,
+				AvailableReplicas:  available,
+			},
+		}
+	}
+	stuck := deployment("auth", 2, 2, 1, 1)
+	stuck.Status.Conditions = []appsv1.DeploymentCondition{{
+		Type: appsv1.DeploymentProgressing, Status: corev1.ConditionFalse, Reason: "ProgressDeadlineExceeded",
+	}}
+
+	tests := []struct {
+		name             string
+		deployments      []appsv1.Deployment
+		expectedStatus   string
+		expectedProgress int
+	}{
+		{name: "rolling out", deployments: []appsv1.Deployment{deployment("rest", 2, 2, 2, 2), deployment("auth", 2, 1, 2, 2)}, expectedStatus: apitypes.OperationStatusRunning, expectedProgress: 50},
+		{name: "waiting for available pods", deployments: []appsv1.Deployment{deployment("rest", 2, 2, 2, 1)}, expectedStatus: apitypes.OperationStatusRunning},
+		{name: "rolled out", deployments: []appsv1.Deployment{deployment("rest", 2, 2, 2, 2), deployment("auth", 3, 3, 2, 2)}, expectedStatus: apitypes.OperationStatusSucceeded},
+		{name: "deadline exceeded", deployments: []appsv1.Deployment{deployment("rest", 2, 2, 2, 2), stuck}, expectedStatus: apitypes.OperationStatusFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state := restartOperationState(tt.deployments)
+			if state.status != tt.expectedStatus || state.progress != tt.expectedProgress {
+				t.Errorf("expected %s at %d%%, got %+v", tt.expectedStatus, tt.expectedProgress, state)
+			}
+		})
+	}
+}
+
+// TestCreateInstance_RecordsOperation tests that CreateInstance returns the ID of the
+// recorded create operation
+func TestCreateInstance_RecordsOperation(t *testing.T) {
+	var recorded string
+	dbClient := &mockDBClient{
+		createOperationFunc: func(operationType, instanceName, instanceUID string, createdBy *int64) (*apitypes.Operation, error) {
+			if createdBy == nil || *createdBy != 7 {
+				t.Errorf("expected the operation to be created by user 7, got %v", createdBy)
+			}
+			recorded = operationType + "/" + instanceName
+			return &apitypes.Operation{ID: 42}, nil
+		},
+	}
+	handler := NewHandler(nil, dbClient, newQuotaCRClient(), &mockK8sClient{clientset: fake.NewSimpleClientset()})
+	c, rec := newTestContext(http.MethodPost, "/api/v1/instances", `{"name":"new-app"}`)
+	setAuthContext(c, 7, "tester", RoleUser)
+
+	if err := handler.CreateInstance(c); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var resp apitypes.CreateInstanceResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.OperationID != 42 || recorded != "create/new-app" {
+		t.Errorf("expected create operation 42 on new-app, got %d (%s)", resp.OperationID, recorded)
+	}
+}
@@ -0,0 +1,53 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+	"github.com/qubitquilt/supacontrol/server/internal/i18n"
+)
+
+// normalizePlacement validates a requested placement and converts it to its CR form.
+// Shared placement is the default, so nil is returned for it.
+func normalizePlacement(req *apitypes.InstancePlacement) (*supacontrolv1alpha1.Placement, error) {
+	if req == nil {
+		return nil, nil
+	}
+	switch req.Mode {
+	case "", apitypes.PlacementShared:
+		if len(req.NodeSelector) > 0 {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, "node selector requires dedicated placement")
+		}
+		return nil, nil
+	case apitypes.PlacementDedicated:
+	default:
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "placement mode must be 'shared' or 'dedicated'")
+	}
+	for key, value := range req.NodeSelector {
+		if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, i18n.Msg("%s is not a valid node label", key))
+		}
+		if errs := validation.IsValidLabelValue(value); len(errs) > 0 {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, i18n.Msg("%s is not a valid node label", key))
+		}
+	}
+	return &supacontrolv1alpha1.Placement{
+		Mode:         supacontrolv1alpha1.PlacementDedicated,
+		NodeSelector: req.NodeSelector,
+	}, nil
+}
+
+// placementToAPIType converts an instance's placement to its API form, omitting shared placement
+func placementToAPIType(placement *supacontrolv1alpha1.Placement) *apitypes.InstancePlacement {
+	if placement == nil || placement.Mode != supacontrolv1alpha1.PlacementDedicated {
+		return nil
+	}
+	return &apitypes.InstancePlacement{
+		Mode:         apitypes.PlacementDedicated,
+		NodeSelector: placement.NodeSelector,
+	}
+}
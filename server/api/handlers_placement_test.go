@@ -0,0 +1,66 @@
+package api
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+)
+
+// TestNormalizePlacement tests placement validation and conversion
+func TestNormalizePlacement(t *testing.T) {
+	tests := []struct {
+		name      string
+		req       *apitypes.InstancePlacement
+		expected  *supacontrolv1alpha1.Placement
+		expectErr bool
+	}{
+		{name: "nil request", req: nil, expected: nil},
+		{name: "shared", req: &apitypes.InstancePlacement{Mode: apitypes.PlacementShared}, expected: nil},
+		{
+			name:     "dedicated",
+			req:      &apitypes.InstancePlacement{Mode: apitypes.PlacementDedicated},
+			expected: &supacontrolv1alpha1.Placement{Mode: supacontrolv1alpha1.PlacementDedicated},
+		},
+		{
+			name: "dedicated with node pool",
+			req: &apitypes.InstancePlacement{
+				Mode:         apitypes.PlacementDedicated,
+				NodeSelector: map[string]string{"cloud.google.com/gke-nodepool": "isolated"},
+			},
+			expected: &supacontrolv1alpha1.Placement{
+				Mode:         supacontrolv1alpha1.PlacementDedicated,
+				NodeSelector: map[string]string{"cloud.google.com/gke-nodepool": "isolated"},
+			},
+		},
+		{name: "unknown mode", req: &apitypes.InstancePlacement{Mode: "exclusive"}, expectErr: true},
+		{
+			name:      "node selector without dedicated mode",
+			req:       &apitypes.InstancePlacement{NodeSelector: map[string]string{"pool": "a"}},
+			expectErr: true,
+		},
+		{
+			name:      "invalid label value",
+			req:       &apitypes.InstancePlacement{Mode: apitypes.PlacementDedicated, NodeSelector: map[string]string{"pool": "not valid"}},
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := normalizePlacement(tt.req)
+			if tt.expectErr {
+				assertHTTPError(t, err, http.StatusBadRequest)
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("expected %+v, got %+v", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+	"github.com/qubitquilt/supacontrol/server/internal/i18n"
+)
+
+// Bounds of the tunable pool sizes, matching the CRD validation
+const (
+	maxDefaultPoolSize      = 500
+	maxClientConnectionsCap = 10000
+)
+
+// poolModes maps the API pool modes to their CR form
+var poolModes = map[string]supacontrolv1alpha1.PoolMode{
+	apitypes.PoolModeSession:     supacontrolv1alpha1.PoolModeSession,
+	apitypes.PoolModeTransaction: supacontrolv1alpha1.PoolModeTransaction,
+	apitypes.PoolModeStatement:   supacontrolv1alpha1.PoolModeStatement,
+}
+
+// normalizeConnectionPooler validates a requested connection pooler and converts it to
+// its CR form. A disabled pooler is the default, so nil is returned for it.
+func normalizeConnectionPooler(req *apitypes.ConnectionPooler) (*supacontrolv1alpha1.ConnectionPooler, error) {
+	if req == nil || !req.Enabled {
+		return nil, nil
+	}
+	pooler := &supacontrolv1alpha1.ConnectionPooler{Enabled: true, PoolMode: supacontrolv1alpha1.PoolModeTransaction}
+	if req.PoolMode != "" {
+		mode, ok := poolModes[req.PoolMode]
+		if !ok {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, "pool mode must be 'session', 'transaction' or 'statement'")
+		}
+		pooler.PoolMode = mode
+	}
+	if req.DefaultPoolSize < 0 || req.DefaultPoolSize > maxDefaultPoolSize {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, i18n.Msg("default pool size must be between 1 and %d", maxDefaultPoolSize))
+	}
+	if req.MaxClientConnections < 0 || req.MaxClientConnections > maxClientConnectionsCap {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, i18n.Msg("max client connections must be between 1 and %d", maxClientConnectionsCap))
+	}
+	pooler.DefaultPoolSize = int32(req.DefaultPoolSize)
+	pooler.MaxClientConnections = int32(req.MaxClientConnections)
+	return pooler, nil
+}
+
+// connectionPoolerToAPIType converts an instance's connection pooler to its API form,
+// omitting a disabled one
+func connectionPoolerToAPIType(pooler *supacontrolv1alpha1.ConnectionPooler) *apitypes.ConnectionPooler {
+	if pooler == nil || !pooler.Enabled {
+		return nil
+	}
+	return &apitypes.ConnectionPooler{
+		Enabled:              true,
+		PoolMode:             strings.ToLower(string(pooler.PoolMode)),
+		DefaultPoolSize:      int(pooler.DefaultPoolSize),
+		MaxClientConnections: int(pooler.MaxClientConnections),
+	}
+}
@@ -0,0 +1,57 @@
+package api
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+)
+
+// TestNormalizeConnectionPooler tests connection pooler validation and conversion
+func TestNormalizeConnectionPooler(t *testing.T) {
+	tests := []struct {
+		name      string
+		req       *apitypes.ConnectionPooler
+		expected  *supacontrolv1alpha1.ConnectionPooler
+		expectErr bool
+	}{
+		{name: "nil request", req: nil, expected: nil},
+		{name: "disabled", req: &apitypes.ConnectionPooler{PoolMode: apitypes.PoolModeSession}, expected: nil},
+		{
+			name:     "enabled with defaults",
+			req:      &apitypes.ConnectionPooler{Enabled: true},
+			expected: &supacontrolv1alpha1.ConnectionPooler{Enabled: true, PoolMode: supacontrolv1alpha1.PoolModeTransaction},
+		},
+		{
+			name: "tuned session pool",
+			req:  &apitypes.ConnectionPooler{Enabled: true, PoolMode: apitypes.PoolModeSession, DefaultPoolSize: 50, MaxClientConnections: 200},
+			expected: &supacontrolv1alpha1.ConnectionPooler{
+				Enabled:              true,
+				PoolMode:             supacontrolv1alpha1.PoolModeSession,
+				DefaultPoolSize:      50,
+				MaxClientConnections: 200,
+			},
+		},
+		{name: "unknown pool mode", req: &apitypes.ConnectionPooler{Enabled: true, PoolMode: "query"}, expectErr: true},
+		{name: "pool size too large", req: &apitypes.ConnectionPooler{Enabled: true, DefaultPoolSize: 501}, expectErr: true},
+		{name: "negative client connections", req: &apitypes.ConnectionPooler{Enabled: true, MaxClientConnections: -1}, expectErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := normalizeConnectionPooler(tt.req)
+			if tt.expectErr {
+				assertHTTPError(t, err, http.StatusBadRequest)
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("expected %+v, got %+v", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,139 @@
+package api
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+	"github.com/qubitquilt/supacontrol/server/controllers"
+	"github.com/qubitquilt/supacontrol/server/internal/i18n"
+)
+
+// WithPostgresImageTags sets the Postgres major versions instances can select besides the
+// chart's default, mapped to the tags of their images
+func WithPostgresImageTags(tags map[string]string) HandlerOption {
+	return func(h *Handler) {
+		h.postgresImageTags = tags
+	}
+}
+
+// chartPostgresVersion returns the Postgres major version a chart version runs by
+// default, or empty when the chart cannot be read
+func (h *Handler) chartPostgresVersion(c echo.Context, chartVersion string) string {
+	if h.chartResolver == nil {
+		return ""
+	}
+	chart, err := h.chartResolver.ComponentVersions(c.Request().Context(), chartVersion)
+	if err != nil {
+		GetLogger(c).Warn("Failed to resolve chart component versions", "chart_version", chartVersion, "error", err)
+		return ""
+	}
+	return controllers.PostgresMajorVersion(chart.Versions[apitypes.ComponentPostgres])
+}
+
+// postgresVersions returns the Postgres major versions instances of a chart version can
+// run, oldest first: those with a configured image and the chart's default
+func (h *Handler) postgresVersions(c echo.Context, chartVersion string) []string {
+	versions := make([]string, 0, len(h.postgresImageTags)+1)
+	for version := range h.postgresImageTags {
+		versions = append(versions, version)
+	}
+	if version := h.chartPostgresVersion(c, chartVersion); version != "" && h.postgresImageTags[version] == "" {
+		versions = append(versions, version)
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		return comparePostgresVersions(versions[i], versions[j]) < 0
+	})
+	return versions
+}
+
+// comparePostgresVersions compares two Postgres major versions numerically
+func comparePostgresVersions(a, b string) int {
+	x, _ := strconv.Atoi(a)
+	y, _ := strconv.Atoi(b)
+	return x - y
+}
+
+// checkPostgresVersion rejects Postgres major versions instances of a chart version
+// cannot run
+func (h *Handler) checkPostgresVersion(c echo.Context, version, chartVersion string) error {
+	versions := h.postgresVersions(c, chartVersion)
+	for _, supported := range versions {
+		if version == supported {
+			return nil
+		}
+	}
+	if len(versions) == 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, i18n.Msg("Postgres version %s is not supported", version))
+	}
+	return echo.NewHTTPError(http.StatusBadRequest, i18n.Msg("Postgres version %s is not supported; supported versions: %s",
+		version, strings.Join(versions, ", ")))
+}
+
+// UpgradeInstancePostgres upgrades a running instance's database in place to a newer
+// Postgres major version (admins and the instance owner only). The controller backs up
+// the database, runs pg_upgrade and verifies the result, restoring the backup when a step
+// fails; progress is reported by the instance phase and its DatabaseUpgraded condition.
+func (h *Handler) UpgradeInstancePostgres(c echo.Context) error {
+	var req apitypes.UpgradePostgresRequest
+	if err := bindRequest(c, &req); err != nil {
+		return err
+	}
+
+	if req.Version == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "version is required")
+	}
+
+	name := c.Param("name")
+	instance, err := h.getInstanceOrError(c, name)
+	if err != nil {
+		return err
+	}
+	if !isAdminOrOwner(GetAuthContext(c), instance) {
+		return echo.NewHTTPError(http.StatusForbidden, "only admins and the instance owner can upgrade Postgres")
+	}
+
+	current := instance.Status.DatabaseVersion
+	if current == "" {
+		current = h.chartPostgresVersion(c, instance.Status.ChartVersion)
+	}
+	if current != "" && comparePostgresVersions(req.Version, current) < 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, i18n.Msg("Postgres cannot be downgraded from %s to %s", current, req.Version))
+	}
+	if err := h.checkPostgresVersion(c, req.Version, instance.Status.ChartVersion); err != nil {
+		return err
+	}
+
+	instance, err = h.patchInstance(c, name, func(instance *supacontrolv1alpha1.SupabaseInstance) error {
+		if instance.Status.Phase != supacontrolv1alpha1.PhaseRunning {
+			return echo.NewHTTPError(http.StatusConflict, "Postgres can only be upgraded while the instance is running")
+		}
+		if current == req.Version || instance.Status.DatabaseVersion == req.Version {
+			return echo.NewHTTPError(http.StatusConflict, i18n.Msg("instance already runs Postgres %s", req.Version))
+		}
+		if instance.Spec.Database == nil {
+			instance.Spec.Database = &supacontrolv1alpha1.Database{}
+		}
+		if instance.Spec.Database.ReadReplicas > 0 {
+			return echo.NewHTTPError(http.StatusConflict, "remove the read replicas before upgrading Postgres")
+		}
+		instance.Spec.Database.Version = req.Version
+		return nil
+	}, "failed to upgrade Postgres")
+	if err != nil {
+		return err
+	}
+
+	h.recordAudit(c, "instance.postgres.upgrade", "instance", name, map[string]string{
+		"from": current,
+		"to":   req.Version,
+	})
+	return c.JSON(http.StatusAccepted, apitypes.GetInstanceResponse{
+		Instance: h.convertCRToAPIType(c, instance),
+	})
+}
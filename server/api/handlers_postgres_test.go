@@ -0,0 +1,105 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+	"github.com/qubitquilt/supacontrol/server/internal/k8s"
+)
+
+// newPostgresChartResolver returns a chart resolver whose charts run Postgres 15 by default
+func newPostgresChartResolver() *mockChartResolver {
+	return &mockChartResolver{
+		componentVersionsFunc: func(_ context.Context, chartVersion string) (*k8s.ChartComponents, error) {
+			return &k8s.ChartComponents{
+				ChartVersion: chartVersion,
+				Versions:     map[string]string{apitypes.ComponentPostgres: "15.8.1.060"},
+			}, nil
+		},
+	}
+}
+
+// TestUpgradeInstancePostgres tests the UpgradeInstancePostgres handler
+func TestUpgradeInstancePostgres(t *testing.T) {
+	tests := []struct {
+		name           string
+		userID         int64
+		role           string
+		phase          supacontrolv1alpha1.SupabaseInstancePhase
+		current        string
+		readReplicas   int32
+		body           string
+		expectedStatus int
+	}{
+		{name: "owner upgrades", userID: 7, role: RoleUser, body: `{"version":"17"}`, expectedStatus: http.StatusAccepted},
+		{name: "admin upgrades from recorded version", userID: 1, role: RoleAdmin, current: "15", body: `{"version":"17"}`, expectedStatus: http.StatusAccepted},
+		{name: "missing version", userID: 7, role: RoleUser, body: `{}`, expectedStatus: http.StatusBadRequest},
+		{name: "downgrade", userID: 7, role: RoleUser, current: "17", body: `{"version":"15"}`, expectedStatus: http.StatusBadRequest},
+		{name: "unsupported version", userID: 7, role: RoleUser, body: `{"version":"16"}`, expectedStatus: http.StatusBadRequest},
+		{name: "same version", userID: 7, role: RoleUser, body: `{"version":"15"}`, expectedStatus: http.StatusConflict},
+		{name: "not running", userID: 7, role: RoleUser, phase: supacontrolv1alpha1.PhaseUpgrading, body: `{"version":"17"}`, expectedStatus: http.StatusConflict},
+		{name: "read replicas", userID: 7, role: RoleUser, readReplicas: 1, body: `{"version":"17"}`, expectedStatus: http.StatusConflict},
+		{name: "other user", userID: 8, role: RoleUser, body: `{"version":"17"}`, expectedStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			instance := newOwnedInstance("my-app", "7")
+			instance.Status.Phase = supacontrolv1alpha1.PhaseRunning
+			if tt.phase != "" {
+				instance.Status.Phase = tt.phase
+			}
+			instance.Status.DatabaseVersion = tt.current
+			if tt.readReplicas > 0 {
+				instance.Spec.Database = &supacontrolv1alpha1.Database{ReadReplicas: tt.readReplicas}
+			}
+			var updated *supacontrolv1alpha1.SupabaseInstance
+			cr := newSuspensionCRClient(nil, instance)
+			cr.updateSupabaseInstanceFunc = func(_ context.Context, instance *supacontrolv1alpha1.SupabaseInstance) error {
+				updated = instance
+				return nil
+			}
+			handler := NewHandler(nil, &mockDBClient{}, cr, nil,
+				WithChartResolver(newPostgresChartResolver()),
+				WithPostgresImageTags(map[string]string{"17": "17.4.1.045"}))
+			c, _ := newTestContext(http.MethodPost, "/api/v1/instances/my-app/postgres-upgrade", tt.body)
+			c.SetParamNames("name")
+			c.SetParamValues("my-app")
+			setAuthContext(c, tt.userID, "someone", tt.role)
+
+			err := handler.UpgradeInstancePostgres(c)
+			if tt.expectedStatus != http.StatusAccepted {
+				assertHTTPError(t, err, tt.expectedStatus)
+				if updated != nil {
+					t.Error("expected the instance not to be updated")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if updated == nil || updated.Spec.Database == nil || updated.Spec.Database.Version != "17" {
+				t.Errorf("expected Postgres 17 in the spec, got %+v", updated)
+			}
+		})
+	}
+}
+
+// TestPostgresVersions tests listing the Postgres major versions instances can run
+func TestPostgresVersions(t *testing.T) {
+	handler := NewHandler(nil, &mockDBClient{}, nil, nil,
+		WithChartResolver(newPostgresChartResolver()),
+		WithPostgresImageTags(map[string]string{"17": "17.4.1.045", "9": "9.6.24"}))
+	c, _ := newTestContext(http.MethodGet, "/api/v1/meta/enums", "")
+
+	if got, want := handler.postgresVersions(c, ""), []string{"9", "15", "17"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+	if err := handler.checkPostgresVersion(c, "16", ""); err == nil {
+		t.Error("expected Postgres 16 to be rejected")
+	}
+}
@@ -0,0 +1,65 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+)
+
+// maxPreferencesSize limits the size of a stored preferences document
+const maxPreferencesSize = 64 * 1024
+
+// GetMyPreferences returns the authenticated user's UI preferences
+func (h *Handler) GetMyPreferences(c echo.Context) error {
+	authCtx := GetAuthContext(c)
+	if authCtx == nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "not authenticated")
+	}
+
+	prefs, err := h.dbClient.GetUserPreferences(authCtx.UserID)
+	if err != nil {
+		GetLogger(c).Error("Failed to get user preferences", "user_id", authCtx.UserID, "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to get preferences")
+	}
+
+	// Users who have never saved preferences get an empty document
+	if prefs == nil {
+		prefs = &apitypes.UserPreferences{Preferences: json.RawMessage("{}")}
+	}
+
+	return c.JSON(http.StatusOK, prefs)
+}
+
+// UpdateMyPreferences replaces the authenticated user's UI preferences
+func (h *Handler) UpdateMyPreferences(c echo.Context) error {
+	authCtx := GetAuthContext(c)
+	if authCtx == nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "not authenticated")
+	}
+
+	var req apitypes.UpdatePreferencesRequest
+	if err := bindRequest(c, &req); err != nil {
+		return err
+	}
+
+	trimmed := bytes.TrimSpace(req.Preferences)
+	if len(trimmed) == 0 || trimmed[0] != '{' {
+		return echo.NewHTTPError(http.StatusBadRequest, "preferences must be a JSON object")
+	}
+
+	if len(trimmed) > maxPreferencesSize {
+		return echo.NewHTTPError(http.StatusRequestEntityTooLarge, "preferences document is too large")
+	}
+
+	prefs, err := h.dbClient.UpsertUserPreferences(authCtx.UserID, json.RawMessage(trimmed))
+	if err != nil {
+		GetLogger(c).Error("Failed to save user preferences", "user_id", authCtx.UserID, "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to save preferences")
+	}
+
+	return c.JSON(http.StatusOK, prefs)
+}
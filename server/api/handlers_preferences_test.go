@@ -0,0 +1,133 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+)
+
+// TestGetMyPreferences tests the GetMyPreferences handler
+func TestGetMyPreferences(t *testing.T) {
+	tests := []struct {
+		name           string
+		stored         *apitypes.UserPreferences
+		getErr         error
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "returns stored preferences",
+			stored:         &apitypes.UserPreferences{Preferences: json.RawMessage(`{"theme":"dark"}`)},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"theme":"dark"}`,
+		},
+		{
+			name:           "returns empty document when none saved",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{}`,
+		},
+		{
+			name:           "database error",
+			getErr:         fmt.Errorf("database unavailable"),
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockDB := &mockDBClient{
+				getUserPreferencesFunc: func(userID int64) (*apitypes.UserPreferences, error) {
+					if userID != 7 {
+						t.Errorf("expected user ID 7, got %d", userID)
+					}
+					return tt.stored, tt.getErr
+				},
+			}
+
+			handler := NewHandler(nil, mockDB, &mockCRClient{}, nil)
+			c, rec := newTestContext(http.MethodGet, "/api/v1/me/preferences", "")
+			setAuthContext(c, 7, "tester", "user")
+
+			err := handler.GetMyPreferences(c)
+			if tt.expectedStatus != http.StatusOK {
+				assertHTTPError(t, err, tt.expectedStatus)
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var resp apitypes.UserPreferences
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if string(resp.Preferences) != tt.expectedBody {
+				t.Errorf("expected preferences %s, got %s", tt.expectedBody, resp.Preferences)
+			}
+		})
+	}
+}
+
+// TestUpdateMyPreferences tests the UpdateMyPreferences handler
+func TestUpdateMyPreferences(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           string
+		expectedStatus int
+		expectSave     bool
+	}{
+		{
+			name:           "saves JSON object",
+			body:           `{"preferences":{"theme":"dark","columns":["name","status"]}}`,
+			expectedStatus: http.StatusOK,
+			expectSave:     true,
+		},
+		{
+			name:           "rejects array",
+			body:           `{"preferences":["dark"]}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "rejects missing preferences",
+			body:           `{}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "rejects oversized document",
+			body:           fmt.Sprintf(`{"preferences":{"blob":"%s"}}`, strings.Repeat("x", maxPreferencesSize)),
+			expectedStatus: http.StatusRequestEntityTooLarge,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			saved := false
+			mockDB := &mockDBClient{
+				upsertUserPreferencesFunc: func(userID int64, preferences json.RawMessage) (*apitypes.UserPreferences, error) {
+					saved = true
+					return &apitypes.UserPreferences{Preferences: preferences}, nil
+				},
+			}
+
+			handler := NewHandler(nil, mockDB, &mockCRClient{}, nil)
+			c, _ := newTestContext(http.MethodPut, "/api/v1/me/preferences", tt.body)
+			setAuthContext(c, 7, "tester", "user")
+
+			err := handler.UpdateMyPreferences(c)
+			if saved != tt.expectSave {
+				t.Errorf("expected saved=%t, got %t", tt.expectSave, saved)
+			}
+			if tt.expectedStatus != http.StatusOK {
+				assertHTTPError(t, err, tt.expectedStatus)
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
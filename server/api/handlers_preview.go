@@ -0,0 +1,105 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+	"github.com/qubitquilt/supacontrol/server/controllers"
+	"github.com/qubitquilt/supacontrol/server/internal/k8s"
+)
+
+// profileValues reads the chart values the controller rendered for an instance, which
+// provisioning and upgrade Jobs apply on top of the release's values
+func (h *Handler) profileValues(ctx context.Context, instance *supacontrolv1alpha1.SupabaseInstance) (map[string]interface{}, error) {
+	values := map[string]interface{}{}
+	if h.k8sClient == nil {
+		return values, nil
+	}
+
+	secret, err := h.k8sClient.GetClientset().CoreV1().Secrets(controllers.ControllerNamespace).Get(ctx, controllers.ProfileValuesSecretName(instance), metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return values, nil
+		}
+		return nil, err
+	}
+	if err := yaml.Unmarshal(secret.Data[controllers.ProfileValuesKey], &values); err != nil {
+		return nil, fmt.Errorf("failed to parse profile values: %w", err)
+	}
+	if values == nil {
+		values = map[string]interface{}{}
+	}
+	return values, nil
+}
+
+// PreviewInstance renders the chart an upgrade would apply to an instance, with the
+// release's values and the instance's current profile values, and returns the manifest
+// changes against the live release without applying them (operators and admins)
+func (h *Handler) PreviewInstance(c echo.Context) error {
+	if h.releasePreviewer == nil {
+		return echo.NewHTTPError(http.StatusServiceUnavailable, "release previews are not enabled")
+	}
+
+	var req apitypes.PreviewInstanceRequest
+	if err := bindRequest(c, &req); err != nil {
+		return err
+	}
+
+	name := c.Param("name")
+	instance, err := h.getInstanceOrError(c, name)
+	if err != nil {
+		return err
+	}
+	// The release of a vCluster-isolated instance lives inside its virtual cluster
+	if instance.Status.IsolationLevel == supacontrolv1alpha1.IsolationVCluster {
+		return echo.NewHTTPError(http.StatusConflict, "release previews are not available for vCluster-isolated instances")
+	}
+	if instance.Status.HelmReleaseName == "" {
+		return echo.NewHTTPError(http.StatusConflict, "instance has no deployed release yet")
+	}
+
+	ctx := c.Request().Context()
+	values, err := h.profileValues(ctx, instance)
+	if err != nil {
+		GetLogger(c).Error("Failed to read profile values", "instance", name, "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to read instance values")
+	}
+
+	chartVersion := strings.TrimSpace(req.ChartVersion)
+	if chartVersion == "" {
+		chartVersion = instance.Spec.ChartVersion
+	}
+
+	preview, err := h.releasePreviewer.PreviewUpgrade(ctx, getInstanceNamespace(instance), instance.Status.HelmReleaseName, chartVersion, values)
+	if err != nil {
+		if errors.Is(err, k8s.ErrReleaseNotFound) {
+			return echo.NewHTTPError(http.StatusConflict, "instance has no deployed release yet")
+		}
+		GetLogger(c).Error("Failed to preview release", "instance", name, "chart_version", chartVersion, "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to preview release")
+	}
+
+	changes, unchanged, err := k8s.ManifestChanges(preview.CurrentManifest, preview.RenderedManifest)
+	if err != nil {
+		GetLogger(c).Error("Failed to compare manifests", "instance", name, "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to preview release")
+	}
+
+	return c.JSON(http.StatusOK, apitypes.InstancePreviewResponse{
+		InstanceName:        name,
+		CurrentChartVersion: preview.CurrentChartVersion,
+		TargetChartVersion:  preview.TargetChartVersion,
+		Changes:             changes,
+		Unchanged:           unchanged,
+	})
+}
@@ -0,0 +1,113 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+	"github.com/qubitquilt/supacontrol/server/controllers"
+	"github.com/qubitquilt/supacontrol/server/internal/k8s"
+)
+
+// TestPreviewInstance tests the PreviewInstance handler
+func TestPreviewInstance(t *testing.T) {
+	tests := []struct {
+		name           string
+		role           string
+		body           string
+		noPreviewer    bool
+		noRelease      bool
+		isolation      supacontrolv1alpha1.IsolationLevel
+		previewErr     error
+		expectedStatus int
+		expectedChart  string
+	}{
+		{name: "instance chart version", role: "admin", expectedStatus: http.StatusOK, expectedChart: "0.1.3"},
+		{name: "requested chart version", role: "admin", body: `{"chart_version":" 0.2.0 "}`, expectedStatus: http.StatusOK, expectedChart: "0.2.0"},
+		{name: "previews disabled", role: "admin", noPreviewer: true, expectedStatus: http.StatusServiceUnavailable},
+		{name: "not provisioned", role: "admin", noRelease: true, expectedStatus: http.StatusConflict},
+		{name: "release missing", role: "admin", previewErr: k8s.ErrReleaseNotFound, expectedStatus: http.StatusConflict},
+		{name: "vcluster isolation", role: "admin", isolation: supacontrolv1alpha1.IsolationVCluster, expectedStatus: http.StatusConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			instance := newOwnedInstance("my-app", "7")
+			instance.Spec.ChartVersion = "0.1.3"
+			instance.Status.IsolationLevel = tt.isolation
+			if tt.noRelease {
+				instance.Status.HelmReleaseName = ""
+			}
+			clientset := fake.NewSimpleClientset(&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      controllers.ProfileValuesSecretName(instance),
+					Namespace: controllers.ControllerNamespace,
+				},
+				Data: map[string][]byte{controllers.ProfileValuesKey: []byte("auth:\n  smtp:\n    host: mail.example.com\n")},
+			})
+
+			var gotChart string
+			var gotValues map[string]interface{}
+			previewer := &mockReleasePreviewer{
+				previewUpgradeFunc: func(_ context.Context, namespace, releaseName, chartVersion string, values map[string]interface{}) (*k8s.ReleasePreview, error) {
+					if namespace != "supa-my-app" || releaseName != "my-app" {
+						t.Errorf("unexpected release %s/%s", namespace, releaseName)
+					}
+					gotChart, gotValues = chartVersion, values
+					if tt.previewErr != nil {
+						return nil, tt.previewErr
+					}
+					return &k8s.ReleasePreview{
+						CurrentChartVersion: "0.1.3",
+						TargetChartVersion:  chartVersion,
+						CurrentManifest:     "kind: Deployment\nmetadata:\n  name: my-app-studio\nspec:\n  replicas: 1\n",
+						RenderedManifest:    "kind: Deployment\nmetadata:\n  name: my-app-studio\nspec:\n  replicas: 2\n",
+					}, nil
+				},
+			}
+
+			var opts []HandlerOption
+			if !tt.noPreviewer {
+				opts = append(opts, WithReleasePreviewer(previewer))
+			}
+			handler := NewHandler(nil, &mockDBClient{}, newSuspensionCRClient(nil, instance), &mockK8sClient{clientset: clientset}, opts...)
+			c, rec := newTestContext(http.MethodPost, "/api/v1/instances/my-app/preview", tt.body)
+			c.SetParamNames("name")
+			c.SetParamValues("my-app")
+			setAuthContext(c, 1, "someone", tt.role)
+
+			err := handler.PreviewInstance(c)
+			if tt.expectedStatus != http.StatusOK {
+				assertHTTPError(t, err, tt.expectedStatus)
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if gotChart != tt.expectedChart {
+				t.Errorf("expected chart version %q, got %q", tt.expectedChart, gotChart)
+			}
+			if _, ok := gotValues["auth"]; !ok {
+				t.Errorf("expected the profile values to be applied, got %v", gotValues)
+			}
+
+			var resp apitypes.InstancePreviewResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if len(resp.Changes) != 1 || resp.Changes[0].Action != apitypes.ManifestChanged {
+				t.Errorf("expected one changed resource, got %+v", resp.Changes)
+			}
+			if resp.TargetChartVersion != tt.expectedChart {
+				t.Errorf("expected target chart version %q, got %q", tt.expectedChart, resp.TargetChartVersion)
+			}
+		})
+	}
+}
@@ -0,0 +1,389 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/mail"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+	"github.com/qubitquilt/supacontrol/server/internal/i18n"
+	"github.com/qubitquilt/supacontrol/server/internal/profiles"
+)
+
+// maxProfileNameLength keeps profile Secret names well within Kubernetes limits
+const maxProfileNameLength = 40
+
+// profileNamePattern matches valid profile names (DNS labels)
+var profileNamePattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]*[a-z0-9])?$`)
+
+// validateProfileFields checks a profile's settings and credentials against its type definition
+func validateProfileFields(profile *profiles.Profile) error {
+	def, ok := profiles.Lookup(profile.Type)
+	if !ok {
+		return echo.NewHTTPError(http.StatusBadRequest, i18n.Msg("profile type must be one of %s", strings.Join(profiles.Types(), ", ")))
+	}
+
+	known := make(map[string]bool, len(def.Settings))
+	for _, field := range def.Settings {
+		known[field.Key] = true
+		value := profile.Settings[field.Key]
+		if value == "" {
+			if field.Required {
+				return echo.NewHTTPError(http.StatusBadRequest, i18n.Msg("setting %s is required", field.Key))
+			}
+			continue
+		}
+		if len(field.Choices) > 0 && !containsString(field.Choices, value) {
+			return echo.NewHTTPError(http.StatusBadRequest, i18n.Msg("setting %s must be one of %s", field.Key, strings.Join(field.Choices, ", ")))
+		}
+	}
+	for key := range profile.Settings {
+		if !known[key] {
+			return echo.NewHTTPError(http.StatusBadRequest, i18n.Msg("unknown setting %s", key))
+		}
+	}
+
+	known = make(map[string]bool, len(def.Secrets))
+	for _, field := range def.Secrets {
+		known[field.Key] = true
+		if field.Required && profile.Secrets[field.Key] == "" {
+			return echo.NewHTTPError(http.StatusBadRequest, i18n.Msg("secret %s is required", field.Key))
+		}
+	}
+	for key := range profile.Secrets {
+		if !known[key] {
+			return echo.NewHTTPError(http.StatusBadRequest, i18n.Msg("unknown secret %s", key))
+		}
+	}
+
+	return nil
+}
+
+// containsString reports whether values contains s
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// getProfile loads a shared service profile, returning nil if it does not exist
+func (h *Handler) getProfile(ctx context.Context, name string) (*profiles.Profile, error) {
+	secret, err := h.k8sClient.GetClientset().CoreV1().Secrets(profiles.Namespace).Get(ctx, profiles.SecretName(name), metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	profile, err := profiles.FromSecret(secret)
+	if err != nil {
+		// A Secret with a profile's name that is not a profile is treated as absent
+		return nil, nil
+	}
+	return profile, nil
+}
+
+// profileUsage maps profile names to the instances that attach them
+func (h *Handler) profileUsage(ctx context.Context) (map[string][]string, error) {
+	crList, err := h.crClient.ListSupabaseInstances(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	usage := make(map[string][]string)
+	for _, instance := range crList.Items {
+		for _, name := range instance.Spec.Profiles {
+			usage[name] = append(usage[name], instance.Spec.ProjectName)
+		}
+	}
+	return usage, nil
+}
+
+// resolveInstanceProfiles checks that the named profiles exist and can be attached together
+func (h *Handler) resolveInstanceProfiles(c echo.Context, names []string) error {
+	slots := make(map[string]string, len(names))
+	for _, name := range names {
+		profile, err := h.getProfile(c.Request().Context(), name)
+		if err != nil {
+			GetLogger(c).Error("Failed to get profile", "profile", name, "error", err)
+			return echo.NewHTTPError(http.StatusInternalServerError, "failed to get profile")
+		}
+		if profile == nil {
+			return echo.NewHTTPError(http.StatusBadRequest, i18n.Msg("profile %s not found", name))
+		}
+
+		slot := profile.Slot()
+		if other, ok := slots[slot]; ok {
+			return echo.NewHTTPError(http.StatusBadRequest, i18n.Msg("profiles %s and %s configure the same service", other, name))
+		}
+		slots[slot] = name
+	}
+	return nil
+}
+
+// toAPIProfile converts a profile to its API representation without credential values
+func toAPIProfile(profile *profiles.Profile, instances []string) *apitypes.ServiceProfile {
+	if instances == nil {
+		instances = []string{}
+	}
+	sort.Strings(instances)
+	return &apitypes.ServiceProfile{
+		Name:       profile.Name,
+		Type:       profile.Type,
+		Settings:   profile.Settings,
+		SecretKeys: profile.SecretKeys(),
+		Instances:  instances,
+		CreatedAt:  profile.CreatedAt,
+	}
+}
+
+// CreateProfile creates a shared service profile (admin only)
+func (h *Handler) CreateProfile(c echo.Context) error {
+
+	var req apitypes.ServiceProfileRequest
+	if err := bindRequest(c, &req); err != nil {
+		return err
+	}
+
+	if req.Name == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "profile name is required")
+	}
+	if len(req.Name) > maxProfileNameLength || !profileNamePattern.MatchString(req.Name) {
+		return echo.NewHTTPError(http.StatusBadRequest, i18n.Msg("profile name must be a lowercase DNS label of at most %d characters", maxProfileNameLength))
+	}
+
+	profile := &profiles.Profile{
+		Name:     req.Name,
+		Type:     req.Type,
+		Settings: req.Settings,
+		Secrets:  req.Secrets,
+	}
+	if err := validateProfileFields(profile); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	secret, err := h.k8sClient.GetClientset().CoreV1().Secrets(profiles.Namespace).Create(ctx, profiles.ToSecret(profile), metav1.CreateOptions{})
+	if err != nil {
+		if apierrors.IsAlreadyExists(err) {
+			return echo.NewHTTPError(http.StatusConflict, "profile with this name already exists")
+		}
+		GetLogger(c).Error("Failed to create profile", "profile", req.Name, "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to create profile")
+	}
+	profile.CreatedAt = secret.CreationTimestamp.Time
+
+	h.recordAudit(c, "profile.create", "profile", profile.Name, map[string]string{"type": profile.Type})
+
+	return c.JSON(http.StatusCreated, toAPIProfile(profile, nil))
+}
+
+// ListProfiles lists shared service profiles and the instances attaching them
+func (h *Handler) ListProfiles(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	secrets, err := h.k8sClient.GetClientset().CoreV1().Secrets(profiles.Namespace).List(ctx, metav1.ListOptions{LabelSelector: profiles.TypeLabel})
+	if err != nil {
+		GetLogger(c).Error("Failed to list profiles", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to list profiles")
+	}
+
+	usage, err := h.profileUsage(ctx)
+	if err != nil {
+		GetLogger(c).Error("Failed to list instances", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to list profiles")
+	}
+
+	result := make([]*apitypes.ServiceProfile, 0, len(secrets.Items))
+	for i := range secrets.Items {
+		profile, err := profiles.FromSecret(&secrets.Items[i])
+		if err != nil {
+			continue
+		}
+		result = append(result, toAPIProfile(profile, usage[profile.Name]))
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+
+	return c.JSON(http.StatusOK, apitypes.ListServiceProfilesResponse{
+		Profiles: result,
+		Count:    len(result),
+	})
+}
+
+// GetProfile gets a single shared service profile
+func (h *Handler) GetProfile(c echo.Context) error {
+	name := c.Param("name")
+	ctx := c.Request().Context()
+
+	profile, err := h.getProfile(ctx, name)
+	if err != nil {
+		GetLogger(c).Error("Failed to get profile", "profile", name, "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to get profile")
+	}
+	if profile == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "profile not found")
+	}
+
+	usage, err := h.profileUsage(ctx)
+	if err != nil {
+		GetLogger(c).Error("Failed to list instances", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to get profile")
+	}
+
+	return c.JSON(http.StatusOK, toAPIProfile(profile, usage[name]))
+}
+
+// UpdateProfile replaces a profile's settings and updates the credentials provided (admin only).
+// Instances pick up the change on their next provisioning or upgrade.
+func (h *Handler) UpdateProfile(c echo.Context) error {
+
+	name := c.Param("name")
+	var req apitypes.ServiceProfileRequest
+	if err := bindRequest(c, &req); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	profile, err := h.getProfile(ctx, name)
+	if err != nil {
+		GetLogger(c).Error("Failed to get profile", "profile", name, "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to update profile")
+	}
+	if profile == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "profile not found")
+	}
+	if req.Type != "" && req.Type != profile.Type {
+		return echo.NewHTTPError(http.StatusBadRequest, "profile type cannot be changed")
+	}
+
+	profile.Settings = req.Settings
+	for key, value := range req.Secrets {
+		profile.Secrets[key] = value
+	}
+	if err := validateProfileFields(profile); err != nil {
+		return err
+	}
+
+	secrets := h.k8sClient.GetClientset().CoreV1().Secrets(profiles.Namespace)
+	secret, err := secrets.Get(ctx, profiles.SecretName(name), metav1.GetOptions{})
+	if err != nil {
+		GetLogger(c).Error("Failed to get profile", "profile", name, "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to update profile")
+	}
+	secret.Data = profiles.ToSecret(profile).Data
+	if _, err := secrets.Update(ctx, secret, metav1.UpdateOptions{}); err != nil {
+		GetLogger(c).Error("Failed to update profile", "profile", name, "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to update profile")
+	}
+
+	h.recordAudit(c, "profile.update", "profile", name, map[string]string{"type": profile.Type})
+
+	usage, err := h.profileUsage(ctx)
+	if err != nil {
+		GetLogger(c).Warn("Failed to list instances", "error", err)
+	}
+
+	return c.JSON(http.StatusOK, toAPIProfile(profile, usage[name]))
+}
+
+// DeleteProfile deletes a shared service profile that no instance attaches (admin only)
+func (h *Handler) DeleteProfile(c echo.Context) error {
+
+	name := c.Param("name")
+	ctx := c.Request().Context()
+
+	usage, err := h.profileUsage(ctx)
+	if err != nil {
+		GetLogger(c).Error("Failed to list instances", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to delete profile")
+	}
+	if instances := usage[name]; len(instances) > 0 {
+		return echo.NewHTTPError(http.StatusConflict, i18n.Msg("profile is attached to instance %s", instances[0]))
+	}
+
+	profile, err := h.getProfile(ctx, name)
+	if err != nil {
+		GetLogger(c).Error("Failed to get profile", "profile", name, "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to delete profile")
+	}
+	if profile == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "profile not found")
+	}
+
+	if err := h.k8sClient.GetClientset().CoreV1().Secrets(profiles.Namespace).Delete(ctx, profiles.SecretName(name), metav1.DeleteOptions{}); err != nil {
+		if apierrors.IsNotFound(err) {
+			return echo.NewHTTPError(http.StatusNotFound, "profile not found")
+		}
+		GetLogger(c).Error("Failed to delete profile", "profile", name, "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to delete profile")
+	}
+
+	h.recordAudit(c, "profile.delete", "profile", name, nil)
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": localize(c, "Profile deleted successfully"),
+	})
+}
+
+// TestSMTPProfile sends a test email through an SMTP profile and returns the SMTP
+// transcript, so misconfigured relays are caught before instances depend on them (admin only).
+// Delivery failures are reported in the response body rather than as an HTTP error.
+func (h *Handler) TestSMTPProfile(c echo.Context) error {
+
+	name := c.Param("name")
+	var req apitypes.SMTPTestRequest
+	if err := bindRequest(c, &req); err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	profile, err := h.getProfile(ctx, name)
+	if err != nil {
+		GetLogger(c).Error("Failed to get profile", "profile", name, "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to get profile")
+	}
+	if profile == nil || profile.Type != profiles.TypeSMTP {
+		return echo.NewHTTPError(http.StatusNotFound, "SMTP profile not found")
+	}
+
+	recipient := strings.TrimSpace(req.To)
+	if recipient == "" {
+		recipient = profile.Settings["sender_email"]
+	}
+	addr, err := mail.ParseAddress(recipient)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid recipient email address")
+	}
+
+	transcript, err := h.sendTestEmail(ctx, profile, addr.Address)
+	resp := apitypes.SMTPTestResponse{
+		Success:    err == nil,
+		Recipient:  addr.Address,
+		Transcript: transcript,
+	}
+	if resp.Transcript == nil {
+		resp.Transcript = []string{}
+	}
+	if err != nil {
+		resp.Error = err.Error()
+		GetLogger(c).Warn("SMTP profile test failed", "profile", name, "error", err)
+	}
+
+	h.recordAudit(c, "profile.smtp_test", "profile", name, map[string]string{
+		"recipient": addr.Address,
+		"success":   strconv.FormatBool(resp.Success),
+	})
+
+	return c.JSON(http.StatusOK, resp)
+}
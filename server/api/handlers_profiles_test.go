@@ -0,0 +1,412 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+	"github.com/qubitquilt/supacontrol/server/internal/k8s"
+	"github.com/qubitquilt/supacontrol/server/internal/profiles"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// testSMTPProfile returns a valid SMTP profile named name
+func testSMTPProfile(name string) *profiles.Profile {
+	return &profiles.Profile{
+		Name:     name,
+		Type:     profiles.TypeSMTP,
+		Settings: map[string]string{"host": "smtp.example.com", "port": "587", "sender_email": "no-reply@example.com"},
+		Secrets:  map[string]string{"username": "user", "password": "hunter2"},
+	}
+}
+
+// instancesWithProfiles returns a CR client listing one instance per profile attachment
+func instancesWithProfiles(attached map[string][]string) *mockCRClient {
+	return &mockCRClient{
+		listSupabaseInstancesFunc: func(context.Context) (*supacontrolv1alpha1.SupabaseInstanceList, error) {
+			list := &supacontrolv1alpha1.SupabaseInstanceList{}
+			for name, profileNames := range attached {
+				instance := newOwnedInstance(name, "1")
+				instance.Spec.Profiles = profileNames
+				list.Items = append(list.Items, *instance)
+			}
+			return list, nil
+		},
+	}
+}
+
+// TestCreateProfile tests the CreateProfile handler
+func TestCreateProfile(t *testing.T) {
+	tests := []struct {
+		name           string
+		role           string
+		body           string
+		expectedStatus int
+	}{
+		{
+			name:           "admin creates smtp profile",
+			role:           "admin",
+			body:           `{"name":"mailgun","type":"smtp","settings":{"host":"smtp.mailgun.org","port":"587","sender_email":"no-reply@example.com"},"secrets":{"username":"postmaster","password":"hunter2"}}`,
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name:           "invalid name",
+			role:           "admin",
+			body:           `{"name":"Mail_Gun","type":"smtp"}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "unknown type",
+			role:           "admin",
+			body:           `{"name":"ftp","type":"ftp"}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "missing required setting",
+			role:           "admin",
+			body:           `{"name":"bucket","type":"s3","settings":{"bucket":"assets"},"secrets":{"access_key_id":"a","secret_access_key":"b"}}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "unknown setting",
+			role:           "admin",
+			body:           `{"name":"bucket","type":"s3","settings":{"bucket":"assets","region":"eu-west-1","acl":"public"},"secrets":{"access_key_id":"a","secret_access_key":"b"}}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "unsupported oauth provider",
+			role:           "admin",
+			body:           `{"name":"myspace","type":"oauth","settings":{"provider":"myspace","client_id":"abc"},"secrets":{"client_secret":"xyz"}}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "missing credential",
+			role:           "admin",
+			body:           `{"name":"github","type":"oauth","settings":{"provider":"github","client_id":"abc"}}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "duplicate name",
+			role:           "admin",
+			body:           `{"name":"existing","type":"smtp","settings":{"host":"smtp.example.com","port":"587","sender_email":"a@example.com"},"secrets":{"username":"u","password":"p"}}`,
+			expectedStatus: http.StatusConflict,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clientset := fake.NewSimpleClientset(profiles.ToSecret(testSMTPProfile("existing")))
+			mockDB := &mockDBClient{
+				createAuditLogFunc: func(int64, string, string, string, map[string]string) error {
+					return nil
+				},
+			}
+
+			handler := NewHandler(nil, mockDB, &mockCRClient{}, &mockK8sClient{clientset: clientset})
+			c, rec := newTestContext(http.MethodPost, "/api/v1/profiles", tt.body)
+			setAuthContext(c, 1, "tester", tt.role)
+
+			err := handler.CreateProfile(c)
+			if tt.expectedStatus != http.StatusCreated {
+				assertHTTPError(t, err, tt.expectedStatus)
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if strings.Contains(rec.Body.String(), "hunter2") {
+				t.Error("response must not include credential values")
+			}
+			var resp apitypes.ServiceProfile
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if strings.Join(resp.SecretKeys, ",") != "password,username" {
+				t.Errorf("unexpected secret keys %v", resp.SecretKeys)
+			}
+
+			secret, err := clientset.CoreV1().Secrets(profiles.Namespace).Get(context.Background(), profiles.SecretName("mailgun"), metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("profile secret not stored: %v", err)
+			}
+			if string(secret.Data["secret.password"]) != "hunter2" {
+				t.Errorf("expected stored password, got %q", secret.Data["secret.password"])
+			}
+		})
+	}
+}
+
+// TestUpdateProfile_KeepsOmittedSecrets tests that credentials left out of an update are preserved
+func TestUpdateProfile_KeepsOmittedSecrets(t *testing.T) {
+	clientset := fake.NewSimpleClientset(profiles.ToSecret(testSMTPProfile("mailgun")))
+	mockDB := &mockDBClient{
+		createAuditLogFunc: func(int64, string, string, string, map[string]string) error {
+			return nil
+		},
+	}
+
+	handler := NewHandler(nil, mockDB, instancesWithProfiles(nil), &mockK8sClient{clientset: clientset})
+	body := `{"settings":{"host":"smtp.eu.mailgun.org","port":"465","sender_email":"no-reply@example.com"},"secrets":{"username":"eu-user"}}`
+	c, _ := newTestContext(http.MethodPut, "/api/v1/profiles/mailgun", body)
+	c.SetParamNames("name")
+	c.SetParamValues("mailgun")
+	setAuthContext(c, 1, "tester", "admin")
+
+	if err := handler.UpdateProfile(c); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	secret, err := clientset.CoreV1().Secrets(profiles.Namespace).Get(context.Background(), profiles.SecretName("mailgun"), metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("failed to get profile secret: %v", err)
+	}
+	profile, err := profiles.FromSecret(secret)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if profile.Settings["host"] != "smtp.eu.mailgun.org" {
+		t.Errorf("expected updated host, got %q", profile.Settings["host"])
+	}
+	if profile.Secrets["username"] != "eu-user" || profile.Secrets["password"] != "hunter2" {
+		t.Errorf("expected updated username and preserved password, got %v", profile.Secrets)
+	}
+}
+
+// TestDeleteProfile tests the DeleteProfile handler
+func TestDeleteProfile(t *testing.T) {
+	tests := []struct {
+		name           string
+		profile        string
+		attached       map[string][]string
+		expectedStatus int
+	}{
+		{
+			name:           "deletes unused profile",
+			profile:        "mailgun",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "profile in use",
+			profile:        "mailgun",
+			attached:       map[string][]string{"my-app": {"mailgun"}},
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			name:           "unknown profile",
+			profile:        "sendgrid",
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clientset := fake.NewSimpleClientset(profiles.ToSecret(testSMTPProfile("mailgun")))
+			mockDB := &mockDBClient{
+				createAuditLogFunc: func(int64, string, string, string, map[string]string) error {
+					return nil
+				},
+			}
+
+			handler := NewHandler(nil, mockDB, instancesWithProfiles(tt.attached), &mockK8sClient{clientset: clientset})
+			c, _ := newTestContext(http.MethodDelete, "/api/v1/profiles/"+tt.profile, "")
+			c.SetParamNames("name")
+			c.SetParamValues(tt.profile)
+			setAuthContext(c, 1, "tester", "admin")
+
+			err := handler.DeleteProfile(c)
+			if tt.expectedStatus != http.StatusOK {
+				assertHTTPError(t, err, tt.expectedStatus)
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+// TestCreateInstance_Profiles tests profile validation when creating an instance
+func TestCreateInstance_Profiles(t *testing.T) {
+	tests := []struct {
+		name           string
+		profiles       string
+		expectedStatus int
+	}{
+		{
+			name:           "attaches existing profiles",
+			profiles:       `["mailgun","github"]`,
+			expectedStatus: http.StatusAccepted,
+		},
+		{
+			name:           "unknown profile",
+			profiles:       `["sendgrid"]`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "two profiles for the same service",
+			profiles:       `["mailgun","ses"]`,
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clientset := fake.NewSimpleClientset(
+				profiles.ToSecret(testSMTPProfile("mailgun")),
+				profiles.ToSecret(testSMTPProfile("ses")),
+				profiles.ToSecret(&profiles.Profile{
+					Name:     "github",
+					Type:     profiles.TypeOAuth,
+					Settings: map[string]string{"provider": "github", "client_id": "abc"},
+					Secrets:  map[string]string{"client_secret": "xyz"},
+				}),
+			)
+			var created *supacontrolv1alpha1.SupabaseInstance
+			mockCR := &mockCRClient{
+				getSupabaseInstanceFunc: func(_ context.Context, name string) (*supacontrolv1alpha1.SupabaseInstance, error) {
+					return nil, k8s.ErrInstanceNotFound
+				},
+				createSupabaseInstanceFunc: func(_ context.Context, instance *supacontrolv1alpha1.SupabaseInstance) error {
+					created = instance
+					return nil
+				},
+			}
+
+			handler := NewHandler(nil, &mockDBClient{}, mockCR, &mockK8sClient{clientset: clientset})
+			c, _ := newTestContext(http.MethodPost, "/api/v1/instances", `{"name":"my-app","profiles":`+tt.profiles+`}`)
+			setAuthContext(c, 1, "tester", "user")
+
+			err := handler.CreateInstance(c)
+			if tt.expectedStatus != http.StatusAccepted {
+				assertHTTPError(t, err, tt.expectedStatus)
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if created == nil || strings.Join(created.Spec.Profiles, ",") != "mailgun,github" {
+				t.Errorf("expected profiles to be set on the instance spec, got %+v", created)
+			}
+		})
+	}
+}
+
+// TestTestSMTPProfile tests the TestSMTPProfile handler
+func TestTestSMTPProfile(t *testing.T) {
+	tests := []struct {
+		name              string
+		profile           string
+		body              string
+		sendErr           error
+		expectedStatus    int
+		expectedRecipient string
+		expectedSuccess   bool
+	}{
+		{
+			name:              "sends to requested recipient",
+			profile:           "mailgun",
+			body:              `{"to":"Admin <admin@example.com>"}`,
+			expectedStatus:    http.StatusOK,
+			expectedRecipient: "admin@example.com",
+			expectedSuccess:   true,
+		},
+		{
+			name:              "defaults to sender address",
+			profile:           "mailgun",
+			body:              `{}`,
+			expectedStatus:    http.StatusOK,
+			expectedRecipient: "no-reply@example.com",
+			expectedSuccess:   true,
+		},
+		{
+			name:              "delivery failure is reported in body",
+			profile:           "mailgun",
+			body:              `{"to":"admin@example.com"}`,
+			sendErr:           fmt.Errorf("server rejected command: 535 authentication failed"),
+			expectedStatus:    http.StatusOK,
+			expectedRecipient: "admin@example.com",
+		},
+		{
+			name:           "invalid recipient",
+			profile:        "mailgun",
+			body:           `{"to":"not-an-email"}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "not an smtp profile",
+			profile:        "github",
+			body:           `{}`,
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "unknown profile",
+			profile:        "sendgrid",
+			body:           `{}`,
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clientset := fake.NewSimpleClientset(
+				profiles.ToSecret(testSMTPProfile("mailgun")),
+				profiles.ToSecret(&profiles.Profile{
+					Name:     "github",
+					Type:     profiles.TypeOAuth,
+					Settings: map[string]string{"provider": "github", "client_id": "abc"},
+					Secrets:  map[string]string{"client_secret": "xyz"},
+				}),
+			)
+			mockDB := &mockDBClient{
+				createAuditLogFunc: func(int64, string, string, string, map[string]string) error {
+					return nil
+				},
+			}
+
+			handler := NewHandler(nil, mockDB, &mockCRClient{}, &mockK8sClient{clientset: clientset})
+			var sentTo string
+			handler.sendTestEmail = func(_ context.Context, _ *profiles.Profile, recipient string) ([]string, error) {
+				sentTo = recipient
+				return []string{"S: 220 fake ESMTP"}, tt.sendErr
+			}
+
+			c, rec := newTestContext(http.MethodPost, "/api/v1/profiles/smtp/"+tt.profile+"/test", tt.body)
+			c.SetParamNames("name")
+			c.SetParamValues(tt.profile)
+			setAuthContext(c, 1, "tester", "admin")
+
+			err := handler.TestSMTPProfile(c)
+			if tt.expectedStatus != http.StatusOK {
+				assertHTTPError(t, err, tt.expectedStatus)
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var resp apitypes.SMTPTestResponse
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if sentTo != tt.expectedRecipient || resp.Recipient != tt.expectedRecipient {
+				t.Errorf("expected recipient %s, sent to %s (response %s)", tt.expectedRecipient, sentTo, resp.Recipient)
+			}
+			if resp.Success != tt.expectedSuccess {
+				t.Errorf("expected success %v, got %v", tt.expectedSuccess, resp.Success)
+			}
+			if !tt.expectedSuccess && resp.Error == "" {
+				t.Error("expected error in response")
+			}
+			if len(resp.Transcript) != 1 {
+				t.Errorf("expected transcript to be returned, got %v", resp.Transcript)
+			}
+		})
+	}
+}
@@ -0,0 +1,242 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+	"github.com/qubitquilt/supacontrol/server/internal/k8s"
+)
+
+const (
+	// defaultProgressInterval is how often the progress stream re-checks an instance
+	defaultProgressInterval = 2 * time.Second
+
+	// progressHeartbeat is how long the progress stream may stay silent before it sends a
+	// comment, so proxies don't close the idle connection
+	progressHeartbeat = 15 * time.Second
+
+	// dnsLookupTimeout bounds the lookup of an instance's API hostname
+	dnsLookupTimeout = 2 * time.Second
+)
+
+// progressSteps are the instance creation steps in the order they happen
+var progressSteps = []string{
+	apitypes.ProgressStepNamespace,
+	apitypes.ProgressStepSecrets,
+	apitypes.ProgressStepChart,
+	apitypes.ProgressStepIngress,
+	apitypes.ProgressStepDNS,
+}
+
+// isProvisioned reports whether an instance's provisioning Job has succeeded, which
+// implies its namespace, secrets and Helm release exist
+func isProvisioned(phase supacontrolv1alpha1.SupabaseInstancePhase) bool {
+	switch phase {
+	case "", supacontrolv1alpha1.PhasePending, supacontrolv1alpha1.PhaseQueued, supacontrolv1alpha1.PhaseProvisioning,
+		supacontrolv1alpha1.PhaseProvisioningInProgress, supacontrolv1alpha1.PhaseFailed:
+		return false
+	}
+	return true
+}
+
+// isCreating reports whether an instance is still on its way to running
+func isCreating(phase supacontrolv1alpha1.SupabaseInstancePhase) bool {
+	return !isProvisioned(phase) && phase != supacontrolv1alpha1.PhaseFailed || phase == supacontrolv1alpha1.PhaseRunning
+}
+
+// completedSteps observes which creation steps of an instance are done. Steps the
+// provisioning Job performs are looked up in the cluster until the Job has succeeded.
+func (h *Handler) completedSteps(ctx context.Context, instance *supacontrolv1alpha1.SupabaseInstance) map[string]bool {
+	done := map[string]bool{}
+	if isProvisioned(instance.Status.Phase) {
+		done[apitypes.ProgressStepNamespace] = true
+		done[apitypes.ProgressStepSecrets] = true
+		done[apitypes.ProgressStepChart] = true
+	} else if h.k8sClient != nil {
+		clientset := h.k8sClient.GetClientset()
+		namespace := getInstanceNamespace(instance)
+		if _, err := clientset.CoreV1().Namespaces().Get(ctx, namespace, metav1.GetOptions{}); err == nil {
+			done[apitypes.ProgressStepNamespace] = true
+		}
+		if _, err := clientset.CoreV1().Secrets(namespace).Get(ctx, instance.Spec.ProjectName+"-secrets", metav1.GetOptions{}); err == nil {
+			done[apitypes.ProgressStepSecrets] = true
+		}
+		release := instance.Status.HelmReleaseName
+		if release == "" {
+			release = instance.Spec.ProjectName
+		}
+		// Helm records each release revision in a Secret labelled with its status
+		revisions, err := clientset.CoreV1().Secrets(namespace).List(ctx, metav1.ListOptions{
+			LabelSelector: "owner=helm,name=" + release,
+		})
+		if err == nil {
+			for _, revision := range revisions.Items {
+				if revision.Labels["status"] == "deployed" {
+					done[apitypes.ProgressStepChart] = true
+				}
+			}
+		}
+	}
+
+	if meta.IsStatusConditionTrue(instance.Status.Conditions, supacontrolv1alpha1.ConditionTypeIngressReady) {
+		done[apitypes.ProgressStepIngress] = true
+		if host := instanceAPIHost(instance); host != "" {
+			lookupCtx, cancel := context.WithTimeout(ctx, dnsLookupTimeout)
+			if addrs, err := h.lookupHost(lookupCtx, host); err == nil && len(addrs) > 0 {
+				done[apitypes.ProgressStepDNS] = true
+			}
+			cancel()
+		}
+	}
+	return done
+}
+
+// instanceAPIHost returns the hostname of an instance's API URL, if it has one yet
+func instanceAPIHost(instance *supacontrolv1alpha1.SupabaseInstance) string {
+	if instance.Status.APIURL == "" {
+		return ""
+	}
+	u, err := url.Parse(instance.Status.APIURL)
+	if err != nil {
+		return ""
+	}
+	return u.Hostname()
+}
+
+// instanceProgress reports the creation progress of an instance. The first step that is
+// not done is in progress while the instance is being created, or failed once
+// provisioning has failed.
+func (h *Handler) instanceProgress(c echo.Context, instance *supacontrolv1alpha1.SupabaseInstance) *apitypes.InstanceProgress {
+	done := h.completedSteps(c.Request().Context(), instance)
+	progress := &apitypes.InstanceProgress{
+		Instance: instance.Spec.ProjectName,
+		Status:   h.convertCRToAPIType(c, instance).Status,
+		Steps:    make([]apitypes.ProgressStep, 0, len(progressSteps)),
+	}
+	if instance.Status.Phase == supacontrolv1alpha1.PhaseFailed {
+		progress.Error = instance.Status.ErrorMessage
+		if progress.Error == "" {
+			progress.Error = "provisioning failed"
+		}
+	}
+
+	current := true
+	for _, name := range progressSteps {
+		step := apitypes.ProgressStep{Step: name, State: apitypes.ProgressPending}
+		switch {
+		case done[name]:
+			step.State = apitypes.ProgressDone
+			progress.Percent += 100 / len(progressSteps)
+		case current && progress.Error != "":
+			step.State = apitypes.ProgressFailed
+			step.Message = progress.Error
+			current = false
+		case current && isCreating(instance.Status.Phase):
+			step.State = apitypes.ProgressInProgress
+			if name == apitypes.ProgressStepDNS {
+				step.Message = fmt.Sprintf("waiting for %s to resolve", instanceAPIHost(instance))
+			}
+			current = false
+		default:
+			current = false
+		}
+		progress.Steps = append(progress.Steps, step)
+	}
+	if len(done) == len(progressSteps) {
+		progress.Percent = 100
+		progress.Complete = true
+	}
+	return progress
+}
+
+// writeEvent writes one Server-Sent Event and flushes it to the client
+func writeEvent(res *echo.Response, event string, data []byte) error {
+	if _, err := fmt.Fprintf(res, "event: %s\ndata: %s\n\n", event, data); err != nil {
+		return err
+	}
+	res.Flush()
+	return nil
+}
+
+// StreamInstanceProgress streams the creation progress of an instance as Server-Sent
+// Events. A progress event is sent whenever a step changes; the stream ends once every
+// step is done, provisioning has failed or the instance is deleted.
+func (h *Handler) StreamInstanceProgress(c echo.Context) error {
+	name := c.Param("name")
+	instance, err := h.getInstanceOrError(c, name)
+	if err != nil {
+		return err
+	}
+
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, "text/event-stream")
+	res.Header().Set(echo.HeaderCacheControl, "no-cache")
+	res.Header().Set(echo.HeaderConnection, "keep-alive")
+	// Keep ingress-nginx from buffering the stream
+	res.Header().Set("X-Accel-Buffering", "no")
+	res.WriteHeader(http.StatusOK)
+
+	ctx := c.Request().Context()
+	ticker := time.NewTicker(h.progressInterval)
+	defer ticker.Stop()
+
+	var last []byte
+	lastWrite := time.Now()
+	for {
+		progress := h.instanceProgress(c, instance)
+		data, err := json.Marshal(progress)
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(data, last) {
+			if err := writeEvent(res, "progress", data); err != nil {
+				return nil
+			}
+			last, lastWrite = data, time.Now()
+		} else if time.Since(lastWrite) >= progressHeartbeat {
+			if _, err := fmt.Fprint(res, ": heartbeat\n\n"); err != nil {
+				return nil
+			}
+			res.Flush()
+			lastWrite = time.Now()
+		}
+		if progress.Complete || progress.Error != "" {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		current, err := h.crClient.GetSupabaseInstance(ctx, name)
+		switch {
+		case errors.Is(err, k8s.ErrInstanceNotFound):
+			data, _ := json.Marshal(apitypes.ErrorResponse{
+				Code:      apitypes.ErrorCodeInstanceNotFound,
+				Message:   localize(c, "instance not found"),
+				RequestID: c.Response().Header().Get("X-Request-ID"),
+			})
+			_ = writeEvent(res, "error", data)
+			return nil
+		case err != nil:
+			// Keep reporting the last known state until the instance can be read again
+			GetLogger(c).Warn("Failed to get instance for progress stream", "instance", name, "error", err)
+		default:
+			instance = current
+		}
+	}
+}
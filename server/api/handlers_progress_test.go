@@ -0,0 +1,210 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+)
+
+// resolveAll is a lookupHost stub that resolves every hostname
+func resolveAll(context.Context, string) ([]string, error) {
+	return []string{"192.0.2.1"}, nil
+}
+
+// resolveNone is a lookupHost stub that resolves no hostname
+func resolveNone(context.Context, string) ([]string, error) {
+	return nil, errors.New("no such host")
+}
+
+// withIngressReady marks an instance's ingresses as created
+func withIngressReady(instance *supacontrolv1alpha1.SupabaseInstance) *supacontrolv1alpha1.SupabaseInstance {
+	meta.SetStatusCondition(&instance.Status.Conditions, metav1.Condition{
+		Type:   supacontrolv1alpha1.ConditionTypeIngressReady,
+		Status: metav1.ConditionTrue,
+		Reason: "IngressCreated",
+	})
+	return instance
+}
+
+// newHelmReleaseSecret returns the Secret Helm stores a release revision in
+func newHelmReleaseSecret(name, status string) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "sh.helm.release.v1." + name + ".v1",
+			Namespace: "supa-" + name,
+			Labels:    map[string]string{"owner": "helm", "name": name, "status": status},
+		},
+	}
+}
+
+// TestInstanceProgress tests how creation steps are derived from an instance and the
+// objects the provisioning Job creates
+func TestInstanceProgress(t *testing.T) {
+	tests := []struct {
+		name       string
+		phase      supacontrolv1alpha1.SupabaseInstancePhase
+		ingress    bool
+		resolves   bool
+		objects    []runtime.Object
+		states     []string
+		percent    int
+		complete   bool
+		errMessage string
+	}{
+		{
+			name:    "nothing created yet",
+			phase:   supacontrolv1alpha1.PhasePending,
+			states:  []string{apitypes.ProgressInProgress, apitypes.ProgressPending, apitypes.ProgressPending, apitypes.ProgressPending, apitypes.ProgressPending},
+			percent: 0,
+		},
+		{
+			name:  "chart installing",
+			phase: supacontrolv1alpha1.PhaseProvisioningInProgress,
+			objects: []runtime.Object{
+				&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "supa-my-app"}},
+				newInstanceSecret("my-app"),
+				newHelmReleaseSecret("my-app", "pending-install"),
+			},
+			states:  []string{apitypes.ProgressDone, apitypes.ProgressDone, apitypes.ProgressInProgress, apitypes.ProgressPending, apitypes.ProgressPending},
+			percent: 40,
+		},
+		{
+			name:     "waiting for DNS",
+			phase:    supacontrolv1alpha1.PhaseRunning,
+			ingress:  true,
+			states:   []string{apitypes.ProgressDone, apitypes.ProgressDone, apitypes.ProgressDone, apitypes.ProgressDone, apitypes.ProgressInProgress},
+			percent:  80,
+			complete: false,
+		},
+		{
+			name:     "complete",
+			phase:    supacontrolv1alpha1.PhaseRunning,
+			ingress:  true,
+			resolves: true,
+			states:   []string{apitypes.ProgressDone, apitypes.ProgressDone, apitypes.ProgressDone, apitypes.ProgressDone, apitypes.ProgressDone},
+			percent:  100,
+			complete: true,
+		},
+		{
+			name:  "provisioning failed",
+			phase: supacontrolv1alpha1.PhaseFailed,
+			objects: []runtime.Object{
+				&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "supa-my-app"}},
+			},
+			states:     []string{apitypes.ProgressDone, apitypes.ProgressFailed, apitypes.ProgressPending, apitypes.ProgressPending, apitypes.ProgressPending},
+			percent:    20,
+			errMessage: "job failed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			instance := newOwnedInstance("my-app", "7")
+			instance.Status.Phase = tt.phase
+			if tt.errMessage != "" {
+				instance.Status.ErrorMessage = tt.errMessage
+			}
+			if tt.ingress {
+				withIngressReady(instance)
+			}
+			handler := NewHandler(nil, &mockDBClient{}, newSuspensionCRClient(nil, instance),
+				&mockK8sClient{clientset: fake.NewSimpleClientset(tt.objects...)})
+			handler.lookupHost = resolveNone
+			if tt.resolves {
+				handler.lookupHost = resolveAll
+			}
+			c, _ := newTestContext(http.MethodGet, "/api/v1/instances/my-app/progress", "")
+
+			progress := handler.instanceProgress(c, instance)
+			for i, step := range progress.Steps {
+				if step.State != tt.states[i] {
+					t.Errorf("step %s: expected %s, got %s", step.Step, tt.states[i], step.State)
+				}
+			}
+			if progress.Percent != tt.percent {
+				t.Errorf("expected %d%%, got %d%%", tt.percent, progress.Percent)
+			}
+			if progress.Complete != tt.complete {
+				t.Errorf("expected complete %v, got %v", tt.complete, progress.Complete)
+			}
+			if progress.Error != tt.errMessage {
+				t.Errorf("expected error %q, got %q", tt.errMessage, progress.Error)
+			}
+		})
+	}
+}
+
+// TestStreamInstanceProgress tests that the progress stream sends an event per change and
+// ends once the instance is ready
+func TestStreamInstanceProgress(t *testing.T) {
+	states := []*supacontrolv1alpha1.SupabaseInstance{
+		newOwnedInstance("my-app", "7"),
+		newOwnedInstance("my-app", "7"),
+		withIngressReady(newOwnedInstance("my-app", "7")),
+	}
+	states[0].Status.Phase = supacontrolv1alpha1.PhaseProvisioningInProgress
+	states[1].Status.Phase = supacontrolv1alpha1.PhaseProvisioningInProgress
+	states[2].Status.Phase = supacontrolv1alpha1.PhaseRunning
+
+	polls := 0
+	cr := newSuspensionCRClient(nil)
+	cr.getSupabaseInstanceFunc = func(context.Context, string) (*supacontrolv1alpha1.SupabaseInstance, error) {
+		instance := states[min(polls, len(states)-1)]
+		polls++
+		return instance, nil
+	}
+	handler := NewHandler(nil, &mockDBClient{}, cr, &mockK8sClient{clientset: fake.NewSimpleClientset()})
+	handler.progressInterval = time.Millisecond
+	handler.lookupHost = resolveAll
+
+	c, rec := newTestContext(http.MethodGet, "/api/v1/instances/my-app/progress", "")
+	c.SetParamNames("name")
+	c.SetParamValues("my-app")
+	if err := handler.StreamInstanceProgress(c); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if ct := rec.Header().Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("expected an event stream, got %q", ct)
+	}
+	var events []apitypes.InstanceProgress
+	for _, line := range strings.Split(rec.Body.String(), "\n") {
+		if data, ok := strings.CutPrefix(line, "data: "); ok {
+			var progress apitypes.InstanceProgress
+			if err := json.Unmarshal([]byte(data), &progress); err != nil {
+				t.Fatalf("failed to decode event: %v", err)
+			}
+			events = append(events, progress)
+		}
+	}
+	// The unchanged second poll must not produce an event
+	if len(events) != 2 {
+		t.Fatalf("expected 2 events, got %d", len(events))
+	}
+	if events[0].Percent != 0 || !events[1].Complete {
+		t.Errorf("expected the stream to go from 0%% to complete, got %+v", events)
+	}
+}
+
+// TestStreamInstanceProgress_NotFound tests that streaming a missing instance fails
+// before the stream starts
+func TestStreamInstanceProgress_NotFound(t *testing.T) {
+	handler := NewHandler(nil, &mockDBClient{}, newSuspensionCRClient(nil), nil)
+	c, _ := newTestContext(http.MethodGet, "/api/v1/instances/missing/progress", "")
+	c.SetParamNames("name")
+	c.SetParamValues("missing")
+	assertHTTPError(t, handler.StreamInstanceProgress(c), http.StatusNotFound)
+}
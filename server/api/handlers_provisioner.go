@@ -0,0 +1,35 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/qubitquilt/supacontrol/server/controllers"
+)
+
+// WithRequireImageDigest rejects provisioner image overrides that are not pinned by digest
+func WithRequireImageDigest(require bool) HandlerOption {
+	return func(h *Handler) {
+		h.requireImageDigest = require
+	}
+}
+
+// checkProvisionerImage validates a requested provisioner image override. Only admins may
+// set one, as the image runs with the provisioner's cluster-wide permissions.
+func (h *Handler) checkProvisionerImage(c echo.Context, image string) error {
+	if image == "" {
+		return nil
+	}
+	if authCtx := GetAuthContext(c); authCtx == nil || !authCtx.IsAdmin() {
+		return echo.NewHTTPError(http.StatusForbidden, "only admins can set the provisioner image")
+	}
+	if err := controllers.ValidateImage(image, h.requireImageDigest); err != nil {
+		if errors.Is(err, controllers.ErrImageNotPinned) {
+			return echo.NewHTTPError(http.StatusBadRequest, "provisioner image must be pinned by digest")
+		}
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid provisioner image")
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// TestCheckProvisionerImage tests that only admins may override the provisioner image,
+// and that digest pinning is enforced when required
+func TestCheckProvisionerImage(t *testing.T) {
+	pinned := "registry.internal/alpine/helm:3.13.0@sha256:" + strings.Repeat("0", 64)
+
+	tests := []struct {
+		name           string
+		image          string
+		role           string
+		requireDigest  bool
+		expectedStatus int
+	}{
+		{name: "no override", image: "", role: RoleUser, expectedStatus: http.StatusOK},
+		{name: "admin tag", image: "registry.internal/alpine/helm:3.13.0", role: RoleAdmin, expectedStatus: http.StatusOK},
+		{name: "admin digest required", image: pinned, role: RoleAdmin, requireDigest: true, expectedStatus: http.StatusOK},
+		{name: "admin tag with digest required", image: "registry.internal/alpine/helm:3.13.0", role: RoleAdmin, requireDigest: true, expectedStatus: http.StatusBadRequest},
+		{name: "admin malformed", image: "registry.internal/alpine/helm:3.13.0 --privileged", role: RoleAdmin, expectedStatus: http.StatusBadRequest},
+		{name: "user", image: pinned, role: RoleUser, expectedStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewHandler(nil, &mockDBClient{}, nil, nil, WithRequireImageDigest(tt.requireDigest))
+			c, _ := newTestContext(http.MethodPost, "/api/v1/instances", "")
+			setAuthContext(c, 1, "someone", tt.role)
+
+			err := handler.checkProvisionerImage(c, tt.image)
+			if tt.expectedStatus != http.StatusOK {
+				assertHTTPError(t, err, tt.expectedStatus)
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
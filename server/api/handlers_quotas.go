@@ -0,0 +1,292 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+	"github.com/qubitquilt/supacontrol/server/internal/i18n"
+)
+
+// WithQuotaDefaults sets the configured per-user and installation-wide quotas, which
+// stored overrides take precedence over (0 means unlimited)
+func WithQuotaDefaults(perUser, global apitypes.QuotaLimits) HandlerOption {
+	return func(h *Handler) {
+		h.userQuotaDefaults = perUser
+		h.globalQuotaDefaults = global
+	}
+}
+
+// applyQuotaOverride returns the defaults with the limits set in override replacing them
+func applyQuotaOverride(defaults apitypes.QuotaLimits, override *apitypes.Quota) apitypes.QuotaLimits {
+	if override == nil {
+		return defaults
+	}
+	if override.MaxInstances != nil {
+		defaults.MaxInstances = *override.MaxInstances
+	}
+	if override.MaxStorageGB != nil {
+		defaults.MaxStorageGB = *override.MaxStorageGB
+	}
+	return defaults
+}
+
+// quotaLimits returns the limits in effect for a user and for the installation
+func (h *Handler) quotaLimits(userID int64) (user, global apitypes.QuotaLimits, err error) {
+	userOverride, err := h.dbClient.GetQuota(&userID)
+	if err != nil {
+		return user, global, err
+	}
+	globalOverride, err := h.dbClient.GetQuota(nil)
+	if err != nil {
+		return user, global, err
+	}
+	return applyQuotaOverride(h.userQuotaDefaults, userOverride), applyQuotaOverride(h.globalQuotaDefaults, globalOverride), nil
+}
+
+// quotaUsage counts the instances, and optionally their storage, owned by a user and
+// across the installation. Instances being deleted no longer count.
+func (h *Handler) quotaUsage(ctx context.Context, userID int64, withStorage bool) (user, global apitypes.QuotaUsage, err error) {
+	list, err := h.crClient.ListSupabaseInstances(ctx)
+	if err != nil {
+		return user, global, err
+	}
+
+	owner := strconv.FormatInt(userID, 10)
+	for i := range list.Items {
+		instance := &list.Items[i]
+		if instance.DeletionTimestamp != nil {
+			continue
+		}
+		var storage int64
+		if withStorage {
+			if storage, err = h.instanceStorageBytes(ctx, instance); err != nil {
+				return user, global, err
+			}
+		}
+		global.Instances++
+		global.StorageBytes += storage
+		if instance.Annotations[supacontrolv1alpha1.AnnotationOwnerID] == owner {
+			user.Instances++
+			user.StorageBytes += storage
+		}
+	}
+	return user, global, nil
+}
+
+// instanceStorageBytes sums the capacity of an instance's persistent volume claims,
+// using the requested size of claims that are not bound yet
+func (h *Handler) instanceStorageBytes(ctx context.Context, instance *supacontrolv1alpha1.SupabaseInstance) (int64, error) {
+	claims, err := h.k8sClient.GetClientset().CoreV1().PersistentVolumeClaims(getInstanceNamespace(instance)).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return 0, err
+	}
+	var total int64
+	for _, claim := range claims.Items {
+		if capacity, ok := claim.Status.Capacity[corev1.ResourceStorage]; ok {
+			total += capacity.Value()
+		} else if request, ok := claim.Spec.Resources.Requests[corev1.ResourceStorage]; ok {
+			total += request.Value()
+		}
+	}
+	return total, nil
+}
+
+// exceedsStorage reports whether usage has reached a storage limit in GB (0 means unlimited)
+func exceedsStorage(usedBytes int64, limitGB int) bool {
+	return limitGB > 0 && usedBytes >= int64(limitGB)<<30
+}
+
+// quotaDetails returns the details of a quota_exceeded error: the scope of the quota
+// (user or global), the limit that was reached and its value
+func quotaDetails(scope, limit string, value int) map[string]string {
+	return map[string]string{"scope": scope, "limit": limit, "value": strconv.Itoa(value)}
+}
+
+// checkQuota rejects creating another instance when the caller or the installation has
+// reached a quota. Storage quotas stop new instances once the limit is reached.
+func (h *Handler) checkQuota(c echo.Context) error {
+	authCtx := GetAuthContext(c)
+	if authCtx == nil {
+		return nil
+	}
+
+	userLimits, globalLimits, err := h.quotaLimits(authCtx.UserID)
+	if err != nil {
+		GetLogger(c).Error("Failed to get quotas", "user_id", authCtx.UserID, "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to check quotas")
+	}
+	if userLimits == (apitypes.QuotaLimits{}) && globalLimits == (apitypes.QuotaLimits{}) {
+		return nil
+	}
+
+	withStorage := userLimits.MaxStorageGB > 0 || globalLimits.MaxStorageGB > 0
+	userUsage, globalUsage, err := h.quotaUsage(c.Request().Context(), authCtx.UserID, withStorage)
+	if err != nil {
+		GetLogger(c).Error("Failed to count quota usage", "user_id", authCtx.UserID, "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to check quotas")
+	}
+
+	switch {
+	case globalLimits.MaxInstances > 0 && globalUsage.Instances >= globalLimits.MaxInstances:
+		return newAPIErrorWithDetails(http.StatusForbidden, apitypes.ErrorCodeQuotaExceeded,
+			i18n.Msg("the installation has reached its limit of %d instances", globalLimits.MaxInstances),
+			quotaDetails("global", "max_instances", globalLimits.MaxInstances))
+	case exceedsStorage(globalUsage.StorageBytes, globalLimits.MaxStorageGB):
+		return newAPIErrorWithDetails(http.StatusForbidden, apitypes.ErrorCodeQuotaExceeded,
+			i18n.Msg("the installation has reached its storage limit of %d GB", globalLimits.MaxStorageGB),
+			quotaDetails("global", "max_storage_gb", globalLimits.MaxStorageGB))
+	case userLimits.MaxInstances > 0 && userUsage.Instances >= userLimits.MaxInstances:
+		return newAPIErrorWithDetails(http.StatusForbidden, apitypes.ErrorCodeQuotaExceeded,
+			i18n.Msg("instance quota exceeded: %d of %d instances in use", userUsage.Instances, userLimits.MaxInstances),
+			quotaDetails("user", "max_instances", userLimits.MaxInstances))
+	case exceedsStorage(userUsage.StorageBytes, userLimits.MaxStorageGB):
+		return newAPIErrorWithDetails(http.StatusForbidden, apitypes.ErrorCodeQuotaExceeded,
+			i18n.Msg("storage quota of %d GB reached", userLimits.MaxStorageGB),
+			quotaDetails("user", "max_storage_gb", userLimits.MaxStorageGB))
+	}
+	return nil
+}
+
+// quotasResponse builds the quota limits and usage of a user and the installation
+func (h *Handler) quotasResponse(c echo.Context, userID int64) (*apitypes.QuotasResponse, error) {
+	userLimits, globalLimits, err := h.quotaLimits(userID)
+	if err != nil {
+		GetLogger(c).Error("Failed to get quotas", "user_id", userID, "error", err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "failed to get quotas")
+	}
+	userUsage, globalUsage, err := h.quotaUsage(c.Request().Context(), userID, true)
+	if err != nil {
+		GetLogger(c).Error("Failed to count quota usage", "user_id", userID, "error", err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "failed to get quotas")
+	}
+	return &apitypes.QuotasResponse{
+		User:   apitypes.QuotaStatus{Limits: userLimits, Usage: userUsage},
+		Global: apitypes.QuotaStatus{Limits: globalLimits, Usage: globalUsage},
+	}, nil
+}
+
+// GetQuotas returns the authenticated user's quota and the installation-wide quota with current usage
+func (h *Handler) GetQuotas(c echo.Context) error {
+	authCtx := GetAuthContext(c)
+	if authCtx == nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "not authenticated")
+	}
+
+	resp, err := h.quotasResponse(c, authCtx.UserID)
+	if err != nil {
+		return err
+	}
+	if resp.Sandbox, err = h.sandboxQuota(c); err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+// quotaUserParam parses the :id parameter and checks that the user exists
+func (h *Handler) quotaUserParam(c echo.Context) (int64, error) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return 0, echo.NewHTTPError(http.StatusBadRequest, "invalid user ID")
+	}
+	user, err := h.dbClient.GetUserByID(id)
+	if err != nil {
+		GetLogger(c).Error("Failed to get user", "user_id", id, "error", err)
+		return 0, echo.NewHTTPError(http.StatusInternalServerError, "failed to get user")
+	}
+	if user == nil {
+		return 0, echo.NewHTTPError(http.StatusNotFound, "user not found")
+	}
+	return id, nil
+}
+
+// bindQuotaOverride reads and validates an UpdateQuotaRequest
+func bindQuotaOverride(c echo.Context) (*apitypes.Quota, error) {
+	var req apitypes.UpdateQuotaRequest
+	if err := bindRequest(c, &req); err != nil {
+		return nil, err
+	}
+	for _, limit := range []*int{req.MaxInstances, req.MaxStorageGB} {
+		if limit != nil && *limit < 0 {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, "quota limits must not be negative")
+		}
+	}
+	return &apitypes.Quota{MaxInstances: req.MaxInstances, MaxStorageGB: req.MaxStorageGB}, nil
+}
+
+// GetUserQuotas returns a user's quota and the installation-wide quota with current usage (admin only)
+func (h *Handler) GetUserQuotas(c echo.Context) error {
+	userID, err := h.quotaUserParam(c)
+	if err != nil {
+		return err
+	}
+
+	resp, err := h.quotasResponse(c, userID)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+// UpdateUserQuota overrides the default quota of a user (admin only)
+func (h *Handler) UpdateUserQuota(c echo.Context) error {
+	userID, err := h.quotaUserParam(c)
+	if err != nil {
+		return err
+	}
+	quota, err := bindQuotaOverride(c)
+	if err != nil {
+		return err
+	}
+	quota.UserID = &userID
+
+	saved, err := h.dbClient.SetQuota(quota)
+	if err != nil {
+		GetLogger(c).Error("Failed to save quota", "user_id", userID, "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to save quota")
+	}
+
+	h.recordAudit(c, "quota.update", "user", strconv.FormatInt(userID, 10), nil)
+	return c.JSON(http.StatusOK, saved)
+}
+
+// DeleteUserQuota removes a user's quota override so the defaults apply again (admin only)
+func (h *Handler) DeleteUserQuota(c echo.Context) error {
+	userID, err := h.quotaUserParam(c)
+	if err != nil {
+		return err
+	}
+
+	if err := h.dbClient.DeleteQuota(userID); err != nil {
+		GetLogger(c).Error("Failed to delete quota", "user_id", userID, "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to delete quota")
+	}
+
+	h.recordAudit(c, "quota.reset", "user", strconv.FormatInt(userID, 10), nil)
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": localize(c, "Quota reset to defaults"),
+	})
+}
+
+// UpdateGlobalQuota overrides the default installation-wide quota (admin only)
+func (h *Handler) UpdateGlobalQuota(c echo.Context) error {
+	quota, err := bindQuotaOverride(c)
+	if err != nil {
+		return err
+	}
+
+	saved, err := h.dbClient.SetQuota(quota)
+	if err != nil {
+		GetLogger(c).Error("Failed to save global quota", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to save quota")
+	}
+
+	h.recordAudit(c, "quota.update", "quota", "global", nil)
+	return c.JSON(http.StatusOK, saved)
+}
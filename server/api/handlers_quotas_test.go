@@ -0,0 +1,202 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"k8s.io/client-go/kubernetes/fake"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+	"github.com/qubitquilt/supacontrol/server/internal/db"
+	"github.com/qubitquilt/supacontrol/server/internal/k8s"
+)
+
+// newQuotaCRClient returns a CR client holding the given instances, where new names do not exist yet
+func newQuotaCRClient(instances ...*supacontrolv1alpha1.SupabaseInstance) *mockCRClient {
+	return &mockCRClient{
+		getSupabaseInstanceFunc: func(_ context.Context, name string) (*supacontrolv1alpha1.SupabaseInstance, error) {
+			return nil, k8s.ErrInstanceNotFound
+		},
+		listSupabaseInstancesFunc: func(_ context.Context) (*supacontrolv1alpha1.SupabaseInstanceList, error) {
+			list := &supacontrolv1alpha1.SupabaseInstanceList{}
+			for _, instance := range instances {
+				list.Items = append(list.Items, *instance)
+			}
+			return list, nil
+		},
+		createSupabaseInstanceFunc: func(_ context.Context, _ *supacontrolv1alpha1.SupabaseInstance) error {
+			return nil
+		},
+	}
+}
+
+// TestCreateInstance_Quotas tests that CreateInstance enforces per-user and global quotas
+func TestCreateInstance_Quotas(t *testing.T) {
+	one, five := 1, 5
+	tests := []struct {
+		name           string
+		userDefaults   apitypes.QuotaLimits
+		globalDefaults apitypes.QuotaLimits
+		userOverride   *apitypes.Quota
+		expectedStatus int
+	}{
+		{name: "unlimited by default", expectedStatus: http.StatusAccepted},
+		{name: "under the user quota", userDefaults: apitypes.QuotaLimits{MaxInstances: 2}, expectedStatus: http.StatusAccepted},
+		{name: "user quota reached", userDefaults: apitypes.QuotaLimits{MaxInstances: 1}, expectedStatus: http.StatusForbidden},
+		{
+			name:           "override raises the user quota",
+			userDefaults:   apitypes.QuotaLimits{MaxInstances: 1},
+			userOverride:   &apitypes.Quota{MaxInstances: &five},
+			expectedStatus: http.StatusAccepted,
+		},
+		{
+			name:           "override of zero removes the user quota",
+			userDefaults:   apitypes.QuotaLimits{MaxInstances: 1},
+			userOverride:   &apitypes.Quota{MaxInstances: new(int)},
+			expectedStatus: http.StatusAccepted,
+		},
+		{name: "global quota reached", globalDefaults: apitypes.QuotaLimits{MaxInstances: 2}, expectedStatus: http.StatusForbidden},
+		{name: "user storage quota reached", userDefaults: apitypes.QuotaLimits{MaxStorageGB: 8}, expectedStatus: http.StatusForbidden},
+		{name: "under the global storage quota", globalDefaults: apitypes.QuotaLimits{MaxStorageGB: 20}, expectedStatus: http.StatusAccepted},
+		{
+			name:           "override lowers the user quota",
+			userOverride:   &apitypes.Quota{MaxInstances: &one},
+			expectedStatus: http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The caller owns my-app (8Gi of storage); another user owns other-app
+			mockCR := newQuotaCRClient(newOwnedInstance("my-app", "7"), newOwnedInstance("other-app", "8"))
+			mockDB := &mockDBClient{
+				getQuotaFunc: func(userID *int64) (*apitypes.Quota, error) {
+					if userID != nil && *userID == 7 {
+						return tt.userOverride, nil
+					}
+					return nil, nil
+				},
+			}
+			clientset := fake.NewSimpleClientset(newInstanceClaim("db-data", "8Gi"))
+			handler := NewHandler(nil, mockDB, mockCR, &mockK8sClient{clientset: clientset},
+				WithQuotaDefaults(tt.userDefaults, tt.globalDefaults))
+
+			c, rec := newTestContext(http.MethodPost, "/api/v1/instances", `{"name":"new-app"}`)
+			setAuthContext(c, 7, "tester", "user")
+
+			err := handler.CreateInstance(c)
+			if tt.expectedStatus != http.StatusAccepted {
+				assertHTTPError(t, err, tt.expectedStatus)
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if rec.Code != http.StatusAccepted {
+				t.Errorf("expected status %d, got %d", http.StatusAccepted, rec.Code)
+			}
+		})
+	}
+}
+
+// TestGetQuotas tests that GetQuotas reports limits and usage for the caller and the installation
+func TestGetQuotas(t *testing.T) {
+	ten := 10
+	mockCR := newQuotaCRClient(newOwnedInstance("my-app", "7"), newOwnedInstance("other-app", "8"))
+	mockDB := &mockDBClient{
+		getQuotaFunc: func(userID *int64) (*apitypes.Quota, error) {
+			if userID == nil {
+				return &apitypes.Quota{MaxInstances: &ten}, nil
+			}
+			return nil, nil
+		},
+	}
+	clientset := fake.NewSimpleClientset(newInstanceClaim("db-data", "8Gi"))
+	handler := NewHandler(nil, mockDB, mockCR, &mockK8sClient{clientset: clientset},
+		WithQuotaDefaults(apitypes.QuotaLimits{MaxInstances: 3, MaxStorageGB: 50}, apitypes.QuotaLimits{MaxInstances: 100}))
+
+	c, rec := newTestContext(http.MethodGet, "/api/v1/quotas", "")
+	setAuthContext(c, 7, "tester", "user")
+
+	if err := handler.GetQuotas(c); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var resp apitypes.QuotasResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.User.Limits != (apitypes.QuotaLimits{MaxInstances: 3, MaxStorageGB: 50}) {
+		t.Errorf("unexpected user limits %+v", resp.User.Limits)
+	}
+	if resp.User.Usage != (apitypes.QuotaUsage{Instances: 1, StorageBytes: 8 << 30}) {
+		t.Errorf("unexpected user usage %+v", resp.User.Usage)
+	}
+	if resp.Global.Limits.MaxInstances != 10 {
+		t.Errorf("expected the global override of 10 instances, got %d", resp.Global.Limits.MaxInstances)
+	}
+	if resp.Global.Usage.Instances != 2 {
+		t.Errorf("expected 2 instances in use globally, got %d", resp.Global.Usage.Instances)
+	}
+}
+
+// TestUpdateUserQuota tests the UpdateUserQuota handler
+func TestUpdateUserQuota(t *testing.T) {
+	tests := []struct {
+		name           string
+		role           string
+		userID         string
+		body           string
+		expectedStatus int
+	}{
+		{name: "admin sets a quota", role: "admin", userID: "8", body: `{"max_instances":5}`, expectedStatus: http.StatusOK},
+		{name: "negative limit", role: "admin", userID: "8", body: `{"max_storage_gb":-1}`, expectedStatus: http.StatusBadRequest},
+		{name: "unknown user", role: "admin", userID: "99", body: `{"max_instances":5}`, expectedStatus: http.StatusNotFound},
+		{name: "invalid user ID", role: "admin", userID: "abc", body: `{}`, expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var saved *apitypes.Quota
+			mockDB := &mockDBClient{
+				getUserByIDFunc: func(id int64) (*db.User, error) {
+					if id == 8 {
+						return &db.User{ID: 8, Username: "other"}, nil
+					}
+					return nil, nil
+				},
+				setQuotaFunc: func(quota *apitypes.Quota) (*apitypes.Quota, error) {
+					saved = quota
+					return quota, nil
+				},
+				createAuditLogFunc: func(_ int64, _, _, _ string, _ map[string]string) error {
+					return nil
+				},
+			}
+			handler := NewHandler(nil, mockDB, nil, nil)
+
+			c, rec := newTestContext(http.MethodPut, "/api/v1/quotas/users/"+tt.userID, tt.body)
+			c.SetParamNames("id")
+			c.SetParamValues(tt.userID)
+			setAuthContext(c, 1, "admin", tt.role)
+
+			err := handler.UpdateUserQuota(c)
+			if tt.expectedStatus != http.StatusOK {
+				assertHTTPError(t, err, tt.expectedStatus)
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if rec.Code != http.StatusOK {
+				t.Errorf("expected status %d, got %d", http.StatusOK, rec.Code)
+			}
+			if saved == nil || saved.UserID == nil || *saved.UserID != 8 || saved.MaxInstances == nil || *saved.MaxInstances != 5 || saved.MaxStorageGB != nil {
+				t.Errorf("unexpected saved quota %+v", saved)
+			}
+		})
+	}
+}
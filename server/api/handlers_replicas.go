@@ -0,0 +1,117 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+	"github.com/qubitquilt/supacontrol/server/internal/i18n"
+)
+
+// validateReadReplicas checks a requested number of read replicas
+func validateReadReplicas(replicas int32) error {
+	if replicas < 0 || replicas > apitypes.MaxReadReplicas {
+		return echo.NewHTTPError(http.StatusBadRequest, i18n.Msg("read replicas must be between 0 and %d", apitypes.MaxReadReplicas))
+	}
+	return nil
+}
+
+// normalizeDatabase validates requested database settings and converts them to their CR
+// form. Nothing requested keeps the defaults, so nil is returned for it. The Postgres
+// version is checked against the chart by checkPostgresVersion.
+func normalizeDatabase(req *apitypes.InstanceDatabase) (*supacontrolv1alpha1.Database, error) {
+	if req == nil || (req.ReadReplicas == 0 && req.Version == "" && !auditEnabled(req.Audit)) {
+		return nil, nil
+	}
+	if err := validateReadReplicas(req.ReadReplicas); err != nil {
+		return nil, err
+	}
+	database := &supacontrolv1alpha1.Database{ReadReplicas: req.ReadReplicas, Version: req.Version}
+	if auditEnabled(req.Audit) {
+		database.Audit = &supacontrolv1alpha1.DatabaseAudit{Enabled: true}
+	}
+	return database, nil
+}
+
+// auditEnabled reports whether requested audit settings turn pgAudit on
+func auditEnabled(audit *apitypes.InstanceDatabaseAudit) bool {
+	return audit != nil && audit.Enabled
+}
+
+// databaseToAPIType converts an instance's database settings to their API form
+func databaseToAPIType(database *supacontrolv1alpha1.Database) *apitypes.InstanceDatabase {
+	if isDefaultDatabase(database) {
+		return nil
+	}
+	converted := &apitypes.InstanceDatabase{ReadReplicas: database.ReadReplicas, Version: database.Version}
+	if database.Audit != nil && database.Audit.Enabled {
+		converted.Audit = &apitypes.InstanceDatabaseAudit{Enabled: true}
+	}
+	return converted
+}
+
+// isDefaultDatabase reports whether database settings leave everything at its default,
+// in which case the spec drops them
+func isDefaultDatabase(database *supacontrolv1alpha1.Database) bool {
+	return database == nil || (database.ReadReplicas == 0 && database.Version == "" &&
+		(database.Audit == nil || !database.Audit.Enabled))
+}
+
+// UpdateInstanceReadReplicas changes the number of an instance's read replicas (admins and
+// the instance owner only). The controller scales the replicas once the instance is running.
+func (h *Handler) UpdateInstanceReadReplicas(c echo.Context) error {
+	var req apitypes.UpdateReadReplicasRequest
+	if err := bindRequest(c, &req); err != nil {
+		return err
+	}
+	if err := validateReadReplicas(req.ReadReplicas); err != nil {
+		return err
+	}
+
+	name := c.Param("name")
+	instance, err := h.getInstanceOrError(c, name)
+	if err != nil {
+		return err
+	}
+	if !isAdminOrOwner(GetAuthContext(c), instance) {
+		return echo.NewHTTPError(http.StatusForbidden, "only admins and the instance owner can change read replicas")
+	}
+	if req.ReadReplicas > 0 && isSandboxInstance(instance) && !GetAuthContext(c).IsAdmin() {
+		return echo.NewHTTPError(http.StatusForbidden, "sandbox instances cannot have read replicas")
+	}
+	if req.ReadReplicas > 0 && instance.Status.IsolationLevel == supacontrolv1alpha1.IsolationVCluster {
+		return echo.NewHTTPError(http.StatusConflict, "read replicas are not supported for vcluster instances")
+	}
+
+	instance, err = h.patchInstance(c, name, func(instance *supacontrolv1alpha1.SupabaseInstance) error {
+		if req.ReadReplicas > 0 && supacontrolv1alpha1.IsDatabaseUpgradePhase(instance.Status.Phase) {
+			return echo.NewHTTPError(http.StatusConflict, "read replicas cannot be added while Postgres is being upgraded")
+		}
+
+		// Keep the selected Postgres version and audit settings
+		database := &supacontrolv1alpha1.Database{}
+		if instance.Spec.Database != nil {
+			database = instance.Spec.Database.DeepCopy()
+		}
+		database.ReadReplicas = req.ReadReplicas
+		if isDefaultDatabase(database) {
+			instance.Spec.Database = nil
+		} else {
+			instance.Spec.Database = database
+		}
+		return nil
+	}, "failed to update read replicas")
+	if err != nil {
+		return err
+	}
+
+	h.recordAudit(c, "instance.read_replicas.update", "instance", name, map[string]string{
+		"read_replicas": strconv.Itoa(int(req.ReadReplicas)),
+	})
+	return c.JSON(http.StatusOK, apitypes.GetInstanceResponse{
+		Instance: h.convertCRToAPIType(c, instance),
+	})
+}
@@ -0,0 +1,66 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+)
+
+// TestUpdateInstanceReadReplicas tests the UpdateInstanceReadReplicas handler
+func TestUpdateInstanceReadReplicas(t *testing.T) {
+	tests := []struct {
+		name             string
+		userID           int64
+		role             string
+		isolation        supacontrolv1alpha1.IsolationLevel
+		body             string
+		conflicts        int
+		expectedStatus   int
+		expectedReplicas int32
+	}{
+		{name: "owner adds replicas", userID: 7, role: RoleUser, body: `{"read_replicas":2}`, expectedStatus: http.StatusOK, expectedReplicas: 2},
+		{name: "retried after a conflicting controller write", userID: 7, role: RoleUser, body: `{"read_replicas":2}`, conflicts: 2, expectedStatus: http.StatusOK, expectedReplicas: 2},
+		{name: "admin removes replicas", userID: 1, role: RoleAdmin, body: `{"read_replicas":0}`, expectedStatus: http.StatusOK},
+		{name: "too many replicas", userID: 7, role: RoleUser, body: `{"read_replicas":6}`, expectedStatus: http.StatusBadRequest},
+		{name: "negative replicas", userID: 7, role: RoleUser, body: `{"read_replicas":-1}`, expectedStatus: http.StatusBadRequest},
+		{name: "vcluster instance", userID: 7, role: RoleUser, isolation: supacontrolv1alpha1.IsolationVCluster, body: `{"read_replicas":1}`, expectedStatus: http.StatusConflict},
+		{name: "other user", userID: 8, role: RoleUser, body: `{"read_replicas":1}`, expectedStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			instance := newOwnedInstance("my-app", "7")
+			instance.Spec.Database = &supacontrolv1alpha1.Database{ReadReplicas: 1}
+			instance.Status.IsolationLevel = tt.isolation
+			var updated *supacontrolv1alpha1.SupabaseInstance
+			cr := newSuspensionCRClient(nil, instance)
+			cr.updateSupabaseInstanceFunc = conflictFirst(tt.conflicts, func(_ context.Context, instance *supacontrolv1alpha1.SupabaseInstance) error {
+				updated = instance
+				return nil
+			})
+			handler := NewHandler(nil, &mockDBClient{}, cr, nil)
+			c, _ := newTestContext(http.MethodPut, "/api/v1/instances/my-app/read-replicas", tt.body)
+			c.SetParamNames("name")
+			c.SetParamValues("my-app")
+			setAuthContext(c, tt.userID, "someone", tt.role)
+
+			err := handler.UpdateInstanceReadReplicas(c)
+			if tt.expectedStatus != http.StatusOK {
+				assertHTTPError(t, err, tt.expectedStatus)
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if updated == nil {
+				t.Fatal("expected the instance to be updated")
+			}
+			if got := databaseToAPIType(updated.Spec.Database); (got == nil) != (tt.expectedReplicas == 0) ||
+				(got != nil && got.ReadReplicas != tt.expectedReplicas) {
+				t.Errorf("expected %d read replicas, got %+v", tt.expectedReplicas, updated.Spec.Database)
+			}
+		})
+	}
+}
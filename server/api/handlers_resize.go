@@ -0,0 +1,147 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+)
+
+// resourceTiers are the predefined database sizes
+var resourceTiers = map[string]supacontrolv1alpha1.ResourceTier{
+	string(supacontrolv1alpha1.ResourceTierSmall):  supacontrolv1alpha1.ResourceTierSmall,
+	string(supacontrolv1alpha1.ResourceTierMedium): supacontrolv1alpha1.ResourceTierMedium,
+	string(supacontrolv1alpha1.ResourceTierLarge):  supacontrolv1alpha1.ResourceTierLarge,
+	string(supacontrolv1alpha1.ResourceTierXLarge): supacontrolv1alpha1.ResourceTierXLarge,
+}
+
+// parseResourceQuantity parses a requested CPU or memory amount, which must be positive
+func parseResourceQuantity(value string) (*resource.Quantity, error) {
+	if value == "" {
+		return nil, nil
+	}
+	quantity, err := resource.ParseQuantity(value)
+	if err != nil || quantity.Sign() <= 0 {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "database CPU and memory must be positive quantities such as '2' or '4Gi'")
+	}
+	return &quantity, nil
+}
+
+// normalizeResources validates requested database resources and converts them to their
+// CR form. Nothing requested keeps the chart defaults, so nil is returned for it.
+func normalizeResources(req *apitypes.InstanceResources) (*supacontrolv1alpha1.Resources, error) {
+	if req == nil || (req.Tier == "" && req.CPU == "" && req.Memory == "") {
+		return nil, nil
+	}
+	resources := &supacontrolv1alpha1.Resources{}
+	if req.Tier != "" {
+		tier, ok := resourceTiers[req.Tier]
+		if !ok {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, "resource tier must be one of small, medium, large or xlarge")
+		}
+		resources.Tier = tier
+	}
+	var err error
+	if resources.CPU, err = parseResourceQuantity(req.CPU); err != nil {
+		return nil, err
+	}
+	if resources.Memory, err = parseResourceQuantity(req.Memory); err != nil {
+		return nil, err
+	}
+	return resources, nil
+}
+
+// resourcesToAPIType converts an instance's database resources to their API form
+func resourcesToAPIType(resources *supacontrolv1alpha1.Resources) *apitypes.InstanceResources {
+	if resources == nil {
+		return nil
+	}
+	result := &apitypes.InstanceResources{Tier: string(resources.Tier)}
+	if resources.CPU != nil {
+		result.CPU = resources.CPU.String()
+	}
+	if resources.Memory != nil {
+		result.Memory = resources.Memory.String()
+	}
+	return result
+}
+
+// isSmallTier reports whether resources stay within the small tier sandboxes are limited to
+func isSmallTier(resources *supacontrolv1alpha1.Resources) bool {
+	return resources == nil ||
+		(resources.Tier == supacontrolv1alpha1.ResourceTierSmall && resources.CPU == nil && resources.Memory == nil)
+}
+
+// UpdateInstance changes the database resources of a running instance and the tags of
+// any instance (admins and the instance owner only). The controller applies resources
+// through the upgrade path, restarting Postgres after a checkpoint, and reports its
+// progress in the Resizing condition.
+func (h *Handler) UpdateInstance(c echo.Context) error {
+	var req apitypes.UpdateInstanceRequest
+	if err := bindRequest(c, &req); err != nil {
+		return err
+	}
+	resources, err := normalizeResources(req.Resources)
+	if err != nil {
+		return err
+	}
+
+	name := c.Param("name")
+	instance, err := h.getInstanceOrError(c, name)
+	if err != nil {
+		return err
+	}
+	authCtx := GetAuthContext(c)
+	if !isAdminOrOwner(authCtx, instance) {
+		if resources == nil {
+			return echo.NewHTTPError(http.StatusForbidden, "only admins and the instance owner can change instance tags")
+		}
+		return echo.NewHTTPError(http.StatusForbidden, "only admins and the instance owner can resize instances")
+	}
+	if resources != nil && isSandboxInstance(instance) && !authCtx.IsAdmin() && !isSmallTier(resources) {
+		return echo.NewHTTPError(http.StatusForbidden, "sandbox instances are limited to the small tier")
+	}
+
+	failure := "failed to resize instance"
+	if resources == nil {
+		failure = "failed to update instance tags"
+	}
+	instance, err = h.patchInstance(c, name, func(instance *supacontrolv1alpha1.SupabaseInstance) error {
+		if resources != nil {
+			if instance.Status.Phase != supacontrolv1alpha1.PhaseRunning {
+				return echo.NewHTTPError(http.StatusConflict, "only running instances can be resized")
+			}
+			instance.Spec.Resources = resources.DeepCopy()
+		}
+		return applyTagChanges(instance, req.Tags)
+	}, failure)
+	if err != nil {
+		return err
+	}
+
+	if resources != nil {
+		applied := resourcesToAPIType(resources)
+		h.recordAudit(c, "instance.resize", "instance", name, map[string]string{
+			"tier":   applied.Tier,
+			"cpu":    applied.CPU,
+			"memory": applied.Memory,
+		})
+	}
+	if len(req.Tags) > 0 {
+		details := make(map[string]string, len(req.Tags))
+		for key, value := range req.Tags {
+			if value == nil {
+				details[key] = ""
+			} else {
+				details[key] = *value
+			}
+		}
+		h.recordAudit(c, "instance.tags.update", "instance", name, details)
+	}
+	return c.JSON(http.StatusOK, apitypes.GetInstanceResponse{
+		Instance: h.convertCRToAPIType(c, instance),
+	})
+}
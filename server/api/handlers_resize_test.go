@@ -0,0 +1,111 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+)
+
+// TestNormalizeResources tests validation of requested database resources
+func TestNormalizeResources(t *testing.T) {
+	tests := []struct {
+		name      string
+		req       *apitypes.InstanceResources
+		expectNil bool
+		expectErr bool
+	}{
+		{name: "nothing requested", req: &apitypes.InstanceResources{}, expectNil: true},
+		{name: "tier", req: &apitypes.InstanceResources{Tier: "large"}},
+		{name: "tier with overrides", req: &apitypes.InstanceResources{Tier: "medium", CPU: "1500m", Memory: "3Gi"}},
+		{name: "quantities only", req: &apitypes.InstanceResources{CPU: "2", Memory: "4Gi"}},
+		{name: "unknown tier", req: &apitypes.InstanceResources{Tier: "huge"}, expectErr: true},
+		{name: "invalid cpu", req: &apitypes.InstanceResources{CPU: "two"}, expectErr: true},
+		{name: "zero memory", req: &apitypes.InstanceResources{Memory: "0"}, expectErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resources, err := normalizeResources(tt.req)
+			if tt.expectErr {
+				assertHTTPError(t, err, http.StatusBadRequest)
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.expectNil != (resources == nil) {
+				t.Fatalf("expected nil %v, got %+v", tt.expectNil, resources)
+			}
+			if resources != nil && *resourcesToAPIType(resources) != *tt.req {
+				t.Errorf("expected %+v to round-trip, got %+v", *tt.req, resourcesToAPIType(resources))
+			}
+		})
+	}
+}
+
+// TestUpdateInstance tests resizing an instance through the UpdateInstance handler
+func TestUpdateInstance(t *testing.T) {
+	tests := []struct {
+		name           string
+		userID         int64
+		role           string
+		phase          supacontrolv1alpha1.SupabaseInstancePhase
+		sandbox        bool
+		body           string
+		conflicts      int
+		expectedStatus int
+	}{
+		{name: "owner picks a tier", userID: 7, role: "user", body: `{"resources":{"tier":"large"}}`, expectedStatus: http.StatusOK},
+		{name: "retried after a conflicting controller write", userID: 7, role: "user", body: `{"resources":{"tier":"large"}}`, conflicts: 2, expectedStatus: http.StatusOK},
+		{name: "admin sets quantities", userID: 1, role: "admin", body: `{"resources":{"cpu":"3","memory":"6Gi"}}`, expectedStatus: http.StatusOK},
+		{name: "sandbox stays small", userID: 7, role: "user", sandbox: true, body: `{"resources":{"tier":"small"}}`, expectedStatus: http.StatusOK},
+		{name: "sandbox grows", userID: 7, role: "user", sandbox: true, body: `{"resources":{"tier":"medium"}}`, expectedStatus: http.StatusForbidden},
+		{name: "admin grows sandbox", userID: 1, role: "admin", sandbox: true, body: `{"resources":{"tier":"medium"}}`, expectedStatus: http.StatusOK},
+		{name: "missing resources", userID: 7, role: "user", body: `{}`, expectedStatus: http.StatusBadRequest},
+		{name: "unknown tier", userID: 7, role: "user", body: `{"resources":{"tier":"huge"}}`, expectedStatus: http.StatusBadRequest},
+		{name: "other user", userID: 8, role: "user", body: `{"resources":{"tier":"large"}}`, expectedStatus: http.StatusForbidden},
+		{name: "still provisioning", userID: 7, role: "user", phase: supacontrolv1alpha1.PhaseProvisioning, body: `{"resources":{"tier":"large"}}`, expectedStatus: http.StatusConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			instance := newOwnedInstance("my-app", "7")
+			instance.Status.Phase = supacontrolv1alpha1.PhaseRunning
+			if tt.phase != "" {
+				instance.Status.Phase = tt.phase
+			}
+			if tt.sandbox {
+				instance.Labels = map[string]string{supacontrolv1alpha1.LabelSandbox: "true"}
+			}
+			var updated *supacontrolv1alpha1.Resources
+			cr := newSuspensionCRClient(nil, instance)
+			cr.updateSupabaseInstanceFunc = conflictFirst(tt.conflicts, func(_ context.Context, instance *supacontrolv1alpha1.SupabaseInstance) error {
+				updated = instance.Spec.Resources
+				return nil
+			})
+			handler := NewHandler(nil, &mockDBClient{}, cr, nil)
+			c, _ := newTestContext(http.MethodPatch, "/api/v1/instances/my-app", tt.body)
+			c.SetParamNames("name")
+			c.SetParamValues("my-app")
+			setAuthContext(c, tt.userID, "someone", tt.role)
+
+			err := handler.UpdateInstance(c)
+			if tt.expectedStatus != http.StatusOK {
+				assertHTTPError(t, err, tt.expectedStatus)
+				if updated != nil {
+					t.Errorf("expected the instance to be left alone, got %+v", updated)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if updated == nil {
+				t.Fatal("expected the resources to be updated")
+			}
+		})
+	}
+}
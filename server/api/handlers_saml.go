@@ -0,0 +1,154 @@
+package api
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/qubitquilt/supacontrol/server/internal/db"
+	"github.com/qubitquilt/supacontrol/server/internal/sso"
+)
+
+const (
+	// samlRequestCookie remembers the ID of the authentication request a browser was sent
+	// to the IdP with, which the IdP's response must refer to
+	samlRequestCookie = "saml_request_id"
+
+	// samlRequestTimeout bounds how long a user may take to authenticate at the IdP
+	samlRequestTimeout = 10 * time.Minute
+)
+
+// WithSAML enables SAML single sign-on through the given service provider. New users get
+// defaultRole unless the IdP manages roles.
+func WithSAML(provider SAMLServiceProvider, defaultRole string) HandlerOption {
+	return func(h *Handler) {
+		h.samlProvider = provider
+		h.samlDefaultRole = defaultRole
+	}
+}
+
+// samlEnabled returns an error when SAML single sign-on is not configured
+func (h *Handler) samlEnabled() error {
+	if h.samlProvider == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "single sign-on is not enabled")
+	}
+	return nil
+}
+
+// GetSAMLMetadata serves the service provider metadata to register with the IdP
+func (h *Handler) GetSAMLMetadata(c echo.Context) error {
+	if err := h.samlEnabled(); err != nil {
+		return err
+	}
+	metadata, err := h.samlProvider.Metadata()
+	if err != nil {
+		GetLogger(c).Error("Failed to build SAML metadata", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to build metadata")
+	}
+	return c.Blob(http.StatusOK, "application/samlmetadata+xml", metadata)
+}
+
+// SAMLLogin starts single sign-on by redirecting the browser to the IdP
+func (h *Handler) SAMLLogin(c echo.Context) error {
+	if err := h.samlEnabled(); err != nil {
+		return err
+	}
+	redirect, requestID, err := h.samlProvider.AuthenticationRequest()
+	if err != nil {
+		GetLogger(c).Error("Failed to create SAML authentication request", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to start single sign-on")
+	}
+
+	// The IdP posts its response cross-site, so the cookie must allow that
+	c.SetCookie(&http.Cookie{
+		Name:     samlRequestCookie,
+		Value:    requestID,
+		Path:     sso.ACSPath,
+		MaxAge:   int(samlRequestTimeout.Seconds()),
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteNoneMode,
+	})
+	return c.Redirect(http.StatusFound, redirect.String())
+}
+
+// SAMLAssertionConsumer consumes the IdP's response, signs the user in and redirects to
+// the UI with the session token in the URL fragment. Users are created on their first
+// sign-in; when the IdP manages roles, the mapped role replaces the stored one.
+func (h *Handler) SAMLAssertionConsumer(c echo.Context) error {
+	if err := h.samlEnabled(); err != nil {
+		return err
+	}
+
+	var requestIDs []string
+	if cookie, err := c.Cookie(samlRequestCookie); err == nil && cookie.Value != "" {
+		requestIDs = append(requestIDs, cookie.Value)
+	}
+	c.SetCookie(&http.Cookie{
+		Name:     samlRequestCookie,
+		Path:     sso.ACSPath,
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteNoneMode,
+	})
+
+	identity, err := h.samlProvider.ParseResponse(c.Request(), requestIDs)
+	if err != nil {
+		GetLogger(c).Warn("Rejected SAML response", "error", err)
+		return h.samlLoginRedirect(c, "error", localize(c, "single sign-on failed"))
+	}
+
+	user, err := h.dbClient.GetUserByUsername(identity.Username)
+	if err != nil {
+		GetLogger(c).Error("Failed to get user", "username", identity.Username, "error", err)
+		return h.samlLoginRedirect(c, "error", localize(c, "failed to authenticate"))
+	}
+	switch {
+	case user == nil:
+		role := identity.Role
+		if role == "" {
+			role = h.samlDefaultRole
+		}
+		user, err = h.dbClient.CreateExternalUser(identity.Username, db.AuthProviderSAML, role)
+		if err != nil {
+			GetLogger(c).Error("Failed to create single sign-on user", "username", identity.Username, "error", err)
+			return h.samlLoginRedirect(c, "error", localize(c, "failed to authenticate"))
+		}
+		if err := h.dbClient.CreateAuditLog(user.ID, "user.sso_create", "user", user.Username, map[string]string{"role": role}); err != nil {
+			GetLogger(c).Error("Failed to record audit log", "action", "user.sso_create", "error", err)
+		}
+	case user.AuthProvider != db.AuthProviderSAML:
+		GetLogger(c).Warn("Refused single sign-on as a local user", "username", identity.Username)
+		return h.samlLoginRedirect(c, "error", localize(c, "this account does not use single sign-on"))
+	case identity.Role != "" && identity.Role != user.Role:
+		if err := h.dbClient.UpdateUserRole(user.ID, identity.Role); err != nil {
+			GetLogger(c).Error("Failed to update user role", "username", identity.Username, "error", err)
+			return h.samlLoginRedirect(c, "error", localize(c, "failed to authenticate"))
+		}
+		if err := h.dbClient.CreateAuditLog(user.ID, "user.sso_role_update", "user", user.Username, map[string]string{
+			"previous_role": user.Role,
+			"role":          identity.Role,
+		}); err != nil {
+			GetLogger(c).Error("Failed to record audit log", "action", "user.sso_role_update", "error", err)
+		}
+		user.Role = identity.Role
+	}
+
+	token, err := h.authService.GenerateJWT(user.ID, user.Username, user.Role, 24*time.Hour)
+	if err != nil {
+		GetLogger(c).Error("Failed to generate token", "username", user.Username, "error", err)
+		return h.samlLoginRedirect(c, "error", localize(c, "failed to generate token"))
+	}
+	return h.samlLoginRedirect(c, "token", token)
+}
+
+// samlLoginRedirect sends the browser to the UI's login page, passing a value in the URL
+// fragment so it is neither sent to servers nor logged
+func (h *Handler) samlLoginRedirect(c echo.Context, key, value string) error {
+	fragment := url.Values{key: {value}}.Encode()
+	return c.Redirect(http.StatusFound, strings.TrimSuffix(h.publicURL, "/")+"/login#"+fragment)
+}
@@ -0,0 +1,141 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/qubitquilt/supacontrol/server/internal/auth"
+	"github.com/qubitquilt/supacontrol/server/internal/db"
+	"github.com/qubitquilt/supacontrol/server/internal/sso"
+)
+
+// TestSAMLDisabled tests that the SAML routes are not found without a service provider
+func TestSAMLDisabled(t *testing.T) {
+	handler := NewHandler(nil, &mockDBClient{}, nil, nil)
+	routes := map[string]func(echo.Context) error{
+		sso.MetadataPath: handler.GetSAMLMetadata,
+		sso.LoginPath:    handler.SAMLLogin,
+		sso.ACSPath:      handler.SAMLAssertionConsumer,
+	}
+	for path, route := range routes {
+		c, _ := newTestContext(http.MethodGet, path, "")
+		assertHTTPError(t, route(c), http.StatusNotFound)
+	}
+}
+
+// TestSAMLLogin tests that single sign-on redirects to the IdP and remembers the request
+func TestSAMLLogin(t *testing.T) {
+	provider := &mockSAMLServiceProvider{
+		authenticationRequestFunc: func() (*url.URL, string, error) {
+			redirect, _ := url.Parse("https://idp.example.com/sso?SAMLRequest=abc")
+			return redirect, "id-123", nil
+		},
+	}
+	handler := NewHandler(nil, &mockDBClient{}, nil, nil, WithSAML(provider, RoleUser))
+	c, rec := newTestContext(http.MethodGet, sso.LoginPath, "")
+
+	if err := handler.SAMLLogin(c); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rec.Code != http.StatusFound || rec.Header().Get("Location") != "https://idp.example.com/sso?SAMLRequest=abc" {
+		t.Errorf("expected redirect to the IdP, got %d to %q", rec.Code, rec.Header().Get("Location"))
+	}
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != samlRequestCookie || cookies[0].Value != "id-123" || !cookies[0].HttpOnly {
+		t.Errorf("expected the request ID cookie, got %v", cookies)
+	}
+}
+
+// TestSAMLAssertionConsumer tests user provisioning and role mapping on single sign-on
+func TestSAMLAssertionConsumer(t *testing.T) {
+	tests := []struct {
+		name            string
+		identity        *sso.Identity
+		parseErr        error
+		existing        *db.User
+		expectedRole    string
+		expectCreate    bool
+		expectRoleSaved bool
+		expectedError   string
+	}{
+		{name: "new user with default role", identity: &sso.Identity{Username: "jane"}, expectedRole: RoleUser, expectCreate: true},
+		{name: "new user with mapped role", identity: &sso.Identity{Username: "jane", Role: RoleOperator}, expectedRole: RoleOperator, expectCreate: true},
+		{name: "returning user keeps role", identity: &sso.Identity{Username: "jane"}, existing: &db.User{ID: 4, Username: "jane", Role: RoleAdmin, AuthProvider: db.AuthProviderSAML}, expectedRole: RoleAdmin},
+		{name: "returning user gets mapped role", identity: &sso.Identity{Username: "jane", Role: RoleReadOnly}, existing: &db.User{ID: 4, Username: "jane", Role: RoleAdmin, AuthProvider: db.AuthProviderSAML}, expectedRole: RoleReadOnly, expectRoleSaved: true},
+		{name: "local account", identity: &sso.Identity{Username: "admin"}, existing: &db.User{ID: 1, Username: "admin", Role: RoleAdmin, AuthProvider: db.AuthProviderLocal}, expectedError: "this account does not use single sign-on"},
+		{name: "invalid response", parseErr: errors.New("signature mismatch"), expectedError: "single sign-on failed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requestIDs []string
+			provider := &mockSAMLServiceProvider{
+				parseResponseFunc: func(r *http.Request, ids []string) (*sso.Identity, error) {
+					requestIDs = ids
+					return tt.identity, tt.parseErr
+				},
+			}
+			var created, roleSaved bool
+			dbClient := &mockDBClient{
+				getUserByUsernameFunc: func(username string) (*db.User, error) {
+					return tt.existing, nil
+				},
+				createExternalUserFunc: func(username, authProvider, role string) (*db.User, error) {
+					created = true
+					if authProvider != db.AuthProviderSAML {
+						t.Errorf("expected a SAML user, got provider %q", authProvider)
+					}
+					return &db.User{ID: 5, Username: username, Role: role, AuthProvider: authProvider}, nil
+				},
+				updateUserRoleFunc: func(id int64, role string) error {
+					roleSaved = true
+					return nil
+				},
+				createAuditLogFunc: func(userID int64, action, resourceType, resourceID string, details map[string]string) error {
+					return nil
+				},
+			}
+			authSvc := auth.NewService("test-secret-key")
+			handler := NewHandler(authSvc, dbClient, nil, nil,
+				WithPublicURL("https://supacontrol.example.com/"), WithSAML(provider, RoleUser))
+			c, rec := newTestContext(http.MethodPost, sso.ACSPath, "SAMLResponse=abc")
+			c.Request().AddCookie(&http.Cookie{Name: samlRequestCookie, Value: "id-123"})
+
+			if err := handler.SAMLAssertionConsumer(c); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(requestIDs, []string{"id-123"}) {
+				t.Errorf("expected the remembered request ID, got %v", requestIDs)
+			}
+
+			location, err := url.Parse(rec.Header().Get("Location"))
+			if rec.Code != http.StatusFound || err != nil || !strings.HasPrefix(location.String(), "https://supacontrol.example.com/login#") {
+				t.Fatalf("expected redirect to the login page, got %d to %q", rec.Code, rec.Header().Get("Location"))
+			}
+			fragment, _ := url.ParseQuery(location.Fragment)
+			if tt.expectedError != "" {
+				if fragment.Get("error") != tt.expectedError {
+					t.Errorf("expected error %q, got %q", tt.expectedError, fragment.Get("error"))
+				}
+				return
+			}
+
+			claims, err := authSvc.ValidateJWT(fragment.Get("token"))
+			if err != nil {
+				t.Fatalf("expected a valid token: %v", err)
+			}
+			if claims.Role != tt.expectedRole {
+				t.Errorf("expected role %q, got %q", tt.expectedRole, claims.Role)
+			}
+			if created != tt.expectCreate || roleSaved != tt.expectRoleSaved {
+				t.Errorf("expected created %v and role saved %v, got %v and %v", tt.expectCreate, tt.expectRoleSaved, created, roleSaved)
+			}
+		})
+	}
+}
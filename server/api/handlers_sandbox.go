@@ -0,0 +1,170 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+	"github.com/qubitquilt/supacontrol/server/internal/i18n"
+)
+
+// SandboxPolicy confines the instances of a designated role or team to developer
+// sandboxes: a limited number of small instances that are deleted automatically. Admins
+// are never sandboxed.
+type SandboxPolicy struct {
+	// Role and Team select the sandboxed users (empty selects nobody)
+	Role string
+	Team string
+
+	// MaxInstances is how many sandbox instances each user may have at once
+	MaxInstances int
+
+	// MaxStorageGB caps the Postgres volume of each sandbox instance
+	MaxStorageGB int
+
+	// TTL is how long after their creation sandbox instances are deleted
+	TTL time.Duration
+}
+
+// WithSandboxPolicy enables developer sandboxes for a role or team
+func WithSandboxPolicy(policy SandboxPolicy) HandlerOption {
+	return func(h *Handler) {
+		h.sandbox = policy
+	}
+}
+
+// isSandboxInstance reports whether an instance was created as a developer sandbox
+func isSandboxInstance(instance *supacontrolv1alpha1.SupabaseInstance) bool {
+	return instance.Labels[supacontrolv1alpha1.LabelSandbox] == "true"
+}
+
+// inSandbox reports whether the sandbox policy applies to the caller
+func (h *Handler) inSandbox(c echo.Context) (bool, error) {
+	authCtx := GetAuthContext(c)
+	if authCtx == nil || authCtx.IsAdmin() || (h.sandbox.Role == "" && h.sandbox.Team == "") {
+		return false, nil
+	}
+	if h.sandbox.Role != "" && authCtx.Role == h.sandbox.Role {
+		return true, nil
+	}
+	if h.sandbox.Team == "" {
+		return false, nil
+	}
+	teams, err := h.dbClient.ListTeamsByUser(authCtx.UserID)
+	if err != nil {
+		GetLogger(c).Error("Failed to list teams", "user_id", authCtx.UserID, "error", err)
+		return false, echo.NewHTTPError(http.StatusInternalServerError, "failed to check sandbox policy")
+	}
+	for _, team := range teams {
+		if team.Name == h.sandbox.Team {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// sandboxStorage returns the storage limit of sandbox instances
+func (h *Handler) sandboxStorage() *resource.Quantity {
+	return resource.NewQuantity(int64(h.sandbox.MaxStorageGB)<<30, resource.BinarySI)
+}
+
+// sandboxInstances counts a user's sandbox instances. Instances being deleted no longer count.
+func (h *Handler) sandboxInstances(ctx context.Context, userID int64) (int, error) {
+	list, err := h.crClient.ListSupabaseInstances(ctx)
+	if err != nil {
+		return 0, err
+	}
+	owner := strconv.FormatInt(userID, 10)
+	count := 0
+	for i := range list.Items {
+		instance := &list.Items[i]
+		if instance.DeletionTimestamp == nil && isSandboxInstance(instance) &&
+			instance.Annotations[supacontrolv1alpha1.AnnotationOwnerID] == owner {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// applySandbox turns an instance requested by a sandboxed user into a sandbox: it rejects
+// settings beyond the small tier and the sandbox limit, caps its storage and TTL, and
+// labels it. Other users' instances are left untouched.
+func (h *Handler) applySandbox(c echo.Context, instance *supacontrolv1alpha1.SupabaseInstance) error {
+	sandboxed, err := h.inSandbox(c)
+	if err != nil || !sandboxed {
+		return err
+	}
+
+	spec := &instance.Spec
+	switch {
+	case spec.Placement != nil && spec.Placement.Mode == supacontrolv1alpha1.PlacementDedicated:
+		return echo.NewHTTPError(http.StatusForbidden, "sandbox instances must use shared placement")
+	case spec.Isolation != nil && spec.Isolation.Level != supacontrolv1alpha1.IsolationNamespace:
+		return echo.NewHTTPError(http.StatusForbidden, "sandbox instances must use namespace isolation")
+	case spec.Database != nil && spec.Database.ReadReplicas > 0:
+		return echo.NewHTTPError(http.StatusForbidden, "sandbox instances cannot have read replicas")
+	case spec.HighAvailability != nil:
+		return echo.NewHTTPError(http.StatusForbidden, "sandbox instances cannot be highly available")
+	case !isSmallTier(spec.Resources):
+		return echo.NewHTTPError(http.StatusForbidden, "sandbox instances are limited to the small tier")
+	case spec.DeletionProtection:
+		return echo.NewHTTPError(http.StatusForbidden, "sandbox instances cannot be protected from deletion")
+	}
+
+	limit := h.sandboxStorage()
+	if spec.Storage == nil {
+		spec.Storage = &supacontrolv1alpha1.Storage{}
+	}
+	if spec.Storage.Size == nil {
+		spec.Storage.Size = limit
+	} else if spec.Storage.Size.Cmp(*limit) > 0 {
+		return echo.NewHTTPError(http.StatusForbidden, i18n.Msg("sandbox instances can use at most %d GB of storage", h.sandbox.MaxStorageGB))
+	}
+	if spec.TTL == nil || spec.TTL.Duration > h.sandbox.TTL {
+		spec.TTL = &metav1.Duration{Duration: h.sandbox.TTL}
+	}
+
+	authCtx := GetAuthContext(c)
+	count, err := h.sandboxInstances(c.Request().Context(), authCtx.UserID)
+	if err != nil {
+		GetLogger(c).Error("Failed to count sandbox instances", "user_id", authCtx.UserID, "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to check sandbox policy")
+	}
+	if count >= h.sandbox.MaxInstances {
+		return echo.NewHTTPError(http.StatusForbidden, i18n.Msg("sandbox limit of %d instances reached", h.sandbox.MaxInstances))
+	}
+
+	if instance.Labels == nil {
+		instance.Labels = map[string]string{}
+	}
+	instance.Labels[supacontrolv1alpha1.LabelSandbox] = "true"
+	return nil
+}
+
+// sandboxQuota reports the caller's sandbox limits and usage, or nil when the caller is
+// not sandboxed
+func (h *Handler) sandboxQuota(c echo.Context) (*apitypes.SandboxQuota, error) {
+	sandboxed, err := h.inSandbox(c)
+	if err != nil || !sandboxed {
+		return nil, err
+	}
+	userID := GetAuthContext(c).UserID
+	count, err := h.sandboxInstances(c.Request().Context(), userID)
+	if err != nil {
+		GetLogger(c).Error("Failed to count sandbox instances", "user_id", userID, "error", err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "failed to get quotas")
+	}
+	return &apitypes.SandboxQuota{
+		MaxInstances: h.sandbox.MaxInstances,
+		MaxStorageGB: h.sandbox.MaxStorageGB,
+		TTL:          h.sandbox.TTL.String(),
+		Instances:    count,
+	}, nil
+}
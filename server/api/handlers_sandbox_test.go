@@ -0,0 +1,204 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+)
+
+// testSandboxPolicy sandboxes the user role and the engineering team
+var testSandboxPolicy = SandboxPolicy{
+	Role:         RoleUser,
+	Team:         "engineering",
+	MaxInstances: 2,
+	MaxStorageGB: 5,
+	TTL:          7 * 24 * time.Hour,
+}
+
+// newSandboxInstance returns a sandbox instance owned by a user
+func newSandboxInstance(name, ownerID string) *supacontrolv1alpha1.SupabaseInstance {
+	instance := newOwnedInstance(name, ownerID)
+	instance.Labels = map[string]string{supacontrolv1alpha1.LabelSandbox: "true"}
+	return instance
+}
+
+// TestCreateInstance_Sandbox tests that instances of sandboxed users are confined to the
+// sandbox tier, capped in count and given the sandbox TTL
+func TestCreateInstance_Sandbox(t *testing.T) {
+	tests := []struct {
+		name           string
+		role           string
+		teams          []string
+		body           string
+		existing       int
+		expectedStatus int
+		expectSandbox  bool
+		expectedTTL    time.Duration
+	}{
+		{name: "sandboxed role", role: RoleUser, body: `{"name":"new-app"}`, expectedStatus: http.StatusAccepted, expectSandbox: true, expectedTTL: 7 * 24 * time.Hour},
+		{name: "shorter TTL kept", role: RoleUser, body: `{"name":"new-app","ttl":"24h"}`, expectedStatus: http.StatusAccepted, expectSandbox: true, expectedTTL: 24 * time.Hour},
+		{name: "longer TTL capped", role: RoleUser, body: `{"name":"new-app","ttl":"240h"}`, expectedStatus: http.StatusAccepted, expectSandbox: true, expectedTTL: 7 * 24 * time.Hour},
+		{name: "sandboxed team", role: RoleOperator, teams: []string{"platform", "engineering"}, body: `{"name":"new-app"}`, expectedStatus: http.StatusAccepted, expectSandbox: true, expectedTTL: 7 * 24 * time.Hour},
+		{name: "other team", role: RoleOperator, teams: []string{"platform"}, body: `{"name":"new-app"}`, expectedStatus: http.StatusAccepted},
+		{name: "admin", role: RoleAdmin, body: `{"name":"new-app"}`, expectedStatus: http.StatusAccepted},
+		{name: "under the limit", role: RoleUser, body: `{"name":"new-app"}`, existing: 1, expectedStatus: http.StatusAccepted, expectSandbox: true, expectedTTL: 7 * 24 * time.Hour},
+		{name: "limit reached", role: RoleUser, body: `{"name":"new-app"}`, existing: 2, expectedStatus: http.StatusForbidden},
+		{name: "dedicated placement", role: RoleUser, body: `{"name":"new-app","placement":{"mode":"dedicated"}}`, expectedStatus: http.StatusForbidden},
+		{name: "vcluster isolation", role: RoleUser, body: `{"name":"new-app","isolation":{"level":"vcluster"}}`, expectedStatus: http.StatusForbidden},
+		{name: "read replicas", role: RoleUser, body: `{"name":"new-app","database":{"read_replicas":1}}`, expectedStatus: http.StatusForbidden},
+		{name: "high availability", role: RoleUser, body: `{"name":"new-app","high_availability":{"replicas":2}}`, expectedStatus: http.StatusForbidden},
+		{name: "large tier", role: RoleUser, body: `{"name":"new-app","resources":{"tier":"large"}}`, expectedStatus: http.StatusForbidden},
+		{name: "too much storage", role: RoleUser, body: `{"name":"new-app","storage":{"size":"10Gi"}}`, expectedStatus: http.StatusForbidden},
+		{name: "deletion protection", role: RoleUser, body: `{"name":"new-app","deletion_protection":true}`, expectedStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Instances of other users and the caller's regular instances don't count
+			instances := []*supacontrolv1alpha1.SupabaseInstance{newOwnedInstance("my-app", "7"), newSandboxInstance("their-app", "8")}
+			for _, name := range []string{"sandbox-1", "sandbox-2"}[:tt.existing] {
+				instances = append(instances, newSandboxInstance(name, "7"))
+			}
+			var created *supacontrolv1alpha1.SupabaseInstance
+			mockCR := newQuotaCRClient(instances...)
+			mockCR.createSupabaseInstanceFunc = func(_ context.Context, instance *supacontrolv1alpha1.SupabaseInstance) error {
+				created = instance
+				return nil
+			}
+			mockDB := &mockDBClient{
+				listTeamsByUserFunc: func(int64) ([]*apitypes.Team, error) {
+					teams := make([]*apitypes.Team, 0, len(tt.teams))
+					for _, name := range tt.teams {
+						teams = append(teams, &apitypes.Team{Name: name})
+					}
+					return teams, nil
+				},
+			}
+			policy := testSandboxPolicy
+			if tt.teams == nil {
+				policy.Team = ""
+			}
+			handler := NewHandler(nil, mockDB, mockCR, &mockK8sClient{clientset: fake.NewSimpleClientset()}, WithSandboxPolicy(policy))
+
+			c, _ := newTestContext(http.MethodPost, "/api/v1/instances", tt.body)
+			setAuthContext(c, 7, "tester", tt.role)
+
+			err := handler.CreateInstance(c)
+			if tt.expectedStatus != http.StatusAccepted {
+				assertHTTPError(t, err, tt.expectedStatus)
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if isSandboxInstance(created) != tt.expectSandbox {
+				t.Fatalf("expected sandbox %v, got labels %v", tt.expectSandbox, created.Labels)
+			}
+			if !tt.expectSandbox {
+				if created.Spec.TTL != nil {
+					t.Errorf("expected no TTL, got %v", created.Spec.TTL.Duration)
+				}
+				return
+			}
+			if created.Spec.TTL == nil || created.Spec.TTL.Duration != tt.expectedTTL {
+				t.Errorf("expected TTL %v, got %v", tt.expectedTTL, created.Spec.TTL)
+			}
+			if created.Spec.Storage == nil || created.Spec.Storage.Size == nil || created.Spec.Storage.Size.String() != "5Gi" {
+				t.Errorf("expected storage capped at 5Gi, got %+v", created.Spec.Storage)
+			}
+		})
+	}
+}
+
+// TestSandboxInstanceChanges tests that only admins can extend, protect or grow sandbox instances
+func TestSandboxInstanceChanges(t *testing.T) {
+	tests := []struct {
+		name           string
+		call           func(*Handler) func(echo.Context) error
+		body           string
+		role           string
+		expectedStatus int
+	}{
+		{name: "owner extends", call: func(h *Handler) func(echo.Context) error { return h.ExtendInstance }, body: `{"duration":"24h"}`, role: RoleUser, expectedStatus: http.StatusForbidden},
+		{name: "admin extends", call: func(h *Handler) func(echo.Context) error { return h.ExtendInstance }, body: `{"duration":"24h"}`, role: RoleAdmin, expectedStatus: http.StatusOK},
+		{name: "owner protects", call: func(h *Handler) func(echo.Context) error { return h.UpdateDeletionProtection }, body: `{"enabled":true}`, role: RoleUser, expectedStatus: http.StatusForbidden},
+		{name: "owner unprotects", call: func(h *Handler) func(echo.Context) error { return h.UpdateDeletionProtection }, body: `{"enabled":false}`, role: RoleUser, expectedStatus: http.StatusOK},
+		{name: "owner resizes within the tier", call: func(h *Handler) func(echo.Context) error { return h.ResizeInstanceStorage }, body: `{"size":"5Gi"}`, role: RoleUser, expectedStatus: http.StatusOK},
+		{name: "owner resizes beyond the tier", call: func(h *Handler) func(echo.Context) error { return h.ResizeInstanceStorage }, body: `{"size":"20Gi"}`, role: RoleUser, expectedStatus: http.StatusForbidden},
+		{name: "admin resizes beyond the tier", call: func(h *Handler) func(echo.Context) error { return h.ResizeInstanceStorage }, body: `{"size":"20Gi"}`, role: RoleAdmin, expectedStatus: http.StatusOK},
+		{name: "owner adds read replicas", call: func(h *Handler) func(echo.Context) error { return h.UpdateInstanceReadReplicas }, body: `{"read_replicas":1}`, role: RoleUser, expectedStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			instance := newSandboxInstance("my-app", "7")
+			instance.CreationTimestamp = metav1.NewTime(time.Now().Add(-time.Hour))
+			instance.Spec.TTL = &metav1.Duration{Duration: 7 * 24 * time.Hour}
+			cr := newSuspensionCRClient(nil, instance)
+			cr.updateSupabaseInstanceFunc = func(context.Context, *supacontrolv1alpha1.SupabaseInstance) error {
+				return nil
+			}
+			handler := NewHandler(nil, &mockDBClient{}, cr, nil, WithSandboxPolicy(testSandboxPolicy))
+			c, rec := newTestContext(http.MethodPut, "/api/v1/instances/my-app", tt.body)
+			c.SetParamNames("name")
+			c.SetParamValues("my-app")
+			setAuthContext(c, 7, "someone", tt.role)
+
+			err := tt.call(handler)(c)
+			if tt.expectedStatus != http.StatusOK {
+				assertHTTPError(t, err, tt.expectedStatus)
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if rec.Code != http.StatusOK {
+				t.Errorf("expected status %d, got %d", http.StatusOK, rec.Code)
+			}
+		})
+	}
+}
+
+// TestGetQuotas_Sandbox tests that sandboxed users get their sandbox limits and usage
+func TestGetQuotas_Sandbox(t *testing.T) {
+	mockCR := newQuotaCRClient(newSandboxInstance("my-app", "7"), newSandboxInstance("their-app", "8"))
+	mockDB := &mockDBClient{
+		getQuotaFunc: func(*int64) (*apitypes.Quota, error) {
+			return nil, nil
+		},
+	}
+	handler := NewHandler(nil, mockDB, mockCR, &mockK8sClient{clientset: fake.NewSimpleClientset()},
+		WithSandboxPolicy(SandboxPolicy{Role: RoleUser, MaxInstances: 2, MaxStorageGB: 5, TTL: 7 * 24 * time.Hour}))
+
+	for _, role := range []string{RoleUser, RoleOperator} {
+		c, rec := newTestContext(http.MethodGet, "/api/v1/quotas", "")
+		setAuthContext(c, 7, "tester", role)
+		if err := handler.GetQuotas(c); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		var resp apitypes.QuotasResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if role == RoleOperator {
+			if resp.Sandbox != nil {
+				t.Errorf("expected no sandbox for operators, got %+v", resp.Sandbox)
+			}
+			continue
+		}
+		expected := apitypes.SandboxQuota{MaxInstances: 2, MaxStorageGB: 5, TTL: "168h0m0s", Instances: 1}
+		if resp.Sandbox == nil || *resp.Sandbox != expected {
+			t.Errorf("expected sandbox %+v, got %+v", expected, resp.Sandbox)
+		}
+	}
+}
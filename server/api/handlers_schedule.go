@@ -0,0 +1,123 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+	"github.com/qubitquilt/supacontrol/server/internal/i18n"
+	"github.com/qubitquilt/supacontrol/server/internal/schedule"
+)
+
+// normalizeSchedule validates a requested schedule and converts it to its CR form. No
+// schedule leaves the instance running until it is stopped by hand, so nil is returned for it.
+func normalizeSchedule(req *apitypes.InstanceSchedule) (*supacontrolv1alpha1.Schedule, error) {
+	if req == nil {
+		return nil, nil
+	}
+	if _, err := schedule.Parse(req.Stop); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "schedule stop must be a five-field cron expression such as 0 19 * * 1-5")
+	}
+	if _, err := schedule.Parse(req.Start); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "schedule start must be a five-field cron expression such as 0 7 * * 1-5")
+	}
+	if req.TimeZone != "" {
+		if _, err := time.LoadLocation(req.TimeZone); err != nil {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, i18n.Msg("unknown time zone %s", req.TimeZone))
+		}
+	}
+	s := &supacontrolv1alpha1.Schedule{Stop: req.Stop, Start: req.Start, TimeZone: req.TimeZone}
+	if err := schedule.Validate(s); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "schedule must stop and start the instance at different times")
+	}
+	return s, nil
+}
+
+// scheduleToAPIType converts an instance's schedule to its API form, with its next stop
+// and start after now
+func scheduleToAPIType(s *supacontrolv1alpha1.Schedule, now time.Time) *apitypes.InstanceSchedule {
+	if s == nil {
+		return nil
+	}
+	resp := &apitypes.InstanceSchedule{Stop: s.Stop, Start: s.Start, TimeZone: s.TimeZone}
+	nextStop, nextStart := schedule.Upcoming(s, now)
+	if !nextStop.IsZero() {
+		resp.NextStop = &nextStop
+	}
+	if !nextStart.IsZero() {
+		resp.NextStart = &nextStart
+	}
+	return resp
+}
+
+// UpdateInstanceSchedule sets when an instance is stopped and started automatically
+// (admins and the instance owner only). The next scheduled change applies; stopping or
+// starting the instance by hand in between is kept until then.
+func (h *Handler) UpdateInstanceSchedule(c echo.Context) error {
+	var req apitypes.InstanceSchedule
+	if err := bindRequest(c, &req); err != nil {
+		return err
+	}
+	s, err := normalizeSchedule(&req)
+	if err != nil {
+		return err
+	}
+
+	name := c.Param("name")
+	instance, err := h.getInstanceOrError(c, name)
+	if err != nil {
+		return err
+	}
+	if !isAdminOrOwner(GetAuthContext(c), instance) {
+		return echo.NewHTTPError(http.StatusForbidden, "only admins and the instance owner can change the schedule")
+	}
+
+	instance, err = h.patchInstance(c, name, func(instance *supacontrolv1alpha1.SupabaseInstance) error {
+		instance.Spec.Schedule = s
+		return nil
+	}, "failed to update schedule")
+	if err != nil {
+		return err
+	}
+
+	h.recordAudit(c, "instance.schedule.update", "instance", name, map[string]string{
+		"stop":      s.Stop,
+		"start":     s.Start,
+		"time_zone": s.TimeZone,
+	})
+	return c.JSON(http.StatusOK, apitypes.GetInstanceResponse{
+		Instance: h.convertCRToAPIType(c, instance),
+	})
+}
+
+// DeleteInstanceSchedule removes an instance's schedule, leaving it stopped or running as
+// it is (admins and the instance owner only)
+func (h *Handler) DeleteInstanceSchedule(c echo.Context) error {
+	name := c.Param("name")
+	instance, err := h.getInstanceOrError(c, name)
+	if err != nil {
+		return err
+	}
+	if !isAdminOrOwner(GetAuthContext(c), instance) {
+		return echo.NewHTTPError(http.StatusForbidden, "only admins and the instance owner can change the schedule")
+	}
+
+	instance, err = h.patchInstance(c, name, func(instance *supacontrolv1alpha1.SupabaseInstance) error {
+		if instance.Spec.Schedule == nil {
+			return echo.NewHTTPError(http.StatusNotFound, "instance has no schedule")
+		}
+		instance.Spec.Schedule = nil
+		return nil
+	}, "failed to remove schedule")
+	if err != nil {
+		return err
+	}
+
+	h.recordAudit(c, "instance.schedule.delete", "instance", name, nil)
+	return c.JSON(http.StatusOK, apitypes.GetInstanceResponse{
+		Instance: h.convertCRToAPIType(c, instance),
+	})
+}
@@ -0,0 +1,119 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+)
+
+// TestUpdateInstanceSchedule tests validation and access checks of the UpdateInstanceSchedule handler
+func TestUpdateInstanceSchedule(t *testing.T) {
+	tests := []struct {
+		name           string
+		userID         int64
+		role           string
+		body           string
+		expectedStatus int
+	}{
+		{name: "owner sets schedule", userID: 7, role: "user", body: `{"stop":"0 19 * * 1-5","start":"0 7 * * 1-5","time_zone":"UTC"}`, expectedStatus: http.StatusOK},
+		{name: "admin sets schedule", userID: 1, role: "admin", body: `{"stop":"0 19 * * *","start":"0 7 * * *"}`, expectedStatus: http.StatusOK},
+		{name: "missing start", userID: 7, role: "user", body: `{"stop":"0 19 * * *"}`, expectedStatus: http.StatusBadRequest},
+		{name: "invalid stop", userID: 7, role: "user", body: `{"stop":"0 25 * * *","start":"0 7 * * *"}`, expectedStatus: http.StatusBadRequest},
+		{name: "invalid start", userID: 7, role: "user", body: `{"stop":"0 19 * * *","start":"@daily"}`, expectedStatus: http.StatusBadRequest},
+		{name: "unknown time zone", userID: 7, role: "user", body: `{"stop":"0 19 * * *","start":"0 7 * * *","time_zone":"Mars/Olympus"}`, expectedStatus: http.StatusBadRequest},
+		{name: "same times", userID: 7, role: "user", body: `{"stop":"0 7 * * *","start":"0 7 * * *"}`, expectedStatus: http.StatusBadRequest},
+		{name: "other user", userID: 8, role: "user", body: `{"stop":"0 19 * * *","start":"0 7 * * *"}`, expectedStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var updated *supacontrolv1alpha1.Schedule
+			cr := newSuspensionCRClient(nil, newOwnedInstance("my-app", "7"))
+			cr.updateSupabaseInstanceFunc = func(_ context.Context, instance *supacontrolv1alpha1.SupabaseInstance) error {
+				updated = instance.Spec.Schedule
+				return nil
+			}
+			handler := NewHandler(nil, &mockDBClient{}, cr, nil)
+			c, rec := newTestContext(http.MethodPut, "/api/v1/instances/my-app/schedule", tt.body)
+			c.SetParamNames("name")
+			c.SetParamValues("my-app")
+			setAuthContext(c, tt.userID, "someone", tt.role)
+
+			err := handler.UpdateInstanceSchedule(c)
+			if tt.expectedStatus != http.StatusOK {
+				assertHTTPError(t, err, tt.expectedStatus)
+				if updated != nil {
+					t.Errorf("expected no update, got %+v", updated)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if updated == nil || updated.Stop == "" || updated.Start == "" {
+				t.Fatalf("expected the schedule to be stored, got %+v", updated)
+			}
+			var resp apitypes.GetInstanceResponse
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			schedule := resp.Instance.Schedule
+			if schedule == nil || schedule.NextStop == nil || schedule.NextStart == nil {
+				t.Errorf("expected the next stop and start to be reported, got %+v", schedule)
+			}
+		})
+	}
+}
+
+// TestDeleteInstanceSchedule tests the DeleteInstanceSchedule handler
+func TestDeleteInstanceSchedule(t *testing.T) {
+	tests := []struct {
+		name           string
+		scheduled      bool
+		conflicts      int
+		expectedStatus int
+	}{
+		{name: "removed", scheduled: true, expectedStatus: http.StatusOK},
+		{name: "retried after a conflicting controller write", scheduled: true, conflicts: 2, expectedStatus: http.StatusOK},
+		{name: "no schedule", expectedStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			instance := newOwnedInstance("my-app", "7")
+			if tt.scheduled {
+				instance.Spec.Schedule = &supacontrolv1alpha1.Schedule{Stop: "0 19 * * *", Start: "0 7 * * *"}
+			}
+			updates := 0
+			cr := newSuspensionCRClient(nil, instance)
+			cr.updateSupabaseInstanceFunc = conflictFirst(tt.conflicts, func(_ context.Context, instance *supacontrolv1alpha1.SupabaseInstance) error {
+				updates++
+				if instance.Spec.Schedule != nil {
+					t.Errorf("expected the schedule to be removed, got %+v", instance.Spec.Schedule)
+				}
+				return nil
+			})
+			handler := NewHandler(nil, &mockDBClient{}, cr, nil)
+			c, _ := newTestContext(http.MethodDelete, "/api/v1/instances/my-app/schedule", "")
+			c.SetParamNames("name")
+			c.SetParamValues("my-app")
+			setAuthContext(c, 7, "someone", "user")
+
+			err := handler.DeleteInstanceSchedule(c)
+			if tt.expectedStatus != http.StatusOK {
+				assertHTTPError(t, err, tt.expectedStatus)
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if updates != 1 {
+				t.Errorf("expected 1 update, got %d", updates)
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+	"github.com/qubitquilt/supacontrol/server/internal/k8s"
+)
+
+// secretKinds maps the CR secret kinds to their API form
+var secretKinds = map[supacontrolv1alpha1.SecretKind]string{
+	supacontrolv1alpha1.SecretKindJWTSecret:        apitypes.SecretKindJWTSecret,
+	supacontrolv1alpha1.SecretKindAnonKey:          apitypes.SecretKindAnonKey,
+	supacontrolv1alpha1.SecretKindServiceRoleKey:   apitypes.SecretKindServiceRoleKey,
+	supacontrolv1alpha1.SecretKindPostgresPassword: apitypes.SecretKindPostgresPassword,
+	supacontrolv1alpha1.SecretKindTLSCertificate:   apitypes.SecretKindTLSCertificate,
+}
+
+// secretAgesToAPIType converts the secret inventory the controller keeps in an instance's
+// status to its API form, with ages in whole days as of now
+func secretAgesToAPIType(secrets []supacontrolv1alpha1.SecretAge, now time.Time) []apitypes.InstanceSecretAge {
+	ages := make([]apitypes.InstanceSecretAge, 0, len(secrets))
+	for _, secret := range secrets {
+		age := apitypes.InstanceSecretAge{
+			Name:       secret.Name,
+			Kind:       secretKinds[secret.Kind],
+			SecretName: secret.SecretName,
+			RotatedAt:  secret.RotatedAt.Time,
+			AgeDays:    int(now.Sub(secret.RotatedAt.Time) / (24 * time.Hour)),
+			Overdue:    secret.Overdue,
+		}
+		if secret.ExpiresAt != nil {
+			expiresAt := secret.ExpiresAt.Time
+			age.ExpiresAt = &expiresAt
+		}
+		ages = append(ages, age)
+	}
+	return ages
+}
+
+// GetInstanceSecurityReport reports the security advisories affecting an instance's
+// running components, and when each of its keys, passwords and TLS certificates was last
+// rotated. Advisory matching is best-effort, like on the instance itself.
+func (h *Handler) GetInstanceSecurityReport(c echo.Context) error {
+	name := c.Param("name")
+	ctx := c.Request().Context()
+
+	instance, err := h.crClient.GetSupabaseInstance(ctx, name)
+	if err != nil {
+		if errors.Is(err, k8s.ErrInstanceNotFound) {
+			return newAPIError(http.StatusNotFound, apitypes.ErrorCodeInstanceNotFound, "instance not found")
+		}
+		GetLogger(c).Error("Failed to get instance", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to get instance")
+	}
+
+	report := apitypes.InstanceSecurityReport{
+		InstanceName: instance.Spec.ProjectName,
+		Advisories:   []apitypes.SecurityAdvisory{},
+		Secrets:      secretAgesToAPIType(instance.Status.Secrets, time.Now()),
+	}
+	for _, secret := range report.Secrets {
+		if secret.Overdue {
+			report.SecretsOverdue = true
+		}
+	}
+
+	if feed := h.loadAdvisories(c); len(feed) > 0 {
+		namespace := getInstanceNamespace(instance)
+		pods, err := h.k8sClient.GetClientset().CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			GetLogger(c).Warn("Failed to list pods for advisory matching", "namespace", namespace, "error", err)
+		} else {
+			images := runningImages(pods.Items)
+			for _, component := range k8s.Components() {
+				report.Advisories = append(report.Advisories, matchAdvisories(feed, component, images[component])...)
+			}
+		}
+	}
+
+	return c.JSON(http.StatusOK, report)
+}
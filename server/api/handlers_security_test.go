@@ -0,0 +1,98 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+	"github.com/qubitquilt/supacontrol/server/internal/advisories"
+	"github.com/qubitquilt/supacontrol/server/internal/k8s"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// TestGetInstanceSecurityReport tests that the report combines matched advisories with the
+// secret inventory the controller keeps in the instance status
+func TestGetInstanceSecurityReport(t *testing.T) {
+	rotatedAt := time.Now().Add(-100 * 24 * time.Hour).Truncate(time.Second)
+	expiresAt := metav1.NewTime(time.Now().Add(60 * 24 * time.Hour).Truncate(time.Second))
+	mockCR := &mockCRClient{
+		getSupabaseInstanceFunc: func(_ context.Context, name string) (*supacontrolv1alpha1.SupabaseInstance, error) {
+			instance := newOwnedInstance(name, "1")
+			instance.Status.Secrets = []supacontrolv1alpha1.SecretAge{
+				{
+					Name:       "jwt-secret",
+					Kind:       supacontrolv1alpha1.SecretKindJWTSecret,
+					SecretName: name + "-secrets",
+					RotatedAt:  metav1.NewTime(rotatedAt),
+					Overdue:    true,
+				},
+				{
+					Name:       name + "-api-ingress-tls",
+					Kind:       supacontrolv1alpha1.SecretKindTLSCertificate,
+					SecretName: name + "-api-ingress-tls",
+					RotatedAt:  metav1.NewTime(time.Now().Add(-30 * 24 * time.Hour)),
+					ExpiresAt:  &expiresAt,
+				},
+			}
+			return instance, nil
+		},
+	}
+	clientset := fake.NewSimpleClientset(newPostgresPod("old-app", "15.1.0.147"))
+	source := &mockAdvisorySource{
+		advisoriesFunc: func(context.Context) ([]advisories.Advisory, error) {
+			return testAdvisories, nil
+		},
+	}
+	handler := NewHandler(nil, &mockDBClient{}, mockCR, &mockK8sClient{clientset: clientset}, WithAdvisorySource(source))
+
+	c, rec := newTestContext(http.MethodGet, "/api/v1/instances/old-app/security", "")
+	c.SetParamNames("name")
+	c.SetParamValues("old-app")
+	setAuthContext(c, 1, "tester", "user")
+
+	if err := handler.GetInstanceSecurityReport(c); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var resp apitypes.InstanceSecurityReport
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if len(resp.Advisories) != 1 || resp.Advisories[0].ID != "SA-2024-01" {
+		t.Errorf("unexpected advisories: %+v", resp.Advisories)
+	}
+	if !resp.SecretsOverdue || len(resp.Secrets) != 2 {
+		t.Fatalf("expected 2 secrets with one overdue, got %+v", resp.Secrets)
+	}
+	jwt := resp.Secrets[0]
+	if jwt.Kind != apitypes.SecretKindJWTSecret || jwt.AgeDays != 100 || !jwt.Overdue || !jwt.RotatedAt.Equal(rotatedAt) {
+		t.Errorf("unexpected JWT secret: %+v", jwt)
+	}
+	certificate := resp.Secrets[1]
+	if certificate.Kind != apitypes.SecretKindTLSCertificate || certificate.Overdue ||
+		certificate.ExpiresAt == nil || !certificate.ExpiresAt.Equal(expiresAt.Time) {
+		t.Errorf("unexpected certificate: %+v", certificate)
+	}
+}
+
+// TestGetInstanceSecurityReport_NotFound tests that unknown instances return 404
+func TestGetInstanceSecurityReport_NotFound(t *testing.T) {
+	mockCR := &mockCRClient{
+		getSupabaseInstanceFunc: func(context.Context, string) (*supacontrolv1alpha1.SupabaseInstance, error) {
+			return nil, k8s.ErrInstanceNotFound
+		},
+	}
+	handler := NewHandler(nil, &mockDBClient{}, mockCR, &mockK8sClient{clientset: fake.NewSimpleClientset()})
+
+	c, _ := newTestContext(http.MethodGet, "/api/v1/instances/missing/security", "")
+	c.SetParamNames("name")
+	c.SetParamValues("missing")
+
+	assertHTTPError(t, handler.GetInstanceSecurityReport(c), http.StatusNotFound)
+}
@@ -0,0 +1,184 @@
+package api
+
+import (
+	"net/http"
+	"net/mail"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+	"github.com/qubitquilt/supacontrol/server/controllers"
+)
+
+// smtpPasswordKey is the key of the SMTP password in the Secret the API stores it in
+const smtpPasswordKey = "password"
+
+// normalizeSMTP validates requested SMTP settings and converts them to their CR form,
+// without the password reference
+func normalizeSMTP(req *apitypes.UpdateSMTPRequest) (*supacontrolv1alpha1.SMTPSettings, error) {
+	if req.Host == "" || len(validation.IsDNS1123Subdomain(req.Host)) > 0 {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "SMTP host must be a valid hostname")
+	}
+	if req.Port < 1 || req.Port > 65535 {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "SMTP port must be between 1 and 65535")
+	}
+	if req.SenderEmail != "" {
+		if _, err := mail.ParseAddress(req.SenderEmail); err != nil {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, "SMTP sender email must be a valid email address")
+		}
+	}
+	return &supacontrolv1alpha1.SMTPSettings{
+		Host:        req.Host,
+		Port:        req.Port,
+		User:        req.User,
+		SenderEmail: req.SenderEmail,
+		SenderName:  req.SenderName,
+	}, nil
+}
+
+// smtpToAPIType converts an instance's SMTP settings to their API form
+func smtpToAPIType(auth *supacontrolv1alpha1.AuthSettings) *apitypes.InstanceSMTP {
+	if auth == nil || auth.SMTP == nil {
+		return nil
+	}
+	smtp := auth.SMTP
+	return &apitypes.InstanceSMTP{
+		Host:        smtp.Host,
+		Port:        smtp.Port,
+		User:        smtp.User,
+		SenderEmail: smtp.SenderEmail,
+		SenderName:  smtp.SenderName,
+		PasswordSet: smtp.SecretRef != nil,
+	}
+}
+
+// storeSMTPPassword writes an instance's SMTP password to its Secret in the controller
+// namespace. The Secret is owned by the instance, so changing it triggers a reconcile and
+// deleting the instance removes it.
+func (h *Handler) storeSMTPPassword(c echo.Context, instance *supacontrolv1alpha1.SupabaseInstance, password string) (*supacontrolv1alpha1.SecretKeyReference, error) {
+	ctx := c.Request().Context()
+	secrets := h.k8sClient.GetClientset().CoreV1().Secrets(controllers.ControllerNamespace)
+	name := controllers.SMTPPasswordSecretName(instance)
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: controllers.ControllerNamespace,
+			Labels: map[string]string{
+				controllers.JobInstanceLabel:  instance.Spec.ProjectName,
+				"app.kubernetes.io/name":      "supacontrol",
+				"app.kubernetes.io/component": "smtp",
+			},
+			OwnerReferences: []metav1.OwnerReference{
+				*metav1.NewControllerRef(instance, supacontrolv1alpha1.GroupVersion.WithKind("SupabaseInstance")),
+			},
+		},
+		Type: corev1.SecretTypeOpaque,
+		Data: map[string][]byte{smtpPasswordKey: []byte(password)},
+	}
+	_, err := secrets.Create(ctx, secret, metav1.CreateOptions{})
+	if apierrors.IsAlreadyExists(err) {
+		_, err = secrets.Update(ctx, secret, metav1.UpdateOptions{})
+	}
+	if err != nil {
+		GetLogger(c).Error("Failed to store SMTP password", "instance", instance.Name, "error", err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "failed to store SMTP password")
+	}
+	return &supacontrolv1alpha1.SecretKeyReference{Name: name, Key: smtpPasswordKey}, nil
+}
+
+// UpdateInstanceSMTP sets the mail server of an instance's auth service (admins and the
+// instance owner only). The controller applies it to the running auth service with a
+// rolling restart.
+func (h *Handler) UpdateInstanceSMTP(c echo.Context) error {
+	var req apitypes.UpdateSMTPRequest
+	if err := bindRequest(c, &req); err != nil {
+		return err
+	}
+	smtp, err := normalizeSMTP(&req)
+	if err != nil {
+		return err
+	}
+
+	name := c.Param("name")
+	instance, err := h.getInstanceOrError(c, name)
+	if err != nil {
+		return err
+	}
+	if !isAdminOrOwner(GetAuthContext(c), instance) {
+		return echo.NewHTTPError(http.StatusForbidden, "only admins and the instance owner can change SMTP settings")
+	}
+
+	if req.Password != "" {
+		if smtp.SecretRef, err = h.storeSMTPPassword(c, instance, req.Password); err != nil {
+			return err
+		}
+	}
+
+	instance, err = h.patchInstance(c, name, func(instance *supacontrolv1alpha1.SupabaseInstance) error {
+		settings := smtp.DeepCopy()
+		// Without a new password the stored one is kept
+		if req.Password == "" && instance.Spec.Auth != nil && instance.Spec.Auth.SMTP != nil {
+			settings.SecretRef = instance.Spec.Auth.SMTP.SecretRef
+		}
+		if instance.Spec.Auth == nil {
+			instance.Spec.Auth = &supacontrolv1alpha1.AuthSettings{}
+		}
+		instance.Spec.Auth.SMTP = settings
+		return nil
+	}, "failed to update SMTP settings")
+	if err != nil {
+		return err
+	}
+
+	h.recordAudit(c, "instance.smtp.update", "instance", name, map[string]string{
+		"host":             smtp.Host,
+		"port":             strconv.Itoa(int(smtp.Port)),
+		"password_changed": strconv.FormatBool(req.Password != ""),
+	})
+	return c.JSON(http.StatusOK, apitypes.GetInstanceResponse{
+		Instance: h.convertCRToAPIType(c, instance),
+	})
+}
+
+// DeleteInstanceSMTP removes the mail server settings of an instance's auth service, which
+// falls back to its chart values (admins and the instance owner only)
+func (h *Handler) DeleteInstanceSMTP(c echo.Context) error {
+	name := c.Param("name")
+	instance, err := h.getInstanceOrError(c, name)
+	if err != nil {
+		return err
+	}
+	if !isAdminOrOwner(GetAuthContext(c), instance) {
+		return echo.NewHTTPError(http.StatusForbidden, "only admins and the instance owner can change SMTP settings")
+	}
+
+	instance, err = h.patchInstance(c, name, func(instance *supacontrolv1alpha1.SupabaseInstance) error {
+		if instance.Spec.Auth == nil || instance.Spec.Auth.SMTP == nil {
+			return echo.NewHTTPError(http.StatusNotFound, "instance has no SMTP settings")
+		}
+		instance.Spec.Auth.SMTP = nil
+		return nil
+	}, "failed to remove SMTP settings")
+	if err != nil {
+		return err
+	}
+
+	err = h.k8sClient.GetClientset().CoreV1().Secrets(controllers.ControllerNamespace).Delete(
+		c.Request().Context(), controllers.SMTPPasswordSecretName(instance), metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		// The Secret is owned by the instance, so it is still removed with it
+		GetLogger(c).Warn("Failed to delete SMTP password", "instance", name, "error", err)
+	}
+
+	h.recordAudit(c, "instance.smtp.delete", "instance", name, nil)
+	return c.JSON(http.StatusOK, apitypes.GetInstanceResponse{
+		Instance: h.convertCRToAPIType(c, instance),
+	})
+}
@@ -0,0 +1,141 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+	"github.com/qubitquilt/supacontrol/server/controllers"
+)
+
+// TestUpdateInstanceSMTP tests the UpdateInstanceSMTP handler
+func TestUpdateInstanceSMTP(t *testing.T) {
+	existing := &supacontrolv1alpha1.SMTPSettings{
+		Host:      "old.example.com",
+		Port:      25,
+		SecretRef: &supacontrolv1alpha1.SecretKeyReference{Name: "supacontrol-smtp-my-app", Key: "password"},
+	}
+
+	tests := []struct {
+		name           string
+		userID         int64
+		role           string
+		existing       *supacontrolv1alpha1.SMTPSettings
+		body           string
+		conflicts      int
+		expectedStatus int
+		expectSecret   bool
+		expectRef      bool
+	}{
+		{name: "owner sets SMTP with password", userID: 7, role: "user", body: `{"host":"smtp.example.com","port":587,"user":"mailer","password":"s3cret","sender_email":"noreply@example.com"}`, expectedStatus: http.StatusOK, expectSecret: true, expectRef: true},
+		{name: "admin sets SMTP without password", userID: 1, role: "admin", body: `{"host":"smtp.example.com","port":587}`, expectedStatus: http.StatusOK},
+		{name: "update keeps stored password", userID: 7, role: "user", existing: existing, body: `{"host":"smtp.example.com","port":587}`, expectedStatus: http.StatusOK, expectRef: true},
+		{name: "retried after a conflicting controller write", userID: 7, role: "user", existing: existing, body: `{"host":"smtp.example.com","port":587}`, conflicts: 2, expectedStatus: http.StatusOK, expectRef: true},
+		{name: "invalid host", userID: 7, role: "user", body: `{"host":"smtp example","port":587}`, expectedStatus: http.StatusBadRequest},
+		{name: "invalid port", userID: 7, role: "user", body: `{"host":"smtp.example.com","port":70000}`, expectedStatus: http.StatusBadRequest},
+		{name: "invalid sender email", userID: 7, role: "user", body: `{"host":"smtp.example.com","port":587,"sender_email":"nobody"}`, expectedStatus: http.StatusBadRequest},
+		{name: "other user", userID: 8, role: "user", body: `{"host":"smtp.example.com","port":587}`, expectedStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			instance := newOwnedInstance("my-app", "7")
+			instance.Spec.ProjectName = "my-app"
+			if tt.existing != nil {
+				instance.Spec.Auth = &supacontrolv1alpha1.AuthSettings{SMTP: tt.existing.DeepCopy()}
+			}
+			var updated *supacontrolv1alpha1.SMTPSettings
+			cr := newSuspensionCRClient(nil, instance)
+			cr.updateSupabaseInstanceFunc = conflictFirst(tt.conflicts, func(_ context.Context, instance *supacontrolv1alpha1.SupabaseInstance) error {
+				updated = instance.Spec.Auth.SMTP
+				return nil
+			})
+			clientset := fake.NewSimpleClientset()
+			handler := NewHandler(nil, &mockDBClient{}, cr, &mockK8sClient{clientset: clientset})
+			c, _ := newTestContext(http.MethodPut, "/api/v1/instances/my-app/smtp", tt.body)
+			c.SetParamNames("name")
+			c.SetParamValues("my-app")
+			setAuthContext(c, tt.userID, "someone", tt.role)
+
+			err := handler.UpdateInstanceSMTP(c)
+			if tt.expectedStatus != http.StatusOK {
+				assertHTTPError(t, err, tt.expectedStatus)
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if updated == nil || updated.Host != "smtp.example.com" || updated.Port != 587 {
+				t.Fatalf("expected the SMTP settings to be updated, got %+v", updated)
+			}
+			if tt.expectRef != (updated.SecretRef != nil) {
+				t.Errorf("expected password reference %v, got %+v", tt.expectRef, updated.SecretRef)
+			}
+
+			secret, err := clientset.CoreV1().Secrets(controllers.ControllerNamespace).Get(context.Background(), "supacontrol-smtp-my-app", metav1.GetOptions{})
+			if tt.expectSecret != (err == nil) {
+				t.Fatalf("expected password Secret %v, got error %v", tt.expectSecret, err)
+			}
+			if secret != nil && tt.expectSecret {
+				if string(secret.Data["password"]) != "s3cret" {
+					t.Errorf("expected the password to be stored, got %q", secret.Data["password"])
+				}
+				if len(secret.OwnerReferences) != 1 || secret.OwnerReferences[0].Kind != "SupabaseInstance" {
+					t.Errorf("expected the Secret to be owned by the instance, got %+v", secret.OwnerReferences)
+				}
+			}
+		})
+	}
+}
+
+// TestDeleteInstanceSMTP tests the DeleteInstanceSMTP handler
+func TestDeleteInstanceSMTP(t *testing.T) {
+	tests := []struct {
+		name           string
+		userID         int64
+		role           string
+		hasSMTP        bool
+		expectedStatus int
+	}{
+		{name: "owner removes SMTP", userID: 7, role: "user", hasSMTP: true, expectedStatus: http.StatusOK},
+		{name: "no SMTP settings", userID: 7, role: "user", expectedStatus: http.StatusNotFound},
+		{name: "other user", userID: 8, role: "user", hasSMTP: true, expectedStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			instance := newOwnedInstance("my-app", "7")
+			instance.Spec.ProjectName = "my-app"
+			if tt.hasSMTP {
+				instance.Spec.Auth = &supacontrolv1alpha1.AuthSettings{SMTP: &supacontrolv1alpha1.SMTPSettings{Host: "smtp.example.com", Port: 587}}
+			}
+			updated := false
+			cr := newSuspensionCRClient(nil, instance)
+			cr.updateSupabaseInstanceFunc = func(_ context.Context, instance *supacontrolv1alpha1.SupabaseInstance) error {
+				updated = instance.Spec.Auth.SMTP == nil
+				return nil
+			}
+			handler := NewHandler(nil, &mockDBClient{}, cr, &mockK8sClient{clientset: fake.NewSimpleClientset()})
+			c, _ := newTestContext(http.MethodDelete, "/api/v1/instances/my-app/smtp", "")
+			c.SetParamNames("name")
+			c.SetParamValues("my-app")
+			setAuthContext(c, tt.userID, "someone", tt.role)
+
+			err := handler.DeleteInstanceSMTP(c)
+			if tt.expectedStatus != http.StatusOK {
+				assertHTTPError(t, err, tt.expectedStatus)
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !updated {
+				t.Error("expected the SMTP settings to be removed")
+			}
+		})
+	}
+}
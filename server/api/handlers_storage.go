@@ -0,0 +1,98 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+	"github.com/qubitquilt/supacontrol/server/internal/i18n"
+)
+
+// parseStorageSize parses a requested volume size, which must be a positive quantity
+func parseStorageSize(size string) (*resource.Quantity, error) {
+	quantity, err := resource.ParseQuantity(size)
+	if err != nil || quantity.Sign() <= 0 {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "storage size must be a positive quantity such as '20Gi'")
+	}
+	return &quantity, nil
+}
+
+// normalizeStorage validates requested storage settings and converts them to their CR
+// form. Nothing requested keeps the chart defaults, so nil is returned for it.
+func normalizeStorage(req *apitypes.InstanceStorage) (*supacontrolv1alpha1.Storage, error) {
+	if req == nil || (req.Size == "" && req.StorageClass == "") {
+		return nil, nil
+	}
+	storage := &supacontrolv1alpha1.Storage{ClassName: req.StorageClass}
+	if req.StorageClass != "" && len(validation.IsDNS1123Subdomain(req.StorageClass)) > 0 {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "storage class must be a valid Kubernetes resource name")
+	}
+	if req.Size != "" {
+		size, err := parseStorageSize(req.Size)
+		if err != nil {
+			return nil, err
+		}
+		storage.Size = size
+	}
+	return storage, nil
+}
+
+// storageToAPIType converts an instance's storage settings to their API form
+func storageToAPIType(storage *supacontrolv1alpha1.Storage) *apitypes.InstanceStorage {
+	if storage == nil {
+		return nil
+	}
+	result := &apitypes.InstanceStorage{StorageClass: storage.ClassName}
+	if storage.Size != nil {
+		result.Size = storage.Size.String()
+	}
+	return result
+}
+
+// ResizeInstanceStorage grows an instance's Postgres volume (admins and the instance owner
+// only). The controller expands the volume claim online once the instance is running.
+func (h *Handler) ResizeInstanceStorage(c echo.Context) error {
+	var req apitypes.ResizeStorageRequest
+	if err := bindRequest(c, &req); err != nil {
+		return err
+	}
+	size, err := parseStorageSize(req.Size)
+	if err != nil {
+		return err
+	}
+
+	name := c.Param("name")
+	instance, err := h.getInstanceOrError(c, name)
+	if err != nil {
+		return err
+	}
+	if !isAdminOrOwner(GetAuthContext(c), instance) {
+		return echo.NewHTTPError(http.StatusForbidden, "only admins and the instance owner can resize storage")
+	}
+	if isSandboxInstance(instance) && !GetAuthContext(c).IsAdmin() && size.Cmp(*h.sandboxStorage()) > 0 {
+		return echo.NewHTTPError(http.StatusForbidden, i18n.Msg("sandbox instances can use at most %d GB of storage", h.sandbox.MaxStorageGB))
+	}
+
+	instance, err = h.patchInstance(c, name, func(instance *supacontrolv1alpha1.SupabaseInstance) error {
+		if instance.Spec.Storage == nil {
+			instance.Spec.Storage = &supacontrolv1alpha1.Storage{}
+		}
+		if current := instance.Spec.Storage.Size; current != nil && size.Cmp(*current) <= 0 {
+			return echo.NewHTTPError(http.StatusBadRequest, "storage can only be increased")
+		}
+		instance.Spec.Storage.Size = size
+		return nil
+	}, "failed to resize storage")
+	if err != nil {
+		return err
+	}
+
+	h.recordAudit(c, "instance.storage.resize", "instance", name, map[string]string{"size": size.String()})
+	return c.JSON(http.StatusOK, apitypes.GetInstanceResponse{
+		Instance: h.convertCRToAPIType(c, instance),
+	})
+}
@@ -0,0 +1,105 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+)
+
+// TestNormalizeStorage tests validation of requested storage settings
+func TestNormalizeStorage(t *testing.T) {
+	tests := []struct {
+		name      string
+		req       *apitypes.InstanceStorage
+		expectNil bool
+		expectErr bool
+	}{
+		{name: "nothing requested", req: &apitypes.InstanceStorage{}, expectNil: true},
+		{name: "size and class", req: &apitypes.InstanceStorage{Size: "20Gi", StorageClass: "fast-ssd"}},
+		{name: "class only", req: &apitypes.InstanceStorage{StorageClass: "fast-ssd"}},
+		{name: "invalid size", req: &apitypes.InstanceStorage{Size: "twenty"}, expectErr: true},
+		{name: "zero size", req: &apitypes.InstanceStorage{Size: "0"}, expectErr: true},
+		{name: "invalid class", req: &apitypes.InstanceStorage{StorageClass: "Fast_SSD"}, expectErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage, err := normalizeStorage(tt.req)
+			if tt.expectErr {
+				assertHTTPError(t, err, http.StatusBadRequest)
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.expectNil != (storage == nil) {
+				t.Fatalf("expected nil %v, got %+v", tt.expectNil, storage)
+			}
+			if storage != nil && storageToAPIType(storage).Size != tt.req.Size {
+				t.Errorf("expected size %q to round-trip, got %+v", tt.req.Size, storageToAPIType(storage))
+			}
+		})
+	}
+}
+
+// TestResizeInstanceStorage tests the ResizeInstanceStorage handler
+func TestResizeInstanceStorage(t *testing.T) {
+	tests := []struct {
+		name           string
+		userID         int64
+		role           string
+		currentSize    string
+		body           string
+		conflicts      int
+		expectedStatus int
+	}{
+		{name: "owner grows storage", userID: 7, role: "user", currentSize: "8Gi", body: `{"size":"20Gi"}`, expectedStatus: http.StatusOK},
+		{name: "retried after a conflicting controller write", userID: 7, role: "user", currentSize: "8Gi", body: `{"size":"20Gi"}`, conflicts: 2, expectedStatus: http.StatusOK},
+		{name: "admin sets first size", userID: 1, role: "admin", body: `{"size":"20Gi"}`, expectedStatus: http.StatusOK},
+		{name: "shrinking", userID: 7, role: "user", currentSize: "20Gi", body: `{"size":"10Gi"}`, expectedStatus: http.StatusBadRequest},
+		{name: "same size", userID: 7, role: "user", currentSize: "20Gi", body: `{"size":"20Gi"}`, expectedStatus: http.StatusBadRequest},
+		{name: "invalid size", userID: 7, role: "user", body: `{"size":"big"}`, expectedStatus: http.StatusBadRequest},
+		{name: "other user", userID: 8, role: "user", body: `{"size":"20Gi"}`, expectedStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			instance := newOwnedInstance("my-app", "7")
+			if tt.currentSize != "" {
+				size := resource.MustParse(tt.currentSize)
+				instance.Spec.Storage = &supacontrolv1alpha1.Storage{Size: &size, ClassName: "fast-ssd"}
+			}
+			var updated *supacontrolv1alpha1.Storage
+			cr := newSuspensionCRClient(nil, instance)
+			cr.updateSupabaseInstanceFunc = conflictFirst(tt.conflicts, func(_ context.Context, instance *supacontrolv1alpha1.SupabaseInstance) error {
+				updated = instance.Spec.Storage
+				return nil
+			})
+			handler := NewHandler(nil, &mockDBClient{}, cr, nil)
+			c, _ := newTestContext(http.MethodPut, "/api/v1/instances/my-app/storage", tt.body)
+			c.SetParamNames("name")
+			c.SetParamValues("my-app")
+			setAuthContext(c, tt.userID, "someone", tt.role)
+
+			err := handler.ResizeInstanceStorage(c)
+			if tt.expectedStatus != http.StatusOK {
+				assertHTTPError(t, err, tt.expectedStatus)
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if updated == nil || updated.Size.String() != "20Gi" {
+				t.Fatalf("expected the size to be updated to 20Gi, got %+v", updated)
+			}
+			if tt.currentSize != "" && updated.ClassName != "fast-ssd" {
+				t.Errorf("expected the storage class to be kept, got %q", updated.ClassName)
+			}
+		})
+	}
+}
@@ -0,0 +1,216 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+	"github.com/qubitquilt/supacontrol/server/controllers"
+	"github.com/qubitquilt/supacontrol/server/internal/auth"
+	"github.com/qubitquilt/supacontrol/server/internal/db"
+)
+
+// studioSessionCookie holds the Studio session a redeemed Studio link opens
+const studioSessionCookie = "supacontrol_studio"
+
+// WithStudioTransport sets the transport that carries proxied Studio requests to instance
+// gateways
+func WithStudioTransport(transport http.RoundTripper) HandlerOption {
+	return func(h *Handler) {
+		h.studioTransport = transport
+	}
+}
+
+// CreateStudioSession signs a one-time link that opens a running instance's Studio after
+// checking the user's access and recording it (admins and the instance owner only). The
+// link expires after a minute.
+func (h *Handler) CreateStudioSession(c echo.Context) error {
+	name := c.Param("name")
+	instance, err := h.getInstanceOrError(c, name)
+	if err != nil {
+		return err
+	}
+	authCtx := GetAuthContext(c)
+	if !isAdminOrOwner(authCtx, instance) {
+		return echo.NewHTTPError(http.StatusForbidden, "only admins and the instance owner can open Studio")
+	}
+	if instance.Status.Phase != supacontrolv1alpha1.PhaseRunning || instance.Status.StudioURL == "" {
+		return echo.NewHTTPError(http.StatusConflict, "Studio is only available while the instance is running")
+	}
+
+	expiresAt := time.Now().Add(apitypes.StudioSessionTTL).UTC().Truncate(time.Second)
+	token, err := h.authService.GenerateStudioSessionToken(authCtx.UserID, name, expiresAt)
+	if err != nil {
+		GetLogger(c).Error("Failed to sign Studio session token", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to create Studio link")
+	}
+
+	h.recordAudit(c, "instance.studio_session.create", "instance", name, map[string]string{
+		"expires_at": expiresAt.Format(time.RFC3339),
+	})
+	return c.JSON(http.StatusCreated, apitypes.CreateStudioSessionResponse{
+		URL:       h.publicURL + "/api/v1/studio-sessions/" + token,
+		ExpiresAt: expiresAt,
+	})
+}
+
+// OpenStudioSession redeems a Studio link and signs the browser in to the instance's
+// Studio, which SupaControl serves through ProxyStudio. The session is a cookie scoped to
+// the instance's proxy path and bound to the redeemed link. Browsers open the link
+// without API credentials, so the signed token authenticates the request and the user's
+// access is checked again.
+func (h *Handler) OpenStudioSession(c echo.Context) error {
+	claims, err := h.authService.ValidateStudioSessionToken(c.Param("token"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusForbidden, "invalid or expired Studio link")
+	}
+	// Redemptions are stored in the database so a link opens once across all replicas
+	redeemed, err := h.dbClient.RedeemStudioSession(claims.ID, claims.ExpiresAt.Time)
+	if err != nil {
+		GetLogger(c).Error("Failed to redeem Studio link", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to open Studio link")
+	}
+	if !redeemed {
+		return echo.NewHTTPError(http.StatusGone, "this Studio link has already been used")
+	}
+
+	user, instance, err := h.studioAccess(c, claims)
+	if err != nil {
+		return err
+	}
+
+	// Record the access before signing in; refuse to open Studio unaudited
+	if err := h.dbClient.CreateAuditLog(user.ID, "instance.studio_session.open", "instance", instance.Name, map[string]string{
+		"username": user.Username,
+	}); err != nil {
+		GetLogger(c).Error("Failed to record Studio session", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to record audit log")
+	}
+
+	expiresAt := time.Now().Add(apitypes.StudioProxySessionTTL)
+	session, err := h.authService.GenerateStudioProxyToken(claims, expiresAt)
+	if err != nil {
+		GetLogger(c).Error("Failed to sign Studio proxy token", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to open Studio link")
+	}
+	c.SetCookie(&http.Cookie{
+		Name:     studioSessionCookie,
+		Value:    session,
+		Path:     studioProxyPath(instance.Name),
+		Expires:  expiresAt,
+		HttpOnly: true,
+		Secure:   c.Scheme() == "https",
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	c.Response().Header().Set("Cache-Control", "no-store")
+	c.Response().Header().Set("Referrer-Policy", "no-referrer")
+	return c.Redirect(http.StatusFound, h.publicURL+studioProxyPath(instance.Name))
+}
+
+// ProxyStudio serves an instance's Studio to a browser signed in by OpenStudioSession.
+// Requests go through the instance's gateway with its dashboard credentials, which never
+// reach the browser, and the user's access is checked on every request.
+func (h *Handler) ProxyStudio(c echo.Context) error {
+	cookie, err := c.Cookie(studioSessionCookie)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "open Studio with a Studio link")
+	}
+	claims, err := h.authService.ValidateStudioProxyToken(cookie.Value)
+	if err != nil || claims.Instance != c.Param("name") {
+		return echo.NewHTTPError(http.StatusUnauthorized, "open Studio with a Studio link")
+	}
+
+	_, instance, err := h.studioAccess(c, claims)
+	if err != nil {
+		return err
+	}
+	// The credentials of vcluster instances live inside the vcluster
+	if instance.Status.IsolationLevel == supacontrolv1alpha1.IsolationVCluster {
+		return echo.NewHTTPError(http.StatusConflict, "Studio cannot be proxied for vcluster instances")
+	}
+
+	namespace := getInstanceNamespace(instance)
+	secret, err := h.k8sClient.GetClientset().CoreV1().Secrets(namespace).Get(c.Request().Context(), getInstanceSecretName(instance), metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return echo.NewHTTPError(http.StatusConflict, "instance credentials not available yet")
+		}
+		GetLogger(c).Error("Failed to get instance secret", "instance", instance.Name, "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to get instance credentials")
+	}
+	username, password := secret.Data[controllers.DashboardUsernameKey], secret.Data[controllers.DashboardPasswordKey]
+	if len(username) == 0 || len(password) == 0 {
+		return echo.NewHTTPError(http.StatusConflict, "instance has no Studio credentials")
+	}
+
+	gateway := &url.URL{
+		Scheme: "http",
+		Host:   fmt.Sprintf("%s-kong.%s.svc.cluster.local:%d", getInstanceReleaseName(instance), namespace, controllers.KongPort),
+	}
+	proxy := &httputil.ReverseProxy{
+		Rewrite: func(r *httputil.ProxyRequest) {
+			r.SetURL(gateway)
+			r.Out.URL.Path = "/" + c.Param("*")
+			r.Out.URL.RawPath = ""
+			r.SetXForwarded()
+			r.Out.Header.Set("X-Forwarded-Prefix", strings.TrimSuffix(studioProxyPath(instance.Name), "/"))
+			r.Out.SetBasicAuth(string(username), string(password))
+			withoutCookie(r.Out, studioSessionCookie)
+		},
+		Transport: h.studioTransport,
+		ErrorHandler: func(w http.ResponseWriter, _ *http.Request, err error) {
+			GetLogger(c).Error("Failed to proxy Studio", "instance", instance.Name, "error", err)
+			w.WriteHeader(http.StatusBadGateway)
+		},
+	}
+	proxy.ServeHTTP(c.Response(), c.Request())
+	return nil
+}
+
+// studioAccess returns the user a Studio token was issued to and the instance it opens,
+// after checking that the user still has access: ownership or the user's role may have
+// changed since the token was signed
+func (h *Handler) studioAccess(c echo.Context, claims *auth.StudioSessionClaims) (*db.User, *supacontrolv1alpha1.SupabaseInstance, error) {
+	user, err := h.dbClient.GetUserByID(claims.UserID)
+	if err != nil || user == nil || user.MustChangePassword {
+		return nil, nil, echo.NewHTTPError(http.StatusForbidden, "invalid or expired Studio link")
+	}
+	authCtx := &AuthContext{UserID: user.ID, Username: user.Username, Role: user.Role}
+	instance, err := h.getInstanceOrError(c, claims.Instance)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !isAdminOrOwner(authCtx, instance) {
+		return nil, nil, echo.NewHTTPError(http.StatusForbidden, "only admins and the instance owner can open Studio")
+	}
+	if instance.Status.Phase != supacontrolv1alpha1.PhaseRunning || instance.Status.StudioURL == "" {
+		return nil, nil, echo.NewHTTPError(http.StatusConflict, "Studio is only available while the instance is running")
+	}
+	return user, instance, nil
+}
+
+// studioProxyPath returns the path under which ProxyStudio serves an instance's Studio
+func studioProxyPath(name string) string {
+	return "/api/v1/studio/" + name + "/"
+}
+
+// withoutCookie removes a cookie from a request, keeping the others
+func withoutCookie(r *http.Request, name string) {
+	cookies := r.Cookies()
+	r.Header.Del("Cookie")
+	for _, cookie := range cookies {
+		if cookie.Name != name {
+			r.AddCookie(cookie)
+		}
+	}
+}
@@ -0,0 +1,258 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/client-go/kubernetes/fake"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+	"github.com/qubitquilt/supacontrol/server/internal/auth"
+	"github.com/qubitquilt/supacontrol/server/internal/db"
+)
+
+// newStudioTestHandler returns a handler serving a running instance owned by user 7
+func newStudioTestHandler(users map[int64]*db.User, audits *[]string, opts ...HandlerOption) *Handler {
+	instance := newOwnedInstance("my-app", "7")
+	instance.Status.Phase = supacontrolv1alpha1.PhaseRunning
+	instance.Status.StudioURL = "https://my-app-studio.example.com/"
+	cr := &mockCRClient{
+		getSupabaseInstanceFunc: func(context.Context, string) (*supacontrolv1alpha1.SupabaseInstance, error) {
+			return instance, nil
+		},
+	}
+	redeemed := map[string]bool{}
+	mockDB := &mockDBClient{
+		redeemStudioSessionFunc: func(jti string, _ time.Time) (bool, error) {
+			if redeemed[jti] {
+				return false, nil
+			}
+			redeemed[jti] = true
+			return true, nil
+		},
+		getUserByIDFunc: func(id int64) (*db.User, error) {
+			return users[id], nil
+		},
+		createAuditLogFunc: func(_ int64, action, _, _ string, _ map[string]string) error {
+			*audits = append(*audits, action)
+			return nil
+		},
+	}
+	secret := newInstanceSecret("my-app")
+	secret.Data["dashboard-username"] = []byte("supacontrol")
+	secret.Data["dashboard-password"] = []byte("dashboard-secret")
+	return NewHandler(auth.NewService("test-secret-key"), mockDB, cr, &mockK8sClient{clientset: fake.NewSimpleClientset(secret)},
+		append([]HandlerOption{WithPublicURL("https://supacontrol.example.com")}, opts...)...)
+}
+
+// openStudioLink opens a Studio link the way a browser would, without credentials
+func openStudioLink(handler *Handler, link string) (*http.Response, error) {
+	token := link[strings.LastIndex(link, "/")+1:]
+	c, rec := newTestContext(http.MethodGet, "/api/v1/studio-sessions/"+token, "")
+	c.SetParamNames("token")
+	c.SetParamValues(token)
+	err := handler.OpenStudioSession(c)
+	return rec.Result(), err
+}
+
+// TestStudioSession tests that Studio links open a Studio session once without exposing
+// any instance credentials
+func TestStudioSession(t *testing.T) {
+	var audits []string
+	users := map[int64]*db.User{7: {ID: 7, Username: "owner", Role: RoleUser}}
+	handler := newStudioTestHandler(users, &audits)
+
+	c, rec := newTestContext(http.MethodPost, "/api/v1/instances/my-app/studio-session", "")
+	c.SetParamNames("name")
+	c.SetParamValues("my-app")
+	setAuthContext(c, 7, "owner", RoleUser)
+	if err := handler.CreateStudioSession(c); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var resp apitypes.CreateStudioSessionResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if !strings.HasPrefix(resp.URL, "https://supacontrol.example.com/api/v1/studio-sessions/") {
+		t.Errorf("unexpected link %q", resp.URL)
+	}
+	if until := time.Until(resp.ExpiresAt); until <= 0 || until > apitypes.StudioSessionTTL {
+		t.Errorf("expected the link to expire within %v, got %v", apitypes.StudioSessionTTL, resp.ExpiresAt)
+	}
+
+	result, err := openStudioLink(handler, resp.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.StatusCode != http.StatusFound {
+		t.Fatalf("expected a redirect, got %d", result.StatusCode)
+	}
+	location, err := url.Parse(result.Header.Get("Location"))
+	if err != nil {
+		t.Fatalf("invalid redirect: %v", err)
+	}
+	if location.String() != "https://supacontrol.example.com/api/v1/studio/my-app/" {
+		t.Errorf("expected a redirect to the Studio proxy without credentials, got %s", location)
+	}
+	cookies := result.Cookies()
+	if len(cookies) != 1 || cookies[0].Name != studioSessionCookie || cookies[0].Path != "/api/v1/studio/my-app/" || !cookies[0].HttpOnly {
+		t.Fatalf("expected an HTTP-only session cookie scoped to the proxy, got %+v", cookies)
+	}
+	if _, err := handler.authService.ValidateStudioProxyToken(cookies[0].Value); err != nil {
+		t.Errorf("expected a valid Studio session: %v", err)
+	}
+	if result.Header.Get("Referrer-Policy") != "no-referrer" {
+		t.Error("expected the redirect not to leak its URL as a referrer")
+	}
+	if strings.Join(audits, ",") != "instance.studio_session.create,instance.studio_session.open" {
+		t.Errorf("unexpected audit entries %v", audits)
+	}
+
+	_, err = openStudioLink(handler, resp.URL)
+	assertHTTPError(t, err, http.StatusGone)
+}
+
+// TestStudioSession_Denied tests that Studio links are refused to users without access
+func TestStudioSession_Denied(t *testing.T) {
+	t.Run("other user cannot create a link", func(t *testing.T) {
+		var audits []string
+		handler := newStudioTestHandler(nil, &audits)
+		c, _ := newTestContext(http.MethodPost, "/api/v1/instances/my-app/studio-session", "")
+		c.SetParamNames("name")
+		c.SetParamValues("my-app")
+		setAuthContext(c, 8, "other", RoleUser)
+		assertHTTPError(t, handler.CreateStudioSession(c), http.StatusForbidden)
+	})
+
+	t.Run("invalid token", func(t *testing.T) {
+		var audits []string
+		handler := newStudioTestHandler(nil, &audits)
+		_, err := openStudioLink(handler, "https://supacontrol.example.com/api/v1/studio-sessions/not-a-token")
+		assertHTTPError(t, err, http.StatusForbidden)
+	})
+
+	t.Run("owner changed since the link was created", func(t *testing.T) {
+		var audits []string
+		users := map[int64]*db.User{8: {ID: 8, Username: "former-owner", Role: RoleUser}}
+		handler := newStudioTestHandler(users, &audits)
+		token, err := handler.authService.GenerateStudioSessionToken(8, "my-app", time.Now().Add(time.Minute))
+		if err != nil {
+			t.Fatalf("failed to sign token: %v", err)
+		}
+		_, err = openStudioLink(handler, token)
+		assertHTTPError(t, err, http.StatusForbidden)
+		if len(audits) != 0 {
+			t.Errorf("expected no access to be recorded, got %v", audits)
+		}
+	})
+}
+
+// roundTripperFunc adapts a function to http.RoundTripper
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+// proxyStudio sends a browser request for path below the instance's Studio proxy
+func proxyStudio(handler *Handler, name, path string, cookies ...*http.Cookie) (*httptest.ResponseRecorder, error) {
+	c, rec := newTestContext(http.MethodGet, "/api/v1/studio/"+name+"/"+path, "")
+	for _, cookie := range cookies {
+		c.Request().AddCookie(cookie)
+	}
+	c.SetParamNames("name", "*")
+	c.SetParamValues(name, path)
+	return rec, handler.ProxyStudio(c)
+}
+
+// TestProxyStudio tests that Studio is served through the instance's gateway with its
+// dashboard credentials to browsers holding a session
+func TestProxyStudio(t *testing.T) {
+	var upstream *http.Request
+	gateway := roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		upstream = r
+		rec := httptest.NewRecorder()
+		rec.WriteString("studio")
+		return rec.Result(), nil
+	})
+	var audits []string
+	users := map[int64]*db.User{7: {ID: 7, Username: "owner", Role: RoleUser}}
+	handler := newStudioTestHandler(users, &audits, WithStudioTransport(gateway))
+
+	link, err := handler.authService.GenerateStudioSessionToken(7, "my-app", time.Now().Add(time.Minute))
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+	result, err := openStudioLink(handler, link)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	session := result.Cookies()[0]
+
+	rec, err := proxyStudio(handler, "my-app", "project/default", session, &http.Cookie{Name: "studio-pref", Value: "dark"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rec.Code != http.StatusOK || rec.Body.String() != "studio" {
+		t.Fatalf("expected Studio's response, got %d %q", rec.Code, rec.Body.String())
+	}
+	if upstream.URL.Host != "my-app-kong.supa-my-app.svc.cluster.local:8000" || upstream.URL.Path != "/project/default" {
+		t.Errorf("expected the request to reach the instance gateway, got %s", upstream.URL)
+	}
+	if username, password, ok := upstream.BasicAuth(); !ok || username != "supacontrol" || password != "dashboard-secret" {
+		t.Errorf("expected the dashboard credentials, got %q %q", username, password)
+	}
+	if _, err := upstream.Cookie(studioSessionCookie); err == nil {
+		t.Error("expected the Studio session not to be forwarded")
+	}
+	if _, err := upstream.Cookie("studio-pref"); err != nil {
+		t.Error("expected Studio's own cookies to be forwarded")
+	}
+
+	tests := []struct {
+		name           string
+		instance       string
+		cookie         *http.Cookie
+		userID         int64
+		expectedStatus int
+	}{
+		{name: "no session", instance: "my-app", expectedStatus: http.StatusUnauthorized},
+		{name: "link instead of a session", instance: "my-app", cookie: &http.Cookie{Name: studioSessionCookie, Value: link}, expectedStatus: http.StatusUnauthorized},
+		{name: "session for another instance", instance: "other-app", cookie: session, expectedStatus: http.StatusUnauthorized},
+		{name: "user lost access", instance: "my-app", userID: 8, expectedStatus: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			upstream = nil
+			cookie := tt.cookie
+			if tt.userID != 0 {
+				claims, err := handler.authService.ValidateStudioSessionToken(link)
+				if err != nil {
+					t.Fatalf("failed to parse token: %v", err)
+				}
+				claims.UserID = tt.userID
+				value, err := handler.authService.GenerateStudioProxyToken(claims, time.Now().Add(time.Hour))
+				if err != nil {
+					t.Fatalf("failed to sign token: %v", err)
+				}
+				cookie = &http.Cookie{Name: studioSessionCookie, Value: value}
+			}
+			var cookies []*http.Cookie
+			if cookie != nil {
+				cookies = append(cookies, cookie)
+			}
+			_, err := proxyStudio(handler, tt.instance, "", cookies...)
+			assertHTTPError(t, err, tt.expectedStatus)
+			if upstream != nil {
+				t.Error("expected nothing to be proxied")
+			}
+		})
+	}
+}
@@ -0,0 +1,281 @@
+package api
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"html/template"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+	"github.com/qubitquilt/supacontrol/server/internal/i18n"
+	"github.com/qubitquilt/supacontrol/server/internal/k8s"
+)
+
+const (
+	// billingSignatureHeader carries the HMAC-SHA256 signature of a billing webhook body
+	billingSignatureHeader = "X-SupaControl-Signature"
+
+	// maxBillingWebhookBody bounds the billing webhook request body
+	maxBillingWebhookBody = 64 << 10
+
+	// maxSuspensionMessage matches the CRD limit on suspension messages
+	maxSuspensionMessage = 500
+)
+
+// errBillingEventNoop stops patching an instance a billing event does not change
+var errBillingEventNoop = errors.New("billing event does not change the instance")
+
+// suspensionReasons maps the API suspension reasons to their CR form
+var suspensionReasons = map[string]supacontrolv1alpha1.SuspensionReason{
+	apitypes.SuspensionReasonBilling:        supacontrolv1alpha1.SuspensionReasonBilling,
+	apitypes.SuspensionReasonQuota:          supacontrolv1alpha1.SuspensionReasonQuota,
+	apitypes.SuspensionReasonAdministrative: supacontrolv1alpha1.SuspensionReasonAdministrative,
+}
+
+// suspendedPage is shown by the ingresses of suspended instances
+var suspendedPage = template.Must(template.New("suspended").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>{{.Title}}</title></head>
+<body style="font-family: sans-serif; text-align: center; margin-top: 15%">
+<h1>{{.Title}}</h1>
+<p>{{.Body}}</p>
+</body>
+</html>
+`))
+
+// WithBillingWebhookSecret enables the billing webhook, whose calls must be signed with secret
+func WithBillingWebhookSecret(secret string) HandlerOption {
+	return func(h *Handler) {
+		h.billingWebhookSecret = secret
+	}
+}
+
+// newSuspension validates a suspension reason and message and builds the CR suspension.
+// An empty reason defaults to fallback.
+func newSuspension(reason, message, fallback string) (*supacontrolv1alpha1.Suspension, error) {
+	if reason == "" {
+		reason = fallback
+	}
+	crReason, ok := suspensionReasons[reason]
+	if !ok {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "suspension reason must be 'billing', 'quota' or 'administrative'")
+	}
+	if len(message) > maxSuspensionMessage {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, i18n.Msg("suspension message must be at most %d characters", maxSuspensionMessage))
+	}
+	return &supacontrolv1alpha1.Suspension{Reason: crReason, Message: message, SuspendedAt: metav1.Now()}, nil
+}
+
+// suspensionToAPIType converts an instance's suspension to its API form
+func suspensionToAPIType(suspension *supacontrolv1alpha1.Suspension) *apitypes.InstanceSuspension {
+	if suspension == nil {
+		return nil
+	}
+	return &apitypes.InstanceSuspension{
+		Reason:      strings.ToLower(string(suspension.Reason)),
+		Message:     suspension.Message,
+		SuspendedAt: suspension.SuspendedAt.Time,
+	}
+}
+
+// getInstanceOrError gets an instance, translating lookup failures into HTTP errors
+func (h *Handler) getInstanceOrError(c echo.Context, name string) (*supacontrolv1alpha1.SupabaseInstance, error) {
+	instance, err := h.crClient.GetSupabaseInstance(c.Request().Context(), name)
+	if err != nil {
+		if errors.Is(err, k8s.ErrInstanceNotFound) {
+			return nil, newAPIError(http.StatusNotFound, apitypes.ErrorCodeInstanceNotFound, "instance not found")
+		}
+		GetLogger(c).Error("Failed to get instance", "error", err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "failed to get instance")
+	}
+	return instance, nil
+}
+
+// SuspendInstance suspends an instance: the controller scales it to zero and marks its
+// ingresses suspended, and its owner cannot start it again (operators and admins)
+func (h *Handler) SuspendInstance(c echo.Context) error {
+
+	var req apitypes.SuspendInstanceRequest
+	if err := bindRequest(c, &req); err != nil {
+		return err
+	}
+	suspension, err := newSuspension(req.Reason, req.Message, apitypes.SuspensionReasonAdministrative)
+	if err != nil {
+		return err
+	}
+
+	name := c.Param("name")
+	_, err = h.patchInstance(c, name, func(instance *supacontrolv1alpha1.SupabaseInstance) error {
+		instance.Spec.Suspension = suspension
+		return nil
+	}, "failed to suspend instance")
+	if err != nil {
+		return err
+	}
+
+	h.recordAudit(c, "instance.suspend", "instance", name, map[string]string{"reason": string(suspension.Reason)})
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": localize(c, "Instance suspension initiated"),
+		"status":  "Suspending",
+	})
+}
+
+// UnsuspendInstance lifts an instance's suspension. It returns to its state before the
+// suspension, so an instance its owner had stopped stays stopped (operators and admins).
+func (h *Handler) UnsuspendInstance(c echo.Context) error {
+
+	name := c.Param("name")
+	_, err := h.patchInstance(c, name, func(instance *supacontrolv1alpha1.SupabaseInstance) error {
+		if instance.Spec.Suspension == nil {
+			return echo.NewHTTPError(http.StatusConflict, "instance is not suspended")
+		}
+		instance.Spec.Suspension = nil
+		return nil
+	}, "failed to lift instance suspension")
+	if err != nil {
+		return err
+	}
+
+	h.recordAudit(c, "instance.unsuspend", "instance", name, nil)
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": localize(c, "Instance suspension lifted"),
+		"status":  "Resuming",
+	})
+}
+
+// verifyBillingSignature checks a "sha256=<hex>" HMAC-SHA256 signature of body
+func verifyBillingSignature(secret string, body []byte, signature string) bool {
+	given, err := hex.DecodeString(strings.TrimPrefix(signature, "sha256="))
+	if err != nil || !strings.HasPrefix(signature, "sha256=") {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(given, mac.Sum(nil))
+}
+
+// billingTargets returns the instances a billing event applies to: the named instance,
+// or every instance owned by the given user
+func (h *Handler) billingTargets(ctx context.Context, event *apitypes.BillingWebhookEvent) ([]*supacontrolv1alpha1.SupabaseInstance, error) {
+	if event.Instance != "" {
+		instance, err := h.crClient.GetSupabaseInstance(ctx, event.Instance)
+		if err != nil {
+			return nil, err
+		}
+		return []*supacontrolv1alpha1.SupabaseInstance{instance}, nil
+	}
+
+	list, err := h.crClient.ListSupabaseInstances(ctx)
+	if err != nil {
+		return nil, err
+	}
+	owner := strconv.FormatInt(*event.UserID, 10)
+	var targets []*supacontrolv1alpha1.SupabaseInstance
+	for i := range list.Items {
+		if list.Items[i].Annotations[supacontrolv1alpha1.AnnotationOwnerID] == owner {
+			targets = append(targets, &list.Items[i])
+		}
+	}
+	return targets, nil
+}
+
+// BillingWebhook suspends or resumes instances on behalf of a billing system. Calls are
+// authenticated by an HMAC-SHA256 signature of the body. Instances that are already
+// suspended keep their suspension, and resuming only lifts suspensions with the event's
+// reason, so the billing system cannot undo an administrator's suspension.
+func (h *Handler) BillingWebhook(c echo.Context) error {
+	if h.billingWebhookSecret == "" {
+		return echo.NewHTTPError(http.StatusNotFound, "billing webhook is not enabled")
+	}
+
+	body, err := io.ReadAll(io.LimitReader(c.Request().Body, maxBillingWebhookBody))
+	if err != nil {
+		return newAPIError(http.StatusBadRequest, apitypes.ErrorCodeInvalidBody, "invalid request body")
+	}
+	if !verifyBillingSignature(h.billingWebhookSecret, body, c.Request().Header.Get(billingSignatureHeader)) {
+		return echo.NewHTTPError(http.StatusUnauthorized, "invalid webhook signature")
+	}
+
+	var event apitypes.BillingWebhookEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		return newAPIError(http.StatusBadRequest, apitypes.ErrorCodeInvalidBody, "invalid request body")
+	}
+	if event.Event != apitypes.BillingEventSuspend && event.Event != apitypes.BillingEventResume {
+		return echo.NewHTTPError(http.StatusBadRequest, "event must be 'suspend' or 'resume'")
+	}
+	if event.Instance == "" && event.UserID == nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "instance or user_id is required")
+	}
+	suspension, err := newSuspension(event.Reason, event.Message, apitypes.SuspensionReasonBilling)
+	if err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	targets, err := h.billingTargets(ctx, &event)
+	if err != nil {
+		if errors.Is(err, k8s.ErrInstanceNotFound) {
+			return newAPIError(http.StatusNotFound, apitypes.ErrorCodeInstanceNotFound, "instance not found")
+		}
+		GetLogger(c).Error("Failed to get billing webhook instances", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to list instances")
+	}
+
+	// Each instance is patched on its latest version, so a suspension set or lifted since
+	// the instances were listed is respected and a conflicting controller write is retried
+	changed := []string{}
+	for _, target := range targets {
+		_, err := h.crClient.PatchSupabaseInstance(ctx, target.Name, func(instance *supacontrolv1alpha1.SupabaseInstance) error {
+			current := instance.Spec.Suspension
+			switch {
+			case event.Event == apitypes.BillingEventSuspend && current == nil:
+				instance.Spec.Suspension = suspension.DeepCopy()
+			case event.Event == apitypes.BillingEventResume && current != nil && current.Reason == suspension.Reason:
+				instance.Spec.Suspension = nil
+			default:
+				return errBillingEventNoop
+			}
+			return nil
+		})
+		switch {
+		case errors.Is(err, errBillingEventNoop), errors.Is(err, k8s.ErrInstanceNotFound):
+			// Deleted instances need no billing changes any more
+			continue
+		case err != nil:
+			GetLogger(c).Error("Failed to apply billing event", "instance", target.Name, "event", event.Event, "error", err, "applied", changed)
+			return echo.NewHTTPError(http.StatusInternalServerError, "failed to apply billing event")
+		}
+		changed = append(changed, target.Name)
+	}
+
+	GetLogger(c).Info("Applied billing event", "event", event.Event, "reason", suspension.Reason, "instances", changed)
+	return c.JSON(http.StatusOK, apitypes.BillingWebhookResponse{Instances: changed})
+}
+
+// SuspendedPage is the page the ingresses of suspended instances redirect to
+func (h *Handler) SuspendedPage(c echo.Context) error {
+	body := localize(c, "This instance is suspended. Contact your administrator to restore access.")
+	if name := c.QueryParam("instance"); name != "" {
+		body = localize(c, "The instance %s is suspended. Contact your administrator to restore access.", name)
+	}
+
+	var page strings.Builder
+	if err := suspendedPage.Execute(&page, map[string]string{
+		"Title": localize(c, "Instance suspended"),
+		"Body":  body,
+	}); err != nil {
+		return err
+	}
+	return c.HTML(http.StatusServiceUnavailable, page.String())
+}
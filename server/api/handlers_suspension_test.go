@@ -0,0 +1,278 @@
+package api
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+	"github.com/qubitquilt/supacontrol/server/internal/k8s"
+)
+
+// newSuspensionCRClient returns a CR client serving copies of the given instances, storing
+// updates to them and recording the suspensions they set in updated (which may be nil)
+func newSuspensionCRClient(updated map[string]*supacontrolv1alpha1.Suspension, instances ...*supacontrolv1alpha1.SupabaseInstance) *mockCRClient {
+	cr := newQuotaCRClient(instances...)
+	cr.getSupabaseInstanceFunc = func(_ context.Context, name string) (*supacontrolv1alpha1.SupabaseInstance, error) {
+		for _, instance := range instances {
+			if instance.Name == name {
+				return instance.DeepCopy(), nil
+			}
+		}
+		return nil, k8s.ErrInstanceNotFound
+	}
+	cr.updateSupabaseInstanceFunc = func(_ context.Context, instance *supacontrolv1alpha1.SupabaseInstance) error {
+		if updated != nil {
+			updated[instance.Name] = instance.Spec.Suspension
+		}
+		return storeInstance(instances, instance)
+	}
+	return cr
+}
+
+// storeInstance writes an updated instance back to the instances a mock client serves,
+// so that retried patches start from the latest version like with the real client
+func storeInstance(instances []*supacontrolv1alpha1.SupabaseInstance, updated *supacontrolv1alpha1.SupabaseInstance) error {
+	for _, instance := range instances {
+		if instance.Name == updated.Name {
+			updated.DeepCopyInto(instance)
+			return nil
+		}
+	}
+	return k8s.ErrInstanceNotFound
+}
+
+// conflictFirst makes the first n calls of update fail with a conflict, like writes
+// racing the controller's
+func conflictFirst(n int, update func(context.Context, *supacontrolv1alpha1.SupabaseInstance) error) func(context.Context, *supacontrolv1alpha1.SupabaseInstance) error {
+	return func(ctx context.Context, instance *supacontrolv1alpha1.SupabaseInstance) error {
+		if n > 0 {
+			n--
+			return apierrors.NewConflict(schema.GroupResource{}, instance.Name, fmt.Errorf("object was modified"))
+		}
+		return update(ctx, instance)
+	}
+}
+
+// signBilling signs a billing webhook body like a billing system would
+func signBilling(secret, body string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(body))
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// TestSuspendInstance tests the SuspendInstance handler
+func TestSuspendInstance(t *testing.T) {
+	tests := []struct {
+		name           string
+		role           string
+		body           string
+		expectedStatus int
+		expectedReason supacontrolv1alpha1.SuspensionReason
+	}{
+		{name: "admin defaults to administrative", role: "admin", body: `{}`, expectedStatus: http.StatusOK, expectedReason: supacontrolv1alpha1.SuspensionReasonAdministrative},
+		{name: "admin with quota reason", role: "admin", body: `{"reason":"quota","message":"storage exceeded"}`, expectedStatus: http.StatusOK, expectedReason: supacontrolv1alpha1.SuspensionReasonQuota},
+		{name: "unknown reason", role: "admin", body: `{"reason":"holiday"}`, expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			updated := map[string]*supacontrolv1alpha1.Suspension{}
+			handler := NewHandler(nil, &mockDBClient{}, newSuspensionCRClient(updated, newOwnedInstance("my-app", "7")), nil)
+			c, rec := newTestContext(http.MethodPost, "/api/v1/instances/my-app/suspend", tt.body)
+			c.SetParamNames("name")
+			c.SetParamValues("my-app")
+			setAuthContext(c, 1, "admin", tt.role)
+
+			err := handler.SuspendInstance(c)
+			if tt.expectedStatus != http.StatusOK {
+				assertHTTPError(t, err, tt.expectedStatus)
+				if len(updated) != 0 {
+					t.Error("expected the instance not to be updated")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if rec.Code != http.StatusOK {
+				t.Errorf("expected status 200, got %d", rec.Code)
+			}
+			suspension := updated["my-app"]
+			if suspension == nil || suspension.Reason != tt.expectedReason {
+				t.Fatalf("expected suspension with reason %s, got %+v", tt.expectedReason, suspension)
+			}
+			if suspension.SuspendedAt.IsZero() {
+				t.Error("expected the suspension time to be set")
+			}
+		})
+	}
+}
+
+// TestUnsuspendInstance tests the UnsuspendInstance handler
+func TestUnsuspendInstance(t *testing.T) {
+	suspended := newOwnedInstance("suspended-app", "7")
+	suspended.Spec.Suspension = &supacontrolv1alpha1.Suspension{Reason: supacontrolv1alpha1.SuspensionReasonBilling}
+	active := newOwnedInstance("active-app", "7")
+
+	t.Run("lifts suspension", func(t *testing.T) {
+		updated := map[string]*supacontrolv1alpha1.Suspension{}
+		handler := NewHandler(nil, &mockDBClient{}, newSuspensionCRClient(updated, suspended.DeepCopy()), nil)
+		c, _ := newTestContext(http.MethodPost, "/api/v1/instances/suspended-app/unsuspend", "")
+		c.SetParamNames("name")
+		c.SetParamValues("suspended-app")
+		setAuthContext(c, 1, "admin", "admin")
+
+		if err := handler.UnsuspendInstance(c); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if suspension, ok := updated["suspended-app"]; !ok || suspension != nil {
+			t.Errorf("expected the suspension to be cleared, got %+v", suspension)
+		}
+	})
+
+	t.Run("not suspended", func(t *testing.T) {
+		handler := NewHandler(nil, &mockDBClient{}, newSuspensionCRClient(map[string]*supacontrolv1alpha1.Suspension{}, active), nil)
+		c, _ := newTestContext(http.MethodPost, "/api/v1/instances/active-app/unsuspend", "")
+		c.SetParamNames("name")
+		c.SetParamValues("active-app")
+		setAuthContext(c, 1, "admin", "admin")
+
+		assertHTTPError(t, handler.UnsuspendInstance(c), http.StatusConflict)
+	})
+}
+
+// TestBillingWebhook tests the BillingWebhook handler
+func TestBillingWebhook(t *testing.T) {
+	const secret = "billing-secret"
+
+	tests := []struct {
+		name            string
+		secret          string
+		body            string
+		signature       string
+		conflicts       int
+		expectedStatus  int
+		expectedChanged []string
+	}{
+		{
+			name:           "webhook disabled",
+			body:           `{"event":"suspend","user_id":7}`,
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "invalid signature",
+			secret:         secret,
+			body:           `{"event":"suspend","user_id":7}`,
+			signature:      signBilling("wrong", `{"event":"suspend","user_id":7}`),
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "unknown event",
+			secret:         secret,
+			body:           `{"event":"refund","user_id":7}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "no target",
+			secret:         secret,
+			body:           `{"event":"suspend"}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:            "suspend user instances",
+			secret:          secret,
+			body:            `{"event":"suspend","user_id":7}`,
+			expectedStatus:  http.StatusOK,
+			expectedChanged: []string{"active-app"},
+		},
+		{
+			name:            "retried after conflicting controller writes",
+			secret:          secret,
+			body:            `{"event":"suspend","user_id":7}`,
+			conflicts:       2,
+			expectedStatus:  http.StatusOK,
+			expectedChanged: []string{"active-app"},
+		},
+		{
+			name:            "resume lifts billing suspensions only",
+			secret:          secret,
+			body:            `{"event":"resume","user_id":7}`,
+			expectedStatus:  http.StatusOK,
+			expectedChanged: []string{"billing-app"},
+		},
+		{
+			name:            "suspend single instance",
+			secret:          secret,
+			body:            `{"event":"suspend","instance":"other-app","reason":"quota"}`,
+			expectedStatus:  http.StatusOK,
+			expectedChanged: []string{"other-app"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			active := newOwnedInstance("active-app", "7")
+			billing := newOwnedInstance("billing-app", "7")
+			billing.Spec.Suspension = &supacontrolv1alpha1.Suspension{Reason: supacontrolv1alpha1.SuspensionReasonBilling}
+			admin := newOwnedInstance("admin-app", "7")
+			admin.Spec.Suspension = &supacontrolv1alpha1.Suspension{Reason: supacontrolv1alpha1.SuspensionReasonAdministrative}
+			other := newOwnedInstance("other-app", "8")
+
+			updated := map[string]*supacontrolv1alpha1.Suspension{}
+			cr := newSuspensionCRClient(updated, active, billing, admin, other)
+			cr.updateSupabaseInstanceFunc = conflictFirst(tt.conflicts, cr.updateSupabaseInstanceFunc)
+			handler := NewHandler(nil, nil, cr, nil, WithBillingWebhookSecret(tt.secret))
+			c, rec := newTestContext(http.MethodPost, "/api/v1/webhooks/billing", tt.body)
+			signature := tt.signature
+			if signature == "" {
+				signature = signBilling(secret, tt.body)
+			}
+			c.Request().Header.Set(billingSignatureHeader, signature)
+
+			err := handler.BillingWebhook(c)
+			if tt.expectedStatus != http.StatusOK {
+				assertHTTPError(t, err, tt.expectedStatus)
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(updated) != len(tt.expectedChanged) {
+				t.Fatalf("expected %v to change, got %v", tt.expectedChanged, updated)
+			}
+			for _, name := range tt.expectedChanged {
+				if _, ok := updated[name]; !ok {
+					t.Errorf("expected %s to change", name)
+				}
+				if !strings.Contains(rec.Body.String(), name) {
+					t.Errorf("expected %s in response, got %s", name, rec.Body.String())
+				}
+			}
+		})
+	}
+}
+
+// TestSuspendedPage tests the page suspended instances redirect to
+func TestSuspendedPage(t *testing.T) {
+	handler := NewHandler(nil, nil, nil, nil)
+	c, rec := newTestContext(http.MethodGet, "/suspended?instance=%3Cb%3Emy-app", "")
+
+	if err := handler.SuspendedPage(c); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected status 503, got %d", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), "&lt;b&gt;my-app") {
+		t.Errorf("expected the escaped instance name in the page, got %s", rec.Body.String())
+	}
+}
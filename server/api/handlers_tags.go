@@ -0,0 +1,141 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+	"github.com/qubitquilt/supacontrol/server/internal/i18n"
+	"github.com/qubitquilt/supacontrol/server/internal/validate"
+)
+
+// validateTag records an error unless key and value can be stored as an instance label
+func validateTag(v *validate.Validator, field, key, value string) {
+	if len(key) > apitypes.MaxTagKeyLength || len(validation.IsQualifiedName(supacontrolv1alpha1.LabelTagPrefix+key)) > 0 {
+		v.Add(field, "keys must be at most %d letters, digits, '-', '_' or '.', ending with a letter or digit", apitypes.MaxTagKeyLength)
+		return
+	}
+	if len(validation.IsValidLabelValue(value)) > 0 {
+		v.Add(field, "values must be at most 63 letters, digits, '-', '_' or '.', starting and ending with a letter or digit")
+	}
+}
+
+// validateTags checks the tags of a create request
+func validateTags(v *validate.Validator, tags map[string]string) {
+	if len(tags) > apitypes.MaxInstanceTags {
+		v.Add("tags", "must have at most %d entries", apitypes.MaxInstanceTags)
+	}
+	for _, key := range sortedTagKeys(tags) {
+		validateTag(v, fmt.Sprintf("tags[%s]", key), key, tags[key])
+	}
+}
+
+// validateTagChanges checks the tags of an update request, where a nil value removes a tag
+func validateTagChanges(v *validate.Validator, changes map[string]*string) {
+	keys := make([]string, 0, len(changes))
+	for key := range changes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		value := ""
+		if changes[key] != nil {
+			value = *changes[key]
+		}
+		validateTag(v, fmt.Sprintf("tags[%s]", key), key, value)
+	}
+}
+
+// sortedTagKeys returns the keys of tags in order, so errors are reported deterministically
+func sortedTagKeys(tags map[string]string) []string {
+	keys := make([]string, 0, len(tags))
+	for key := range tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// instanceTags returns the tags stored in an instance's labels
+func instanceTags(instance *supacontrolv1alpha1.SupabaseInstance) map[string]string {
+	var tags map[string]string
+	for key, value := range instance.Labels {
+		if tag, ok := strings.CutPrefix(key, supacontrolv1alpha1.LabelTagPrefix); ok {
+			if tags == nil {
+				tags = map[string]string{}
+			}
+			tags[tag] = value
+		}
+	}
+	return tags
+}
+
+// setInstanceTags stores tags in an instance's labels
+func setInstanceTags(instance *supacontrolv1alpha1.SupabaseInstance, tags map[string]string) {
+	if len(tags) == 0 {
+		return
+	}
+	if instance.Labels == nil {
+		instance.Labels = map[string]string{}
+	}
+	for key, value := range tags {
+		instance.Labels[supacontrolv1alpha1.LabelTagPrefix+key] = value
+	}
+}
+
+// applyTagChanges merges tag changes into an instance's labels, removing the tags whose
+// value is nil. It fails when the instance would end up with too many tags.
+func applyTagChanges(instance *supacontrolv1alpha1.SupabaseInstance, changes map[string]*string) error {
+	for key, value := range changes {
+		if value == nil {
+			delete(instance.Labels, supacontrolv1alpha1.LabelTagPrefix+key)
+			continue
+		}
+		if instance.Labels == nil {
+			instance.Labels = map[string]string{}
+		}
+		instance.Labels[supacontrolv1alpha1.LabelTagPrefix+key] = *value
+	}
+	if len(instanceTags(instance)) > apitypes.MaxInstanceTags {
+		return echo.NewHTTPError(http.StatusBadRequest, i18n.Msg("instances can have at most %d tags", apitypes.MaxInstanceTags))
+	}
+	return nil
+}
+
+// tagFilter matches instances with a tag; an empty value matches any value
+type tagFilter struct {
+	key   string
+	value string
+}
+
+// parseTagFilters reads the tag query parameters of a request, each key:value or just
+// key to match any value
+func parseTagFilters(c echo.Context) ([]tagFilter, error) {
+	params := c.QueryParams()["tag"]
+	filters := make([]tagFilter, 0, len(params))
+	for _, param := range params {
+		key, value, _ := strings.Cut(param, ":")
+		if key == "" {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, "tag must be key:value or key")
+		}
+		filters = append(filters, tagFilter{key: key, value: value})
+	}
+	return filters, nil
+}
+
+// matchesTags reports whether an instance has every tag of filters
+func matchesTags(instance *supacontrolv1alpha1.SupabaseInstance, filters []tagFilter) bool {
+	for _, filter := range filters {
+		value, ok := instance.Labels[supacontrolv1alpha1.LabelTagPrefix+filter.key]
+		if !ok || (filter.value != "" && value != filter.value) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,183 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+)
+
+// newTaggedInstance returns an instance owned by user 7 with the given tags
+func newTaggedInstance(name string, tags map[string]string) *supacontrolv1alpha1.SupabaseInstance {
+	instance := newOwnedInstance(name, "7")
+	setInstanceTags(instance, tags)
+	return instance
+}
+
+// TestListInstances_TagFilter tests filtering ListInstances by tags
+func TestListInstances_TagFilter(t *testing.T) {
+	instances := []*supacontrolv1alpha1.SupabaseInstance{
+		newTaggedInstance("staging-api", map[string]string{"env": "staging", "team": "api"}),
+		newTaggedInstance("staging-web", map[string]string{"env": "staging", "team": "web"}),
+		newTaggedInstance("prod-api", map[string]string{"env": "production", "team": "api"}),
+		newTaggedInstance("untagged", nil),
+	}
+
+	tests := []struct {
+		name           string
+		query          string
+		expectedStatus int
+		expected       []string
+	}{
+		{name: "no filter", expectedStatus: http.StatusOK, expected: []string{"staging-api", "staging-web", "prod-api", "untagged"}},
+		{name: "key and value", query: "?tag=env:staging", expectedStatus: http.StatusOK, expected: []string{"staging-api", "staging-web"}},
+		{name: "all tags must match", query: "?tag=env:staging&tag=team:api", expectedStatus: http.StatusOK, expected: []string{"staging-api"}},
+		{name: "key only", query: "?tag=team", expectedStatus: http.StatusOK, expected: []string{"staging-api", "staging-web", "prod-api"}},
+		{name: "no match", query: "?tag=env:dev", expectedStatus: http.StatusOK, expected: []string{}},
+		{name: "missing key", query: "?tag=:staging", expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewHandler(nil, &mockDBClient{}, newQuotaCRClient(instances...), nil)
+			c, rec := newTestContext(http.MethodGet, "/api/v1/instances"+tt.query, "")
+
+			err := handler.ListInstances(c)
+			if tt.expectedStatus != http.StatusOK {
+				assertHTTPError(t, err, tt.expectedStatus)
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var resp apitypes.ListInstancesResponse
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			names := make([]string, 0, len(resp.Instances))
+			for _, instance := range resp.Instances {
+				names = append(names, instance.ProjectName)
+			}
+			if len(names) != len(tt.expected) {
+				t.Fatalf("expected instances %v, got %v", tt.expected, names)
+			}
+			for i := range names {
+				if names[i] != tt.expected[i] {
+					t.Errorf("expected instances %v, got %v", tt.expected, names)
+					break
+				}
+			}
+		})
+	}
+}
+
+// TestUpdateInstance_Tags tests changing instance tags through UpdateInstance
+func TestUpdateInstance_Tags(t *testing.T) {
+	tests := []struct {
+		name           string
+		userID         int64
+		role           string
+		phase          supacontrolv1alpha1.SupabaseInstancePhase
+		body           string
+		expectedStatus int
+		expectedTags   map[string]string
+	}{
+		{name: "owner adds and changes tags", userID: 7, role: "user", body: `{"tags":{"env":"production","team":"api"}}`, expectedStatus: http.StatusOK, expectedTags: map[string]string{"env": "production", "team": "api", "tier": "gold"}},
+		{name: "null removes a tag", userID: 1, role: "admin", body: `{"tags":{"tier":null}}`, expectedStatus: http.StatusOK, expectedTags: map[string]string{"env": "staging"}},
+		{name: "instance need not be running", userID: 7, role: "user", phase: supacontrolv1alpha1.PhaseStopped, body: `{"tags":{"env":"dev"}}`, expectedStatus: http.StatusOK, expectedTags: map[string]string{"env": "dev", "tier": "gold"}},
+		{name: "other user", userID: 8, role: "user", body: `{"tags":{"env":"dev"}}`, expectedStatus: http.StatusForbidden},
+		{name: "invalid key", userID: 7, role: "user", body: `{"tags":{"env/name":"dev"}}`, expectedStatus: http.StatusBadRequest},
+		{name: "invalid value", userID: 7, role: "user", body: `{"tags":{"env":"not valid"}}`, expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			instance := newTaggedInstance("my-app", map[string]string{"env": "staging", "tier": "gold"})
+			instance.Status.Phase = supacontrolv1alpha1.PhaseRunning
+			if tt.phase != "" {
+				instance.Status.Phase = tt.phase
+			}
+			var updated *supacontrolv1alpha1.SupabaseInstance
+			cr := newSuspensionCRClient(nil, instance)
+			cr.updateSupabaseInstanceFunc = func(_ context.Context, instance *supacontrolv1alpha1.SupabaseInstance) error {
+				updated = instance
+				return nil
+			}
+			handler := NewHandler(nil, &mockDBClient{}, cr, nil)
+			c, rec := newTestContext(http.MethodPatch, "/api/v1/instances/my-app", tt.body)
+			c.SetParamNames("name")
+			c.SetParamValues("my-app")
+			setAuthContext(c, tt.userID, "someone", tt.role)
+
+			err := handler.UpdateInstance(c)
+			if tt.expectedStatus != http.StatusOK {
+				assertHTTPError(t, err, tt.expectedStatus)
+				if updated != nil {
+					t.Error("expected the instance to be left alone")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if updated == nil {
+				t.Fatal("expected the instance to be updated")
+			}
+			if updated.Spec.Resources != nil {
+				t.Errorf("expected resources to be left alone, got %+v", updated.Spec.Resources)
+			}
+
+			var resp apitypes.GetInstanceResponse
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if len(resp.Instance.Tags) != len(tt.expectedTags) {
+				t.Fatalf("expected tags %v, got %v", tt.expectedTags, resp.Instance.Tags)
+			}
+			for key, value := range tt.expectedTags {
+				if resp.Instance.Tags[key] != value {
+					t.Errorf("expected tags %v, got %v", tt.expectedTags, resp.Instance.Tags)
+				}
+				if updated.Labels[supacontrolv1alpha1.LabelTagPrefix+key] != value {
+					t.Errorf("expected label %s%s=%s, got %v", supacontrolv1alpha1.LabelTagPrefix, key, value, updated.Labels)
+				}
+			}
+		})
+	}
+}
+
+// TestValidateRequest_Tags tests validation of the tags of a create request
+func TestValidateRequest_Tags(t *testing.T) {
+	tooMany := map[string]string{}
+	for _, key := range "abcdefghijklmnopqrstu" {
+		tooMany[string(key)] = "x"
+	}
+
+	tests := []struct {
+		name    string
+		tags    map[string]string
+		wantErr bool
+	}{
+		{name: "valid", tags: map[string]string{"env": "staging", "cost.center": "4711", "owner_team": "api"}},
+		{name: "empty value", tags: map[string]string{"archived": ""}},
+		{name: "key too long", tags: map[string]string{"a123456789012345678901234567890123456789012345678901234567890": "x"}, wantErr: true},
+		{name: "key with slash", tags: map[string]string{"team/name": "x"}, wantErr: true},
+		{name: "value with space", tags: map[string]string{"env": "staging env"}, wantErr: true},
+		{name: "too many tags", tags: tooMany, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &apitypes.CreateInstanceRequest{Name: "my-app", Tags: tt.tags}
+			err := validateRequest(req, time.Now())
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateRequest() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,536 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+	"github.com/qubitquilt/supacontrol/server/internal/db"
+	"github.com/qubitquilt/supacontrol/server/internal/i18n"
+	"github.com/qubitquilt/supacontrol/server/internal/notify"
+	"github.com/qubitquilt/supacontrol/server/internal/validate"
+)
+
+const (
+	// defaultInvitationTTL is how long an invitation stays valid when no expiry is requested
+	defaultInvitationTTL = 72 * time.Hour
+
+	// maxInvitationTTL caps how long an invitation may stay valid
+	maxInvitationTTL = 30 * 24 * time.Hour
+
+	// minPasswordLength is the minimum length of passwords set through the API
+	minPasswordLength = 8
+)
+
+// parseTeamID parses the :id path parameter
+func parseTeamID(c echo.Context) (int64, error) {
+	teamID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return 0, echo.NewHTTPError(http.StatusBadRequest, "invalid team ID")
+	}
+	return teamID, nil
+}
+
+// requireTeamAdmin ensures the team exists and the caller is a global admin or a team admin
+func (h *Handler) requireTeamAdmin(c echo.Context, authCtx *AuthContext, teamID int64) (*apitypes.Team, error) {
+	team, err := h.dbClient.GetTeamByID(teamID)
+	if err != nil {
+		GetLogger(c).Error("Failed to get team", "team_id", teamID, "error", err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "failed to get team")
+	}
+	if team == nil {
+		return nil, echo.NewHTTPError(http.StatusNotFound, "team not found")
+	}
+
+	if authCtx.IsAdmin() {
+		return team, nil
+	}
+
+	member, err := h.dbClient.GetTeamMember(teamID, authCtx.UserID)
+	if err != nil {
+		GetLogger(c).Error("Failed to get team membership", "team_id", teamID, "error", err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "failed to get team membership")
+	}
+	if member == nil || member.Role != apitypes.TeamRoleAdmin {
+		return nil, echo.NewHTTPError(http.StatusForbidden, "team admin access required")
+	}
+
+	return team, nil
+}
+
+// buildInvitationURL returns the link a recipient follows to accept an invitation
+func (h *Handler) buildInvitationURL(token string) string {
+	return fmt.Sprintf("%s/invitations/accept?token=%s", h.publicURL, url.QueryEscape(token))
+}
+
+// CreateTeam creates a new team with the caller as team admin
+func (h *Handler) CreateTeam(c echo.Context) error {
+	authCtx := GetAuthContext(c)
+	if authCtx == nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "not authenticated")
+	}
+
+	var req apitypes.CreateTeamRequest
+	if err := bindRequest(c, &req); err != nil {
+		return err
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+
+	team, err := h.dbClient.CreateTeam(req.Name, authCtx.UserID)
+	if err != nil {
+		GetLogger(c).Error("Failed to create team", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to create team")
+	}
+
+	h.recordAudit(c, "team.create", "team", strconv.FormatInt(team.ID, 10), map[string]string{"name": team.Name})
+
+	return c.JSON(http.StatusCreated, team)
+}
+
+// ListTeams lists the caller's teams (all teams for admins)
+func (h *Handler) ListTeams(c echo.Context) error {
+	authCtx := GetAuthContext(c)
+	if authCtx == nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "not authenticated")
+	}
+
+	var teams []*apitypes.Team
+	var err error
+
+	if authCtx.IsAdmin() {
+		teams, err = h.dbClient.ListAllTeams()
+	} else {
+		teams, err = h.dbClient.ListTeamsByUser(authCtx.UserID)
+	}
+
+	if err != nil {
+		GetLogger(c).Error("Failed to list teams", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to list teams")
+	}
+
+	return c.JSON(http.StatusOK, apitypes.ListTeamsResponse{
+		Teams: teams,
+		Count: len(teams),
+	})
+}
+
+// CreateTeamInvitation creates a signed invitation link for joining a team
+func (h *Handler) CreateTeamInvitation(c echo.Context) error {
+	authCtx := GetAuthContext(c)
+	if authCtx == nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "not authenticated")
+	}
+
+	teamID, err := parseTeamID(c)
+	if err != nil {
+		return err
+	}
+
+	var req apitypes.CreateInvitationRequest
+	if err := bindRequest(c, &req); err != nil {
+		return err
+	}
+
+	req.Email = strings.TrimSpace(req.Email)
+
+	if req.Role == "" {
+		req.Role = apitypes.TeamRoleMember
+	}
+	if req.Role != apitypes.TeamRoleMember && req.Role != apitypes.TeamRoleAdmin {
+		return echo.NewHTTPError(http.StatusBadRequest, "role must be 'member' or 'admin'")
+	}
+
+	ttl, err := invitationTTL(req.ExpiresInHours)
+	if err != nil {
+		return err
+	}
+
+	team, err := h.requireTeamAdmin(c, authCtx, teamID)
+	if err != nil {
+		return err
+	}
+
+	invitation, err := h.dbClient.CreateTeamInvitation(teamID, req.Email, req.Role, authCtx.UserID, time.Now().Add(ttl))
+	if err != nil {
+		GetLogger(c).Error("Failed to create team invitation", "team_id", teamID, "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to create invitation")
+	}
+
+	token, err := h.authService.GenerateInvitationToken(invitation.ID, teamID, invitation.ExpiresAt)
+	if err != nil {
+		GetLogger(c).Error("Failed to sign invitation token", "invitation_id", invitation.ID, "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to create invitation")
+	}
+
+	h.recordAudit(c, "team.invitation.create", "team", strconv.FormatInt(teamID, 10), map[string]string{
+		"email": req.Email,
+		"role":  req.Role,
+	})
+
+	inviteURL := h.buildInvitationURL(token)
+	return c.JSON(http.StatusCreated, apitypes.CreateInvitationResponse{
+		Invitation: invitation,
+		Token:      token,
+		InviteURL:  inviteURL,
+		Notified:   h.notifyInvitation(c, team, invitation, inviteURL),
+	})
+}
+
+// ResendTeamInvitation extends a pending or expired invitation and issues a new link for it
+func (h *Handler) ResendTeamInvitation(c echo.Context) error {
+	authCtx := GetAuthContext(c)
+	if authCtx == nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "not authenticated")
+	}
+
+	teamID, err := parseTeamID(c)
+	if err != nil {
+		return err
+	}
+
+	invitationID, err := strconv.ParseInt(c.Param("invitationId"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid invitation ID")
+	}
+
+	// The body is optional: without one the invitation gets the default validity
+	var req apitypes.ResendInvitationRequest
+	if c.Request().ContentLength > 0 {
+		if err := bindRequest(c, &req); err != nil {
+			return err
+		}
+	}
+
+	ttl, err := invitationTTL(req.ExpiresInHours)
+	if err != nil {
+		return err
+	}
+
+	team, err := h.requireTeamAdmin(c, authCtx, teamID)
+	if err != nil {
+		return err
+	}
+
+	invitation, err := h.dbClient.GetTeamInvitationByID(invitationID)
+	if err != nil {
+		GetLogger(c).Error("Failed to get team invitation", "invitation_id", invitationID, "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to get invitation")
+	}
+	if invitation == nil || invitation.TeamID != teamID {
+		return echo.NewHTTPError(http.StatusNotFound, "invitation not found")
+	}
+
+	invitation, err = h.dbClient.ExtendTeamInvitation(invitationID, time.Now().Add(ttl))
+	if err != nil {
+		if errors.Is(err, db.ErrInvitationNotPending) {
+			return echo.NewHTTPError(http.StatusConflict, "invitation is no longer pending")
+		}
+		GetLogger(c).Error("Failed to extend team invitation", "invitation_id", invitationID, "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to resend invitation")
+	}
+
+	token, err := h.authService.GenerateInvitationToken(invitation.ID, teamID, invitation.ExpiresAt)
+	if err != nil {
+		GetLogger(c).Error("Failed to sign invitation token", "invitation_id", invitation.ID, "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to resend invitation")
+	}
+
+	h.recordAudit(c, "team.invitation.resend", "team", strconv.FormatInt(teamID, 10), map[string]string{
+		"invitation_id": strconv.FormatInt(invitationID, 10),
+		"expires_at":    invitation.ExpiresAt.UTC().Format(time.RFC3339),
+	})
+
+	inviteURL := h.buildInvitationURL(token)
+	return c.JSON(http.StatusOK, apitypes.CreateInvitationResponse{
+		Invitation: invitation,
+		Token:      token,
+		InviteURL:  inviteURL,
+		Notified:   h.notifyInvitation(c, team, invitation, inviteURL),
+	})
+}
+
+// invitationTTL returns how long an invitation requested to be valid for hours stays
+// valid (0 uses the default)
+func invitationTTL(hours int) (time.Duration, error) {
+	ttl := defaultInvitationTTL
+	if hours > 0 {
+		ttl = time.Duration(hours) * time.Hour
+	}
+	if ttl > maxInvitationTTL {
+		return 0, echo.NewHTTPError(http.StatusBadRequest, "invitations may be valid for at most 30 days")
+	}
+	return ttl, nil
+}
+
+// notifyInvitation delivers an invitation link through the notification webhook, which
+// forwards it to the invitee. Delivery is best effort: the inviter still gets the link
+// in the response. It reports whether the link was delivered.
+func (h *Handler) notifyInvitation(c echo.Context, team *apitypes.Team, invitation *apitypes.TeamInvitation, inviteURL string) bool {
+	if h.notifier == nil {
+		return false
+	}
+	err := h.notifier.Notify(c.Request().Context(), notify.Event{
+		Type:    notify.EventTeamInvitation,
+		Message: fmt.Sprintf("%s was invited to join team %s", invitation.Email, team.Name),
+		Time:    time.Now().UTC(),
+		Data: map[string]string{
+			"email":      invitation.Email,
+			"role":       invitation.Role,
+			"team":       team.Name,
+			"invite_url": inviteURL,
+			"expires_at": invitation.ExpiresAt.UTC().Format(time.RFC3339),
+		},
+	})
+	if err != nil {
+		GetLogger(c).Warn("Failed to deliver team invitation", "invitation_id", invitation.ID, "error", err)
+		return false
+	}
+	return true
+}
+
+// ListTeamInvitations lists all invitations for a team
+func (h *Handler) ListTeamInvitations(c echo.Context) error {
+	authCtx := GetAuthContext(c)
+	if authCtx == nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "not authenticated")
+	}
+
+	teamID, err := parseTeamID(c)
+	if err != nil {
+		return err
+	}
+
+	if _, err := h.requireTeamAdmin(c, authCtx, teamID); err != nil {
+		return err
+	}
+
+	invitations, err := h.dbClient.ListTeamInvitations(teamID)
+	if err != nil {
+		GetLogger(c).Error("Failed to list team invitations", "team_id", teamID, "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to list invitations")
+	}
+
+	return c.JSON(http.StatusOK, apitypes.ListInvitationsResponse{
+		Invitations: invitations,
+		Count:       len(invitations),
+	})
+}
+
+// RevokeTeamInvitation revokes a pending invitation
+func (h *Handler) RevokeTeamInvitation(c echo.Context) error {
+	authCtx := GetAuthContext(c)
+	if authCtx == nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "not authenticated")
+	}
+
+	teamID, err := parseTeamID(c)
+	if err != nil {
+		return err
+	}
+
+	invitationID, err := strconv.ParseInt(c.Param("invitationId"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid invitation ID")
+	}
+
+	if _, err := h.requireTeamAdmin(c, authCtx, teamID); err != nil {
+		return err
+	}
+
+	invitation, err := h.dbClient.GetTeamInvitationByID(invitationID)
+	if err != nil {
+		GetLogger(c).Error("Failed to get team invitation", "invitation_id", invitationID, "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to get invitation")
+	}
+	if invitation == nil || invitation.TeamID != teamID {
+		return echo.NewHTTPError(http.StatusNotFound, "invitation not found")
+	}
+
+	if err := h.dbClient.RevokeTeamInvitation(invitationID); err != nil {
+		if errors.Is(err, db.ErrInvitationNotPending) {
+			return echo.NewHTTPError(http.StatusConflict, "invitation is no longer pending")
+		}
+		GetLogger(c).Error("Failed to revoke team invitation", "invitation_id", invitationID, "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to revoke invitation")
+	}
+
+	h.recordAudit(c, "team.invitation.revoke", "team", strconv.FormatInt(teamID, 10), map[string]string{
+		"invitation_id": strconv.FormatInt(invitationID, 10),
+	})
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": localize(c, "Invitation revoked successfully"),
+	})
+}
+
+// AcceptInvitation accepts a team invitation, creating a new account or linking an existing one.
+// This endpoint is public: the signed invitation token is the credential.
+func (h *Handler) AcceptInvitation(c echo.Context) error {
+	var req apitypes.AcceptInvitationRequest
+	if err := bindRequest(c, &req); err != nil {
+		return err
+	}
+
+	claims, err := h.authService.ValidateInvitationToken(req.Token)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "invalid or expired invitation")
+	}
+
+	invitation, err := h.dbClient.GetTeamInvitationByID(claims.InvitationID)
+	if err != nil {
+		GetLogger(c).Error("Failed to get team invitation", "invitation_id", claims.InvitationID, "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to get invitation")
+	}
+	if invitation == nil || invitation.TeamID != claims.TeamID {
+		return echo.NewHTTPError(http.StatusNotFound, "invitation not found")
+	}
+	if invitation.AcceptedAt != nil || invitation.RevokedAt != nil || invitation.ExpiresAt.Before(time.Now()) {
+		return echo.NewHTTPError(http.StatusGone, "invitation is no longer valid")
+	}
+
+	// A signed-in user accepts with their session. Otherwise the request names an existing
+	// account to link, proven by its password, or a new one to create.
+	user, err := h.invitationSessionUser(c)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		if user, err = h.invitationAccountUser(c, &req, invitation); err != nil {
+			return err
+		}
+	}
+
+	member, err := h.dbClient.AcceptTeamInvitation(invitation.ID, user.ID)
+	if err != nil {
+		if errors.Is(err, db.ErrInvitationNotPending) {
+			return echo.NewHTTPError(http.StatusGone, "invitation is no longer valid")
+		}
+		// The invitation is bound to the address it was sent to, not to whoever holds the link
+		if errors.Is(err, db.ErrInvitationEmailMismatch) {
+			return echo.NewHTTPError(http.StatusForbidden, "invitation was sent to a different email address")
+		}
+		if errors.Is(err, db.ErrEmailInUse) {
+			return echo.NewHTTPError(http.StatusConflict, "invitation email belongs to another account")
+		}
+		GetLogger(c).Error("Failed to accept team invitation", "invitation_id", invitation.ID, "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to accept invitation")
+	}
+
+	team, err := h.dbClient.GetTeamByID(invitation.TeamID)
+	if err != nil || team == nil {
+		GetLogger(c).Error("Failed to get team", "team_id", invitation.TeamID, "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to get team")
+	}
+
+	token, err := h.authService.GenerateJWT(user.ID, user.Username, user.Role, 24*time.Hour)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to generate token")
+	}
+
+	if err := h.dbClient.CreateAuditLog(user.ID, "team.invitation.accept", "team", strconv.FormatInt(team.ID, 10), map[string]string{
+		"invitation_id": strconv.FormatInt(invitation.ID, 10),
+		"role":          member.Role,
+	}); err != nil {
+		GetLogger(c).Error("Failed to record audit log", "action", "team.invitation.accept", "error", err)
+	}
+
+	return c.JSON(http.StatusOK, apitypes.AcceptInvitationResponse{
+		Token: token,
+		User: &apitypes.UserInfo{
+			ID:       user.ID,
+			Username: user.Username,
+			Role:     user.Role,
+		},
+		Team: team,
+		Role: member.Role,
+	})
+}
+
+// invitationSessionUser returns the user signed in with the request's bearer token, or nil
+// when the request carries none
+func (h *Handler) invitationSessionUser(c echo.Context) (*db.User, error) {
+	authHeader := c.Request().Header.Get("Authorization")
+	if authHeader == "" {
+		return nil, nil
+	}
+
+	token, ok := strings.CutPrefix(authHeader, "Bearer ")
+	if !ok || strings.HasPrefix(token, "sk_") {
+		return nil, echo.NewHTTPError(http.StatusUnauthorized, "invitations can only be accepted with a login session")
+	}
+	claims, err := h.authService.ValidateJWT(token)
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusUnauthorized, "invalid JWT token")
+	}
+
+	user, err := h.dbClient.GetUserByID(claims.UserID)
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "failed to verify user")
+	}
+	if user == nil {
+		return nil, echo.NewHTTPError(http.StatusUnauthorized, "user not found")
+	}
+	if err := requirePasswordChange(c, user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// invitationAccountUser links the existing account named in the request when its password
+// matches, or creates it
+func (h *Handler) invitationAccountUser(c echo.Context, req *apitypes.AcceptInvitationRequest, invitation *apitypes.TeamInvitation) (*db.User, error) {
+	var v validate.Validator
+	v.Required("username", req.Username)
+	v.Required("password", req.Password)
+	if err := v.Err(); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, err)
+	}
+
+	user, err := h.dbClient.GetUserByUsername(req.Username)
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "failed to look up user")
+	}
+
+	if user != nil {
+		valid, err := h.authService.VerifyPassword(req.Password, user.PasswordHash)
+		if err != nil {
+			return nil, echo.NewHTTPError(http.StatusInternalServerError, "failed to verify password")
+		}
+		if !valid {
+			return nil, echo.NewHTTPError(http.StatusUnauthorized, "invalid credentials")
+		}
+		return user, nil
+	}
+
+	if len(req.Password) < minPasswordLength {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, i18n.Msg("password must be at least %d characters", minPasswordLength))
+	}
+
+	// The address may only belong to one account, so its owner has to sign in instead
+	owner, err := h.dbClient.GetUserByEmail(invitation.Email)
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "failed to look up user")
+	}
+	if owner != nil {
+		return nil, echo.NewHTTPError(http.StatusConflict, "invitation email belongs to another account")
+	}
+
+	passwordHash, err := h.authService.HashPassword(req.Password)
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "failed to hash password")
+	}
+	user, err = h.dbClient.CreateUser(req.Username, passwordHash, "user")
+	if err != nil {
+		GetLogger(c).Error("Failed to create user from invitation", "error", err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "failed to create user")
+	}
+	return user, nil
+}
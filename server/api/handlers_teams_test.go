@@ -0,0 +1,571 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+	"github.com/qubitquilt/supacontrol/server/internal/auth"
+	"github.com/qubitquilt/supacontrol/server/internal/db"
+	"github.com/qubitquilt/supacontrol/server/internal/notify"
+)
+
+// assertHTTPError checks that err is an *echo.HTTPError with the given status
+func assertHTTPError(t *testing.T, err error, status int) {
+	t.Helper()
+	httpErr, ok := err.(*echo.HTTPError)
+	if !ok {
+		t.Fatalf("expected *echo.HTTPError, got %T (%v)", err, err)
+	}
+	if httpErr.Code != status {
+		t.Errorf("expected status %d, got %d (%v)", status, httpErr.Code, httpErr.Message)
+	}
+}
+
+// TestCreateTeamInvitation tests the CreateTeamInvitation handler
+func TestCreateTeamInvitation(t *testing.T) {
+	tests := []struct {
+		name           string
+		userID         int64
+		role           string
+		memberRole     string
+		body           string
+		expectedStatus int
+	}{
+		{
+			name:           "team admin invites member",
+			userID:         7,
+			role:           "user",
+			memberRole:     apitypes.TeamRoleAdmin,
+			body:           `{"email":"new@example.com"}`,
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name:           "global admin invites without membership",
+			userID:         1,
+			role:           "admin",
+			body:           `{"email":"new@example.com","role":"admin","expires_in_hours":24}`,
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name:           "team member is forbidden",
+			userID:         8,
+			role:           "user",
+			memberRole:     apitypes.TeamRoleMember,
+			body:           `{"email":"new@example.com"}`,
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "non-member is forbidden",
+			userID:         9,
+			role:           "user",
+			body:           `{"email":"new@example.com"}`,
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "invalid email",
+			userID:         1,
+			role:           "admin",
+			body:           `{"email":"not-an-email"}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "invalid role",
+			userID:         1,
+			role:           "admin",
+			body:           `{"email":"new@example.com","role":"owner"}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "expiry too long",
+			userID:         1,
+			role:           "admin",
+			body:           `{"email":"new@example.com","expires_in_hours":10000}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			authService := auth.NewService("test-secret")
+			audited := false
+			mockDB := &mockDBClient{
+				getTeamByIDFunc: func(id int64) (*apitypes.Team, error) {
+					return &apitypes.Team{ID: id, Name: "platform"}, nil
+				},
+				getTeamMemberFunc: func(teamID, userID int64) (*apitypes.TeamMember, error) {
+					if tt.memberRole == "" {
+						return nil, nil
+					}
+					return &apitypes.TeamMember{TeamID: teamID, UserID: userID, Role: tt.memberRole}, nil
+				},
+				createTeamInvitationFunc: func(teamID int64, email, role string, invitedBy int64, expiresAt time.Time) (*apitypes.TeamInvitation, error) {
+					return &apitypes.TeamInvitation{ID: 42, TeamID: teamID, Email: email, Role: role, InvitedBy: &invitedBy, ExpiresAt: expiresAt}, nil
+				},
+				createAuditLogFunc: func(_ int64, action, _, _ string, _ map[string]string) error {
+					audited = action == "team.invitation.create"
+					return nil
+				},
+			}
+
+			handler := NewHandler(authService, mockDB, &mockCRClient{}, nil, WithPublicURL("https://supacontrol.example.com/"))
+			c, rec := newTestContext(http.MethodPost, "/api/v1/teams/3/invitations", tt.body)
+			c.SetParamNames("id")
+			c.SetParamValues("3")
+			setAuthContext(c, tt.userID, "tester", tt.role)
+
+			err := handler.CreateTeamInvitation(c)
+			if tt.expectedStatus != http.StatusCreated {
+				assertHTTPError(t, err, tt.expectedStatus)
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var resp apitypes.CreateInvitationResponse
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if !strings.HasPrefix(resp.InviteURL, "https://supacontrol.example.com/invitations/accept?token=") {
+				t.Errorf("unexpected invite URL %q", resp.InviteURL)
+			}
+			claims, err := authService.ValidateInvitationToken(resp.Token)
+			if err != nil {
+				t.Fatalf("token did not validate: %v", err)
+			}
+			if claims.InvitationID != 42 || claims.TeamID != 3 {
+				t.Errorf("unexpected claims: %+v", claims)
+			}
+			if !audited {
+				t.Error("expected invitation creation to be audited")
+			}
+		})
+	}
+}
+
+// TestRevokeTeamInvitation tests the RevokeTeamInvitation handler
+func TestRevokeTeamInvitation(t *testing.T) {
+	tests := []struct {
+		name           string
+		invitationTeam int64
+		revokeErr      error
+		expectedStatus int
+	}{
+		{
+			name:           "revokes pending invitation",
+			invitationTeam: 3,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "invitation from another team",
+			invitationTeam: 4,
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "already accepted",
+			invitationTeam: 3,
+			revokeErr:      db.ErrInvitationNotPending,
+			expectedStatus: http.StatusConflict,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockDB := &mockDBClient{
+				getTeamByIDFunc: func(id int64) (*apitypes.Team, error) {
+					return &apitypes.Team{ID: id, Name: "platform"}, nil
+				},
+				getTeamInvitationByIDFunc: func(id int64) (*apitypes.TeamInvitation, error) {
+					return &apitypes.TeamInvitation{ID: id, TeamID: tt.invitationTeam}, nil
+				},
+				revokeTeamInvitationFunc: func(int64) error {
+					return tt.revokeErr
+				},
+				createAuditLogFunc: func(int64, string, string, string, map[string]string) error {
+					return nil
+				},
+			}
+
+			handler := NewHandler(nil, mockDB, &mockCRClient{}, nil)
+			c, _ := newTestContext(http.MethodDelete, "/api/v1/teams/3/invitations/42", "")
+			c.SetParamNames("id", "invitationId")
+			c.SetParamValues("3", "42")
+			setAuthContext(c, 1, "admin", "admin")
+
+			err := handler.RevokeTeamInvitation(c)
+			if tt.expectedStatus != http.StatusOK {
+				assertHTTPError(t, err, tt.expectedStatus)
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+// TestAcceptInvitation tests the AcceptInvitation handler
+func TestAcceptInvitation(t *testing.T) {
+	authService := auth.NewService("test-secret")
+	existingHash, err := authService.HashPassword("correct-password")
+	if err != nil {
+		t.Fatalf("failed to hash password: %v", err)
+	}
+
+	now := time.Now()
+	validToken, err := authService.GenerateInvitationToken(42, 3, now.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("failed to generate token: %v", err)
+	}
+	loginToken, err := authService.GenerateJWT(1, "admin", "admin", time.Hour)
+	if err != nil {
+		t.Fatalf("failed to generate token: %v", err)
+	}
+	sessionToken, err := authService.GenerateJWT(5, "existing", "user", time.Hour)
+	if err != nil {
+		t.Fatalf("failed to generate token: %v", err)
+	}
+
+	tests := []struct {
+		name           string
+		token          string
+		session        string
+		username       string
+		password       string
+		acceptedAt     *time.Time
+		emailOwned     bool
+		acceptErr      error
+		expectCreate   bool
+		expectedUser   int64
+		expectedStatus int
+	}{
+		{
+			name:           "new user account is created",
+			token:          validToken,
+			username:       "newbie",
+			password:       "long-enough",
+			expectCreate:   true,
+			expectedUser:   6,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "existing user is linked",
+			token:          validToken,
+			username:       "existing",
+			password:       "correct-password",
+			expectedUser:   5,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "signed-in user accepts with their session",
+			token:          validToken,
+			session:        "Bearer " + sessionToken,
+			expectedUser:   5,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "API key instead of a session",
+			token:          validToken,
+			session:        "Bearer sk_test",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "invalid session",
+			token:          validToken,
+			session:        "Bearer not-a-jwt",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "no session and no credentials",
+			token:          validToken,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "existing user with wrong password",
+			token:          validToken,
+			username:       "existing",
+			password:       "wrong-password",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "new user with short password",
+			token:          validToken,
+			username:       "newbie",
+			password:       "short",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "new user for an address another account owns",
+			token:          validToken,
+			username:       "newbie",
+			password:       "long-enough",
+			emailOwned:     true,
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			name:           "login token is rejected",
+			token:          loginToken,
+			username:       "newbie",
+			password:       "long-enough",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "already accepted invitation",
+			token:          validToken,
+			username:       "existing",
+			password:       "correct-password",
+			acceptedAt:     &now,
+			expectedStatus: http.StatusGone,
+		},
+		{
+			name:           "accepted concurrently",
+			token:          validToken,
+			username:       "existing",
+			password:       "correct-password",
+			acceptErr:      db.ErrInvitationNotPending,
+			expectedStatus: http.StatusGone,
+		},
+		{
+			name:           "account with a different email",
+			token:          validToken,
+			session:        "Bearer " + sessionToken,
+			acceptErr:      db.ErrInvitationEmailMismatch,
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "address taken by another account meanwhile",
+			token:          validToken,
+			username:       "existing",
+			password:       "correct-password",
+			acceptErr:      db.ErrEmailInUse,
+			expectedStatus: http.StatusConflict,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			created := false
+			existing := &db.User{ID: 5, Username: "existing", PasswordHash: existingHash, Role: "user"}
+			mockDB := &mockDBClient{
+				getTeamInvitationByIDFunc: func(id int64) (*apitypes.TeamInvitation, error) {
+					return &apitypes.TeamInvitation{
+						ID:         id,
+						TeamID:     3,
+						Email:      "invitee@example.com",
+						Role:       apitypes.TeamRoleMember,
+						ExpiresAt:  now.Add(time.Hour),
+						AcceptedAt: tt.acceptedAt,
+					}, nil
+				},
+				getUserByUsernameFunc: func(username string) (*db.User, error) {
+					if username == existing.Username {
+						return existing, nil
+					}
+					return nil, nil
+				},
+				getUserByIDFunc: func(id int64) (*db.User, error) {
+					if id == existing.ID {
+						return existing, nil
+					}
+					return nil, nil
+				},
+				getUserByEmailFunc: func(string) (*db.User, error) {
+					if tt.emailOwned {
+						return existing, nil
+					}
+					return nil, nil
+				},
+				createUserFunc: func(username, passwordHash, role string) (*db.User, error) {
+					created = true
+					return &db.User{ID: 6, Username: username, PasswordHash: passwordHash, Role: role}, nil
+				},
+				acceptTeamInvitationFunc: func(id, userID int64) (*apitypes.TeamMember, error) {
+					if tt.acceptErr != nil {
+						return nil, tt.acceptErr
+					}
+					return &apitypes.TeamMember{TeamID: 3, UserID: userID, Role: apitypes.TeamRoleMember}, nil
+				},
+				getTeamByIDFunc: func(id int64) (*apitypes.Team, error) {
+					return &apitypes.Team{ID: id, Name: "platform"}, nil
+				},
+				createAuditLogFunc: func(int64, string, string, string, map[string]string) error {
+					return nil
+				},
+			}
+
+			handler := NewHandler(authService, mockDB, &mockCRClient{}, nil)
+			body := fmt.Sprintf(`{"token":%q,"username":%q,"password":%q}`, tt.token, tt.username, tt.password)
+			c, rec := newTestContext(http.MethodPost, "/api/v1/invitations/accept", body)
+			if tt.session != "" {
+				c.Request().Header.Set("Authorization", tt.session)
+			}
+
+			err := handler.AcceptInvitation(c)
+			if created != tt.expectCreate {
+				t.Errorf("expected created=%t, got %t", tt.expectCreate, created)
+			}
+			if tt.expectedStatus != http.StatusOK {
+				assertHTTPError(t, err, tt.expectedStatus)
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var resp apitypes.AcceptInvitationResponse
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if resp.Team == nil || resp.Team.ID != 3 || resp.Role != apitypes.TeamRoleMember {
+				t.Errorf("unexpected response: %+v", resp)
+			}
+			if resp.User == nil || resp.User.ID != tt.expectedUser {
+				t.Errorf("expected user %d, got %+v", tt.expectedUser, resp.User)
+			}
+			if _, err := authService.ValidateJWT(resp.Token); err != nil {
+				t.Errorf("expected a valid login token: %v", err)
+			}
+		})
+	}
+}
+
+// recordingNotifier records the events it is asked to deliver
+type recordingNotifier struct {
+	events []notify.Event
+	err    error
+}
+
+func (n *recordingNotifier) Notify(_ context.Context, event notify.Event) error {
+	n.events = append(n.events, event)
+	return n.err
+}
+
+// TestResendTeamInvitation tests the ResendTeamInvitation handler
+func TestResendTeamInvitation(t *testing.T) {
+	authService := auth.NewService("test-secret")
+
+	tests := []struct {
+		name           string
+		body           string
+		invitationTeam int64
+		extendErr      error
+		notifyErr      error
+		expectedTTL    time.Duration
+		expectNotified bool
+		expectedStatus int
+	}{
+		{
+			name:           "extends by the default validity",
+			invitationTeam: 3,
+			expectedTTL:    defaultInvitationTTL,
+			expectNotified: true,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "extends by the requested validity",
+			body:           `{"expires_in_hours": 24}`,
+			invitationTeam: 3,
+			expectedTTL:    24 * time.Hour,
+			expectNotified: true,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "failed delivery still returns the link",
+			invitationTeam: 3,
+			notifyErr:      fmt.Errorf("webhook unavailable"),
+			expectedTTL:    defaultInvitationTTL,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "validity too long",
+			body:           `{"expires_in_hours": 1000}`,
+			invitationTeam: 3,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "invitation from another team",
+			invitationTeam: 4,
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "already accepted",
+			invitationTeam: 3,
+			extendErr:      db.ErrInvitationNotPending,
+			expectedStatus: http.StatusConflict,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var extendedTo time.Time
+			mockDB := &mockDBClient{
+				getTeamByIDFunc: func(id int64) (*apitypes.Team, error) {
+					return &apitypes.Team{ID: id, Name: "platform"}, nil
+				},
+				getTeamInvitationByIDFunc: func(id int64) (*apitypes.TeamInvitation, error) {
+					return &apitypes.TeamInvitation{ID: id, TeamID: tt.invitationTeam, Email: "jane@example.com", Role: apitypes.TeamRoleMember}, nil
+				},
+				extendTeamInvitationFunc: func(id int64, expiresAt time.Time) (*apitypes.TeamInvitation, error) {
+					if tt.extendErr != nil {
+						return nil, tt.extendErr
+					}
+					extendedTo = expiresAt
+					return &apitypes.TeamInvitation{ID: id, TeamID: 3, Email: "jane@example.com", Role: apitypes.TeamRoleMember, ExpiresAt: expiresAt}, nil
+				},
+				createAuditLogFunc: func(int64, string, string, string, map[string]string) error {
+					return nil
+				},
+			}
+			notifier := &recordingNotifier{err: tt.notifyErr}
+
+			handler := NewHandler(authService, mockDB, &mockCRClient{}, nil, WithPublicURL("https://supacontrol.example.com"), WithNotifier(notifier))
+			c, rec := newTestContext(http.MethodPost, "/api/v1/teams/3/invitations/42/resend", tt.body)
+			c.SetParamNames("id", "invitationId")
+			c.SetParamValues("3", "42")
+			setAuthContext(c, 1, "admin", "admin")
+
+			err := handler.ResendTeamInvitation(c)
+			if tt.expectedStatus != http.StatusOK {
+				assertHTTPError(t, err, tt.expectedStatus)
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if ttl := time.Until(extendedTo); ttl > tt.expectedTTL || ttl < tt.expectedTTL-time.Minute {
+				t.Errorf("expected the invitation to be extended by %v, got %v", tt.expectedTTL, ttl)
+			}
+
+			var resp apitypes.CreateInvitationResponse
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			claims, err := authService.ValidateInvitationToken(resp.Token)
+			if err != nil {
+				t.Fatalf("expected a valid invitation token: %v", err)
+			}
+			if claims.InvitationID != 42 || claims.TeamID != 3 {
+				t.Errorf("unexpected claims: %+v", claims)
+			}
+			if resp.Notified != tt.expectNotified {
+				t.Errorf("expected notified=%t, got %t", tt.expectNotified, resp.Notified)
+			}
+
+			if len(notifier.events) != 1 {
+				t.Fatalf("expected one notification, got %d", len(notifier.events))
+			}
+			event := notifier.events[0]
+			if event.Type != notify.EventTeamInvitation || event.Data["email"] != "jane@example.com" || event.Data["invite_url"] != resp.InviteURL {
+				t.Errorf("unexpected notification: %+v", event)
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+	"github.com/qubitquilt/supacontrol/server/internal/i18n"
+	"github.com/qubitquilt/supacontrol/server/internal/k8s"
+)
+
+// templateToAPIType converts an instance template to its API representation, leaving out
+// its chart values
+func templateToAPIType(template *supacontrolv1alpha1.SupabaseInstanceTemplate) apitypes.InstanceTemplate {
+	result := apitypes.InstanceTemplate{
+		Name:         template.Name,
+		DisplayName:  template.Spec.DisplayName,
+		Description:  template.Spec.Description,
+		ChartVersion: template.Spec.ChartVersion,
+		Resources:    resourcesToAPIType(template.Spec.Resources),
+		Storage:      storageToAPIType(template.Spec.Storage),
+	}
+	if result.DisplayName == "" {
+		result.DisplayName = template.Name
+	}
+	if quota := template.Spec.Quota; quota != nil && (quota.MaxInstances > 0 || quota.MaxInstancesPerUser > 0) {
+		result.Quota = &apitypes.TemplateQuota{
+			MaxInstances:        int(quota.MaxInstances),
+			MaxInstancesPerUser: int(quota.MaxInstancesPerUser),
+		}
+	}
+	return result
+}
+
+// ListTemplates lists the templates instances can be created from, sorted by name
+func (h *Handler) ListTemplates(c echo.Context) error {
+	list, err := h.crClient.ListSupabaseInstanceTemplates(c.Request().Context())
+	if err != nil {
+		GetLogger(c).Error("Failed to list instance templates", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to list templates")
+	}
+
+	templates := make([]apitypes.InstanceTemplate, 0, len(list.Items))
+	for i := range list.Items {
+		templates = append(templates, templateToAPIType(&list.Items[i]))
+	}
+	sort.Slice(templates, func(i, j int) bool { return templates[i].Name < templates[j].Name })
+	return c.JSON(http.StatusOK, apitypes.ListTemplatesResponse{
+		Templates: templates,
+		Count:     len(templates),
+	})
+}
+
+// resolveTemplate returns the template a new instance is created from, or nil when the
+// request names none
+func (h *Handler) resolveTemplate(c echo.Context, name string) (*supacontrolv1alpha1.SupabaseInstanceTemplate, error) {
+	if name == "" {
+		return nil, nil
+	}
+	template, err := h.crClient.GetSupabaseInstanceTemplate(c.Request().Context(), name)
+	if err != nil {
+		if errors.Is(err, k8s.ErrTemplateNotFound) {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, i18n.Msg("template %s not found", name))
+		}
+		GetLogger(c).Error("Failed to get instance template", "template", name, "error", err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "failed to get template")
+	}
+	return template, nil
+}
+
+// checkTemplateQuota rejects creating another instance from a template that has reached
+// its installation-wide or per-user limit. Instances being deleted no longer count.
+func (h *Handler) checkTemplateQuota(c echo.Context, template *supacontrolv1alpha1.SupabaseInstanceTemplate) error {
+	quota := template.Spec.Quota
+	if quota == nil || (quota.MaxInstances == 0 && quota.MaxInstancesPerUser == 0) {
+		return nil
+	}
+
+	list, err := h.crClient.ListSupabaseInstances(c.Request().Context())
+	if err != nil {
+		GetLogger(c).Error("Failed to count template instances", "template", template.Name, "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to check quotas")
+	}
+	owner := ""
+	if authCtx := GetAuthContext(c); authCtx != nil {
+		owner = strconv.FormatInt(authCtx.UserID, 10)
+	}
+	var total, owned int32
+	for i := range list.Items {
+		instance := &list.Items[i]
+		if instance.DeletionTimestamp != nil || instance.Spec.TemplateRef != template.Name {
+			continue
+		}
+		total++
+		if owner != "" && instance.Annotations[supacontrolv1alpha1.AnnotationOwnerID] == owner {
+			owned++
+		}
+	}
+
+	switch {
+	case quota.MaxInstances > 0 && total >= quota.MaxInstances:
+		return newAPIErrorWithDetails(http.StatusForbidden, apitypes.ErrorCodeQuotaExceeded,
+			i18n.Msg("template %s has reached its limit of %d instances", template.Name, quota.MaxInstances),
+			quotaDetails("template", "max_instances", int(quota.MaxInstances)))
+	case owner != "" && quota.MaxInstancesPerUser > 0 && owned >= quota.MaxInstancesPerUser:
+		return newAPIErrorWithDetails(http.StatusForbidden, apitypes.ErrorCodeQuotaExceeded,
+			i18n.Msg("template %s allows %d instances per user", template.Name, quota.MaxInstancesPerUser),
+			quotaDetails("template", "max_instances_per_user", int(quota.MaxInstancesPerUser)))
+	}
+	return nil
+}
+
+// applyTemplate records the template an instance is created from and fills in the chart
+// version, resources and storage the request left to it. The template's chart values are
+// not copied: the controller reads them when it renders the instance's chart values.
+func applyTemplate(instance *supacontrolv1alpha1.SupabaseInstance, template *supacontrolv1alpha1.SupabaseInstanceTemplate) {
+	if template == nil {
+		return
+	}
+	spec := &instance.Spec
+	spec.TemplateRef = template.Name
+	if spec.ChartVersion == "" {
+		spec.ChartVersion = template.Spec.ChartVersion
+	}
+	if spec.Resources == nil {
+		spec.Resources = template.Spec.Resources.DeepCopy()
+	}
+	if spec.Storage == nil {
+		spec.Storage = template.Spec.Storage.DeepCopy()
+	}
+}
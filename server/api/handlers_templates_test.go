@@ -0,0 +1,137 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+	"github.com/qubitquilt/supacontrol/server/internal/k8s"
+)
+
+// newProTemplate returns a template sizing instances large with 50Gi of storage
+func newProTemplate(quota *supacontrolv1alpha1.TemplateQuota) *supacontrolv1alpha1.SupabaseInstanceTemplate {
+	size := resource.MustParse("50Gi")
+	return &supacontrolv1alpha1.SupabaseInstanceTemplate{
+		ObjectMeta: metav1.ObjectMeta{Name: "pro"},
+		Spec: supacontrolv1alpha1.SupabaseInstanceTemplateSpec{
+			DisplayName:  "Pro",
+			ChartVersion: "0.1.3",
+			Resources:    &supacontrolv1alpha1.Resources{Tier: supacontrolv1alpha1.ResourceTierLarge},
+			Storage:      &supacontrolv1alpha1.Storage{Size: &size},
+			Values:       &runtime.RawExtension{Raw: []byte(`{"studio":{"enabled":false}}`)},
+			Quota:        quota,
+		},
+	}
+}
+
+// TestListTemplates tests that templates are listed by name without their chart values
+func TestListTemplates(t *testing.T) {
+	mockCR := &mockCRClient{
+		listTemplatesFunc: func(_ context.Context) (*supacontrolv1alpha1.SupabaseInstanceTemplateList, error) {
+			return &supacontrolv1alpha1.SupabaseInstanceTemplateList{Items: []supacontrolv1alpha1.SupabaseInstanceTemplate{
+				*newProTemplate(&supacontrolv1alpha1.TemplateQuota{MaxInstancesPerUser: 3}),
+				{ObjectMeta: metav1.ObjectMeta{Name: "hobby"}},
+			}}, nil
+		},
+	}
+	handler := NewHandler(nil, &mockDBClient{}, mockCR, nil)
+	c, rec := newTestContext(http.MethodGet, "/api/v1/templates", "")
+	setAuthContext(c, 7, "someone", RoleUser)
+
+	if err := handler.ListTemplates(c); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var resp apitypes.ListTemplatesResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Count != 2 || resp.Templates[0].Name != "hobby" || resp.Templates[1].Name != "pro" {
+		t.Fatalf("expected hobby and pro sorted by name, got %+v", resp.Templates)
+	}
+	if hobby := resp.Templates[0]; hobby.DisplayName != "hobby" || hobby.Quota != nil {
+		t.Errorf("expected hobby to show its name and no quota, got %+v", hobby)
+	}
+	pro := resp.Templates[1]
+	if pro.DisplayName != "Pro" || pro.Resources == nil || pro.Resources.Tier != "large" ||
+		pro.Storage == nil || pro.Storage.Size != "50Gi" || pro.Quota == nil || pro.Quota.MaxInstancesPerUser != 3 {
+		t.Errorf("unexpected pro template %+v", pro)
+	}
+
+	mockCR.listTemplatesFunc = func(_ context.Context) (*supacontrolv1alpha1.SupabaseInstanceTemplateList, error) {
+		return nil, fmt.Errorf("connection refused")
+	}
+	c, _ = newTestContext(http.MethodGet, "/api/v1/templates", "")
+	assertHTTPError(t, handler.ListTemplates(c), http.StatusInternalServerError)
+}
+
+// TestCreateInstance_Template tests creating instances from a template
+func TestCreateInstance_Template(t *testing.T) {
+	fromPro := func(name, owner string) *supacontrolv1alpha1.SupabaseInstance {
+		instance := newOwnedInstance(name, owner)
+		instance.Spec.TemplateRef = "pro"
+		return instance
+	}
+	tests := []struct {
+		name           string
+		body           string
+		quota          *supacontrolv1alpha1.TemplateQuota
+		expectedStatus int
+		expectedTier   supacontrolv1alpha1.ResourceTier
+	}{
+		{name: "applies the template", body: `{"name":"new-app","template":"pro"}`, expectedStatus: http.StatusAccepted, expectedTier: supacontrolv1alpha1.ResourceTierLarge},
+		{name: "request overrides the template", body: `{"name":"new-app","template":"pro","resources":{"tier":"medium"}}`, expectedStatus: http.StatusAccepted, expectedTier: supacontrolv1alpha1.ResourceTierMedium},
+		{name: "unknown template", body: `{"name":"new-app","template":"enterprise"}`, expectedStatus: http.StatusBadRequest},
+		{name: "under the per-user quota", body: `{"name":"new-app","template":"pro"}`, quota: &supacontrolv1alpha1.TemplateQuota{MaxInstancesPerUser: 2}, expectedStatus: http.StatusAccepted, expectedTier: supacontrolv1alpha1.ResourceTierLarge},
+		{name: "per-user quota reached", body: `{"name":"new-app","template":"pro"}`, quota: &supacontrolv1alpha1.TemplateQuota{MaxInstancesPerUser: 1}, expectedStatus: http.StatusForbidden},
+		{name: "installation quota reached", body: `{"name":"new-app","template":"pro"}`, quota: &supacontrolv1alpha1.TemplateQuota{MaxInstances: 2}, expectedStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The caller created my-app from the template, another user other-app
+			mockCR := newQuotaCRClient(fromPro("my-app", "7"), fromPro("other-app", "8"), newOwnedInstance("plain-app", "7"))
+			mockCR.getTemplateFunc = func(_ context.Context, name string) (*supacontrolv1alpha1.SupabaseInstanceTemplate, error) {
+				if name != "pro" {
+					return nil, k8s.ErrTemplateNotFound
+				}
+				return newProTemplate(tt.quota), nil
+			}
+			var created *supacontrolv1alpha1.SupabaseInstance
+			mockCR.createSupabaseInstanceFunc = func(_ context.Context, instance *supacontrolv1alpha1.SupabaseInstance) error {
+				created = instance
+				return nil
+			}
+			handler := NewHandler(nil, &mockDBClient{}, mockCR, &mockK8sClient{clientset: fake.NewSimpleClientset()})
+			c, _ := newTestContext(http.MethodPost, "/api/v1/instances", tt.body)
+			setAuthContext(c, 7, "tester", RoleUser)
+
+			err := handler.CreateInstance(c)
+			if tt.expectedStatus != http.StatusAccepted {
+				assertHTTPError(t, err, tt.expectedStatus)
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			spec := created.Spec
+			if spec.TemplateRef != "pro" || spec.ChartVersion != "0.1.3" {
+				t.Errorf("expected the template and its chart version recorded, got %q and %q", spec.TemplateRef, spec.ChartVersion)
+			}
+			if spec.Resources == nil || spec.Resources.Tier != tt.expectedTier {
+				t.Errorf("expected tier %s, got %+v", tt.expectedTier, spec.Resources)
+			}
+			if spec.Storage == nil || spec.Storage.Size == nil || spec.Storage.Size.String() != "50Gi" {
+				t.Errorf("expected the template's storage, got %+v", spec.Storage)
+			}
+		})
+	}
+}
@@ -0,0 +1,185 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/labstack/echo/v4"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+	"github.com/qubitquilt/supacontrol/server/internal/i18n"
+)
+
+// tunnelUpgrader upgrades tunnel connections to WebSockets. Its default origin check
+// admits clients that send no Origin, such as CLIs, and keeps other sites' pages out.
+var tunnelUpgrader = websocket.Upgrader{}
+
+// WithPortForwarder sets the forwarder carrying database tunnels, which enables them
+func WithPortForwarder(forwarder PortForwarder) HandlerOption {
+	return func(h *Handler) {
+		h.portForwarder = forwarder
+	}
+}
+
+// CreateTunnel opens a time-limited tunnel to an instance database for local tools such
+// as psql (admins and the instance owner only). It returns a signed tunnel path that the
+// same user connects a WebSocket to; every WebSocket carries one Postgres connection.
+func (h *Handler) CreateTunnel(c echo.Context) error {
+	if h.portForwarder == nil {
+		return echo.NewHTTPError(http.StatusServiceUnavailable, "database tunnels are not available")
+	}
+	var req apitypes.CreateTunnelRequest
+	if err := bindRequest(c, &req); err != nil {
+		return err
+	}
+	ttl := apitypes.DefaultTunnelTTL
+	if req.TTL != "" {
+		duration, err := time.ParseDuration(req.TTL)
+		if err != nil || duration <= 0 {
+			return echo.NewHTTPError(http.StatusBadRequest, "ttl must be a positive duration such as 30m")
+		}
+		if duration > apitypes.MaxTunnelTTL {
+			return echo.NewHTTPError(http.StatusBadRequest, i18n.Msg("tunnels can stay open for at most %d hours", int(apitypes.MaxTunnelTTL.Hours())))
+		}
+		ttl = duration
+	}
+
+	name := c.Param("name")
+	instance, err := h.getInstanceOrError(c, name)
+	if err != nil {
+		return err
+	}
+	authCtx := GetAuthContext(c)
+	if !isAdminOrOwner(authCtx, instance) {
+		return echo.NewHTTPError(http.StatusForbidden, "only admins and the instance owner can open database tunnels")
+	}
+	if _, _, _, err := h.databasePod(c, instance); err != nil {
+		return err
+	}
+
+	expiresAt := time.Now().Add(ttl).UTC().Truncate(time.Second)
+	token, err := h.authService.GenerateTunnelToken(authCtx.UserID, name, expiresAt)
+	if err != nil {
+		GetLogger(c).Error("Failed to sign tunnel token", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to open tunnel")
+	}
+
+	// Record the tunnel before handing it out; refuse to open unaudited database access
+	if err := h.dbClient.CreateAuditLog(authCtx.UserID, "instance.tunnel.create", "instance", name, map[string]string{
+		"username":   authCtx.Username,
+		"expires_at": expiresAt.Format(time.RFC3339),
+	}); err != nil {
+		GetLogger(c).Error("Failed to record tunnel creation", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to record audit log")
+	}
+
+	return c.JSON(http.StatusCreated, apitypes.CreateTunnelResponse{
+		Path:      "/api/v1/tunnels/" + token,
+		Token:     token,
+		ExpiresAt: expiresAt,
+	})
+}
+
+// ConnectTunnel upgrades the request to a WebSocket and forwards its binary messages to
+// the Postgres port of the instance's database pod and back. Only the user the tunnel was
+// opened for can connect, and the connection is closed once the tunnel expires.
+func (h *Handler) ConnectTunnel(c echo.Context) error {
+	if h.portForwarder == nil {
+		return echo.NewHTTPError(http.StatusServiceUnavailable, "database tunnels are not available")
+	}
+	claims, err := h.authService.ValidateTunnelToken(c.Param("token"))
+	authCtx := GetAuthContext(c)
+	if err != nil || authCtx == nil || claims.UserID != authCtx.UserID {
+		return echo.NewHTTPError(http.StatusForbidden, "invalid or expired tunnel")
+	}
+	instance, err := h.getInstanceOrError(c, claims.Instance)
+	if err != nil {
+		return err
+	}
+	// Ownership may have changed since the tunnel was opened
+	if !isAdminOrOwner(authCtx, instance) {
+		return echo.NewHTTPError(http.StatusForbidden, "only admins and the instance owner can open database tunnels")
+	}
+	namespace, pod, _, err := h.databasePod(c, instance)
+	if err != nil {
+		return err
+	}
+
+	conn, err := tunnelUpgrader.Upgrade(c.Response(), c.Request(), nil)
+	if err != nil {
+		// The upgrader has already replied with an error
+		GetLogger(c).Warn("Failed to upgrade tunnel connection", "error", err)
+		return nil
+	}
+	defer conn.Close()
+
+	h.recordAudit(c, "instance.tunnel.open", "instance", instance.Name, map[string]string{"pod": pod})
+	started := time.Now()
+	ctx, cancel := context.WithDeadline(c.Request().Context(), claims.ExpiresAt.Time)
+	defer cancel()
+
+	err = h.portForwarder.Forward(ctx, namespace, pod, postgresPort, &websocketStream{conn: conn})
+	reason, code := "closed", websocket.CloseNormalClosure
+	switch {
+	case err != nil:
+		GetLogger(c).Warn("Database tunnel failed", "instance", instance.Name, "error", err)
+		reason, code = "database connection failed", websocket.CloseInternalServerErr
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		reason = "tunnel expired"
+	}
+	_ = conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(time.Second))
+
+	h.recordAudit(c, "instance.tunnel.close", "instance", instance.Name, map[string]string{
+		"reason":   reason,
+		"duration": time.Since(started).Round(time.Second).String(),
+	})
+	return nil
+}
+
+// websocketStream reads and writes the binary messages of a WebSocket as a byte stream.
+// It supports one concurrent reader and one concurrent writer.
+type websocketStream struct {
+	conn    *websocket.Conn
+	message io.Reader
+}
+
+// Read reads from the current binary message, moving on to the next once it is consumed.
+// A normal close by the client ends the stream.
+func (s *websocketStream) Read(p []byte) (int, error) {
+	for {
+		if s.message == nil {
+			messageType, message, err := s.conn.NextReader()
+			if err != nil {
+				if websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+					return 0, io.EOF
+				}
+				return 0, err
+			}
+			if messageType != websocket.BinaryMessage {
+				continue
+			}
+			s.message = message
+		}
+		n, err := s.message.Read(p)
+		if errors.Is(err, io.EOF) {
+			s.message = nil
+			if n == 0 {
+				continue
+			}
+			err = nil
+		}
+		return n, err
+	}
+}
+
+// Write sends p as one binary message
+func (s *websocketStream) Write(p []byte) (int, error) {
+	if err := s.conn.WriteMessage(websocket.BinaryMessage, p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -0,0 +1,229 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/labstack/echo/v4"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+	"github.com/qubitquilt/supacontrol/server/internal/auth"
+)
+
+// newTunnelHandler returns a handler for the my-app instance owned by user 7, whose
+// tunnels are carried by forward
+func newTunnelHandler(authService *auth.Service, pod *corev1.Pod, dbClient *mockDBClient, forward func(ctx context.Context, namespace, pod string, port int, conn io.ReadWriter) error) *Handler {
+	clientset := fake.NewSimpleClientset()
+	if pod != nil {
+		clientset = fake.NewSimpleClientset(pod)
+	}
+	return NewHandler(authService, dbClient, newSuspensionCRClient(nil, newOwnedInstance("my-app", "7")),
+		&mockK8sClient{clientset: clientset}, WithPortForwarder(&mockPortForwarder{forwardFunc: forward}))
+}
+
+// TestCreateTunnel tests the CreateTunnel handler
+func TestCreateTunnel(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           string
+		userID         int64
+		pod            *corev1.Pod
+		auditErr       error
+		expectedStatus int
+		expectedTTL    time.Duration
+	}{
+		{name: "default ttl", body: `{}`, userID: 7, pod: newDatabasePod(), expectedStatus: http.StatusCreated, expectedTTL: time.Hour},
+		{name: "custom ttl", body: `{"ttl":"30m"}`, userID: 7, pod: newDatabasePod(), expectedStatus: http.StatusCreated, expectedTTL: 30 * time.Minute},
+		{name: "invalid ttl", body: `{"ttl":"soon"}`, userID: 7, pod: newDatabasePod(), expectedStatus: http.StatusBadRequest},
+		{name: "ttl too long", body: `{"ttl":"24h"}`, userID: 7, pod: newDatabasePod(), expectedStatus: http.StatusBadRequest},
+		{name: "not the owner", body: `{}`, userID: 8, pod: newDatabasePod(), expectedStatus: http.StatusForbidden},
+		{name: "database not running", body: `{}`, userID: 7, expectedStatus: http.StatusConflict},
+		{name: "audit log unavailable", body: `{}`, userID: 7, pod: newDatabasePod(), auditErr: fmt.Errorf("connection refused"), expectedStatus: http.StatusInternalServerError},
+	}
+
+	authService := auth.NewService("test-secret-key")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var audited []string
+			dbClient := &mockDBClient{
+				createAuditLogFunc: func(_ int64, action, _, _ string, _ map[string]string) error {
+					audited = append(audited, action)
+					return tt.auditErr
+				},
+			}
+			handler := newTunnelHandler(authService, tt.pod, dbClient, nil)
+			c, rec := newTestContext(http.MethodPost, "/api/v1/instances/my-app/tunnel", tt.body)
+			c.SetParamNames("name")
+			c.SetParamValues("my-app")
+			setAuthContext(c, tt.userID, "user", RoleUser)
+
+			err := handler.CreateTunnel(c)
+			if tt.expectedStatus != http.StatusCreated {
+				assertHTTPError(t, err, tt.expectedStatus)
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var resp apitypes.CreateTunnelResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if resp.Path != "/api/v1/tunnels/"+resp.Token {
+				t.Errorf("unexpected tunnel path %q", resp.Path)
+			}
+			if ttl := time.Until(resp.ExpiresAt); ttl > tt.expectedTTL || ttl < tt.expectedTTL-time.Minute {
+				t.Errorf("expected the tunnel to expire in %s, got %s", tt.expectedTTL, ttl)
+			}
+			claims, err := authService.ValidateTunnelToken(resp.Token)
+			if err != nil || claims.UserID != 7 || claims.Instance != "my-app" {
+				t.Errorf("unexpected tunnel token claims %+v: %v", claims, err)
+			}
+			if len(audited) != 1 || audited[0] != "instance.tunnel.create" {
+				t.Errorf("expected the tunnel to be audited, got %v", audited)
+			}
+		})
+	}
+}
+
+// TestCreateTunnel_Unavailable tests that tunnels are refused without a port forwarder
+func TestCreateTunnel_Unavailable(t *testing.T) {
+	handler := NewHandler(nil, &mockDBClient{}, &mockCRClient{}, nil)
+	c, _ := newTestContext(http.MethodPost, "/api/v1/instances/my-app/tunnel", `{}`)
+	assertHTTPError(t, handler.CreateTunnel(c), http.StatusServiceUnavailable)
+}
+
+// startTunnelServer serves ConnectTunnel to user 7 and returns the WebSocket URL of a token
+func startTunnelServer(t *testing.T, handler *Handler) func(token string) string {
+	t.Helper()
+	e := echo.New()
+	e.GET("/api/v1/tunnels/:token", handler.ConnectTunnel, func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			setAuthContext(c, 7, "owner", RoleUser)
+			return next(c)
+		}
+	})
+	server := httptest.NewServer(e)
+	t.Cleanup(server.Close)
+	return func(token string) string {
+		return "ws" + strings.TrimPrefix(server.URL, "http") + "/api/v1/tunnels/" + token
+	}
+}
+
+// TestConnectTunnel tests that WebSocket messages reach the database pod and back, and
+// that opening and closing the connection is audited
+func TestConnectTunnel(t *testing.T) {
+	authService := auth.NewService("test-secret-key")
+	var mu sync.Mutex
+	var audited []string
+	closed := make(chan map[string]string, 1)
+	dbClient := &mockDBClient{
+		createAuditLogFunc: func(_ int64, action, _, _ string, details map[string]string) error {
+			mu.Lock()
+			defer mu.Unlock()
+			audited = append(audited, action)
+			if action == "instance.tunnel.close" {
+				closed <- details
+			}
+			return nil
+		},
+	}
+	handler := newTunnelHandler(authService, newDatabasePod(), dbClient, func(_ context.Context, namespace, pod string, port int, conn io.ReadWriter) error {
+		if namespace != "supa-my-app" || pod != "my-app-supabase-db-0" || port != 5432 {
+			return fmt.Errorf("unexpected forward to %s/%s:%d", namespace, pod, port)
+		}
+		// Echo the client's bytes like a database answering them
+		_, err := io.Copy(conn, conn)
+		return err
+	})
+	tunnelURL := startTunnelServer(t, handler)
+
+	token, err := authService.GenerateTunnelToken(7, "my-app", time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("failed to sign tunnel token: %v", err)
+	}
+	conn, _, err := websocket.DefaultDialer.Dial(tunnelURL(token), nil)
+	if err != nil {
+		t.Fatalf("failed to connect the tunnel: %v", err)
+	}
+	if err := conn.WriteMessage(websocket.BinaryMessage, []byte("SELECT 1")); err != nil {
+		t.Fatalf("failed to write to the tunnel: %v", err)
+	}
+	_, message, err := conn.ReadMessage()
+	if err != nil || string(message) != "SELECT 1" {
+		t.Fatalf("expected the message back, got %q: %v", message, err)
+	}
+	_ = conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+	_ = conn.Close()
+
+	select {
+	case details := <-closed:
+		if details["reason"] != "closed" {
+			t.Errorf("expected a normal close, got %v", details)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the closed tunnel to be audited")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(audited) != 2 || audited[0] != "instance.tunnel.open" {
+		t.Errorf("unexpected audit log %v", audited)
+	}
+}
+
+// TestConnectTunnel_Expires tests that a tunnel is closed once it expires
+func TestConnectTunnel_Expires(t *testing.T) {
+	authService := auth.NewService("test-secret-key")
+	dbClient := &mockDBClient{createAuditLogFunc: func(int64, string, string, string, map[string]string) error { return nil }}
+	handler := newTunnelHandler(authService, newDatabasePod(), dbClient, func(ctx context.Context, _, _ string, _ int, _ io.ReadWriter) error {
+		<-ctx.Done()
+		return nil
+	})
+	tunnelURL := startTunnelServer(t, handler)
+
+	token, err := authService.GenerateTunnelToken(7, "my-app", time.Now().Add(2*time.Second))
+	if err != nil {
+		t.Fatalf("failed to sign tunnel token: %v", err)
+	}
+	conn, _, err := websocket.DefaultDialer.Dial(tunnelURL(token), nil)
+	if err != nil {
+		t.Fatalf("failed to connect the tunnel: %v", err)
+	}
+	defer conn.Close()
+
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, _, err = conn.ReadMessage()
+	if !websocket.IsCloseError(err, websocket.CloseNormalClosure) || !strings.Contains(err.Error(), "tunnel expired") {
+		t.Errorf("expected the tunnel to be closed as expired, got %v", err)
+	}
+}
+
+// TestConnectTunnel_Rejected tests that tunnels of other users and invalid tokens are refused
+func TestConnectTunnel_Rejected(t *testing.T) {
+	authService := auth.NewService("test-secret-key")
+	handler := newTunnelHandler(authService, newDatabasePod(), &mockDBClient{}, nil)
+	tunnelURL := startTunnelServer(t, handler)
+
+	otherUser, err := authService.GenerateTunnelToken(8, "my-app", time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("failed to sign tunnel token: %v", err)
+	}
+	for _, token := range []string{otherUser, "not-a-token"} {
+		_, resp, err := websocket.DefaultDialer.Dial(tunnelURL(token), nil)
+		if err == nil || resp == nil || resp.StatusCode != http.StatusForbidden {
+			t.Errorf("expected the tunnel to be forbidden, got %v", err)
+		}
+	}
+}
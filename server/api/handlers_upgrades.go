@@ -0,0 +1,181 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+	"github.com/qubitquilt/supacontrol/server/internal/db"
+	"github.com/qubitquilt/supacontrol/server/internal/i18n"
+	"github.com/qubitquilt/supacontrol/server/internal/k8s"
+)
+
+const (
+	// defaultUpgradeCanaries is how many instances are upgraded first when no canary count is given
+	defaultUpgradeCanaries = 1
+
+	// maxUpgradeConcurrency caps how many instances may be upgraded at the same time
+	maxUpgradeConcurrency = 10
+)
+
+// newUpgradeResponse builds the progress view of an upgrade run
+func newUpgradeResponse(upgrade *apitypes.Upgrade, targets []*apitypes.UpgradeTarget) apitypes.UpgradeResponse {
+	if targets == nil {
+		targets = []*apitypes.UpgradeTarget{}
+	}
+
+	progress := apitypes.UpgradeProgress{Total: len(targets)}
+	for _, target := range targets {
+		switch target.Status {
+		case apitypes.UpgradeTargetPending:
+			progress.Pending++
+		case apitypes.UpgradeTargetInProgress:
+			progress.InProgress++
+		case apitypes.UpgradeTargetSucceeded:
+			progress.Succeeded++
+		case apitypes.UpgradeTargetFailed:
+			progress.Failed++
+		case apitypes.UpgradeTargetSkipped:
+			progress.Skipped++
+		}
+	}
+
+	return apitypes.UpgradeResponse{
+		Upgrade:  upgrade,
+		Targets:  targets,
+		Progress: progress,
+	}
+}
+
+// CreateUpgrade starts rolling a chart version across the selected instances (operators
+// and admins). The run itself is driven in the background by the upgrade runner.
+func (h *Handler) CreateUpgrade(c echo.Context) error {
+	authCtx := GetAuthContext(c)
+
+	var req apitypes.CreateUpgradeRequest
+	if err := bindRequest(c, &req); err != nil {
+		return err
+	}
+
+	req.ChartVersion = strings.TrimSpace(req.ChartVersion)
+
+	concurrency := req.Concurrency
+	if concurrency == 0 {
+		concurrency = 1
+	}
+	if concurrency < 1 || concurrency > maxUpgradeConcurrency {
+		return echo.NewHTTPError(http.StatusBadRequest, i18n.Msg("concurrency must be between 1 and %d", maxUpgradeConcurrency))
+	}
+
+	canaryCount := defaultUpgradeCanaries
+	if req.CanaryCount != nil {
+		canaryCount = *req.CanaryCount
+	}
+	if canaryCount < 0 || canaryCount > len(req.Instances) {
+		return echo.NewHTTPError(http.StatusBadRequest, "canary count must be between 0 and the number of instances")
+	}
+
+	ctx := c.Request().Context()
+	seen := make(map[string]bool, len(req.Instances))
+	for _, name := range req.Instances {
+		if seen[name] {
+			return echo.NewHTTPError(http.StatusBadRequest, i18n.Msg("instance %s is listed more than once", name))
+		}
+		seen[name] = true
+
+		if _, err := h.crClient.GetSupabaseInstance(ctx, name); err != nil {
+			if errors.Is(err, k8s.ErrInstanceNotFound) {
+				return echo.NewHTTPError(http.StatusBadRequest, i18n.Msg("instance %s not found", name))
+			}
+			GetLogger(c).Error("Failed to get instance", "instance", name, "error", err)
+			return echo.NewHTTPError(http.StatusInternalServerError, "failed to get instance")
+		}
+	}
+
+	upgrade, targets, err := h.dbClient.CreateUpgrade(&apitypes.Upgrade{
+		ChartVersion: req.ChartVersion,
+		Concurrency:  concurrency,
+		MaxFailures:  req.MaxFailures,
+		CreatedBy:    &authCtx.UserID,
+	}, req.Instances, canaryCount)
+	if err != nil {
+		if errors.Is(err, db.ErrUpgradeInProgress) {
+			return echo.NewHTTPError(http.StatusConflict, "an upgrade is already in progress")
+		}
+		GetLogger(c).Error("Failed to create upgrade", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to create upgrade")
+	}
+
+	h.recordAudit(c, "upgrade.create", "upgrade", strconv.FormatInt(upgrade.ID, 10), map[string]string{
+		"chart_version": upgrade.ChartVersion,
+		"instances":     strings.Join(req.Instances, ","),
+	})
+
+	return c.JSON(http.StatusAccepted, newUpgradeResponse(upgrade, targets))
+}
+
+// ListUpgrades lists upgrade runs, newest first (operators and admins)
+func (h *Handler) ListUpgrades(c echo.Context) error {
+
+	upgrades, err := h.dbClient.ListUpgrades()
+	if err != nil {
+		GetLogger(c).Error("Failed to list upgrades", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to list upgrades")
+	}
+	if upgrades == nil {
+		upgrades = []*apitypes.Upgrade{}
+	}
+
+	return c.JSON(http.StatusOK, apitypes.ListUpgradesResponse{
+		Upgrades: upgrades,
+		Count:    len(upgrades),
+	})
+}
+
+// GetUpgrade returns the progress of an upgrade run (operators and admins)
+func (h *Handler) GetUpgrade(c echo.Context) error {
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid upgrade ID")
+	}
+
+	upgrade, err := h.dbClient.GetUpgrade(id)
+	if err != nil {
+		GetLogger(c).Error("Failed to get upgrade", "upgrade_id", id, "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to get upgrade")
+	}
+	if upgrade == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "upgrade not found")
+	}
+
+	targets, err := h.dbClient.ListUpgradeTargets(id)
+	if err != nil {
+		GetLogger(c).Error("Failed to list upgrade targets", "upgrade_id", id, "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to get upgrade")
+	}
+
+	return c.JSON(http.StatusOK, newUpgradeResponse(upgrade, targets))
+}
+
+// ListChartVersions returns the Supabase chart versions indexed from the chart
+// repository, newest first
+func (h *Handler) ListChartVersions(c echo.Context) error {
+	versions, err := h.dbClient.ListChartVersions()
+	if err != nil {
+		GetLogger(c).Error("Failed to list chart versions", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to list chart versions")
+	}
+	if versions == nil {
+		versions = []*apitypes.ChartVersion{}
+	}
+
+	return c.JSON(http.StatusOK, apitypes.ListChartVersionsResponse{
+		ChartVersions: versions,
+		Count:         len(versions),
+	})
+}
@@ -0,0 +1,281 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+	"github.com/qubitquilt/supacontrol/server/internal/auth"
+	"github.com/qubitquilt/supacontrol/server/internal/db"
+	"github.com/qubitquilt/supacontrol/server/internal/k8s"
+)
+
+// TestCreateUpgrade tests the CreateUpgrade handler
+func TestCreateUpgrade(t *testing.T) {
+	tests := []struct {
+		name           string
+		role           string
+		body           string
+		createErr      error
+		expectedStatus int
+		expectedCanary int
+	}{
+		{
+			name:           "admin starts upgrade with default canary",
+			role:           "admin",
+			body:           `{"chart_version":"0.2.0","instances":["app-a","app-b"]}`,
+			expectedStatus: http.StatusAccepted,
+			expectedCanary: 1,
+		},
+		{
+			name:           "explicit zero canaries",
+			role:           "admin",
+			body:           `{"chart_version":"0.2.0","instances":["app-a","app-b"],"canary_count":0,"concurrency":2}`,
+			expectedStatus: http.StatusAccepted,
+		},
+		{
+			name:           "missing chart version",
+			role:           "admin",
+			body:           `{"instances":["app-a"]}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "no instances",
+			role:           "admin",
+			body:           `{"chart_version":"0.2.0"}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "concurrency too high",
+			role:           "admin",
+			body:           `{"chart_version":"0.2.0","instances":["app-a"],"concurrency":50}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "negative failure threshold",
+			role:           "admin",
+			body:           `{"chart_version":"0.2.0","instances":["app-a"],"max_failures":-1}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "more canaries than instances",
+			role:           "admin",
+			body:           `{"chart_version":"0.2.0","instances":["app-a"],"canary_count":2}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "duplicate instance",
+			role:           "admin",
+			body:           `{"chart_version":"0.2.0","instances":["app-a","app-a"]}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "unknown instance",
+			role:           "admin",
+			body:           `{"chart_version":"0.2.0","instances":["app-a","missing"]}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "upgrade already running",
+			role:           "admin",
+			body:           `{"chart_version":"0.2.0","instances":["app-a"]}`,
+			createErr:      db.ErrUpgradeInProgress,
+			expectedStatus: http.StatusConflict,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotCanary int
+			mockDB := &mockDBClient{
+				createUpgradeFunc: func(upgrade *apitypes.Upgrade, names []string, canaryCount int) (*apitypes.Upgrade, []*apitypes.UpgradeTarget, error) {
+					if tt.createErr != nil {
+						return nil, nil, tt.createErr
+					}
+					gotCanary = canaryCount
+					upgrade.ID = 5
+					upgrade.Status = apitypes.UpgradeStatusRunning
+					targets := make([]*apitypes.UpgradeTarget, 0, len(names))
+					for i, name := range names {
+						targets = append(targets, &apitypes.UpgradeTarget{
+							UpgradeID:    upgrade.ID,
+							InstanceName: name,
+							Canary:       i < canaryCount,
+							Status:       apitypes.UpgradeTargetPending,
+						})
+					}
+					return upgrade, targets, nil
+				},
+				createAuditLogFunc: func(int64, string, string, string, map[string]string) error {
+					return nil
+				},
+			}
+			mockCR := &mockCRClient{
+				getSupabaseInstanceFunc: func(_ context.Context, name string) (*supacontrolv1alpha1.SupabaseInstance, error) {
+					if name == "missing" {
+						return nil, k8s.ErrInstanceNotFound
+					}
+					return newOwnedInstance(name, "1"), nil
+				},
+			}
+
+			handler := NewHandler(auth.NewService("test-secret"), mockDB, mockCR, nil)
+			c, rec := newTestContext(http.MethodPost, "/api/v1/upgrades", tt.body)
+			setAuthContext(c, 1, "tester", tt.role)
+
+			err := handler.CreateUpgrade(c)
+			if tt.expectedStatus != http.StatusAccepted {
+				assertHTTPError(t, err, tt.expectedStatus)
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if rec.Code != http.StatusAccepted {
+				t.Fatalf("expected status %d, got %d", http.StatusAccepted, rec.Code)
+			}
+			if gotCanary != tt.expectedCanary {
+				t.Errorf("expected %d canaries, got %d", tt.expectedCanary, gotCanary)
+			}
+
+			var resp apitypes.UpgradeResponse
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if resp.Progress.Total != 2 || resp.Progress.Pending != 2 {
+				t.Errorf("unexpected progress: %+v", resp.Progress)
+			}
+		})
+	}
+}
+
+// TestGetUpgrade tests the GetUpgrade handler
+func TestGetUpgrade(t *testing.T) {
+	tests := []struct {
+		name           string
+		id             string
+		role           string
+		expectedStatus int
+	}{
+		{
+			name:           "returns progress",
+			id:             "5",
+			role:           "admin",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "invalid ID",
+			id:             "abc",
+			role:           "admin",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "not found",
+			id:             "6",
+			role:           "admin",
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockDB := &mockDBClient{
+				getUpgradeFunc: func(id int64) (*apitypes.Upgrade, error) {
+					if id != 5 {
+						return nil, nil
+					}
+					return &apitypes.Upgrade{ID: id, ChartVersion: "0.2.0", Status: apitypes.UpgradeStatusRunning}, nil
+				},
+				listUpgradeTargetsFunc: func(upgradeID int64) ([]*apitypes.UpgradeTarget, error) {
+					return []*apitypes.UpgradeTarget{
+						{UpgradeID: upgradeID, InstanceName: "app-a", Canary: true, Status: apitypes.UpgradeTargetSucceeded},
+						{UpgradeID: upgradeID, InstanceName: "app-b", Status: apitypes.UpgradeTargetInProgress},
+						{UpgradeID: upgradeID, InstanceName: "app-c", Status: apitypes.UpgradeTargetFailed},
+					}, nil
+				},
+			}
+
+			handler := NewHandler(auth.NewService("test-secret"), mockDB, &mockCRClient{}, nil)
+			c, rec := newTestContext(http.MethodGet, "/api/v1/upgrades/"+tt.id, "")
+			c.SetParamNames("id")
+			c.SetParamValues(tt.id)
+			setAuthContext(c, 1, "tester", tt.role)
+
+			err := handler.GetUpgrade(c)
+			if tt.expectedStatus != http.StatusOK {
+				assertHTTPError(t, err, tt.expectedStatus)
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var resp apitypes.UpgradeResponse
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			want := apitypes.UpgradeProgress{Total: 3, InProgress: 1, Succeeded: 1, Failed: 1}
+			if resp.Progress != want {
+				t.Errorf("expected progress %+v, got %+v", want, resp.Progress)
+			}
+		})
+	}
+}
+
+// TestListChartVersions tests the ListChartVersions handler
+func TestListChartVersions(t *testing.T) {
+	t.Run("indexed versions", func(t *testing.T) {
+		mockDB := &mockDBClient{
+			listChartVersionsFunc: func() ([]*apitypes.ChartVersion, error) {
+				return []*apitypes.ChartVersion{
+					{Version: "0.2.0", Latest: true},
+					{Version: "0.1.0", Position: 1},
+				}, nil
+			},
+		}
+		handler := NewHandler(auth.NewService("test-secret"), mockDB, nil, nil)
+		c, rec := newTestContext(http.MethodGet, "/api/v1/chart-versions", "")
+		setAuthContext(c, 1, "tester", "user")
+
+		if err := handler.ListChartVersions(c); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		var resp apitypes.ListChartVersionsResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if resp.Count != 2 || resp.ChartVersions[0].Version != "0.2.0" || !resp.ChartVersions[0].Latest {
+			t.Errorf("unexpected response: %+v", resp)
+		}
+	})
+
+	t.Run("nothing indexed yet", func(t *testing.T) {
+		mockDB := &mockDBClient{
+			listChartVersionsFunc: func() ([]*apitypes.ChartVersion, error) {
+				return nil, nil
+			},
+		}
+		handler := NewHandler(auth.NewService("test-secret"), mockDB, nil, nil)
+		c, rec := newTestContext(http.MethodGet, "/api/v1/chart-versions", "")
+		setAuthContext(c, 1, "tester", "user")
+
+		if err := handler.ListChartVersions(c); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if body := rec.Body.String(); !strings.Contains(body, `"chart_versions":[]`) {
+			t.Errorf("expected an empty list, got %s", body)
+		}
+	})
+
+	t.Run("database error", func(t *testing.T) {
+		handler := NewHandler(auth.NewService("test-secret"), &mockDBClient{}, nil, nil)
+		c, _ := newTestContext(http.MethodGet, "/api/v1/chart-versions", "")
+		setAuthContext(c, 1, "tester", "user")
+
+		assertHTTPError(t, handler.ListChartVersions(c), http.StatusInternalServerError)
+	})
+}
@@ -0,0 +1,104 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+	"github.com/qubitquilt/supacontrol/server/internal/k8s"
+)
+
+// runningImages returns the image of each Supabase component running in the pods,
+// preferring the image reported in container status over the pod spec
+func runningImages(pods []corev1.Pod) map[string]string {
+	images := make(map[string]string)
+
+	for _, pod := range pods {
+		statusImages := make(map[string]string, len(pod.Status.ContainerStatuses))
+		for _, status := range pod.Status.ContainerStatuses {
+			statusImages[status.Name] = status.Image
+		}
+
+		for _, container := range pod.Spec.Containers {
+			image := container.Image
+			if statusImage := statusImages[container.Name]; statusImage != "" {
+				image = statusImage
+			}
+
+			component, ok := k8s.ComponentForImage(image)
+			if !ok {
+				continue
+			}
+			if _, seen := images[component]; !seen {
+				images[component] = image
+			}
+		}
+	}
+
+	return images
+}
+
+// GetInstanceVersions reports the component versions running in an instance and
+// whether the target chart version ships newer ones
+func (h *Handler) GetInstanceVersions(c echo.Context) error {
+	name := c.Param("name")
+	ctx := c.Request().Context()
+
+	instance, err := h.crClient.GetSupabaseInstance(ctx, name)
+	if err != nil {
+		if errors.Is(err, k8s.ErrInstanceNotFound) {
+			return newAPIError(http.StatusNotFound, apitypes.ErrorCodeInstanceNotFound, "instance not found")
+		}
+		GetLogger(c).Error("Failed to get instance", "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to get instance")
+	}
+
+	namespace := getInstanceNamespace(instance)
+	pods, err := h.k8sClient.GetClientset().CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		GetLogger(c).Error("Failed to list pods", "namespace", namespace, "error", err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to get component versions")
+	}
+
+	resp := apitypes.InstanceVersionsResponse{
+		InstanceName: instance.Spec.ProjectName,
+		ChartVersion: instance.Spec.ChartVersion,
+	}
+
+	// Look up the versions shipped by the target chart; the report is still useful without them
+	var target map[string]string
+	if h.chartResolver == nil {
+		resp.Warning = localize(c, "target chart versions are unavailable")
+	} else if chart, err := h.chartResolver.ComponentVersions(ctx, instance.Spec.ChartVersion); err != nil {
+		GetLogger(c).Warn("Failed to resolve chart component versions", "chart_version", instance.Spec.ChartVersion, "error", err)
+		resp.Warning = localize(c, "target chart versions are unavailable")
+	} else {
+		resp.ChartVersion = chart.ChartVersion
+		target = chart.Versions
+	}
+
+	feed := h.loadAdvisories(c)
+	images := runningImages(pods.Items)
+	for _, component := range k8s.Components() {
+		version := apitypes.ComponentVersion{
+			Component:     component,
+			Image:         images[component],
+			TargetVersion: target[component],
+		}
+		if version.Image != "" {
+			_, version.CurrentVersion = k8s.ParseImage(version.Image)
+			version.Advisories = matchAdvisories(feed, component, version.Image)
+		}
+		version.UpdateAvailable = k8s.IsUpdateAvailable(version.CurrentVersion, version.TargetVersion)
+		if version.UpdateAvailable {
+			resp.UpdatesAvailable = true
+		}
+		resp.Components = append(resp.Components, version)
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
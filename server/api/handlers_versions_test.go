@@ -0,0 +1,150 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+	"github.com/qubitquilt/supacontrol/server/internal/k8s"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// newComponentPod returns a pod in the instance namespace running a single image
+func newComponentPod(name, image string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "supa-my-app",
+		},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{Name: name, Image: image}},
+		},
+	}
+}
+
+// TestGetInstanceVersions tests the GetInstanceVersions handler
+func TestGetInstanceVersions(t *testing.T) {
+	pods := []corev1.Pod{
+		*newComponentPod("db", "supabase/postgres:15.1.0.147"),
+		*newComponentPod("auth", "supabase/gotrue:v2.151.0"),
+		*newComponentPod("rest", "postgrest/postgrest:v12.2.0"),
+		*newComponentPod("kong", "kong:2.8.1"),
+		*newComponentPod("metrics", "prom/node-exporter:v1.8.0"),
+	}
+
+	tests := []struct {
+		name              string
+		resolver          ChartVersionResolver
+		expectedUpdates   bool
+		expectedWarning   bool
+		expectedTargets   map[string]string
+		expectedAvailable map[string]bool
+	}{
+		{
+			name: "reports available updates",
+			resolver: &mockChartResolver{
+				componentVersionsFunc: func(_ context.Context, chartVersion string) (*k8s.ChartComponents, error) {
+					return &k8s.ChartComponents{
+						ChartVersion: "0.2.0",
+						Versions: map[string]string{
+							apitypes.ComponentPostgres:  "15.1.0.147",
+							apitypes.ComponentGoTrue:    "v2.160.0",
+							apitypes.ComponentPostgREST: "v12.2.0",
+							apitypes.ComponentKong:      "2.8.1",
+							apitypes.ComponentStudio:    "20240326-5e5586d",
+						},
+					}, nil
+				},
+			},
+			expectedUpdates: true,
+			expectedTargets: map[string]string{apitypes.ComponentGoTrue: "v2.160.0"},
+			expectedAvailable: map[string]bool{
+				apitypes.ComponentPostgres: false,
+				apitypes.ComponentGoTrue:   true,
+				apitypes.ComponentStudio:   false,
+			},
+		},
+		{
+			name: "chart lookup failure still reports running versions",
+			resolver: &mockChartResolver{
+				componentVersionsFunc: func(context.Context, string) (*k8s.ChartComponents, error) {
+					return nil, fmt.Errorf("repository unreachable")
+				},
+			},
+			expectedWarning: true,
+		},
+		{
+			name:            "no resolver configured",
+			expectedWarning: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockCR := &mockCRClient{
+				getSupabaseInstanceFunc: func(_ context.Context, name string) (*supacontrolv1alpha1.SupabaseInstance, error) {
+					return newOwnedInstance(name, "7"), nil
+				},
+			}
+			clientset := fake.NewSimpleClientset(&corev1.PodList{Items: pods})
+
+			var opts []HandlerOption
+			if tt.resolver != nil {
+				opts = append(opts, WithChartResolver(tt.resolver))
+			}
+			handler := NewHandler(nil, &mockDBClient{}, mockCR, &mockK8sClient{clientset: clientset}, opts...)
+
+			c, rec := newTestContext(http.MethodGet, "/api/v1/instances/my-app/versions", "")
+			c.SetParamNames("name")
+			c.SetParamValues("my-app")
+			setAuthContext(c, 7, "tester", "user")
+
+			if err := handler.GetInstanceVersions(c); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var resp apitypes.InstanceVersionsResponse
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+
+			if resp.UpdatesAvailable != tt.expectedUpdates {
+				t.Errorf("expected updates_available=%t, got %t", tt.expectedUpdates, resp.UpdatesAvailable)
+			}
+			if (resp.Warning != "") != tt.expectedWarning {
+				t.Errorf("unexpected warning %q", resp.Warning)
+			}
+			if len(resp.Components) != len(k8s.Components()) {
+				t.Fatalf("expected %d components, got %d", len(k8s.Components()), len(resp.Components))
+			}
+
+			byComponent := make(map[string]apitypes.ComponentVersion)
+			for _, component := range resp.Components {
+				byComponent[component.Component] = component
+			}
+
+			if got := byComponent[apitypes.ComponentPostgres].CurrentVersion; got != "15.1.0.147" {
+				t.Errorf("expected postgres version 15.1.0.147, got %q", got)
+			}
+			if got := byComponent[apitypes.ComponentStudio].CurrentVersion; got != "" {
+				t.Errorf("expected studio not running, got %q", got)
+			}
+			for component, target := range tt.expectedTargets {
+				if got := byComponent[component].TargetVersion; got != target {
+					t.Errorf("expected %s target %q, got %q", component, target, got)
+				}
+			}
+			for component, available := range tt.expectedAvailable {
+				if got := byComponent[component].UpdateAvailable; got != available {
+					t.Errorf("expected %s update_available=%t, got %t", component, available, got)
+				}
+			}
+		})
+	}
+}
@@ -2,13 +2,21 @@ package api
 
 import (
 	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
 	"time"
 
 	"k8s.io/client-go/kubernetes"
 
 	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
 	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+	"github.com/qubitquilt/supacontrol/server/internal/advisories"
 	"github.com/qubitquilt/supacontrol/server/internal/db"
+	"github.com/qubitquilt/supacontrol/server/internal/k8s"
+	"github.com/qubitquilt/supacontrol/server/internal/sso"
+	"github.com/qubitquilt/supacontrol/server/internal/storageapi"
 )
 
 // DBClient defines the database operations needed by API handlers
@@ -17,6 +25,11 @@ type DBClient interface {
 	// User operations
 	GetUserByUsername(username string) (*db.User, error)
 	GetUserByID(id int64) (*db.User, error)
+	GetUserByEmail(email string) (*db.User, error)
+	CreateUser(username, passwordHash, role string) (*db.User, error)
+	CreateExternalUser(username, authProvider, role string) (*db.User, error)
+	UpdateUserRole(id int64, role string) error
+	UpdateUserPassword(id int64, passwordHash string) error
 
 	// API key operations
 	CreateAPIKey(userID int64, name, keyHash string, expiresAt *time.Time) (*apitypes.APIKey, error)
@@ -24,18 +37,99 @@ type DBClient interface {
 	ListAllAPIKeys() ([]*apitypes.APIKey, error)
 	GetAPIKeyByID(id int64) (*apitypes.APIKey, error)
 	DeleteAPIKey(id int64) error
+	RotateAPIKey(id int64, keyHash string, previousValidUntil *time.Time) (*apitypes.APIKey, error)
 	GetAPIKeyByHash(keyHash string) (*apitypes.APIKey, error)
 	UpdateAPIKeyLastUsed(id int64) error
+
+	// Audit log operations
+	CreateAuditLog(userID int64, action, resourceType, resourceID string, details map[string]string) error
+
+	// Team operations
+	CreateTeam(name string, createdBy int64) (*apitypes.Team, error)
+	GetTeamByID(id int64) (*apitypes.Team, error)
+	ListAllTeams() ([]*apitypes.Team, error)
+	ListTeamsByUser(userID int64) ([]*apitypes.Team, error)
+	GetTeamMember(teamID, userID int64) (*apitypes.TeamMember, error)
+
+	// Team invitation operations
+	CreateTeamInvitation(teamID int64, email, role string, invitedBy int64, expiresAt time.Time) (*apitypes.TeamInvitation, error)
+	GetTeamInvitationByID(id int64) (*apitypes.TeamInvitation, error)
+	ListTeamInvitations(teamID int64) ([]*apitypes.TeamInvitation, error)
+	RevokeTeamInvitation(id int64) error
+	ExtendTeamInvitation(id int64, expiresAt time.Time) (*apitypes.TeamInvitation, error)
+	AcceptTeamInvitation(id, userID int64) (*apitypes.TeamMember, error)
+
+	// User preference operations
+	GetUserPreferences(userID int64) (*apitypes.UserPreferences, error)
+	UpsertUserPreferences(userID int64, preferences json.RawMessage) (*apitypes.UserPreferences, error)
+
+	// Upgrade operations
+	CreateUpgrade(upgrade *apitypes.Upgrade, instanceNames []string, canaryCount int) (*apitypes.Upgrade, []*apitypes.UpgradeTarget, error)
+	GetUpgrade(id int64) (*apitypes.Upgrade, error)
+	ListUpgrades() ([]*apitypes.Upgrade, error)
+	ListUpgradeTargets(upgradeID int64) ([]*apitypes.UpgradeTarget, error)
+
+	// Instance metadata operations
+	ListInstanceMetadata(userID int64, instances []db.InstanceRef) (map[string]*db.InstanceMetadata, error)
+	SetInstanceNotes(instance db.InstanceRef, notes string, updatedBy int64) error
+	SetInstanceFavorite(userID int64, instance db.InstanceRef, favorite bool) error
+
+	// Chart version catalog operations
+	ListChartVersions() ([]*apitypes.ChartVersion, error)
+
+	// Instance record operations
+	ListInstanceRecords(filter db.InstanceRecordFilter) ([]*apitypes.InstanceRecord, error)
+
+	// Quota operations
+	GetQuota(userID *int64) (*apitypes.Quota, error)
+	SetQuota(quota *apitypes.Quota) (*apitypes.Quota, error)
+	DeleteQuota(userID int64) error
+
+	// Backup operations
+	CreateBackup(instanceName, objectKey, keyID string, sizeBytes int64, createdBy *int64, createdAt time.Time) (*apitypes.Backup, error)
+	GetBackup(id int64) (*apitypes.Backup, error)
+	ListBackups(instanceName string) ([]*apitypes.Backup, error)
+	SetBackupHeld(id int64, held bool) (*apitypes.Backup, error)
+	GetBackupRetentionPolicy(instanceName string) (*apitypes.BackupRetentionPolicy, error)
+	SetBackupRetentionPolicy(instanceName string, keepDaily, keepWeekly, keepMonthly int) (*apitypes.BackupRetentionPolicy, error)
+
+	// Benchmark operations
+	CreateBenchmark(benchmark *apitypes.Benchmark) (*apitypes.Benchmark, error)
+	ListBenchmarks(instanceName string) ([]*apitypes.Benchmark, error)
+	FinishBenchmark(benchmark *apitypes.Benchmark) error
+
+	// Operation tracking
+	CreateOperation(operationType, instanceName, instanceUID string, createdBy *int64) (*apitypes.Operation, error)
+	GetOperation(id int64) (*apitypes.Operation, error)
+	FinishOperation(operation *apitypes.Operation) error
+
+	// Studio session operations
+	RedeemStudioSession(jti string, expiresAt time.Time) (bool, error)
+
+	// Connection operations
+	CreateConnection(source, target string, requestedBy int64, approveSource, approveTarget bool) (*apitypes.Connection, error)
+	GetConnection(id int64) (*apitypes.Connection, error)
+	ListConnections() ([]*apitypes.Connection, error)
+	ApproveConnection(id int64, approveSource, approveTarget bool) (*apitypes.Connection, error)
+	ActivateConnection(id int64) error
+	RevokeConnection(id int64) error
 }
 
-// CRClient defines the Kubernetes Custom Resource operations needed by API handlers
+// CRClient defines the Kubernetes Custom Resource operations needed by API handlers.
+// Missing and already existing instances are reported as k8s.ErrInstanceNotFound and
+// k8s.ErrAlreadyExists.
 // This interface allows for easy mocking in tests
 type CRClient interface {
 	CreateSupabaseInstance(ctx context.Context, instance *supacontrolv1alpha1.SupabaseInstance) error
 	GetSupabaseInstance(ctx context.Context, name string) (*supacontrolv1alpha1.SupabaseInstance, error)
 	ListSupabaseInstances(ctx context.Context) (*supacontrolv1alpha1.SupabaseInstanceList, error)
+	ListSupabaseInstancesWithOptions(ctx context.Context, opts k8s.ListOptions) (*supacontrolv1alpha1.SupabaseInstanceList, error)
 	UpdateSupabaseInstance(ctx context.Context, instance *supacontrolv1alpha1.SupabaseInstance) error
+	PatchSupabaseInstance(ctx context.Context, name string, mutate func(*supacontrolv1alpha1.SupabaseInstance) error) (*supacontrolv1alpha1.SupabaseInstance, error)
+	UpdateSupabaseInstanceStatus(ctx context.Context, instance *supacontrolv1alpha1.SupabaseInstance) error
 	DeleteSupabaseInstance(ctx context.Context, name string) error
+	ListSupabaseInstanceTemplates(ctx context.Context) (*supacontrolv1alpha1.SupabaseInstanceTemplateList, error)
+	GetSupabaseInstanceTemplate(ctx context.Context, name string) (*supacontrolv1alpha1.SupabaseInstanceTemplate, error)
 }
 
 // K8sClient defines the Kubernetes operations needed by API handlers
@@ -43,3 +137,77 @@ type CRClient interface {
 type K8sClient interface {
 	GetClientset() kubernetes.Interface
 }
+
+// ChartVersionResolver looks up the published Supabase chart versions and the component
+// versions each of them ships
+// This interface allows for easy mocking in tests
+type ChartVersionResolver interface {
+	ComponentVersions(ctx context.Context, chartVersion string) (*k8s.ChartComponents, error)
+	ChartVersions(ctx context.Context) ([]string, error)
+}
+
+// ChartCatalog reports the newest Supabase chart version indexed from the chart repository
+// This interface allows for easy mocking in tests
+type ChartCatalog interface {
+	LatestChartVersion() string
+}
+
+// ReleasePreviewer renders a chart upgrade of a Helm release without applying it
+// This interface allows for easy mocking in tests
+type ReleasePreviewer interface {
+	PreviewUpgrade(ctx context.Context, namespace, releaseName, chartVersion string, values map[string]interface{}) (*k8s.ReleasePreview, error)
+}
+
+// AdvisorySource provides the current security advisory feed
+// This interface allows for easy mocking in tests
+type AdvisorySource interface {
+	Advisories(ctx context.Context) ([]advisories.Advisory, error)
+}
+
+// UsageMetricsSource reports the resource usage of the pods and volumes in a namespace
+// This interface allows for easy mocking in tests
+type UsageMetricsSource interface {
+	Name() string
+	NamespaceUsage(ctx context.Context, namespace string) (*k8s.NamespaceUsage, error)
+}
+
+// ErrorSummarySource reports the errors recently found in an instance's component logs
+// This interface allows for easy mocking in tests
+type ErrorSummarySource interface {
+	Summary(instance string) *apitypes.InstanceErrorSummary
+}
+
+// APIKeyUsageRecorder notes API key uses, to be written to the database later
+// This interface allows for easy mocking in tests
+type APIKeyUsageRecorder interface {
+	Record(id int64)
+}
+
+// SAMLServiceProvider authenticates users through SAML single sign-on
+// This interface allows for easy mocking in tests
+type SAMLServiceProvider interface {
+	Metadata() ([]byte, error)
+	AuthenticationRequest() (redirect *url.URL, requestID string, err error)
+	ParseResponse(r *http.Request, requestIDs []string) (*sso.Identity, error)
+}
+
+// PodExecutor runs commands in the containers of instance pods
+// This interface allows for easy mocking in tests
+type PodExecutor interface {
+	Exec(ctx context.Context, namespace, pod, container string, command []string, stdin string) (string, error)
+}
+
+// StorageAPI manages the buckets of instance storage services, authenticating with the
+// instance's service-role key
+// This interface allows for easy mocking in tests
+type StorageAPI interface {
+	ListBuckets(ctx context.Context, endpoint, serviceRoleKey string) ([]storageapi.Bucket, error)
+	CreateBucket(ctx context.Context, endpoint, serviceRoleKey string, bucket storageapi.Bucket) error
+	DeleteBucket(ctx context.Context, endpoint, serviceRoleKey, id string) error
+}
+
+// PortForwarder connects to ports of instance pods
+// This interface allows for easy mocking in tests
+type PortForwarder interface {
+	Forward(ctx context.Context, namespace, pod string, port int, conn io.ReadWriter) error
+}
@@ -2,6 +2,7 @@ package api
 
 import (
 	"context"
+	"errors"
 	"log/slog"
 	"net/http"
 	"strconv"
@@ -11,9 +12,12 @@ import (
 	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
 	"github.com/prometheus/client_golang/prometheus"
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
 	"github.com/qubitquilt/supacontrol/server/internal/auth"
 	"github.com/qubitquilt/supacontrol/server/internal/db"
+	"github.com/qubitquilt/supacontrol/server/internal/i18n"
 	"github.com/qubitquilt/supacontrol/server/internal/metrics"
+	"github.com/qubitquilt/supacontrol/server/internal/validate"
 )
 
 // loggerKey is a private type for context keys to prevent collisions
@@ -27,8 +31,23 @@ type AuthContext struct {
 	IsAPIKey bool
 }
 
+// passwordChangeRoutes are the only routes open to users who must change their password
+var passwordChangeRoutes = map[string]bool{
+	http.MethodGet + " /api/v1/auth/me":       true,
+	http.MethodPut + " /api/v1/auth/password": true,
+}
+
+// requirePasswordChange blocks users who must change their password from every route but
+// those changing it
+func requirePasswordChange(c echo.Context, user *db.User) error {
+	if user.MustChangePassword && !passwordChangeRoutes[c.Request().Method+" "+c.Path()] {
+		return echo.NewHTTPError(http.StatusForbidden, "password change required")
+	}
+	return nil
+}
+
 // AuthMiddleware creates middleware for authentication
-func AuthMiddleware(authService *auth.Service, dbClient *db.Client) echo.MiddlewareFunc {
+func AuthMiddleware(authService *auth.Service, dbClient *db.Client, apiKeyUsage APIKeyUsageRecorder) echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
 			authHeader := c.Request().Header.Get("Authorization")
@@ -45,7 +64,7 @@ func AuthMiddleware(authService *auth.Service, dbClient *db.Client) echo.Middlew
 
 			// Try API key first (starts with "sk_")
 			if strings.HasPrefix(token, "sk_") {
-				return authenticateAPIKey(c, next, authService, dbClient, token)
+				return authenticateAPIKey(c, next, authService, dbClient, apiKeyUsage, token)
 			}
 
 			// Otherwise, try JWT
@@ -55,7 +74,7 @@ func AuthMiddleware(authService *auth.Service, dbClient *db.Client) echo.Middlew
 }
 
 // authenticateAPIKey authenticates using an API key
-func authenticateAPIKey(c echo.Context, next echo.HandlerFunc, authService *auth.Service, dbClient *db.Client, apiKey string) error {
+func authenticateAPIKey(c echo.Context, next echo.HandlerFunc, authService *auth.Service, dbClient *db.Client, apiKeyUsage APIKeyUsageRecorder, apiKey string) error {
 	// Hash the API key
 	keyHash, err := authService.HashAPIKey(apiKey)
 	if err != nil {
@@ -81,13 +100,20 @@ func authenticateAPIKey(c echo.Context, next echo.HandlerFunc, authService *auth
 	if user == nil {
 		return echo.NewHTTPError(http.StatusUnauthorized, "user not found")
 	}
+	if err := requirePasswordChange(c, user); err != nil {
+		return err
+	}
 
-	// Update last used timestamp (async, don't wait)
-	go func() {
-		if err := dbClient.UpdateAPIKeyLastUsed(apiKeyRecord.ID); err != nil {
-			slog.Error("Failed to update API key last used timestamp", "api_key_id", apiKeyRecord.ID, "error", err)
-		}
-	}()
+	// Update last used timestamp: batched by the recorder, otherwise async without waiting
+	if apiKeyUsage != nil {
+		apiKeyUsage.Record(apiKeyRecord.ID)
+	} else {
+		go func() {
+			if err := dbClient.UpdateAPIKeyLastUsed(apiKeyRecord.ID); err != nil {
+				slog.Error("Failed to update API key last used timestamp", "api_key_id", apiKeyRecord.ID, "error", err)
+			}
+		}()
+	}
 
 	// Set auth context
 	c.Set("auth", &AuthContext{
@@ -116,6 +142,9 @@ func authenticateJWT(c echo.Context, next echo.HandlerFunc, authService *auth.Se
 	if user == nil {
 		return echo.NewHTTPError(http.StatusUnauthorized, "user not found")
 	}
+	if err := requirePasswordChange(c, user); err != nil {
+		return err
+	}
 
 	// Set auth context
 	c.Set("auth", &AuthContext{
@@ -137,22 +166,6 @@ func GetAuthContext(c echo.Context) *AuthContext {
 	return auth
 }
 
-// RequireAdmin middleware ensures the user is an admin
-func RequireAdmin(next echo.HandlerFunc) echo.HandlerFunc {
-	return func(c echo.Context) error {
-		authCtx := GetAuthContext(c)
-		if authCtx == nil {
-			return echo.NewHTTPError(http.StatusUnauthorized, "not authenticated")
-		}
-
-		if authCtx.Role != "admin" {
-			return echo.NewHTTPError(http.StatusForbidden, "admin access required")
-		}
-
-		return next(c)
-	}
-}
-
 // CorrelationIDMiddleware generates a unique request ID for each request
 // and adds it to the response header and logger context for tracing
 func CorrelationIDMiddleware() echo.MiddlewareFunc {
@@ -185,6 +198,122 @@ func CorrelationIDMiddleware() echo.MiddlewareFunc {
 	}
 }
 
+// StatusClientClosedRequest is the non-standard status, popularized by nginx, recorded for
+// requests the client abandoned before a response was written
+const StatusClientClosedRequest = 499
+
+// TimeoutMiddleware bounds each request's context, and so the Kubernetes and database
+// calls made with it, to the timeout of its route. Routes missing from routeTimeouts use
+// defaultTimeout; a zero timeout leaves long-lived streams unbounded. Server errors
+// returned after the deadline passed or the client went away become 504 and 499
+// responses, so slow dependencies show up as timeouts rather than internal errors.
+func TimeoutMiddleware(defaultTimeout time.Duration, routeTimeouts map[string]time.Duration) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			timeout, ok := routeTimeouts[c.Path()]
+			if !ok {
+				timeout = defaultTimeout
+			}
+			ctx := c.Request().Context()
+			if timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, timeout)
+				defer cancel()
+				c.SetRequest(c.Request().WithContext(ctx))
+			}
+
+			err := next(c)
+			if err == nil {
+				return nil
+			}
+			var he *echo.HTTPError
+			if errors.As(err, &he) && he.Code < http.StatusInternalServerError {
+				return err
+			}
+			switch {
+			case errors.Is(ctx.Err(), context.DeadlineExceeded):
+				return echo.NewHTTPError(http.StatusGatewayTimeout, "request timed out").SetInternal(err)
+			case errors.Is(ctx.Err(), context.Canceled):
+				return echo.NewHTTPError(StatusClientClosedRequest, "client closed request").SetInternal(err)
+			}
+			return err
+		}
+	}
+}
+
+// LoadSheddingConfig caps the requests handled at once, in a default budget and a smaller
+// one for expensive operations. Zero caps leave a budget unlimited.
+type LoadSheddingConfig struct {
+	MaxRequests          int
+	MaxExpensiveRequests int
+
+	// QueueTimeout is how long a request waits for a slot before it is rejected
+	QueueTimeout time.Duration
+}
+
+// loadBudget holds the slots of one load shedding budget
+type loadBudget struct {
+	name  string
+	slots chan struct{}
+}
+
+// newLoadBudget returns a budget of size slots, or nil for an unlimited one
+func newLoadBudget(name string, size int) *loadBudget {
+	if size <= 0 {
+		return nil
+	}
+	return &loadBudget{name: name, slots: make(chan struct{}, size)}
+}
+
+// LoadSheddingMiddleware admits requests while their budget has free slots. Requests on
+// expensiveRoutes draw from the expensive budget, all others except exemptRoutes, such as
+// long-lived streams, from the default one. A request that finds its budget full waits
+// up to the queue timeout and is then rejected with 503 and Retry-After, so a burst of
+// slow operations cannot degrade every request.
+func LoadSheddingMiddleware(cfg LoadSheddingConfig, expensiveRoutes, exemptRoutes []string) echo.MiddlewareFunc {
+	defaultBudget := newLoadBudget("default", cfg.MaxRequests)
+	expensiveBudget := newLoadBudget("expensive", cfg.MaxExpensiveRequests)
+	budgets := map[string]*loadBudget{}
+	for _, route := range expensiveRoutes {
+		budgets[route] = expensiveBudget
+	}
+	for _, route := range exemptRoutes {
+		budgets[route] = nil
+	}
+	retryAfter := strconv.Itoa(max(1, int(cfg.QueueTimeout.Round(time.Second)/time.Second)))
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			budget, ok := budgets[c.Path()]
+			if !ok {
+				budget = defaultBudget
+			}
+			if budget == nil {
+				return next(c)
+			}
+
+			select {
+			case budget.slots <- struct{}{}:
+			default:
+				// Wait for a slot rather than fail on a momentary spike
+				timer := time.NewTimer(cfg.QueueTimeout)
+				defer timer.Stop()
+				select {
+				case budget.slots <- struct{}{}:
+				case <-timer.C:
+					metrics.APIRequestsShedTotal.WithLabelValues(budget.name).Inc()
+					c.Response().Header().Set("Retry-After", retryAfter)
+					return newAPIError(http.StatusServiceUnavailable, apitypes.ErrorCodeServerBusy, "server is busy, retry later")
+				case <-c.Request().Context().Done():
+					return echo.NewHTTPError(StatusClientClosedRequest, "client closed request")
+				}
+			}
+			defer func() { <-budget.slots }()
+			return next(c)
+		}
+	}
+}
+
 // GetLogger retrieves the structured logger from the request context
 func GetLogger(c echo.Context) *slog.Logger {
 	if logger, ok := c.Request().Context().Value(loggerKey{}).(*slog.Logger); ok {
@@ -256,3 +385,77 @@ func ObserveHistogram(histogram prometheus.Observer) func() {
 		histogram.Observe(time.Since(start).Seconds())
 	}
 }
+
+// LocaleMiddleware negotiates the response locale from the Accept-Language header
+// and stores it in the request context for handlers and the error handler
+func LocaleMiddleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			locale := i18n.Default().Negotiate(c.Request().Header.Get("Accept-Language"))
+			c.Set("locale", locale)
+
+			c.Response().Header().Set("Content-Language", locale)
+			c.Response().Header().Add("Vary", "Accept-Language")
+
+			return next(c)
+		}
+	}
+}
+
+// GetLocale retrieves the negotiated locale for the request
+func GetLocale(c echo.Context) string {
+	if locale, ok := c.Get("locale").(string); ok {
+		return locale
+	}
+	// Fallback when the middleware did not run
+	return i18n.Default().Negotiate(c.Request().Header.Get("Accept-Language"))
+}
+
+// localize translates a catalog message into the request's locale
+func localize(c echo.Context, key string, args ...interface{}) string {
+	return i18n.Default().Translate(GetLocale(c), key, args...)
+}
+
+// LocalizedHTTPErrorHandler renders errors as an apitypes.ErrorResponse, with the
+// message translated into the request's locale, before handing them to the next error
+// handler. Errors that are not HTTP errors become 500 Internal Server Error.
+func LocalizedHTTPErrorHandler(next echo.HTTPErrorHandler) echo.HTTPErrorHandler {
+	return func(err error, c echo.Context) {
+		he, ok := err.(*echo.HTTPError)
+		if !ok {
+			he = &echo.HTTPError{Code: http.StatusInternalServerError, Message: http.StatusText(http.StatusInternalServerError), Internal: err}
+		}
+
+		resp := &apitypes.ErrorResponse{
+			Code:      errorCodeForStatus(he.Code),
+			RequestID: c.Response().Header().Get("X-Request-ID"),
+		}
+		msg := he.Message
+		if coded, ok := msg.(*codedMessage); ok {
+			resp.Code = coded.Code
+			resp.Details = coded.Details
+			msg = coded.Message
+		}
+		switch msg := msg.(type) {
+		case string:
+			resp.Message = localize(c, msg)
+		case *i18n.Message:
+			resp.Message = i18n.Default().Localize(GetLocale(c), msg)
+		case validate.Errors:
+			resp.Code = apitypes.ErrorCodeValidationFailed
+			resp.Message = localize(c, "request validation failed")
+			resp.Errors = localizeFieldErrors(c, msg)
+		case error:
+			resp.Message = msg.Error()
+		default:
+			resp.Message = http.StatusText(he.Code)
+		}
+
+		// The envelope replaces the message; an internal HTTP error would replace it again
+		internal := he.Internal
+		if _, ok := internal.(*echo.HTTPError); ok {
+			internal = nil
+		}
+		next(&echo.HTTPError{Code: he.Code, Message: resp, Internal: internal}, c)
+	}
+}
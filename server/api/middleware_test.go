@@ -1,12 +1,21 @@
 package api
 
 import (
+	"context"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/prometheus/client_golang/prometheus/testutil"
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+	"github.com/qubitquilt/supacontrol/server/internal/i18n"
 	"github.com/qubitquilt/supacontrol/server/internal/metrics"
 	"github.com/stretchr/testify/assert"
 )
@@ -272,3 +281,248 @@ func (m mockHistogramObserver) Observe(v float64) {
 		m.observeFunc(v)
 	}
 }
+
+func TestLocaleMiddleware(t *testing.T) {
+	tests := []struct {
+		name           string
+		acceptLanguage string
+		expected       string
+	}{
+		{name: "defaults to English", acceptLanguage: "", expected: "en"},
+		{name: "negotiates Spanish", acceptLanguage: "es-MX,es;q=0.9,en;q=0.5", expected: "es"},
+		{name: "falls back for unsupported locale", acceptLanguage: "ja-JP", expected: "en"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodGet, "/test", nil)
+			req.Header.Set("Accept-Language", tt.acceptLanguage)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			handler := LocaleMiddleware()(func(c echo.Context) error {
+				assert.Equal(t, tt.expected, GetLocale(c))
+				return c.String(http.StatusOK, "test")
+			})
+
+			assert.NoError(t, handler(c))
+			assert.Equal(t, tt.expected, rec.Header().Get("Content-Language"))
+		})
+	}
+}
+
+func TestLocalizedHTTPErrorHandler(t *testing.T) {
+	tests := []struct {
+		name           string
+		acceptLanguage string
+		err            error
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "translates catalog message",
+			acceptLanguage: "es",
+			err:            echo.NewHTTPError(http.StatusNotFound, "instance not found"),
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"code":"not_found","message":"instancia no encontrada"}`,
+		},
+		{
+			name:           "formats message arguments",
+			acceptLanguage: "de",
+			err:            echo.NewHTTPError(http.StatusBadRequest, i18n.Msg("password must be at least %d characters", 8)),
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"code":"bad_request","message":"das Passwort muss mindestens 8 Zeichen lang sein"}`,
+		},
+		{
+			name:           "keeps English by default",
+			err:            echo.NewHTTPError(http.StatusNotFound, "instance not found"),
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"code":"not_found","message":"instance not found"}`,
+		},
+		{
+			name:           "keeps specific code",
+			acceptLanguage: "es",
+			err:            newAPIError(http.StatusNotFound, apitypes.ErrorCodeInstanceNotFound, "instance not found"),
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"code":"instance_not_found","message":"instancia no encontrada"}`,
+		},
+		{
+			name: "returns details",
+			err: newAPIErrorWithDetails(http.StatusForbidden, apitypes.ErrorCodeQuotaExceeded,
+				i18n.Msg("storage quota of %d GB reached", 10), quotaDetails("user", "max_storage_gb", 10)),
+			expectedStatus: http.StatusForbidden,
+			expectedBody: `{"code":"quota_exceeded","message":"storage quota of 10 GB reached",
+				"details":{"scope":"user","limit":"max_storage_gb","value":"10"}}`,
+		},
+		{
+			name:           "hides other errors",
+			err:            errors.New("connection refused"),
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   `{"code":"internal_error","message":"Internal Server Error"}`,
+		},
+		{
+			name:           "passes through unknown messages",
+			acceptLanguage: "es",
+			err:            echo.NewHTTPError(http.StatusTeapot, "short and stout"),
+			expectedStatus: http.StatusTeapot,
+			expectedBody:   `{"code":"bad_request","message":"short and stout"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodGet, "/test", nil)
+			req.Header.Set("Accept-Language", tt.acceptLanguage)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			LocalizedHTTPErrorHandler(e.DefaultHTTPErrorHandler)(tt.err, c)
+
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+			assert.JSONEq(t, tt.expectedBody, rec.Body.String())
+		})
+	}
+}
+
+// TestLocalizedHTTPErrorHandler_RequestID tests that error responses name the request ID
+// the correlation middleware set
+func TestLocalizedHTTPErrorHandler_RequestID(t *testing.T) {
+	e := echo.New()
+	rec := httptest.NewRecorder()
+	c := e.NewContext(httptest.NewRequest(http.MethodGet, "/test", nil), rec)
+	c.Response().Header().Set("X-Request-ID", "3f0c7f5e")
+
+	LocalizedHTTPErrorHandler(e.DefaultHTTPErrorHandler)(echo.NewHTTPError(http.StatusConflict, "instance is already being purged"), c)
+
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	assert.JSONEq(t, `{"code":"conflict","message":"instance is already being purged","request_id":"3f0c7f5e"}`, rec.Body.String())
+}
+
+func TestTimeoutMiddleware(t *testing.T) {
+	failure := errors.New("kubernetes call failed")
+	tests := []struct {
+		name           string
+		path           string
+		cancel         bool
+		err            error
+		expectDeadline bool
+		expectedStatus int
+	}{
+		{name: "deadline exceeded", path: "/api/v1/instances", err: failure, expectDeadline: true, expectedStatus: http.StatusGatewayTimeout},
+		{name: "client went away", path: "/api/v1/instances", cancel: true, err: failure, expectDeadline: true, expectedStatus: StatusClientClosedRequest},
+		{name: "client error kept", path: "/api/v1/instances", err: echo.NewHTTPError(http.StatusNotFound, "instance not found"), expectDeadline: true, expectedStatus: http.StatusNotFound},
+		{name: "unbounded stream", path: "/api/v1/instances/:name/progress", err: failure, expectedStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			req := httptest.NewRequest(http.MethodGet, "/test", nil).WithContext(ctx)
+			c := e.NewContext(req, httptest.NewRecorder())
+			c.SetPath(tt.path)
+
+			middleware := TimeoutMiddleware(time.Millisecond, map[string]time.Duration{"/api/v1/instances/:name/progress": 0})
+			err := middleware(func(c echo.Context) error {
+				_, hasDeadline := c.Request().Context().Deadline()
+				assert.Equal(t, tt.expectDeadline, hasDeadline)
+				if tt.cancel {
+					cancel()
+				} else if hasDeadline {
+					<-c.Request().Context().Done()
+				}
+				return tt.err
+			})(c)
+
+			code := http.StatusInternalServerError
+			var he *echo.HTTPError
+			if errors.As(err, &he) {
+				code = he.Code
+			}
+			assert.Equal(t, tt.expectedStatus, code)
+		})
+	}
+}
+
+// TestExpensiveRoutesAreRegistered tests that every expensive and streaming route names a
+// registered route, since the middleware matches them by path
+func TestExpensiveRoutesAreRegistered(t *testing.T) {
+	e := echo.New()
+	SetupRouter(e, &Handler{}, nil, nil)
+
+	registered := map[string]bool{}
+	for _, route := range e.Routes() {
+		registered[route.Path] = true
+	}
+	for _, route := range append(append([]string{}, expensiveRoutes...), streamingRoutes...) {
+		assert.True(t, registered[route], "route %s is not registered", route)
+	}
+}
+
+func TestLoadSheddingMiddleware(t *testing.T) {
+	cfg := LoadSheddingConfig{MaxRequests: 1, MaxExpensiveRequests: 1, QueueTimeout: 10 * time.Millisecond}
+	middleware := LoadSheddingMiddleware(cfg, []string{"/api/v1/instances/:name/logs"}, []string{"/api/v1/instances/:name/progress"})
+
+	// Hold the only default slot until released
+	release := make(chan struct{})
+	held := make(chan struct{})
+	go func() {
+		c := echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/test", nil), httptest.NewRecorder())
+		c.SetPath("/api/v1/instances")
+		_ = middleware(func(echo.Context) error {
+			close(held)
+			<-release
+			return nil
+		})(c)
+	}()
+	<-held
+	defer close(release)
+
+	serve := func(path string) (*httptest.ResponseRecorder, error) {
+		rec := httptest.NewRecorder()
+		c := echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/test", nil), rec)
+		c.SetPath(path)
+		return rec, middleware(func(c echo.Context) error { return c.NoContent(http.StatusOK) })(c)
+	}
+
+	rec, err := serve("/api/v1/instances/:name")
+	var he *echo.HTTPError
+	if assert.True(t, errors.As(err, &he)) {
+		assert.Equal(t, http.StatusServiceUnavailable, he.Code)
+	}
+	assert.Equal(t, "1", rec.Header().Get("Retry-After"))
+
+	// Expensive and streaming routes do not share the saturated default budget
+	rec, err = serve("/api/v1/instances/:name/logs")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	_, err = serve("/api/v1/instances/:name/progress")
+	assert.NoError(t, err)
+}
+
+// TestMessagesAreInCatalog ensures every user-facing message in this package has a catalog entry
+func TestMessagesAreInCatalog(t *testing.T) {
+	files, err := filepath.Glob("*.go")
+	if err != nil {
+		t.Fatalf("failed to list source files: %v", err)
+	}
+
+	pattern := regexp.MustCompile(`(?:NewHTTPError\(http\.\w+, |newAPIError(?:WithDetails)?\(http\.\w+, apitypes\.\w+, |localize\(c, |i18n\.Msg\()"((?:[^"\\]|\\.)*)"`)
+	for _, file := range files {
+		if strings.HasSuffix(file, "_test.go") {
+			continue
+		}
+		src, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", file, err)
+		}
+		for _, match := range pattern.FindAllStringSubmatch(string(src), -1) {
+			if !i18n.Default().Has(match[1]) {
+				t.Errorf("%s: message %q is missing from the i18n catalog", file, match[1])
+			}
+		}
+	}
+}
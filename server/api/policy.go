@@ -0,0 +1,144 @@
+package api
+
+import (
+	"net/http"
+	"slices"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Roles a user account can hold
+const (
+	// RoleAdmin may call every endpoint and act on every user's resources
+	RoleAdmin = "admin"
+
+	// RoleOperator runs fleet operations (upgrades, previews, suspensions) but cannot
+	// change quotas or shared profiles
+	RoleOperator = "operator"
+
+	// RoleUser manages their own instances
+	RoleUser = "user"
+
+	// RoleReadOnly can only view resources
+	RoleReadOnly = "readonly"
+
+	// RoleViewer can view instances, their logs and metrics, but cannot create API keys
+	RoleViewer = "viewer"
+)
+
+// Scope is a permission a route requires of the caller's role
+type Scope string
+
+const (
+	// ScopeRead views resources and changes the caller's own settings
+	ScopeRead Scope = "read"
+
+	// ScopeAccount manages the caller's own API keys
+	ScopeAccount Scope = "account"
+
+	// ScopeWrite creates and changes instances, teams and other shared resources
+	ScopeWrite Scope = "write"
+
+	// ScopeOperate runs fleet operations
+	ScopeOperate Scope = "operate"
+
+	// ScopeAdmin changes configuration that applies to every user
+	ScopeAdmin Scope = "admin"
+)
+
+// Policy decides which roles may call which routes. Routes are keyed by method and
+// route pattern, e.g. "PUT /api/v1/quotas/global". Routes without a rule require
+// ScopeRead for GET and HEAD requests and ScopeWrite otherwise.
+type Policy struct {
+	// Roles lists the scopes each role grants. Unknown roles grant nothing.
+	Roles map[string][]Scope
+
+	// Routes lists the scope required by routes that differ from the default
+	Routes map[string]Scope
+}
+
+// DefaultPolicy returns the built-in roles and route rules
+func DefaultPolicy() *Policy {
+	return &Policy{
+		Roles: map[string][]Scope{
+			RoleAdmin:    {ScopeRead, ScopeAccount, ScopeWrite, ScopeOperate, ScopeAdmin},
+			RoleOperator: {ScopeRead, ScopeAccount, ScopeWrite, ScopeOperate},
+			RoleUser:     {ScopeRead, ScopeAccount, ScopeWrite},
+			RoleReadOnly: {ScopeRead, ScopeAccount},
+			RoleViewer:   {ScopeRead},
+		},
+		Routes: map[string]Scope{
+			// Personal settings only affect the caller, so read-only users may change them
+			"POST /api/v1/auth/api-keys":            ScopeAccount,
+			"DELETE /api/v1/auth/api-keys/:id":      ScopeAccount,
+			"POST /api/v1/auth/api-keys/:id/rotate": ScopeAccount,
+			"PUT /api/v1/auth/password":             ScopeRead,
+			"PUT /api/v1/me/preferences":            ScopeRead,
+			"PUT /api/v1/instances/:name/favorite":  ScopeRead,
+
+			// Tunnels carry writes to the database, whichever method connects them
+			"GET /api/v1/tunnels/:token": ScopeWrite,
+
+			"POST /api/v1/instances/:name/preview":   ScopeOperate,
+			"GET /api/v1/instances/:name/export":     ScopeOperate,
+			"POST /api/v1/instances/:name/suspend":   ScopeOperate,
+			"POST /api/v1/instances/:name/unsuspend": ScopeOperate,
+			"POST /api/v1/upgrades":                  ScopeOperate,
+			"GET /api/v1/upgrades":                   ScopeOperate,
+			"GET /api/v1/upgrades/:id":               ScopeOperate,
+
+			"POST /api/v1/profiles":                  ScopeAdmin,
+			"PUT /api/v1/profiles/:name":             ScopeAdmin,
+			"DELETE /api/v1/profiles/:name":          ScopeAdmin,
+			"POST /api/v1/profiles/smtp/:name/test":  ScopeAdmin,
+			"POST /api/v1/instances/:name/benchmark": ScopeAdmin,
+			"POST /api/v1/instances/import":          ScopeAdmin,
+			"PUT /api/v1/quotas/global":              ScopeAdmin,
+			"GET /api/v1/quotas/users/:id":           ScopeAdmin,
+			"PUT /api/v1/quotas/users/:id":           ScopeAdmin,
+			"DELETE /api/v1/quotas/users/:id":        ScopeAdmin,
+		},
+	}
+}
+
+// RequiredScope returns the scope a request to a route pattern requires
+func (p *Policy) RequiredScope(method, path string) Scope {
+	if scope, ok := p.Routes[method+" "+path]; ok {
+		return scope
+	}
+	if method == http.MethodGet || method == http.MethodHead {
+		return ScopeRead
+	}
+	return ScopeWrite
+}
+
+// Allows reports whether a role grants a scope
+func (p *Policy) Allows(role string, scope Scope) bool {
+	return slices.Contains(p.Roles[role], scope)
+}
+
+// PolicyMiddleware rejects requests whose caller's role does not grant the scope their
+// route requires. It must run after AuthMiddleware.
+func PolicyMiddleware(policy *Policy) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			authCtx := GetAuthContext(c)
+			if authCtx == nil {
+				return echo.NewHTTPError(http.StatusUnauthorized, "not authenticated")
+			}
+
+			scope := policy.RequiredScope(c.Request().Method, c.Path())
+			if !policy.Allows(authCtx.Role, scope) {
+				GetLogger(c).Warn("Request denied by policy", "role", authCtx.Role, "scope", scope)
+				return echo.NewHTTPError(http.StatusForbidden, "your role does not permit this action")
+			}
+
+			return next(c)
+		}
+	}
+}
+
+// IsAdmin reports whether the caller may act on every user's resources
+func (a *AuthContext) IsAdmin() bool {
+	return a.Role == RoleAdmin
+}
@@ -0,0 +1,122 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestDefaultPolicyRoutesExist tests that every route rule names a registered route, so
+// a renamed route does not silently fall back to the default scope
+func TestDefaultPolicyRoutesExist(t *testing.T) {
+	e := echo.New()
+	SetupRouter(e, &Handler{}, nil, nil)
+
+	registered := make(map[string]bool)
+	for _, route := range e.Routes() {
+		registered[route.Method+" "+route.Path] = true
+	}
+	for route := range DefaultPolicy().Routes {
+		assert.True(t, registered[route], "policy rule %s does not match a registered route", route)
+	}
+}
+
+// TestPolicyMiddleware tests which roles may call which routes under the default policy
+func TestPolicyMiddleware(t *testing.T) {
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		role           string
+		expectedStatus int
+	}{
+		{name: "user lists instances", method: http.MethodGet, path: "/api/v1/instances", role: RoleUser, expectedStatus: http.StatusOK},
+		{name: "user creates an instance", method: http.MethodPost, path: "/api/v1/instances", role: RoleUser, expectedStatus: http.StatusOK},
+		{name: "read-only user lists instances", method: http.MethodGet, path: "/api/v1/instances", role: RoleReadOnly, expectedStatus: http.StatusOK},
+		{name: "read-only user creates an instance", method: http.MethodPost, path: "/api/v1/instances", role: RoleReadOnly, expectedStatus: http.StatusForbidden},
+		{name: "read-only user stops an instance", method: http.MethodPost, path: "/api/v1/instances/:name/stop", role: RoleReadOnly, expectedStatus: http.StatusForbidden},
+		{name: "read-only user updates preferences", method: http.MethodPut, path: "/api/v1/me/preferences", role: RoleReadOnly, expectedStatus: http.StatusOK},
+		{name: "read-only user creates an API key", method: http.MethodPost, path: "/api/v1/auth/api-keys", role: RoleReadOnly, expectedStatus: http.StatusOK},
+		{name: "viewer lists instances", method: http.MethodGet, path: "/api/v1/instances", role: RoleViewer, expectedStatus: http.StatusOK},
+		{name: "viewer gets an instance", method: http.MethodGet, path: "/api/v1/instances/:name", role: RoleViewer, expectedStatus: http.StatusOK},
+		{name: "viewer views logs", method: http.MethodGet, path: "/api/v1/instances/:name/logs", role: RoleViewer, expectedStatus: http.StatusOK},
+		{name: "viewer views metrics", method: http.MethodGet, path: "/api/v1/instances/:name/metrics", role: RoleViewer, expectedStatus: http.StatusOK},
+		{name: "viewer changes their password", method: http.MethodPut, path: "/api/v1/auth/password", role: RoleViewer, expectedStatus: http.StatusOK},
+		{name: "viewer creates an instance", method: http.MethodPost, path: "/api/v1/instances", role: RoleViewer, expectedStatus: http.StatusForbidden},
+		{name: "viewer deletes an instance", method: http.MethodDelete, path: "/api/v1/instances/:name", role: RoleViewer, expectedStatus: http.StatusForbidden},
+		{name: "viewer starts an instance", method: http.MethodPost, path: "/api/v1/instances/:name/start", role: RoleViewer, expectedStatus: http.StatusForbidden},
+		{name: "viewer stops an instance", method: http.MethodPost, path: "/api/v1/instances/:name/stop", role: RoleViewer, expectedStatus: http.StatusForbidden},
+		{name: "viewer creates an API key", method: http.MethodPost, path: "/api/v1/auth/api-keys", role: RoleViewer, expectedStatus: http.StatusForbidden},
+		{name: "user previews an upgrade", method: http.MethodPost, path: "/api/v1/instances/:name/preview", role: RoleUser, expectedStatus: http.StatusForbidden},
+		{name: "user suspends an instance", method: http.MethodPost, path: "/api/v1/instances/:name/suspend", role: RoleUser, expectedStatus: http.StatusForbidden},
+		{name: "user starts an upgrade", method: http.MethodPost, path: "/api/v1/upgrades", role: RoleUser, expectedStatus: http.StatusForbidden},
+		{name: "user views an upgrade", method: http.MethodGet, path: "/api/v1/upgrades/:id", role: RoleUser, expectedStatus: http.StatusForbidden},
+		{name: "operator starts an upgrade", method: http.MethodPost, path: "/api/v1/upgrades", role: RoleOperator, expectedStatus: http.StatusOK},
+		{name: "operator suspends an instance", method: http.MethodPost, path: "/api/v1/instances/:name/suspend", role: RoleOperator, expectedStatus: http.StatusOK},
+		{name: "operator creates a profile", method: http.MethodPost, path: "/api/v1/profiles", role: RoleOperator, expectedStatus: http.StatusForbidden},
+		{name: "user creates a profile", method: http.MethodPost, path: "/api/v1/profiles", role: RoleUser, expectedStatus: http.StatusForbidden},
+		{name: "user lists profiles", method: http.MethodGet, path: "/api/v1/profiles", role: RoleUser, expectedStatus: http.StatusOK},
+		{name: "user sets a quota", method: http.MethodPut, path: "/api/v1/quotas/users/:id", role: RoleUser, expectedStatus: http.StatusForbidden},
+		{name: "admin sets a quota", method: http.MethodPut, path: "/api/v1/quotas/users/:id", role: RoleAdmin, expectedStatus: http.StatusOK},
+		{name: "unknown role", method: http.MethodGet, path: "/api/v1/instances", role: "guest", expectedStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			e.Add(tt.method, tt.path, func(c echo.Context) error {
+				return c.NoContent(http.StatusOK)
+			}, func(next echo.HandlerFunc) echo.HandlerFunc {
+				return func(c echo.Context) error {
+					setAuthContext(c, 7, "tester", tt.role)
+					return next(c)
+				}
+			}, PolicyMiddleware(DefaultPolicy()))
+
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+		})
+	}
+}
+
+// TestDefaultPolicyViewer tests that viewers are denied every mutating route except the
+// ones changing their own settings
+func TestDefaultPolicyViewer(t *testing.T) {
+	e := echo.New()
+	SetupRouter(e, &Handler{}, nil, nil)
+	personal := map[string]bool{
+		"PUT /api/v1/auth/password":            true,
+		"PUT /api/v1/me/preferences":           true,
+		"PUT /api/v1/instances/:name/favorite": true,
+	}
+
+	policy := DefaultPolicy()
+	mutating := 0
+	for _, route := range e.Routes() {
+		if !strings.HasPrefix(route.Path, "/api/v1/") || route.Method == http.MethodGet || route.Method == http.MethodHead {
+			continue
+		}
+		mutating++
+		key := route.Method + " " + route.Path
+		allowed := policy.Allows(RoleViewer, policy.RequiredScope(route.Method, route.Path))
+		assert.Equal(t, personal[key], allowed, "viewer access to %s", key)
+	}
+	assert.NotZero(t, mutating, "expected mutating routes to be registered")
+}
+
+// TestPolicyMiddleware_Unauthenticated tests that requests without an auth context are rejected
+func TestPolicyMiddleware_Unauthenticated(t *testing.T) {
+	c, _ := newTestContext(http.MethodGet, "/api/v1/instances", "")
+	handler := PolicyMiddleware(DefaultPolicy())(func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	})
+
+	assertHTTPError(t, handler(c), http.StatusUnauthorized)
+}
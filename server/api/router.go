@@ -1,17 +1,47 @@
 package api
 
 import (
+	"time"
+
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/qubitquilt/supacontrol/server/internal/auth"
 	"github.com/qubitquilt/supacontrol/server/internal/db"
+	"github.com/qubitquilt/supacontrol/server/internal/metrics"
 )
 
+// expensiveRoutes stream every container's logs, render a chart, run SQL in an instance's
+// database pod, or copy a whole instance in or out. They get a longer request timeout and
+// a smaller load shedding budget of their own.
+var expensiveRoutes = []string{
+	"/api/v1/instances/:name/logs",
+	"/api/v1/instances/:name/preview",
+	"/api/v1/instances/:name/database/cron-jobs",
+	"/api/v1/instances/:name/database/cron-jobs/:job",
+	"/api/v1/instances/:name/benchmark",
+	"/api/v1/instances/:name/backups",
+	"/api/v1/instances/:name/backups/:id/restore",
+	"/api/v1/instances/:name/export",
+	"/api/v1/instances/import",
+}
+
+// streamingRoutes stay open until the client disconnects, so they are neither timed out
+// nor counted against a load shedding budget
+var streamingRoutes = []string{
+	"/api/v1/instances/:name/progress",
+	"/api/v1/tunnels/:token",
+}
+
 // SetupRouter configures all routes for the API
 func SetupRouter(e *echo.Echo, handler *Handler, authService *auth.Service, dbClient *db.Client) {
+	// Translate error messages into the negotiated locale
+	e.HTTPErrorHandler = LocalizedHTTPErrorHandler(e.DefaultHTTPErrorHandler)
+
 	// Middleware (order matters!)
 	e.Use(CorrelationIDMiddleware()) // Add request ID first
+	e.Use(LocaleMiddleware())        // Negotiate response locale
 	e.Use(MetricsMiddleware())       // Record metrics for all requests
 	e.Use(middleware.Logger())       // Log after correlation ID is set
 	e.Use(middleware.Recover())      // Recover from panics
@@ -19,28 +49,153 @@ func SetupRouter(e *echo.Echo, handler *Handler, authService *auth.Service, dbCl
 
 	// Public routes
 	e.GET("/healthz", handler.HealthCheck)
-	e.GET("/metrics", echo.WrapHandler(promhttp.Handler())) // Prometheus metrics endpoint
+	e.GET("/livez", handler.LivenessCheck)
+	e.GET("/readyz", handler.ReadinessCheck)
+	e.GET("/metrics", echo.WrapHandler(promhttp.InstrumentMetricHandler( // Prometheus metrics endpoint
+		prometheus.DefaultRegisterer, promhttp.HandlerFor(metrics.Gatherer(), promhttp.HandlerOpts{}),
+	)))
+	e.GET("/api/v1/openapi.json", handler.GetOpenAPISpec)
+	e.GET("/api/docs", handler.GetAPIDocs)
 	e.POST("/api/v1/auth/login", handler.Login)
+	e.GET("/api/v1/auth/saml/metadata", handler.GetSAMLMetadata)
+	e.GET("/api/v1/auth/saml/login", handler.SAMLLogin)
+	e.POST("/api/v1/auth/saml/acs", handler.SAMLAssertionConsumer) // Authenticated by the IdP's signature
+	e.POST("/api/v1/invitations/accept", handler.AcceptInvitation)
+	e.POST("/api/v1/webhooks/billing", handler.BillingWebhook) // Authenticated by HMAC signature
+	e.GET("/suspended", handler.SuspendedPage)
+	e.GET("/badges/:name/status.svg", handler.GetStatusBadge)
+	e.GET("/api/v1/studio-sessions/:token", handler.OpenStudioSession) // Authenticated by the signed one-time link
+	e.Any("/api/v1/studio/:name/*", handler.ProxyStudio)               // Authenticated by the session cookie the link sets
 
 	// Authenticated routes
 	api := e.Group("/api/v1")
-	api.Use(AuthMiddleware(authService, dbClient))
+	routeTimeouts := map[string]time.Duration{}
+	for _, route := range expensiveRoutes {
+		routeTimeouts[route] = slowRequestTimeout
+	}
+	for _, route := range streamingRoutes {
+		routeTimeouts[route] = 0
+	}
+	api.Use(LoadSheddingMiddleware(handler.loadShedding, expensiveRoutes, streamingRoutes))
+	api.Use(TimeoutMiddleware(handler.requestTimeout, routeTimeouts))
+	api.Use(AuthMiddleware(authService, dbClient, handler.apiKeyUsage))
+	api.Use(PolicyMiddleware(DefaultPolicy())) // Role-based route authorization
 
 	// Auth endpoints
 	api.GET("/auth/me", handler.GetAuthMe)
+	api.PUT("/auth/password", handler.ChangePassword)
 	api.POST("/auth/api-keys", handler.CreateAPIKey)
 	api.GET("/auth/api-keys", handler.ListAPIKeys)
 	api.DELETE("/auth/api-keys/:id", handler.DeleteAPIKey)
+	api.POST("/auth/api-keys/:id/rotate", handler.RotateAPIKey)
+
+	// User preference endpoints
+	api.GET("/me/preferences", handler.GetMyPreferences)
+	api.PUT("/me/preferences", handler.UpdateMyPreferences)
+
+	// Allowed values for client pickers
+	api.GET("/meta/enums", handler.GetMetaEnums)
 
 	// Instance endpoints
 	api.POST("/instances", handler.CreateInstance)
+	api.POST("/instances/import", handler.ImportInstance)
 	api.GET("/instances", handler.ListInstances)
 	api.GET("/instances/:name", handler.GetInstance)
+	api.PATCH("/instances/:name", handler.UpdateInstance)
 	api.DELETE("/instances/:name", handler.DeleteInstance)
+	api.GET("/instance-history", handler.ListInstanceHistory)
 
 	// Instance lifecycle endpoints
 	api.POST("/instances/:name/start", handler.StartInstance)
 	api.POST("/instances/:name/stop", handler.StopInstance)
 	api.POST("/instances/:name/restart", handler.RestartInstance)
+	api.POST("/instances/:name/retry", handler.RetryInstance)
+	api.POST("/instances/:name/suspend", handler.SuspendInstance)
+	api.POST("/instances/:name/unsuspend", handler.UnsuspendInstance)
+	api.POST("/instances/:name/maintenance", handler.UpdateInstanceMaintenance)
+	api.PUT("/instances/:name/domains", handler.UpdateInstanceDomains)
+	api.PUT("/instances/:name/badge", handler.UpdateStatusBadge)
+	api.PUT("/instances/:name/notes", handler.UpdateInstanceNotes)
+	api.PUT("/instances/:name/favorite", handler.UpdateInstanceFavorite)
+	api.PUT("/instances/:name/storage", handler.ResizeInstanceStorage)
+	api.GET("/instances/:name/storage/buckets", handler.ListBuckets)
+	api.POST("/instances/:name/storage/buckets", handler.CreateBucket)
+	api.DELETE("/instances/:name/storage/buckets/:bucket", handler.DeleteBucket)
+	api.PUT("/instances/:name/read-replicas", handler.UpdateInstanceReadReplicas)
+	api.POST("/instances/:name/postgres-upgrade", handler.UpgradeInstancePostgres)
+	api.PUT("/instances/:name/database-audit", handler.UpdateInstanceDatabaseAudit)
+	api.PUT("/instances/:name/smtp", handler.UpdateInstanceSMTP)
+	api.DELETE("/instances/:name/smtp", handler.DeleteInstanceSMTP)
+	api.PUT("/instances/:name/schedule", handler.UpdateInstanceSchedule)
+	api.DELETE("/instances/:name/schedule", handler.DeleteInstanceSchedule)
+	api.POST("/instances/:name/benchmark", handler.CreateBenchmark)
+	api.GET("/instances/:name/benchmarks", handler.ListInstanceBenchmarks)
+	api.POST("/instances/:name/addons", handler.EnableInstanceAddon)
+	api.DELETE("/instances/:name/addons/:addon", handler.DisableInstanceAddon)
+	api.GET("/addons", handler.ListAddons)
+	api.GET("/templates", handler.ListTemplates)
+	api.GET("/operations/:id", handler.GetOperation)
+	api.PUT("/instances/:name/deletion-protection", handler.UpdateDeletionProtection)
+	api.POST("/instances/:name/extend", handler.ExtendInstance)
+	api.GET("/instances/:name/database/cron-jobs", handler.ListCronJobs)
+	api.POST("/instances/:name/database/cron-jobs", handler.CreateCronJob)
+	api.DELETE("/instances/:name/database/cron-jobs/:job", handler.DeleteCronJob)
+	api.GET("/instances/:name/backups", handler.ListBackups)
+	api.POST("/instances/:name/backups", handler.CreateBackup)
+	api.POST("/instances/:name/backups/:id/restore", handler.RestoreBackup)
+	api.PUT("/instances/:name/backups/:id/hold", handler.UpdateBackupHold)
+	api.GET("/instances/:name/backups/retention", handler.GetBackupRetention)
+	api.PUT("/instances/:name/backups/retention", handler.UpdateBackupRetention)
+	api.POST("/instances/:name/undelete", handler.UndeleteInstance)
+	api.GET("/instances/:name/progress", handler.StreamInstanceProgress)
+	api.POST("/instances/:name/preview", handler.PreviewInstance)
+	api.GET("/instances/:name/export", handler.ExportInstance)
 	api.GET("/instances/:name/logs", handler.GetLogs)
+	api.GET("/instances/:name/errors", handler.GetInstanceErrors)
+	api.GET("/instances/:name/credentials", handler.GetInstanceCredentials)
+	api.POST("/instances/:name/tunnel", handler.CreateTunnel)
+	api.GET("/tunnels/:token", handler.ConnectTunnel)
+	api.POST("/instances/:name/studio-session", handler.CreateStudioSession)
+	api.GET("/instances/:name/versions", handler.GetInstanceVersions)
+	api.GET("/instances/:name/security", handler.GetInstanceSecurityReport)
+	api.GET("/instances/:name/health", handler.GetInstanceHealth)
+	api.GET("/instances/:name/metrics", handler.GetInstanceMetrics)
+	api.GET("/instances/:name/metrics/prometheus", handler.GetInstancePrometheusMetrics)
+
+	// Shared service profile endpoints
+	api.POST("/profiles", handler.CreateProfile)
+	api.GET("/profiles", handler.ListProfiles)
+	api.GET("/profiles/:name", handler.GetProfile)
+	api.PUT("/profiles/:name", handler.UpdateProfile)
+	api.DELETE("/profiles/:name", handler.DeleteProfile)
+	api.POST("/profiles/smtp/:name/test", handler.TestSMTPProfile)
+
+	// Fleet upgrade endpoints
+	api.POST("/upgrades", handler.CreateUpgrade)
+	api.GET("/upgrades", handler.ListUpgrades)
+	api.GET("/upgrades/:id", handler.GetUpgrade)
+
+	// Chart versions indexed from the chart repository
+	api.GET("/chart-versions", handler.ListChartVersions)
+
+	// Quota endpoints
+	api.GET("/quotas", handler.GetQuotas)
+	api.PUT("/quotas/global", handler.UpdateGlobalQuota)
+	api.GET("/quotas/users/:id", handler.GetUserQuotas)
+	api.PUT("/quotas/users/:id", handler.UpdateUserQuota)
+	api.DELETE("/quotas/users/:id", handler.DeleteUserQuota)
+
+	// Team endpoints
+	api.POST("/teams", handler.CreateTeam)
+	api.GET("/teams", handler.ListTeams)
+	api.POST("/teams/:id/invitations", handler.CreateTeamInvitation)
+	api.GET("/teams/:id/invitations", handler.ListTeamInvitations)
+	api.DELETE("/teams/:id/invitations/:invitationId", handler.RevokeTeamInvitation)
+	api.POST("/teams/:id/invitations/:invitationId/resend", handler.ResendTeamInvitation)
+
+	// Connection endpoints
+	api.GET("/connections", handler.ListConnections)
+	api.POST("/connections", handler.CreateConnection)
+	api.POST("/connections/:id/approve", handler.ApproveConnection)
+	api.DELETE("/connections/:id", handler.RevokeConnection)
 }
@@ -2,30 +2,90 @@ package api
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
+	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"strings"
 	"time"
 
 	"github.com/labstack/echo/v4"
 	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
 	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+	"github.com/qubitquilt/supacontrol/server/internal/advisories"
 	"github.com/qubitquilt/supacontrol/server/internal/db"
+	"github.com/qubitquilt/supacontrol/server/internal/k8s"
+	"github.com/qubitquilt/supacontrol/server/internal/sso"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/util/retry"
 )
 
 // mockDBClient is a mock implementation of DBClient for testing
 type mockDBClient struct {
 	getUserByUsernameFunc    func(username string) (*db.User, error)
 	getUserByIDFunc          func(id int64) (*db.User, error)
+	getUserByEmailFunc       func(email string) (*db.User, error)
 	createAPIKeyFunc         func(userID int64, name, keyHash string, expiresAt *time.Time) (*apitypes.APIKey, error)
 	listAPIKeysByUserFunc    func(userID int64) ([]*apitypes.APIKey, error)
 	listAllAPIKeysFunc       func() ([]*apitypes.APIKey, error)
 	getAPIKeyByIDFunc        func(id int64) (*apitypes.APIKey, error)
 	deleteAPIKeyFunc         func(id int64) error
+	rotateAPIKeyFunc         func(id int64, keyHash string, previousValidUntil *time.Time) (*apitypes.APIKey, error)
 	getAPIKeyByHashFunc      func(keyHash string) (*apitypes.APIKey, error)
 	updateAPIKeyLastUsedFunc func(id int64) error
+	createAuditLogFunc       func(userID int64, action, resourceType, resourceID string, details map[string]string) error
+	createUserFunc           func(username, passwordHash, role string) (*db.User, error)
+	createExternalUserFunc   func(username, authProvider, role string) (*db.User, error)
+	updateUserPasswordFunc   func(id int64, passwordHash string) error
+	updateUserRoleFunc       func(id int64, role string) error
+
+	createTeamFunc            func(name string, createdBy int64) (*apitypes.Team, error)
+	getTeamByIDFunc           func(id int64) (*apitypes.Team, error)
+	listAllTeamsFunc          func() ([]*apitypes.Team, error)
+	listTeamsByUserFunc       func(userID int64) ([]*apitypes.Team, error)
+	getTeamMemberFunc         func(teamID, userID int64) (*apitypes.TeamMember, error)
+	createTeamInvitationFunc  func(teamID int64, email, role string, invitedBy int64, expiresAt time.Time) (*apitypes.TeamInvitation, error)
+	getTeamInvitationByIDFunc func(id int64) (*apitypes.TeamInvitation, error)
+	listTeamInvitationsFunc   func(teamID int64) ([]*apitypes.TeamInvitation, error)
+	revokeTeamInvitationFunc  func(id int64) error
+	extendTeamInvitationFunc  func(id int64, expiresAt time.Time) (*apitypes.TeamInvitation, error)
+	acceptTeamInvitationFunc  func(id, userID int64) (*apitypes.TeamMember, error)
+	getUserPreferencesFunc    func(userID int64) (*apitypes.UserPreferences, error)
+	upsertUserPreferencesFunc func(userID int64, preferences json.RawMessage) (*apitypes.UserPreferences, error)
+	createUpgradeFunc         func(upgrade *apitypes.Upgrade, instanceNames []string, canaryCount int) (*apitypes.Upgrade, []*apitypes.UpgradeTarget, error)
+	getUpgradeFunc            func(id int64) (*apitypes.Upgrade, error)
+	listUpgradesFunc          func() ([]*apitypes.Upgrade, error)
+	listUpgradeTargetsFunc    func(upgradeID int64) ([]*apitypes.UpgradeTarget, error)
+	listInstanceMetadataFunc  func(userID int64, instances []db.InstanceRef) (map[string]*db.InstanceMetadata, error)
+	setInstanceNotesFunc      func(instance db.InstanceRef, notes string, updatedBy int64) error
+	setInstanceFavoriteFunc   func(userID int64, instance db.InstanceRef, favorite bool) error
+	listChartVersionsFunc     func() ([]*apitypes.ChartVersion, error)
+	listInstanceRecordsFunc   func(filter db.InstanceRecordFilter) ([]*apitypes.InstanceRecord, error)
+	getQuotaFunc              func(userID *int64) (*apitypes.Quota, error)
+	setQuotaFunc              func(quota *apitypes.Quota) (*apitypes.Quota, error)
+	deleteQuotaFunc           func(userID int64) error
+	createBenchmarkFunc       func(benchmark *apitypes.Benchmark) (*apitypes.Benchmark, error)
+	listBenchmarksFunc        func(instanceName string) ([]*apitypes.Benchmark, error)
+	finishBenchmarkFunc       func(benchmark *apitypes.Benchmark) error
+	createOperationFunc       func(operationType, instanceName, instanceUID string, createdBy *int64) (*apitypes.Operation, error)
+	getOperationFunc          func(id int64) (*apitypes.Operation, error)
+	finishOperationFunc       func(operation *apitypes.Operation) error
+	redeemStudioSessionFunc   func(jti string, expiresAt time.Time) (bool, error)
+	createBackupFunc          func(instanceName, objectKey, keyID string, sizeBytes int64, createdBy *int64, createdAt time.Time) (*apitypes.Backup, error)
+	getBackupFunc             func(id int64) (*apitypes.Backup, error)
+	listBackupsFunc           func(instanceName string) ([]*apitypes.Backup, error)
+	setBackupHeldFunc         func(id int64, held bool) (*apitypes.Backup, error)
+	getBackupRetentionFunc    func(instanceName string) (*apitypes.BackupRetentionPolicy, error)
+	setBackupRetentionFunc    func(instanceName string, keepDaily, keepWeekly, keepMonthly int) (*apitypes.BackupRetentionPolicy, error)
+	createConnectionFunc      func(source, target string, requestedBy int64, approveSource, approveTarget bool) (*apitypes.Connection, error)
+	getConnectionFunc         func(id int64) (*apitypes.Connection, error)
+	listConnectionsFunc       func() ([]*apitypes.Connection, error)
+	approveConnectionFunc     func(id int64, approveSource, approveTarget bool) (*apitypes.Connection, error)
+	activateConnectionFunc    func(id int64) error
+	revokeConnectionFunc      func(id int64) error
 }
 
 func (m *mockDBClient) GetUserByUsername(username string) (*db.User, error) {
@@ -42,6 +102,55 @@ func (m *mockDBClient) GetUserByID(id int64) (*db.User, error) {
 	return nil, fmt.Errorf("GetUserByID not implemented")
 }
 
+func (m *mockDBClient) GetUserByEmail(email string) (*db.User, error) {
+	if m.getUserByEmailFunc != nil {
+		return m.getUserByEmailFunc(email)
+	}
+	return nil, fmt.Errorf("GetUserByEmail not implemented")
+}
+
+func (m *mockDBClient) CreateBackup(instanceName, objectKey, keyID string, sizeBytes int64, createdBy *int64, createdAt time.Time) (*apitypes.Backup, error) {
+	if m.createBackupFunc != nil {
+		return m.createBackupFunc(instanceName, objectKey, keyID, sizeBytes, createdBy, createdAt)
+	}
+	return nil, fmt.Errorf("CreateBackup not implemented")
+}
+
+func (m *mockDBClient) GetBackup(id int64) (*apitypes.Backup, error) {
+	if m.getBackupFunc != nil {
+		return m.getBackupFunc(id)
+	}
+	return nil, fmt.Errorf("GetBackup not implemented")
+}
+
+func (m *mockDBClient) ListBackups(instanceName string) ([]*apitypes.Backup, error) {
+	if m.listBackupsFunc != nil {
+		return m.listBackupsFunc(instanceName)
+	}
+	return nil, fmt.Errorf("ListBackups not implemented")
+}
+
+func (m *mockDBClient) SetBackupHeld(id int64, held bool) (*apitypes.Backup, error) {
+	if m.setBackupHeldFunc != nil {
+		return m.setBackupHeldFunc(id, held)
+	}
+	return nil, fmt.Errorf("SetBackupHeld not implemented")
+}
+
+func (m *mockDBClient) GetBackupRetentionPolicy(instanceName string) (*apitypes.BackupRetentionPolicy, error) {
+	if m.getBackupRetentionFunc != nil {
+		return m.getBackupRetentionFunc(instanceName)
+	}
+	return nil, fmt.Errorf("GetBackupRetentionPolicy not implemented")
+}
+
+func (m *mockDBClient) SetBackupRetentionPolicy(instanceName string, keepDaily, keepWeekly, keepMonthly int) (*apitypes.BackupRetentionPolicy, error) {
+	if m.setBackupRetentionFunc != nil {
+		return m.setBackupRetentionFunc(instanceName, keepDaily, keepWeekly, keepMonthly)
+	}
+	return nil, fmt.Errorf("SetBackupRetentionPolicy not implemented")
+}
+
 func (m *mockDBClient) CreateAPIKey(userID int64, name, keyHash string, expiresAt *time.Time) (*apitypes.APIKey, error) {
 	if m.createAPIKeyFunc != nil {
 		return m.createAPIKeyFunc(userID, name, keyHash, expiresAt)
@@ -77,6 +186,13 @@ func (m *mockDBClient) DeleteAPIKey(id int64) error {
 	return fmt.Errorf("DeleteAPIKey not implemented")
 }
 
+func (m *mockDBClient) RotateAPIKey(id int64, keyHash string, previousValidUntil *time.Time) (*apitypes.APIKey, error) {
+	if m.rotateAPIKeyFunc != nil {
+		return m.rotateAPIKeyFunc(id, keyHash, previousValidUntil)
+	}
+	return nil, fmt.Errorf("RotateAPIKey not implemented")
+}
+
 func (m *mockDBClient) GetAPIKeyByHash(keyHash string) (*apitypes.APIKey, error) {
 	if m.getAPIKeyByHashFunc != nil {
 		return m.getAPIKeyByHashFunc(keyHash)
@@ -91,13 +207,321 @@ func (m *mockDBClient) UpdateAPIKeyLastUsed(id int64) error {
 	return fmt.Errorf("UpdateAPIKeyLastUsed not implemented")
 }
 
+func (m *mockDBClient) CreateAuditLog(userID int64, action, resourceType, resourceID string, details map[string]string) error {
+	if m.createAuditLogFunc != nil {
+		return m.createAuditLogFunc(userID, action, resourceType, resourceID, details)
+	}
+	return fmt.Errorf("CreateAuditLog not implemented")
+}
+
+func (m *mockDBClient) CreateUser(username, passwordHash, role string) (*db.User, error) {
+	if m.createUserFunc != nil {
+		return m.createUserFunc(username, passwordHash, role)
+	}
+	return nil, fmt.Errorf("CreateUser not implemented")
+}
+
+func (m *mockDBClient) CreateExternalUser(username, authProvider, role string) (*db.User, error) {
+	if m.createExternalUserFunc != nil {
+		return m.createExternalUserFunc(username, authProvider, role)
+	}
+	return nil, fmt.Errorf("CreateExternalUser not implemented")
+}
+
+func (m *mockDBClient) UpdateUserPassword(id int64, passwordHash string) error {
+	if m.updateUserPasswordFunc != nil {
+		return m.updateUserPasswordFunc(id, passwordHash)
+	}
+	return fmt.Errorf("UpdateUserPassword not implemented")
+}
+
+func (m *mockDBClient) UpdateUserRole(id int64, role string) error {
+	if m.updateUserRoleFunc != nil {
+		return m.updateUserRoleFunc(id, role)
+	}
+	return fmt.Errorf("UpdateUserRole not implemented")
+}
+
+func (m *mockDBClient) CreateTeam(name string, createdBy int64) (*apitypes.Team, error) {
+	if m.createTeamFunc != nil {
+		return m.createTeamFunc(name, createdBy)
+	}
+	return nil, fmt.Errorf("CreateTeam not implemented")
+}
+
+func (m *mockDBClient) GetTeamByID(id int64) (*apitypes.Team, error) {
+	if m.getTeamByIDFunc != nil {
+		return m.getTeamByIDFunc(id)
+	}
+	return nil, fmt.Errorf("GetTeamByID not implemented")
+}
+
+func (m *mockDBClient) ListAllTeams() ([]*apitypes.Team, error) {
+	if m.listAllTeamsFunc != nil {
+		return m.listAllTeamsFunc()
+	}
+	return nil, fmt.Errorf("ListAllTeams not implemented")
+}
+
+func (m *mockDBClient) ListTeamsByUser(userID int64) ([]*apitypes.Team, error) {
+	if m.listTeamsByUserFunc != nil {
+		return m.listTeamsByUserFunc(userID)
+	}
+	return nil, fmt.Errorf("ListTeamsByUser not implemented")
+}
+
+func (m *mockDBClient) GetTeamMember(teamID, userID int64) (*apitypes.TeamMember, error) {
+	if m.getTeamMemberFunc != nil {
+		return m.getTeamMemberFunc(teamID, userID)
+	}
+	return nil, fmt.Errorf("GetTeamMember not implemented")
+}
+
+func (m *mockDBClient) CreateTeamInvitation(teamID int64, email, role string, invitedBy int64, expiresAt time.Time) (*apitypes.TeamInvitation, error) {
+	if m.createTeamInvitationFunc != nil {
+		return m.createTeamInvitationFunc(teamID, email, role, invitedBy, expiresAt)
+	}
+	return nil, fmt.Errorf("CreateTeamInvitation not implemented")
+}
+
+func (m *mockDBClient) GetTeamInvitationByID(id int64) (*apitypes.TeamInvitation, error) {
+	if m.getTeamInvitationByIDFunc != nil {
+		return m.getTeamInvitationByIDFunc(id)
+	}
+	return nil, fmt.Errorf("GetTeamInvitationByID not implemented")
+}
+
+func (m *mockDBClient) ListTeamInvitations(teamID int64) ([]*apitypes.TeamInvitation, error) {
+	if m.listTeamInvitationsFunc != nil {
+		return m.listTeamInvitationsFunc(teamID)
+	}
+	return nil, fmt.Errorf("ListTeamInvitations not implemented")
+}
+
+func (m *mockDBClient) RevokeTeamInvitation(id int64) error {
+	if m.revokeTeamInvitationFunc != nil {
+		return m.revokeTeamInvitationFunc(id)
+	}
+	return fmt.Errorf("RevokeTeamInvitation not implemented")
+}
+
+func (m *mockDBClient) ExtendTeamInvitation(id int64, expiresAt time.Time) (*apitypes.TeamInvitation, error) {
+	if m.extendTeamInvitationFunc != nil {
+		return m.extendTeamInvitationFunc(id, expiresAt)
+	}
+	return nil, fmt.Errorf("ExtendTeamInvitation not implemented")
+}
+
+func (m *mockDBClient) AcceptTeamInvitation(id, userID int64) (*apitypes.TeamMember, error) {
+	if m.acceptTeamInvitationFunc != nil {
+		return m.acceptTeamInvitationFunc(id, userID)
+	}
+	return nil, fmt.Errorf("AcceptTeamInvitation not implemented")
+}
+
+func (m *mockDBClient) GetUserPreferences(userID int64) (*apitypes.UserPreferences, error) {
+	if m.getUserPreferencesFunc != nil {
+		return m.getUserPreferencesFunc(userID)
+	}
+	return nil, fmt.Errorf("GetUserPreferences not implemented")
+}
+
+func (m *mockDBClient) UpsertUserPreferences(userID int64, preferences json.RawMessage) (*apitypes.UserPreferences, error) {
+	if m.upsertUserPreferencesFunc != nil {
+		return m.upsertUserPreferencesFunc(userID, preferences)
+	}
+	return nil, fmt.Errorf("UpsertUserPreferences not implemented")
+}
+
+func (m *mockDBClient) CreateUpgrade(upgrade *apitypes.Upgrade, instanceNames []string, canaryCount int) (*apitypes.Upgrade, []*apitypes.UpgradeTarget, error) {
+	if m.createUpgradeFunc != nil {
+		return m.createUpgradeFunc(upgrade, instanceNames, canaryCount)
+	}
+	return nil, nil, fmt.Errorf("CreateUpgrade not implemented")
+}
+
+func (m *mockDBClient) GetUpgrade(id int64) (*apitypes.Upgrade, error) {
+	if m.getUpgradeFunc != nil {
+		return m.getUpgradeFunc(id)
+	}
+	return nil, fmt.Errorf("GetUpgrade not implemented")
+}
+
+func (m *mockDBClient) ListUpgrades() ([]*apitypes.Upgrade, error) {
+	if m.listUpgradesFunc != nil {
+		return m.listUpgradesFunc()
+	}
+	return nil, fmt.Errorf("ListUpgrades not implemented")
+}
+
+func (m *mockDBClient) ListUpgradeTargets(upgradeID int64) ([]*apitypes.UpgradeTarget, error) {
+	if m.listUpgradeTargetsFunc != nil {
+		return m.listUpgradeTargetsFunc(upgradeID)
+	}
+	return nil, fmt.Errorf("ListUpgradeTargets not implemented")
+}
+
+// ListInstanceMetadata defaults to no stored metadata
+func (m *mockDBClient) ListInstanceMetadata(userID int64, instances []db.InstanceRef) (map[string]*db.InstanceMetadata, error) {
+	if m.listInstanceMetadataFunc != nil {
+		return m.listInstanceMetadataFunc(userID, instances)
+	}
+	return map[string]*db.InstanceMetadata{}, nil
+}
+
+func (m *mockDBClient) SetInstanceNotes(instance db.InstanceRef, notes string, updatedBy int64) error {
+	if m.setInstanceNotesFunc != nil {
+		return m.setInstanceNotesFunc(instance, notes, updatedBy)
+	}
+	return fmt.Errorf("SetInstanceNotes not implemented")
+}
+
+func (m *mockDBClient) SetInstanceFavorite(userID int64, instance db.InstanceRef, favorite bool) error {
+	if m.setInstanceFavoriteFunc != nil {
+		return m.setInstanceFavoriteFunc(userID, instance, favorite)
+	}
+	return fmt.Errorf("SetInstanceFavorite not implemented")
+}
+
+func (m *mockDBClient) ListChartVersions() ([]*apitypes.ChartVersion, error) {
+	if m.listChartVersionsFunc != nil {
+		return m.listChartVersionsFunc()
+	}
+	return nil, fmt.Errorf("ListChartVersions not implemented")
+}
+
+func (m *mockDBClient) ListInstanceRecords(filter db.InstanceRecordFilter) ([]*apitypes.InstanceRecord, error) {
+	if m.listInstanceRecordsFunc != nil {
+		return m.listInstanceRecordsFunc(filter)
+	}
+	return nil, fmt.Errorf("ListInstanceRecords not implemented")
+}
+
+// GetQuota defaults to no stored override, so handlers fall back to the configured quotas
+func (m *mockDBClient) GetQuota(userID *int64) (*apitypes.Quota, error) {
+	if m.getQuotaFunc != nil {
+		return m.getQuotaFunc(userID)
+	}
+	return nil, nil
+}
+
+func (m *mockDBClient) SetQuota(quota *apitypes.Quota) (*apitypes.Quota, error) {
+	if m.setQuotaFunc != nil {
+		return m.setQuotaFunc(quota)
+	}
+	return nil, fmt.Errorf("SetQuota not implemented")
+}
+
+func (m *mockDBClient) DeleteQuota(userID int64) error {
+	if m.deleteQuotaFunc != nil {
+		return m.deleteQuotaFunc(userID)
+	}
+	return fmt.Errorf("DeleteQuota not implemented")
+}
+
+func (m *mockDBClient) CreateBenchmark(benchmark *apitypes.Benchmark) (*apitypes.Benchmark, error) {
+	if m.createBenchmarkFunc != nil {
+		return m.createBenchmarkFunc(benchmark)
+	}
+	return nil, fmt.Errorf("CreateBenchmark not implemented")
+}
+
+func (m *mockDBClient) ListBenchmarks(instanceName string) ([]*apitypes.Benchmark, error) {
+	if m.listBenchmarksFunc != nil {
+		return m.listBenchmarksFunc(instanceName)
+	}
+	return nil, fmt.Errorf("ListBenchmarks not implemented")
+}
+
+func (m *mockDBClient) FinishBenchmark(benchmark *apitypes.Benchmark) error {
+	if m.finishBenchmarkFunc != nil {
+		return m.finishBenchmarkFunc(benchmark)
+	}
+	return fmt.Errorf("FinishBenchmark not implemented")
+}
+
+func (m *mockDBClient) CreateOperation(operationType, instanceName, instanceUID string, createdBy *int64) (*apitypes.Operation, error) {
+	if m.createOperationFunc != nil {
+		return m.createOperationFunc(operationType, instanceName, instanceUID, createdBy)
+	}
+	return nil, fmt.Errorf("CreateOperation not implemented")
+}
+
+func (m *mockDBClient) GetOperation(id int64) (*apitypes.Operation, error) {
+	if m.getOperationFunc != nil {
+		return m.getOperationFunc(id)
+	}
+	return nil, fmt.Errorf("GetOperation not implemented")
+}
+
+func (m *mockDBClient) FinishOperation(operation *apitypes.Operation) error {
+	if m.finishOperationFunc != nil {
+		return m.finishOperationFunc(operation)
+	}
+	return fmt.Errorf("FinishOperation not implemented")
+}
+
+func (m *mockDBClient) RedeemStudioSession(jti string, expiresAt time.Time) (bool, error) {
+	if m.redeemStudioSessionFunc != nil {
+		return m.redeemStudioSessionFunc(jti, expiresAt)
+	}
+	return false, fmt.Errorf("RedeemStudioSession not implemented")
+}
+
+func (m *mockDBClient) CreateConnection(source, target string, requestedBy int64, approveSource, approveTarget bool) (*apitypes.Connection, error) {
+	if m.createConnectionFunc != nil {
+		return m.createConnectionFunc(source, target, requestedBy, approveSource, approveTarget)
+	}
+	return nil, fmt.Errorf("CreateConnection not implemented")
+}
+
+func (m *mockDBClient) GetConnection(id int64) (*apitypes.Connection, error) {
+	if m.getConnectionFunc != nil {
+		return m.getConnectionFunc(id)
+	}
+	return nil, fmt.Errorf("GetConnection not implemented")
+}
+
+func (m *mockDBClient) ListConnections() ([]*apitypes.Connection, error) {
+	if m.listConnectionsFunc != nil {
+		return m.listConnectionsFunc()
+	}
+	return nil, fmt.Errorf("ListConnections not implemented")
+}
+
+func (m *mockDBClient) ApproveConnection(id int64, approveSource, approveTarget bool) (*apitypes.Connection, error) {
+	if m.approveConnectionFunc != nil {
+		return m.approveConnectionFunc(id, approveSource, approveTarget)
+	}
+	return nil, fmt.Errorf("ApproveConnection not implemented")
+}
+
+func (m *mockDBClient) ActivateConnection(id int64) error {
+	if m.activateConnectionFunc != nil {
+		return m.activateConnectionFunc(id)
+	}
+	return fmt.Errorf("ActivateConnection not implemented")
+}
+
+func (m *mockDBClient) RevokeConnection(id int64) error {
+	if m.revokeConnectionFunc != nil {
+		return m.revokeConnectionFunc(id)
+	}
+	return fmt.Errorf("RevokeConnection not implemented")
+}
+
 // mockCRClient is a mock implementation of CRClient for testing
 type mockCRClient struct {
-	createSupabaseInstanceFunc func(ctx context.Context, instance *supacontrolv1alpha1.SupabaseInstance) error
-	getSupabaseInstanceFunc    func(ctx context.Context, name string) (*supacontrolv1alpha1.SupabaseInstance, error)
-	listSupabaseInstancesFunc  func(ctx context.Context) (*supacontrolv1alpha1.SupabaseInstanceList, error)
-	updateSupabaseInstanceFunc func(ctx context.Context, instance *supacontrolv1alpha1.SupabaseInstance) error
-	deleteSupabaseInstanceFunc func(ctx context.Context, name string) error
+	createSupabaseInstanceFunc       func(ctx context.Context, instance *supacontrolv1alpha1.SupabaseInstance) error
+	getSupabaseInstanceFunc          func(ctx context.Context, name string) (*supacontrolv1alpha1.SupabaseInstance, error)
+	listSupabaseInstancesFunc        func(ctx context.Context) (*supacontrolv1alpha1.SupabaseInstanceList, error)
+	listWithOptionsFunc              func(ctx context.Context, opts k8s.ListOptions) (*supacontrolv1alpha1.SupabaseInstanceList, error)
+	updateSupabaseInstanceFunc       func(ctx context.Context, instance *supacontrolv1alpha1.SupabaseInstance) error
+	patchSupabaseInstanceFunc        func(ctx context.Context, name string, mutate func(*supacontrolv1alpha1.SupabaseInstance) error) (*supacontrolv1alpha1.SupabaseInstance, error)
+	updateSupabaseInstanceStatusFunc func(ctx context.Context, instance *supacontrolv1alpha1.SupabaseInstance) error
+	deleteSupabaseInstanceFunc       func(ctx context.Context, name string) error
+	listTemplatesFunc                func(ctx context.Context) (*supacontrolv1alpha1.SupabaseInstanceTemplateList, error)
+	getTemplateFunc                  func(ctx context.Context, name string) (*supacontrolv1alpha1.SupabaseInstanceTemplate, error)
 }
 
 func (m *mockCRClient) CreateSupabaseInstance(ctx context.Context, instance *supacontrolv1alpha1.SupabaseInstance) error {
@@ -121,6 +545,13 @@ func (m *mockCRClient) ListSupabaseInstances(ctx context.Context) (*supacontrolv
 	return nil, fmt.Errorf("ListSupabaseInstances not implemented")
 }
 
+func (m *mockCRClient) ListSupabaseInstancesWithOptions(ctx context.Context, opts k8s.ListOptions) (*supacontrolv1alpha1.SupabaseInstanceList, error) {
+	if m.listWithOptionsFunc != nil {
+		return m.listWithOptionsFunc(ctx, opts)
+	}
+	return nil, fmt.Errorf("ListSupabaseInstancesWithOptions not implemented")
+}
+
 func (m *mockCRClient) UpdateSupabaseInstance(ctx context.Context, instance *supacontrolv1alpha1.SupabaseInstance) error {
 	if m.updateSupabaseInstanceFunc != nil {
 		return m.updateSupabaseInstanceFunc(ctx, instance)
@@ -128,6 +559,37 @@ func (m *mockCRClient) UpdateSupabaseInstance(ctx context.Context, instance *sup
 	return fmt.Errorf("UpdateSupabaseInstance not implemented")
 }
 
+// PatchSupabaseInstance defaults to the get and update mocks, retried on conflicts like
+// the real client
+func (m *mockCRClient) PatchSupabaseInstance(ctx context.Context, name string, mutate func(*supacontrolv1alpha1.SupabaseInstance) error) (*supacontrolv1alpha1.SupabaseInstance, error) {
+	if m.patchSupabaseInstanceFunc != nil {
+		return m.patchSupabaseInstanceFunc(ctx, name, mutate)
+	}
+	var patched *supacontrolv1alpha1.SupabaseInstance
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		instance, err := m.GetSupabaseInstance(ctx, name)
+		if err != nil {
+			return err
+		}
+		if err := mutate(instance); err != nil {
+			return err
+		}
+		if err := m.UpdateSupabaseInstance(ctx, instance); err != nil {
+			return err
+		}
+		patched = instance
+		return nil
+	})
+	return patched, err
+}
+
+func (m *mockCRClient) UpdateSupabaseInstanceStatus(ctx context.Context, instance *supacontrolv1alpha1.SupabaseInstance) error {
+	if m.updateSupabaseInstanceStatusFunc != nil {
+		return m.updateSupabaseInstanceStatusFunc(ctx, instance)
+	}
+	return fmt.Errorf("UpdateSupabaseInstanceStatus not implemented")
+}
+
 func (m *mockCRClient) DeleteSupabaseInstance(ctx context.Context, name string) error {
 	if m.deleteSupabaseInstanceFunc != nil {
 		return m.deleteSupabaseInstanceFunc(ctx, name)
@@ -135,6 +597,139 @@ func (m *mockCRClient) DeleteSupabaseInstance(ctx context.Context, name string)
 	return fmt.Errorf("DeleteSupabaseInstance not implemented")
 }
 
+func (m *mockCRClient) ListSupabaseInstanceTemplates(ctx context.Context) (*supacontrolv1alpha1.SupabaseInstanceTemplateList, error) {
+	if m.listTemplatesFunc != nil {
+		return m.listTemplatesFunc(ctx)
+	}
+	return nil, fmt.Errorf("ListSupabaseInstanceTemplates not implemented")
+}
+
+func (m *mockCRClient) GetSupabaseInstanceTemplate(ctx context.Context, name string) (*supacontrolv1alpha1.SupabaseInstanceTemplate, error) {
+	if m.getTemplateFunc != nil {
+		return m.getTemplateFunc(ctx, name)
+	}
+	return nil, fmt.Errorf("GetSupabaseInstanceTemplate not implemented")
+}
+
+// mockChartResolver is a mock implementation of the ChartVersionResolver interface for testing
+type mockChartResolver struct {
+	componentVersionsFunc func(ctx context.Context, chartVersion string) (*k8s.ChartComponents, error)
+	chartVersionsFunc     func(ctx context.Context) ([]string, error)
+}
+
+func (m *mockChartResolver) ComponentVersions(ctx context.Context, chartVersion string) (*k8s.ChartComponents, error) {
+	if m.componentVersionsFunc != nil {
+		return m.componentVersionsFunc(ctx, chartVersion)
+	}
+	return nil, fmt.Errorf("ComponentVersions not implemented")
+}
+
+func (m *mockChartResolver) ChartVersions(ctx context.Context) ([]string, error) {
+	if m.chartVersionsFunc != nil {
+		return m.chartVersionsFunc(ctx)
+	}
+	return nil, fmt.Errorf("ChartVersions not implemented")
+}
+
+// staticChartCatalog is a ChartCatalog reporting a fixed latest chart version
+type staticChartCatalog string
+
+func (s staticChartCatalog) LatestChartVersion() string {
+	return string(s)
+}
+
+// mockReleasePreviewer is a mock implementation of the ReleasePreviewer interface for testing
+type mockReleasePreviewer struct {
+	previewUpgradeFunc func(ctx context.Context, namespace, releaseName, chartVersion string, values map[string]interface{}) (*k8s.ReleasePreview, error)
+}
+
+func (m *mockReleasePreviewer) PreviewUpgrade(ctx context.Context, namespace, releaseName, chartVersion string, values map[string]interface{}) (*k8s.ReleasePreview, error) {
+	if m.previewUpgradeFunc != nil {
+		return m.previewUpgradeFunc(ctx, namespace, releaseName, chartVersion, values)
+	}
+	return nil, fmt.Errorf("PreviewUpgrade not implemented")
+}
+
+// mockAdvisorySource is a mock implementation of the AdvisorySource interface for testing
+type mockAdvisorySource struct {
+	advisoriesFunc func(ctx context.Context) ([]advisories.Advisory, error)
+}
+
+func (m *mockAdvisorySource) Advisories(ctx context.Context) ([]advisories.Advisory, error) {
+	if m.advisoriesFunc != nil {
+		return m.advisoriesFunc(ctx)
+	}
+	return nil, fmt.Errorf("Advisories not implemented")
+}
+
+// mockUsageSource is a mock implementation of the UsageMetricsSource interface for testing
+type mockUsageSource struct {
+	namespaceUsageFunc func(ctx context.Context, namespace string) (*k8s.NamespaceUsage, error)
+}
+
+func (m *mockUsageSource) Name() string {
+	return apitypes.MetricsSourceMetricsServer
+}
+
+func (m *mockUsageSource) NamespaceUsage(ctx context.Context, namespace string) (*k8s.NamespaceUsage, error) {
+	if m.namespaceUsageFunc != nil {
+		return m.namespaceUsageFunc(ctx, namespace)
+	}
+	return nil, fmt.Errorf("NamespaceUsage not implemented")
+}
+
+// mockSAMLServiceProvider is a mock implementation of the SAMLServiceProvider interface for testing
+type mockSAMLServiceProvider struct {
+	metadataFunc              func() ([]byte, error)
+	authenticationRequestFunc func() (*url.URL, string, error)
+	parseResponseFunc         func(r *http.Request, requestIDs []string) (*sso.Identity, error)
+}
+
+func (m *mockSAMLServiceProvider) Metadata() ([]byte, error) {
+	if m.metadataFunc != nil {
+		return m.metadataFunc()
+	}
+	return nil, fmt.Errorf("Metadata not implemented")
+}
+
+func (m *mockSAMLServiceProvider) AuthenticationRequest() (*url.URL, string, error) {
+	if m.authenticationRequestFunc != nil {
+		return m.authenticationRequestFunc()
+	}
+	return nil, "", fmt.Errorf("AuthenticationRequest not implemented")
+}
+
+func (m *mockSAMLServiceProvider) ParseResponse(r *http.Request, requestIDs []string) (*sso.Identity, error) {
+	if m.parseResponseFunc != nil {
+		return m.parseResponseFunc(r, requestIDs)
+	}
+	return nil, fmt.Errorf("ParseResponse not implemented")
+}
+
+// mockPodExecutor is a mock implementation of the PodExecutor interface for testing
+type mockPodExecutor struct {
+	execFunc func(ctx context.Context, namespace, pod, container string, command []string, stdin string) (string, error)
+}
+
+func (m *mockPodExecutor) Exec(ctx context.Context, namespace, pod, container string, command []string, stdin string) (string, error) {
+	if m.execFunc != nil {
+		return m.execFunc(ctx, namespace, pod, container, command, stdin)
+	}
+	return "", fmt.Errorf("Exec not implemented")
+}
+
+// mockPortForwarder is a mock implementation of the PortForwarder interface for testing
+type mockPortForwarder struct {
+	forwardFunc func(ctx context.Context, namespace, pod string, port int, conn io.ReadWriter) error
+}
+
+func (m *mockPortForwarder) Forward(ctx context.Context, namespace, pod string, port int, conn io.ReadWriter) error {
+	if m.forwardFunc != nil {
+		return m.forwardFunc(ctx, namespace, pod, port, conn)
+	}
+	return fmt.Errorf("Forward not implemented")
+}
+
 // mockK8sClient is a mock implementation of the K8sClient interface for testing
 type mockK8sClient struct {
 	clientset kubernetes.Interface
@@ -0,0 +1,5 @@
+package v1alpha1
+
+// Hub marks v1alpha1, the storage version, as the version the other served versions
+// of SupabaseInstance are converted through
+func (*SupabaseInstance) Hub() {}
@@ -1,6 +1,7 @@
 package v1alpha1
 
 import (
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -11,31 +12,630 @@ type SupabaseInstanceSpec struct {
 	// +kubebuilder:validation:Pattern=`^[a-z0-9]([a-z0-9-]*[a-z0-9])?$`
 	ProjectName string `json:"projectName"`
 
-	// IngressClass specifies the Kubernetes ingress class to use
+	// IngressClass specifies the Kubernetes ingress class to use. Changing it on a running
+	// instance moves its ingresses to the new class in place.
 	// +optional
 	IngressClass string `json:"ingressClass,omitempty"`
 
-	// IngressDomain specifies the base domain for instance URLs
+	// IngressDomain specifies the base domain for instance URLs. Changing it on a running
+	// instance moves its ingresses and URLs to the new domain.
 	// +optional
 	IngressDomain string `json:"ingressDomain,omitempty"`
 
-	// ChartVersion specifies the Supabase Helm chart version to use
+	// ChartVersion specifies the Supabase Helm chart version to use. Changing it on a
+	// running instance upgrades the Helm release in place.
 	// +optional
 	ChartVersion string `json:"chartVersion,omitempty"`
 
-	// Paused indicates whether reconciliation should be paused
+	// TemplateRef names the SupabaseInstanceTemplate the instance was created from. Its
+	// chart values are applied beneath the instance's own settings when the instance is
+	// provisioned or upgraded.
+	// +optional
+	TemplateRef string `json:"templateRef,omitempty"`
+
+	// ProvisionerImage overrides the image running the instance's provisioning, upgrade
+	// and cleanup Jobs, e.g. a mirror in an internal registry on air-gapped clusters
+	// +optional
+	ProvisionerImage string `json:"provisionerImage,omitempty"`
+
+	// Adoption takes over an existing Supabase Helm release instead of provisioning a new
+	// one: the controller verifies the release is deployed, labels its namespace and
+	// moves the instance to Running without running a provisioning Job
+	// +optional
+	Adoption *Adoption `json:"adoption,omitempty"`
+
+	// Paused stops the instance: once provisioned, all of its Deployments and
+	// StatefulSets are scaled to zero until Paused is cleared again
 	// +optional
 	Paused bool `json:"paused,omitempty"`
+
+	// Profiles names the shared service profiles (SMTP, S3, OAuth) whose settings
+	// are injected into the instance's chart values
+	// +optional
+	Profiles []string `json:"profiles,omitempty"`
+
+	// CustomDomains serves the instance on customer-owned hostnames instead of the
+	// generated <projectName>-api and <projectName>-studio names under IngressDomain
+	// +optional
+	CustomDomains *CustomDomains `json:"customDomains,omitempty"`
+
+	// Ingress customizes the annotations and TLS of the instance's ingresses
+	// +optional
+	Ingress *IngressSettings `json:"ingress,omitempty"`
+
+	// DNS passes hints to external-dns when the controller publishes the instance's
+	// hostnames through it
+	// +optional
+	DNS *DNSSettings `json:"dns,omitempty"`
+
+	// AutoRetry tunes automatic retries of failed provisioning, which back off
+	// exponentially. Transient failures are retried without it; failures that could
+	// not be classified only with it, and permanent failures wait for a manual retry.
+	// +optional
+	AutoRetry *AutoRetryPolicy `json:"autoRetry,omitempty"`
+
+	// Provisioning tunes the timeout and retries of the Job provisioning the instance
+	// +optional
+	Provisioning *Provisioning `json:"provisioning,omitempty"`
+
+	// Placement controls which nodes the instance's pods are scheduled on.
+	// It is applied when the instance is provisioned.
+	// +optional
+	Placement *Placement `json:"placement,omitempty"`
+
+	// Isolation selects how strongly the instance is separated from other tenants.
+	// It is applied when the instance is provisioned.
+	// +optional
+	Isolation *Isolation `json:"isolation,omitempty"`
+
+	// NetworkIsolation adds default-deny NetworkPolicies to the instance namespace that
+	// only admit traffic from the instance's own pods and the ingress controller, so other
+	// tenants cannot reach its database or services
+	// +optional
+	NetworkIsolation bool `json:"networkIsolation,omitempty"`
+
+	// NamespaceLabels are set on the instance namespace in addition to the labels the
+	// platform sets on every instance namespace, e.g. to attribute its costs to a team.
+	// Keys in the kubernetes.io, k8s.io and supacontrol.io domains are refused.
+	// +optional
+	// +kubebuilder:validation:MaxProperties=20
+	NamespaceLabels map[string]string `json:"namespaceLabels,omitempty"`
+
+	// ConnectionPooler deploys PgBouncer in front of the instance database.
+	// It is applied when the instance is provisioned.
+	// +optional
+	ConnectionPooler *ConnectionPooler `json:"connectionPooler,omitempty"`
+
+	// Monitoring deploys a Postgres exporter next to the instance database and a
+	// ServiceMonitor that lets the platform's Prometheus scrape it
+	// +optional
+	Monitoring *Monitoring `json:"monitoring,omitempty"`
+
+	// Suspension takes the instance offline on behalf of an administrator or the billing
+	// system: its workloads are scaled to zero and its ingresses are marked suspended.
+	// Unlike Paused, the instance owner cannot lift it.
+	// +optional
+	Suspension *Suspension `json:"suspension,omitempty"`
+
+	// Maintenance points the instance's ingresses at a maintenance page while it is set,
+	// e.g. during a manual upgrade. Unlike a suspension, the ingresses keep their hosts and
+	// the workloads keep running.
+	// +optional
+	Maintenance *Maintenance `json:"maintenance,omitempty"`
+
+	// PublicStatusBadge publishes an unauthenticated status badge for the instance at
+	// /badges/<projectName>/status.svg
+	// +optional
+	PublicStatusBadge bool `json:"publicStatusBadge,omitempty"`
+
+	// Storage sizes the instance's Postgres volume and selects its StorageClass
+	// +optional
+	Storage *Storage `json:"storage,omitempty"`
+
+	// Database configures the instance's Postgres database
+	// +optional
+	Database *Database `json:"database,omitempty"`
+
+	// Resources sizes the CPU and memory of the instance's Postgres database. Changing it
+	// on a running instance resizes the database with a checkpointed restart.
+	// +optional
+	Resources *Resources `json:"resources,omitempty"`
+
+	// HighAvailability runs Kong, GoTrue and Realtime with several replicas spread
+	// across nodes, each guarded by a PodDisruptionBudget
+	// +optional
+	HighAvailability *HighAvailability `json:"highAvailability,omitempty"`
+
+	// DeletionProtection refuses deletion of the instance through the API until it is
+	// cleared, and holds back the purge of an instance already pending deletion
+	// +optional
+	DeletionProtection bool `json:"deletionProtection,omitempty"`
+
+	// TTL makes the instance ephemeral: it is deleted once TTL has passed since its
+	// creation, unless deletion protection is set. An Expiring condition and a webhook
+	// notification warn an hour before. Extending the instance raises TTL.
+	// +optional
+	TTL *metav1.Duration `json:"ttl,omitempty"`
+
+	// Schedule stops and starts the instance automatically, e.g. to stop a development
+	// instance at night and on weekends. Stopping and starting it by hand in between is
+	// kept until the next scheduled change.
+	// +optional
+	Schedule *Schedule `json:"schedule,omitempty"`
+
+	// Env sets additional environment variables of the instance's Supabase components,
+	// such as GOTRUE_DISABLE_SIGNUP. Only allowlisted settings and feature flags are
+	// accepted; credentials belong in shared service profiles. It is applied when the
+	// instance is provisioned or upgraded.
+	// +optional
+	// +kubebuilder:validation:MaxProperties=50
+	Env map[string]string `json:"env,omitempty"`
+
+	// Auth configures the instance's auth service (GoTrue)
+	// +optional
+	Auth *AuthSettings `json:"auth,omitempty"`
+
+	// Addons names the add-ons enabled for the instance. Their chart values are applied
+	// when the instance is provisioned or upgraded, their manifests as soon as it runs.
+	// +optional
+	// +kubebuilder:validation:MaxItems=20
+	Addons []string `json:"addons,omitempty"`
+
+	// AllowedConnections names the instances whose pods may connect to this instance's
+	// database. Each one gets a read-only database role with regularly rotated credentials,
+	// published as Secret connection-<projectName> in its namespace. Connections are
+	// approved by the owners of both instances through the API.
+	// +optional
+	// +kubebuilder:validation:MaxItems=20
+	AllowedConnections []string `json:"allowedConnections,omitempty"`
+
+	// PendingDeletion moves the instance to the trash: its workloads are scaled to zero
+	// and it is purged once PurgeAfter has passed. Clearing it before then recovers the
+	// instance.
+	// +optional
+	PendingDeletion *PendingDeletion `json:"pendingDeletion,omitempty"`
+}
+
+// AuthSettings configures the auth service (GoTrue) of an instance
+type AuthSettings struct {
+	// SMTP sets the mail server auth emails are sent through. It overrides an SMTP shared
+	// service profile, and changes are applied to the running auth service with a
+	// rolling restart.
+	// +optional
+	SMTP *SMTPSettings `json:"smtp,omitempty"`
+}
+
+// SMTPSettings configures the mail server of an instance's auth service
+type SMTPSettings struct {
+	// Host is the SMTP server hostname
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:MaxLength=253
+	Host string `json:"host"`
+
+	// Port is the SMTP server port
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	Port int32 `json:"port"`
+
+	// User is the SMTP username
+	// +optional
+	User string `json:"user,omitempty"`
+
+	// SenderEmail is the address auth emails are sent from
+	// +optional
+	SenderEmail string `json:"senderEmail,omitempty"`
+
+	// SenderName is the display name auth emails are sent with
+	// +optional
+	SenderName string `json:"senderName,omitempty"`
+
+	// SecretRef selects the key of a Secret in the controller namespace that holds the
+	// SMTP password
+	// +optional
+	SecretRef *SecretKeyReference `json:"secretRef,omitempty"`
+}
+
+// SecretKeyReference selects a key of a Secret
+type SecretKeyReference struct {
+	// Name is the name of the Secret
+	Name string `json:"name"`
+
+	// Key is the key of the Secret's data
+	Key string `json:"key"`
+}
+
+// PendingDeletion records a deletion that is held back for a grace period
+type PendingDeletion struct {
+	// RequestedAt is when the deletion was requested
+	RequestedAt metav1.Time `json:"requestedAt"`
+
+	// PurgeAfter is when the instance is deleted for good
+	PurgeAfter metav1.Time `json:"purgeAfter"`
+}
+
+// Storage configures the Postgres volume of an instance
+type Storage struct {
+	// Size is the requested size of the Postgres volume, e.g. 20Gi. Increasing it on a
+	// running instance expands the volume online; volumes cannot shrink.
+	// +optional
+	Size *resource.Quantity `json:"size,omitempty"`
+
+	// ClassName is the StorageClass of the Postgres volume; empty uses the cluster default.
+	// It is applied when the instance is provisioned.
+	// +optional
+	ClassName string `json:"className,omitempty"`
+}
+
+// Database configures the Postgres database of an instance
+type Database struct {
+	// ReadReplicas is the number of streaming read replicas of the instance database,
+	// served through a separate read-only Service. It can be changed on a running
+	// instance; zero removes the replicas.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=5
+	ReadReplicas int32 `json:"readReplicas,omitempty"`
+
+	// Version is the Postgres major version of the database, e.g. "17"; empty runs the
+	// chart's default. Raising it on a running instance upgrades the database in place:
+	// the controller backs it up, runs pg_upgrade and verifies the result, and restores
+	// the backup when a step fails. Downgrades are not supported.
+	// +optional
+	// +kubebuilder:validation:Pattern=`^[1-9][0-9]$`
+	Version string `json:"version,omitempty"`
+
+	// Audit configures pgAudit statement logging of the database
+	// +optional
+	Audit *DatabaseAudit `json:"audit,omitempty"`
+}
+
+// DatabaseAudit configures pgAudit statement logging of an instance database
+type DatabaseAudit struct {
+	// Enabled logs DDL, role changes and writes through pgAudit. Audit lines carry the
+	// "AUDIT:" prefix in the Postgres log.
+	Enabled bool `json:"enabled"`
+}
+
+// ResourceTier is a predefined size of an instance's Postgres database
+// +kubebuilder:validation:Enum=small;medium;large;xlarge
+type ResourceTier string
+
+const (
+	// ResourceTierSmall gives the database 0.5 CPU and 1Gi of memory
+	ResourceTierSmall ResourceTier = "small"
+
+	// ResourceTierMedium gives the database 1 CPU and 2Gi of memory
+	ResourceTierMedium ResourceTier = "medium"
+
+	// ResourceTierLarge gives the database 2 CPUs and 4Gi of memory
+	ResourceTierLarge ResourceTier = "large"
+
+	// ResourceTierXLarge gives the database 4 CPUs and 8Gi of memory
+	ResourceTierXLarge ResourceTier = "xlarge"
+)
+
+// Resources configures the CPU and memory of an instance's Postgres database
+type Resources struct {
+	// Tier selects a predefined size; empty keeps the chart defaults
+	// +optional
+	Tier ResourceTier `json:"tier,omitempty"`
+
+	// CPU overrides the CPU of the tier, e.g. 1500m
+	// +optional
+	CPU *resource.Quantity `json:"cpu,omitempty"`
+
+	// Memory overrides the memory of the tier, e.g. 3Gi
+	// +optional
+	Memory *resource.Quantity `json:"memory,omitempty"`
+}
+
+// HighAvailability configures the replicas of an instance's stateless services
+type HighAvailability struct {
+	// Replicas is the number of Kong, GoTrue and Realtime pods, which are preferably
+	// scheduled on different nodes
+	// +kubebuilder:validation:Minimum=2
+	// +kubebuilder:validation:Maximum=10
+	Replicas int32 `json:"replicas"`
+}
+
+// IsolationLevel selects the tenant isolation of an instance
+// +kubebuilder:validation:Enum=Namespace;VCluster;KataRuntime
+type IsolationLevel string
+
+const (
+	// IsolationNamespace runs the instance in its own namespace of the host cluster
+	IsolationNamespace IsolationLevel = "Namespace"
+
+	// IsolationVCluster runs the instance inside a virtual cluster hosted in its namespace,
+	// giving it a separate API server and control plane
+	IsolationVCluster IsolationLevel = "VCluster"
+
+	// IsolationKataRuntime runs the instance's pods in lightweight VMs through the
+	// Kata Containers RuntimeClass
+	IsolationKataRuntime IsolationLevel = "KataRuntime"
+)
+
+// IsolationFallback selects what happens when the cluster cannot provide an isolation level
+// +kubebuilder:validation:Enum=Fail;Namespace
+type IsolationFallback string
+
+const (
+	// IsolationFallbackFail fails provisioning
+	IsolationFallbackFail IsolationFallback = "Fail"
+
+	// IsolationFallbackNamespace provisions the instance with namespace isolation instead
+	IsolationFallbackNamespace IsolationFallback = "Namespace"
+)
+
+// Isolation configures tenant isolation for an instance
+type Isolation struct {
+	// Level is Namespace (the default), VCluster or KataRuntime
+	// +optional
+	// +kubebuilder:default=Namespace
+	Level IsolationLevel `json:"level,omitempty"`
+
+	// Fallback applies when the cluster does not support Level: Fail (the default)
+	// or Namespace
+	// +optional
+	// +kubebuilder:default=Fail
+	Fallback IsolationFallback `json:"fallback,omitempty"`
+}
+
+// PlacementMode selects how an instance's pods are scheduled
+// +kubebuilder:validation:Enum=Shared;Dedicated
+type PlacementMode string
+
+const (
+	// PlacementShared schedules the instance's pods on any node, alongside other instances
+	PlacementShared PlacementMode = "Shared"
+
+	// PlacementDedicated reserves a node for the instance alone. The controller labels
+	// and taints the node, and the instance's pods select and tolerate it.
+	PlacementDedicated PlacementMode = "Dedicated"
+)
+
+// Placement configures node placement for an instance
+type Placement struct {
+	// Mode is Shared (the default) or Dedicated
+	// +optional
+	// +kubebuilder:default=Shared
+	Mode PlacementMode `json:"mode,omitempty"`
+
+	// NodeSelector restricts which nodes may be reserved in Dedicated mode, e.g. the
+	// node group label of a cluster autoscaler pool
+	// +optional
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+}
+
+// PoolMode selects when PgBouncer returns a server connection to the pool
+// +kubebuilder:validation:Enum=Session;Transaction;Statement
+type PoolMode string
+
+const (
+	// PoolModeSession keeps a server connection for the lifetime of the client connection
+	PoolModeSession PoolMode = "Session"
+
+	// PoolModeTransaction releases the server connection after each transaction
+	PoolModeTransaction PoolMode = "Transaction"
+
+	// PoolModeStatement releases the server connection after each statement
+	PoolModeStatement PoolMode = "Statement"
+)
+
+// ConnectionPooler configures the PgBouncer pool in front of an instance database
+type ConnectionPooler struct {
+	// Enabled deploys the pooler
+	Enabled bool `json:"enabled"`
+
+	// PoolMode is Session, Transaction (the default) or Statement
+	// +optional
+	// +kubebuilder:default=Transaction
+	PoolMode PoolMode `json:"poolMode,omitempty"`
+
+	// DefaultPoolSize is the number of server connections per user and database
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=500
+	// +kubebuilder:default=20
+	DefaultPoolSize int32 `json:"defaultPoolSize,omitempty"`
+
+	// MaxClientConnections is the number of client connections the pooler accepts
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=10000
+	// +kubebuilder:default=1000
+	MaxClientConnections int32 `json:"maxClientConnections,omitempty"`
+}
+
+// Monitoring configures the Prometheus monitoring of an instance
+type Monitoring struct {
+	// Enabled deploys the exporter and its ServiceMonitor
+	Enabled bool `json:"enabled"`
+}
+
+// SuspensionReason records why an instance was suspended
+// +kubebuilder:validation:Enum=Billing;Quota;Administrative
+type SuspensionReason string
+
+const (
+	// SuspensionReasonBilling suspends an instance for an unpaid or failed payment
+	SuspensionReasonBilling SuspensionReason = "Billing"
+
+	// SuspensionReasonQuota suspends an instance whose owner exceeded a quota
+	SuspensionReasonQuota SuspensionReason = "Quota"
+
+	// SuspensionReasonAdministrative suspends an instance for any other reason
+	SuspensionReasonAdministrative SuspensionReason = "Administrative"
+)
+
+// Suspension describes an administrative suspension of an instance
+type Suspension struct {
+	// Reason is Billing, Quota or Administrative
+	Reason SuspensionReason `json:"reason"`
+
+	// Message is shown to the instance owner
+	// +optional
+	// +kubebuilder:validation:MaxLength=500
+	Message string `json:"message,omitempty"`
+
+	// SuspendedAt is when the suspension was requested
+	SuspendedAt metav1.Time `json:"suspendedAt"`
+}
+
+// Maintenance describes a maintenance window of an instance
+type Maintenance struct {
+	// Message is shown on the maintenance page
+	// +optional
+	// +kubebuilder:validation:MaxLength=500
+	Message string `json:"message,omitempty"`
+
+	// StartedAt is when the maintenance was requested
+	StartedAt metav1.Time `json:"startedAt"`
+}
+
+// Schedule stops and starts an instance at times given by five-field cron expressions
+// (minute, hour, day of month, month, day of week)
+type Schedule struct {
+	// Stop is when the instance is stopped, e.g. "0 19 * * 1-5" for weekday evenings
+	// +kubebuilder:validation:MinLength=9
+	// +kubebuilder:validation:MaxLength=100
+	Stop string `json:"stop"`
+
+	// Start is when the instance is started again, e.g. "0 7 * * 1-5" for weekday mornings,
+	// which keeps it stopped over the weekend
+	// +kubebuilder:validation:MinLength=9
+	// +kubebuilder:validation:MaxLength=100
+	Start string `json:"start"`
+
+	// TimeZone is the IANA time zone the expressions are evaluated in, UTC by default
+	// +optional
+	// +kubebuilder:validation:MaxLength=64
+	TimeZone string `json:"timeZone,omitempty"`
+}
+
+// Adoption names the Helm release of a Supabase installation made outside of SupaControl
+type Adoption struct {
+	// Namespace is the namespace the release is installed in
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:MaxLength=63
+	Namespace string `json:"namespace"`
+
+	// ReleaseName is the name of the Helm release, the project name by default
+	// +optional
+	// +kubebuilder:validation:MaxLength=53
+	ReleaseName string `json:"releaseName,omitempty"`
+}
+
+// AutoRetryPolicy configures automatic retries of failed provisioning
+type AutoRetryPolicy struct {
+	// MaxAttempts is the number of automatic retries before the instance stays Failed
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=10
+	// +kubebuilder:default=3
+	MaxAttempts int32 `json:"maxAttempts,omitempty"`
+}
+
+// Provisioning tunes the Job provisioning an instance. Large clusters and slow image
+// registries can need more than the default 15 minutes.
+type Provisioning struct {
+	// TimeoutSeconds bounds how long the provisioning Job may run, retries included,
+	// before the instance fails
+	// +optional
+	// +kubebuilder:validation:Minimum=300
+	// +kubebuilder:validation:Maximum=7200
+	// +kubebuilder:default=900
+	TimeoutSeconds int32 `json:"timeoutSeconds,omitempty"`
+
+	// Retries is how often the provisioning Job retries a failed attempt before the
+	// instance fails
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=10
+	// +kubebuilder:default=3
+	Retries *int32 `json:"retries,omitempty"`
+}
+
+// FailureType classifies why an instance failed, deciding whether it is retried automatically
+// +kubebuilder:validation:Enum=Transient;Permanent
+type FailureType string
+
+const (
+	// FailureTransient is a failure retrying may resolve, such as an image pull or a
+	// chart repository timeout
+	FailureTransient FailureType = "Transient"
+
+	// FailurePermanent is a failure only a change can resolve, such as invalid chart values
+	FailurePermanent FailureType = "Permanent"
+)
+
+// CustomDomains holds customer-owned hostnames for an instance. Each one left empty
+// falls back to the generated hostname.
+type CustomDomains struct {
+	// API is the hostname serving the Supabase API (Kong), e.g. api.example.com
+	// +optional
+	// +kubebuilder:validation:MaxLength=253
+	// +kubebuilder:validation:Pattern=`^([a-z0-9]([a-z0-9-]*[a-z0-9])?\.)+[a-z0-9]([a-z0-9-]*[a-z0-9])?$`
+	API string `json:"api,omitempty"`
+
+	// Studio is the hostname serving Supabase Studio, e.g. studio.example.com
+	// +optional
+	// +kubebuilder:validation:MaxLength=253
+	// +kubebuilder:validation:Pattern=`^([a-z0-9]([a-z0-9-]*[a-z0-9])?\.)+[a-z0-9]([a-z0-9-]*[a-z0-9])?$`
+	Studio string `json:"studio,omitempty"`
+}
+
+// IngressSettings customizes an instance's ingresses. Changing them on a running instance
+// updates its ingresses in place.
+type IngressSettings struct {
+	// Annotations are set on the instance's ingresses, e.g. to configure the ingress
+	// controller. They take precedence over the cert-manager annotation SupaControl sets.
+	// +optional
+	// +kubebuilder:validation:MaxProperties=50
+	Annotations map[string]string `json:"annotations,omitempty"`
+
+	// TLSIssuer is the cert-manager ClusterIssuer of the instance's certificates, instead
+	// of the server's default issuer
+	// +optional
+	// +kubebuilder:validation:MaxLength=253
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([a-z0-9.-]*[a-z0-9])?$`
+	TLSIssuer string `json:"tlsIssuer,omitempty"`
+
+	// DisableTLS serves the instance over plain HTTP without certificates, e.g. on
+	// bare-metal development clusters without cert-manager
+	// +optional
+	DisableTLS bool `json:"disableTLS,omitempty"`
+}
+
+// DNSSettings holds external-dns hints for an instance's hostnames
+type DNSSettings struct {
+	// Provider selects the external-dns deployment that publishes the hostnames, e.g.
+	// route53 or cloudflare. It is set as the supacontrol.io/dns-provider label, which
+	// each external-dns deployment can select with --label-filter.
+	// +optional
+	// +kubebuilder:validation:MaxLength=63
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([a-z0-9-]*[a-z0-9])?$`
+	Provider string `json:"provider,omitempty"`
+
+	// TTL is the time to live of the published records in seconds (empty uses the
+	// external-dns default)
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=86400
+	TTL int32 `json:"ttl,omitempty"`
 }
 
 // SupabaseInstancePhase represents the current phase of a SupabaseInstance
-// +kubebuilder:validation:Enum=Pending;Provisioning;ProvisioningInProgress;Running;Deleting;DeletingInProgress;Failed
+// +kubebuilder:validation:Enum=Pending;Queued;Provisioning;ProvisioningInProgress;Running;Upgrading;BackingUpDatabase;UpgradingDatabase;VerifyingDatabase;RollingBackDatabase;Stopped;Suspended;PendingDeletion;Deleting;DeletingInProgress;Failed
 type SupabaseInstancePhase string
 
 const (
 	// PhasePending indicates the instance is waiting to be provisioned
 	PhasePending SupabaseInstancePhase = "Pending"
 
+	// PhaseQueued indicates the instance is waiting for a free provisioning Job slot
+	PhaseQueued SupabaseInstancePhase = "Queued"
+
 	// PhaseProvisioning indicates the provisioning Job has been created
 	PhaseProvisioning SupabaseInstancePhase = "Provisioning"
 
@@ -45,6 +645,33 @@ const (
 	// PhaseRunning indicates the instance is running and healthy
 	PhaseRunning SupabaseInstancePhase = "Running"
 
+	// PhaseUpgrading indicates an upgrade Job is moving the Helm release to a new chart version
+	PhaseUpgrading SupabaseInstancePhase = "Upgrading"
+
+	// PhaseBackingUpDatabase indicates the database is dumped before a Postgres major upgrade
+	PhaseBackingUpDatabase SupabaseInstancePhase = "BackingUpDatabase"
+
+	// PhaseUpgradingDatabase indicates pg_upgrade is moving the database to a new Postgres
+	// major version
+	PhaseUpgradingDatabase SupabaseInstancePhase = "UpgradingDatabase"
+
+	// PhaseVerifyingDatabase indicates the upgraded database is being checked
+	PhaseVerifyingDatabase SupabaseInstancePhase = "VerifyingDatabase"
+
+	// PhaseRollingBackDatabase indicates a failed Postgres major upgrade is being undone by
+	// restoring the backup taken before it
+	PhaseRollingBackDatabase SupabaseInstancePhase = "RollingBackDatabase"
+
+	// PhaseStopped indicates the instance is paused and its workloads are scaled to zero
+	PhaseStopped SupabaseInstancePhase = "Stopped"
+
+	// PhaseSuspended indicates the instance is suspended and its workloads are scaled to zero
+	PhaseSuspended SupabaseInstancePhase = "Suspended"
+
+	// PhasePendingDeletion indicates the instance is in the trash with its workloads scaled
+	// to zero, waiting to be purged or recovered
+	PhasePendingDeletion SupabaseInstancePhase = "PendingDeletion"
+
 	// PhaseDeleting indicates the cleanup Job has been created
 	PhaseDeleting SupabaseInstancePhase = "Deleting"
 
@@ -60,15 +687,33 @@ const (
 func AllPhases() []string {
 	return []string{
 		string(PhasePending),
+		string(PhaseQueued),
 		string(PhaseProvisioning),
 		string(PhaseProvisioningInProgress),
 		string(PhaseRunning),
+		string(PhaseUpgrading),
+		string(PhaseBackingUpDatabase),
+		string(PhaseUpgradingDatabase),
+		string(PhaseVerifyingDatabase),
+		string(PhaseRollingBackDatabase),
+		string(PhaseStopped),
+		string(PhaseSuspended),
+		string(PhasePendingDeletion),
 		string(PhaseDeleting),
 		string(PhaseDeletingInProgress),
 		string(PhaseFailed),
 	}
 }
 
+// IsDatabaseUpgradePhase reports whether a phase is a step of a Postgres major upgrade
+func IsDatabaseUpgradePhase(phase SupabaseInstancePhase) bool {
+	switch phase {
+	case PhaseBackingUpDatabase, PhaseUpgradingDatabase, PhaseVerifyingDatabase, PhaseRollingBackDatabase:
+		return true
+	}
+	return false
+}
+
 // SupabaseInstanceStatus defines the observed state of SupabaseInstance
 type SupabaseInstanceStatus struct {
 	// Phase represents the current phase of the instance
@@ -111,9 +756,190 @@ type SupabaseInstanceStatus struct {
 	// +optional
 	ProvisioningJobName string `json:"provisioningJobName,omitempty"`
 
+	// QueuedAt is when the instance started waiting for a provisioning Job slot; queued
+	// instances are provisioned in this order
+	// +optional
+	QueuedAt *metav1.Time `json:"queuedAt,omitempty"`
+
+	// RetryCount is the number of automatic provisioning retries since the instance was
+	// created or last retried manually
+	// +optional
+	RetryCount int32 `json:"retryCount,omitempty"`
+
+	// FailureType classifies the failure of a Failed instance; empty when it could not
+	// be classified
+	// +optional
+	FailureType FailureType `json:"failureType,omitempty"`
+
+	// NextRetryAt is when a Failed instance is retried automatically; unset when it
+	// waits for a manual retry
+	// +optional
+	NextRetryAt *metav1.Time `json:"nextRetryAt,omitempty"`
+
 	// CleanupJobName is the name of the current/last cleanup Job
 	// +optional
 	CleanupJobName string `json:"cleanupJobName,omitempty"`
+
+	// ChartVersion is the chart version the Helm release was last installed or upgraded to
+	// +optional
+	ChartVersion string `json:"chartVersion,omitempty"`
+
+	// Resources is the database sizing the Helm release was last installed or resized with
+	// +optional
+	Resources *Resources `json:"resources,omitempty"`
+
+	// ProfilesChecksum is a checksum of the shared service profiles rendered into the
+	// chart values of the last provisioning or upgrade Job. A running instance whose
+	// profiles no longer match is upgraded to reapply them.
+	// +optional
+	ProfilesChecksum string `json:"profilesChecksum,omitempty"`
+
+	// HelmRevision is the latest revision of the instance's Helm release, read from the
+	// release Secret Helm keeps in the instance namespace
+	// +optional
+	HelmRevision int32 `json:"helmRevision,omitempty"`
+
+	// HelmReleaseStatus is the status of that revision, e.g. deployed or failed
+	// +optional
+	HelmReleaseStatus string `json:"helmReleaseStatus,omitempty"`
+
+	// UpgradeJobName is the name of the current/last upgrade Job
+	// +optional
+	UpgradeJobName string `json:"upgradeJobName,omitempty"`
+
+	// DatabaseVersion is the Postgres major version the database was last installed or
+	// upgraded to; empty when it runs the chart's default
+	// +optional
+	DatabaseVersion string `json:"databaseVersion,omitempty"`
+
+	// DatabaseUpgrade tracks the Postgres major upgrade in progress
+	// +optional
+	DatabaseUpgrade *DatabaseUpgradeStatus `json:"databaseUpgrade,omitempty"`
+
+	// DedicatedNode is the node reserved for the instance in Dedicated placement mode
+	// +optional
+	DedicatedNode string `json:"dedicatedNode,omitempty"`
+
+	// IsolationLevel is the isolation the instance was provisioned with, which differs
+	// from the requested level after a fallback
+	// +optional
+	IsolationLevel IsolationLevel `json:"isolationLevel,omitempty"`
+
+	// Health counts the consecutive results of the health checks of a running instance
+	// +optional
+	Health *HealthStatus `json:"health,omitempty"`
+
+	// Addons lists the add-ons whose manifests are applied in the instance namespace,
+	// so the manifests of add-ons removed from the spec can be deleted
+	// +optional
+	Addons []string `json:"addons,omitempty"`
+
+	// Connections lists the instances whose connections to this instance are
+	// materialized, so the resources of connections removed from the spec can be deleted
+	// +optional
+	Connections []string `json:"connections,omitempty"`
+
+	// ReadyReadReplicas is the number of the instance's read replicas that are ready
+	// +optional
+	ReadyReadReplicas int32 `json:"readyReadReplicas,omitempty"`
+
+	// ExpiresAt is when an ephemeral instance will be deleted
+	// +optional
+	ExpiresAt *metav1.Time `json:"expiresAt,omitempty"`
+
+	// Secrets lists the instance's keys, passwords and TLS certificates with when each
+	// was last rotated
+	// +optional
+	Secrets []SecretAge `json:"secrets,omitempty"`
+}
+
+// SecretKind is the kind of an instance secret
+// +kubebuilder:validation:Enum=JWTSecret;AnonKey;ServiceRoleKey;PostgresPassword;TLSCertificate
+type SecretKind string
+
+const (
+	// SecretKindJWTSecret is the secret the instance's JWTs are signed with
+	SecretKindJWTSecret SecretKind = "JWTSecret"
+
+	// SecretKindAnonKey is the API key of anonymous clients
+	SecretKindAnonKey SecretKind = "AnonKey"
+
+	// SecretKindServiceRoleKey is the API key that bypasses row level security
+	SecretKindServiceRoleKey SecretKind = "ServiceRoleKey"
+
+	// SecretKindPostgresPassword is the password of the postgres superuser
+	SecretKindPostgresPassword SecretKind = "PostgresPassword"
+
+	// SecretKindTLSCertificate is the TLS certificate of one of the instance's ingresses
+	SecretKindTLSCertificate SecretKind = "TLSCertificate"
+)
+
+// DatabaseUpgradeStatus tracks a Postgres major upgrade through its backup, upgrade,
+// verification and, on failure, rollback steps
+type DatabaseUpgradeStatus struct {
+	// FromVersion is the major version the database ran before the upgrade; empty for
+	// the chart's default
+	// +optional
+	FromVersion string `json:"fromVersion,omitempty"`
+
+	// ToVersion is the major version the database is upgraded to
+	ToVersion string `json:"toVersion"`
+
+	// JobName is the name of the Job running the current step
+	// +optional
+	JobName string `json:"jobName,omitempty"`
+
+	// StartedAt is when the upgrade started
+	StartedAt metav1.Time `json:"startedAt"`
+
+	// Error is why the upgrade is being rolled back
+	// +optional
+	Error string `json:"error,omitempty"`
+}
+
+// SecretAge records when one of an instance's secrets was last rotated
+type SecretAge struct {
+	// Name identifies the secret: its key in the instance Secret, or the name of the
+	// Secret holding a TLS certificate
+	Name string `json:"name"`
+
+	// Kind is the kind of secret
+	Kind SecretKind `json:"kind"`
+
+	// SecretName is the Secret in the instance namespace that holds it
+	SecretName string `json:"secretName"`
+
+	// RotatedAt is when the secret was last rotated, or a certificate issued
+	RotatedAt metav1.Time `json:"rotatedAt"`
+
+	// ExpiresAt is when a TLS certificate expires
+	// +optional
+	ExpiresAt *metav1.Time `json:"expiresAt,omitempty"`
+
+	// Overdue reports whether the secret is older than the maximum secret age, or the
+	// certificate has expired
+	// +optional
+	Overdue bool `json:"overdue,omitempty"`
+}
+
+// HealthStatus tracks consecutive health check results, so the Ready and Degraded
+// conditions only change once several checks in a row agree
+type HealthStatus struct {
+	// ConsecutiveSuccesses is the number of checks passed in a row, capped at the threshold
+	// +optional
+	ConsecutiveSuccesses int32 `json:"consecutiveSuccesses,omitempty"`
+
+	// ConsecutiveFailures is the number of checks failed in a row, capped at the threshold
+	// +optional
+	ConsecutiveFailures int32 `json:"consecutiveFailures,omitempty"`
+
+	// LastCheckTime is when the instance was last checked
+	// +optional
+	LastCheckTime *metav1.Time `json:"lastCheckTime,omitempty"`
+
+	// LastFailure describes why the last failed check failed
+	// +optional
+	LastFailure string `json:"lastFailure,omitempty"`
 }
 
 // Condition types for SupabaseInstance
@@ -132,8 +958,134 @@ const (
 
 	// ConditionTypeIngressReady indicates whether ingress is configured
 	ConditionTypeIngressReady = "IngressReady"
+
+	// ConditionTypeUpgraded reports the outcome of the most recent chart upgrade
+	ConditionTypeUpgraded = "Upgraded"
+
+	// ConditionTypePlacementReady indicates whether the node requested by the placement is reserved
+	ConditionTypePlacementReady = "PlacementReady"
+
+	// ConditionTypeIsolationReady indicates whether the requested isolation level is available
+	ConditionTypeIsolationReady = "IsolationReady"
+
+	// ConditionTypeDegraded indicates whether a running instance has failed its health checks
+	ConditionTypeDegraded = "Degraded"
+
+	// ConditionTypeStorageExpanded reports the outcome of the most recent Postgres volume expansion
+	ConditionTypeStorageExpanded = "StorageExpanded"
+
+	// ConditionTypeSMTPConfigured indicates whether the SMTP settings are applied to the auth service
+	ConditionTypeSMTPConfigured = "SMTPConfigured"
+
+	// ConditionTypeAddonsReady indicates whether the manifests of the instance's add-ons are applied
+	ConditionTypeAddonsReady = "AddonsReady"
+
+	// ConditionTypeConnectionsReady indicates whether the instance's allowed connections are materialized
+	ConditionTypeConnectionsReady = "ConnectionsReady"
+
+	// ConditionTypeReadReplicasReady indicates whether the instance's read replicas are ready
+	ConditionTypeReadReplicasReady = "ReadReplicasReady"
+
+	// ConditionTypeHighAvailabilityReady indicates whether the PodDisruptionBudgets of a
+	// highly available instance exist
+	ConditionTypeHighAvailabilityReady = "HighAvailabilityReady"
+
+	// ConditionTypeResizing reports the progress and outcome of the most recent database resize
+	ConditionTypeResizing = "Resizing"
+
+	// ConditionTypeExpiring warns that an ephemeral instance will soon be deleted
+	ConditionTypeExpiring = "Expiring"
+
+	// ConditionTypeMonitoringReady indicates whether the instance's Postgres exporter and
+	// ServiceMonitor are in place
+	ConditionTypeMonitoringReady = "MonitoringReady"
+
+	// ConditionTypeIngressAdmitted indicates whether an ingress controller has admitted the
+	// instance's ingresses
+	ConditionTypeIngressAdmitted = "IngressAdmitted"
+
+	// ConditionTypeCertificatesReady indicates whether cert-manager has issued the TLS
+	// certificates of the instance's ingresses
+	ConditionTypeCertificatesReady = "CertificatesReady"
+
+	// ConditionTypeMaintenance indicates whether the instance's ingresses serve the
+	// maintenance page
+	ConditionTypeMaintenance = "Maintenance"
+
+	// ConditionTypeDNSReady indicates whether external-dns has picked up the instance's
+	// hostnames
+	ConditionTypeDNSReady = "DNSReady"
+
+	// ConditionTypeSecretsRotated indicates whether every instance secret was rotated within
+	// the maximum secret age
+	ConditionTypeSecretsRotated = "SecretsRotated"
+
+	// ConditionTypeDatabaseUpgraded reports the progress and outcome of the most recent
+	// Postgres major upgrade
+	ConditionTypeDatabaseUpgraded = "DatabaseUpgraded"
+
+	// ConditionTypeDatabaseAuditReady indicates whether pgAudit statement logging is
+	// configured as the spec requests
+	ConditionTypeDatabaseAuditReady = "DatabaseAuditReady"
 )
 
+// Annotation keys for SupabaseInstance
+const (
+	// AnnotationOwnerID records the ID of the user who created the instance
+	AnnotationOwnerID = "supacontrol.io/owner-id"
+
+	// AnnotationPhaseHistory holds the instance's most recent phase transitions as a
+	// JSON array of PhaseTransition, oldest first
+	AnnotationPhaseHistory = "supacontrol.io/phase-history"
+
+	// AnnotationScheduleAppliedAt records the time of the last scheduled stop or start
+	// applied to the instance, in RFC 3339 format, so each is applied only once
+	AnnotationScheduleAppliedAt = "supacontrol.io/schedule-applied-at"
+)
+
+// Label keys for SupabaseInstance
+const (
+	// LabelSandbox marks developer sandbox instances, which are confined to a small tier
+	// and expire automatically
+	LabelSandbox = "supacontrol.io/sandbox"
+
+	// LabelTagPrefix prefixes the keys of the labels an instance's tags are stored in, so
+	// the tag env=staging is the label supacontrol.io/tag-env=staging
+	LabelTagPrefix = "supacontrol.io/tag-"
+
+	// LabelDNSProvider marks the DNSEndpoints and ingresses of an instance with its DNS
+	// provider hint
+	LabelDNSProvider = "supacontrol.io/dns-provider"
+)
+
+// MaxPhaseHistory is the number of phase transitions kept in AnnotationPhaseHistory
+const MaxPhaseHistory = 20
+
+// PhaseTransition is an entry of AnnotationPhaseHistory. Its keys are kept short, as the
+// whole history is stored in one annotation.
+// +k8s:deepcopy-gen=false
+type PhaseTransition struct {
+	// From is the phase the instance left; empty for its first phase
+	From SupabaseInstancePhase `json:"from,omitempty"`
+
+	// To is the phase the instance entered
+	To SupabaseInstancePhase `json:"to"`
+
+	// At is when the transition happened
+	At metav1.Time `json:"at"`
+
+	// Reason explains the transition
+	Reason string `json:"reason,omitempty"`
+}
+
+// LabelDedicatedInstance marks a node reserved for one instance in Dedicated placement
+// mode. The node also carries a NoSchedule taint with the same key and value.
+const LabelDedicatedInstance = "supacontrol.io/dedicated-instance"
+
+// LabelSuspended marks the ingresses of a suspended instance; its value is the lowercase
+// suspension reason
+const LabelSuspended = "supacontrol.io/suspended"
+
 // SupabaseInstance is the Schema for the supabaseinstances API
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
@@ -151,6 +1103,16 @@ type SupabaseInstance struct {
 	Status SupabaseInstanceStatus `json:"status,omitempty"`
 }
 
+// ExpiryTime returns when an ephemeral instance expires, which is its TTL after its
+// creation truncated to the second like stored times, or nil for instances without a TTL
+func (in *SupabaseInstance) ExpiryTime() *metav1.Time {
+	if in.Spec.TTL == nil {
+		return nil
+	}
+	expiresAt := metav1.NewTime(in.CreationTimestamp.Add(in.Spec.TTL.Duration)).Rfc3339Copy()
+	return &expiresAt
+}
+
 // SupabaseInstanceList contains a list of SupabaseInstance
 // +kubebuilder:object:root=true
 type SupabaseInstanceList struct {
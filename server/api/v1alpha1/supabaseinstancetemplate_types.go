@@ -0,0 +1,86 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// SupabaseInstanceTemplateSpec defines a standard configuration that instances can be
+// created from, such as the plans offered to users
+type SupabaseInstanceTemplateSpec struct {
+	// DisplayName is the name of the template shown to users, e.g. "Pro"; empty shows
+	// the template's name
+	// +optional
+	DisplayName string `json:"displayName,omitempty"`
+
+	// Description tells users what the template is meant for
+	// +optional
+	Description string `json:"description,omitempty"`
+
+	// ChartVersion is the Supabase Helm chart version of instances created from the
+	// template; empty uses the installation default
+	// +optional
+	ChartVersion string `json:"chartVersion,omitempty"`
+
+	// Resources sizes the CPU and memory of the database of instances created from the
+	// template, usually by tier
+	// +optional
+	Resources *Resources `json:"resources,omitempty"`
+
+	// Storage sizes the Postgres volume of instances created from the template
+	// +optional
+	Storage *Storage `json:"storage,omitempty"`
+
+	// Values are Helm chart values applied to every instance referencing the template.
+	// Settings of the instance itself, such as its resources, profiles and add-ons, take
+	// precedence over them. Changes apply when an instance is provisioned or upgraded.
+	// +optional
+	// +kubebuilder:pruning:PreserveUnknownFields
+	// +kubebuilder:validation:Type=object
+	Values *runtime.RawExtension `json:"values,omitempty"`
+
+	// Quota caps the number of instances created from the template
+	// +optional
+	Quota *TemplateQuota `json:"quota,omitempty"`
+}
+
+// TemplateQuota caps the instances created from a template. Instances being deleted no
+// longer count; zero means unlimited.
+type TemplateQuota struct {
+	// MaxInstances caps the instances created from the template across the installation
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	MaxInstances int32 `json:"maxInstances,omitempty"`
+
+	// MaxInstancesPerUser caps the instances each user creates from the template
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	MaxInstancesPerUser int32 `json:"maxInstancesPerUser,omitempty"`
+}
+
+// SupabaseInstanceTemplate is the Schema for the supabaseinstancetemplates API. Instances
+// name it in spec.templateRef.
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster,shortName=sbit
+// +kubebuilder:printcolumn:name="Display Name",type=string,JSONPath=`.spec.displayName`
+// +kubebuilder:printcolumn:name="Tier",type=string,JSONPath=`.spec.resources.tier`
+// +kubebuilder:printcolumn:name="Chart Version",type=string,JSONPath=`.spec.chartVersion`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+type SupabaseInstanceTemplate struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec SupabaseInstanceTemplateSpec `json:"spec,omitempty"`
+}
+
+// SupabaseInstanceTemplateList contains a list of SupabaseInstanceTemplate
+// +kubebuilder:object:root=true
+type SupabaseInstanceTemplateList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []SupabaseInstanceTemplate `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&SupabaseInstanceTemplate{}, &SupabaseInstanceTemplateList{})
+}
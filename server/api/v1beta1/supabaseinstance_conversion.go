@@ -0,0 +1,118 @@
+package v1beta1
+
+import (
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+
+	"github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+)
+
+// ConvertTo converts this SupabaseInstance to the hub version (v1alpha1)
+func (src *SupabaseInstance) ConvertTo(dstRaw conversion.Hub) error {
+	dst := dstRaw.(*v1alpha1.SupabaseInstance)
+	in := src.DeepCopy()
+
+	dst.ObjectMeta = in.ObjectMeta
+	dst.Spec = v1alpha1.SupabaseInstanceSpec{
+		ProjectName:        in.Spec.ProjectName,
+		ChartVersion:       in.Spec.ChartVersion,
+		TemplateRef:        in.Spec.TemplateRef,
+		ProvisionerImage:   in.Spec.ProvisionerImage,
+		Adoption:           in.Spec.Adoption,
+		Paused:             in.Spec.Paused,
+		Profiles:           in.Spec.Profiles,
+		AutoRetry:          in.Spec.AutoRetry,
+		Provisioning:       in.Spec.Provisioning,
+		Placement:          in.Spec.Placement,
+		Isolation:          in.Spec.Isolation,
+		NetworkIsolation:   in.Spec.NetworkIsolation,
+		NamespaceLabels:    in.Spec.NamespaceLabels,
+		ConnectionPooler:   in.Spec.ConnectionPooler,
+		Monitoring:         in.Spec.Monitoring,
+		Suspension:         in.Spec.Suspension,
+		Maintenance:        in.Spec.Maintenance,
+		PublicStatusBadge:  in.Spec.PublicStatusBadge,
+		Storage:            in.Spec.Storage,
+		Database:           in.Spec.Database,
+		Resources:          in.Spec.Resources,
+		HighAvailability:   in.Spec.HighAvailability,
+		DeletionProtection: in.Spec.DeletionProtection,
+		TTL:                in.Spec.TTL,
+		Schedule:           in.Spec.Schedule,
+		Env:                in.Spec.Env,
+		Auth:               in.Spec.Auth,
+		Addons:             in.Spec.Addons,
+		AllowedConnections: in.Spec.AllowedConnections,
+		PendingDeletion:    in.Spec.PendingDeletion,
+		DNS:                in.Spec.DNS,
+	}
+	if in.Spec.Ingress != nil {
+		dst.Spec.IngressClass = in.Spec.Ingress.ClassName
+		dst.Spec.IngressDomain = in.Spec.Ingress.Domain
+		dst.Spec.CustomDomains = in.Spec.Ingress.CustomDomains
+		if len(in.Spec.Ingress.Annotations) > 0 || in.Spec.Ingress.TLSIssuer != "" || in.Spec.Ingress.DisableTLS {
+			dst.Spec.Ingress = &v1alpha1.IngressSettings{
+				Annotations: in.Spec.Ingress.Annotations,
+				TLSIssuer:   in.Spec.Ingress.TLSIssuer,
+				DisableTLS:  in.Spec.Ingress.DisableTLS,
+			}
+		}
+	}
+	dst.Status = in.Status
+	return nil
+}
+
+// ConvertFrom converts from the hub version (v1alpha1) to this version
+func (dst *SupabaseInstance) ConvertFrom(srcRaw conversion.Hub) error {
+	in := srcRaw.(*v1alpha1.SupabaseInstance).DeepCopy()
+
+	dst.ObjectMeta = in.ObjectMeta
+	dst.Spec = SupabaseInstanceSpec{
+		ProjectName:        in.Spec.ProjectName,
+		Storage:            in.Spec.Storage,
+		Database:           in.Spec.Database,
+		Resources:          in.Spec.Resources,
+		HighAvailability:   in.Spec.HighAvailability,
+		ChartVersion:       in.Spec.ChartVersion,
+		TemplateRef:        in.Spec.TemplateRef,
+		ProvisionerImage:   in.Spec.ProvisionerImage,
+		Adoption:           in.Spec.Adoption,
+		Paused:             in.Spec.Paused,
+		Profiles:           in.Spec.Profiles,
+		AutoRetry:          in.Spec.AutoRetry,
+		Provisioning:       in.Spec.Provisioning,
+		Placement:          in.Spec.Placement,
+		Isolation:          in.Spec.Isolation,
+		NetworkIsolation:   in.Spec.NetworkIsolation,
+		NamespaceLabels:    in.Spec.NamespaceLabels,
+		ConnectionPooler:   in.Spec.ConnectionPooler,
+		Monitoring:         in.Spec.Monitoring,
+		Suspension:         in.Spec.Suspension,
+		Maintenance:        in.Spec.Maintenance,
+		PublicStatusBadge:  in.Spec.PublicStatusBadge,
+		DeletionProtection: in.Spec.DeletionProtection,
+		TTL:                in.Spec.TTL,
+		Schedule:           in.Spec.Schedule,
+		Env:                in.Spec.Env,
+		Auth:               in.Spec.Auth,
+		Addons:             in.Spec.Addons,
+		AllowedConnections: in.Spec.AllowedConnections,
+		PendingDeletion:    in.Spec.PendingDeletion,
+		DNS:                in.Spec.DNS,
+	}
+	// An instance without ingress settings converts to one without an ingress block,
+	// so it round-trips unchanged
+	if in.Spec.IngressClass != "" || in.Spec.IngressDomain != "" || in.Spec.CustomDomains != nil || in.Spec.Ingress != nil {
+		dst.Spec.Ingress = &Ingress{
+			ClassName:     in.Spec.IngressClass,
+			Domain:        in.Spec.IngressDomain,
+			CustomDomains: in.Spec.CustomDomains,
+		}
+		if settings := in.Spec.Ingress; settings != nil {
+			dst.Spec.Ingress.Annotations = settings.Annotations
+			dst.Spec.Ingress.TLSIssuer = settings.TLSIssuer
+			dst.Spec.Ingress.DisableTLS = settings.DisableTLS
+		}
+	}
+	dst.Status = in.Status
+	return nil
+}
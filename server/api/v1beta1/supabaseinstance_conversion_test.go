@@ -0,0 +1,137 @@
+package v1beta1
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/conversion"
+
+	"github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+)
+
+func newHubInstance() *v1alpha1.SupabaseInstance {
+	size := resource.MustParse("20Gi")
+	memory := resource.MustParse("6Gi")
+	return &v1alpha1.SupabaseInstance{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "alpha",
+			Annotations: map[string]string{v1alpha1.AnnotationOwnerID: "7"},
+		},
+		Spec: v1alpha1.SupabaseInstanceSpec{
+			ProjectName:        "alpha",
+			IngressClass:       "nginx",
+			IngressDomain:      "supabase.example.com",
+			CustomDomains:      &v1alpha1.CustomDomains{API: "api.acme.io"},
+			ChartVersion:       "0.1.3",
+			TemplateRef:        "pro",
+			Profiles:           []string{"smtp"},
+			Storage:            &v1alpha1.Storage{Size: &size, ClassName: "fast"},
+			Database:           &v1alpha1.Database{ReadReplicas: 2, Audit: &v1alpha1.DatabaseAudit{Enabled: true}},
+			Provisioning:       &v1alpha1.Provisioning{TimeoutSeconds: 1800, Retries: ptr.To(int32(5))},
+			Resources:          &v1alpha1.Resources{Tier: v1alpha1.ResourceTierLarge, Memory: &memory},
+			HighAvailability:   &v1alpha1.HighAvailability{Replicas: 3},
+			Monitoring:         &v1alpha1.Monitoring{Enabled: true},
+			TTL:                &metav1.Duration{Duration: 48 * time.Hour},
+			Adoption:           &v1alpha1.Adoption{Namespace: "supabase", ReleaseName: "supabase"},
+			Schedule:           &v1alpha1.Schedule{Stop: "0 19 * * 1-5", Start: "0 7 * * 1-5", TimeZone: "Europe/Berlin"},
+			Env:                map[string]string{"GOTRUE_DISABLE_SIGNUP": "true"},
+			NamespaceLabels:    map[string]string{"cost-center": "cc-1234"},
+			Addons:             []string{"pgvector"},
+			AllowedConnections: []string{"analytics"},
+		},
+		Status: v1alpha1.SupabaseInstanceStatus{
+			Phase:     v1alpha1.PhaseRunning,
+			Namespace: "supa-alpha",
+			Conditions: []metav1.Condition{
+				{Type: v1alpha1.ConditionTypeReady, Status: metav1.ConditionTrue, Reason: "Running"},
+			},
+			Addons: []string{"pgvector"},
+		},
+	}
+}
+
+// TestConvertFrom tests that the ingress settings are grouped and the status is kept
+func TestConvertFrom(t *testing.T) {
+	hub := newHubInstance()
+
+	var instance SupabaseInstance
+	require.NoError(t, instance.ConvertFrom(hub))
+
+	require.NotNil(t, instance.Spec.Ingress)
+	assert.Equal(t, "nginx", instance.Spec.Ingress.ClassName)
+	assert.Equal(t, "supabase.example.com", instance.Spec.Ingress.Domain)
+	assert.Equal(t, "api.acme.io", instance.Spec.Ingress.CustomDomains.API)
+	assert.Equal(t, "fast", instance.Spec.Storage.ClassName)
+	assert.Equal(t, hub.Status, instance.Status)
+	assert.Equal(t, "7", instance.Annotations[v1alpha1.AnnotationOwnerID])
+
+	// The converted object does not share state with the hub
+	instance.Spec.Ingress.CustomDomains.API = "changed"
+	assert.Equal(t, "api.acme.io", hub.Spec.CustomDomains.API)
+}
+
+// TestConversionRoundTrip tests that converting to v1beta1 and back is lossless
+func TestConversionRoundTrip(t *testing.T) {
+	tests := []struct {
+		name   string
+		mutate func(*v1alpha1.SupabaseInstance)
+	}{
+		{name: "full spec"},
+		{name: "no ingress settings", mutate: func(hub *v1alpha1.SupabaseInstance) {
+			hub.Spec.IngressClass = ""
+			hub.Spec.IngressDomain = ""
+			hub.Spec.CustomDomains = nil
+		}},
+		{name: "only a custom domain", mutate: func(hub *v1alpha1.SupabaseInstance) {
+			hub.Spec.IngressClass = ""
+			hub.Spec.IngressDomain = ""
+		}},
+		{name: "only ingress settings", mutate: func(hub *v1alpha1.SupabaseInstance) {
+			hub.Spec.IngressClass = ""
+			hub.Spec.IngressDomain = ""
+			hub.Spec.CustomDomains = nil
+			hub.Spec.Ingress = &v1alpha1.IngressSettings{
+				Annotations: map[string]string{"nginx.ingress.kubernetes.io/proxy-body-size": "50m"},
+				TLSIssuer:   "letsencrypt-staging",
+			}
+		}},
+		{name: "TLS disabled", mutate: func(hub *v1alpha1.SupabaseInstance) {
+			hub.Spec.Ingress = &v1alpha1.IngressSettings{DisableTLS: true}
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hub := newHubInstance()
+			if tt.mutate != nil {
+				tt.mutate(hub)
+			}
+
+			var instance SupabaseInstance
+			require.NoError(t, instance.ConvertFrom(hub))
+			var back v1alpha1.SupabaseInstance
+			require.NoError(t, instance.ConvertTo(&back))
+
+			assert.Equal(t, hub.ObjectMeta, back.ObjectMeta)
+			assert.Equal(t, hub.Spec, back.Spec)
+			assert.Equal(t, hub.Status, back.Status)
+		})
+	}
+}
+
+// TestIsConvertible tests that the webhook can convert between the served versions
+func TestIsConvertible(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, v1alpha1.AddToScheme(scheme))
+	require.NoError(t, AddToScheme(scheme))
+
+	convertible, err := conversion.IsConvertible(scheme, &SupabaseInstance{})
+	require.NoError(t, err)
+	assert.True(t, convertible)
+}
@@ -0,0 +1,273 @@
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+)
+
+// Settings whose shape did not change between versions share the v1alpha1 types
+type (
+	CustomDomains          = v1alpha1.CustomDomains
+	DNSSettings            = v1alpha1.DNSSettings
+	AutoRetryPolicy        = v1alpha1.AutoRetryPolicy
+	Provisioning           = v1alpha1.Provisioning
+	Placement              = v1alpha1.Placement
+	Isolation              = v1alpha1.Isolation
+	ConnectionPooler       = v1alpha1.ConnectionPooler
+	Monitoring             = v1alpha1.Monitoring
+	Suspension             = v1alpha1.Suspension
+	Maintenance            = v1alpha1.Maintenance
+	Schedule               = v1alpha1.Schedule
+	Adoption               = v1alpha1.Adoption
+	Storage                = v1alpha1.Storage
+	Database               = v1alpha1.Database
+	DatabaseAudit          = v1alpha1.DatabaseAudit
+	Resources              = v1alpha1.Resources
+	HighAvailability       = v1alpha1.HighAvailability
+	AuthSettings           = v1alpha1.AuthSettings
+	PendingDeletion        = v1alpha1.PendingDeletion
+	SupabaseInstanceStatus = v1alpha1.SupabaseInstanceStatus
+)
+
+// SupabaseInstanceSpec defines the desired state of SupabaseInstance. Unlike v1alpha1,
+// the ingress settings are grouped in one block.
+type SupabaseInstanceSpec struct {
+	// ProjectName is the unique identifier for this Supabase instance
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([a-z0-9-]*[a-z0-9])?$`
+	ProjectName string `json:"projectName"`
+
+	// Ingress configures how the instance is exposed
+	// +optional
+	Ingress *Ingress `json:"ingress,omitempty"`
+
+	// DNS passes hints to external-dns when the controller publishes the instance's
+	// hostnames through it
+	// +optional
+	DNS *DNSSettings `json:"dns,omitempty"`
+
+	// Storage sizes the instance's Postgres volume and selects its StorageClass
+	// +optional
+	Storage *Storage `json:"storage,omitempty"`
+
+	// Database configures the instance's Postgres database
+	// +optional
+	Database *Database `json:"database,omitempty"`
+
+	// Resources sizes the CPU and memory of the instance's Postgres database. Changing it
+	// on a running instance resizes the database with a checkpointed restart.
+	// +optional
+	Resources *Resources `json:"resources,omitempty"`
+
+	// HighAvailability runs Kong, GoTrue and Realtime with several replicas spread
+	// across nodes, each guarded by a PodDisruptionBudget
+	// +optional
+	HighAvailability *HighAvailability `json:"highAvailability,omitempty"`
+
+	// ChartVersion specifies the Supabase Helm chart version to use. Changing it on a
+	// running instance upgrades the Helm release in place.
+	// +optional
+	ChartVersion string `json:"chartVersion,omitempty"`
+
+	// TemplateRef names the SupabaseInstanceTemplate the instance was created from. Its
+	// chart values are applied beneath the instance's own settings when the instance is
+	// provisioned or upgraded.
+	// +optional
+	TemplateRef string `json:"templateRef,omitempty"`
+
+	// ProvisionerImage overrides the image running the instance's provisioning, upgrade
+	// and cleanup Jobs, e.g. a mirror in an internal registry on air-gapped clusters
+	// +optional
+	ProvisionerImage string `json:"provisionerImage,omitempty"`
+
+	// Adoption takes over an existing Supabase Helm release instead of provisioning a new
+	// one: the controller verifies the release is deployed, labels its namespace and
+	// moves the instance to Running without running a provisioning Job
+	// +optional
+	Adoption *Adoption `json:"adoption,omitempty"`
+
+	// Paused stops the instance: once provisioned, all of its Deployments and
+	// StatefulSets are scaled to zero until Paused is cleared again
+	// +optional
+	Paused bool `json:"paused,omitempty"`
+
+	// Profiles names the shared service profiles (SMTP, S3, OAuth) whose settings
+	// are injected into the instance's chart values
+	// +optional
+	Profiles []string `json:"profiles,omitempty"`
+
+	// AutoRetry tunes automatic retries of failed provisioning, which back off
+	// exponentially. Transient failures are retried without it; failures that could
+	// not be classified only with it, and permanent failures wait for a manual retry.
+	// +optional
+	AutoRetry *AutoRetryPolicy `json:"autoRetry,omitempty"`
+
+	// Provisioning tunes the timeout and retries of the Job provisioning the instance
+	// +optional
+	Provisioning *Provisioning `json:"provisioning,omitempty"`
+
+	// Placement controls which nodes the instance's pods are scheduled on.
+	// It is applied when the instance is provisioned.
+	// +optional
+	Placement *Placement `json:"placement,omitempty"`
+
+	// Isolation selects how strongly the instance is separated from other tenants.
+	// It is applied when the instance is provisioned.
+	// +optional
+	Isolation *Isolation `json:"isolation,omitempty"`
+
+	// NetworkIsolation adds default-deny NetworkPolicies to the instance namespace that
+	// only admit traffic from the instance's own pods and the ingress controller, so other
+	// tenants cannot reach its database or services
+	// +optional
+	NetworkIsolation bool `json:"networkIsolation,omitempty"`
+
+	// NamespaceLabels are set on the instance namespace in addition to the labels the
+	// platform sets on every instance namespace, e.g. to attribute its costs to a team.
+	// Keys in the kubernetes.io, k8s.io and supacontrol.io domains are refused.
+	// +optional
+	// +kubebuilder:validation:MaxProperties=20
+	NamespaceLabels map[string]string `json:"namespaceLabels,omitempty"`
+
+	// ConnectionPooler deploys PgBouncer in front of the instance database.
+	// It is applied when the instance is provisioned.
+	// +optional
+	ConnectionPooler *ConnectionPooler `json:"connectionPooler,omitempty"`
+
+	// Monitoring deploys a Postgres exporter next to the instance database and a
+	// ServiceMonitor that lets the platform's Prometheus scrape it
+	// +optional
+	Monitoring *Monitoring `json:"monitoring,omitempty"`
+
+	// Suspension takes the instance offline on behalf of an administrator or the billing
+	// system: its workloads are scaled to zero and its ingresses are marked suspended.
+	// Unlike Paused, the instance owner cannot lift it.
+	// +optional
+	Suspension *Suspension `json:"suspension,omitempty"`
+
+	// Maintenance points the instance's ingresses at a maintenance page while it is set,
+	// e.g. during a manual upgrade. Unlike a suspension, the ingresses keep their hosts and
+	// the workloads keep running.
+	// +optional
+	Maintenance *Maintenance `json:"maintenance,omitempty"`
+
+	// PublicStatusBadge publishes an unauthenticated status badge for the instance at
+	// /badges/<projectName>/status.svg
+	// +optional
+	PublicStatusBadge bool `json:"publicStatusBadge,omitempty"`
+
+	// DeletionProtection refuses deletion of the instance through the API until it is
+	// cleared, and holds back the purge of an instance already pending deletion
+	// +optional
+	DeletionProtection bool `json:"deletionProtection,omitempty"`
+
+	// TTL makes the instance ephemeral: it is deleted once TTL has passed since its
+	// creation, unless deletion protection is set. An Expiring condition and a webhook
+	// notification warn an hour before. Extending the instance raises TTL.
+	// +optional
+	TTL *metav1.Duration `json:"ttl,omitempty"`
+
+	// Schedule stops and starts the instance automatically, e.g. to stop a development
+	// instance at night and on weekends. Stopping and starting it by hand in between is
+	// kept until the next scheduled change.
+	// +optional
+	Schedule *Schedule `json:"schedule,omitempty"`
+
+	// Env sets additional environment variables of the instance's Supabase components,
+	// such as GOTRUE_DISABLE_SIGNUP. Only allowlisted settings and feature flags are
+	// accepted; credentials belong in shared service profiles. It is applied when the
+	// instance is provisioned or upgraded.
+	// +optional
+	// +kubebuilder:validation:MaxProperties=50
+	Env map[string]string `json:"env,omitempty"`
+
+	// Auth configures the instance's auth service (GoTrue)
+	// +optional
+	Auth *AuthSettings `json:"auth,omitempty"`
+
+	// Addons names the add-ons enabled for the instance. Their chart values are applied
+	// when the instance is provisioned or upgraded, their manifests as soon as it runs.
+	// +optional
+	// +kubebuilder:validation:MaxItems=20
+	Addons []string `json:"addons,omitempty"`
+
+	// AllowedConnections names the instances whose pods may connect to this instance's
+	// database. Each one gets a read-only database role with regularly rotated credentials,
+	// published as Secret connection-<projectName> in its namespace. Connections are
+	// approved by the owners of both instances through the API.
+	// +optional
+	// +kubebuilder:validation:MaxItems=20
+	AllowedConnections []string `json:"allowedConnections,omitempty"`
+
+	// PendingDeletion moves the instance to the trash: its workloads are scaled to zero
+	// and it is purged once PurgeAfter has passed. Clearing it before then recovers the
+	// instance.
+	// +optional
+	PendingDeletion *PendingDeletion `json:"pendingDeletion,omitempty"`
+}
+
+// Ingress configures how an instance is exposed
+type Ingress struct {
+	// ClassName specifies the Kubernetes ingress class to use. Changing it on a running
+	// instance moves its ingresses to the new class in place.
+	// +optional
+	ClassName string `json:"className,omitempty"`
+
+	// Domain specifies the base domain for instance URLs. Changing it on a running instance
+	// moves its ingresses and URLs to the new domain.
+	// +optional
+	Domain string `json:"domain,omitempty"`
+
+	// CustomDomains serves the instance on customer-owned hostnames instead of the
+	// generated <projectName>-api and <projectName>-studio names under Domain
+	// +optional
+	CustomDomains *CustomDomains `json:"customDomains,omitempty"`
+
+	// Annotations are set on the instance's ingresses, e.g. to configure the ingress
+	// controller. They take precedence over the cert-manager annotation SupaControl sets.
+	// +optional
+	// +kubebuilder:validation:MaxProperties=50
+	Annotations map[string]string `json:"annotations,omitempty"`
+
+	// TLSIssuer is the cert-manager ClusterIssuer of the instance's certificates, instead
+	// of the server's default issuer
+	// +optional
+	// +kubebuilder:validation:MaxLength=253
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([a-z0-9.-]*[a-z0-9])?$`
+	TLSIssuer string `json:"tlsIssuer,omitempty"`
+
+	// DisableTLS serves the instance over plain HTTP without certificates, e.g. on
+	// bare-metal development clusters without cert-manager
+	// +optional
+	DisableTLS bool `json:"disableTLS,omitempty"`
+}
+
+// SupabaseInstance is the Schema for the supabaseinstances API
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:scope=Cluster,shortName=sbi;sbinst
+// +kubebuilder:printcolumn:name="Project",type=string,JSONPath=`.spec.projectName`
+// +kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+// +kubebuilder:printcolumn:name="Namespace",type=string,JSONPath=`.status.namespace`
+// +kubebuilder:printcolumn:name="Studio URL",type=string,JSONPath=`.status.studioUrl`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+type SupabaseInstance struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   SupabaseInstanceSpec   `json:"spec,omitempty"`
+	Status SupabaseInstanceStatus `json:"status,omitempty"`
+}
+
+// SupabaseInstanceList contains a list of SupabaseInstance
+// +kubebuilder:object:root=true
+type SupabaseInstanceList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []SupabaseInstance `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&SupabaseInstance{}, &SupabaseInstanceList{})
+}
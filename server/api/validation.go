@@ -0,0 +1,123 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	k8svalidation "k8s.io/apimachinery/pkg/util/validation"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+	"github.com/qubitquilt/supacontrol/server/internal/i18n"
+	"github.com/qubitquilt/supacontrol/server/internal/validate"
+)
+
+// bindRequest decodes the request body into req and checks its fields. Invalid fields
+// are reported together in an ErrorResponse with code validation_failed.
+func bindRequest(c echo.Context, req interface{}) error {
+	if err := c.Bind(req); err != nil {
+		return newAPIError(http.StatusBadRequest, apitypes.ErrorCodeInvalidBody, "invalid request body")
+	}
+	if err := validateRequest(req, time.Now()); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err)
+	}
+	return nil
+}
+
+// validateRequest checks the fields of a request struct that can be validated without
+// looking anything up. Handlers check the rest, e.g. whether a named instance exists.
+func validateRequest(req interface{}, now time.Time) error {
+	var v validate.Validator
+	switch r := req.(type) {
+	case *apitypes.LoginRequest:
+		v.Required("username", r.Username)
+		v.Required("password", r.Password)
+	case *apitypes.ChangePasswordRequest:
+		v.Required("current_password", r.CurrentPassword)
+		if len(r.NewPassword) < minPasswordLength {
+			v.Add("new_password", "must be at least %d characters", minPasswordLength)
+		}
+	case *apitypes.CreateAPIKeyRequest:
+		v.Required("name", r.Name)
+		v.MaxLength("name", r.Name, apitypes.MaxAPIKeyNameLength)
+		v.Future("expires_at", r.ExpiresAt, now)
+	case *apitypes.CreateInstanceRequest:
+		v.Required("name", r.Name)
+		v.DNSLabel("name", r.Name, apitypes.MaxInstanceNameLength)
+		for i, profile := range r.Profiles {
+			v.Required(fmt.Sprintf("profiles[%d]", i), profile)
+		}
+		if r.Schedule != nil {
+			v.Required("schedule.stop", r.Schedule.Stop)
+			v.Required("schedule.start", r.Schedule.Start)
+		}
+		validateTags(&v, r.Tags)
+	case *apitypes.ImportInstanceRequest:
+		v.Required("name", r.Name)
+		v.DNSLabel("name", r.Name, apitypes.MaxInstanceNameLength)
+		v.Required("namespace", r.Namespace)
+		v.DNSLabel("namespace", r.Namespace, k8svalidation.DNS1123LabelMaxLength)
+		v.DNSLabel("release_name", r.ReleaseName, apitypes.MaxReleaseNameLength)
+	case *apitypes.CreateConnectionRequest:
+		v.Required("source_instance", r.SourceInstance)
+		v.DNSLabel("source_instance", strings.TrimSpace(r.SourceInstance), apitypes.MaxInstanceNameLength)
+		v.Required("target_instance", r.TargetInstance)
+		v.DNSLabel("target_instance", strings.TrimSpace(r.TargetInstance), apitypes.MaxInstanceNameLength)
+	case *apitypes.CreateUpgradeRequest:
+		v.Required("chart_version", r.ChartVersion)
+		if len(r.Instances) == 0 {
+			v.Add("instances", "must list at least one instance")
+		}
+		for i, name := range r.Instances {
+			field := fmt.Sprintf("instances[%d]", i)
+			v.Required(field, name)
+			v.DNSLabel(field, name, apitypes.MaxInstanceNameLength)
+		}
+		v.NonNegative("max_failures", r.MaxFailures)
+	case *apitypes.EnableAddonRequest:
+		v.Required("name", r.Name)
+	case *apitypes.ExtendInstanceRequest:
+		v.Required("duration", r.Duration)
+	case *apitypes.ResizeStorageRequest:
+		v.Required("size", r.Size)
+	case *apitypes.CreateBucketRequest:
+		v.Required("name", r.Name)
+		v.MaxLength("name", r.Name, apitypes.MaxBucketNameLength)
+		if r.FileSizeLimit != nil && *r.FileSizeLimit < 0 {
+			v.Add("file_size_limit", "must not be negative")
+		}
+	case *apitypes.InstanceSchedule:
+		v.Required("stop", r.Stop)
+		v.Required("start", r.Start)
+	case *apitypes.UpdateInstanceRequest:
+		if r.Resources != nil && r.Resources.Tier == "" && r.Resources.CPU == "" && r.Resources.Memory == "" {
+			v.Add("resources", "is required")
+		}
+		if r.Resources == nil && len(r.Tags) == 0 {
+			v.Add("resources", "is required unless tags are changed")
+		}
+		validateTagChanges(&v, r.Tags)
+	case *apitypes.CreateTeamRequest:
+		v.Required("name", r.Name)
+		v.MaxLength("name", r.Name, apitypes.MaxTeamNameLength)
+	case *apitypes.CreateInvitationRequest:
+		v.Required("email", r.Email)
+		v.Email("email", strings.TrimSpace(r.Email))
+		v.NonNegative("expires_in_hours", r.ExpiresInHours)
+	case *apitypes.AcceptInvitationRequest:
+		v.Required("token", r.Token)
+	}
+	return v.Err()
+}
+
+// localizeFieldErrors translates field errors into the request's locale
+func localizeFieldErrors(c echo.Context, errs validate.Errors) []apitypes.FieldError {
+	locale := GetLocale(c)
+	fields := make([]apitypes.FieldError, len(errs))
+	for i, fe := range errs {
+		fields[i] = apitypes.FieldError{Field: fe.Field, Message: i18n.Default().Localize(locale, fe.Message)}
+	}
+	return fields
+}
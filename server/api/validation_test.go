@@ -0,0 +1,109 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+	"github.com/qubitquilt/supacontrol/server/internal/validate"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestValidateRequest tests the field rules of request structs
+func TestValidateRequest(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	past := now.Add(-time.Minute)
+	future := now.Add(24 * time.Hour)
+
+	tests := []struct {
+		name       string
+		req        interface{}
+		wantFields []string
+	}{
+		{name: "valid instance", req: &apitypes.CreateInstanceRequest{Name: "my-app"}},
+		{name: "instance name missing", req: &apitypes.CreateInstanceRequest{}, wantFields: []string{"name"}},
+		{name: "instance name not a DNS label", req: &apitypes.CreateInstanceRequest{Name: "My_App"}, wantFields: []string{"name"}},
+		{
+			name:       "instance name too long for its namespace",
+			req:        &apitypes.CreateInstanceRequest{Name: "a123456789b123456789c123456789d123456789e123456789f12345678"},
+			wantFields: []string{"name"},
+		},
+		{name: "empty profile name", req: &apitypes.CreateInstanceRequest{Name: "app", Profiles: []string{"mail", ""}}, wantFields: []string{"profiles[1]"}},
+		{name: "valid API key", req: &apitypes.CreateAPIKeyRequest{Name: "ci", ExpiresAt: &future}},
+		{name: "API key expired", req: &apitypes.CreateAPIKeyRequest{Name: "ci", ExpiresAt: &past}, wantFields: []string{"expires_at"}},
+		{name: "API key name missing", req: &apitypes.CreateAPIKeyRequest{}, wantFields: []string{"name"}},
+		{name: "short new password", req: &apitypes.ChangePasswordRequest{CurrentPassword: "old", NewPassword: "short"}, wantFields: []string{"new_password"}},
+		{name: "login fields missing", req: &apitypes.LoginRequest{}, wantFields: []string{"username", "password"}},
+		{name: "invalid invitation", req: &apitypes.CreateInvitationRequest{Email: "jane", ExpiresInHours: -1}, wantFields: []string{"email", "expires_in_hours"}},
+		{name: "padded invitation email", req: &apitypes.CreateInvitationRequest{Email: " jane@example.com "}},
+		{
+			name:       "upgrade instances",
+			req:        &apitypes.CreateUpgradeRequest{ChartVersion: "1.2.0", Instances: []string{"app", "Bad!"}, MaxFailures: -1},
+			wantFields: []string{"instances[1]", "max_failures"},
+		},
+		{name: "upgrade without instances", req: &apitypes.CreateUpgradeRequest{ChartVersion: "1.2.0"}, wantFields: []string{"instances"}},
+		{name: "connection", req: &apitypes.CreateConnectionRequest{SourceInstance: "app", TargetInstance: ""}, wantFields: []string{"target_instance"}},
+		{name: "request without rules", req: &apitypes.UpdateStatusBadgeRequest{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateRequest(tt.req, now)
+			if len(tt.wantFields) == 0 {
+				assert.NoError(t, err)
+				return
+			}
+			errs, ok := err.(validate.Errors)
+			require.True(t, ok, "expected validate.Errors, got %T", err)
+			fields := make([]string, len(errs))
+			for i, fe := range errs {
+				fields[i] = fe.Field
+			}
+			assert.Equal(t, tt.wantFields, fields)
+		})
+	}
+}
+
+// TestBindRequest tests that invalid fields are returned as a 400 carrying the field errors
+func TestBindRequest(t *testing.T) {
+	c, _ := newTestContext(http.MethodPost, "/api/v1/instances", `{"name":"Not Valid"}`)
+
+	var req apitypes.CreateInstanceRequest
+	err := bindRequest(c, &req)
+
+	httpErr, ok := err.(*echo.HTTPError)
+	require.True(t, ok, "expected *echo.HTTPError, got %T", err)
+	assert.Equal(t, http.StatusBadRequest, httpErr.Code)
+	errs, ok := httpErr.Message.(validate.Errors)
+	require.True(t, ok, "expected validate.Errors message, got %T", httpErr.Message)
+	assert.Equal(t, "name", errs[0].Field)
+
+	c, _ = newTestContext(http.MethodPost, "/api/v1/instances", `{"name":`)
+	err = bindRequest(c, &req)
+	httpErr, ok = err.(*echo.HTTPError)
+	require.True(t, ok, "expected *echo.HTTPError, got %T", err)
+	assert.Equal(t, &codedMessage{Code: apitypes.ErrorCodeInvalidBody, Message: "invalid request body"}, httpErr.Message)
+}
+
+// TestValidationErrorResponse tests that field errors are written as a localized ErrorResponse
+func TestValidationErrorResponse(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/teams", nil)
+	req.Header.Set("Accept-Language", "de")
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	err := validateRequest(&apitypes.CreateTeamRequest{}, time.Now())
+	LocalizedHTTPErrorHandler(e.DefaultHTTPErrorHandler)(echo.NewHTTPError(http.StatusBadRequest, err), c)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.JSONEq(t, `{
+		"code": "validation_failed",
+		"message": "Validierung der Anfrage fehlgeschlagen",
+		"errors": [{"field": "name", "message": "ist erforderlich"}]
+	}`, rec.Body.String())
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// newAPIKeyCmd builds the "apikey" command group
+func newAPIKeyCmd(opts *globalOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "apikey",
+		Aliases: []string{"apikeys"},
+		Short:   "Manage API keys",
+	}
+
+	var expiresIn time.Duration
+	createCmd := &cobra.Command{
+		Use:   "create NAME",
+		Short: "Create a new API key",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := opts.newClient()
+			if err != nil {
+				return err
+			}
+
+			var expiresAt *time.Time
+			if expiresIn > 0 {
+				t := time.Now().Add(expiresIn).UTC()
+				expiresAt = &t
+			}
+
+			resp, err := client.CreateAPIKey(cmd.Context(), args[0], expiresAt)
+			if err != nil {
+				return err
+			}
+			if opts.output == outputJSON {
+				return printJSON(cmd.OutOrStdout(), resp)
+			}
+
+			fmt.Fprintln(cmd.OutOrStdout(), resp.Message)
+			fmt.Fprintln(cmd.OutOrStdout())
+			expires := "never"
+			if resp.APIKey != nil && resp.APIKey.ExpiresAt != nil {
+				expires = formatTime(*resp.APIKey.ExpiresAt)
+			}
+			return printTable(cmd.OutOrStdout(), []string{"NAME", "KEY", "EXPIRES"}, [][]string{{args[0], resp.Key, expires}})
+		},
+	}
+	createCmd.Flags().DurationVar(&expiresIn, "expires-in", 0, "Key lifetime, e.g. 720h (default: never expires)")
+	cmd.AddCommand(createCmd)
+
+	return cmd
+}
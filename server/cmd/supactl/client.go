@@ -0,0 +1,180 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+)
+
+// Client is a minimal SupaControl REST API client authenticated with an API key
+type Client struct {
+	baseURL    string
+	apiKey     string
+	httpClient *http.Client
+}
+
+// APIError is returned when the server responds with a non-2xx status. Code is the
+// server's machine-readable error code (empty for responses without one), Fields lists
+// the invalid request fields reported by the server, if any, and RequestID identifies
+// the request in the server logs.
+type APIError struct {
+	StatusCode int
+	Code       apitypes.ErrorCode
+	Message    string
+	Details    map[string]string
+	Fields     []apitypes.FieldError
+	RequestID  string
+}
+
+func (e *APIError) Error() string {
+	msg := e.Message
+	for _, fe := range e.Fields {
+		msg += fmt.Sprintf("; %s: %s", fe.Field, fe.Message)
+	}
+	return fmt.Sprintf("%s (HTTP %d)", msg, e.StatusCode)
+}
+
+// NewClient creates a client for the SupaControl server at baseURL
+func NewClient(baseURL, apiKey string) *Client {
+	return &Client{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		apiKey:     apiKey,
+		httpClient: &http.Client{Timeout: 60 * time.Second},
+	}
+}
+
+// do performs a request against /api/v1 and returns the raw response body
+func (c *Client) do(ctx context.Context, method, path string, body interface{}) ([]byte, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode request: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+"/api/v1"+path, reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		apiErr := &APIError{StatusCode: resp.StatusCode, Message: http.StatusText(resp.StatusCode)}
+		var errBody apitypes.ErrorResponse
+		if json.Unmarshal(data, &errBody) == nil && errBody.Message != "" {
+			apiErr.Code = errBody.Code
+			apiErr.Message = errBody.Message
+			apiErr.Details = errBody.Details
+			apiErr.Fields = errBody.Errors
+			apiErr.RequestID = errBody.RequestID
+		}
+		return nil, apiErr
+	}
+
+	return data, nil
+}
+
+// doJSON performs a request and decodes the JSON response into out
+func (c *Client) doJSON(ctx context.Context, method, path string, body, out interface{}) error {
+	data, err := c.do(ctx, method, path, body)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// CreateInstance requests a new Supabase instance
+func (c *Client) CreateInstance(ctx context.Context, name string) (*apitypes.CreateInstanceResponse, error) {
+	var resp apitypes.CreateInstanceResponse
+	if err := c.doJSON(ctx, http.MethodPost, "/instances", apitypes.CreateInstanceRequest{Name: name}, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// ListInstances lists all instances, or those with every tag of tags (key:value or key)
+func (c *Client) ListInstances(ctx context.Context, tags ...string) (*apitypes.ListInstancesResponse, error) {
+	path := "/instances"
+	if len(tags) > 0 {
+		path += "?" + url.Values{"tag": tags}.Encode()
+	}
+	var resp apitypes.ListInstancesResponse
+	if err := c.doJSON(ctx, http.MethodGet, path, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// GetInstance gets a single instance
+func (c *Client) GetInstance(ctx context.Context, name string) (*apitypes.GetInstanceResponse, error) {
+	var resp apitypes.GetInstanceResponse
+	if err := c.doJSON(ctx, http.MethodGet, "/instances/"+url.PathEscape(name), nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// DeleteInstance requests deletion of an instance. With force, the finalizer of an
+// instance whose deletion is stuck is removed instead, skipping its cleanup.
+func (c *Client) DeleteInstance(ctx context.Context, name string, force bool) (*apitypes.DeleteInstanceResponse, error) {
+	path := "/instances/" + url.PathEscape(name)
+	if force {
+		path += "?force=true"
+	}
+	var resp apitypes.DeleteInstanceResponse
+	if err := c.doJSON(ctx, http.MethodDelete, path, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// GetLogs retrieves the most recent log lines of every container in an instance
+func (c *Client) GetLogs(ctx context.Context, name string, lines int) (string, error) {
+	path := "/instances/" + url.PathEscape(name) + "/logs"
+	if lines > 0 {
+		path += "?lines=" + strconv.Itoa(lines)
+	}
+	data, err := c.do(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// CreateAPIKey creates a new API key for the authenticated user
+func (c *Client) CreateAPIKey(ctx context.Context, name string, expiresAt *time.Time) (*apitypes.CreateAPIKeyResponse, error) {
+	var resp apitypes.CreateAPIKeyResponse
+	req := apitypes.CreateAPIKeyRequest{Name: name, ExpiresAt: expiresAt}
+	if err := c.doJSON(ctx, http.MethodPost, "/auth/api-keys", req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+)
+
+func TestClient_SendsAPIKeyAndDecodesResponse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer sk_test", r.Header.Get("Authorization"))
+		assert.Equal(t, "/api/v1/instances/demo", r.URL.Path)
+		_ = json.NewEncoder(w).Encode(apitypes.GetInstanceResponse{
+			Instance: &apitypes.Instance{ProjectName: "demo", Status: apitypes.StatusRunning},
+		})
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL+"/", "sk_test")
+	resp, err := client.GetInstance(context.Background(), "demo")
+	require.NoError(t, err)
+	assert.Equal(t, "demo", resp.Instance.ProjectName)
+	assert.Equal(t, apitypes.StatusRunning, resp.Instance.Status)
+}
+
+func TestClient_ReturnsAPIError(t *testing.T) {
+	tests := []struct {
+		name         string
+		body         string
+		expectedCode apitypes.ErrorCode
+		expectedMsg  string
+		expectedErr  string
+	}{
+		{
+			name:         "server message",
+			body:         `{"code":"instance_not_found","message":"instance not found","request_id":"abc"}`,
+			expectedCode: apitypes.ErrorCodeInstanceNotFound,
+			expectedMsg:  "instance not found",
+		},
+		{
+			name:        "message without code",
+			body:        `{"message":"instance not found"}`,
+			expectedMsg: "instance not found",
+		},
+		{
+			name:        "non-JSON body",
+			body:        "oops",
+			expectedMsg: "Not Found",
+		},
+		{
+			name:        "field errors",
+			body:        `{"message":"request validation failed","errors":[{"field":"name","message":"is required"}]}`,
+			expectedMsg: "request validation failed",
+			expectedErr: "request validation failed; name: is required (HTTP 404)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusNotFound)
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			_, err := NewClient(server.URL, "sk_test").GetInstance(context.Background(), "missing")
+			require.Error(t, err)
+
+			var apiErr *APIError
+			require.True(t, errors.As(err, &apiErr))
+			assert.Equal(t, http.StatusNotFound, apiErr.StatusCode)
+			assert.Equal(t, tt.expectedCode, apiErr.Code)
+			assert.Equal(t, tt.expectedMsg, apiErr.Message)
+			if tt.expectedErr != "" {
+				assert.Equal(t, tt.expectedErr, apiErr.Error())
+			}
+		})
+	}
+}
+
+func TestClient_CreateAPIKeySendsExpiry(t *testing.T) {
+	expiresAt := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "/api/v1/auth/api-keys", r.URL.Path)
+
+		var req apitypes.CreateAPIKeyRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, "ci", req.Name)
+		require.NotNil(t, req.ExpiresAt)
+		assert.True(t, expiresAt.Equal(*req.ExpiresAt))
+
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(apitypes.CreateAPIKeyResponse{Key: "sk_new", Message: "API key created successfully"})
+	}))
+	defer server.Close()
+
+	resp, err := NewClient(server.URL, "sk_test").CreateAPIKey(context.Background(), "ci", &expiresAt)
+	require.NoError(t, err)
+	assert.Equal(t, "sk_new", resp.Key)
+}
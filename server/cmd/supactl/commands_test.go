@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+)
+
+// runCommand executes supactl with args against server and returns stdout
+func runCommand(t *testing.T, serverURL string, args ...string) (string, error) {
+	t.Helper()
+	t.Setenv("SUPACONTROL_URL", serverURL)
+	t.Setenv("SUPACONTROL_API_KEY", "sk_test")
+
+	cmd := newRootCmd()
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetErr(&out)
+	cmd.SetArgs(args)
+	err := cmd.Execute()
+	return out.String(), err
+}
+
+func newInstancesServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/api/v1/instances":
+			_ = json.NewEncoder(w).Encode(apitypes.ListInstancesResponse{
+				Instances: []*apitypes.Instance{
+					{ProjectName: "alpha", Namespace: "supa-alpha", Status: apitypes.StatusRunning},
+					{ProjectName: "beta", Namespace: "supa-beta", Status: apitypes.StatusProvisioning},
+				},
+				Count: 2,
+			})
+		case r.Method == http.MethodDelete && r.URL.Path == "/api/v1/instances/alpha":
+			w.WriteHeader(http.StatusAccepted)
+			_ = json.NewEncoder(w).Encode(apitypes.DeleteInstanceResponse{Message: "Instance deletion initiated"})
+		case r.Method == http.MethodGet && r.URL.Path == "/api/v1/instances/alpha/logs":
+			assert.Equal(t, "20", r.URL.Query().Get("lines"))
+			_, _ = w.Write([]byte("=== Pod: db ===\nready\n"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"message":"instance not found"}`))
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestCommands(t *testing.T) {
+	server := newInstancesServer(t)
+
+	tests := []struct {
+		name          string
+		args          []string
+		expectedError string
+		contains      []string
+	}{
+		{
+			name:     "list as table",
+			args:     []string{"instance", "list"},
+			contains: []string{"NAME", "STATUS", "alpha", "running", "supa-beta"},
+		},
+		{
+			name:     "list as json",
+			args:     []string{"instance", "list", "-o", "json"},
+			contains: []string{`"count": 2`, `"project_name": "alpha"`},
+		},
+		{
+			name:     "delete",
+			args:     []string{"instance", "delete", "alpha"},
+			contains: []string{"Instance deletion initiated"},
+		},
+		{
+			name:     "logs",
+			args:     []string{"instance", "logs", "alpha", "--lines", "20"},
+			contains: []string{"=== Pod: db ===", "ready"},
+		},
+		{
+			name:          "server error",
+			args:          []string{"instance", "get", "missing"},
+			expectedError: "instance not found (HTTP 404)",
+		},
+		{
+			name:          "invalid output format",
+			args:          []string{"instance", "list", "-o", "yaml"},
+			expectedError: `unsupported output format "yaml"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := runCommand(t, server.URL, tt.args...)
+			if tt.expectedError != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			for _, s := range tt.contains {
+				assert.Contains(t, out, s)
+			}
+		})
+	}
+}
+
+func TestCommands_RequireCredentials(t *testing.T) {
+	t.Setenv("SUPACONTROL_URL", "")
+	t.Setenv("SUPACONTROL_API_KEY", "")
+
+	cmd := newRootCmd()
+	cmd.SetArgs([]string{"--server", "http://localhost:8091", "instance", "list"})
+	err := cmd.Execute()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "API key is required")
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+)
+
+// newInstanceCmd builds the "instance" command group
+func newInstanceCmd(opts *globalOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "instance",
+		Aliases: []string{"instances", "inst"},
+		Short:   "Manage Supabase instances",
+	}
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "create NAME",
+		Short: "Create a new instance",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := opts.newClient()
+			if err != nil {
+				return err
+			}
+			resp, err := client.CreateInstance(cmd.Context(), args[0])
+			if err != nil {
+				return err
+			}
+			if opts.output == outputJSON {
+				return printJSON(cmd.OutOrStdout(), resp)
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), resp.Message)
+			return printInstances(cmd, []*apitypes.Instance{resp.Instance})
+		},
+	})
+
+	var tags []string
+	listCmd := &cobra.Command{
+		Use:     "list",
+		Aliases: []string{"ls"},
+		Short:   "List instances",
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			client, err := opts.newClient()
+			if err != nil {
+				return err
+			}
+			resp, err := client.ListInstances(cmd.Context(), tags...)
+			if err != nil {
+				return err
+			}
+			if opts.output == outputJSON {
+				return printJSON(cmd.OutOrStdout(), resp)
+			}
+			return printInstances(cmd, resp.Instances)
+		},
+	}
+	listCmd.Flags().StringArrayVar(&tags, "tag", nil, "Only list instances with this tag, as key:value or key (repeatable)")
+	cmd.AddCommand(listCmd)
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "get NAME",
+		Short: "Show an instance",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := opts.newClient()
+			if err != nil {
+				return err
+			}
+			resp, err := client.GetInstance(cmd.Context(), args[0])
+			if err != nil {
+				return err
+			}
+			if opts.output == outputJSON {
+				return printJSON(cmd.OutOrStdout(), resp)
+			}
+			return printInstances(cmd, []*apitypes.Instance{resp.Instance})
+		},
+	})
+
+	var force bool
+	deleteCmd := &cobra.Command{
+		Use:     "delete NAME",
+		Aliases: []string{"rm"},
+		Short:   "Delete an instance",
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := opts.newClient()
+			if err != nil {
+				return err
+			}
+			resp, err := client.DeleteInstance(cmd.Context(), args[0], force)
+			if err != nil {
+				return err
+			}
+			if opts.output == outputJSON {
+				return printJSON(cmd.OutOrStdout(), resp)
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), resp.Message)
+			return nil
+		},
+	}
+	deleteCmd.Flags().BoolVar(&force, "force", false, "Remove the finalizer of an instance whose deletion is stuck, skipping cleanup (admins only)")
+	cmd.AddCommand(deleteCmd)
+
+	var lines int
+	logsCmd := &cobra.Command{
+		Use:   "logs NAME",
+		Short: "Print recent logs from an instance's containers",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := opts.newClient()
+			if err != nil {
+				return err
+			}
+			logs, err := client.GetLogs(cmd.Context(), args[0], lines)
+			if err != nil {
+				return err
+			}
+			if opts.output == outputJSON {
+				return printJSON(cmd.OutOrStdout(), map[string]string{"logs": logs})
+			}
+			fmt.Fprint(cmd.OutOrStdout(), logs)
+			return nil
+		},
+	}
+	logsCmd.Flags().IntVarP(&lines, "lines", "n", 100, "Number of lines per container")
+	cmd.AddCommand(logsCmd)
+
+	return cmd
+}
+
+// printInstances renders instances as a table
+func printInstances(cmd *cobra.Command, instances []*apitypes.Instance) error {
+	rows := make([][]string, 0, len(instances))
+	for _, inst := range instances {
+		if inst == nil {
+			continue
+		}
+		rows = append(rows, []string{
+			inst.ProjectName,
+			string(inst.Status),
+			inst.Namespace,
+			inst.StudioURL,
+			inst.APIURL,
+			formatTime(inst.CreatedAt),
+		})
+	}
+	return printTable(cmd.OutOrStdout(), []string{"NAME", "STATUS", "NAMESPACE", "STUDIO URL", "API URL", "CREATED"}, rows)
+}
@@ -0,0 +1,22 @@
+// Command supactl is a command-line client for the SupaControl REST API.
+//
+// It authenticates with an API key and supports managing instances and API keys:
+//
+//	supactl instance create|list|get|delete|logs
+//	supactl apikey create
+//
+// The server URL and API key are read from the --server and --api-key flags,
+// or from the SUPACONTROL_URL and SUPACONTROL_API_KEY environment variables.
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+func main() {
+	if err := newRootCmd().Execute(); err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+}
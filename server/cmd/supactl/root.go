@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+)
+
+// globalOptions holds the flags shared by every command
+type globalOptions struct {
+	serverURL string
+	apiKey    string
+	output    string
+}
+
+// newRootCmd builds the supactl command tree
+func newRootCmd() *cobra.Command {
+	opts := &globalOptions{}
+
+	cmd := &cobra.Command{
+		Use:           "supactl",
+		Short:         "Manage Supabase instances through a SupaControl server",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		PersistentPreRunE: func(cmd *cobra.Command, _ []string) error {
+			if opts.output != outputTable && opts.output != outputJSON {
+				return fmt.Errorf("unsupported output format %q (use %s or %s)", opts.output, outputTable, outputJSON)
+			}
+			return nil
+		},
+	}
+
+	cmd.PersistentFlags().StringVar(&opts.serverURL, "server", os.Getenv("SUPACONTROL_URL"), "SupaControl server URL (env SUPACONTROL_URL)")
+	cmd.PersistentFlags().StringVar(&opts.apiKey, "api-key", os.Getenv("SUPACONTROL_API_KEY"), "API key used to authenticate (env SUPACONTROL_API_KEY)")
+	cmd.PersistentFlags().StringVarP(&opts.output, "output", "o", outputTable, "Output format: table or json")
+
+	cmd.AddCommand(newInstanceCmd(opts))
+	cmd.AddCommand(newAPIKeyCmd(opts))
+
+	return cmd
+}
+
+// newClient builds an API client from the global options
+func (o *globalOptions) newClient() (*Client, error) {
+	if o.serverURL == "" {
+		return nil, fmt.Errorf("server URL is required (set --server or SUPACONTROL_URL)")
+	}
+	if o.apiKey == "" {
+		return nil, fmt.Errorf("API key is required (set --api-key or SUPACONTROL_API_KEY)")
+	}
+	return NewClient(o.serverURL, o.apiKey), nil
+}
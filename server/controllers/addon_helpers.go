@@ -0,0 +1,181 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+	"github.com/qubitquilt/supacontrol/server/internal/addons"
+)
+
+const (
+	// AddonLabel is the label key holding the add-on an object was applied for
+	AddonLabel = "supacontrol.io/addon"
+
+	// addonFieldOwner is the field manager add-on manifests are applied with
+	addonFieldOwner = "supacontrol-addons"
+)
+
+// addonParams returns the instance details add-on templates are rendered with
+func (r *SupabaseInstanceReconciler) addonParams(instance *supacontrolv1alpha1.SupabaseInstance) addons.Params {
+	namespace := r.instanceNamespace(instance)
+	release := instance.Status.HelmReleaseName
+	if release == "" {
+		release = instance.Spec.ProjectName
+	}
+	return addons.Params{
+		Instance:       instance.Spec.ProjectName,
+		Namespace:      namespace,
+		Release:        release,
+		DatabaseHost:   fmt.Sprintf("%s-db.%s.svc.cluster.local", release, namespace),
+		DatabaseSecret: InstanceSecretName(instance.Spec.ProjectName),
+	}
+}
+
+// setAddonValues merges the chart values of an instance's add-ons into its chart values.
+// Unknown add-ons are skipped; reconcileAddons reports them.
+func (r *SupabaseInstanceReconciler) setAddonValues(values map[string]interface{}, instance *supacontrolv1alpha1.SupabaseInstance) error {
+	params := r.addonParams(instance)
+	for _, name := range instance.Spec.Addons {
+		addon, ok := addons.Get(name)
+		if !ok || !addon.HasValues() {
+			continue
+		}
+		addonValues, err := addon.Values(params)
+		if err != nil {
+			return err
+		}
+		addons.MergeValues(values, addonValues)
+	}
+	return nil
+}
+
+// reconcileAddons applies the manifests of a running instance's add-ons in its namespace
+// and deletes those of add-ons removed from the spec. The objects are owned by the
+// instance, so they are removed with it. The outcome is recorded in the AddonsReady
+// condition; unknown add-ons fail the condition rather than the reconcile.
+func (r *SupabaseInstanceReconciler) reconcileAddons(ctx context.Context, instance *supacontrolv1alpha1.SupabaseInstance) error {
+	if len(instance.Spec.Addons) == 0 && len(instance.Status.Addons) == 0 &&
+		meta.FindStatusCondition(instance.Status.Conditions, supacontrolv1alpha1.ConditionTypeAddonsReady) == nil {
+		return nil
+	}
+
+	condition := metav1.Condition{
+		Type:               supacontrolv1alpha1.ConditionTypeAddonsReady,
+		ObservedGeneration: instance.Generation,
+	}
+	params := r.addonParams(instance)
+
+	// Delete the manifests of removed add-ons first, so an add-on that was removed and
+	// added again in one spec change is re-applied below
+	for _, name := range instance.Status.Addons {
+		if slices.Contains(instance.Spec.Addons, name) {
+			continue
+		}
+		if err := r.deleteAddonManifests(ctx, name, params); err != nil {
+			return err
+		}
+	}
+
+	if len(instance.Spec.Addons) == 0 {
+		instance.Status.Addons = nil
+		meta.RemoveStatusCondition(&instance.Status.Conditions, condition.Type)
+		return r.updateStatus(ctx, instance)
+	}
+
+	var applied, unknown []string
+	if instance.Status.IsolationLevel == supacontrolv1alpha1.IsolationVCluster {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "IsolationUnsupported"
+		condition.Message = "Add-ons cannot be applied to vcluster instances"
+	} else {
+		for _, name := range instance.Spec.Addons {
+			addon, ok := addons.Get(name)
+			if !ok {
+				unknown = append(unknown, name)
+				continue
+			}
+			if addon.HasManifests() {
+				if err := r.applyAddonManifests(ctx, instance, addon, params); err != nil {
+					return err
+				}
+				applied = append(applied, name)
+			}
+		}
+		switch {
+		case len(unknown) > 0:
+			condition.Status = metav1.ConditionFalse
+			condition.Reason = "UnknownAddon"
+			condition.Message = "Unknown add-ons: " + strings.Join(unknown, ", ")
+		default:
+			condition.Status = metav1.ConditionTrue
+			condition.Reason = "Applied"
+			condition.Message = "Add-ons applied: " + strings.Join(instance.Spec.Addons, ", ")
+		}
+	}
+
+	if existing := meta.FindStatusCondition(instance.Status.Conditions, condition.Type); existing != nil &&
+		existing.Status == condition.Status && existing.Reason == condition.Reason &&
+		existing.Message == condition.Message && existing.ObservedGeneration == condition.ObservedGeneration &&
+		slices.Equal(instance.Status.Addons, applied) {
+		return nil
+	}
+	instance.Status.Addons = applied
+	meta.SetStatusCondition(&instance.Status.Conditions, condition)
+	return r.updateStatus(ctx, instance)
+}
+
+// applyAddonManifests applies the manifests of an add-on in the instance namespace with
+// server-side apply, which leaves fields set by other managers, such as Job defaults, alone
+func (r *SupabaseInstanceReconciler) applyAddonManifests(ctx context.Context, instance *supacontrolv1alpha1.SupabaseInstance, addon *addons.Addon, params addons.Params) error {
+	objects, err := addon.Manifests(params)
+	if err != nil {
+		return err
+	}
+	for _, object := range objects {
+		object.SetNamespace(params.Namespace)
+		labels := object.GetLabels()
+		if labels == nil {
+			labels = map[string]string{}
+		}
+		labels["app.kubernetes.io/managed-by"] = "supacontrol"
+		labels[JobInstanceLabel] = instance.Spec.ProjectName
+		labels[AddonLabel] = addon.Name
+		object.SetLabels(labels)
+		if err := controllerutil.SetControllerReference(instance, object, r.Scheme); err != nil {
+			return fmt.Errorf("failed to set controller reference: %w", err)
+		}
+		if err := r.Patch(ctx, object, client.Apply, client.FieldOwner(addonFieldOwner), client.ForceOwnership); err != nil {
+			return fmt.Errorf("failed to apply %s %s of add-on %s: %w", object.GetKind(), object.GetName(), addon.Name, err)
+		}
+	}
+	return nil
+}
+
+// deleteAddonManifests deletes the objects an add-on applied in the instance namespace
+func (r *SupabaseInstanceReconciler) deleteAddonManifests(ctx context.Context, name string, params addons.Params) error {
+	addon, ok := addons.Get(name)
+	if !ok {
+		return nil
+	}
+	objects, err := addon.Manifests(params)
+	if err != nil {
+		return err
+	}
+	for _, object := range objects {
+		object.SetNamespace(params.Namespace)
+		err := r.Delete(ctx, object, client.PropagationPolicy(metav1.DeletePropagationBackground))
+		if err != nil && !apierrors.IsNotFound(err) && !meta.IsNoMatchError(err) {
+			return fmt.Errorf("failed to delete %s %s of add-on %s: %w", object.GetKind(), object.GetName(), name, err)
+		}
+	}
+	return nil
+}
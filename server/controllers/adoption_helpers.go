@@ -0,0 +1,153 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	"helm.sh/helm/v3/pkg/release"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+	"github.com/qubitquilt/supacontrol/server/internal/namespaces"
+)
+
+// releaseInstanceLabel is the label Helm charts conventionally put on the objects of a
+// release, set to the release name
+const releaseInstanceLabel = "app.kubernetes.io/instance"
+
+// adoptedReleaseName returns the name of the Helm release an instance adopts
+func adoptedReleaseName(instance *supacontrolv1alpha1.SupabaseInstance) string {
+	if instance.Spec.Adoption.ReleaseName != "" {
+		return instance.Spec.Adoption.ReleaseName
+	}
+	return instance.Spec.ProjectName
+}
+
+// instanceLabels returns the labels the provisioning Job puts on an instance's namespace
+// and secrets, which mark them as managed by SupaControl
+func instanceLabels(instance *supacontrolv1alpha1.SupabaseInstance) map[string]string {
+	return map[string]string{
+		"app.kubernetes.io/managed-by": "supacontrol",
+		JobInstanceLabel:               instance.Spec.ProjectName,
+	}
+}
+
+// adoptRelease takes over the Helm release an instance's Adoption names instead of
+// provisioning one. The release must be deployed in a namespace no other instance
+// manages; its namespace and workloads are then labeled like provisioned ones and the
+// instance moves straight to Running.
+func (r *SupabaseInstanceReconciler) adoptRelease(ctx context.Context, instance *supacontrolv1alpha1.SupabaseInstance) (ctrl.Result, error) {
+	logger := ctrl.LoggerFrom(ctx)
+	namespace := instance.Spec.Adoption.Namespace
+	releaseName := adoptedReleaseName(instance)
+	logger.Info("Adopting Helm release", "projectName", instance.Spec.ProjectName, "namespace", namespace, "release", releaseName)
+
+	if err := namespaces.Validate(namespace, ControllerNamespace, r.ingressControllerNamespace(), r.monitoringNamespace()); err != nil {
+		return r.transitionToFailed(ctx, instance, fmt.Sprintf("Invalid instance namespace: %v", err))
+	}
+	if err := namespaces.ValidateLabels(instance.Spec.NamespaceLabels); err != nil {
+		return r.transitionToFailed(ctx, instance, fmt.Sprintf("Invalid instance namespace: %v", err))
+	}
+
+	ns := &corev1.Namespace{}
+	if err := r.Get(ctx, client.ObjectKey{Name: namespace}, ns); err != nil {
+		if apierrors.IsNotFound(err) {
+			return r.transitionToFailed(ctx, instance, fmt.Sprintf("Namespace '%s' not found", namespace))
+		}
+		return ctrl.Result{}, fmt.Errorf("failed to get namespace %s: %w", namespace, err)
+	}
+	if owner := ns.Labels[JobInstanceLabel]; owner != "" && owner != instance.Spec.ProjectName {
+		return r.transitionToFailed(ctx, instance, fmt.Sprintf("Namespace '%s' belongs to instance '%s'", namespace, owner))
+	}
+
+	latest, err := latestRevision(ctx, r.Client, namespace, releaseName)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if latest == nil {
+		return r.transitionToFailed(ctx, instance, fmt.Sprintf("Helm release '%s' not found in namespace '%s'", releaseName, namespace))
+	}
+	if latest.Status != release.StatusDeployed.String() {
+		return r.transitionToFailed(ctx, instance, fmt.Sprintf("Revision %d of Helm release '%s' is %s, not deployed", latest.Revision, releaseName, latest.Status))
+	}
+
+	if err := r.labelAdoptedResources(ctx, instance, ns, releaseName); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	instance.Status.Namespace = namespace
+	instance.Status.HelmReleaseName = releaseName
+	instance.Status.HelmRevision = latest.Revision
+	instance.Status.HelmReleaseStatus = latest.Status
+	instance.Status.ChartVersion = latest.ChartVersion
+	condition := metav1.Condition{
+		Type:               supacontrolv1alpha1.ConditionTypeHelmReleaseReady,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: instance.Generation,
+		Reason:             "Adopted",
+		Message:            fmt.Sprintf("Revision %d of the Helm release was adopted", latest.Revision),
+	}
+	r.setObservedCondition(instance, condition)
+	return r.transitionToRunning(ctx, instance)
+}
+
+// labelAdoptedResources labels the namespace of an adopted release and the Deployments and
+// StatefulSets the release installed as belonging to the instance
+func (r *SupabaseInstanceReconciler) labelAdoptedResources(ctx context.Context, instance *supacontrolv1alpha1.SupabaseInstance, namespace *corev1.Namespace, releaseName string) error {
+	labels := instanceLabels(instance)
+	if err := r.addLabels(ctx, namespace, labels); err != nil {
+		return fmt.Errorf("failed to label namespace %s: %w", namespace.Name, err)
+	}
+
+	selector := client.MatchingLabels{releaseInstanceLabel: releaseName}
+	var deployments appsv1.DeploymentList
+	if err := r.List(ctx, &deployments, client.InNamespace(namespace.Name), selector); err != nil {
+		return fmt.Errorf("failed to list deployments: %w", err)
+	}
+	for i := range deployments.Items {
+		if err := r.addLabels(ctx, &deployments.Items[i], labels); err != nil {
+			return fmt.Errorf("failed to label deployment %s: %w", deployments.Items[i].Name, err)
+		}
+	}
+
+	var statefulSets appsv1.StatefulSetList
+	if err := r.List(ctx, &statefulSets, client.InNamespace(namespace.Name), selector); err != nil {
+		return fmt.Errorf("failed to list statefulsets: %w", err)
+	}
+	for i := range statefulSets.Items {
+		if err := r.addLabels(ctx, &statefulSets.Items[i], labels); err != nil {
+			return fmt.Errorf("failed to label statefulset %s: %w", statefulSets.Items[i].Name, err)
+		}
+	}
+	return nil
+}
+
+// addLabels merges labels into those of an object, patching it only when one is missing
+func (r *SupabaseInstanceReconciler) addLabels(ctx context.Context, object client.Object, labels map[string]string) error {
+	current := object.GetLabels()
+	changed := false
+	for key, value := range labels {
+		if current[key] != value {
+			changed = true
+			break
+		}
+	}
+	if !changed {
+		return nil
+	}
+
+	patch := client.MergeFrom(object.DeepCopyObject().(client.Object))
+	if current == nil {
+		current = map[string]string{}
+	}
+	for key, value := range labels {
+		current[key] = value
+	}
+	object.SetLabels(current)
+	return r.Patch(ctx, object, patch)
+}
@@ -0,0 +1,373 @@
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+	"github.com/qubitquilt/supacontrol/server/internal/k8s"
+)
+
+const (
+	// DefaultConnectionRotationInterval is how often the credentials of a connection
+	// between instances are rotated when the reconciler does not set an interval
+	DefaultConnectionRotationInterval = 30 * 24 * time.Hour
+
+	// AnnotationCredentialsRotatedAt records when the credentials of a connection were
+	// last rotated (RFC 3339)
+	AnnotationCredentialsRotatedAt = "supacontrol.io/credentials-rotated-at"
+
+	// ConnectionLabel is the label key holding the instance a connection is allowed from
+	ConnectionLabel = "supacontrol.io/connection"
+
+	// connectionJobImage runs the psql commands that manage connection roles
+	connectionJobImage = "postgres:15-alpine"
+
+	// connectionGrantScript creates the read-only role of a connection or sets its new password
+	connectionGrantScript = `psql -v ON_ERROR_STOP=1 -v role="$ROLE" -v password="$ROLE_PASSWORD" <<'SQL'
+SELECT format('CREATE ROLE %I', :'role') WHERE NOT EXISTS (SELECT FROM pg_roles WHERE rolname = :'role')\gexec
+ALTER ROLE :"role" WITH LOGIN PASSWORD :'password';
+GRANT pg_read_all_data TO :"role";
+SQL`
+
+	// connectionRevokeScript disables the role of a removed connection and ends its sessions
+	connectionRevokeScript = `psql -v ON_ERROR_STOP=1 -v role="$ROLE" <<'SQL'
+SELECT format('ALTER ROLE %I NOLOGIN', :'role') WHERE EXISTS (SELECT FROM pg_roles WHERE rolname = :'role')\gexec
+SELECT pg_terminate_backend(pid) FROM pg_stat_activity WHERE usename = :'role';
+SQL`
+)
+
+// ConnectionSecretName returns the Secret in a connected instance's namespace that holds
+// the credentials for the database of the instance named target
+func ConnectionSecretName(target string) string {
+	return "connection-" + target
+}
+
+// connectionRole returns the database role a connection from the instance named source uses
+func connectionRole(source string) string {
+	return "conn_" + strings.ReplaceAll(source, "-", "_")
+}
+
+// connectionCredentialsName returns the Secret in the instance namespace that holds the
+// current credentials of a connection
+func connectionCredentialsName(projectName, source string) string {
+	return fmt.Sprintf("%s-connection-%s", projectName, source)
+}
+
+// connectionPolicyName returns the NetworkPolicy admitting a connected instance's
+// namespace to the database
+func connectionPolicyName(projectName, source string) string {
+	return fmt.Sprintf("%s-allow-%s", projectName, source)
+}
+
+// connectionRotationInterval returns the configured credential rotation interval or its default
+func (r *SupabaseInstanceReconciler) connectionRotationInterval() time.Duration {
+	if r.ConnectionRotationInterval > 0 {
+		return r.ConnectionRotationInterval
+	}
+	return DefaultConnectionRotationInterval
+}
+
+// credentialsExpired reports whether the credentials in a connection Secret are due for rotation
+func (r *SupabaseInstanceReconciler) credentialsExpired(secret *corev1.Secret, now time.Time) bool {
+	rotatedAt, err := time.Parse(time.RFC3339, secret.Annotations[AnnotationCredentialsRotatedAt])
+	return err != nil || now.Sub(rotatedAt) >= r.connectionRotationInterval()
+}
+
+// reconcileConnections materializes the connections other instances are allowed to make
+// to a running instance's database: a NetworkPolicy admitting their namespace, a read-only
+// database role with rotated credentials, and a copy of the credentials in their namespace.
+// Connections removed from the spec, or whose instance is gone, are torn down again. The
+// outcome is recorded in the ConnectionsReady condition.
+func (r *SupabaseInstanceReconciler) reconcileConnections(ctx context.Context, instance *supacontrolv1alpha1.SupabaseInstance) error {
+	if len(instance.Spec.AllowedConnections) == 0 && len(instance.Status.Connections) == 0 &&
+		meta.FindStatusCondition(instance.Status.Conditions, supacontrolv1alpha1.ConditionTypeConnectionsReady) == nil {
+		return nil
+	}
+
+	condition := metav1.Condition{
+		Type:               supacontrolv1alpha1.ConditionTypeConnectionsReady,
+		ObservedGeneration: instance.Generation,
+	}
+
+	for _, source := range instance.Status.Connections {
+		if slices.Contains(instance.Spec.AllowedConnections, source) {
+			continue
+		}
+		if err := r.removeConnection(ctx, instance, source); err != nil {
+			return err
+		}
+	}
+
+	if len(instance.Spec.AllowedConnections) == 0 {
+		instance.Status.Connections = nil
+		meta.RemoveStatusCondition(&instance.Status.Conditions, condition.Type)
+		return r.updateStatus(ctx, instance)
+	}
+
+	var materialized, waiting []string
+	if instance.Status.IsolationLevel == supacontrolv1alpha1.IsolationVCluster {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "IsolationUnsupported"
+		condition.Message = "Connections cannot be materialized for vcluster instances"
+	} else {
+		for _, source := range instance.Spec.AllowedConnections {
+			ok, err := r.applyConnection(ctx, instance, source)
+			if err != nil {
+				return err
+			}
+			if ok {
+				materialized = append(materialized, source)
+				continue
+			}
+			waiting = append(waiting, source)
+			if slices.Contains(instance.Status.Connections, source) {
+				if err := r.removeConnection(ctx, instance, source); err != nil {
+					return err
+				}
+			}
+		}
+		if len(waiting) > 0 {
+			condition.Status = metav1.ConditionFalse
+			condition.Reason = "SourceNotReady"
+			condition.Message = "Waiting for instances to be provisioned: " + strings.Join(waiting, ", ")
+		} else {
+			condition.Status = metav1.ConditionTrue
+			condition.Reason = "Applied"
+			condition.Message = "Connections allowed from: " + strings.Join(materialized, ", ")
+		}
+	}
+
+	if existing := meta.FindStatusCondition(instance.Status.Conditions, condition.Type); existing != nil &&
+		existing.Status == condition.Status && existing.Reason == condition.Reason &&
+		existing.Message == condition.Message && existing.ObservedGeneration == condition.ObservedGeneration &&
+		slices.Equal(instance.Status.Connections, materialized) {
+		return nil
+	}
+	instance.Status.Connections = materialized
+	meta.SetStatusCondition(&instance.Status.Conditions, condition)
+	return r.updateStatus(ctx, instance)
+}
+
+// applyConnection materializes the connection from the instance named source. It reports
+// false without changing anything when that instance does not exist or has no namespace yet.
+func (r *SupabaseInstanceReconciler) applyConnection(ctx context.Context, instance *supacontrolv1alpha1.SupabaseInstance, source string) (bool, error) {
+	sourceInstance := &supacontrolv1alpha1.SupabaseInstance{}
+	if err := r.Get(ctx, client.ObjectKey{Name: source}, sourceInstance); err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to get instance %s: %w", source, err)
+	}
+	if sourceInstance.Status.Namespace == "" {
+		return false, nil
+	}
+	labels := map[string]string{
+		"app.kubernetes.io/managed-by": "supacontrol",
+		JobInstanceLabel:               instance.Spec.ProjectName,
+		ConnectionLabel:                source,
+	}
+
+	policy := &networkingv1.NetworkPolicy{ObjectMeta: metav1.ObjectMeta{
+		Name:      connectionPolicyName(instance.Spec.ProjectName, source),
+		Namespace: instance.Status.Namespace,
+	}}
+	_, err := controllerutil.CreateOrUpdate(ctx, r.Client, policy, func() error {
+		policy.Labels = labels
+		policy.Spec = networkingv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{},
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress},
+			Ingress: []networkingv1.NetworkPolicyIngressRule{{
+				From: []networkingv1.NetworkPolicyPeer{{NamespaceSelector: &metav1.LabelSelector{
+					MatchLabels: map[string]string{namespaceNameLabel: sourceInstance.Status.Namespace},
+				}}},
+				Ports: []networkingv1.NetworkPolicyPort{{
+					Protocol: ptr.To(corev1.ProtocolTCP),
+					Port:     ptr.To(intstr.FromInt32(5432)),
+				}},
+			}},
+		}
+		return controllerutil.SetControllerReference(instance, policy, r.Scheme)
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to apply NetworkPolicy for connection from %s: %w", source, err)
+	}
+
+	credentials, err := r.ensureConnectionCredentials(ctx, instance, source, labels)
+	if err != nil {
+		return false, err
+	}
+
+	// Publish the credentials where the connected instance's workloads can mount them. The
+	// copy is owned by this instance, so it is removed together with it.
+	published := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{
+		Name:      ConnectionSecretName(instance.Spec.ProjectName),
+		Namespace: sourceInstance.Status.Namespace,
+	}}
+	_, err = controllerutil.CreateOrUpdate(ctx, r.Client, published, func() error {
+		published.Labels = labels
+		published.Annotations = map[string]string{
+			AnnotationCredentialsRotatedAt: credentials.Annotations[AnnotationCredentialsRotatedAt],
+		}
+		published.Data = credentials.Data
+		return controllerutil.SetControllerReference(instance, published, r.Scheme)
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to publish credentials for connection from %s: %w", source, err)
+	}
+	return true, nil
+}
+
+// ensureConnectionCredentials returns the credentials of a connection, generating new ones
+// when they are missing or due for rotation. A Job then sets the new password on the
+// connection's database role, creating the role first if needed.
+func (r *SupabaseInstanceReconciler) ensureConnectionCredentials(ctx context.Context, instance *supacontrolv1alpha1.SupabaseInstance, source string, labels map[string]string) (*corev1.Secret, error) {
+	now := time.Now()
+	secret := &corev1.Secret{}
+	err := r.Get(ctx, client.ObjectKey{Namespace: instance.Status.Namespace, Name: connectionCredentialsName(instance.Spec.ProjectName, source)}, secret)
+	if err == nil && !r.credentialsExpired(secret, now) {
+		return secret, nil
+	}
+	if err != nil && !apierrors.IsNotFound(err) {
+		return nil, fmt.Errorf("failed to get credentials for connection from %s: %w", source, err)
+	}
+
+	password, err := k8s.GenerateSecurePassword()
+	if err != nil {
+		return nil, err
+	}
+	params := r.addonParams(instance)
+	secret = &corev1.Secret{ObjectMeta: metav1.ObjectMeta{
+		Name:      connectionCredentialsName(instance.Spec.ProjectName, source),
+		Namespace: instance.Status.Namespace,
+	}}
+	_, err = controllerutil.CreateOrUpdate(ctx, r.Client, secret, func() error {
+		secret.Labels = labels
+		secret.Annotations = map[string]string{AnnotationCredentialsRotatedAt: now.UTC().Format(time.RFC3339)}
+		secret.Data = map[string][]byte{
+			"host":     []byte(params.DatabaseHost),
+			"port":     []byte("5432"),
+			"database": []byte("postgres"),
+			"username": []byte(connectionRole(source)),
+			"password": []byte(password),
+		}
+		return controllerutil.SetControllerReference(instance, secret, r.Scheme)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to store credentials for connection from %s: %w", source, err)
+	}
+
+	if err := r.runConnectionJob(ctx, instance, source, connectionGrantScript, now); err != nil {
+		return nil, err
+	}
+	ctrl.LoggerFrom(ctx).Info("Rotated connection credentials", "instance", instance.Spec.ProjectName, "source", source)
+	return secret, nil
+}
+
+// removeConnection tears down a connection: its NetworkPolicy and credentials are deleted
+// and a Job disables its database role and ends its open sessions
+func (r *SupabaseInstanceReconciler) removeConnection(ctx context.Context, instance *supacontrolv1alpha1.SupabaseInstance, source string) error {
+	objects := []client.Object{
+		&networkingv1.NetworkPolicy{ObjectMeta: metav1.ObjectMeta{
+			Name:      connectionPolicyName(instance.Spec.ProjectName, source),
+			Namespace: instance.Status.Namespace,
+		}},
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{
+			Name:      connectionCredentialsName(instance.Spec.ProjectName, source),
+			Namespace: instance.Status.Namespace,
+		}},
+	}
+	sourceInstance := &supacontrolv1alpha1.SupabaseInstance{}
+	err := r.Get(ctx, client.ObjectKey{Name: source}, sourceInstance)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to get instance %s: %w", source, err)
+	}
+	if err == nil && sourceInstance.Status.Namespace != "" {
+		objects = append(objects, &corev1.Secret{ObjectMeta: metav1.ObjectMeta{
+			Name:      ConnectionSecretName(instance.Spec.ProjectName),
+			Namespace: sourceInstance.Status.Namespace,
+		}})
+	}
+	for _, object := range objects {
+		if err := r.Delete(ctx, object); err != nil && !apierrors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete %s of connection from %s: %w", object.GetName(), source, err)
+		}
+	}
+
+	if err := r.runConnectionJob(ctx, instance, source, connectionRevokeScript, time.Now()); err != nil {
+		return err
+	}
+	ctrl.LoggerFrom(ctx).Info("Removed connection", "instance", instance.Spec.ProjectName, "source", source)
+	return nil
+}
+
+// runConnectionJob starts a Job running a psql script against the instance database as
+// the postgres user, with the connection's role in $ROLE and its password in $ROLE_PASSWORD
+func (r *SupabaseInstanceReconciler) runConnectionJob(ctx context.Context, instance *supacontrolv1alpha1.SupabaseInstance, source, script string, now time.Time) error {
+	params := r.addonParams(instance)
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s\x00%s\x00%s\x00%d", instance.Spec.ProjectName, source, script, now.UnixNano())))
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "connection-" + hex.EncodeToString(sum[:])[:12],
+			Namespace: instance.Status.Namespace,
+			Labels: map[string]string{
+				"app.kubernetes.io/managed-by": "supacontrol",
+				JobInstanceLabel:               instance.Spec.ProjectName,
+				ConnectionLabel:                source,
+			},
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit:            ptr.To(int32(5)),
+			TTLSecondsAfterFinished: ptr.To(int32(3600)), // Clean up after 1 hour
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					RestartPolicy: corev1.RestartPolicyNever,
+					Containers: []corev1.Container{{
+						Name:    "psql",
+						Image:   connectionJobImage,
+						Command: []string{"sh", "-c", script},
+						Env: []corev1.EnvVar{
+							{Name: "PGHOST", Value: params.DatabaseHost},
+							{Name: "PGUSER", Value: "postgres"},
+							{Name: "PGDATABASE", Value: "postgres"},
+							{Name: "PGPASSWORD", ValueFrom: &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{
+								LocalObjectReference: corev1.LocalObjectReference{Name: params.DatabaseSecret},
+								Key:                  "postgres-password",
+							}}},
+							{Name: "ROLE", Value: connectionRole(source)},
+							{Name: "ROLE_PASSWORD", ValueFrom: &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{
+								LocalObjectReference: corev1.LocalObjectReference{Name: connectionCredentialsName(instance.Spec.ProjectName, source)},
+								Key:                  "password",
+								Optional:             ptr.To(true),
+							}}},
+						},
+					}},
+				},
+			},
+		},
+	}
+	if err := controllerutil.SetControllerReference(instance, job, r.Scheme); err != nil {
+		return fmt.Errorf("failed to set controller reference: %w", err)
+	}
+	if err := r.Create(ctx, job); err != nil {
+		return fmt.Errorf("failed to create Job for connection from %s: %w", source, err)
+	}
+	return nil
+}
@@ -0,0 +1,233 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+)
+
+const (
+	// DatabaseAuditLog is the pgaudit.log setting of instances with auditing enabled:
+	// DDL, role and privilege changes, and writes
+	DatabaseAuditLog = "ddl,role,write"
+
+	// databaseAuditStepLabel is the label key holding whether a database audit Job
+	// enables or disables pgAudit
+	databaseAuditStepLabel = "supacontrol.io/database-audit"
+
+	// databaseAuditEnableScript loads pgAudit into the database and turns on statement
+	// logging. The Supabase image preloads the library; the check fails the Job with a
+	// clear message on images that do not.
+	databaseAuditEnableScript = `set -e
+if ! psql -tAc "SHOW shared_preload_libraries" | grep -q pgaudit; then
+  echo "pgaudit is not in shared_preload_libraries of $PGHOST" >&2
+  exit 1
+fi
+psql -v ON_ERROR_STOP=1 <<EOF
+CREATE EXTENSION IF NOT EXISTS pgaudit;
+ALTER SYSTEM SET pgaudit.log = '$AUDIT_LOG';
+ALTER SYSTEM SET pgaudit.log_relation = on;
+ALTER SYSTEM SET pgaudit.log_parameter = off;
+SELECT pg_reload_conf();
+EOF
+`
+
+	// databaseAuditDisableScript turns statement logging off again. The extension is
+	// left in place, since dropping it would fail while other settings reference it.
+	databaseAuditDisableScript = `set -e
+psql -v ON_ERROR_STOP=1 <<EOF
+ALTER SYSTEM RESET pgaudit.log;
+ALTER SYSTEM RESET pgaudit.log_relation;
+ALTER SYSTEM RESET pgaudit.log_parameter;
+SELECT pg_reload_conf();
+EOF
+`
+)
+
+// DatabaseAuditEnabled reports whether an instance asks for pgAudit statement logging
+func DatabaseAuditEnabled(instance *supacontrolv1alpha1.SupabaseInstance) bool {
+	database := instance.Spec.Database
+	return database != nil && database.Audit != nil && database.Audit.Enabled
+}
+
+// databaseAuditJobName returns the name of the Job configuring pgAudit of an instance
+func databaseAuditJobName(instance *supacontrolv1alpha1.SupabaseInstance) string {
+	return instance.Spec.ProjectName + "-database-audit"
+}
+
+// databaseAuditStep returns the step a database audit Job runs for the requested setting
+func databaseAuditStep(enabled bool) string {
+	if enabled {
+		return "enable"
+	}
+	return "disable"
+}
+
+// reconcileDatabaseAudit turns pgAudit statement logging of a running instance on or off
+// with a Job running psql in its namespace. Audit lines are written to the Postgres log,
+// where the logs endpoint reads them. The chart offers no values for Postgres settings,
+// so they are applied with ALTER SYSTEM, which survives restarts. The outcome is recorded
+// in the DatabaseAuditReady condition, which is removed once auditing is turned off; a
+// failed Job is retried when the spec changes.
+func (r *SupabaseInstanceReconciler) reconcileDatabaseAudit(ctx context.Context, instance *supacontrolv1alpha1.SupabaseInstance) error {
+	enabled := DatabaseAuditEnabled(instance)
+	existing := meta.FindStatusCondition(instance.Status.Conditions, supacontrolv1alpha1.ConditionTypeDatabaseAuditReady)
+	if !enabled && existing == nil {
+		return nil
+	}
+	if instance.Status.Namespace == "" {
+		return nil
+	}
+
+	condition := metav1.Condition{
+		Type:               supacontrolv1alpha1.ConditionTypeDatabaseAuditReady,
+		ObservedGeneration: instance.Generation,
+	}
+	switch {
+	case instance.Status.IsolationLevel == supacontrolv1alpha1.IsolationVCluster:
+		if !enabled {
+			meta.RemoveStatusCondition(&instance.Status.Conditions, condition.Type)
+			return r.updateStatus(ctx, instance)
+		}
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "IsolationUnsupported"
+		condition.Message = "pgAudit cannot be configured for vcluster instances"
+	case enabled && existing != nil && existing.Reason == "Enabled":
+		return nil
+	case existing != nil && existing.Reason == "ApplyFailed" && existing.ObservedGeneration == instance.Generation:
+		return nil
+	default:
+		job, err := r.ensureDatabaseAuditJob(ctx, instance, enabled)
+		if err != nil {
+			return err
+		}
+		switch {
+		case job != nil && isJobSucceeded(job):
+			if err := r.deleteDatabaseAuditJob(ctx, job); err != nil {
+				return err
+			}
+			if !enabled {
+				meta.RemoveStatusCondition(&instance.Status.Conditions, condition.Type)
+				return r.updateStatus(ctx, instance)
+			}
+			condition.Status = metav1.ConditionTrue
+			condition.Reason = "Enabled"
+			condition.Message = "pgAudit logs " + DatabaseAuditLog + " statements"
+		case job != nil && isJobFailed(job):
+			message := "Failed to configure pgAudit"
+			if detail := r.jobFailureDetail(ctx, job); detail != "" {
+				message += ": " + detail
+			}
+			if err := r.deleteDatabaseAuditJob(ctx, job); err != nil {
+				return err
+			}
+			condition.Status = metav1.ConditionFalse
+			condition.Reason = "ApplyFailed"
+			condition.Message = message
+		default:
+			condition.Status = metav1.ConditionFalse
+			condition.Reason = "Applying"
+			condition.Message = "Configuring pgAudit"
+		}
+	}
+
+	if existing != nil && existing.Status == condition.Status && existing.Reason == condition.Reason &&
+		existing.Message == condition.Message && existing.ObservedGeneration == condition.ObservedGeneration {
+		return nil
+	}
+	meta.SetStatusCondition(&instance.Status.Conditions, condition)
+	return r.updateStatus(ctx, instance)
+}
+
+// ensureDatabaseAuditJob returns the Job configuring pgAudit of an instance, creating it
+// when missing. A Job left over from the opposite setting is deleted first, in which case
+// nil is returned and the Job is created on a later reconcile.
+func (r *SupabaseInstanceReconciler) ensureDatabaseAuditJob(ctx context.Context, instance *supacontrolv1alpha1.SupabaseInstance, enabled bool) (*batchv1.Job, error) {
+	step := databaseAuditStep(enabled)
+	job := &batchv1.Job{}
+	err := r.Get(ctx, client.ObjectKey{Namespace: instance.Status.Namespace, Name: databaseAuditJobName(instance)}, job)
+	switch {
+	case err == nil && job.Labels[databaseAuditStepLabel] == step:
+		return job, nil
+	case err == nil:
+		return nil, r.deleteDatabaseAuditJob(ctx, job)
+	case !apierrors.IsNotFound(err):
+		return nil, fmt.Errorf("failed to get database audit Job: %w", err)
+	}
+
+	job = r.databaseAuditJob(instance, enabled)
+	if err := controllerutil.SetControllerReference(instance, job, r.Scheme); err != nil {
+		return nil, fmt.Errorf("failed to set controller reference: %w", err)
+	}
+	if err := r.Create(ctx, job); err != nil && !apierrors.IsAlreadyExists(err) {
+		return nil, fmt.Errorf("failed to create database audit Job: %w", err)
+	}
+	return job, nil
+}
+
+// deleteDatabaseAuditJob deletes a finished or outdated database audit Job with its pods
+func (r *SupabaseInstanceReconciler) deleteDatabaseAuditJob(ctx context.Context, job *batchv1.Job) error {
+	err := r.Delete(ctx, job, client.PropagationPolicy(metav1.DeletePropagationBackground))
+	if err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete database audit Job: %w", err)
+	}
+	return nil
+}
+
+// databaseAuditJob renders the Job enabling or disabling pgAudit of an instance. It
+// connects to the primary as the superuser, since ALTER SYSTEM requires one.
+func (r *SupabaseInstanceReconciler) databaseAuditJob(instance *supacontrolv1alpha1.SupabaseInstance, enabled bool) *batchv1.Job {
+	params := r.addonParams(instance)
+	script := databaseAuditDisableScript
+	if enabled {
+		script = databaseAuditEnableScript
+	}
+	labels := map[string]string{
+		"app.kubernetes.io/managed-by": "supacontrol",
+		JobInstanceLabel:               instance.Spec.ProjectName,
+		databaseAuditStepLabel:         databaseAuditStep(enabled),
+	}
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      databaseAuditJobName(instance),
+			Namespace: params.Namespace,
+			Labels:    labels,
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit:            ptr.To(int32(3)),
+			TTLSecondsAfterFinished: ptr.To(int32(3600)),
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+				Spec: corev1.PodSpec{
+					RestartPolicy: corev1.RestartPolicyNever,
+					Containers: []corev1.Container{{
+						Name:                     "psql",
+						Image:                    ReadReplicaImage,
+						Command:                  []string{"/bin/sh", "-c", script},
+						TerminationMessagePolicy: corev1.TerminationMessageFallbackToLogsOnError,
+						Env: []corev1.EnvVar{
+							{Name: "PGHOST", Value: params.DatabaseHost},
+							{Name: "PGUSER", Value: postgresSuperuser},
+							{Name: "PGDATABASE", Value: "postgres"},
+							{Name: "AUDIT_LOG", Value: DatabaseAuditLog},
+							{Name: "PGPASSWORD", ValueFrom: &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{
+								LocalObjectReference: corev1.LocalObjectReference{Name: params.DatabaseSecret},
+								Key:                  "postgres-password",
+							}}},
+						},
+					}},
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,436 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+	"github.com/qubitquilt/supacontrol/server/internal/metrics"
+)
+
+const (
+	// OperationDatabaseUpgrade is the Postgres major upgrade operation value
+	OperationDatabaseUpgrade = "database-upgrade"
+
+	// DefaultPostgresUpgradeImage runs pg_upgrade during Postgres major upgrades, with
+	// {version} replaced by the target major version. The image ships the binaries of
+	// every older version.
+	DefaultPostgresUpgradeImage = "pgautoupgrade/pgautoupgrade:{version}-bookworm"
+
+	// DefaultDatabaseBackupSize sizes the backup volume of instances without a Postgres
+	// volume size in their spec
+	DefaultDatabaseBackupSize = "10Gi"
+
+	// postgresDataDir is where the chart mounts the Postgres volume
+	postgresDataDir = "/var/lib/postgresql/data"
+
+	// postgresSuperuser owns every object in Supabase databases, so backups are taken and
+	// restored as it
+	postgresSuperuser = "supabase_admin"
+
+	// databaseBackupMountPath is where database upgrade Jobs mount the backup volume
+	databaseBackupMountPath = "/backup"
+)
+
+// Steps of a Postgres major upgrade, each run by a Job of its own
+const (
+	databaseUpgradeStepBackup   = "backup"
+	databaseUpgradeStepUpgrade  = "upgrade"
+	databaseUpgradeStepVerify   = "verify"
+	databaseUpgradeStepRollback = "rollback"
+)
+
+// databaseUpgradeSteps maps each phase of a Postgres major upgrade to the step its Job runs
+var databaseUpgradeSteps = map[supacontrolv1alpha1.SupabaseInstancePhase]string{
+	supacontrolv1alpha1.PhaseBackingUpDatabase:   databaseUpgradeStepBackup,
+	supacontrolv1alpha1.PhaseUpgradingDatabase:   databaseUpgradeStepUpgrade,
+	supacontrolv1alpha1.PhaseVerifyingDatabase:   databaseUpgradeStepVerify,
+	supacontrolv1alpha1.PhaseRollingBackDatabase: databaseUpgradeStepRollback,
+}
+
+// PostgresMajorVersion returns the major version of a Postgres version or image tag,
+// e.g. "15" for "15.8.1.060"
+func PostgresMajorVersion(version string) string {
+	major, _, _ := strings.Cut(strings.TrimPrefix(version, "v"), ".")
+	return major
+}
+
+// requestedDatabaseVersion returns the Postgres major version in an instance's spec, or
+// empty for the chart's default
+func requestedDatabaseVersion(instance *supacontrolv1alpha1.SupabaseInstance) string {
+	if instance.Spec.Database == nil {
+		return ""
+	}
+	return instance.Spec.Database.Version
+}
+
+// postgresImageTag returns the tag of the Postgres image running a major version, or
+// empty for the chart's default image
+func (r *SupabaseInstanceReconciler) postgresImageTag(version string) string {
+	return r.PostgresImageTags[version]
+}
+
+// postgresUpgradeImage returns the image running pg_upgrade to a major version
+func (r *SupabaseInstanceReconciler) postgresUpgradeImage(version string) string {
+	image := r.PostgresUpgradeImage
+	if image == "" {
+		image = DefaultPostgresUpgradeImage
+	}
+	return strings.ReplaceAll(image, "{version}", version)
+}
+
+// setDatabaseVersionValues pins the image tag of the chart's Postgres container
+func setDatabaseVersionValues(values map[string]interface{}, tag string) {
+	if tag == "" {
+		return
+	}
+	db, ok := values["db"].(map[string]interface{})
+	if !ok {
+		db = map[string]interface{}{}
+		values["db"] = db
+	}
+	image, ok := db["image"].(map[string]interface{})
+	if !ok {
+		image = map[string]interface{}{}
+		db["image"] = image
+	}
+	image["tag"] = tag
+}
+
+// needsDatabaseUpgrade reports whether the spec requests a Postgres major version other
+// than the one the database runs. A failed upgrade is not retried until the spec changes.
+func needsDatabaseUpgrade(instance *supacontrolv1alpha1.SupabaseInstance) bool {
+	version := requestedDatabaseVersion(instance)
+	if version == "" || version == instance.Status.DatabaseVersion {
+		return false
+	}
+	condition := meta.FindStatusCondition(instance.Status.Conditions, supacontrolv1alpha1.ConditionTypeDatabaseUpgraded)
+	return condition == nil || condition.Status == metav1.ConditionTrue || condition.ObservedGeneration != instance.Generation
+}
+
+// databaseBackupClaimName returns the name of the volume holding an instance's backup
+// during Postgres major upgrades
+func databaseBackupClaimName(instance *supacontrolv1alpha1.SupabaseInstance) string {
+	return fmt.Sprintf("supacontrol-pg-backup-%s", instance.Spec.ProjectName)
+}
+
+// databaseUpgradeJobName returns the name of the Job running a step of an instance's
+// Postgres major upgrade
+func databaseUpgradeJobName(instance *supacontrolv1alpha1.SupabaseInstance, step string) string {
+	return fmt.Sprintf("supacontrol-pg-%s-%s-%d", step, instance.Spec.ProjectName, instance.Generation)
+}
+
+// startDatabaseUpgrade starts a Postgres major upgrade by backing up the database.
+// Instances with read replicas are refused, as streaming replication does not survive
+// pg_upgrade.
+func (r *SupabaseInstanceReconciler) startDatabaseUpgrade(ctx context.Context, instance *supacontrolv1alpha1.SupabaseInstance) (ctrl.Result, error) {
+	version := requestedDatabaseVersion(instance)
+	ctrl.LoggerFrom(ctx).Info("Starting Postgres major upgrade", "projectName", instance.Spec.ProjectName,
+		"from", instance.Status.DatabaseVersion, "to", version)
+
+	if instance.Spec.Database.ReadReplicas > 0 {
+		r.setDatabaseUpgradedCondition(instance, metav1.ConditionFalse, "ReadReplicasPresent",
+			fmt.Sprintf("Remove the read replicas before upgrading to Postgres %s", version))
+		return ctrl.Result{RequeueAfter: r.resyncInterval()}, r.updateStatus(ctx, instance)
+	}
+
+	instance.Status.DatabaseUpgrade = &supacontrolv1alpha1.DatabaseUpgradeStatus{
+		FromVersion: instance.Status.DatabaseVersion,
+		ToVersion:   version,
+		StartedAt:   metav1.Now(),
+	}
+	if err := r.ensureDatabaseBackupClaim(ctx, instance); err != nil {
+		return r.endDatabaseUpgrade(ctx, instance, "BackupFailed", err.Error())
+	}
+	return r.startDatabaseUpgradeStep(ctx, instance, supacontrolv1alpha1.PhaseBackingUpDatabase,
+		fmt.Sprintf("Backing up the database before upgrading to Postgres %s", version))
+}
+
+// reconcileDatabaseUpgrade monitors the Job of the current step of a Postgres major
+// upgrade and moves on to the next step: backup, upgrade, verification, and rollback when
+// the upgrade or verification fails. A failed backup leaves the database untouched; a
+// failed rollback fails the instance, as the database may then be unusable.
+func (r *SupabaseInstanceReconciler) reconcileDatabaseUpgrade(ctx context.Context, instance *supacontrolv1alpha1.SupabaseInstance) (ctrl.Result, error) {
+	logger := ctrl.LoggerFrom(ctx)
+	upgrade := instance.Status.DatabaseUpgrade
+	if upgrade == nil {
+		logger.Info("Database upgrade state missing, returning to Running", "phase", instance.Status.Phase)
+		return r.endDatabaseUpgrade(ctx, instance, "UpgradeInterrupted", "The database upgrade state was lost")
+	}
+
+	errMsg := ""
+	job, err := r.getJobStatus(ctx, upgrade.JobName)
+	switch {
+	case apierrors.IsNotFound(err):
+		errMsg = fmt.Sprintf("Database upgrade Job '%s' not found", upgrade.JobName)
+	case err != nil:
+		return ctrl.Result{}, err
+	case isJobFailed(job):
+		recordJobDuration(OperationDatabaseUpgrade, "failed", job)
+		errMsg = getJobConditionMessage(job)
+		if errMsg == "" {
+			errMsg = fmt.Sprintf("Database %s Job failed", databaseUpgradeSteps[instance.Status.Phase])
+		}
+	case isJobSucceeded(job):
+		recordJobDuration(OperationDatabaseUpgrade, "succeeded", job)
+	default:
+		logger.V(1).Info("Database upgrade Job still running", "jobName", upgrade.JobName, "active", job.Status.Active)
+		return ctrl.Result{RequeueAfter: r.jobPollInterval()}, nil
+	}
+	if errMsg != "" {
+		logger.Error(errors.New(errMsg), "Database upgrade step failed", "phase", instance.Status.Phase, "jobName", upgrade.JobName)
+	}
+
+	switch instance.Status.Phase {
+	case supacontrolv1alpha1.PhaseBackingUpDatabase:
+		if errMsg != "" {
+			return r.endDatabaseUpgrade(ctx, instance, "BackupFailed", "Backup failed, the database was not changed: "+errMsg)
+		}
+		return r.startDatabaseUpgradeStep(ctx, instance, supacontrolv1alpha1.PhaseUpgradingDatabase,
+			fmt.Sprintf("Running pg_upgrade to Postgres %s", upgrade.ToVersion))
+	case supacontrolv1alpha1.PhaseUpgradingDatabase, supacontrolv1alpha1.PhaseVerifyingDatabase:
+		if errMsg != "" {
+			upgrade.Error = errMsg
+			return r.startDatabaseUpgradeStep(ctx, instance, supacontrolv1alpha1.PhaseRollingBackDatabase,
+				"Restoring the backup after a failed upgrade: "+errMsg)
+		}
+		if instance.Status.Phase == supacontrolv1alpha1.PhaseUpgradingDatabase {
+			return r.startDatabaseUpgradeStep(ctx, instance, supacontrolv1alpha1.PhaseVerifyingDatabase,
+				fmt.Sprintf("Verifying the database on Postgres %s", upgrade.ToVersion))
+		}
+		instance.Status.DatabaseVersion = upgrade.ToVersion
+		return r.endDatabaseUpgrade(ctx, instance, "UpgradeSucceeded", fmt.Sprintf("Upgraded to Postgres %s", upgrade.ToVersion))
+	default:
+		if errMsg != "" {
+			return r.transitionToFailed(ctx, instance, fmt.Sprintf("Postgres upgrade to %s failed (%s) and so did the rollback: %s",
+				upgrade.ToVersion, upgrade.Error, errMsg))
+		}
+		return r.endDatabaseUpgrade(ctx, instance, "RolledBack",
+			fmt.Sprintf("Upgrade to Postgres %s failed and the backup was restored: %s", upgrade.ToVersion, upgrade.Error))
+	}
+}
+
+// startDatabaseUpgradeStep creates the Job of a step of a Postgres major upgrade and moves
+// the instance to the phase of that step
+func (r *SupabaseInstanceReconciler) startDatabaseUpgradeStep(ctx context.Context, instance *supacontrolv1alpha1.SupabaseInstance, phase supacontrolv1alpha1.SupabaseInstancePhase, message string) (ctrl.Result, error) {
+	job, err := r.createDatabaseUpgradeJob(ctx, instance, databaseUpgradeSteps[phase])
+	if err != nil {
+		if phase == supacontrolv1alpha1.PhaseBackingUpDatabase {
+			return r.endDatabaseUpgrade(ctx, instance, "BackupFailed", err.Error())
+		}
+		// Retry creating the Job rather than leaving the database half upgraded
+		return ctrl.Result{}, err
+	}
+
+	instance.Status.Phase = phase
+	instance.Status.DatabaseUpgrade.JobName = job.Name
+	now := metav1.Now()
+	instance.Status.LastTransitionTime = &now
+	r.setDatabaseUpgradedCondition(instance, metav1.ConditionFalse, string(phase), message)
+	if err := r.updateStatus(ctx, instance); err != nil {
+		return ctrl.Result{}, err
+	}
+	metrics.SetInstanceStatus(instance.Spec.ProjectName, string(phase), supacontrolv1alpha1.AllPhases())
+	return ctrl.Result{RequeueAfter: r.jobPollInterval()}, nil
+}
+
+// endDatabaseUpgrade records the outcome of a Postgres major upgrade in the
+// DatabaseUpgraded condition and returns the instance to Running. The spec is marked as
+// handled unless a chart upgrade or resize requested meanwhile is still to run.
+func (r *SupabaseInstanceReconciler) endDatabaseUpgrade(ctx context.Context, instance *supacontrolv1alpha1.SupabaseInstance, reason, message string) (ctrl.Result, error) {
+	status := metav1.ConditionFalse
+	result := "failed"
+	if reason == "UpgradeSucceeded" {
+		status, result = metav1.ConditionTrue, "succeeded"
+	}
+	r.setDatabaseUpgradedCondition(instance, status, reason, message)
+	metrics.JobStatusTotal.WithLabelValues(OperationDatabaseUpgrade, result).Inc()
+
+	instance.Status.DatabaseUpgrade = nil
+	instance.Status.Phase = supacontrolv1alpha1.PhaseRunning
+	if !needsUpgrade(instance) && !needsResize(instance) {
+		instance.Status.ObservedGeneration = instance.Generation
+	}
+	now := metav1.Now()
+	instance.Status.LastTransitionTime = &now
+	if err := r.updateStatus(ctx, instance); err != nil {
+		return ctrl.Result{}, err
+	}
+	metrics.SetInstanceStatus(instance.Spec.ProjectName, string(supacontrolv1alpha1.PhaseRunning), supacontrolv1alpha1.AllPhases())
+	return ctrl.Result{RequeueAfter: r.jobPollInterval()}, nil
+}
+
+// setDatabaseUpgradedCondition records the progress of a Postgres major upgrade
+func (r *SupabaseInstanceReconciler) setDatabaseUpgradedCondition(instance *supacontrolv1alpha1.SupabaseInstance, status metav1.ConditionStatus, reason, message string) {
+	r.setObservedCondition(instance, metav1.Condition{
+		Type:               supacontrolv1alpha1.ConditionTypeDatabaseUpgraded,
+		Status:             status,
+		ObservedGeneration: instance.Generation,
+		Reason:             reason,
+		Message:            message,
+	})
+}
+
+// ensureDatabaseBackupClaim creates the volume database upgrade Jobs keep the backup on,
+// sized like the Postgres volume. It is kept after the upgrade, so the last backup stays
+// available until the instance is deleted.
+func (r *SupabaseInstanceReconciler) ensureDatabaseBackupClaim(ctx context.Context, instance *supacontrolv1alpha1.SupabaseInstance) error {
+	size := resource.MustParse(DefaultDatabaseBackupSize)
+	if storage := instance.Spec.Storage; storage != nil && storage.Size != nil {
+		size = *storage.Size
+	}
+
+	claim := &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      databaseBackupClaimName(instance),
+			Namespace: ControllerNamespace,
+		},
+	}
+	_, err := controllerutil.CreateOrUpdate(ctx, r.Client, claim, func() error {
+		claim.Labels = map[string]string{
+			JobInstanceLabel:              instance.Spec.ProjectName,
+			"app.kubernetes.io/name":      "supacontrol",
+			"app.kubernetes.io/component": "database-backup",
+		}
+		// The spec of a bound claim is immutable apart from its size
+		if claim.CreationTimestamp.IsZero() {
+			claim.Spec.AccessModes = []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce}
+		}
+		if current := claim.Spec.Resources.Requests[corev1.ResourceStorage]; current.Cmp(size) < 0 {
+			claim.Spec.Resources.Requests = corev1.ResourceList{corev1.ResourceStorage: size}
+		}
+		return controllerutil.SetControllerReference(instance, claim, r.Scheme)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create database backup volume: %w", err)
+	}
+	return nil
+}
+
+// createDatabaseUpgradeJob creates the Job running a step of an instance's Postgres major
+// upgrade. Every step mounts the backup volume: the backup step writes the dump, the Helm
+// revision and the list of databases to it, which the later steps check and restore.
+func (r *SupabaseInstanceReconciler) createDatabaseUpgradeJob(ctx context.Context, instance *supacontrolv1alpha1.SupabaseInstance, step string) (*batchv1.Job, error) {
+	logger := ctrl.LoggerFrom(ctx)
+	upgrade := instance.Status.DatabaseUpgrade
+
+	jobName := databaseUpgradeJobName(instance, step)
+	existingJob := &batchv1.Job{}
+	if err := r.Get(ctx, client.ObjectKey{Namespace: ControllerNamespace, Name: jobName}, existingJob); err == nil {
+		logger.Info("Database upgrade Job already exists", "jobName", jobName)
+		return existingJob, nil
+	}
+
+	image, err := r.provisionerImageFor(instance)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.ensureProfileValues(ctx, instance); err != nil {
+		return nil, err
+	}
+	if _, err := r.ensureProvisionerScripts(ctx); err != nil {
+		return nil, err
+	}
+	volumes, mounts, jobEnv := r.jobFiles(instance)
+	scriptsVolume, scriptsMount := provisionerScriptsVolume()
+	volumes = append(volumes, scriptsVolume, corev1.Volume{
+		Name: "backup",
+		VolumeSource: corev1.VolumeSource{
+			PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: databaseBackupClaimName(instance)},
+		},
+	})
+	mounts = append(mounts, scriptsMount, corev1.VolumeMount{Name: "backup", MountPath: databaseBackupMountPath})
+	jobEnv = append(jobEnv, r.Proxy.EnvVars()...)
+	jobEnv = append(jobEnv, r.isolationEnv(instance)...)
+
+	labels := map[string]string{
+		JobInstanceLabel:  instance.Spec.ProjectName,
+		JobOperationLabel: OperationDatabaseUpgrade,
+	}
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      jobName,
+			Namespace: ControllerNamespace,
+			Labels: map[string]string{
+				JobInstanceLabel:              instance.Spec.ProjectName,
+				JobOperationLabel:             OperationDatabaseUpgrade,
+				"app.kubernetes.io/name":      "supacontrol",
+				"app.kubernetes.io/component": "provisioner",
+			},
+			Annotations: map[string]string{
+				"supacontrol.io/instance-uid": string(instance.UID),
+			},
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit:            ptr.To(int32(0)),    // steps are not idempotent; failures roll back instead
+			ActiveDeadlineSeconds:   ptr.To(int64(3600)), // dumps and pg_upgrade of large databases take a while
+			TTLSecondsAfterFinished: ptr.To(int32(86400)),
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+				Spec: corev1.PodSpec{
+					ServiceAccountName: ServiceAccountName,
+					RestartPolicy:      corev1.RestartPolicyNever,
+					Volumes:            volumes,
+					Containers: []corev1.Container{{
+						Name:    "database-upgrade",
+						Image:   image,
+						Command: []string{"/bin/sh"},
+						Args:    []string{provisionerScriptsMountPath + "/" + DatabaseUpgradeScriptKey},
+						Env: append([]corev1.EnvVar{
+							{Name: "STEP", Value: step},
+							{Name: "INSTANCE_NAME", Value: instance.Spec.ProjectName},
+							{Name: "NAMESPACE", Value: instance.Status.Namespace},
+							{Name: "RELEASE_NAME", Value: supabaseReleaseName(instance)},
+							{Name: "CHART_REPO", Value: r.ChartRepo},
+							{Name: "CHART_NAME", Value: r.ChartName},
+							{Name: "CHART_VERSION", Value: r.upgradeChartVersion(instance)},
+							{Name: "FROM_VERSION", Value: upgrade.FromVersion},
+							{Name: "TO_VERSION", Value: upgrade.ToVersion},
+							{Name: "TARGET_IMAGE_TAG", Value: r.postgresImageTag(upgrade.ToVersion)},
+							{Name: "UPGRADE_IMAGE", Value: r.postgresUpgradeImage(upgrade.ToVersion)},
+							{Name: "DB_SELECTOR", Value: fmt.Sprintf("app.kubernetes.io/name=supabase-db,app.kubernetes.io/instance=%s", supabaseReleaseName(instance))},
+							{Name: "DB_CLAIM", Value: postgresClaimName(instance)},
+							{Name: "DB_SUPERUSER", Value: postgresSuperuser},
+							{Name: "PGDATA", Value: postgresDataDir},
+							{Name: "BACKUP_DIR", Value: databaseBackupMountPath},
+						}, jobEnv...),
+						VolumeMounts: mounts,
+						Resources: corev1.ResourceRequirements{
+							Requests: corev1.ResourceList{
+								corev1.ResourceCPU:    resource.MustParse("100m"),
+								corev1.ResourceMemory: resource.MustParse("256Mi"),
+							},
+							Limits: corev1.ResourceList{
+								corev1.ResourceCPU:    resource.MustParse("1"),
+								corev1.ResourceMemory: resource.MustParse("512Mi"),
+							},
+						},
+					}},
+				},
+			},
+		},
+	}
+
+	if err := controllerutil.SetControllerReference(instance, job, r.Scheme); err != nil {
+		return nil, fmt.Errorf("failed to set controller reference: %w", err)
+	}
+	if err := r.Create(ctx, job); err != nil {
+		return nil, fmt.Errorf("failed to create database upgrade Job: %w", err)
+	}
+
+	logger.Info("Created database upgrade Job", "jobName", jobName, "step", step, "namespace", ControllerNamespace)
+	return job, nil
+}
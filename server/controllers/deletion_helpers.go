@@ -0,0 +1,85 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+	"github.com/qubitquilt/supacontrol/server/internal/metrics"
+)
+
+// reconcilePendingDeletion keeps an instance in the trash until its grace period is over
+// and then deletes it, so the finalizer runs the cleanup Job. Meanwhile a provisioned
+// instance is scaled to zero and transitions to PendingDeletion; instances that are not
+// provisioned are left alone until they are purged or recovered.
+func (r *SupabaseInstanceReconciler) reconcilePendingDeletion(ctx context.Context, instance *supacontrolv1alpha1.SupabaseInstance) (ctrl.Result, error) {
+	logger := ctrl.LoggerFrom(ctx)
+	pending := instance.Spec.PendingDeletion
+
+	wait := time.Until(pending.PurgeAfter.Time)
+	if wait <= 0 {
+		if instance.Spec.DeletionProtection {
+			logger.Info("Purge held back by deletion protection", "projectName", instance.Spec.ProjectName)
+			return ctrl.Result{RequeueAfter: r.resyncInterval()}, nil
+		}
+		logger.Info("Grace period over, purging instance", "projectName", instance.Spec.ProjectName,
+			"requestedAt", pending.RequestedAt.Time)
+		if err := r.Delete(ctx, instance); err != nil && !apierrors.IsNotFound(err) {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{}, nil
+	}
+	// Requeue at least every resync interval, so workloads scaled up out-of-band are stopped again
+	requeue := ctrl.Result{RequeueAfter: min(wait, r.resyncInterval())}
+
+	switch instance.Status.Phase {
+	case supacontrolv1alpha1.PhaseRunning, supacontrolv1alpha1.PhaseStopped, supacontrolv1alpha1.PhasePendingDeletion:
+	case supacontrolv1alpha1.PhaseSuspended:
+		// A suspension still in place when the instance is recovered marks the ingresses again
+		if err := r.markIngressesSuspended(ctx, instance, false); err != nil {
+			return ctrl.Result{}, err
+		}
+	default:
+		logger.Info("Instance pending deletion", "projectName", instance.Spec.ProjectName, "purgeAfter", pending.PurgeAfter.Time)
+		return requeue, nil
+	}
+
+	scaled, err := r.scaleDownInstance(ctx, instance)
+	if err != nil {
+		logger.Error(err, "Failed to scale down workloads", "namespace", instance.Status.Namespace)
+		metrics.ReconciliationErrorsTotal.WithLabelValues(string(instance.Status.Phase)).Inc()
+		return ctrl.Result{}, err
+	}
+
+	if instance.Status.Phase != supacontrolv1alpha1.PhasePendingDeletion {
+		logger.Info("Instance moved to the trash", "projectName", instance.Spec.ProjectName,
+			"purgeAfter", pending.PurgeAfter.Time, "workloadsScaled", scaled)
+		instance.Status.Phase = supacontrolv1alpha1.PhasePendingDeletion
+		now := metav1.Now()
+		instance.Status.LastTransitionTime = &now
+
+		meta.SetStatusCondition(&instance.Status.Conditions, metav1.Condition{
+			Type:               supacontrolv1alpha1.ConditionTypeReady,
+			Status:             metav1.ConditionFalse,
+			ObservedGeneration: instance.Generation,
+			Reason:             "PendingDeletion",
+			Message:            fmt.Sprintf("Instance is pending deletion and will be purged after %s", pending.PurgeAfter.UTC().Format(time.RFC3339)),
+		})
+		instance.Status.ObservedGeneration = instance.Generation
+
+		if err := r.updateStatus(ctx, instance); err != nil {
+			return ctrl.Result{}, err
+		}
+
+		// Update metrics
+		metrics.SetInstanceStatus(instance.Spec.ProjectName, string(supacontrolv1alpha1.PhasePendingDeletion), supacontrolv1alpha1.AllPhases())
+	}
+
+	return requeue, nil
+}
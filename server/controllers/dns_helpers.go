@@ -0,0 +1,320 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+	"time"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+)
+
+const (
+	// ExternalDNSModeDNSEndpoint publishes instance hostnames through DNSEndpoint resources
+	// of the external-dns CRD source
+	ExternalDNSModeDNSEndpoint = "dnsendpoint"
+
+	// ExternalDNSModeAnnotation publishes instance hostnames through external-dns
+	// annotations on the instance's ingresses
+	ExternalDNSModeAnnotation = "annotation"
+
+	// DefaultDNSReadyTimeout is how long a new instance waits for DNSReady when the
+	// reconciler sets no timeout
+	DefaultDNSReadyTimeout = 10 * time.Minute
+
+	// externalDNSHostnameAnnotation and externalDNSTTLAnnotation configure the records
+	// external-dns publishes for an ingress
+	externalDNSHostnameAnnotation = "external-dns.alpha.kubernetes.io/hostname"
+	externalDNSTTLAnnotation      = "external-dns.alpha.kubernetes.io/ttl"
+)
+
+// dnsEndpointGVK is the external-dns DNSEndpoint. DNSEndpoints are written as unstructured
+// objects, so the controller does not depend on external-dns' API module.
+var dnsEndpointGVK = schema.GroupVersionKind{Group: "externaldns.k8s.io", Version: "v1alpha1", Kind: "DNSEndpoint"}
+
+// dnsWaitingReasons are the reasons of a false DNSReady condition a new instance waits out
+// before it is marked Running
+var dnsWaitingReasons = map[string]bool{
+	"AddressPending": true,
+	"Publishing":     true,
+}
+
+// DNSEndpointName returns the name of the DNSEndpoint publishing an instance's hostnames
+func DNSEndpointName(projectName string) string {
+	return fmt.Sprintf("%s-dns", projectName)
+}
+
+// externalDNSEnabled reports whether instance hostnames are published through external-dns
+func (r *SupabaseInstanceReconciler) externalDNSEnabled() bool {
+	return r.ExternalDNSMode == ExternalDNSModeDNSEndpoint || r.ExternalDNSMode == ExternalDNSModeAnnotation
+}
+
+// dnsReadyTimeout returns the configured DNS readiness timeout or its default
+func (r *SupabaseInstanceReconciler) dnsReadyTimeout() time.Duration {
+	if r.DNSReadyTimeout > 0 {
+		return r.DNSReadyTimeout
+	}
+	return DefaultDNSReadyTimeout
+}
+
+// dnsProvider returns the DNS provider hint of an instance, or empty when it has none
+func dnsProvider(instance *supacontrolv1alpha1.SupabaseInstance) string {
+	if instance.Spec.DNS == nil {
+		return ""
+	}
+	return instance.Spec.DNS.Provider
+}
+
+// dnsTTL returns the record TTL of an instance in seconds, or 0 for the external-dns default
+func dnsTTL(instance *supacontrolv1alpha1.SupabaseInstance) int32 {
+	if instance.Spec.DNS == nil {
+		return 0
+	}
+	return instance.Spec.DNS.TTL
+}
+
+// externalDNSAnnotations returns the annotations that have external-dns publish host for an
+// ingress, or nil unless hostnames are published through ingress annotations
+func (r *SupabaseInstanceReconciler) externalDNSAnnotations(instance *supacontrolv1alpha1.SupabaseInstance, host string) map[string]string {
+	if r.ExternalDNSMode != ExternalDNSModeAnnotation {
+		return nil
+	}
+	annotations := map[string]string{externalDNSHostnameAnnotation: host}
+	if ttl := dnsTTL(instance); ttl > 0 {
+		annotations[externalDNSTTLAnnotation] = fmt.Sprint(ttl)
+	}
+	return annotations
+}
+
+// ingressLabels returns the labels of an instance's ingresses, including its DNS provider
+// hint when external-dns reads the ingresses
+func (r *SupabaseInstanceReconciler) ingressLabels(instance *supacontrolv1alpha1.SupabaseInstance) map[string]string {
+	labels := map[string]string{
+		"app.kubernetes.io/managed-by": "supacontrol",
+		"supacontrol.io/instance":      instance.Spec.ProjectName,
+	}
+	if provider := dnsProvider(instance); provider != "" && r.ExternalDNSMode == ExternalDNSModeAnnotation {
+		labels[supacontrolv1alpha1.LabelDNSProvider] = provider
+	}
+	return labels
+}
+
+// dnsRecords returns the DNSEndpoint endpoints that point host at the addresses an ingress
+// controller gave its ingress: A and AAAA records for IP addresses, or a CNAME record for
+// the first hostname of a load balancer that only has hostnames
+func dnsRecords(host string, addresses []networkingv1.IngressLoadBalancerIngress, ttl int32) []interface{} {
+	var ipv4, ipv6, hostnames []string
+	for _, address := range addresses {
+		switch ip := net.ParseIP(address.IP); {
+		case ip == nil && address.Hostname != "":
+			hostnames = append(hostnames, address.Hostname)
+		case ip == nil:
+		case ip.To4() != nil:
+			ipv4 = append(ipv4, address.IP)
+		default:
+			ipv6 = append(ipv6, address.IP)
+		}
+	}
+
+	record := func(recordType string, targets []string) interface{} {
+		slices.Sort(targets)
+		values := make([]interface{}, len(targets))
+		for i, target := range targets {
+			values[i] = target
+		}
+		endpoint := map[string]interface{}{
+			"dnsName":    host,
+			"recordType": recordType,
+			"targets":    values,
+		}
+		if ttl > 0 {
+			endpoint["recordTTL"] = int64(ttl)
+		}
+		return endpoint
+	}
+
+	var records []interface{}
+	if len(ipv4) > 0 {
+		records = append(records, record("A", ipv4))
+	}
+	if len(ipv6) > 0 {
+		records = append(records, record("AAAA", ipv6))
+	}
+	if len(records) == 0 && len(hostnames) > 0 {
+		records = append(records, record("CNAME", hostnames[:1]))
+	}
+	return records
+}
+
+// applyDNSEndpoint creates or updates the DNSEndpoint that publishes an instance's hostnames
+// at the addresses of its ingresses
+func (r *SupabaseInstanceReconciler) applyDNSEndpoint(ctx context.Context, instance *supacontrolv1alpha1.SupabaseInstance, records []interface{}) (*unstructured.Unstructured, error) {
+	endpoint := &unstructured.Unstructured{}
+	endpoint.SetGroupVersionKind(dnsEndpointGVK)
+	endpoint.SetNamespace(instance.Status.Namespace)
+	endpoint.SetName(DNSEndpointName(instance.Spec.ProjectName))
+
+	_, err := controllerutil.CreateOrUpdate(ctx, r.Client, endpoint, func() error {
+		labels := map[string]string{
+			"app.kubernetes.io/managed-by": "supacontrol",
+			"supacontrol.io/instance":      instance.Spec.ProjectName,
+		}
+		if provider := dnsProvider(instance); provider != "" {
+			labels[supacontrolv1alpha1.LabelDNSProvider] = provider
+		}
+		endpoint.SetLabels(labels)
+		return unstructured.SetNestedSlice(endpoint.Object, records, "spec", "endpoints")
+	})
+	if err != nil {
+		return nil, err
+	}
+	return endpoint, nil
+}
+
+// dnsCondition publishes a running or just provisioned instance's hostnames through
+// external-dns and reports whether external-dns picked them up. With DNSEndpoints that is
+// when external-dns has observed the current generation of the DNSEndpoint; with ingress
+// annotations, which external-dns does not acknowledge, it is when every ingress has the
+// address its records point at. It returns nil when external-dns is not used.
+func (r *SupabaseInstanceReconciler) dnsCondition(ctx context.Context, instance *supacontrolv1alpha1.SupabaseInstance) (*metav1.Condition, error) {
+	if !r.externalDNSEnabled() || instance.Status.Namespace == "" {
+		return nil, nil
+	}
+	condition := &metav1.Condition{
+		Type:               supacontrolv1alpha1.ConditionTypeDNSReady,
+		ObservedGeneration: instance.Generation,
+	}
+
+	var records []interface{}
+	var pending []string
+	studioIngress, apiIngress := ingressNames(instance)
+	studioHost, apiHost := r.instanceHosts(instance)
+	for _, item := range []struct{ name, host string }{{studioIngress, studioHost}, {apiIngress, apiHost}} {
+		ingress := &networkingv1.Ingress{}
+		err := r.Get(ctx, client.ObjectKey{Namespace: instance.Status.Namespace, Name: item.name}, ingress)
+		if err != nil && !apierrors.IsNotFound(err) {
+			return nil, fmt.Errorf("failed to get ingress %s: %w", item.name, err)
+		}
+		hostRecords := dnsRecords(item.host, ingress.Status.LoadBalancer.Ingress, dnsTTL(instance))
+		if len(hostRecords) == 0 {
+			pending = append(pending, item.host)
+		}
+		records = append(records, hostRecords...)
+	}
+	if len(pending) > 0 {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "AddressPending"
+		condition.Message = fmt.Sprintf("Waiting for an ingress controller to assign the address of %s", strings.Join(pending, ", "))
+		return condition, nil
+	}
+
+	if r.ExternalDNSMode == ExternalDNSModeAnnotation {
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = "Annotated"
+		condition.Message = fmt.Sprintf("Ingresses of %s and %s are annotated for external-dns", studioHost, apiHost)
+		return condition, nil
+	}
+
+	endpoint, err := r.applyDNSEndpoint(ctx, instance, records)
+	if meta.IsNoMatchError(err) {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "DNSEndpointUnsupported"
+		condition.Message = "The cluster has no DNSEndpoint API; install external-dns with its CRD source"
+		return condition, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to apply DNSEndpoint: %w", err)
+	}
+
+	observed, _, _ := unstructured.NestedInt64(endpoint.Object, "status", "observedGeneration")
+	if observed < endpoint.GetGeneration() {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "Publishing"
+		condition.Message = fmt.Sprintf("Waiting for external-dns to publish %s and %s", studioHost, apiHost)
+		return condition, nil
+	}
+	condition.Status = metav1.ConditionTrue
+	condition.Reason = "Published"
+	condition.Message = fmt.Sprintf("external-dns published %s and %s", studioHost, apiHost)
+	return condition, nil
+}
+
+// reconcileDNS keeps the DNS records of a running instance in line with its hostnames and
+// ingress addresses and reflects them in the DNSReady condition, which is removed once
+// external-dns is no longer used
+func (r *SupabaseInstanceReconciler) reconcileDNS(ctx context.Context, instance *supacontrolv1alpha1.SupabaseInstance) error {
+	condition, err := r.dnsCondition(ctx, instance)
+	if err != nil {
+		return err
+	}
+	if condition == nil {
+		if meta.RemoveStatusCondition(&instance.Status.Conditions, supacontrolv1alpha1.ConditionTypeDNSReady) {
+			return r.updateStatus(ctx, instance)
+		}
+		return nil
+	}
+	if !r.setObservedCondition(instance, *condition) {
+		return nil
+	}
+	return r.updateStatus(ctx, instance)
+}
+
+// waitForDNS publishes the hostnames of an instance whose provisioning Job succeeded at
+// completedAt, and holds it back from Running until external-dns picked them up or the DNS
+// readiness timeout passed. It reports whether the instance keeps waiting.
+func (r *SupabaseInstanceReconciler) waitForDNS(ctx context.Context, instance *supacontrolv1alpha1.SupabaseInstance, completedAt time.Time) (bool, ctrl.Result, error) {
+	if !r.externalDNSEnabled() {
+		return false, ctrl.Result{}, nil
+	}
+
+	// The records point at the ingresses' addresses, so the ingresses go up first
+	if err := r.ensureNetworkPolicies(ctx, instance); err != nil {
+		return true, ctrl.Result{}, err
+	}
+	if err := r.ensureNamespaceMetadata(ctx, instance); err != nil {
+		return true, ctrl.Result{}, err
+	}
+	if err := r.ensureIngresses(ctx, instance); err != nil {
+		return true, ctrl.Result{}, err
+	}
+
+	condition, err := r.dnsCondition(ctx, instance)
+	if err != nil {
+		return true, ctrl.Result{}, err
+	}
+	if condition == nil {
+		return false, ctrl.Result{}, nil
+	}
+	r.setObservedCondition(instance, *condition)
+	if condition.Status == metav1.ConditionTrue || !dnsWaitingReasons[condition.Reason] ||
+		time.Since(completedAt) >= r.dnsReadyTimeout() {
+		// transitionToRunning saves the condition with the rest of the status
+		return false, ctrl.Result{}, nil
+	}
+
+	ctrl.LoggerFrom(ctx).Info("Waiting for DNS before marking the instance Running",
+		"projectName", instance.Spec.ProjectName, "reason", condition.Reason)
+	meta.SetStatusCondition(&instance.Status.Conditions, metav1.Condition{
+		Type:               supacontrolv1alpha1.ConditionTypeReady,
+		Status:             metav1.ConditionFalse,
+		ObservedGeneration: instance.Generation,
+		Reason:             "WaitingForDNS",
+		Message:            condition.Message,
+	})
+	if err := r.updateStatus(ctx, instance); err != nil {
+		return true, ctrl.Result{}, err
+	}
+	return true, ctrl.Result{RequeueAfter: r.jobPollInterval()}, nil
+}
@@ -0,0 +1,137 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+	"github.com/qubitquilt/supacontrol/server/internal/metrics"
+)
+
+// isHelmReleaseSecret reports whether an object is a revision of a Helm release, which Helm
+// stores as a Secret labeled with the release name and status
+func isHelmReleaseSecret(object client.Object) bool {
+	return object.GetLabels()["owner"] == "helm"
+}
+
+// hostReleaseName returns the Helm release installed in the instance namespace: the
+// instance's vcluster when it runs in one, or else the Supabase release
+func hostReleaseName(instance *supacontrolv1alpha1.SupabaseInstance) string {
+	if instance.Status.IsolationLevel == supacontrolv1alpha1.IsolationVCluster {
+		return instance.Spec.ProjectName + "-vcluster"
+	}
+	if instance.Status.HelmReleaseName != "" {
+		return instance.Status.HelmReleaseName
+	}
+	return instance.Spec.ProjectName
+}
+
+// releaseInstalled reports whether a Helm release has a revision that is not uninstalled.
+// An uninstall deletes the revisions, or marks them uninstalled when it keeps the history.
+func releaseInstalled(revisions []corev1.Secret) bool {
+	for _, revision := range revisions {
+		if revision.Labels["status"] != "uninstalled" {
+			return true
+		}
+	}
+	return false
+}
+
+// namespaceExists reports whether a namespace exists and is not being deleted
+func namespaceExists(ctx context.Context, reader client.Reader, name string) (bool, error) {
+	namespace := &corev1.Namespace{}
+	if err := reader.Get(ctx, client.ObjectKey{Name: name}, namespace); err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to get namespace %s: %w", name, err)
+	}
+	return namespace.DeletionTimestamp.IsZero(), nil
+}
+
+// releaseExists reports whether a Helm release is installed in a namespace
+func releaseExists(ctx context.Context, reader client.Reader, namespace, release string) (bool, error) {
+	revisions := &corev1.SecretList{}
+	if err := reader.List(ctx, revisions, client.InNamespace(namespace),
+		client.MatchingLabels{"owner": "helm", "name": release}); err != nil {
+		return false, fmt.Errorf("failed to list revisions of Helm release %s: %w", release, err)
+	}
+	return releaseInstalled(revisions.Items), nil
+}
+
+// removedResources checks that the namespace and Helm release of a running instance still
+// exist, and explains which was removed if not. A resource missing from the cache is looked
+// up again on the API server, so a cache that lags behind cannot fail an instance.
+func (r *SupabaseInstanceReconciler) removedResources(ctx context.Context, instance *supacontrolv1alpha1.SupabaseInstance) (reason, message string, err error) {
+	namespace := instance.Status.Namespace
+	if namespace == "" {
+		return "", "", nil
+	}
+
+	exists, err := namespaceExists(ctx, r.Client, namespace)
+	if err == nil && !exists && r.APIReader != nil {
+		exists, err = namespaceExists(ctx, r.APIReader, namespace)
+	}
+	if err != nil {
+		return "", "", err
+	}
+	if !exists {
+		return "NamespaceDeleted", fmt.Sprintf("Namespace %s was deleted outside of SupaControl", namespace), nil
+	}
+
+	release := hostReleaseName(instance)
+	exists, err = releaseExists(ctx, r.Client, namespace, release)
+	if err == nil && !exists && r.APIReader != nil {
+		exists, err = releaseExists(ctx, r.APIReader, namespace, release)
+	}
+	if err != nil {
+		return "", "", err
+	}
+	if !exists {
+		return "ReleaseUninstalled", fmt.Sprintf("Helm release %s was uninstalled outside of SupaControl", release), nil
+	}
+	return "", "", nil
+}
+
+// failRemovedInstance fails a running instance whose namespace or Helm release was removed
+// outside of SupaControl, so it no longer reports Running. The instance can be
+// reprovisioned by retrying it, or automatically by its retry policy.
+func (r *SupabaseInstanceReconciler) failRemovedInstance(ctx context.Context, instance *supacontrolv1alpha1.SupabaseInstance, reason, message string) (ctrl.Result, error) {
+	ctrl.LoggerFrom(ctx).Info("Instance resources were removed, marking it failed",
+		"projectName", instance.Spec.ProjectName, "reason", message)
+
+	instance.Status.Phase = supacontrolv1alpha1.PhaseFailed
+	instance.Status.ErrorMessage = message
+	now := metav1.Now()
+	instance.Status.LastTransitionTime = &now
+	meta.SetStatusCondition(&instance.Status.Conditions, metav1.Condition{
+		Type:               supacontrolv1alpha1.ConditionTypeReady,
+		Status:             metav1.ConditionFalse,
+		ObservedGeneration: instance.Generation,
+		Reason:             reason,
+		Message:            message,
+	})
+	meta.SetStatusCondition(&instance.Status.Conditions, metav1.Condition{
+		Type:               supacontrolv1alpha1.ConditionTypeDegraded,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: instance.Generation,
+		Reason:             reason,
+		Message:            message,
+	})
+	if err := r.updateStatus(ctx, instance); err != nil {
+		return ctrl.Result{}, err
+	}
+	if r.Recorder != nil {
+		r.Recorder.Event(instance, corev1.EventTypeWarning, reason, message)
+	}
+
+	metrics.SetInstanceStatus(instance.Spec.ProjectName, string(supacontrolv1alpha1.PhaseFailed), supacontrolv1alpha1.AllPhases())
+	return ctrl.Result{RequeueAfter: r.failedRequeueInterval()}, nil
+}
@@ -0,0 +1,101 @@
+package controllers
+
+import (
+	"fmt"
+	"sort"
+)
+
+// MaxEnvValueLength limits the length of an instance environment variable's value
+const MaxEnvValueLength = 1024
+
+// allowedEnv maps the environment variables an instance may set to the chart component
+// that receives them. Only settings and feature flags are listed: credentials belong in
+// shared service profiles, whose secrets are kept out of plain chart values.
+var allowedEnv = map[string]string{
+	// Auth (GoTrue)
+	"GOTRUE_SITE_URL":                           "auth",
+	"GOTRUE_URI_ALLOW_LIST":                     "auth",
+	"GOTRUE_DISABLE_SIGNUP":                     "auth",
+	"GOTRUE_JWT_EXP":                            "auth",
+	"GOTRUE_EXTERNAL_EMAIL_ENABLED":             "auth",
+	"GOTRUE_EXTERNAL_PHONE_ENABLED":             "auth",
+	"GOTRUE_MAILER_AUTOCONFIRM":                 "auth",
+	"GOTRUE_MAILER_SECURE_EMAIL_CHANGE_ENABLED": "auth",
+	"GOTRUE_SMS_AUTOCONFIRM":                    "auth",
+	"GOTRUE_RATE_LIMIT_EMAIL_SENT":              "auth",
+	"SMTP_HOST":                                 "auth",
+	"SMTP_PORT":                                 "auth",
+	"SMTP_ADMIN_EMAIL":                          "auth",
+	"SMTP_SENDER_NAME":                          "auth",
+
+	// REST (PostgREST)
+	"PGRST_DB_SCHEMAS":           "rest",
+	"PGRST_DB_EXTRA_SEARCH_PATH": "rest",
+	"PGRST_DB_MAX_ROWS":          "rest",
+
+	// Storage
+	"FILE_SIZE_LIMIT":             "storage",
+	"ENABLE_IMAGE_TRANSFORMATION": "storage",
+
+	// Studio
+	"STUDIO_DEFAULT_ORGANIZATION": "studio",
+	"STUDIO_DEFAULT_PROJECT":      "studio",
+}
+
+// AllowedEnv returns the names of the environment variables an instance may set, sorted
+func AllowedEnv() []string {
+	names := make([]string, 0, len(allowedEnv))
+	for name := range allowedEnv {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// EnvAllowed reports whether an instance may set an environment variable
+func EnvAllowed(name string) bool {
+	_, ok := allowedEnv[name]
+	return ok
+}
+
+// ValidateEnv checks that every variable is on the allowlist and its value is not too long
+func ValidateEnv(env map[string]string) error {
+	for _, name := range sortedEnvNames(env) {
+		if !EnvAllowed(name) {
+			return fmt.Errorf("environment variable %q is not allowed", name)
+		}
+		if len(env[name]) > MaxEnvValueLength {
+			return fmt.Errorf("environment variable %q must be at most %d characters", name, MaxEnvValueLength)
+		}
+	}
+	return nil
+}
+
+// setEnvValues merges an instance's environment variables into <component>.environment of
+// its chart values, overriding values set by shared service profiles
+func setEnvValues(values map[string]interface{}, env map[string]string) {
+	for _, name := range sortedEnvNames(env) {
+		component := allowedEnv[name]
+		componentValues, ok := values[component].(map[string]interface{})
+		if !ok {
+			componentValues = map[string]interface{}{}
+			values[component] = componentValues
+		}
+		environment, ok := componentValues["environment"].(map[string]interface{})
+		if !ok {
+			environment = map[string]interface{}{}
+			componentValues["environment"] = environment
+		}
+		environment[name] = env[name]
+	}
+}
+
+// sortedEnvNames returns the names of an instance's environment variables in sorted order
+func sortedEnvNames(env map[string]string) []string {
+	names := make([]string, 0, len(env))
+	for name := range env {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,107 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+	"github.com/qubitquilt/supacontrol/server/internal/notify"
+)
+
+// ExpiryWarning is how long before an ephemeral instance is deleted it is marked
+// Expiring and its deletion is announced
+const ExpiryWarning = time.Hour
+
+// reconcileExpiry deletes an ephemeral instance once its TTL has passed, unless deletion
+// protection holds it back, so the finalizer runs the cleanup Job. Within ExpiryWarning
+// of the expiry the instance is marked Expiring and a notification is sent; when sending
+// fails the condition is left unset, so the notification is retried. It reports whether
+// the instance was deleted, and otherwise how long until the instance must be
+// reconciled again for its expiry (zero when nothing is due).
+func (r *SupabaseInstanceReconciler) reconcileExpiry(ctx context.Context, instance *supacontrolv1alpha1.SupabaseInstance) (bool, time.Duration, error) {
+	logger := ctrl.LoggerFrom(ctx)
+	expiresAt := instance.ExpiryTime()
+
+	if expiresAt == nil {
+		// The TTL was removed, so the instance no longer expires
+		if instance.Status.ExpiresAt == nil && meta.FindStatusCondition(instance.Status.Conditions, supacontrolv1alpha1.ConditionTypeExpiring) == nil {
+			return false, 0, nil
+		}
+		instance.Status.ExpiresAt = nil
+		meta.RemoveStatusCondition(&instance.Status.Conditions, supacontrolv1alpha1.ConditionTypeExpiring)
+		return false, 0, r.updateStatus(ctx, instance)
+	}
+
+	remaining := time.Until(expiresAt.Time)
+	if remaining <= 0 {
+		if instance.Spec.DeletionProtection {
+			logger.Info("Expiry held back by deletion protection", "projectName", instance.Spec.ProjectName)
+			return false, 0, nil
+		}
+		logger.Info("TTL passed, deleting instance", "projectName", instance.Spec.ProjectName, "expiresAt", expiresAt.Time)
+		if err := r.Delete(ctx, instance); err != nil && !apierrors.IsNotFound(err) {
+			return false, 0, err
+		}
+		return true, 0, nil
+	}
+
+	changed := instance.Status.ExpiresAt == nil || !instance.Status.ExpiresAt.Equal(expiresAt)
+	instance.Status.ExpiresAt = expiresAt
+
+	wait := remaining - ExpiryWarning
+	if wait > 0 {
+		// Extending the instance withdraws an earlier warning
+		if meta.RemoveStatusCondition(&instance.Status.Conditions, supacontrolv1alpha1.ConditionTypeExpiring) {
+			changed = true
+		}
+	} else {
+		wait = remaining
+		message := fmt.Sprintf("Instance expires at %s and will then be deleted", expiresAt.UTC().Format(time.RFC3339))
+		if !meta.IsStatusConditionTrue(instance.Status.Conditions, supacontrolv1alpha1.ConditionTypeExpiring) ||
+			meta.FindStatusCondition(instance.Status.Conditions, supacontrolv1alpha1.ConditionTypeExpiring).Message != message {
+			if err := r.notifyExpiring(ctx, instance, expiresAt, message); err != nil {
+				logger.Error(err, "Failed to send expiry notification", "projectName", instance.Spec.ProjectName)
+				return false, 0, err
+			}
+			meta.SetStatusCondition(&instance.Status.Conditions, metav1.Condition{
+				Type:               supacontrolv1alpha1.ConditionTypeExpiring,
+				Status:             metav1.ConditionTrue,
+				ObservedGeneration: instance.Generation,
+				Reason:             "TTLExpiring",
+				Message:            message,
+			})
+			changed = true
+		}
+	}
+
+	if changed {
+		if err := r.updateStatus(ctx, instance); err != nil {
+			return false, 0, err
+		}
+	}
+	return false, wait, nil
+}
+
+// notifyExpiring announces the upcoming deletion of an instance (a no-op without a notifier)
+func (r *SupabaseInstanceReconciler) notifyExpiring(ctx context.Context, instance *supacontrolv1alpha1.SupabaseInstance, expiresAt *metav1.Time, message string) error {
+	if r.Notifier == nil {
+		return nil
+	}
+	data := map[string]string{"expires_at": expiresAt.UTC().Format(time.RFC3339)}
+	if owner := instance.Annotations[supacontrolv1alpha1.AnnotationOwnerID]; owner != "" {
+		data["owner_id"] = owner
+	}
+	return r.Notifier.Notify(ctx, notify.Event{
+		Type:     notify.EventInstanceExpiring,
+		Instance: instance.Spec.ProjectName,
+		Message:  message,
+		Time:     time.Now().UTC(),
+		Data:     data,
+	})
+}
@@ -0,0 +1,144 @@
+package controllers
+
+import (
+	"context"
+	"math/rand/v2"
+	"regexp"
+	"strings"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+)
+
+// AutoRetryJitter is the largest fraction taken off the wait before an automatic retry,
+// so instances that failed together, e.g. during a registry outage, do not retry together
+const AutoRetryJitter = 0.2
+
+// maxFailureDetailLength truncates the pod output appended to failure messages
+const maxFailureDetailLength = 500
+
+var (
+	// permanentFailurePatterns match failures retrying cannot resolve. They are checked
+	// before the transient ones, so a timeout while rejecting invalid values is permanent.
+	permanentFailurePatterns = regexp.MustCompile(`(?i)` + strings.Join([]string{
+		`invalid`,
+		`cannot unmarshal`,
+		`parse error`,
+		`unknown field`,
+		`values don't meet the specifications of the schema`,
+		`admission webhook .* denied`,
+		`is forbidden`,
+		`chart .*not found`,
+		`namespace '.*' (not found|belongs to instance)`,
+		`helm release '.*' not found`,
+		`rollback`,
+	}, "|"))
+
+	// transientFailurePatterns match failures of the cluster, registry or chart repository
+	// that retrying may resolve
+	transientFailurePatterns = regexp.MustCompile(`(?i)` + strings.Join([]string{
+		`ErrImagePull`,
+		`ImagePullBackOff`,
+		`DeadlineExceeded`,
+		`context deadline exceeded`,
+		`timed out`,
+		`timeout`,
+		`connection refused`,
+		`connection reset`,
+		`no such host`,
+		`temporary failure in name resolution`,
+		`unexpected EOF`,
+		`too many requests`,
+		`service unavailable`,
+		`bad gateway`,
+		`the server is currently unable to handle the request`,
+		`failed to create provisioning job`,
+		`failed to fetch`,
+		`failed to download`,
+	}, "|"))
+)
+
+// classifyFailure classifies a failure message as transient or permanent, or returns
+// empty when it matches neither
+func classifyFailure(message string) supacontrolv1alpha1.FailureType {
+	switch {
+	case permanentFailurePatterns.MatchString(message):
+		return supacontrolv1alpha1.FailurePermanent
+	case transientFailurePatterns.MatchString(message):
+		return supacontrolv1alpha1.FailureTransient
+	}
+	return ""
+}
+
+// autoRetryLimit returns how many automatic retries a failure of an instance gets: those
+// of its AutoRetry policy, or the default for transient failures without one. Permanent
+// failures, and unclassified ones without a policy, get none.
+func autoRetryLimit(instance *supacontrolv1alpha1.SupabaseInstance, failure supacontrolv1alpha1.FailureType) int32 {
+	policy := instance.Spec.AutoRetry
+	switch {
+	case failure == supacontrolv1alpha1.FailurePermanent:
+		return 0
+	case policy != nil:
+		return autoRetryAttempts(policy)
+	case failure == supacontrolv1alpha1.FailureTransient:
+		return DefaultAutoRetryAttempts
+	}
+	return 0
+}
+
+// jitterRetryDelay takes a random fraction of up to AutoRetryJitter off a retry delay,
+// so the jittered delay never exceeds AutoRetryMaxDelay
+func jitterRetryDelay(delay time.Duration) time.Duration {
+	return delay - time.Duration(rand.Float64()*AutoRetryJitter*float64(delay))
+}
+
+// provisioningFailureMessage describes why a provisioning Job failed, with what its pods
+// report when they still exist
+func (r *SupabaseInstanceReconciler) provisioningFailureMessage(ctx context.Context, job *batchv1.Job) string {
+	message := getJobConditionMessage(job)
+	if message == "" {
+		message = "Provisioning Job failed after retries"
+	}
+	if detail := r.jobFailureDetail(ctx, job); detail != "" {
+		message += ": " + detail
+	}
+	return message
+}
+
+// jobFailureDetail returns what the failed pods of a Job report: image pull errors, or
+// the last lines their containers logged before failing. It returns empty when the pods
+// are gone or report nothing.
+func (r *SupabaseInstanceReconciler) jobFailureDetail(ctx context.Context, job *batchv1.Job) string {
+	pods := &corev1.PodList{}
+	if err := r.podReader().List(ctx, pods, client.InNamespace(job.Namespace),
+		client.MatchingLabels{"job-name": job.Name}); err != nil {
+		return ""
+	}
+
+	var latest *corev1.Pod
+	for i := range pods.Items {
+		if latest == nil || latest.CreationTimestamp.Before(&pods.Items[i].CreationTimestamp) {
+			latest = &pods.Items[i]
+		}
+	}
+	if latest == nil {
+		return ""
+	}
+	for _, status := range latest.Status.ContainerStatuses {
+		if waiting := status.State.Waiting; waiting != nil && waiting.Reason != "" && waiting.Reason != "ContainerCreating" {
+			return strings.TrimSpace(waiting.Reason + ": " + waiting.Message)
+		}
+		if terminated := status.State.Terminated; terminated != nil && terminated.ExitCode != 0 {
+			detail := strings.TrimSpace(terminated.Message)
+			if len(detail) > maxFailureDetailLength {
+				detail = "..." + detail[len(detail)-maxFailureDetailLength:]
+			}
+			return detail
+		}
+	}
+	return ""
+}
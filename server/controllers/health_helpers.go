@@ -0,0 +1,183 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+)
+
+const (
+	// DefaultHealthSuccessThreshold is how many health checks in a row must pass before a
+	// running instance is marked Ready again
+	DefaultHealthSuccessThreshold = 3
+
+	// DefaultHealthFailureThreshold is how many health checks in a row must fail before a
+	// running instance is marked Degraded
+	DefaultHealthFailureThreshold = 3
+)
+
+// healthSuccessThreshold returns the configured success threshold or its default
+func (r *SupabaseInstanceReconciler) healthSuccessThreshold() int32 {
+	if r.HealthSuccessThreshold > 0 {
+		return r.HealthSuccessThreshold
+	}
+	return DefaultHealthSuccessThreshold
+}
+
+// healthFailureThreshold returns the configured failure threshold or its default
+func (r *SupabaseInstanceReconciler) healthFailureThreshold() int32 {
+	if r.HealthFailureThreshold > 0 {
+		return r.HealthFailureThreshold
+	}
+	return DefaultHealthFailureThreshold
+}
+
+// podReader returns the reader pods are listed with. Pods are read from the API server
+// rather than the cache, so the controller does not watch every pod in the cluster.
+func (r *SupabaseInstanceReconciler) podReader() client.Reader {
+	if r.APIReader != nil {
+		return r.APIReader
+	}
+	return r.Client
+}
+
+// checkHealth reports whether every pod in the instance namespace is ready, and why not
+func (r *SupabaseInstanceReconciler) checkHealth(ctx context.Context, instance *supacontrolv1alpha1.SupabaseInstance) (bool, string, error) {
+	pods := &corev1.PodList{}
+	if err := r.podReader().List(ctx, pods, client.InNamespace(instance.Status.Namespace)); err != nil {
+		return false, "", fmt.Errorf("failed to list pods: %w", err)
+	}
+
+	total, ready := 0, 0
+	for _, pod := range pods.Items {
+		// Completed Job pods are not part of the running instance, and failed or evicted
+		// pods have been replaced by their controllers
+		if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		total++
+		for _, condition := range pod.Status.Conditions {
+			if condition.Type == corev1.PodReady && condition.Status == corev1.ConditionTrue {
+				ready++
+				break
+			}
+		}
+	}
+
+	switch {
+	case total == 0:
+		return false, "No pods are running", nil
+	case ready < total:
+		return false, fmt.Sprintf("%d of %d pods are ready", ready, total), nil
+	}
+	return true, "", nil
+}
+
+// healthSettled reports whether the latest health checks agree with the Ready condition,
+// so the instance does not need to be checked again soon
+func healthSettled(instance *supacontrolv1alpha1.SupabaseInstance) bool {
+	health := instance.Status.Health
+	if health == nil {
+		return true
+	}
+	ready := meta.IsStatusConditionTrue(instance.Status.Conditions, supacontrolv1alpha1.ConditionTypeReady)
+	return (ready && health.ConsecutiveFailures == 0) || (!ready && health.ConsecutiveSuccesses == 0)
+}
+
+// healthCheckInterval is how long to wait between health checks: settled instances are
+// checked on every resync, while a streak that could flip Ready is followed up quickly
+func (r *SupabaseInstanceReconciler) healthCheckInterval(instance *supacontrolv1alpha1.SupabaseInstance) time.Duration {
+	if healthSettled(instance) {
+		return r.resyncInterval()
+	}
+	return r.jobPollInterval()
+}
+
+// evaluateHealth checks a running instance and records the result. Ready only turns false
+// (and Degraded true) after the failure threshold of checks in a row failed, and back after
+// the success threshold passed, so transient pod restarts do not make the conditions flap.
+// It returns how long to wait before the next check.
+func (r *SupabaseInstanceReconciler) evaluateHealth(ctx context.Context, instance *supacontrolv1alpha1.SupabaseInstance) (time.Duration, error) {
+	if instance.Status.Namespace == "" {
+		return r.resyncInterval(), nil
+	}
+
+	// Status writes requeue the instance, so checks are spaced out by the last check time
+	interval := r.healthCheckInterval(instance)
+	if health := instance.Status.Health; health != nil && health.LastCheckTime != nil {
+		if wait := time.Until(health.LastCheckTime.Add(interval)); wait > 0 {
+			return wait, nil
+		}
+	}
+
+	healthy, reason, err := r.checkHealth(ctx, instance)
+	if err != nil {
+		return 0, err
+	}
+	if err := r.reconcileHighAvailability(ctx, instance); err != nil {
+		return 0, err
+	}
+
+	if instance.Status.Health == nil {
+		instance.Status.Health = &supacontrolv1alpha1.HealthStatus{}
+	}
+	health := instance.Status.Health
+	now := metav1.Now()
+	health.LastCheckTime = &now
+	if healthy {
+		health.ConsecutiveSuccesses = min(health.ConsecutiveSuccesses+1, r.healthSuccessThreshold())
+		health.ConsecutiveFailures = 0
+		health.LastFailure = ""
+	} else {
+		health.ConsecutiveFailures = min(health.ConsecutiveFailures+1, r.healthFailureThreshold())
+		health.ConsecutiveSuccesses = 0
+		health.LastFailure = reason
+	}
+
+	logger := ctrl.LoggerFrom(ctx)
+	ready := meta.IsStatusConditionTrue(instance.Status.Conditions, supacontrolv1alpha1.ConditionTypeReady)
+	switch {
+	case ready && health.ConsecutiveFailures >= r.healthFailureThreshold():
+		logger.Info("Instance failed its health checks, marking it degraded", "reason", reason)
+		setHealthConditions(instance, false, "HealthChecksFailing", reason)
+	case !ready && health.ConsecutiveSuccesses >= r.healthSuccessThreshold():
+		logger.Info("Instance passed its health checks, marking it ready")
+		setHealthConditions(instance, true, "HealthChecksPassing", "Instance is running and ready")
+	}
+
+	if err := r.updateStatus(ctx, instance); err != nil {
+		return 0, err
+	}
+	return r.healthCheckInterval(instance), nil
+}
+
+// setHealthConditions sets the Ready and Degraded conditions of a running instance
+func setHealthConditions(instance *supacontrolv1alpha1.SupabaseInstance, ready bool, reason, message string) {
+	readyStatus, degradedStatus := metav1.ConditionFalse, metav1.ConditionTrue
+	if ready {
+		readyStatus, degradedStatus = metav1.ConditionTrue, metav1.ConditionFalse
+	}
+
+	meta.SetStatusCondition(&instance.Status.Conditions, metav1.Condition{
+		Type:               supacontrolv1alpha1.ConditionTypeReady,
+		Status:             readyStatus,
+		ObservedGeneration: instance.Generation,
+		Reason:             reason,
+		Message:            message,
+	})
+	meta.SetStatusCondition(&instance.Status.Conditions, metav1.Condition{
+		Type:               supacontrolv1alpha1.ConditionTypeDegraded,
+		Status:             degradedStatus,
+		ObservedGeneration: instance.Generation,
+		Reason:             reason,
+		Message:            message,
+	})
+}
@@ -0,0 +1,164 @@
+package controllers
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"helm.sh/helm/v3/pkg/release"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+)
+
+// gzipMagic starts the release payload Helm compresses before storing it
+var gzipMagic = []byte{0x1f, 0x8b, 0x08}
+
+// releaseRevision is the latest revision of a Helm release, as Helm stores it in a Secret
+type releaseRevision struct {
+	Revision     int32
+	Status       string
+	Description  string
+	ChartVersion string
+}
+
+// latestRevision returns the latest revision of a Helm release in a namespace, or nil when
+// the release has none. Its revision and status come from the labels Helm puts on the
+// Secret; its description and chart version from the encoded release, when present.
+func latestRevision(ctx context.Context, reader client.Reader, namespace, name string) (*releaseRevision, error) {
+	secrets := &corev1.SecretList{}
+	if err := reader.List(ctx, secrets, client.InNamespace(namespace),
+		client.MatchingLabels{"owner": "helm", "name": name}); err != nil {
+		return nil, fmt.Errorf("failed to list revisions of Helm release %s: %w", name, err)
+	}
+
+	var latest *corev1.Secret
+	var revision int
+	for i := range secrets.Items {
+		version, err := strconv.Atoi(secrets.Items[i].Labels["version"])
+		if err != nil {
+			continue
+		}
+		if latest == nil || version > revision {
+			latest, revision = &secrets.Items[i], version
+		}
+	}
+	if latest == nil {
+		return nil, nil
+	}
+
+	result := &releaseRevision{Revision: int32(revision), Status: latest.Labels["status"]}
+	if data, ok := latest.Data["release"]; ok {
+		rel, err := decodeRelease(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode revision %d of Helm release %s: %w", revision, name, err)
+		}
+		if rel.Info != nil {
+			result.Description = rel.Info.Description
+			if rel.Info.Status != "" {
+				result.Status = rel.Info.Status.String()
+			}
+		}
+		if rel.Chart != nil && rel.Chart.Metadata != nil {
+			result.ChartVersion = rel.Chart.Metadata.Version
+		}
+	}
+	return result, nil
+}
+
+// decodeRelease decodes a release the way Helm's Secret driver encodes it: JSON, gzipped,
+// then base64 encoded
+func decodeRelease(data []byte) (*release.Release, error) {
+	b, err := base64.StdEncoding.DecodeString(string(data))
+	if err != nil {
+		return nil, err
+	}
+	if bytes.HasPrefix(b, gzipMagic) {
+		reader, err := gzip.NewReader(bytes.NewReader(b))
+		if err != nil {
+			return nil, err
+		}
+		defer reader.Close()
+		if b, err = io.ReadAll(reader); err != nil {
+			return nil, err
+		}
+	}
+	rel := &release.Release{}
+	if err := json.Unmarshal(b, rel); err != nil {
+		return nil, err
+	}
+	return rel, nil
+}
+
+// releaseStatusReason turns a Helm release status such as pending-upgrade into a condition
+// reason such as PendingUpgrade
+func releaseStatusReason(status string) string {
+	var reason strings.Builder
+	for _, word := range strings.Split(status, "-") {
+		if word != "" {
+			reason.WriteString(strings.ToUpper(word[:1]) + word[1:])
+		}
+	}
+	if reason.Len() == 0 {
+		return "Unknown"
+	}
+	return reason.String()
+}
+
+// reconcileHelmRelease copies the revision and status of a running instance's Helm release
+// into its status and the HelmReleaseReady condition, so a failed or stuck upgrade run
+// outside of SupaControl shows up on the instance. The chart version is only taken from
+// deployed revisions, so it keeps naming the version that is actually running. Instances
+// in a vcluster are skipped, since their Supabase release is installed inside the vcluster.
+func (r *SupabaseInstanceReconciler) reconcileHelmRelease(ctx context.Context, instance *supacontrolv1alpha1.SupabaseInstance) error {
+	if instance.Status.Namespace == "" || instance.Status.IsolationLevel == supacontrolv1alpha1.IsolationVCluster {
+		return nil
+	}
+
+	latest, err := latestRevision(ctx, r.Client, instance.Status.Namespace, hostReleaseName(instance))
+	if err != nil || latest == nil {
+		return err
+	}
+
+	changed := false
+	if instance.Status.HelmRevision != latest.Revision || instance.Status.HelmReleaseStatus != latest.Status {
+		instance.Status.HelmRevision = latest.Revision
+		instance.Status.HelmReleaseStatus = latest.Status
+		changed = true
+	}
+
+	condition := metav1.Condition{
+		Type:               supacontrolv1alpha1.ConditionTypeHelmReleaseReady,
+		ObservedGeneration: instance.Generation,
+	}
+	if latest.Status == release.StatusDeployed.String() {
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = "Deployed"
+		condition.Message = fmt.Sprintf("Revision %d of the Helm release is deployed", latest.Revision)
+		if latest.ChartVersion != "" && instance.Status.ChartVersion != latest.ChartVersion {
+			instance.Status.ChartVersion = latest.ChartVersion
+			changed = true
+		}
+	} else {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = releaseStatusReason(latest.Status)
+		condition.Message = fmt.Sprintf("Revision %d of the Helm release is %s", latest.Revision, latest.Status)
+		if latest.Description != "" {
+			condition.Message += ": " + latest.Description
+		}
+	}
+	changed = r.setObservedCondition(instance, condition) || changed
+
+	if !changed {
+		return nil
+	}
+	return r.updateStatus(ctx, instance)
+}
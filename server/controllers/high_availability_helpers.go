@@ -0,0 +1,184 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+)
+
+// highAvailabilityComponents are the stateless chart components a highly available
+// instance runs with several replicas
+var highAvailabilityComponents = []string{"kong", "auth", "realtime"}
+
+// HighAvailabilityReplicas returns the number of replicas of each highly available
+// component, or zero when the instance is not highly available
+func HighAvailabilityReplicas(instance *supacontrolv1alpha1.SupabaseInstance) int32 {
+	if instance.Spec.HighAvailability == nil {
+		return 0
+	}
+	return instance.Spec.HighAvailability.Replicas
+}
+
+// podDisruptionBudgetName returns the name of the PodDisruptionBudget guarding one
+// component of an instance
+func podDisruptionBudgetName(projectName, component string) string {
+	return fmt.Sprintf("%s-%s-pdb", projectName, component)
+}
+
+// componentSelector selects the pods the Supabase chart runs for one component of a release
+func componentSelector(releaseName, component string) map[string]string {
+	return map[string]string{
+		"app.kubernetes.io/name":     "supabase-" + component,
+		"app.kubernetes.io/instance": releaseName,
+	}
+}
+
+// supabaseReleaseName returns the name of the instance's Supabase Helm release
+func supabaseReleaseName(instance *supacontrolv1alpha1.SupabaseInstance) string {
+	if instance.Status.HelmReleaseName != "" {
+		return instance.Status.HelmReleaseName
+	}
+	return instance.Spec.ProjectName
+}
+
+// setHighAvailabilityValues scales the highly available components and prefers to
+// schedule their replicas on different nodes
+func setHighAvailabilityValues(values map[string]interface{}, instance *supacontrolv1alpha1.SupabaseInstance) {
+	replicas := HighAvailabilityReplicas(instance)
+	if replicas == 0 {
+		return
+	}
+	releaseName := supabaseReleaseName(instance)
+	for _, component := range highAvailabilityComponents {
+		section, ok := values[component].(map[string]interface{})
+		if !ok {
+			section = map[string]interface{}{}
+			values[component] = section
+		}
+		matchLabels := map[string]interface{}{}
+		for key, value := range componentSelector(releaseName, component) {
+			matchLabels[key] = value
+		}
+		section["replicaCount"] = replicas
+		section["affinity"] = map[string]interface{}{
+			"podAntiAffinity": map[string]interface{}{
+				"preferredDuringSchedulingIgnoredDuringExecution": []interface{}{
+					map[string]interface{}{
+						"weight": 100,
+						"podAffinityTerm": map[string]interface{}{
+							"labelSelector": map[string]interface{}{"matchLabels": matchLabels},
+							"topologyKey":   corev1.LabelHostname,
+						},
+					},
+				},
+			},
+		}
+	}
+}
+
+// reconcileHighAvailability keeps a PodDisruptionBudget for each highly available
+// component of a running instance, recreating any that went missing, and removes them
+// once the instance is no longer highly available. The result is recorded in the
+// HighAvailabilityReady condition, which the caller persists with the health status.
+func (r *SupabaseInstanceReconciler) reconcileHighAvailability(ctx context.Context, instance *supacontrolv1alpha1.SupabaseInstance) error {
+	replicas := HighAvailabilityReplicas(instance)
+	existing := meta.FindStatusCondition(instance.Status.Conditions, supacontrolv1alpha1.ConditionTypeHighAvailabilityReady)
+	if replicas == 0 && existing == nil {
+		return nil
+	}
+	namespace := instance.Status.Namespace
+
+	if replicas == 0 {
+		for _, component := range highAvailabilityComponents {
+			budget := &policyv1.PodDisruptionBudget{ObjectMeta: metav1.ObjectMeta{
+				Name:      podDisruptionBudgetName(instance.Spec.ProjectName, component),
+				Namespace: namespace,
+			}}
+			if err := r.Delete(ctx, budget); err != nil && !apierrors.IsNotFound(err) {
+				return fmt.Errorf("failed to delete PodDisruptionBudget %s: %w", budget.Name, err)
+			}
+		}
+		meta.RemoveStatusCondition(&instance.Status.Conditions, supacontrolv1alpha1.ConditionTypeHighAvailabilityReady)
+		return nil
+	}
+
+	condition := metav1.Condition{
+		Type:               supacontrolv1alpha1.ConditionTypeHighAvailabilityReady,
+		ObservedGeneration: instance.Generation,
+	}
+	if instance.Status.IsolationLevel == supacontrolv1alpha1.IsolationVCluster {
+		// The chart's pods run inside the vcluster, where the controller manages nothing
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "IsolationUnsupported"
+		condition.Message = "PodDisruptionBudgets cannot be managed for vcluster instances"
+		meta.SetStatusCondition(&instance.Status.Conditions, condition)
+		return nil
+	}
+
+	var recreated, unprotected []string
+	for _, component := range highAvailabilityComponents {
+		budget, result, err := r.applyPodDisruptionBudget(ctx, instance, component)
+		if err != nil {
+			return err
+		}
+		// A budget created after the first one was applied has gone missing
+		if result == controllerutil.OperationResultCreated && existing != nil {
+			recreated = append(recreated, budget.Name)
+		}
+		if budget.Status.ObservedGeneration < budget.Generation || budget.Status.CurrentHealthy < budget.Status.DesiredHealthy {
+			unprotected = append(unprotected, component)
+		}
+	}
+	if len(recreated) > 0 {
+		ctrl.LoggerFrom(ctx).Info("Recreated missing PodDisruptionBudgets", "budgets", recreated)
+		if r.Recorder != nil {
+			r.Recorder.Event(instance, corev1.EventTypeWarning, "PodDisruptionBudgetMissing",
+				fmt.Sprintf("Recreated missing PodDisruptionBudgets %s", strings.Join(recreated, ", ")))
+		}
+	}
+
+	if len(unprotected) > 0 {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "ReplicasNotHealthy"
+		condition.Message = fmt.Sprintf("Too few healthy replicas of %s", strings.Join(unprotected, ", "))
+	} else {
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = "PodDisruptionBudgetsReady"
+		condition.Message = fmt.Sprintf("%d replicas of %s guarded by PodDisruptionBudgets",
+			replicas, strings.Join(highAvailabilityComponents, ", "))
+	}
+	meta.SetStatusCondition(&instance.Status.Conditions, condition)
+	return nil
+}
+
+// applyPodDisruptionBudget applies the PodDisruptionBudget of one highly available
+// component, which keeps at least one of its replicas running through voluntary
+// disruptions such as node drains
+func (r *SupabaseInstanceReconciler) applyPodDisruptionBudget(ctx context.Context, instance *supacontrolv1alpha1.SupabaseInstance, component string) (*policyv1.PodDisruptionBudget, controllerutil.OperationResult, error) {
+	budget := &policyv1.PodDisruptionBudget{ObjectMeta: metav1.ObjectMeta{
+		Name:      podDisruptionBudgetName(instance.Spec.ProjectName, component),
+		Namespace: instance.Status.Namespace,
+	}}
+	result, err := controllerutil.CreateOrUpdate(ctx, r.Client, budget, func() error {
+		budget.Labels = map[string]string{"app.kubernetes.io/managed-by": "supacontrol"}
+		budget.Spec.MinAvailable = ptr.To(intstr.FromInt32(1))
+		budget.Spec.Selector = &metav1.LabelSelector{MatchLabels: componentSelector(supabaseReleaseName(instance), component)}
+		return controllerutil.SetControllerReference(instance, budget, r.Scheme)
+	})
+	if err != nil {
+		return nil, result, fmt.Errorf("failed to apply PodDisruptionBudget %s: %w", budget.Name, err)
+	}
+	return budget, result, nil
+}
@@ -0,0 +1,94 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+)
+
+// maxTransitionReasonLength bounds the reason kept for a phase transition, so that error
+// messages cannot grow the history annotation without limit
+const maxTransitionReasonLength = 256
+
+// PhaseHistory returns the phase transitions recorded on an instance, oldest first. A
+// missing or unreadable history is empty.
+func PhaseHistory(instance *supacontrolv1alpha1.SupabaseInstance) []supacontrolv1alpha1.PhaseTransition {
+	value, ok := instance.Annotations[supacontrolv1alpha1.AnnotationPhaseHistory]
+	if !ok {
+		return nil
+	}
+	var history []supacontrolv1alpha1.PhaseTransition
+	if err := json.Unmarshal([]byte(value), &history); err != nil {
+		return nil
+	}
+	return history
+}
+
+// transitionReason explains why an instance entered its current phase: the error of a
+// failed instance, or else the reason of its most recently changed condition
+func transitionReason(instance *supacontrolv1alpha1.SupabaseInstance) string {
+	reason := ""
+	if instance.Status.Phase == supacontrolv1alpha1.PhaseFailed && instance.Status.ErrorMessage != "" {
+		reason = instance.Status.ErrorMessage
+	} else {
+		var latest *metav1.Condition
+		for i := range instance.Status.Conditions {
+			condition := &instance.Status.Conditions[i]
+			if latest == nil || !condition.LastTransitionTime.Before(&latest.LastTransitionTime) {
+				latest = condition
+			}
+		}
+		if latest != nil {
+			reason = latest.Reason
+		}
+	}
+	if len(reason) > maxTransitionReasonLength {
+		reason = reason[:maxTransitionReasonLength]
+	}
+	return reason
+}
+
+// appendPhaseTransition adds a transition to a history, dropping the oldest entries
+// beyond MaxPhaseHistory
+func appendPhaseTransition(history []supacontrolv1alpha1.PhaseTransition, transition supacontrolv1alpha1.PhaseTransition) []supacontrolv1alpha1.PhaseTransition {
+	history = append(history, transition)
+	if len(history) > supacontrolv1alpha1.MaxPhaseHistory {
+		history = history[len(history)-supacontrolv1alpha1.MaxPhaseHistory:]
+	}
+	return history
+}
+
+// recordPhaseTransition appends the instance's move from phase to its current phase to
+// its history annotation. The history is informational, so failing to record it is
+// logged rather than failing the reconcile.
+func (r *SupabaseInstanceReconciler) recordPhaseTransition(ctx context.Context, instance *supacontrolv1alpha1.SupabaseInstance, from supacontrolv1alpha1.SupabaseInstancePhase) {
+	if instance.Status.Phase == from || instance.Status.Phase == "" {
+		return
+	}
+	history := appendPhaseTransition(PhaseHistory(instance), supacontrolv1alpha1.PhaseTransition{
+		From:   from,
+		To:     instance.Status.Phase,
+		At:     metav1.Now(),
+		Reason: transitionReason(instance),
+	})
+	value, err := json.Marshal(history)
+	if err != nil {
+		ctrl.LoggerFrom(ctx).Error(err, "Failed to encode phase history")
+		return
+	}
+
+	patch := client.MergeFrom(instance.DeepCopy())
+	if instance.Annotations == nil {
+		instance.Annotations = map[string]string{}
+	}
+	instance.Annotations[supacontrolv1alpha1.AnnotationPhaseHistory] = string(value)
+	if err := r.Patch(ctx, instance, patch); err != nil && !apierrors.IsNotFound(err) {
+		ctrl.LoggerFrom(ctx).Error(err, "Failed to record phase history", "projectName", instance.Spec.ProjectName)
+	}
+}
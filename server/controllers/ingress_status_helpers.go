@@ -0,0 +1,244 @@
+package controllers
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+)
+
+// certificateGVK is the cert-manager Certificate, which cert-manager creates for the TLS
+// secret of every ingress annotated with an issuer. Certificates are read as unstructured
+// objects, so the controller does not depend on cert-manager's API module.
+var certificateGVK = schema.GroupVersionKind{Group: "cert-manager.io", Version: "v1", Kind: "Certificate"}
+
+// waitingReasons are the reasons of conditions that are not true yet while an instance is
+// being created or upgraded, so their events are not warnings
+var waitingReasons = map[string]bool{
+	"AddressPending":  true,
+	"Issuing":         true,
+	"PendingInstall":  true,
+	"PendingUpgrade":  true,
+	"PendingRollback": true,
+
+	string(supacontrolv1alpha1.PhaseBackingUpDatabase): true,
+	string(supacontrolv1alpha1.PhaseUpgradingDatabase): true,
+	string(supacontrolv1alpha1.PhaseVerifyingDatabase): true,
+}
+
+// instanceForLabeledObject maps an object labeled with the instance it belongs to onto a
+// request for that instance. cert-manager copies the labels of an ingress onto the
+// Certificates it creates for it.
+func instanceForLabeledObject(_ context.Context, object client.Object) []reconcile.Request {
+	name := object.GetLabels()["supacontrol.io/instance"]
+	if name == "" {
+		return nil
+	}
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: name}}}
+}
+
+// reconcileIngressStatus reflects the state of a running instance's ingresses in the
+// IngressAdmitted condition and of their TLS certificates in the CertificatesReady
+// condition. Both kinds are watched, so certificate issuance failures and ingress
+// misconfigurations show up as soon as they happen, and every change is recorded as an
+// event on the instance.
+func (r *SupabaseInstanceReconciler) reconcileIngressStatus(ctx context.Context, instance *supacontrolv1alpha1.SupabaseInstance) error {
+	if instance.Status.Namespace == "" {
+		return nil
+	}
+
+	ingresses := &networkingv1.IngressList{}
+	if err := r.List(ctx, ingresses, client.InNamespace(instance.Status.Namespace),
+		client.MatchingLabels{"supacontrol.io/instance": instance.Spec.ProjectName}); err != nil {
+		return fmt.Errorf("failed to list ingresses: %w", err)
+	}
+	// The ingresses are only just being created
+	if len(ingresses.Items) == 0 {
+		return nil
+	}
+	slices.SortFunc(ingresses.Items, func(a, b networkingv1.Ingress) int { return cmp.Compare(a.Name, b.Name) })
+
+	admitted, err := r.ingressCondition(ctx, instance, ingresses.Items)
+	if err != nil {
+		return err
+	}
+	changed := r.setObservedCondition(instance, admitted)
+
+	certificates, err := r.certificateCondition(ctx, instance)
+	if err != nil {
+		return err
+	}
+	if certificates != nil {
+		changed = r.setObservedCondition(instance, *certificates) || changed
+	} else if meta.RemoveStatusCondition(&instance.Status.Conditions, supacontrolv1alpha1.ConditionTypeCertificatesReady) {
+		changed = true
+	}
+
+	if !changed {
+		return nil
+	}
+	return r.updateStatus(ctx, instance)
+}
+
+// ingressCondition checks that the IngressClass of every ingress exists and that an
+// ingress controller has admitted the ingress by giving it an address
+func (r *SupabaseInstanceReconciler) ingressCondition(ctx context.Context, instance *supacontrolv1alpha1.SupabaseInstance, ingresses []networkingv1.Ingress) (metav1.Condition, error) {
+	condition := metav1.Condition{
+		Type:               supacontrolv1alpha1.ConditionTypeIngressAdmitted,
+		ObservedGeneration: instance.Generation,
+	}
+
+	var pending []string
+	for _, ingress := range ingresses {
+		if class := ptr.Deref(ingress.Spec.IngressClassName, ""); class != "" {
+			err := r.Get(ctx, client.ObjectKey{Name: class}, &networkingv1.IngressClass{})
+			if apierrors.IsNotFound(err) {
+				condition.Status = metav1.ConditionFalse
+				condition.Reason = "IngressClassNotFound"
+				condition.Message = fmt.Sprintf("Ingress %s uses IngressClass %s, which does not exist", ingress.Name, class)
+				return condition, nil
+			}
+			if err != nil {
+				return condition, fmt.Errorf("failed to get IngressClass %s: %w", class, err)
+			}
+		}
+		if len(ingress.Status.LoadBalancer.Ingress) == 0 {
+			pending = append(pending, ingress.Name)
+		}
+	}
+
+	if len(pending) > 0 {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "AddressPending"
+		condition.Message = fmt.Sprintf("No ingress controller has admitted %s yet; check that one serves their IngressClass", strings.Join(pending, ", "))
+		return condition, nil
+	}
+	condition.Status = metav1.ConditionTrue
+	condition.Reason = "Admitted"
+	condition.Message = "Every ingress has an address"
+	return condition, nil
+}
+
+// certificateState reads whether a Certificate is ready and, if it is not, whether its last
+// issuance attempt failed and why
+func certificateState(certificate *unstructured.Unstructured) (ready, failed bool, message string) {
+	conditions, _, _ := unstructured.NestedSlice(certificate.Object, "status", "conditions")
+	var issuingMessage string
+	for _, item := range conditions {
+		condition, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		switch condition["type"] {
+		case "Ready":
+			ready = condition["status"] == "True"
+			message, _ = condition["message"].(string)
+		case "Issuing":
+			if condition["reason"] == "Failed" {
+				issuingMessage, _ = condition["message"].(string)
+			}
+		}
+	}
+	if ready {
+		return true, false, ""
+	}
+
+	_, failed, _ = unstructured.NestedString(certificate.Object, "status", "lastFailureTime")
+	if issuingMessage != "" {
+		failed, message = true, issuingMessage
+	}
+	return false, failed, message
+}
+
+// certificateCondition reports whether cert-manager has issued the TLS certificates of the
+// instance's ingresses. It returns nil when the instance has no issuer or its TLS is
+// disabled, or cert-manager is not installed, as the controller then does not request
+// certificates.
+func (r *SupabaseInstanceReconciler) certificateCondition(ctx context.Context, instance *supacontrolv1alpha1.SupabaseInstance) (*metav1.Condition, error) {
+	if r.tlsIssuer(instance) == "" {
+		return nil, nil
+	}
+
+	certificates := &unstructured.UnstructuredList{}
+	certificates.SetGroupVersionKind(certificateGVK.GroupVersion().WithKind(certificateGVK.Kind + "List"))
+	err := r.List(ctx, certificates, client.InNamespace(instance.Status.Namespace),
+		client.MatchingLabels{"supacontrol.io/instance": instance.Spec.ProjectName})
+	if meta.IsNoMatchError(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list certificates: %w", err)
+	}
+	slices.SortFunc(certificates.Items, func(a, b unstructured.Unstructured) int { return cmp.Compare(a.GetName(), b.GetName()) })
+
+	condition := &metav1.Condition{
+		Type:               supacontrolv1alpha1.ConditionTypeCertificatesReady,
+		ObservedGeneration: instance.Generation,
+	}
+	if len(certificates.Items) == 0 {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "Issuing"
+		condition.Message = "Waiting for cert-manager to request the TLS certificates"
+		return condition, nil
+	}
+
+	var issuing []string
+	for i := range certificates.Items {
+		ready, failed, message := certificateState(&certificates.Items[i])
+		switch {
+		case failed:
+			condition.Status = metav1.ConditionFalse
+			condition.Reason = "IssuanceFailed"
+			condition.Message = fmt.Sprintf("Certificate %s could not be issued: %s", certificates.Items[i].GetName(), message)
+			return condition, nil
+		case !ready:
+			issuing = append(issuing, certificates.Items[i].GetName())
+		}
+	}
+
+	if len(issuing) > 0 {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "Issuing"
+		condition.Message = fmt.Sprintf("Waiting for cert-manager to issue %s", strings.Join(issuing, ", "))
+		return condition, nil
+	}
+	condition.Status = metav1.ConditionTrue
+	condition.Reason = "Issued"
+	condition.Message = "Every TLS certificate is issued"
+	return condition, nil
+}
+
+// setObservedCondition sets a condition unless it is unchanged, and records an event on the
+// instance when its status or reason changes; failures are recorded as warnings. It reports
+// whether the condition changed.
+func (r *SupabaseInstanceReconciler) setObservedCondition(instance *supacontrolv1alpha1.SupabaseInstance, condition metav1.Condition) bool {
+	existing := meta.FindStatusCondition(instance.Status.Conditions, condition.Type)
+	if existing != nil && existing.Status == condition.Status && existing.Reason == condition.Reason &&
+		existing.Message == condition.Message && existing.ObservedGeneration == condition.ObservedGeneration {
+		return false
+	}
+	if r.Recorder != nil && (existing == nil || existing.Status != condition.Status || existing.Reason != condition.Reason) {
+		eventType := corev1.EventTypeNormal
+		if condition.Status != metav1.ConditionTrue && !waitingReasons[condition.Reason] {
+			eventType = corev1.EventTypeWarning
+		}
+		r.Recorder.Event(instance, eventType, condition.Reason, condition.Message)
+	}
+	meta.SetStatusCondition(&instance.Status.Conditions, condition)
+	return true
+}
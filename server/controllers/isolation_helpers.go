@@ -0,0 +1,194 @@
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	nodev1 "k8s.io/api/node/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+)
+
+const (
+	// defaultStorageClassAnnotation marks the StorageClass used by claims that name none
+	defaultStorageClassAnnotation = "storageclass.kubernetes.io/is-default-class"
+
+	// vclusterSetup installs or updates the instance's vcluster, when it runs in one, and
+	// points kubectl and helm at it. The vcluster lives in the instance namespace, so
+	// deleting the namespace removes it with everything it runs.
+	vclusterSetup = `
+if [ "${ISOLATION_LEVEL:-}" = "vcluster" ]; then
+  echo "Ensuring vcluster: $VCLUSTER_RELEASE"
+  helm upgrade --install "$VCLUSTER_RELEASE" vcluster \
+    --repo "$VCLUSTER_CHART_REPO" \
+    ${VCLUSTER_CHART_VERSION:+--version "$VCLUSTER_CHART_VERSION"} \
+    --namespace "$NAMESPACE" \
+    --wait \
+    --timeout 10m
+  kubectl get secret "vc-$VCLUSTER_RELEASE" --namespace "$NAMESPACE" -o jsonpath='{.data.config}' | base64 -d > /tmp/vcluster.kubeconfig
+  # The generated kubeconfig targets localhost; reach the vcluster through its Service instead
+  sed -i "s#server: .*#server: https://$VCLUSTER_RELEASE.$NAMESPACE.svc:443#" /tmp/vcluster.kubeconfig
+  export KUBECONFIG=/tmp/vcluster.kubeconfig
+  kubectl create namespace "$NAMESPACE" --dry-run=client -o yaml | kubectl apply -f -
+fi
+`
+)
+
+// requestedIsolation returns the isolation level and fallback requested by the instance
+func requestedIsolation(instance *supacontrolv1alpha1.SupabaseInstance) (supacontrolv1alpha1.IsolationLevel, supacontrolv1alpha1.IsolationFallback) {
+	level, fallback := supacontrolv1alpha1.IsolationNamespace, supacontrolv1alpha1.IsolationFallbackFail
+	if isolation := instance.Spec.Isolation; isolation != nil {
+		if isolation.Level != "" {
+			level = isolation.Level
+		}
+		if isolation.Fallback != "" {
+			fallback = isolation.Fallback
+		}
+	}
+	return level, fallback
+}
+
+// isolationUnavailable reports why the cluster cannot provide an isolation level, or ""
+// when it can. VCluster needs the vcluster chart repository and a default StorageClass
+// for its data; KataRuntime needs the Kata RuntimeClass.
+func (r *SupabaseInstanceReconciler) isolationUnavailable(ctx context.Context, level supacontrolv1alpha1.IsolationLevel) (string, error) {
+	switch level {
+	case supacontrolv1alpha1.IsolationVCluster:
+		if r.VClusterChartRepo == "" {
+			return "vcluster support is not configured", nil
+		}
+		classes := &storagev1.StorageClassList{}
+		if err := r.List(ctx, classes); err != nil {
+			return "", fmt.Errorf("failed to list storage classes: %w", err)
+		}
+		for _, class := range classes.Items {
+			if class.Annotations[defaultStorageClassAnnotation] == "true" {
+				return "", nil
+			}
+		}
+		return "no default StorageClass for the vcluster data", nil
+	case supacontrolv1alpha1.IsolationKataRuntime:
+		if r.KataRuntimeClass == "" {
+			return "Kata Containers support is not configured", nil
+		}
+		runtimeClass := &nodev1.RuntimeClass{}
+		if err := r.Get(ctx, client.ObjectKey{Name: r.KataRuntimeClass}, runtimeClass); err != nil {
+			if apierrors.IsNotFound(err) {
+				return fmt.Sprintf("RuntimeClass %q not found", r.KataRuntimeClass), nil
+			}
+			return "", fmt.Errorf("failed to get RuntimeClass %s: %w", r.KataRuntimeClass, err)
+		}
+	}
+	return "", nil
+}
+
+// resolveIsolation sets the isolation level the instance runs with and its IsolationReady
+// condition. When the requested level is unavailable the instance either falls back to
+// namespace isolation or, by default, fails with the returned message.
+func (r *SupabaseInstanceReconciler) resolveIsolation(ctx context.Context, instance *supacontrolv1alpha1.SupabaseInstance) (string, error) {
+	level, fallback := requestedIsolation(instance)
+	reason, err := r.isolationUnavailable(ctx, level)
+	if err != nil {
+		return "", err
+	}
+
+	condition := metav1.Condition{
+		Type:               supacontrolv1alpha1.ConditionTypeIsolationReady,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: instance.Generation,
+		Reason:             "Available",
+		Message:            fmt.Sprintf("Running with %s isolation", level),
+	}
+	failure := ""
+	switch {
+	case reason == "":
+		instance.Status.IsolationLevel = level
+	case fallback == supacontrolv1alpha1.IsolationFallbackNamespace:
+		instance.Status.IsolationLevel = supacontrolv1alpha1.IsolationNamespace
+		condition.Reason = "FellBackToNamespace"
+		condition.Message = fmt.Sprintf("%s isolation is unavailable (%s); running with Namespace isolation", level, reason)
+	default:
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "Unavailable"
+		condition.Message = fmt.Sprintf("%s isolation is unavailable: %s", level, reason)
+		failure = condition.Message
+	}
+	meta.SetStatusCondition(&instance.Status.Conditions, condition)
+	return failure, nil
+}
+
+// isolationEnv returns the environment provisioning and upgrade Jobs need to reach the
+// instance's vcluster; it is empty for other isolation levels
+func (r *SupabaseInstanceReconciler) isolationEnv(instance *supacontrolv1alpha1.SupabaseInstance) []corev1.EnvVar {
+	if instance.Status.IsolationLevel != supacontrolv1alpha1.IsolationVCluster {
+		return nil
+	}
+	return []corev1.EnvVar{
+		{Name: "ISOLATION_LEVEL", Value: "vcluster"},
+		{Name: "VCLUSTER_RELEASE", Value: vclusterRelease(instance)},
+		{Name: "VCLUSTER_CHART_REPO", Value: r.VClusterChartRepo},
+		{Name: "VCLUSTER_CHART_VERSION", Value: r.VClusterChartVersion},
+	}
+}
+
+// vclusterRelease returns the name of the vcluster release an instance runs in, which is
+// also the name of the vcluster's StatefulSet and Service in the instance namespace
+func vclusterRelease(instance *supacontrolv1alpha1.SupabaseInstance) string {
+	return instance.Spec.ProjectName + "-vcluster"
+}
+
+// vclusterHostName returns the name vcluster gives an object of a virtual namespace when
+// it syncs it to the host namespace, such as the chart's Services that the host ingresses
+// route to. Names longer than 63 characters are shortened with a hash like vcluster does.
+func vclusterHostName(name, namespace, vcluster string) string {
+	full := strings.Join([]string{name, "x", namespace, "x", vcluster}, "-")
+	if len(full) <= 63 {
+		return full
+	}
+	digest := sha256.Sum256([]byte(full))
+	return strings.ReplaceAll(full[:52]+"-"+hex.EncodeToString(digest[:])[:10], ".-", "-")
+}
+
+// vclusterClient returns a client for the inside of an instance's vcluster, built from
+// the kubeconfig vcluster stores in the instance namespace. Like the provisioning Jobs,
+// it reaches the vcluster through its Service rather than the localhost address the
+// kubeconfig names.
+func (r *SupabaseInstanceReconciler) vclusterClient(ctx context.Context, instance *supacontrolv1alpha1.SupabaseInstance) (client.Client, error) {
+	release := vclusterRelease(instance)
+	secret := &corev1.Secret{}
+	if err := r.Get(ctx, client.ObjectKey{Namespace: instance.Status.Namespace, Name: "vc-" + release}, secret); err != nil {
+		return nil, fmt.Errorf("failed to get kubeconfig of vcluster %s: %w", release, err)
+	}
+	config, err := clientcmd.RESTConfigFromKubeConfig(secret.Data["config"])
+	if err != nil {
+		return nil, fmt.Errorf("invalid kubeconfig of vcluster %s: %w", release, err)
+	}
+	config.Host = fmt.Sprintf("https://%s.%s.svc:443", release, instance.Status.Namespace)
+
+	if r.VClusterClient != nil {
+		return r.VClusterClient(config)
+	}
+	return client.New(config, client.Options{Scheme: r.Scheme})
+}
+
+// setRuntimeClassValues runs every chart component with the given RuntimeClass
+func setRuntimeClassValues(values map[string]interface{}, runtimeClass string) {
+	for _, component := range chartComponents {
+		section, ok := values[component].(map[string]interface{})
+		if !ok {
+			section = map[string]interface{}{}
+			values[component] = section
+		}
+		section["runtimeClassName"] = runtimeClass
+	}
+}
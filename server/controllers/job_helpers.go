@@ -7,14 +7,18 @@ import (
 
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/utils/ptr"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/yaml"
 
 	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+	"github.com/qubitquilt/supacontrol/server/internal/addons"
+	"github.com/qubitquilt/supacontrol/server/internal/profiles"
 )
 
 const (
@@ -30,7 +34,17 @@ const (
 	// OperationCleanup is the cleanup operation value
 	OperationCleanup = "cleanup"
 
-	// ProvisionerImage is the Docker image used for provisioning Jobs
+	// OperationUpgrade is the chart upgrade operation value
+	OperationUpgrade = "upgrade"
+
+	// DefaultProvisioningTimeoutSeconds bounds provisioning Jobs of instances that set no timeout
+	DefaultProvisioningTimeoutSeconds = 900
+
+	// DefaultProvisioningRetries is how often provisioning Jobs of instances that set no
+	// retries retry a failed attempt
+	DefaultProvisioningRetries = 3
+
+	// ProvisionerImage is the default Docker image used for provisioning Jobs
 	ProvisionerImage = "alpine/helm:3.13.0"
 
 	// ServiceAccountName is the name of the ServiceAccount used by Jobs
@@ -38,14 +52,274 @@ const (
 
 	// ControllerNamespace is the namespace where the controller runs
 	ControllerNamespace = "supacontrol-system"
+
+	// ProfileValuesKey is the Secret key holding chart values rendered from shared service profiles
+	ProfileValuesKey = "profiles.yaml"
+
+	// profileValuesMountPath is where Jobs mount the rendered profile values
+	profileValuesMountPath = "/etc/supacontrol"
+
+	// CABundleKey is the ConfigMap key holding the custom CA bundle (PEM)
+	CABundleKey = "ca.crt"
+
+	// caBundleMountPath is where Jobs mount the custom CA bundle
+	caBundleMountPath = "/etc/supacontrol-ca"
+
+	// caBundleSetup adds the custom CA bundle, when mounted, to the trust store used by helm
+	caBundleSetup = `
+if [ -n "${CA_BUNDLE:-}" ]; then
+  echo "Trusting custom CA bundle: $CA_BUNDLE"
+  cat /etc/ssl/certs/ca-certificates.crt "$CA_BUNDLE" > /tmp/ca-certificates.crt
+  export SSL_CERT_FILE=/tmp/ca-certificates.crt
+fi
+`
+
+	// ChartRepoUsernameKey and ChartRepoPasswordKey are the keys of the Secret holding the
+	// chart repository credentials
+	ChartRepoUsernameKey = "username"
+	ChartRepoPasswordKey = "password"
+
+	// chartRepoSetup defines add_chart_repo, which adds the chart repository with its
+	// credentials, when set, and the trust store including the custom CA bundle. The
+	// password is passed on stdin so it doesn't show up in the process list.
+	chartRepoSetup = `
+add_chart_repo() {
+  if [ -n "${SSL_CERT_FILE:-}" ]; then
+    set -- "$@" --ca-file "$SSL_CERT_FILE"
+  fi
+  if [ -n "${CHART_REPO_USERNAME:-}" ]; then
+    echo "Authenticating to the chart repository as $CHART_REPO_USERNAME"
+    printf '%s' "$CHART_REPO_PASSWORD" | helm repo add "$@" --username "$CHART_REPO_USERNAME" --password-stdin
+  else
+    helm repo add "$@"
+  fi
+}
+`
 )
 
+// chartVersionFor returns the chart version an instance should run: its own
+// ChartVersion if set, otherwise the controller default (empty means latest)
+func (r *SupabaseInstanceReconciler) chartVersionFor(instance *supacontrolv1alpha1.SupabaseInstance) string {
+	if instance.Spec.ChartVersion != "" {
+		return instance.Spec.ChartVersion
+	}
+	return r.ChartVersion
+}
+
+// provisioningJobName returns the name of an instance's provisioning Job
+func provisioningJobName(instance *supacontrolv1alpha1.SupabaseInstance) string {
+	return fmt.Sprintf("supacontrol-provision-%s", instance.Spec.ProjectName)
+}
+
+// provisioningTimeout returns how long an instance's provisioning Job may run, in seconds
+func provisioningTimeout(instance *supacontrolv1alpha1.SupabaseInstance) int64 {
+	if provisioning := instance.Spec.Provisioning; provisioning != nil && provisioning.TimeoutSeconds > 0 {
+		return int64(provisioning.TimeoutSeconds)
+	}
+	return DefaultProvisioningTimeoutSeconds
+}
+
+// provisioningRetries returns how often an instance's provisioning Job retries a failed attempt
+func provisioningRetries(instance *supacontrolv1alpha1.SupabaseInstance) int32 {
+	if provisioning := instance.Spec.Provisioning; provisioning != nil && provisioning.Retries != nil {
+		return *provisioning.Retries
+	}
+	return DefaultProvisioningRetries
+}
+
+// deleteFailedProvisioningJob deletes a failed provisioning Job left over from an earlier
+// attempt, so a retry does not adopt it. It reports false while such a Job still exists.
+func (r *SupabaseInstanceReconciler) deleteFailedProvisioningJob(ctx context.Context, instance *supacontrolv1alpha1.SupabaseInstance) (bool, error) {
+	job, err := r.getJobStatus(ctx, provisioningJobName(instance))
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return true, nil
+		}
+		return false, err
+	}
+	if !isJobFailed(job) && !hasJobCondition(job, batchv1.JobFailed) {
+		return true, nil
+	}
+	if job.DeletionTimestamp == nil {
+		if err := r.Delete(ctx, job, client.PropagationPolicy(metav1.DeletePropagationBackground)); err != nil && !apierrors.IsNotFound(err) {
+			return false, err
+		}
+	}
+	return false, nil
+}
+
+// resolveProfiles reads the shared service profiles the instance references
+func (r *SupabaseInstanceReconciler) resolveProfiles(ctx context.Context, instance *supacontrolv1alpha1.SupabaseInstance) ([]*profiles.Profile, error) {
+	resolved := make([]*profiles.Profile, 0, len(instance.Spec.Profiles))
+	for _, name := range instance.Spec.Profiles {
+		secret := &corev1.Secret{}
+		if err := r.Get(ctx, client.ObjectKey{Namespace: profiles.Namespace, Name: profiles.SecretName(name)}, secret); err != nil {
+			if apierrors.IsNotFound(err) {
+				return nil, fmt.Errorf("shared service profile %q not found", name)
+			}
+			return nil, fmt.Errorf("failed to get shared service profile %q: %w", name, err)
+		}
+		profile, err := profiles.FromSecret(secret)
+		if err != nil {
+			return nil, err
+		}
+		resolved = append(resolved, profile)
+	}
+	return resolved, nil
+}
+
+// needsProfileSync reports whether the shared service profiles of a running instance were
+// attached, detached or edited since they were last rendered into its chart values
+func (r *SupabaseInstanceReconciler) needsProfileSync(ctx context.Context, instance *supacontrolv1alpha1.SupabaseInstance) (bool, error) {
+	resolved, err := r.resolveProfiles(ctx, instance)
+	if err != nil {
+		return false, err
+	}
+	return profiles.Checksum(resolved) != instance.Status.ProfilesChecksum, nil
+}
+
+// ProfileValuesSecretName returns the name of the Secret holding an instance's profile values
+func ProfileValuesSecretName(instance *supacontrolv1alpha1.SupabaseInstance) string {
+	return fmt.Sprintf("supacontrol-values-%s", instance.Spec.ProjectName)
+}
+
+// ensureProfileValues renders the shared service profiles referenced by the instance, its
+// dedicated node placement if requested, its Kata RuntimeClass, its Postgres volume
+// settings, resources and version, its highly available replicas, its add-ons and its environment variables into chart values and stores them in a Secret
+// that provisioning and upgrade Jobs mount. They are merged over the values of the
+// instance's template. Profiles and templates are read on every call so Jobs always
+// apply their current settings, and the checksum of the profiles is recorded in the
+// status for the caller to store.
+func (r *SupabaseInstanceReconciler) ensureProfileValues(ctx context.Context, instance *supacontrolv1alpha1.SupabaseInstance) error {
+	// The API validates the environment too, but instances may be created without it
+	if err := ValidateEnv(instance.Spec.Env); err != nil {
+		return err
+	}
+
+	resolved, err := r.resolveProfiles(ctx, instance)
+	if err != nil {
+		return err
+	}
+	instance.Status.ProfilesChecksum = profiles.Checksum(resolved)
+
+	chartValues, err := r.templateValues(ctx, instance)
+	if err != nil {
+		return err
+	}
+	_, apiURL := r.instanceURLs(instance)
+	addons.MergeValues(chartValues, profiles.Values(resolved, apiURL))
+	if instance.Status.IsolationLevel == supacontrolv1alpha1.IsolationVCluster {
+		setOAuthSecretValues(chartValues, profiles.OAuthSecrets(resolved))
+	}
+	if isDedicated(instance) {
+		setDedicatedPlacementValues(chartValues, instance.Spec.ProjectName)
+	}
+	if instance.Status.IsolationLevel == supacontrolv1alpha1.IsolationKataRuntime {
+		setRuntimeClassValues(chartValues, r.KataRuntimeClass)
+	}
+	setStorageValues(chartValues, instance.Spec.Storage)
+	setResourceValues(chartValues, instance.Spec.Resources)
+	setDatabaseVersionValues(chartValues, r.postgresImageTag(instance.Status.DatabaseVersion))
+	setHighAvailabilityValues(chartValues, instance)
+	if err := r.setAddonValues(chartValues, instance); err != nil {
+		return err
+	}
+	setEnvValues(chartValues, instance.Spec.Env)
+	values, err := yaml.Marshal(chartValues)
+	if err != nil {
+		return fmt.Errorf("failed to render chart values: %w", err)
+	}
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      ProfileValuesSecretName(instance),
+			Namespace: ControllerNamespace,
+		},
+	}
+	_, err = controllerutil.CreateOrUpdate(ctx, r.Client, secret, func() error {
+		secret.Labels = map[string]string{
+			JobInstanceLabel:              instance.Spec.ProjectName,
+			"app.kubernetes.io/name":      "supacontrol",
+			"app.kubernetes.io/component": "provisioner",
+		}
+		secret.Data = map[string][]byte{ProfileValuesKey: values}
+		return controllerutil.SetControllerReference(instance, secret, r.Scheme)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to store profile values: %w", err)
+	}
+	return nil
+}
+
+// jobFiles returns the volumes, mounts and environment that expose an instance's profile
+// values, and the custom CA bundle if one is configured, to a provisioning or upgrade Job
+func (r *SupabaseInstanceReconciler) jobFiles(instance *supacontrolv1alpha1.SupabaseInstance) ([]corev1.Volume, []corev1.VolumeMount, []corev1.EnvVar) {
+	volumes := []corev1.Volume{{
+		Name: "profile-values",
+		VolumeSource: corev1.VolumeSource{
+			Secret: &corev1.SecretVolumeSource{SecretName: ProfileValuesSecretName(instance)},
+		},
+	}}
+	mounts := []corev1.VolumeMount{{
+		Name:      "profile-values",
+		MountPath: profileValuesMountPath,
+		ReadOnly:  true,
+	}}
+	env := []corev1.EnvVar{{
+		Name:  "PROFILE_VALUES",
+		Value: profileValuesMountPath + "/" + ProfileValuesKey,
+	}}
+
+	if r.CABundleConfigMap != "" {
+		volumes = append(volumes, corev1.Volume{
+			Name: "ca-bundle",
+			VolumeSource: corev1.VolumeSource{
+				ConfigMap: &corev1.ConfigMapVolumeSource{
+					LocalObjectReference: corev1.LocalObjectReference{Name: r.CABundleConfigMap},
+				},
+			},
+		})
+		mounts = append(mounts, corev1.VolumeMount{
+			Name:      "ca-bundle",
+			MountPath: caBundleMountPath,
+			ReadOnly:  true,
+		})
+		env = append(env, corev1.EnvVar{
+			Name:  "CA_BUNDLE",
+			Value: caBundleMountPath + "/" + CABundleKey,
+		})
+	}
+
+	if r.ChartRepoCredentialsSecret != "" {
+		env = append(env,
+			chartRepoCredentialEnv("CHART_REPO_USERNAME", r.ChartRepoCredentialsSecret, ChartRepoUsernameKey),
+			chartRepoCredentialEnv("CHART_REPO_PASSWORD", r.ChartRepoCredentialsSecret, ChartRepoPasswordKey),
+		)
+	}
+
+	return volumes, mounts, env
+}
+
+// chartRepoCredentialEnv returns an environment variable read from a key of the chart
+// repository credentials Secret
+func chartRepoCredentialEnv(name, secretName, key string) corev1.EnvVar {
+	return corev1.EnvVar{
+		Name: name,
+		ValueFrom: &corev1.EnvVarSource{
+			SecretKeyRef: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: secretName},
+				Key:                  key,
+			},
+		},
+	}
+}
+
 // createProvisioningJob creates a Kubernetes Job for provisioning a Supabase instance
 func (r *SupabaseInstanceReconciler) createProvisioningJob(ctx context.Context, instance *supacontrolv1alpha1.SupabaseInstance) (*batchv1.Job, error) {
 	logger := ctrl.LoggerFrom(ctx)
 
-	jobName := fmt.Sprintf("supacontrol-provision-%s", instance.Spec.ProjectName)
-	namespace := fmt.Sprintf("supa-%s", instance.Spec.ProjectName)
+	jobName := provisioningJobName(instance)
+	namespace := r.instanceNamespace(instance)
 
 	// Check if job already exists
 	existingJob := &batchv1.Job{}
@@ -55,11 +329,26 @@ func (r *SupabaseInstanceReconciler) createProvisioningJob(ctx context.Context,
 		return existingJob, nil
 	}
 
-	// Determine chart version
-	chartVersion := r.ChartVersion
-	if instance.Spec.ChartVersion != "" {
-		chartVersion = instance.Spec.ChartVersion
+	image, err := r.provisionerImageFor(instance)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.ensureProfileValues(ctx, instance); err != nil {
+		return nil, err
+	}
+	scriptChecksum, err := r.ensureProvisionerScripts(ctx)
+	if err != nil {
+		return nil, err
 	}
+	volumes, mounts, jobEnv := r.jobFiles(instance)
+	scriptsVolume, scriptsMount := provisionerScriptsVolume()
+	volumes = append(volumes, scriptsVolume)
+	mounts = append(mounts, scriptsMount)
+	jobEnv = append(jobEnv, r.Proxy.EnvVars()...)
+	jobEnv = append(jobEnv, r.isolationEnv(instance)...)
+	jobEnv = append(jobEnv, poolerEnv(instance)...)
+
+	chartVersion := r.chartVersionFor(instance)
 
 	job := &batchv1.Job{
 		ObjectMeta: metav1.ObjectMeta{
@@ -73,12 +362,13 @@ func (r *SupabaseInstanceReconciler) createProvisioningJob(ctx context.Context,
 			},
 			Annotations: map[string]string{
 				"supacontrol.io/instance-uid": string(instance.UID),
+				ScriptChecksumAnnotation:      scriptChecksum,
 			},
 			OwnerReferences: []metav1.OwnerReference{*metav1.NewControllerRef(instance, supacontrolv1alpha1.GroupVersion.WithKind("SupabaseInstance"))},
 		},
 		Spec: batchv1.JobSpec{
-			BackoffLimit:            ptr.To(int32(3)),    // Retry up to 3 times
-			ActiveDeadlineSeconds:   ptr.To(int64(900)),  // 15 minute timeout
+			BackoffLimit:            ptr.To(provisioningRetries(instance)),
+			ActiveDeadlineSeconds:   ptr.To(provisioningTimeout(instance)),
 			TTLSecondsAfterFinished: ptr.To(int32(3600)), // Clean up after 1 hour
 			Template: corev1.PodTemplateSpec{
 				ObjectMeta: metav1.ObjectMeta{
@@ -90,80 +380,14 @@ func (r *SupabaseInstanceReconciler) createProvisioningJob(ctx context.Context,
 				Spec: corev1.PodSpec{
 					ServiceAccountName: ServiceAccountName,
 					RestartPolicy:      corev1.RestartPolicyNever,
+					Volumes:            volumes,
 					Containers: []corev1.Container{
 						{
 							Name:    "provisioner",
-							Image:   ProvisionerImage,
-							Command: []string{"/bin/sh", "-c"},
-							Args: []string{`
-set -euo pipefail
-
-echo "========================================"
-echo "SupaControl Provisioning Job"
-echo "Instance: $INSTANCE_NAME"
-echo "Namespace: $NAMESPACE"
-echo "========================================"
-
-# Step 1: Create namespace
-echo "[1/5] Creating namespace: $NAMESPACE"
-kubectl create namespace "$NAMESPACE" --dry-run=client -o yaml | kubectl apply -f -
-kubectl label namespace "$NAMESPACE" \
-  app.kubernetes.io/managed-by=supacontrol \
-  supacontrol.io/instance="$INSTANCE_NAME" \
-  --overwrite
-
-# Step 2: Generate and create secrets
-echo "[2/5] Generating secrets"
-POSTGRES_PASSWORD=$(openssl rand -base64 32 | tr -d '\n')
-JWT_SECRET=$(openssl rand -base64 64 | tr -d '\n')
-ANON_KEY=$(openssl rand -base64 32 | tr -d '\n')
-SERVICE_ROLE_KEY=$(openssl rand -base64 32 | tr -d '\n')
-
-cat <<EOF | kubectl apply -f -
-apiVersion: v1
-kind: Secret
-metadata:
-  name: $INSTANCE_NAME-secrets
-  namespace: $NAMESPACE
-  labels:
-    app.kubernetes.io/managed-by: supacontrol
-    supacontrol.io/instance: $INSTANCE_NAME
-stringData:
-  postgres-password: "$POSTGRES_PASSWORD"
-  jwt-secret: "$JWT_SECRET"
-  anon-key: "$ANON_KEY"
-  service-role-key: "$SERVICE_ROLE_KEY"
-EOF
-
-echo "[2/5] Secrets created successfully"
-
-# Step 3: Add Helm repository
-echo "[3/5] Adding Helm repository: $CHART_REPO"
-helm repo add supabase-community "$CHART_REPO" || true
-helm repo update
-
-# Step 4: Install Helm chart
-echo "[4/5] Installing Helm chart: $CHART_NAME (version: $CHART_VERSION)"
-helm install "$INSTANCE_NAME" supabase-community/"$CHART_NAME" \
-  --namespace "$NAMESPACE" \
-  --version "$CHART_VERSION" \
-  --set postgresql.auth.postgresPassword="$POSTGRES_PASSWORD" \
-  --set jwt.secret="$JWT_SECRET" \
-  --set jwt.anonKey="$ANON_KEY" \
-  --set jwt.serviceRoleKey="$SERVICE_ROLE_KEY" \
-  --wait \
-  --timeout 10m
-
-echo "[4/5] Helm chart installed successfully"
-
-# Step 5: Report completion
-echo "[5/5] Provisioning complete!"
-echo "========================================"
-echo "Instance '$INSTANCE_NAME' is now running"
-echo "Namespace: $NAMESPACE"
-echo "========================================"
-`},
-							Env: []corev1.EnvVar{
+							Image:   image,
+							Command: []string{"/bin/sh"},
+							Args:    []string{provisionerScriptsMountPath + "/" + ProvisionScriptKey},
+							Env: append([]corev1.EnvVar{
 								{
 									Name:  "INSTANCE_NAME",
 									Value: instance.Spec.ProjectName,
@@ -184,7 +408,10 @@ echo "========================================"
 									Name:  "CHART_VERSION",
 									Value: chartVersion,
 								},
-							},
+							}, jobEnv...),
+							VolumeMounts: mounts,
+							// The last lines a failed attempt logged tell whether it is retried automatically
+							TerminationMessagePolicy: corev1.TerminationMessageFallbackToLogsOnError,
 							Resources: corev1.ResourceRequirements{
 								Requests: corev1.ResourceList{
 									corev1.ResourceCPU:    resource.MustParse("100m"),
@@ -219,10 +446,7 @@ func (r *SupabaseInstanceReconciler) createCleanupJob(ctx context.Context, insta
 	logger := ctrl.LoggerFrom(ctx)
 
 	jobName := fmt.Sprintf("supacontrol-cleanup-%s", instance.Spec.ProjectName)
-	namespace := instance.Status.Namespace
-	if namespace == "" {
-		namespace = fmt.Sprintf("supa-%s", instance.Spec.ProjectName)
-	}
+	namespace := r.instanceNamespace(instance)
 
 	// Check if job already exists
 	existingJob := &batchv1.Job{}
@@ -237,6 +461,11 @@ func (r *SupabaseInstanceReconciler) createCleanupJob(ctx context.Context, insta
 		releaseName = instance.Spec.ProjectName
 	}
 
+	image, err := r.provisionerImageFor(instance)
+	if err != nil {
+		return nil, err
+	}
+
 	job := &batchv1.Job{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      jobName,
@@ -269,7 +498,7 @@ func (r *SupabaseInstanceReconciler) createCleanupJob(ctx context.Context, insta
 					Containers: []corev1.Container{
 						{
 							Name:    "cleanup",
-							Image:   ProvisionerImage,
+							Image:   image,
 							Command: []string{"/bin/sh", "-c"},
 							Args: []string{`
 set -euo pipefail
@@ -299,7 +528,7 @@ echo "========================================"
 echo "Instance '$INSTANCE_NAME' has been deleted"
 echo "========================================"
 `},
-							Env: []corev1.EnvVar{
+							Env: append([]corev1.EnvVar{
 								{
 									Name:  "INSTANCE_NAME",
 									Value: instance.Spec.ProjectName,
@@ -312,7 +541,7 @@ echo "========================================"
 									Name:  "RELEASE_NAME",
 									Value: releaseName,
 								},
-							},
+							}, r.Proxy.EnvVars()...),
 							Resources: corev1.ResourceRequirements{
 								Requests: corev1.ResourceList{
 									corev1.ResourceCPU:    resource.MustParse("100m"),
@@ -338,6 +567,183 @@ echo "========================================"
 	return job, nil
 }
 
+// upgradeChartVersion returns the chart version an upgrade Job installs: the one in the
+// spec, or the deployed one when only the database is resized
+func (r *SupabaseInstanceReconciler) upgradeChartVersion(instance *supacontrolv1alpha1.SupabaseInstance) string {
+	if instance.Spec.ChartVersion != "" {
+		return instance.Spec.ChartVersion
+	}
+	if instance.Status.ChartVersion != "" {
+		return instance.Status.ChartVersion
+	}
+	return r.ChartVersion
+}
+
+// createUpgradeJob creates a Kubernetes Job that upgrades an instance's Helm release to
+// Spec.ChartVersion with its current chart values. When the database resources changed,
+// the Job checkpoints Postgres before the upgrade restarts it and verifies it afterwards.
+// The Job name includes the spec generation and profile checksum so each upgrade gets its
+// own Job.
+func (r *SupabaseInstanceReconciler) createUpgradeJob(ctx context.Context, instance *supacontrolv1alpha1.SupabaseInstance) (*batchv1.Job, error) {
+	logger := ctrl.LoggerFrom(ctx)
+
+	resolved, err := r.resolveProfiles(ctx, instance)
+	if err != nil {
+		return nil, err
+	}
+	jobName := fmt.Sprintf("supacontrol-upgrade-%s-%d", instance.Spec.ProjectName, instance.Generation)
+	if checksum := profiles.Checksum(resolved); checksum != "" {
+		jobName += "-" + checksum[:8]
+	}
+
+	// Check if job already exists
+	existingJob := &batchv1.Job{}
+	err = r.Get(ctx, client.ObjectKey{Namespace: ControllerNamespace, Name: jobName}, existingJob)
+	if err == nil {
+		logger.Info("Upgrade Job already exists", "jobName", jobName)
+		return existingJob, nil
+	}
+
+	releaseName := instance.Status.HelmReleaseName
+	if releaseName == "" {
+		releaseName = instance.Spec.ProjectName
+	}
+
+	image, err := r.provisionerImageFor(instance)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.ensureProfileValues(ctx, instance); err != nil {
+		return nil, err
+	}
+	volumes, mounts, jobEnv := r.jobFiles(instance)
+	jobEnv = append(jobEnv, r.Proxy.EnvVars()...)
+	jobEnv = append(jobEnv, r.isolationEnv(instance)...)
+	jobEnv = append(jobEnv, resizeEnv(instance)...)
+
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      jobName,
+			Namespace: ControllerNamespace,
+			Labels: map[string]string{
+				JobInstanceLabel:              instance.Spec.ProjectName,
+				JobOperationLabel:             OperationUpgrade,
+				"app.kubernetes.io/name":      "supacontrol",
+				"app.kubernetes.io/component": "provisioner",
+			},
+			Annotations: map[string]string{
+				"supacontrol.io/instance-uid": string(instance.UID),
+			},
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit:            ptr.To(int32(1)),    // helm --atomic already rolls back; retry once
+			ActiveDeadlineSeconds:   ptr.To(int64(900)),  // 15 minute timeout
+			TTLSecondsAfterFinished: ptr.To(int32(3600)), // Clean up after 1 hour
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{
+						JobInstanceLabel:  instance.Spec.ProjectName,
+						JobOperationLabel: OperationUpgrade,
+					},
+				},
+				Spec: corev1.PodSpec{
+					ServiceAccountName: ServiceAccountName,
+					RestartPolicy:      corev1.RestartPolicyNever,
+					Volumes:            volumes,
+					Containers: []corev1.Container{
+						{
+							Name:    "upgrade",
+							Image:   image,
+							Command: []string{"/bin/sh", "-c"},
+							Args: []string{`
+set -euo pipefail
+
+echo "========================================"
+echo "SupaControl Upgrade Job"
+echo "Instance: $INSTANCE_NAME"
+echo "Namespace: $NAMESPACE"
+echo "Target chart version: $CHART_VERSION"
+echo "========================================"
+` + caBundleSetup + chartRepoSetup + vclusterSetup + `
+# Step 1: Add Helm repository
+echo "[1/3] Adding Helm repository: $CHART_REPO"
+add_chart_repo supabase-community "$CHART_REPO" || true
+helm repo update
+
+# Step 2: Upgrade Helm release, rolling back automatically on failure
+` + resizeCheckpoint + `
+echo "[2/3] Upgrading Helm release: $RELEASE_NAME"
+helm upgrade "$RELEASE_NAME" supabase-community/"$CHART_NAME" \
+  --namespace "$NAMESPACE" \
+  --version "$CHART_VERSION" \
+  --reuse-values \
+  --values "$PROFILE_VALUES" \
+  --atomic \
+  --wait \
+  --timeout 10m
+` + resizeVerify + `
+# Step 3: Report completion
+echo "[3/3] Upgrade complete!"
+echo "========================================"
+echo "Instance '$INSTANCE_NAME' now runs chart version $CHART_VERSION"
+echo "========================================"
+`},
+							Env: append([]corev1.EnvVar{
+								{
+									Name:  "INSTANCE_NAME",
+									Value: instance.Spec.ProjectName,
+								},
+								{
+									Name:  "NAMESPACE",
+									Value: instance.Status.Namespace,
+								},
+								{
+									Name:  "RELEASE_NAME",
+									Value: releaseName,
+								},
+								{
+									Name:  "CHART_REPO",
+									Value: r.ChartRepo,
+								},
+								{
+									Name:  "CHART_NAME",
+									Value: r.ChartName,
+								},
+								{
+									Name:  "CHART_VERSION",
+									Value: r.upgradeChartVersion(instance),
+								},
+							}, jobEnv...),
+							VolumeMounts: mounts,
+							Resources: corev1.ResourceRequirements{
+								Requests: corev1.ResourceList{
+									corev1.ResourceCPU:    resource.MustParse("100m"),
+									corev1.ResourceMemory: resource.MustParse("256Mi"),
+								},
+								Limits: corev1.ResourceList{
+									corev1.ResourceCPU:    resource.MustParse("500m"),
+									corev1.ResourceMemory: resource.MustParse("512Mi"),
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	if err := controllerutil.SetControllerReference(instance, job, r.Scheme); err != nil {
+		return nil, fmt.Errorf("failed to set controller reference: %w", err)
+	}
+
+	if err := r.Create(ctx, job); err != nil {
+		return nil, fmt.Errorf("failed to create upgrade Job: %w", err)
+	}
+
+	logger.Info("Created upgrade Job", "jobName", jobName, "namespace", ControllerNamespace)
+	return job, nil
+}
+
 // getJobStatus retrieves the status of a Job
 func (r *SupabaseInstanceReconciler) getJobStatus(ctx context.Context, jobName string) (*batchv1.Job, error) {
 	job := &batchv1.Job{}
@@ -353,12 +759,26 @@ func isJobSucceeded(job *batchv1.Job) bool {
 	return job.Status.Succeeded > 0
 }
 
-// isJobFailed checks if a Job has failed permanently (exhausted retries)
+// isJobFailed checks if a Job has failed permanently: it exhausted its retries or ran
+// past its deadline. A backoff limit of n allows n+1 attempts.
 func isJobFailed(job *batchv1.Job) bool {
+	if hasJobCondition(job, batchv1.JobFailed) {
+		return true
+	}
 	if job.Spec.BackoffLimit == nil {
 		return false
 	}
-	return job.Status.Failed >= *job.Spec.BackoffLimit
+	return job.Status.Failed > *job.Spec.BackoffLimit
+}
+
+// hasJobCondition checks if a Job has the given condition set to true
+func hasJobCondition(job *batchv1.Job, conditionType batchv1.JobConditionType) bool {
+	for _, condition := range job.Status.Conditions {
+		if condition.Type == conditionType && condition.Status == corev1.ConditionTrue {
+			return true
+		}
+	}
+	return false
 }
 
 // isJobActive checks if a Job is currently running
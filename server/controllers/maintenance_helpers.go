@@ -0,0 +1,297 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"html/template"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+)
+
+const (
+	// MaintenancePageImage serves the maintenance page of instances in maintenance
+	MaintenancePageImage = "nginxinc/nginx-unprivileged:1.27-alpine"
+
+	// MaintenancePagePort is the port the maintenance page is served on
+	MaintenancePagePort = 8080
+
+	// maintenanceContentAnnotation records a hash of the maintenance page on its pods, so
+	// they are replaced when the message changes
+	maintenanceContentAnnotation = "supacontrol.io/maintenance-content"
+)
+
+// maintenanceServerConfig answers every request with the maintenance page and status 503,
+// so clients retry later rather than treating the response as the API's
+const maintenanceServerConfig = `server {
+    listen 8080;
+    root /usr/share/nginx/html;
+    error_page 503 /index.html;
+    location / {
+        return 503;
+    }
+    location = /index.html {
+        internal;
+        add_header Retry-After 300 always;
+        add_header Cache-Control no-store always;
+    }
+}
+`
+
+// maintenancePage is served by the ingresses of instances in maintenance
+var maintenancePage = template.Must(template.New("maintenance").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>{{.Name}} is under maintenance</title></head>
+<body style="font-family: sans-serif; text-align: center; margin-top: 15%">
+<h1>{{.Name}} is under maintenance</h1>
+<p>{{if .Message}}{{.Message}}{{else}}The service will be back shortly.{{end}}</p>
+</body>
+</html>
+`))
+
+// MaintenancePageName returns the name of the ConfigMap, Deployment and Service of an
+// instance's maintenance page
+func MaintenancePageName(projectName string) string {
+	return fmt.Sprintf("%s-maintenance", projectName)
+}
+
+// InMaintenance reports whether an instance's ingresses should serve its maintenance page
+func InMaintenance(instance *supacontrolv1alpha1.SupabaseInstance) bool {
+	return instance.Spec.Maintenance != nil
+}
+
+// maintenancePageSelector returns the labels selecting an instance's maintenance page pods
+func maintenancePageSelector(projectName string) map[string]string {
+	return map[string]string{
+		"app.kubernetes.io/name":  "maintenance-page",
+		"supacontrol.io/instance": projectName,
+	}
+}
+
+// renderMaintenancePage renders the maintenance page of an instance
+func renderMaintenancePage(instance *supacontrolv1alpha1.SupabaseInstance) (string, error) {
+	var page bytes.Buffer
+	err := maintenancePage.Execute(&page, map[string]string{
+		"Name":    instance.Spec.ProjectName,
+		"Message": instance.Spec.Maintenance.Message,
+	})
+	return page.String(), err
+}
+
+// ingressBackends returns the Services the Studio and API ingresses of an instance route
+// to: the instance's own, or its maintenance page while it is in maintenance. The
+// maintenance page runs in the host namespace for every isolation level.
+func ingressBackends(instance *supacontrolv1alpha1.SupabaseInstance) (studio, api networkingv1.IngressServiceBackend) {
+	if InMaintenance(instance) {
+		page := networkingv1.IngressServiceBackend{
+			Name: MaintenancePageName(instance.Spec.ProjectName),
+			Port: networkingv1.ServiceBackendPort{Number: MaintenancePagePort},
+		}
+		return page, page
+	}
+	releaseName := instance.Status.HelmReleaseName
+	studio = networkingv1.IngressServiceBackend{
+		Name: fmt.Sprintf("%s-studio", releaseName),
+		Port: networkingv1.ServiceBackendPort{Number: 3000},
+	}
+	api = networkingv1.IngressServiceBackend{
+		Name: fmt.Sprintf("%s-kong", releaseName),
+		Port: networkingv1.ServiceBackendPort{Number: KongPort},
+	}
+	// The chart of a vcluster instance runs inside the vcluster, whose Services reach the
+	// host namespace under the names vcluster syncs them to
+	if instance.Status.IsolationLevel == supacontrolv1alpha1.IsolationVCluster {
+		studio.Name = vclusterHostName(studio.Name, instance.Status.Namespace, vclusterRelease(instance))
+		api.Name = vclusterHostName(api.Name, instance.Status.Namespace, vclusterRelease(instance))
+	}
+	return studio, api
+}
+
+// ingressBackend returns the Service an ingress routes to, or nil when it has none
+func ingressBackend(ingress *networkingv1.Ingress) *networkingv1.IngressServiceBackend {
+	for _, rule := range ingress.Spec.Rules {
+		if rule.HTTP == nil {
+			continue
+		}
+		for _, path := range rule.HTTP.Paths {
+			return path.Backend.Service
+		}
+	}
+	return nil
+}
+
+// reconcileMaintenance runs the maintenance page of a running instance in maintenance and
+// records it in the Maintenance condition. The ingresses are switched over by
+// reconcileRunning once the page exists. When the maintenance ends, the ingresses are
+// pointed back at the instance before the page is removed, so no request hits a missing
+// Service.
+func (r *SupabaseInstanceReconciler) reconcileMaintenance(ctx context.Context, instance *supacontrolv1alpha1.SupabaseInstance) error {
+	existing := meta.FindStatusCondition(instance.Status.Conditions, supacontrolv1alpha1.ConditionTypeMaintenance)
+	if instance.Status.Namespace == "" || (!InMaintenance(instance) && existing == nil) {
+		return nil
+	}
+
+	if !InMaintenance(instance) {
+		ctrl.LoggerFrom(ctx).Info("Ending maintenance", "projectName", instance.Spec.ProjectName)
+		if err := r.ensureIngresses(ctx, instance); err != nil {
+			return err
+		}
+		if err := r.deleteMaintenancePage(ctx, instance); err != nil {
+			return err
+		}
+		meta.RemoveStatusCondition(&instance.Status.Conditions, supacontrolv1alpha1.ConditionTypeMaintenance)
+		if r.Recorder != nil {
+			r.Recorder.Event(instance, corev1.EventTypeNormal, "MaintenanceEnded", "Ingresses route to the instance again")
+		}
+		return r.updateStatus(ctx, instance)
+	}
+
+	if err := r.applyMaintenancePage(ctx, instance); err != nil {
+		return err
+	}
+	message := "Ingresses serve the maintenance page"
+	if instance.Spec.Maintenance.Message != "" {
+		message += ": " + instance.Spec.Maintenance.Message
+	}
+	if !r.setObservedCondition(instance, metav1.Condition{
+		Type:               supacontrolv1alpha1.ConditionTypeMaintenance,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: instance.Generation,
+		Reason:             "InMaintenance",
+		Message:            message,
+	}) {
+		return nil
+	}
+	return r.updateStatus(ctx, instance)
+}
+
+// applyMaintenancePage applies the ConfigMap, Deployment and Service serving an instance's
+// maintenance page
+func (r *SupabaseInstanceReconciler) applyMaintenancePage(ctx context.Context, instance *supacontrolv1alpha1.SupabaseInstance) error {
+	namespace := instance.Status.Namespace
+	name := MaintenancePageName(instance.Spec.ProjectName)
+	selector := maintenancePageSelector(instance.Spec.ProjectName)
+	labels := map[string]string{"app.kubernetes.io/managed-by": "supacontrol"}
+	for key, value := range selector {
+		labels[key] = value
+	}
+
+	page, err := renderMaintenancePage(instance)
+	if err != nil {
+		return fmt.Errorf("failed to render maintenance page: %w", err)
+	}
+	content := sha256.Sum256([]byte(page))
+
+	configMap := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace}}
+	_, err = controllerutil.CreateOrUpdate(ctx, r.Client, configMap, func() error {
+		configMap.Labels = labels
+		configMap.Data = map[string]string{
+			"index.html":   page,
+			"default.conf": maintenanceServerConfig,
+		}
+		return controllerutil.SetControllerReference(instance, configMap, r.Scheme)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to apply maintenance page ConfigMap: %w", err)
+	}
+
+	configVolume := func(volumeName, key string) corev1.Volume {
+		return corev1.Volume{Name: volumeName, VolumeSource: corev1.VolumeSource{ConfigMap: &corev1.ConfigMapVolumeSource{
+			LocalObjectReference: corev1.LocalObjectReference{Name: name},
+			Items:                []corev1.KeyToPath{{Key: key, Path: key}},
+		}}}
+	}
+	deployment := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace}}
+	_, err = controllerutil.CreateOrUpdate(ctx, r.Client, deployment, func() error {
+		deployment.Labels = labels
+		deployment.Spec.Replicas = ptr.To(int32(1))
+		// The selector is immutable, so it is only set when the Deployment is created
+		if deployment.CreationTimestamp.IsZero() {
+			deployment.Spec.Selector = &metav1.LabelSelector{MatchLabels: selector}
+		}
+		deployment.Spec.Template = corev1.PodTemplateSpec{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels:      labels,
+				Annotations: map[string]string{maintenanceContentAnnotation: hex.EncodeToString(content[:8])},
+			},
+			Spec: corev1.PodSpec{
+				Containers: []corev1.Container{{
+					Name:  "maintenance-page",
+					Image: MaintenancePageImage,
+					Ports: []corev1.ContainerPort{{Name: "http", ContainerPort: MaintenancePagePort}},
+					VolumeMounts: []corev1.VolumeMount{
+						{Name: "page", MountPath: "/usr/share/nginx/html", ReadOnly: true},
+						{Name: "config", MountPath: "/etc/nginx/conf.d", ReadOnly: true},
+					},
+					Resources: corev1.ResourceRequirements{
+						Requests: corev1.ResourceList{
+							corev1.ResourceCPU:    resource.MustParse("10m"),
+							corev1.ResourceMemory: resource.MustParse("16Mi"),
+						},
+						Limits: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("64Mi")},
+					},
+					SecurityContext: &corev1.SecurityContext{
+						RunAsNonRoot:             ptr.To(true),
+						AllowPrivilegeEscalation: ptr.To(false),
+					},
+				}},
+				Volumes: []corev1.Volume{
+					configVolume("page", "index.html"),
+					configVolume("config", "default.conf"),
+				},
+			},
+		}
+		return controllerutil.SetControllerReference(instance, deployment, r.Scheme)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to apply maintenance page Deployment: %w", err)
+	}
+
+	service := &corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace}}
+	_, err = controllerutil.CreateOrUpdate(ctx, r.Client, service, func() error {
+		service.Labels = labels
+		service.Spec.Selector = selector
+		service.Spec.Ports = []corev1.ServicePort{{
+			Name:       "http",
+			Port:       MaintenancePagePort,
+			TargetPort: intstr.FromString("http"),
+		}}
+		return controllerutil.SetControllerReference(instance, service, r.Scheme)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to apply maintenance page Service: %w", err)
+	}
+	return nil
+}
+
+// deleteMaintenancePage removes the maintenance page of an instance
+func (r *SupabaseInstanceReconciler) deleteMaintenancePage(ctx context.Context, instance *supacontrolv1alpha1.SupabaseInstance) error {
+	name := MaintenancePageName(instance.Spec.ProjectName)
+	objects := []client.Object{
+		&corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: instance.Status.Namespace}},
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: instance.Status.Namespace}},
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: instance.Status.Namespace}},
+	}
+	for _, object := range objects {
+		err := r.Delete(ctx, object, client.PropagationPolicy(metav1.DeletePropagationBackground))
+		if err != nil && !apierrors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete maintenance page %T %s: %w", object, name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,191 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrlmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+	"github.com/qubitquilt/supacontrol/server/internal/metrics"
+)
+
+// Requeue reasons recorded by the supacontrol_reconciliation_requeues_total metric
+const (
+	// RequeueReasonError is a reconciliation that failed and is retried with backoff
+	RequeueReasonError = "error"
+
+	// RequeueReasonPhaseChange is a reconciliation that moved the instance to another phase
+	RequeueReasonPhaseChange = "phase_change"
+
+	// RequeueReasonPoll is a reconciliation waiting on a Job, a retry or a health check
+	// streak, so it runs again sooner than the resync interval
+	RequeueReasonPoll = "poll"
+
+	// RequeueReasonResync is a settled instance checked again on the resync interval
+	RequeueReasonResync = "resync"
+)
+
+// phaseCollectorTimeout bounds listing the instances when /metrics is scraped
+const phaseCollectorTimeout = 5 * time.Second
+
+var (
+	// instancesByPhaseDesc describes the supacontrol_instances_by_phase gauge
+	instancesByPhaseDesc = prometheus.NewDesc(
+		"supacontrol_instances_by_phase",
+		"Number of Supabase instances by phase",
+		[]string{"phase"}, nil,
+	)
+
+	// instanceFailuresTotal counts transitions to the Failed phase, by the phase the instance
+	// failed in and the reason of its Ready condition
+	instanceFailuresTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "supacontrol_instance_failures_total",
+			Help: "Total number of instance transitions to the Failed phase by the phase they failed in and reason",
+		},
+		[]string{"phase", "reason"},
+	)
+)
+
+func init() {
+	ctrlmetrics.Registry.MustRegister(instanceFailuresTotal)
+}
+
+// phaseCollector reports the number of instances in each phase. It counts the instances in
+// the manager's cache on every scrape, so deleted instances never linger in the gauge.
+type phaseCollector struct {
+	reader client.Reader
+}
+
+// Describe implements prometheus.Collector
+func (c *phaseCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- instancesByPhaseDesc
+}
+
+// Collect implements prometheus.Collector
+func (c *phaseCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), phaseCollectorTimeout)
+	defer cancel()
+
+	var instances supacontrolv1alpha1.SupabaseInstanceList
+	if err := c.reader.List(ctx, &instances); err != nil {
+		ch <- prometheus.NewInvalidMetric(instancesByPhaseDesc, err)
+		return
+	}
+	counts := make(map[string]int)
+	for _, phase := range supacontrolv1alpha1.AllPhases() {
+		counts[phase] = 0
+	}
+	for _, instance := range instances.Items {
+		phase := instance.Status.Phase
+		if phase == "" {
+			// New instances are pending until they are first reconciled
+			phase = supacontrolv1alpha1.PhasePending
+		}
+		counts[string(phase)]++
+	}
+	for phase, count := range counts {
+		ch <- prometheus.MustNewConstMetric(instancesByPhaseDesc, prometheus.GaugeValue, float64(count), phase)
+	}
+}
+
+// registerPhaseCollector serves the instances per phase with the controller runtime's
+// metrics. A collector registered by an earlier manager is kept.
+func registerPhaseCollector(reader client.Reader) error {
+	err := ctrlmetrics.Registry.Register(&phaseCollector{reader: reader})
+	var registered prometheus.AlreadyRegisteredError
+	if errors.As(err, &registered) {
+		return nil
+	}
+	return err
+}
+
+// jobRunDuration returns how long a finished Job ran, or false when the API server did not
+// record its start or end
+func jobRunDuration(job *batchv1.Job) (time.Duration, bool) {
+	if job.Status.StartTime == nil {
+		return 0, false
+	}
+	end := job.Status.CompletionTime
+	if end == nil {
+		// Failed Jobs have no completion time, but their Failed condition says when they gave up
+		for _, condition := range job.Status.Conditions {
+			if condition.Type == batchv1.JobFailed && condition.Status == corev1.ConditionTrue {
+				end = &condition.LastTransitionTime
+				break
+			}
+		}
+	}
+	if end == nil || end.Before(job.Status.StartTime) {
+		return 0, false
+	}
+	return end.Sub(job.Status.StartTime.Time), true
+}
+
+// recordJobDuration records how long a finished provisioning, upgrade or cleanup Job ran
+func recordJobDuration(operation, status string, job *batchv1.Job) {
+	if duration, ok := jobRunDuration(job); ok {
+		metrics.JobDuration.WithLabelValues(operation, status).Observe(duration.Seconds())
+	}
+}
+
+// phaseEnteredAt returns when an instance entered its current phase. Instances that never
+// transitioned have been in their phase since they were created.
+func phaseEnteredAt(instance *supacontrolv1alpha1.SupabaseInstance) time.Time {
+	if instance.Status.LastTransitionTime != nil {
+		return instance.Status.LastTransitionTime.Time
+	}
+	return instance.CreationTimestamp.Time
+}
+
+// requeueReason classifies the outcome of a reconciliation, or returns "" when it was not
+// requeued
+func (r *SupabaseInstanceReconciler) requeueReason(result ctrl.Result, err error, phaseChanged bool) string {
+	switch {
+	case err != nil:
+		return RequeueReasonError
+	case result.RequeueAfter <= 0:
+		return ""
+	case phaseChanged:
+		return RequeueReasonPhaseChange
+	case result.RequeueAfter < r.resyncInterval():
+		return RequeueReasonPoll
+	}
+	return RequeueReasonResync
+}
+
+// recordReconcileOutcome records the time spent in a phase the instance left and why the
+// reconciliation was requeued. phase and enteredAt describe the instance before it was
+// reconciled.
+func (r *SupabaseInstanceReconciler) recordReconcileOutcome(instance *supacontrolv1alpha1.SupabaseInstance, phase supacontrolv1alpha1.SupabaseInstancePhase, enteredAt time.Time, result ctrl.Result, err error) {
+	next := instance.Status.Phase
+	// A failed reconciliation may have changed the phase in memory without persisting it
+	phaseChanged := err == nil && next != phase
+	if phaseChanged && phase != "" && !enteredAt.IsZero() {
+		metrics.PhaseDuration.WithLabelValues(string(phase), string(next)).Observe(time.Since(enteredAt).Seconds())
+	}
+
+	label := string(phase)
+	if label == "" {
+		label = "unknown"
+	}
+	if phaseChanged && next == supacontrolv1alpha1.PhaseFailed {
+		reason := "unknown"
+		if ready := meta.FindStatusCondition(instance.Status.Conditions, supacontrolv1alpha1.ConditionTypeReady); ready != nil && ready.Status == metav1.ConditionFalse {
+			reason = ready.Reason
+		}
+		instanceFailuresTotal.WithLabelValues(label, reason).Inc()
+	}
+	if reason := r.requeueReason(result, err, phaseChanged); reason != "" {
+		metrics.ReconciliationRequeuesTotal.WithLabelValues(label, reason).Inc()
+	}
+}
@@ -0,0 +1,235 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+)
+
+const (
+	// PostgresExporterImage is the postgres_exporter image deployed for monitored instances
+	PostgresExporterImage = "quay.io/prometheuscommunity/postgres-exporter:v0.15.0"
+
+	// PostgresExporterPort is the port the exporter serves metrics on
+	PostgresExporterPort = 9187
+
+	// DefaultMonitoringNamespace is where Prometheus runs when the reconciler does not
+	// name its namespace
+	DefaultMonitoringNamespace = "monitoring"
+
+	// exporterScrapeInterval is how often Prometheus scrapes an instance's exporter
+	exporterScrapeInterval = "30s"
+)
+
+// PostgresExporterName returns the name of the Deployment, Service and ServiceMonitor of
+// an instance's Postgres exporter
+func PostgresExporterName(projectName string) string {
+	return fmt.Sprintf("%s-postgres-exporter", projectName)
+}
+
+// HasMonitoring reports whether the instance requests Prometheus monitoring
+func HasMonitoring(instance *supacontrolv1alpha1.SupabaseInstance) bool {
+	return instance.Spec.Monitoring != nil && instance.Spec.Monitoring.Enabled
+}
+
+// monitoringNamespace returns the configured Prometheus namespace or its default
+func (r *SupabaseInstanceReconciler) monitoringNamespace() string {
+	if r.MonitoringNamespace != "" {
+		return r.MonitoringNamespace
+	}
+	return DefaultMonitoringNamespace
+}
+
+// exporterSelector returns the labels selecting an instance's exporter pods
+func exporterSelector(projectName string) map[string]string {
+	return map[string]string{
+		"app.kubernetes.io/name":  "postgres-exporter",
+		"supacontrol.io/instance": projectName,
+	}
+}
+
+// reconcileMonitoring runs a Postgres exporter for a running instance that requests
+// monitoring, with a ServiceMonitor labeled for the platform's Prometheus, and removes
+// them once monitoring is turned off. The outcome is recorded in the MonitoringReady
+// condition; a cluster without the Prometheus Operator fails the condition rather than
+// the reconcile.
+func (r *SupabaseInstanceReconciler) reconcileMonitoring(ctx context.Context, instance *supacontrolv1alpha1.SupabaseInstance) error {
+	enabled := HasMonitoring(instance)
+	existing := meta.FindStatusCondition(instance.Status.Conditions, supacontrolv1alpha1.ConditionTypeMonitoringReady)
+	if !enabled && existing == nil {
+		return nil
+	}
+	namespace := instance.Status.Namespace
+	if namespace == "" {
+		return nil
+	}
+	name := PostgresExporterName(instance.Spec.ProjectName)
+
+	if !enabled {
+		objects := []client.Object{
+			&monitoringv1.ServiceMonitor{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace}},
+			&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace}},
+			&corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace}},
+		}
+		for _, object := range objects {
+			err := r.Delete(ctx, object, client.PropagationPolicy(metav1.DeletePropagationBackground))
+			if err != nil && !apierrors.IsNotFound(err) && !meta.IsNoMatchError(err) {
+				return fmt.Errorf("failed to delete exporter %T %s: %w", object, name, err)
+			}
+		}
+		meta.RemoveStatusCondition(&instance.Status.Conditions, supacontrolv1alpha1.ConditionTypeMonitoringReady)
+		return r.updateStatus(ctx, instance)
+	}
+
+	condition := metav1.Condition{
+		Type:               supacontrolv1alpha1.ConditionTypeMonitoringReady,
+		ObservedGeneration: instance.Generation,
+	}
+	if instance.Status.IsolationLevel == supacontrolv1alpha1.IsolationVCluster {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "IsolationUnsupported"
+		condition.Message = "Monitoring cannot be deployed for vcluster instances"
+	} else {
+		if err := r.applyExporter(ctx, instance); err != nil {
+			return err
+		}
+		err := r.applyServiceMonitor(ctx, instance)
+		switch {
+		case meta.IsNoMatchError(err):
+			condition.Status = metav1.ConditionFalse
+			condition.Reason = "ServiceMonitorUnsupported"
+			condition.Message = "The exporter is running, but the cluster has no ServiceMonitor API; install the Prometheus Operator"
+		case err != nil:
+			return err
+		default:
+			condition.Status = metav1.ConditionTrue
+			condition.Reason = "Deployed"
+			condition.Message = "Postgres exporter and ServiceMonitor deployed"
+		}
+	}
+
+	if existing != nil && existing.Status == condition.Status && existing.Reason == condition.Reason &&
+		existing.Message == condition.Message && existing.ObservedGeneration == condition.ObservedGeneration {
+		return nil
+	}
+	meta.SetStatusCondition(&instance.Status.Conditions, condition)
+	return r.updateStatus(ctx, instance)
+}
+
+// applyExporter applies the exporter Deployment, which connects to the primary database as
+// the postgres user, and the Service Prometheus scrapes it through
+func (r *SupabaseInstanceReconciler) applyExporter(ctx context.Context, instance *supacontrolv1alpha1.SupabaseInstance) error {
+	params := r.addonParams(instance)
+	name := PostgresExporterName(instance.Spec.ProjectName)
+	selector := exporterSelector(instance.Spec.ProjectName)
+	labels := map[string]string{"app.kubernetes.io/managed-by": "supacontrol"}
+	for key, value := range selector {
+		labels[key] = value
+	}
+
+	deployment := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: params.Namespace}}
+	_, err := controllerutil.CreateOrUpdate(ctx, r.Client, deployment, func() error {
+		deployment.Labels = labels
+		deployment.Spec.Replicas = ptr.To(int32(1))
+		// The selector is immutable, so it is only set when the Deployment is created
+		if deployment.CreationTimestamp.IsZero() {
+			deployment.Spec.Selector = &metav1.LabelSelector{MatchLabels: selector}
+		}
+		deployment.Spec.Template = corev1.PodTemplateSpec{
+			ObjectMeta: metav1.ObjectMeta{Labels: labels},
+			Spec: corev1.PodSpec{
+				Containers: []corev1.Container{{
+					Name:  "postgres-exporter",
+					Image: PostgresExporterImage,
+					Env: []corev1.EnvVar{
+						{Name: "DATA_SOURCE_URI", Value: fmt.Sprintf("%s:5432/postgres?sslmode=disable", params.DatabaseHost)},
+						{Name: "DATA_SOURCE_USER", Value: "postgres"},
+						{Name: "DATA_SOURCE_PASS", ValueFrom: &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{
+							LocalObjectReference: corev1.LocalObjectReference{Name: params.DatabaseSecret},
+							Key:                  "postgres-password",
+						}}},
+					},
+					Ports: []corev1.ContainerPort{{Name: "metrics", ContainerPort: PostgresExporterPort}},
+					Resources: corev1.ResourceRequirements{
+						Requests: corev1.ResourceList{
+							corev1.ResourceCPU:    resource.MustParse("10m"),
+							corev1.ResourceMemory: resource.MustParse("32Mi"),
+						},
+						Limits: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("128Mi")},
+					},
+					ReadinessProbe: &corev1.Probe{
+						ProbeHandler: corev1.ProbeHandler{HTTPGet: &corev1.HTTPGetAction{
+							Path: "/",
+							Port: intstr.FromString("metrics"),
+						}},
+						PeriodSeconds: 10,
+					},
+				}},
+			},
+		}
+		return controllerutil.SetControllerReference(instance, deployment, r.Scheme)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to apply exporter Deployment: %w", err)
+	}
+
+	service := &corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: params.Namespace}}
+	_, err = controllerutil.CreateOrUpdate(ctx, r.Client, service, func() error {
+		service.Labels = labels
+		service.Spec.Selector = selector
+		service.Spec.Ports = []corev1.ServicePort{{
+			Name:       "metrics",
+			Port:       PostgresExporterPort,
+			TargetPort: intstr.FromString("metrics"),
+		}}
+		return controllerutil.SetControllerReference(instance, service, r.Scheme)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to apply exporter Service: %w", err)
+	}
+	return nil
+}
+
+// applyServiceMonitor applies the ServiceMonitor of the exporter, carrying the labels the
+// platform's Prometheus selects ServiceMonitors by. Scraped series get the instance's
+// project name as their supacontrol_io_instance label.
+func (r *SupabaseInstanceReconciler) applyServiceMonitor(ctx context.Context, instance *supacontrolv1alpha1.SupabaseInstance) error {
+	name := PostgresExporterName(instance.Spec.ProjectName)
+	selector := exporterSelector(instance.Spec.ProjectName)
+	labels := map[string]string{"app.kubernetes.io/managed-by": "supacontrol"}
+	for key, value := range r.MonitoringLabels {
+		labels[key] = value
+	}
+	for key, value := range selector {
+		labels[key] = value
+	}
+
+	serviceMonitor := &monitoringv1.ServiceMonitor{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: instance.Status.Namespace}}
+	_, err := controllerutil.CreateOrUpdate(ctx, r.Client, serviceMonitor, func() error {
+		serviceMonitor.Labels = labels
+		serviceMonitor.Spec.Selector = metav1.LabelSelector{MatchLabels: selector}
+		serviceMonitor.Spec.TargetLabels = []string{"supacontrol.io/instance"}
+		serviceMonitor.Spec.Endpoints = []monitoringv1.Endpoint{{
+			Port:     "metrics",
+			Interval: exporterScrapeInterval,
+		}}
+		return controllerutil.SetControllerReference(instance, serviceMonitor, r.Scheme)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to apply exporter ServiceMonitor: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"maps"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+	"github.com/qubitquilt/supacontrol/server/internal/namespaces"
+)
+
+// namespaceFieldOwner is the field manager the labels and annotations of instance
+// namespaces are applied with, so ones removed from the configuration or the spec are
+// removed from the namespace as well
+const namespaceFieldOwner = "supacontrol-namespace"
+
+// instanceNamespace returns the namespace of an instance: the one it was provisioned in,
+// or else the one NamespaceTemplate names for it
+func (r *SupabaseInstanceReconciler) instanceNamespace(instance *supacontrolv1alpha1.SupabaseInstance) string {
+	if instance.Status.Namespace != "" {
+		return instance.Status.Namespace
+	}
+	return namespaces.Name(r.NamespaceTemplate, instance.Spec.ProjectName)
+}
+
+// validateNamespace checks that an instance would not be installed into a system namespace
+// or one of the platform's own, and that its namespace labels are valid
+func (r *SupabaseInstanceReconciler) validateNamespace(instance *supacontrolv1alpha1.SupabaseInstance) error {
+	if err := namespaces.Validate(r.instanceNamespace(instance),
+		ControllerNamespace, r.ingressControllerNamespace(), r.monitoringNamespace()); err != nil {
+		return err
+	}
+	return namespaces.ValidateLabels(instance.Spec.NamespaceLabels)
+}
+
+// instanceForNamespace maps a namespace, or an object in one, onto a request for the
+// instance the namespace belongs to
+func (r *SupabaseInstanceReconciler) instanceForNamespace(_ context.Context, object client.Object) []reconcile.Request {
+	namespace := object.GetNamespace()
+	if namespace == "" {
+		namespace = object.GetName()
+	}
+	name, ok := namespaces.Project(r.NamespaceTemplate, namespace)
+	if !ok {
+		return nil
+	}
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: name}}}
+}
+
+// namespaceLabels returns the labels an instance namespace carries besides the ones the
+// provisioning Job sets: the platform's, overridden by the instance's own
+func (r *SupabaseInstanceReconciler) namespaceLabels(instance *supacontrolv1alpha1.SupabaseInstance) map[string]string {
+	labels := maps.Clone(r.NamespaceLabels)
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	maps.Copy(labels, instance.Spec.NamespaceLabels)
+	return labels
+}
+
+// ensureNamespaceMetadata applies the configured labels and annotations to the namespace of
+// a provisioned instance. Namespaces that never carried any are left alone, as are missing
+// namespaces, which drift detection reports.
+func (r *SupabaseInstanceReconciler) ensureNamespaceMetadata(ctx context.Context, instance *supacontrolv1alpha1.SupabaseInstance) error {
+	name := r.instanceNamespace(instance)
+	current := &corev1.Namespace{}
+	if err := r.Get(ctx, client.ObjectKey{Name: name}, current); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to get namespace %s: %w", name, err)
+	}
+
+	labels := r.namespaceLabels(instance)
+	if len(labels) == 0 && len(r.NamespaceAnnotations) == 0 && !managedBy(current, namespaceFieldOwner) {
+		return nil
+	}
+
+	namespace := &corev1.Namespace{
+		TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "Namespace"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Labels:      labels,
+			Annotations: maps.Clone(r.NamespaceAnnotations),
+		},
+	}
+	if err := r.Patch(ctx, namespace, client.Apply, client.FieldOwner(namespaceFieldOwner), client.ForceOwnership); err != nil {
+		return fmt.Errorf("failed to apply labels of namespace %s: %w", name, err)
+	}
+	return nil
+}
+
+// managedBy reports whether a field manager has applied fields of an object
+func managedBy(object client.Object, manager string) bool {
+	for _, entry := range object.GetManagedFields() {
+		if entry.Manager == manager {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,159 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+)
+
+// DefaultIngressControllerNamespace is where the ingress controller runs when the
+// reconciler does not name its namespace
+const DefaultIngressControllerNamespace = "ingress-nginx"
+
+// namespaceNameLabel is set by Kubernetes on every namespace to its name
+const namespaceNameLabel = "kubernetes.io/metadata.name"
+
+// StorageAPIPort is the port of the storage service of the Supabase chart, which the API
+// calls from the controller namespace to manage buckets
+const StorageAPIPort = 5000
+
+// KongPort is the port of the API gateway of the Supabase chart. Besides the instance API
+// it serves Studio behind the dashboard credentials, which is how the API proxies Studio.
+const KongPort = 8000
+
+// defaultDenyPolicyName returns the name of the NetworkPolicy that denies all traffic
+// into an isolated instance's namespace
+func defaultDenyPolicyName(projectName string) string {
+	return fmt.Sprintf("%s-default-deny", projectName)
+}
+
+// allowPolicyName returns the name of the NetworkPolicy that admits the instance's own
+// pods, the ingress controller and the API's storage calls into an isolated instance's
+// namespace
+func allowPolicyName(projectName string) string {
+	return fmt.Sprintf("%s-allow-ingress", projectName)
+}
+
+// allowMonitoringPolicyName returns the name of the NetworkPolicy that admits Prometheus
+// scrapes of the exporter of an isolated, monitored instance
+func allowMonitoringPolicyName(projectName string) string {
+	return fmt.Sprintf("%s-allow-monitoring", projectName)
+}
+
+// ingressControllerNamespace returns the configured ingress controller namespace or its default
+func (r *SupabaseInstanceReconciler) ingressControllerNamespace() string {
+	if r.IngressControllerNamespace != "" {
+		return r.IngressControllerNamespace
+	}
+	return DefaultIngressControllerNamespace
+}
+
+// ensureNetworkPolicies creates the NetworkPolicies of an instance with network isolation,
+// and removes them again once isolation is turned off. Egress is left open, so instances
+// can still reach SMTP servers, object storage and the like.
+func (r *SupabaseInstanceReconciler) ensureNetworkPolicies(ctx context.Context, instance *supacontrolv1alpha1.SupabaseInstance) error {
+	namespace := instance.Status.Namespace
+	if namespace == "" {
+		return nil
+	}
+	names := []string{defaultDenyPolicyName(instance.Spec.ProjectName), allowPolicyName(instance.Spec.ProjectName)}
+	monitoringPolicy := allowMonitoringPolicyName(instance.Spec.ProjectName)
+
+	if !instance.Spec.NetworkIsolation {
+		return r.deleteNetworkPolicies(ctx, namespace, append(names, monitoringPolicy))
+	}
+
+	ingressOnly := []networkingv1.PolicyType{networkingv1.PolicyTypeIngress}
+	specs := map[string]networkingv1.NetworkPolicySpec{
+		// Selecting every pod without allowing anything denies all ingress traffic
+		names[0]: {
+			PodSelector: metav1.LabelSelector{},
+			PolicyTypes: ingressOnly,
+		},
+		names[1]: {
+			PodSelector: metav1.LabelSelector{},
+			PolicyTypes: ingressOnly,
+			Ingress: []networkingv1.NetworkPolicyIngressRule{
+				{
+					From: []networkingv1.NetworkPolicyPeer{
+						{PodSelector: &metav1.LabelSelector{}},
+						{NamespaceSelector: &metav1.LabelSelector{
+							MatchLabels: map[string]string{namespaceNameLabel: r.ingressControllerNamespace()},
+						}},
+					},
+				},
+				// The API manages buckets through the storage service and proxies Studio
+				// through the gateway
+				{
+					From: []networkingv1.NetworkPolicyPeer{{NamespaceSelector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{namespaceNameLabel: ControllerNamespace},
+					}}},
+					Ports: []networkingv1.NetworkPolicyPort{
+						{Port: ptr.To(intstr.FromInt32(StorageAPIPort))},
+						{Port: ptr.To(intstr.FromInt32(KongPort))},
+					},
+				},
+			},
+		},
+	}
+
+	if HasMonitoring(instance) {
+		names = append(names, monitoringPolicy)
+		specs[monitoringPolicy] = networkingv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{MatchLabels: exporterSelector(instance.Spec.ProjectName)},
+			PolicyTypes: ingressOnly,
+			Ingress: []networkingv1.NetworkPolicyIngressRule{{
+				From: []networkingv1.NetworkPolicyPeer{{NamespaceSelector: &metav1.LabelSelector{
+					MatchLabels: map[string]string{namespaceNameLabel: r.monitoringNamespace()},
+				}}},
+				Ports: []networkingv1.NetworkPolicyPort{{Port: ptr.To(intstr.FromInt32(PostgresExporterPort))}},
+			}},
+		}
+	} else if err := r.deleteNetworkPolicies(ctx, namespace, []string{monitoringPolicy}); err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		policy := &networkingv1.NetworkPolicy{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace}}
+		_, err := controllerutil.CreateOrUpdate(ctx, r.Client, policy, func() error {
+			policy.Labels = map[string]string{
+				"app.kubernetes.io/managed-by": "supacontrol",
+				"supacontrol.io/instance":      instance.Spec.ProjectName,
+			}
+			policy.Spec = specs[name]
+			return controllerutil.SetControllerReference(instance, policy, r.Scheme)
+		})
+		if err != nil {
+			return fmt.Errorf("failed to apply NetworkPolicy %s: %w", name, err)
+		}
+	}
+	ctrl.LoggerFrom(ctx).V(1).Info("Applied network isolation", "namespace", namespace)
+	return nil
+}
+
+// deleteNetworkPolicies deletes the named NetworkPolicies of an instance namespace that exist
+func (r *SupabaseInstanceReconciler) deleteNetworkPolicies(ctx context.Context, namespace string, names []string) error {
+	for _, name := range names {
+		policy := &networkingv1.NetworkPolicy{}
+		if err := r.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, policy); err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return err
+		}
+		if err := r.Delete(ctx, policy); err != nil && !apierrors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete NetworkPolicy %s: %w", name, err)
+		}
+	}
+	return nil
+}
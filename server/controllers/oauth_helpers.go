@@ -0,0 +1,154 @@
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+	"github.com/qubitquilt/supacontrol/server/internal/profiles"
+)
+
+const (
+	// OAuthSecretName is the Secret in an instance's namespace that holds the client
+	// secrets of its OAuth profiles
+	OAuthSecretName = "supacontrol-oauth"
+
+	// AnnotationOAuthChecksum records a checksum of the OAuth client secrets applied to
+	// the auth service's pod template. Changing it rolls the auth pods.
+	AnnotationOAuthChecksum = "supacontrol.io/oauth-checksum"
+)
+
+// oauthEnvNames lists the auth service variables holding OAuth client secrets, one per
+// supported provider
+func oauthEnvNames() []string {
+	names := make([]string, 0, len(profiles.OAuthProviders))
+	for _, provider := range profiles.OAuthProviders {
+		names = append(names, profiles.OAuthSecretEnvName(provider))
+	}
+	return names
+}
+
+// oauthEnv returns the auth service environment that reads each client secret from
+// OAuthSecretName, keyed by the variable's name
+func oauthEnv(secrets map[string]string) []corev1.EnvVar {
+	names := make([]string, 0, len(secrets))
+	for name := range secrets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	env := make([]corev1.EnvVar, 0, len(names))
+	for _, name := range names {
+		env = append(env, corev1.EnvVar{
+			Name: name,
+			ValueFrom: &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: OAuthSecretName},
+				Key:                  name,
+			}},
+		})
+	}
+	return env
+}
+
+// oauthChecksum returns a checksum of the OAuth client secrets, so that rotating one
+// rolls the auth pods
+func oauthChecksum(secrets map[string]string) string {
+	sum := sha256.New()
+	for _, v := range oauthEnv(secrets) {
+		fmt.Fprintf(sum, "%s=%s\x00", v.Name, secrets[v.Name])
+	}
+	return hex.EncodeToString(sum.Sum(nil))[:16]
+}
+
+// setOAuthSecretValues writes OAuth client secrets into the auth service environment of
+// chart values. Only vcluster instances get them this way, as the controller cannot
+// update the Deployments inside a vcluster.
+func setOAuthSecretValues(values map[string]interface{}, secrets map[string]string) {
+	if len(secrets) == 0 {
+		return
+	}
+	auth, ok := values["auth"].(map[string]interface{})
+	if !ok {
+		auth = map[string]interface{}{}
+		values["auth"] = auth
+	}
+	env, ok := auth["environment"].(map[string]interface{})
+	if !ok {
+		env = map[string]interface{}{}
+		auth["environment"] = env
+	}
+	for name, secret := range secrets {
+		env[name] = secret
+	}
+}
+
+// reconcileOAuth gives a running instance's auth service the client secrets of its OAuth
+// profiles. They are kept out of the chart values: the controller copies them into
+// OAuthSecretName in the instance namespace and points the auth Deployment's variables
+// at it, so they never appear in the Helm release or the Deployment spec.
+func (r *SupabaseInstanceReconciler) reconcileOAuth(ctx context.Context, instance *supacontrolv1alpha1.SupabaseInstance) error {
+	if instance.Status.IsolationLevel == supacontrolv1alpha1.IsolationVCluster {
+		return nil
+	}
+	resolved, err := r.resolveProfiles(ctx, instance)
+	if err != nil {
+		return err
+	}
+	secrets := profiles.OAuthSecrets(resolved)
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      OAuthSecretName,
+			Namespace: instance.Status.Namespace,
+		},
+	}
+	if len(secrets) == 0 {
+		return r.removeOAuth(ctx, instance, secret)
+	}
+
+	_, err = controllerutil.CreateOrUpdate(ctx, r.Client, secret, func() error {
+		secret.Labels = map[string]string{
+			"app.kubernetes.io/name":       "supacontrol",
+			"app.kubernetes.io/component":  "oauth",
+			"app.kubernetes.io/managed-by": "supacontrol",
+		}
+		secret.Data = make(map[string][]byte, len(secrets))
+		for name, value := range secrets {
+			secret.Data[name] = []byte(value)
+		}
+		return controllerutil.SetControllerReference(instance, secret, r.Scheme)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to store OAuth client secrets: %w", err)
+	}
+	return r.updateAuthDeployments(ctx, instance, oauthEnvNames(), oauthEnv(secrets), AnnotationOAuthChecksum, oauthChecksum(secrets))
+}
+
+// removeOAuth removes the OAuth client secrets SupaControl applied to the auth service
+// once the instance has no OAuth profiles left
+func (r *SupabaseInstanceReconciler) removeOAuth(ctx context.Context, instance *supacontrolv1alpha1.SupabaseInstance, secret *corev1.Secret) error {
+	if err := r.Get(ctx, client.ObjectKeyFromObject(secret), secret); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to get OAuth client secrets: %w", err)
+	}
+	if err := r.updateAuthDeployments(ctx, instance, oauthEnvNames(), nil, AnnotationOAuthChecksum, ""); err != nil {
+		return err
+	}
+	if err := r.Delete(ctx, secret); err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete OAuth client secrets: %w", err)
+	}
+	ctrl.LoggerFrom(ctx).Info("Removed OAuth client secrets", "namespace", secret.Namespace)
+	return nil
+}
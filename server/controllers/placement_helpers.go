@@ -0,0 +1,182 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+)
+
+// chartComponents are the Supabase chart's per-service value sections, each of which
+// accepts nodeSelector and tolerations
+var chartComponents = []string{
+	"db", "studio", "auth", "rest", "realtime", "meta", "storage",
+	"imgproxy", "kong", "analytics", "vector", "functions", "minio",
+}
+
+// controlPlaneLabel marks control plane nodes, which are never reserved for an instance
+const controlPlaneLabel = "node-role.kubernetes.io/control-plane"
+
+// isDedicated reports whether the instance requests a node of its own
+func isDedicated(instance *supacontrolv1alpha1.SupabaseInstance) bool {
+	return instance.Spec.Placement != nil && instance.Spec.Placement.Mode == supacontrolv1alpha1.PlacementDedicated
+}
+
+// dedicatedTaint returns the taint that keeps other pods off an instance's node
+func dedicatedTaint(projectName string) corev1.Taint {
+	return corev1.Taint{
+		Key:    supacontrolv1alpha1.LabelDedicatedInstance,
+		Value:  projectName,
+		Effect: corev1.TaintEffectNoSchedule,
+	}
+}
+
+// isReservable reports whether a node can be reserved: it is ready, schedulable, not a
+// control plane node and not reserved for another instance
+func isReservable(node *corev1.Node) bool {
+	if node.Spec.Unschedulable {
+		return false
+	}
+	if _, ok := node.Labels[controlPlaneLabel]; ok {
+		return false
+	}
+	if _, ok := node.Labels[supacontrolv1alpha1.LabelDedicatedInstance]; ok {
+		return false
+	}
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == corev1.NodeReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// nodeNameField selects pods by the node they are bound to
+const nodeNameField = "spec.nodeName"
+
+// isWorkloadPod reports whether a pod would share a reserved node with the instance.
+// DaemonSet and static pods run on every node, and finished pods no longer run.
+func isWorkloadPod(pod *corev1.Pod) bool {
+	if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+		return false
+	}
+	if _, ok := pod.Annotations[corev1.MirrorPodAnnotationKey]; ok {
+		return false
+	}
+	if owner := metav1.GetControllerOf(pod); owner != nil && owner.Kind == "DaemonSet" {
+		return false
+	}
+	return true
+}
+
+// hostsWorkloads reports whether other workloads run on a node. The NoSchedule taint
+// only keeps new pods off a reserved node, so a node already running them is skipped.
+func (r *SupabaseInstanceReconciler) hostsWorkloads(ctx context.Context, nodeName string) (bool, error) {
+	pods := &corev1.PodList{}
+	if err := r.podReader().List(ctx, pods, client.MatchingFields{nodeNameField: nodeName}); err != nil {
+		return false, fmt.Errorf("failed to list pods on node %s: %w", nodeName, err)
+	}
+	for i := range pods.Items {
+		if isWorkloadPod(&pods.Items[i]) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// reserveDedicatedNode reserves a node for the instance by labelling and tainting it,
+// returning its name. An existing reservation is reused, so retries keep their node.
+// It returns an empty name when no matching node is free of other workloads.
+func (r *SupabaseInstanceReconciler) reserveDedicatedNode(ctx context.Context, instance *supacontrolv1alpha1.SupabaseInstance) (string, error) {
+	reserved := &corev1.NodeList{}
+	if err := r.List(ctx, reserved, client.MatchingLabels{supacontrolv1alpha1.LabelDedicatedInstance: instance.Spec.ProjectName}); err != nil {
+		return "", fmt.Errorf("failed to list reserved nodes: %w", err)
+	}
+	if len(reserved.Items) > 0 {
+		return reserved.Items[0].Name, nil
+	}
+
+	candidates := &corev1.NodeList{}
+	if err := r.List(ctx, candidates, client.MatchingLabels(instance.Spec.Placement.NodeSelector)); err != nil {
+		return "", fmt.Errorf("failed to list nodes: %w", err)
+	}
+	sort.Slice(candidates.Items, func(i, j int) bool { return candidates.Items[i].Name < candidates.Items[j].Name })
+
+	for i := range candidates.Items {
+		node := &candidates.Items[i]
+		if !isReservable(node) {
+			continue
+		}
+		busy, err := r.hostsWorkloads(ctx, node.Name)
+		if err != nil {
+			return "", err
+		}
+		if busy {
+			continue
+		}
+		if node.Labels == nil {
+			node.Labels = map[string]string{}
+		}
+		node.Labels[supacontrolv1alpha1.LabelDedicatedInstance] = instance.Spec.ProjectName
+		node.Spec.Taints = append(node.Spec.Taints, dedicatedTaint(instance.Spec.ProjectName))
+
+		// The update fails on a conflict if another instance reserved the node concurrently
+		if err := r.Update(ctx, node); err != nil {
+			return "", fmt.Errorf("failed to reserve node %s: %w", node.Name, err)
+		}
+		return node.Name, nil
+	}
+	return "", nil
+}
+
+// releaseDedicatedNode removes the instance's reservation label and taint from its node
+func (r *SupabaseInstanceReconciler) releaseDedicatedNode(ctx context.Context, instance *supacontrolv1alpha1.SupabaseInstance) error {
+	reserved := &corev1.NodeList{}
+	if err := r.List(ctx, reserved, client.MatchingLabels{supacontrolv1alpha1.LabelDedicatedInstance: instance.Spec.ProjectName}); err != nil {
+		return fmt.Errorf("failed to list reserved nodes: %w", err)
+	}
+	for i := range reserved.Items {
+		node := &reserved.Items[i]
+		delete(node.Labels, supacontrolv1alpha1.LabelDedicatedInstance)
+		taints := node.Spec.Taints[:0]
+		for _, taint := range node.Spec.Taints {
+			if taint.Key != supacontrolv1alpha1.LabelDedicatedInstance {
+				taints = append(taints, taint)
+			}
+		}
+		node.Spec.Taints = taints
+		if err := r.Update(ctx, node); err != nil {
+			return fmt.Errorf("failed to release node %s: %w", node.Name, err)
+		}
+	}
+	return nil
+}
+
+// setDedicatedPlacementValues pins every chart component to the instance's reserved node
+// by adding a nodeSelector for its label and a toleration for its taint
+func setDedicatedPlacementValues(values map[string]interface{}, projectName string) {
+	taint := dedicatedTaint(projectName)
+	for _, component := range chartComponents {
+		section, ok := values[component].(map[string]interface{})
+		if !ok {
+			section = map[string]interface{}{}
+			values[component] = section
+		}
+		section["nodeSelector"] = map[string]interface{}{
+			supacontrolv1alpha1.LabelDedicatedInstance: projectName,
+		}
+		section["tolerations"] = []interface{}{
+			map[string]interface{}{
+				"key":      taint.Key,
+				"operator": string(corev1.TolerationOpEqual),
+				"value":    taint.Value,
+				"effect":   string(taint.Effect),
+			},
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package controllers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+)
+
+const (
+	// PoolerImage is the PgBouncer image deployed for instances with a connection pooler
+	PoolerImage = "edoburu/pgbouncer:v1.23.1-p2"
+
+	// PoolerPort is the port the connection pooler Service listens on, matching the
+	// port Supabase uses for pooled connections
+	PoolerPort = 6543
+
+	// Pool settings used when the instance leaves them unset
+	defaultPoolSize             = 20
+	defaultMaxClientConnections = 1000
+
+	// poolerSetup deploys PgBouncer next to the instance database when the instance asks for
+	// a connection pooler. It runs after the chart is installed, so it reuses the generated
+	// database password and lands in the instance's vcluster when there is one.
+	poolerSetup = `
+if [ "${POOLER_ENABLED:-}" = "true" ]; then
+  echo "Deploying connection pooler: $INSTANCE_NAME-pooler (pool mode: $POOL_MODE)"
+  cat <<EOF | kubectl apply -f -
+apiVersion: v1
+kind: Secret
+metadata:
+  name: $INSTANCE_NAME-pooler
+  namespace: $NAMESPACE
+  labels:
+    app.kubernetes.io/managed-by: supacontrol
+    supacontrol.io/instance: $INSTANCE_NAME
+stringData:
+  db-password: "$POSTGRES_PASSWORD"
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: $INSTANCE_NAME-pooler
+  namespace: $NAMESPACE
+  labels:
+    app.kubernetes.io/name: pgbouncer
+    app.kubernetes.io/managed-by: supacontrol
+    supacontrol.io/instance: $INSTANCE_NAME
+spec:
+  replicas: 1
+  selector:
+    matchLabels:
+      app.kubernetes.io/name: pgbouncer
+      supacontrol.io/instance: $INSTANCE_NAME
+  template:
+    metadata:
+      labels:
+        app.kubernetes.io/name: pgbouncer
+        supacontrol.io/instance: $INSTANCE_NAME
+    spec:
+      containers:
+        - name: pgbouncer
+          image: $POOLER_IMAGE
+          ports:
+            - containerPort: $POOLER_PORT
+          env:
+            - name: DB_HOST
+              value: "$INSTANCE_NAME-db"
+            - name: DB_USER
+              value: postgres
+            - name: DB_PASSWORD
+              valueFrom:
+                secretKeyRef:
+                  name: $INSTANCE_NAME-pooler
+                  key: db-password
+            - name: AUTH_TYPE
+              value: scram-sha-256
+            - name: LISTEN_PORT
+              value: "$POOLER_PORT"
+            - name: POOL_MODE
+              value: "$POOL_MODE"
+            - name: DEFAULT_POOL_SIZE
+              value: "$DEFAULT_POOL_SIZE"
+            - name: MAX_CLIENT_CONN
+              value: "$MAX_CLIENT_CONN"
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: $INSTANCE_NAME-pooler
+  namespace: $NAMESPACE
+  labels:
+    app.kubernetes.io/managed-by: supacontrol
+    supacontrol.io/instance: $INSTANCE_NAME
+spec:
+  selector:
+    app.kubernetes.io/name: pgbouncer
+    supacontrol.io/instance: $INSTANCE_NAME
+  ports:
+    - name: postgres
+      port: $POOLER_PORT
+      targetPort: $POOLER_PORT
+EOF
+  kubectl rollout status deployment "$INSTANCE_NAME-pooler" --namespace "$NAMESPACE" --timeout 5m
+fi
+`
+)
+
+// PoolerServiceName returns the name of the Service exposing an instance's connection pooler
+func PoolerServiceName(projectName string) string {
+	return fmt.Sprintf("%s-pooler", projectName)
+}
+
+// HasConnectionPooler reports whether the instance requests a connection pooler
+func HasConnectionPooler(instance *supacontrolv1alpha1.SupabaseInstance) bool {
+	return instance.Spec.ConnectionPooler != nil && instance.Spec.ConnectionPooler.Enabled
+}
+
+// poolerEnv returns the settings the provisioning Job needs to deploy the instance's
+// connection pooler; it is empty when the instance has none
+func poolerEnv(instance *supacontrolv1alpha1.SupabaseInstance) []corev1.EnvVar {
+	if !HasConnectionPooler(instance) {
+		return nil
+	}
+	pooler := instance.Spec.ConnectionPooler
+	mode := pooler.PoolMode
+	if mode == "" {
+		mode = supacontrolv1alpha1.PoolModeTransaction
+	}
+	poolSize := pooler.DefaultPoolSize
+	if poolSize == 0 {
+		poolSize = defaultPoolSize
+	}
+	maxClients := pooler.MaxClientConnections
+	if maxClients == 0 {
+		maxClients = defaultMaxClientConnections
+	}
+	return []corev1.EnvVar{
+		{Name: "POOLER_ENABLED", Value: "true"},
+		{Name: "POOLER_IMAGE", Value: PoolerImage},
+		{Name: "POOLER_PORT", Value: strconv.Itoa(PoolerPort)},
+		{Name: "POOL_MODE", Value: strings.ToLower(string(mode))},
+		{Name: "DEFAULT_POOL_SIZE", Value: strconv.Itoa(int(poolSize))},
+		{Name: "MAX_CLIENT_CONN", Value: strconv.Itoa(int(maxClients))},
+	}
+}
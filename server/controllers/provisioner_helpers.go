@@ -0,0 +1,193 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	_ "embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+)
+
+const (
+	// ProvisionerScriptsConfigMap is the ConfigMap in the controller namespace holding the
+	// rendered scripts provisioning Jobs run
+	ProvisionerScriptsConfigMap = "supacontrol-provisioner-scripts"
+
+	// ProvisionScriptKey is the ConfigMap key holding the provisioning script
+	ProvisionScriptKey = "provision.sh"
+
+	// DatabaseUpgradeScriptKey is the ConfigMap key holding the script that runs the steps
+	// of Postgres major upgrades
+	DatabaseUpgradeScriptKey = "database-upgrade.sh"
+
+	// provisionerScriptsMountPath is where Jobs mount the provisioner scripts
+	provisionerScriptsMountPath = "/etc/supacontrol-scripts"
+
+	// ScriptChecksumAnnotation records on a Job the checksum of the script it was created for
+	ScriptChecksumAnnotation = "supacontrol.io/script-checksum"
+)
+
+var (
+	// ErrInvalidImage is returned for a malformed container image reference
+	ErrInvalidImage = errors.New("invalid container image reference")
+
+	// ErrImageNotPinned is returned for an image without a digest when digests are required
+	ErrImageNotPinned = errors.New("container image must be pinned by digest")
+)
+
+//go:embed scripts/provision.sh.tmpl
+var provisionScriptSource string
+
+//go:embed scripts/database-upgrade.sh.tmpl
+var databaseUpgradeScriptSource string
+
+// The script templates are parsed once; a broken template is a build defect
+var (
+	provisionScriptTemplate       = template.Must(template.New(ProvisionScriptKey).Parse(provisionScriptSource))
+	databaseUpgradeScriptTemplate = template.Must(template.New(DatabaseUpgradeScriptKey).Parse(databaseUpgradeScriptSource))
+)
+
+// imageReferencePattern matches [registry[:port]/]repository[:tag][@sha256:digest]
+var imageReferencePattern = regexp.MustCompile(
+	`^(?:[a-zA-Z0-9][a-zA-Z0-9.-]*(?::[0-9]+)?/)?` +
+		`[a-z0-9]+(?:[._-]+[a-z0-9]+)*(?:/[a-z0-9]+(?:[._-]+[a-z0-9]+)*)*` +
+		`(?::[a-zA-Z0-9_][a-zA-Z0-9_.-]{0,127})?` +
+		`(?:@sha256:[a-f0-9]{64})?$`)
+
+// ValidateImage checks a container image reference. With requireDigest the image must be
+// pinned by a sha256 digest, so a mutable tag in a registry cannot change what Jobs run.
+func ValidateImage(image string, requireDigest bool) error {
+	if len(image) > 512 || !imageReferencePattern.MatchString(image) {
+		return fmt.Errorf("%w: %q", ErrInvalidImage, image)
+	}
+	if requireDigest && !strings.Contains(image, "@sha256:") {
+		return fmt.Errorf("%w: %q", ErrImageNotPinned, image)
+	}
+	return nil
+}
+
+// provisionerImageFor returns the image that runs an instance's Jobs: the instance's own
+// override for internal registries, the controller's configured image, or the default
+func (r *SupabaseInstanceReconciler) provisionerImageFor(instance *supacontrolv1alpha1.SupabaseInstance) (string, error) {
+	image := instance.Spec.ProvisionerImage
+	if image == "" {
+		image = r.ProvisionerImage
+	}
+	if image == "" {
+		return ProvisionerImage, nil
+	}
+	// The API validates the image too, but instances may be created without it
+	if err := ValidateImage(image, r.RequireImageDigest); err != nil {
+		return "", err
+	}
+	return image, nil
+}
+
+// RenderProvisionScript renders the provisioning script from its template, including the
+// optional CA bundle, chart repository credentials, vcluster and connection pooler steps
+func RenderProvisionScript() (string, error) {
+	var script bytes.Buffer
+	err := provisionScriptTemplate.Execute(&script, struct {
+		CABundleSetup  string
+		ChartRepoSetup string
+		VClusterSetup  string
+		PoolerSetup    string
+	}{
+		CABundleSetup:  caBundleSetup,
+		ChartRepoSetup: chartRepoSetup,
+		VClusterSetup:  vclusterSetup,
+		PoolerSetup:    poolerSetup,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to render provisioning script: %w", err)
+	}
+	return script.String(), nil
+}
+
+// RenderDatabaseUpgradeScript renders the script of Postgres major upgrade Jobs, which
+// runs the step named by its STEP environment variable
+func RenderDatabaseUpgradeScript() (string, error) {
+	var script bytes.Buffer
+	err := databaseUpgradeScriptTemplate.Execute(&script, struct {
+		CABundleSetup  string
+		ChartRepoSetup string
+		VClusterSetup  string
+	}{
+		CABundleSetup:  caBundleSetup,
+		ChartRepoSetup: chartRepoSetup,
+		VClusterSetup:  vclusterSetup,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to render database upgrade script: %w", err)
+	}
+	return script.String(), nil
+}
+
+// ensureProvisionerScripts stores the rendered provisioner scripts in a ConfigMap that
+// provisioning and database upgrade Jobs mount, and returns a checksum of the provisioning
+// script. The ConfigMap is shared by all instances, as the scripts take everything
+// instance specific from their environment.
+func (r *SupabaseInstanceReconciler) ensureProvisionerScripts(ctx context.Context) (string, error) {
+	script, err := RenderProvisionScript()
+	if err != nil {
+		return "", err
+	}
+	upgradeScript, err := RenderDatabaseUpgradeScript()
+	if err != nil {
+		return "", err
+	}
+
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      ProvisionerScriptsConfigMap,
+			Namespace: ControllerNamespace,
+		},
+	}
+	_, err = controllerutil.CreateOrUpdate(ctx, r.Client, configMap, func() error {
+		configMap.Labels = map[string]string{
+			"app.kubernetes.io/name":      "supacontrol",
+			"app.kubernetes.io/component": "provisioner",
+		}
+		configMap.Data = map[string]string{
+			ProvisionScriptKey:       script,
+			DatabaseUpgradeScriptKey: upgradeScript,
+		}
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to store provisioner scripts: %w", err)
+	}
+
+	sum := sha256.Sum256([]byte(script))
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// provisionerScriptsVolume returns the volume and mount exposing the provisioner scripts
+// to a Job
+func provisionerScriptsVolume() (corev1.Volume, corev1.VolumeMount) {
+	volume := corev1.Volume{
+		Name: "provisioner-scripts",
+		VolumeSource: corev1.VolumeSource{
+			ConfigMap: &corev1.ConfigMapVolumeSource{
+				LocalObjectReference: corev1.LocalObjectReference{Name: ProvisionerScriptsConfigMap},
+			},
+		},
+	}
+	mount := corev1.VolumeMount{
+		Name:      "provisioner-scripts",
+		MountPath: provisionerScriptsMountPath,
+		ReadOnly:  true,
+	}
+	return volume, mount
+}
@@ -0,0 +1,154 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	batchv1 "k8s.io/api/batch/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+	"github.com/qubitquilt/supacontrol/server/internal/metrics"
+)
+
+// jobReader returns the reader provisioning Jobs are counted with. Jobs are read from
+// the API server when possible, so a Job created a moment ago is counted even before
+// the cache has seen it.
+func (r *SupabaseInstanceReconciler) jobReader() client.Reader {
+	if r.APIReader != nil {
+		return r.APIReader
+	}
+	return r.Client
+}
+
+// activeProvisioningJobs counts the provisioning Jobs that have not finished yet
+func (r *SupabaseInstanceReconciler) activeProvisioningJobs(ctx context.Context) (int, error) {
+	var jobs batchv1.JobList
+	if err := r.jobReader().List(ctx, &jobs, client.InNamespace(ControllerNamespace),
+		client.MatchingLabels{JobOperationLabel: OperationProvision}); err != nil {
+		return 0, fmt.Errorf("failed to list provisioning Jobs: %w", err)
+	}
+	active := 0
+	for i := range jobs.Items {
+		if !isJobSucceeded(&jobs.Items[i]) && !isJobFailed(&jobs.Items[i]) {
+			active++
+		}
+	}
+	return active, nil
+}
+
+// isWaitingInQueue reports whether an instance holds a place in the provisioning queue.
+// Paused, suspended, trashed and deleted instances keep their phase but give up their
+// place until they are resumed.
+func isWaitingInQueue(instance *supacontrolv1alpha1.SupabaseInstance) bool {
+	return instance.Status.Phase == supacontrolv1alpha1.PhaseQueued &&
+		instance.DeletionTimestamp.IsZero() &&
+		!instance.Spec.Paused &&
+		instance.Spec.Suspension == nil &&
+		instance.Spec.PendingDeletion == nil
+}
+
+// sortQueue orders queued instances first in, first out, by when they were queued and
+// then by creation time and name
+func sortQueue(queue []supacontrolv1alpha1.SupabaseInstance) {
+	sort.SliceStable(queue, func(i, j int) bool {
+		a, b := queue[i].Status.QueuedAt, queue[j].Status.QueuedAt
+		switch {
+		case a == nil && b != nil:
+			return false
+		case a != nil && b == nil:
+			return true
+		case a != nil && b != nil && !a.Equal(b):
+			return a.Before(b)
+		}
+		if !queue[i].CreationTimestamp.Equal(&queue[j].CreationTimestamp) {
+			return queue[i].CreationTimestamp.Before(&queue[j].CreationTimestamp)
+		}
+		return queue[i].Name < queue[j].Name
+	})
+}
+
+// queuePosition returns how many queued instances are ahead of an instance. Instances
+// that are not queued yet go to the back of the queue.
+func (r *SupabaseInstanceReconciler) queuePosition(ctx context.Context, instance *supacontrolv1alpha1.SupabaseInstance) (int, error) {
+	var instances supacontrolv1alpha1.SupabaseInstanceList
+	if err := r.List(ctx, &instances); err != nil {
+		return 0, fmt.Errorf("failed to list instances: %w", err)
+	}
+	queue := make([]supacontrolv1alpha1.SupabaseInstance, 0, len(instances.Items))
+	for _, item := range instances.Items {
+		if isWaitingInQueue(&item) {
+			queue = append(queue, item)
+		}
+	}
+	sortQueue(queue)
+	for i := range queue {
+		if queue[i].UID == instance.UID {
+			return i, nil
+		}
+	}
+	return len(queue), nil
+}
+
+// admitProvisioning reports whether an instance may start its provisioning Job now. With
+// MaxConcurrentProvisioningJobs set, an instance is admitted only while fewer Jobs are
+// running than the limit allows, counting the queued instances ahead of it. It returns
+// the number of running Jobs and of instances ahead for instances that must wait.
+func (r *SupabaseInstanceReconciler) admitProvisioning(ctx context.Context, instance *supacontrolv1alpha1.SupabaseInstance) (admitted bool, active, ahead int, err error) {
+	if r.MaxConcurrentProvisioningJobs <= 0 {
+		return true, 0, 0, nil
+	}
+	active, err = r.activeProvisioningJobs(ctx)
+	if err != nil {
+		return false, 0, 0, err
+	}
+	ahead, err = r.queuePosition(ctx, instance)
+	if err != nil {
+		return false, 0, 0, err
+	}
+	return active+ahead < r.MaxConcurrentProvisioningJobs, active, ahead, nil
+}
+
+// queueProvisioning moves an instance to the Queued phase until a provisioning Job slot
+// frees up, keeping its place if it was already queued
+func (r *SupabaseInstanceReconciler) queueProvisioning(ctx context.Context, instance *supacontrolv1alpha1.SupabaseInstance, active, ahead int) (ctrl.Result, error) {
+	changed := false
+	if instance.Status.Phase != supacontrolv1alpha1.PhaseQueued {
+		ctrl.LoggerFrom(ctx).Info("Queueing provisioning", "projectName", instance.Spec.ProjectName,
+			"activeJobs", active, "ahead", ahead)
+		instance.Status.Phase = supacontrolv1alpha1.PhaseQueued
+		now := metav1.Now()
+		instance.Status.LastTransitionTime = &now
+		changed = true
+	}
+	if instance.Status.QueuedAt == nil {
+		now := metav1.Now()
+		instance.Status.QueuedAt = &now
+		changed = true
+	}
+
+	if meta.SetStatusCondition(&instance.Status.Conditions, metav1.Condition{
+		Type:               supacontrolv1alpha1.ConditionTypeReady,
+		Status:             metav1.ConditionFalse,
+		ObservedGeneration: instance.Generation,
+		Reason:             "Queued",
+		Message: fmt.Sprintf("Waiting for a provisioning slot: %d of %d provisioning Jobs running, %d instance(s) ahead",
+			active, r.MaxConcurrentProvisioningJobs, ahead),
+	}) {
+		changed = true
+	}
+
+	if changed {
+		if err := r.updateStatus(ctx, instance); err != nil {
+			return ctrl.Result{}, err
+		}
+		metrics.SetInstanceStatus(instance.Spec.ProjectName, string(supacontrolv1alpha1.PhaseQueued), supacontrolv1alpha1.AllPhases())
+	}
+
+	// Check for a free slot as often as running Jobs are checked for completion
+	return ctrl.Result{RequeueAfter: r.jobPollInterval()}, nil
+}
@@ -0,0 +1,241 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+)
+
+const (
+	// ReadReplicaImage is the Postgres image read replicas run, matching the database image
+	// of the Supabase chart so replicas can stream from the primary
+	ReadReplicaImage = "supabase/postgres:15.1.0.147"
+
+	// defaultReplicaVolumeSize sizes the volume of each read replica when the instance
+	// leaves its storage size unset, matching the chart's default database volume
+	defaultReplicaVolumeSize = "8Gi"
+
+	// replicaBootstrapScript clones the primary into an empty data directory and configures
+	// it as a hot standby. The primary is first allowed to accept replication connections,
+	// which the Supabase image does not by default.
+	replicaBootstrapScript = `set -e
+if [ -s "$PGDATA/PG_VERSION" ]; then
+  exit 0
+fi
+if ! psql -h "$PRIMARY_HOST" -U postgres -d postgres -tAc "SELECT 1 FROM pg_hba_file_rules WHERE 'replication' = ANY(database)" | grep -q 1; then
+  echo "Allowing replication connections on $PRIMARY_HOST"
+  psql -h "$PRIMARY_HOST" -U postgres -d postgres -v ON_ERROR_STOP=1 <<'EOF'
+CREATE TEMP TABLE hba (line text);
+SELECT format('COPY hba FROM %L', current_setting('hba_file')) \gexec
+INSERT INTO hba VALUES ('host replication postgres 0.0.0.0/0 scram-sha-256');
+SELECT format('COPY hba TO %L', current_setting('hba_file')) \gexec
+SELECT pg_reload_conf();
+EOF
+fi
+echo "Cloning $PRIMARY_HOST into $PGDATA"
+pg_basebackup -h "$PRIMARY_HOST" -U postgres -D "$PGDATA" -X stream -R
+`
+)
+
+// ReadReplicaServiceName returns the name of the read-only Service in front of an
+// instance's read replicas
+func ReadReplicaServiceName(projectName string) string {
+	return fmt.Sprintf("%s-db-read", projectName)
+}
+
+// readReplicaName returns the name of the StatefulSet running an instance's read replicas
+func readReplicaName(projectName string) string {
+	return fmt.Sprintf("%s-db-replica", projectName)
+}
+
+// ReadReplicaCount returns the number of read replicas the instance requests
+func ReadReplicaCount(instance *supacontrolv1alpha1.SupabaseInstance) int32 {
+	if instance.Spec.Database == nil {
+		return 0
+	}
+	return instance.Spec.Database.ReadReplicas
+}
+
+// reconcileReadReplicas runs the read replicas of a running instance's database as a
+// StatefulSet behind a read-only Service, and removes both once no replicas are requested.
+// Replica readiness is recorded in the ReadReplicasReady condition.
+func (r *SupabaseInstanceReconciler) reconcileReadReplicas(ctx context.Context, instance *supacontrolv1alpha1.SupabaseInstance) error {
+	replicas := ReadReplicaCount(instance)
+	existing := meta.FindStatusCondition(instance.Status.Conditions, supacontrolv1alpha1.ConditionTypeReadReplicasReady)
+	if replicas == 0 && existing == nil {
+		return nil
+	}
+	namespace := instance.Status.Namespace
+	if namespace == "" {
+		return nil
+	}
+	projectName := instance.Spec.ProjectName
+
+	if replicas == 0 {
+		objects := []client.Object{
+			&appsv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{Name: readReplicaName(projectName), Namespace: namespace}},
+			&corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: ReadReplicaServiceName(projectName), Namespace: namespace}},
+		}
+		for _, object := range objects {
+			if err := r.Delete(ctx, object, client.PropagationPolicy(metav1.DeletePropagationBackground)); err != nil && !apierrors.IsNotFound(err) {
+				return fmt.Errorf("failed to delete read replica %T %s: %w", object, object.GetName(), err)
+			}
+		}
+		instance.Status.ReadyReadReplicas = 0
+		meta.RemoveStatusCondition(&instance.Status.Conditions, supacontrolv1alpha1.ConditionTypeReadReplicasReady)
+		return r.updateStatus(ctx, instance)
+	}
+
+	condition := metav1.Condition{
+		Type:               supacontrolv1alpha1.ConditionTypeReadReplicasReady,
+		ObservedGeneration: instance.Generation,
+	}
+	var ready int32
+	if instance.Status.IsolationLevel == supacontrolv1alpha1.IsolationVCluster {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "IsolationUnsupported"
+		condition.Message = "Read replicas cannot be run for vcluster instances"
+	} else {
+		statefulSet, err := r.applyReadReplicas(ctx, instance, replicas)
+		if err != nil {
+			return err
+		}
+		ready = statefulSet.Status.ReadyReplicas
+		if ready >= replicas {
+			condition.Status = metav1.ConditionTrue
+			condition.Reason = "ReplicasReady"
+		} else {
+			condition.Status = metav1.ConditionFalse
+			condition.Reason = "ReplicasNotReady"
+		}
+		condition.Message = fmt.Sprintf("%d of %d read replicas ready", min(ready, replicas), replicas)
+	}
+
+	if existing != nil && existing.Status == condition.Status && existing.Reason == condition.Reason &&
+		existing.Message == condition.Message && existing.ObservedGeneration == condition.ObservedGeneration &&
+		instance.Status.ReadyReadReplicas == ready {
+		return nil
+	}
+	instance.Status.ReadyReadReplicas = ready
+	meta.SetStatusCondition(&instance.Status.Conditions, condition)
+	return r.updateStatus(ctx, instance)
+}
+
+// applyReadReplicas applies the read replica StatefulSet and its Service. Each replica
+// clones the primary on first start and then follows it through streaming replication.
+func (r *SupabaseInstanceReconciler) applyReadReplicas(ctx context.Context, instance *supacontrolv1alpha1.SupabaseInstance, replicas int32) (*appsv1.StatefulSet, error) {
+	params := r.addonParams(instance)
+	name := readReplicaName(instance.Spec.ProjectName)
+	selector := map[string]string{
+		"app.kubernetes.io/name":  "supabase-db-replica",
+		"supacontrol.io/instance": instance.Spec.ProjectName,
+	}
+	labels := map[string]string{"app.kubernetes.io/managed-by": "supacontrol"}
+	for key, value := range selector {
+		labels[key] = value
+	}
+
+	size := resource.MustParse(defaultReplicaVolumeSize)
+	var storageClass *string
+	if storage := instance.Spec.Storage; storage != nil {
+		if storage.Size != nil {
+			size = *storage.Size
+		}
+		if storage.ClassName != "" {
+			storageClass = ptr.To(storage.ClassName)
+		}
+	}
+	env := []corev1.EnvVar{
+		{Name: "PRIMARY_HOST", Value: params.DatabaseHost},
+		{Name: "PGDATA", Value: "/var/lib/postgresql/data/pgdata"},
+		{Name: "PGPASSWORD", ValueFrom: &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{
+			LocalObjectReference: corev1.LocalObjectReference{Name: params.DatabaseSecret},
+			Key:                  "postgres-password",
+		}}},
+	}
+	mounts := []corev1.VolumeMount{{Name: "data", MountPath: "/var/lib/postgresql/data"}}
+
+	statefulSet := &appsv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: params.Namespace}}
+	_, err := controllerutil.CreateOrUpdate(ctx, r.Client, statefulSet, func() error {
+		statefulSet.Labels = labels
+		statefulSet.Spec.Replicas = ptr.To(replicas)
+		// The selector, service name and claim templates are immutable, so they are only
+		// set when the StatefulSet is created
+		if statefulSet.CreationTimestamp.IsZero() {
+			statefulSet.Spec.Selector = &metav1.LabelSelector{MatchLabels: selector}
+			statefulSet.Spec.ServiceName = ReadReplicaServiceName(instance.Spec.ProjectName)
+			statefulSet.Spec.PersistentVolumeClaimRetentionPolicy = &appsv1.StatefulSetPersistentVolumeClaimRetentionPolicy{
+				WhenDeleted: appsv1.DeletePersistentVolumeClaimRetentionPolicyType,
+				WhenScaled:  appsv1.DeletePersistentVolumeClaimRetentionPolicyType,
+			}
+			statefulSet.Spec.VolumeClaimTemplates = []corev1.PersistentVolumeClaim{{
+				ObjectMeta: metav1.ObjectMeta{Name: "data"},
+				Spec: corev1.PersistentVolumeClaimSpec{
+					AccessModes:      []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+					StorageClassName: storageClass,
+					Resources: corev1.VolumeResourceRequirements{
+						Requests: corev1.ResourceList{corev1.ResourceStorage: size},
+					},
+				},
+			}}
+		}
+		statefulSet.Spec.Template = corev1.PodTemplateSpec{
+			ObjectMeta: metav1.ObjectMeta{Labels: labels},
+			Spec: corev1.PodSpec{
+				InitContainers: []corev1.Container{{
+					Name:         "clone",
+					Image:        ReadReplicaImage,
+					Command:      []string{"/bin/sh", "-c", replicaBootstrapScript},
+					Env:          env,
+					VolumeMounts: mounts,
+				}},
+				Containers: []corev1.Container{{
+					Name:         "postgres",
+					Image:        ReadReplicaImage,
+					Args:         []string{"postgres", "-c", "hot_standby=on"},
+					Env:          env,
+					Ports:        []corev1.ContainerPort{{Name: "postgres", ContainerPort: 5432}},
+					VolumeMounts: mounts,
+					ReadinessProbe: &corev1.Probe{
+						ProbeHandler: corev1.ProbeHandler{Exec: &corev1.ExecAction{
+							Command: []string{"pg_isready", "-U", "postgres", "-h", "127.0.0.1"},
+						}},
+						PeriodSeconds: 10,
+					},
+				}},
+			},
+		}
+		return controllerutil.SetControllerReference(instance, statefulSet, r.Scheme)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to apply read replica StatefulSet: %w", err)
+	}
+
+	service := &corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: ReadReplicaServiceName(instance.Spec.ProjectName), Namespace: params.Namespace}}
+	_, err = controllerutil.CreateOrUpdate(ctx, r.Client, service, func() error {
+		service.Labels = labels
+		service.Spec.Selector = selector
+		service.Spec.Ports = []corev1.ServicePort{{
+			Name:       "postgres",
+			Port:       5432,
+			TargetPort: intstr.FromString("postgres"),
+		}}
+		return controllerutil.SetControllerReference(instance, service, r.Scheme)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to apply read replica Service: %w", err)
+	}
+	return statefulSet, nil
+}
@@ -0,0 +1,119 @@
+package controllers
+
+import (
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+)
+
+const (
+	// resizeCheckpoint flushes dirty buffers to disk before a resize restarts Postgres, so
+	// the restarted database replays as little WAL as possible. It runs in the instance's
+	// vcluster when there is one.
+	resizeCheckpoint = `
+if [ "${RESIZE:-}" = "true" ]; then
+  echo "Checkpointing Postgres before the restart"
+  DB_POD=$(kubectl get pods --namespace "$NAMESPACE" -l "$DB_SELECTOR" -o jsonpath='{.items[0].metadata.name}')
+  kubectl exec --namespace "$NAMESPACE" "$DB_POD" -- psql -U postgres -c CHECKPOINT
+fi
+`
+
+	// resizeVerify waits for the restarted database and checks that it accepts connections
+	resizeVerify = `
+if [ "${RESIZE:-}" = "true" ]; then
+  echo "Verifying the restarted database"
+  kubectl wait pods --namespace "$NAMESPACE" -l "$DB_SELECTOR" --for condition=Ready --timeout 5m
+  DB_POD=$(kubectl get pods --namespace "$NAMESPACE" -l "$DB_SELECTOR" -o jsonpath='{.items[0].metadata.name}')
+  kubectl exec --namespace "$NAMESPACE" "$DB_POD" -- pg_isready -U postgres
+fi
+`
+)
+
+// tierResources are the CPU and memory of each resource tier
+var tierResources = map[supacontrolv1alpha1.ResourceTier][2]string{
+	supacontrolv1alpha1.ResourceTierSmall:  {"500m", "1Gi"},
+	supacontrolv1alpha1.ResourceTierMedium: {"1", "2Gi"},
+	supacontrolv1alpha1.ResourceTierLarge:  {"2", "4Gi"},
+	supacontrolv1alpha1.ResourceTierXLarge: {"4", "8Gi"},
+}
+
+// databaseResources returns the CPU and memory requested for the Postgres container:
+// those of the tier, overridden by explicit quantities. Unset values are omitted.
+func databaseResources(resources *supacontrolv1alpha1.Resources) corev1.ResourceList {
+	list := corev1.ResourceList{}
+	if resources == nil {
+		return list
+	}
+	if tier, ok := tierResources[resources.Tier]; ok {
+		list[corev1.ResourceCPU] = resource.MustParse(tier[0])
+		list[corev1.ResourceMemory] = resource.MustParse(tier[1])
+	}
+	if resources.CPU != nil {
+		list[corev1.ResourceCPU] = *resources.CPU
+	}
+	if resources.Memory != nil {
+		list[corev1.ResourceMemory] = *resources.Memory
+	}
+	return list
+}
+
+// setResourceValues sets the requests and limits of the chart's Postgres container. Both
+// are equal, so a resized database gets exactly what it asked for.
+func setResourceValues(values map[string]interface{}, resources *supacontrolv1alpha1.Resources) {
+	list := databaseResources(resources)
+	if len(list) == 0 {
+		return
+	}
+	db, ok := values["db"].(map[string]interface{})
+	if !ok {
+		db = map[string]interface{}{}
+		values["db"] = db
+	}
+	quantities := map[string]interface{}{}
+	for name, quantity := range list {
+		quantities[string(name)] = quantity.String()
+	}
+	db["resources"] = map[string]interface{}{"requests": quantities, "limits": quantities}
+}
+
+// needsResize reports whether the spec was changed to database resources other than the
+// ones applied
+func needsResize(instance *supacontrolv1alpha1.SupabaseInstance) bool {
+	return instance.Generation != instance.Status.ObservedGeneration &&
+		!equality.Semantic.DeepEqual(databaseResources(instance.Spec.Resources), databaseResources(instance.Status.Resources))
+}
+
+// describeResources renders database resources for condition messages, e.g. "tier large (cpu 2, memory 4Gi)"
+func describeResources(resources *supacontrolv1alpha1.Resources) string {
+	list := databaseResources(resources)
+	if len(list) == 0 {
+		return "the chart defaults"
+	}
+	var parts []string
+	for _, name := range []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory} {
+		if quantity, ok := list[name]; ok {
+			parts = append(parts, fmt.Sprintf("%s %s", name, quantity.String()))
+		}
+	}
+	if resources.Tier != "" {
+		return fmt.Sprintf("tier %s (%s)", resources.Tier, strings.Join(parts, ", "))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// resizeEnv returns the settings the upgrade Job needs to checkpoint and verify the
+// instance database around a resize; it is empty when the resources are unchanged
+func resizeEnv(instance *supacontrolv1alpha1.SupabaseInstance) []corev1.EnvVar {
+	if !needsResize(instance) {
+		return nil
+	}
+	return []corev1.EnvVar{
+		{Name: "RESIZE", Value: "true"},
+		{Name: "DB_SELECTOR", Value: fmt.Sprintf("app.kubernetes.io/name=supabase-db,app.kubernetes.io/instance=%s", supabaseReleaseName(instance))},
+	}
+}
@@ -0,0 +1,184 @@
+// Package controllers provides workload scaling functionality for paused SupaControl instances.
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+)
+
+const (
+	// AnnotationPausedReplicas records a workload's replica count before it was scaled to zero,
+	// so that resuming the instance restores the original size
+	AnnotationPausedReplicas = "supacontrol.io/paused-replicas"
+)
+
+// scaleDownInstance scales an instance's workloads to zero replicas and returns the number
+// of workloads that were changed. The chart of a vcluster instance runs inside the
+// vcluster, whose workloads are scaled instead; the vcluster itself keeps running so they
+// can be scaled back up through it.
+func (r *SupabaseInstanceReconciler) scaleDownInstance(ctx context.Context, instance *supacontrolv1alpha1.SupabaseInstance) (int, error) {
+	namespace := instance.Status.Namespace
+	if instance.Status.IsolationLevel != supacontrolv1alpha1.IsolationVCluster {
+		return r.scaleDownWorkloads(ctx, r.Client, namespace, "")
+	}
+
+	vcluster, err := r.vclusterClient(ctx, instance)
+	if err != nil {
+		return 0, err
+	}
+	inner, err := r.scaleDownWorkloads(ctx, vcluster, namespace, "")
+	if err != nil {
+		return inner, err
+	}
+	host, err := r.scaleDownWorkloads(ctx, r.Client, namespace, vclusterRelease(instance))
+	return inner + host, err
+}
+
+// scaleUpInstance restores the workloads scaleDownInstance scaled to zero and returns the
+// number of workloads that were changed
+func (r *SupabaseInstanceReconciler) scaleUpInstance(ctx context.Context, instance *supacontrolv1alpha1.SupabaseInstance) (int, error) {
+	namespace := instance.Status.Namespace
+	host, err := r.scaleUpWorkloads(ctx, r.Client, namespace)
+	if err != nil || instance.Status.IsolationLevel != supacontrolv1alpha1.IsolationVCluster {
+		return host, err
+	}
+
+	vcluster, err := r.vclusterClient(ctx, instance)
+	if err != nil {
+		return host, err
+	}
+	inner, err := r.scaleUpWorkloads(ctx, vcluster, namespace)
+	return host + inner, err
+}
+
+// scaleDownWorkloads scales every Deployment and StatefulSet in the namespace except the
+// one named keep to zero replicas, remembering the previous replica count in an annotation.
+// Returns the number of workloads that were changed.
+func (r *SupabaseInstanceReconciler) scaleDownWorkloads(ctx context.Context, c client.Client, namespace, keep string) (int, error) {
+	logger := ctrl.LoggerFrom(ctx)
+	changed := 0
+
+	var deployments appsv1.DeploymentList
+	if err := c.List(ctx, &deployments, client.InNamespace(namespace)); err != nil {
+		return changed, fmt.Errorf("failed to list deployments: %w", err)
+	}
+	for i := range deployments.Items {
+		d := &deployments.Items[i]
+		if d.Name == keep || !recordPausedReplicas(&d.ObjectMeta.Annotations, d.Spec.Replicas) {
+			continue
+		}
+		d.Spec.Replicas = ptr.To[int32](0)
+		if err := c.Update(ctx, d); err != nil {
+			return changed, fmt.Errorf("failed to scale down deployment %s: %w", d.Name, err)
+		}
+		logger.Info("Scaled down deployment", "namespace", namespace, "deployment", d.Name)
+		changed++
+	}
+
+	var statefulSets appsv1.StatefulSetList
+	if err := c.List(ctx, &statefulSets, client.InNamespace(namespace)); err != nil {
+		return changed, fmt.Errorf("failed to list statefulsets: %w", err)
+	}
+	for i := range statefulSets.Items {
+		s := &statefulSets.Items[i]
+		if s.Name == keep || !recordPausedReplicas(&s.ObjectMeta.Annotations, s.Spec.Replicas) {
+			continue
+		}
+		s.Spec.Replicas = ptr.To[int32](0)
+		if err := c.Update(ctx, s); err != nil {
+			return changed, fmt.Errorf("failed to scale down statefulset %s: %w", s.Name, err)
+		}
+		logger.Info("Scaled down statefulset", "namespace", namespace, "statefulset", s.Name)
+		changed++
+	}
+
+	return changed, nil
+}
+
+// scaleUpWorkloads restores every Deployment and StatefulSet in the namespace that was
+// scaled down by scaleDownWorkloads to its recorded replica count.
+// Returns the number of workloads that were changed.
+func (r *SupabaseInstanceReconciler) scaleUpWorkloads(ctx context.Context, c client.Client, namespace string) (int, error) {
+	logger := ctrl.LoggerFrom(ctx)
+	changed := 0
+
+	var deployments appsv1.DeploymentList
+	if err := c.List(ctx, &deployments, client.InNamespace(namespace)); err != nil {
+		return changed, fmt.Errorf("failed to list deployments: %w", err)
+	}
+	for i := range deployments.Items {
+		d := &deployments.Items[i]
+		replicas, ok := popPausedReplicas(d.ObjectMeta.Annotations)
+		if !ok {
+			continue
+		}
+		d.Spec.Replicas = ptr.To(replicas)
+		if err := c.Update(ctx, d); err != nil {
+			return changed, fmt.Errorf("failed to scale up deployment %s: %w", d.Name, err)
+		}
+		logger.Info("Scaled up deployment", "namespace", namespace, "deployment", d.Name, "replicas", replicas)
+		changed++
+	}
+
+	var statefulSets appsv1.StatefulSetList
+	if err := c.List(ctx, &statefulSets, client.InNamespace(namespace)); err != nil {
+		return changed, fmt.Errorf("failed to list statefulsets: %w", err)
+	}
+	for i := range statefulSets.Items {
+		s := &statefulSets.Items[i]
+		replicas, ok := popPausedReplicas(s.ObjectMeta.Annotations)
+		if !ok {
+			continue
+		}
+		s.Spec.Replicas = ptr.To(replicas)
+		if err := c.Update(ctx, s); err != nil {
+			return changed, fmt.Errorf("failed to scale up statefulset %s: %w", s.Name, err)
+		}
+		logger.Info("Scaled up statefulset", "namespace", namespace, "statefulset", s.Name, "replicas", replicas)
+		changed++
+	}
+
+	return changed, nil
+}
+
+// recordPausedReplicas stores the current replica count in the paused-replicas annotation.
+// Returns false if the workload is already scaled to zero and needs no change.
+func recordPausedReplicas(annotations *map[string]string, replicas *int32) bool {
+	current := int32(1)
+	if replicas != nil {
+		current = *replicas
+	}
+	if current == 0 {
+		return false
+	}
+
+	if *annotations == nil {
+		*annotations = map[string]string{}
+	}
+	(*annotations)[AnnotationPausedReplicas] = strconv.Itoa(int(current))
+	return true
+}
+
+// popPausedReplicas reads and removes the paused-replicas annotation.
+// Returns false if the workload was not scaled down by the controller.
+func popPausedReplicas(annotations map[string]string) (int32, bool) {
+	value, ok := annotations[AnnotationPausedReplicas]
+	if !ok {
+		return 0, false
+	}
+	delete(annotations, AnnotationPausedReplicas)
+
+	replicas, err := strconv.ParseInt(value, 10, 32)
+	if err != nil || replicas < 1 {
+		replicas = 1
+	}
+	return int32(replicas), true
+}
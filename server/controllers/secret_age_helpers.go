@@ -0,0 +1,212 @@
+package controllers
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+)
+
+const (
+	// DefaultSecretMaxAge is how long instance secrets may go without rotation when the
+	// reconciler does not set a maximum age
+	DefaultSecretMaxAge = 90 * 24 * time.Hour
+
+	// AnnotationSecretRotatedAtPrefix prefixes the annotations of the instance Secret that
+	// record when each of its keys was last rotated (RFC 3339), e.g.
+	// rotated-at.supacontrol.io/jwt-secret. A key without one counts as rotated when the
+	// Secret was created.
+	AnnotationSecretRotatedAtPrefix = "rotated-at.supacontrol.io/"
+)
+
+// instanceSecretKeys are the keys of the instance Secret the provisioning Job creates
+var instanceSecretKeys = []struct {
+	key  string
+	kind supacontrolv1alpha1.SecretKind
+}{
+	{key: "jwt-secret", kind: supacontrolv1alpha1.SecretKindJWTSecret},
+	{key: "anon-key", kind: supacontrolv1alpha1.SecretKindAnonKey},
+	{key: "service-role-key", kind: supacontrolv1alpha1.SecretKindServiceRoleKey},
+	{key: "postgres-password", kind: supacontrolv1alpha1.SecretKindPostgresPassword},
+}
+
+// InstanceSecretName returns the Secret holding an instance's keys and Postgres password
+func InstanceSecretName(projectName string) string {
+	return projectName + "-secrets"
+}
+
+// Keys of the instance Secret holding the credentials the gateway asks for before serving
+// Studio. Only the API's Studio proxy uses them; users open Studio through SupaControl.
+const (
+	DashboardUsernameKey = "dashboard-username"
+	DashboardPasswordKey = "dashboard-password"
+)
+
+// isWatchedSecret reports whether a Secret change may affect an instance: a revision of a
+// Helm release, or a Secret labeled with the instance it belongs to
+func isWatchedSecret(object client.Object) bool {
+	return isHelmReleaseSecret(object) || object.GetLabels()["supacontrol.io/instance"] != ""
+}
+
+// secretMaxAge returns the configured maximum secret age or its default
+func (r *SupabaseInstanceReconciler) secretMaxAge() time.Duration {
+	if r.SecretMaxAge > 0 {
+		return r.SecretMaxAge
+	}
+	return DefaultSecretMaxAge
+}
+
+// reconcileSecretAges records when each of a running instance's secrets was last rotated
+// in its status, and sets the SecretsRotated condition false while any of them is older
+// than the maximum secret age or is a certificate that has expired
+func (r *SupabaseInstanceReconciler) reconcileSecretAges(ctx context.Context, instance *supacontrolv1alpha1.SupabaseInstance) error {
+	if instance.Status.Namespace == "" {
+		return nil
+	}
+
+	secrets, err := r.secretAges(ctx, instance, time.Now())
+	if err != nil {
+		return err
+	}
+
+	changed := !equality.Semantic.DeepEqual(instance.Status.Secrets, secrets)
+	instance.Status.Secrets = secrets
+	if len(secrets) == 0 {
+		// The instance Secret lives inside the vcluster, or was never created
+		if meta.RemoveStatusCondition(&instance.Status.Conditions, supacontrolv1alpha1.ConditionTypeSecretsRotated) {
+			changed = true
+		}
+	} else {
+		changed = r.setObservedCondition(instance, r.secretsRotatedCondition(instance, secrets)) || changed
+	}
+
+	if !changed {
+		return nil
+	}
+	return r.updateStatus(ctx, instance)
+}
+
+// secretAges lists an instance's keys and Postgres password, followed by the TLS
+// certificates of its ingresses sorted by name
+func (r *SupabaseInstanceReconciler) secretAges(ctx context.Context, instance *supacontrolv1alpha1.SupabaseInstance, now time.Time) ([]supacontrolv1alpha1.SecretAge, error) {
+	namespace := instance.Status.Namespace
+	maxAge := r.secretMaxAge()
+	var ages []supacontrolv1alpha1.SecretAge
+
+	secret := &corev1.Secret{}
+	err := r.Get(ctx, client.ObjectKey{Namespace: namespace, Name: InstanceSecretName(instance.Spec.ProjectName)}, secret)
+	switch {
+	case err == nil:
+		for _, s := range instanceSecretKeys {
+			if _, ok := secret.Data[s.key]; !ok {
+				continue
+			}
+			rotatedAt := secret.CreationTimestamp.Time
+			if at, err := time.Parse(time.RFC3339, secret.Annotations[AnnotationSecretRotatedAtPrefix+s.key]); err == nil {
+				rotatedAt = at
+			}
+			ages = append(ages, supacontrolv1alpha1.SecretAge{
+				Name:       s.key,
+				Kind:       s.kind,
+				SecretName: secret.Name,
+				RotatedAt:  metav1.NewTime(rotatedAt.UTC()),
+				Overdue:    now.Sub(rotatedAt) > maxAge,
+			})
+		}
+	case !apierrors.IsNotFound(err):
+		return nil, fmt.Errorf("failed to get instance secret: %w", err)
+	}
+
+	ingresses := &networkingv1.IngressList{}
+	if err := r.List(ctx, ingresses, client.InNamespace(namespace),
+		client.MatchingLabels{"supacontrol.io/instance": instance.Spec.ProjectName}); err != nil {
+		return nil, fmt.Errorf("failed to list ingresses: %w", err)
+	}
+	var tlsSecrets []string
+	for _, ingress := range ingresses.Items {
+		for _, tls := range ingress.Spec.TLS {
+			if tls.SecretName != "" && !slices.Contains(tlsSecrets, tls.SecretName) {
+				tlsSecrets = append(tlsSecrets, tls.SecretName)
+			}
+		}
+	}
+	slices.Sort(tlsSecrets)
+
+	for _, name := range tlsSecrets {
+		secret := &corev1.Secret{}
+		if err := r.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, secret); err != nil {
+			// cert-manager has not issued the certificate yet
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return nil, fmt.Errorf("failed to get TLS secret %s: %w", name, err)
+		}
+		age := supacontrolv1alpha1.SecretAge{
+			Name:       name,
+			Kind:       supacontrolv1alpha1.SecretKindTLSCertificate,
+			SecretName: name,
+			RotatedAt:  metav1.NewTime(secret.CreationTimestamp.UTC()),
+		}
+		if certificate := parseCertificate(secret.Data[corev1.TLSCertKey]); certificate != nil {
+			age.RotatedAt = metav1.NewTime(certificate.NotBefore.UTC())
+			expiresAt := metav1.NewTime(certificate.NotAfter.UTC())
+			age.ExpiresAt = &expiresAt
+		}
+		age.Overdue = now.Sub(age.RotatedAt.Time) > maxAge || (age.ExpiresAt != nil && !now.Before(age.ExpiresAt.Time))
+		ages = append(ages, age)
+	}
+	return ages, nil
+}
+
+// parseCertificate returns the first certificate of a PEM encoded chain, or nil
+func parseCertificate(data []byte) *x509.Certificate {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil
+	}
+	certificate, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil
+	}
+	return certificate
+}
+
+// secretsRotatedCondition reports whether every secret was rotated within the maximum
+// secret age, naming those that were not
+func (r *SupabaseInstanceReconciler) secretsRotatedCondition(instance *supacontrolv1alpha1.SupabaseInstance, secrets []supacontrolv1alpha1.SecretAge) metav1.Condition {
+	condition := metav1.Condition{
+		Type:               supacontrolv1alpha1.ConditionTypeSecretsRotated,
+		ObservedGeneration: instance.Generation,
+	}
+	days := int(r.secretMaxAge() / (24 * time.Hour))
+
+	var overdue []string
+	for _, secret := range secrets {
+		if secret.Overdue {
+			overdue = append(overdue, secret.Name)
+		}
+	}
+	if len(overdue) > 0 {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "RotationOverdue"
+		condition.Message = fmt.Sprintf("Not rotated within %d days or expired: %s", days, strings.Join(overdue, ", "))
+		return condition
+	}
+	condition.Status = metav1.ConditionTrue
+	condition.Reason = "RotatedWithinMaxAge"
+	condition.Message = fmt.Sprintf("Every secret was rotated within %d days", days)
+	return condition
+}
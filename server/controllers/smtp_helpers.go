@@ -0,0 +1,274 @@
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+	"github.com/qubitquilt/supacontrol/server/internal/k8s"
+)
+
+const (
+	// SMTPSecretName is the Secret in an instance's namespace that holds the SMTP
+	// credentials the auth service reads
+	SMTPSecretName = "supacontrol-smtp"
+
+	// AnnotationSMTPChecksum records a checksum of the SMTP settings applied to the auth
+	// service's pod template. Changing it rolls the auth pods.
+	AnnotationSMTPChecksum = "supacontrol.io/smtp-checksum"
+)
+
+// smtpEnvNames lists the auth service environment variables managed from Spec.Auth.SMTP
+var smtpEnvNames = []string{
+	"GOTRUE_SMTP_HOST",
+	"GOTRUE_SMTP_PORT",
+	"GOTRUE_SMTP_ADMIN_EMAIL",
+	"GOTRUE_SMTP_SENDER_NAME",
+	"GOTRUE_SMTP_USER",
+	"GOTRUE_SMTP_PASS",
+}
+
+// SMTPPasswordSecretName returns the Secret in the controller namespace that stores the
+// SMTP password set through the API for an instance
+func SMTPPasswordSecretName(instance *supacontrolv1alpha1.SupabaseInstance) string {
+	return "supacontrol-smtp-" + instance.Spec.ProjectName
+}
+
+// smtpEnv returns the auth service environment for SMTP settings. Credentials are read
+// from the SMTP Secret in the instance namespace.
+func smtpEnv(smtp *supacontrolv1alpha1.SMTPSettings) []corev1.EnvVar {
+	fromSecret := func(key string) *corev1.EnvVarSource {
+		return &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{
+			LocalObjectReference: corev1.LocalObjectReference{Name: SMTPSecretName},
+			Key:                  key,
+		}}
+	}
+	return []corev1.EnvVar{
+		{Name: "GOTRUE_SMTP_HOST", Value: smtp.Host},
+		{Name: "GOTRUE_SMTP_PORT", Value: strconv.Itoa(int(smtp.Port))},
+		{Name: "GOTRUE_SMTP_ADMIN_EMAIL", Value: smtp.SenderEmail},
+		{Name: "GOTRUE_SMTP_SENDER_NAME", Value: smtp.SenderName},
+		{Name: "GOTRUE_SMTP_USER", ValueFrom: fromSecret("username")},
+		{Name: "GOTRUE_SMTP_PASS", ValueFrom: fromSecret("password")},
+	}
+}
+
+// smtpChecksum returns a checksum of SMTP settings and their password, so that changing
+// either one rolls the auth pods
+func smtpChecksum(smtp *supacontrolv1alpha1.SMTPSettings, password []byte) string {
+	sum := sha256.New()
+	fmt.Fprintf(sum, "%s\x00%d\x00%s\x00%s\x00%s\x00", smtp.Host, smtp.Port, smtp.User, smtp.SenderEmail, smtp.SenderName)
+	sum.Write(password)
+	return hex.EncodeToString(sum.Sum(nil))[:16]
+}
+
+// setContainerEnv replaces the variables named in names of a container with env. A nil
+// env removes them. Variables set by the chart keep their position, so that overriding
+// them does not reorder the container's environment.
+func setContainerEnv(container *corev1.Container, names []string, env []corev1.EnvVar) {
+	managed := make(map[string]bool, len(names))
+	for _, name := range names {
+		managed[name] = true
+	}
+	desired := make(map[string]corev1.EnvVar, len(env))
+	for _, v := range env {
+		desired[v.Name] = v
+	}
+
+	result := make([]corev1.EnvVar, 0, len(container.Env)+len(env))
+	for _, v := range container.Env {
+		if !managed[v.Name] {
+			result = append(result, v)
+			continue
+		}
+		if d, ok := desired[v.Name]; ok {
+			result = append(result, d)
+			delete(desired, v.Name)
+		}
+	}
+	for _, v := range env {
+		if d, ok := desired[v.Name]; ok {
+			result = append(result, d)
+		}
+	}
+	container.Env = result
+}
+
+// reconcileSMTP applies Spec.Auth.SMTP to a running instance's auth service. The
+// credentials are copied into SMTPSecretName in the instance namespace and the settings
+// are set on the auth Deployment, whose pods roll when the settings or password change.
+// The outcome is recorded in the SMTPConfigured condition; a missing password Secret
+// fails the condition rather than the reconcile.
+func (r *SupabaseInstanceReconciler) reconcileSMTP(ctx context.Context, instance *supacontrolv1alpha1.SupabaseInstance) error {
+	var smtp *supacontrolv1alpha1.SMTPSettings
+	if instance.Spec.Auth != nil {
+		smtp = instance.Spec.Auth.SMTP
+	}
+	if smtp == nil && meta.FindStatusCondition(instance.Status.Conditions, supacontrolv1alpha1.ConditionTypeSMTPConfigured) == nil {
+		return nil
+	}
+
+	condition := metav1.Condition{
+		Type:               supacontrolv1alpha1.ConditionTypeSMTPConfigured,
+		ObservedGeneration: instance.Generation,
+	}
+	switch {
+	case instance.Status.IsolationLevel == supacontrolv1alpha1.IsolationVCluster:
+		if smtp == nil {
+			meta.RemoveStatusCondition(&instance.Status.Conditions, condition.Type)
+			return r.updateStatus(ctx, instance)
+		}
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "IsolationUnsupported"
+		condition.Message = "SMTP settings cannot be applied to vcluster instances"
+	case smtp == nil:
+		if err := r.removeSMTP(ctx, instance); err != nil {
+			return err
+		}
+		meta.RemoveStatusCondition(&instance.Status.Conditions, condition.Type)
+		return r.updateStatus(ctx, instance)
+	default:
+		message, err := r.applySMTP(ctx, instance, smtp)
+		if err != nil {
+			return err
+		}
+		if message != "" {
+			condition.Status = metav1.ConditionFalse
+			condition.Reason = "SecretNotFound"
+			condition.Message = message
+		} else {
+			condition.Status = metav1.ConditionTrue
+			condition.Reason = "Applied"
+			condition.Message = fmt.Sprintf("Auth emails are sent through %s:%d", smtp.Host, smtp.Port)
+		}
+	}
+
+	if existing := meta.FindStatusCondition(instance.Status.Conditions, condition.Type); existing != nil &&
+		existing.Status == condition.Status && existing.Reason == condition.Reason &&
+		existing.Message == condition.Message && existing.ObservedGeneration == condition.ObservedGeneration {
+		return nil
+	}
+	meta.SetStatusCondition(&instance.Status.Conditions, condition)
+	return r.updateStatus(ctx, instance)
+}
+
+// applySMTP writes the SMTP credentials and settings for the auth service. It returns a
+// message instead of an error when the password Secret cannot be found.
+func (r *SupabaseInstanceReconciler) applySMTP(ctx context.Context, instance *supacontrolv1alpha1.SupabaseInstance, smtp *supacontrolv1alpha1.SMTPSettings) (string, error) {
+	var password []byte
+	if ref := smtp.SecretRef; ref != nil {
+		source := &corev1.Secret{}
+		if err := r.Get(ctx, client.ObjectKey{Namespace: ControllerNamespace, Name: ref.Name}, source); err != nil {
+			if apierrors.IsNotFound(err) {
+				return fmt.Sprintf("SMTP password Secret %s not found", ref.Name), nil
+			}
+			return "", fmt.Errorf("failed to get SMTP password secret: %w", err)
+		}
+		var ok bool
+		if password, ok = source.Data[ref.Key]; !ok {
+			return fmt.Sprintf("SMTP password Secret %s has no key %s", ref.Name, ref.Key), nil
+		}
+	}
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      SMTPSecretName,
+			Namespace: instance.Status.Namespace,
+		},
+	}
+	_, err := controllerutil.CreateOrUpdate(ctx, r.Client, secret, func() error {
+		secret.Labels = map[string]string{
+			"app.kubernetes.io/name":       "supacontrol",
+			"app.kubernetes.io/component":  "smtp",
+			"app.kubernetes.io/managed-by": "supacontrol",
+		}
+		secret.Data = map[string][]byte{
+			"username": []byte(smtp.User),
+			"password": password,
+		}
+		return controllerutil.SetControllerReference(instance, secret, r.Scheme)
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to store SMTP credentials: %w", err)
+	}
+
+	return "", r.updateAuthDeployments(ctx, instance, smtpEnvNames, smtpEnv(smtp), AnnotationSMTPChecksum, smtpChecksum(smtp, password))
+}
+
+// removeSMTP removes the SMTP settings SupaControl applied to the auth service, which
+// falls back to the settings from its chart values
+func (r *SupabaseInstanceReconciler) removeSMTP(ctx context.Context, instance *supacontrolv1alpha1.SupabaseInstance) error {
+	if err := r.updateAuthDeployments(ctx, instance, smtpEnvNames, nil, AnnotationSMTPChecksum, ""); err != nil {
+		return err
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      SMTPSecretName,
+			Namespace: instance.Status.Namespace,
+		},
+	}
+	if err := r.Delete(ctx, secret); err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete SMTP credentials: %w", err)
+	}
+	return nil
+}
+
+// updateAuthDeployments sets the variables named in names to env, and the checksum
+// annotation to checksum, on the auth Deployments in the instance namespace. An empty
+// checksum removes both. Deployments that already match are left alone, so their pods
+// only roll when the settings change.
+func (r *SupabaseInstanceReconciler) updateAuthDeployments(ctx context.Context, instance *supacontrolv1alpha1.SupabaseInstance, names []string, env []corev1.EnvVar, annotation, checksum string) error {
+	logger := ctrl.LoggerFrom(ctx)
+
+	var deployments appsv1.DeploymentList
+	if err := r.List(ctx, &deployments, client.InNamespace(instance.Status.Namespace)); err != nil {
+		return fmt.Errorf("failed to list deployments: %w", err)
+	}
+	for i := range deployments.Items {
+		d := &deployments.Items[i]
+		original := d.Spec.Template.DeepCopy()
+		auth := false
+		for j := range d.Spec.Template.Spec.Containers {
+			container := &d.Spec.Template.Spec.Containers[j]
+			if component, ok := k8s.ComponentForImage(container.Image); ok && component == apitypes.ComponentGoTrue {
+				setContainerEnv(container, names, env)
+				auth = true
+			}
+		}
+		if !auth {
+			continue
+		}
+		if equality.Semantic.DeepEqual(original.Spec, d.Spec.Template.Spec) &&
+			original.Annotations[annotation] == checksum {
+			continue
+		}
+
+		if checksum == "" {
+			delete(d.Spec.Template.Annotations, annotation)
+		} else {
+			if d.Spec.Template.Annotations == nil {
+				d.Spec.Template.Annotations = map[string]string{}
+			}
+			d.Spec.Template.Annotations[annotation] = checksum
+		}
+		if err := r.Update(ctx, d); err != nil {
+			return fmt.Errorf("failed to update auth settings of deployment %s: %w", d.Name, err)
+		}
+		logger.Info("Applied auth settings", "namespace", d.Namespace, "deployment", d.Name, "annotation", annotation)
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package controllers
+
+import (
+	"context"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+)
+
+// statusBaseKey is the context key of the copy of an instance that a reconcile's status
+// patches are computed against
+type statusBaseKey struct{}
+
+// statusBase holds the last version of an instance a reconcile read or wrote
+type statusBase struct {
+	instance *supacontrolv1alpha1.SupabaseInstance
+}
+
+// withStatusBase records instance, as just read by a reconcile, as the base of its status
+// patches
+func withStatusBase(ctx context.Context, instance *supacontrolv1alpha1.SupabaseInstance) context.Context {
+	return context.WithValue(ctx, statusBaseKey{}, &statusBase{instance: instance.DeepCopy()})
+}
+
+// rebaseStatus records instance, as just written, as the base of later status patches
+func rebaseStatus(ctx context.Context, instance *supacontrolv1alpha1.SupabaseInstance) {
+	if base, ok := ctx.Value(statusBaseKey{}).(*statusBase); ok {
+		base.instance = instance.DeepCopy()
+	}
+}
+
+// updateStatus writes the status computed for an instance. The status is sent as a merge
+// patch against the version of the instance this reconcile read, locked to its
+// resourceVersion, so a concurrent write such as the API resetting a failed instance is
+// a conflict that requeues the reconcile rather than being overwritten by a status
+// computed from stale state. On success instance reflects the stored object.
+func (r *SupabaseInstanceReconciler) updateStatus(ctx context.Context, instance *supacontrolv1alpha1.SupabaseInstance) error {
+	base, ok := ctx.Value(statusBaseKey{}).(*statusBase)
+	if !ok || base.instance.UID != instance.UID {
+		// Without a recorded base the update carries instance's own resourceVersion
+		return r.Status().Update(ctx, instance)
+	}
+
+	patch := client.MergeFromWithOptions(base.instance, client.MergeFromWithOptimisticLock{})
+	if err := r.Status().Patch(ctx, instance, patch); err != nil {
+		return err
+	}
+	base.instance = instance.DeepCopy()
+	return nil
+}
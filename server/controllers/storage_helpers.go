@@ -0,0 +1,101 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+)
+
+// postgresClaimName returns the name of the Postgres PVC the Supabase chart creates for a
+// release (<fullname of the db component>-pvc)
+func postgresClaimName(instance *supacontrolv1alpha1.SupabaseInstance) string {
+	releaseName := instance.Status.HelmReleaseName
+	if releaseName == "" {
+		releaseName = instance.Spec.ProjectName
+	}
+	return releaseName + "-supabase-db-pvc"
+}
+
+// setStorageValues sizes the chart's Postgres volume and selects its StorageClass
+func setStorageValues(values map[string]interface{}, storage *supacontrolv1alpha1.Storage) {
+	if storage == nil || (storage.Size == nil && storage.ClassName == "") {
+		return
+	}
+	db, ok := values["db"].(map[string]interface{})
+	if !ok {
+		db = map[string]interface{}{}
+		values["db"] = db
+	}
+	persistence := map[string]interface{}{"enabled": true}
+	if storage.Size != nil {
+		persistence["size"] = storage.Size.String()
+	}
+	if storage.ClassName != "" {
+		persistence["storageClassName"] = storage.ClassName
+	}
+	db["persistence"] = persistence
+}
+
+// expandStorage grows the instance's Postgres PVC when its requested size is below the
+// size in the spec. Smaller sizes are ignored, as volumes cannot shrink. The outcome is
+// recorded in the StorageExpanded condition; a StorageClass that does not allow expansion
+// fails the condition rather than the reconcile, so it is not retried until the spec changes.
+func (r *SupabaseInstanceReconciler) expandStorage(ctx context.Context, instance *supacontrolv1alpha1.SupabaseInstance) error {
+	if instance.Spec.Storage == nil || instance.Spec.Storage.Size == nil {
+		return nil
+	}
+	size := *instance.Spec.Storage.Size
+
+	claim := &corev1.PersistentVolumeClaim{}
+	key := client.ObjectKey{Namespace: instance.Status.Namespace, Name: postgresClaimName(instance)}
+	if err := r.Get(ctx, key, claim); err != nil {
+		if apierrors.IsNotFound(err) {
+			// Chart versions without persistence have no volume to expand
+			return nil
+		}
+		return fmt.Errorf("failed to get Postgres volume claim: %w", err)
+	}
+	current := claim.Spec.Resources.Requests[corev1.ResourceStorage]
+	if size.Cmp(current) <= 0 {
+		return nil
+	}
+
+	condition := metav1.Condition{
+		Type:               supacontrolv1alpha1.ConditionTypeStorageExpanded,
+		ObservedGeneration: instance.Generation,
+	}
+	if existing := meta.FindStatusCondition(instance.Status.Conditions, condition.Type); existing != nil &&
+		existing.Status == metav1.ConditionFalse && existing.ObservedGeneration == instance.Generation {
+		return nil
+	}
+
+	ctrl.LoggerFrom(ctx).Info("Expanding Postgres volume", "projectName", instance.Spec.ProjectName,
+		"claim", claim.Name, "from", current.String(), "to", size.String())
+	if claim.Spec.Resources.Requests == nil {
+		claim.Spec.Resources.Requests = corev1.ResourceList{}
+	}
+	claim.Spec.Resources.Requests[corev1.ResourceStorage] = size
+	if err := r.Update(ctx, claim); err != nil {
+		if !apierrors.IsInvalid(err) && !apierrors.IsForbidden(err) {
+			return fmt.Errorf("failed to expand Postgres volume claim: %w", err)
+		}
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "ExpansionRejected"
+		condition.Message = fmt.Sprintf("Expanding %s to %s was rejected: %v", claim.Name, size.String(), err)
+	} else {
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = "ExpansionRequested"
+		condition.Message = fmt.Sprintf("Expanding %s from %s to %s", claim.Name, current.String(), size.String())
+	}
+
+	meta.SetStatusCondition(&instance.Status.Conditions, condition)
+	return r.updateStatus(ctx, instance)
+}
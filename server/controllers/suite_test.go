@@ -13,6 +13,7 @@ import (
 
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/rest"
@@ -253,6 +254,18 @@ func setJobSucceeded(ctx context.Context, t *testing.T, jobName string) {
 		} else {
 			t.Logf("Successfully created instance namespace: %s", instanceNs.Name)
 		}
+
+		// Record the Helm release a provisioning Job installs, as Helm stores it
+		if strings.HasPrefix(jobName, "supacontrol-provision-") {
+			release := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{
+				Name:      "sh.helm.release.v1." + instanceName + ".v1",
+				Namespace: instanceNs.Name,
+				Labels:    map[string]string{"owner": "helm", "name": instanceName, "status": "deployed", "version": "1"},
+			}}
+			if err := client.IgnoreAlreadyExists(k8sClient.Create(ctx, release)); err != nil {
+				t.Fatalf("Failed to create Helm release of %s: %v", instanceName, err)
+			}
+		}
 	} else {
 		t.Logf("Warning: could not extract instance name from job name: %s", jobName)
 	}
@@ -4,6 +4,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"maps"
+	"strings"
+	"sync"
 	"time"
 
 	batchv1 "k8s.io/api/batch/v1"
@@ -12,19 +15,41 @@ import (
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/utils/ptr"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
 	"github.com/qubitquilt/supacontrol/server/internal/metrics"
+	"github.com/qubitquilt/supacontrol/server/internal/notify"
+	"github.com/qubitquilt/supacontrol/server/internal/proxy"
 )
 
 const (
 	// FinalizerName is the name of the finalizer added to SupabaseInstance resources
 	FinalizerName = "supacontrol.qubitquilt.com/finalizer"
+
+	// DefaultAutoRetryAttempts is the number of automatic retries when AutoRetry.MaxAttempts
+	// is unset, and of transient failures of instances without AutoRetry
+	DefaultAutoRetryAttempts = 3
+
+	// AutoRetryBaseDelay is the wait before the first automatic retry; it doubles with each retry
+	AutoRetryBaseDelay = time.Minute
+
+	// AutoRetryMaxDelay caps the wait between automatic retries
+	AutoRetryMaxDelay = time.Hour
 )
 
 // SupabaseInstanceReconciler reconciles a SupabaseInstance object
@@ -37,20 +62,148 @@ type SupabaseInstanceReconciler struct {
 	DefaultIngressClass  string
 	DefaultIngressDomain string
 	CertManagerIssuer    string
+
+	// CABundleConfigMap names a ConfigMap in the controller namespace whose ca.crt
+	// key holds extra CAs trusted by provisioning and upgrade Jobs (empty disables)
+	CABundleConfigMap string
+
+	// ChartRepoCredentialsSecret names a Secret in the controller namespace whose username
+	// and password keys authenticate provisioning and upgrade Jobs to a private chart
+	// repository (empty uses the repository anonymously)
+	ChartRepoCredentialsSecret string
+
+	// ProvisionerImage runs provisioning, upgrade and cleanup Jobs of instances without an
+	// override of their own (empty uses the ProvisionerImage default), and
+	// RequireImageDigest rejects images that are not pinned by digest
+	ProvisionerImage   string
+	RequireImageDigest bool
+
+	// Proxy is injected into every Job so helm can reach the chart repository
+	Proxy proxy.Config
+
+	// KataRuntimeClass is the RuntimeClass that runs instances with kata-runtime isolation
+	KataRuntimeClass string
+
+	// VClusterChartRepo and VClusterChartVersion locate the chart that provisions instances
+	// with vcluster isolation (an empty repository disables vcluster isolation)
+	VClusterChartRepo    string
+	VClusterChartVersion string
+
+	// VClusterClient builds the client that reaches the workloads inside an instance's
+	// vcluster from its kubeconfig (nil uses client.New)
+	VClusterClient func(config *rest.Config) (client.Client, error)
+
+	// SuspendedPageURL is the page the ingresses of suspended instances redirect to
+	// (empty only labels them)
+	SuspendedPageURL string
+
+	// MaxConcurrentReconciles is how many instances are reconciled in parallel, and
+	// RateLimiter throttles reconciliations after failures (zero values use the defaults)
+	MaxConcurrentReconciles int
+	RateLimiter             workqueue.TypedRateLimiter[reconcile.Request]
+
+	// ExternalDNSMode publishes instance hostnames through external-dns, with DNSEndpoint
+	// resources or annotations on the instance ingresses (empty disables), and
+	// DNSReadyTimeout is how long a new instance waits for DNSReady before it is marked
+	// Running anyway (zero uses DefaultDNSReadyTimeout)
+	ExternalDNSMode string
+	DNSReadyTimeout time.Duration
+
+	// MaxConcurrentProvisioningJobs caps how many provisioning Jobs run at once; further
+	// instances wait in the Queued phase and are provisioned first in, first out (zero
+	// disables the cap)
+	MaxConcurrentProvisioningJobs int
+
+	// JobPollInterval, ResyncInterval and FailedRequeueInterval override how often
+	// instances are requeued (zero values use the defaults)
+	JobPollInterval       time.Duration
+	ResyncInterval        time.Duration
+	FailedRequeueInterval time.Duration
+
+	// IngressControllerNamespace is the namespace whose pods network-isolated instances
+	// admit traffic from (empty uses DefaultIngressControllerNamespace)
+	IngressControllerNamespace string
+
+	// MonitoringNamespace is the namespace whose Prometheus network-isolated instances admit
+	// scrapes of their exporter from (empty uses DefaultMonitoringNamespace), and
+	// MonitoringLabels are set on ServiceMonitors so that Prometheus selects them
+	MonitoringNamespace string
+	MonitoringLabels    map[string]string
+
+	// NamespaceTemplate names instance namespaces, with {name} replaced by the project
+	// name (empty uses namespaces.DefaultTemplate). NamespaceLabels and
+	// NamespaceAnnotations are set on every instance namespace; the labels in an
+	// instance's spec take precedence.
+	NamespaceTemplate    string
+	NamespaceLabels      map[string]string
+	NamespaceAnnotations map[string]string
+
+	// HealthSuccessThreshold and HealthFailureThreshold are how many health checks in a
+	// row must pass or fail before Ready changes (zero values use the defaults)
+	HealthSuccessThreshold int32
+	HealthFailureThreshold int32
+
+	// ConnectionRotationInterval is how often the credentials of connections between
+	// instances are rotated (zero uses DefaultConnectionRotationInterval)
+	ConnectionRotationInterval time.Duration
+
+	// SecretMaxAge is how long instance secrets may go without rotation before the
+	// SecretsRotated condition turns false (zero uses DefaultSecretMaxAge)
+	SecretMaxAge time.Duration
+
+	// Notifier announces the upcoming deletion of ephemeral instances (nil disables
+	// notifications)
+	Notifier notify.Notifier
+
+	// APIReader reads pods for health checks directly from the API server (nil uses the
+	// client, which caches every pod it lists)
+	APIReader client.Reader
+
+	// PostgresImageTags maps Postgres major versions to the tag of the Supabase Postgres
+	// image running them; versions without a tag run the chart's default image.
+	// PostgresUpgradeImage runs pg_upgrade during major upgrades, with {version} replaced
+	// by the target version (empty uses DefaultPostgresUpgradeImage).
+	PostgresImageTags    map[string]string
+	PostgresUpgradeImage string
+
+	// Recorder records events on instances when the state of their ingresses or
+	// certificates changes (nil records none)
+	Recorder record.EventRecorder
+
+	// provisioningMu serializes the admission of queued instances, so parallel
+	// reconciles cannot start more provisioning Jobs than MaxConcurrentProvisioningJobs
+	provisioningMu sync.Mutex
 }
 
 // +kubebuilder:rbac:groups=supacontrol.qubitquilt.com,resources=supabaseinstances,verbs=get;list;create;update;patch;delete
 // +kubebuilder:rbac:groups=supacontrol.qubitquilt.com,resources=supabaseinstances/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=supacontrol.qubitquilt.com,resources=supabaseinstances/finalizers,verbs=update
-// +kubebuilder:rbac:groups=core,resources=namespaces,verbs=get;create;update;patch;delete
+// +kubebuilder:rbac:groups=supacontrol.qubitquilt.com,resources=supabaseinstancetemplates,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core,resources=namespaces,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=nodes,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups=core,resources=persistentvolumeclaims,verbs=get;list;watch;create;update;patch
+// +kubebuilder:rbac:groups=core,resources=pods,verbs=get;list
+// +kubebuilder:rbac:groups=node.k8s.io,resources=runtimeclasses,verbs=get;list;watch
+// +kubebuilder:rbac:groups=storage.k8s.io,resources=storageclasses,verbs=get;list;watch
 // +kubebuilder:rbac:groups=rbac.authorization.k8s.io,resources=roles;rolebindings,verbs=get;create;update;patch;delete
 // +kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;create;update;patch;delete
 // +kubebuilder:rbac:groups=batch,resources=jobs/status,verbs=get
+// +kubebuilder:rbac:groups=apps,resources=deployments;statefulsets,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=services,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=secrets,verbs=get;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=networking.k8s.io,resources=ingresses,verbs=get;list;watch;create;update;patch
+// +kubebuilder:rbac:groups=networking.k8s.io,resources=networkpolicies,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=networking.k8s.io,resources=ingressclasses,verbs=get;list;watch
+// +kubebuilder:rbac:groups=cert-manager.io,resources=certificates,verbs=get;list;watch
+// +kubebuilder:rbac:groups=externaldns.k8s.io,resources=dnsendpoints,verbs=get;list;watch;create;update;patch
+// +kubebuilder:rbac:groups=policy,resources=poddisruptionbudgets,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=monitoring.coreos.com,resources=servicemonitors,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=coordination.k8s.io,resources=leases,verbs=get;create;update;patch;delete
 // +kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
 
 // Reconcile is the main reconciliation loop
-func (r *SupabaseInstanceReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+func (r *SupabaseInstanceReconciler) Reconcile(ctx context.Context, req ctrl.Request) (result ctrl.Result, err error) {
 	logger := ctrl.LoggerFrom(ctx)
 	startTime := time.Now()
 
@@ -65,6 +218,16 @@ func (r *SupabaseInstanceReconciler) Reconcile(ctx context.Context, req ctrl.Req
 		metrics.ReconciliationErrorsTotal.WithLabelValues("unknown").Inc()
 		return ctrl.Result{}, err
 	}
+	ctx = withStatusBase(ctx, instance)
+
+	// A status patch that conflicts with a concurrent write is computed from stale state;
+	// reconcile again from the current version rather than reporting an error
+	defer func() {
+		if apierrors.IsConflict(err) {
+			logger.V(1).Info("Instance changed during reconciliation, requeueing")
+			result, err = ctrl.Result{Requeue: true}, nil
+		}
+	}()
 
 	phase := string(instance.Status.Phase)
 	if phase == "" {
@@ -72,34 +235,66 @@ func (r *SupabaseInstanceReconciler) Reconcile(ctx context.Context, req ctrl.Req
 	}
 
 	// Track reconciliation
+	startPhase, enteredAt := instance.Status.Phase, phaseEnteredAt(instance)
 	defer func() {
 		duration := time.Since(startTime).Seconds()
 		metrics.ReconciliationTotal.WithLabelValues(phase).Inc()
 		metrics.ReconciliationDuration.WithLabelValues(phase).Observe(duration)
+		r.recordReconcileOutcome(instance, startPhase, enteredAt, result, err)
+		// A failed reconciliation may have changed the phase in memory without persisting it
+		if err == nil {
+			r.recordPhaseTransition(ctx, instance, startPhase)
+		}
 	}()
 
-	// Check if reconciliation is paused
-	if instance.Spec.Paused {
-		logger.Info("Reconciliation paused for instance", "projectName", instance.Spec.ProjectName)
-		return ctrl.Result{}, nil
-	}
-
-	// Handle deletion with finalizer
+	// Handle deletion with finalizer (paused instances must remain deletable)
 	if !instance.DeletionTimestamp.IsZero() {
 		return r.reconcileDelete(ctx, instance)
 	}
 
-	// Add finalizer if not present
+	// Add finalizer if not present. This comes before the paused, suspended and trash
+	// branches so that an instance created or left in one of them is still cleaned up
+	// when it is deleted.
 	if !controllerutil.ContainsFinalizer(instance, FinalizerName) {
 		controllerutil.AddFinalizer(instance, FinalizerName)
 		if err := r.Update(ctx, instance); err != nil {
 			metrics.ReconciliationErrorsTotal.WithLabelValues(phase).Inc()
 			return ctrl.Result{}, err
 		}
+		rebaseStatus(ctx, instance)
 		// Increment instance counter when first created (finalizer added)
 		metrics.InstancesTotal.Inc()
 	}
 
+	// Instances in the trash are scaled to zero until they are purged or recovered
+	if instance.Spec.PendingDeletion != nil {
+		return r.reconcilePendingDeletion(ctx, instance)
+	}
+
+	// Ephemeral instances are deleted once their TTL has passed
+	expired, wait, expiryErr := r.reconcileExpiry(ctx, instance)
+	if expiryErr != nil || expired {
+		return ctrl.Result{}, expiryErr
+	}
+	if wait > 0 {
+		// Whatever else is due, come back in time for the warning and the expiry
+		defer func() {
+			if err == nil && (result.RequeueAfter == 0 || result.RequeueAfter > wait) {
+				result.RequeueAfter = wait
+			}
+		}()
+	}
+
+	// Suspended instances are scaled to zero like paused ones, whatever their owner asks for
+	if instance.Spec.Suspension != nil {
+		return r.reconcileSuspended(ctx, instance)
+	}
+
+	// Paused instances are scaled to zero; unprovisioned ones are simply left alone
+	if instance.Spec.Paused {
+		return r.reconcilePaused(ctx, instance)
+	}
+
 	// Reconcile based on current phase
 	return r.reconcileNormal(ctx, instance)
 }
@@ -113,7 +308,7 @@ func (r *SupabaseInstanceReconciler) reconcileNormal(ctx context.Context, instan
 	if instance.Status.Phase == "" {
 		instance.Status.Phase = supacontrolv1alpha1.PhasePending
 		instance.Status.ObservedGeneration = instance.Generation
-		if err := r.Status().Update(ctx, instance); err != nil {
+		if err := r.updateStatus(ctx, instance); err != nil {
 			return ctrl.Result{}, err
 		}
 		// Update metrics for initial phase
@@ -123,7 +318,7 @@ func (r *SupabaseInstanceReconciler) reconcileNormal(ctx context.Context, instan
 
 	// State machine based on phase
 	switch instance.Status.Phase {
-	case supacontrolv1alpha1.PhasePending:
+	case supacontrolv1alpha1.PhasePending, supacontrolv1alpha1.PhaseQueued:
 		return r.reconcilePending(ctx, instance)
 	case supacontrolv1alpha1.PhaseProvisioning:
 		return r.reconcileProvisioning(ctx, instance)
@@ -131,23 +326,107 @@ func (r *SupabaseInstanceReconciler) reconcileNormal(ctx context.Context, instan
 		return r.reconcileProvisioningInProgress(ctx, instance)
 	case supacontrolv1alpha1.PhaseRunning:
 		return r.reconcileRunning(ctx, instance)
+	case supacontrolv1alpha1.PhaseUpgrading:
+		return r.reconcileUpgrading(ctx, instance)
+	case supacontrolv1alpha1.PhaseBackingUpDatabase, supacontrolv1alpha1.PhaseUpgradingDatabase,
+		supacontrolv1alpha1.PhaseVerifyingDatabase, supacontrolv1alpha1.PhaseRollingBackDatabase:
+		return r.reconcileDatabaseUpgrade(ctx, instance)
+	case supacontrolv1alpha1.PhaseStopped, supacontrolv1alpha1.PhaseSuspended, supacontrolv1alpha1.PhasePendingDeletion:
+		return r.reconcileStopped(ctx, instance)
 	case supacontrolv1alpha1.PhaseFailed:
 		return r.reconcileFailed(ctx, instance)
 	default:
 		logger.Info("Unknown phase, resetting to Pending", "phase", instance.Status.Phase)
 		instance.Status.Phase = supacontrolv1alpha1.PhasePending
-		if err := r.Status().Update(ctx, instance); err != nil {
+		if err := r.updateStatus(ctx, instance); err != nil {
 			return ctrl.Result{}, err
 		}
 		return ctrl.Result{RequeueAfter: time.Second}, nil
 	}
 }
 
-// reconcilePending transitions from Pending to Provisioning by creating a Job
+// reconcilePending transitions from Pending to Provisioning by creating a Job, or to
+// Queued while the provisioning Job cap is reached
 func (r *SupabaseInstanceReconciler) reconcilePending(ctx context.Context, instance *supacontrolv1alpha1.SupabaseInstance) (ctrl.Result, error) {
+	// Imported instances take over an existing Helm release instead of installing one
+	if instance.Spec.Adoption != nil {
+		return r.adoptRelease(ctx, instance)
+	}
+
 	logger := ctrl.LoggerFrom(ctx)
 	logger.Info("Starting provisioning via Job", "projectName", instance.Spec.ProjectName)
 
+	// Wait until the Job of a failed earlier attempt is gone
+	cleared, err := r.deleteFailedProvisioningJob(ctx, instance)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if !cleared {
+		logger.Info("Waiting for the previous provisioning Job to be deleted", "projectName", instance.Spec.ProjectName)
+		return ctrl.Result{RequeueAfter: 2 * time.Second}, nil
+	}
+
+	// Refuse system namespaces before the provisioning Job creates anything in them
+	if err := r.validateNamespace(instance); err != nil {
+		return r.transitionToFailed(ctx, instance, fmt.Sprintf("Invalid instance namespace: %v", err))
+	}
+
+	// Check that the cluster supports the requested isolation level before provisioning
+	failure, err := r.resolveIsolation(ctx, instance)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if failure != "" {
+		return r.transitionToFailed(ctx, instance, failure)
+	}
+
+	// Wait for a free slot when too many provisioning Jobs are running
+	r.provisioningMu.Lock()
+	defer r.provisioningMu.Unlock()
+	admitted, active, ahead, err := r.admitProvisioning(ctx, instance)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if !admitted {
+		return r.queueProvisioning(ctx, instance, active, ahead)
+	}
+
+	// Reserve a node before provisioning when the instance runs in dedicated mode
+	if isDedicated(instance) {
+		nodeName, err := r.reserveDedicatedNode(ctx, instance)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if nodeName == "" {
+			logger.Info("No node available for dedicated placement", "projectName", instance.Spec.ProjectName)
+			meta.SetStatusCondition(&instance.Status.Conditions, metav1.Condition{
+				Type:               supacontrolv1alpha1.ConditionTypePlacementReady,
+				Status:             metav1.ConditionFalse,
+				ObservedGeneration: instance.Generation,
+				Reason:             "NoNodeAvailable",
+				Message:            "No schedulable node matching the placement node selector is free",
+			})
+			if err := r.updateStatus(ctx, instance); err != nil {
+				return ctrl.Result{}, err
+			}
+			return ctrl.Result{RequeueAfter: time.Minute}, nil
+		}
+		instance.Status.DedicatedNode = nodeName
+		meta.SetStatusCondition(&instance.Status.Conditions, metav1.Condition{
+			Type:               supacontrolv1alpha1.ConditionTypePlacementReady,
+			Status:             metav1.ConditionTrue,
+			ObservedGeneration: instance.Generation,
+			Reason:             "NodeReserved",
+			Message:            fmt.Sprintf("Node '%s' reserved for the instance", nodeName),
+		})
+	}
+
+	// Install the requested Postgres version. Later changes go through the upgrade
+	// workflow, so a reinstall after a failure keeps the version the data was written with.
+	if instance.Status.DatabaseVersion == "" {
+		instance.Status.DatabaseVersion = requestedDatabaseVersion(instance)
+	}
+
 	// Create provisioning Job
 	job, err := r.createProvisioningJob(ctx, instance)
 	if err != nil {
@@ -156,9 +435,10 @@ func (r *SupabaseInstanceReconciler) reconcilePending(ctx context.Context, insta
 
 	// Transition to Provisioning phase
 	instance.Status.Phase = supacontrolv1alpha1.PhaseProvisioning
-	instance.Status.Namespace = fmt.Sprintf("supa-%s", instance.Spec.ProjectName)
+	instance.Status.Namespace = r.instanceNamespace(instance)
 	instance.Status.HelmReleaseName = instance.Spec.ProjectName
 	instance.Status.ProvisioningJobName = job.Name
+	instance.Status.QueuedAt = nil
 	now := metav1.Now()
 	instance.Status.LastTransitionTime = &now
 
@@ -170,7 +450,7 @@ func (r *SupabaseInstanceReconciler) reconcilePending(ctx context.Context, insta
 		Message:            fmt.Sprintf("Provisioning Job '%s' created", job.Name),
 	})
 
-	if err := r.Status().Update(ctx, instance); err != nil {
+	if err := r.updateStatus(ctx, instance); err != nil {
 		return ctrl.Result{}, err
 	}
 
@@ -195,7 +475,7 @@ func (r *SupabaseInstanceReconciler) reconcileProvisioning(ctx context.Context,
 			return r.transitionToFailed(ctx, instance, fmt.Sprintf("Failed to create provisioning Job: %v", err))
 		}
 		instance.Status.ProvisioningJobName = job.Name
-		if err := r.Status().Update(ctx, instance); err != nil {
+		if err := r.updateStatus(ctx, instance); err != nil {
 			return ctrl.Result{}, err
 		}
 		return ctrl.Result{RequeueAfter: time.Second}, nil
@@ -225,7 +505,7 @@ func (r *SupabaseInstanceReconciler) reconcileProvisioning(ctx context.Context,
 			Message:            fmt.Sprintf("Provisioning Job '%s' is running", jobName),
 		})
 
-		if err := r.Status().Update(ctx, instance); err != nil {
+		if err := r.updateStatus(ctx, instance); err != nil {
 			return ctrl.Result{}, err
 		}
 
@@ -233,21 +513,18 @@ func (r *SupabaseInstanceReconciler) reconcileProvisioning(ctx context.Context,
 		metrics.SetInstanceStatus(instance.Spec.ProjectName, string(supacontrolv1alpha1.PhaseProvisioningInProgress), supacontrolv1alpha1.AllPhases())
 
 		// Requeue to check status again
-		return ctrl.Result{RequeueAfter: 10 * time.Second}, nil
+		return ctrl.Result{RequeueAfter: r.jobPollInterval()}, nil
 	}
 
 	// Check if Job succeeded
 	if isJobSucceeded(job) {
-		return r.transitionToRunning(ctx, instance)
+		return r.finishProvisioning(ctx, instance, job)
 	}
 
 	// Check if Job failed
 	if isJobFailed(job) {
-		errMsg := getJobConditionMessage(job)
-		if errMsg == "" {
-			errMsg = "Provisioning Job failed after retries"
-		}
-		return r.transitionToFailed(ctx, instance, errMsg)
+		recordJobDuration(OperationProvision, "failed", job)
+		return r.transitionToFailed(ctx, instance, r.provisioningFailureMessage(ctx, job))
 	}
 
 	// Job exists but hasn't started yet, requeue
@@ -272,22 +549,34 @@ func (r *SupabaseInstanceReconciler) reconcileProvisioningInProgress(ctx context
 	// Check if Job succeeded
 	if isJobSucceeded(job) {
 		logger.Info("Provisioning Job succeeded", "jobName", jobName)
-		return r.transitionToRunning(ctx, instance)
+		return r.finishProvisioning(ctx, instance, job)
 	}
 
 	// Check if Job failed
 	if isJobFailed(job) {
-		errMsg := getJobConditionMessage(job)
-		if errMsg == "" {
-			errMsg = "Provisioning Job failed after retries"
-		}
+		recordJobDuration(OperationProvision, "failed", job)
+		errMsg := r.provisioningFailureMessage(ctx, job)
 		logger.Error(errors.New(errMsg), "Provisioning Job failed", "jobName", jobName)
 		return r.transitionToFailed(ctx, instance, errMsg)
 	}
 
 	// Job still running, requeue
 	logger.V(1).Info("Provisioning Job still running", "jobName", jobName, "active", job.Status.Active)
-	return ctrl.Result{RequeueAfter: 10 * time.Second}, nil
+	return ctrl.Result{RequeueAfter: r.jobPollInterval()}, nil
+}
+
+// finishProvisioning moves an instance whose provisioning Job succeeded to Running, once
+// external-dns has published its hostnames when it is used
+func (r *SupabaseInstanceReconciler) finishProvisioning(ctx context.Context, instance *supacontrolv1alpha1.SupabaseInstance, job *batchv1.Job) (ctrl.Result, error) {
+	completedAt := time.Now()
+	if job.Status.CompletionTime != nil {
+		completedAt = job.Status.CompletionTime.Time
+	}
+	if waiting, result, err := r.waitForDNS(ctx, instance, completedAt); waiting {
+		return result, err
+	}
+	recordJobDuration(OperationProvision, "succeeded", job)
+	return r.transitionToRunning(ctx, instance)
 }
 
 // transitionToRunning transitions the instance to Running phase
@@ -304,16 +593,24 @@ func (r *SupabaseInstanceReconciler) transitionToRunning(ctx context.Context, in
 
 	instance.Status.Phase = supacontrolv1alpha1.PhaseRunning
 	instance.Status.ErrorMessage = ""
+	// An adopted release keeps the chart version it was installed with
+	if instance.Spec.Adoption == nil || instance.Status.ChartVersion == "" {
+		instance.Status.ChartVersion = r.chartVersionFor(instance)
+	}
+	instance.Status.Resources = instance.Spec.Resources.DeepCopy()
 	now := metav1.Now()
 	instance.Status.LastTransitionTime = &now
 
 	// Set URLs
-	ingressDomain := r.DefaultIngressDomain
-	if instance.Spec.IngressDomain != "" {
-		ingressDomain = instance.Spec.IngressDomain
+	instance.Status.StudioURL, instance.Status.APIURL = r.instanceURLs(instance)
+
+	// Isolate and label the namespace before the instance is published
+	if err := r.ensureNetworkPolicies(ctx, instance); err != nil {
+		return ctrl.Result{}, err
+	}
+	if err := r.ensureNamespaceMetadata(ctx, instance); err != nil {
+		return ctrl.Result{}, err
 	}
-	instance.Status.StudioURL = fmt.Sprintf("https://%s-studio.%s", instance.Spec.ProjectName, ingressDomain)
-	instance.Status.APIURL = fmt.Sprintf("https://%s-api.%s", instance.Spec.ProjectName, ingressDomain)
 
 	// Create ingresses
 	if err := r.ensureIngresses(ctx, instance); err != nil {
@@ -333,7 +630,7 @@ func (r *SupabaseInstanceReconciler) transitionToRunning(ctx context.Context, in
 	// Update observedGeneration to indicate this spec has been reconciled
 	instance.Status.ObservedGeneration = instance.Generation
 
-	if err := r.Status().Update(ctx, instance); err != nil {
+	if err := r.updateStatus(ctx, instance); err != nil {
 		return ctrl.Result{}, err
 	}
 
@@ -342,34 +639,523 @@ func (r *SupabaseInstanceReconciler) transitionToRunning(ctx context.Context, in
 	metrics.JobStatusTotal.WithLabelValues("provision", "succeeded").Inc()
 
 	// Requeue with delay for periodic health checks
-	return ctrl.Result{RequeueAfter: 5 * time.Minute}, nil
+	return ctrl.Result{RequeueAfter: r.resyncInterval()}, nil
 }
 
-// reconcileRunning handles the running phase (health checks, drift detection)
-func (r *SupabaseInstanceReconciler) reconcileRunning(_ context.Context, _ *supacontrolv1alpha1.SupabaseInstance) (ctrl.Result, error) {
-	// In a production operator, you would:
-	// 1. Check if namespace still exists
-	// 2. Check if Helm release is healthy
-	// 3. Check if ingresses are configured correctly
-	// 4. Detect and reconcile drift
-	//
-	// For now, we'll just requeue periodically for basic health checks
-	return ctrl.Result{RequeueAfter: 5 * time.Minute}, nil
+// reconcileRunning handles the running phase (chart upgrades, health checks, drift detection)
+func (r *SupabaseInstanceReconciler) reconcileRunning(ctx context.Context, instance *supacontrolv1alpha1.SupabaseInstance) (ctrl.Result, error) {
+	if reason, message, err := r.removedResources(ctx, instance); err != nil {
+		return ctrl.Result{}, err
+	} else if reason != "" {
+		return r.failRemovedInstance(ctx, instance, reason, message)
+	}
+	if needsUpgrade(instance) || needsResize(instance) {
+		return r.startUpgrade(ctx, instance)
+	}
+	if sync, err := r.needsProfileSync(ctx, instance); err != nil {
+		return ctrl.Result{}, err
+	} else if sync {
+		return r.startUpgrade(ctx, instance)
+	}
+	if needsDatabaseUpgrade(instance) {
+		return r.startDatabaseUpgrade(ctx, instance)
+	}
+	if err := r.reconcileMaintenance(ctx, instance); err != nil {
+		return ctrl.Result{}, err
+	}
+	if changes, err := r.ingressChanges(ctx, instance); err != nil {
+		return ctrl.Result{}, err
+	} else if len(changes) > 0 {
+		return r.updateIngresses(ctx, instance, changes)
+	}
+	if err := r.expandStorage(ctx, instance); err != nil {
+		return ctrl.Result{}, err
+	}
+	if err := r.ensureNetworkPolicies(ctx, instance); err != nil {
+		return ctrl.Result{}, err
+	}
+	if err := r.ensureNamespaceMetadata(ctx, instance); err != nil {
+		return ctrl.Result{}, err
+	}
+	if err := r.reconcileSMTP(ctx, instance); err != nil {
+		return ctrl.Result{}, err
+	}
+	if err := r.reconcileOAuth(ctx, instance); err != nil {
+		return ctrl.Result{}, err
+	}
+	if err := r.reconcileAddons(ctx, instance); err != nil {
+		return ctrl.Result{}, err
+	}
+	if err := r.reconcileConnections(ctx, instance); err != nil {
+		return ctrl.Result{}, err
+	}
+	if err := r.reconcileReadReplicas(ctx, instance); err != nil {
+		return ctrl.Result{}, err
+	}
+	if err := r.reconcileMonitoring(ctx, instance); err != nil {
+		return ctrl.Result{}, err
+	}
+	if err := r.reconcileDatabaseAudit(ctx, instance); err != nil {
+		return ctrl.Result{}, err
+	}
+	if err := r.reconcileIngressStatus(ctx, instance); err != nil {
+		return ctrl.Result{}, err
+	}
+	if err := r.reconcileDNS(ctx, instance); err != nil {
+		return ctrl.Result{}, err
+	}
+	if err := r.reconcileHelmRelease(ctx, instance); err != nil {
+		return ctrl.Result{}, err
+	}
+	if err := r.reconcileSecretAges(ctx, instance); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	next, err := r.evaluateHealth(ctx, instance)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{RequeueAfter: next}, nil
+}
+
+// needsUpgrade reports whether the spec asks for a chart version other than the one deployed.
+// A failed upgrade is not retried until the spec changes again. The Upgraded condition
+// rather than the status's observed generation tells whether the current spec was tried,
+// since resuming a stopped instance observes a version change made while it was stopped.
+func needsUpgrade(instance *supacontrolv1alpha1.SupabaseInstance) bool {
+	if instance.Spec.ChartVersion == "" || instance.Spec.ChartVersion == instance.Status.ChartVersion {
+		return false
+	}
+	condition := meta.FindStatusCondition(instance.Status.Conditions, supacontrolv1alpha1.ConditionTypeUpgraded)
+	return condition == nil || condition.Status == metav1.ConditionTrue || condition.ObservedGeneration != instance.Generation
+}
+
+// upgradeInProgress reports whether the running upgrade Job changes the chart version, as
+// recorded when it was started. needsUpgrade no longer holds once the Job is started.
+func upgradeInProgress(instance *supacontrolv1alpha1.SupabaseInstance) bool {
+	condition := meta.FindStatusCondition(instance.Status.Conditions, supacontrolv1alpha1.ConditionTypeUpgraded)
+	return condition != nil && condition.Reason == "UpgradeInProgress"
+}
+
+// ingressChanges compares a running instance's ingresses and published URLs with its spec,
+// e.g. after its ingress domain, class, settings or custom domains were edited, and
+// describes each difference
+func (r *SupabaseInstanceReconciler) ingressChanges(ctx context.Context, instance *supacontrolv1alpha1.SupabaseInstance) ([]string, error) {
+	studioURL, apiURL := r.instanceURLs(instance)
+	var changes []string
+	if instance.Status.StudioURL != studioURL {
+		changes = append(changes, fmt.Sprintf("Studio URL is now %s", studioURL))
+	}
+	if instance.Status.APIURL != apiURL {
+		changes = append(changes, fmt.Sprintf("API URL is now %s", apiURL))
+	}
+
+	ingressClass := r.ingressClass(instance)
+	tls := ingressTLSEnabled(instance)
+	provider := r.ingressLabels(instance)[supacontrolv1alpha1.LabelDNSProvider]
+	studioIngress, apiIngress := ingressNames(instance)
+	studioHost, apiHost := r.instanceHosts(instance)
+	studioBackend, apiBackend := ingressBackends(instance)
+	items := []struct {
+		name, host string
+		backend    networkingv1.IngressServiceBackend
+	}{{studioIngress, studioHost, studioBackend}, {apiIngress, apiHost, apiBackend}}
+	for _, item := range items {
+		name := item.name
+		ingress := &networkingv1.Ingress{}
+		err := r.Get(ctx, client.ObjectKey{Namespace: instance.Status.Namespace, Name: name}, ingress)
+		if apierrors.IsNotFound(err) {
+			changes = append(changes, fmt.Sprintf("ingress %s was recreated", name))
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get ingress %s: %w", name, err)
+		}
+		if current := ptr.Deref(ingress.Spec.IngressClassName, ""); current != ingressClass {
+			changes = append(changes, fmt.Sprintf("ingress %s moved from IngressClass %s to %s", name, current, ingressClass))
+		}
+		if !maps.Equal(ingress.Annotations, r.ingressAnnotations(instance, item.host)) {
+			changes = append(changes, fmt.Sprintf("annotations of ingress %s changed", name))
+		}
+		if current := ingress.Labels[supacontrolv1alpha1.LabelDNSProvider]; current != provider {
+			changes = append(changes, fmt.Sprintf("DNS provider of ingress %s changed", name))
+		}
+		if current := ingressBackend(ingress); current == nil || current.Name != item.backend.Name {
+			changes = append(changes, fmt.Sprintf("ingress %s routes to Service %s", name, item.backend.Name))
+		}
+		if current := len(ingress.Spec.TLS) > 0; current != tls {
+			state := "disabled"
+			if tls {
+				state = "enabled"
+			}
+			changes = append(changes, fmt.Sprintf("TLS %s on ingress %s", state, name))
+		}
+	}
+	return changes, nil
+}
+
+// updateIngresses brings a running instance's ingresses in line with its spec, publishes
+// its current URLs and records the changes as an event. The ingresses keep their names, so
+// the switch happens in place.
+func (r *SupabaseInstanceReconciler) updateIngresses(ctx context.Context, instance *supacontrolv1alpha1.SupabaseInstance, changes []string) (ctrl.Result, error) {
+	logger := ctrl.LoggerFrom(ctx)
+	logger.Info("Updating instance ingresses", "projectName", instance.Spec.ProjectName, "changes", changes)
+
+	if err := r.ensureIngresses(ctx, instance); err != nil {
+		return ctrl.Result{}, err
+	}
+	instance.Status.StudioURL, instance.Status.APIURL = r.instanceURLs(instance)
+	instance.Status.ObservedGeneration = instance.Generation
+
+	if err := r.updateStatus(ctx, instance); err != nil {
+		return ctrl.Result{}, err
+	}
+	if r.Recorder != nil {
+		r.Recorder.Event(instance, corev1.EventTypeNormal, "IngressUpdated", "Updated ingresses: "+strings.Join(changes, "; "))
+	}
+	return ctrl.Result{RequeueAfter: r.resyncInterval()}, nil
+}
+
+// startUpgrade creates an upgrade Job and transitions the instance to Upgrading. A resize
+// of the database goes through the same Job, which reports its progress in the Resizing
+// condition.
+func (r *SupabaseInstanceReconciler) startUpgrade(ctx context.Context, instance *supacontrolv1alpha1.SupabaseInstance) (ctrl.Result, error) {
+	logger := ctrl.LoggerFrom(ctx)
+	upgrading, resizing := needsUpgrade(instance), needsResize(instance)
+	if upgrading {
+		logger.Info("Starting chart upgrade", "projectName", instance.Spec.ProjectName,
+			"from", instance.Status.ChartVersion, "to", instance.Spec.ChartVersion)
+	}
+	if resizing {
+		logger.Info("Starting database resize", "projectName", instance.Spec.ProjectName,
+			"from", describeResources(instance.Status.Resources), "to", describeResources(instance.Spec.Resources))
+	}
+	if !upgrading && !resizing {
+		logger.Info("Reapplying shared service profiles", "projectName", instance.Spec.ProjectName)
+	}
+
+	job, err := r.createUpgradeJob(ctx, instance)
+	if err != nil {
+		// The release is untouched, so the instance keeps running on its current version
+		return r.finishUpgrade(ctx, instance, fmt.Sprintf("Failed to create upgrade Job: %v", err))
+	}
+
+	instance.Status.Phase = supacontrolv1alpha1.PhaseUpgrading
+	instance.Status.UpgradeJobName = job.Name
+	now := metav1.Now()
+	instance.Status.LastTransitionTime = &now
+
+	if upgrading {
+		meta.SetStatusCondition(&instance.Status.Conditions, metav1.Condition{
+			Type:               supacontrolv1alpha1.ConditionTypeUpgraded,
+			Status:             metav1.ConditionFalse,
+			ObservedGeneration: instance.Generation,
+			Reason:             "UpgradeInProgress",
+			Message:            fmt.Sprintf("Upgrading to chart version %s", instance.Spec.ChartVersion),
+		})
+	}
+	if resizing {
+		meta.SetStatusCondition(&instance.Status.Conditions, metav1.Condition{
+			Type:               supacontrolv1alpha1.ConditionTypeResizing,
+			Status:             metav1.ConditionTrue,
+			ObservedGeneration: instance.Generation,
+			Reason:             "ResizeInProgress",
+			Message: fmt.Sprintf("Resizing the database to %s: checkpointing, restarting and verifying Postgres (Job %s)",
+				describeResources(instance.Spec.Resources), job.Name),
+		})
+	}
+
+	if err := r.updateStatus(ctx, instance); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// Update metrics
+	metrics.SetInstanceStatus(instance.Spec.ProjectName, string(supacontrolv1alpha1.PhaseUpgrading), supacontrolv1alpha1.AllPhases())
+
+	return ctrl.Result{RequeueAfter: r.jobPollInterval()}, nil
+}
+
+// reconcileUpgrading monitors the upgrade Job
+func (r *SupabaseInstanceReconciler) reconcileUpgrading(ctx context.Context, instance *supacontrolv1alpha1.SupabaseInstance) (ctrl.Result, error) {
+	logger := ctrl.LoggerFrom(ctx)
+
+	jobName := instance.Status.UpgradeJobName
+	job, err := r.getJobStatus(ctx, jobName)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			logger.Error(err, "Upgrade Job not found", "jobName", jobName)
+			return r.finishUpgrade(ctx, instance, fmt.Sprintf("Upgrade Job '%s' not found", jobName))
+		}
+		return ctrl.Result{}, err
+	}
+
+	if isJobSucceeded(job) {
+		logger.Info("Upgrade Job succeeded", "jobName", jobName)
+		recordJobDuration(OperationUpgrade, "succeeded", job)
+		return r.finishUpgrade(ctx, instance, "")
+	}
+
+	if isJobFailed(job) {
+		recordJobDuration(OperationUpgrade, "failed", job)
+		errMsg := getJobConditionMessage(job)
+		if errMsg == "" {
+			errMsg = "Upgrade Job failed after retries"
+		}
+		logger.Error(errors.New(errMsg), "Upgrade Job failed", "jobName", jobName)
+		return r.finishUpgrade(ctx, instance, errMsg)
+	}
+
+	// Job still running, requeue
+	logger.V(1).Info("Upgrade Job still running", "jobName", jobName, "active", job.Status.Active)
+	return ctrl.Result{RequeueAfter: r.jobPollInterval()}, nil
+}
+
+// finishUpgrade records the outcome of an upgrade or resize and returns the instance to
+// Running. Failed upgrades are rolled back by Helm, so the instance keeps its previous chart
+// version and database resources.
+func (r *SupabaseInstanceReconciler) finishUpgrade(ctx context.Context, instance *supacontrolv1alpha1.SupabaseInstance, errMsg string) (ctrl.Result, error) {
+	resizing := needsResize(instance) || meta.IsStatusConditionTrue(instance.Status.Conditions, supacontrolv1alpha1.ConditionTypeResizing)
+	if resizing {
+		r.finishResize(instance, errMsg)
+	}
+	if !upgradeInProgress(instance) {
+		// The chart version was left alone, so there is no upgrade to report
+		if errMsg != "" && !resizing {
+			ctrl.LoggerFrom(ctx).Info("Failed to reapply shared service profiles", "projectName", instance.Spec.ProjectName, "error", errMsg)
+		}
+		return r.completeUpgrade(ctx, instance)
+	}
+
+	condition := metav1.Condition{
+		Type:               supacontrolv1alpha1.ConditionTypeUpgraded,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: instance.Generation,
+		Reason:             "UpgradeSucceeded",
+		Message:            fmt.Sprintf("Upgraded to chart version %s", instance.Spec.ChartVersion),
+	}
+	if errMsg != "" {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "UpgradeFailed"
+		condition.Message = errMsg
+		metrics.JobStatusTotal.WithLabelValues(OperationUpgrade, "failed").Inc()
+	} else {
+		instance.Status.ChartVersion = instance.Spec.ChartVersion
+		metrics.JobStatusTotal.WithLabelValues(OperationUpgrade, "succeeded").Inc()
+	}
+	meta.SetStatusCondition(&instance.Status.Conditions, condition)
+	return r.completeUpgrade(ctx, instance)
+}
+
+// finishResize records the outcome of a database resize in the Resizing condition
+func (r *SupabaseInstanceReconciler) finishResize(instance *supacontrolv1alpha1.SupabaseInstance, errMsg string) {
+	condition := metav1.Condition{
+		Type:               supacontrolv1alpha1.ConditionTypeResizing,
+		Status:             metav1.ConditionFalse,
+		ObservedGeneration: instance.Generation,
+		Reason:             "ResizeSucceeded",
+		Message:            fmt.Sprintf("Resized the database to %s", describeResources(instance.Spec.Resources)),
+	}
+	if errMsg != "" {
+		condition.Reason = "ResizeFailed"
+		condition.Message = errMsg
+	} else {
+		instance.Status.Resources = instance.Spec.Resources.DeepCopy()
+	}
+	meta.SetStatusCondition(&instance.Status.Conditions, condition)
+}
+
+// completeUpgrade returns the instance to Running once its upgrade Job has finished
+func (r *SupabaseInstanceReconciler) completeUpgrade(ctx context.Context, instance *supacontrolv1alpha1.SupabaseInstance) (ctrl.Result, error) {
+	instance.Status.Phase = supacontrolv1alpha1.PhaseRunning
+	now := metav1.Now()
+	instance.Status.LastTransitionTime = &now
+	// Mark the spec as handled so a failed upgrade is not retried until the spec changes again
+	instance.Status.ObservedGeneration = instance.Generation
+
+	if err := r.updateStatus(ctx, instance); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// Update metrics
+	metrics.SetInstanceStatus(instance.Spec.ProjectName, string(supacontrolv1alpha1.PhaseRunning), supacontrolv1alpha1.AllPhases())
+
+	return ctrl.Result{RequeueAfter: r.resyncInterval()}, nil
+}
+
+// reconcilePaused scales a provisioned instance's workloads to zero and transitions it to Stopped
+func (r *SupabaseInstanceReconciler) reconcilePaused(ctx context.Context, instance *supacontrolv1alpha1.SupabaseInstance) (ctrl.Result, error) {
+	logger := ctrl.LoggerFrom(ctx)
+
+	// Only running instances have workloads to scale; anything still provisioning
+	// (or failed) is left untouched until it is resumed
+	switch instance.Status.Phase {
+	case supacontrolv1alpha1.PhaseRunning, supacontrolv1alpha1.PhaseStopped, supacontrolv1alpha1.PhasePendingDeletion:
+	case supacontrolv1alpha1.PhaseSuspended:
+		// A lifted suspension leaves the instance paused if its owner had paused it
+		if err := r.markIngressesSuspended(ctx, instance, false); err != nil {
+			return ctrl.Result{}, err
+		}
+	default:
+		logger.Info("Reconciliation paused for instance", "projectName", instance.Spec.ProjectName)
+		return ctrl.Result{}, nil
+	}
+
+	scaled, err := r.scaleDownInstance(ctx, instance)
+	if err != nil {
+		logger.Error(err, "Failed to scale down workloads", "namespace", instance.Status.Namespace)
+		metrics.ReconciliationErrorsTotal.WithLabelValues(string(instance.Status.Phase)).Inc()
+		return ctrl.Result{}, err
+	}
+
+	if instance.Status.Phase != supacontrolv1alpha1.PhaseStopped {
+		logger.Info("Instance stopped", "projectName", instance.Spec.ProjectName, "workloadsScaled", scaled)
+		instance.Status.Phase = supacontrolv1alpha1.PhaseStopped
+		now := metav1.Now()
+		instance.Status.LastTransitionTime = &now
+
+		meta.SetStatusCondition(&instance.Status.Conditions, metav1.Condition{
+			Type:               supacontrolv1alpha1.ConditionTypeReady,
+			Status:             metav1.ConditionFalse,
+			ObservedGeneration: instance.Generation,
+			Reason:             "Stopped",
+			Message:            "Instance is stopped and its workloads are scaled to zero",
+		})
+		instance.Status.ObservedGeneration = instance.Generation
+
+		if err := r.updateStatus(ctx, instance); err != nil {
+			return ctrl.Result{}, err
+		}
+
+		// Update metrics
+		metrics.SetInstanceStatus(instance.Spec.ProjectName, string(supacontrolv1alpha1.PhaseStopped), supacontrolv1alpha1.AllPhases())
+	}
+
+	// Requeue periodically so workloads scaled up out-of-band are stopped again
+	return ctrl.Result{RequeueAfter: r.resyncInterval()}, nil
+}
+
+// reconcileStopped resumes a stopped, suspended or trashed instance once it is no longer
+// paused, suspended or pending deletion
+func (r *SupabaseInstanceReconciler) reconcileStopped(ctx context.Context, instance *supacontrolv1alpha1.SupabaseInstance) (ctrl.Result, error) {
+	logger := ctrl.LoggerFrom(ctx)
+
+	if instance.Status.Phase == supacontrolv1alpha1.PhaseSuspended {
+		if err := r.markIngressesSuspended(ctx, instance, false); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	scaled, err := r.scaleUpInstance(ctx, instance)
+	if err != nil {
+		logger.Error(err, "Failed to scale up workloads", "namespace", instance.Status.Namespace)
+		metrics.ReconciliationErrorsTotal.WithLabelValues(string(instance.Status.Phase)).Inc()
+		return ctrl.Result{}, err
+	}
+
+	logger.Info("Instance resumed", "projectName", instance.Spec.ProjectName, "workloadsScaled", scaled)
+	instance.Status.Phase = supacontrolv1alpha1.PhaseRunning
+	now := metav1.Now()
+	instance.Status.LastTransitionTime = &now
+
+	meta.SetStatusCondition(&instance.Status.Conditions, metav1.Condition{
+		Type:               supacontrolv1alpha1.ConditionTypeReady,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: instance.Generation,
+		Reason:             "Resumed",
+		Message:            "Instance resumed and its workloads are scaled back up",
+	})
+	instance.Status.ObservedGeneration = instance.Generation
+
+	if err := r.updateStatus(ctx, instance); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// Update metrics
+	metrics.SetInstanceStatus(instance.Spec.ProjectName, string(supacontrolv1alpha1.PhaseRunning), supacontrolv1alpha1.AllPhases())
+
+	return ctrl.Result{RequeueAfter: r.resyncInterval()}, nil
 }
 
 // reconcileFailed handles the failed phase
 func (r *SupabaseInstanceReconciler) reconcileFailed(ctx context.Context, instance *supacontrolv1alpha1.SupabaseInstance) (ctrl.Result, error) {
-	// In a production operator, you might:
-	// 1. Implement retry logic with backoff
-	// 2. Alert/notify about the failure
-	// 3. Attempt automatic remediation
-	//
-	// For now, we'll just log and wait
 	logger := ctrl.LoggerFrom(ctx)
-	logger.Info("Instance in failed state", "projectName", instance.Spec.ProjectName, "error", instance.Status.ErrorMessage)
 
-	// Requeue after a delay to allow manual intervention
-	return ctrl.Result{RequeueAfter: 10 * time.Minute}, nil
+	limit := autoRetryLimit(instance, instance.Status.FailureType)
+	if instance.Status.RetryCount >= limit {
+		logger.Info("Instance in failed state", "projectName", instance.Spec.ProjectName,
+			"failureType", instance.Status.FailureType, "error", instance.Status.ErrorMessage)
+
+		// Requeue after a delay to allow manual intervention
+		return ctrl.Result{RequeueAfter: r.failedRequeueInterval()}, nil
+	}
+
+	// Back off until the retry scheduled when the instance failed, or from the time it
+	// failed for instances that failed before retries were scheduled
+	retryAt := instance.Status.NextRetryAt
+	if retryAt == nil && instance.Status.LastTransitionTime != nil {
+		retryAt = &metav1.Time{Time: instance.Status.LastTransitionTime.Add(autoRetryDelay(instance.Status.RetryCount))}
+	}
+	if retryAt != nil {
+		if wait := time.Until(retryAt.Time); wait > 0 {
+			logger.Info("Waiting to retry failed provisioning", "projectName", instance.Spec.ProjectName,
+				"attempt", instance.Status.RetryCount+1, "wait", wait)
+			return ctrl.Result{RequeueAfter: wait}, nil
+		}
+	}
+
+	instance.Status.RetryCount++
+	return r.retryProvisioning(ctx, instance, "AutoRetry",
+		fmt.Sprintf("Automatic retry %d of %d", instance.Status.RetryCount, limit))
+}
+
+// autoRetryAttempts returns the number of automatic retries a policy allows
+func autoRetryAttempts(policy *supacontrolv1alpha1.AutoRetryPolicy) int32 {
+	if policy.MaxAttempts > 0 {
+		return policy.MaxAttempts
+	}
+	return DefaultAutoRetryAttempts
+}
+
+// autoRetryDelay returns how long to wait before the automatic retry following the given
+// number of earlier retries: the base delay doubled per retry, capped at the maximum
+func autoRetryDelay(retries int32) time.Duration {
+	delay := AutoRetryBaseDelay
+	for i := int32(0); i < retries && delay < AutoRetryMaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, AutoRetryMaxDelay)
+}
+
+// retryProvisioning clears the failure state and moves the instance back to Pending, where
+// the provisioning Job of the failed attempt is replaced by a new one
+func (r *SupabaseInstanceReconciler) retryProvisioning(ctx context.Context, instance *supacontrolv1alpha1.SupabaseInstance, reason, message string) (ctrl.Result, error) {
+	logger := ctrl.LoggerFrom(ctx)
+	logger.Info("Retrying provisioning", "projectName", instance.Spec.ProjectName, "reason", reason)
+
+	instance.Status.Phase = supacontrolv1alpha1.PhasePending
+	instance.Status.ErrorMessage = ""
+	instance.Status.FailureType = ""
+	instance.Status.NextRetryAt = nil
+	instance.Status.ProvisioningJobName = ""
+	now := metav1.Now()
+	instance.Status.LastTransitionTime = &now
+
+	meta.SetStatusCondition(&instance.Status.Conditions, metav1.Condition{
+		Type:               supacontrolv1alpha1.ConditionTypeReady,
+		Status:             metav1.ConditionFalse,
+		ObservedGeneration: instance.Generation,
+		Reason:             reason,
+		Message:            message,
+	})
+
+	if err := r.updateStatus(ctx, instance); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// Update metrics
+	metrics.SetInstanceStatus(instance.Spec.ProjectName, string(supacontrolv1alpha1.PhasePending), supacontrolv1alpha1.AllPhases())
+
+	return ctrl.Result{RequeueAfter: time.Second}, nil
 }
 
 // reconcileDelete handles deletion with cleanup using a Job
@@ -383,7 +1169,7 @@ func (r *SupabaseInstanceReconciler) reconcileDelete(ctx context.Context, instan
 			instance.Status.Phase = supacontrolv1alpha1.PhaseDeleting
 			now := metav1.Now()
 			instance.Status.LastTransitionTime = &now
-			if err := r.Status().Update(ctx, instance); err != nil {
+			if err := r.updateStatus(ctx, instance); err != nil {
 				return ctrl.Result{}, err
 			}
 			// Update metrics for Deleting phase
@@ -396,6 +1182,14 @@ func (r *SupabaseInstanceReconciler) reconcileDelete(ctx context.Context, instan
 			return ctrl.Result{RequeueAfter: 30 * time.Second}, err
 		}
 
+		// Free the instance's dedicated node once its workloads are gone
+		if isDedicated(instance) || instance.Status.DedicatedNode != "" {
+			if err := r.releaseDedicatedNode(ctx, instance); err != nil {
+				logger.Error(err, "Failed to release dedicated node")
+				return ctrl.Result{RequeueAfter: 30 * time.Second}, err
+			}
+		}
+
 		// Remove finalizer after cleanup complete
 		controllerutil.RemoveFinalizer(instance, FinalizerName)
 		if err := r.Update(ctx, instance); err != nil {
@@ -414,6 +1208,12 @@ func (r *SupabaseInstanceReconciler) reconcileDelete(ctx context.Context, instan
 func (r *SupabaseInstanceReconciler) cleanupViaJob(ctx context.Context, instance *supacontrolv1alpha1.SupabaseInstance) error {
 	logger := ctrl.LoggerFrom(ctx)
 
+	// An import that never adopted its release leaves the namespace it named alone
+	if instance.Spec.Adoption != nil && instance.Status.Namespace == "" {
+		logger.Info("Release was never adopted, skipping cleanup", "projectName", instance.Spec.ProjectName)
+		return nil
+	}
+
 	// Check if cleanup Job already exists
 	jobName := instance.Status.CleanupJobName
 	if jobName == "" {
@@ -426,7 +1226,7 @@ func (r *SupabaseInstanceReconciler) cleanupViaJob(ctx context.Context, instance
 		instance.Status.Phase = supacontrolv1alpha1.PhaseDeletingInProgress
 		now := metav1.Now()
 		instance.Status.LastTransitionTime = &now
-		if err := r.Status().Update(ctx, instance); err != nil {
+		if err := r.updateStatus(ctx, instance); err != nil {
 			return err
 		}
 		logger.Info("Created cleanup Job", "jobName", job.Name)
@@ -448,6 +1248,7 @@ func (r *SupabaseInstanceReconciler) cleanupViaJob(ctx context.Context, instance
 	if isJobSucceeded(job) {
 		logger.Info("Cleanup Job succeeded", "jobName", jobName)
 		metrics.JobStatusTotal.WithLabelValues("cleanup", "succeeded").Inc()
+		recordJobDuration(OperationCleanup, "succeeded", job)
 		return nil
 	}
 
@@ -456,6 +1257,7 @@ func (r *SupabaseInstanceReconciler) cleanupViaJob(ctx context.Context, instance
 		errMsg := getJobConditionMessage(job)
 		logger.Error(errors.New(errMsg), "Cleanup Job failed", "jobName", jobName)
 		metrics.JobStatusTotal.WithLabelValues("cleanup", "failed").Inc()
+		recordJobDuration(OperationCleanup, "failed", job)
 		// Don't block deletion on cleanup failure, just log it
 		return nil
 	}
@@ -466,7 +1268,7 @@ func (r *SupabaseInstanceReconciler) cleanupViaJob(ctx context.Context, instance
 		instance.Status.Phase = supacontrolv1alpha1.PhaseDeletingInProgress
 		now := metav1.Now()
 		instance.Status.LastTransitionTime = &now
-		if err := r.Status().Update(ctx, instance); err != nil {
+		if err := r.updateStatus(ctx, instance); err != nil {
 			return err
 		}
 		// Update metrics for DeletingInProgress phase
@@ -482,30 +1284,30 @@ func (r *SupabaseInstanceReconciler) cleanupViaJob(ctx context.Context, instance
 func (r *SupabaseInstanceReconciler) ensureIngresses(ctx context.Context, instance *supacontrolv1alpha1.SupabaseInstance) error {
 	logger := ctrl.LoggerFrom(ctx)
 	namespace := instance.Status.Namespace
-	releaseName := instance.Status.HelmReleaseName
 
-	ingressClass := r.DefaultIngressClass
-	if instance.Spec.IngressClass != "" {
-		ingressClass = instance.Spec.IngressClass
-	}
+	ingressClass := r.ingressClass(instance)
+	studioHost, apiHost := r.instanceHosts(instance)
+	studioIngress, apiIngress := ingressNames(instance)
+	studioBackend, apiBackend := ingressBackends(instance)
 
-	ingressDomain := r.DefaultIngressDomain
-	if instance.Spec.IngressDomain != "" {
-		ingressDomain = instance.Spec.IngressDomain
-	}
+	var errs []error
 
 	// Create Studio ingress
-	studioHost := fmt.Sprintf("%s-studio.%s", instance.Spec.ProjectName, ingressDomain)
-	if err := r.createIngress(ctx, namespace, fmt.Sprintf("%s-studio-ingress", instance.Spec.ProjectName),
-		studioHost, fmt.Sprintf("%s-studio", releaseName), 3000, ingressClass, instance); err != nil {
+	if err := r.createIngress(ctx, namespace, studioIngress,
+		studioHost, studioBackend, ingressClass, instance); err != nil {
 		logger.Error(err, "Failed to create Studio ingress")
+		errs = append(errs, err)
 	}
 
 	// Create API ingress
-	apiHost := fmt.Sprintf("%s-api.%s", instance.Spec.ProjectName, ingressDomain)
-	if err := r.createIngress(ctx, namespace, fmt.Sprintf("%s-api-ingress", instance.Spec.ProjectName),
-		apiHost, fmt.Sprintf("%s-kong", releaseName), 8000, ingressClass, instance); err != nil {
+	if err := r.createIngress(ctx, namespace, apiIngress,
+		apiHost, apiBackend, ingressClass, instance); err != nil {
 		logger.Error(err, "Failed to create API ingress")
+		errs = append(errs, err)
+	}
+
+	if len(errs) > 0 {
+		return errors.Join(errs...)
 	}
 
 	logger.Info("Created ingresses", "namespace", namespace)
@@ -520,43 +1322,115 @@ func (r *SupabaseInstanceReconciler) ensureIngresses(ctx context.Context, instan
 	return nil
 }
 
-// createIngress creates an ingress resource
-func (r *SupabaseInstanceReconciler) createIngress(ctx context.Context, namespace, name, host, serviceName string, port int32, ingressClass string, instance *supacontrolv1alpha1.SupabaseInstance) error {
+// ingressNames returns the names of the Studio and API ingresses of an instance
+func ingressNames(instance *supacontrolv1alpha1.SupabaseInstance) (studioIngress, apiIngress string) {
+	return fmt.Sprintf("%s-studio-ingress", instance.Spec.ProjectName), fmt.Sprintf("%s-api-ingress", instance.Spec.ProjectName)
+}
+
+// ingressClass returns the IngressClass of an instance's ingresses
+func (r *SupabaseInstanceReconciler) ingressClass(instance *supacontrolv1alpha1.SupabaseInstance) string {
+	if instance.Spec.IngressClass != "" {
+		return instance.Spec.IngressClass
+	}
+	return r.DefaultIngressClass
+}
+
+// instanceHosts returns the Studio and API hostnames of an instance: its custom domains
+// where set, otherwise <projectName>-studio and <projectName>-api under the ingress domain
+func (r *SupabaseInstanceReconciler) instanceHosts(instance *supacontrolv1alpha1.SupabaseInstance) (studioHost, apiHost string) {
+	ingressDomain := r.DefaultIngressDomain
+	if instance.Spec.IngressDomain != "" {
+		ingressDomain = instance.Spec.IngressDomain
+	}
+	studioHost = fmt.Sprintf("%s-studio.%s", instance.Spec.ProjectName, ingressDomain)
+	apiHost = fmt.Sprintf("%s-api.%s", instance.Spec.ProjectName, ingressDomain)
+
+	if domains := instance.Spec.CustomDomains; domains != nil {
+		if domains.Studio != "" {
+			studioHost = domains.Studio
+		}
+		if domains.API != "" {
+			apiHost = domains.API
+		}
+	}
+	return studioHost, apiHost
+}
+
+// ingressTLSEnabled reports whether an instance's ingresses serve TLS
+func ingressTLSEnabled(instance *supacontrolv1alpha1.SupabaseInstance) bool {
+	return instance.Spec.Ingress == nil || !instance.Spec.Ingress.DisableTLS
+}
+
+// instanceURLs returns the Studio and API URLs of an instance, which are plain HTTP when
+// its TLS is disabled
+func (r *SupabaseInstanceReconciler) instanceURLs(instance *supacontrolv1alpha1.SupabaseInstance) (studioURL, apiURL string) {
+	scheme := "https://"
+	if !ingressTLSEnabled(instance) {
+		scheme = "http://"
+	}
+	studioHost, apiHost := r.instanceHosts(instance)
+	return scheme + studioHost, scheme + apiHost
+}
+
+// tlsIssuer returns the cert-manager ClusterIssuer of an instance's certificates, or empty
+// when it requests none
+func (r *SupabaseInstanceReconciler) tlsIssuer(instance *supacontrolv1alpha1.SupabaseInstance) string {
+	if !ingressTLSEnabled(instance) {
+		return ""
+	}
+	if instance.Spec.Ingress != nil && instance.Spec.Ingress.TLSIssuer != "" {
+		return instance.Spec.Ingress.TLSIssuer
+	}
+	return r.CertManagerIssuer
+}
+
+// ingressAnnotations returns the annotations of an instance's ingress serving host: the
+// cert-manager issuer of its certificates and the external-dns annotations, overridden by
+// the annotations in its spec
+func (r *SupabaseInstanceReconciler) ingressAnnotations(instance *supacontrolv1alpha1.SupabaseInstance, host string) map[string]string {
+	annotations := map[string]string{}
+	if issuer := r.tlsIssuer(instance); issuer != "" {
+		annotations["cert-manager.io/cluster-issuer"] = issuer
+	}
+	maps.Copy(annotations, r.externalDNSAnnotations(instance, host))
+	if instance.Spec.Ingress != nil {
+		maps.Copy(annotations, instance.Spec.Ingress.Annotations)
+	}
+	return annotations
+}
+
+// createIngress creates an ingress resource, or points an existing one at the given host
+// and backend. cert-manager reissues the TLS certificate whenever the host changes.
+func (r *SupabaseInstanceReconciler) createIngress(ctx context.Context, namespace, name, host string, backend networkingv1.IngressServiceBackend, ingressClass string, instance *supacontrolv1alpha1.SupabaseInstance) error {
 	pathTypePrefix := networkingv1.PathTypePrefix
 
 	ingress := &networkingv1.Ingress{}
 	ingress.Namespace = namespace
 	ingress.Name = name
-	ingress.Labels = map[string]string{
-		"app.kubernetes.io/managed-by": "supacontrol",
-		"supacontrol.io/instance":      instance.Spec.ProjectName,
-	}
-	ingress.Annotations = map[string]string{
-		"cert-manager.io/cluster-issuer": r.CertManagerIssuer,
-	}
-	ingress.Spec = networkingv1.IngressSpec{
-		IngressClassName: &ingressClass,
-		TLS: []networkingv1.IngressTLS{
-			{
+	_, err := controllerutil.CreateOrUpdate(ctx, r.Client, ingress, func() error {
+		ingress.Labels = r.ingressLabels(instance)
+		ingress.Annotations = r.ingressAnnotations(instance, host)
+		var tls []networkingv1.IngressTLS
+		if ingressTLSEnabled(instance) {
+			tls = []networkingv1.IngressTLS{{
 				Hosts:      []string{host},
 				SecretName: fmt.Sprintf("%s-tls", name),
-			},
-		},
-		Rules: []networkingv1.IngressRule{
-			{
-				Host: host,
-				IngressRuleValue: networkingv1.IngressRuleValue{
-					HTTP: &networkingv1.HTTPIngressRuleValue{
-						Paths: []networkingv1.HTTPIngressPath{
-							{
-								Path:     "/",
-								PathType: &pathTypePrefix,
-								Backend: networkingv1.IngressBackend{
-									Service: &networkingv1.IngressServiceBackend{
-										Name: serviceName,
-										Port: networkingv1.ServiceBackendPort{
-											Number: port,
-										},
+			}}
+		}
+		ingress.Spec = networkingv1.IngressSpec{
+			IngressClassName: &ingressClass,
+			TLS:              tls,
+			Rules: []networkingv1.IngressRule{
+				{
+					Host: host,
+					IngressRuleValue: networkingv1.IngressRuleValue{
+						HTTP: &networkingv1.HTTPIngressRuleValue{
+							Paths: []networkingv1.HTTPIngressPath{
+								{
+									Path:     "/",
+									PathType: &pathTypePrefix,
+									Backend: networkingv1.IngressBackend{
+										Service: &backend,
 									},
 								},
 							},
@@ -564,17 +1438,10 @@ func (r *SupabaseInstanceReconciler) createIngress(ctx context.Context, namespac
 					},
 				},
 			},
-		},
-	}
-
-	if err := r.Create(ctx, ingress); err != nil {
-		if apierrors.IsAlreadyExists(err) {
-			return nil
 		}
-		return err
-	}
-
-	return nil
+		return nil
+	})
+	return err
 }
 
 func (r *SupabaseInstanceReconciler) transitionToFailed(ctx context.Context, instance *supacontrolv1alpha1.SupabaseInstance, errorMsg string) (ctrl.Result, error) {
@@ -583,9 +1450,18 @@ func (r *SupabaseInstanceReconciler) transitionToFailed(ctx context.Context, ins
 
 	instance.Status.Phase = supacontrolv1alpha1.PhaseFailed
 	instance.Status.ErrorMessage = errorMsg
+	instance.Status.FailureType = classifyFailure(errorMsg)
 	now := metav1.Now()
 	instance.Status.LastTransitionTime = &now
 
+	// Schedule the next automatic retry, if the failure has any left
+	instance.Status.NextRetryAt = nil
+	requeueAfter := r.failedRequeueInterval()
+	if instance.Status.RetryCount < autoRetryLimit(instance, instance.Status.FailureType) {
+		requeueAfter = jitterRetryDelay(autoRetryDelay(instance.Status.RetryCount))
+		instance.Status.NextRetryAt = &metav1.Time{Time: now.Add(requeueAfter)}
+	}
+
 	meta.SetStatusCondition(&instance.Status.Conditions, metav1.Condition{
 		Type:               supacontrolv1alpha1.ConditionTypeReady,
 		Status:             metav1.ConditionFalse,
@@ -594,7 +1470,7 @@ func (r *SupabaseInstanceReconciler) transitionToFailed(ctx context.Context, ins
 		Message:            errorMsg,
 	})
 
-	if err := r.Status().Update(ctx, instance); err != nil {
+	if err := r.updateStatus(ctx, instance); err != nil {
 		return ctrl.Result{}, err
 	}
 
@@ -602,8 +1478,8 @@ func (r *SupabaseInstanceReconciler) transitionToFailed(ctx context.Context, ins
 	metrics.SetInstanceStatus(instance.Spec.ProjectName, string(supacontrolv1alpha1.PhaseFailed), supacontrolv1alpha1.AllPhases())
 	metrics.JobStatusTotal.WithLabelValues("provision", "failed").Inc()
 
-	// Requeue with delay for periodic monitoring of failed state
-	return ctrl.Result{RequeueAfter: 10 * time.Minute}, nil
+	// Requeue for the automatic retry, or with a delay for periodic monitoring of failed state
+	return ctrl.Result{RequeueAfter: requeueAfter}, nil
 }
 
 // SetupWithManager sets up the controller with the Manager
@@ -611,11 +1487,57 @@ func (r *SupabaseInstanceReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	// Initialize the logger
 	ctrl.SetLogger(zap.New(zap.UseDevMode(true)))
 
-	return ctrl.NewControllerManagedBy(mgr).
+	controllerBuilder := ctrl.NewControllerManagedBy(mgr).
+		WithOptions(controller.Options{
+			MaxConcurrentReconciles: r.maxConcurrentReconciles(),
+			RateLimiter:             r.RateLimiter,
+		}).
 		For(&supacontrolv1alpha1.SupabaseInstance{}).
 		Owns(&batchv1.Job{}).
-		Owns(&corev1.Namespace{}).
 		Owns(&corev1.Secret{}).
-		Owns(&networkingv1.Ingress{}).
-		Complete(r)
+		// Instance namespaces, Secrets and Helm releases are created by provisioning Jobs,
+		// so they are mapped to their instance to notice when they are removed or rotated
+		Watches(&corev1.Namespace{}, handler.EnqueueRequestsFromMapFunc(r.instanceForNamespace)).
+		Watches(&corev1.Secret{}, handler.EnqueueRequestsFromMapFunc(r.instanceForNamespace),
+			builder.WithPredicates(predicate.NewPredicateFuncs(isWatchedSecret))).
+		Owns(&networkingv1.NetworkPolicy{}).
+		// Instance ingresses are labeled rather than owned, and their status reports
+		// whether an ingress controller admitted them
+		Watches(&networkingv1.Ingress{}, handler.EnqueueRequestsFromMapFunc(instanceForLabeledObject))
+
+	// Certificates are only watched when cert-manager is installed; otherwise the watch
+	// would keep the controller from starting
+	_, err := mgr.GetRESTMapper().RESTMapping(certificateGVK.GroupKind(), certificateGVK.Version)
+	switch {
+	case err == nil:
+		certificate := &unstructured.Unstructured{}
+		certificate.SetGroupVersionKind(certificateGVK)
+		controllerBuilder = controllerBuilder.Watches(certificate, handler.EnqueueRequestsFromMapFunc(instanceForLabeledObject))
+	case meta.IsNoMatchError(err):
+		mgr.GetLogger().Info("cert-manager is not installed, certificate issuance is not watched")
+	default:
+		return fmt.Errorf("failed to look up the cert-manager Certificate API: %w", err)
+	}
+
+	// DNSEndpoints are only watched when they are written; external-dns reports in their
+	// status when it has published them
+	if r.ExternalDNSMode == ExternalDNSModeDNSEndpoint {
+		_, err := mgr.GetRESTMapper().RESTMapping(dnsEndpointGVK.GroupKind(), dnsEndpointGVK.Version)
+		switch {
+		case err == nil:
+			endpoint := &unstructured.Unstructured{}
+			endpoint.SetGroupVersionKind(dnsEndpointGVK)
+			controllerBuilder = controllerBuilder.Watches(endpoint, handler.EnqueueRequestsFromMapFunc(instanceForLabeledObject))
+		case meta.IsNoMatchError(err):
+			mgr.GetLogger().Info("external-dns' DNSEndpoint CRD is not installed, DNS publication is not watched")
+		default:
+			return fmt.Errorf("failed to look up the external-dns DNSEndpoint API: %w", err)
+		}
+	}
+
+	if err := registerPhaseCollector(mgr.GetClient()); err != nil {
+		return fmt.Errorf("failed to register the instance phase metrics: %w", err)
+	}
+
+	return controllerBuilder.Complete(r)
 }
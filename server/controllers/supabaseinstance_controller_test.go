@@ -1,20 +1,55 @@
 package controllers
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
 	"fmt"
+	"math/big"
+	"reflect"
+	"slices"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/release"
+	appsv1 "k8s.io/api/apps/v1"
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	nodev1 "k8s.io/api/node/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 
 	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+	"github.com/qubitquilt/supacontrol/server/internal/metrics"
+	"github.com/qubitquilt/supacontrol/server/internal/notify"
+	"github.com/qubitquilt/supacontrol/server/internal/profiles"
 )
 
 // TestReconcilePending_CreatesProvisioningJob tests that reconciling a Pending instance creates a provisioning Job
@@ -459,6 +494,48 @@ func TestReconcileDelete_CreatesCleanupJob(t *testing.T) {
 	}
 }
 
+// TestReconcileDelete_PausedInstance tests that deleting an instance that has been paused
+// since its creation still runs cleanup instead of dropping the instance unprocessed
+func TestReconcileDelete_PausedInstance(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	reconciler := createTestReconciler()
+
+	instance := createBasicInstance(t.Name())
+	instance.Spec.Paused = true
+	if err := k8sClient.Create(ctx, instance); err != nil {
+		t.Fatalf("Failed to create test instance: %v", err)
+	}
+
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: instance.Name}}
+	if _, err := reconciler.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+
+	current := getInstanceState(ctx, t, instance.Name)
+	if current == nil {
+		t.Fatal("Instance not found")
+	}
+	if !controllerutil.ContainsFinalizer(current, FinalizerName) {
+		t.Fatalf("Expected paused instance to get finalizer %s, got %v", FinalizerName, current.Finalizers)
+	}
+
+	if err := k8sClient.Delete(ctx, current); err != nil {
+		t.Fatalf("Failed to delete instance: %v", err)
+	}
+	if _, err := reconciler.Reconcile(ctx, req); err == nil {
+		t.Error("Expected error indicating cleanup in progress")
+	}
+
+	current = getInstanceState(ctx, t, instance.Name)
+	if current == nil {
+		t.Fatal("Instance should still exist during cleanup")
+	}
+	if current.Status.CleanupJobName == "" {
+		t.Error("Expected a cleanup Job for the paused instance")
+	}
+}
+
 // TestCleanupViaJob_TransitionsToDeletingInProgress tests cleanup Job state transitions
 func TestCleanupViaJob_TransitionsToDeletingInProgress(t *testing.T) {
 	t.Parallel()
@@ -836,8 +913,3837 @@ func TestReconcileRunning_PeriodicHealthChecks(t *testing.T) {
 		t.Error("Expected periodic requeue for Running instance health checks")
 	}
 
-	expectedRequeue := 5 * time.Minute
+	expectedRequeue := DefaultResyncInterval
 	if result.RequeueAfter != expectedRequeue {
 		t.Errorf("Expected requeue after %v, got %v", expectedRequeue, result.RequeueAfter)
 	}
 }
+
+// TestReconcilePaused_ScalesWorkloadsToZero tests that pausing a Running instance scales its
+// workloads to zero and that resuming restores the original replica counts
+func TestReconcilePaused_ScalesWorkloadsToZero(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	reconciler := createTestReconciler()
+
+	// Create and transition instance to Running
+	instance := createBasicInstance(t.Name())
+	err := k8sClient.Create(ctx, instance)
+	if err != nil {
+		t.Fatalf("Failed to create test instance: %v", err)
+	}
+	defer cleanupInstance(ctx, t, instance)
+
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: instance.Name}}
+	reconcileToPending(ctx, t, reconciler, instance.Name)
+	reconcileToProvisioning(ctx, t, reconciler, instance.Name)
+
+	current := getInstanceState(ctx, t, instance.Name)
+	if current != nil && current.Status.ProvisioningJobName != "" {
+		setJobSucceeded(ctx, t, current.Status.ProvisioningJobName)
+	}
+	if _, err := reconciler.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Failed to reconcile Running state: %v", err)
+	}
+
+	current = getInstanceState(ctx, t, instance.Name)
+	if current == nil || current.Status.Phase != supacontrolv1alpha1.PhaseRunning {
+		t.Fatalf("Instance not in Running phase")
+	}
+
+	// Create a workload the Helm release would normally own
+	labels := map[string]string{"app": "kong"}
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "kong",
+			Namespace: current.Status.Namespace,
+		},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: ptr.To[int32](2),
+			Selector: &metav1.LabelSelector{MatchLabels: labels},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{Name: "kong", Image: "kong:2.8.1"}},
+				},
+			},
+		},
+	}
+	if err := k8sClient.Create(ctx, deployment); err != nil {
+		t.Fatalf("Failed to create deployment: %v", err)
+	}
+
+	// Pause the instance
+	current.Spec.Paused = true
+	if err := k8sClient.Update(ctx, current); err != nil {
+		t.Fatalf("Failed to pause instance: %v", err)
+	}
+	if _, err := reconciler.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile paused instance failed: %v", err)
+	}
+
+	current = getInstanceState(ctx, t, instance.Name)
+	if current.Status.Phase != supacontrolv1alpha1.PhaseStopped {
+		t.Errorf("Expected phase Stopped, got %s", current.Status.Phase)
+	}
+
+	scaled := &appsv1.Deployment{}
+	if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(deployment), scaled); err != nil {
+		t.Fatalf("Failed to get deployment: %v", err)
+	}
+	if scaled.Spec.Replicas == nil || *scaled.Spec.Replicas != 0 {
+		t.Errorf("Expected deployment scaled to 0, got %v", scaled.Spec.Replicas)
+	}
+	if scaled.Annotations[AnnotationPausedReplicas] != "2" {
+		t.Errorf("Expected paused-replicas annotation '2', got %q", scaled.Annotations[AnnotationPausedReplicas])
+	}
+
+	// Resume the instance
+	current.Spec.Paused = false
+	if err := k8sClient.Update(ctx, current); err != nil {
+		t.Fatalf("Failed to resume instance: %v", err)
+	}
+	if _, err := reconciler.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile resumed instance failed: %v", err)
+	}
+
+	current = getInstanceState(ctx, t, instance.Name)
+	if current.Status.Phase != supacontrolv1alpha1.PhaseRunning {
+		t.Errorf("Expected phase Running after resume, got %s", current.Status.Phase)
+	}
+
+	if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(deployment), scaled); err != nil {
+		t.Fatalf("Failed to get deployment: %v", err)
+	}
+	if scaled.Spec.Replicas == nil || *scaled.Spec.Replicas != 2 {
+		t.Errorf("Expected deployment scaled back to 2, got %v", scaled.Spec.Replicas)
+	}
+	if _, ok := scaled.Annotations[AnnotationPausedReplicas]; ok {
+		t.Error("Expected paused-replicas annotation to be removed")
+	}
+}
+
+// TestReconcileSuspended_MarksIngresses tests that suspending a Running instance moves it
+// to Suspended and redirects its ingresses, and that lifting the suspension restores both
+func TestReconcileSuspended_MarksIngresses(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	reconciler := createTestReconciler()
+	reconciler.SuspendedPageURL = "https://supacontrol.example.com/suspended"
+
+	instance := createBasicInstance(t.Name())
+	if err := k8sClient.Create(ctx, instance); err != nil {
+		t.Fatalf("Failed to create test instance: %v", err)
+	}
+	defer cleanupInstance(ctx, t, instance)
+
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: instance.Name}}
+	reconcileToPending(ctx, t, reconciler, instance.Name)
+	reconcileToProvisioning(ctx, t, reconciler, instance.Name)
+
+	current := getInstanceState(ctx, t, instance.Name)
+	if current != nil && current.Status.ProvisioningJobName != "" {
+		setJobSucceeded(ctx, t, current.Status.ProvisioningJobName)
+	}
+	if _, err := reconciler.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Failed to reconcile Running state: %v", err)
+	}
+	current = getInstanceState(ctx, t, instance.Name)
+	if current == nil || current.Status.Phase != supacontrolv1alpha1.PhaseRunning {
+		t.Fatalf("Instance not in Running phase")
+	}
+
+	ingress := &networkingv1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "kong",
+			Namespace: current.Status.Namespace,
+		},
+		Spec: networkingv1.IngressSpec{
+			DefaultBackend: &networkingv1.IngressBackend{
+				Service: &networkingv1.IngressServiceBackend{
+					Name: "kong",
+					Port: networkingv1.ServiceBackendPort{Number: 8000},
+				},
+			},
+		},
+	}
+	if err := k8sClient.Create(ctx, ingress); err != nil {
+		t.Fatalf("Failed to create ingress: %v", err)
+	}
+
+	// Suspend the instance
+	current.Spec.Suspension = &supacontrolv1alpha1.Suspension{
+		Reason:      supacontrolv1alpha1.SuspensionReasonBilling,
+		SuspendedAt: metav1.Now(),
+	}
+	if err := k8sClient.Update(ctx, current); err != nil {
+		t.Fatalf("Failed to suspend instance: %v", err)
+	}
+	if _, err := reconciler.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile suspended instance failed: %v", err)
+	}
+
+	current = getInstanceState(ctx, t, instance.Name)
+	if current.Status.Phase != supacontrolv1alpha1.PhaseSuspended {
+		t.Errorf("Expected phase Suspended, got %s", current.Status.Phase)
+	}
+	marked := &networkingv1.Ingress{}
+	if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(ingress), marked); err != nil {
+		t.Fatalf("Failed to get ingress: %v", err)
+	}
+	if marked.Labels[supacontrolv1alpha1.LabelSuspended] != "billing" {
+		t.Errorf("Expected suspended label 'billing', got %q", marked.Labels[supacontrolv1alpha1.LabelSuspended])
+	}
+	if !strings.HasPrefix(marked.Annotations[suspendedRedirectAnnotation], reconciler.SuspendedPageURL) {
+		t.Errorf("Expected redirect to the suspended page, got %q", marked.Annotations[suspendedRedirectAnnotation])
+	}
+
+	// Lift the suspension
+	current.Spec.Suspension = nil
+	if err := k8sClient.Update(ctx, current); err != nil {
+		t.Fatalf("Failed to lift suspension: %v", err)
+	}
+	if _, err := reconciler.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile unsuspended instance failed: %v", err)
+	}
+
+	current = getInstanceState(ctx, t, instance.Name)
+	if current.Status.Phase != supacontrolv1alpha1.PhaseRunning {
+		t.Errorf("Expected phase Running after the suspension is lifted, got %s", current.Status.Phase)
+	}
+	if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(ingress), marked); err != nil {
+		t.Fatalf("Failed to get ingress: %v", err)
+	}
+	if _, ok := marked.Labels[supacontrolv1alpha1.LabelSuspended]; ok {
+		t.Error("Expected suspended label to be removed")
+	}
+	if _, ok := marked.Annotations[suspendedRedirectAnnotation]; ok {
+		t.Error("Expected suspended redirect to be removed")
+	}
+}
+
+// TestReconcileRunning_UpgradesChartVersion verifies that changing Spec.ChartVersion on a
+// running instance runs an upgrade Job and records the new version once it succeeds
+func TestReconcileRunning_UpgradesChartVersion(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	reconciler := createTestReconciler()
+
+	// Create and transition instance to Running
+	instance := createBasicInstance(t.Name())
+	err := k8sClient.Create(ctx, instance)
+	if err != nil {
+		t.Fatalf("Failed to create test instance: %v", err)
+	}
+	defer cleanupInstance(ctx, t, instance)
+
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: instance.Name}}
+	reconcileToPending(ctx, t, reconciler, instance.Name)
+	reconcileToProvisioning(ctx, t, reconciler, instance.Name)
+
+	current := getInstanceState(ctx, t, instance.Name)
+	if current != nil && current.Status.ProvisioningJobName != "" {
+		setJobSucceeded(ctx, t, current.Status.ProvisioningJobName)
+	}
+	if _, err := reconciler.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Failed to reconcile Running state: %v", err)
+	}
+
+	current = getInstanceState(ctx, t, instance.Name)
+	if current == nil || current.Status.Phase != supacontrolv1alpha1.PhaseRunning {
+		t.Fatalf("Instance not in Running phase")
+	}
+
+	// Request a new chart version
+	current.Spec.ChartVersion = "0.2.0"
+	if err := k8sClient.Update(ctx, current); err != nil {
+		t.Fatalf("Failed to update chart version: %v", err)
+	}
+	if _, err := reconciler.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile upgrade failed: %v", err)
+	}
+
+	current = getInstanceState(ctx, t, instance.Name)
+	if current.Status.Phase != supacontrolv1alpha1.PhaseUpgrading {
+		t.Fatalf("Expected phase Upgrading, got %s", current.Status.Phase)
+	}
+	if current.Status.UpgradeJobName == "" {
+		t.Fatal("Expected upgrade Job name to be set")
+	}
+
+	job := &batchv1.Job{}
+	if err := k8sClient.Get(ctx, types.NamespacedName{Name: current.Status.UpgradeJobName, Namespace: ControllerNamespace}, job); err != nil {
+		t.Fatalf("Failed to get upgrade Job: %v", err)
+	}
+	if job.Labels[JobOperationLabel] != OperationUpgrade {
+		t.Errorf("Expected operation label %q, got %q", OperationUpgrade, job.Labels[JobOperationLabel])
+	}
+
+	// Complete the upgrade
+	setJobSucceeded(ctx, t, current.Status.UpgradeJobName)
+	if _, err := reconciler.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile upgrade completion failed: %v", err)
+	}
+
+	current = getInstanceState(ctx, t, instance.Name)
+	if current.Status.Phase != supacontrolv1alpha1.PhaseRunning {
+		t.Errorf("Expected phase Running after upgrade, got %s", current.Status.Phase)
+	}
+	if current.Status.ChartVersion != "0.2.0" {
+		t.Errorf("Expected deployed chart version 0.2.0, got %q", current.Status.ChartVersion)
+	}
+	if current.Status.ObservedGeneration != current.Generation {
+		t.Errorf("Expected observedGeneration %d, got %d", current.Generation, current.Status.ObservedGeneration)
+	}
+	condition := meta.FindStatusCondition(current.Status.Conditions, supacontrolv1alpha1.ConditionTypeUpgraded)
+	if condition == nil || condition.Status != metav1.ConditionTrue {
+		t.Errorf("Expected Upgraded condition to be True, got %+v", condition)
+	}
+}
+
+// TestReconcileRunning_ResizesDatabase tests that changed database resources are applied
+// through an upgrade Job that checkpoints and verifies Postgres, with the progress and
+// outcome reported in the Resizing condition
+func TestReconcileRunning_ResizesDatabase(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	reconciler := createTestReconciler()
+
+	instance := createBasicInstance(t.Name())
+	instance.Spec.Resources = &supacontrolv1alpha1.Resources{Tier: supacontrolv1alpha1.ResourceTierSmall}
+	if err := k8sClient.Create(ctx, instance); err != nil {
+		t.Fatalf("Failed to create test instance: %v", err)
+	}
+	defer cleanupInstance(ctx, t, instance)
+
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: instance.Name}}
+	reconcileToPending(ctx, t, reconciler, instance.Name)
+	reconcileToProvisioning(ctx, t, reconciler, instance.Name)
+	current := getInstanceState(ctx, t, instance.Name)
+	setJobSucceeded(ctx, t, current.Status.ProvisioningJobName)
+	if _, err := reconciler.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Failed to reconcile Running state: %v", err)
+	}
+	current = getInstanceState(ctx, t, instance.Name)
+	if current.Status.Phase != supacontrolv1alpha1.PhaseRunning {
+		t.Fatalf("Instance not in Running phase")
+	}
+	if current.Status.Resources == nil || current.Status.Resources.Tier != supacontrolv1alpha1.ResourceTierSmall {
+		t.Fatalf("Expected the provisioned resources to be recorded, got %+v", current.Status.Resources)
+	}
+
+	// Move to a larger tier
+	current.Spec.Resources = &supacontrolv1alpha1.Resources{Tier: supacontrolv1alpha1.ResourceTierLarge}
+	if err := k8sClient.Update(ctx, current); err != nil {
+		t.Fatalf("Failed to update resources: %v", err)
+	}
+	if _, err := reconciler.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile resize failed: %v", err)
+	}
+
+	current = getInstanceState(ctx, t, instance.Name)
+	if current.Status.Phase != supacontrolv1alpha1.PhaseUpgrading {
+		t.Fatalf("Expected phase Upgrading, got %s", current.Status.Phase)
+	}
+	condition := meta.FindStatusCondition(current.Status.Conditions, supacontrolv1alpha1.ConditionTypeResizing)
+	if condition == nil || condition.Status != metav1.ConditionTrue || condition.Reason != "ResizeInProgress" {
+		t.Errorf("Expected Resizing condition to be in progress, got %+v", condition)
+	}
+	if meta.FindStatusCondition(current.Status.Conditions, supacontrolv1alpha1.ConditionTypeUpgraded) != nil {
+		t.Error("Expected no Upgraded condition for a resize alone")
+	}
+
+	job := &batchv1.Job{}
+	if err := k8sClient.Get(ctx, types.NamespacedName{Name: current.Status.UpgradeJobName, Namespace: ControllerNamespace}, job); err != nil {
+		t.Fatalf("Failed to get upgrade Job: %v", err)
+	}
+	container := job.Spec.Template.Spec.Containers[0]
+	env := map[string]string{}
+	for _, e := range container.Env {
+		env[e.Name] = e.Value
+	}
+	if env["RESIZE"] != "true" || env["DB_SELECTOR"] == "" {
+		t.Errorf("Expected the Job to checkpoint and verify the database, got env %v", env)
+	}
+	if env["CHART_VERSION"] != current.Status.ChartVersion {
+		t.Errorf("Expected the deployed chart version %q to be kept, got %q", current.Status.ChartVersion, env["CHART_VERSION"])
+	}
+	script := container.Args[0]
+	if !strings.Contains(script, "CHECKPOINT") || !strings.Contains(script, "pg_isready") {
+		t.Error("Expected the Job script to checkpoint and verify Postgres")
+	}
+
+	setJobSucceeded(ctx, t, current.Status.UpgradeJobName)
+	if _, err := reconciler.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile resize completion failed: %v", err)
+	}
+
+	current = getInstanceState(ctx, t, instance.Name)
+	if current.Status.Phase != supacontrolv1alpha1.PhaseRunning {
+		t.Errorf("Expected phase Running after resize, got %s", current.Status.Phase)
+	}
+	if current.Status.Resources == nil || current.Status.Resources.Tier != supacontrolv1alpha1.ResourceTierLarge {
+		t.Errorf("Expected the large tier to be recorded, got %+v", current.Status.Resources)
+	}
+	condition = meta.FindStatusCondition(current.Status.Conditions, supacontrolv1alpha1.ConditionTypeResizing)
+	if condition == nil || condition.Status != metav1.ConditionFalse || condition.Reason != "ResizeSucceeded" {
+		t.Errorf("Expected Resizing condition to report success, got %+v", condition)
+	}
+}
+
+func TestNeedsResize(t *testing.T) {
+	memory := resource.MustParse("4Gi")
+	tests := []struct {
+		name     string
+		instance *supacontrolv1alpha1.SupabaseInstance
+		expected bool
+	}{
+		{
+			name: "tier changed",
+			instance: &supacontrolv1alpha1.SupabaseInstance{
+				ObjectMeta: metav1.ObjectMeta{Generation: 3},
+				Spec:       supacontrolv1alpha1.SupabaseInstanceSpec{Resources: &supacontrolv1alpha1.Resources{Tier: supacontrolv1alpha1.ResourceTierLarge}},
+				Status:     supacontrolv1alpha1.SupabaseInstanceStatus{ObservedGeneration: 2, Resources: &supacontrolv1alpha1.Resources{Tier: supacontrolv1alpha1.ResourceTierSmall}},
+			},
+			expected: true,
+		},
+		{
+			name: "same sizing expressed differently",
+			instance: &supacontrolv1alpha1.SupabaseInstance{
+				ObjectMeta: metav1.ObjectMeta{Generation: 3},
+				Spec:       supacontrolv1alpha1.SupabaseInstanceSpec{Resources: &supacontrolv1alpha1.Resources{Tier: supacontrolv1alpha1.ResourceTierLarge}},
+				Status: supacontrolv1alpha1.SupabaseInstanceStatus{ObservedGeneration: 2, Resources: &supacontrolv1alpha1.Resources{
+					Tier: supacontrolv1alpha1.ResourceTierMedium, CPU: resource.NewQuantity(2, resource.DecimalSI), Memory: &memory,
+				}},
+			},
+			expected: false,
+		},
+		{
+			name: "spec already reconciled",
+			instance: &supacontrolv1alpha1.SupabaseInstance{
+				ObjectMeta: metav1.ObjectMeta{Generation: 3},
+				Spec:       supacontrolv1alpha1.SupabaseInstanceSpec{Resources: &supacontrolv1alpha1.Resources{Tier: supacontrolv1alpha1.ResourceTierLarge}},
+				Status:     supacontrolv1alpha1.SupabaseInstanceStatus{ObservedGeneration: 3},
+			},
+			expected: false,
+		},
+		{
+			name: "no resources requested",
+			instance: &supacontrolv1alpha1.SupabaseInstance{
+				ObjectMeta: metav1.ObjectMeta{Generation: 3},
+				Status:     supacontrolv1alpha1.SupabaseInstanceStatus{ObservedGeneration: 2},
+			},
+			expected: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := needsResize(tt.instance); got != tt.expected {
+				t.Errorf("needsResize() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestNeedsUpgrade(t *testing.T) {
+	upgraded := func(status metav1.ConditionStatus, generation int64) []metav1.Condition {
+		return []metav1.Condition{{Type: supacontrolv1alpha1.ConditionTypeUpgraded, Status: status, ObservedGeneration: generation}}
+	}
+	tests := []struct {
+		name     string
+		instance *supacontrolv1alpha1.SupabaseInstance
+		expected bool
+	}{
+		{
+			name: "spec changed to new version",
+			instance: &supacontrolv1alpha1.SupabaseInstance{
+				ObjectMeta: metav1.ObjectMeta{Generation: 3},
+				Spec:       supacontrolv1alpha1.SupabaseInstanceSpec{ChartVersion: "0.2.0"},
+				Status:     supacontrolv1alpha1.SupabaseInstanceStatus{ObservedGeneration: 2, ChartVersion: "0.1.0"},
+			},
+			expected: true,
+		},
+		{
+			name: "version changed while stopped",
+			instance: &supacontrolv1alpha1.SupabaseInstance{
+				ObjectMeta: metav1.ObjectMeta{Generation: 3},
+				Spec:       supacontrolv1alpha1.SupabaseInstanceSpec{ChartVersion: "0.2.0"},
+				Status: supacontrolv1alpha1.SupabaseInstanceStatus{
+					ObservedGeneration: 3, ChartVersion: "0.1.0", Conditions: upgraded(metav1.ConditionTrue, 1),
+				},
+			},
+			expected: true,
+		},
+		{
+			name: "upgrade to this version failed",
+			instance: &supacontrolv1alpha1.SupabaseInstance{
+				ObjectMeta: metav1.ObjectMeta{Generation: 3},
+				Spec:       supacontrolv1alpha1.SupabaseInstanceSpec{ChartVersion: "0.2.0"},
+				Status: supacontrolv1alpha1.SupabaseInstanceStatus{
+					ObservedGeneration: 3, ChartVersion: "0.1.0", Conditions: upgraded(metav1.ConditionFalse, 3),
+				},
+			},
+			expected: false,
+		},
+		{
+			name: "failed upgrade retried after a spec change",
+			instance: &supacontrolv1alpha1.SupabaseInstance{
+				ObjectMeta: metav1.ObjectMeta{Generation: 4},
+				Spec:       supacontrolv1alpha1.SupabaseInstanceSpec{ChartVersion: "0.2.0"},
+				Status: supacontrolv1alpha1.SupabaseInstanceStatus{
+					ObservedGeneration: 3, ChartVersion: "0.1.0", Conditions: upgraded(metav1.ConditionFalse, 3),
+				},
+			},
+			expected: true,
+		},
+		{
+			name: "unrelated spec change",
+			instance: &supacontrolv1alpha1.SupabaseInstance{
+				ObjectMeta: metav1.ObjectMeta{Generation: 3},
+				Spec:       supacontrolv1alpha1.SupabaseInstanceSpec{ChartVersion: "0.1.0"},
+				Status:     supacontrolv1alpha1.SupabaseInstanceStatus{ObservedGeneration: 2, ChartVersion: "0.1.0"},
+			},
+			expected: false,
+		},
+		{
+			name: "no chart version pinned",
+			instance: &supacontrolv1alpha1.SupabaseInstance{
+				ObjectMeta: metav1.ObjectMeta{Generation: 3},
+				Status:     supacontrolv1alpha1.SupabaseInstanceStatus{ObservedGeneration: 2, ChartVersion: "0.1.0"},
+			},
+			expected: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := needsUpgrade(tt.instance); got != tt.expected {
+				t.Errorf("needsUpgrade() = %t, want %t", got, tt.expected)
+			}
+		})
+	}
+}
+
+// TestReconcilePending_InjectsProfileValues tests that referenced shared service profiles are
+// rendered into a values Secret mounted by the provisioning Job
+func TestReconcilePending_InjectsProfileValues(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	reconciler := createTestReconciler()
+
+	profileName := strings.ToLower(strings.ReplaceAll(t.Name(), "_", "-"))
+	profileSecret := profiles.ToSecret(&profiles.Profile{
+		Name:     profileName,
+		Type:     profiles.TypeSMTP,
+		Settings: map[string]string{"host": "smtp.example.com", "port": "587", "sender_email": "no-reply@example.com"},
+		Secrets:  map[string]string{"username": "user", "password": "pass"},
+	})
+	if err := k8sClient.Create(ctx, profileSecret); err != nil {
+		t.Fatalf("Failed to create profile secret: %v", err)
+	}
+	defer func() { _ = k8sClient.Delete(ctx, profileSecret) }()
+
+	instance := createBasicInstance(t.Name())
+	instance.Spec.Profiles = []string{profileName}
+	if err := k8sClient.Create(ctx, instance); err != nil {
+		t.Fatalf("Failed to create test instance: %v", err)
+	}
+	defer cleanupInstance(ctx, t, instance)
+
+	// First reconcile initializes the phase, the second creates the provisioning Job
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: instance.Name}}
+	for i := 0; i < 2; i++ {
+		if _, err := reconciler.Reconcile(ctx, req); err != nil {
+			t.Fatalf("Reconcile %d failed: %v", i+1, err)
+		}
+	}
+
+	current := getInstanceState(ctx, t, instance.Name)
+	if current == nil {
+		t.Fatal("Instance not found after reconcile")
+	}
+	if current.Status.Phase != supacontrolv1alpha1.PhaseProvisioning {
+		t.Fatalf("Expected phase Provisioning, got %s (%s)", current.Status.Phase, current.Status.ErrorMessage)
+	}
+
+	valuesSecret := &corev1.Secret{}
+	if err := k8sClient.Get(ctx, types.NamespacedName{Name: ProfileValuesSecretName(instance), Namespace: ControllerNamespace}, valuesSecret); err != nil {
+		t.Fatalf("Profile values Secret not found: %v", err)
+	}
+	if !strings.Contains(string(valuesSecret.Data[ProfileValuesKey]), "SMTP_HOST: smtp.example.com") {
+		t.Errorf("Expected SMTP settings in profile values, got %q", valuesSecret.Data[ProfileValuesKey])
+	}
+
+	job := &batchv1.Job{}
+	if err := k8sClient.Get(ctx, types.NamespacedName{Name: current.Status.ProvisioningJobName, Namespace: ControllerNamespace}, job); err != nil {
+		t.Fatalf("Provisioning Job not found: %v", err)
+	}
+	volumes := job.Spec.Template.Spec.Volumes
+	if len(volumes) != 1 || volumes[0].Secret == nil || volumes[0].Secret.SecretName != ProfileValuesSecretName(instance) {
+		t.Errorf("Expected Job to mount profile values Secret, got volumes %+v", volumes)
+	}
+}
+
+// TestReconcilePending_MissingProfileFails tests that referencing an unknown profile fails provisioning
+func TestReconcilePending_MissingProfileFails(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	reconciler := createTestReconciler()
+
+	instance := createBasicInstance(t.Name())
+	instance.Spec.Profiles = []string{"does-not-exist"}
+	if err := k8sClient.Create(ctx, instance); err != nil {
+		t.Fatalf("Failed to create test instance: %v", err)
+	}
+	defer cleanupInstance(ctx, t, instance)
+
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: instance.Name}}
+	for i := 0; i < 2; i++ {
+		if _, err := reconciler.Reconcile(ctx, req); err != nil {
+			t.Fatalf("Reconcile %d failed: %v", i+1, err)
+		}
+	}
+
+	current := getInstanceState(ctx, t, instance.Name)
+	if current == nil {
+		t.Fatal("Instance not found after reconcile")
+	}
+	if current.Status.Phase != supacontrolv1alpha1.PhaseFailed {
+		t.Errorf("Expected phase Failed, got %s", current.Status.Phase)
+	}
+	if !strings.Contains(current.Status.ErrorMessage, `"does-not-exist" not found`) {
+		t.Errorf("Expected missing profile in error message, got %q", current.Status.ErrorMessage)
+	}
+}
+
+// TestReconcilePending_InjectsTemplateValues tests that the values of an instance's template
+// are rendered beneath the instance's own settings
+func TestReconcilePending_InjectsTemplateValues(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	reconciler := createTestReconciler()
+
+	template := &supacontrolv1alpha1.SupabaseInstanceTemplate{
+		ObjectMeta: metav1.ObjectMeta{Name: strings.ToLower(strings.ReplaceAll(t.Name(), "_", "-"))},
+		Spec: supacontrolv1alpha1.SupabaseInstanceTemplateSpec{
+			Values: &runtime.RawExtension{Raw: []byte(`{"studio":{"enabled":false},"auth":{"environment":{"GOTRUE_DISABLE_SIGNUP":"true","GOTRUE_JWT_EXP":"3600"}}}`)},
+		},
+	}
+	if err := k8sClient.Create(ctx, template); err != nil {
+		t.Fatalf("Failed to create template: %v", err)
+	}
+	defer func() { _ = k8sClient.Delete(ctx, template) }()
+
+	instance := createBasicInstance(t.Name())
+	instance.Spec.TemplateRef = template.Name
+	instance.Spec.Env = map[string]string{"GOTRUE_JWT_EXP": "7200"}
+	if err := k8sClient.Create(ctx, instance); err != nil {
+		t.Fatalf("Failed to create test instance: %v", err)
+	}
+	defer cleanupInstance(ctx, t, instance)
+
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: instance.Name}}
+	for i := 0; i < 2; i++ {
+		if _, err := reconciler.Reconcile(ctx, req); err != nil {
+			t.Fatalf("Reconcile %d failed: %v", i+1, err)
+		}
+	}
+
+	valuesSecret := &corev1.Secret{}
+	if err := k8sClient.Get(ctx, types.NamespacedName{Name: ProfileValuesSecretName(instance), Namespace: ControllerNamespace}, valuesSecret); err != nil {
+		t.Fatalf("Profile values Secret not found: %v", err)
+	}
+	values := string(valuesSecret.Data[ProfileValuesKey])
+	for _, expected := range []string{"enabled: false", `GOTRUE_DISABLE_SIGNUP: "true"`, `GOTRUE_JWT_EXP: "7200"`} {
+		if !strings.Contains(values, expected) {
+			t.Errorf("Expected %q in profile values, got %q", expected, values)
+		}
+	}
+}
+
+// TestReconcilePending_MissingTemplateFails tests that referencing an unknown template fails provisioning
+func TestReconcilePending_MissingTemplateFails(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	reconciler := createTestReconciler()
+
+	instance := createBasicInstance(t.Name())
+	instance.Spec.TemplateRef = "does-not-exist"
+	if err := k8sClient.Create(ctx, instance); err != nil {
+		t.Fatalf("Failed to create test instance: %v", err)
+	}
+	defer cleanupInstance(ctx, t, instance)
+
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: instance.Name}}
+	for i := 0; i < 2; i++ {
+		if _, err := reconciler.Reconcile(ctx, req); err != nil {
+			t.Fatalf("Reconcile %d failed: %v", i+1, err)
+		}
+	}
+
+	current := getInstanceState(ctx, t, instance.Name)
+	if current == nil {
+		t.Fatal("Instance not found after reconcile")
+	}
+	if current.Status.Phase != supacontrolv1alpha1.PhaseFailed {
+		t.Errorf("Expected phase Failed, got %s", current.Status.Phase)
+	}
+	if !strings.Contains(current.Status.ErrorMessage, `instance template "does-not-exist" not found`) {
+		t.Errorf("Expected missing template in error message, got %q", current.Status.ErrorMessage)
+	}
+}
+
+// TestTemplateChartValues tests decoding the chart values of instance templates
+func TestTemplateChartValues(t *testing.T) {
+	tests := []struct {
+		name        string
+		values      *runtime.RawExtension
+		expectedLen int
+		expectError bool
+	}{
+		{name: "no values"},
+		{name: "null values", values: &runtime.RawExtension{Raw: []byte("null")}},
+		{name: "values", values: &runtime.RawExtension{Raw: []byte(`{"studio":{"enabled":false},"kong":{}}`)}, expectedLen: 2},
+		{name: "not an object", values: &runtime.RawExtension{Raw: []byte(`["studio"]`)}, expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			template := &supacontrolv1alpha1.SupabaseInstanceTemplate{
+				ObjectMeta: metav1.ObjectMeta{Name: "pro"},
+				Spec:       supacontrolv1alpha1.SupabaseInstanceTemplateSpec{Values: tt.values},
+			}
+			values, err := templateChartValues(template)
+			if tt.expectError {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if values == nil || len(values) != tt.expectedLen {
+				t.Errorf("expected %d values, got %v", tt.expectedLen, values)
+			}
+		})
+	}
+}
+
+// TestJobFiles_CABundle tests that the custom CA bundle is mounted into Jobs only when configured
+func TestJobFiles_CABundle(t *testing.T) {
+	instance := &supacontrolv1alpha1.SupabaseInstance{
+		Spec: supacontrolv1alpha1.SupabaseInstanceSpec{ProjectName: "my-app"},
+	}
+
+	volumes, mounts, env := (&SupabaseInstanceReconciler{}).jobFiles(instance)
+	if len(volumes) != 1 || len(mounts) != 1 || len(env) != 1 {
+		t.Fatalf("expected only profile values without a CA bundle, got %d volumes, %d mounts, %d env", len(volumes), len(mounts), len(env))
+	}
+
+	volumes, mounts, env = (&SupabaseInstanceReconciler{CABundleConfigMap: "corp-ca"}).jobFiles(instance)
+	if len(volumes) != 2 || volumes[1].ConfigMap == nil || volumes[1].ConfigMap.Name != "corp-ca" {
+		t.Fatalf("expected CA bundle ConfigMap volume, got %+v", volumes)
+	}
+	if len(mounts) != 2 || mounts[1].MountPath != caBundleMountPath {
+		t.Errorf("expected CA bundle mount at %s, got %+v", caBundleMountPath, mounts)
+	}
+	if len(env) != 2 || env[1].Name != "CA_BUNDLE" || env[1].Value != caBundleMountPath+"/"+CABundleKey {
+		t.Errorf("expected CA_BUNDLE env, got %+v", env)
+	}
+}
+
+// TestJobFiles_ChartRepoCredentials tests that chart repository credentials are read from
+// their Secret only when configured
+func TestJobFiles_ChartRepoCredentials(t *testing.T) {
+	instance := &supacontrolv1alpha1.SupabaseInstance{
+		Spec: supacontrolv1alpha1.SupabaseInstanceSpec{ProjectName: "my-app"},
+	}
+
+	_, _, env := (&SupabaseInstanceReconciler{ChartRepoCredentialsSecret: "harbor-credentials"}).jobFiles(instance)
+	if len(env) != 3 {
+		t.Fatalf("expected profile values and two credential env vars, got %+v", env)
+	}
+	for i, expected := range []struct{ name, key string }{
+		{"CHART_REPO_USERNAME", ChartRepoUsernameKey},
+		{"CHART_REPO_PASSWORD", ChartRepoPasswordKey},
+	} {
+		got := env[i+1]
+		if got.Name != expected.name || got.Value != "" || got.ValueFrom == nil || got.ValueFrom.SecretKeyRef == nil {
+			t.Fatalf("expected %s from a Secret, got %+v", expected.name, got)
+		}
+		if ref := got.ValueFrom.SecretKeyRef; ref.Name != "harbor-credentials" || ref.Key != expected.key {
+			t.Errorf("expected %s from harbor-credentials/%s, got %s/%s", expected.name, expected.key, ref.Name, ref.Key)
+		}
+	}
+}
+
+// TestInstanceHosts tests that custom domains override the generated hostnames individually
+func TestInstanceHosts(t *testing.T) {
+	r := &SupabaseInstanceReconciler{DefaultIngressDomain: "supabase.example.com"}
+
+	tests := []struct {
+		name           string
+		spec           supacontrolv1alpha1.SupabaseInstanceSpec
+		expectedStudio string
+		expectedAPI    string
+	}{
+		{
+			name:           "generated hostnames",
+			spec:           supacontrolv1alpha1.SupabaseInstanceSpec{ProjectName: "my-app"},
+			expectedStudio: "my-app-studio.supabase.example.com",
+			expectedAPI:    "my-app-api.supabase.example.com",
+		},
+		{
+			name:           "instance ingress domain",
+			spec:           supacontrolv1alpha1.SupabaseInstanceSpec{ProjectName: "my-app", IngressDomain: "apps.acme.io"},
+			expectedStudio: "my-app-studio.apps.acme.io",
+			expectedAPI:    "my-app-api.apps.acme.io",
+		},
+		{
+			name: "both custom domains",
+			spec: supacontrolv1alpha1.SupabaseInstanceSpec{
+				ProjectName:   "my-app",
+				CustomDomains: &supacontrolv1alpha1.CustomDomains{API: "api.acme.io", Studio: "studio.acme.io"},
+			},
+			expectedStudio: "studio.acme.io",
+			expectedAPI:    "api.acme.io",
+		},
+		{
+			name: "only API custom domain",
+			spec: supacontrolv1alpha1.SupabaseInstanceSpec{
+				ProjectName:   "my-app",
+				CustomDomains: &supacontrolv1alpha1.CustomDomains{API: "api.acme.io"},
+			},
+			expectedStudio: "my-app-studio.supabase.example.com",
+			expectedAPI:    "api.acme.io",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			studioHost, apiHost := r.instanceHosts(&supacontrolv1alpha1.SupabaseInstance{Spec: tt.spec})
+			if studioHost != tt.expectedStudio {
+				t.Errorf("studio host = %q, want %q", studioHost, tt.expectedStudio)
+			}
+			if apiHost != tt.expectedAPI {
+				t.Errorf("API host = %q, want %q", apiHost, tt.expectedAPI)
+			}
+		})
+	}
+}
+
+// TestReconcileRunning_MovesToCustomDomains tests that setting custom domains on a Running
+// instance repoints its existing ingresses and publishes the new URLs
+func TestReconcileRunning_MovesToCustomDomains(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	reconciler := createTestReconciler()
+
+	instance := createBasicInstance(t.Name())
+	if err := k8sClient.Create(ctx, instance); err != nil {
+		t.Fatalf("Failed to create test instance: %v", err)
+	}
+	defer cleanupInstance(ctx, t, instance)
+
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: instance.Name}}
+	reconcileToPending(ctx, t, reconciler, instance.Name)
+	reconcileToProvisioning(ctx, t, reconciler, instance.Name)
+
+	current := getInstanceState(ctx, t, instance.Name)
+	if current == nil || current.Status.ProvisioningJobName == "" {
+		t.Fatal("Provisioning Job not created")
+	}
+	setJobSucceeded(ctx, t, current.Status.ProvisioningJobName)
+	if _, err := reconciler.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Failed to reconcile Running state: %v", err)
+	}
+
+	current = getInstanceState(ctx, t, instance.Name)
+	if current.Status.Phase != supacontrolv1alpha1.PhaseRunning {
+		t.Fatalf("Instance not in Running phase: %s", current.Status.Phase)
+	}
+
+	current.Spec.CustomDomains = &supacontrolv1alpha1.CustomDomains{
+		API:    "api." + instance.Name + ".acme.io",
+		Studio: "studio." + instance.Name + ".acme.io",
+	}
+	if err := k8sClient.Update(ctx, current); err != nil {
+		t.Fatalf("Failed to set custom domains: %v", err)
+	}
+	if _, err := reconciler.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Failed to reconcile custom domains: %v", err)
+	}
+
+	current = getInstanceState(ctx, t, instance.Name)
+	if current.Status.APIURL != "https://api."+instance.Name+".acme.io" {
+		t.Errorf("Expected API URL on custom domain, got %s", current.Status.APIURL)
+	}
+	if current.Status.StudioURL != "https://studio."+instance.Name+".acme.io" {
+		t.Errorf("Expected Studio URL on custom domain, got %s", current.Status.StudioURL)
+	}
+
+	ingress := &networkingv1.Ingress{}
+	key := client.ObjectKey{Namespace: current.Status.Namespace, Name: fmt.Sprintf("%s-api-ingress", current.Spec.ProjectName)}
+	if err := k8sClient.Get(ctx, key, ingress); err != nil {
+		t.Fatalf("Failed to get API ingress: %v", err)
+	}
+	if ingress.Spec.Rules[0].Host != current.Spec.CustomDomains.API || ingress.Spec.TLS[0].Hosts[0] != current.Spec.CustomDomains.API {
+		t.Errorf("Expected API ingress on %s, got %+v", current.Spec.CustomDomains.API, ingress.Spec)
+	}
+}
+
+// TestReconcileRunning_MovesIngressClassAndDomain tests that changing the ingress class and
+// domain of a Running instance updates its ingresses in place, publishes the new URLs and
+// records the change as an event
+func TestReconcileRunning_MovesIngressClassAndDomain(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	reconciler := createTestReconciler()
+	recorder := record.NewFakeRecorder(10)
+	reconciler.Recorder = recorder
+
+	instance := createBasicInstance(t.Name())
+	if err := k8sClient.Create(ctx, instance); err != nil {
+		t.Fatalf("Failed to create test instance: %v", err)
+	}
+	defer cleanupInstance(ctx, t, instance)
+
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: instance.Name}}
+	reconcileToPending(ctx, t, reconciler, instance.Name)
+	reconcileToProvisioning(ctx, t, reconciler, instance.Name)
+
+	current := getInstanceState(ctx, t, instance.Name)
+	if current == nil || current.Status.ProvisioningJobName == "" {
+		t.Fatal("Provisioning Job not created")
+	}
+	setJobSucceeded(ctx, t, current.Status.ProvisioningJobName)
+	if _, err := reconciler.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Failed to reconcile Running state: %v", err)
+	}
+
+	current = getInstanceState(ctx, t, instance.Name)
+	if current.Status.Phase != supacontrolv1alpha1.PhaseRunning {
+		t.Fatalf("Instance not in Running phase: %s", current.Status.Phase)
+	}
+
+	current.Spec.IngressClass = "traefik"
+	current.Spec.IngressDomain = "apps.example.org"
+	if err := k8sClient.Update(ctx, current); err != nil {
+		t.Fatalf("Failed to change ingress class and domain: %v", err)
+	}
+	if _, err := reconciler.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Failed to reconcile ingress changes: %v", err)
+	}
+
+	current = getInstanceState(ctx, t, instance.Name)
+	if expected := fmt.Sprintf("https://%s-api.apps.example.org", current.Spec.ProjectName); current.Status.APIURL != expected {
+		t.Errorf("Expected API URL %s, got %s", expected, current.Status.APIURL)
+	}
+
+	studioIngress, apiIngress := ingressNames(current)
+	for _, name := range []string{studioIngress, apiIngress} {
+		ingress := &networkingv1.Ingress{}
+		if err := k8sClient.Get(ctx, client.ObjectKey{Namespace: current.Status.Namespace, Name: name}, ingress); err != nil {
+			t.Fatalf("Failed to get ingress %s: %v", name, err)
+		}
+		if ptr.Deref(ingress.Spec.IngressClassName, "") != "traefik" {
+			t.Errorf("Expected ingress %s on IngressClass traefik, got %v", name, ingress.Spec.IngressClassName)
+		}
+		if !strings.HasSuffix(ingress.Spec.Rules[0].Host, ".apps.example.org") {
+			t.Errorf("Expected ingress %s on apps.example.org, got %s", name, ingress.Spec.Rules[0].Host)
+		}
+	}
+
+	found := false
+	for len(recorder.Events) > 0 {
+		if event := <-recorder.Events; strings.Contains(event, "IngressUpdated") && strings.Contains(event, "IngressClass nginx to traefik") {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("Expected an IngressUpdated event describing the class change")
+	}
+}
+
+// TestReconcileRunning_IngressSettings tests that per-instance annotations and TLS issuer
+// are merged into the ingress annotations, and that disabling TLS drops the certificates
+// and publishes plain HTTP URLs
+func TestReconcileRunning_IngressSettings(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	reconciler := createTestReconciler()
+
+	instance := createBasicInstance(t.Name())
+	instance.Spec.Ingress = &supacontrolv1alpha1.IngressSettings{
+		Annotations: map[string]string{"nginx.ingress.kubernetes.io/proxy-body-size": "50m"},
+		TLSIssuer:   "letsencrypt-staging",
+	}
+	if err := k8sClient.Create(ctx, instance); err != nil {
+		t.Fatalf("Failed to create test instance: %v", err)
+	}
+	defer cleanupInstance(ctx, t, instance)
+
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: instance.Name}}
+	reconcileToPending(ctx, t, reconciler, instance.Name)
+	reconcileToProvisioning(ctx, t, reconciler, instance.Name)
+
+	current := getInstanceState(ctx, t, instance.Name)
+	if current == nil || current.Status.ProvisioningJobName == "" {
+		t.Fatal("Provisioning Job not created")
+	}
+	setJobSucceeded(ctx, t, current.Status.ProvisioningJobName)
+	if _, err := reconciler.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Failed to reconcile Running state: %v", err)
+	}
+
+	current = getInstanceState(ctx, t, instance.Name)
+	studioIngress, apiIngress := ingressNames(current)
+	ingress := &networkingv1.Ingress{}
+	if err := k8sClient.Get(ctx, client.ObjectKey{Namespace: current.Status.Namespace, Name: apiIngress}, ingress); err != nil {
+		t.Fatalf("Failed to get ingress %s: %v", apiIngress, err)
+	}
+	if ingress.Annotations["cert-manager.io/cluster-issuer"] != "letsencrypt-staging" ||
+		ingress.Annotations["nginx.ingress.kubernetes.io/proxy-body-size"] != "50m" {
+		t.Errorf("Expected merged annotations, got %v", ingress.Annotations)
+	}
+
+	current.Spec.Ingress = &supacontrolv1alpha1.IngressSettings{DisableTLS: true}
+	if err := k8sClient.Update(ctx, current); err != nil {
+		t.Fatalf("Failed to disable TLS: %v", err)
+	}
+	if _, err := reconciler.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Failed to reconcile ingress changes: %v", err)
+	}
+
+	current = getInstanceState(ctx, t, instance.Name)
+	if !strings.HasPrefix(current.Status.APIURL, "http://") || !strings.HasPrefix(current.Status.StudioURL, "http://") {
+		t.Errorf("Expected plain HTTP URLs, got %s and %s", current.Status.APIURL, current.Status.StudioURL)
+	}
+	for _, name := range []string{studioIngress, apiIngress} {
+		ingress := &networkingv1.Ingress{}
+		if err := k8sClient.Get(ctx, client.ObjectKey{Namespace: current.Status.Namespace, Name: name}, ingress); err != nil {
+			t.Fatalf("Failed to get ingress %s: %v", name, err)
+		}
+		if len(ingress.Spec.TLS) > 0 || len(ingress.Annotations) > 0 {
+			t.Errorf("Expected ingress %s without TLS or annotations, got %v and %v", name, ingress.Spec.TLS, ingress.Annotations)
+		}
+	}
+}
+
+// TestAutoRetryDelay tests the exponential backoff between automatic retries
+func TestAutoRetryDelay(t *testing.T) {
+	tests := []struct {
+		retries  int32
+		expected time.Duration
+	}{
+		{retries: 0, expected: time.Minute},
+		{retries: 1, expected: 2 * time.Minute},
+		{retries: 3, expected: 8 * time.Minute},
+		{retries: 6, expected: time.Hour},
+		{retries: 40, expected: time.Hour},
+	}
+
+	for _, tt := range tests {
+		if got := autoRetryDelay(tt.retries); got != tt.expected {
+			t.Errorf("autoRetryDelay(%d) = %v, want %v", tt.retries, got, tt.expected)
+		}
+	}
+}
+
+// TestJitterRetryDelay tests that jitter only shortens the wait before automatic retries
+func TestJitterRetryDelay(t *testing.T) {
+	t.Parallel()
+
+	for i := 0; i < 100; i++ {
+		got := jitterRetryDelay(AutoRetryMaxDelay)
+		if got > AutoRetryMaxDelay || got < time.Duration(float64(AutoRetryMaxDelay)*(1-AutoRetryJitter)) {
+			t.Fatalf("jitterRetryDelay(%v) = %v, outside the jitter range", AutoRetryMaxDelay, got)
+		}
+	}
+}
+
+// TestClassifyFailure tests that failures are classified as transient or permanent
+func TestClassifyFailure(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		message  string
+		expected supacontrolv1alpha1.FailureType
+	}{
+		{message: "Job failed: BackoffLimitExceeded - Job has reached the specified backoff limit: ErrImagePull: rpc error: pull access denied", expected: supacontrolv1alpha1.FailureTransient},
+		{message: "Job failed: DeadlineExceeded - Job was active longer than specified deadline", expected: supacontrolv1alpha1.FailureTransient},
+		{message: "Job failed: BackoffLimitExceeded - x: Error: looks like \"https://charts.example.com\" is not a valid chart repository or cannot be reached: Get \"https://charts.example.com/index.yaml\": dial tcp: i/o timeout", expected: supacontrolv1alpha1.FailureTransient},
+		{message: "Job failed: BackoffLimitExceeded - x: Error: INSTALLATION FAILED: context deadline exceeded", expected: supacontrolv1alpha1.FailureTransient},
+		{message: "Failed to create provisioning Job: the server is currently unable to handle the request", expected: supacontrolv1alpha1.FailureTransient},
+		{message: "Job failed: BackoffLimitExceeded - x: Error: INSTALLATION FAILED: values don't meet the specifications of the schema(s)", expected: supacontrolv1alpha1.FailurePermanent},
+		{message: "Job failed: BackoffLimitExceeded - x: Error: chart \"supabase\" version \"9.9.9\" not found in https://charts.example.com repository", expected: supacontrolv1alpha1.FailurePermanent},
+		{message: "Invalid instance namespace: kube-system is a system namespace", expected: supacontrolv1alpha1.FailurePermanent},
+		{message: "Postgres upgrade to 17 failed (verify failed) and so did the rollback: helm failed", expected: supacontrolv1alpha1.FailurePermanent},
+		{message: "Provisioning Job failed after retries"},
+	}
+
+	for _, tt := range tests {
+		if got := classifyFailure(tt.message); got != tt.expected {
+			t.Errorf("classifyFailure(%q) = %q, want %q", tt.message, got, tt.expected)
+		}
+	}
+}
+
+// TestAutoRetryLimit tests how many automatic retries each kind of failure gets
+func TestAutoRetryLimit(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		policy   *supacontrolv1alpha1.AutoRetryPolicy
+		failure  supacontrolv1alpha1.FailureType
+		expected int32
+	}{
+		{name: "transient without policy", failure: supacontrolv1alpha1.FailureTransient, expected: DefaultAutoRetryAttempts},
+		{name: "transient with policy", policy: &supacontrolv1alpha1.AutoRetryPolicy{MaxAttempts: 5}, failure: supacontrolv1alpha1.FailureTransient, expected: 5},
+		{name: "unclassified without policy"},
+		{name: "unclassified with policy", policy: &supacontrolv1alpha1.AutoRetryPolicy{MaxAttempts: 2}, expected: 2},
+		{name: "permanent with policy", policy: &supacontrolv1alpha1.AutoRetryPolicy{MaxAttempts: 2}, failure: supacontrolv1alpha1.FailurePermanent},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			instance := &supacontrolv1alpha1.SupabaseInstance{}
+			instance.Spec.AutoRetry = tt.policy
+			if got := autoRetryLimit(instance, tt.failure); got != tt.expected {
+				t.Errorf("autoRetryLimit() = %d, want %d", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestProvisioningLimits(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name            string
+		provisioning    *supacontrolv1alpha1.Provisioning
+		expectedTimeout int64
+		expectedRetries int32
+	}{
+		{name: "defaults", expectedTimeout: DefaultProvisioningTimeoutSeconds, expectedRetries: DefaultProvisioningRetries},
+		{name: "longer timeout", provisioning: &supacontrolv1alpha1.Provisioning{TimeoutSeconds: 3600}, expectedTimeout: 3600, expectedRetries: DefaultProvisioningRetries},
+		{name: "no retries", provisioning: &supacontrolv1alpha1.Provisioning{Retries: ptr.To(int32(0))}, expectedTimeout: DefaultProvisioningTimeoutSeconds},
+		{name: "both", provisioning: &supacontrolv1alpha1.Provisioning{TimeoutSeconds: 1200, Retries: ptr.To(int32(6))}, expectedTimeout: 1200, expectedRetries: 6},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			instance := &supacontrolv1alpha1.SupabaseInstance{}
+			instance.Spec.Provisioning = tt.provisioning
+			if got := provisioningTimeout(instance); got != tt.expectedTimeout {
+				t.Errorf("provisioningTimeout() = %d, want %d", got, tt.expectedTimeout)
+			}
+			if got := provisioningRetries(instance); got != tt.expectedRetries {
+				t.Errorf("provisioningRetries() = %d, want %d", got, tt.expectedRetries)
+			}
+		})
+	}
+}
+
+func TestIsJobFailed(t *testing.T) {
+	t.Parallel()
+
+	failedCondition := []batchv1.JobCondition{{Type: batchv1.JobFailed, Status: corev1.ConditionTrue, Reason: "DeadlineExceeded"}}
+	tests := []struct {
+		name         string
+		backoffLimit *int32
+		failed       int32
+		conditions   []batchv1.JobCondition
+		expected     bool
+	}{
+		{name: "no retries, nothing failed yet", backoffLimit: ptr.To(int32(0))},
+		{name: "no retries, one attempt failed", backoffLimit: ptr.To(int32(0)), failed: 1, expected: true},
+		{name: "retries left", backoffLimit: ptr.To(int32(3)), failed: 3},
+		{name: "retries exhausted", backoffLimit: ptr.To(int32(3)), failed: 4, expected: true},
+		{name: "deadline exceeded", backoffLimit: ptr.To(int32(3)), failed: 1, conditions: failedCondition, expected: true},
+		{name: "no backoff limit"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job := &batchv1.Job{}
+			job.Spec.BackoffLimit = tt.backoffLimit
+			job.Status.Failed = tt.failed
+			job.Status.Conditions = tt.conditions
+			if got := isJobFailed(job); got != tt.expected {
+				t.Errorf("isJobFailed() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+// TestReconcileFailed_AutoRetry tests that a Failed instance with AutoRetry waits out the
+// backoff, then returns to Pending, and stays Failed once its attempts are used up
+func TestReconcileFailed_AutoRetry(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	reconciler := createTestReconciler()
+
+	instance := createBasicInstance(t.Name())
+	instance.Spec.AutoRetry = &supacontrolv1alpha1.AutoRetryPolicy{MaxAttempts: 1}
+	if err := k8sClient.Create(ctx, instance); err != nil {
+		t.Fatalf("Failed to create test instance: %v", err)
+	}
+	defer cleanupInstance(ctx, t, instance)
+
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: instance.Name}}
+	reconcileToPending(ctx, t, reconciler, instance.Name)
+
+	setFailed := func(failedAt time.Time) {
+		current := getInstanceState(ctx, t, instance.Name)
+		current.Status.Phase = supacontrolv1alpha1.PhaseFailed
+		current.Status.ErrorMessage = "Provisioning Job failed after retries"
+		current.Status.LastTransitionTime = &metav1.Time{Time: failedAt}
+		if err := k8sClient.Status().Update(ctx, current); err != nil {
+			t.Fatalf("Failed to mark instance Failed: %v", err)
+		}
+	}
+
+	// Within the backoff the instance keeps waiting
+	setFailed(time.Now())
+	result, err := reconciler.Reconcile(ctx, req)
+	if err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	if result.RequeueAfter <= 0 || result.RequeueAfter > AutoRetryBaseDelay {
+		t.Errorf("Expected requeue within %v, got %v", AutoRetryBaseDelay, result.RequeueAfter)
+	}
+	if current := getInstanceState(ctx, t, instance.Name); current.Status.Phase != supacontrolv1alpha1.PhaseFailed {
+		t.Fatalf("Expected instance to stay Failed during backoff, got %s", current.Status.Phase)
+	}
+
+	// Once the backoff has passed it is retried
+	setFailed(time.Now().Add(-2 * AutoRetryBaseDelay))
+	if _, err := reconciler.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	current := getInstanceState(ctx, t, instance.Name)
+	if current.Status.Phase != supacontrolv1alpha1.PhasePending {
+		t.Fatalf("Expected phase Pending after auto retry, got %s", current.Status.Phase)
+	}
+	if current.Status.RetryCount != 1 || current.Status.ErrorMessage != "" {
+		t.Errorf("Expected RetryCount 1 and cleared error, got %d %q", current.Status.RetryCount, current.Status.ErrorMessage)
+	}
+	if cond := meta.FindStatusCondition(current.Status.Conditions, supacontrolv1alpha1.ConditionTypeReady); cond == nil || cond.Reason != "AutoRetry" {
+		t.Errorf("Expected Ready condition with reason AutoRetry, got %+v", cond)
+	}
+
+	// With its attempts used up the instance stays Failed
+	setFailed(time.Now().Add(-24 * time.Hour))
+	if _, err := reconciler.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	if current := getInstanceState(ctx, t, instance.Name); current.Status.Phase != supacontrolv1alpha1.PhaseFailed {
+		t.Errorf("Expected instance to stay Failed after its retries, got %s", current.Status.Phase)
+	}
+}
+
+// TestReconcilePending_ReservesDedicatedNode tests that a dedicated instance reserves and taints
+// a matching node before provisioning, and releases it on deletion
+func TestReconcilePending_ReservesDedicatedNode(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	reconciler := createTestReconciler()
+
+	pool := strings.ToLower(strings.ReplaceAll(t.Name(), "_", "-"))
+	instance := createBasicInstance(t.Name())
+	instance.Spec.Placement = &supacontrolv1alpha1.Placement{
+		Mode:         supacontrolv1alpha1.PlacementDedicated,
+		NodeSelector: map[string]string{"pool": pool},
+	}
+	if err := k8sClient.Create(ctx, instance); err != nil {
+		t.Fatalf("Failed to create test instance: %v", err)
+	}
+	defer cleanupInstance(ctx, t, instance)
+
+	// Without a matching node the instance waits in Pending
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: instance.Name}}
+	for i := 0; i < 2; i++ {
+		if _, err := reconciler.Reconcile(ctx, req); err != nil {
+			t.Fatalf("Reconcile %d failed: %v", i+1, err)
+		}
+	}
+	current := getInstanceState(ctx, t, instance.Name)
+	if current.Status.Phase != supacontrolv1alpha1.PhasePending {
+		t.Fatalf("Expected phase Pending without a free node, got %s", current.Status.Phase)
+	}
+	if cond := meta.FindStatusCondition(current.Status.Conditions, supacontrolv1alpha1.ConditionTypePlacementReady); cond == nil || cond.Reason != "NoNodeAvailable" {
+		t.Errorf("Expected PlacementReady condition with reason NoNodeAvailable, got %+v", cond)
+	}
+
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: pool, Labels: map[string]string{"pool": pool}}}
+	if err := k8sClient.Create(ctx, node); err != nil {
+		t.Fatalf("Failed to create node: %v", err)
+	}
+	defer func() { _ = k8sClient.Delete(ctx, node) }()
+	node.Status.Conditions = []corev1.NodeCondition{{Type: corev1.NodeReady, Status: corev1.ConditionTrue}}
+	if err := k8sClient.Status().Update(ctx, node); err != nil {
+		t.Fatalf("Failed to mark node ready: %v", err)
+	}
+
+	if _, err := reconciler.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	current = getInstanceState(ctx, t, instance.Name)
+	if current.Status.Phase != supacontrolv1alpha1.PhaseProvisioning {
+		t.Fatalf("Expected phase Provisioning, got %s (%s)", current.Status.Phase, current.Status.ErrorMessage)
+	}
+	if current.Status.DedicatedNode != node.Name {
+		t.Errorf("Expected dedicated node %s, got %q", node.Name, current.Status.DedicatedNode)
+	}
+
+	if err := k8sClient.Get(ctx, types.NamespacedName{Name: node.Name}, node); err != nil {
+		t.Fatalf("Failed to get node: %v", err)
+	}
+	if node.Labels[supacontrolv1alpha1.LabelDedicatedInstance] != instance.Spec.ProjectName {
+		t.Errorf("Expected node to be labelled for the instance, got labels %v", node.Labels)
+	}
+	if len(node.Spec.Taints) != 1 || node.Spec.Taints[0] != dedicatedTaint(instance.Spec.ProjectName) {
+		t.Errorf("Expected dedicated taint on node, got %+v", node.Spec.Taints)
+	}
+
+	valuesSecret := &corev1.Secret{}
+	if err := k8sClient.Get(ctx, types.NamespacedName{Name: ProfileValuesSecretName(instance), Namespace: ControllerNamespace}, valuesSecret); err != nil {
+		t.Fatalf("Chart values Secret not found: %v", err)
+	}
+	if !strings.Contains(string(valuesSecret.Data[ProfileValuesKey]), "effect: NoSchedule") {
+		t.Errorf("Expected tolerations in chart values, got %q", valuesSecret.Data[ProfileValuesKey])
+	}
+
+	if err := reconciler.releaseDedicatedNode(ctx, current); err != nil {
+		t.Fatalf("releaseDedicatedNode failed: %v", err)
+	}
+	if err := k8sClient.Get(ctx, types.NamespacedName{Name: node.Name}, node); err != nil {
+		t.Fatalf("Failed to get node: %v", err)
+	}
+	if _, ok := node.Labels[supacontrolv1alpha1.LabelDedicatedInstance]; ok || len(node.Spec.Taints) != 0 {
+		t.Errorf("Expected reservation to be removed, got labels %v and taints %+v", node.Labels, node.Spec.Taints)
+	}
+}
+
+// TestIsReservable tests which nodes can be reserved for a dedicated instance
+func TestIsReservable(t *testing.T) {
+	ready := []corev1.NodeCondition{{Type: corev1.NodeReady, Status: corev1.ConditionTrue}}
+	tests := []struct {
+		name     string
+		node     corev1.Node
+		expected bool
+	}{
+		{name: "ready worker", node: corev1.Node{Status: corev1.NodeStatus{Conditions: ready}}, expected: true},
+		{name: "not ready", node: corev1.Node{}, expected: false},
+		{
+			name:     "cordoned",
+			node:     corev1.Node{Spec: corev1.NodeSpec{Unschedulable: true}, Status: corev1.NodeStatus{Conditions: ready}},
+			expected: false,
+		},
+		{
+			name: "control plane",
+			node: corev1.Node{
+				ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{controlPlaneLabel: ""}},
+				Status:     corev1.NodeStatus{Conditions: ready},
+			},
+			expected: false,
+		},
+		{
+			name: "reserved by another instance",
+			node: corev1.Node{
+				ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{supacontrolv1alpha1.LabelDedicatedInstance: "other"}},
+				Status:     corev1.NodeStatus{Conditions: ready},
+			},
+			expected: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isReservable(&tt.node); got != tt.expected {
+				t.Errorf("isReservable() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+// TestIsWorkloadPod tests which pods keep a node from being reserved
+func TestIsWorkloadPod(t *testing.T) {
+	daemonSetOwner := []metav1.OwnerReference{{Kind: "DaemonSet", Name: "node-exporter", Controller: ptr.To(true)}}
+	tests := []struct {
+		name     string
+		pod      corev1.Pod
+		expected bool
+	}{
+		{name: "running pod", pod: corev1.Pod{Status: corev1.PodStatus{Phase: corev1.PodRunning}}, expected: true},
+		{name: "pending pod", pod: corev1.Pod{Status: corev1.PodStatus{Phase: corev1.PodPending}}, expected: true},
+		{name: "finished pod", pod: corev1.Pod{Status: corev1.PodStatus{Phase: corev1.PodSucceeded}}, expected: false},
+		{
+			name:     "DaemonSet pod",
+			pod:      corev1.Pod{ObjectMeta: metav1.ObjectMeta{OwnerReferences: daemonSetOwner}, Status: corev1.PodStatus{Phase: corev1.PodRunning}},
+			expected: false,
+		},
+		{
+			name: "static pod",
+			pod: corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{corev1.MirrorPodAnnotationKey: "hash"}},
+				Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+			},
+			expected: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isWorkloadPod(&tt.pod); got != tt.expected {
+				t.Errorf("isWorkloadPod() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+// TestReserveDedicatedNode_SkipsBusyNodes tests that nodes already running other
+// workloads are not reserved
+func TestReserveDedicatedNode_SkipsBusyNodes(t *testing.T) {
+	ctx := context.Background()
+	ready := []corev1.NodeCondition{{Type: corev1.NodeReady, Status: corev1.ConditionTrue}}
+	node := func(name string) *corev1.Node {
+		return &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name}, Status: corev1.NodeStatus{Conditions: ready}}
+	}
+	pod := func(name, nodeName string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Spec:       corev1.PodSpec{NodeName: nodeName},
+			Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+		}
+	}
+
+	c := fake.NewClientBuilder().
+		WithObjects(node("node-a"), node("node-b"), pod("web", "node-a")).
+		WithIndex(&corev1.Pod{}, nodeNameField, func(obj client.Object) []string {
+			return []string{obj.(*corev1.Pod).Spec.NodeName}
+		}).Build()
+	reconciler := &SupabaseInstanceReconciler{Client: c}
+	instance := &supacontrolv1alpha1.SupabaseInstance{
+		Spec: supacontrolv1alpha1.SupabaseInstanceSpec{
+			ProjectName: "app",
+			Placement:   &supacontrolv1alpha1.Placement{Mode: supacontrolv1alpha1.PlacementDedicated},
+		},
+	}
+
+	nodeName, err := reconciler.reserveDedicatedNode(ctx, instance)
+	if err != nil {
+		t.Fatalf("reserveDedicatedNode failed: %v", err)
+	}
+	if nodeName != "node-b" {
+		t.Errorf("Expected the idle node node-b to be reserved, got %q", nodeName)
+	}
+}
+
+// TestReconcilePending_KataIsolation tests Kata RuntimeClass detection, the namespace
+// fallback and the runtimeClassName set in chart values
+func TestReconcilePending_KataIsolation(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	runtimeClass := strings.ToLower(strings.ReplaceAll(t.Name(), "_", "-"))
+	reconciler := createTestReconciler()
+	reconciler.KataRuntimeClass = runtimeClass
+
+	reconcileTwice := func(instance *supacontrolv1alpha1.SupabaseInstance) *supacontrolv1alpha1.SupabaseInstance {
+		if err := k8sClient.Create(ctx, instance); err != nil {
+			t.Fatalf("Failed to create test instance: %v", err)
+		}
+		req := ctrl.Request{NamespacedName: types.NamespacedName{Name: instance.Name}}
+		for i := 0; i < 2; i++ {
+			if _, err := reconciler.Reconcile(ctx, req); err != nil {
+				t.Fatalf("Reconcile %d failed: %v", i+1, err)
+			}
+		}
+		return getInstanceState(ctx, t, instance.Name)
+	}
+
+	// Without the RuntimeClass, provisioning fails unless the instance accepts a fallback
+	failing := createBasicInstance(t.Name() + "-fail")
+	failing.Spec.Isolation = &supacontrolv1alpha1.Isolation{Level: supacontrolv1alpha1.IsolationKataRuntime}
+	defer cleanupInstance(ctx, t, failing)
+	if current := reconcileTwice(failing); current.Status.Phase != supacontrolv1alpha1.PhaseFailed {
+		t.Errorf("Expected phase Failed without the RuntimeClass, got %s", current.Status.Phase)
+	}
+
+	fallback := createBasicInstance(t.Name() + "-fallback")
+	fallback.Spec.Isolation = &supacontrolv1alpha1.Isolation{
+		Level:    supacontrolv1alpha1.IsolationKataRuntime,
+		Fallback: supacontrolv1alpha1.IsolationFallbackNamespace,
+	}
+	defer cleanupInstance(ctx, t, fallback)
+	current := reconcileTwice(fallback)
+	if current.Status.Phase != supacontrolv1alpha1.PhaseProvisioning || current.Status.IsolationLevel != supacontrolv1alpha1.IsolationNamespace {
+		t.Errorf("Expected Provisioning with Namespace isolation, got %s with %q", current.Status.Phase, current.Status.IsolationLevel)
+	}
+	if cond := meta.FindStatusCondition(current.Status.Conditions, supacontrolv1alpha1.ConditionTypeIsolationReady); cond == nil || cond.Reason != "FellBackToNamespace" {
+		t.Errorf("Expected IsolationReady condition with reason FellBackToNamespace, got %+v", cond)
+	}
+
+	class := &nodev1.RuntimeClass{ObjectMeta: metav1.ObjectMeta{Name: runtimeClass}, Handler: "kata"}
+	if err := k8sClient.Create(ctx, class); err != nil {
+		t.Fatalf("Failed to create RuntimeClass: %v", err)
+	}
+	defer func() { _ = k8sClient.Delete(ctx, class) }()
+
+	kata := createBasicInstance(t.Name() + "-kata")
+	kata.Spec.Isolation = &supacontrolv1alpha1.Isolation{Level: supacontrolv1alpha1.IsolationKataRuntime}
+	defer cleanupInstance(ctx, t, kata)
+	current = reconcileTwice(kata)
+	if current.Status.IsolationLevel != supacontrolv1alpha1.IsolationKataRuntime {
+		t.Fatalf("Expected KataRuntime isolation, got %q (%s)", current.Status.IsolationLevel, current.Status.ErrorMessage)
+	}
+	valuesSecret := &corev1.Secret{}
+	if err := k8sClient.Get(ctx, types.NamespacedName{Name: ProfileValuesSecretName(kata), Namespace: ControllerNamespace}, valuesSecret); err != nil {
+		t.Fatalf("Chart values Secret not found: %v", err)
+	}
+	if !strings.Contains(string(valuesSecret.Data[ProfileValuesKey]), "runtimeClassName: "+runtimeClass) {
+		t.Errorf("Expected runtimeClassName in chart values, got %q", valuesSecret.Data[ProfileValuesKey])
+	}
+}
+
+// TestIsolationEnv tests that Jobs only receive vcluster settings for vcluster instances
+func TestIsolationEnv(t *testing.T) {
+	reconciler := &SupabaseInstanceReconciler{VClusterChartRepo: "https://charts.example.com"}
+	instance := &supacontrolv1alpha1.SupabaseInstance{
+		Spec: supacontrolv1alpha1.SupabaseInstanceSpec{ProjectName: "my-app"},
+	}
+
+	instance.Status.IsolationLevel = supacontrolv1alpha1.IsolationKataRuntime
+	if env := reconciler.isolationEnv(instance); len(env) != 0 {
+		t.Errorf("expected no vcluster env for Kata isolation, got %+v", env)
+	}
+
+	instance.Status.IsolationLevel = supacontrolv1alpha1.IsolationVCluster
+	env := map[string]string{}
+	for _, e := range reconciler.isolationEnv(instance) {
+		env[e.Name] = e.Value
+	}
+	if env["ISOLATION_LEVEL"] != "vcluster" || env["VCLUSTER_RELEASE"] != "my-app-vcluster" || env["VCLUSTER_CHART_REPO"] != "https://charts.example.com" {
+		t.Errorf("unexpected vcluster env %v", env)
+	}
+}
+
+// TestPoolerEnv tests the connection pooler settings passed to the provisioning Job
+func TestPoolerEnv(t *testing.T) {
+	instance := &supacontrolv1alpha1.SupabaseInstance{
+		Spec: supacontrolv1alpha1.SupabaseInstanceSpec{ProjectName: "my-app"},
+	}
+	if env := poolerEnv(instance); len(env) != 0 {
+		t.Errorf("expected no pooler env without a connection pooler, got %+v", env)
+	}
+
+	instance.Spec.ConnectionPooler = &supacontrolv1alpha1.ConnectionPooler{Enabled: true, DefaultPoolSize: 50}
+	env := map[string]string{}
+	for _, e := range poolerEnv(instance) {
+		env[e.Name] = e.Value
+	}
+	if env["POOLER_ENABLED"] != "true" || env["POOL_MODE"] != "transaction" || env["DEFAULT_POOL_SIZE"] != "50" || env["MAX_CLIENT_CONN"] != "1000" || env["POOLER_PORT"] != "6543" {
+		t.Errorf("unexpected pooler env %v", env)
+	}
+}
+
+// TestSetStorageValues tests the Postgres volume chart values
+func TestSetStorageValues(t *testing.T) {
+	values := map[string]interface{}{}
+	setStorageValues(values, nil)
+	if len(values) != 0 {
+		t.Errorf("expected no values without storage settings, got %v", values)
+	}
+
+	size := resource.MustParse("20Gi")
+	values = map[string]interface{}{"db": map[string]interface{}{"nodeSelector": map[string]interface{}{}}}
+	setStorageValues(values, &supacontrolv1alpha1.Storage{Size: &size, ClassName: "fast-ssd"})
+	db := values["db"].(map[string]interface{})
+	if _, ok := db["nodeSelector"]; !ok {
+		t.Error("expected existing db values to be kept")
+	}
+	persistence := db["persistence"].(map[string]interface{})
+	if persistence["enabled"] != true || persistence["size"] != "20Gi" || persistence["storageClassName"] != "fast-ssd" {
+		t.Errorf("unexpected persistence values %v", persistence)
+	}
+}
+
+func TestSetResourceValues(t *testing.T) {
+	values := map[string]interface{}{}
+	setResourceValues(values, nil)
+	if len(values) != 0 {
+		t.Errorf("expected no values without resources, got %v", values)
+	}
+
+	memory := resource.MustParse("3Gi")
+	values = map[string]interface{}{"db": map[string]interface{}{"persistence": map[string]interface{}{}}}
+	setResourceValues(values, &supacontrolv1alpha1.Resources{Tier: supacontrolv1alpha1.ResourceTierMedium, Memory: &memory})
+	db := values["db"].(map[string]interface{})
+	if _, ok := db["persistence"]; !ok {
+		t.Error("expected existing db values to be kept")
+	}
+	resources := db["resources"].(map[string]interface{})
+	for _, key := range []string{"requests", "limits"} {
+		list := resources[key].(map[string]interface{})
+		if list["cpu"] != "1" || list["memory"] != "3Gi" {
+			t.Errorf("unexpected %s %v", key, list)
+		}
+	}
+}
+
+// TestSetHighAvailabilityValues tests that the highly available components are scaled and
+// spread across nodes, and that other chart values are kept
+func TestSetHighAvailabilityValues(t *testing.T) {
+	instance := createBasicInstance("ha-values")
+	values := map[string]interface{}{}
+	setHighAvailabilityValues(values, instance)
+	if len(values) != 0 {
+		t.Errorf("expected no values without high availability, got %v", values)
+	}
+
+	instance.Spec.HighAvailability = &supacontrolv1alpha1.HighAvailability{Replicas: 3}
+	values = map[string]interface{}{"auth": map[string]interface{}{"environment": map[string]interface{}{}}}
+	setHighAvailabilityValues(values, instance)
+	for _, component := range []string{"kong", "auth", "realtime"} {
+		section := values[component].(map[string]interface{})
+		if section["replicaCount"] != int32(3) {
+			t.Errorf("expected 3 %s replicas, got %v", component, section["replicaCount"])
+		}
+		terms := section["affinity"].(map[string]interface{})["podAntiAffinity"].(map[string]interface{})["preferredDuringSchedulingIgnoredDuringExecution"].([]interface{})
+		term := terms[0].(map[string]interface{})["podAffinityTerm"].(map[string]interface{})
+		matchLabels := term["labelSelector"].(map[string]interface{})["matchLabels"].(map[string]interface{})
+		if term["topologyKey"] != corev1.LabelHostname || matchLabels["app.kubernetes.io/name"] != "supabase-"+component ||
+			matchLabels["app.kubernetes.io/instance"] != instance.Spec.ProjectName {
+			t.Errorf("unexpected %s anti-affinity %v", component, term)
+		}
+	}
+	if _, ok := values["auth"].(map[string]interface{})["environment"]; !ok {
+		t.Error("expected existing auth values to be kept")
+	}
+	if _, ok := values["db"]; ok {
+		t.Error("expected the database to keep a single replica")
+	}
+}
+
+// TestSetEnvValues tests that instance environment variables are routed to their
+// component and override values set by shared service profiles
+func TestSetEnvValues(t *testing.T) {
+	values := profiles.Values([]*profiles.Profile{{
+		Type:     profiles.TypeSMTP,
+		Settings: map[string]string{"host": "smtp.example.com", "port": "587"},
+	}}, "https://api.example.com")
+	setEnvValues(values, map[string]string{
+		"GOTRUE_DISABLE_SIGNUP": "true",
+		"SMTP_PORT":             "2525",
+		"PGRST_DB_MAX_ROWS":     "500",
+	})
+
+	auth := values["auth"].(map[string]interface{})["environment"].(map[string]interface{})
+	if auth["GOTRUE_DISABLE_SIGNUP"] != "true" || auth["SMTP_PORT"] != "2525" || auth["SMTP_HOST"] != "smtp.example.com" {
+		t.Errorf("unexpected auth environment %v", auth)
+	}
+	rest := values["rest"].(map[string]interface{})["environment"].(map[string]interface{})
+	if rest["PGRST_DB_MAX_ROWS"] != "500" {
+		t.Errorf("unexpected rest environment %v", rest)
+	}
+}
+
+// TestValidateEnv tests that only allowlisted variables are accepted
+func TestValidateEnv(t *testing.T) {
+	if err := ValidateEnv(map[string]string{"GOTRUE_DISABLE_SIGNUP": "true"}); err != nil {
+		t.Errorf("expected an allowlisted variable to be accepted, got %v", err)
+	}
+	if err := ValidateEnv(map[string]string{"SMTP_PASS": "hunter2"}); err == nil {
+		t.Error("expected a secret to be rejected")
+	}
+	if err := ValidateEnv(map[string]string{"SMTP_HOST": strings.Repeat("x", MaxEnvValueLength+1)}); err == nil {
+		t.Error("expected a value that is too long to be rejected")
+	}
+}
+
+// TestSetContainerEnv tests that SMTP settings override the chart's variables in place and
+// are removed again without touching other variables
+func TestSetContainerEnv(t *testing.T) {
+	container := &corev1.Container{Env: []corev1.EnvVar{
+		{Name: "GOTRUE_SITE_URL", Value: "https://example.com"},
+		{Name: "GOTRUE_SMTP_HOST", Value: "chart.example.com"},
+		{Name: "GOTRUE_JWT_EXP", Value: "3600"},
+	}}
+	smtp := &supacontrolv1alpha1.SMTPSettings{Host: "smtp.example.com", Port: 587}
+
+	setContainerEnv(container, smtpEnvNames, smtpEnv(smtp))
+	if len(container.Env) != 8 {
+		t.Fatalf("expected 8 variables, got %v", container.Env)
+	}
+	if container.Env[1].Name != "GOTRUE_SMTP_HOST" || container.Env[1].Value != "smtp.example.com" {
+		t.Errorf("expected the chart's SMTP host to be overridden in place, got %v", container.Env[1])
+	}
+	if pass := container.Env[len(container.Env)-1]; pass.ValueFrom == nil || pass.ValueFrom.SecretKeyRef.Name != SMTPSecretName {
+		t.Errorf("expected the password to be read from %s, got %v", SMTPSecretName, pass)
+	}
+
+	setContainerEnv(container, smtpEnvNames, nil)
+	if len(container.Env) != 2 || container.Env[0].Name != "GOTRUE_SITE_URL" || container.Env[1].Name != "GOTRUE_JWT_EXP" {
+		t.Errorf("expected only the SMTP variables to be removed, got %v", container.Env)
+	}
+}
+
+// TestSMTPChecksum tests that changing the SMTP password changes the checksum that rolls
+// the auth pods
+func TestSMTPChecksum(t *testing.T) {
+	smtp := &supacontrolv1alpha1.SMTPSettings{Host: "smtp.example.com", Port: 587, User: "mailer"}
+	if smtpChecksum(smtp, []byte("one")) == smtpChecksum(smtp, []byte("two")) {
+		t.Error("expected a new password to change the checksum")
+	}
+	if smtpChecksum(smtp, []byte("one")) != smtpChecksum(smtp.DeepCopy(), []byte("one")) {
+		t.Error("expected equal settings to have equal checksums")
+	}
+}
+
+// TestReconcileOAuth tests that OAuth client secrets reach the auth Deployment through a
+// Secret reference and are removed again when the profile is detached
+func TestReconcileOAuth(t *testing.T) {
+	ctx := context.Background()
+	testScheme := runtime.NewScheme()
+	for _, add := range []func(*runtime.Scheme) error{supacontrolv1alpha1.AddToScheme, corev1.AddToScheme, appsv1.AddToScheme} {
+		if err := add(testScheme); err != nil {
+			t.Fatalf("Failed to build scheme: %v", err)
+		}
+	}
+	profile := profiles.ToSecret(&profiles.Profile{
+		Name:     "github",
+		Type:     profiles.TypeOAuth,
+		Settings: map[string]string{"provider": "github", "client_id": "abc"},
+		Secrets:  map[string]string{"client_secret": "xyz"},
+	})
+	auth := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "app-supabase-auth", Namespace: "supa-app"},
+		Spec: appsv1.DeploymentSpec{Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{Name: "auth", Image: "supabase/gotrue:v2.151.0"}},
+		}}},
+	}
+	instance := &supacontrolv1alpha1.SupabaseInstance{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", UID: "uid-app"},
+		Spec:       supacontrolv1alpha1.SupabaseInstanceSpec{ProjectName: "app", Profiles: []string{"github"}},
+		Status:     supacontrolv1alpha1.SupabaseInstanceStatus{Namespace: "supa-app"},
+	}
+	c := fake.NewClientBuilder().WithScheme(testScheme).WithObjects(profile, auth, instance).Build()
+	reconciler := &SupabaseInstanceReconciler{Client: c, Scheme: testScheme}
+
+	if err := reconciler.reconcileOAuth(ctx, instance); err != nil {
+		t.Fatalf("reconcileOAuth failed: %v", err)
+	}
+	secret := &corev1.Secret{}
+	if err := c.Get(ctx, client.ObjectKey{Namespace: "supa-app", Name: OAuthSecretName}, secret); err != nil {
+		t.Fatalf("OAuth Secret not found: %v", err)
+	}
+	if string(secret.Data["GOTRUE_EXTERNAL_GITHUB_SECRET"]) != "xyz" {
+		t.Errorf("Expected the client secret in %s, got %v", OAuthSecretName, secret.Data)
+	}
+	if err := c.Get(ctx, client.ObjectKeyFromObject(auth), auth); err != nil {
+		t.Fatalf("Failed to get auth Deployment: %v", err)
+	}
+	env := auth.Spec.Template.Spec.Containers[0].Env
+	if len(env) != 1 || env[0].Value != "" || env[0].ValueFrom == nil || env[0].ValueFrom.SecretKeyRef.Name != OAuthSecretName {
+		t.Errorf("Expected the client secret to be read from %s, got %+v", OAuthSecretName, env)
+	}
+	if auth.Spec.Template.Annotations[AnnotationOAuthChecksum] == "" {
+		t.Error("Expected the OAuth checksum annotation to roll the auth pods")
+	}
+
+	instance.Spec.Profiles = nil
+	if err := reconciler.reconcileOAuth(ctx, instance); err != nil {
+		t.Fatalf("reconcileOAuth failed: %v", err)
+	}
+	if err := c.Get(ctx, client.ObjectKey{Namespace: "supa-app", Name: OAuthSecretName}, secret); !apierrors.IsNotFound(err) {
+		t.Errorf("Expected the OAuth Secret to be deleted, got %v", err)
+	}
+	if err := c.Get(ctx, client.ObjectKeyFromObject(auth), auth); err != nil {
+		t.Fatalf("Failed to get auth Deployment: %v", err)
+	}
+	if len(auth.Spec.Template.Spec.Containers[0].Env) != 0 {
+		t.Errorf("Expected the client secret variable to be removed, got %+v", auth.Spec.Template.Spec.Containers[0].Env)
+	}
+}
+
+// TestNeedsProfileSync tests that editing an attached profile calls for an upgrade
+func TestNeedsProfileSync(t *testing.T) {
+	ctx := context.Background()
+	testScheme := runtime.NewScheme()
+	if err := corev1.AddToScheme(testScheme); err != nil {
+		t.Fatalf("Failed to build scheme: %v", err)
+	}
+	profile := &profiles.Profile{
+		Name:     "mail",
+		Type:     profiles.TypeSMTP,
+		Settings: map[string]string{"host": "smtp.example.com"},
+	}
+	c := fake.NewClientBuilder().WithScheme(testScheme).WithObjects(profiles.ToSecret(profile)).Build()
+	reconciler := &SupabaseInstanceReconciler{Client: c, Scheme: testScheme}
+	instance := &supacontrolv1alpha1.SupabaseInstance{
+		Spec:   supacontrolv1alpha1.SupabaseInstanceSpec{ProjectName: "app", Profiles: []string{"mail"}},
+		Status: supacontrolv1alpha1.SupabaseInstanceStatus{ProfilesChecksum: profiles.Checksum([]*profiles.Profile{profile})},
+	}
+
+	if sync, err := reconciler.needsProfileSync(ctx, instance); err != nil || sync {
+		t.Fatalf("Expected no sync for applied profiles, got %v, %v", sync, err)
+	}
+
+	profile.Settings["host"] = "relay.example.com"
+	if err := c.Update(ctx, profiles.ToSecret(profile)); err != nil {
+		t.Fatalf("Failed to update profile: %v", err)
+	}
+	if sync, err := reconciler.needsProfileSync(ctx, instance); err != nil || !sync {
+		t.Errorf("Expected an edited profile to call for a sync, got %v, %v", sync, err)
+	}
+
+	instance.Spec.Profiles = nil
+	instance.Status.ProfilesChecksum = ""
+	if sync, err := reconciler.needsProfileSync(ctx, instance); err != nil || sync {
+		t.Errorf("Expected no sync without profiles, got %v, %v", sync, err)
+	}
+}
+
+// TestAppendPhaseTransition tests that the phase history keeps only the newest transitions
+func TestAppendPhaseTransition(t *testing.T) {
+	var history []supacontrolv1alpha1.PhaseTransition
+	for i := 0; i < supacontrolv1alpha1.MaxPhaseHistory+5; i++ {
+		history = appendPhaseTransition(history, supacontrolv1alpha1.PhaseTransition{
+			To:     supacontrolv1alpha1.PhaseRunning,
+			Reason: fmt.Sprintf("transition-%d", i),
+		})
+	}
+	if len(history) != supacontrolv1alpha1.MaxPhaseHistory {
+		t.Fatalf("expected %d transitions, got %d", supacontrolv1alpha1.MaxPhaseHistory, len(history))
+	}
+	if history[0].Reason != "transition-5" {
+		t.Errorf("expected the oldest transitions to be dropped, got %q first", history[0].Reason)
+	}
+}
+
+// TestTransitionReason tests that transitions are explained by the error of a failed
+// instance or the most recently changed condition
+func TestTransitionReason(t *testing.T) {
+	earlier := metav1.NewTime(time.Now().Add(-time.Minute))
+	later := metav1.Now()
+	instance := &supacontrolv1alpha1.SupabaseInstance{Status: supacontrolv1alpha1.SupabaseInstanceStatus{
+		Phase: supacontrolv1alpha1.PhaseRunning,
+		Conditions: []metav1.Condition{
+			{Type: supacontrolv1alpha1.ConditionTypeReady, Reason: "ProvisioningComplete", LastTransitionTime: later},
+			{Type: supacontrolv1alpha1.ConditionTypeStorageExpanded, Reason: "ExpansionRequested", LastTransitionTime: earlier},
+		},
+	}}
+	if reason := transitionReason(instance); reason != "ProvisioningComplete" {
+		t.Errorf("expected the newest condition's reason, got %q", reason)
+	}
+
+	instance.Status.Phase = supacontrolv1alpha1.PhaseFailed
+	instance.Status.ErrorMessage = strings.Repeat("x", maxTransitionReasonLength+10)
+	if reason := transitionReason(instance); len(reason) != maxTransitionReasonLength {
+		t.Errorf("expected the error message truncated to %d characters, got %d", maxTransitionReasonLength, len(reason))
+	}
+
+	if history := PhaseHistory(&supacontrolv1alpha1.SupabaseInstance{ObjectMeta: metav1.ObjectMeta{
+		Annotations: map[string]string{supacontrolv1alpha1.AnnotationPhaseHistory: "not json"},
+	}}); history != nil {
+		t.Errorf("expected an unreadable history to be empty, got %+v", history)
+	}
+}
+
+// TestReconcileRunning_ExpandsStorage verifies that increasing Spec.Storage.Size on a
+// running instance grows its Postgres volume claim
+func TestReconcileRunning_ExpandsStorage(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	reconciler := createTestReconciler()
+
+	instance := createBasicInstance(t.Name())
+	if err := k8sClient.Create(ctx, instance); err != nil {
+		t.Fatalf("Failed to create test instance: %v", err)
+	}
+	defer cleanupInstance(ctx, t, instance)
+
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: instance.Name}}
+	reconcileToPending(ctx, t, reconciler, instance.Name)
+	reconcileToProvisioning(ctx, t, reconciler, instance.Name)
+
+	current := getInstanceState(ctx, t, instance.Name)
+	if current != nil && current.Status.ProvisioningJobName != "" {
+		setJobSucceeded(ctx, t, current.Status.ProvisioningJobName)
+	}
+	if _, err := reconciler.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Failed to reconcile Running state: %v", err)
+	}
+	current = getInstanceState(ctx, t, instance.Name)
+	if current == nil || current.Status.Phase != supacontrolv1alpha1.PhaseRunning {
+		t.Fatalf("Instance not in Running phase")
+	}
+
+	claim := &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      postgresClaimName(current),
+			Namespace: current.Status.Namespace,
+		},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+			Resources: corev1.VolumeResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("8Gi")},
+			},
+		},
+	}
+	if err := k8sClient.Create(ctx, claim); err != nil {
+		t.Fatalf("Failed to create volume claim: %v", err)
+	}
+	// Only bound claims can be expanded
+	claim.Status.Phase = corev1.ClaimBound
+	claim.Status.Capacity = corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("8Gi")}
+	if err := k8sClient.Status().Update(ctx, claim); err != nil {
+		t.Fatalf("Failed to bind volume claim: %v", err)
+	}
+
+	size := resource.MustParse("20Gi")
+	current.Spec.Storage = &supacontrolv1alpha1.Storage{Size: &size}
+	if err := k8sClient.Update(ctx, current); err != nil {
+		t.Fatalf("Failed to resize storage: %v", err)
+	}
+	if _, err := reconciler.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile resized instance failed: %v", err)
+	}
+
+	expanded := &corev1.PersistentVolumeClaim{}
+	if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(claim), expanded); err != nil {
+		t.Fatalf("Failed to get volume claim: %v", err)
+	}
+	if request := expanded.Spec.Resources.Requests[corev1.ResourceStorage]; request.Cmp(size) != 0 {
+		t.Errorf("Expected the claim to request 20Gi, got %s", request.String())
+	}
+	current = getInstanceState(ctx, t, instance.Name)
+	if !meta.IsStatusConditionTrue(current.Status.Conditions, supacontrolv1alpha1.ConditionTypeStorageExpanded) {
+		t.Errorf("Expected StorageExpanded condition to be true, got %+v", current.Status.Conditions)
+	}
+}
+
+// TestReconcilePendingDeletion verifies that a trashed instance is scaled to zero, can be
+// recovered before its grace period is over and is deleted once it is
+func TestReconcilePendingDeletion(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	reconciler := createTestReconciler()
+
+	instance := createBasicInstance(t.Name())
+	if err := k8sClient.Create(ctx, instance); err != nil {
+		t.Fatalf("Failed to create test instance: %v", err)
+	}
+	defer cleanupInstance(ctx, t, instance)
+
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: instance.Name}}
+	reconcileToPending(ctx, t, reconciler, instance.Name)
+	reconcileToProvisioning(ctx, t, reconciler, instance.Name)
+
+	current := getInstanceState(ctx, t, instance.Name)
+	if current != nil && current.Status.ProvisioningJobName != "" {
+		setJobSucceeded(ctx, t, current.Status.ProvisioningJobName)
+	}
+	if _, err := reconciler.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Failed to reconcile Running state: %v", err)
+	}
+	current = getInstanceState(ctx, t, instance.Name)
+	if current == nil || current.Status.Phase != supacontrolv1alpha1.PhaseRunning {
+		t.Fatalf("Instance not in Running phase")
+	}
+
+	// Move the instance to the trash
+	now := metav1.Now()
+	current.Spec.PendingDeletion = &supacontrolv1alpha1.PendingDeletion{
+		RequestedAt: now,
+		PurgeAfter:  metav1.NewTime(now.Add(time.Hour)),
+	}
+	if err := k8sClient.Update(ctx, current); err != nil {
+		t.Fatalf("Failed to delete instance: %v", err)
+	}
+	result, err := reconciler.Reconcile(ctx, req)
+	if err != nil {
+		t.Fatalf("Reconcile pending deletion failed: %v", err)
+	}
+	if result.RequeueAfter <= 0 || result.RequeueAfter > DefaultResyncInterval {
+		t.Errorf("Expected a requeue within %v, got %v", DefaultResyncInterval, result.RequeueAfter)
+	}
+	current = getInstanceState(ctx, t, instance.Name)
+	if current.Status.Phase != supacontrolv1alpha1.PhasePendingDeletion {
+		t.Errorf("Expected phase PendingDeletion, got %s", current.Status.Phase)
+	}
+
+	// Recover it
+	current.Spec.PendingDeletion = nil
+	if err := k8sClient.Update(ctx, current); err != nil {
+		t.Fatalf("Failed to undelete instance: %v", err)
+	}
+	if _, err := reconciler.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile recovered instance failed: %v", err)
+	}
+	current = getInstanceState(ctx, t, instance.Name)
+	if current.Status.Phase != supacontrolv1alpha1.PhaseRunning {
+		t.Errorf("Expected phase Running after recovery, got %s", current.Status.Phase)
+	}
+
+	// Deletion protection holds back the purge of an expired pending deletion
+	current.Spec.DeletionProtection = true
+	current.Spec.PendingDeletion = &supacontrolv1alpha1.PendingDeletion{
+		RequestedAt: metav1.NewTime(now.Add(-2 * time.Hour)),
+		PurgeAfter:  metav1.NewTime(now.Add(-time.Hour)),
+	}
+	if err := k8sClient.Update(ctx, current); err != nil {
+		t.Fatalf("Failed to update instance: %v", err)
+	}
+	if _, err := reconciler.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile protected instance failed: %v", err)
+	}
+	current = getInstanceState(ctx, t, instance.Name)
+	if !current.DeletionTimestamp.IsZero() {
+		t.Fatal("Expected a protected instance not to be purged")
+	}
+
+	// Without protection the instance is purged
+	current.Spec.DeletionProtection = false
+	if err := k8sClient.Update(ctx, current); err != nil {
+		t.Fatalf("Failed to update instance: %v", err)
+	}
+	if _, err := reconciler.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile expired instance failed: %v", err)
+	}
+	current = getInstanceState(ctx, t, instance.Name)
+	if current != nil && current.DeletionTimestamp.IsZero() {
+		t.Error("Expected the instance to be deleted once its grace period is over")
+	}
+}
+
+// recordingNotifier records the events it is sent, failing while err is set
+type recordingNotifier struct {
+	events []notify.Event
+	err    error
+}
+
+func (n *recordingNotifier) Notify(_ context.Context, event notify.Event) error {
+	if n.err != nil {
+		return n.err
+	}
+	n.events = append(n.events, event)
+	return nil
+}
+
+// TestReconcileExpiry verifies that an ephemeral instance is warned about once within an
+// hour of its expiry, that extending it withdraws the warning, and that it is deleted
+// once expired unless deletion protection holds it back
+func TestReconcileExpiry(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	reconciler := createTestReconciler()
+	notifier := &recordingNotifier{err: errors.New("webhook unavailable")}
+	reconciler.Notifier = notifier
+
+	instance := createBasicInstance(t.Name())
+	instance.Spec.TTL = &metav1.Duration{Duration: 30 * time.Minute}
+	if err := k8sClient.Create(ctx, instance); err != nil {
+		t.Fatalf("Failed to create test instance: %v", err)
+	}
+	defer cleanupInstance(ctx, t, instance)
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: instance.Name}}
+
+	// A failed notification leaves the warning unset, so it is sent again
+	if _, err := reconciler.Reconcile(ctx, req); err == nil {
+		t.Fatal("Expected the failed notification to fail the reconciliation")
+	}
+	current := getInstanceState(ctx, t, instance.Name)
+	if meta.FindStatusCondition(current.Status.Conditions, supacontrolv1alpha1.ConditionTypeExpiring) != nil {
+		t.Error("Expected no Expiring condition before the notification was sent")
+	}
+
+	notifier.err = nil
+	for range 2 {
+		result, err := reconciler.Reconcile(ctx, req)
+		if err != nil {
+			t.Fatalf("Reconcile expiring instance failed: %v", err)
+		}
+		if result.RequeueAfter <= 0 || result.RequeueAfter > 30*time.Minute {
+			t.Errorf("Expected a requeue by the expiry, got %v", result.RequeueAfter)
+		}
+	}
+	if len(notifier.events) != 1 || notifier.events[0].Type != notify.EventInstanceExpiring || notifier.events[0].Instance != instance.Spec.ProjectName {
+		t.Errorf("Expected one expiry notification, got %+v", notifier.events)
+	}
+	current = getInstanceState(ctx, t, instance.Name)
+	if !meta.IsStatusConditionTrue(current.Status.Conditions, supacontrolv1alpha1.ConditionTypeExpiring) {
+		t.Errorf("Expected the Expiring condition, got %+v", current.Status.Conditions)
+	}
+	if current.Status.ExpiresAt == nil || !current.Status.ExpiresAt.Equal(current.ExpiryTime()) {
+		t.Errorf("Expected expiresAt %v, got %v", current.ExpiryTime(), current.Status.ExpiresAt)
+	}
+
+	// Extending the instance withdraws the warning
+	current.Spec.TTL = &metav1.Duration{Duration: 3 * time.Hour}
+	if err := k8sClient.Update(ctx, current); err != nil {
+		t.Fatalf("Failed to extend instance: %v", err)
+	}
+	result, err := reconciler.Reconcile(ctx, req)
+	if err != nil {
+		t.Fatalf("Reconcile extended instance failed: %v", err)
+	}
+	if result.RequeueAfter <= 0 || result.RequeueAfter > 2*time.Hour {
+		t.Errorf("Expected a requeue by the warning, got %v", result.RequeueAfter)
+	}
+	current = getInstanceState(ctx, t, instance.Name)
+	if meta.FindStatusCondition(current.Status.Conditions, supacontrolv1alpha1.ConditionTypeExpiring) != nil {
+		t.Errorf("Expected the Expiring condition to be removed, got %+v", current.Status.Conditions)
+	}
+
+	// Deletion protection holds back the deletion of an expired instance
+	current.Spec.TTL = &metav1.Duration{Duration: time.Nanosecond}
+	current.Spec.DeletionProtection = true
+	if err := k8sClient.Update(ctx, current); err != nil {
+		t.Fatalf("Failed to update instance: %v", err)
+	}
+	if _, err := reconciler.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile protected instance failed: %v", err)
+	}
+	current = getInstanceState(ctx, t, instance.Name)
+	if !current.DeletionTimestamp.IsZero() {
+		t.Fatal("Expected a protected instance not to be deleted")
+	}
+
+	// Without protection the instance is deleted
+	current.Spec.DeletionProtection = false
+	if err := k8sClient.Update(ctx, current); err != nil {
+		t.Fatalf("Failed to update instance: %v", err)
+	}
+	if _, err := reconciler.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile expired instance failed: %v", err)
+	}
+	current = getInstanceState(ctx, t, instance.Name)
+	if current != nil && current.DeletionTimestamp.IsZero() {
+		t.Error("Expected the instance to be deleted once its TTL has passed")
+	}
+}
+
+// TestUpdateStatus_ConflictsWithConcurrentWrite tests that a status computed from the
+// version of an instance a reconcile read is not written over a concurrent change, and
+// TestFinishUpgrade_ResizeWithChartUpgrade tests that an upgrade Job that also resized
+// the database reports the chart upgrade
+func TestFinishUpgrade_ResizeWithChartUpgrade(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	testScheme := runtime.NewScheme()
+	if err := supacontrolv1alpha1.AddToScheme(testScheme); err != nil {
+		t.Fatalf("Failed to build scheme: %v", err)
+	}
+	instance := &supacontrolv1alpha1.SupabaseInstance{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Generation: 2},
+		Spec: supacontrolv1alpha1.SupabaseInstanceSpec{
+			ProjectName:  "app",
+			ChartVersion: "0.2.0",
+			Resources:    &supacontrolv1alpha1.Resources{Tier: "large"},
+		},
+		Status: supacontrolv1alpha1.SupabaseInstanceStatus{
+			Phase:              supacontrolv1alpha1.PhaseUpgrading,
+			ObservedGeneration: 1,
+			ChartVersion:       "0.1.0",
+			Conditions: []metav1.Condition{
+				{Type: supacontrolv1alpha1.ConditionTypeUpgraded, Status: metav1.ConditionFalse, ObservedGeneration: 2, Reason: "UpgradeInProgress"},
+				{Type: supacontrolv1alpha1.ConditionTypeResizing, Status: metav1.ConditionTrue, ObservedGeneration: 2, Reason: "ResizeInProgress"},
+			},
+		},
+	}
+	c := fake.NewClientBuilder().WithScheme(testScheme).WithObjects(instance).
+		WithStatusSubresource(&supacontrolv1alpha1.SupabaseInstance{}).Build()
+	reconciler := &SupabaseInstanceReconciler{Client: c, Scheme: testScheme}
+
+	if _, err := reconciler.finishUpgrade(ctx, instance, ""); err != nil {
+		t.Fatalf("finishUpgrade failed: %v", err)
+	}
+	if instance.Status.ChartVersion != "0.2.0" {
+		t.Errorf("Expected chart version 0.2.0, got %q", instance.Status.ChartVersion)
+	}
+	if !meta.IsStatusConditionTrue(instance.Status.Conditions, supacontrolv1alpha1.ConditionTypeUpgraded) {
+		t.Errorf("Expected the Upgraded condition to be true, got %+v", instance.Status.Conditions)
+	}
+	if instance.Status.Resources == nil || instance.Status.Resources.Tier != "large" {
+		t.Errorf("Expected the resized resources to be recorded, got %+v", instance.Status.Resources)
+	}
+}
+
+// that consecutive status updates of one reconcile build on each other
+func TestUpdateStatus_ConflictsWithConcurrentWrite(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	testScheme := runtime.NewScheme()
+	if err := supacontrolv1alpha1.AddToScheme(testScheme); err != nil {
+		t.Fatalf("Failed to build scheme: %v", err)
+	}
+	instance := &supacontrolv1alpha1.SupabaseInstance{ObjectMeta: metav1.ObjectMeta{Name: "app"}}
+	c := fake.NewClientBuilder().WithScheme(testScheme).WithObjects(instance).
+		WithStatusSubresource(&supacontrolv1alpha1.SupabaseInstance{}).Build()
+	reconciler := &SupabaseInstanceReconciler{Client: c, Scheme: testScheme}
+	get := func() *supacontrolv1alpha1.SupabaseInstance {
+		current := &supacontrolv1alpha1.SupabaseInstance{}
+		if err := c.Get(ctx, client.ObjectKeyFromObject(instance), current); err != nil {
+			t.Fatalf("Failed to get instance: %v", err)
+		}
+		return current
+	}
+
+	read := get()
+	readCtx := withStatusBase(ctx, read)
+
+	read.Status.Phase = supacontrolv1alpha1.PhasePending
+	if err := reconciler.updateStatus(readCtx, read); err != nil {
+		t.Fatalf("First status update failed: %v", err)
+	}
+	read.Status.Phase = supacontrolv1alpha1.PhaseProvisioning
+	if err := reconciler.updateStatus(readCtx, read); err != nil {
+		t.Fatalf("Second status update failed: %v", err)
+	}
+
+	// The API resets the instance meanwhile, bumping its resourceVersion
+	current := get()
+	current.Status.Phase = supacontrolv1alpha1.PhasePending
+	if err := c.Status().Update(ctx, current); err != nil {
+		t.Fatalf("Failed to reset instance: %v", err)
+	}
+
+	read.Status.Phase = supacontrolv1alpha1.PhaseFailed
+	err := reconciler.updateStatus(readCtx, read)
+	if !apierrors.IsConflict(err) {
+		t.Fatalf("Expected a conflict, got %v", err)
+	}
+
+	stored := get()
+	if stored.Status.Phase != supacontrolv1alpha1.PhasePending {
+		t.Errorf("Expected the concurrent reset to be kept, got phase %s", stored.Status.Phase)
+	}
+}
+
+// TestReconcileRunning_NetworkIsolation tests that network-isolated instances get their
+// NetworkPolicies before they run, and lose them once isolation is turned off
+func TestReconcileRunning_NetworkIsolation(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	reconciler := createTestReconciler()
+	reconciler.IngressControllerNamespace = "traefik"
+
+	instance := createBasicInstance(t.Name())
+	instance.Spec.NetworkIsolation = true
+	if err := k8sClient.Create(ctx, instance); err != nil {
+		t.Fatalf("Failed to create test instance: %v", err)
+	}
+	defer cleanupInstance(ctx, t, instance)
+
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: instance.Name}}
+	reconcileToPending(ctx, t, reconciler, instance.Name)
+	reconcileToProvisioning(ctx, t, reconciler, instance.Name)
+
+	current := getInstanceState(ctx, t, instance.Name)
+	if current == nil || current.Status.ProvisioningJobName == "" {
+		t.Fatal("Provisioning Job not created")
+	}
+	setJobSucceeded(ctx, t, current.Status.ProvisioningJobName)
+	if _, err := reconciler.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Failed to reconcile Running state: %v", err)
+	}
+
+	current = getInstanceState(ctx, t, instance.Name)
+	if current.Status.Phase != supacontrolv1alpha1.PhaseRunning {
+		t.Fatalf("Instance not in Running phase: %s", current.Status.Phase)
+	}
+
+	deny := &networkingv1.NetworkPolicy{}
+	denyKey := client.ObjectKey{Namespace: current.Status.Namespace, Name: defaultDenyPolicyName(current.Spec.ProjectName)}
+	if err := k8sClient.Get(ctx, denyKey, deny); err != nil {
+		t.Fatalf("Failed to get default-deny NetworkPolicy: %v", err)
+	}
+	if len(deny.Spec.Ingress) != 0 {
+		t.Errorf("Expected the default-deny policy to allow nothing, got %+v", deny.Spec.Ingress)
+	}
+
+	allow := &networkingv1.NetworkPolicy{}
+	allowKey := client.ObjectKey{Namespace: current.Status.Namespace, Name: allowPolicyName(current.Spec.ProjectName)}
+	if err := k8sClient.Get(ctx, allowKey, allow); err != nil {
+		t.Fatalf("Failed to get allow NetworkPolicy: %v", err)
+	}
+	peers := allow.Spec.Ingress[0].From
+	if len(peers) != 2 || peers[1].NamespaceSelector.MatchLabels[namespaceNameLabel] != "traefik" {
+		t.Errorf("Expected the namespace's pods and the ingress controller to be allowed, got %+v", peers)
+	}
+	if len(allow.Spec.Ingress) != 2 || allow.Spec.Ingress[1].From[0].NamespaceSelector.MatchLabels[namespaceNameLabel] != ControllerNamespace ||
+		len(allow.Spec.Ingress[1].Ports) != 2 || allow.Spec.Ingress[1].Ports[0].Port.IntValue() != StorageAPIPort ||
+		allow.Spec.Ingress[1].Ports[1].Port.IntValue() != KongPort {
+		t.Errorf("Expected the controller namespace to be allowed to the storage and gateway ports only, got %+v", allow.Spec.Ingress)
+	}
+
+	current.Spec.NetworkIsolation = false
+	if err := k8sClient.Update(ctx, current); err != nil {
+		t.Fatalf("Failed to turn off network isolation: %v", err)
+	}
+	if _, err := reconciler.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Failed to reconcile without network isolation: %v", err)
+	}
+	for _, key := range []client.ObjectKey{denyKey, allowKey} {
+		if err := k8sClient.Get(ctx, key, &networkingv1.NetworkPolicy{}); client.IgnoreNotFound(err) != nil || err == nil {
+			t.Errorf("Expected NetworkPolicy %s to be removed, got %v", key.Name, err)
+		}
+	}
+}
+
+// TestCheckHealth_SkipsFinishedPods tests that completed, failed and evicted pods do not
+// count against the health of an instance
+func TestCheckHealth_SkipsFinishedPods(t *testing.T) {
+	t.Parallel()
+	pod := func(name string, phase corev1.PodPhase, ready bool) *corev1.Pod {
+		status := corev1.ConditionFalse
+		if ready {
+			status = corev1.ConditionTrue
+		}
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "supa-app"},
+			Status: corev1.PodStatus{
+				Phase:      phase,
+				Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: status}},
+			},
+		}
+	}
+	evicted := pod("rest-evicted", corev1.PodFailed, false)
+	evicted.Status.Reason = "Evicted"
+
+	reader := fake.NewClientBuilder().WithObjects(
+		pod("rest", corev1.PodRunning, true),
+		pod("migrate", corev1.PodSucceeded, false),
+		pod("auth-crashed", corev1.PodFailed, false),
+		evicted,
+	).Build()
+	reconciler := &SupabaseInstanceReconciler{APIReader: reader}
+	instance := &supacontrolv1alpha1.SupabaseInstance{}
+	instance.Status.Namespace = "supa-app"
+
+	healthy, reason, err := reconciler.checkHealth(context.Background(), instance)
+	if err != nil {
+		t.Fatalf("checkHealth failed: %v", err)
+	}
+	if !healthy {
+		t.Errorf("Expected the instance to be healthy, got %q", reason)
+	}
+}
+
+// TestReconcileRunning_HealthHysteresis tests that Ready only flips after the configured
+// number of health checks in a row failed or passed
+func TestReconcileRunning_HealthHysteresis(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	reconciler := createTestReconciler()
+	reconciler.HealthSuccessThreshold = 2
+	reconciler.HealthFailureThreshold = 3
+
+	instance := createBasicInstance(t.Name())
+	if err := k8sClient.Create(ctx, instance); err != nil {
+		t.Fatalf("Failed to create test instance: %v", err)
+	}
+	defer cleanupInstance(ctx, t, instance)
+
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: instance.Name}}
+	reconcileToPending(ctx, t, reconciler, instance.Name)
+	reconcileToProvisioning(ctx, t, reconciler, instance.Name)
+
+	current := getInstanceState(ctx, t, instance.Name)
+	if current == nil || current.Status.ProvisioningJobName == "" {
+		t.Fatal("Provisioning Job not created")
+	}
+	setJobSucceeded(ctx, t, current.Status.ProvisioningJobName)
+	if _, err := reconciler.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Failed to reconcile Running state: %v", err)
+	}
+
+	current = getInstanceState(ctx, t, instance.Name)
+	if current.Status.Phase != supacontrolv1alpha1.PhaseRunning {
+		t.Fatalf("Instance not in Running phase: %s", current.Status.Phase)
+	}
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: current.Status.Namespace}}
+	if err := client.IgnoreAlreadyExists(k8sClient.Create(ctx, ns)); err != nil {
+		t.Fatalf("Failed to create instance namespace: %v", err)
+	}
+	current.Status.Health = nil
+
+	// check runs one health check as if the previous one was long ago
+	check := func() {
+		t.Helper()
+		if current.Status.Health != nil {
+			current.Status.Health.LastCheckTime = nil
+		}
+		if _, err := reconciler.evaluateHealth(ctx, current); err != nil {
+			t.Fatalf("Failed to evaluate health: %v", err)
+		}
+		current = getInstanceState(ctx, t, instance.Name)
+	}
+	ready := func() bool {
+		return meta.IsStatusConditionTrue(current.Status.Conditions, supacontrolv1alpha1.ConditionTypeReady)
+	}
+
+	// No pods are running, but Ready only turns false after three failed checks
+	for i := 1; i < 3; i++ {
+		check()
+		if !ready() {
+			t.Fatalf("Expected the instance to stay Ready after %d failed checks", i)
+		}
+	}
+	check()
+	if ready() || !meta.IsStatusConditionTrue(current.Status.Conditions, supacontrolv1alpha1.ConditionTypeDegraded) {
+		t.Fatalf("Expected the instance to be Degraded, got %+v", current.Status.Conditions)
+	}
+	if current.Status.Health.ConsecutiveFailures != 3 || current.Status.Health.LastFailure != "No pods are running" {
+		t.Errorf("Unexpected health status: %+v", current.Status.Health)
+	}
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "db-0", Namespace: current.Status.Namespace},
+		Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "db", Image: "postgres"}}},
+	}
+	if err := k8sClient.Create(ctx, pod); err != nil {
+		t.Fatalf("Failed to create pod: %v", err)
+	}
+	pod.Status.Phase = corev1.PodRunning
+	pod.Status.Conditions = []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}}
+	if err := k8sClient.Status().Update(ctx, pod); err != nil {
+		t.Fatalf("Failed to mark pod ready: %v", err)
+	}
+
+	check()
+	if ready() {
+		t.Fatal("Expected the instance to stay Degraded after one passed check")
+	}
+	check()
+	if !ready() || meta.IsStatusConditionTrue(current.Status.Conditions, supacontrolv1alpha1.ConditionTypeDegraded) {
+		t.Fatalf("Expected the instance to be Ready again, got %+v", current.Status.Conditions)
+	}
+	if current.Status.Health.ConsecutiveFailures != 0 || current.Status.Health.LastFailure != "" {
+		t.Errorf("Expected the failure streak to be reset, got %+v", current.Status.Health)
+	}
+}
+
+// TestReconcileRunning_HighAvailability tests that the health check keeps a
+// PodDisruptionBudget for each highly available component, recreates a deleted one and
+// removes them once high availability is turned off
+func TestReconcileRunning_HighAvailability(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	reconciler := createTestReconciler()
+
+	instance := createBasicInstance(t.Name())
+	instance.Spec.HighAvailability = &supacontrolv1alpha1.HighAvailability{Replicas: 2}
+	if err := k8sClient.Create(ctx, instance); err != nil {
+		t.Fatalf("Failed to create test instance: %v", err)
+	}
+	defer cleanupInstance(ctx, t, instance)
+
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: instance.Name}}
+	reconcileToPending(ctx, t, reconciler, instance.Name)
+	reconcileToProvisioning(ctx, t, reconciler, instance.Name)
+
+	current := getInstanceState(ctx, t, instance.Name)
+	if current == nil || current.Status.ProvisioningJobName == "" {
+		t.Fatal("Provisioning Job not created")
+	}
+	setJobSucceeded(ctx, t, current.Status.ProvisioningJobName)
+	if _, err := reconciler.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Failed to reconcile Running state: %v", err)
+	}
+	current = getInstanceState(ctx, t, instance.Name)
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: current.Status.Namespace}}
+	if err := client.IgnoreAlreadyExists(k8sClient.Create(ctx, ns)); err != nil {
+		t.Fatalf("Failed to create instance namespace: %v", err)
+	}
+
+	// check runs one health check as if the previous one was long ago
+	check := func() {
+		t.Helper()
+		if current.Status.Health != nil {
+			current.Status.Health.LastCheckTime = nil
+		}
+		if _, err := reconciler.evaluateHealth(ctx, current); err != nil {
+			t.Fatalf("Failed to evaluate health: %v", err)
+		}
+		current = getInstanceState(ctx, t, instance.Name)
+	}
+	budgetKey := func(component string) client.ObjectKey {
+		return client.ObjectKey{Namespace: current.Status.Namespace, Name: podDisruptionBudgetName(current.Spec.ProjectName, component)}
+	}
+
+	check()
+	for _, component := range highAvailabilityComponents {
+		budget := &policyv1.PodDisruptionBudget{}
+		if err := k8sClient.Get(ctx, budgetKey(component), budget); err != nil {
+			t.Fatalf("Failed to get %s PodDisruptionBudget: %v", component, err)
+		}
+		if budget.Spec.MinAvailable.IntValue() != 1 || budget.Spec.Selector.MatchLabels["app.kubernetes.io/name"] != "supabase-"+component {
+			t.Errorf("Unexpected %s PodDisruptionBudget %+v", component, budget.Spec)
+		}
+	}
+	if meta.FindStatusCondition(current.Status.Conditions, supacontrolv1alpha1.ConditionTypeHighAvailabilityReady) == nil {
+		t.Fatal("Expected a HighAvailabilityReady condition")
+	}
+
+	kong := &policyv1.PodDisruptionBudget{ObjectMeta: metav1.ObjectMeta{Name: budgetKey("kong").Name, Namespace: budgetKey("kong").Namespace}}
+	if err := k8sClient.Delete(ctx, kong); err != nil {
+		t.Fatalf("Failed to delete PodDisruptionBudget: %v", err)
+	}
+	check()
+	if err := k8sClient.Get(ctx, budgetKey("kong"), &policyv1.PodDisruptionBudget{}); err != nil {
+		t.Fatalf("Expected the deleted PodDisruptionBudget to be recreated: %v", err)
+	}
+
+	current.Spec.HighAvailability = nil
+	if err := k8sClient.Update(ctx, current); err != nil {
+		t.Fatalf("Failed to turn off high availability: %v", err)
+	}
+	current = getInstanceState(ctx, t, instance.Name)
+	check()
+	for _, component := range highAvailabilityComponents {
+		if err := k8sClient.Get(ctx, budgetKey(component), &policyv1.PodDisruptionBudget{}); client.IgnoreNotFound(err) != nil || err == nil {
+			t.Errorf("Expected the %s PodDisruptionBudget to be removed, got %v", component, err)
+		}
+	}
+	if meta.FindStatusCondition(current.Status.Conditions, supacontrolv1alpha1.ConditionTypeHighAvailabilityReady) != nil {
+		t.Error("Expected the HighAvailabilityReady condition to be removed")
+	}
+}
+
+// TestReconcileRunning_DetectsRemovedRelease tests that a running instance whose Helm
+// release was uninstalled outside of SupaControl is failed with the reason
+func TestReconcileRunning_DetectsRemovedRelease(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	reconciler := createTestReconciler()
+
+	instance := createBasicInstance(t.Name())
+	if err := k8sClient.Create(ctx, instance); err != nil {
+		t.Fatalf("Failed to create test instance: %v", err)
+	}
+	defer cleanupInstance(ctx, t, instance)
+
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: instance.Name}}
+	reconcileToPending(ctx, t, reconciler, instance.Name)
+	reconcileToProvisioning(ctx, t, reconciler, instance.Name)
+
+	current := getInstanceState(ctx, t, instance.Name)
+	if current == nil || current.Status.ProvisioningJobName == "" {
+		t.Fatal("Provisioning Job not created")
+	}
+	setJobSucceeded(ctx, t, current.Status.ProvisioningJobName)
+	if _, err := reconciler.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Failed to reconcile Running state: %v", err)
+	}
+	if _, err := reconciler.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Failed to reconcile running instance: %v", err)
+	}
+	current = getInstanceState(ctx, t, instance.Name)
+	if current.Status.Phase != supacontrolv1alpha1.PhaseRunning {
+		t.Fatalf("Expected an instance with its release to stay Running, got %s", current.Status.Phase)
+	}
+
+	err := k8sClient.DeleteAllOf(ctx, &corev1.Secret{}, client.InNamespace(current.Status.Namespace),
+		client.MatchingLabels{"owner": "helm", "name": current.Spec.ProjectName})
+	if err != nil {
+		t.Fatalf("Failed to uninstall the Helm release: %v", err)
+	}
+	if _, err := reconciler.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Failed to reconcile uninstalled instance: %v", err)
+	}
+
+	current = getInstanceState(ctx, t, instance.Name)
+	if current.Status.Phase != supacontrolv1alpha1.PhaseFailed {
+		t.Fatalf("Expected the instance to fail, got %s", current.Status.Phase)
+	}
+	ready := meta.FindStatusCondition(current.Status.Conditions, supacontrolv1alpha1.ConditionTypeReady)
+	if ready == nil || ready.Reason != "ReleaseUninstalled" {
+		t.Errorf("Expected Ready to report the uninstalled release, got %+v", ready)
+	}
+}
+
+// TestReconcilePending_AdoptsRelease tests that an imported instance takes over its deployed
+// Helm release without a provisioning Job, and that one whose release is missing fails
+// without cleaning up the namespace it named
+func TestReconcilePending_AdoptsRelease(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	reconciler := createTestReconciler()
+
+	instance := createBasicInstance(t.Name())
+	namespace := "legacy-" + instance.Name
+	instance.Spec.Adoption = &supacontrolv1alpha1.Adoption{Namespace: namespace, ReleaseName: "supabase"}
+
+	if err := k8sClient.Create(ctx, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace}}); err != nil {
+		t.Fatalf("Failed to create namespace: %v", err)
+	}
+	labels := map[string]string{"app.kubernetes.io/instance": "supabase", "app.kubernetes.io/name": "supabase-db"}
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "supabase-supabase-db", Namespace: namespace, Labels: labels},
+		Spec: appsv1.DeploymentSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: labels},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+				Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "db", Image: "supabase/postgres"}}},
+			},
+		},
+	}
+	if err := k8sClient.Create(ctx, deployment); err != nil {
+		t.Fatalf("Failed to create deployment: %v", err)
+	}
+	revision := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "sh.helm.release.v1.supabase.v3",
+			Namespace: namespace,
+			Labels:    map[string]string{"owner": "helm", "name": "supabase", "status": "deployed", "version": "3"},
+		},
+		Data: map[string][]byte{"release": encodeRelease(t, &release.Release{
+			Name:    "supabase",
+			Version: 3,
+			Info:    &release.Info{Status: release.StatusDeployed},
+			Chart:   &chart.Chart{Metadata: &chart.Metadata{Name: "supabase", Version: "0.1.3"}},
+		})},
+	}
+	if err := k8sClient.Create(ctx, revision); err != nil {
+		t.Fatalf("Failed to create Helm release: %v", err)
+	}
+
+	if err := k8sClient.Create(ctx, instance); err != nil {
+		t.Fatalf("Failed to create test instance: %v", err)
+	}
+	defer cleanupInstance(ctx, t, instance)
+
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: instance.Name}}
+	reconcileToPending(ctx, t, reconciler, instance.Name)
+	if _, err := reconciler.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Failed to adopt release: %v", err)
+	}
+
+	current := getInstanceState(ctx, t, instance.Name)
+	if current.Status.Phase != supacontrolv1alpha1.PhaseRunning {
+		t.Fatalf("Expected the adopted instance to be Running, got %s: %s", current.Status.Phase, current.Status.ErrorMessage)
+	}
+	if current.Status.ProvisioningJobName != "" {
+		t.Errorf("Expected no provisioning Job, got %s", current.Status.ProvisioningJobName)
+	}
+	if current.Status.Namespace != namespace || current.Status.HelmReleaseName != "supabase" {
+		t.Errorf("Expected release supabase in %s, got %s in %s", namespace, current.Status.HelmReleaseName, current.Status.Namespace)
+	}
+	if current.Status.HelmRevision != 3 || current.Status.ChartVersion != "0.1.3" {
+		t.Errorf("Expected revision 3 of chart 0.1.3, got revision %d of %s", current.Status.HelmRevision, current.Status.ChartVersion)
+	}
+
+	ns := &corev1.Namespace{}
+	if err := k8sClient.Get(ctx, client.ObjectKey{Name: namespace}, ns); err != nil {
+		t.Fatalf("Failed to get namespace: %v", err)
+	}
+	if ns.Labels[JobInstanceLabel] != instance.Name || ns.Labels["app.kubernetes.io/managed-by"] != "supacontrol" {
+		t.Errorf("Expected the namespace to be labeled, got %v", ns.Labels)
+	}
+	if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(deployment), deployment); err != nil {
+		t.Fatalf("Failed to get deployment: %v", err)
+	}
+	if deployment.Labels[JobInstanceLabel] != instance.Name {
+		t.Errorf("Expected the deployment to be labeled, got %v", deployment.Labels)
+	}
+
+	// An import naming a release that does not exist fails and leaves the namespace alone
+	missing := createBasicInstance(t.Name())
+	missing.Spec.Adoption = &supacontrolv1alpha1.Adoption{Namespace: namespace, ReleaseName: "other"}
+	if err := k8sClient.Create(ctx, missing); err != nil {
+		t.Fatalf("Failed to create test instance: %v", err)
+	}
+	reconcileToPending(ctx, t, reconciler, missing.Name)
+	if _, err := reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Name: missing.Name}}); err != nil {
+		t.Fatalf("Failed to reconcile missing release: %v", err)
+	}
+	current = getInstanceState(ctx, t, missing.Name)
+	if current.Status.Phase != supacontrolv1alpha1.PhaseFailed || !strings.Contains(current.Status.ErrorMessage, "not found") {
+		t.Errorf("Expected the import of a missing release to fail, got %s: %s", current.Status.Phase, current.Status.ErrorMessage)
+	}
+
+	if err := k8sClient.Delete(ctx, missing); err != nil {
+		t.Fatalf("Failed to delete instance: %v", err)
+	}
+	if _, err := reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Name: missing.Name}}); err != nil {
+		t.Fatalf("Failed to reconcile deletion: %v", err)
+	}
+	job := &batchv1.Job{}
+	err := k8sClient.Get(ctx, client.ObjectKey{Namespace: ControllerNamespace, Name: "supacontrol-cleanup-" + missing.Name}, job)
+	if err == nil {
+		t.Error("Expected no cleanup Job for a release that was never adopted")
+	}
+}
+
+// TestInstanceForNamespace tests that instance namespaces and the objects in them map onto
+// their instance
+func TestInstanceForNamespace(t *testing.T) {
+	tests := []struct {
+		name     string
+		object   client.Object
+		expected string
+	}{
+		{name: "instance namespace", object: &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "supa-my-app"}}, expected: "my-app"},
+		{name: "release secret", object: &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "sh.helm.release.v1.my-app.v1", Namespace: "supa-my-app"}}, expected: "my-app"},
+		{name: "other namespace", object: &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system"}}},
+		{name: "object in other namespace", object: &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "supa-token", Namespace: "default"}}},
+	}
+
+	reconciler := createTestReconciler()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests := reconciler.instanceForNamespace(context.Background(), tt.object)
+			switch {
+			case tt.expected == "" && len(requests) != 0:
+				t.Errorf("Expected no request, got %v", requests)
+			case tt.expected != "" && (len(requests) != 1 || requests[0].Name != tt.expected):
+				t.Errorf("Expected a request for %s, got %v", tt.expected, requests)
+			}
+		})
+	}
+
+	reconciler.NamespaceTemplate = "tenants-{name}"
+	if requests := reconciler.instanceForNamespace(context.Background(), &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "tenants-my-app"}}); len(requests) != 1 || requests[0].Name != "my-app" {
+		t.Errorf("Expected a request for my-app under a custom template, got %v", requests)
+	}
+	if requests := reconciler.instanceForNamespace(context.Background(), &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "supa-my-app"}}); len(requests) != 0 {
+		t.Errorf("Expected no request for a namespace outside the custom template, got %v", requests)
+	}
+
+	uninstalled := []corev1.Secret{{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"status": "uninstalled"}}}}
+	if releaseInstalled(uninstalled) || releaseInstalled(nil) {
+		t.Error("Expected a release without revisions or with only uninstalled ones to be missing")
+	}
+	if !releaseInstalled(append(uninstalled, corev1.Secret{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"status": "failed"}}})) {
+		t.Error("Expected a release with a failed revision to be installed")
+	}
+}
+
+// TestValidateNamespace tests that instances are refused system namespaces and reserved
+// namespace labels
+func TestValidateNamespace(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		template string
+		project  string
+		labels   map[string]string
+		valid    bool
+	}{
+		{name: "default template", project: "my-app", labels: map[string]string{"cost-center": "cc-1234"}, valid: true},
+		{name: "custom template", template: "tenants-{name}", project: "my-app", valid: true},
+		{name: "system namespace", template: "{name}", project: "default"},
+		{name: "controller namespace", template: "{name}", project: ControllerNamespace},
+		{name: "reserved label", project: "my-app", labels: map[string]string{"app.kubernetes.io/managed-by": "someone"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reconciler := createTestReconciler()
+			reconciler.NamespaceTemplate = tt.template
+			instance := &supacontrolv1alpha1.SupabaseInstance{
+				Spec: supacontrolv1alpha1.SupabaseInstanceSpec{ProjectName: tt.project, NamespaceLabels: tt.labels},
+			}
+			err := reconciler.validateNamespace(instance)
+			if tt.valid && err != nil {
+				t.Errorf("Expected the namespace to be valid, got %v", err)
+			}
+			if !tt.valid && err == nil {
+				t.Error("Expected the namespace to be refused")
+			}
+		})
+	}
+}
+
+// TestNamespaceLabels tests that instance namespace labels override the platform's
+func TestNamespaceLabels(t *testing.T) {
+	t.Parallel()
+
+	reconciler := createTestReconciler()
+	reconciler.NamespaceLabels = map[string]string{"team": "platform", "environment": "production"}
+	instance := &supacontrolv1alpha1.SupabaseInstance{
+		Spec: supacontrolv1alpha1.SupabaseInstanceSpec{NamespaceLabels: map[string]string{"team": "payments", "cost-center": "cc-1234"}},
+	}
+
+	labels := reconciler.namespaceLabels(instance)
+	expected := map[string]string{"team": "payments", "environment": "production", "cost-center": "cc-1234"}
+	if !reflect.DeepEqual(labels, expected) {
+		t.Errorf("Expected labels %v, got %v", expected, labels)
+	}
+	if reconciler.NamespaceLabels["team"] != "platform" {
+		t.Error("Expected the platform labels to be left unchanged")
+	}
+}
+
+// TestRecordReconcileOutcome tests that phase durations are recorded when an instance
+// leaves a phase, and that requeues are counted by reason
+func TestRecordReconcileOutcome(t *testing.T) {
+	// Not parallel: other tests reconcile instances and update the same metrics
+	reconciler := createTestReconciler()
+	reconciler.ResyncInterval = 10 * time.Minute
+
+	tests := []struct {
+		name           string
+		phase          supacontrolv1alpha1.SupabaseInstancePhase
+		next           supacontrolv1alpha1.SupabaseInstancePhase
+		result         ctrl.Result
+		err            error
+		expectedReason string
+	}{
+		{name: "phase change", phase: supacontrolv1alpha1.PhaseProvisioningInProgress, next: supacontrolv1alpha1.PhaseRunning, result: ctrl.Result{RequeueAfter: 10 * time.Minute}, expectedReason: RequeueReasonPhaseChange},
+		{name: "job poll", phase: supacontrolv1alpha1.PhaseUpgrading, next: supacontrolv1alpha1.PhaseUpgrading, result: ctrl.Result{RequeueAfter: 5 * time.Second}, expectedReason: RequeueReasonPoll},
+		{name: "resync", phase: supacontrolv1alpha1.PhaseStopped, next: supacontrolv1alpha1.PhaseStopped, result: ctrl.Result{RequeueAfter: 10 * time.Minute}, expectedReason: RequeueReasonResync},
+		{name: "error", phase: supacontrolv1alpha1.PhaseDeleting, next: supacontrolv1alpha1.PhaseDeletingInProgress, err: fmt.Errorf("conflict"), expectedReason: RequeueReasonError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requeues := metrics.ReconciliationRequeuesTotal.WithLabelValues(string(tt.phase), tt.expectedReason)
+			before := testutil.ToFloat64(requeues)
+			durations := metrics.PhaseDuration.WithLabelValues(string(tt.phase), string(tt.next)).(prometheus.Histogram)
+			observed := histogramSampleCount(t, durations)
+
+			instance := &supacontrolv1alpha1.SupabaseInstance{}
+			instance.Status.Phase = tt.next
+			reconciler.recordReconcileOutcome(instance, tt.phase, time.Now().Add(-time.Minute), tt.result, tt.err)
+
+			if got := testutil.ToFloat64(requeues); got != before+1 {
+				t.Errorf("Expected one %s requeue to be counted, got %v", tt.expectedReason, got-before)
+			}
+			expectedObserved := observed
+			if tt.expectedReason == RequeueReasonPhaseChange {
+				expectedObserved++
+			}
+			if got := histogramSampleCount(t, durations); got != expectedObserved {
+				t.Errorf("Expected %d %s phase durations, got %d", expectedObserved-observed, tt.phase, got-observed)
+			}
+		})
+	}
+}
+
+// TestRecordReconcileOutcome_CountsFailures tests that transitions to Failed are counted by
+// the phase the instance failed in and the reason of its Ready condition
+func TestRecordReconcileOutcome_CountsFailures(t *testing.T) {
+	reconciler := createTestReconciler()
+	failures := instanceFailuresTotal.WithLabelValues(string(supacontrolv1alpha1.PhaseProvisioningInProgress), "ProvisioningFailed")
+	before := testutil.ToFloat64(failures)
+
+	instance := &supacontrolv1alpha1.SupabaseInstance{}
+	instance.Status.Phase = supacontrolv1alpha1.PhaseFailed
+	meta.SetStatusCondition(&instance.Status.Conditions, metav1.Condition{
+		Type:   supacontrolv1alpha1.ConditionTypeReady,
+		Status: metav1.ConditionFalse,
+		Reason: "ProvisioningFailed",
+	})
+	reconciler.recordReconcileOutcome(instance, supacontrolv1alpha1.PhaseProvisioningInProgress, time.Now().Add(-time.Minute), ctrl.Result{RequeueAfter: time.Minute}, nil)
+	// Reconciling the failed instance again is not another failure
+	reconciler.recordReconcileOutcome(instance, supacontrolv1alpha1.PhaseFailed, time.Now(), ctrl.Result{RequeueAfter: time.Minute}, nil)
+
+	if got := testutil.ToFloat64(failures); got != before+1 {
+		t.Errorf("Expected one failure to be counted, got %v", got-before)
+	}
+}
+
+// TestJobRunDuration tests how long finished Jobs are reported to have run
+func TestJobRunDuration(t *testing.T) {
+	t.Parallel()
+
+	start := metav1.NewTime(time.Date(2026, 1, 2, 3, 0, 0, 0, time.UTC))
+	end := metav1.NewTime(start.Add(90 * time.Second))
+	tests := []struct {
+		name     string
+		status   batchv1.JobStatus
+		expected time.Duration
+		ok       bool
+	}{
+		{name: "completed", status: batchv1.JobStatus{StartTime: &start, CompletionTime: &end}, expected: 90 * time.Second, ok: true},
+		{name: "failed", status: batchv1.JobStatus{StartTime: &start, Conditions: []batchv1.JobCondition{
+			{Type: batchv1.JobFailed, Status: corev1.ConditionTrue, LastTransitionTime: end},
+		}}, expected: 90 * time.Second, ok: true},
+		{name: "never started", status: batchv1.JobStatus{CompletionTime: &end}},
+		{name: "still running", status: batchv1.JobStatus{StartTime: &start}},
+	}
+
+	for _, tt := range tests {
+		duration, ok := jobRunDuration(&batchv1.Job{Status: tt.status})
+		if ok != tt.ok || duration != tt.expected {
+			t.Errorf("%s: jobRunDuration() = %v, %v, want %v, %v", tt.name, duration, ok, tt.expected, tt.ok)
+		}
+	}
+}
+
+// TestPhaseCollector tests that the instances in each phase are counted on collection
+func TestPhaseCollector(t *testing.T) {
+	t.Parallel()
+
+	testScheme := runtime.NewScheme()
+	if err := supacontrolv1alpha1.AddToScheme(testScheme); err != nil {
+		t.Fatalf("Failed to build scheme: %v", err)
+	}
+	newInstance := func(name string, phase supacontrolv1alpha1.SupabaseInstancePhase) *supacontrolv1alpha1.SupabaseInstance {
+		instance := &supacontrolv1alpha1.SupabaseInstance{ObjectMeta: metav1.ObjectMeta{Name: name}}
+		instance.Status.Phase = phase
+		return instance
+	}
+	reader := fake.NewClientBuilder().WithScheme(testScheme).WithObjects(
+		newInstance("a", supacontrolv1alpha1.PhaseRunning),
+		newInstance("b", supacontrolv1alpha1.PhaseRunning),
+		newInstance("c", supacontrolv1alpha1.PhaseFailed),
+		newInstance("d", ""),
+	).Build()
+
+	expected := `
+# HELP supacontrol_instances_by_phase Number of Supabase instances by phase
+# TYPE supacontrol_instances_by_phase gauge
+`
+	counts := map[string]int{"Running": 2, "Failed": 1, "Pending": 1}
+	phases := supacontrolv1alpha1.AllPhases()
+	slices.Sort(phases)
+	for _, phase := range phases {
+		expected += fmt.Sprintf("supacontrol_instances_by_phase{phase=%q} %d\n", phase, counts[phase])
+	}
+	if err := testutil.CollectAndCompare(&phaseCollector{reader: reader}, strings.NewReader(expected)); err != nil {
+		t.Error(err)
+	}
+}
+
+// histogramSampleCount returns how many observations a histogram recorded
+func histogramSampleCount(t *testing.T, histogram prometheus.Histogram) uint64 {
+	t.Helper()
+	metric := &dto.Metric{}
+	if err := histogram.Write(metric); err != nil {
+		t.Fatalf("Failed to read histogram: %v", err)
+	}
+	return metric.GetHistogram().GetSampleCount()
+}
+
+// TestValidateImage tests provisioner image references, with and without digest pinning
+func TestValidateImage(t *testing.T) {
+	t.Parallel()
+
+	digest := "@sha256:" + strings.Repeat("a", 64)
+	tests := []struct {
+		image         string
+		requireDigest bool
+		expectedErr   error
+	}{
+		{image: "alpine/helm:3.13.0"},
+		{image: "registry.internal:5000/mirror/alpine/helm:3.13.0"},
+		{image: "registry.internal/alpine/helm:3.13.0" + digest, requireDigest: true},
+		{image: "registry.internal/alpine/helm" + digest, requireDigest: true},
+		{image: "alpine/helm:3.13.0", requireDigest: true, expectedErr: ErrImageNotPinned},
+		{image: "Alpine/Helm", expectedErr: ErrInvalidImage},
+		{image: "alpine/helm:3.13.0; rm -rf /", expectedErr: ErrInvalidImage},
+		{image: "alpine/helm@sha256:short", expectedErr: ErrInvalidImage},
+	}
+
+	for _, tt := range tests {
+		err := ValidateImage(tt.image, tt.requireDigest)
+		if !errors.Is(err, tt.expectedErr) {
+			t.Errorf("ValidateImage(%q, %v) = %v, want %v", tt.image, tt.requireDigest, err, tt.expectedErr)
+		}
+	}
+}
+
+// TestRenderProvisionScript tests that the optional steps are rendered into the
+// provisioning script in order
+func TestRenderProvisionScript(t *testing.T) {
+	t.Parallel()
+
+	script, err := RenderProvisionScript()
+	if err != nil {
+		t.Fatalf("Failed to render provisioning script: %v", err)
+	}
+	if strings.Contains(script, "{{") {
+		t.Error("Expected all template actions to be rendered")
+	}
+	steps := []string{"set -euo pipefail", strings.TrimSpace(caBundleSetup), strings.TrimSpace(chartRepoSetup), strings.TrimSpace(vclusterSetup),
+		"add_chart_repo supabase-community", "helm install", strings.TrimSpace(poolerSetup), "Provisioning complete"}
+	position := 0
+	for _, step := range steps {
+		index := strings.Index(script[position:], step)
+		if index < 0 {
+			t.Fatalf("Expected %q after position %d of the provisioning script", step[:min(len(step), 40)], position)
+		}
+		position += index + len(step)
+	}
+}
+
+// TestRenderDatabaseUpgradeScript tests that the database upgrade script renders every step
+func TestRenderDatabaseUpgradeScript(t *testing.T) {
+	t.Parallel()
+
+	script, err := RenderDatabaseUpgradeScript()
+	if err != nil {
+		t.Fatalf("Failed to render database upgrade script: %v", err)
+	}
+	if strings.Contains(script, "{{.") {
+		t.Error("Expected all template actions to be rendered")
+	}
+	steps := []string{"set -euo pipefail", strings.TrimSpace(chartRepoSetup), "backup)", "pg_dumpall", "upgrade)", "helm upgrade",
+		"verify)", "pg_isready", "rollback)", "helm rollback"}
+	position := 0
+	for _, step := range steps {
+		index := strings.Index(script[position:], step)
+		if index < 0 {
+			t.Fatalf("Expected %q after position %d of the database upgrade script", step[:min(len(step), 40)], position)
+		}
+		position += index + len(step)
+	}
+}
+
+// TestNeedsDatabaseUpgrade tests when a Postgres major upgrade is started
+func TestNeedsDatabaseUpgrade(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		version   string
+		current   string
+		condition *metav1.Condition
+		expected  bool
+	}{
+		{name: "chart default", current: "15"},
+		{name: "same version", version: "15", current: "15"},
+		{name: "newer version", version: "17", current: "15", expected: true},
+		{name: "after a previous upgrade", version: "17", current: "15",
+			condition: &metav1.Condition{Status: metav1.ConditionTrue, ObservedGeneration: 1}, expected: true},
+		{name: "failed for this generation", version: "17", current: "15",
+			condition: &metav1.Condition{Status: metav1.ConditionFalse, ObservedGeneration: 2}},
+		{name: "failed for an earlier generation", version: "17", current: "15",
+			condition: &metav1.Condition{Status: metav1.ConditionFalse, ObservedGeneration: 1}, expected: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			instance := &supacontrolv1alpha1.SupabaseInstance{}
+			instance.Generation = 2
+			instance.Spec.Database = &supacontrolv1alpha1.Database{Version: tt.version}
+			instance.Status.DatabaseVersion = tt.current
+			if tt.condition != nil {
+				tt.condition.Type = supacontrolv1alpha1.ConditionTypeDatabaseUpgraded
+				tt.condition.Reason = "Test"
+				instance.Status.Conditions = []metav1.Condition{*tt.condition}
+			}
+			if got := needsDatabaseUpgrade(instance); got != tt.expected {
+				t.Errorf("Expected needsDatabaseUpgrade %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+// TestSetDatabaseVersionValues tests that the Postgres image tag is pinned without
+// replacing other database values
+func TestSetDatabaseVersionValues(t *testing.T) {
+	t.Parallel()
+
+	values := map[string]interface{}{
+		"db": map[string]interface{}{"image": map[string]interface{}{"repository": "supabase/postgres"}},
+	}
+	setDatabaseVersionValues(values, "17.4.1.045")
+	image := values["db"].(map[string]interface{})["image"].(map[string]interface{})
+	if image["tag"] != "17.4.1.045" || image["repository"] != "supabase/postgres" {
+		t.Errorf("Unexpected database image values: %v", image)
+	}
+
+	empty := map[string]interface{}{}
+	setDatabaseVersionValues(empty, "")
+	if len(empty) != 0 {
+		t.Errorf("Expected no values for the chart's default image, got %v", empty)
+	}
+}
+
+// TestSetAddonValues tests that add-on chart values are rendered for the instance and
+// merged without replacing values of the same component
+func TestSetAddonValues(t *testing.T) {
+	t.Parallel()
+
+	instance := &supacontrolv1alpha1.SupabaseInstance{}
+	instance.Spec.ProjectName = "alpha"
+	instance.Spec.Addons = []string{"pgvector", "imgproxy", "unknown"}
+	values := map[string]interface{}{
+		"storage": map[string]interface{}{"environment": map[string]interface{}{"TENANT_ID": "alpha"}},
+	}
+
+	if err := createTestReconciler().setAddonValues(values, instance); err != nil {
+		t.Fatalf("Failed to set add-on values: %v", err)
+	}
+
+	environment := values["storage"].(map[string]interface{})["environment"].(map[string]interface{})
+	if environment["TENANT_ID"] != "alpha" {
+		t.Errorf("Expected existing storage values to be kept, got %v", environment)
+	}
+	if environment["IMGPROXY_URL"] != "http://alpha-supabase-imgproxy:5001" {
+		t.Errorf("Expected the imgproxy URL of the release, got %v", environment["IMGPROXY_URL"])
+	}
+}
+
+// TestCredentialsExpired tests that connection credentials rotate once the configured
+// interval passed and when their rotation time is unknown
+func TestCredentialsExpired(t *testing.T) {
+	t.Parallel()
+
+	reconciler := &SupabaseInstanceReconciler{ConnectionRotationInterval: 24 * time.Hour}
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	secretRotatedAt := func(value string) *corev1.Secret {
+		secret := &corev1.Secret{}
+		if value != "" {
+			secret.Annotations = map[string]string{AnnotationCredentialsRotatedAt: value}
+		}
+		return secret
+	}
+
+	if reconciler.credentialsExpired(secretRotatedAt(now.Add(-time.Hour).Format(time.RFC3339)), now) {
+		t.Error("Expected recently rotated credentials to be kept")
+	}
+	if !reconciler.credentialsExpired(secretRotatedAt(now.Add(-25*time.Hour).Format(time.RFC3339)), now) {
+		t.Error("Expected credentials older than the interval to be rotated")
+	}
+	if !reconciler.credentialsExpired(secretRotatedAt(""), now) {
+		t.Error("Expected credentials without a rotation time to be rotated")
+	}
+	if (&SupabaseInstanceReconciler{}).connectionRotationInterval() != DefaultConnectionRotationInterval {
+		t.Error("Expected the default rotation interval when none is configured")
+	}
+	if connectionRole("my-app") != "conn_my_app" {
+		t.Errorf("Expected a valid Postgres role name, got %q", connectionRole("my-app"))
+	}
+}
+
+// TestReconcileRunning_ReadReplicas tests that read replicas are run behind a read-only
+// Service and removed again once no replicas are requested
+func TestReconcileRunning_ReadReplicas(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	reconciler := createTestReconciler()
+
+	instance := createBasicInstance(t.Name())
+	instance.Spec.Database = &supacontrolv1alpha1.Database{ReadReplicas: 2}
+	if err := k8sClient.Create(ctx, instance); err != nil {
+		t.Fatalf("Failed to create test instance: %v", err)
+	}
+	defer cleanupInstance(ctx, t, instance)
+
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: instance.Name}}
+	reconcileToPending(ctx, t, reconciler, instance.Name)
+	reconcileToProvisioning(ctx, t, reconciler, instance.Name)
+
+	current := getInstanceState(ctx, t, instance.Name)
+	if current == nil || current.Status.ProvisioningJobName == "" {
+		t.Fatal("Provisioning Job not created")
+	}
+	setJobSucceeded(ctx, t, current.Status.ProvisioningJobName)
+	if _, err := reconciler.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Failed to reconcile Running state: %v", err)
+	}
+	if _, err := reconciler.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Failed to reconcile read replicas: %v", err)
+	}
+
+	current = getInstanceState(ctx, t, instance.Name)
+	statefulSet := &appsv1.StatefulSet{}
+	statefulSetKey := client.ObjectKey{Namespace: current.Status.Namespace, Name: readReplicaName(current.Spec.ProjectName)}
+	if err := k8sClient.Get(ctx, statefulSetKey, statefulSet); err != nil {
+		t.Fatalf("Failed to get read replica StatefulSet: %v", err)
+	}
+	if *statefulSet.Spec.Replicas != 2 {
+		t.Errorf("Expected 2 read replicas, got %d", *statefulSet.Spec.Replicas)
+	}
+	serviceKey := client.ObjectKey{Namespace: current.Status.Namespace, Name: ReadReplicaServiceName(current.Spec.ProjectName)}
+	if err := k8sClient.Get(ctx, serviceKey, &corev1.Service{}); err != nil {
+		t.Fatalf("Failed to get read replica Service: %v", err)
+	}
+	// envtest runs no pods, so the replicas never become ready
+	condition := meta.FindStatusCondition(current.Status.Conditions, supacontrolv1alpha1.ConditionTypeReadReplicasReady)
+	if condition == nil || condition.Status != metav1.ConditionFalse || condition.Reason != "ReplicasNotReady" {
+		t.Errorf("Expected ReadReplicasReady=False/ReplicasNotReady, got %+v", condition)
+	}
+
+	current.Spec.Database = nil
+	if err := k8sClient.Update(ctx, current); err != nil {
+		t.Fatalf("Failed to remove read replicas: %v", err)
+	}
+	if _, err := reconciler.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Failed to reconcile without read replicas: %v", err)
+	}
+	if err := k8sClient.Get(ctx, serviceKey, &corev1.Service{}); client.IgnoreNotFound(err) != nil || err == nil {
+		t.Errorf("Expected the read replica Service to be removed, got %v", err)
+	}
+	current = getInstanceState(ctx, t, instance.Name)
+	if meta.FindStatusCondition(current.Status.Conditions, supacontrolv1alpha1.ConditionTypeReadReplicasReady) != nil {
+		t.Error("Expected the ReadReplicasReady condition to be removed")
+	}
+}
+
+func TestReconcileRunning_DatabaseAudit(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	reconciler := createTestReconciler()
+
+	instance := createBasicInstance(t.Name())
+	instance.Spec.Database = &supacontrolv1alpha1.Database{Audit: &supacontrolv1alpha1.DatabaseAudit{Enabled: true}}
+	if err := k8sClient.Create(ctx, instance); err != nil {
+		t.Fatalf("Failed to create test instance: %v", err)
+	}
+	defer cleanupInstance(ctx, t, instance)
+
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: instance.Name}}
+	reconcileToPending(ctx, t, reconciler, instance.Name)
+	reconcileToProvisioning(ctx, t, reconciler, instance.Name)
+
+	current := getInstanceState(ctx, t, instance.Name)
+	if current == nil || current.Status.ProvisioningJobName == "" {
+		t.Fatal("Provisioning Job not created")
+	}
+	setJobSucceeded(ctx, t, current.Status.ProvisioningJobName)
+	if _, err := reconciler.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Failed to reconcile Running state: %v", err)
+	}
+	if _, err := reconciler.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Failed to reconcile database audit: %v", err)
+	}
+
+	current = getInstanceState(ctx, t, instance.Name)
+	job := &batchv1.Job{}
+	jobKey := client.ObjectKey{Namespace: current.Status.Namespace, Name: databaseAuditJobName(current)}
+	if err := k8sClient.Get(ctx, jobKey, job); err != nil {
+		t.Fatalf("Failed to get database audit Job: %v", err)
+	}
+	if job.Labels[databaseAuditStepLabel] != "enable" {
+		t.Errorf("Expected an enable Job, got labels %v", job.Labels)
+	}
+	condition := meta.FindStatusCondition(current.Status.Conditions, supacontrolv1alpha1.ConditionTypeDatabaseAuditReady)
+	if condition == nil || condition.Status != metav1.ConditionFalse || condition.Reason != "Applying" {
+		t.Errorf("Expected DatabaseAuditReady=False/Applying, got %+v", condition)
+	}
+
+	job.Status.Succeeded = 1
+	if err := k8sClient.Status().Update(ctx, job); err != nil {
+		t.Fatalf("Failed to mark the database audit Job succeeded: %v", err)
+	}
+	if _, err := reconciler.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Failed to reconcile the finished database audit Job: %v", err)
+	}
+	current = getInstanceState(ctx, t, instance.Name)
+	condition = meta.FindStatusCondition(current.Status.Conditions, supacontrolv1alpha1.ConditionTypeDatabaseAuditReady)
+	if condition == nil || condition.Status != metav1.ConditionTrue || condition.Reason != "Enabled" {
+		t.Errorf("Expected DatabaseAuditReady=True/Enabled, got %+v", condition)
+	}
+}
+
+// TestDatabaseAuditJob tests the Jobs turning pgAudit on and off
+func TestDatabaseAuditJob(t *testing.T) {
+	reconciler := &SupabaseInstanceReconciler{}
+	instance := &supacontrolv1alpha1.SupabaseInstance{
+		Spec:   supacontrolv1alpha1.SupabaseInstanceSpec{ProjectName: "demo"},
+		Status: supacontrolv1alpha1.SupabaseInstanceStatus{Namespace: "supa-demo", HelmReleaseName: "demo"},
+	}
+
+	enable := reconciler.databaseAuditJob(instance, true)
+	if enable.Name != "demo-database-audit" || enable.Namespace != "supa-demo" {
+		t.Errorf("Expected demo-database-audit in supa-demo, got %s/%s", enable.Namespace, enable.Name)
+	}
+	container := enable.Spec.Template.Spec.Containers[0]
+	if !strings.Contains(container.Command[2], "CREATE EXTENSION IF NOT EXISTS pgaudit") {
+		t.Errorf("Expected the enable script to create the extension, got %q", container.Command[2])
+	}
+	env := map[string]string{}
+	for _, e := range container.Env {
+		env[e.Name] = e.Value
+	}
+	if env["PGHOST"] != "demo-db.supa-demo.svc.cluster.local" || env["PGUSER"] != postgresSuperuser || env["AUDIT_LOG"] != DatabaseAuditLog {
+		t.Errorf("Unexpected Job environment: %v", env)
+	}
+
+	disable := reconciler.databaseAuditJob(instance, false)
+	if disable.Labels[databaseAuditStepLabel] != "disable" ||
+		!strings.Contains(disable.Spec.Template.Spec.Containers[0].Command[2], "ALTER SYSTEM RESET pgaudit.log;") {
+		t.Errorf("Expected a Job resetting the pgAudit settings, got %+v", disable)
+	}
+
+	if DatabaseAuditEnabled(instance) {
+		t.Error("Expected auditing off without database settings")
+	}
+	instance.Spec.Database = &supacontrolv1alpha1.Database{Audit: &supacontrolv1alpha1.DatabaseAudit{Enabled: true}}
+	if !DatabaseAuditEnabled(instance) {
+		t.Error("Expected auditing on")
+	}
+}
+
+// encodeRelease encodes a Helm release the way Helm's Secret driver stores it
+func encodeRelease(t *testing.T, rel *release.Release) []byte {
+	t.Helper()
+	b, err := json.Marshal(rel)
+	if err != nil {
+		t.Fatalf("Failed to marshal release: %v", err)
+	}
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(b); err != nil {
+		t.Fatalf("Failed to compress release: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Failed to compress release: %v", err)
+	}
+	return []byte(base64.StdEncoding.EncodeToString(buf.Bytes()))
+}
+
+// TestDecodeRelease tests that releases are decoded whether or not Helm compressed them
+func TestDecodeRelease(t *testing.T) {
+	rel := &release.Release{
+		Name:    "my-app",
+		Version: 3,
+		Info:    &release.Info{Status: release.StatusFailed, Description: "Upgrade \"my-app\" failed: timed out"},
+		Chart:   &chart.Chart{Metadata: &chart.Metadata{Name: "supabase", Version: "0.1.3"}},
+	}
+
+	plain, err := json.Marshal(rel)
+	if err != nil {
+		t.Fatalf("Failed to marshal release: %v", err)
+	}
+	for name, data := range map[string][]byte{
+		"gzipped": encodeRelease(t, rel),
+		"plain":   []byte(base64.StdEncoding.EncodeToString(plain)),
+	} {
+		decoded, err := decodeRelease(data)
+		if err != nil {
+			t.Fatalf("%s: decodeRelease() error = %v", name, err)
+		}
+		if decoded.Version != 3 || decoded.Info.Status != release.StatusFailed || decoded.Chart.Metadata.Version != "0.1.3" {
+			t.Errorf("%s: unexpected release %+v", name, decoded)
+		}
+	}
+
+	if _, err := decodeRelease([]byte("not base64!")); err == nil {
+		t.Error("Expected an error for a payload that is not base64")
+	}
+}
+
+// TestReleaseStatusReason tests that Helm release statuses become condition reasons
+func TestReleaseStatusReason(t *testing.T) {
+	tests := map[string]string{
+		"deployed":        "Deployed",
+		"failed":          "Failed",
+		"pending-upgrade": "PendingUpgrade",
+		"":                "Unknown",
+	}
+	for status, expected := range tests {
+		if got := releaseStatusReason(status); got != expected {
+			t.Errorf("releaseStatusReason(%q) = %q, want %q", status, got, expected)
+		}
+	}
+}
+
+// TestReconcileRunning_SurfacesHelmRelease tests that the revision and status of a running
+// instance's Helm release are copied into its status, and that a failed upgrade marks the
+// HelmReleaseReady condition false without changing the deployed chart version
+func TestReconcileRunning_SurfacesHelmRelease(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	reconciler := createTestReconciler()
+
+	instance := createBasicInstance(t.Name())
+	if err := k8sClient.Create(ctx, instance); err != nil {
+		t.Fatalf("Failed to create test instance: %v", err)
+	}
+	defer cleanupInstance(ctx, t, instance)
+
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: instance.Name}}
+	reconcileToPending(ctx, t, reconciler, instance.Name)
+	reconcileToProvisioning(ctx, t, reconciler, instance.Name)
+
+	current := getInstanceState(ctx, t, instance.Name)
+	if current == nil || current.Status.ProvisioningJobName == "" {
+		t.Fatal("Provisioning Job not created")
+	}
+	setJobSucceeded(ctx, t, current.Status.ProvisioningJobName)
+	for range 2 {
+		if _, err := reconciler.Reconcile(ctx, req); err != nil {
+			t.Fatalf("Failed to reconcile Running state: %v", err)
+		}
+	}
+
+	current = getInstanceState(ctx, t, instance.Name)
+	if current.Status.Phase != supacontrolv1alpha1.PhaseRunning {
+		t.Fatalf("Instance not in Running phase: %s", current.Status.Phase)
+	}
+	if current.Status.HelmRevision != 1 || current.Status.HelmReleaseStatus != "deployed" {
+		t.Errorf("Expected revision 1 deployed, got revision %d %s", current.Status.HelmRevision, current.Status.HelmReleaseStatus)
+	}
+	if !meta.IsStatusConditionTrue(current.Status.Conditions, supacontrolv1alpha1.ConditionTypeHelmReleaseReady) {
+		t.Error("Expected HelmReleaseReady to be true")
+	}
+	deployedVersion := current.Status.ChartVersion
+
+	failed := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "sh.helm.release.v1." + hostReleaseName(current) + ".v2",
+			Namespace: current.Status.Namespace,
+			Labels:    map[string]string{"owner": "helm", "name": hostReleaseName(current), "status": "failed", "version": "2"},
+		},
+		Data: map[string][]byte{"release": encodeRelease(t, &release.Release{
+			Name:    hostReleaseName(current),
+			Version: 2,
+			Info:    &release.Info{Status: release.StatusFailed, Description: "Upgrade failed: timed out waiting for the condition"},
+			Chart:   &chart.Chart{Metadata: &chart.Metadata{Name: "supabase", Version: "99.0.0"}},
+		})},
+	}
+	if err := k8sClient.Create(ctx, failed); err != nil {
+		t.Fatalf("Failed to create failed revision: %v", err)
+	}
+	if _, err := reconciler.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Failed to reconcile failed revision: %v", err)
+	}
+
+	current = getInstanceState(ctx, t, instance.Name)
+	if current.Status.HelmRevision != 2 || current.Status.HelmReleaseStatus != "failed" {
+		t.Errorf("Expected revision 2 failed, got revision %d %s", current.Status.HelmRevision, current.Status.HelmReleaseStatus)
+	}
+	if current.Status.ChartVersion != deployedVersion {
+		t.Errorf("Expected chart version to stay %q, got %q", deployedVersion, current.Status.ChartVersion)
+	}
+	condition := meta.FindStatusCondition(current.Status.Conditions, supacontrolv1alpha1.ConditionTypeHelmReleaseReady)
+	if condition == nil || condition.Status != metav1.ConditionFalse || condition.Reason != "Failed" ||
+		!strings.Contains(condition.Message, "timed out waiting for the condition") {
+		t.Errorf("Expected HelmReleaseReady false with the failure, got %+v", condition)
+	}
+}
+
+// selfSignedCertificate returns a PEM encoded self-signed certificate valid in the given window
+func selfSignedCertificate(t *testing.T, notBefore, notAfter time.Time) []byte {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "example.com"},
+		NotBefore:    notBefore,
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+// TestReconcileRunning_TracksSecretAges tests that the rotation times of a running instance's
+// keys and TLS certificates are recorded in its status, and that keys older than the maximum
+// secret age and expired certificates turn SecretsRotated false
+func TestReconcileRunning_TracksSecretAges(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	reconciler := createTestReconciler()
+	reconciler.SecretMaxAge = 30 * 24 * time.Hour
+
+	instance := createBasicInstance(t.Name())
+	if err := k8sClient.Create(ctx, instance); err != nil {
+		t.Fatalf("Failed to create test instance: %v", err)
+	}
+	defer cleanupInstance(ctx, t, instance)
+
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: instance.Name}}
+	reconcileToPending(ctx, t, reconciler, instance.Name)
+	reconcileToProvisioning(ctx, t, reconciler, instance.Name)
+
+	current := getInstanceState(ctx, t, instance.Name)
+	if current == nil || current.Status.ProvisioningJobName == "" {
+		t.Fatal("Provisioning Job not created")
+	}
+	setJobSucceeded(ctx, t, current.Status.ProvisioningJobName)
+	if _, err := reconciler.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Failed to reconcile Running state: %v", err)
+	}
+
+	current = getInstanceState(ctx, t, instance.Name)
+	if current.Status.Phase != supacontrolv1alpha1.PhaseRunning {
+		t.Fatalf("Instance not in Running phase: %s", current.Status.Phase)
+	}
+
+	rotatedAt := time.Now().Add(-45 * 24 * time.Hour).UTC().Truncate(time.Second)
+	secrets := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        InstanceSecretName(current.Spec.ProjectName),
+			Namespace:   current.Status.Namespace,
+			Annotations: map[string]string{AnnotationSecretRotatedAtPrefix + "jwt-secret": rotatedAt.Format(time.RFC3339)},
+		},
+		Data: map[string][]byte{"jwt-secret": []byte("jwt"), "anon-key": []byte("anon")},
+	}
+	if err := k8sClient.Create(ctx, secrets); err != nil {
+		t.Fatalf("Failed to create instance secret: %v", err)
+	}
+	_, apiIngress := ingressNames(current)
+	certificate := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: apiIngress + "-tls", Namespace: current.Status.Namespace},
+		Type:       corev1.SecretTypeTLS,
+		Data: map[string][]byte{
+			corev1.TLSCertKey:       selfSignedCertificate(t, time.Now().Add(-10*24*time.Hour), time.Now().Add(-time.Hour)),
+			corev1.TLSPrivateKeyKey: []byte("key"),
+		},
+	}
+	if err := k8sClient.Create(ctx, certificate); err != nil {
+		t.Fatalf("Failed to create TLS secret: %v", err)
+	}
+
+	if _, err := reconciler.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Failed to reconcile secret ages: %v", err)
+	}
+
+	current = getInstanceState(ctx, t, instance.Name)
+	ages := make(map[string]supacontrolv1alpha1.SecretAge)
+	for _, age := range current.Status.Secrets {
+		ages[age.Name] = age
+	}
+	if jwt := ages["jwt-secret"]; !jwt.Overdue || !jwt.RotatedAt.Time.Equal(rotatedAt) {
+		t.Errorf("Expected jwt-secret rotated at %v and overdue, got %+v", rotatedAt, jwt)
+	}
+	if anon, ok := ages["anon-key"]; !ok || anon.Overdue {
+		t.Errorf("Expected anon-key to be recent, got %+v", anon)
+	}
+	if tls, ok := ages[certificate.Name]; !ok || !tls.Overdue || tls.ExpiresAt == nil {
+		t.Errorf("Expected the expired certificate to be overdue, got %+v", tls)
+	}
+
+	condition := meta.FindStatusCondition(current.Status.Conditions, supacontrolv1alpha1.ConditionTypeSecretsRotated)
+	if condition == nil || condition.Status != metav1.ConditionFalse || condition.Reason != "RotationOverdue" ||
+		!strings.Contains(condition.Message, "jwt-secret") || strings.Contains(condition.Message, "anon-key") {
+		t.Errorf("Expected SecretsRotated false naming jwt-secret, got %+v", condition)
+	}
+}
+
+// TestSortQueue tests that queued instances are ordered first in, first out, and that
+// paused or deleted instances give up their place
+func TestSortQueue(t *testing.T) {
+	created := metav1.NewTime(time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC))
+	queued := func(name string, queuedAt *metav1.Time) supacontrolv1alpha1.SupabaseInstance {
+		instance := supacontrolv1alpha1.SupabaseInstance{ObjectMeta: metav1.ObjectMeta{Name: name, CreationTimestamp: created}}
+		instance.Status.Phase = supacontrolv1alpha1.PhaseQueued
+		instance.Status.QueuedAt = queuedAt
+		return instance
+	}
+	at := func(minutes int) *metav1.Time {
+		timestamp := metav1.NewTime(created.Add(time.Duration(minutes) * time.Minute))
+		return &timestamp
+	}
+
+	queue := []supacontrolv1alpha1.SupabaseInstance{
+		queued("unqueued", nil),
+		queued("third", at(2)),
+		queued("first-b", at(1)),
+		queued("first-a", at(1)),
+	}
+	sortQueue(queue)
+	var names []string
+	for _, instance := range queue {
+		names = append(names, instance.Name)
+	}
+	if want := []string{"first-a", "first-b", "third", "unqueued"}; !slices.Equal(names, want) {
+		t.Errorf("sortQueue() = %v, want %v", names, want)
+	}
+
+	paused := queued("paused", at(0))
+	paused.Spec.Paused = true
+	deleted := queued("deleted", at(0))
+	deleted.DeletionTimestamp = at(3)
+	for _, instance := range []supacontrolv1alpha1.SupabaseInstance{paused, deleted} {
+		if isWaitingInQueue(&instance) {
+			t.Errorf("Expected %s instance not to hold a place in the queue", instance.Name)
+		}
+	}
+	if waiting := queued("waiting", at(0)); !isWaitingInQueue(&waiting) {
+		t.Error("Expected queued instance to hold a place in the queue")
+	}
+}
+
+// TestReconcileProvisioningInProgress_WaitsForDNS tests that a provisioned instance is only
+// marked Running once its ingresses have the addresses external-dns publishes
+func TestReconcileProvisioningInProgress_WaitsForDNS(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	reconciler := createTestReconciler()
+	reconciler.ExternalDNSMode = ExternalDNSModeAnnotation
+
+	instance := createBasicInstance(t.Name())
+	instance.Spec.DNS = &supacontrolv1alpha1.DNSSettings{Provider: "route53", TTL: 60}
+	if err := k8sClient.Create(ctx, instance); err != nil {
+		t.Fatalf("Failed to create test instance: %v", err)
+	}
+	defer cleanupInstance(ctx, t, instance)
+
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: instance.Name}}
+	reconcileToPending(ctx, t, reconciler, instance.Name)
+	reconcileToProvisioning(ctx, t, reconciler, instance.Name)
+	current := getInstanceState(ctx, t, instance.Name)
+	setJobSucceeded(ctx, t, current.Status.ProvisioningJobName)
+
+	if _, err := reconciler.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	current = getInstanceState(ctx, t, instance.Name)
+	if current.Status.Phase == supacontrolv1alpha1.PhaseRunning {
+		t.Fatal("Expected the instance to wait for DNS before Running")
+	}
+	if ready := meta.FindStatusCondition(current.Status.Conditions, supacontrolv1alpha1.ConditionTypeReady); ready == nil || ready.Reason != "WaitingForDNS" {
+		t.Errorf("Expected Ready reason WaitingForDNS, got %+v", ready)
+	}
+	if dns := meta.FindStatusCondition(current.Status.Conditions, supacontrolv1alpha1.ConditionTypeDNSReady); dns == nil || dns.Reason != "AddressPending" {
+		t.Errorf("Expected DNSReady reason AddressPending, got %+v", dns)
+	}
+
+	studioIngress, apiIngress := ingressNames(current)
+	studioHost, apiHost := reconciler.instanceHosts(current)
+	for _, item := range []struct{ name, host string }{{studioIngress, studioHost}, {apiIngress, apiHost}} {
+		ingress := &networkingv1.Ingress{}
+		if err := k8sClient.Get(ctx, client.ObjectKey{Namespace: current.Status.Namespace, Name: item.name}, ingress); err != nil {
+			t.Fatalf("Failed to get ingress %s: %v", item.name, err)
+		}
+		if ingress.Annotations[externalDNSHostnameAnnotation] != item.host || ingress.Annotations[externalDNSTTLAnnotation] != "60" {
+			t.Errorf("Ingress %s annotations = %v", item.name, ingress.Annotations)
+		}
+		if ingress.Labels[supacontrolv1alpha1.LabelDNSProvider] != "route53" {
+			t.Errorf("Ingress %s labels = %v", item.name, ingress.Labels)
+		}
+		ingress.Status.LoadBalancer.Ingress = []networkingv1.IngressLoadBalancerIngress{{IP: "203.0.113.10"}}
+		if err := k8sClient.Status().Update(ctx, ingress); err != nil {
+			t.Fatalf("Failed to set ingress %s address: %v", item.name, err)
+		}
+	}
+
+	if _, err := reconciler.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile failed: %v", err)
+	}
+	current = getInstanceState(ctx, t, instance.Name)
+	if current.Status.Phase != supacontrolv1alpha1.PhaseRunning {
+		t.Errorf("Expected phase Running once the ingresses have addresses, got %s", current.Status.Phase)
+	}
+	if dns := meta.FindStatusCondition(current.Status.Conditions, supacontrolv1alpha1.ConditionTypeDNSReady); dns == nil || dns.Status != metav1.ConditionTrue {
+		t.Errorf("Expected DNSReady True, got %+v", dns)
+	}
+}
+
+// TestReconcileRunning_Maintenance tests that the ingresses of an instance in maintenance
+// route to its maintenance page, and back to the instance once the maintenance ends
+func TestReconcileRunning_Maintenance(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	reconciler := createTestReconciler()
+
+	instance := createBasicInstance(t.Name())
+	if err := k8sClient.Create(ctx, instance); err != nil {
+		t.Fatalf("Failed to create test instance: %v", err)
+	}
+	defer cleanupInstance(ctx, t, instance)
+
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: instance.Name}}
+	reconcileToPending(ctx, t, reconciler, instance.Name)
+	reconcileToProvisioning(ctx, t, reconciler, instance.Name)
+	current := getInstanceState(ctx, t, instance.Name)
+	setJobSucceeded(ctx, t, current.Status.ProvisioningJobName)
+	if _, err := reconciler.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Failed to reconcile Running state: %v", err)
+	}
+
+	current = getInstanceState(ctx, t, instance.Name)
+	current.Spec.Maintenance = &supacontrolv1alpha1.Maintenance{Message: "Upgrading Postgres", StartedAt: metav1.Now()}
+	if err := k8sClient.Update(ctx, current); err != nil {
+		t.Fatalf("Failed to start maintenance: %v", err)
+	}
+	if _, err := reconciler.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Failed to reconcile maintenance: %v", err)
+	}
+
+	current = getInstanceState(ctx, t, instance.Name)
+	if cond := meta.FindStatusCondition(current.Status.Conditions, supacontrolv1alpha1.ConditionTypeMaintenance); cond == nil || cond.Status != metav1.ConditionTrue {
+		t.Errorf("Expected Maintenance True, got %+v", cond)
+	}
+	pageName := MaintenancePageName(current.Spec.ProjectName)
+	configMap := &corev1.ConfigMap{}
+	if err := k8sClient.Get(ctx, client.ObjectKey{Namespace: current.Status.Namespace, Name: pageName}, configMap); err != nil {
+		t.Fatalf("Failed to get maintenance page ConfigMap: %v", err)
+	}
+	if !strings.Contains(configMap.Data["index.html"], "Upgrading Postgres") {
+		t.Errorf("Expected the maintenance page to show the message, got %q", configMap.Data["index.html"])
+	}
+	if err := k8sClient.Get(ctx, client.ObjectKey{Namespace: current.Status.Namespace, Name: pageName}, &corev1.Service{}); err != nil {
+		t.Fatalf("Failed to get maintenance page Service: %v", err)
+	}
+
+	studioIngress, apiIngress := ingressNames(current)
+	assertBackend := func(want string) {
+		t.Helper()
+		for _, name := range []string{studioIngress, apiIngress} {
+			ingress := &networkingv1.Ingress{}
+			if err := k8sClient.Get(ctx, client.ObjectKey{Namespace: current.Status.Namespace, Name: name}, ingress); err != nil {
+				t.Fatalf("Failed to get ingress %s: %v", name, err)
+			}
+			if backend := ingressBackend(ingress); backend == nil || (want != "" && backend.Name != want) || (want == "" && backend.Name == pageName) {
+				t.Errorf("Ingress %s routes to %+v, expected %q", name, backend, want)
+			}
+		}
+	}
+	assertBackend(pageName)
+
+	current.Spec.Maintenance = nil
+	if err := k8sClient.Update(ctx, current); err != nil {
+		t.Fatalf("Failed to end maintenance: %v", err)
+	}
+	if _, err := reconciler.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Failed to reconcile the end of maintenance: %v", err)
+	}
+
+	current = getInstanceState(ctx, t, instance.Name)
+	if cond := meta.FindStatusCondition(current.Status.Conditions, supacontrolv1alpha1.ConditionTypeMaintenance); cond != nil {
+		t.Errorf("Expected the Maintenance condition to be removed, got %+v", cond)
+	}
+	assertBackend("")
+	if err := k8sClient.Get(ctx, client.ObjectKey{Namespace: current.Status.Namespace, Name: pageName}, &corev1.Service{}); client.IgnoreNotFound(err) != nil || err == nil {
+		t.Errorf("Expected the maintenance page Service to be removed, got %v", err)
+	}
+}
+
+func TestDNSRecords(t *testing.T) {
+	tests := []struct {
+		name      string
+		addresses []networkingv1.IngressLoadBalancerIngress
+		want      []interface{}
+	}{
+		{name: "no address"},
+		{
+			name:      "IPv4 and IPv6",
+			addresses: []networkingv1.IngressLoadBalancerIngress{{IP: "203.0.113.20"}, {IP: "2001:db8::1"}, {IP: "203.0.113.10"}},
+			want: []interface{}{
+				map[string]interface{}{"dnsName": "demo.example.com", "recordType": "A", "targets": []interface{}{"203.0.113.10", "203.0.113.20"}, "recordTTL": int64(60)},
+				map[string]interface{}{"dnsName": "demo.example.com", "recordType": "AAAA", "targets": []interface{}{"2001:db8::1"}, "recordTTL": int64(60)},
+			},
+		},
+		{
+			name:      "load balancer hostname",
+			addresses: []networkingv1.IngressLoadBalancerIngress{{Hostname: "lb-1.elb.amazonaws.com"}, {Hostname: "lb-2.elb.amazonaws.com"}},
+			want: []interface{}{
+				map[string]interface{}{"dnsName": "demo.example.com", "recordType": "CNAME", "targets": []interface{}{"lb-1.elb.amazonaws.com"}, "recordTTL": int64(60)},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := dnsRecords("demo.example.com", tt.addresses, 60); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("dnsRecords() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVClusterHostName(t *testing.T) {
+	if got := vclusterHostName("app-kong", "supa-app", "app-vcluster"); got != "app-kong-x-supa-app-x-app-vcluster" {
+		t.Errorf("Expected the synced Service name, got %q", got)
+	}
+	long := vclusterHostName("a-very-long-release-name-kong", "supa-a-very-long-release-name", "a-very-long-release-name-vcluster")
+	if len(long) != 63 || !strings.HasPrefix(long, "a-very-long-release-name-kong-x-supa-a-very-long-rel-") {
+		t.Errorf("Expected a name shortened to 63 characters with a hash, got %q", long)
+	}
+}
+
+func TestIngressBackends_VCluster(t *testing.T) {
+	instance := &supacontrolv1alpha1.SupabaseInstance{Spec: supacontrolv1alpha1.SupabaseInstanceSpec{ProjectName: "app"}}
+	instance.Status.Namespace = "supa-app"
+	instance.Status.HelmReleaseName = "app"
+	instance.Status.IsolationLevel = supacontrolv1alpha1.IsolationVCluster
+
+	studio, api := ingressBackends(instance)
+	if studio.Name != "app-studio-x-supa-app-x-app-vcluster" || api.Name != "app-kong-x-supa-app-x-app-vcluster" {
+		t.Errorf("Expected the Services synced from the vcluster, got %q and %q", studio.Name, api.Name)
+	}
+
+	instance.Spec.Maintenance = &supacontrolv1alpha1.Maintenance{}
+	if studio, _ := ingressBackends(instance); studio.Name != MaintenancePageName("app") {
+		t.Errorf("Expected the host maintenance page during maintenance, got %q", studio.Name)
+	}
+}
+
+func TestScaleInstance_VCluster(t *testing.T) {
+	ctx := context.Background()
+	instance := &supacontrolv1alpha1.SupabaseInstance{Spec: supacontrolv1alpha1.SupabaseInstanceSpec{ProjectName: "app"}}
+	instance.Status.Namespace = "supa-app"
+	instance.Status.IsolationLevel = supacontrolv1alpha1.IsolationVCluster
+
+	kubeconfig := `apiVersion: v1
+kind: Config
+clusters:
+- name: vcluster
+  cluster:
+    server: https://localhost:8443
+contexts:
+- name: vcluster
+  context:
+    cluster: vcluster
+    user: admin
+current-context: vcluster
+users:
+- name: admin
+  user:
+    token: secret
+`
+	host := fake.NewClientBuilder().WithObjects(
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "vc-app-vcluster", Namespace: "supa-app"},
+			Data:       map[string][]byte{"config": []byte(kubeconfig)},
+		},
+		&appsv1.StatefulSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "app-vcluster", Namespace: "supa-app"},
+			Spec:       appsv1.StatefulSetSpec{Replicas: ptr.To[int32](1)},
+		},
+	).Build()
+	inner := fake.NewClientBuilder().WithObjects(&appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "app-kong", Namespace: "supa-app"},
+		Spec:       appsv1.DeploymentSpec{Replicas: ptr.To[int32](2)},
+	}).Build()
+
+	var server string
+	reconciler := &SupabaseInstanceReconciler{
+		Client: host,
+		VClusterClient: func(config *rest.Config) (client.Client, error) {
+			server = config.Host
+			return inner, nil
+		},
+	}
+
+	if _, err := reconciler.scaleDownInstance(ctx, instance); err != nil {
+		t.Fatalf("scaleDownInstance() error = %v", err)
+	}
+	if server != "https://app-vcluster.supa-app.svc:443" {
+		t.Errorf("Expected the vcluster to be reached through its Service, got %q", server)
+	}
+	deployment := &appsv1.Deployment{}
+	if err := inner.Get(ctx, client.ObjectKey{Namespace: "supa-app", Name: "app-kong"}, deployment); err != nil {
+		t.Fatalf("Failed to get deployment: %v", err)
+	}
+	if *deployment.Spec.Replicas != 0 {
+		t.Errorf("Expected the deployment inside the vcluster to be scaled to zero, got %d", *deployment.Spec.Replicas)
+	}
+	vcluster := &appsv1.StatefulSet{}
+	if err := host.Get(ctx, client.ObjectKey{Namespace: "supa-app", Name: "app-vcluster"}, vcluster); err != nil {
+		t.Fatalf("Failed to get vcluster: %v", err)
+	}
+	if *vcluster.Spec.Replicas != 1 {
+		t.Errorf("Expected the vcluster to keep running, got %d replicas", *vcluster.Spec.Replicas)
+	}
+
+	if _, err := reconciler.scaleUpInstance(ctx, instance); err != nil {
+		t.Fatalf("scaleUpInstance() error = %v", err)
+	}
+	if err := inner.Get(ctx, client.ObjectKey{Namespace: "supa-app", Name: "app-kong"}, deployment); err != nil {
+		t.Fatalf("Failed to get deployment: %v", err)
+	}
+	if *deployment.Spec.Replicas != 2 {
+		t.Errorf("Expected the deployment to be scaled back to 2, got %d", *deployment.Spec.Replicas)
+	}
+}
@@ -0,0 +1,125 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+	"github.com/qubitquilt/supacontrol/server/internal/metrics"
+)
+
+// suspendedRedirectAnnotation makes ingress-nginx redirect every request to the suspended page
+const suspendedRedirectAnnotation = "nginx.ingress.kubernetes.io/temporal-redirect"
+
+// suspendedPageURL returns the page a suspended instance's ingresses redirect to
+func (r *SupabaseInstanceReconciler) suspendedPageURL(instance *supacontrolv1alpha1.SupabaseInstance) string {
+	return r.SuspendedPageURL + "?instance=" + url.QueryEscape(instance.Spec.ProjectName)
+}
+
+// markIngressesSuspended labels the instance's ingresses with the suspension reason and,
+// when a suspended page is configured, redirects them to it. With suspended false it
+// removes both again. Ingresses are only updated when something changes.
+func (r *SupabaseInstanceReconciler) markIngressesSuspended(ctx context.Context, instance *supacontrolv1alpha1.SupabaseInstance, suspended bool) error {
+	ingresses := &networkingv1.IngressList{}
+	if err := r.List(ctx, ingresses, client.InNamespace(instance.Status.Namespace)); err != nil {
+		return fmt.Errorf("failed to list ingresses: %w", err)
+	}
+
+	for i := range ingresses.Items {
+		ingress := &ingresses.Items[i]
+		changed := false
+		if suspended {
+			reason := strings.ToLower(string(instance.Spec.Suspension.Reason))
+			if ingress.Labels[supacontrolv1alpha1.LabelSuspended] != reason {
+				if ingress.Labels == nil {
+					ingress.Labels = map[string]string{}
+				}
+				ingress.Labels[supacontrolv1alpha1.LabelSuspended] = reason
+				changed = true
+			}
+			if r.SuspendedPageURL != "" && ingress.Annotations[suspendedRedirectAnnotation] != r.suspendedPageURL(instance) {
+				if ingress.Annotations == nil {
+					ingress.Annotations = map[string]string{}
+				}
+				ingress.Annotations[suspendedRedirectAnnotation] = r.suspendedPageURL(instance)
+				changed = true
+			}
+		} else if _, ok := ingress.Labels[supacontrolv1alpha1.LabelSuspended]; ok {
+			// Only ingresses marked by a suspension lose the redirect
+			delete(ingress.Labels, supacontrolv1alpha1.LabelSuspended)
+			delete(ingress.Annotations, suspendedRedirectAnnotation)
+			changed = true
+		}
+
+		if changed {
+			if err := r.Update(ctx, ingress); err != nil {
+				return fmt.Errorf("failed to update ingress %s: %w", ingress.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// reconcileSuspended scales a provisioned instance's workloads to zero, marks its ingresses
+// suspended and transitions it to Suspended. Instances that are not provisioned yet are
+// left alone until the suspension is lifted, like paused ones.
+func (r *SupabaseInstanceReconciler) reconcileSuspended(ctx context.Context, instance *supacontrolv1alpha1.SupabaseInstance) (ctrl.Result, error) {
+	logger := ctrl.LoggerFrom(ctx)
+
+	switch instance.Status.Phase {
+	case supacontrolv1alpha1.PhaseRunning, supacontrolv1alpha1.PhaseStopped, supacontrolv1alpha1.PhaseSuspended,
+		supacontrolv1alpha1.PhasePendingDeletion:
+	default:
+		logger.Info("Reconciliation suspended for instance", "projectName", instance.Spec.ProjectName)
+		return ctrl.Result{}, nil
+	}
+
+	scaled, err := r.scaleDownInstance(ctx, instance)
+	if err != nil {
+		logger.Error(err, "Failed to scale down workloads", "namespace", instance.Status.Namespace)
+		metrics.ReconciliationErrorsTotal.WithLabelValues(string(instance.Status.Phase)).Inc()
+		return ctrl.Result{}, err
+	}
+	if err := r.markIngressesSuspended(ctx, instance, true); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if instance.Status.Phase != supacontrolv1alpha1.PhaseSuspended {
+		suspension := instance.Spec.Suspension
+		logger.Info("Instance suspended", "projectName", instance.Spec.ProjectName, "reason", suspension.Reason, "workloadsScaled", scaled)
+		instance.Status.Phase = supacontrolv1alpha1.PhaseSuspended
+		now := metav1.Now()
+		instance.Status.LastTransitionTime = &now
+
+		message := fmt.Sprintf("Instance is suspended (%s)", suspension.Reason)
+		if suspension.Message != "" {
+			message += ": " + suspension.Message
+		}
+		meta.SetStatusCondition(&instance.Status.Conditions, metav1.Condition{
+			Type:               supacontrolv1alpha1.ConditionTypeReady,
+			Status:             metav1.ConditionFalse,
+			ObservedGeneration: instance.Generation,
+			Reason:             "Suspended",
+			Message:            message,
+		})
+		instance.Status.ObservedGeneration = instance.Generation
+
+		if err := r.updateStatus(ctx, instance); err != nil {
+			return ctrl.Result{}, err
+		}
+
+		// Update metrics
+		metrics.SetInstanceStatus(instance.Spec.ProjectName, string(supacontrolv1alpha1.PhaseSuspended), supacontrolv1alpha1.AllPhases())
+	}
+
+	// Requeue periodically so workloads scaled up out-of-band are stopped again
+	return ctrl.Result{RequeueAfter: r.resyncInterval()}, nil
+}
@@ -0,0 +1,45 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+)
+
+// templateChartValues decodes the chart values of an instance template
+func templateChartValues(template *supacontrolv1alpha1.SupabaseInstanceTemplate) (map[string]interface{}, error) {
+	values := map[string]interface{}{}
+	if template.Spec.Values == nil || len(template.Spec.Values.Raw) == 0 {
+		return values, nil
+	}
+	if err := json.Unmarshal(template.Spec.Values.Raw, &values); err != nil {
+		return nil, fmt.Errorf("instance template %q has invalid values: %w", template.Name, err)
+	}
+	if values == nil {
+		values = map[string]interface{}{}
+	}
+	return values, nil
+}
+
+// templateValues returns the chart values of the template an instance references, which
+// the instance's own settings are merged over, or empty values for instances without one.
+// A missing template fails like a missing shared service profile, since provisioning
+// without it would silently deviate from the offering the instance was created from.
+func (r *SupabaseInstanceReconciler) templateValues(ctx context.Context, instance *supacontrolv1alpha1.SupabaseInstance) (map[string]interface{}, error) {
+	if instance.Spec.TemplateRef == "" {
+		return map[string]interface{}{}, nil
+	}
+	template := &supacontrolv1alpha1.SupabaseInstanceTemplate{}
+	if err := r.Get(ctx, client.ObjectKey{Name: instance.Spec.TemplateRef}, template); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, fmt.Errorf("instance template %q not found", instance.Spec.TemplateRef)
+		}
+		return nil, fmt.Errorf("failed to get instance template %q: %w", instance.Spec.TemplateRef, err)
+	}
+	return templateChartValues(template)
+}
@@ -0,0 +1,78 @@
+package controllers
+
+import (
+	"time"
+
+	"golang.org/x/time/rate"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// Defaults of the reconciler's concurrency, rate limiting and requeue settings. The rate
+// limit defaults match controller-runtime's own.
+const (
+	// DefaultMaxConcurrentReconciles is how many instances are reconciled in parallel
+	DefaultMaxConcurrentReconciles = 1
+
+	// DefaultRateLimitBaseDelay and DefaultRateLimitMaxDelay bound the per-instance
+	// exponential backoff after failed reconciliations
+	DefaultRateLimitBaseDelay = 5 * time.Millisecond
+	DefaultRateLimitMaxDelay  = 1000 * time.Second
+
+	// DefaultRateLimitQPS and DefaultRateLimitBurst limit reconciliations across all instances
+	DefaultRateLimitQPS   = 10
+	DefaultRateLimitBurst = 100
+
+	// DefaultJobPollInterval is how often a running provisioning or upgrade Job is checked
+	DefaultJobPollInterval = 10 * time.Second
+
+	// DefaultResyncInterval is how often settled instances (running, stopped, suspended or
+	// pending deletion) are reconciled again to catch drift
+	DefaultResyncInterval = 5 * time.Minute
+
+	// DefaultFailedRequeueInterval is how often a failed instance without automatic retries
+	// left is checked again
+	DefaultFailedRequeueInterval = 10 * time.Minute
+)
+
+// NewRateLimiter returns a workqueue rate limiter that backs off each instance
+// exponentially from baseDelay to maxDelay after failures, and limits reconciliations
+// across all instances to qps with bursts of burst
+func NewRateLimiter(baseDelay, maxDelay time.Duration, qps, burst int) workqueue.TypedRateLimiter[reconcile.Request] {
+	return workqueue.NewTypedMaxOfRateLimiter(
+		workqueue.NewTypedItemExponentialFailureRateLimiter[reconcile.Request](baseDelay, maxDelay),
+		&workqueue.TypedBucketRateLimiter[reconcile.Request]{Limiter: rate.NewLimiter(rate.Limit(qps), burst)},
+	)
+}
+
+// maxConcurrentReconciles returns the configured reconcile concurrency or its default
+func (r *SupabaseInstanceReconciler) maxConcurrentReconciles() int {
+	if r.MaxConcurrentReconciles > 0 {
+		return r.MaxConcurrentReconciles
+	}
+	return DefaultMaxConcurrentReconciles
+}
+
+// jobPollInterval returns the configured Job poll interval or its default
+func (r *SupabaseInstanceReconciler) jobPollInterval() time.Duration {
+	if r.JobPollInterval > 0 {
+		return r.JobPollInterval
+	}
+	return DefaultJobPollInterval
+}
+
+// resyncInterval returns the configured resync interval or its default
+func (r *SupabaseInstanceReconciler) resyncInterval() time.Duration {
+	if r.ResyncInterval > 0 {
+		return r.ResyncInterval
+	}
+	return DefaultResyncInterval
+}
+
+// failedRequeueInterval returns the configured failed instance requeue interval or its default
+func (r *SupabaseInstanceReconciler) failedRequeueInterval() time.Duration {
+	if r.FailedRequeueInterval > 0 {
+		return r.FailedRequeueInterval
+	}
+	return DefaultFailedRequeueInterval
+}
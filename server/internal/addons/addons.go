@@ -0,0 +1,139 @@
+// Package addons defines optional services that can be added to an instance, such as
+// the pgvector extension, image transformations or a Metabase dashboard.
+//
+// An add-on is a named bundle of Helm chart values and extra manifests, both templated
+// with the instance they are added to. The controller merges the values into the
+// instance's chart values, so they take effect when the instance is provisioned or
+// upgraded, and applies the manifests in the instance namespace right away. The
+// manifests are owned by the instance, so they go away with it.
+package addons
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+)
+
+// Params are the instance details add-on templates are rendered with
+type Params struct {
+	// Instance is the project name of the instance
+	Instance string
+
+	// Namespace is the instance namespace the manifests are applied in
+	Namespace string
+
+	// Release is the instance's Helm release name
+	Release string
+
+	// DatabaseHost is the in-cluster host name of the instance database, and
+	// DatabaseSecret the Secret holding its postgres-password
+	DatabaseHost   string
+	DatabaseSecret string
+}
+
+// Addon is a named bundle of chart values and extra manifests
+type Addon struct {
+	Name        string
+	Description string
+
+	// values and manifests are YAML templates rendered with Params; manifests may hold
+	// several documents separated by ---
+	values    string
+	manifests string
+}
+
+// HasValues reports whether the add-on sets chart values, which only take effect when
+// the instance is provisioned or upgraded
+func (a *Addon) HasValues() bool {
+	return strings.TrimSpace(a.values) != ""
+}
+
+// HasManifests reports whether the add-on applies extra manifests
+func (a *Addon) HasManifests() bool {
+	return strings.TrimSpace(a.manifests) != ""
+}
+
+// Values renders the chart values of the add-on
+func (a *Addon) Values(p Params) (map[string]interface{}, error) {
+	rendered, err := a.render("values", a.values, p)
+	if err != nil {
+		return nil, err
+	}
+	values := map[string]interface{}{}
+	if err := yaml.Unmarshal(rendered, &values); err != nil {
+		return nil, fmt.Errorf("add-on %s has invalid values: %w", a.Name, err)
+	}
+	return values, nil
+}
+
+// Manifests renders the extra objects of the add-on
+func (a *Addon) Manifests(p Params) ([]*unstructured.Unstructured, error) {
+	rendered, err := a.render("manifests", a.manifests, p)
+	if err != nil {
+		return nil, err
+	}
+
+	var objects []*unstructured.Unstructured
+	for _, document := range strings.Split(string(rendered), "\n---") {
+		if strings.TrimSpace(document) == "" {
+			continue
+		}
+		object := &unstructured.Unstructured{}
+		if err := yaml.Unmarshal([]byte(document), &object.Object); err != nil {
+			return nil, fmt.Errorf("add-on %s has an invalid manifest: %w", a.Name, err)
+		}
+		if object.GetAPIVersion() == "" || object.GetKind() == "" || object.GetName() == "" {
+			return nil, fmt.Errorf("add-on %s has a manifest without apiVersion, kind or name", a.Name)
+		}
+		objects = append(objects, object)
+	}
+	return objects, nil
+}
+
+// render executes one of the add-on's templates
+func (a *Addon) render(part, text string, p Params) ([]byte, error) {
+	tmpl, err := template.New(a.Name + "/" + part).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("add-on %s has an invalid %s template: %w", a.Name, part, err)
+	}
+	var out bytes.Buffer
+	if err := tmpl.Execute(&out, p); err != nil {
+		return nil, fmt.Errorf("failed to render %s of add-on %s: %w", part, a.Name, err)
+	}
+	return out.Bytes(), nil
+}
+
+// Get returns the add-on with the given name
+func Get(name string) (*Addon, bool) {
+	addon, ok := catalog[name]
+	return addon, ok
+}
+
+// List returns all add-ons sorted by name
+func List() []*Addon {
+	list := make([]*Addon, 0, len(catalog))
+	for _, addon := range catalog {
+		list = append(list, addon)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// MergeValues merges src into dst, recursing into nested maps so that add-ons can set
+// single keys of a component without replacing its other values
+func MergeValues(dst, src map[string]interface{}) {
+	for key, value := range src {
+		srcMap, srcIsMap := value.(map[string]interface{})
+		dstMap, dstIsMap := dst[key].(map[string]interface{})
+		if srcIsMap && dstIsMap {
+			MergeValues(dstMap, srcMap)
+			continue
+		}
+		dst[key] = value
+	}
+}
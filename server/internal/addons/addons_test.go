@@ -0,0 +1,77 @@
+package addons
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testParams = Params{
+	Instance:       "alpha",
+	Namespace:      "supa-alpha",
+	Release:        "alpha",
+	DatabaseHost:   "alpha-db.supa-alpha.svc.cluster.local",
+	DatabaseSecret: "alpha-secrets",
+}
+
+// TestCatalogRenders tests that every add-on in the catalog renders valid values and manifests
+func TestCatalogRenders(t *testing.T) {
+	for _, addon := range List() {
+		t.Run(addon.Name, func(t *testing.T) {
+			assert.NotEmpty(t, addon.Description)
+			assert.True(t, addon.HasValues() || addon.HasManifests(), "add-on adds nothing")
+
+			values, err := addon.Values(testParams)
+			require.NoError(t, err)
+			assert.Equal(t, addon.HasValues(), len(values) > 0)
+
+			objects, err := addon.Manifests(testParams)
+			require.NoError(t, err)
+			assert.Equal(t, addon.HasManifests(), len(objects) > 0)
+		})
+	}
+}
+
+// TestManifests tests that manifests are rendered with the instance's details
+func TestManifests(t *testing.T) {
+	addon, ok := Get("pgvector")
+	require.True(t, ok)
+
+	objects, err := addon.Manifests(testParams)
+	require.NoError(t, err)
+	require.Len(t, objects, 1)
+	assert.Equal(t, "Job", objects[0].GetKind())
+	assert.Equal(t, "alpha-addon-pgvector", objects[0].GetName())
+
+	metabase, ok := Get("metabase")
+	require.True(t, ok)
+	objects, err = metabase.Manifests(testParams)
+	require.NoError(t, err)
+	require.Len(t, objects, 2)
+	assert.Equal(t, "Deployment", objects[0].GetKind())
+	assert.Equal(t, "Service", objects[1].GetKind())
+
+	_, ok = Get("unknown")
+	assert.False(t, ok)
+}
+
+// TestMergeValues tests that add-on values are merged into nested chart values
+func TestMergeValues(t *testing.T) {
+	values := map[string]interface{}{
+		"storage": map[string]interface{}{
+			"environment": map[string]interface{}{"FILE_SIZE_LIMIT": "52428800"},
+		},
+	}
+	addon, _ := Get("imgproxy")
+	addonValues, err := addon.Values(testParams)
+	require.NoError(t, err)
+
+	MergeValues(values, addonValues)
+
+	environment := values["storage"].(map[string]interface{})["environment"].(map[string]interface{})
+	assert.Equal(t, "52428800", environment["FILE_SIZE_LIMIT"])
+	assert.Equal(t, "true", environment["ENABLE_IMAGE_TRANSFORMATION"])
+	assert.Equal(t, "http://alpha-supabase-imgproxy:5001", environment["IMGPROXY_URL"])
+	assert.Equal(t, true, values["imgproxy"].(map[string]interface{})["enabled"])
+}
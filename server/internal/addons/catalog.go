@@ -0,0 +1,111 @@
+package addons
+
+// catalog holds the add-ons instances can enable, by name
+var catalog = map[string]*Addon{
+	"pgvector": {
+		Name:        "pgvector",
+		Description: "Enables the pgvector extension for vector similarity search in the instance database",
+		manifests: `
+apiVersion: batch/v1
+kind: Job
+metadata:
+  name: {{ .Instance }}-addon-pgvector
+spec:
+  backoffLimit: 5
+  template:
+    metadata:
+      labels:
+        app.kubernetes.io/name: pgvector
+    spec:
+      restartPolicy: Never
+      containers:
+      - name: enable-extension
+        image: postgres:15-alpine
+        command: ["psql", "-v", "ON_ERROR_STOP=1", "-c", "CREATE EXTENSION IF NOT EXISTS vector WITH SCHEMA extensions"]
+        env:
+        - name: PGHOST
+          value: {{ .DatabaseHost }}
+        - name: PGUSER
+          value: postgres
+        - name: PGDATABASE
+          value: postgres
+        - name: PGPASSWORD
+          valueFrom:
+            secretKeyRef:
+              name: {{ .DatabaseSecret }}
+              key: postgres-password
+        resources:
+          requests:
+            cpu: 10m
+            memory: 16Mi
+          limits:
+            cpu: 100m
+            memory: 64Mi
+`,
+	},
+	"imgproxy": {
+		Name:        "imgproxy",
+		Description: "Deploys imgproxy and turns on image transformations in the storage API",
+		values: `
+imgproxy:
+  enabled: true
+storage:
+  environment:
+    ENABLE_IMAGE_TRANSFORMATION: "true"
+    IMGPROXY_URL: http://{{ .Release }}-supabase-imgproxy:5001
+`,
+	},
+	"metabase": {
+		Name:        "metabase",
+		Description: "Deploys Metabase for dashboards on the instance database, reachable in the instance namespace as service metabase on port 3000",
+		manifests: `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: metabase
+spec:
+  replicas: 1
+  selector:
+    matchLabels:
+      app.kubernetes.io/name: metabase
+      supacontrol.io/instance: {{ .Instance }}
+  template:
+    metadata:
+      labels:
+        app.kubernetes.io/name: metabase
+        supacontrol.io/instance: {{ .Instance }}
+    spec:
+      containers:
+      - name: metabase
+        image: metabase/metabase:v0.50.20
+        ports:
+        - name: http
+          containerPort: 3000
+        readinessProbe:
+          httpGet:
+            path: /api/health
+            port: http
+          initialDelaySeconds: 30
+        resources:
+          requests:
+            cpu: 250m
+            memory: 1Gi
+          limits:
+            cpu: "1"
+            memory: 2Gi
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: metabase
+spec:
+  selector:
+    app.kubernetes.io/name: metabase
+    supacontrol.io/instance: {{ .Instance }}
+  ports:
+  - name: http
+    port: 3000
+    targetPort: http
+`,
+	},
+}
@@ -0,0 +1,199 @@
+// Package advisories loads a feed of Supabase and Postgres security advisories
+// and matches them against the component versions running in an instance.
+package advisories
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/qubitquilt/supacontrol/server/internal/k8s"
+)
+
+// DefaultRefreshInterval is how often a loaded feed is fetched again
+const DefaultRefreshInterval = time.Hour
+
+// retryInterval is how soon a feed that failed to load is fetched again
+const retryInterval = time.Minute
+
+// maxFeedSize limits the size of a feed document
+const maxFeedSize = 10 << 20
+
+// Advisory describes a vulnerability in one Supabase component.
+// A version is affected when it is at or above Introduced (if set) and below Fixed (if set).
+type Advisory struct {
+	ID         string `json:"id"`
+	Component  string `json:"component"`
+	Severity   string `json:"severity"`
+	Summary    string `json:"summary"`
+	URL        string `json:"url,omitempty"`
+	Introduced string `json:"introduced,omitempty"`
+	Fixed      string `json:"fixed,omitempty"`
+}
+
+// document is the on-disk and over-the-wire feed format
+type document struct {
+	Advisories []Advisory `json:"advisories"`
+}
+
+// Affects reports whether the advisory applies to a component version.
+// Versions that cannot be compared numerically (e.g. "latest") never match.
+func (a Advisory) Affects(component, version string) bool {
+	if a.Component != component || version == "" {
+		return false
+	}
+	if a.Introduced == "" && a.Fixed == "" {
+		return false
+	}
+	if a.Introduced != "" {
+		cmp, ok := k8s.CompareVersions(version, a.Introduced)
+		if !ok || cmp < 0 {
+			return false
+		}
+	}
+	if a.Fixed != "" {
+		cmp, ok := k8s.CompareVersions(version, a.Fixed)
+		if !ok || cmp >= 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Match returns the advisories that apply to a component version
+func Match(advisories []Advisory, component, version string) []Advisory {
+	var matched []Advisory
+	for _, advisory := range advisories {
+		if advisory.Affects(component, version) {
+			matched = append(matched, advisory)
+		}
+	}
+	return matched
+}
+
+// Parse decodes a feed document and validates its entries
+func Parse(data []byte) ([]Advisory, error) {
+	var doc document
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("invalid advisory feed: %w", err)
+	}
+	for i, advisory := range doc.Advisories {
+		if advisory.ID == "" || advisory.Component == "" {
+			return nil, fmt.Errorf("advisory %d is missing an id or component", i)
+		}
+		if advisory.Introduced == "" && advisory.Fixed == "" {
+			return nil, fmt.Errorf("advisory %s must set introduced or fixed", advisory.ID)
+		}
+	}
+	return doc.Advisories, nil
+}
+
+// Feed loads advisories from an HTTP(S) URL or a local file and caches them. It is a
+// manager runnable: Start refreshes the cache in the background and requests read it.
+type Feed struct {
+	source     string
+	refresh    time.Duration
+	httpClient *http.Client
+
+	mu      sync.Mutex
+	cached  []Advisory
+	loaded  bool
+	lastErr error
+}
+
+// NewFeed creates a feed for source, which is either an http(s) URL or a file path
+func NewFeed(source string, refresh time.Duration) *Feed {
+	if refresh <= 0 {
+		refresh = DefaultRefreshInterval
+	}
+	return &Feed{
+		source:     source,
+		refresh:    refresh,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// NeedLeaderElection lets every replica refresh, as the feed is kept in memory
+func (f *Feed) NeedLeaderElection() bool {
+	return false
+}
+
+// Start loads the feed and refreshes it until ctx is cancelled. A failed load is
+// retried sooner than the refresh interval.
+func (f *Feed) Start(ctx context.Context) error {
+	for {
+		wait := f.refresh
+		if err := f.Refresh(ctx); err != nil {
+			wait = retryInterval
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil
+		case <-timer.C:
+		}
+	}
+}
+
+// Refresh fetches the feed and replaces the cached advisories. On failure the previous
+// advisories are kept and the error is reported by Advisories until the next success.
+func (f *Feed) Refresh(ctx context.Context) error {
+	data, err := f.read(ctx)
+	var advisories []Advisory
+	if err == nil {
+		advisories, err = Parse(data)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err != nil {
+		f.lastErr = fmt.Errorf("failed to load advisory feed %s: %w", f.source, err)
+		return f.lastErr
+	}
+	f.cached = advisories
+	f.loaded = true
+	f.lastErr = nil
+	return nil
+}
+
+// Advisories returns the advisories from the last successful refresh without fetching.
+// If the latest refresh failed, the previous advisories are returned with its error.
+func (f *Feed) Advisories(_ context.Context) ([]Advisory, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if !f.loaded && f.lastErr == nil {
+		return nil, fmt.Errorf("advisory feed %s has not been loaded yet", f.source)
+	}
+	return f.cached, f.lastErr
+}
+
+// read fetches the raw feed document
+func (f *Feed) read(ctx context.Context) ([]byte, error) {
+	if !strings.HasPrefix(f.source, "http://") && !strings.HasPrefix(f.source, "https://") {
+		return os.ReadFile(f.source)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, f.source, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := f.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, maxFeedSize))
+}
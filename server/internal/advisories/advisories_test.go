@@ -0,0 +1,131 @@
+package advisories
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testFeed = `{
+  "advisories": [
+    {"id": "SA-1", "component": "postgres", "severity": "high", "summary": "privilege escalation", "introduced": "15.1.0", "fixed": "15.1.0.150"},
+    {"id": "SA-2", "component": "gotrue", "severity": "medium", "summary": "token reuse", "fixed": "2.100.0"}
+  ]
+}`
+
+func TestAdvisory_Affects(t *testing.T) {
+	advisory := Advisory{ID: "SA-1", Component: "postgres", Introduced: "15.1.0", Fixed: "15.1.0.150"}
+
+	tests := []struct {
+		name      string
+		component string
+		version   string
+		expected  bool
+	}{
+		{name: "inside range", component: "postgres", version: "15.1.0.147", expected: true},
+		{name: "at introduced", component: "postgres", version: "15.1.0", expected: true},
+		{name: "at fixed", component: "postgres", version: "15.1.0.150", expected: false},
+		{name: "before introduced", component: "postgres", version: "14.9", expected: false},
+		{name: "other component", component: "gotrue", version: "15.1.0.147", expected: false},
+		{name: "non-numeric version", component: "postgres", version: "latest", expected: false},
+		{name: "unknown version", component: "postgres", version: "", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, advisory.Affects(tt.component, tt.version))
+		})
+	}
+}
+
+func TestMatch(t *testing.T) {
+	advisories, err := Parse([]byte(testFeed))
+	require.NoError(t, err)
+
+	matched := Match(advisories, "gotrue", "v2.99.1")
+	require.Len(t, matched, 1)
+	assert.Equal(t, "SA-2", matched[0].ID)
+
+	assert.Empty(t, Match(advisories, "gotrue", "v2.100.0"))
+}
+
+func TestParse_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{name: "malformed JSON", data: `{`},
+		{name: "missing component", data: `{"advisories":[{"id":"SA-1","fixed":"1.0"}]}`},
+		{name: "missing range", data: `{"advisories":[{"id":"SA-1","component":"kong"}]}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.data))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestFeed_LoadsFromFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "feed.json")
+	require.NoError(t, os.WriteFile(path, []byte(testFeed), 0o600))
+
+	feed := NewFeed(path, 0)
+	require.NoError(t, feed.Refresh(context.Background()))
+
+	advisories, err := feed.Advisories(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, advisories, 2)
+}
+
+func TestFeed_ServesCacheAndKeepsLastGoodFeed(t *testing.T) {
+	requests := 0
+	fail := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		requests++
+		if fail {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		_, _ = w.Write([]byte(testFeed))
+	}))
+	defer server.Close()
+
+	feed := NewFeed(server.URL, time.Hour)
+
+	// Nothing is fetched on read before the first refresh
+	_, err := feed.Advisories(context.Background())
+	assert.Error(t, err)
+	assert.Equal(t, 0, requests)
+
+	require.NoError(t, feed.Refresh(context.Background()))
+	advisories, err := feed.Advisories(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, advisories, 2)
+
+	// Reads serve the cached copy
+	_, err = feed.Advisories(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, requests)
+
+	// A failed refresh keeps the previous advisories and reports the error
+	fail = true
+	assert.Error(t, feed.Refresh(context.Background()))
+	advisories, err = feed.Advisories(context.Background())
+	assert.Error(t, err)
+	assert.Len(t, advisories, 2)
+
+	// A later successful refresh clears the error
+	fail = false
+	require.NoError(t, feed.Refresh(context.Background()))
+	_, err = feed.Advisories(context.Background())
+	assert.NoError(t, err)
+}
@@ -0,0 +1,100 @@
+// Package apikeyusage records when API keys were last used.
+//
+// Authenticating with an API key used to write its last-used time to the database on
+// every request, which under high request rates loaded the database more than the
+// requests themselves. A Recorder instead keeps the latest use of each key in memory
+// and writes them all in one statement every flush interval, so a key used a thousand
+// times between flushes costs one row update.
+package apikeyusage
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// DefaultInterval is how often recorded uses are written to the database
+const DefaultInterval = 30 * time.Second
+
+// Store persists the last-used times of API keys
+type Store interface {
+	UpdateAPIKeysLastUsed(lastUsed map[int64]time.Time) error
+}
+
+// Recorder batches API key uses in memory and flushes them to the store. It implements
+// the controller-runtime Runnable interface.
+type Recorder struct {
+	store Store
+
+	mu      sync.Mutex
+	pending map[int64]time.Time
+
+	Interval time.Duration
+}
+
+// NewRecorder creates a recorder with the default interval
+func NewRecorder(store Store) *Recorder {
+	return &Recorder{
+		store:    store,
+		pending:  map[int64]time.Time{},
+		Interval: DefaultInterval,
+	}
+}
+
+// NeedLeaderElection lets every replica flush the uses of the requests it served
+func (r *Recorder) NeedLeaderElection() bool {
+	return false
+}
+
+// Record notes that an API key was used now. It never blocks on the database.
+func (r *Recorder) Record(id int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.pending[id] = time.Now()
+}
+
+// Start flushes recorded uses every interval until ctx is cancelled, and once more
+// before returning so uses recorded during shutdown are kept
+func (r *Recorder) Start(ctx context.Context) error {
+	ticker := time.NewTicker(r.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			if err := r.Flush(); err != nil {
+				slog.Error("Failed to flush API key last used timestamps", "error", err)
+			}
+			return nil
+		case <-ticker.C:
+			if err := r.Flush(); err != nil {
+				slog.Error("Failed to flush API key last used timestamps", "error", err)
+			}
+		}
+	}
+}
+
+// Flush writes the uses recorded since the last flush. When the write fails they are
+// kept for the next flush, unless the key was used again in the meantime.
+func (r *Recorder) Flush() error {
+	r.mu.Lock()
+	batch := r.pending
+	r.pending = map[int64]time.Time{}
+	r.mu.Unlock()
+
+	if len(batch) == 0 {
+		return nil
+	}
+	if err := r.store.UpdateAPIKeysLastUsed(batch); err != nil {
+		r.mu.Lock()
+		for id, usedAt := range batch {
+			if current, ok := r.pending[id]; !ok || current.Before(usedAt) {
+				r.pending[id] = usedAt
+			}
+		}
+		r.mu.Unlock()
+		return err
+	}
+	return nil
+}
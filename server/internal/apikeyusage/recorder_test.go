@@ -0,0 +1,90 @@
+package apikeyusage
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memoryStore keeps the flushed batches, failing while err is set
+type memoryStore struct {
+	mu      sync.Mutex
+	batches []map[int64]time.Time
+	err     error
+}
+
+func (s *memoryStore) UpdateAPIKeysLastUsed(lastUsed map[int64]time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return s.err
+	}
+	s.batches = append(s.batches, lastUsed)
+	return nil
+}
+
+// TestRecorder_Flush tests that uses are batched per key and written once
+func TestRecorder_Flush(t *testing.T) {
+	store := &memoryStore{}
+	recorder := NewRecorder(store)
+
+	for range 100 {
+		recorder.Record(1)
+	}
+	recorder.Record(2)
+	if len(store.batches) != 0 {
+		t.Fatal("expected nothing written before the flush")
+	}
+
+	if err := recorder.Flush(); err != nil {
+		t.Fatalf("Flush() failed: %v", err)
+	}
+	if len(store.batches) != 1 || len(store.batches[0]) != 2 {
+		t.Fatalf("expected one batch with two keys, got %v", store.batches)
+	}
+
+	// Nothing new was recorded, so the next flush writes nothing
+	if err := recorder.Flush(); err != nil {
+		t.Fatalf("Flush() failed: %v", err)
+	}
+	if len(store.batches) != 1 {
+		t.Errorf("expected no second batch, got %d batches", len(store.batches))
+	}
+}
+
+// TestRecorder_FlushRetries tests that uses are kept when writing them fails
+func TestRecorder_FlushRetries(t *testing.T) {
+	store := &memoryStore{err: fmt.Errorf("database unavailable")}
+	recorder := NewRecorder(store)
+	recorder.Record(1)
+
+	if err := recorder.Flush(); err == nil {
+		t.Fatal("expected the flush to fail")
+	}
+	store.err = nil
+	if err := recorder.Flush(); err != nil {
+		t.Fatalf("Flush() failed: %v", err)
+	}
+	if len(store.batches) != 1 || store.batches[0][1].IsZero() {
+		t.Errorf("expected the failed use written on the next flush, got %v", store.batches)
+	}
+}
+
+// TestRecorder_StartFlushesOnShutdown tests that uses recorded before shutdown are written
+func TestRecorder_StartFlushesOnShutdown(t *testing.T) {
+	store := &memoryStore{}
+	recorder := NewRecorder(store)
+	recorder.Interval = time.Hour
+	recorder.Record(3)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := recorder.Start(ctx); err != nil {
+		t.Fatalf("Start() failed: %v", err)
+	}
+	if len(store.batches) != 1 || store.batches[0][3].IsZero() {
+		t.Errorf("expected the use flushed on shutdown, got %v", store.batches)
+	}
+}
@@ -172,8 +172,221 @@ func (s *Service) ValidateJWT(tokenString string) (*JWTClaims, error) {
 	}
 
 	if claims, ok := token.Claims.(*JWTClaims); ok && token.Valid {
+		// Invitation, tunnel and Studio tokens share the signing key but carry an
+		// audience, and must never authenticate a session
+		if len(claims.Audience) > 0 {
+			return nil, fmt.Errorf("invalid JWT token")
+		}
 		return claims, nil
 	}
 
 	return nil, fmt.Errorf("invalid JWT token")
 }
+
+// invitationAudience marks tokens that may only be used to accept a team invitation
+const invitationAudience = "supacontrol-invitation"
+
+// InvitationClaims represents the claims carried by a signed team invitation token
+type InvitationClaims struct {
+	InvitationID int64 `json:"invitation_id"`
+	TeamID       int64 `json:"team_id"`
+	jwt.RegisteredClaims
+}
+
+// GenerateInvitationToken signs a token that identifies a pending team invitation
+func (s *Service) GenerateInvitationToken(invitationID, teamID int64, expiresAt time.Time) (string, error) {
+	claims := InvitationClaims{
+		InvitationID: invitationID,
+		TeamID:       teamID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Audience:  jwt.ClaimStrings{invitationAudience},
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	signedToken, err := token.SignedString(s.jwtSecret)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign invitation token: %w", err)
+	}
+
+	return signedToken, nil
+}
+
+// ValidateInvitationToken validates and parses a team invitation token
+func (s *Service) ValidateInvitationToken(tokenString string) (*InvitationClaims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &InvitationClaims{}, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return s.jwtSecret, nil
+	}, jwt.WithAudience(invitationAudience))
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse invitation token: %w", err)
+	}
+
+	if claims, ok := token.Claims.(*InvitationClaims); ok && token.Valid {
+		return claims, nil
+	}
+
+	return nil, fmt.Errorf("invalid invitation token")
+}
+
+// tunnelAudience marks tokens that may only be used to connect a database tunnel
+const tunnelAudience = "supacontrol-tunnel"
+
+// TunnelClaims represents the claims carried by a signed database tunnel token
+type TunnelClaims struct {
+	UserID   int64  `json:"user_id"`
+	Instance string `json:"instance"`
+	jwt.RegisteredClaims
+}
+
+// GenerateTunnelToken signs a token that lets a user connect a tunnel to an instance
+// database until expiresAt
+func (s *Service) GenerateTunnelToken(userID int64, instance string, expiresAt time.Time) (string, error) {
+	claims := TunnelClaims{
+		UserID:   userID,
+		Instance: instance,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Audience:  jwt.ClaimStrings{tunnelAudience},
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	signedToken, err := token.SignedString(s.jwtSecret)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign tunnel token: %w", err)
+	}
+
+	return signedToken, nil
+}
+
+// ValidateTunnelToken validates and parses a database tunnel token
+func (s *Service) ValidateTunnelToken(tokenString string) (*TunnelClaims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &TunnelClaims{}, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return s.jwtSecret, nil
+	}, jwt.WithAudience(tunnelAudience))
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse tunnel token: %w", err)
+	}
+
+	if claims, ok := token.Claims.(*TunnelClaims); ok && token.Valid {
+		return claims, nil
+	}
+
+	return nil, fmt.Errorf("invalid tunnel token")
+}
+
+// studioSessionAudience marks tokens that may only be used to open an instance's Studio
+const studioSessionAudience = "supacontrol-studio-session"
+
+// StudioSessionClaims represents the claims carried by a signed Studio session link. The
+// token ID lets the API accept each link only once.
+type StudioSessionClaims struct {
+	UserID   int64  `json:"user_id"`
+	Instance string `json:"instance"`
+	jwt.RegisteredClaims
+}
+
+// GenerateStudioSessionToken signs a token that lets a user open an instance's Studio
+// once until expiresAt
+func (s *Service) GenerateStudioSessionToken(userID int64, instance string, expiresAt time.Time) (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", fmt.Errorf("failed to generate Studio session ID: %w", err)
+	}
+	claims := StudioSessionClaims{
+		UserID:   userID,
+		Instance: instance,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        base64.RawURLEncoding.EncodeToString(id),
+			Audience:  jwt.ClaimStrings{studioSessionAudience},
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	signedToken, err := token.SignedString(s.jwtSecret)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign Studio session token: %w", err)
+	}
+
+	return signedToken, nil
+}
+
+// ValidateStudioSessionToken validates and parses a Studio session token
+func (s *Service) ValidateStudioSessionToken(tokenString string) (*StudioSessionClaims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &StudioSessionClaims{}, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return s.jwtSecret, nil
+	}, jwt.WithAudience(studioSessionAudience))
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse Studio session token: %w", err)
+	}
+
+	if claims, ok := token.Claims.(*StudioSessionClaims); ok && token.Valid && claims.ID != "" {
+		return claims, nil
+	}
+
+	return nil, fmt.Errorf("invalid Studio session token")
+}
+
+// studioProxyAudience marks tokens that may only be used to reach an instance's Studio
+// through the API's proxy
+const studioProxyAudience = "supacontrol-studio-proxy"
+
+// GenerateStudioProxyToken signs the session a redeemed Studio link opens. It carries the
+// link's ID, so every proxy session traces back to the link that opened it.
+func (s *Service) GenerateStudioProxyToken(link *StudioSessionClaims, expiresAt time.Time) (string, error) {
+	claims := StudioSessionClaims{
+		UserID:   link.UserID,
+		Instance: link.Instance,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        link.ID,
+			Audience:  jwt.ClaimStrings{studioProxyAudience},
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	signedToken, err := token.SignedString(s.jwtSecret)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign Studio proxy token: %w", err)
+	}
+
+	return signedToken, nil
+}
+
+// ValidateStudioProxyToken validates and parses a Studio proxy session token
+func (s *Service) ValidateStudioProxyToken(tokenString string) (*StudioSessionClaims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &StudioSessionClaims{}, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return s.jwtSecret, nil
+	}, jwt.WithAudience(studioProxyAudience))
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse Studio proxy token: %w", err)
+	}
+
+	if claims, ok := token.Claims.(*StudioSessionClaims); ok && token.Valid && claims.ID != "" {
+		return claims, nil
+	}
+
+	return nil, fmt.Errorf("invalid Studio proxy token")
+}
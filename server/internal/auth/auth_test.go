@@ -225,3 +225,177 @@ func TestValidateJWTWrongSecret(t *testing.T) {
 		t.Error("ValidateJWT() should fail for token signed with different secret")
 	}
 }
+
+func TestInvitationToken(t *testing.T) {
+	service := NewService("test-secret-key")
+
+	token, err := service.GenerateInvitationToken(42, 7, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("GenerateInvitationToken() error = %v", err)
+	}
+
+	claims, err := service.ValidateInvitationToken(token)
+	if err != nil {
+		t.Fatalf("ValidateInvitationToken() error = %v", err)
+	}
+	if claims.InvitationID != 42 || claims.TeamID != 7 {
+		t.Errorf("ValidateInvitationToken() claims = %+v, want invitation 42 team 7", claims)
+	}
+
+	// Invitation tokens must not be accepted as session tokens
+	if _, err := service.ValidateJWT(token); err == nil {
+		t.Error("ValidateJWT() should reject invitation tokens")
+	}
+}
+
+func TestInvitationTokenRejectsSessionTokens(t *testing.T) {
+	service := NewService("test-secret-key")
+
+	sessionToken, err := service.GenerateJWT(1, "testuser", "admin", time.Hour)
+	if err != nil {
+		t.Fatalf("GenerateJWT() error = %v", err)
+	}
+
+	if _, err := service.ValidateInvitationToken(sessionToken); err == nil {
+		t.Error("ValidateInvitationToken() should reject session tokens")
+	}
+}
+
+func TestInvitationTokenExpired(t *testing.T) {
+	service := NewService("test-secret-key")
+
+	token, err := service.GenerateInvitationToken(1, 1, time.Now().Add(-time.Minute))
+	if err != nil {
+		t.Fatalf("GenerateInvitationToken() error = %v", err)
+	}
+
+	if _, err := service.ValidateInvitationToken(token); err == nil {
+		t.Error("ValidateInvitationToken() should fail for expired token")
+	}
+}
+
+func TestTunnelToken(t *testing.T) {
+	service := NewService("test-secret-key")
+
+	token, err := service.GenerateTunnelToken(7, "my-app", time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("GenerateTunnelToken() error = %v", err)
+	}
+
+	claims, err := service.ValidateTunnelToken(token)
+	if err != nil {
+		t.Fatalf("ValidateTunnelToken() error = %v", err)
+	}
+	if claims.UserID != 7 || claims.Instance != "my-app" {
+		t.Errorf("ValidateTunnelToken() claims = %+v, want user 7 instance my-app", claims)
+	}
+
+	// Tunnel tokens carry a user ID but must not be accepted as session tokens
+	if _, err := service.ValidateJWT(token); err == nil {
+		t.Error("ValidateJWT() should reject tunnel tokens")
+	}
+
+	invitation, err := service.GenerateInvitationToken(1, 1, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("GenerateInvitationToken() error = %v", err)
+	}
+	if _, err := service.ValidateTunnelToken(invitation); err == nil {
+		t.Error("ValidateTunnelToken() should reject invitation tokens")
+	}
+
+	expired, err := service.GenerateTunnelToken(7, "my-app", time.Now().Add(-time.Minute))
+	if err != nil {
+		t.Fatalf("GenerateTunnelToken() error = %v", err)
+	}
+	if _, err := service.ValidateTunnelToken(expired); err == nil {
+		t.Error("ValidateTunnelToken() should fail for expired token")
+	}
+}
+
+func TestStudioSessionToken(t *testing.T) {
+	service := NewService("test-secret-key")
+
+	token, err := service.GenerateStudioSessionToken(7, "my-app", time.Now().Add(time.Minute))
+	if err != nil {
+		t.Fatalf("GenerateStudioSessionToken() error = %v", err)
+	}
+	claims, err := service.ValidateStudioSessionToken(token)
+	if err != nil {
+		t.Fatalf("ValidateStudioSessionToken() error = %v", err)
+	}
+	if claims.UserID != 7 || claims.Instance != "my-app" || claims.ID == "" {
+		t.Errorf("ValidateStudioSessionToken() claims = %+v, want user 7 instance my-app with an ID", claims)
+	}
+
+	// Each link gets its own ID, so redeeming one does not redeem the others
+	other, err := service.GenerateStudioSessionToken(7, "my-app", time.Now().Add(time.Minute))
+	if err != nil {
+		t.Fatalf("GenerateStudioSessionToken() error = %v", err)
+	}
+	if otherClaims, err := service.ValidateStudioSessionToken(other); err != nil || otherClaims.ID == claims.ID {
+		t.Errorf("expected a distinct token ID, got %+v (%v)", otherClaims, err)
+	}
+
+	if _, err := service.ValidateJWT(token); err == nil {
+		t.Error("ValidateJWT() should reject Studio session tokens")
+	}
+	tunnel, err := service.GenerateTunnelToken(7, "my-app", time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("GenerateTunnelToken() error = %v", err)
+	}
+	if _, err := service.ValidateStudioSessionToken(tunnel); err == nil {
+		t.Error("ValidateStudioSessionToken() should reject tunnel tokens")
+	}
+
+	expired, err := service.GenerateStudioSessionToken(7, "my-app", time.Now().Add(-time.Minute))
+	if err != nil {
+		t.Fatalf("GenerateStudioSessionToken() error = %v", err)
+	}
+	if _, err := service.ValidateStudioSessionToken(expired); err == nil {
+		t.Error("ValidateStudioSessionToken() should fail for expired token")
+	}
+}
+
+func TestStudioProxyToken(t *testing.T) {
+	service := NewService("test-secret-key")
+
+	link, err := service.GenerateStudioSessionToken(7, "my-app", time.Now().Add(time.Minute))
+	if err != nil {
+		t.Fatalf("GenerateStudioSessionToken() error = %v", err)
+	}
+	linkClaims, err := service.ValidateStudioSessionToken(link)
+	if err != nil {
+		t.Fatalf("ValidateStudioSessionToken() error = %v", err)
+	}
+
+	token, err := service.GenerateStudioProxyToken(linkClaims, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("GenerateStudioProxyToken() error = %v", err)
+	}
+	claims, err := service.ValidateStudioProxyToken(token)
+	if err != nil {
+		t.Fatalf("ValidateStudioProxyToken() error = %v", err)
+	}
+	if claims.UserID != 7 || claims.Instance != "my-app" || claims.ID != linkClaims.ID {
+		t.Errorf("ValidateStudioProxyToken() claims = %+v, want user 7 instance my-app with the link's ID", claims)
+	}
+
+	// Neither token can stand in for the other, nor for a login session
+	if _, err := service.ValidateStudioProxyToken(link); err == nil {
+		t.Error("ValidateStudioProxyToken() should reject Studio links")
+	}
+	if _, err := service.ValidateStudioSessionToken(token); err == nil {
+		t.Error("ValidateStudioSessionToken() should reject Studio proxy tokens")
+	}
+	if _, err := service.ValidateJWT(token); err == nil {
+		t.Error("ValidateJWT() should reject Studio proxy tokens")
+	}
+
+	expired, err := service.GenerateStudioProxyToken(linkClaims, time.Now().Add(-time.Minute))
+	if err != nil {
+		t.Fatalf("GenerateStudioProxyToken() error = %v", err)
+	}
+	if _, err := service.ValidateStudioProxyToken(expired); err == nil {
+		t.Error("ValidateStudioProxyToken() should fail for expired token")
+	}
+}
@@ -0,0 +1,34 @@
+// Package backup takes and restores logical backups of instance databases.
+//
+// A backup is a pg_dump of the instance's postgres database, encrypted with a key from
+// the configured Keyring before it leaves the API and stored in object storage. The key
+// ID travels with the artifact and its database record, so a backup can only be
+// restored while its key is still configured. A Pruner running on the elected leader
+// deletes the backups an instance's retention policy no longer keeps.
+package backup
+
+import (
+	"fmt"
+	"time"
+)
+
+// DumpCommand returns the pg_dump invocation run in the instance's database container.
+// The dump drops objects before recreating them, so it can be restored over the live
+// database. Connecting over the loopback interface lets the chart's trust rule
+// authenticate the postgres user without a password.
+func DumpCommand() []string {
+	return []string{"pg_dump", "-h", "127.0.0.1", "-U", "postgres", "-d", "postgres", "--clean", "--if-exists"}
+}
+
+// RestoreCommand returns the psql invocation that restores a dump passed on stdin. The
+// dump is applied in a single transaction that stops at the first error, so a failed
+// restore leaves the database as it was.
+func RestoreCommand() []string {
+	return []string{"psql", "-X", "-q", "-v", "ON_ERROR_STOP=1", "--single-transaction",
+		"-h", "127.0.0.1", "-U", "postgres", "-d", "postgres"}
+}
+
+// ObjectKey returns the object storage key of a backup of an instance taken at createdAt
+func ObjectKey(instance string, createdAt time.Time) string {
+	return fmt.Sprintf("backups/%s/%s.sql.enc", instance, createdAt.UTC().Format("20060102T150405.000000000Z"))
+}
@@ -0,0 +1,144 @@
+package backup
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// KeySize is the size of backup encryption keys in bytes (AES-256)
+const KeySize = 32
+
+// magic starts every encrypted backup and names the format version
+const magic = "SCBACKUP1"
+
+var (
+	// ErrUnknownKey is returned when a backup was encrypted with a key that is not configured
+	ErrUnknownKey = errors.New("backup encryption key is not configured")
+
+	// ErrCorrupt is returned when a backup is not an encrypted backup or was modified
+	ErrCorrupt = errors.New("backup is corrupt or was modified")
+)
+
+// Keyring holds the keys backups are encrypted with, by ID. New backups use the active
+// key; the others are kept to restore backups taken before the active key was rotated.
+type Keyring struct {
+	keys   map[string]cipher.AEAD
+	active string
+}
+
+// NewKeyring builds a keyring from base64-encoded 256-bit keys by ID. The active key
+// may be left empty when there is only one key.
+func NewKeyring(keys map[string]string, active string) (*Keyring, error) {
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no backup encryption keys")
+	}
+	ring := &Keyring{keys: make(map[string]cipher.AEAD, len(keys)), active: active}
+	for id, encoded := range keys {
+		if id == "" || len(id) > 64 || strings.ContainsAny(id, " ,=") {
+			return nil, fmt.Errorf("invalid backup encryption key ID %q", id)
+		}
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil || len(key) != KeySize {
+			return nil, fmt.Errorf("backup encryption key %q must be %d base64-encoded bytes", id, KeySize)
+		}
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, fmt.Errorf("backup encryption key %q: %w", id, err)
+		}
+		if ring.keys[id], err = cipher.NewGCM(block); err != nil {
+			return nil, fmt.Errorf("backup encryption key %q: %w", id, err)
+		}
+	}
+	if ring.active == "" {
+		if len(keys) > 1 {
+			return nil, fmt.Errorf("the active backup encryption key must be chosen among %s", strings.Join(ring.KeyIDs(), ", "))
+		}
+		for id := range keys {
+			ring.active = id
+		}
+	}
+	if _, ok := ring.keys[ring.active]; !ok {
+		return nil, fmt.Errorf("active backup encryption key %q is not configured", ring.active)
+	}
+	return ring, nil
+}
+
+// ActiveKeyID returns the ID of the key new backups are encrypted with
+func (k *Keyring) ActiveKeyID() string {
+	return k.active
+}
+
+// KeyIDs returns the IDs of the configured keys, sorted
+func (k *Keyring) KeyIDs() []string {
+	ids := make([]string, 0, len(k.keys))
+	for id := range k.keys {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Encrypt encrypts a backup with the active key. The output starts with a header naming
+// the key, which is authenticated along with the backup so it cannot be swapped.
+func (k *Keyring) Encrypt(plaintext []byte) ([]byte, error) {
+	aead := k.keys[k.active]
+	header := encodeHeader(k.active)
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	out := make([]byte, 0, len(header)+len(nonce)+len(plaintext)+aead.Overhead())
+	out = append(append(out, header...), nonce...)
+	return aead.Seal(out, nonce, plaintext, header), nil
+}
+
+// Decrypt decrypts a backup with the key named in its header
+func (k *Keyring) Decrypt(ciphertext []byte) ([]byte, error) {
+	keyID, err := KeyID(ciphertext)
+	if err != nil {
+		return nil, err
+	}
+	aead, ok := k.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKey, keyID)
+	}
+	header := encodeHeader(keyID)
+	body := ciphertext[len(header):]
+	if len(body) < aead.NonceSize()+aead.Overhead() {
+		return nil, ErrCorrupt
+	}
+	nonce, sealed := body[:aead.NonceSize()], body[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, sealed, header)
+	if err != nil {
+		return nil, ErrCorrupt
+	}
+	return plaintext, nil
+}
+
+// KeyID returns the ID of the key an encrypted backup was encrypted with
+func KeyID(ciphertext []byte) (string, error) {
+	if !bytes.HasPrefix(ciphertext, []byte(magic)) || len(ciphertext) < len(magic)+1 {
+		return "", ErrCorrupt
+	}
+	length := int(ciphertext[len(magic)])
+	if length == 0 || len(ciphertext) < len(magic)+1+length {
+		return "", ErrCorrupt
+	}
+	return string(ciphertext[len(magic)+1 : len(magic)+1+length]), nil
+}
+
+// encodeHeader returns the header of a backup encrypted with the key keyID: the format
+// magic, then the key ID prefixed with its length
+func encodeHeader(keyID string) []byte {
+	header := make([]byte, 0, len(magic)+1+len(keyID))
+	header = append(header, magic...)
+	header = append(header, byte(len(keyID)))
+	return append(header, keyID...)
+}
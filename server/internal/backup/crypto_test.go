@@ -0,0 +1,99 @@
+package backup
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"testing"
+)
+
+func testKey(b byte) string {
+	return base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{b}, KeySize))
+}
+
+func TestNewKeyring(t *testing.T) {
+	tests := []struct {
+		name    string
+		keys    map[string]string
+		active  string
+		want    string
+		wantErr bool
+	}{
+		{name: "single key is active", keys: map[string]string{"k1": testKey(1)}, want: "k1"},
+		{name: "chosen key is active", keys: map[string]string{"k1": testKey(1), "k2": testKey(2)}, active: "k2", want: "k2"},
+		{name: "several keys need a choice", keys: map[string]string{"k1": testKey(1), "k2": testKey(2)}, wantErr: true},
+		{name: "active key missing", keys: map[string]string{"k1": testKey(1)}, active: "k2", wantErr: true},
+		{name: "short key", keys: map[string]string{"k1": base64.StdEncoding.EncodeToString([]byte("short"))}, wantErr: true},
+		{name: "not base64", keys: map[string]string{"k1": "not base64!"}, wantErr: true},
+		{name: "no keys", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ring, err := NewKeyring(tt.keys, tt.active)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("NewKeyring() expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewKeyring() error = %v", err)
+			}
+			if ring.ActiveKeyID() != tt.want {
+				t.Errorf("ActiveKeyID() = %q, want %q", ring.ActiveKeyID(), tt.want)
+			}
+		})
+	}
+}
+
+func TestKeyring_EncryptDecrypt(t *testing.T) {
+	old, err := NewKeyring(map[string]string{"2025": testKey(1)}, "")
+	if err != nil {
+		t.Fatalf("NewKeyring() error = %v", err)
+	}
+	dump := []byte("CREATE TABLE todos (id bigint);")
+
+	ciphertext, err := old.Encrypt(dump)
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+	if bytes.Contains(ciphertext, dump) {
+		t.Error("Encrypt() left the dump readable")
+	}
+	if id, err := KeyID(ciphertext); err != nil || id != "2025" {
+		t.Errorf("KeyID() = %q, %v; want 2025", id, err)
+	}
+
+	// After a rotation the old key still restores backups taken with it
+	rotated, err := NewKeyring(map[string]string{"2025": testKey(1), "2026": testKey(2)}, "2026")
+	if err != nil {
+		t.Fatalf("NewKeyring() error = %v", err)
+	}
+	plaintext, err := rotated.Decrypt(ciphertext)
+	if err != nil || !bytes.Equal(plaintext, dump) {
+		t.Fatalf("Decrypt() = %q, %v; want the dump", plaintext, err)
+	}
+
+	// Without the key the backup cannot be restored
+	other, err := NewKeyring(map[string]string{"2026": testKey(2)}, "")
+	if err != nil {
+		t.Fatalf("NewKeyring() error = %v", err)
+	}
+	if _, err := other.Decrypt(ciphertext); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("Decrypt() error = %v, want ErrUnknownKey", err)
+	}
+
+	// Neither the backup nor the key named in its header may be changed
+	tampered := bytes.Clone(ciphertext)
+	tampered[len(tampered)-1] ^= 1
+	if _, err := rotated.Decrypt(tampered); !errors.Is(err, ErrCorrupt) {
+		t.Errorf("Decrypt() of a modified backup error = %v, want ErrCorrupt", err)
+	}
+	swapped := append(encodeHeader("2026"), ciphertext[len(encodeHeader("2025")):]...)
+	if _, err := rotated.Decrypt(swapped); !errors.Is(err, ErrCorrupt) {
+		t.Errorf("Decrypt() with a swapped key ID error = %v, want ErrCorrupt", err)
+	}
+	if _, err := rotated.Decrypt(dump); !errors.Is(err, ErrCorrupt) {
+		t.Errorf("Decrypt() of a plain dump error = %v, want ErrCorrupt", err)
+	}
+}
@@ -0,0 +1,111 @@
+package backup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+	"github.com/qubitquilt/supacontrol/server/internal/objectstore"
+)
+
+// DefaultPruneInterval is how often retention policies are applied
+const DefaultPruneInterval = time.Hour
+
+// Store persists backup records and retention policies
+type Store interface {
+	ListBackupRetentionPolicies() ([]*apitypes.BackupRetentionPolicy, error)
+	ListBackups(instanceName string) ([]*apitypes.Backup, error)
+	MarkBackupPruned(id int64) (bool, error)
+	UnmarkBackupPruned(id int64) error
+}
+
+// Pruner deletes the artifacts of backups their instance's retention policy no longer
+// keeps and marks their records pruned. It implements the controller-runtime Runnable
+// interface and only runs on the elected leader.
+type Pruner struct {
+	store   Store
+	objects objectstore.Backend
+
+	Interval time.Duration
+}
+
+// NewPruner creates a pruner with the default interval
+func NewPruner(store Store, objects objectstore.Backend) *Pruner {
+	return &Pruner{
+		store:    store,
+		objects:  objects,
+		Interval: DefaultPruneInterval,
+	}
+}
+
+// NeedLeaderElection ensures only one replica prunes backups
+func (p *Pruner) NeedLeaderElection() bool {
+	return true
+}
+
+// Start prunes backups until ctx is cancelled
+func (p *Pruner) Start(ctx context.Context) error {
+	ticker := time.NewTicker(p.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := p.Prune(ctx); err != nil {
+			slog.Error("Failed to prune backups", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Prune applies every retention policy once. A backup is marked pruned before its
+// artifact is deleted, so a hold placed in the meantime wins; when the deletion fails
+// the mark is reverted and the backup is retried on the next pass.
+func (p *Pruner) Prune(ctx context.Context) error {
+	policies, err := p.store.ListBackupRetentionPolicies()
+	if err != nil {
+		return fmt.Errorf("failed to list retention policies: %w", err)
+	}
+
+	var errs []error
+	for _, policy := range policies {
+		backups, err := p.store.ListBackups(policy.InstanceName)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to list backups of %s: %w", policy.InstanceName, err))
+			continue
+		}
+		for _, backup := range Expired(policy, backups) {
+			if err := p.prune(ctx, backup); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (p *Pruner) prune(ctx context.Context, backup *apitypes.Backup) error {
+	claimed, err := p.store.MarkBackupPruned(backup.ID)
+	if err != nil {
+		return fmt.Errorf("failed to mark backup %d pruned: %w", backup.ID, err)
+	}
+	if !claimed {
+		// Held or pruned since it was listed
+		return nil
+	}
+
+	if err := p.objects.Delete(ctx, backup.ObjectKey); err != nil {
+		if unmarkErr := p.store.UnmarkBackupPruned(backup.ID); unmarkErr != nil {
+			slog.Error("Failed to unmark backup pruned", "backup_id", backup.ID, "error", unmarkErr)
+		}
+		return fmt.Errorf("failed to delete backup %d: %w", backup.ID, err)
+	}
+
+	slog.Info("Pruned backup", "backup_id", backup.ID, "instance", backup.InstanceName, "created_at", backup.CreatedAt)
+	return nil
+}
@@ -0,0 +1,168 @@
+package backup
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+	"github.com/qubitquilt/supacontrol/server/internal/objectstore"
+)
+
+// memoryStore keeps backups and policies in memory. Backups in heldLater are listed
+// unheld but held by the time the pruner claims them.
+type memoryStore struct {
+	policies  []*apitypes.BackupRetentionPolicy
+	backups   []*apitypes.Backup
+	heldLater map[int64]bool
+}
+
+func (s *memoryStore) ListBackupRetentionPolicies() ([]*apitypes.BackupRetentionPolicy, error) {
+	return s.policies, nil
+}
+
+func (s *memoryStore) ListBackups(instanceName string) ([]*apitypes.Backup, error) {
+	var backups []*apitypes.Backup
+	for _, backup := range s.backups {
+		if backup.InstanceName == instanceName && backup.PrunedAt == nil {
+			backups = append(backups, backup)
+		}
+	}
+	return backups, nil
+}
+
+func (s *memoryStore) MarkBackupPruned(id int64) (bool, error) {
+	for _, backup := range s.backups {
+		if backup.ID == id && !backup.Held && !s.heldLater[id] && backup.PrunedAt == nil {
+			now := time.Now()
+			backup.PrunedAt = &now
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *memoryStore) UnmarkBackupPruned(id int64) error {
+	for _, backup := range s.backups {
+		if backup.ID == id {
+			backup.PrunedAt = nil
+		}
+	}
+	return nil
+}
+
+func (s *memoryStore) pruned() []int64 {
+	var ids []int64
+	for _, backup := range s.backups {
+		if backup.PrunedAt != nil {
+			ids = append(ids, backup.ID)
+		}
+	}
+	return ids
+}
+
+// failingDeletes is an object store whose deletes fail for keys containing "fail"
+type failingDeletes struct {
+	objectstore.Backend
+}
+
+func (f failingDeletes) Delete(ctx context.Context, key string) error {
+	if strings.Contains(key, "fail") {
+		return errors.New("storage unavailable")
+	}
+	return f.Backend.Delete(ctx, key)
+}
+
+func newPrunerFixture(t *testing.T, keys ...string) (*memoryStore, objectstore.Backend) {
+	t.Helper()
+	objects, err := objectstore.New(objectstore.Config{Provider: objectstore.ProviderLocal, Path: t.TempDir()})
+	if err != nil {
+		t.Fatalf("objectstore.New() error = %v", err)
+	}
+
+	store := &memoryStore{
+		policies: []*apitypes.BackupRetentionPolicy{{InstanceName: "my-app", KeepDaily: 1}},
+	}
+	newest := time.Date(2026, time.October, 17, 3, 0, 0, 0, time.UTC)
+	for i, key := range keys {
+		if err := objects.Put(context.Background(), key, strings.NewReader("artifact"), int64(len("artifact"))); err != nil {
+			t.Fatalf("Put() error = %v", err)
+		}
+		store.backups = append(store.backups, &apitypes.Backup{
+			ID:           int64(i + 1),
+			InstanceName: "my-app",
+			ObjectKey:    key,
+			CreatedAt:    newest.AddDate(0, 0, -i),
+		})
+	}
+	return store, objects
+}
+
+func exists(t *testing.T, objects objectstore.Backend, key string) bool {
+	t.Helper()
+	body, err := objects.Get(context.Background(), key)
+	if errors.Is(err, objectstore.ErrNotFound) {
+		return false
+	}
+	if err != nil {
+		t.Fatalf("Get(%s) error = %v", key, err)
+	}
+	_ = body.Close()
+	return true
+}
+
+// TestPruner_Prune tests that expired backups are deleted and marked, while kept, held
+// and newly held backups stay
+func TestPruner_Prune(t *testing.T) {
+	store, objects := newPrunerFixture(t, "backups/my-app/1", "backups/my-app/2", "backups/my-app/3", "backups/my-app/4")
+	store.backups[2].Held = true
+	store.heldLater = map[int64]bool{4: true}
+	// Another instance without a policy is left alone
+	store.backups = append(store.backups, &apitypes.Backup{ID: 9, InstanceName: "other", ObjectKey: "backups/other/1"})
+
+	if err := NewPruner(store, objects).Prune(context.Background()); err != nil {
+		t.Fatalf("Prune() error = %v", err)
+	}
+
+	if got := store.pruned(); len(got) != 1 || got[0] != 2 {
+		t.Errorf("Expected only backup 2 to be pruned, got %v", got)
+	}
+	for key, want := range map[string]bool{
+		"backups/my-app/1": true,
+		"backups/my-app/2": false,
+		"backups/my-app/3": true,
+		"backups/my-app/4": true,
+	} {
+		if got := exists(t, objects, key); got != want {
+			t.Errorf("Object %s exists = %v, want %v", key, got, want)
+		}
+	}
+}
+
+// TestPruner_DeleteFails tests that a backup whose artifact cannot be deleted stays
+// listed and is retried
+func TestPruner_DeleteFails(t *testing.T) {
+	store, objects := newPrunerFixture(t, "backups/my-app/1", "backups/my-app/fail", "backups/my-app/3")
+	pruner := NewPruner(store, failingDeletes{objects})
+
+	if err := pruner.Prune(context.Background()); err == nil || !strings.Contains(err.Error(), "failed to delete backup 2") {
+		t.Fatalf("Expected the failed delete to be reported, got %v", err)
+	}
+	if got := store.pruned(); len(got) != 1 || got[0] != 3 {
+		t.Errorf("Expected only backup 3 to be pruned, got %v", got)
+	}
+
+	// The next pass retries it
+	pruner.objects = objects
+	if err := pruner.Prune(context.Background()); err != nil {
+		t.Fatalf("Prune() error = %v", err)
+	}
+	if got := store.pruned(); len(got) != 2 {
+		t.Errorf("Expected backups 2 and 3 to be pruned, got %v", got)
+	}
+	if exists(t, objects, "backups/my-app/fail") {
+		t.Error("Expected the retried artifact to be deleted")
+	}
+}
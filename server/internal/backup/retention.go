@@ -0,0 +1,64 @@
+package backup
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+)
+
+// Expired returns the backups a retention policy no longer keeps, newest first. Each
+// rule keeps the newest backup of each of its most recent periods that have a backup,
+// and a backup is kept when any rule keeps it. Held backups are never expired, and a
+// policy without rules expires nothing.
+func Expired(policy *apitypes.BackupRetentionPolicy, backups []*apitypes.Backup) []*apitypes.Backup {
+	if policy == nil || (policy.KeepDaily <= 0 && policy.KeepWeekly <= 0 && policy.KeepMonthly <= 0) {
+		return nil
+	}
+
+	sorted := slices.Clone(backups)
+	slices.SortFunc(sorted, func(a, b *apitypes.Backup) int {
+		if c := b.CreatedAt.Compare(a.CreatedAt); c != 0 {
+			return c
+		}
+		return int(b.ID - a.ID)
+	})
+
+	kept := map[int64]bool{}
+	keepNewest(sorted, policy.KeepDaily, kept, func(t time.Time) string {
+		return t.Format("2006-01-02")
+	})
+	keepNewest(sorted, policy.KeepWeekly, kept, func(t time.Time) string {
+		year, week := t.ISOWeek()
+		return fmt.Sprintf("%d-W%02d", year, week)
+	})
+	keepNewest(sorted, policy.KeepMonthly, kept, func(t time.Time) string {
+		return t.Format("2006-01")
+	})
+
+	var expired []*apitypes.Backup
+	for _, backup := range sorted {
+		if !kept[backup.ID] && !backup.Held {
+			expired = append(expired, backup)
+		}
+	}
+	return expired
+}
+
+// keepNewest marks the newest backup of each of the last count periods, given newest
+// first backups and the UTC period a backup falls in
+func keepNewest(backups []*apitypes.Backup, count int, kept map[int64]bool, period func(time.Time) string) {
+	seen := map[string]bool{}
+	for _, backup := range backups {
+		if len(seen) >= count {
+			return
+		}
+		p := period(backup.CreatedAt.UTC())
+		if seen[p] {
+			continue
+		}
+		seen[p] = true
+		kept[backup.ID] = true
+	}
+}
@@ -0,0 +1,74 @@
+package backup
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+)
+
+func TestExpired(t *testing.T) {
+	at := func(month time.Month, day, hour int) time.Time {
+		return time.Date(2026, month, day, hour, 0, 0, 0, time.UTC)
+	}
+	// 17 and 12 October share an ISO week, 5 October is the week before
+	backups := func() []*apitypes.Backup {
+		return []*apitypes.Backup{
+			{ID: 5, CreatedAt: at(time.October, 5, 9)},
+			{ID: 1, CreatedAt: at(time.October, 17, 12)},
+			{ID: 7, CreatedAt: at(time.August, 1, 9)},
+			{ID: 3, CreatedAt: at(time.October, 16, 9)},
+			{ID: 2, CreatedAt: at(time.October, 17, 6)},
+			{ID: 6, CreatedAt: at(time.September, 20, 9)},
+			{ID: 4, CreatedAt: at(time.October, 12, 9)},
+		}
+	}
+
+	tests := []struct {
+		name   string
+		policy *apitypes.BackupRetentionPolicy
+		held   int64
+		want   []int64
+	}{
+		{name: "no policy", policy: nil},
+		{name: "no rules", policy: &apitypes.BackupRetentionPolicy{}},
+		{name: "daily", policy: &apitypes.BackupRetentionPolicy{KeepDaily: 2}, want: []int64{2, 4, 5, 6, 7}},
+		{name: "weekly", policy: &apitypes.BackupRetentionPolicy{KeepWeekly: 2}, want: []int64{2, 3, 4, 6, 7}},
+		{name: "monthly", policy: &apitypes.BackupRetentionPolicy{KeepMonthly: 3}, want: []int64{2, 3, 4, 5}},
+		{name: "rules combine", policy: &apitypes.BackupRetentionPolicy{KeepDaily: 1, KeepMonthly: 2}, want: []int64{2, 3, 4, 5, 7}},
+		{name: "more periods than backups", policy: &apitypes.BackupRetentionPolicy{KeepDaily: 30}, want: []int64{2}},
+		{name: "held backups are kept", policy: &apitypes.BackupRetentionPolicy{KeepDaily: 1}, held: 5, want: []int64{2, 3, 4, 6, 7}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			all := backups()
+			for _, backup := range all {
+				backup.Held = backup.ID == tt.held
+			}
+
+			var got []int64
+			for _, backup := range Expired(tt.policy, all) {
+				got = append(got, backup.ID)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Expired() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestExpired_Timezone tests that periods are UTC days regardless of the timestamp's zone
+func TestExpired_Timezone(t *testing.T) {
+	berlin := time.FixedZone("CEST", 2*60*60)
+	backups := []*apitypes.Backup{
+		// The same UTC day, 16 October, although the first is the 17th in Berlin
+		{ID: 1, CreatedAt: time.Date(2026, time.October, 17, 1, 0, 0, 0, berlin)},
+		{ID: 2, CreatedAt: time.Date(2026, time.October, 16, 12, 0, 0, 0, time.UTC)},
+	}
+
+	expired := Expired(&apitypes.BackupRetentionPolicy{KeepDaily: 2}, backups)
+	if len(expired) != 1 || expired[0].ID != 2 {
+		t.Errorf("Expected backup 2 to expire, got %+v", expired)
+	}
+}
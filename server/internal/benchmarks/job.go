@@ -0,0 +1,175 @@
+// Package benchmarks load-tests instance databases with pgbench.
+//
+// A benchmark is stored in the database by the API, which also starts its Job in the
+// instance namespace. A Runner on the elected leader waits for the Job to finish, reads
+// the throughput and latency from its log and records them on the benchmark.
+package benchmarks
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+)
+
+const (
+	// Image runs the benchmark; it ships psql and pgbench
+	Image = "postgres:15-alpine"
+
+	// Database is the scratch database the benchmark creates its test data in and drops
+	// again, so the instance's own data is left alone
+	Database = "supacontrol_benchmark"
+
+	// jobSetupSeconds is added to a benchmark's duration for loading its test data
+	// before the Job times out
+	jobSetupSeconds = 600
+)
+
+// Limits and defaults of benchmark parameters
+const (
+	DefaultClients         = 10
+	MaxClients             = 100
+	DefaultDurationSeconds = 60
+	MinDurationSeconds     = 10
+	MaxDurationSeconds     = 600
+	DefaultScale           = 10
+	MaxScale               = 100
+)
+
+// script loads the pgbench test data into a scratch database, runs the load and drops the
+// database again
+const script = `
+set -eu
+cleanup() {
+  psql -v ON_ERROR_STOP=1 -d postgres -c "DROP DATABASE IF EXISTS $BENCHMARK_DATABASE" >/dev/null
+}
+trap cleanup EXIT
+
+echo "Preparing test data (scale $SCALE)"
+cleanup
+psql -v ON_ERROR_STOP=1 -d postgres -c "CREATE DATABASE $BENCHMARK_DATABASE" >/dev/null
+pgbench -i -q -s "$SCALE" "$BENCHMARK_DATABASE"
+
+echo "Running $CLIENTS clients for $DURATION seconds"
+pgbench -n -c "$CLIENTS" -j "$CLIENTS" -T "$DURATION" "$BENCHMARK_DATABASE"
+`
+
+// Params are the parameters of a benchmark Job
+type Params struct {
+	// Name and Namespace of the Job
+	Name      string
+	Namespace string
+
+	// Instance is the project name of the benchmarked instance
+	Instance string
+
+	// Host of the instance database, and the Secret and key holding its postgres password
+	Host              string
+	PasswordSecret    string
+	PasswordSecretKey string
+
+	Clients         int
+	DurationSeconds int
+	Scale           int
+}
+
+// NewJob builds the Job that runs a benchmark. It does not retry, as a second attempt
+// would measure a database still busy with the first.
+func NewJob(p Params) *batchv1.Job {
+	labels := map[string]string{
+		"app.kubernetes.io/name":      "supacontrol",
+		"app.kubernetes.io/component": "benchmark",
+		"supacontrol.io/instance":     p.Instance,
+	}
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      p.Name,
+			Namespace: p.Namespace,
+			Labels:    labels,
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit:            ptr.To(int32(0)),
+			ActiveDeadlineSeconds:   ptr.To(int64(p.DurationSeconds + jobSetupSeconds)),
+			TTLSecondsAfterFinished: ptr.To(int32(3600)), // Clean up after 1 hour
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+				Spec: corev1.PodSpec{
+					RestartPolicy: corev1.RestartPolicyNever,
+					Containers: []corev1.Container{{
+						Name:    "pgbench",
+						Image:   Image,
+						Command: []string{"/bin/sh", "-c"},
+						Args:    []string{script},
+						Env: []corev1.EnvVar{
+							{Name: "PGHOST", Value: p.Host},
+							{Name: "PGPORT", Value: "5432"},
+							{Name: "PGUSER", Value: "postgres"},
+							{Name: "PGPASSWORD", ValueFrom: &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{
+								LocalObjectReference: corev1.LocalObjectReference{Name: p.PasswordSecret},
+								Key:                  p.PasswordSecretKey,
+							}}},
+							{Name: "BENCHMARK_DATABASE", Value: Database},
+							{Name: "CLIENTS", Value: strconv.Itoa(p.Clients)},
+							{Name: "DURATION", Value: strconv.Itoa(p.DurationSeconds)},
+							{Name: "SCALE", Value: strconv.Itoa(p.Scale)},
+						},
+						Resources: corev1.ResourceRequirements{
+							Requests: corev1.ResourceList{
+								corev1.ResourceCPU:    resource.MustParse("100m"),
+								corev1.ResourceMemory: resource.MustParse("64Mi"),
+							},
+							Limits: corev1.ResourceList{
+								corev1.ResourceCPU:    resource.MustParse("1"),
+								corev1.ResourceMemory: resource.MustParse("256Mi"),
+							},
+						},
+					}},
+				},
+			},
+		},
+	}
+}
+
+// Result is the outcome of a pgbench run
+type Result struct {
+	Transactions          int64
+	TransactionsPerSecond float64
+	LatencyAverageMS      float64
+}
+
+var (
+	transactionsPattern = regexp.MustCompile(`(?m)^number of transactions actually processed: (\d+)`)
+	latencyPattern      = regexp.MustCompile(`(?m)^latency average = ([\d.]+) ms`)
+	// pgbench 14 and later report "(without initial connection time)", older versions
+	// "(excluding connections establishing)"; both come last
+	tpsPattern = regexp.MustCompile(`(?m)^tps = ([\d.]+) \((?:without initial connection time|excluding connections establishing)\)`)
+)
+
+// ParseOutput reads the results from the log of a benchmark Job
+func ParseOutput(output string) (*Result, error) {
+	tps := tpsPattern.FindStringSubmatch(output)
+	latency := latencyPattern.FindStringSubmatch(output)
+	transactions := transactionsPattern.FindStringSubmatch(output)
+	if tps == nil || latency == nil || transactions == nil {
+		return nil, errors.New("benchmark output has no pgbench summary")
+	}
+
+	result := &Result{}
+	var err error
+	if result.TransactionsPerSecond, err = strconv.ParseFloat(tps[1], 64); err != nil {
+		return nil, fmt.Errorf("invalid tps %q: %w", tps[1], err)
+	}
+	if result.LatencyAverageMS, err = strconv.ParseFloat(latency[1], 64); err != nil {
+		return nil, fmt.Errorf("invalid latency %q: %w", latency[1], err)
+	}
+	if result.Transactions, err = strconv.ParseInt(transactions[1], 10, 64); err != nil {
+		return nil, fmt.Errorf("invalid transaction count %q: %w", transactions[1], err)
+	}
+	return result, nil
+}
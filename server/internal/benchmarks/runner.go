@@ -0,0 +1,186 @@
+package benchmarks
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/utils/ptr"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+)
+
+const (
+	// DefaultScanInterval is how often the runner checks the Jobs of running benchmarks
+	DefaultScanInterval = 10 * time.Second
+
+	// maxLogBytes bounds how much of a benchmark Job's log is read
+	maxLogBytes = 64 << 10
+
+	// maxErrorLength bounds the log excerpt kept as a failed benchmark's error
+	maxErrorLength = 1024
+)
+
+// Store persists benchmarks and their results
+type Store interface {
+	ListRunningBenchmarks() ([]*apitypes.Benchmark, error)
+	FinishBenchmark(benchmark *apitypes.Benchmark) error
+}
+
+// Runner records the results of benchmark Jobs once they finish. It implements the
+// controller-runtime Runnable interface and only runs on the elected leader.
+type Runner struct {
+	store     Store
+	clientset kubernetes.Interface
+
+	ScanInterval time.Duration
+}
+
+// NewRunner creates a runner with the default scan interval
+func NewRunner(store Store, clientset kubernetes.Interface) *Runner {
+	return &Runner{
+		store:        store,
+		clientset:    clientset,
+		ScanInterval: DefaultScanInterval,
+	}
+}
+
+// NeedLeaderElection ensures only one replica records benchmark results
+func (r *Runner) NeedLeaderElection() bool {
+	return true
+}
+
+// Start checks running benchmarks, including ones left behind by a previous leader,
+// until ctx is cancelled
+func (r *Runner) Start(ctx context.Context) error {
+	ticker := time.NewTicker(r.ScanInterval)
+	defer ticker.Stop()
+
+	for {
+		r.scan(ctx)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// scan checks the Job of every running benchmark
+func (r *Runner) scan(ctx context.Context) {
+	benchmarks, err := r.store.ListRunningBenchmarks()
+	if err != nil {
+		slog.Error("Failed to list running benchmarks", "error", err)
+		return
+	}
+
+	for _, benchmark := range benchmarks {
+		if err := r.Check(ctx, benchmark); err != nil {
+			slog.Error("Failed to check benchmark", "benchmark_id", benchmark.ID, "error", err)
+		}
+	}
+}
+
+// Check records the outcome of a benchmark whose Job has finished, or leaves it running
+func (r *Runner) Check(ctx context.Context, benchmark *apitypes.Benchmark) error {
+	job, err := r.clientset.BatchV1().Jobs(benchmark.Namespace).Get(ctx, benchmark.JobName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return r.fail(benchmark, "benchmark Job no longer exists")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get benchmark Job: %w", err)
+	}
+
+	var finished, succeeded bool
+	for _, condition := range job.Status.Conditions {
+		if condition.Status != corev1.ConditionTrue {
+			continue
+		}
+		switch condition.Type {
+		case batchv1.JobComplete:
+			finished, succeeded = true, true
+		case batchv1.JobFailed:
+			finished = true
+		}
+	}
+	if !finished {
+		return nil
+	}
+
+	output, err := r.jobLog(ctx, job)
+	if err != nil {
+		return err
+	}
+	if !succeeded {
+		return r.fail(benchmark, "benchmark Job failed: "+lastLines(output))
+	}
+
+	result, err := ParseOutput(output)
+	if err != nil {
+		return r.fail(benchmark, err.Error())
+	}
+	benchmark.Status = apitypes.BenchmarkStatusSucceeded
+	benchmark.Transactions = &result.Transactions
+	benchmark.TransactionsPerSecond = &result.TransactionsPerSecond
+	benchmark.LatencyAverageMS = &result.LatencyAverageMS
+	slog.Info("Benchmark finished", "benchmark_id", benchmark.ID, "instance", benchmark.InstanceName,
+		"tps", result.TransactionsPerSecond, "latency_ms", result.LatencyAverageMS)
+	return r.store.FinishBenchmark(benchmark)
+}
+
+// fail records a benchmark as failed
+func (r *Runner) fail(benchmark *apitypes.Benchmark, message string) error {
+	benchmark.Status = apitypes.BenchmarkStatusFailed
+	benchmark.ErrorMessage = &message
+	slog.Warn("Benchmark failed", "benchmark_id", benchmark.ID, "instance", benchmark.InstanceName, "error", message)
+	return r.store.FinishBenchmark(benchmark)
+}
+
+// jobLog returns the log of a benchmark Job's pod. A Job without a pod, e.g. one that
+// hit its deadline before being scheduled, has an empty log.
+func (r *Runner) jobLog(ctx context.Context, job *batchv1.Job) (string, error) {
+	pods, err := r.clientset.CoreV1().Pods(job.Namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: "job-name=" + job.Name,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to list benchmark pods: %w", err)
+	}
+	if len(pods.Items) == 0 {
+		return "", nil
+	}
+
+	stream, err := r.clientset.CoreV1().Pods(job.Namespace).GetLogs(pods.Items[0].Name, &corev1.PodLogOptions{
+		LimitBytes: ptr.To(int64(maxLogBytes)),
+	}).Stream(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to read benchmark log: %w", err)
+	}
+	defer func() { _ = stream.Close() }()
+
+	output, err := io.ReadAll(stream)
+	if err != nil {
+		return "", fmt.Errorf("failed to read benchmark log: %w", err)
+	}
+	return string(output), nil
+}
+
+// lastLines returns the end of a log, where the error of a failed run is reported
+func lastLines(output string) string {
+	output = strings.TrimSpace(output)
+	if output == "" {
+		return "no output"
+	}
+	if len(output) > maxErrorLength {
+		output = output[len(output)-maxErrorLength:]
+	}
+	return output
+}
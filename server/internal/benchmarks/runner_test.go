@@ -0,0 +1,116 @@
+package benchmarks
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+)
+
+// fakeStore records finished benchmarks
+type fakeStore struct {
+	finished []*apitypes.Benchmark
+}
+
+func (s *fakeStore) ListRunningBenchmarks() ([]*apitypes.Benchmark, error) {
+	return nil, nil
+}
+
+func (s *fakeStore) FinishBenchmark(benchmark *apitypes.Benchmark) error {
+	s.finished = append(s.finished, benchmark)
+	return nil
+}
+
+const pgbenchOutput = `Preparing test data (scale 10)
+Running 10 clients for 60 seconds
+pgbench (15.5)
+transaction type: <builtin: TPC-B (sort of)>
+scaling factor: 10
+query mode: simple
+number of clients: 10
+number of threads: 10
+maximum number of tries: 1
+duration: 60 s
+number of transactions actually processed: 74070
+number of failed transactions: 0 (0.000%)
+latency average = 8.101 ms
+initial connection time = 21.384 ms
+tps = 1234.421337 (without initial connection time)
+`
+
+// TestParseOutput tests reading results from pgbench output
+func TestParseOutput(t *testing.T) {
+	result, err := ParseOutput(pgbenchOutput)
+	require.NoError(t, err)
+	assert.Equal(t, int64(74070), result.Transactions)
+	assert.InDelta(t, 1234.421337, result.TransactionsPerSecond, 0.0001)
+	assert.InDelta(t, 8.101, result.LatencyAverageMS, 0.0001)
+
+	// pgbench before version 14
+	legacy := "number of transactions actually processed: 100\nlatency average = 5.0 ms\n" +
+		"tps = 20.5 (including connections establishing)\ntps = 21.0 (excluding connections establishing)\n"
+	result, err = ParseOutput(legacy)
+	require.NoError(t, err)
+	assert.InDelta(t, 21.0, result.TransactionsPerSecond, 0.0001)
+
+	_, err = ParseOutput("psql: error: connection refused")
+	assert.Error(t, err)
+}
+
+// TestRunnerCheck tests how the runner records benchmarks by the state of their Job
+func TestRunnerCheck(t *testing.T) {
+	newJob := func(condition batchv1.JobConditionType) *batchv1.Job {
+		job := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "supacontrol-benchmark-1", Namespace: "supa-alpha"}}
+		if condition != "" {
+			job.Status.Conditions = []batchv1.JobCondition{{Type: condition, Status: corev1.ConditionTrue}}
+		}
+		return job
+	}
+
+	tests := []struct {
+		name           string
+		job            *batchv1.Job
+		expectFinished bool
+	}{
+		{name: "still running", job: newJob(""), expectFinished: false},
+		{name: "job failed", job: newJob(batchv1.JobFailed), expectFinished: true},
+		{name: "job missing", expectFinished: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clientset := fake.NewSimpleClientset()
+			if tt.job != nil {
+				clientset = fake.NewSimpleClientset(tt.job)
+			}
+			store := &fakeStore{}
+			runner := NewRunner(store, clientset)
+
+			benchmark := &apitypes.Benchmark{ID: 1, InstanceName: "alpha", Namespace: "supa-alpha", JobName: "supacontrol-benchmark-1", Status: apitypes.BenchmarkStatusRunning}
+			require.NoError(t, runner.Check(context.Background(), benchmark))
+
+			if !tt.expectFinished {
+				assert.Empty(t, store.finished)
+				return
+			}
+			require.Len(t, store.finished, 1)
+			assert.Equal(t, apitypes.BenchmarkStatusFailed, benchmark.Status)
+			assert.NotNil(t, benchmark.ErrorMessage)
+		})
+	}
+}
+
+// TestNewJob tests that a benchmark Job does not retry and times out after its load
+func TestNewJob(t *testing.T) {
+	job := NewJob(Params{Name: "supacontrol-benchmark-1", Namespace: "supa-alpha", Instance: "alpha", DurationSeconds: 60, Clients: 4, Scale: 2})
+	assert.Equal(t, int32(0), *job.Spec.BackoffLimit)
+	assert.Equal(t, int64(60+jobSetupSeconds), *job.Spec.ActiveDeadlineSeconds)
+	assert.Equal(t, corev1.RestartPolicyNever, job.Spec.Template.Spec.RestartPolicy)
+}
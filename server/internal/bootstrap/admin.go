@@ -0,0 +1,124 @@
+// Package bootstrap creates the first admin account of a new installation.
+//
+// No account is seeded with a well-known password. Instead, when the server starts and
+// the users table is empty, it creates an admin with a one-time random password and
+// hands the password to the operator, either in a Kubernetes Secret or in the server
+// log. The account must change its password on first login before it can do anything
+// else.
+package bootstrap
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"log/slog"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/qubitquilt/supacontrol/server/internal/db"
+)
+
+// DefaultUsername is the username of the first admin account
+const DefaultUsername = "admin"
+
+// Store creates the first admin account unless users already exist
+type Store interface {
+	CreateBootstrapAdmin(username, passwordHash string) (*db.User, error)
+}
+
+// Hasher hashes passwords for storage
+type Hasher interface {
+	HashPassword(password string) (string, error)
+}
+
+// Options configures how the first admin account is created
+type Options struct {
+	// Username of the account (defaults to DefaultUsername)
+	Username string
+
+	// Secret receiving the one-time password under the keys username and password.
+	// When SecretName is empty, the password is printed to the server log instead.
+	Clientset       kubernetes.Interface
+	SecretNamespace string
+	SecretName      string
+}
+
+// Admin creates the first admin account with a one-time password when no user exists
+// yet. It reports whether the account was created.
+func Admin(ctx context.Context, store Store, hasher Hasher, opts Options) (bool, error) {
+	username := opts.Username
+	if username == "" {
+		username = DefaultUsername
+	}
+
+	password, err := generatePassword()
+	if err != nil {
+		return false, err
+	}
+	hash, err := hasher.HashPassword(password)
+	if err != nil {
+		return false, fmt.Errorf("failed to hash password: %w", err)
+	}
+	user, err := store.CreateBootstrapAdmin(username, hash)
+	if err != nil {
+		return false, err
+	}
+	if user == nil {
+		return false, nil
+	}
+
+	if opts.SecretName != "" && opts.Clientset != nil {
+		err := writeSecret(ctx, opts.Clientset, opts.SecretNamespace, opts.SecretName, username, password)
+		if err == nil {
+			slog.Info("Created the first admin account; its one-time password is in a Secret",
+				"username", username, "secret", opts.SecretNamespace+"/"+opts.SecretName)
+			return true, nil
+		}
+		// The account exists now, so losing its password would lock everyone out
+		slog.Error("Failed to write the first admin password to its Secret, printing it instead",
+			"secret", opts.SecretNamespace+"/"+opts.SecretName, "error", err)
+	}
+	slog.Warn("Created the first admin account with a one-time password, which must be changed on first login",
+		"username", username, "password", password)
+	return true, nil
+}
+
+// generatePassword returns a random password of 32 URL-safe characters
+func generatePassword() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate password: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// writeSecret stores the credentials of the first admin account in a Secret, replacing
+// those of an earlier installation whose database was reset
+func writeSecret(ctx context.Context, clientset kubernetes.Interface, namespace, name, username, password string) error {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			Labels:    map[string]string{"app.kubernetes.io/managed-by": "supacontrol"},
+		},
+		Type: corev1.SecretTypeBasicAuth,
+		StringData: map[string]string{
+			corev1.BasicAuthUsernameKey: username,
+			corev1.BasicAuthPasswordKey: password,
+		},
+	}
+
+	secrets := clientset.CoreV1().Secrets(namespace)
+	_, err := secrets.Create(ctx, secret, metav1.CreateOptions{})
+	if apierrors.IsAlreadyExists(err) {
+		_, err = secrets.Update(ctx, secret, metav1.UpdateOptions{})
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write secret %s/%s: %w", namespace, name, err)
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package bootstrap
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/qubitquilt/supacontrol/server/internal/db"
+)
+
+// fakeStore records the bootstrapped account and creates it only while it has no users
+type fakeStore struct {
+	hasUsers bool
+	err      error
+	username string
+	hash     string
+}
+
+func (s *fakeStore) CreateBootstrapAdmin(username, passwordHash string) (*db.User, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	if s.hasUsers {
+		return nil, nil
+	}
+	s.hasUsers, s.username, s.hash = true, username, passwordHash
+	return &db.User{ID: 1, Username: username, PasswordHash: passwordHash, Role: "admin", MustChangePassword: true}, nil
+}
+
+// plainHasher "hashes" a password by prefixing it, so tests can recover it
+type plainHasher struct{}
+
+func (plainHasher) HashPassword(password string) (string, error) {
+	return "hash:" + password, nil
+}
+
+func TestAdmin_WritesSecret(t *testing.T) {
+	store := &fakeStore{}
+	clientset := fake.NewSimpleClientset(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "initial-admin", Namespace: "supacontrol"},
+		StringData: map[string]string{corev1.BasicAuthPasswordKey: "stale"},
+	})
+
+	created, err := Admin(context.Background(), store, plainHasher{}, Options{
+		Clientset:       clientset,
+		SecretNamespace: "supacontrol",
+		SecretName:      "initial-admin",
+	})
+	if err != nil {
+		t.Fatalf("Admin() error = %v", err)
+	}
+	if !created || store.username != DefaultUsername {
+		t.Fatalf("expected %s to be created, got created=%v username=%q", DefaultUsername, created, store.username)
+	}
+
+	secret, err := clientset.CoreV1().Secrets("supacontrol").Get(context.Background(), "initial-admin", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("failed to get secret: %v", err)
+	}
+	password := secret.StringData[corev1.BasicAuthPasswordKey]
+	if len(password) != 32 || store.hash != "hash:"+password {
+		t.Errorf("secret password %q does not match the stored hash %q", password, store.hash)
+	}
+	if secret.StringData[corev1.BasicAuthUsernameKey] != DefaultUsername {
+		t.Errorf("unexpected secret username %q", secret.StringData[corev1.BasicAuthUsernameKey])
+	}
+}
+
+func TestAdmin_UsersExist(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	created, err := Admin(context.Background(), &fakeStore{hasUsers: true}, plainHasher{}, Options{
+		Clientset:       clientset,
+		SecretNamespace: "supacontrol",
+		SecretName:      "initial-admin",
+	})
+	if err != nil {
+		t.Fatalf("Admin() error = %v", err)
+	}
+	if created {
+		t.Error("expected no account to be created once users exist")
+	}
+	secrets, _ := clientset.CoreV1().Secrets("supacontrol").List(context.Background(), metav1.ListOptions{})
+	if len(secrets.Items) != 0 {
+		t.Errorf("expected no secret, got %d", len(secrets.Items))
+	}
+}
+
+func TestAdmin_StoreError(t *testing.T) {
+	_, err := Admin(context.Background(), &fakeStore{err: errors.New("connection refused")}, plainHasher{}, Options{Username: "root"})
+	if err == nil {
+		t.Error("expected the store error to be returned")
+	}
+}
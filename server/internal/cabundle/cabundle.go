@@ -0,0 +1,72 @@
+// Package cabundle makes a custom CA bundle trusted for all outbound TLS
+// connections of the process, for environments with TLS-intercepting proxies
+// or private chart repositories signed by an internal CA.
+//
+// Go loads the system trust store once, on first use, honoring SSL_CERT_FILE.
+// Install writes the system bundle plus the custom CAs to a single file and
+// points SSL_CERT_FILE at it, so every client that relies on system roots —
+// the advisory feed, Helm repository fetches, SMTP tests — trusts the custom
+// CAs without per-client configuration. It must run before the first TLS
+// connection is made.
+package cabundle
+
+import (
+	"crypto/x509"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// CombinedFileName is the name of the merged bundle written to the temp directory
+const CombinedFileName = "supacontrol-ca-bundle.pem"
+
+// systemBundles are the well-known system CA bundle locations, in the order Go checks them
+var systemBundles = []string{
+	"/etc/ssl/certs/ca-certificates.crt",                // Debian/Ubuntu/Gentoo/Alpine
+	"/etc/pki/tls/certs/ca-bundle.crt",                  // Fedora/RHEL 6
+	"/etc/ssl/ca-bundle.pem",                            // OpenSUSE
+	"/etc/pki/tls/cacert.pem",                           // OpenELEC
+	"/etc/pki/ca-trust/extracted/pem/tls-ca-bundle.pem", // CentOS/RHEL 7
+	"/etc/ssl/cert.pem",                                 // Alpine Linux
+}
+
+// Install trusts the CAs in the PEM file at path in addition to the system
+// CAs and returns the path of the merged bundle
+func Install(path string) (string, error) {
+	return install(path, systemBundles, os.TempDir())
+}
+
+// install merges the bundle at path with the first readable candidate and writes the result to dir
+func install(path string, candidates []string, dir string) (string, error) {
+	custom, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read CA bundle: %w", err)
+	}
+	if !x509.NewCertPool().AppendCertsFromPEM(custom) {
+		return "", fmt.Errorf("CA bundle %s contains no PEM certificates", path)
+	}
+
+	// An explicit SSL_CERT_FILE replaces the system bundle, so it is the base to extend
+	if current := os.Getenv("SSL_CERT_FILE"); current != "" {
+		candidates = []string{current}
+	}
+
+	var combined []byte
+	for _, candidate := range candidates {
+		system, err := os.ReadFile(candidate)
+		if err == nil {
+			combined = append(system, '\n')
+			break
+		}
+	}
+	combined = append(combined, custom...)
+
+	out := filepath.Join(dir, CombinedFileName)
+	if err := os.WriteFile(out, combined, 0o644); err != nil {
+		return "", fmt.Errorf("failed to write combined CA bundle: %w", err)
+	}
+	if err := os.Setenv("SSL_CERT_FILE", out); err != nil {
+		return "", fmt.Errorf("failed to set SSL_CERT_FILE: %w", err)
+	}
+	return out, nil
+}
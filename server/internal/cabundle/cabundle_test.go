@@ -0,0 +1,109 @@
+package cabundle
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeTestCA writes a self-signed CA certificate to dir and returns its path and PEM
+func writeTestCA(t *testing.T, dir, name string) (string, []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	data := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	path := filepath.Join(dir, name+".pem")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatalf("failed to write certificate: %v", err)
+	}
+	return path, data
+}
+
+// TestInstall tests that the custom CAs are appended to the system bundle
+func TestInstall(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("SSL_CERT_FILE", "")
+	systemPath, systemPEM := writeTestCA(t, dir, "system")
+	customPath, customPEM := writeTestCA(t, dir, "custom")
+
+	out, err := install(customPath, []string{filepath.Join(dir, "missing.pem"), systemPath}, dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := os.Getenv("SSL_CERT_FILE"); got != out {
+		t.Errorf("expected SSL_CERT_FILE=%s, got %s", out, got)
+	}
+	combined, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("failed to read combined bundle: %v", err)
+	}
+	if !strings.Contains(string(combined), string(systemPEM)) || !strings.Contains(string(combined), string(customPEM)) {
+		t.Error("expected combined bundle to contain system and custom CAs")
+	}
+}
+
+// TestInstall_ExtendsExplicitCertFile tests that an existing SSL_CERT_FILE is kept as the base
+func TestInstall_ExtendsExplicitCertFile(t *testing.T) {
+	dir := t.TempDir()
+	explicitPath, explicitPEM := writeTestCA(t, dir, "explicit")
+	systemPath, systemPEM := writeTestCA(t, dir, "system")
+	customPath, _ := writeTestCA(t, dir, "custom")
+	t.Setenv("SSL_CERT_FILE", explicitPath)
+
+	out, err := install(customPath, []string{systemPath}, dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	combined, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("failed to read combined bundle: %v", err)
+	}
+	if !strings.Contains(string(combined), string(explicitPEM)) {
+		t.Error("expected combined bundle to extend SSL_CERT_FILE")
+	}
+	if strings.Contains(string(combined), string(systemPEM)) {
+		t.Error("expected system bundle to be ignored when SSL_CERT_FILE is set")
+	}
+}
+
+// TestInstall_RejectsInvalidBundle tests that files without certificates are rejected
+func TestInstall_RejectsInvalidBundle(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("SSL_CERT_FILE", "")
+	path := filepath.Join(dir, "bundle.pem")
+	if err := os.WriteFile(path, []byte("not a certificate"), 0o644); err != nil {
+		t.Fatalf("failed to write bundle: %v", err)
+	}
+
+	if _, err := install(path, nil, dir); err == nil {
+		t.Error("expected error for bundle without certificates")
+	}
+	if _, err := install(filepath.Join(dir, "missing.pem"), nil, dir); err == nil {
+		t.Error("expected error for missing bundle")
+	}
+}
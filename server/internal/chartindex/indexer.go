@@ -0,0 +1,111 @@
+// Package chartindex keeps a catalog of the Supabase chart versions published in the
+// configured chart repository.
+//
+// An Indexer periodically downloads the repository index, records the versions in the
+// database and remembers the newest one, so the API can flag instances running an
+// older chart. The latest version is held in memory, so every API replica runs its
+// own indexer; when the repository cannot be reached the last stored catalog is used.
+package chartindex
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+)
+
+// DefaultInterval is how often the chart repository is indexed
+const DefaultInterval = time.Hour
+
+// Source lists the chart versions published in the repository, newest first
+type Source interface {
+	ChartVersions(ctx context.Context) ([]string, error)
+}
+
+// Store persists the chart version catalog
+type Store interface {
+	SyncChartVersions(versions []string) error
+	ListChartVersions() ([]*apitypes.ChartVersion, error)
+}
+
+// Indexer records published chart versions and tracks the latest one. It implements
+// the controller-runtime Runnable interface.
+type Indexer struct {
+	source Source
+	store  Store
+
+	Interval time.Duration
+
+	mu     sync.RWMutex
+	latest string
+}
+
+// NewIndexer creates an indexer with the default interval
+func NewIndexer(source Source, store Store) *Indexer {
+	return &Indexer{
+		source:   source,
+		store:    store,
+		Interval: DefaultInterval,
+	}
+}
+
+// NeedLeaderElection lets every replica index, as the latest version is kept in memory
+func (i *Indexer) NeedLeaderElection() bool {
+	return false
+}
+
+// Start indexes the chart repository until ctx is cancelled
+func (i *Indexer) Start(ctx context.Context) error {
+	ticker := time.NewTicker(i.Interval)
+	defer ticker.Stop()
+
+	for {
+		i.Index(ctx)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Index refreshes the catalog from the chart repository. If the repository cannot be
+// read, the latest version is taken from the stored catalog instead.
+func (i *Indexer) Index(ctx context.Context) {
+	versions, err := i.source.ChartVersions(ctx)
+	if err == nil {
+		if err := i.store.SyncChartVersions(versions); err != nil {
+			slog.Error("Failed to store chart versions", "error", err)
+		}
+	} else {
+		slog.Warn("Failed to index chart repository, using stored chart versions", "error", err)
+
+		stored, err := i.store.ListChartVersions()
+		if err != nil {
+			slog.Error("Failed to list stored chart versions", "error", err)
+			return
+		}
+		versions = make([]string, 0, len(stored))
+		for _, version := range stored {
+			versions = append(versions, version.Version)
+		}
+	}
+
+	if len(versions) == 0 {
+		return
+	}
+	i.mu.Lock()
+	i.latest = versions[0]
+	i.mu.Unlock()
+}
+
+// LatestChartVersion returns the newest indexed chart version, or "" before the first
+// successful indexing run
+func (i *Indexer) LatestChartVersion() string {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	return i.latest
+}
@@ -0,0 +1,74 @@
+package chartindex
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+)
+
+// fakeSource returns fixed versions or an error
+type fakeSource struct {
+	versions []string
+	err      error
+}
+
+func (s *fakeSource) ChartVersions(context.Context) ([]string, error) {
+	return s.versions, s.err
+}
+
+// memoryStore keeps the catalog in memory
+type memoryStore struct {
+	versions []string
+	syncs    int
+}
+
+func (s *memoryStore) SyncChartVersions(versions []string) error {
+	s.versions = versions
+	s.syncs++
+	return nil
+}
+
+func (s *memoryStore) ListChartVersions() ([]*apitypes.ChartVersion, error) {
+	versions := make([]*apitypes.ChartVersion, 0, len(s.versions))
+	for i, version := range s.versions {
+		versions = append(versions, &apitypes.ChartVersion{Version: version, Position: i, Latest: i == 0})
+	}
+	return versions, nil
+}
+
+// TestIndexer_Index tests that indexed versions are stored and the newest is tracked
+func TestIndexer_Index(t *testing.T) {
+	source := &fakeSource{versions: []string{"0.2.0", "0.1.0"}}
+	store := &memoryStore{}
+	indexer := NewIndexer(source, store)
+
+	if got := indexer.LatestChartVersion(); got != "" {
+		t.Errorf("Expected no latest version before indexing, got %q", got)
+	}
+
+	indexer.Index(context.Background())
+	if store.syncs != 1 || len(store.versions) != 2 {
+		t.Errorf("Expected the versions to be stored, got %v", store.versions)
+	}
+	if got := indexer.LatestChartVersion(); got != "0.2.0" {
+		t.Errorf("Expected latest version 0.2.0, got %q", got)
+	}
+}
+
+// TestIndexer_IndexFallsBackToStore tests that the stored catalog is used when the
+// repository cannot be read
+func TestIndexer_IndexFallsBackToStore(t *testing.T) {
+	source := &fakeSource{err: fmt.Errorf("connection refused")}
+	store := &memoryStore{versions: []string{"0.1.1", "0.1.0"}}
+	indexer := NewIndexer(source, store)
+
+	indexer.Index(context.Background())
+	if store.syncs != 0 {
+		t.Errorf("Expected the stored catalog to be left alone, got %d syncs", store.syncs)
+	}
+	if got := indexer.LatestChartVersion(); got != "0.1.1" {
+		t.Errorf("Expected latest version 0.1.1 from the store, got %q", got)
+	}
+}
@@ -5,15 +5,35 @@ package config
 import (
 	"bufio"
 	"fmt"
+	"net/url"
 	"os"
+	"slices"
+	"strconv"
 	"strings"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+	"github.com/qubitquilt/supacontrol/server/internal/backup"
+	"github.com/qubitquilt/supacontrol/server/internal/namespaces"
+	"github.com/qubitquilt/supacontrol/server/internal/objectstore"
+	"github.com/qubitquilt/supacontrol/server/internal/sso"
 )
 
+// userRoles are the roles users can hold
+var userRoles = []string{"admin", "operator", "user", "readonly", "viewer"}
+
 // Config holds all application configuration
 type Config struct {
 	// Server configuration
 	ServerPort string
 	ServerHost string
+	PublicURL  string // Externally reachable base URL, used in links such as team invitations
+
+	RequestTimeoutSeconds int // How long authenticated API requests may take
+
+	// Load shedding caps on authenticated API requests handled at once (0 means unlimited)
+	LoadSheddingMaxRequests          int // Requests other than log fetches and release previews
+	LoadSheddingMaxExpensiveRequests int // Log fetches, release previews, cron jobs, benchmarks, exports and imports
+	LoadSheddingQueueTimeoutMS       int // How long a request waits for a free slot before it is rejected
 
 	// Database configuration
 	DBHost     string
@@ -25,17 +45,165 @@ type Config struct {
 	// JWT configuration
 	JWTSecret string
 
+	// First admin account, created with a one-time password when no user exists
+	InitialAdminUsername string
+	InitialAdminSecret   string // "<namespace>/<name>" of the Secret receiving the password (empty logs it)
+
 	// Kubernetes configuration
-	KubeConfig            string // Path to kubeconfig (empty means in-cluster)
-	DefaultIngressClass   string
-	DefaultIngressDomain  string
-	CertManagerIssuer     string // cert-manager ClusterIssuer name for TLS
-	LeaderElectionEnabled bool   // Enable leader election for HA deployments
+	KubeConfig                     string // Path to kubeconfig (empty means in-cluster)
+	DefaultIngressClass            string
+	DefaultIngressDomain           string
+	IngressControllerNamespace     string // Namespace of the ingress controller admitted into network-isolated instances
+	CertManagerIssuer              string // cert-manager ClusterIssuer name for TLS
+	ExternalDNSMode                string // Publish instance hostnames through external-dns: dnsendpoint, annotation or empty
+	ExternalDNSReadyTimeoutSeconds int    // How long new instances wait for their DNS records before they are marked Running
+	LeaderElectionEnabled          bool   // Enable leader election for HA deployments
+	APICacheEnabled                bool   // Serve API reads of instances from the controller's watch cache
+	CacheSyncPeriodMinutes         int    // How often the watch cache is fully resynced
+	InstanceSyncIntervalSeconds    int    // How often instances are mirrored into the database
+	APIKeyUsageFlushSeconds        int    // How often batched API key last-used times are written
+
+	// Client-side rate limit of the Kubernetes client dedicated to log fetches and pod exec,
+	// so observability traffic cannot starve provisioning and other API calls
+	ObservabilityClientQPS   int
+	ObservabilityClientBurst int
+
+	// Instance namespaces
+	NamespaceTemplate    string            // Name of instance namespaces, with {name} replaced by the project name
+	NamespaceLabels      map[string]string // Labels set on every instance namespace, e.g. a cost center
+	NamespaceAnnotations map[string]string // Annotations set on every instance namespace
+
+	// Prometheus monitoring of instances
+	MonitoringNamespace string            // Namespace of the Prometheus admitted into monitored, network-isolated instances
+	MonitoringLabels    map[string]string // Labels of instance ServiceMonitors, so the platform's Prometheus selects them
+
+	// Conversion webhook serving SupabaseInstance API versions other than the stored v1alpha1
+	WebhookEnabled bool
+	WebhookPort    int    // Port of the webhook server (HTTPS)
+	WebhookCertDir string // Directory holding the serving certificate (tls.crt and tls.key)
 
 	// Supabase Helm chart configuration
 	SupabaseChartRepo    string
 	SupabaseChartName    string
 	SupabaseChartVersion string
+
+	// Private chart repository authentication: the server pulls charts with
+	// ChartRepoUsername and ChartRepoPassword, and Jobs read the same credentials from
+	// ChartRepoCredentialsSecret (keys username and password) in the controller namespace
+	ChartRepoUsername          string
+	ChartRepoPassword          string
+	ChartRepoCredentialsSecret string
+
+	// Image running provisioning, upgrade and cleanup Jobs (empty uses the built-in default)
+	ProvisionerImage              string
+	ProvisionerImageRequireDigest bool // Reject provisioner images not pinned by digest
+
+	// Postgres versions instances can select besides the chart's default, as major version
+	// to Supabase Postgres image tag, and the image running pg_upgrade during major
+	// upgrades, with {version} replaced by the target version (empty uses the default)
+	PostgresImageTags    map[string]string
+	PostgresUpgradeImage string
+
+	// Stronger per-instance isolation
+	KataRuntimeClass     string // RuntimeClass for kata-runtime isolation (empty disables it)
+	VClusterChartRepo    string // vcluster chart repository (empty disables vcluster isolation)
+	VClusterChartVersion string // vcluster chart version (empty means latest)
+
+	// Administrative suspension
+	SuspendedPageURL     string // Page suspended instances redirect to (defaults to PUBLIC_URL/suspended)
+	BillingWebhookSecret string // HMAC secret of the billing webhook (empty disables the webhook)
+
+	// Security advisory feed (http(s) URL or file path; empty disables advisory matching)
+	AdvisoryFeed string
+
+	// Prometheus server queried for instance usage metrics (empty uses metrics-server)
+	PrometheusURL string
+
+	// Log error analysis behind GET /instances/:name/errors
+	LogErrorAnalysisEnabled bool
+
+	// Hours deleted instances stay in the trash before they are purged (0 deletes immediately)
+	DeletionGracePeriodHours int
+
+	// Hours the previous value of a rotated API key keeps working by default (0 revokes it
+	// immediately)
+	APIKeyRotationOverlapHours int
+
+	// Days between rotations of the credentials of connections between instances
+	ConnectionRotationDays int
+
+	// Days instance secrets may go without rotation before the SecretsRotated condition
+	// turns false
+	SecretMaxAgeDays int
+
+	// Webhook notified of upcoming deletions of ephemeral instances (empty disables
+	// notifications), and the HMAC secret its requests are signed with
+	NotificationWebhookURL    string
+	NotificationWebhookSecret string
+
+	// Controller concurrency, rate limiting and requeue intervals
+	ControllerMaxConcurrentReconciles       int // Instances reconciled in parallel
+	ControllerMaxConcurrentProvisioningJobs int // Provisioning Jobs run at once; further instances are queued (0 is unlimited)
+	ControllerRateLimitBaseDelayMS          int // First retry delay after a failed reconciliation, doubled per failure
+	ControllerRateLimitMaxDelaySeconds      int // Cap of the per-instance retry delay
+	ControllerRateLimitQPS                  int // Reconciliations per second across all instances
+	ControllerRateLimitBurst                int // Reconciliations allowed in a burst above the QPS
+	ControllerJobPollIntervalSeconds        int // How often running provisioning and upgrade Jobs are checked
+	ControllerResyncIntervalSeconds         int // How often settled instances are reconciled again
+	ControllerFailedRequeueIntervalSeconds  int // How often failed instances are checked again
+	ControllerHealthSuccessThreshold        int // Health checks in a row that must pass before an instance is Ready again
+	ControllerHealthFailureThreshold        int // Health checks in a row that must fail before an instance is Degraded
+
+	// Custom CA bundle trusted for outbound TLS
+	CABundleFile      string // PEM file trusted by the server process (empty uses system CAs only)
+	CABundleConfigMap string // ConfigMap (key ca.crt) mounted into provisioning Jobs
+
+	// Outbound proxy, propagated to provisioning Jobs (empty means direct connections)
+	HTTPProxy  string
+	HTTPSProxy string
+	NoProxy    string
+
+	// Object storage for backups and bundles (empty provider disables it)
+	ObjectStorageProvider  string // s3, gcs, azure, minio or local
+	ObjectStorageBucket    string
+	ObjectStoragePrefix    string
+	ObjectStorageEndpoint  string
+	ObjectStorageRegion    string
+	ObjectStorageAccessKey string
+	ObjectStorageSecretKey string
+	ObjectStoragePath      string // Root directory of the local provider
+
+	// Backups of instance databases, stored in object storage and always encrypted: they
+	// are enabled by configuring keys. BackupEncryptionKeys holds base64-encoded 256-bit
+	// keys by ID, BackupEncryptionKeyID names the key new backups use.
+	BackupEncryptionKeys  map[string]string
+	BackupEncryptionKeyID string
+
+	// SAML single sign-on (SupaControl acts as the service provider)
+	SAMLEnabled           bool
+	SAMLIDPMetadata       string // IdP metadata (http(s) URL or file path)
+	SAMLEntityID          string // Service provider entity ID (defaults to the metadata URL)
+	SAMLCertFile          string // PEM certificate signing requests and decrypting assertions (optional)
+	SAMLKeyFile           string // PEM key of SAMLCertFile
+	SAMLUsernameAttribute string // Attribute holding the username (empty uses the NameID)
+	SAMLRoleAttribute     string // Attribute roles are mapped from (empty leaves roles to SupaControl)
+	SAMLRoleMappings      []sso.RoleMapping
+	SAMLDefaultRole       string // Role of users no mapping matches
+	SAMLAllowIDPInitiated bool   // Accept sign-ins started at the IdP
+
+	// Default quotas, overridable per user and globally through the API (0 means unlimited)
+	QuotaMaxInstancesPerUser int
+	QuotaMaxStorageGBPerUser int
+	QuotaMaxTotalInstances   int
+	QuotaMaxTotalStorageGB   int
+
+	// Developer sandbox: users holding SandboxRole or in the team named SandboxTeam (empty
+	// selects nobody) may only create a few small instances, deleted after SandboxTTLHours
+	SandboxRole         string
+	SandboxTeam         string
+	SandboxMaxInstances int
+	SandboxMaxStorageGB int
+	SandboxTTLHours     int
 }
 
 // Load loads configuration from environment variables with defaults
@@ -48,6 +216,7 @@ func Load() (*Config, error) {
 	cfg := &Config{
 		ServerPort: getEnv("SERVER_PORT", "8091"),
 		ServerHost: getEnv("SERVER_HOST", "0.0.0.0"),
+		PublicURL:  getEnv("PUBLIC_URL", ""),
 
 		DBHost:     getEnv("DB_HOST", "localhost"),
 		DBPort:     getEnv("DB_PORT", "5432"),
@@ -57,15 +226,78 @@ func Load() (*Config, error) {
 
 		JWTSecret: getEnv("JWT_SECRET", ""),
 
-		KubeConfig:            getEnv("KUBECONFIG", ""),
-		DefaultIngressClass:   getEnv("DEFAULT_INGRESS_CLASS", "nginx"),
-		DefaultIngressDomain:  getEnv("DEFAULT_INGRESS_DOMAIN", "supabase.example.com"),
-		CertManagerIssuer:     getEnv("CERT_MANAGER_ISSUER", "letsencrypt-prod"),
-		LeaderElectionEnabled: getEnvBool("LEADER_ELECTION_ENABLED", false),
+		InitialAdminUsername: getEnv("INITIAL_ADMIN_USERNAME", "admin"),
+		InitialAdminSecret:   getEnv("INITIAL_ADMIN_SECRET", ""),
+
+		KubeConfig:                 getEnv("KUBECONFIG", ""),
+		DefaultIngressClass:        getEnv("DEFAULT_INGRESS_CLASS", "nginx"),
+		DefaultIngressDomain:       getEnv("DEFAULT_INGRESS_DOMAIN", "supabase.example.com"),
+		IngressControllerNamespace: getEnv("INGRESS_CONTROLLER_NAMESPACE", "ingress-nginx"),
+		MonitoringNamespace:        getEnv("MONITORING_NAMESPACE", "monitoring"),
+		NamespaceTemplate:          getEnv("NAMESPACE_TEMPLATE", namespaces.DefaultTemplate),
+		CertManagerIssuer:          getEnv("CERT_MANAGER_ISSUER", "letsencrypt-prod"),
+		ExternalDNSMode:            getEnv("EXTERNAL_DNS_MODE", ""),
+		LeaderElectionEnabled:      getEnvBool("LEADER_ELECTION_ENABLED", false),
+		APICacheEnabled:            getEnvBool("API_CACHE_ENABLED", true),
+
+		WebhookEnabled: getEnvBool("WEBHOOK_ENABLED", false),
+		WebhookCertDir: getEnv("WEBHOOK_CERT_DIR", "/tmp/k8s-webhook-server/serving-certs"),
 
 		SupabaseChartRepo:    getEnv("SUPABASE_CHART_REPO", "https://supabase-community.github.io/supabase-kubernetes"),
 		SupabaseChartName:    getEnv("SUPABASE_CHART_NAME", "supabase"),
 		SupabaseChartVersion: getEnv("SUPABASE_CHART_VERSION", ""),
+
+		ChartRepoUsername:          getEnv("CHART_REPO_USERNAME", ""),
+		ChartRepoPassword:          getEnv("CHART_REPO_PASSWORD", ""),
+		ChartRepoCredentialsSecret: getEnv("CHART_REPO_CREDENTIALS_SECRET", ""),
+
+		ProvisionerImage:              getEnv("PROVISIONER_IMAGE", ""),
+		ProvisionerImageRequireDigest: getEnvBool("PROVISIONER_IMAGE_REQUIRE_DIGEST", false),
+
+		PostgresUpgradeImage: getEnv("POSTGRES_UPGRADE_IMAGE", ""),
+
+		KataRuntimeClass:     getEnv("KATA_RUNTIME_CLASS", "kata"),
+		VClusterChartRepo:    getEnv("VCLUSTER_CHART_REPO", "https://charts.loft.sh"),
+		VClusterChartVersion: getEnv("VCLUSTER_CHART_VERSION", ""),
+
+		SuspendedPageURL:     getEnv("SUSPENDED_PAGE_URL", ""),
+		BillingWebhookSecret: getEnv("BILLING_WEBHOOK_SECRET", ""),
+
+		NotificationWebhookURL:    getEnv("NOTIFICATION_WEBHOOK_URL", ""),
+		NotificationWebhookSecret: getEnv("NOTIFICATION_WEBHOOK_SECRET", ""),
+
+		AdvisoryFeed: getEnv("SECURITY_ADVISORY_FEED", ""),
+
+		PrometheusURL: getEnv("PROMETHEUS_URL", ""),
+
+		LogErrorAnalysisEnabled: getEnvBool("LOG_ERROR_ANALYSIS_ENABLED", true),
+
+		CABundleFile:      getEnv("CA_BUNDLE_FILE", ""),
+		CABundleConfigMap: getEnv("CA_BUNDLE_CONFIGMAP", ""),
+
+		HTTPProxy:  getEnvAny("HTTP_PROXY", "http_proxy"),
+		HTTPSProxy: getEnvAny("HTTPS_PROXY", "https_proxy"),
+		NoProxy:    getEnvAny("NO_PROXY", "no_proxy"),
+
+		ObjectStorageProvider:  getEnv("OBJECT_STORAGE_PROVIDER", ""),
+		ObjectStorageBucket:    getEnv("OBJECT_STORAGE_BUCKET", ""),
+		ObjectStoragePrefix:    getEnv("OBJECT_STORAGE_PREFIX", ""),
+		ObjectStorageEndpoint:  getEnv("OBJECT_STORAGE_ENDPOINT", ""),
+		ObjectStorageRegion:    getEnv("OBJECT_STORAGE_REGION", ""),
+		ObjectStorageAccessKey: getEnv("OBJECT_STORAGE_ACCESS_KEY", ""),
+		ObjectStorageSecretKey: getEnv("OBJECT_STORAGE_SECRET_KEY", ""),
+		ObjectStoragePath:      getEnv("OBJECT_STORAGE_PATH", ""),
+		BackupEncryptionKeyID:  getEnv("BACKUP_ENCRYPTION_KEY_ID", ""),
+
+		SAMLEnabled:           getEnvBool("SAML_ENABLED", false),
+		SAMLIDPMetadata:       getEnv("SAML_IDP_METADATA", ""),
+		SAMLEntityID:          getEnv("SAML_ENTITY_ID", ""),
+		SAMLCertFile:          getEnv("SAML_CERT_FILE", ""),
+		SAMLKeyFile:           getEnv("SAML_KEY_FILE", ""),
+		SAMLUsernameAttribute: getEnv("SAML_USERNAME_ATTRIBUTE", ""),
+		SAMLRoleAttribute:     getEnv("SAML_ROLE_ATTRIBUTE", ""),
+		SAMLDefaultRole:       getEnv("SAML_DEFAULT_ROLE", "user"),
+		SAMLAllowIDPInitiated: getEnvBool("SAML_ALLOW_IDP_INITIATED", false),
 	}
 
 	// Validate required fields
@@ -77,9 +309,316 @@ func Load() (*Config, error) {
 		return nil, fmt.Errorf("JWT_SECRET is required")
 	}
 
+	if cfg.InitialAdminSecret != "" {
+		if namespace, name, ok := strings.Cut(cfg.InitialAdminSecret, "/"); !ok || namespace == "" || name == "" {
+			return nil, fmt.Errorf("INITIAL_ADMIN_SECRET must be <namespace>/<name>")
+		}
+	}
+
+	gracePeriod, err := getEnvInt("DELETION_GRACE_PERIOD_HOURS", 72)
+	if err != nil {
+		return nil, err
+	}
+	if gracePeriod < 0 {
+		return nil, fmt.Errorf("DELETION_GRACE_PERIOD_HOURS must not be negative")
+	}
+	cfg.DeletionGracePeriodHours = gracePeriod
+
+	rotationOverlap, err := getEnvInt("API_KEY_ROTATION_OVERLAP_HOURS", 24)
+	if err != nil {
+		return nil, err
+	}
+	maxOverlap := int(apitypes.MaxAPIKeyRotationOverlap.Hours())
+	if rotationOverlap < 0 || rotationOverlap > maxOverlap {
+		return nil, fmt.Errorf("API_KEY_ROTATION_OVERLAP_HOURS must be between 0 and %d", maxOverlap)
+	}
+	cfg.APIKeyRotationOverlapHours = rotationOverlap
+
+	rotationDays, err := getEnvInt("CONNECTION_ROTATION_DAYS", 30)
+	if err != nil {
+		return nil, err
+	}
+	if rotationDays < 1 {
+		return nil, fmt.Errorf("CONNECTION_ROTATION_DAYS must be at least 1")
+	}
+	cfg.ConnectionRotationDays = rotationDays
+
+	secretMaxAgeDays, err := getEnvInt("SECRET_MAX_AGE_DAYS", 90)
+	if err != nil {
+		return nil, err
+	}
+	if secretMaxAgeDays < 1 {
+		return nil, fmt.Errorf("SECRET_MAX_AGE_DAYS must be at least 1")
+	}
+	cfg.SecretMaxAgeDays = secretMaxAgeDays
+
+	if cfg.NotificationWebhookURL != "" {
+		if u, err := url.Parse(cfg.NotificationWebhookURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("NOTIFICATION_WEBHOOK_URL must be an http(s) URL")
+		}
+	}
+
+	requestTimeout, err := getEnvInt("API_REQUEST_TIMEOUT_SECONDS", 30)
+	if err != nil {
+		return nil, err
+	}
+	if requestTimeout < 1 {
+		return nil, fmt.Errorf("API_REQUEST_TIMEOUT_SECONDS must be at least 1")
+	}
+	cfg.RequestTimeoutSeconds = requestTimeout
+
+	webhookPort, err := getEnvInt("WEBHOOK_PORT", 9443)
+	if err != nil {
+		return nil, err
+	}
+	if webhookPort < 1 || webhookPort > 65535 {
+		return nil, fmt.Errorf("WEBHOOK_PORT must be between 1 and 65535")
+	}
+	cfg.WebhookPort = webhookPort
+
+	loadShedding := []struct {
+		key          string
+		defaultValue int
+		target       *int
+	}{
+		{"LOAD_SHEDDING_MAX_REQUESTS", 200, &cfg.LoadSheddingMaxRequests},
+		{"LOAD_SHEDDING_MAX_EXPENSIVE_REQUESTS", 10, &cfg.LoadSheddingMaxExpensiveRequests},
+		{"LOAD_SHEDDING_QUEUE_TIMEOUT_MS", 1000, &cfg.LoadSheddingQueueTimeoutMS},
+	}
+	for _, setting := range loadShedding {
+		value, err := getEnvInt(setting.key, setting.defaultValue)
+		if err != nil {
+			return nil, err
+		}
+		if value < 0 {
+			return nil, fmt.Errorf("%s must not be negative", setting.key)
+		}
+		*setting.target = value
+	}
+
+	controllerSettings := []struct {
+		key          string
+		defaultValue int
+		target       *int
+	}{
+		{"CONTROLLER_MAX_CONCURRENT_RECONCILES", 1, &cfg.ControllerMaxConcurrentReconciles},
+		{"CONTROLLER_RATE_LIMIT_BASE_DELAY_MS", 5, &cfg.ControllerRateLimitBaseDelayMS},
+		{"CONTROLLER_RATE_LIMIT_MAX_DELAY_SECONDS", 1000, &cfg.ControllerRateLimitMaxDelaySeconds},
+		{"CONTROLLER_RATE_LIMIT_QPS", 10, &cfg.ControllerRateLimitQPS},
+		{"CONTROLLER_RATE_LIMIT_BURST", 100, &cfg.ControllerRateLimitBurst},
+		{"CONTROLLER_JOB_POLL_INTERVAL_SECONDS", 10, &cfg.ControllerJobPollIntervalSeconds},
+		{"CONTROLLER_RESYNC_INTERVAL_SECONDS", 300, &cfg.ControllerResyncIntervalSeconds},
+		{"CONTROLLER_FAILED_REQUEUE_INTERVAL_SECONDS", 600, &cfg.ControllerFailedRequeueIntervalSeconds},
+		{"CONTROLLER_HEALTH_SUCCESS_THRESHOLD", 3, &cfg.ControllerHealthSuccessThreshold},
+		{"CONTROLLER_HEALTH_FAILURE_THRESHOLD", 3, &cfg.ControllerHealthFailureThreshold},
+		{"EXTERNAL_DNS_READY_TIMEOUT_SECONDS", 600, &cfg.ExternalDNSReadyTimeoutSeconds},
+		{"CACHE_SYNC_PERIOD_MINUTES", 600, &cfg.CacheSyncPeriodMinutes},
+		{"INSTANCE_SYNC_INTERVAL_SECONDS", 30, &cfg.InstanceSyncIntervalSeconds},
+		{"API_KEY_USAGE_FLUSH_SECONDS", 30, &cfg.APIKeyUsageFlushSeconds},
+		{"OBSERVABILITY_CLIENT_QPS", 5, &cfg.ObservabilityClientQPS},
+		{"OBSERVABILITY_CLIENT_BURST", 10, &cfg.ObservabilityClientBurst},
+	}
+	for _, setting := range controllerSettings {
+		value, err := getEnvInt(setting.key, setting.defaultValue)
+		if err != nil {
+			return nil, err
+		}
+		if value < 1 {
+			return nil, fmt.Errorf("%s must be at least 1", setting.key)
+		}
+		*setting.target = value
+	}
+	maxProvisioningJobs, err := getEnvInt("CONTROLLER_MAX_CONCURRENT_PROVISIONING_JOBS", 0)
+	if err != nil {
+		return nil, err
+	}
+	if maxProvisioningJobs < 0 {
+		return nil, fmt.Errorf("CONTROLLER_MAX_CONCURRENT_PROVISIONING_JOBS must not be negative")
+	}
+	cfg.ControllerMaxConcurrentProvisioningJobs = maxProvisioningJobs
+	if cfg.ControllerRateLimitMaxDelaySeconds*1000 < cfg.ControllerRateLimitBaseDelayMS {
+		return nil, fmt.Errorf("CONTROLLER_RATE_LIMIT_MAX_DELAY_SECONDS must not be below CONTROLLER_RATE_LIMIT_BASE_DELAY_MS")
+	}
+
+	switch cfg.ExternalDNSMode {
+	case "", "dnsendpoint", "annotation":
+	default:
+		return nil, fmt.Errorf("EXTERNAL_DNS_MODE must be one of dnsendpoint, annotation")
+	}
+
+	if cfg.SuspendedPageURL == "" && cfg.PublicURL != "" {
+		cfg.SuspendedPageURL = strings.TrimSuffix(cfg.PublicURL, "/") + "/suspended"
+	}
+
+	quotas := []struct {
+		key    string
+		target *int
+	}{
+		{"QUOTA_MAX_INSTANCES_PER_USER", &cfg.QuotaMaxInstancesPerUser},
+		{"QUOTA_MAX_STORAGE_GB_PER_USER", &cfg.QuotaMaxStorageGBPerUser},
+		{"QUOTA_MAX_TOTAL_INSTANCES", &cfg.QuotaMaxTotalInstances},
+		{"QUOTA_MAX_TOTAL_STORAGE_GB", &cfg.QuotaMaxTotalStorageGB},
+	}
+	for _, quota := range quotas {
+		value, err := getEnvInt(quota.key, 0)
+		if err != nil {
+			return nil, err
+		}
+		if value < 0 {
+			return nil, fmt.Errorf("%s must not be negative", quota.key)
+		}
+		*quota.target = value
+	}
+
+	cfg.SandboxRole = getEnv("SANDBOX_ROLE", "")
+	cfg.SandboxTeam = getEnv("SANDBOX_TEAM", "")
+	if cfg.SandboxRole != "" && (cfg.SandboxRole == "admin" || !slices.Contains(userRoles, cfg.SandboxRole)) {
+		return nil, fmt.Errorf("SANDBOX_ROLE must be one of operator, user, readonly, viewer")
+	}
+	sandboxSettings := []struct {
+		key          string
+		defaultValue int
+		target       *int
+	}{
+		{"SANDBOX_MAX_INSTANCES", 2, &cfg.SandboxMaxInstances},
+		{"SANDBOX_MAX_STORAGE_GB", 5, &cfg.SandboxMaxStorageGB},
+		{"SANDBOX_TTL_HOURS", 168, &cfg.SandboxTTLHours},
+	}
+	for _, setting := range sandboxSettings {
+		value, err := getEnvInt(setting.key, setting.defaultValue)
+		if err != nil {
+			return nil, err
+		}
+		if value < 1 {
+			return nil, fmt.Errorf("%s must be at least 1", setting.key)
+		}
+		*setting.target = value
+	}
+	if maxHours := int(apitypes.MaxInstanceTTL.Hours()); cfg.SandboxTTLHours > maxHours {
+		return nil, fmt.Errorf("SANDBOX_TTL_HOURS must be at most %d", maxHours)
+	}
+
+	// MONITORING_SERVICE_MONITOR_LABELS lists key=value pairs, e.g. "release=kube-prometheus-stack"
+	if cfg.MonitoringLabels, err = getEnvPairs("MONITORING_SERVICE_MONITOR_LABELS"); err != nil {
+		return nil, err
+	}
+
+	// POSTGRES_IMAGE_TAGS lists major=tag pairs, e.g. "17=17.4.1.045"
+	if cfg.PostgresImageTags, err = getEnvPairs("POSTGRES_IMAGE_TAGS"); err != nil {
+		return nil, err
+	}
+	for version := range cfg.PostgresImageTags {
+		if major, err := strconv.Atoi(version); err != nil || major < 10 || major > 99 || strconv.Itoa(major) != version {
+			return nil, fmt.Errorf("POSTGRES_IMAGE_TAGS: %q is not a Postgres major version", version)
+		}
+	}
+
+	if (cfg.ChartRepoUsername == "") != (cfg.ChartRepoPassword == "") {
+		return nil, fmt.Errorf("CHART_REPO_USERNAME and CHART_REPO_PASSWORD must be set together")
+	}
+
+	if err := namespaces.ValidateTemplate(cfg.NamespaceTemplate); err != nil {
+		return nil, fmt.Errorf("NAMESPACE_TEMPLATE: %w", err)
+	}
+	// NAMESPACE_LABELS and NAMESPACE_ANNOTATIONS list key=value pairs, e.g. "cost-center=cc-1234"
+	if cfg.NamespaceLabels, err = getEnvPairs("NAMESPACE_LABELS"); err != nil {
+		return nil, err
+	}
+	if err := namespaces.ValidateLabels(cfg.NamespaceLabels); err != nil {
+		return nil, fmt.Errorf("NAMESPACE_LABELS: %w", err)
+	}
+	if cfg.NamespaceAnnotations, err = getEnvPairs("NAMESPACE_ANNOTATIONS"); err != nil {
+		return nil, err
+	}
+	if err := namespaces.ValidateAnnotations(cfg.NamespaceAnnotations); err != nil {
+		return nil, fmt.Errorf("NAMESPACE_ANNOTATIONS: %w", err)
+	}
+
+	if cfg.ObjectStorageProvider != "" {
+		if err := cfg.ObjectStorage().Validate(); err != nil {
+			return nil, fmt.Errorf("invalid object storage configuration: %w", err)
+		}
+	}
+
+	// BACKUP_ENCRYPTION_KEYS lists id=key pairs, e.g. "2026=<base64 key>"
+	if cfg.BackupEncryptionKeys, err = getEnvPairs("BACKUP_ENCRYPTION_KEYS"); err != nil {
+		return nil, err
+	}
+	if len(cfg.BackupEncryptionKeys) > 0 {
+		if cfg.ObjectStorageProvider == "" {
+			return nil, fmt.Errorf("BACKUP_ENCRYPTION_KEYS requires OBJECT_STORAGE_PROVIDER")
+		}
+		if _, err := backup.NewKeyring(cfg.BackupEncryptionKeys, cfg.BackupEncryptionKeyID); err != nil {
+			return nil, fmt.Errorf("BACKUP_ENCRYPTION_KEYS: %w", err)
+		}
+	}
+
+	if cfg.SAMLEnabled {
+		if err := cfg.loadSAML(); err != nil {
+			return nil, err
+		}
+	}
+
 	return cfg, nil
 }
 
+// loadSAML validates the single sign-on settings and parses the role mappings
+func (c *Config) loadSAML() error {
+	if c.PublicURL == "" {
+		return fmt.Errorf("PUBLIC_URL is required when SAML_ENABLED is set")
+	}
+	if c.SAMLIDPMetadata == "" {
+		return fmt.Errorf("SAML_IDP_METADATA is required when SAML_ENABLED is set")
+	}
+	if (c.SAMLCertFile == "") != (c.SAMLKeyFile == "") {
+		return fmt.Errorf("SAML_CERT_FILE and SAML_KEY_FILE must be set together")
+	}
+	if !slices.Contains(userRoles, c.SAMLDefaultRole) {
+		return fmt.Errorf("SAML_DEFAULT_ROLE must be one of %s", strings.Join(userRoles, ", "))
+	}
+
+	// SAML_ROLE_MAPPING lists value=role pairs, e.g. "supacontrol-admins=admin,ops=operator"
+	for _, pair := range strings.Split(getEnv("SAML_ROLE_MAPPING", ""), ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		value, role, ok := strings.Cut(pair, "=")
+		value, role = strings.TrimSpace(value), strings.TrimSpace(role)
+		if !ok || value == "" || !slices.Contains(userRoles, role) {
+			return fmt.Errorf("invalid SAML_ROLE_MAPPING entry %q: expected value=role with role one of %s", pair, strings.Join(userRoles, ", "))
+		}
+		c.SAMLRoleMappings = append(c.SAMLRoleMappings, sso.RoleMapping{Value: value, Role: role})
+	}
+	if len(c.SAMLRoleMappings) > 0 && c.SAMLRoleAttribute == "" {
+		return fmt.Errorf("SAML_ROLE_ATTRIBUTE is required when SAML_ROLE_MAPPING is set")
+	}
+	return nil
+}
+
+// SAML returns the single sign-on settings
+func (c *Config) SAML() sso.Config {
+	return sso.Config{
+		PublicURL:         c.PublicURL,
+		EntityID:          c.SAMLEntityID,
+		IDPMetadata:       c.SAMLIDPMetadata,
+		CertFile:          c.SAMLCertFile,
+		KeyFile:           c.SAMLKeyFile,
+		UsernameAttribute: c.SAMLUsernameAttribute,
+		RoleAttribute:     c.SAMLRoleAttribute,
+		RoleMappings:      c.SAMLRoleMappings,
+		DefaultRole:       c.SAMLDefaultRole,
+		AllowIDPInitiated: c.SAMLAllowIDPInitiated,
+	}
+}
+
+// InitialAdminSecretRef returns the namespace and name of the Secret receiving the
+// password of the first admin account, which are empty when it is logged instead
+func (c *Config) InitialAdminSecretRef() (namespace, name string) {
+	namespace, name, _ = strings.Cut(c.InitialAdminSecret, "/")
+	return namespace, name
+}
+
 // GetDSN returns the PostgreSQL connection string
 func (c *Config) GetDSN() string {
 	return fmt.Sprintf(
@@ -88,11 +627,47 @@ func (c *Config) GetDSN() string {
 	)
 }
 
+// ObjectStorage returns the object storage settings
+func (c *Config) ObjectStorage() objectstore.Config {
+	return objectstore.Config{
+		Provider:  c.ObjectStorageProvider,
+		Bucket:    c.ObjectStorageBucket,
+		Prefix:    c.ObjectStoragePrefix,
+		Endpoint:  c.ObjectStorageEndpoint,
+		Region:    c.ObjectStorageRegion,
+		AccessKey: c.ObjectStorageAccessKey,
+		SecretKey: c.ObjectStorageSecretKey,
+		Path:      c.ObjectStoragePath,
+	}
+}
+
 // GetServerAddr returns the server address
 func (c *Config) GetServerAddr() string {
 	return fmt.Sprintf("%s:%s", c.ServerHost, c.ServerPort)
 }
 
+// getEnvPairs parses an environment variable listing comma-separated key=value pairs, and
+// returns nil when it is empty
+func getEnvPairs(key string) (map[string]string, error) {
+	var pairs map[string]string
+	for _, pair := range strings.Split(getEnv(key, ""), ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(pair, "=")
+		name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid %s entry %q: expected key=value", key, pair)
+		}
+		if pairs == nil {
+			pairs = map[string]string{}
+		}
+		pairs[name] = value
+	}
+	return pairs, nil
+}
+
 // getEnv gets an environment variable with a fallback default value
 func getEnv(key, defaultValue string) string {
 	value := os.Getenv(key)
@@ -102,6 +677,16 @@ func getEnv(key, defaultValue string) string {
 	return value
 }
 
+// getEnvAny returns the first non-empty value among the given environment variables
+func getEnvAny(keys ...string) string {
+	for _, key := range keys {
+		if value := os.Getenv(key); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
 // getEnvBool gets a boolean environment variable with a fallback default value
 func getEnvBool(key string, defaultValue bool) bool {
 	value := os.Getenv(key)
@@ -112,6 +697,19 @@ func getEnvBool(key string, defaultValue bool) bool {
 	return value == "true" || value == "1" || value == "yes"
 }
 
+// getEnvInt gets an integer environment variable with a fallback default value
+func getEnvInt(key string, defaultValue int) (int, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be an integer: %w", key, err)
+	}
+	return n, nil
+}
+
 // loadDotEnv loads environment variables from .env file
 func loadDotEnv() error {
 	// Try to load from current directory first
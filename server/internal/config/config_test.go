@@ -182,6 +182,59 @@ func TestLoadConfigDefaults(t *testing.T) {
 		t.Errorf("ServerHost = %v, want 0.0.0.0", cfg.ServerHost)
 	}
 
+	if cfg.PublicURL != "" {
+		t.Errorf("PublicURL = %v, want empty", cfg.PublicURL)
+	}
+
+	if cfg.AdvisoryFeed != "" {
+		t.Errorf("AdvisoryFeed = %v, want empty", cfg.AdvisoryFeed)
+	}
+
+	if cfg.PrometheusURL != "" {
+		t.Errorf("PrometheusURL = %v, want empty", cfg.PrometheusURL)
+	}
+
+	if !cfg.LogErrorAnalysisEnabled {
+		t.Error("LogErrorAnalysisEnabled = false, want true")
+	}
+
+	if cfg.RequestTimeoutSeconds != 30 {
+		t.Errorf("RequestTimeoutSeconds = %v, want 30", cfg.RequestTimeoutSeconds)
+	}
+
+	if cfg.LoadSheddingMaxRequests != 200 || cfg.LoadSheddingMaxExpensiveRequests != 10 || cfg.LoadSheddingQueueTimeoutMS != 1000 {
+		t.Errorf("load shedding = %v/%v/%vms, want 200/10/1000ms",
+			cfg.LoadSheddingMaxRequests, cfg.LoadSheddingMaxExpensiveRequests, cfg.LoadSheddingQueueTimeoutMS)
+	}
+
+	if cfg.ControllerHealthSuccessThreshold != 3 || cfg.ControllerHealthFailureThreshold != 3 {
+		t.Errorf("health thresholds = %v/%v, want 3/3", cfg.ControllerHealthSuccessThreshold, cfg.ControllerHealthFailureThreshold)
+	}
+
+	if !cfg.APICacheEnabled || cfg.CacheSyncPeriodMinutes != 600 {
+		t.Errorf("API cache = %v with %d minute resync, want true with 600", cfg.APICacheEnabled, cfg.CacheSyncPeriodMinutes)
+	}
+
+	if cfg.DeletionGracePeriodHours != 72 {
+		t.Errorf("DeletionGracePeriodHours = %v, want 72", cfg.DeletionGracePeriodHours)
+	}
+
+	if cfg.APIKeyRotationOverlapHours != 24 {
+		t.Errorf("APIKeyRotationOverlapHours = %v, want 24", cfg.APIKeyRotationOverlapHours)
+	}
+
+	if cfg.CABundleFile != "" || cfg.CABundleConfigMap != "" {
+		t.Errorf("CA bundle = %v/%v, want empty", cfg.CABundleFile, cfg.CABundleConfigMap)
+	}
+
+	if cfg.ProvisionerImage != "" || cfg.ProvisionerImageRequireDigest {
+		t.Errorf("provisioner image = %v (require digest %v), want empty and unpinned", cfg.ProvisionerImage, cfg.ProvisionerImageRequireDigest)
+	}
+
+	if cfg.KataRuntimeClass != "kata" || cfg.VClusterChartRepo != "https://charts.loft.sh" || cfg.VClusterChartVersion != "" {
+		t.Errorf("isolation = %v/%v/%v, want kata/https://charts.loft.sh/empty", cfg.KataRuntimeClass, cfg.VClusterChartRepo, cfg.VClusterChartVersion)
+	}
+
 	if cfg.DBHost != "localhost" {
 		t.Errorf("DBHost = %v, want localhost", cfg.DBHost)
 	}
@@ -193,4 +246,486 @@ func TestLoadConfigDefaults(t *testing.T) {
 	if cfg.DefaultIngressClass != "nginx" {
 		t.Errorf("DefaultIngressClass = %v, want nginx", cfg.DefaultIngressClass)
 	}
+
+	if cfg.SecretMaxAgeDays != 90 {
+		t.Errorf("SecretMaxAgeDays = %v, want 90", cfg.SecretMaxAgeDays)
+	}
+}
+
+func TestLoadConfigProxy(t *testing.T) {
+	t.Setenv("DB_PASSWORD", "testpass")
+	t.Setenv("JWT_SECRET", "test-secret")
+	t.Setenv("HTTP_PROXY", "")
+	t.Setenv("http_proxy", "http://lower:3128")
+	t.Setenv("HTTPS_PROXY", "http://upper:3128")
+	t.Setenv("https_proxy", "http://lower:3128")
+	t.Setenv("NO_PROXY", "")
+	t.Setenv("no_proxy", ".corp")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	if cfg.HTTPProxy != "http://lower:3128" {
+		t.Errorf("HTTPProxy = %v, want lowercase fallback", cfg.HTTPProxy)
+	}
+	if cfg.HTTPSProxy != "http://upper:3128" {
+		t.Errorf("HTTPSProxy = %v, want uppercase to win", cfg.HTTPSProxy)
+	}
+	if cfg.NoProxy != ".corp" {
+		t.Errorf("NoProxy = %v, want .corp", cfg.NoProxy)
+	}
+}
+
+func TestLoadConfigObjectStorage(t *testing.T) {
+	t.Setenv("DB_PASSWORD", "testpass")
+	t.Setenv("JWT_SECRET", "test-secret")
+	t.Setenv("OBJECT_STORAGE_PROVIDER", "minio")
+	t.Setenv("OBJECT_STORAGE_BUCKET", "supacontrol")
+	t.Setenv("OBJECT_STORAGE_ENDPOINT", "http://minio:9000")
+	t.Setenv("OBJECT_STORAGE_ACCESS_KEY", "access")
+	t.Setenv("OBJECT_STORAGE_SECRET_KEY", "secret")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	store := cfg.ObjectStorage()
+	if store.Provider != "minio" || store.Bucket != "supacontrol" || store.Endpoint != "http://minio:9000" {
+		t.Errorf("ObjectStorage() = %+v", store)
+	}
+
+	// Provider-specific validation rejects incomplete settings
+	t.Setenv("OBJECT_STORAGE_ENDPOINT", "")
+	if _, err := Load(); err == nil {
+		t.Error("Load() succeeded without the endpoint MinIO requires")
+	}
+}
+
+func TestLoadConfigBackupEncryption(t *testing.T) {
+	t.Setenv("DB_PASSWORD", "testpass")
+	t.Setenv("JWT_SECRET", "test-secret")
+	key := "MDEyMzQ1Njc4OTAxMjM0NTY3ODkwMTIzNDU2Nzg5MDE="
+	t.Setenv("BACKUP_ENCRYPTION_KEYS", "2025="+key+", 2026="+key)
+	t.Setenv("BACKUP_ENCRYPTION_KEY_ID", "2026")
+
+	// Backups are stored in object storage
+	if _, err := Load(); err == nil {
+		t.Error("Load() succeeded with backup keys but no object storage")
+	}
+
+	t.Setenv("OBJECT_STORAGE_PROVIDER", "local")
+	t.Setenv("OBJECT_STORAGE_PATH", t.TempDir())
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(cfg.BackupEncryptionKeys) != 2 || cfg.BackupEncryptionKeys["2025"] != key || cfg.BackupEncryptionKeyID != "2026" {
+		t.Errorf("backup keys = %v, active %q", cfg.BackupEncryptionKeys, cfg.BackupEncryptionKeyID)
+	}
+
+	t.Setenv("BACKUP_ENCRYPTION_KEY_ID", "")
+	if _, err := Load(); err == nil {
+		t.Error("Load() succeeded without choosing among several keys")
+	}
+	t.Setenv("BACKUP_ENCRYPTION_KEYS", "2026=c2hvcnQ=")
+	if _, err := Load(); err == nil {
+		t.Error("Load() succeeded with a short key")
+	}
+}
+
+func TestLoadConfigQuotas(t *testing.T) {
+	t.Setenv("DB_PASSWORD", "testpass")
+	t.Setenv("JWT_SECRET", "test-secret")
+	t.Setenv("QUOTA_MAX_INSTANCES_PER_USER", "3")
+	t.Setenv("QUOTA_MAX_TOTAL_STORAGE_GB", "500")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.QuotaMaxInstancesPerUser != 3 || cfg.QuotaMaxTotalStorageGB != 500 {
+		t.Errorf("quotas = %d instances per user, %d GB total; want 3 and 500", cfg.QuotaMaxInstancesPerUser, cfg.QuotaMaxTotalStorageGB)
+	}
+	if cfg.QuotaMaxStorageGBPerUser != 0 || cfg.QuotaMaxTotalInstances != 0 {
+		t.Error("unset quotas should default to 0 (unlimited)")
+	}
+
+	for _, value := range []string{"three", "-1"} {
+		t.Setenv("QUOTA_MAX_TOTAL_INSTANCES", value)
+		if _, err := Load(); err == nil {
+			t.Errorf("Load() accepted QUOTA_MAX_TOTAL_INSTANCES=%s", value)
+		}
+	}
+}
+
+func TestLoadConfigSandbox(t *testing.T) {
+	t.Setenv("DB_PASSWORD", "testpass")
+	t.Setenv("JWT_SECRET", "test-secret")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.SandboxRole != "" || cfg.SandboxTeam != "" {
+		t.Error("sandboxes should be disabled by default")
+	}
+	if cfg.SandboxMaxInstances != 2 || cfg.SandboxMaxStorageGB != 5 || cfg.SandboxTTLHours != 168 {
+		t.Errorf("sandbox limits = %d instances, %d GB, %d hours; want 2, 5 and 168",
+			cfg.SandboxMaxInstances, cfg.SandboxMaxStorageGB, cfg.SandboxTTLHours)
+	}
+
+	t.Setenv("SANDBOX_ROLE", "user")
+	t.Setenv("SANDBOX_TEAM", "engineering")
+	if cfg, err = Load(); err != nil || cfg.SandboxRole != "user" || cfg.SandboxTeam != "engineering" {
+		t.Errorf("Load() = %+v, %v; want the user role and engineering team sandboxed", cfg, err)
+	}
+
+	for key, value := range map[string]string{
+		"SANDBOX_ROLE":          "admin",
+		"SANDBOX_MAX_INSTANCES": "0",
+		"SANDBOX_TTL_HOURS":     "721",
+	} {
+		t.Run(key, func(t *testing.T) {
+			t.Setenv(key, value)
+			if _, err := Load(); err == nil {
+				t.Errorf("Load() accepted %s=%s", key, value)
+			}
+		})
+	}
+}
+
+func TestLoadConfigSuspendedPage(t *testing.T) {
+	t.Setenv("DB_PASSWORD", "testpass")
+	t.Setenv("JWT_SECRET", "test-secret")
+	t.Setenv("PUBLIC_URL", "https://supacontrol.example.com/")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.SuspendedPageURL != "https://supacontrol.example.com/suspended" {
+		t.Errorf("SuspendedPageURL = %q, want the PUBLIC_URL suspended page", cfg.SuspendedPageURL)
+	}
+
+	t.Setenv("SUSPENDED_PAGE_URL", "https://status.example.com/suspended")
+	cfg, err = Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.SuspendedPageURL != "https://status.example.com/suspended" {
+		t.Errorf("SuspendedPageURL = %q, want the configured page", cfg.SuspendedPageURL)
+	}
+}
+
+func TestLoadConfigInitialAdmin(t *testing.T) {
+	t.Setenv("DB_PASSWORD", "testpass")
+	t.Setenv("JWT_SECRET", "test-secret")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if namespace, name := cfg.InitialAdminSecretRef(); cfg.InitialAdminUsername != "admin" || namespace != "" || name != "" {
+		t.Errorf("unexpected initial admin defaults: %q %q/%q", cfg.InitialAdminUsername, namespace, name)
+	}
+
+	t.Setenv("INITIAL_ADMIN_SECRET", "supacontrol/supacontrol-initial-admin")
+	cfg, err = Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if namespace, name := cfg.InitialAdminSecretRef(); namespace != "supacontrol" || name != "supacontrol-initial-admin" {
+		t.Errorf("InitialAdminSecretRef() = %q, %q", namespace, name)
+	}
+
+	t.Setenv("INITIAL_ADMIN_SECRET", "supacontrol-initial-admin")
+	if _, err := Load(); err == nil {
+		t.Error("expected an error for a secret without a namespace")
+	}
+}
+
+func TestLoadConfigController(t *testing.T) {
+	t.Setenv("DB_PASSWORD", "testpass")
+	t.Setenv("JWT_SECRET", "test-secret")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.ControllerMaxConcurrentReconciles != 1 || cfg.ControllerResyncIntervalSeconds != 300 {
+		t.Errorf("controller defaults = %d concurrent, %ds resync; want 1 and 300",
+			cfg.ControllerMaxConcurrentReconciles, cfg.ControllerResyncIntervalSeconds)
+	}
+
+	t.Setenv("CONTROLLER_MAX_CONCURRENT_RECONCILES", "8")
+	t.Setenv("CONTROLLER_JOB_POLL_INTERVAL_SECONDS", "30")
+	cfg, err = Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.ControllerMaxConcurrentReconciles != 8 || cfg.ControllerJobPollIntervalSeconds != 30 {
+		t.Errorf("controller settings = %d concurrent, %ds Job poll; want 8 and 30",
+			cfg.ControllerMaxConcurrentReconciles, cfg.ControllerJobPollIntervalSeconds)
+	}
+
+	for _, value := range []string{"many", "0"} {
+		t.Setenv("CONTROLLER_MAX_CONCURRENT_RECONCILES", value)
+		if _, err := Load(); err == nil {
+			t.Errorf("Load() accepted CONTROLLER_MAX_CONCURRENT_RECONCILES=%s", value)
+		}
+	}
+	t.Setenv("CONTROLLER_MAX_CONCURRENT_RECONCILES", "8")
+
+	if cfg.ControllerMaxConcurrentProvisioningJobs != 0 {
+		t.Errorf("ControllerMaxConcurrentProvisioningJobs = %d, want 0 (unlimited)", cfg.ControllerMaxConcurrentProvisioningJobs)
+	}
+	t.Setenv("CONTROLLER_MAX_CONCURRENT_PROVISIONING_JOBS", "5")
+	if cfg, err = Load(); err != nil || cfg.ControllerMaxConcurrentProvisioningJobs != 5 {
+		t.Errorf("Load() = %v, %v; want 5 concurrent provisioning Jobs", cfg, err)
+	}
+	t.Setenv("CONTROLLER_MAX_CONCURRENT_PROVISIONING_JOBS", "-1")
+	if _, err := Load(); err == nil {
+		t.Error("Load() accepted CONTROLLER_MAX_CONCURRENT_PROVISIONING_JOBS=-1")
+	}
+	t.Setenv("CONTROLLER_MAX_CONCURRENT_PROVISIONING_JOBS", "5")
+
+	if cfg.ObservabilityClientQPS != 5 || cfg.ObservabilityClientBurst != 10 {
+		t.Errorf("observability client limits = %d QPS, %d burst; want 5 and 10",
+			cfg.ObservabilityClientQPS, cfg.ObservabilityClientBurst)
+	}
+	t.Setenv("OBSERVABILITY_CLIENT_QPS", "0")
+	if _, err := Load(); err == nil {
+		t.Error("Load() accepted OBSERVABILITY_CLIENT_QPS=0")
+	}
+	t.Setenv("OBSERVABILITY_CLIENT_QPS", "20")
+
+	t.Setenv("CONTROLLER_RATE_LIMIT_BASE_DELAY_MS", "5000")
+	t.Setenv("CONTROLLER_RATE_LIMIT_MAX_DELAY_SECONDS", "1")
+	if _, err := Load(); err == nil {
+		t.Error("Load() accepted a max retry delay below the base delay")
+	}
+}
+
+func TestLoadConfigWebhook(t *testing.T) {
+	t.Setenv("DB_PASSWORD", "testpass")
+	t.Setenv("JWT_SECRET", "test-secret")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.WebhookEnabled || cfg.WebhookPort != 9443 {
+		t.Errorf("webhook defaults = enabled %v, port %d; want disabled on 9443", cfg.WebhookEnabled, cfg.WebhookPort)
+	}
+
+	t.Setenv("WEBHOOK_ENABLED", "true")
+	t.Setenv("WEBHOOK_PORT", "10250")
+	t.Setenv("WEBHOOK_CERT_DIR", "/certs")
+	cfg, err = Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if !cfg.WebhookEnabled || cfg.WebhookPort != 10250 || cfg.WebhookCertDir != "/certs" {
+		t.Errorf("webhook settings = enabled %v, port %d, certs %q", cfg.WebhookEnabled, cfg.WebhookPort, cfg.WebhookCertDir)
+	}
+
+	t.Setenv("WEBHOOK_PORT", "70000")
+	if _, err := Load(); err == nil {
+		t.Error("Load() accepted WEBHOOK_PORT=70000")
+	}
+}
+
+func TestLoadConfigNotificationWebhook(t *testing.T) {
+	t.Setenv("DB_PASSWORD", "testpass")
+	t.Setenv("JWT_SECRET", "test-secret")
+	t.Setenv("NOTIFICATION_WEBHOOK_URL", "https://hooks.example.com/supacontrol")
+	t.Setenv("NOTIFICATION_WEBHOOK_SECRET", "hook-secret")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.NotificationWebhookURL != "https://hooks.example.com/supacontrol" || cfg.NotificationWebhookSecret != "hook-secret" {
+		t.Errorf("notification webhook = %q, %q", cfg.NotificationWebhookURL, cfg.NotificationWebhookSecret)
+	}
+
+	t.Setenv("NOTIFICATION_WEBHOOK_URL", "hooks.example.com")
+	if _, err := Load(); err == nil {
+		t.Error("Load() accepted a NOTIFICATION_WEBHOOK_URL without a scheme")
+	}
+}
+
+func TestLoadConfigChartRepoCredentials(t *testing.T) {
+	t.Setenv("DB_PASSWORD", "testpass")
+	t.Setenv("JWT_SECRET", "test-secret")
+	t.Setenv("CHART_REPO_USERNAME", "robot$supacontrol")
+	t.Setenv("CHART_REPO_PASSWORD", "harbor-token")
+	t.Setenv("CHART_REPO_CREDENTIALS_SECRET", "harbor-credentials")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.ChartRepoUsername != "robot$supacontrol" || cfg.ChartRepoPassword != "harbor-token" || cfg.ChartRepoCredentialsSecret != "harbor-credentials" {
+		t.Errorf("chart repo credentials = %q, %q, %q", cfg.ChartRepoUsername, cfg.ChartRepoPassword, cfg.ChartRepoCredentialsSecret)
+	}
+
+	t.Setenv("CHART_REPO_PASSWORD", "")
+	if _, err := Load(); err == nil {
+		t.Error("Load() accepted CHART_REPO_USERNAME without CHART_REPO_PASSWORD")
+	}
+}
+
+func TestLoadConfigExternalDNS(t *testing.T) {
+	t.Setenv("DB_PASSWORD", "testpass")
+	t.Setenv("JWT_SECRET", "test-secret")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.ExternalDNSMode != "" || cfg.ExternalDNSReadyTimeoutSeconds != 600 {
+		t.Errorf("external-dns defaults = %q, %d", cfg.ExternalDNSMode, cfg.ExternalDNSReadyTimeoutSeconds)
+	}
+
+	t.Setenv("EXTERNAL_DNS_MODE", "dnsendpoint")
+	t.Setenv("EXTERNAL_DNS_READY_TIMEOUT_SECONDS", "120")
+	if cfg, err = Load(); err != nil || cfg.ExternalDNSMode != "dnsendpoint" || cfg.ExternalDNSReadyTimeoutSeconds != 120 {
+		t.Errorf("Load() = %v, %v; want dnsendpoint mode with a 120s timeout", cfg, err)
+	}
+
+	t.Setenv("EXTERNAL_DNS_MODE", "route53")
+	if _, err := Load(); err == nil {
+		t.Error("Load() accepted EXTERNAL_DNS_MODE=route53")
+	}
+}
+
+func TestLoadConfigMonitoring(t *testing.T) {
+	t.Setenv("DB_PASSWORD", "testpass")
+	t.Setenv("JWT_SECRET", "test-secret")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.MonitoringNamespace != "monitoring" || cfg.MonitoringLabels != nil {
+		t.Errorf("monitoring defaults = %q, %v", cfg.MonitoringNamespace, cfg.MonitoringLabels)
+	}
+
+	t.Setenv("MONITORING_SERVICE_MONITOR_LABELS", "release=kube-prometheus-stack, team = platform")
+	cfg, err = Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(cfg.MonitoringLabels) != 2 || cfg.MonitoringLabels["release"] != "kube-prometheus-stack" || cfg.MonitoringLabels["team"] != "platform" {
+		t.Errorf("MonitoringLabels = %v", cfg.MonitoringLabels)
+	}
+
+	t.Setenv("MONITORING_SERVICE_MONITOR_LABELS", "kube-prometheus-stack")
+	if _, err := Load(); err == nil {
+		t.Error("Load() accepted a label without a value")
+	}
+}
+
+func TestLoadConfigPostgresImageTags(t *testing.T) {
+	t.Setenv("DB_PASSWORD", "testpass")
+	t.Setenv("JWT_SECRET", "test-secret")
+
+	t.Setenv("POSTGRES_IMAGE_TAGS", "15=15.8.1.060,17=17.4.1.045")
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(cfg.PostgresImageTags) != 2 || cfg.PostgresImageTags["17"] != "17.4.1.045" {
+		t.Errorf("PostgresImageTags = %v", cfg.PostgresImageTags)
+	}
+
+	for _, value := range []string{"17.4=17.4.1.045", "v17=17.4.1.045", "9=9.6.24"} {
+		t.Setenv("POSTGRES_IMAGE_TAGS", value)
+		if _, err := Load(); err == nil {
+			t.Errorf("Load() accepted POSTGRES_IMAGE_TAGS=%q", value)
+		}
+	}
+}
+
+func TestLoadConfigNamespaces(t *testing.T) {
+	t.Setenv("DB_PASSWORD", "testpass")
+	t.Setenv("JWT_SECRET", "test-secret")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.NamespaceTemplate != "supa-{name}" || cfg.NamespaceLabels != nil || cfg.NamespaceAnnotations != nil {
+		t.Errorf("namespace defaults = %q, %v, %v", cfg.NamespaceTemplate, cfg.NamespaceLabels, cfg.NamespaceAnnotations)
+	}
+
+	t.Setenv("NAMESPACE_TEMPLATE", "tenants-{name}")
+	t.Setenv("NAMESPACE_LABELS", "cost-center=cc-1234,team=payments")
+	t.Setenv("NAMESPACE_ANNOTATIONS", "example.com/owner=payments@example.com")
+	cfg, err = Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.NamespaceTemplate != "tenants-{name}" || len(cfg.NamespaceLabels) != 2 || cfg.NamespaceAnnotations["example.com/owner"] != "payments@example.com" {
+		t.Errorf("namespace settings = %q, %v, %v", cfg.NamespaceTemplate, cfg.NamespaceLabels, cfg.NamespaceAnnotations)
+	}
+
+	tests := []struct {
+		key   string
+		value string
+	}{
+		{key: "NAMESPACE_TEMPLATE", value: "tenants"},
+		{key: "NAMESPACE_TEMPLATE", value: "kube-{name}"},
+		{key: "NAMESPACE_LABELS", value: "pod-security.kubernetes.io/enforce=privileged"},
+		{key: "NAMESPACE_ANNOTATIONS", value: "supacontrol.io/owner"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key+"="+tt.value, func(t *testing.T) {
+			t.Setenv(tt.key, tt.value)
+			if _, err := Load(); err == nil {
+				t.Errorf("Load() accepted %s=%q", tt.key, tt.value)
+			}
+		})
+	}
+}
+
+func TestLoadConfigSAML(t *testing.T) {
+	t.Setenv("DB_PASSWORD", "testpass")
+	t.Setenv("JWT_SECRET", "test-secret")
+	t.Setenv("SAML_ENABLED", "true")
+	t.Setenv("PUBLIC_URL", "https://supacontrol.example.com")
+	t.Setenv("SAML_IDP_METADATA", "https://idp.example.com/metadata")
+	t.Setenv("SAML_ROLE_ATTRIBUTE", "groups")
+	t.Setenv("SAML_ROLE_MAPPING", "supacontrol-admins=admin, ops = operator")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	saml := cfg.SAML()
+	if len(saml.RoleMappings) != 2 || saml.RoleMappings[1].Value != "ops" || saml.RoleMappings[1].Role != "operator" {
+		t.Errorf("RoleMappings = %+v", saml.RoleMappings)
+	}
+	if saml.DefaultRole != "user" || saml.RoleAttribute != "groups" {
+		t.Errorf("SAML() = %+v", saml)
+	}
+
+	invalid := map[string]string{
+		"SAML_ROLE_MAPPING": "supacontrol-admins=root",
+		"SAML_DEFAULT_ROLE": "guest",
+		"SAML_CERT_FILE":    "/certs/tls.crt",
+		"PUBLIC_URL":        "",
+		"SAML_IDP_METADATA": "",
+	}
+	for key, value := range invalid {
+		t.Run(key, func(t *testing.T) {
+			t.Setenv(key, value)
+			if _, err := Load(); err == nil {
+				t.Errorf("Load() accepted %s=%q", key, value)
+			}
+		})
+	}
 }
@@ -7,6 +7,9 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+
 	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
 )
 
@@ -32,7 +35,11 @@ func (c *Client) CreateAPIKey(userID int64, name, keyHash string, expiresAt *tim
 func (c *Client) GetAPIKeyByHash(keyHash string) (*apitypes.APIKey, error) {
 	var apiKey apitypes.APIKey
 
-	query := `SELECT * FROM api_keys WHERE key_hash = $1`
+	// The hash a key had before its last rotation keeps working until the overlap ends
+	query := `
+		SELECT * FROM api_keys
+		WHERE key_hash = $1 OR (previous_key_hash = $1 AND previous_key_expires_at > NOW())
+	`
 
 	err := c.db.Get(&apiKey, query, keyHash)
 	if err == sql.ErrNoRows {
@@ -107,6 +114,74 @@ func (c *Client) UpdateAPIKeyLastUsed(id int64) error {
 	return nil
 }
 
+// UpdateAPIKeysLastUsed sets the last_used timestamps of several API keys in one
+// statement. A timestamp older than the stored one is ignored, so batches flushed out of
+// order by different API replicas never move it back.
+func (c *Client) UpdateAPIKeysLastUsed(lastUsed map[int64]time.Time) error {
+	if len(lastUsed) == 0 {
+		return nil
+	}
+	ids := make(pq.Int64Array, 0, len(lastUsed))
+	times := make(pq.StringArray, 0, len(lastUsed))
+	for id, usedAt := range lastUsed {
+		ids = append(ids, id)
+		times = append(times, usedAt.UTC().Format(time.RFC3339Nano))
+	}
+
+	query := `
+		UPDATE api_keys AS k SET last_used = u.last_used
+		FROM unnest($1::bigint[], $2::timestamptz[]) AS u(id, last_used)
+		WHERE k.id = u.id AND (k.last_used IS NULL OR k.last_used < u.last_used)
+	`
+
+	if _, err := c.db.Exec(query, ids, times); err != nil {
+		return fmt.Errorf("failed to update API keys last used: %w", err)
+	}
+
+	return nil
+}
+
+// RotateAPIKey replaces the hash of an API key in one transaction. The current hash
+// becomes the previous one and stays valid until previousValidUntil; a nil
+// previousValidUntil revokes it right away. Returns nil if the key doesn't exist.
+func (c *Client) RotateAPIKey(id int64, keyHash string, previousValidUntil *time.Time) (*apitypes.APIKey, error) {
+	var apiKey *apitypes.APIKey
+
+	err := c.WithinTransaction(func(tx *sqlx.Tx) error {
+		var current apitypes.APIKey
+		err := tx.Get(&current, `SELECT * FROM api_keys WHERE id = $1 FOR UPDATE`, id)
+		if err == sql.ErrNoRows {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get API key: %w", err)
+		}
+
+		var previousHash *string
+		if previousValidUntil != nil {
+			previousHash = &current.KeyHash
+		}
+
+		query := `
+			UPDATE api_keys
+			SET key_hash = $2, previous_key_hash = $3, previous_key_expires_at = $4
+			WHERE id = $1
+			RETURNING *
+		`
+		var rotated apitypes.APIKey
+		if err := tx.QueryRowx(query, id, keyHash, previousHash, previousValidUntil).StructScan(&rotated); err != nil {
+			return fmt.Errorf("failed to rotate API key: %w", err)
+		}
+		apiKey = &rotated
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return apiKey, nil
+}
+
 // DeleteAPIKey deletes an API key
 func (c *Client) DeleteAPIKey(id int64) error {
 	query := `DELETE FROM api_keys WHERE id = $1`
@@ -540,6 +540,48 @@ func TestClient_UpdateAPIKeyLastUsed_NonExistent(t *testing.T) {
 	}
 }
 
+func TestClient_UpdateAPIKeysLastUsed(t *testing.T) {
+	client, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	user := createTestUserWithDefaults(t, client)
+	first, err := client.CreateAPIKey(user.ID, "first-key", "firsthash", nil)
+	if err != nil {
+		t.Fatalf("Failed to create API key: %v", err)
+	}
+	second, err := client.CreateAPIKey(user.ID, "second-key", "secondhash", nil)
+	if err != nil {
+		t.Fatalf("Failed to create API key: %v", err)
+	}
+
+	usedAt := time.Now().Add(-time.Minute).Truncate(time.Second)
+	err = client.UpdateAPIKeysLastUsed(map[int64]time.Time{first.ID: usedAt, second.ID: usedAt, 99999: usedAt})
+	if err != nil {
+		t.Fatalf("UpdateAPIKeysLastUsed() failed: %v", err)
+	}
+	for _, id := range []int64{first.ID, second.ID} {
+		key, err := client.GetAPIKeyByID(id)
+		if err != nil {
+			t.Fatalf("Failed to get API key: %v", err)
+		}
+		if key.LastUsed == nil || !key.LastUsed.Equal(usedAt) {
+			t.Errorf("Expected key %d last used at %v, got %v", id, usedAt, key.LastUsed)
+		}
+	}
+
+	// An older batch, e.g. from another replica, does not move the timestamp back
+	if err := client.UpdateAPIKeysLastUsed(map[int64]time.Time{first.ID: usedAt.Add(-time.Hour)}); err != nil {
+		t.Fatalf("UpdateAPIKeysLastUsed() failed: %v", err)
+	}
+	key, err := client.GetAPIKeyByID(first.ID)
+	if err != nil {
+		t.Fatalf("Failed to get API key: %v", err)
+	}
+	if key.LastUsed == nil || !key.LastUsed.Equal(usedAt) {
+		t.Errorf("Expected last used to stay at %v, got %v", usedAt, key.LastUsed)
+	}
+}
+
 func TestClient_DeleteAPIKey(t *testing.T) {
 	client, cleanup := setupTestDB(t)
 	defer cleanup()
@@ -580,6 +622,63 @@ func TestClient_DeleteAPIKey_NotFound(t *testing.T) {
 	}
 }
 
+func TestClient_RotateAPIKey(t *testing.T) {
+	client, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	user := createTestUserWithDefaults(t, client)
+	key, err := client.CreateAPIKey(user.ID, "rotated-key", "oldhash", nil)
+	if err != nil {
+		t.Fatalf("CreateAPIKey() failed: %v", err)
+	}
+
+	rotated, err := client.RotateAPIKey(key.ID, "newhash", timePtr(time.Now().Add(time.Hour)))
+	if err != nil {
+		t.Fatalf("RotateAPIKey() failed: %v", err)
+	}
+	if rotated == nil || rotated.KeyHash != "newhash" || rotated.PreviousKeyExpiresAt == nil {
+		t.Fatalf("RotateAPIKey() = %+v, want new hash with previous key expiry", rotated)
+	}
+
+	// Both hashes authenticate during the overlap
+	for _, hash := range []string{"oldhash", "newhash"} {
+		found, err := client.GetAPIKeyByHash(hash)
+		if err != nil {
+			t.Fatalf("GetAPIKeyByHash(%q) failed: %v", hash, err)
+		}
+		if found == nil || found.ID != key.ID {
+			t.Errorf("GetAPIKeyByHash(%q) = %v, want key %d", hash, found, key.ID)
+		}
+	}
+
+	// Rotating without overlap revokes the previous hash immediately
+	if _, err := client.RotateAPIKey(key.ID, "newesthash", nil); err != nil {
+		t.Fatalf("RotateAPIKey() failed: %v", err)
+	}
+	for _, hash := range []string{"oldhash", "newhash"} {
+		found, err := client.GetAPIKeyByHash(hash)
+		if err != nil {
+			t.Fatalf("GetAPIKeyByHash(%q) failed: %v", hash, err)
+		}
+		if found != nil {
+			t.Errorf("GetAPIKeyByHash(%q) = %v, want nil after rotation without overlap", hash, found)
+		}
+	}
+}
+
+func TestClient_RotateAPIKey_NotFound(t *testing.T) {
+	client, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	rotated, err := client.RotateAPIKey(99999, "newhash", nil)
+	if err != nil {
+		t.Fatalf("RotateAPIKey() failed: %v", err)
+	}
+	if rotated != nil {
+		t.Errorf("RotateAPIKey() = %v, want nil", rotated)
+	}
+}
+
 func TestClient_DeleteExpiredAPIKeys(t *testing.T) {
 	client, cleanup := setupTestDB(t)
 	defer cleanup()
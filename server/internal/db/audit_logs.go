@@ -0,0 +1,56 @@
+// Package db provides database operations for SupaControl.
+// This file specifically handles audit log operations.
+package db
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// AuditLog represents a recorded security-relevant action
+type AuditLog struct {
+	ID           int64     `db:"id"`
+	UserID       *int64    `db:"user_id"`
+	Action       string    `db:"action"`
+	ResourceType string    `db:"resource_type"`
+	ResourceID   *string   `db:"resource_id"`
+	Details      []byte    `db:"details"`
+	CreatedAt    time.Time `db:"created_at"`
+}
+
+// CreateAuditLog records an action performed by a user against a resource
+func (c *Client) CreateAuditLog(userID int64, action, resourceType, resourceID string, details map[string]string) error {
+	var detailsJSON []byte
+	if len(details) > 0 {
+		var err error
+		detailsJSON, err = json.Marshal(details)
+		if err != nil {
+			return fmt.Errorf("failed to marshal audit log details: %w", err)
+		}
+	}
+
+	query := `
+		INSERT INTO audit_logs (user_id, action, resource_type, resource_id, details)
+		VALUES ($1, $2, $3, $4, $5)
+	`
+
+	if _, err := c.db.Exec(query, userID, action, resourceType, resourceID, detailsJSON); err != nil {
+		return fmt.Errorf("failed to create audit log: %w", err)
+	}
+
+	return nil
+}
+
+// ListAuditLogsByResource retrieves audit log entries for a resource, newest first
+func (c *Client) ListAuditLogsByResource(resourceType, resourceID string) ([]*AuditLog, error) {
+	var logs []*AuditLog
+
+	query := `SELECT * FROM audit_logs WHERE resource_type = $1 AND resource_id = $2 ORDER BY created_at DESC, id DESC`
+
+	if err := c.db.Select(&logs, query, resourceType, resourceID); err != nil {
+		return nil, fmt.Errorf("failed to list audit logs: %w", err)
+	}
+
+	return logs, nil
+}
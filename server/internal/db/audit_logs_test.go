@@ -0,0 +1,54 @@
+package db
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestClient_CreateAuditLog(t *testing.T) {
+	client, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	user := createTestUserWithDefaults(t, client)
+
+	if err := client.CreateAuditLog(user.ID, "instance.credentials.read", "instance", "my-app", map[string]string{"method": "jwt"}); err != nil {
+		t.Fatalf("CreateAuditLog() error = %v", err)
+	}
+	if err := client.CreateAuditLog(user.ID, "instance.credentials.read", "instance", "other-app", nil); err != nil {
+		t.Fatalf("CreateAuditLog() without details error = %v", err)
+	}
+
+	logs, err := client.ListAuditLogsByResource("instance", "my-app")
+	if err != nil {
+		t.Fatalf("ListAuditLogsByResource() error = %v", err)
+	}
+	if len(logs) != 1 {
+		t.Fatalf("Expected 1 audit log, got %d", len(logs))
+	}
+
+	entry := logs[0]
+	if entry.UserID == nil || *entry.UserID != user.ID {
+		t.Errorf("Expected user ID %d, got %v", user.ID, entry.UserID)
+	}
+	if entry.Action != "instance.credentials.read" {
+		t.Errorf("Expected action 'instance.credentials.read', got %s", entry.Action)
+	}
+
+	var details map[string]string
+	if err := json.Unmarshal(entry.Details, &details); err != nil {
+		t.Fatalf("Failed to decode details: %v", err)
+	}
+	if details["method"] != "jwt" {
+		t.Errorf("Expected details method 'jwt', got %s", details["method"])
+	}
+}
+
+func TestClient_CreateAuditLog_InvalidUserID(t *testing.T) {
+	client, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	err := client.CreateAuditLog(99999, "instance.credentials.read", "instance", "my-app", nil)
+	if err == nil {
+		t.Error("Expected error for non-existent user ID")
+	}
+}
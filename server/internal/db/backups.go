@@ -0,0 +1,155 @@
+// Package db provides database operations for SupaControl.
+// This file specifically handles instance database backups.
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+)
+
+// CreateBackup records a backup stored in object storage
+func (c *Client) CreateBackup(instanceName, objectKey, keyID string, sizeBytes int64, createdBy *int64, createdAt time.Time) (*apitypes.Backup, error) {
+	var created apitypes.Backup
+
+	err := c.db.QueryRowx(
+		`INSERT INTO backups (instance_name, object_key, key_id, size_bytes, created_by, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING *`,
+		instanceName, objectKey, keyID, sizeBytes, createdBy, createdAt.UTC(),
+	).StructScan(&created)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create backup: %w", err)
+	}
+
+	return &created, nil
+}
+
+// GetBackup retrieves a backup by ID. Pruned backups are not returned.
+func (c *Client) GetBackup(id int64) (*apitypes.Backup, error) {
+	var backup apitypes.Backup
+
+	err := c.db.Get(&backup, `SELECT * FROM backups WHERE id = $1 AND pruned_at IS NULL`, id)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get backup: %w", err)
+	}
+
+	return &backup, nil
+}
+
+// ListBackups retrieves the backups of an instance that have not been pruned, newest first
+func (c *Client) ListBackups(instanceName string) ([]*apitypes.Backup, error) {
+	backups := []*apitypes.Backup{}
+
+	if err := c.db.Select(&backups,
+		`SELECT * FROM backups WHERE instance_name = $1 AND pruned_at IS NULL ORDER BY created_at DESC, id DESC`,
+		instanceName,
+	); err != nil {
+		return nil, fmt.Errorf("failed to list backups: %w", err)
+	}
+
+	return backups, nil
+}
+
+// SetBackupHeld places or releases the hold on a backup. It returns nil when the backup
+// does not exist or has been pruned.
+func (c *Client) SetBackupHeld(id int64, held bool) (*apitypes.Backup, error) {
+	var backup apitypes.Backup
+
+	err := c.db.QueryRowx(
+		`UPDATE backups SET held = $2 WHERE id = $1 AND pruned_at IS NULL RETURNING *`,
+		id, held,
+	).StructScan(&backup)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to set backup hold: %w", err)
+	}
+
+	return &backup, nil
+}
+
+// MarkBackupPruned marks a backup pruned unless it is held, and reports whether it was.
+// The pruner claims a backup this way before deleting its artifact, so a hold placed in
+// the meantime still protects it.
+func (c *Client) MarkBackupPruned(id int64) (bool, error) {
+	result, err := c.db.Exec(`UPDATE backups SET pruned_at = NOW() WHERE id = $1 AND NOT held AND pruned_at IS NULL`, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to mark backup pruned: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to mark backup pruned: %w", err)
+	}
+
+	return rows > 0, nil
+}
+
+// UnmarkBackupPruned reverts MarkBackupPruned after the artifact could not be deleted,
+// so the next pruning pass retries it
+func (c *Client) UnmarkBackupPruned(id int64) error {
+	if _, err := c.db.Exec(`UPDATE backups SET pruned_at = NULL WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("failed to unmark backup pruned: %w", err)
+	}
+
+	return nil
+}
+
+// GetBackupRetentionPolicy retrieves the retention policy of an instance. It returns nil
+// when none was set.
+func (c *Client) GetBackupRetentionPolicy(instanceName string) (*apitypes.BackupRetentionPolicy, error) {
+	var policy apitypes.BackupRetentionPolicy
+
+	err := c.db.Get(&policy, `SELECT * FROM backup_retention_policies WHERE instance_name = $1`, instanceName)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get backup retention policy: %w", err)
+	}
+
+	return &policy, nil
+}
+
+// ListBackupRetentionPolicies retrieves the retention policies that prune backups, that
+// is those with at least one non-zero rule
+func (c *Client) ListBackupRetentionPolicies() ([]*apitypes.BackupRetentionPolicy, error) {
+	policies := []*apitypes.BackupRetentionPolicy{}
+
+	if err := c.db.Select(&policies,
+		`SELECT * FROM backup_retention_policies
+		WHERE keep_daily > 0 OR keep_weekly > 0 OR keep_monthly > 0
+		ORDER BY instance_name`,
+	); err != nil {
+		return nil, fmt.Errorf("failed to list backup retention policies: %w", err)
+	}
+
+	return policies, nil
+}
+
+// SetBackupRetentionPolicy replaces the retention policy of an instance
+func (c *Client) SetBackupRetentionPolicy(instanceName string, keepDaily, keepWeekly, keepMonthly int) (*apitypes.BackupRetentionPolicy, error) {
+	var policy apitypes.BackupRetentionPolicy
+
+	err := c.db.QueryRowx(
+		`INSERT INTO backup_retention_policies (instance_name, keep_daily, keep_weekly, keep_monthly)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (instance_name) DO UPDATE
+		SET keep_daily = EXCLUDED.keep_daily, keep_weekly = EXCLUDED.keep_weekly,
+			keep_monthly = EXCLUDED.keep_monthly, updated_at = NOW()
+		RETURNING *`,
+		instanceName, keepDaily, keepWeekly, keepMonthly,
+	).StructScan(&policy)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set backup retention policy: %w", err)
+	}
+
+	return &policy, nil
+}
@@ -0,0 +1,143 @@
+package db
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClient_Backups(t *testing.T) {
+	client, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	user := createTestUserWithDefaults(t, client)
+	now := time.Now().UTC().Truncate(time.Second)
+
+	older, err := client.CreateBackup("alpha", "backups/alpha/1.sql.enc", "2025", 1024, &user.ID, now.Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("CreateBackup() error = %v", err)
+	}
+	newer, err := client.CreateBackup("alpha", "backups/alpha/2.sql.enc", "2026", 2048, nil, now)
+	if err != nil {
+		t.Fatalf("CreateBackup() error = %v", err)
+	}
+	if _, err := client.CreateBackup("beta", "backups/beta/1.sql.enc", "2026", 512, nil, now); err != nil {
+		t.Fatalf("CreateBackup() error = %v", err)
+	}
+
+	backups, err := client.ListBackups("alpha")
+	if err != nil {
+		t.Fatalf("ListBackups() error = %v", err)
+	}
+	if len(backups) != 2 || backups[0].ID != newer.ID || backups[1].ID != older.ID {
+		t.Fatalf("Expected alpha's backups newest first, got %+v", backups)
+	}
+
+	got, err := client.GetBackup(older.ID)
+	if err != nil {
+		t.Fatalf("GetBackup() error = %v", err)
+	}
+	if got == nil || got.KeyID != "2025" || got.SizeBytes != 1024 || got.ObjectKey != "backups/alpha/1.sql.enc" ||
+		got.CreatedBy == nil || *got.CreatedBy != user.ID || !got.CreatedAt.Equal(now.Add(-time.Hour)) {
+		t.Errorf("Unexpected backup %+v", got)
+	}
+
+	missing, err := client.GetBackup(older.ID + 1000)
+	if err != nil {
+		t.Fatalf("GetBackup() error = %v", err)
+	}
+	if missing != nil {
+		t.Errorf("Expected nil for a missing backup, got %+v", missing)
+	}
+}
+
+func TestClient_BackupRetention(t *testing.T) {
+	client, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	now := time.Now().UTC().Truncate(time.Second)
+	held, err := client.CreateBackup("alpha", "backups/alpha/1.sql.enc", "2026", 1024, nil, now.Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("CreateBackup() error = %v", err)
+	}
+	expired, err := client.CreateBackup("alpha", "backups/alpha/2.sql.enc", "2026", 1024, nil, now)
+	if err != nil {
+		t.Fatalf("CreateBackup() error = %v", err)
+	}
+
+	updated, err := client.SetBackupHeld(held.ID, true)
+	if err != nil {
+		t.Fatalf("SetBackupHeld() error = %v", err)
+	}
+	if updated == nil || !updated.Held {
+		t.Fatalf("Expected the backup to be held, got %+v", updated)
+	}
+
+	// Held backups cannot be claimed by the pruner
+	if claimed, err := client.MarkBackupPruned(held.ID); err != nil || claimed {
+		t.Errorf("MarkBackupPruned(held) = %v, %v, want false", claimed, err)
+	}
+	if claimed, err := client.MarkBackupPruned(expired.ID); err != nil || !claimed {
+		t.Fatalf("MarkBackupPruned() = %v, %v, want true", claimed, err)
+	}
+	if claimed, err := client.MarkBackupPruned(expired.ID); err != nil || claimed {
+		t.Errorf("MarkBackupPruned(pruned) = %v, %v, want false", claimed, err)
+	}
+
+	// Pruned backups are no longer listed, fetched or held
+	backups, err := client.ListBackups("alpha")
+	if err != nil {
+		t.Fatalf("ListBackups() error = %v", err)
+	}
+	if len(backups) != 1 || backups[0].ID != held.ID {
+		t.Errorf("Expected only the held backup, got %+v", backups)
+	}
+	if got, err := client.GetBackup(expired.ID); err != nil || got != nil {
+		t.Errorf("GetBackup(pruned) = %+v, %v, want nil", got, err)
+	}
+	if got, err := client.SetBackupHeld(expired.ID, true); err != nil || got != nil {
+		t.Errorf("SetBackupHeld(pruned) = %+v, %v, want nil", got, err)
+	}
+
+	if err := client.UnmarkBackupPruned(expired.ID); err != nil {
+		t.Fatalf("UnmarkBackupPruned() error = %v", err)
+	}
+	if got, err := client.GetBackup(expired.ID); err != nil || got == nil {
+		t.Errorf("Expected the unmarked backup to be back, got %+v, %v", got, err)
+	}
+}
+
+func TestClient_BackupRetentionPolicies(t *testing.T) {
+	client, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	policy, err := client.GetBackupRetentionPolicy("alpha")
+	if err != nil {
+		t.Fatalf("GetBackupRetentionPolicy() error = %v", err)
+	}
+	if policy != nil {
+		t.Errorf("Expected no policy, got %+v", policy)
+	}
+
+	if _, err := client.SetBackupRetentionPolicy("alpha", 7, 0, 0); err != nil {
+		t.Fatalf("SetBackupRetentionPolicy() error = %v", err)
+	}
+	policy, err = client.SetBackupRetentionPolicy("alpha", 7, 4, 12)
+	if err != nil {
+		t.Fatalf("SetBackupRetentionPolicy() error = %v", err)
+	}
+	if policy.KeepDaily != 7 || policy.KeepWeekly != 4 || policy.KeepMonthly != 12 || policy.UpdatedAt == nil {
+		t.Errorf("Unexpected policy %+v", policy)
+	}
+	if _, err := client.SetBackupRetentionPolicy("beta", 0, 0, 0); err != nil {
+		t.Fatalf("SetBackupRetentionPolicy() error = %v", err)
+	}
+
+	// Policies without rules prune nothing and are left out
+	policies, err := client.ListBackupRetentionPolicies()
+	if err != nil {
+		t.Fatalf("ListBackupRetentionPolicies() error = %v", err)
+	}
+	if len(policies) != 1 || policies[0].InstanceName != "alpha" {
+		t.Errorf("Expected only alpha's policy, got %+v", policies)
+	}
+}
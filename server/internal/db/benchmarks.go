@@ -0,0 +1,97 @@
+// Package db provides database operations for SupaControl.
+// This file specifically handles instance benchmarks.
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+)
+
+// ErrBenchmarkInProgress is returned when a benchmark is created for an instance that
+// already has one running
+var ErrBenchmarkInProgress = errors.New("a benchmark is already running for this instance")
+
+// CreateBenchmark creates a running benchmark
+func (c *Client) CreateBenchmark(benchmark *apitypes.Benchmark) (*apitypes.Benchmark, error) {
+	var created apitypes.Benchmark
+
+	err := c.db.QueryRowx(
+		`INSERT INTO benchmarks (instance_name, namespace, job_name, status, clients, duration_seconds, scale, storage_class, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING *`,
+		benchmark.InstanceName, benchmark.Namespace, benchmark.JobName, apitypes.BenchmarkStatusRunning,
+		benchmark.Clients, benchmark.DurationSeconds, benchmark.Scale, benchmark.StorageClass, benchmark.CreatedBy,
+	).StructScan(&created)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation && pqErr.Constraint == "idx_benchmarks_single_running" {
+			return nil, ErrBenchmarkInProgress
+		}
+		return nil, fmt.Errorf("failed to create benchmark: %w", err)
+	}
+
+	return &created, nil
+}
+
+// GetBenchmark retrieves a benchmark by ID
+func (c *Client) GetBenchmark(id int64) (*apitypes.Benchmark, error) {
+	var benchmark apitypes.Benchmark
+
+	err := c.db.Get(&benchmark, `SELECT * FROM benchmarks WHERE id = $1`, id)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get benchmark: %w", err)
+	}
+
+	return &benchmark, nil
+}
+
+// ListBenchmarks retrieves the benchmarks of an instance, newest first
+func (c *Client) ListBenchmarks(instanceName string) ([]*apitypes.Benchmark, error) {
+	var benchmarks []*apitypes.Benchmark
+
+	if err := c.db.Select(&benchmarks,
+		`SELECT * FROM benchmarks WHERE instance_name = $1 ORDER BY created_at DESC, id DESC`, instanceName,
+	); err != nil {
+		return nil, fmt.Errorf("failed to list benchmarks: %w", err)
+	}
+
+	return benchmarks, nil
+}
+
+// ListRunningBenchmarks retrieves benchmarks that have not finished yet
+func (c *Client) ListRunningBenchmarks() ([]*apitypes.Benchmark, error) {
+	var benchmarks []*apitypes.Benchmark
+
+	if err := c.db.Select(&benchmarks,
+		`SELECT * FROM benchmarks WHERE status = $1 ORDER BY id`, apitypes.BenchmarkStatusRunning,
+	); err != nil {
+		return nil, fmt.Errorf("failed to list running benchmarks: %w", err)
+	}
+
+	return benchmarks, nil
+}
+
+// FinishBenchmark saves the status, results and error of a finished benchmark
+func (c *Client) FinishBenchmark(benchmark *apitypes.Benchmark) error {
+	query := `
+		UPDATE benchmarks
+		SET status = $1, transactions = $2, transactions_per_second = $3, latency_average_ms = $4,
+			error_message = $5, completed_at = NOW()
+		WHERE id = $6
+	`
+
+	if _, err := c.db.Exec(query, benchmark.Status, benchmark.Transactions, benchmark.TransactionsPerSecond,
+		benchmark.LatencyAverageMS, benchmark.ErrorMessage, benchmark.ID); err != nil {
+		return fmt.Errorf("failed to finish benchmark: %w", err)
+	}
+
+	return nil
+}
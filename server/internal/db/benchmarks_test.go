@@ -0,0 +1,77 @@
+package db
+
+import (
+	"errors"
+	"testing"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+)
+
+func TestClient_Benchmarks(t *testing.T) {
+	client, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	user := createTestUserWithDefaults(t, client)
+
+	benchmark, err := client.CreateBenchmark(&apitypes.Benchmark{
+		InstanceName:    "alpha",
+		Namespace:       "supa-alpha",
+		JobName:         "alpha-benchmark-1",
+		Clients:         10,
+		DurationSeconds: 60,
+		Scale:           10,
+		StorageClass:    "fast-ssd",
+		CreatedBy:       &user.ID,
+	})
+	if err != nil {
+		t.Fatalf("CreateBenchmark() error = %v", err)
+	}
+	if benchmark.Status != apitypes.BenchmarkStatusRunning {
+		t.Errorf("Expected status running, got %s", benchmark.Status)
+	}
+
+	// Only one benchmark may run against an instance at a time
+	_, err = client.CreateBenchmark(&apitypes.Benchmark{InstanceName: "alpha", Namespace: "supa-alpha", JobName: "alpha-benchmark-2", Clients: 1, DurationSeconds: 10, Scale: 1})
+	if !errors.Is(err, ErrBenchmarkInProgress) {
+		t.Errorf("Expected ErrBenchmarkInProgress, got %v", err)
+	}
+
+	running, err := client.ListRunningBenchmarks()
+	if err != nil {
+		t.Fatalf("ListRunningBenchmarks() error = %v", err)
+	}
+	if len(running) != 1 || running[0].ID != benchmark.ID {
+		t.Errorf("Expected the new benchmark to be running, got %+v", running)
+	}
+
+	tps, latency, transactions := 1234.5, 8.1, int64(74070)
+	benchmark.Status = apitypes.BenchmarkStatusSucceeded
+	benchmark.TransactionsPerSecond = &tps
+	benchmark.LatencyAverageMS = &latency
+	benchmark.Transactions = &transactions
+	if err := client.FinishBenchmark(benchmark); err != nil {
+		t.Fatalf("FinishBenchmark() error = %v", err)
+	}
+
+	finished, err := client.GetBenchmark(benchmark.ID)
+	if err != nil {
+		t.Fatalf("GetBenchmark() error = %v", err)
+	}
+	if finished.Status != apitypes.BenchmarkStatusSucceeded || finished.CompletedAt == nil ||
+		finished.TransactionsPerSecond == nil || *finished.TransactionsPerSecond != tps {
+		t.Errorf("Expected succeeded benchmark with results, got %+v", finished)
+	}
+
+	// A finished benchmark no longer blocks new ones
+	if _, err := client.CreateBenchmark(&apitypes.Benchmark{InstanceName: "alpha", Namespace: "supa-alpha", JobName: "alpha-benchmark-3", Clients: 1, DurationSeconds: 10, Scale: 1}); err != nil {
+		t.Errorf("Expected new benchmark after the first finished, got %v", err)
+	}
+
+	benchmarks, err := client.ListBenchmarks("alpha")
+	if err != nil {
+		t.Fatalf("ListBenchmarks() error = %v", err)
+	}
+	if len(benchmarks) != 2 || benchmarks[1].ID != benchmark.ID {
+		t.Errorf("Expected two benchmarks, newest first, got %+v", benchmarks)
+	}
+}
@@ -0,0 +1,50 @@
+// Package db provides database operations for SupaControl.
+// This file specifically handles the catalog of indexed Supabase chart versions.
+package db
+
+import (
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+)
+
+// SyncChartVersions replaces the catalog with versions, given newest first. Versions
+// already known keep their first-seen time; versions no longer listed are removed.
+func (c *Client) SyncChartVersions(versions []string) error {
+	err := c.WithinTransaction(func(tx *sqlx.Tx) error {
+		for i, version := range versions {
+			if _, err := tx.Exec(
+				`INSERT INTO chart_versions (version, position) VALUES ($1, $2)
+				ON CONFLICT (version) DO UPDATE SET position = EXCLUDED.position, last_seen_at = NOW()`,
+				version, i,
+			); err != nil {
+				return err
+			}
+		}
+
+		_, err := tx.Exec(`DELETE FROM chart_versions WHERE NOT (version = ANY($1))`, pq.Array(versions))
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to sync chart versions: %w", err)
+	}
+
+	return nil
+}
+
+// ListChartVersions retrieves the indexed chart versions, newest first
+func (c *Client) ListChartVersions() ([]*apitypes.ChartVersion, error) {
+	var versions []*apitypes.ChartVersion
+
+	if err := c.db.Select(&versions, `SELECT * FROM chart_versions ORDER BY position`); err != nil {
+		return nil, fmt.Errorf("failed to list chart versions: %w", err)
+	}
+	if len(versions) > 0 {
+		versions[0].Latest = true
+	}
+
+	return versions, nil
+}
@@ -0,0 +1,41 @@
+package db
+
+import (
+	"testing"
+)
+
+func TestClient_SyncChartVersions(t *testing.T) {
+	client, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	if err := client.SyncChartVersions([]string{"0.2.0", "0.1.1", "0.1.0"}); err != nil {
+		t.Fatalf("SyncChartVersions() error = %v", err)
+	}
+	before, err := client.ListChartVersions()
+	if err != nil {
+		t.Fatalf("ListChartVersions() error = %v", err)
+	}
+
+	// A new release is indexed and a yanked one disappears
+	if err := client.SyncChartVersions([]string{"0.3.0", "0.2.0", "0.1.0"}); err != nil {
+		t.Fatalf("SyncChartVersions() error = %v", err)
+	}
+	versions, err := client.ListChartVersions()
+	if err != nil {
+		t.Fatalf("ListChartVersions() error = %v", err)
+	}
+
+	got := make([]string, 0, len(versions))
+	for _, version := range versions {
+		got = append(got, version.Version)
+	}
+	if len(got) != 3 || got[0] != "0.3.0" || got[1] != "0.2.0" || got[2] != "0.1.0" {
+		t.Fatalf("Expected [0.3.0 0.2.0 0.1.0], got %v", got)
+	}
+	if !versions[0].Latest || versions[1].Latest {
+		t.Errorf("Expected only the newest version to be marked latest")
+	}
+	if !versions[1].FirstSeenAt.Equal(before[0].FirstSeenAt) {
+		t.Errorf("Expected 0.2.0 to keep its first-seen time, got %v and %v", before[0].FirstSeenAt, versions[1].FirstSeenAt)
+	}
+}
@@ -0,0 +1,119 @@
+// Package db provides database operations for SupaControl.
+// This file specifically handles connections between instances.
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+)
+
+var (
+	// ErrConnectionExists is returned when a connection is requested between instances
+	// that already have a pending or active one
+	ErrConnectionExists = errors.New("a connection between these instances already exists")
+
+	// ErrConnectionNotPending is returned when a connection that is already active or
+	// revoked is approved
+	ErrConnectionNotPending = errors.New("connection is no longer pending")
+)
+
+// CreateConnection creates a pending connection, approved on the sides the requester owns
+func (c *Client) CreateConnection(source, target string, requestedBy int64, approveSource, approveTarget bool) (*apitypes.Connection, error) {
+	var created apitypes.Connection
+
+	err := c.db.QueryRowx(
+		`INSERT INTO instance_connections (source_instance, target_instance, status, requested_by, source_approved_at, target_approved_at)
+		VALUES ($1, $2, $3, $4, CASE WHEN $5 THEN NOW() END, CASE WHEN $6 THEN NOW() END)
+		RETURNING *`,
+		source, target, apitypes.ConnectionStatusPending, requestedBy, approveSource, approveTarget,
+	).StructScan(&created)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation && pqErr.Constraint == "idx_instance_connections_single_open" {
+			return nil, ErrConnectionExists
+		}
+		return nil, fmt.Errorf("failed to create connection: %w", err)
+	}
+
+	return &created, nil
+}
+
+// GetConnection retrieves a connection by ID
+func (c *Client) GetConnection(id int64) (*apitypes.Connection, error) {
+	var connection apitypes.Connection
+
+	err := c.db.Get(&connection, `SELECT * FROM instance_connections WHERE id = $1`, id)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get connection: %w", err)
+	}
+
+	return &connection, nil
+}
+
+// ListConnections retrieves all connections, newest first
+func (c *Client) ListConnections() ([]*apitypes.Connection, error) {
+	var connections []*apitypes.Connection
+
+	if err := c.db.Select(&connections,
+		`SELECT * FROM instance_connections ORDER BY created_at DESC, id DESC`,
+	); err != nil {
+		return nil, fmt.Errorf("failed to list connections: %w", err)
+	}
+
+	return connections, nil
+}
+
+// ApproveConnection records the approval of a pending connection's source and/or target
+// side. Sides approved before keep their approval time.
+func (c *Client) ApproveConnection(id int64, approveSource, approveTarget bool) (*apitypes.Connection, error) {
+	var connection apitypes.Connection
+
+	err := c.db.QueryRowx(
+		`UPDATE instance_connections
+		SET source_approved_at = CASE WHEN $2 THEN COALESCE(source_approved_at, NOW()) ELSE source_approved_at END,
+			target_approved_at = CASE WHEN $3 THEN COALESCE(target_approved_at, NOW()) ELSE target_approved_at END
+		WHERE id = $1 AND status = $4
+		RETURNING *`,
+		id, approveSource, approveTarget, apitypes.ConnectionStatusPending,
+	).StructScan(&connection)
+	if err == sql.ErrNoRows {
+		return nil, ErrConnectionNotPending
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to approve connection: %w", err)
+	}
+
+	return &connection, nil
+}
+
+// ActivateConnection marks an approved connection active once its target allows it
+func (c *Client) ActivateConnection(id int64) error {
+	if _, err := c.db.Exec(
+		`UPDATE instance_connections SET status = $1, activated_at = NOW() WHERE id = $2 AND status = $3`,
+		apitypes.ConnectionStatusActive, id, apitypes.ConnectionStatusPending,
+	); err != nil {
+		return fmt.Errorf("failed to activate connection: %w", err)
+	}
+
+	return nil
+}
+
+// RevokeConnection revokes a pending or active connection
+func (c *Client) RevokeConnection(id int64) error {
+	if _, err := c.db.Exec(
+		`UPDATE instance_connections SET status = $1, revoked_at = NOW() WHERE id = $2 AND status IN ($3, $4)`,
+		apitypes.ConnectionStatusRevoked, id, apitypes.ConnectionStatusPending, apitypes.ConnectionStatusActive,
+	); err != nil {
+		return fmt.Errorf("failed to revoke connection: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,72 @@
+package db
+
+import (
+	"errors"
+	"testing"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+)
+
+func TestClient_Connections(t *testing.T) {
+	client, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	user := createTestUserWithDefaults(t, client)
+
+	connection, err := client.CreateConnection("analytics", "app", user.ID, true, false)
+	if err != nil {
+		t.Fatalf("CreateConnection() error = %v", err)
+	}
+	if connection.Status != apitypes.ConnectionStatusPending || connection.SourceApprovedAt == nil || connection.TargetApprovedAt != nil {
+		t.Errorf("Expected a pending connection approved by its source, got %+v", connection)
+	}
+
+	// An instance pair has at most one open connection
+	_, err = client.CreateConnection("analytics", "app", user.ID, false, true)
+	if !errors.Is(err, ErrConnectionExists) {
+		t.Errorf("Expected ErrConnectionExists, got %v", err)
+	}
+
+	approved, err := client.ApproveConnection(connection.ID, false, true)
+	if err != nil {
+		t.Fatalf("ApproveConnection() error = %v", err)
+	}
+	if !approved.Approved() || !approved.SourceApprovedAt.Equal(*connection.SourceApprovedAt) {
+		t.Errorf("Expected both sides approved with the source approval kept, got %+v", approved)
+	}
+
+	if err := client.ActivateConnection(connection.ID); err != nil {
+		t.Fatalf("ActivateConnection() error = %v", err)
+	}
+	if _, err := client.ApproveConnection(connection.ID, true, true); !errors.Is(err, ErrConnectionNotPending) {
+		t.Errorf("Expected ErrConnectionNotPending for an active connection, got %v", err)
+	}
+
+	if err := client.RevokeConnection(connection.ID); err != nil {
+		t.Fatalf("RevokeConnection() error = %v", err)
+	}
+	revoked, err := client.GetConnection(connection.ID)
+	if err != nil {
+		t.Fatalf("GetConnection() error = %v", err)
+	}
+	if revoked.Status != apitypes.ConnectionStatusRevoked || revoked.ActivatedAt == nil || revoked.RevokedAt == nil {
+		t.Errorf("Expected a revoked connection, got %+v", revoked)
+	}
+
+	// Revoked connections don't block a new request for the same pair
+	if _, err := client.CreateConnection("analytics", "app", user.ID, true, true); err != nil {
+		t.Errorf("CreateConnection() after revoke error = %v", err)
+	}
+	connections, err := client.ListConnections()
+	if err != nil {
+		t.Fatalf("ListConnections() error = %v", err)
+	}
+	if len(connections) != 2 || connections[0].Status != apitypes.ConnectionStatusPending {
+		t.Errorf("Expected the new connection first, got %+v", connections)
+	}
+
+	missing, err := client.GetConnection(9999)
+	if err != nil || missing != nil {
+		t.Errorf("Expected no connection for an unknown ID, got %+v, %v", missing, err)
+	}
+}
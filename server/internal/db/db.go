@@ -1,14 +1,15 @@
 package db
 
 import (
+	"context"
 	"database/sql"
+	"embed"
 	"fmt"
 	"log/slog"
-	"os"
-	"path/filepath"
 
 	"github.com/jmoiron/sqlx"
 	_ "github.com/lib/pq" // PostgreSQL driver
+	migrate "github.com/rubenv/sql-migrate"
 )
 
 // Client wraps the database connection
@@ -40,27 +41,59 @@ func (c *Client) GetDB() *sqlx.DB {
 	return c.db
 }
 
-// RunMigrations runs all SQL migrations from the migrations directory
-func (c *Client) RunMigrations(migrationsPath string) error {
-	files, err := filepath.Glob(filepath.Join(migrationsPath, "*.sql"))
-	if err != nil {
-		return fmt.Errorf("failed to read migration files: %w", err)
+// MigrationsTable records which migrations have been applied to the database
+const MigrationsTable = "schema_migrations"
+
+// migrationLockID identifies the advisory lock held while migrating, so that replicas
+// starting together don't apply the same migrations concurrently
+const migrationLockID = 7283461
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// migrations are the schema migrations built into the server
+var migrations = migrate.EmbedFileSystemMigrationSource{FileSystem: migrationFiles, Root: "migrations"}
+
+// RunMigrations applies the migrations that haven't been applied yet and returns how
+// many were applied
+func (c *Client) RunMigrations() (int, error) {
+	return c.migrate(migrations, migrate.Up, 0)
+}
+
+// RollbackMigrations reverts the last n applied migrations and returns how many were
+// reverted
+func (c *Client) RollbackMigrations(n int) (int, error) {
+	if n < 1 {
+		return 0, fmt.Errorf("number of migrations to roll back must be at least 1")
 	}
+	return c.migrate(migrations, migrate.Down, n)
+}
 
-	for _, file := range files {
-		content, err := os.ReadFile(file)
-		if err != nil {
-			return fmt.Errorf("failed to read migration file %s: %w", file, err)
-		}
+// migrate applies up to max migrations (0 for all) from source in the given direction,
+// holding the migration lock
+func (c *Client) migrate(source migrate.MigrationSource, dir migrate.MigrationDirection, max int) (int, error) {
+	ctx := context.Background()
+	conn, err := c.db.Conn(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to acquire connection: %w", err)
+	}
+	defer conn.Close()
 
-		if _, err := c.db.Exec(string(content)); err != nil {
-			return fmt.Errorf("failed to execute migration %s: %w", file, err)
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", migrationLockID); err != nil {
+		return 0, fmt.Errorf("failed to acquire migration lock: %w", err)
+	}
+	defer func() {
+		if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", migrationLockID); err != nil {
+			slog.Error("Failed to release migration lock", "error", err)
 		}
+	}()
 
-		fmt.Printf("Successfully executed migration: %s\n", filepath.Base(file))
+	set := migrate.MigrationSet{TableName: MigrationsTable}
+	n, err := set.ExecMax(c.db.DB, "postgres", source, dir, max)
+	if err != nil {
+		return n, fmt.Errorf("failed to run migrations: %w", err)
 	}
-
-	return nil
+	return n, nil
 }
 
 // Ping checks if the database connection is alive
@@ -103,14 +136,28 @@ func (c *Client) WithinTransaction(fn func(*sqlx.Tx) error) error {
 	return nil
 }
 
+// Authentication providers of users
+const (
+	// AuthProviderLocal users sign in with their password
+	AuthProviderLocal = "local"
+
+	// AuthProviderSAML users sign in through SAML single sign-on and have no password
+	AuthProviderSAML = "saml"
+)
+
 // User represents a user in the database
 type User struct {
 	ID           int64  `db:"id"`
 	Username     string `db:"username"`
 	PasswordHash string `db:"password_hash"`
 	Role         string `db:"role"`
-	CreatedAt    string `db:"created_at"`
-	UpdatedAt    string `db:"updated_at"`
+	AuthProvider string `db:"auth_provider"`
+	// Email is the address of the first team invitation the user accepted, if any
+	Email *string `db:"email"`
+	// MustChangePassword restricts the user to changing their password until they do
+	MustChangePassword bool   `db:"must_change_password"`
+	CreatedAt          string `db:"created_at"`
+	UpdatedAt          string `db:"updated_at"`
 }
 
 // GetUserByUsername retrieves a user by username
@@ -126,6 +173,19 @@ func (c *Client) GetUserByUsername(username string) (*User, error) {
 	return &user, nil
 }
 
+// GetUserByEmail retrieves the user owning an email address, ignoring case
+func (c *Client) GetUserByEmail(email string) (*User, error) {
+	var user User
+	err := c.db.Get(&user, "SELECT * FROM users WHERE LOWER(email) = LOWER($1)", email)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	return &user, nil
+}
+
 // GetUserByID retrieves a user by ID
 func (c *Client) GetUserByID(id int64) (*User, error) {
 	var user User
@@ -153,3 +213,83 @@ func (c *Client) CreateUser(username, passwordHash, role string) (*User, error)
 	}
 	return &user, nil
 }
+
+// CreateExternalUser creates a user who signs in through an external authentication
+// provider and therefore has no password
+func (c *Client) CreateExternalUser(username, authProvider, role string) (*User, error) {
+	var user User
+	err := c.db.QueryRowx(
+		`INSERT INTO users (username, password_hash, role, auth_provider)
+		 VALUES ($1, '', $2, $3)
+		 RETURNING *`,
+		username, role, authProvider,
+	).StructScan(&user)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create user: %w", err)
+	}
+	return &user, nil
+}
+
+// CreateBootstrapAdmin creates the first admin account, which must change its password on
+// first login. It returns nil without creating the account when any user already exists,
+// so concurrently starting servers create it at most once.
+func (c *Client) CreateBootstrapAdmin(username, passwordHash string) (*User, error) {
+	var user User
+	err := c.db.QueryRowx(
+		`INSERT INTO users (username, password_hash, role, must_change_password)
+		 SELECT $1, $2, 'admin', true
+		 WHERE NOT EXISTS (SELECT 1 FROM users)
+		 ON CONFLICT (username) DO NOTHING
+		 RETURNING *`,
+		username, passwordHash,
+	).StructScan(&user)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create bootstrap admin: %w", err)
+	}
+	return &user, nil
+}
+
+// UpdateUserPassword sets a user's password and lifts any requirement to change it
+func (c *Client) UpdateUserPassword(id int64, passwordHash string) error {
+	result, err := c.db.Exec(
+		"UPDATE users SET password_hash = $1, must_change_password = false, updated_at = NOW() WHERE id = $2",
+		passwordHash, id,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update user password: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("user not found")
+	}
+	return nil
+}
+
+// UpdateUserRole changes the role of a user
+func (c *Client) UpdateUserRole(id int64, role string) error {
+	result, err := c.db.Exec(
+		"UPDATE users SET role = $1, updated_at = NOW() WHERE id = $2",
+		role, id,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update user role: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("user not found")
+	}
+	return nil
+}
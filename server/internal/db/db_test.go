@@ -4,11 +4,11 @@ import (
 	"errors"
 	"fmt"
 	"os"
-	"path/filepath"
 	"strings"
 	"testing"
 
 	"github.com/jmoiron/sqlx"
+	migrate "github.com/rubenv/sql-migrate"
 )
 
 func TestNewClient(t *testing.T) {
@@ -157,6 +157,20 @@ func TestClient_CreateUser(t *testing.T) {
 			role:         "operator",
 			wantErr:      false,
 		},
+		{
+			name:         "valid viewer user",
+			username:     "viewer1",
+			passwordHash: "hash321",
+			role:         "viewer",
+			wantErr:      false,
+		},
+		{
+			name:         "unknown role",
+			username:     "guest1",
+			passwordHash: "hash654",
+			role:         "guest",
+			wantErr:      true,
+		},
 		{
 			name:         "duplicate username",
 			username:     "duplicate",
@@ -398,6 +412,104 @@ func TestClient_GetUserByID(t *testing.T) {
 	})
 }
 
+func TestClient_CreateExternalUser(t *testing.T) {
+	client, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	local := createTestUser(t, client, "local", "testhash", "user")
+	if local.AuthProvider != AuthProviderLocal {
+		t.Errorf("AuthProvider = %v, want %v", local.AuthProvider, AuthProviderLocal)
+	}
+
+	user, err := client.CreateExternalUser("jane@example.com", AuthProviderSAML, "operator")
+	if err != nil {
+		t.Fatalf("CreateExternalUser() error = %v", err)
+	}
+	if user.AuthProvider != AuthProviderSAML || user.Role != "operator" || user.PasswordHash != "" {
+		t.Errorf("unexpected user %+v", user)
+	}
+
+	if _, err := client.CreateExternalUser("local", AuthProviderSAML, "user"); err == nil {
+		t.Error("Expected error for duplicate username")
+	}
+}
+
+func TestClient_UpdateUserRole(t *testing.T) {
+	client, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	created := createTestUser(t, client, "testuser", "testhash", "user")
+	if err := client.UpdateUserRole(created.ID, "admin"); err != nil {
+		t.Fatalf("UpdateUserRole() error = %v", err)
+	}
+
+	user, err := client.GetUserByID(created.ID)
+	if err != nil {
+		t.Fatalf("GetUserByID() error = %v", err)
+	}
+	if user.Role != "admin" {
+		t.Errorf("Role = %v, want admin", user.Role)
+	}
+
+	if err := client.UpdateUserRole(99999, "admin"); err == nil {
+		t.Error("Expected error for non-existent user")
+	}
+	if err := client.UpdateUserRole(created.ID, "guest"); err == nil {
+		t.Error("Expected error for unknown role")
+	}
+}
+
+func TestClient_CreateBootstrapAdmin(t *testing.T) {
+	client, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	if _, err := client.db.Exec("DELETE FROM users"); err != nil {
+		t.Fatalf("failed to empty users: %v", err)
+	}
+
+	admin, err := client.CreateBootstrapAdmin("admin", "testhash")
+	if err != nil {
+		t.Fatalf("CreateBootstrapAdmin() error = %v", err)
+	}
+	if admin == nil || admin.Role != "admin" || !admin.MustChangePassword {
+		t.Fatalf("unexpected admin %+v", admin)
+	}
+
+	// Only the first account is bootstrapped
+	again, err := client.CreateBootstrapAdmin("other", "testhash")
+	if err != nil {
+		t.Fatalf("CreateBootstrapAdmin() error = %v", err)
+	}
+	if again != nil {
+		t.Errorf("expected no admin once users exist, got %+v", again)
+	}
+}
+
+func TestClient_UpdateUserPassword(t *testing.T) {
+	client, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	created := createTestUser(t, client, "testuser", "testhash", "user")
+	if _, err := client.db.Exec("UPDATE users SET must_change_password = true WHERE id = $1", created.ID); err != nil {
+		t.Fatalf("failed to flag user: %v", err)
+	}
+	if err := client.UpdateUserPassword(created.ID, "newhash"); err != nil {
+		t.Fatalf("UpdateUserPassword() error = %v", err)
+	}
+
+	user, err := client.GetUserByID(created.ID)
+	if err != nil {
+		t.Fatalf("GetUserByID() error = %v", err)
+	}
+	if user.PasswordHash != "newhash" || user.MustChangePassword {
+		t.Errorf("unexpected user %+v", user)
+	}
+
+	if err := client.UpdateUserPassword(99999, "newhash"); err == nil {
+		t.Error("Expected error for non-existent user")
+	}
+}
+
 func TestClient_WithinTransaction_Success(t *testing.T) {
 	client, cleanup := setupTestDB(t)
 	defer cleanup()
@@ -649,20 +761,26 @@ func TestClient_RunMigrations(t *testing.T) {
 	}()
 
 	// Clean all tables first
-	tables := []string{"api_keys", "users"}
+	tables := []string{"api_keys", "users", MigrationsTable}
 	for _, table := range tables {
 		_, _ = client.db.Exec(fmt.Sprintf("DROP TABLE IF EXISTS %s CASCADE", table))
 	}
 
 	// Test migration
-	migrationsPath := "migrations"
-	err = client.RunMigrations(migrationsPath)
+	applied, err := client.RunMigrations()
 	if err != nil {
 		t.Fatalf("RunMigrations() failed: %v", err)
 	}
+	files, err := migrationFiles.ReadDir("migrations")
+	if err != nil {
+		t.Fatalf("Failed to read migrations: %v", err)
+	}
+	if applied != len(files) {
+		t.Errorf("Expected %d migrations to be applied, got %d", len(files), applied)
+	}
 
 	// Verify tables exist
-	expectedTables := []string{"users", "api_keys"}
+	expectedTables := []string{"users", "api_keys", MigrationsTable}
 	for _, table := range expectedTables {
 		var exists bool
 		err := client.db.Get(&exists,
@@ -679,76 +797,61 @@ func TestClient_RunMigrations(t *testing.T) {
 		}
 	}
 
-	// Test running migrations again (should be idempotent)
-	err = client.RunMigrations(migrationsPath)
+	// Applied migrations are recorded and not run again
+	applied, err = client.RunMigrations()
 	if err != nil {
 		t.Errorf("RunMigrations() second run failed: %v", err)
 	}
-}
-
-func TestClient_RunMigrations_InvalidPath(t *testing.T) {
-	client, cleanup := setupTestDB(t)
-	defer cleanup()
-
-	err := client.RunMigrations("/nonexistent/path")
-	if err == nil {
-		t.Error("Expected error for invalid migrations path")
+	if applied != 0 {
+		t.Errorf("Expected no migrations on second run, got %d", applied)
 	}
 }
 
-func TestClient_RunMigrations_InvalidSQL(t *testing.T) {
+func TestClient_RollbackMigrations(t *testing.T) {
 	client, cleanup := setupTestDB(t)
 	defer cleanup()
 
-	// Create a temporary directory for test migrations
-	tempDir := t.TempDir()
+	// Roll back the latest migration and apply it again
+	reverted, err := client.RollbackMigrations(1)
+	if err != nil {
+		t.Fatalf("RollbackMigrations() failed: %v", err)
+	}
+	if reverted != 1 {
+		t.Errorf("Expected 1 migration to be reverted, got %d", reverted)
+	}
 
-	// Create a migration file with invalid SQL
-	invalidSQL := "INVALID SQL STATEMENT THAT WILL FAIL"
-	migrationFile := filepath.Join(tempDir, "001_invalid.sql")
-	err := os.WriteFile(migrationFile, []byte(invalidSQL), 0644)
+	applied, err := client.RunMigrations()
 	if err != nil {
-		t.Fatalf("Failed to create test migration file: %v", err)
+		t.Fatalf("RunMigrations() after rollback failed: %v", err)
+	}
+	if applied != 1 {
+		t.Errorf("Expected 1 migration to be applied again, got %d", applied)
 	}
 
-	// Test that RunMigrations fails with invalid SQL
-	err = client.RunMigrations(tempDir)
-	if err == nil {
-		t.Error("Expected error for invalid SQL in migration file")
+	if _, err := client.RollbackMigrations(0); err == nil {
+		t.Error("Expected error for rolling back no migrations")
 	}
 }
 
-func TestClient_RunMigrations_FileReadError(t *testing.T) {
+func TestClient_RunMigrations_InvalidSQL(t *testing.T) {
 	client, cleanup := setupTestDB(t)
 	defer cleanup()
 
-	// Create a temporary directory for test migrations
-	tempDir := t.TempDir()
-
-	// Create a migration file
-	validSQL := "SELECT 1;"
-	migrationFile := filepath.Join(tempDir, "001_test.sql")
-	err := os.WriteFile(migrationFile, []byte(validSQL), 0644)
-	if err != nil {
-		t.Fatalf("Failed to create test migration file: %v", err)
+	// A migration with invalid SQL fails and is not recorded
+	source := &migrate.MemoryMigrationSource{
+		Migrations: []*migrate.Migration{
+			{Id: "999_invalid.sql", Up: []string{"INVALID SQL STATEMENT THAT WILL FAIL"}},
+		},
 	}
-
-	// Remove read permissions from the file (if possible)
-	// Note: This may not work on all systems, but we can still test the error handling
-	err = os.Chmod(migrationFile, 0000)
-	if err != nil {
-		// If we can't change permissions, skip this test
-		t.Skip("Cannot change file permissions for test")
-		return
+	if _, err := client.migrate(source, migrate.Up, 0); err == nil {
+		t.Error("Expected error for invalid SQL in migration")
 	}
-	defer func() {
-		// Restore permissions for cleanup
-		_ = os.Chmod(migrationFile, 0644)
-	}()
 
-	// Test that RunMigrations fails when file cannot be read
-	err = client.RunMigrations(tempDir)
-	if err == nil {
-		t.Error("Expected error when migration file cannot be read")
+	var recorded bool
+	if err := client.db.Get(&recorded, "SELECT EXISTS (SELECT 1 FROM "+MigrationsTable+" WHERE id = $1)", "999_invalid.sql"); err != nil {
+		t.Fatalf("Failed to check migration records: %v", err)
+	}
+	if recorded {
+		t.Error("Expected failed migration not to be recorded")
 	}
 }
@@ -0,0 +1,99 @@
+// Package db provides database operations for SupaControl.
+// This file specifically handles metadata kept about instances: notes and favorites.
+package db
+
+import (
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+// InstanceRef identifies a SupabaseInstance by name and the UID of the resource, so
+// metadata written for a deleted instance is not attached to a new one with its name
+type InstanceRef struct {
+	Name string
+	UID  string
+}
+
+// InstanceMetadata is the metadata stored about one instance, as seen by one user
+type InstanceMetadata struct {
+	InstanceName string `db:"instance_name"`
+	Notes        string `db:"notes"`
+	Favorite     bool   `db:"favorite"`
+}
+
+// ListInstanceMetadata retrieves the metadata of many instances in a single query, keyed
+// by instance name. Instances without metadata, and rows written for an earlier
+// instance with the same name, are left out.
+func (c *Client) ListInstanceMetadata(userID int64, instances []InstanceRef) (map[string]*InstanceMetadata, error) {
+	metadata := make(map[string]*InstanceMetadata)
+	if len(instances) == 0 {
+		return metadata, nil
+	}
+
+	names := make([]string, 0, len(instances))
+	uids := make([]string, 0, len(instances))
+	for _, instance := range instances {
+		names = append(names, instance.Name)
+		uids = append(uids, instance.UID)
+	}
+
+	query := `
+		SELECT i.name AS instance_name, COALESCE(n.notes, '') AS notes, f.user_id IS NOT NULL AS favorite
+		FROM unnest($1::text[], $2::text[]) AS i(name, uid)
+		LEFT JOIN instance_notes n ON n.instance_name = i.name AND n.instance_uid = i.uid
+		LEFT JOIN instance_favorites f ON f.instance_name = i.name AND f.instance_uid = i.uid AND f.user_id = $3
+		WHERE n.instance_name IS NOT NULL OR f.user_id IS NOT NULL
+	`
+
+	var rows []*InstanceMetadata
+	if err := c.db.Select(&rows, query, pq.Array(names), pq.Array(uids), userID); err != nil {
+		return nil, fmt.Errorf("failed to list instance metadata: %w", err)
+	}
+	for _, row := range rows {
+		metadata[row.InstanceName] = row
+	}
+
+	return metadata, nil
+}
+
+// SetInstanceNotes replaces the notes of an instance. Empty notes are removed.
+func (c *Client) SetInstanceNotes(instance InstanceRef, notes string, updatedBy int64) error {
+	var err error
+	if notes == "" {
+		_, err = c.db.Exec(`DELETE FROM instance_notes WHERE instance_name = $1`, instance.Name)
+	} else {
+		_, err = c.db.Exec(
+			`INSERT INTO instance_notes (instance_name, instance_uid, notes, updated_by)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT (instance_name) DO UPDATE
+			SET instance_uid = EXCLUDED.instance_uid, notes = EXCLUDED.notes, updated_by = EXCLUDED.updated_by`,
+			instance.Name, instance.UID, notes, updatedBy,
+		)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to set instance notes: %w", err)
+	}
+
+	return nil
+}
+
+// SetInstanceFavorite adds an instance to or removes it from a user's favorites
+func (c *Client) SetInstanceFavorite(userID int64, instance InstanceRef, favorite bool) error {
+	var err error
+	if favorite {
+		_, err = c.db.Exec(
+			`INSERT INTO instance_favorites (user_id, instance_name, instance_uid)
+			VALUES ($1, $2, $3)
+			ON CONFLICT (user_id, instance_name) DO UPDATE SET instance_uid = EXCLUDED.instance_uid`,
+			userID, instance.Name, instance.UID,
+		)
+	} else {
+		_, err = c.db.Exec(`DELETE FROM instance_favorites WHERE user_id = $1 AND instance_name = $2`, userID, instance.Name)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to set instance favorite: %w", err)
+	}
+
+	return nil
+}
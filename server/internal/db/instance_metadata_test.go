@@ -0,0 +1,64 @@
+package db
+
+import (
+	"testing"
+)
+
+func TestClient_ListInstanceMetadata(t *testing.T) {
+	client, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	user := createTestUserWithDefaults(t, client)
+	other := createTestUser(t, client, "other", "testhash", "user")
+
+	alpha := InstanceRef{Name: "alpha", UID: "uid-alpha"}
+	beta := InstanceRef{Name: "beta", UID: "uid-beta"}
+	if err := client.SetInstanceNotes(alpha, "billing team", user.ID); err != nil {
+		t.Fatalf("SetInstanceNotes() error = %v", err)
+	}
+	if err := client.SetInstanceFavorite(user.ID, beta, true); err != nil {
+		t.Fatalf("SetInstanceFavorite() error = %v", err)
+	}
+	if err := client.SetInstanceFavorite(other.ID, alpha, true); err != nil {
+		t.Fatalf("SetInstanceFavorite() error = %v", err)
+	}
+	// Orphan rows of an instance that no longer exists
+	if err := client.SetInstanceNotes(InstanceRef{Name: "gone", UID: "uid-gone"}, "old notes", user.ID); err != nil {
+		t.Fatalf("SetInstanceNotes() error = %v", err)
+	}
+
+	gamma := InstanceRef{Name: "gamma", UID: "uid-gamma"}
+	recreated := InstanceRef{Name: "gone", UID: "uid-new"}
+	metadata, err := client.ListInstanceMetadata(user.ID, []InstanceRef{alpha, beta, gamma, recreated})
+	if err != nil {
+		t.Fatalf("ListInstanceMetadata() error = %v", err)
+	}
+
+	if len(metadata) != 2 {
+		t.Fatalf("Expected metadata for alpha and beta only, got %d rows", len(metadata))
+	}
+	if got := metadata["alpha"]; got.Notes != "billing team" || got.Favorite {
+		t.Errorf("Expected alpha notes without the other user's favorite, got %+v", got)
+	}
+	if got := metadata["beta"]; got.Notes != "" || !got.Favorite {
+		t.Errorf("Expected beta to be a favorite without notes, got %+v", got)
+	}
+	if _, ok := metadata["gone"]; ok {
+		t.Error("Expected the notes of the deleted instance not to be attached to its successor")
+	}
+
+	// Clearing notes and favorites removes their rows
+	if err := client.SetInstanceNotes(alpha, "", user.ID); err != nil {
+		t.Fatalf("SetInstanceNotes() error = %v", err)
+	}
+	if err := client.SetInstanceFavorite(user.ID, beta, false); err != nil {
+		t.Fatalf("SetInstanceFavorite() error = %v", err)
+	}
+	metadata, err = client.ListInstanceMetadata(user.ID, []InstanceRef{alpha, beta})
+	if err != nil {
+		t.Fatalf("ListInstanceMetadata() error = %v", err)
+	}
+	if len(metadata) != 0 {
+		t.Errorf("Expected no metadata after clearing, got %d rows", len(metadata))
+	}
+}
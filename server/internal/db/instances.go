@@ -0,0 +1,115 @@
+// Package db provides database operations for SupaControl.
+// This file specifically handles the instance records mirrored from SupabaseInstance
+// resources.
+package db
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+)
+
+// InstanceRecordFilter selects instance records. Zero fields match every record.
+type InstanceRecordFilter struct {
+	// Query matches records whose name contains it, ignoring case
+	Query string
+
+	// CreatedAfter and CreatedBefore bound when the instances were created
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+
+	// Deleted selects only deleted (true) or only existing (false) instances
+	Deleted *bool
+
+	// Limit caps the number of records returned
+	Limit int
+}
+
+// SyncInstanceRecords mirrors the existing instances into the database. Records of
+// instances no longer listed are marked as deleted; a record reappearing, e.g. after
+// a restore that kept its UID, is marked as existing again.
+func (c *Client) SyncInstanceRecords(records []*apitypes.InstanceRecord) error {
+	uids := make([]string, 0, len(records))
+	err := c.WithinTransaction(func(tx *sqlx.Tx) error {
+		for _, record := range records {
+			if _, err := tx.Exec(
+				`INSERT INTO instances (uid, name, namespace, owner_id, phase, chart_version, studio_url, api_url, error_message, created_at)
+				VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+				ON CONFLICT (uid) DO UPDATE SET
+					namespace = EXCLUDED.namespace, owner_id = EXCLUDED.owner_id, phase = EXCLUDED.phase,
+					chart_version = EXCLUDED.chart_version, studio_url = EXCLUDED.studio_url, api_url = EXCLUDED.api_url,
+					error_message = EXCLUDED.error_message, deleted_at = NULL, synced_at = NOW()`,
+				record.UID, record.Name, record.Namespace, record.OwnerID, record.Phase, record.ChartVersion,
+				record.StudioURL, record.APIURL, record.ErrorMessage, record.CreatedAt,
+			); err != nil {
+				return err
+			}
+			uids = append(uids, record.UID)
+		}
+
+		_, err := tx.Exec(
+			`UPDATE instances SET deleted_at = NOW(), synced_at = NOW() WHERE deleted_at IS NULL AND NOT (uid = ANY($1))`,
+			pq.Array(uids),
+		)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to sync instance records: %w", err)
+	}
+
+	return nil
+}
+
+// ListInstanceRecords retrieves the instance records matching filter, newest first
+func (c *Client) ListInstanceRecords(filter InstanceRecordFilter) ([]*apitypes.InstanceRecord, error) {
+	var conditions []string
+	var args []interface{}
+	addCondition := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if filter.Query != "" {
+		addCondition("name ILIKE $%d", "%"+escapeLike(filter.Query)+"%")
+	}
+	if filter.CreatedAfter != nil {
+		addCondition("created_at >= $%d", *filter.CreatedAfter)
+	}
+	if filter.CreatedBefore != nil {
+		addCondition("created_at < $%d", *filter.CreatedBefore)
+	}
+	if filter.Deleted != nil {
+		if *filter.Deleted {
+			conditions = append(conditions, "deleted_at IS NOT NULL")
+		} else {
+			conditions = append(conditions, "deleted_at IS NULL")
+		}
+	}
+
+	query := `SELECT * FROM instances`
+	if len(conditions) > 0 {
+		query += ` WHERE ` + strings.Join(conditions, " AND ")
+	}
+	query += ` ORDER BY created_at DESC, name`
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit)
+		query += fmt.Sprintf(` LIMIT $%d`, len(args))
+	}
+
+	var records []*apitypes.InstanceRecord
+	if err := c.db.Select(&records, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to list instance records: %w", err)
+	}
+
+	return records, nil
+}
+
+// escapeLike escapes the wildcards of a LIKE pattern
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
@@ -0,0 +1,69 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+)
+
+func TestClient_SyncInstanceRecords(t *testing.T) {
+	client, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	lastMonth := time.Now().UTC().AddDate(0, -1, 0).Truncate(time.Second)
+	today := time.Now().UTC().Truncate(time.Second)
+	records := []*apitypes.InstanceRecord{
+		{UID: "uid-1", Name: "old-app", Namespace: "supa-old-app", Phase: "Running", CreatedAt: lastMonth},
+		{UID: "uid-2", Name: "new-app", Namespace: "supa-new-app", Phase: "Provisioning", CreatedAt: today},
+	}
+	if err := client.SyncInstanceRecords(records); err != nil {
+		t.Fatalf("SyncInstanceRecords() error = %v", err)
+	}
+
+	// old-app is deleted and new-app finishes provisioning
+	records[1].Phase = "Running"
+	if err := client.SyncInstanceRecords(records[1:]); err != nil {
+		t.Fatalf("SyncInstanceRecords() error = %v", err)
+	}
+
+	all, err := client.ListInstanceRecords(InstanceRecordFilter{})
+	if err != nil {
+		t.Fatalf("ListInstanceRecords() error = %v", err)
+	}
+	if len(all) != 2 || all[0].Name != "new-app" || all[1].Name != "old-app" {
+		t.Fatalf("Expected [new-app old-app], got %+v", all)
+	}
+	if all[0].Phase != "Running" || all[0].DeletedAt != nil {
+		t.Errorf("Expected new-app to be running and existing, got %+v", all[0])
+	}
+	if all[1].DeletedAt == nil {
+		t.Errorf("Expected old-app to be marked as deleted")
+	}
+
+	deleted := true
+	gone, err := client.ListInstanceRecords(InstanceRecordFilter{Deleted: &deleted})
+	if err != nil {
+		t.Fatalf("ListInstanceRecords() error = %v", err)
+	}
+	if len(gone) != 1 || gone[0].UID != "uid-1" {
+		t.Errorf("Expected only old-app to be deleted, got %+v", gone)
+	}
+
+	after := today.Add(-24 * time.Hour)
+	recent, err := client.ListInstanceRecords(InstanceRecordFilter{CreatedAfter: &after})
+	if err != nil {
+		t.Fatalf("ListInstanceRecords() error = %v", err)
+	}
+	if len(recent) != 1 || recent[0].UID != "uid-2" {
+		t.Errorf("Expected only new-app to be created recently, got %+v", recent)
+	}
+
+	matches, err := client.ListInstanceRecords(InstanceRecordFilter{Query: "OLD"})
+	if err != nil {
+		t.Fatalf("ListInstanceRecords() error = %v", err)
+	}
+	if len(matches) != 1 || matches[0].UID != "uid-1" {
+		t.Errorf("Expected the search to match old-app, got %+v", matches)
+	}
+}
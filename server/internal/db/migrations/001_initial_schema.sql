@@ -1,5 +1,7 @@
 -- SupaControl Database Schema
 
+-- +migrate Up
+
 -- Users table for admin authentication
 CREATE TABLE IF NOT EXISTS users (
     id SERIAL PRIMARY KEY,
@@ -41,6 +43,7 @@ CREATE INDEX IF NOT EXISTS idx_api_keys_user_id ON api_keys(user_id);
 CREATE INDEX IF NOT EXISTS idx_api_keys_key_hash ON api_keys(key_hash);
 
 -- Function to update updated_at timestamp
+-- +migrate StatementBegin
 CREATE OR REPLACE FUNCTION update_updated_at_column()
 RETURNS TRIGGER AS $$
 BEGIN
@@ -48,6 +51,7 @@ BEGIN
     RETURN NEW;
 END;
 $$ language 'plpgsql';
+-- +migrate StatementEnd
 
 -- Triggers to auto-update updated_at
 DROP TRIGGER IF EXISTS update_users_updated_at ON users;
@@ -57,3 +61,9 @@ CREATE TRIGGER update_users_updated_at BEFORE UPDATE ON users
 DROP TRIGGER IF EXISTS update_instances_updated_at ON instances;
 CREATE TRIGGER update_instances_updated_at BEFORE UPDATE ON instances
     FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+-- +migrate Down
+DROP TABLE IF EXISTS instances;
+DROP TABLE IF EXISTS api_keys;
+DROP TABLE IF EXISTS users;
+DROP FUNCTION IF EXISTS update_updated_at_column();
@@ -1,14 +1,9 @@
 -- Seed data for SupaControl
+--
+-- No default accounts are seeded. On its first start, when the users table is empty,
+-- the server creates an admin account with a one-time random password that must be
+-- changed on first login (see 014_must_change_password.sql).
 
--- Default admin user
--- Username: admin
--- Password: admin (CHANGE THIS IN PRODUCTION!)
--- Password hash generated using argon2
-INSERT INTO users (username, password_hash, role)
-VALUES ('admin', '$argon2id$v=19$m=65536,t=3,p=2$Bf6ExJJ5cMiNs0KvwcTt1g$yMF+Kkkk7JwmjLd+yZviCJo5FoTrKuLpKOSrk3cTLoM', 'admin')
-ON CONFLICT (username) DO NOTHING;
+-- +migrate Up
 
--- Note: In production, you should:
--- 1. Change the default admin password immediately
--- 2. Use a secure random salt
--- 3. Consider using environment variables for the initial admin credentials
+-- +migrate Down
@@ -7,6 +7,8 @@
 -- Date: 2025-11-11
 -- Reference: docs/adr/001-crd-as-single-source-of-truth.md
 
+-- +migrate Up
+
 -- Drop the trigger for updating updated_at on instances table
 DROP TRIGGER IF EXISTS update_instances_updated_at ON instances;
 
@@ -19,3 +21,22 @@ DROP INDEX IF EXISTS idx_instances_project_name;
 DROP TABLE IF EXISTS instances;
 
 -- Note: We keep the update_updated_at_column() function as it's still used by the users table
+
+-- +migrate Down
+CREATE TABLE IF NOT EXISTS instances (
+    id SERIAL PRIMARY KEY,
+    project_name VARCHAR(63) UNIQUE NOT NULL,
+    namespace VARCHAR(63) UNIQUE NOT NULL,
+    status VARCHAR(50) NOT NULL DEFAULT 'PROVISIONING',
+    studio_url VARCHAR(255),
+    api_url VARCHAR(255),
+    error_message TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_instances_status ON instances(status);
+CREATE INDEX IF NOT EXISTS idx_instances_project_name ON instances(project_name);
+
+CREATE TRIGGER update_instances_updated_at BEFORE UPDATE ON instances
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
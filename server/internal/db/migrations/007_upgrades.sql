@@ -0,0 +1,44 @@
+-- Migration: Fleet upgrades
+--
+-- An upgrade rolls a chart version across selected instances. Each instance is
+-- tracked as a target so progress survives restarts and leader changes.
+
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS upgrades (
+    id SERIAL PRIMARY KEY,
+    chart_version VARCHAR(255) NOT NULL,
+    status VARCHAR(32) NOT NULL DEFAULT 'running',
+    concurrency INTEGER NOT NULL DEFAULT 1,
+    max_failures INTEGER NOT NULL DEFAULT 0,
+    halt_reason TEXT,
+    created_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    completed_at TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS upgrade_targets (
+    id SERIAL PRIMARY KEY,
+    upgrade_id INTEGER NOT NULL REFERENCES upgrades(id) ON DELETE CASCADE,
+    instance_name VARCHAR(255) NOT NULL,
+    position INTEGER NOT NULL,
+    canary BOOLEAN NOT NULL DEFAULT FALSE,
+    status VARCHAR(32) NOT NULL DEFAULT 'pending',
+    from_version VARCHAR(255),
+    error_message TEXT,
+    started_at TIMESTAMP,
+    completed_at TIMESTAMP,
+    UNIQUE (upgrade_id, instance_name)
+);
+
+-- Only one upgrade may run at a time
+CREATE UNIQUE INDEX IF NOT EXISTS idx_upgrades_single_running ON upgrades ((status)) WHERE status = 'running';
+CREATE INDEX IF NOT EXISTS idx_upgrade_targets_upgrade_id ON upgrade_targets(upgrade_id);
+
+DROP TRIGGER IF EXISTS update_upgrades_updated_at ON upgrades;
+CREATE TRIGGER update_upgrades_updated_at BEFORE UPDATE ON upgrades
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+-- +migrate Down
+DROP TABLE IF EXISTS upgrade_targets;
+DROP TABLE IF EXISTS upgrades;
@@ -0,0 +1,24 @@
+-- Migration: Quotas
+--
+-- Overrides of the configured default quotas. A row with a user_id limits that
+-- user; the row without one limits the whole installation. A NULL limit falls
+-- back to the configured default and 0 means unlimited.
+
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS quotas (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+    max_instances INTEGER CHECK (max_instances >= 0),
+    max_storage_gb INTEGER CHECK (max_storage_gb >= 0),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- One row per user, plus a single global row
+CREATE UNIQUE INDEX IF NOT EXISTS idx_quotas_scope ON quotas ((COALESCE(user_id, 0)));
+
+DROP TRIGGER IF EXISTS update_quotas_updated_at ON quotas;
+CREATE TRIGGER update_quotas_updated_at BEFORE UPDATE ON quotas
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+-- +migrate Down
+DROP TABLE IF EXISTS quotas;
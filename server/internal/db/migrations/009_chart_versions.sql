@@ -0,0 +1,18 @@
+-- Migration: Chart versions
+--
+-- Versions of the Supabase chart published in the configured chart repository, as
+-- last indexed. Position orders them newest first; versions removed from the
+-- repository index are dropped on the next indexing run.
+
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS chart_versions (
+    version VARCHAR(255) PRIMARY KEY,
+    position INTEGER NOT NULL,
+    first_seen_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    last_seen_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_chart_versions_position ON chart_versions(position);
+
+-- +migrate Down
+DROP TABLE IF EXISTS chart_versions;
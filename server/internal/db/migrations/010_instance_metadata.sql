@@ -0,0 +1,31 @@
+-- Migration: Instance metadata
+--
+-- Notes on instances and per-user favorites. Instances live in Kubernetes, so
+-- rows are keyed by instance name and record the UID of the SupabaseInstance
+-- they were written for; rows left behind by a deleted instance whose name was
+-- reused carry a stale UID and are ignored.
+
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS instance_notes (
+    instance_name VARCHAR(63) PRIMARY KEY,
+    instance_uid VARCHAR(36) NOT NULL,
+    notes TEXT NOT NULL,
+    updated_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS instance_favorites (
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    instance_name VARCHAR(63) NOT NULL,
+    instance_uid VARCHAR(36) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, instance_name)
+);
+
+DROP TRIGGER IF EXISTS update_instance_notes_updated_at ON instance_notes;
+CREATE TRIGGER update_instance_notes_updated_at BEFORE UPDATE ON instance_notes
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+-- +migrate Down
+DROP TABLE IF EXISTS instance_favorites;
+DROP TABLE IF EXISTS instance_notes;
@@ -0,0 +1,32 @@
+-- Migration: Instance benchmarks
+--
+-- A benchmark runs a pgbench load Job against an instance's database and keeps
+-- its throughput and latency, so tiers and storage classes can be compared.
+-- Rows are keyed by instance name, like instance metadata, and outlive the Job.
+
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS benchmarks (
+    id SERIAL PRIMARY KEY,
+    instance_name VARCHAR(63) NOT NULL,
+    namespace VARCHAR(63) NOT NULL,
+    job_name VARCHAR(63) NOT NULL,
+    status VARCHAR(32) NOT NULL DEFAULT 'running',
+    clients INTEGER NOT NULL,
+    duration_seconds INTEGER NOT NULL,
+    scale INTEGER NOT NULL,
+    storage_class VARCHAR(253) NOT NULL DEFAULT '',
+    transactions BIGINT,
+    transactions_per_second DOUBLE PRECISION,
+    latency_average_ms DOUBLE PRECISION,
+    error_message TEXT,
+    created_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    completed_at TIMESTAMP
+);
+
+-- Only one benchmark may run against an instance at a time
+CREATE UNIQUE INDEX IF NOT EXISTS idx_benchmarks_single_running ON benchmarks (instance_name) WHERE status = 'running';
+CREATE INDEX IF NOT EXISTS idx_benchmarks_instance_name ON benchmarks (instance_name, created_at DESC);
+
+-- +migrate Down
+DROP TABLE IF EXISTS benchmarks;
@@ -0,0 +1,29 @@
+-- Migration: Connections between instances
+--
+-- A connection lets the workloads of one instance (the source, e.g. an analytics
+-- instance) read the database of another (the target). It is requested by the
+-- owner of either instance and takes effect once the owners of both approved it,
+-- when the target's allowedConnections lists the source. Revoked connections are
+-- kept for the record.
+
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS instance_connections (
+    id SERIAL PRIMARY KEY,
+    source_instance VARCHAR(63) NOT NULL,
+    target_instance VARCHAR(63) NOT NULL,
+    status VARCHAR(32) NOT NULL DEFAULT 'pending',
+    requested_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    source_approved_at TIMESTAMP,
+    target_approved_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    activated_at TIMESTAMP,
+    revoked_at TIMESTAMP
+);
+
+-- An instance pair has at most one pending or active connection
+CREATE UNIQUE INDEX IF NOT EXISTS idx_instance_connections_single_open ON instance_connections (source_instance, target_instance)
+    WHERE status IN ('pending', 'active');
+CREATE INDEX IF NOT EXISTS idx_instance_connections_target ON instance_connections (target_instance);
+
+-- +migrate Down
+DROP TABLE IF EXISTS instance_connections;
@@ -0,0 +1,11 @@
+-- Migration: Authentication provider of users
+--
+-- Users signing in through SAML single sign-on are created on their first login and
+-- have no password. auth_provider keeps them apart from local accounts, so an IdP
+-- can never sign in as a local user of the same name.
+
+-- +migrate Up
+ALTER TABLE users ADD COLUMN IF NOT EXISTS auth_provider VARCHAR(32) NOT NULL DEFAULT 'local';
+
+-- +migrate Down
+ALTER TABLE users DROP COLUMN IF EXISTS auth_provider;
@@ -0,0 +1,16 @@
+-- Migration: Forced password rotation
+--
+-- Users flagged with must_change_password can only change their password until they do.
+-- The first admin account is created with a one-time password and this flag set. Admins
+-- still using the password admin/admin seeded by earlier versions are flagged as well.
+
+-- +migrate Up
+ALTER TABLE users ADD COLUMN IF NOT EXISTS must_change_password BOOLEAN NOT NULL DEFAULT false;
+
+UPDATE users SET must_change_password = true
+WHERE username = 'admin'
+  AND password_hash = '$argon2id$v=19$m=65536,t=3,p=2$Bf6ExJJ5cMiNs0KvwcTt1g$yMF+Kkkk7JwmjLd+yZviCJo5FoTrKuLpKOSrk3cTLoM'
+  AND NOT must_change_password;
+
+-- +migrate Down
+ALTER TABLE users DROP COLUMN IF EXISTS must_change_password;
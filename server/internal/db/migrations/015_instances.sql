@@ -0,0 +1,30 @@
+-- Migration: Instance records
+--
+-- A read-only mirror of SupabaseInstance resources, written by the instance syncer on
+-- the elected leader. The resources remain the source of truth (ADR-001); the mirror
+-- serves listings when the Kubernetes API is unavailable and queries over time, such
+-- as instances created last month. Records are keyed by UID, so a reused name gets a
+-- new record, and are kept with deleted_at set once their resource is gone.
+
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS instances (
+    uid VARCHAR(36) PRIMARY KEY,
+    name VARCHAR(63) NOT NULL,
+    namespace VARCHAR(63) NOT NULL DEFAULT '',
+    owner_id INTEGER,
+    phase VARCHAR(32) NOT NULL DEFAULT '',
+    chart_version VARCHAR(255) NOT NULL DEFAULT '',
+    studio_url VARCHAR(255) NOT NULL DEFAULT '',
+    api_url VARCHAR(255) NOT NULL DEFAULT '',
+    error_message TEXT,
+    created_at TIMESTAMP NOT NULL,
+    deleted_at TIMESTAMP,
+    synced_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_instances_name ON instances(name);
+CREATE INDEX IF NOT EXISTS idx_instances_created_at ON instances(created_at);
+CREATE INDEX IF NOT EXISTS idx_instances_deleted_at ON instances(deleted_at);
+
+-- +migrate Down
+DROP TABLE IF EXISTS instances;
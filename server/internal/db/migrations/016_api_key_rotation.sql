@@ -0,0 +1,16 @@
+-- Migration: API key rotation
+--
+-- Rotating an API key replaces its hash. The old hash is kept in previous_key_hash and
+-- keeps authenticating until previous_key_expires_at, so automation holding the old
+-- key can switch over without downtime.
+
+-- +migrate Up
+ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS previous_key_hash VARCHAR(255);
+ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS previous_key_expires_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_api_keys_previous_key_hash ON api_keys(previous_key_hash);
+
+-- +migrate Down
+DROP INDEX IF EXISTS idx_api_keys_previous_key_hash;
+ALTER TABLE api_keys DROP COLUMN IF EXISTS previous_key_expires_at;
+ALTER TABLE api_keys DROP COLUMN IF EXISTS previous_key_hash;
@@ -0,0 +1,16 @@
+-- Migration: User role constraint
+--
+-- Restricts users.role to the roles the API policy knows, including the read-only
+-- viewer role. Accounts holding another role could not call any route, so they are
+-- moved to viewer, the role granting the least.
+
+-- +migrate Up
+UPDATE users SET role = 'viewer'
+WHERE role NOT IN ('admin', 'operator', 'user', 'readonly', 'viewer');
+
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_role_check;
+ALTER TABLE users ADD CONSTRAINT users_role_check
+    CHECK (role IN ('admin', 'operator', 'user', 'readonly', 'viewer'));
+
+-- +migrate Down
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_role_check;
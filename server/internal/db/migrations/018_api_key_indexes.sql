@@ -0,0 +1,28 @@
+-- Migration: API key indexes
+--
+-- Every request authenticated with an API key looks the key up by its current or
+-- previous hash. The previous hash is only set during a rotation overlap, so its index
+-- covers just those rows. key_hash is already indexed by its UNIQUE constraint, and
+-- listing a user's keys is served by (user_id, created_at), so the older single-column
+-- indexes only slowed writes down. last_used is deliberately left unindexed, so its
+-- batched updates remain heap-only tuple updates.
+
+-- +migrate Up
+DROP INDEX IF EXISTS idx_api_keys_key_hash;
+DROP INDEX IF EXISTS idx_api_keys_user_id;
+DROP INDEX IF EXISTS idx_api_keys_previous_key_hash;
+
+CREATE INDEX IF NOT EXISTS idx_api_keys_user_id_created_at ON api_keys(user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_api_keys_previous_key_hash ON api_keys(previous_key_hash)
+    WHERE previous_key_hash IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_api_keys_expires_at ON api_keys(expires_at)
+    WHERE expires_at IS NOT NULL;
+
+-- +migrate Down
+DROP INDEX IF EXISTS idx_api_keys_expires_at;
+DROP INDEX IF EXISTS idx_api_keys_previous_key_hash;
+DROP INDEX IF EXISTS idx_api_keys_user_id_created_at;
+
+CREATE INDEX IF NOT EXISTS idx_api_keys_previous_key_hash ON api_keys(previous_key_hash);
+CREATE INDEX IF NOT EXISTS idx_api_keys_user_id ON api_keys(user_id);
+CREATE INDEX IF NOT EXISTS idx_api_keys_key_hash ON api_keys(key_hash);
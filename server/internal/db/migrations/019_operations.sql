@@ -0,0 +1,25 @@
+-- Migration: Instance operations
+--
+-- An operation records a create, delete or restart request so clients can follow
+-- its progress by ID instead of polling the instance and guessing which request
+-- its status belongs to. Progress is derived from the instance's phase and Jobs;
+-- only the outcome is stored, once the operation is seen to finish. The instance
+-- UID tells the instance apart from one re-created later under the same name.
+
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS operations (
+    id SERIAL PRIMARY KEY,
+    type VARCHAR(32) NOT NULL,
+    instance_name VARCHAR(63) NOT NULL,
+    instance_uid VARCHAR(36) NOT NULL DEFAULT '',
+    status VARCHAR(32) NOT NULL DEFAULT 'running',
+    error_message TEXT,
+    created_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    completed_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_operations_instance_name ON operations (instance_name, created_at DESC);
+
+-- +migrate Down
+DROP TABLE IF EXISTS operations;
@@ -0,0 +1,17 @@
+-- Migration: Studio session redemptions
+--
+-- A Studio link may be opened only once. Redeemed links are recorded by the ID of
+-- their token here rather than in API memory, so every replica refuses a link that
+-- was already opened on another. Rows are removed once the token has expired.
+
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS studio_session_redemptions (
+    jti VARCHAR(64) PRIMARY KEY,
+    expires_at TIMESTAMP NOT NULL,
+    redeemed_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_studio_session_redemptions_expires_at ON studio_session_redemptions (expires_at);
+
+-- +migrate Down
+DROP TABLE IF EXISTS studio_session_redemptions;
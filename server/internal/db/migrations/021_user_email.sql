@@ -0,0 +1,14 @@
+-- Migration: User email addresses
+--
+-- An account's email is the address a team invitation it accepted was sent to, so
+-- it is only ever set from an invitation link that was delivered there. Later
+-- invitations can then only be accepted by the account that owns their address.
+
+-- +migrate Up
+ALTER TABLE users ADD COLUMN IF NOT EXISTS email VARCHAR(255);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email ON users (LOWER(email)) WHERE email IS NOT NULL;
+
+-- +migrate Down
+DROP INDEX IF EXISTS idx_users_email;
+ALTER TABLE users DROP COLUMN IF EXISTS email;
@@ -0,0 +1,22 @@
+-- Migration: Instance database backups
+--
+-- A backup is an encrypted pg_dump of an instance database in object storage. The
+-- record points to the artifact and names the encryption key, which has to be
+-- configured to restore it. Records outlive their instance, so a deleted instance's
+-- backups can still be found.
+
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS backups (
+    id SERIAL PRIMARY KEY,
+    instance_name VARCHAR(63) NOT NULL,
+    object_key TEXT NOT NULL UNIQUE,
+    key_id VARCHAR(64) NOT NULL,
+    size_bytes BIGINT NOT NULL,
+    created_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_backups_instance_name ON backups (instance_name, created_at DESC);
+
+-- +migrate Down
+DROP TABLE IF EXISTS backups;
@@ -0,0 +1,23 @@
+-- Migration: Backup retention
+--
+-- A retention policy keeps the newest backup of each of the last N days, weeks and
+-- months of an instance. The pruner deletes the artifacts of the other backups and
+-- marks their records pruned instead of deleting them, so the audit trail still
+-- resolves. Held backups are never pruned.
+
+-- +migrate Up
+ALTER TABLE backups ADD COLUMN IF NOT EXISTS held BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE backups ADD COLUMN IF NOT EXISTS pruned_at TIMESTAMP;
+
+CREATE TABLE IF NOT EXISTS backup_retention_policies (
+    instance_name VARCHAR(63) PRIMARY KEY,
+    keep_daily INTEGER NOT NULL DEFAULT 0,
+    keep_weekly INTEGER NOT NULL DEFAULT 0,
+    keep_monthly INTEGER NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- +migrate Down
+DROP TABLE IF EXISTS backup_retention_policies;
+ALTER TABLE backups DROP COLUMN IF EXISTS pruned_at;
+ALTER TABLE backups DROP COLUMN IF EXISTS held;
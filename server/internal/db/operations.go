@@ -0,0 +1,57 @@
+// Package db provides database operations for SupaControl.
+// This file specifically handles instance operations.
+package db
+
+import (
+	"database/sql"
+	"fmt"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+)
+
+// CreateOperation records a running operation on an instance, identified by its name and UID
+func (c *Client) CreateOperation(operationType, instanceName, instanceUID string, createdBy *int64) (*apitypes.Operation, error) {
+	var created apitypes.Operation
+
+	err := c.db.QueryRowx(
+		`INSERT INTO operations (type, instance_name, instance_uid, status, created_by)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING *`,
+		operationType, instanceName, instanceUID, apitypes.OperationStatusRunning, createdBy,
+	).StructScan(&created)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create operation: %w", err)
+	}
+
+	return &created, nil
+}
+
+// GetOperation retrieves an operation by ID
+func (c *Client) GetOperation(id int64) (*apitypes.Operation, error) {
+	var operation apitypes.Operation
+
+	err := c.db.Get(&operation, `SELECT * FROM operations WHERE id = $1`, id)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get operation: %w", err)
+	}
+
+	return &operation, nil
+}
+
+// FinishOperation saves the status and error of a finished operation
+func (c *Client) FinishOperation(operation *apitypes.Operation) error {
+	query := `
+		UPDATE operations
+		SET status = $1, error_message = $2, completed_at = NOW()
+		WHERE id = $3
+	`
+
+	if _, err := c.db.Exec(query, operation.Status, operation.ErrorMessage, operation.ID); err != nil {
+		return fmt.Errorf("failed to finish operation: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,47 @@
+package db
+
+import (
+	"testing"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+)
+
+func TestClient_Operations(t *testing.T) {
+	client, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	user := createTestUserWithDefaults(t, client)
+
+	operation, err := client.CreateOperation(apitypes.OperationTypeCreate, "alpha", "0b1c8f3e-5d6a-4c2b-9e7f-1a2b3c4d5e6f", &user.ID)
+	if err != nil {
+		t.Fatalf("CreateOperation() error = %v", err)
+	}
+	if operation.Status != apitypes.OperationStatusRunning || operation.Type != apitypes.OperationTypeCreate ||
+		operation.InstanceName != "alpha" || operation.InstanceUID != "0b1c8f3e-5d6a-4c2b-9e7f-1a2b3c4d5e6f" || operation.CompletedAt != nil {
+		t.Errorf("Expected running create operation on alpha, got %+v", operation)
+	}
+
+	message := "provisioning job failed"
+	operation.Status = apitypes.OperationStatusFailed
+	operation.ErrorMessage = &message
+	if err := client.FinishOperation(operation); err != nil {
+		t.Fatalf("FinishOperation() error = %v", err)
+	}
+
+	finished, err := client.GetOperation(operation.ID)
+	if err != nil {
+		t.Fatalf("GetOperation() error = %v", err)
+	}
+	if finished.Status != apitypes.OperationStatusFailed || finished.CompletedAt == nil ||
+		finished.ErrorMessage == nil || *finished.ErrorMessage != message {
+		t.Errorf("Expected failed operation with its error, got %+v", finished)
+	}
+
+	missing, err := client.GetOperation(operation.ID + 1000)
+	if err != nil {
+		t.Fatalf("GetOperation() error = %v", err)
+	}
+	if missing != nil {
+		t.Errorf("Expected nil for a missing operation, got %+v", missing)
+	}
+}
@@ -0,0 +1,46 @@
+// Package db provides database operations for SupaControl.
+// This file specifically handles per-user preference storage.
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+)
+
+// GetUserPreferences retrieves a user's stored preferences, or nil if none have been saved
+func (c *Client) GetUserPreferences(userID int64) (*apitypes.UserPreferences, error) {
+	var prefs apitypes.UserPreferences
+
+	query := `SELECT preferences, updated_at FROM user_preferences WHERE user_id = $1`
+
+	if err := c.db.Get(&prefs, query, userID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get user preferences: %w", err)
+	}
+
+	return &prefs, nil
+}
+
+// UpsertUserPreferences replaces a user's preferences document
+func (c *Client) UpsertUserPreferences(userID int64, preferences json.RawMessage) (*apitypes.UserPreferences, error) {
+	var prefs apitypes.UserPreferences
+
+	query := `
+		INSERT INTO user_preferences (user_id, preferences)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE SET preferences = EXCLUDED.preferences
+		RETURNING preferences, updated_at
+	`
+
+	if err := c.db.Get(&prefs, query, userID, []byte(preferences)); err != nil {
+		return nil, fmt.Errorf("failed to save user preferences: %w", err)
+	}
+
+	return &prefs, nil
+}
@@ -0,0 +1,46 @@
+package db
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestClient_UserPreferences(t *testing.T) {
+	client, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	user := createTestUserWithDefaults(t, client)
+
+	prefs, err := client.GetUserPreferences(user.ID)
+	if err != nil {
+		t.Fatalf("GetUserPreferences() error = %v", err)
+	}
+	if prefs != nil {
+		t.Errorf("Expected no preferences for new user, got %+v", prefs)
+	}
+
+	saved, err := client.UpsertUserPreferences(user.ID, json.RawMessage(`{"theme":"dark"}`))
+	if err != nil {
+		t.Fatalf("UpsertUserPreferences() error = %v", err)
+	}
+	if saved.UpdatedAt == nil {
+		t.Error("Expected updated_at to be set")
+	}
+
+	if _, err := client.UpsertUserPreferences(user.ID, json.RawMessage(`{"theme":"light","columns":["name"]}`)); err != nil {
+		t.Fatalf("UpsertUserPreferences() second call error = %v", err)
+	}
+
+	prefs, err = client.GetUserPreferences(user.ID)
+	if err != nil {
+		t.Fatalf("GetUserPreferences() error = %v", err)
+	}
+
+	var doc map[string]interface{}
+	if err := json.Unmarshal(prefs.Preferences, &doc); err != nil {
+		t.Fatalf("failed to decode preferences: %v", err)
+	}
+	if doc["theme"] != "light" {
+		t.Errorf("Expected theme 'light', got %v", doc["theme"])
+	}
+}
@@ -0,0 +1,55 @@
+// Package db provides database operations for SupaControl.
+// This file specifically handles per-user and global quota overrides.
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+)
+
+// GetQuota retrieves the quota override of a user, or the global override when userID is nil.
+// It returns nil if no override has been stored.
+func (c *Client) GetQuota(userID *int64) (*apitypes.Quota, error) {
+	var quota apitypes.Quota
+
+	query := `SELECT user_id, max_instances, max_storage_gb, updated_at FROM quotas WHERE COALESCE(user_id, 0) = COALESCE($1, 0)`
+
+	if err := c.db.Get(&quota, query, userID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get quota: %w", err)
+	}
+
+	return &quota, nil
+}
+
+// SetQuota creates or replaces the quota override of quota.UserID, or the global override when it is nil
+func (c *Client) SetQuota(quota *apitypes.Quota) (*apitypes.Quota, error) {
+	var saved apitypes.Quota
+
+	query := `
+		INSERT INTO quotas (user_id, max_instances, max_storage_gb)
+		VALUES ($1, $2, $3)
+		ON CONFLICT ((COALESCE(user_id, 0))) DO UPDATE
+		SET max_instances = EXCLUDED.max_instances, max_storage_gb = EXCLUDED.max_storage_gb
+		RETURNING user_id, max_instances, max_storage_gb, updated_at
+	`
+
+	if err := c.db.Get(&saved, query, quota.UserID, quota.MaxInstances, quota.MaxStorageGB); err != nil {
+		return nil, fmt.Errorf("failed to save quota: %w", err)
+	}
+
+	return &saved, nil
+}
+
+// DeleteQuota removes the quota override of a user so the configured defaults apply again
+func (c *Client) DeleteQuota(userID int64) error {
+	if _, err := c.db.Exec(`DELETE FROM quotas WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("failed to delete quota: %w", err)
+	}
+	return nil
+}
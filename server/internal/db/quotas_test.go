@@ -0,0 +1,59 @@
+package db
+
+import (
+	"testing"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+)
+
+func TestClient_Quotas(t *testing.T) {
+	client, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	user := createTestUserWithDefaults(t, client)
+	userID := user.ID
+
+	quota, err := client.GetQuota(&userID)
+	if err != nil {
+		t.Fatalf("GetQuota() error = %v", err)
+	}
+	if quota != nil {
+		t.Errorf("Expected no quota for new user, got %+v", quota)
+	}
+
+	three, ten := 3, 10
+	if _, err := client.SetQuota(&apitypes.Quota{UserID: &userID, MaxInstances: &three}); err != nil {
+		t.Fatalf("SetQuota() error = %v", err)
+	}
+	if _, err := client.SetQuota(&apitypes.Quota{MaxInstances: &ten, MaxStorageGB: &ten}); err != nil {
+		t.Fatalf("SetQuota() global error = %v", err)
+	}
+
+	// Setting again replaces the override rather than adding a row
+	saved, err := client.SetQuota(&apitypes.Quota{UserID: &userID, MaxStorageGB: &ten})
+	if err != nil {
+		t.Fatalf("SetQuota() second call error = %v", err)
+	}
+	if saved.MaxInstances != nil || saved.MaxStorageGB == nil || *saved.MaxStorageGB != 10 {
+		t.Errorf("Expected only max_storage_gb = 10, got %+v", saved)
+	}
+
+	global, err := client.GetQuota(nil)
+	if err != nil {
+		t.Fatalf("GetQuota() global error = %v", err)
+	}
+	if global == nil || global.UserID != nil || global.MaxInstances == nil || *global.MaxInstances != 10 {
+		t.Errorf("Expected global max_instances = 10, got %+v", global)
+	}
+
+	if err := client.DeleteQuota(userID); err != nil {
+		t.Fatalf("DeleteQuota() error = %v", err)
+	}
+	quota, err = client.GetQuota(&userID)
+	if err != nil {
+		t.Fatalf("GetQuota() error = %v", err)
+	}
+	if quota != nil {
+		t.Errorf("Expected quota to be deleted, got %+v", quota)
+	}
+}
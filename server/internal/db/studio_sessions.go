@@ -0,0 +1,34 @@
+// Package db provides database operations for SupaControl.
+// This file specifically handles Studio session redemptions.
+package db
+
+import (
+	"fmt"
+	"time"
+)
+
+// RedeemStudioSession marks the Studio link with token ID jti as used, reporting false
+// when it was used before. Redemptions of expired links are removed, since their tokens
+// no longer validate.
+func (c *Client) RedeemStudioSession(jti string, expiresAt time.Time) (bool, error) {
+	if _, err := c.db.Exec(`DELETE FROM studio_session_redemptions WHERE expires_at < NOW()`); err != nil {
+		return false, fmt.Errorf("failed to delete expired Studio session redemptions: %w", err)
+	}
+
+	result, err := c.db.Exec(
+		`INSERT INTO studio_session_redemptions (jti, expires_at)
+		VALUES ($1, $2)
+		ON CONFLICT (jti) DO NOTHING`,
+		jti, expiresAt,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to redeem Studio session: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rowsAffected == 1, nil
+}
@@ -0,0 +1,36 @@
+package db
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClient_RedeemStudioSession(t *testing.T) {
+	client, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	expiresAt := time.Now().Add(time.Minute)
+	redeemed, err := client.RedeemStudioSession("link-1", expiresAt)
+	if err != nil {
+		t.Fatalf("RedeemStudioSession() error = %v", err)
+	}
+	if !redeemed {
+		t.Error("Expected the first redemption to succeed")
+	}
+
+	redeemed, err = client.RedeemStudioSession("link-1", expiresAt)
+	if err != nil {
+		t.Fatalf("RedeemStudioSession() error = %v", err)
+	}
+	if redeemed {
+		t.Error("Expected a link to be redeemable only once")
+	}
+
+	redeemed, err = client.RedeemStudioSession("link-2", expiresAt)
+	if err != nil {
+		t.Fatalf("RedeemStudioSession() error = %v", err)
+	}
+	if !redeemed {
+		t.Error("Expected another link to be redeemable")
+	}
+}
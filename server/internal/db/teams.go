@@ -0,0 +1,274 @@
+// Package db provides database operations for SupaControl.
+// This file specifically handles team, membership, and invitation operations.
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+)
+
+// ErrInvitationNotPending is returned when an invitation was already accepted, revoked, or has expired
+var ErrInvitationNotPending = errors.New("invitation is no longer pending")
+
+// ErrInvitationEmailMismatch is returned when the accepting user's email differs from
+// the address the invitation was sent to
+var ErrInvitationEmailMismatch = errors.New("invitation was sent to a different email address")
+
+// ErrEmailInUse is returned when an invitation's address already belongs to another user
+var ErrEmailInUse = errors.New("email address belongs to another user")
+
+// CreateTeam creates a team and adds the creator as a team admin
+func (c *Client) CreateTeam(name string, createdBy int64) (*apitypes.Team, error) {
+	var team apitypes.Team
+
+	err := c.WithinTransaction(func(tx *sqlx.Tx) error {
+		if err := tx.QueryRowx(
+			`INSERT INTO teams (name) VALUES ($1) RETURNING id, name, created_at, updated_at`,
+			name,
+		).StructScan(&team); err != nil {
+			return err
+		}
+
+		_, err := tx.Exec(
+			`INSERT INTO team_members (team_id, user_id, role) VALUES ($1, $2, $3)`,
+			team.ID, createdBy, apitypes.TeamRoleAdmin,
+		)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create team: %w", err)
+	}
+
+	return &team, nil
+}
+
+// GetTeamByID retrieves a team by ID
+func (c *Client) GetTeamByID(id int64) (*apitypes.Team, error) {
+	var team apitypes.Team
+
+	err := c.db.Get(&team, `SELECT * FROM teams WHERE id = $1`, id)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get team: %w", err)
+	}
+
+	return &team, nil
+}
+
+// ListAllTeams retrieves all teams (admin function)
+func (c *Client) ListAllTeams() ([]*apitypes.Team, error) {
+	var teams []*apitypes.Team
+
+	if err := c.db.Select(&teams, `SELECT * FROM teams ORDER BY name`); err != nil {
+		return nil, fmt.Errorf("failed to list teams: %w", err)
+	}
+
+	return teams, nil
+}
+
+// ListTeamsByUser retrieves all teams a user is a member of
+func (c *Client) ListTeamsByUser(userID int64) ([]*apitypes.Team, error) {
+	var teams []*apitypes.Team
+
+	query := `
+		SELECT t.* FROM teams t
+		JOIN team_members m ON m.team_id = t.id
+		WHERE m.user_id = $1
+		ORDER BY t.name
+	`
+
+	if err := c.db.Select(&teams, query, userID); err != nil {
+		return nil, fmt.Errorf("failed to list teams: %w", err)
+	}
+
+	return teams, nil
+}
+
+// GetTeamMember retrieves a user's membership in a team
+func (c *Client) GetTeamMember(teamID, userID int64) (*apitypes.TeamMember, error) {
+	var member apitypes.TeamMember
+
+	err := c.db.Get(&member, `SELECT * FROM team_members WHERE team_id = $1 AND user_id = $2`, teamID, userID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get team member: %w", err)
+	}
+
+	return &member, nil
+}
+
+// CreateTeamInvitation creates a pending invitation to join a team
+func (c *Client) CreateTeamInvitation(teamID int64, email, role string, invitedBy int64, expiresAt time.Time) (*apitypes.TeamInvitation, error) {
+	var invitation apitypes.TeamInvitation
+
+	query := `
+		INSERT INTO team_invitations (team_id, email, role, invited_by, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING *
+	`
+
+	if err := c.db.QueryRowx(query, teamID, email, role, invitedBy, expiresAt).StructScan(&invitation); err != nil {
+		return nil, fmt.Errorf("failed to create team invitation: %w", err)
+	}
+
+	return &invitation, nil
+}
+
+// GetTeamInvitationByID retrieves an invitation by ID
+func (c *Client) GetTeamInvitationByID(id int64) (*apitypes.TeamInvitation, error) {
+	var invitation apitypes.TeamInvitation
+
+	err := c.db.Get(&invitation, `SELECT * FROM team_invitations WHERE id = $1`, id)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get team invitation: %w", err)
+	}
+
+	return &invitation, nil
+}
+
+// ListTeamInvitations retrieves all invitations for a team, newest first
+func (c *Client) ListTeamInvitations(teamID int64) ([]*apitypes.TeamInvitation, error) {
+	var invitations []*apitypes.TeamInvitation
+
+	query := `SELECT * FROM team_invitations WHERE team_id = $1 ORDER BY created_at DESC, id DESC`
+
+	if err := c.db.Select(&invitations, query, teamID); err != nil {
+		return nil, fmt.Errorf("failed to list team invitations: %w", err)
+	}
+
+	return invitations, nil
+}
+
+// RevokeTeamInvitation marks a pending invitation as revoked
+func (c *Client) RevokeTeamInvitation(id int64) error {
+	query := `
+		UPDATE team_invitations SET revoked_at = NOW()
+		WHERE id = $1 AND accepted_at IS NULL AND revoked_at IS NULL
+	`
+
+	result, err := c.db.Exec(query, id)
+	if err != nil {
+		return fmt.Errorf("failed to revoke team invitation: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return ErrInvitationNotPending
+	}
+
+	return nil
+}
+
+// ExtendTeamInvitation moves the expiry of an invitation that was neither accepted nor
+// revoked, which revives an expired one until it is cleaned up
+func (c *Client) ExtendTeamInvitation(id int64, expiresAt time.Time) (*apitypes.TeamInvitation, error) {
+	var invitation apitypes.TeamInvitation
+
+	query := `
+		UPDATE team_invitations SET expires_at = $2
+		WHERE id = $1 AND accepted_at IS NULL AND revoked_at IS NULL
+		RETURNING *
+	`
+
+	err := c.db.QueryRowx(query, id, expiresAt).StructScan(&invitation)
+	if err == sql.ErrNoRows {
+		return nil, ErrInvitationNotPending
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to extend team invitation: %w", err)
+	}
+
+	return &invitation, nil
+}
+
+// AcceptTeamInvitation marks an invitation as accepted and adds the user to the team
+// with the invited role. The user must own the invitation's email address, which a user
+// without one is given. All changes are applied atomically.
+func (c *Client) AcceptTeamInvitation(id, userID int64) (*apitypes.TeamMember, error) {
+	var member apitypes.TeamMember
+
+	err := c.WithinTransaction(func(tx *sqlx.Tx) error {
+		var invitation apitypes.TeamInvitation
+		err := tx.QueryRowx(
+			`UPDATE team_invitations SET accepted_at = NOW(), accepted_by = $2
+			 WHERE id = $1 AND accepted_at IS NULL AND revoked_at IS NULL AND expires_at > NOW()
+			 RETURNING *`,
+			id, userID,
+		).StructScan(&invitation)
+		if err == sql.ErrNoRows {
+			return ErrInvitationNotPending
+		}
+		if err != nil {
+			return err
+		}
+
+		// A user without an email takes the invitation's address, as only someone who
+		// received the link could have accepted it. Otherwise the addresses must match.
+		var email string
+		err = tx.QueryRowx(
+			`UPDATE users SET email = COALESCE(email, $2) WHERE id = $1 RETURNING email`,
+			userID, invitation.Email,
+		).Scan(&email)
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+			return ErrEmailInUse
+		}
+		if err != nil {
+			return err
+		}
+		if !strings.EqualFold(email, invitation.Email) {
+			return ErrInvitationEmailMismatch
+		}
+
+		return tx.QueryRowx(
+			`INSERT INTO team_members (team_id, user_id, role) VALUES ($1, $2, $3)
+			 ON CONFLICT (team_id, user_id) DO UPDATE SET role = EXCLUDED.role
+			 RETURNING *`,
+			invitation.TeamID, userID, invitation.Role,
+		).StructScan(&member)
+	})
+	if errors.Is(err, ErrInvitationNotPending) || errors.Is(err, ErrInvitationEmailMismatch) || errors.Is(err, ErrEmailInUse) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to accept team invitation: %w", err)
+	}
+
+	return &member, nil
+}
+
+// DeleteExpiredTeamInvitations deletes invitations that expired without being accepted
+func (c *Client) DeleteExpiredTeamInvitations() (int64, error) {
+	query := `DELETE FROM team_invitations WHERE accepted_at IS NULL AND expires_at < NOW()`
+
+	result, err := c.db.Exec(query)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired team invitations: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rowsAffected, nil
+}
@@ -0,0 +1,197 @@
+package db
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+)
+
+func TestClient_CreateTeam(t *testing.T) {
+	client, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	user := createTestUserWithDefaults(t, client)
+
+	team, err := client.CreateTeam("platform", user.ID)
+	if err != nil {
+		t.Fatalf("CreateTeam() error = %v", err)
+	}
+	if team.Name != "platform" {
+		t.Errorf("Expected team name 'platform', got %s", team.Name)
+	}
+
+	member, err := client.GetTeamMember(team.ID, user.ID)
+	if err != nil {
+		t.Fatalf("GetTeamMember() error = %v", err)
+	}
+	if member == nil || member.Role != apitypes.TeamRoleAdmin {
+		t.Errorf("Expected creator to be team admin, got %+v", member)
+	}
+
+	teams, err := client.ListTeamsByUser(user.ID)
+	if err != nil {
+		t.Fatalf("ListTeamsByUser() error = %v", err)
+	}
+	if len(teams) != 1 {
+		t.Errorf("Expected 1 team, got %d", len(teams))
+	}
+
+	if _, err := client.CreateTeam("platform", user.ID); err == nil {
+		t.Error("Expected error creating team with duplicate name")
+	}
+}
+
+func TestClient_AcceptTeamInvitation(t *testing.T) {
+	client, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	admin := createTestUserWithDefaults(t, client)
+	invitee := createTestUser(t, client, "invitee", "hash", "user")
+
+	team, err := client.CreateTeam("platform", admin.ID)
+	if err != nil {
+		t.Fatalf("CreateTeam() error = %v", err)
+	}
+
+	invitation, err := client.CreateTeamInvitation(team.ID, "invitee@example.com", apitypes.TeamRoleMember, admin.ID, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("CreateTeamInvitation() error = %v", err)
+	}
+
+	member, err := client.AcceptTeamInvitation(invitation.ID, invitee.ID)
+	if err != nil {
+		t.Fatalf("AcceptTeamInvitation() error = %v", err)
+	}
+	if member.Role != apitypes.TeamRoleMember || member.UserID != invitee.ID {
+		t.Errorf("Unexpected membership %+v", member)
+	}
+
+	// Invitations are single use
+	if _, err := client.AcceptTeamInvitation(invitation.ID, invitee.ID); !errors.Is(err, ErrInvitationNotPending) {
+		t.Errorf("Expected ErrInvitationNotPending on second accept, got %v", err)
+	}
+}
+
+func TestClient_AcceptTeamInvitation_Email(t *testing.T) {
+	client, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	admin := createTestUserWithDefaults(t, client)
+	invitee := createTestUser(t, client, "invitee", "hash", "user")
+	other := createTestUser(t, client, "other", "hash", "user")
+
+	team, err := client.CreateTeam("platform", admin.ID)
+	if err != nil {
+		t.Fatalf("CreateTeam() error = %v", err)
+	}
+	invite := func(email string) *apitypes.TeamInvitation {
+		t.Helper()
+		invitation, err := client.CreateTeamInvitation(team.ID, email, apitypes.TeamRoleMember, admin.ID, time.Now().Add(time.Hour))
+		if err != nil {
+			t.Fatalf("CreateTeamInvitation() error = %v", err)
+		}
+		return invitation
+	}
+
+	// The first accepted invitation gives the user its address
+	if _, err := client.AcceptTeamInvitation(invite("Invitee@Example.com").ID, invitee.ID); err != nil {
+		t.Fatalf("AcceptTeamInvitation() error = %v", err)
+	}
+	user, err := client.GetUserByEmail("invitee@example.com")
+	if err != nil {
+		t.Fatalf("GetUserByEmail() error = %v", err)
+	}
+	if user == nil || user.ID != invitee.ID {
+		t.Fatalf("Expected invitee to own the address, got %+v", user)
+	}
+
+	// Later invitations must be sent to that address
+	mismatch := invite("someone@example.com")
+	if _, err := client.AcceptTeamInvitation(mismatch.ID, invitee.ID); !errors.Is(err, ErrInvitationEmailMismatch) {
+		t.Errorf("Expected ErrInvitationEmailMismatch, got %v", err)
+	}
+	if _, err := client.AcceptTeamInvitation(mismatch.ID, other.ID); err != nil {
+		t.Errorf("Expected the rejected invitation to stay pending, got %v", err)
+	}
+
+	// and nobody else can take it
+	if _, err := client.AcceptTeamInvitation(invite("invitee@example.com").ID, admin.ID); !errors.Is(err, ErrEmailInUse) {
+		t.Errorf("Expected ErrEmailInUse, got %v", err)
+	}
+}
+
+func TestClient_AcceptTeamInvitation_ExpiredOrRevoked(t *testing.T) {
+	client, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	admin := createTestUserWithDefaults(t, client)
+	invitee := createTestUser(t, client, "invitee", "hash", "user")
+
+	team, err := client.CreateTeam("platform", admin.ID)
+	if err != nil {
+		t.Fatalf("CreateTeam() error = %v", err)
+	}
+
+	expired, err := client.CreateTeamInvitation(team.ID, "a@example.com", apitypes.TeamRoleMember, admin.ID, time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("CreateTeamInvitation() error = %v", err)
+	}
+	if _, err := client.AcceptTeamInvitation(expired.ID, invitee.ID); !errors.Is(err, ErrInvitationNotPending) {
+		t.Errorf("Expected ErrInvitationNotPending for expired invitation, got %v", err)
+	}
+
+	revoked, err := client.CreateTeamInvitation(team.ID, "b@example.com", apitypes.TeamRoleMember, admin.ID, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("CreateTeamInvitation() error = %v", err)
+	}
+	if err := client.RevokeTeamInvitation(revoked.ID); err != nil {
+		t.Fatalf("RevokeTeamInvitation() error = %v", err)
+	}
+	if _, err := client.AcceptTeamInvitation(revoked.ID, invitee.ID); !errors.Is(err, ErrInvitationNotPending) {
+		t.Errorf("Expected ErrInvitationNotPending for revoked invitation, got %v", err)
+	}
+
+	deleted, err := client.DeleteExpiredTeamInvitations()
+	if err != nil {
+		t.Fatalf("DeleteExpiredTeamInvitations() error = %v", err)
+	}
+	if deleted != 1 {
+		t.Errorf("Expected 1 expired invitation deleted, got %d", deleted)
+	}
+}
+
+func TestClient_ExtendTeamInvitation(t *testing.T) {
+	client, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	admin := createTestUserWithDefaults(t, client)
+	invitee := createTestUser(t, client, "invitee", "hash", "user")
+
+	team, err := client.CreateTeam("platform", admin.ID)
+	if err != nil {
+		t.Fatalf("CreateTeam() error = %v", err)
+	}
+
+	invitation, err := client.CreateTeamInvitation(team.ID, "invitee", apitypes.TeamRoleMember, admin.ID, time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("CreateTeamInvitation() error = %v", err)
+	}
+
+	// An expired invitation can be extended and accepted again
+	extended, err := client.ExtendTeamInvitation(invitation.ID, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("ExtendTeamInvitation() error = %v", err)
+	}
+	if !extended.ExpiresAt.After(time.Now()) {
+		t.Errorf("Expected the expiry to move into the future, got %v", extended.ExpiresAt)
+	}
+	if _, err := client.AcceptTeamInvitation(invitation.ID, invitee.ID); err != nil {
+		t.Fatalf("AcceptTeamInvitation() error = %v", err)
+	}
+
+	if _, err := client.ExtendTeamInvitation(invitation.ID, time.Now().Add(time.Hour)); !errors.Is(err, ErrInvitationNotPending) {
+		t.Errorf("Expected ErrInvitationNotPending for an accepted invitation, got %v", err)
+	}
+}
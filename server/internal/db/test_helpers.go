@@ -34,11 +34,8 @@ func setupTestDB(t *testing.T) (*Client, func()) {
 		t.Fatalf("Failed to ping test database: %v", err)
 	}
 
-	// Get migrations path (relative to this file)
-	migrationsPath := "migrations"
-
 	// Run migrations
-	if err := client.RunMigrations(migrationsPath); err != nil {
+	if _, err := client.RunMigrations(); err != nil {
 		if closeErr := client.Close(); closeErr != nil {
 			t.Errorf("Failed to close client after migration failure: %v", closeErr)
 		}
@@ -62,7 +59,7 @@ func cleanTestData(t *testing.T, client *Client) {
 
 	// TRUNCATE is faster than DELETE and resets auto-incrementing counters.
 	// CASCADE handles foreign key relationships automatically.
-	query := "TRUNCATE TABLE users, api_keys RESTART IDENTITY CASCADE"
+	query := "TRUNCATE TABLE users, api_keys, audit_logs, teams, team_members, team_invitations, user_preferences, upgrades, upgrade_targets, quotas, chart_versions, instance_notes, instance_favorites, benchmarks, instance_connections, instances, backups, backup_retention_policies RESTART IDENTITY CASCADE"
 	_, err := client.db.Exec(query)
 	if err != nil {
 		t.Fatalf("Failed to clean test data: %v", err)
@@ -0,0 +1,152 @@
+// Package db provides database operations for SupaControl.
+// This file specifically handles fleet upgrade runs and their per-instance targets.
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+)
+
+// ErrUpgradeInProgress is returned when an upgrade is created while another is still running
+var ErrUpgradeInProgress = errors.New("an upgrade is already in progress")
+
+// uniqueViolation is the PostgreSQL error code for unique constraint violations
+const uniqueViolation = "23505"
+
+// CreateUpgrade creates a running upgrade with one pending target per instance, in order.
+// The first canaryCount instances are marked as canaries.
+func (c *Client) CreateUpgrade(upgrade *apitypes.Upgrade, instanceNames []string, canaryCount int) (*apitypes.Upgrade, []*apitypes.UpgradeTarget, error) {
+	var created apitypes.Upgrade
+	targets := make([]*apitypes.UpgradeTarget, 0, len(instanceNames))
+
+	err := c.WithinTransaction(func(tx *sqlx.Tx) error {
+		if err := tx.QueryRowx(
+			`INSERT INTO upgrades (chart_version, status, concurrency, max_failures, created_by)
+			VALUES ($1, $2, $3, $4, $5)
+			RETURNING *`,
+			upgrade.ChartVersion, apitypes.UpgradeStatusRunning, upgrade.Concurrency, upgrade.MaxFailures, upgrade.CreatedBy,
+		).StructScan(&created); err != nil {
+			return err
+		}
+
+		for i, name := range instanceNames {
+			var target apitypes.UpgradeTarget
+			if err := tx.QueryRowx(
+				`INSERT INTO upgrade_targets (upgrade_id, instance_name, position, canary, status)
+				VALUES ($1, $2, $3, $4, $5)
+				RETURNING *`,
+				created.ID, name, i, i < canaryCount, apitypes.UpgradeTargetPending,
+			).StructScan(&target); err != nil {
+				return err
+			}
+			targets = append(targets, &target)
+		}
+		return nil
+	})
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation && pqErr.Constraint == "idx_upgrades_single_running" {
+			return nil, nil, ErrUpgradeInProgress
+		}
+		return nil, nil, fmt.Errorf("failed to create upgrade: %w", err)
+	}
+
+	return &created, targets, nil
+}
+
+// GetUpgrade retrieves an upgrade by ID
+func (c *Client) GetUpgrade(id int64) (*apitypes.Upgrade, error) {
+	var upgrade apitypes.Upgrade
+
+	err := c.db.Get(&upgrade, `SELECT * FROM upgrades WHERE id = $1`, id)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get upgrade: %w", err)
+	}
+
+	return &upgrade, nil
+}
+
+// ListUpgrades retrieves all upgrades, newest first
+func (c *Client) ListUpgrades() ([]*apitypes.Upgrade, error) {
+	var upgrades []*apitypes.Upgrade
+
+	if err := c.db.Select(&upgrades, `SELECT * FROM upgrades ORDER BY created_at DESC, id DESC`); err != nil {
+		return nil, fmt.Errorf("failed to list upgrades: %w", err)
+	}
+
+	return upgrades, nil
+}
+
+// ListRunningUpgrades retrieves upgrades that have not finished yet
+func (c *Client) ListRunningUpgrades() ([]*apitypes.Upgrade, error) {
+	var upgrades []*apitypes.Upgrade
+
+	if err := c.db.Select(&upgrades, `SELECT * FROM upgrades WHERE status = $1 ORDER BY id`, apitypes.UpgradeStatusRunning); err != nil {
+		return nil, fmt.Errorf("failed to list running upgrades: %w", err)
+	}
+
+	return upgrades, nil
+}
+
+// ListUpgradeTargets retrieves the targets of an upgrade in rollout order
+func (c *Client) ListUpgradeTargets(upgradeID int64) ([]*apitypes.UpgradeTarget, error) {
+	var targets []*apitypes.UpgradeTarget
+
+	if err := c.db.Select(&targets, `SELECT * FROM upgrade_targets WHERE upgrade_id = $1 ORDER BY position`, upgradeID); err != nil {
+		return nil, fmt.Errorf("failed to list upgrade targets: %w", err)
+	}
+
+	return targets, nil
+}
+
+// UpdateUpgradeTarget saves the progress of an upgrade target
+func (c *Client) UpdateUpgradeTarget(target *apitypes.UpgradeTarget) error {
+	query := `
+		UPDATE upgrade_targets
+		SET status = $1, from_version = $2, error_message = $3, started_at = $4, completed_at = $5
+		WHERE id = $6
+	`
+
+	if _, err := c.db.Exec(query, target.Status, target.FromVersion, target.ErrorMessage, target.StartedAt, target.CompletedAt, target.ID); err != nil {
+		return fmt.Errorf("failed to update upgrade target: %w", err)
+	}
+
+	return nil
+}
+
+// FinishUpgrade marks an upgrade as completed or halted. Targets that never started are skipped.
+func (c *Client) FinishUpgrade(id int64, status, haltReason string) error {
+	var reason *string
+	if haltReason != "" {
+		reason = &haltReason
+	}
+
+	err := c.WithinTransaction(func(tx *sqlx.Tx) error {
+		if _, err := tx.Exec(
+			`UPDATE upgrades SET status = $1, halt_reason = $2, completed_at = NOW() WHERE id = $3`,
+			status, reason, id,
+		); err != nil {
+			return err
+		}
+
+		_, err := tx.Exec(
+			`UPDATE upgrade_targets SET status = $1, error_message = $2 WHERE upgrade_id = $3 AND status = $4`,
+			apitypes.UpgradeTargetSkipped, reason, id, apitypes.UpgradeTargetPending,
+		)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to finish upgrade: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,93 @@
+package db
+
+import (
+	"errors"
+	"testing"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+)
+
+func TestClient_CreateUpgrade(t *testing.T) {
+	client, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	user := createTestUserWithDefaults(t, client)
+
+	upgrade, targets, err := client.CreateUpgrade(&apitypes.Upgrade{
+		ChartVersion: "0.2.0",
+		Concurrency:  2,
+		MaxFailures:  1,
+		CreatedBy:    &user.ID,
+	}, []string{"alpha", "beta", "gamma"}, 1)
+	if err != nil {
+		t.Fatalf("CreateUpgrade() error = %v", err)
+	}
+	if upgrade.Status != apitypes.UpgradeStatusRunning {
+		t.Errorf("Expected status running, got %s", upgrade.Status)
+	}
+	if len(targets) != 3 {
+		t.Fatalf("Expected 3 targets, got %d", len(targets))
+	}
+	if !targets[0].Canary || targets[1].Canary {
+		t.Errorf("Expected only the first target to be a canary")
+	}
+
+	// Only one upgrade may run at a time
+	_, _, err = client.CreateUpgrade(&apitypes.Upgrade{ChartVersion: "0.3.0", Concurrency: 1}, []string{"alpha"}, 1)
+	if !errors.Is(err, ErrUpgradeInProgress) {
+		t.Errorf("Expected ErrUpgradeInProgress, got %v", err)
+	}
+
+	running, err := client.ListRunningUpgrades()
+	if err != nil {
+		t.Fatalf("ListRunningUpgrades() error = %v", err)
+	}
+	if len(running) != 1 || running[0].ID != upgrade.ID {
+		t.Errorf("Expected the new upgrade to be running, got %+v", running)
+	}
+}
+
+func TestClient_FinishUpgrade(t *testing.T) {
+	client, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	upgrade, targets, err := client.CreateUpgrade(&apitypes.Upgrade{ChartVersion: "0.2.0", Concurrency: 1}, []string{"alpha", "beta"}, 1)
+	if err != nil {
+		t.Fatalf("CreateUpgrade() error = %v", err)
+	}
+
+	errMsg := "upgrade Job failed"
+	targets[0].Status = apitypes.UpgradeTargetFailed
+	targets[0].ErrorMessage = &errMsg
+	if err := client.UpdateUpgradeTarget(targets[0]); err != nil {
+		t.Fatalf("UpdateUpgradeTarget() error = %v", err)
+	}
+
+	if err := client.FinishUpgrade(upgrade.ID, apitypes.UpgradeStatusHalted, "canary alpha failed"); err != nil {
+		t.Fatalf("FinishUpgrade() error = %v", err)
+	}
+
+	finished, err := client.GetUpgrade(upgrade.ID)
+	if err != nil {
+		t.Fatalf("GetUpgrade() error = %v", err)
+	}
+	if finished.Status != apitypes.UpgradeStatusHalted || finished.CompletedAt == nil {
+		t.Errorf("Expected halted upgrade with completion time, got %+v", finished)
+	}
+
+	saved, err := client.ListUpgradeTargets(upgrade.ID)
+	if err != nil {
+		t.Fatalf("ListUpgradeTargets() error = %v", err)
+	}
+	if saved[0].Status != apitypes.UpgradeTargetFailed {
+		t.Errorf("Expected first target failed, got %s", saved[0].Status)
+	}
+	if saved[1].Status != apitypes.UpgradeTargetSkipped {
+		t.Errorf("Expected pending target to be skipped, got %s", saved[1].Status)
+	}
+
+	// A finished upgrade no longer blocks new ones
+	if _, _, err := client.CreateUpgrade(&apitypes.Upgrade{ChartVersion: "0.2.1", Concurrency: 1}, []string{"alpha"}, 0); err != nil {
+		t.Errorf("Expected new upgrade after halt, got %v", err)
+	}
+}
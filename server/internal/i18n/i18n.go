@@ -0,0 +1,173 @@
+// Package i18n provides the message catalog for user-facing API text and
+// negotiates the response locale from the Accept-Language header.
+//
+// Messages are keyed by their English source text, so code keeps using plain
+// readable strings and translators work from locales/<tag>.json files that map
+// each English message to its translation. Messages with no translation fall
+// back to English.
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"golang.org/x/text/language"
+)
+
+// DefaultLocale is the locale used when negotiation finds no better match
+const DefaultLocale = "en"
+
+//go:embed locales/*.json
+var localeFS embed.FS
+
+// Catalog holds the translations for every supported locale
+type Catalog struct {
+	locales  []string
+	matcher  language.Matcher
+	messages map[string]map[string]string
+}
+
+// Message is a user-facing message whose arguments are substituted after translation
+type Message struct {
+	Key  string
+	Args []interface{}
+}
+
+// Msg returns a Message for a catalog key with optional format arguments
+func Msg(key string, args ...interface{}) *Message {
+	return &Message{Key: key, Args: args}
+}
+
+// String returns the message rendered in the default locale
+func (m *Message) String() string {
+	return render(m.Key, m.Args)
+}
+
+// MarshalText renders the message in the default locale
+func (m *Message) MarshalText() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// New loads the embedded locale files into a catalog
+func New() (*Catalog, error) {
+	entries, err := localeFS.ReadDir("locales")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read locales: %w", err)
+	}
+
+	c := &Catalog{messages: make(map[string]map[string]string)}
+	for _, entry := range entries {
+		locale := strings.TrimSuffix(entry.Name(), ".json")
+		data, err := localeFS.ReadFile(path.Join("locales", entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read locale %s: %w", locale, err)
+		}
+
+		var messages map[string]string
+		if err := json.Unmarshal(data, &messages); err != nil {
+			return nil, fmt.Errorf("failed to parse locale %s: %w", locale, err)
+		}
+		c.messages[locale] = messages
+	}
+
+	if _, ok := c.messages[DefaultLocale]; !ok {
+		return nil, fmt.Errorf("default locale %q is missing", DefaultLocale)
+	}
+
+	// The default locale must come first so the matcher falls back to it
+	c.locales = []string{DefaultLocale}
+	for locale := range c.messages {
+		if locale != DefaultLocale {
+			c.locales = append(c.locales, locale)
+		}
+	}
+	sort.Strings(c.locales[1:])
+
+	tags := make([]language.Tag, len(c.locales))
+	for i, locale := range c.locales {
+		tags[i] = language.MustParse(locale)
+	}
+	c.matcher = language.NewMatcher(tags)
+
+	return c, nil
+}
+
+var defaultCatalog = mustNew()
+
+func mustNew() *Catalog {
+	c, err := New()
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+// Default returns the catalog built from the embedded locale files
+func Default() *Catalog {
+	return defaultCatalog
+}
+
+// Locales returns the supported locales, default first
+func (c *Catalog) Locales() []string {
+	return append([]string(nil), c.locales...)
+}
+
+// Keys returns every message key in the default locale
+func (c *Catalog) Keys() []string {
+	keys := make([]string, 0, len(c.messages[DefaultLocale]))
+	for key := range c.messages[DefaultLocale] {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Has reports whether the catalog contains a message key
+func (c *Catalog) Has(key string) bool {
+	_, ok := c.messages[DefaultLocale][key]
+	return ok
+}
+
+// Negotiate picks the best supported locale for an Accept-Language header value
+func (c *Catalog) Negotiate(acceptLanguage string) string {
+	if acceptLanguage == "" {
+		return DefaultLocale
+	}
+
+	tags, _, err := language.ParseAcceptLanguage(acceptLanguage)
+	if err != nil || len(tags) == 0 {
+		return DefaultLocale
+	}
+
+	_, index, confidence := c.matcher.Match(tags...)
+	if confidence == language.No {
+		return DefaultLocale
+	}
+	return c.locales[index]
+}
+
+// Translate returns the message for key in the given locale, formatted with args.
+// Unknown locales and missing translations fall back to the key itself.
+func (c *Catalog) Translate(locale, key string, args ...interface{}) string {
+	if translated, ok := c.messages[locale][key]; ok && translated != "" {
+		return render(translated, args)
+	}
+	return render(key, args)
+}
+
+// Localize renders a Message in the given locale
+func (c *Catalog) Localize(locale string, m *Message) string {
+	return c.Translate(locale, m.Key, m.Args...)
+}
+
+// render applies format arguments, leaving argument-free messages untouched
+func render(format string, args []interface{}) string {
+	if len(args) == 0 {
+		return format
+	}
+	return fmt.Sprintf(format, args...)
+}
@@ -0,0 +1,82 @@
+package i18n
+
+import (
+	"testing"
+)
+
+func TestNegotiate(t *testing.T) {
+	c := Default()
+
+	tests := []struct {
+		name           string
+		acceptLanguage string
+		want           string
+	}{
+		{name: "empty header", acceptLanguage: "", want: "en"},
+		{name: "exact match", acceptLanguage: "es", want: "es"},
+		{name: "regional variant", acceptLanguage: "de-AT", want: "de"},
+		{name: "quality ordering", acceptLanguage: "fr;q=0.9, de;q=0.8, es;q=0.5", want: "de"},
+		{name: "unsupported language", acceptLanguage: "ja", want: "en"},
+		{name: "malformed header", acceptLanguage: ";;;", want: "en"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := c.Negotiate(tt.acceptLanguage); got != tt.want {
+				t.Errorf("Negotiate(%q) = %q, want %q", tt.acceptLanguage, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTranslate(t *testing.T) {
+	c := Default()
+
+	if got := c.Translate("es", "instance not found"); got != "instancia no encontrada" {
+		t.Errorf("Translate(es) = %q", got)
+	}
+	if got := c.Translate("de", "password must be at least %d characters", 8); got != "das Passwort muss mindestens 8 Zeichen lang sein" {
+		t.Errorf("Translate(de) with args = %q", got)
+	}
+	if got := c.Translate("es", "message missing from catalog"); got != "message missing from catalog" {
+		t.Errorf("expected fallback to key, got %q", got)
+	}
+	if got := c.Translate("xx", "instance not found"); got != "instance not found" {
+		t.Errorf("expected fallback for unknown locale, got %q", got)
+	}
+}
+
+func TestMessage(t *testing.T) {
+	m := Msg("password must be at least %d characters", 8)
+
+	if got := m.String(); got != "password must be at least 8 characters" {
+		t.Errorf("String() = %q", got)
+	}
+	if got := Default().Localize("es", m); got != "la contraseña debe tener al menos 8 caracteres" {
+		t.Errorf("Localize(es) = %q", got)
+	}
+}
+
+// TestCatalogCompleteness ensures every locale translates exactly the default locale's keys
+func TestCatalogCompleteness(t *testing.T) {
+	c := Default()
+	keys := c.Keys()
+
+	if c.Locales()[0] != DefaultLocale {
+		t.Errorf("expected default locale first, got %v", c.Locales())
+	}
+
+	for _, locale := range c.Locales() {
+		messages := c.messages[locale]
+		for _, key := range keys {
+			if messages[key] == "" {
+				t.Errorf("locale %s is missing a translation for %q", locale, key)
+			}
+		}
+		for key := range messages {
+			if !c.Has(key) {
+				t.Errorf("locale %s has unknown key %q", locale, key)
+			}
+		}
+	}
+}
@@ -0,0 +1,110 @@
+// Package inventory mirrors SupabaseInstance resources into the database.
+//
+// SupabaseInstance resources are the source of truth for instance state (ADR-001). A
+// Syncer running on the elected leader periodically copies them into a read-only
+// projection, which the API reads when the Kubernetes API cannot be reached and to
+// answer questions the Kubernetes API cannot, such as which instances were created last
+// month or which have been deleted.
+package inventory
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+)
+
+// DefaultInterval is how often instances are mirrored into the database
+const DefaultInterval = 30 * time.Second
+
+// InstanceLister lists SupabaseInstance resources
+type InstanceLister interface {
+	ListSupabaseInstances(ctx context.Context) (*supacontrolv1alpha1.SupabaseInstanceList, error)
+}
+
+// Store persists instance records
+type Store interface {
+	SyncInstanceRecords(records []*apitypes.InstanceRecord) error
+}
+
+// Syncer mirrors instances into the database. It implements the controller-runtime
+// Runnable interface and only runs on the elected leader.
+type Syncer struct {
+	instances InstanceLister
+	store     Store
+
+	Interval time.Duration
+}
+
+// NewSyncer creates a syncer with the default interval
+func NewSyncer(instances InstanceLister, store Store) *Syncer {
+	return &Syncer{
+		instances: instances,
+		store:     store,
+		Interval:  DefaultInterval,
+	}
+}
+
+// NeedLeaderElection ensures only one replica writes the mirror
+func (s *Syncer) NeedLeaderElection() bool {
+	return true
+}
+
+// Start mirrors instances until ctx is cancelled
+func (s *Syncer) Start(ctx context.Context) error {
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.Sync(ctx); err != nil {
+			slog.Error("Failed to sync instance records", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Sync mirrors the current instances into the database. Nothing is written when the
+// instances cannot be listed, so an unreachable API server never marks them deleted.
+func (s *Syncer) Sync(ctx context.Context) error {
+	list, err := s.instances.ListSupabaseInstances(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list instances: %w", err)
+	}
+
+	records := make([]*apitypes.InstanceRecord, 0, len(list.Items))
+	for i := range list.Items {
+		records = append(records, Record(&list.Items[i]))
+	}
+	return s.store.SyncInstanceRecords(records)
+}
+
+// Record converts an instance to its database record
+func Record(instance *supacontrolv1alpha1.SupabaseInstance) *apitypes.InstanceRecord {
+	record := &apitypes.InstanceRecord{
+		UID:          string(instance.UID),
+		Name:         instance.Name,
+		Namespace:    instance.Status.Namespace,
+		Phase:        string(instance.Status.Phase),
+		ChartVersion: instance.Status.ChartVersion,
+		StudioURL:    instance.Status.StudioURL,
+		APIURL:       instance.Status.APIURL,
+		CreatedAt:    instance.CreationTimestamp.UTC(),
+	}
+	if ownerID, err := strconv.ParseInt(instance.Annotations[supacontrolv1alpha1.AnnotationOwnerID], 10, 64); err == nil {
+		record.OwnerID = &ownerID
+	}
+	if instance.Status.ErrorMessage != "" {
+		message := instance.Status.ErrorMessage
+		record.ErrorMessage = &message
+	}
+	return record
+}
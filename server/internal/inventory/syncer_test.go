@@ -0,0 +1,98 @@
+package inventory
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+)
+
+// fakeLister returns fixed instances or an error
+type fakeLister struct {
+	instances []supacontrolv1alpha1.SupabaseInstance
+	err       error
+}
+
+func (l *fakeLister) ListSupabaseInstances(context.Context) (*supacontrolv1alpha1.SupabaseInstanceList, error) {
+	if l.err != nil {
+		return nil, l.err
+	}
+	return &supacontrolv1alpha1.SupabaseInstanceList{Items: l.instances}, nil
+}
+
+// memoryStore keeps the last synced records
+type memoryStore struct {
+	records []*apitypes.InstanceRecord
+	syncs   int
+}
+
+func (s *memoryStore) SyncInstanceRecords(records []*apitypes.InstanceRecord) error {
+	s.records = records
+	s.syncs++
+	return nil
+}
+
+// TestSyncer_Sync tests that every instance is mirrored with its state and owner
+func TestSyncer_Sync(t *testing.T) {
+	created := time.Date(2026, 9, 14, 10, 0, 0, 0, time.UTC)
+	lister := &fakeLister{instances: []supacontrolv1alpha1.SupabaseInstance{
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              "my-app",
+				UID:               "uid-1",
+				CreationTimestamp: metav1.NewTime(created),
+				Annotations:       map[string]string{supacontrolv1alpha1.AnnotationOwnerID: "7"},
+			},
+			Status: supacontrolv1alpha1.SupabaseInstanceStatus{
+				Phase:        supacontrolv1alpha1.PhaseFailed,
+				Namespace:    "supa-my-app",
+				ChartVersion: "0.1.3",
+				ErrorMessage: "helm install failed",
+			},
+		},
+		{ObjectMeta: metav1.ObjectMeta{Name: "gitops-app", UID: "uid-2"}},
+	}}
+	store := &memoryStore{}
+
+	if err := NewSyncer(lister, store).Sync(context.Background()); err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+	if len(store.records) != 2 {
+		t.Fatalf("Expected 2 records, got %d", len(store.records))
+	}
+
+	record := store.records[0]
+	if record.UID != "uid-1" || record.Name != "my-app" || record.Namespace != "supa-my-app" ||
+		record.Phase != "Failed" || record.ChartVersion != "0.1.3" || !record.CreatedAt.Equal(created) {
+		t.Errorf("Unexpected record %+v", record)
+	}
+	if record.OwnerID == nil || *record.OwnerID != 7 {
+		t.Errorf("Expected owner 7, got %v", record.OwnerID)
+	}
+	if record.ErrorMessage == nil || *record.ErrorMessage != "helm install failed" {
+		t.Errorf("Expected the error message to be mirrored, got %v", record.ErrorMessage)
+	}
+
+	// Instances created outside the API have no owner
+	if store.records[1].OwnerID != nil || store.records[1].ErrorMessage != nil {
+		t.Errorf("Expected no owner or error, got %+v", store.records[1])
+	}
+}
+
+// TestSyncer_SyncListFailure tests that nothing is written when instances cannot be
+// listed, so they are not marked deleted
+func TestSyncer_SyncListFailure(t *testing.T) {
+	store := &memoryStore{}
+	err := NewSyncer(&fakeLister{err: fmt.Errorf("connection refused")}, store).Sync(context.Background())
+	if err == nil {
+		t.Fatal("Expected an error when instances cannot be listed")
+	}
+	if store.syncs != 0 {
+		t.Errorf("Expected no sync, got %d", store.syncs)
+	}
+}
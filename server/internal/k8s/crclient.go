@@ -3,17 +3,59 @@ package k8s
 
 import (
 	"context"
+	"errors"
+	"fmt"
 
 	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/util/retry"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
+var (
+	// ErrInstanceNotFound is returned when a SupabaseInstance CR does not exist
+	ErrInstanceNotFound = errors.New("supabase instance not found")
+
+	// ErrAlreadyExists is returned when creating a SupabaseInstance CR whose name is taken
+	ErrAlreadyExists = errors.New("supabase instance already exists")
+
+	// ErrTemplateNotFound is returned when a SupabaseInstanceTemplate does not exist
+	ErrTemplateNotFound = errors.New("supabase instance template not found")
+)
+
+// instanceError wraps not found and already exists API errors in the matching sentinel
+// error, keeping the API error in the chain for callers that inspect it
+func instanceError(err error) error {
+	switch {
+	case err == nil, errors.Is(err, ErrInstanceNotFound), errors.Is(err, ErrAlreadyExists):
+		return err
+	case apierrors.IsNotFound(err):
+		return fmt.Errorf("%w: %w", ErrInstanceNotFound, err)
+	case apierrors.IsAlreadyExists(err):
+		return fmt.Errorf("%w: %w", ErrAlreadyExists, err)
+	}
+	return err
+}
+
+// ListOptions narrows a SupabaseInstance CR listing
+type ListOptions struct {
+	// Labels restricts the listing to instances carrying all of these labels
+	Labels map[string]string
+	// Limit caps the number of instances returned. The list's Continue token then resumes
+	// the listing when passed as Continue.
+	Limit    int64
+	Continue string
+}
+
 // CRClient wraps controller-runtime client for SupabaseInstance operations
 type CRClient struct {
 	client.Client
 	scheme *runtime.Scheme
+	// apiReader serves paged listings, which a cache-backed client cannot continue
+	apiReader client.Reader
 }
 
 // NewCRClient creates a new CR client
@@ -29,11 +71,24 @@ func NewCRClient(config *rest.Config) (*CRClient, error) {
 	}
 
 	return &CRClient{
-		Client: c,
-		scheme: scheme,
+		Client:    c,
+		scheme:    scheme,
+		apiReader: c,
 	}, nil
 }
 
+// NewCachedCRClient creates a CR client whose reads are served from a watch-backed cache,
+// such as a controller manager's client, while writes go to the API server. Reads may
+// briefly lag writes; patches retry on the resulting conflicts. Paged listings are read
+// from apiReader.
+func NewCachedCRClient(c client.Client, apiReader client.Reader, scheme *runtime.Scheme) *CRClient {
+	return &CRClient{
+		Client:    c,
+		scheme:    scheme,
+		apiReader: apiReader,
+	}
+}
+
 // GetScheme returns the runtime scheme
 func (c *CRClient) GetScheme() *runtime.Scheme {
 	return c.scheme
@@ -41,14 +96,14 @@ func (c *CRClient) GetScheme() *runtime.Scheme {
 
 // CreateSupabaseInstance creates a new SupabaseInstance CR
 func (c *CRClient) CreateSupabaseInstance(ctx context.Context, instance *supacontrolv1alpha1.SupabaseInstance) error {
-	return c.Create(ctx, instance)
+	return instanceError(c.Create(ctx, instance))
 }
 
 // GetSupabaseInstance gets a SupabaseInstance CR by name
 func (c *CRClient) GetSupabaseInstance(ctx context.Context, name string) (*supacontrolv1alpha1.SupabaseInstance, error) {
 	instance := &supacontrolv1alpha1.SupabaseInstance{}
 	if err := c.Get(ctx, client.ObjectKey{Name: name}, instance); err != nil {
-		return nil, err
+		return nil, instanceError(err)
 	}
 	return instance, nil
 }
@@ -62,14 +117,105 @@ func (c *CRClient) ListSupabaseInstances(ctx context.Context) (*supacontrolv1alp
 	return list, nil
 }
 
+// ListSupabaseInstancesWithOptions lists the SupabaseInstance CRs matching opts
+func (c *CRClient) ListSupabaseInstancesWithOptions(ctx context.Context, opts ListOptions) (*supacontrolv1alpha1.SupabaseInstanceList, error) {
+	var reader client.Reader = c.Client
+	if (opts.Limit > 0 || opts.Continue != "") && c.apiReader != nil {
+		reader = c.apiReader
+	}
+
+	var listOpts []client.ListOption
+	if len(opts.Labels) > 0 {
+		listOpts = append(listOpts, client.MatchingLabels(opts.Labels))
+	}
+	if opts.Limit > 0 {
+		listOpts = append(listOpts, client.Limit(opts.Limit))
+	}
+	if opts.Continue != "" {
+		listOpts = append(listOpts, client.Continue(opts.Continue))
+	}
+
+	list := &supacontrolv1alpha1.SupabaseInstanceList{}
+	if err := reader.List(ctx, list, listOpts...); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
 // DeleteSupabaseInstance deletes a SupabaseInstance CR
 func (c *CRClient) DeleteSupabaseInstance(ctx context.Context, name string) error {
 	instance := &supacontrolv1alpha1.SupabaseInstance{}
 	instance.Name = name
-	return c.Delete(ctx, instance)
+	return instanceError(c.Delete(ctx, instance))
 }
 
 // UpdateSupabaseInstance updates a SupabaseInstance CR
 func (c *CRClient) UpdateSupabaseInstance(ctx context.Context, instance *supacontrolv1alpha1.SupabaseInstance) error {
-	return c.Update(ctx, instance)
+	return instanceError(c.Update(ctx, instance))
+}
+
+// PatchSupabaseInstance applies mutate to the latest version of a SupabaseInstance CR and
+// sends the changes as a merge patch. When the patch conflicts with a concurrent write,
+// such as a controller status update, the instance is read again from the API server,
+// which a cache may lag behind, and mutate re-applied, so mutate should check its
+// preconditions on the instance it is given. An error from mutate aborts without
+// patching and is returned as is.
+func (c *CRClient) PatchSupabaseInstance(ctx context.Context, name string, mutate func(*supacontrolv1alpha1.SupabaseInstance) error) (*supacontrolv1alpha1.SupabaseInstance, error) {
+	var patched *supacontrolv1alpha1.SupabaseInstance
+	var reader client.Reader = c.Client
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		instance := &supacontrolv1alpha1.SupabaseInstance{}
+		if err := reader.Get(ctx, client.ObjectKey{Name: name}, instance); err != nil {
+			return err
+		}
+		// A cached copy may still be the version we conflicted with
+		if c.apiReader != nil {
+			reader = c.apiReader
+		}
+		original := instance.DeepCopy()
+		if err := mutate(instance); err != nil {
+			return err
+		}
+		// The optimistic lock turns a write that raced with ours into a conflict to retry
+		patch := client.MergeFromWithOptions(original, client.MergeFromWithOptimisticLock{})
+		if err := c.Patch(ctx, instance, patch); err != nil {
+			return err
+		}
+		patched = instance
+		return nil
+	})
+	if err != nil {
+		return nil, instanceError(err)
+	}
+	return patched, nil
+}
+
+// UpdateSupabaseInstanceStatus updates the status subresource of a SupabaseInstance CR
+func (c *CRClient) UpdateSupabaseInstanceStatus(ctx context.Context, instance *supacontrolv1alpha1.SupabaseInstance) error {
+	return instanceError(c.Status().Update(ctx, instance))
+}
+
+// ListSupabaseInstanceTemplates lists all SupabaseInstanceTemplates. Installations that
+// have not installed the template CRD have none.
+func (c *CRClient) ListSupabaseInstanceTemplates(ctx context.Context) (*supacontrolv1alpha1.SupabaseInstanceTemplateList, error) {
+	list := &supacontrolv1alpha1.SupabaseInstanceTemplateList{}
+	if err := c.List(ctx, list); err != nil {
+		if meta.IsNoMatchError(err) {
+			return list, nil
+		}
+		return nil, err
+	}
+	return list, nil
+}
+
+// GetSupabaseInstanceTemplate gets a SupabaseInstanceTemplate by name
+func (c *CRClient) GetSupabaseInstanceTemplate(ctx context.Context, name string) (*supacontrolv1alpha1.SupabaseInstanceTemplate, error) {
+	template := &supacontrolv1alpha1.SupabaseInstanceTemplate{}
+	if err := c.Get(ctx, client.ObjectKey{Name: name}, template); err != nil {
+		if apierrors.IsNotFound(err) || meta.IsNoMatchError(err) {
+			return nil, fmt.Errorf("%w: %w", ErrTemplateNotFound, err)
+		}
+		return nil, err
+	}
+	return template, nil
 }
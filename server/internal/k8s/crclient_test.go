@@ -0,0 +1,268 @@
+package k8s
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+)
+
+// TestPatchSupabaseInstance tests that patches are retried on conflicts with mutate
+// re-applied to the latest instance, and that mutate errors abort the patch
+func TestPatchSupabaseInstance(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := supacontrolv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatalf("failed to build scheme: %v", err)
+	}
+	instance := &supacontrolv1alpha1.SupabaseInstance{
+		ObjectMeta: metav1.ObjectMeta{Name: "my-app"},
+		Spec:       supacontrolv1alpha1.SupabaseInstanceSpec{ProjectName: "my-app"},
+	}
+
+	conflicts := 2
+	patches := 0
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(instance).
+		WithInterceptorFuncs(interceptor.Funcs{
+			Patch: func(ctx context.Context, c client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+				patches++
+				if conflicts > 0 {
+					conflicts--
+					return apierrors.NewConflict(schema.GroupResource{Resource: "supabaseinstances"}, obj.GetName(), errors.New("object was modified"))
+				}
+				return c.Patch(ctx, obj, patch, opts...)
+			},
+		}).
+		Build()
+	crClient := &CRClient{Client: fakeClient, scheme: scheme}
+
+	mutations := 0
+	patched, err := crClient.PatchSupabaseInstance(context.Background(), "my-app", func(instance *supacontrolv1alpha1.SupabaseInstance) error {
+		mutations++
+		instance.Spec.Paused = true
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if patches != 3 || mutations != 3 {
+		t.Errorf("expected 3 attempts, got %d patches and %d mutations", patches, mutations)
+	}
+	if !patched.Spec.Paused {
+		t.Error("expected the patched instance to be returned")
+	}
+	stored, err := crClient.GetSupabaseInstance(context.Background(), "my-app")
+	if err != nil {
+		t.Fatalf("failed to get instance: %v", err)
+	}
+	if !stored.Spec.Paused {
+		t.Error("expected the patch to be stored")
+	}
+
+	abort := errors.New("already paused")
+	patches = 0
+	_, err = crClient.PatchSupabaseInstance(context.Background(), "my-app", func(*supacontrolv1alpha1.SupabaseInstance) error {
+		return abort
+	})
+	if !errors.Is(err, abort) {
+		t.Errorf("expected the mutate error, got %v", err)
+	}
+	if patches != 0 {
+		t.Errorf("expected no patch after mutate failed, got %d", patches)
+	}
+}
+
+// TestPatchSupabaseInstance_StaleCache tests that a patch conflicting because the cache
+// lags behind the API server is retried on the instance read from the API server
+func TestPatchSupabaseInstance_StaleCache(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := supacontrolv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatalf("failed to build scheme: %v", err)
+	}
+	newInstance := func() *supacontrolv1alpha1.SupabaseInstance {
+		return &supacontrolv1alpha1.SupabaseInstance{ObjectMeta: metav1.ObjectMeta{Name: "my-app"}}
+	}
+	live := fake.NewClientBuilder().WithScheme(scheme).WithObjects(newInstance()).Build()
+	// The controller changed the instance after the cache last saw it
+	current := newInstance()
+	if err := live.Get(context.Background(), client.ObjectKeyFromObject(current), current); err != nil {
+		t.Fatalf("failed to get instance: %v", err)
+	}
+	current.Spec.ProjectName = "my-app"
+	if err := live.Update(context.Background(), current); err != nil {
+		t.Fatalf("failed to update instance: %v", err)
+	}
+	cached := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(newInstance()).
+		WithInterceptorFuncs(interceptor.Funcs{
+			Patch: func(ctx context.Context, _ client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+				return live.Patch(ctx, obj, patch, opts...)
+			},
+		}).
+		Build()
+	crClient := NewCachedCRClient(cached, live, scheme)
+
+	patched, err := crClient.PatchSupabaseInstance(context.Background(), "my-app", func(instance *supacontrolv1alpha1.SupabaseInstance) error {
+		instance.Spec.Paused = true
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !patched.Spec.Paused || patched.Spec.ProjectName != "my-app" {
+		t.Errorf("expected the patch to apply to the live instance, got %+v", patched.Spec)
+	}
+}
+
+// TestCRClientSentinelErrors tests that not found and already exists errors match the
+// sentinel errors while remaining API errors
+func TestCRClientSentinelErrors(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := supacontrolv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatalf("failed to build scheme: %v", err)
+	}
+	instance := &supacontrolv1alpha1.SupabaseInstance{
+		ObjectMeta: metav1.ObjectMeta{Name: "my-app"},
+		Spec:       supacontrolv1alpha1.SupabaseInstanceSpec{ProjectName: "my-app"},
+	}
+	crClient := &CRClient{Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(instance).Build(), scheme: scheme}
+	ctx := context.Background()
+
+	_, err := crClient.GetSupabaseInstance(ctx, "missing")
+	if !errors.Is(err, ErrInstanceNotFound) || !apierrors.IsNotFound(err) {
+		t.Errorf("expected a not found error from get, got %v", err)
+	}
+	if err := crClient.DeleteSupabaseInstance(ctx, "missing"); !errors.Is(err, ErrInstanceNotFound) {
+		t.Errorf("expected a not found error from delete, got %v", err)
+	}
+	_, err = crClient.PatchSupabaseInstance(ctx, "missing", func(*supacontrolv1alpha1.SupabaseInstance) error { return nil })
+	if !errors.Is(err, ErrInstanceNotFound) {
+		t.Errorf("expected a not found error from patch, got %v", err)
+	}
+	if strings.Count(err.Error(), ErrInstanceNotFound.Error()) != 1 {
+		t.Errorf("expected the sentinel to be wrapped once, got %q", err)
+	}
+
+	err = crClient.CreateSupabaseInstance(ctx, &supacontrolv1alpha1.SupabaseInstance{ObjectMeta: metav1.ObjectMeta{Name: "my-app"}})
+	if !errors.Is(err, ErrAlreadyExists) || !apierrors.IsAlreadyExists(err) {
+		t.Errorf("expected an already exists error from create, got %v", err)
+	}
+}
+
+// TestListSupabaseInstancesWithOptions tests that listings are narrowed by labels and limits
+func TestListSupabaseInstancesWithOptions(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := supacontrolv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatalf("failed to build scheme: %v", err)
+	}
+	var objects []client.Object
+	for _, name := range []string{"app-a", "app-b", "app-c"} {
+		objects = append(objects, &supacontrolv1alpha1.SupabaseInstance{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{"team": "core"}},
+		})
+	}
+	objects = append(objects, &supacontrolv1alpha1.SupabaseInstance{ObjectMeta: metav1.ObjectMeta{Name: "other"}})
+	crClient := &CRClient{Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build(), scheme: scheme}
+
+	list, err := crClient.ListSupabaseInstancesWithOptions(context.Background(), ListOptions{Labels: map[string]string{"team": "core"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(list.Items) != 3 {
+		t.Errorf("expected 3 labelled instances, got %d", len(list.Items))
+	}
+
+	var got client.ListOptions
+	crClient.Client = interceptor.NewClient(crClient.Client.(client.WithWatch), interceptor.Funcs{
+		List: func(ctx context.Context, c client.WithWatch, list client.ObjectList, opts ...client.ListOption) error {
+			got.ApplyOptions(opts)
+			return c.List(ctx, list, opts...)
+		},
+	})
+	if _, err := crClient.ListSupabaseInstancesWithOptions(context.Background(), ListOptions{Limit: 2, Continue: "token"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.Limit != 2 || got.Continue != "token" {
+		t.Errorf("expected the limit and continue token to be passed, got %d and %q", got.Limit, got.Continue)
+	}
+}
+
+// TestCachedCRClientPagedListing tests that a cached client serves plain listings from its
+// cache and paged listings from the API reader
+func TestCachedCRClientPagedListing(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := supacontrolv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatalf("failed to build scheme: %v", err)
+	}
+	cached := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		&supacontrolv1alpha1.SupabaseInstance{ObjectMeta: metav1.ObjectMeta{Name: "app-a"}},
+	).Build()
+	live := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		&supacontrolv1alpha1.SupabaseInstance{ObjectMeta: metav1.ObjectMeta{Name: "app-a"}},
+		&supacontrolv1alpha1.SupabaseInstance{ObjectMeta: metav1.ObjectMeta{Name: "app-b"}},
+	).Build()
+	crClient := NewCachedCRClient(cached, live, scheme)
+
+	list, err := crClient.ListSupabaseInstancesWithOptions(context.Background(), ListOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(list.Items) != 1 {
+		t.Errorf("expected the cached instance, got %d instances", len(list.Items))
+	}
+
+	list, err = crClient.ListSupabaseInstancesWithOptions(context.Background(), ListOptions{Limit: 10})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(list.Items) != 2 {
+		t.Errorf("expected the live instances, got %d instances", len(list.Items))
+	}
+}
+
+// TestSupabaseInstanceTemplates tests getting and listing templates, including on
+// installations without the template CRD
+func TestSupabaseInstanceTemplates(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := supacontrolv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatalf("failed to build scheme: %v", err)
+	}
+	fakeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		&supacontrolv1alpha1.SupabaseInstanceTemplate{ObjectMeta: metav1.ObjectMeta{Name: "pro"}},
+	).Build()
+	crClient := NewCachedCRClient(fakeClient, fakeClient, scheme)
+
+	if template, err := crClient.GetSupabaseInstanceTemplate(context.Background(), "pro"); err != nil || template.Name != "pro" {
+		t.Errorf("expected template pro, got %v, %v", template, err)
+	}
+	if _, err := crClient.GetSupabaseInstanceTemplate(context.Background(), "enterprise"); !errors.Is(err, ErrTemplateNotFound) {
+		t.Errorf("expected ErrTemplateNotFound, got %v", err)
+	}
+	list, err := crClient.ListSupabaseInstanceTemplates(context.Background())
+	if err != nil || len(list.Items) != 1 {
+		t.Errorf("expected one template, got %v, %v", list, err)
+	}
+
+	noCRD := fake.NewClientBuilder().WithScheme(scheme).WithInterceptorFuncs(interceptor.Funcs{
+		List: func(ctx context.Context, c client.WithWatch, list client.ObjectList, opts ...client.ListOption) error {
+			return &meta.NoKindMatchError{GroupKind: supacontrolv1alpha1.GroupVersion.WithKind("SupabaseInstanceTemplate").GroupKind()}
+		},
+	}).Build()
+	list, err = NewCachedCRClient(noCRD, noCRD, scheme).ListSupabaseInstanceTemplates(context.Background())
+	if err != nil || len(list.Items) != 0 {
+		t.Errorf("expected no templates without the CRD, got %v, %v", list, err)
+	}
+}
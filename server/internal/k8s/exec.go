@@ -0,0 +1,61 @@
+package k8s
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/remotecommand"
+)
+
+// PodExecutor runs commands in the containers of running pods through the pods/exec API,
+// so it reaches pods whose network policies admit no traffic from the control plane
+type PodExecutor struct {
+	clientset kubernetes.Interface
+	config    *rest.Config
+}
+
+// NewPodExecutor creates an executor using the client's connection
+func NewPodExecutor(client *Client) *PodExecutor {
+	return &PodExecutor{clientset: client.clientset, config: client.config}
+}
+
+// Exec runs command in a container of a pod, writing stdin to it, and returns its
+// standard output. The error of a failing command carries its standard error.
+func (e *PodExecutor) Exec(ctx context.Context, namespace, pod, container string, command []string, stdin string) (string, error) {
+	req := e.clientset.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Namespace(namespace).
+		Name(pod).
+		SubResource("exec").
+		VersionedParams(&corev1.PodExecOptions{
+			Container: container,
+			Command:   command,
+			Stdin:     stdin != "",
+			Stdout:    true,
+			Stderr:    true,
+		}, scheme.ParameterCodec)
+
+	executor, err := remotecommand.NewSPDYExecutor(e.config, "POST", req.URL())
+	if err != nil {
+		return "", fmt.Errorf("failed to create executor: %w", err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	options := remotecommand.StreamOptions{Stdout: &stdout, Stderr: &stderr}
+	if stdin != "" {
+		options.Stdin = strings.NewReader(stdin)
+	}
+	if err := executor.StreamWithContext(ctx, options); err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return "", fmt.Errorf("%w: %s", err, message)
+		}
+		return "", err
+	}
+	return stdout.String(), nil
+}
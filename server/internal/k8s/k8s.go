@@ -57,6 +57,22 @@ func NewClient(kubeconfig string) (*Client, error) {
 	}, nil
 }
 
+// WithRateLimit returns a client connecting like c but with its own client-side rate
+// limit, so its requests do not use up the budget of c. The limit also throttles log
+// streams and exec sessions as they are opened.
+func (c *Client) WithRateLimit(qps float32, burst int) (*Client, error) {
+	config := rest.CopyConfig(c.config)
+	config.QPS = qps
+	config.Burst = burst
+	config.RateLimiter = nil
+
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create kubernetes client: %w", err)
+	}
+	return &Client{clientset: clientset, config: config}, nil
+}
+
 // GetConfig returns the Kubernetes REST config
 func (c *Client) GetConfig() *rest.Config {
 	return c.config
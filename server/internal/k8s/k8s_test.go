@@ -2,6 +2,8 @@ package k8s
 
 import (
 	"testing"
+
+	"k8s.io/client-go/rest"
 )
 
 func TestGenerateRandomString(t *testing.T) {
@@ -90,3 +92,21 @@ func TestGenerateJWTSecret(t *testing.T) {
 		t.Error("GenerateJWTSecret() generated identical secrets")
 	}
 }
+
+func TestClientWithRateLimit(t *testing.T) {
+	client := &Client{config: &rest.Config{Host: "https://kubernetes.default.svc", QPS: 50, Burst: 100}}
+
+	limited, err := client.WithRateLimit(5, 10)
+	if err != nil {
+		t.Fatalf("WithRateLimit() error = %v", err)
+	}
+	if limited.config.QPS != 5 || limited.config.Burst != 10 || limited.config.Host != client.config.Host {
+		t.Errorf("unexpected config %+v", limited.config)
+	}
+	if client.config.QPS != 50 || client.config.Burst != 100 {
+		t.Errorf("the original config was changed: %+v", client.config)
+	}
+	if limited.GetClientset() == nil {
+		t.Error("expected a clientset")
+	}
+}
@@ -2,6 +2,7 @@ package k8s
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 
@@ -10,8 +11,20 @@ import (
 	"helm.sh/helm/v3/pkg/chart/loader"
 	"helm.sh/helm/v3/pkg/cli"
 	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/storage/driver"
 )
 
+// ErrReleaseNotFound is returned when an instance has no Helm release to preview against
+var ErrReleaseNotFound = errors.New("helm release not found")
+
+// ReleasePreview holds the manifest of a release next to the one a chart upgrade would apply
+type ReleasePreview struct {
+	CurrentChartVersion string
+	TargetChartVersion  string
+	CurrentManifest     string
+	RenderedManifest    string
+}
+
 // Orchestrator handles the lifecycle of Supabase instances
 type Orchestrator struct {
 	k8sClient            *Client
@@ -20,6 +33,10 @@ type Orchestrator struct {
 	chartVersion         string
 	defaultIngressClass  string
 	defaultIngressDomain string
+
+	// chartRepoUsername and chartRepoPassword authenticate to a private chart repository
+	chartRepoUsername string
+	chartRepoPassword string
 }
 
 // NewOrchestrator creates a new orchestrator
@@ -34,6 +51,13 @@ func NewOrchestrator(k8sClient *Client, chartRepo, chartName, chartVersion, ingr
 	}
 }
 
+// WithChartRepoCredentials authenticates the orchestrator to a private chart repository
+func (o *Orchestrator) WithChartRepoCredentials(username, password string) *Orchestrator {
+	o.chartRepoUsername = username
+	o.chartRepoPassword = password
+	return o
+}
+
 // CreateInstance provisions a new Supabase instance
 func (o *Orchestrator) CreateInstance(ctx context.Context, projectName string) (*apitypes.Instance, error) {
 	log.Printf("Starting provisioning of instance: %s", projectName)
@@ -194,6 +218,8 @@ func (o *Orchestrator) installHelmChart(namespace, releaseName, postgresPassword
 		chartPath = fmt.Sprintf("%s/%s", o.chartRepo, o.chartName)
 	}
 
+	client.Username = o.chartRepoUsername
+	client.Password = o.chartRepoPassword
 	cp, err := client.LocateChart(chartPath, settings)
 	if err != nil {
 		return fmt.Errorf("failed to locate chart: %w", err)
@@ -252,3 +278,58 @@ func (o *Orchestrator) GetRelease(namespace, releaseName string) (*release.Relea
 
 	return rel, nil
 }
+
+// PreviewUpgrade renders the chart the way an upgrade Job applies it, reusing the release's
+// values and overlaying values, without changing the release. An empty chart version uses
+// the configured default, or the latest chart if none is configured.
+func (o *Orchestrator) PreviewUpgrade(ctx context.Context, namespace, releaseName, chartVersion string, values map[string]interface{}) (*ReleasePreview, error) {
+	settings := cli.New()
+	settings.SetNamespace(namespace)
+
+	actionConfig := new(action.Configuration)
+	if err := actionConfig.Init(settings.RESTClientGetter(), namespace, "secret", log.Printf); err != nil {
+		return nil, fmt.Errorf("failed to initialize helm action config: %w", err)
+	}
+
+	current, err := action.NewGet(actionConfig).Run(releaseName)
+	if err != nil {
+		if errors.Is(err, driver.ErrReleaseNotFound) {
+			return nil, ErrReleaseNotFound
+		}
+		return nil, fmt.Errorf("failed to get release: %w", err)
+	}
+
+	client := action.NewUpgrade(actionConfig)
+	client.Namespace = namespace
+	client.DryRun = true
+	client.ReuseValues = true
+	client.RepoURL = o.chartRepo
+	client.Username = o.chartRepoUsername
+	client.Password = o.chartRepoPassword
+	client.Version = chartVersion
+	if client.Version == "" {
+		client.Version = o.chartVersion
+	}
+
+	chartPath, err := client.LocateChart(o.chartName, settings)
+	if err != nil {
+		return nil, fmt.Errorf("failed to locate chart: %w", err)
+	}
+
+	chartRequested, err := loader.Load(chartPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load chart: %w", err)
+	}
+
+	rendered, err := client.RunWithContext(ctx, releaseName, chartRequested, values)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render chart: %w", err)
+	}
+
+	return &ReleasePreview{
+		CurrentChartVersion: current.Chart.Metadata.Version,
+		TargetChartVersion:  chartRequested.Metadata.Version,
+		CurrentManifest:     current.Manifest,
+		RenderedManifest:    rendered.Manifest,
+	}, nil
+}
@@ -0,0 +1,94 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/portforward"
+	"k8s.io/client-go/transport/spdy"
+)
+
+// PortForwarder connects to ports of running pods through the pods/portforward API, so
+// it reaches pods whose network policies admit no traffic from the control plane
+type PortForwarder struct {
+	clientset kubernetes.Interface
+	config    *rest.Config
+}
+
+// NewPortForwarder creates a port forwarder using the client's connection
+func NewPortForwarder(client *Client) *PortForwarder {
+	return &PortForwarder{clientset: client.clientset, config: client.config}
+}
+
+// Forward copies conn to a port of a pod and back until either side closes the
+// connection or ctx is done. It returns nil when the connection ended normally.
+func (f *PortForwarder) Forward(ctx context.Context, namespace, pod string, port int, conn io.ReadWriter) error {
+	req := f.clientset.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Namespace(namespace).
+		Name(pod).
+		SubResource("portforward")
+
+	transport, upgrader, err := spdy.RoundTripperFor(f.config)
+	if err != nil {
+		return fmt.Errorf("failed to create port forward transport: %w", err)
+	}
+	dialer := spdy.NewDialer(upgrader, &http.Client{Transport: transport}, http.MethodPost, req.URL())
+	streams, _, err := dialer.Dial(portforward.PortForwardProtocolV1Name)
+	if err != nil {
+		return fmt.Errorf("failed to open port forward: %w", err)
+	}
+	defer streams.Close()
+
+	// Each connection uses an error stream, which the kubelet writes failures to, and a
+	// data stream carrying the connection itself
+	headers := http.Header{}
+	headers.Set(corev1.StreamType, corev1.StreamTypeError)
+	headers.Set(corev1.PortHeader, strconv.Itoa(port))
+	headers.Set(corev1.PortForwardRequestIDHeader, "0")
+	errorStream, err := streams.CreateStream(headers)
+	if err != nil {
+		return fmt.Errorf("failed to create error stream: %w", err)
+	}
+	// Nothing is written to the error stream
+	_ = errorStream.Close()
+
+	headers.Set(corev1.StreamType, corev1.StreamTypeData)
+	dataStream, err := streams.CreateStream(headers)
+	if err != nil {
+		return fmt.Errorf("failed to create data stream: %w", err)
+	}
+
+	done := make(chan error, 3)
+	go func() {
+		message, err := io.ReadAll(errorStream)
+		switch {
+		case err != nil:
+			done <- fmt.Errorf("failed to read error stream: %w", err)
+		case len(message) > 0:
+			done <- fmt.Errorf("port forward to %s:%d failed: %s", pod, port, strings.TrimSpace(string(message)))
+		}
+	}()
+	go func() {
+		_, err := io.Copy(conn, dataStream)
+		done <- err
+	}()
+	go func() {
+		_, err := io.Copy(dataStream, conn)
+		done <- err
+	}()
+
+	select {
+	case <-ctx.Done():
+		return nil
+	case err := <-done:
+		return err
+	}
+}
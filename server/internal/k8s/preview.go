@@ -0,0 +1,136 @@
+package k8s
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"sort"
+
+	"github.com/pmezard/go-difflib/difflib"
+	"sigs.k8s.io/yaml"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+)
+
+// documentSeparator splits a multi-document manifest
+var documentSeparator = regexp.MustCompile(`(?m)^---\s*$`)
+
+// manifestResource is one resource of a rendered manifest, normalized for comparison
+type manifestResource struct {
+	kind      string
+	name      string
+	namespace string
+	yaml      string
+}
+
+// parseManifest splits a Helm manifest into its resources keyed by kind, namespace and
+// name. Each resource is re-marshalled so formatting differences don't show as changes.
+func parseManifest(manifest string) (map[string]manifestResource, error) {
+	resources := make(map[string]manifestResource)
+	for _, doc := range documentSeparator.Split(manifest, -1) {
+		var object map[string]interface{}
+		if err := yaml.Unmarshal([]byte(doc), &object); err != nil {
+			return nil, fmt.Errorf("failed to parse manifest: %w", err)
+		}
+		if object == nil {
+			continue
+		}
+
+		resource := manifestResource{}
+		resource.kind, _ = object["kind"].(string)
+		if metadata, ok := object["metadata"].(map[string]interface{}); ok {
+			resource.name, _ = metadata["name"].(string)
+			resource.namespace, _ = metadata["namespace"].(string)
+		}
+		if resource.kind == "Secret" {
+			redactSecret(object)
+		}
+
+		normalized, err := yaml.Marshal(object)
+		if err != nil {
+			return nil, fmt.Errorf("failed to render resource: %w", err)
+		}
+		resource.yaml = string(normalized)
+		resources[resource.kind+"/"+resource.namespace+"/"+resource.name] = resource
+	}
+	return resources, nil
+}
+
+// redactSecret replaces Secret values with a short digest, so changed values still show
+// up in a diff without being disclosed
+func redactSecret(object map[string]interface{}) {
+	for _, field := range []string{"data", "stringData"} {
+		values, ok := object[field].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		for key, value := range values {
+			sum := sha256.Sum256([]byte(fmt.Sprint(value)))
+			values[key] = "<redacted sha256:" + hex.EncodeToString(sum[:4]) + ">"
+		}
+	}
+}
+
+// unifiedDiff returns the unified diff between two versions of a resource
+func unifiedDiff(current, rendered string) string {
+	diff, _ := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(current),
+		B:        difflib.SplitLines(rendered),
+		FromFile: "current",
+		ToFile:   "preview",
+		Context:  3,
+	})
+	return diff
+}
+
+// ManifestChanges compares the live manifest of a release with a rendered one resource by
+// resource. It returns the added, removed and changed resources, sorted by kind and name,
+// and how many resources are unchanged.
+func ManifestChanges(current, rendered string) ([]apitypes.ManifestChange, int, error) {
+	before, err := parseManifest(current)
+	if err != nil {
+		return nil, 0, err
+	}
+	after, err := parseManifest(rendered)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	keys := make([]string, 0, len(before)+len(after))
+	for key := range before {
+		keys = append(keys, key)
+	}
+	for key := range after {
+		if _, ok := before[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	changes := []apitypes.ManifestChange{}
+	unchanged := 0
+	for _, key := range keys {
+		old, hadOld := before[key]
+		updated, hasNew := after[key]
+
+		change := apitypes.ManifestChange{}
+		switch {
+		case !hasNew:
+			change.Action = apitypes.ManifestRemoved
+			updated = manifestResource{kind: old.kind, name: old.name, namespace: old.namespace}
+		case !hadOld:
+			change.Action = apitypes.ManifestAdded
+		case old.yaml == updated.yaml:
+			unchanged++
+			continue
+		default:
+			change.Action = apitypes.ManifestChanged
+		}
+
+		change.Kind, change.Name, change.Namespace = updated.kind, updated.name, updated.namespace
+		change.Diff = unifiedDiff(old.yaml, updated.yaml)
+		changes = append(changes, change)
+	}
+	return changes, unchanged, nil
+}
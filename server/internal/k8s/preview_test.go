@@ -0,0 +1,111 @@
+package k8s
+
+import (
+	"strings"
+	"testing"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+)
+
+// TestManifestChanges tests the resource by resource comparison of release manifests
+func TestManifestChanges(t *testing.T) {
+	current := `---
+# Source: supabase/templates/kong.yaml
+apiVersion: v1
+kind: Service
+metadata:
+  name: my-app-kong
+spec:
+  ports:
+  - port: 8000
+---
+# Source: supabase/templates/studio.yaml
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: my-app-studio
+spec:
+  replicas: 1
+---
+# Source: supabase/templates/secret.yaml
+apiVersion: v1
+kind: Secret
+metadata:
+  name: my-app-jwt
+data:
+  secret: c2VjcmV0
+---
+# Source: supabase/templates/old.yaml
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: my-app-old
+`
+	rendered := `---
+# Source: supabase/templates/kong.yaml
+apiVersion: v1
+kind: Service
+metadata:
+  name:   my-app-kong
+spec:
+  ports:
+    - port: 8000
+---
+# Source: supabase/templates/studio.yaml
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: my-app-studio
+spec:
+  replicas: 2
+---
+# Source: supabase/templates/secret.yaml
+apiVersion: v1
+kind: Secret
+metadata:
+  name: my-app-jwt
+data:
+  secret: cm90YXRlZA==
+---
+# Source: supabase/templates/new.yaml
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: my-app-new
+`
+
+	changes, unchanged, err := ManifestChanges(current, rendered)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if unchanged != 1 {
+		t.Errorf("expected the reformatted Service to be unchanged, got %d unchanged", unchanged)
+	}
+
+	expected := []struct{ kind, name, action string }{
+		{"ConfigMap", "my-app-new", apitypes.ManifestAdded},
+		{"ConfigMap", "my-app-old", apitypes.ManifestRemoved},
+		{"Deployment", "my-app-studio", apitypes.ManifestChanged},
+		{"Secret", "my-app-jwt", apitypes.ManifestChanged},
+	}
+	if len(changes) != len(expected) {
+		t.Fatalf("expected %d changes, got %+v", len(expected), changes)
+	}
+	for i, want := range expected {
+		got := changes[i]
+		if got.Kind != want.kind || got.Name != want.name || got.Action != want.action {
+			t.Errorf("change %d: expected %s %s %s, got %s %s %s", i, want.action, want.kind, want.name, got.Action, got.Kind, got.Name)
+		}
+	}
+
+	if diff := changes[2].Diff; !strings.Contains(diff, "-  replicas: 1") || !strings.Contains(diff, "+  replicas: 2") {
+		t.Errorf("expected the replica change in the diff, got:\n%s", diff)
+	}
+	secretDiff := changes[3].Diff
+	if strings.Contains(secretDiff, "c2VjcmV0") || strings.Contains(secretDiff, "cm90YXRlZA==") {
+		t.Errorf("expected secret values to be redacted, got:\n%s", secretDiff)
+	}
+	if !strings.Contains(secretDiff, "<redacted sha256:") {
+		t.Errorf("expected redacted secret values in the diff, got:\n%s", secretDiff)
+	}
+}
@@ -0,0 +1,246 @@
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// NamespaceUsage is the resource usage of the pods and volumes in a namespace
+type NamespaceUsage struct {
+	Pods []apitypes.PodMetrics
+
+	// VolumeUsedBytes maps persistent volume claim names to their used bytes.
+	// It is nil when the source could not report volume usage.
+	VolumeUsedBytes map[string]int64
+}
+
+// MetricsServerSource reads pod usage from the metrics.k8s.io API served by
+// metrics-server, and volume usage from the kubelet stats summary of each node
+type MetricsServerSource struct {
+	clientset kubernetes.Interface
+
+	// get fetches a raw API server path; replaced in tests
+	get func(ctx context.Context, path string) ([]byte, error)
+}
+
+// NewMetricsServerSource creates a usage source backed by metrics-server
+func NewMetricsServerSource(clientset kubernetes.Interface) *MetricsServerSource {
+	return &MetricsServerSource{
+		clientset: clientset,
+		get: func(ctx context.Context, path string) ([]byte, error) {
+			return clientset.Discovery().RESTClient().Get().AbsPath(path).DoRaw(ctx)
+		},
+	}
+}
+
+// Name identifies the source in usage reports
+func (s *MetricsServerSource) Name() string {
+	return apitypes.MetricsSourceMetricsServer
+}
+
+// podMetricsList is the subset of a metrics.k8s.io PodMetricsList used here
+type podMetricsList struct {
+	Items []struct {
+		Metadata struct {
+			Name string `json:"name"`
+		} `json:"metadata"`
+		Containers []struct {
+			Usage map[string]resource.Quantity `json:"usage"`
+		} `json:"containers"`
+	} `json:"items"`
+}
+
+// statsSummary is the subset of a kubelet stats summary used here
+type statsSummary struct {
+	Pods []struct {
+		Volumes []struct {
+			UsedBytes *int64 `json:"usedBytes"`
+			PVCRef    *struct {
+				Name      string `json:"name"`
+				Namespace string `json:"namespace"`
+			} `json:"pvcRef"`
+		} `json:"volume"`
+	} `json:"pods"`
+}
+
+// NamespaceUsage reports the current usage of the pods and volumes in namespace.
+// Volume usage is best effort, as reading kubelet stats needs nodes/proxy access.
+func (s *MetricsServerSource) NamespaceUsage(ctx context.Context, namespace string) (*NamespaceUsage, error) {
+	raw, err := s.get(ctx, "/apis/metrics.k8s.io/v1beta1/namespaces/"+namespace+"/pods")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query metrics-server: %w", err)
+	}
+	var list podMetricsList
+	if err := json.Unmarshal(raw, &list); err != nil {
+		return nil, fmt.Errorf("failed to decode pod metrics: %w", err)
+	}
+
+	usage := &NamespaceUsage{}
+	for _, item := range list.Items {
+		pod := apitypes.PodMetrics{Name: item.Metadata.Name}
+		for _, container := range item.Containers {
+			if cpu, ok := container.Usage["cpu"]; ok {
+				pod.CPUMillicores += cpu.MilliValue()
+			}
+			if memory, ok := container.Usage["memory"]; ok {
+				pod.MemoryBytes += memory.Value()
+			}
+		}
+		usage.Pods = append(usage.Pods, pod)
+	}
+	sort.Slice(usage.Pods, func(i, j int) bool { return usage.Pods[i].Name < usage.Pods[j].Name })
+
+	usage.VolumeUsedBytes, _ = s.volumeUsage(ctx, namespace)
+	return usage, nil
+}
+
+// volumeUsage reads the used bytes of the namespace's claims from the stats summary
+// of every node running one of its pods
+func (s *MetricsServerSource) volumeUsage(ctx context.Context, namespace string) (map[string]int64, error) {
+	pods, err := s.clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	nodes := make(map[string]bool)
+	for _, pod := range pods.Items {
+		if pod.Spec.NodeName != "" {
+			nodes[pod.Spec.NodeName] = true
+		}
+	}
+
+	used := make(map[string]int64)
+	for node := range nodes {
+		raw, err := s.get(ctx, "/api/v1/nodes/"+node+"/proxy/stats/summary")
+		if err != nil {
+			return nil, fmt.Errorf("failed to read stats summary of node %s: %w", node, err)
+		}
+		var summary statsSummary
+		if err := json.Unmarshal(raw, &summary); err != nil {
+			return nil, fmt.Errorf("failed to decode stats summary of node %s: %w", node, err)
+		}
+		for _, pod := range summary.Pods {
+			for _, volume := range pod.Volumes {
+				if volume.PVCRef != nil && volume.PVCRef.Namespace == namespace && volume.UsedBytes != nil {
+					used[volume.PVCRef.Name] = *volume.UsedBytes
+				}
+			}
+		}
+	}
+	return used, nil
+}
+
+// PrometheusSource reads pod and volume usage from the cAdvisor and kubelet
+// metrics scraped by a Prometheus server
+type PrometheusSource struct {
+	url    string
+	client *http.Client
+}
+
+// NewPrometheusSource creates a usage source querying the Prometheus server at baseURL
+func NewPrometheusSource(baseURL string) *PrometheusSource {
+	return &PrometheusSource{
+		url:    strings.TrimSuffix(baseURL, "/"),
+		client: &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+// Name identifies the source in usage reports
+func (s *PrometheusSource) Name() string {
+	return apitypes.MetricsSourcePrometheus
+}
+
+// NamespaceUsage reports the current usage of the pods and volumes in namespace
+func (s *PrometheusSource) NamespaceUsage(ctx context.Context, namespace string) (*NamespaceUsage, error) {
+	selector := fmt.Sprintf(`namespace=%q`, namespace)
+	cpu, err := s.query(ctx, "pod", `sum by (pod) (rate(container_cpu_usage_seconds_total{`+selector+`,container!=""}[5m]))`)
+	if err != nil {
+		return nil, err
+	}
+	memory, err := s.query(ctx, "pod", `sum by (pod) (container_memory_working_set_bytes{`+selector+`,container!=""})`)
+	if err != nil {
+		return nil, err
+	}
+	volumes, err := s.query(ctx, "persistentvolumeclaim", `sum by (persistentvolumeclaim) (kubelet_volume_stats_used_bytes{`+selector+`})`)
+	if err != nil {
+		return nil, err
+	}
+
+	usage := &NamespaceUsage{VolumeUsedBytes: make(map[string]int64, len(volumes))}
+	for name, cores := range cpu {
+		usage.Pods = append(usage.Pods, apitypes.PodMetrics{
+			Name:          name,
+			CPUMillicores: int64(math.Round(cores * 1000)),
+			MemoryBytes:   int64(memory[name]),
+		})
+	}
+	for name, bytes := range memory {
+		if _, ok := cpu[name]; !ok {
+			usage.Pods = append(usage.Pods, apitypes.PodMetrics{Name: name, MemoryBytes: int64(bytes)})
+		}
+	}
+	sort.Slice(usage.Pods, func(i, j int) bool { return usage.Pods[i].Name < usage.Pods[j].Name })
+	for name, bytes := range volumes {
+		usage.VolumeUsedBytes[name] = int64(bytes)
+	}
+	return usage, nil
+}
+
+// promResponse is the Prometheus instant query response
+type promResponse struct {
+	Status string `json:"status"`
+	Error  string `json:"error"`
+	Data   struct {
+		Result []struct {
+			Metric map[string]string `json:"metric"`
+			Value  [2]interface{}    `json:"value"`
+		} `json:"result"`
+	} `json:"data"`
+}
+
+// query runs an instant vector query and returns the sample values keyed by the given label
+func (s *PrometheusSource) query(ctx context.Context, label, promql string) (map[string]float64, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url+"/api/v1/query?query="+url.QueryEscape(promql), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query prometheus: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	var body promResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("failed to decode prometheus response (%s): %w", resp.Status, err)
+	}
+	if body.Status != "success" {
+		return nil, fmt.Errorf("prometheus query failed: %s", body.Error)
+	}
+
+	values := make(map[string]float64, len(body.Data.Result))
+	for _, sample := range body.Data.Result {
+		raw, ok := sample.Value[1].(string)
+		if !ok {
+			continue
+		}
+		value, err := strconv.ParseFloat(raw, 64)
+		if err != nil || math.IsNaN(value) {
+			continue
+		}
+		values[sample.Metric[label]] = value
+	}
+	return values, nil
+}
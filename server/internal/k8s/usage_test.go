@@ -0,0 +1,120 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestMetricsServerSource_NamespaceUsage(t *testing.T) {
+	clientset := fake.NewSimpleClientset(&corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "db-0", Namespace: "supa-app"},
+		Spec:       corev1.PodSpec{NodeName: "node-a"},
+	})
+	responses := map[string]string{
+		"/apis/metrics.k8s.io/v1beta1/namespaces/supa-app/pods": `{"items":[
+			{"metadata":{"name":"kong-1"},"containers":[{"usage":{"cpu":"5m","memory":"64Mi"}}]},
+			{"metadata":{"name":"db-0"},"containers":[
+				{"usage":{"cpu":"250m","memory":"512Mi"}},
+				{"usage":{"cpu":"1500000n","memory":"1Mi"}}
+			]}
+		]}`,
+		"/api/v1/nodes/node-a/proxy/stats/summary": `{"pods":[
+			{"volume":[{"name":"data","usedBytes":1073741824,"pvcRef":{"name":"db-data","namespace":"supa-app"}}]},
+			{"volume":[{"name":"data","usedBytes":5,"pvcRef":{"name":"other","namespace":"supa-other"}}]}
+		]}`,
+	}
+	source := &MetricsServerSource{
+		clientset: clientset,
+		get: func(ctx context.Context, path string) ([]byte, error) {
+			body, ok := responses[path]
+			if !ok {
+				return nil, fmt.Errorf("unexpected path %s", path)
+			}
+			return []byte(body), nil
+		},
+	}
+
+	usage, err := source.NamespaceUsage(context.Background(), "supa-app")
+	if err != nil {
+		t.Fatalf("NamespaceUsage() error = %v", err)
+	}
+	if len(usage.Pods) != 2 {
+		t.Fatalf("NamespaceUsage() pods = %+v, want 2", usage.Pods)
+	}
+	db := usage.Pods[0]
+	if db.Name != "db-0" || db.CPUMillicores != 252 || db.MemoryBytes != 513<<20 {
+		t.Errorf("db pod = %+v, want 252m CPU and 513Mi memory", db)
+	}
+	if len(usage.VolumeUsedBytes) != 1 || usage.VolumeUsedBytes["db-data"] != 1<<30 {
+		t.Errorf("VolumeUsedBytes = %v, want only db-data at 1Gi", usage.VolumeUsedBytes)
+	}
+}
+
+func TestMetricsServerSource_Unavailable(t *testing.T) {
+	source := &MetricsServerSource{
+		clientset: fake.NewSimpleClientset(),
+		get: func(ctx context.Context, path string) ([]byte, error) {
+			return nil, fmt.Errorf("the server could not find the requested resource")
+		},
+	}
+	if _, err := source.NamespaceUsage(context.Background(), "supa-app"); err == nil {
+		t.Error("NamespaceUsage() succeeded without metrics-server")
+	}
+}
+
+func TestPrometheusSource_NamespaceUsage(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query().Get("query")
+		if !strings.Contains(query, `namespace="supa-app"`) {
+			t.Errorf("query %q is not scoped to the instance namespace", query)
+		}
+		switch {
+		case strings.Contains(query, "container_cpu_usage_seconds_total"):
+			fmt.Fprint(w, `{"status":"success","data":{"resultType":"vector","result":[{"metric":{"pod":"db-0"},"value":[1700000000,"0.25"]}]}}`)
+		case strings.Contains(query, "container_memory_working_set_bytes"):
+			fmt.Fprint(w, `{"status":"success","data":{"resultType":"vector","result":[{"metric":{"pod":"db-0"},"value":[1700000000,"536870912"]},{"metric":{"pod":"studio-1"},"value":[1700000000,"1024"]}]}}`)
+		case strings.Contains(query, "kubelet_volume_stats_used_bytes"):
+			fmt.Fprint(w, `{"status":"success","data":{"resultType":"vector","result":[{"metric":{"persistentvolumeclaim":"db-data"},"value":[1700000000,"2048"]}]}}`)
+		default:
+			fmt.Fprint(w, `{"status":"error","error":"unexpected query"}`)
+		}
+	}))
+	defer server.Close()
+
+	usage, err := NewPrometheusSource(server.URL).NamespaceUsage(context.Background(), "supa-app")
+	if err != nil {
+		t.Fatalf("NamespaceUsage() error = %v", err)
+	}
+	if len(usage.Pods) != 2 {
+		t.Fatalf("NamespaceUsage() pods = %+v, want 2", usage.Pods)
+	}
+	if usage.Pods[0].Name != "db-0" || usage.Pods[0].CPUMillicores != 250 || usage.Pods[0].MemoryBytes != 512<<20 {
+		t.Errorf("db pod = %+v, want 250m CPU and 512Mi memory", usage.Pods[0])
+	}
+	if usage.Pods[1].Name != "studio-1" || usage.Pods[1].MemoryBytes != 1024 {
+		t.Errorf("studio pod = %+v, want 1024 bytes memory", usage.Pods[1])
+	}
+	if usage.VolumeUsedBytes["db-data"] != 2048 {
+		t.Errorf("VolumeUsedBytes = %v, want db-data at 2048", usage.VolumeUsedBytes)
+	}
+}
+
+func TestPrometheusSource_QueryError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"status":"error","errorType":"bad_data","error":"parse error"}`)
+	}))
+	defer server.Close()
+
+	if _, err := NewPrometheusSource(server.URL).NamespaceUsage(context.Background(), "supa-app"); err == nil {
+		t.Error("NamespaceUsage() succeeded on a failed query")
+	}
+}
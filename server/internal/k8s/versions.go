@@ -0,0 +1,295 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/cli"
+	"helm.sh/helm/v3/pkg/getter"
+	"helm.sh/helm/v3/pkg/repo"
+)
+
+// chartCacheTTL bounds how long resolved chart component versions are reused
+const chartCacheTTL = time.Hour
+
+// componentImages maps the last path segment of an image repository to a Supabase component
+var componentImages = map[string]string{
+	"postgres":  apitypes.ComponentPostgres,
+	"gotrue":    apitypes.ComponentGoTrue,
+	"auth":      apitypes.ComponentGoTrue,
+	"postgrest": apitypes.ComponentPostgREST,
+	"kong":      apitypes.ComponentKong,
+	"studio":    apitypes.ComponentStudio,
+}
+
+// Components returns the reported Supabase components in display order
+func Components() []string {
+	return []string{
+		apitypes.ComponentPostgres,
+		apitypes.ComponentGoTrue,
+		apitypes.ComponentPostgREST,
+		apitypes.ComponentKong,
+		apitypes.ComponentStudio,
+	}
+}
+
+// ParseImage splits a container image reference into repository and tag.
+// Digests are dropped and a missing tag is reported as "latest".
+func ParseImage(image string) (repository, tag string) {
+	if at := strings.Index(image, "@"); at >= 0 {
+		image = image[:at]
+	}
+
+	// A colon after the last slash separates the tag; earlier colons belong to a registry port
+	slash := strings.LastIndex(image, "/")
+	if colon := strings.LastIndex(image, ":"); colon > slash {
+		return image[:colon], image[colon+1:]
+	}
+	return image, "latest"
+}
+
+// ComponentForImage identifies the Supabase component an image belongs to
+func ComponentForImage(image string) (string, bool) {
+	repository, _ := ParseImage(image)
+	name := repository[strings.LastIndex(repository, "/")+1:]
+	component, ok := componentImages[name]
+	return component, ok
+}
+
+// CompareVersions compares two dotted version strings numerically, ignoring a leading "v".
+// The boolean is false when either version is not purely numeric (e.g. date-based tags).
+func CompareVersions(a, b string) (int, bool) {
+	as := strings.Split(strings.TrimPrefix(a, "v"), ".")
+	bs := strings.Split(strings.TrimPrefix(b, "v"), ".")
+
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var av, bv int
+		var err error
+		if i < len(as) {
+			if av, err = strconv.Atoi(as[i]); err != nil {
+				return 0, false
+			}
+		}
+		if i < len(bs) {
+			if bv, err = strconv.Atoi(bs[i]); err != nil {
+				return 0, false
+			}
+		}
+		if av != bv {
+			if av < bv {
+				return -1, true
+			}
+			return 1, true
+		}
+	}
+	return 0, true
+}
+
+// IsUpdateAvailable reports whether target is a newer version than current.
+// Non-numeric versions are considered updatable whenever they differ.
+func IsUpdateAvailable(current, target string) bool {
+	if current == "" || target == "" || current == target {
+		return false
+	}
+	if cmp, ok := CompareVersions(current, target); ok {
+		return cmp < 0
+	}
+	return true
+}
+
+// ImageVersionsFromValues extracts component versions from Helm chart values by finding
+// every "image" block with a repository and tag
+func ImageVersionsFromValues(values map[string]interface{}) map[string]string {
+	versions := make(map[string]string)
+	collectImageVersions(values, versions)
+	return versions
+}
+
+func collectImageVersions(values map[string]interface{}, versions map[string]string) {
+	for key, value := range values {
+		nested, ok := value.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		if key == "image" {
+			repository, _ := nested["repository"].(string)
+			tag := fmt.Sprint(nested["tag"])
+			if repository != "" && nested["tag"] != nil && tag != "" {
+				if component, ok := ComponentForImage(repository); ok {
+					if _, seen := versions[component]; !seen {
+						versions[component] = tag
+					}
+				}
+			}
+			continue
+		}
+
+		collectImageVersions(nested, versions)
+	}
+}
+
+// ChartComponents describes the component versions shipped by a chart version
+type ChartComponents struct {
+	ChartVersion string
+	Versions     map[string]string
+}
+
+type cachedChartComponents struct {
+	components *ChartComponents
+	fetchedAt  time.Time
+}
+
+// ChartInspector reads component image versions from the Supabase Helm chart
+type ChartInspector struct {
+	chartRepo      string
+	chartName      string
+	defaultVersion string
+
+	// username and password authenticate to a private chart repository
+	username string
+	password string
+
+	mu    sync.Mutex
+	cache map[string]cachedChartComponents
+
+	// versions caches the chart versions published in the repository
+	versions          []string
+	versionsFetchedAt time.Time
+}
+
+// NewChartInspector creates a chart inspector for the given chart repository
+func NewChartInspector(chartRepo, chartName, defaultVersion string) *ChartInspector {
+	return &ChartInspector{
+		chartRepo:      chartRepo,
+		chartName:      chartName,
+		defaultVersion: defaultVersion,
+		cache:          make(map[string]cachedChartComponents),
+	}
+}
+
+// WithCredentials authenticates the inspector to a private chart repository
+func (i *ChartInspector) WithCredentials(username, password string) *ChartInspector {
+	i.username = username
+	i.password = password
+	return i
+}
+
+// ComponentVersions returns the component versions of a chart version.
+// An empty version uses the configured default, or the latest chart if none is configured.
+func (i *ChartInspector) ComponentVersions(ctx context.Context, version string) (*ChartComponents, error) {
+	if version == "" {
+		version = i.defaultVersion
+	}
+
+	i.mu.Lock()
+	cached, ok := i.cache[version]
+	i.mu.Unlock()
+	if ok && time.Since(cached.fetchedAt) < chartCacheTTL {
+		return cached.components, nil
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	settings := cli.New()
+	pathOptions := action.ChartPathOptions{
+		RepoURL:  i.chartRepo,
+		Version:  version,
+		Username: i.username,
+		Password: i.password,
+	}
+
+	chartPath, err := pathOptions.LocateChart(i.chartName, settings)
+	if err != nil {
+		return nil, fmt.Errorf("failed to locate chart: %w", err)
+	}
+
+	chart, err := loader.Load(chartPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load chart: %w", err)
+	}
+
+	components := &ChartComponents{
+		ChartVersion: chart.Metadata.Version,
+		Versions:     ImageVersionsFromValues(chart.Values),
+	}
+
+	i.mu.Lock()
+	i.cache[version] = cachedChartComponents{components: components, fetchedAt: time.Now()}
+	i.mu.Unlock()
+
+	return components, nil
+}
+
+// ChartVersions lists the versions of the chart published in the repository, newest first.
+// Only classic HTTP repositories have an index to list; OCI registries return an error.
+func (i *ChartInspector) ChartVersions(ctx context.Context) ([]string, error) {
+	i.mu.Lock()
+	versions, fetchedAt := i.versions, i.versionsFetchedAt
+	i.mu.Unlock()
+	if versions != nil && time.Since(fetchedAt) < chartCacheTTL {
+		return versions, nil
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	cacheDir, err := os.MkdirTemp("", "supacontrol-chart-index-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create index cache: %w", err)
+	}
+	defer func() { _ = os.RemoveAll(cacheDir) }()
+
+	chartRepo, err := repo.NewChartRepository(&repo.Entry{
+		Name:     i.chartName,
+		URL:      i.chartRepo,
+		Username: i.username,
+		Password: i.password,
+	}, getter.All(cli.New()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open chart repository: %w", err)
+	}
+	chartRepo.CachePath = cacheDir
+
+	indexPath, err := chartRepo.DownloadIndexFile()
+	if err != nil {
+		return nil, fmt.Errorf("failed to download chart index: %w", err)
+	}
+	index, err := repo.LoadIndexFile(indexPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load chart index: %w", err)
+	}
+
+	versions = chartVersionsFromIndex(index, i.chartName)
+	if len(versions) == 0 {
+		return nil, fmt.Errorf("chart %q not found in %s", i.chartName, i.chartRepo)
+	}
+
+	i.mu.Lock()
+	i.versions, i.versionsFetchedAt = versions, time.Now()
+	i.mu.Unlock()
+
+	return versions, nil
+}
+
+// chartVersionsFromIndex returns the versions of a chart in a repository index, newest first
+func chartVersionsFromIndex(index *repo.IndexFile, chartName string) []string {
+	index.SortEntries()
+	entries := index.Entries[chartName]
+	versions := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		versions = append(versions, entry.Version)
+	}
+	return versions
+}
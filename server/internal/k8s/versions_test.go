@@ -0,0 +1,149 @@
+package k8s
+
+import (
+	"testing"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/repo"
+)
+
+func TestParseImage(t *testing.T) {
+	tests := []struct {
+		image      string
+		repository string
+		tag        string
+	}{
+		{image: "supabase/postgres:15.1.0.147", repository: "supabase/postgres", tag: "15.1.0.147"},
+		{image: "kong", repository: "kong", tag: "latest"},
+		{image: "registry.local:5000/supabase/studio:20240326-5e5586d", repository: "registry.local:5000/supabase/studio", tag: "20240326-5e5586d"},
+		{image: "registry.local:5000/kong", repository: "registry.local:5000/kong", tag: "latest"},
+		{image: "supabase/gotrue:v2.151.0@sha256:abc", repository: "supabase/gotrue", tag: "v2.151.0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.image, func(t *testing.T) {
+			repository, tag := ParseImage(tt.image)
+			if repository != tt.repository || tag != tt.tag {
+				t.Errorf("ParseImage(%q) = %q, %q; want %q, %q", tt.image, repository, tag, tt.repository, tt.tag)
+			}
+		})
+	}
+}
+
+func TestComponentForImage(t *testing.T) {
+	tests := []struct {
+		image     string
+		component string
+		ok        bool
+	}{
+		{image: "supabase/postgres:15.1.0.147", component: apitypes.ComponentPostgres, ok: true},
+		{image: "supabase/gotrue:v2.151.0", component: apitypes.ComponentGoTrue, ok: true},
+		{image: "supabase/auth:v2.160.0", component: apitypes.ComponentGoTrue, ok: true},
+		{image: "postgrest/postgrest:v12.2.0", component: apitypes.ComponentPostgREST, ok: true},
+		{image: "kong:2.8.1", component: apitypes.ComponentKong, ok: true},
+		{image: "supabase/studio:latest", component: apitypes.ComponentStudio, ok: true},
+		{image: "supabase/realtime:v2.28.32", ok: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.image, func(t *testing.T) {
+			component, ok := ComponentForImage(tt.image)
+			if ok != tt.ok || component != tt.component {
+				t.Errorf("ComponentForImage(%q) = %q, %t; want %q, %t", tt.image, component, ok, tt.component, tt.ok)
+			}
+		})
+	}
+}
+
+func TestIsUpdateAvailable(t *testing.T) {
+	tests := []struct {
+		name    string
+		current string
+		target  string
+		want    bool
+	}{
+		{name: "newer patch", current: "v2.151.0", target: "v2.151.1", want: true},
+		{name: "same version", current: "15.1.0.147", target: "15.1.0.147", want: false},
+		{name: "older target", current: "v12.2.0", target: "v12.1.0", want: false},
+		{name: "numeric not lexical", current: "2.9.0", target: "2.10.0", want: true},
+		{name: "date tags differ", current: "20240101-aaaaaaa", target: "20240326-5e5586d", want: true},
+		{name: "unknown target", current: "2.8.1", target: "", want: false},
+		{name: "not running", current: "", target: "2.8.1", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsUpdateAvailable(tt.current, tt.target); got != tt.want {
+				t.Errorf("IsUpdateAvailable(%q, %q) = %t, want %t", tt.current, tt.target, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestImageVersionsFromValues(t *testing.T) {
+	values := map[string]interface{}{
+		"db": map[string]interface{}{
+			"enabled": true,
+			"image": map[string]interface{}{
+				"repository": "supabase/postgres",
+				"tag":        "15.1.0.147",
+			},
+		},
+		"auth": map[string]interface{}{
+			"image": map[string]interface{}{
+				"repository": "supabase/gotrue",
+				"tag":        "v2.151.0",
+			},
+		},
+		"kong": map[string]interface{}{
+			"image": map[string]interface{}{
+				"repository": "kong",
+				"tag":        2.8,
+			},
+		},
+		"realtime": map[string]interface{}{
+			"image": map[string]interface{}{
+				"repository": "supabase/realtime",
+				"tag":        "v2.28.32",
+			},
+		},
+	}
+
+	versions := ImageVersionsFromValues(values)
+
+	expected := map[string]string{
+		apitypes.ComponentPostgres: "15.1.0.147",
+		apitypes.ComponentGoTrue:   "v2.151.0",
+		apitypes.ComponentKong:     "2.8",
+	}
+	if len(versions) != len(expected) {
+		t.Errorf("expected %d versions, got %v", len(expected), versions)
+	}
+	for component, version := range expected {
+		if versions[component] != version {
+			t.Errorf("expected %s version %q, got %q", component, version, versions[component])
+		}
+	}
+}
+
+func TestChartVersionsFromIndex(t *testing.T) {
+	index := repo.NewIndexFile()
+	for _, md := range []*chart.Metadata{
+		{APIVersion: chart.APIVersionV2, Name: "supabase", Version: "0.1.2"},
+		{APIVersion: chart.APIVersionV2, Name: "supabase", Version: "0.1.10"},
+		{APIVersion: chart.APIVersionV2, Name: "other", Version: "9.9.9"},
+	} {
+		if err := index.MustAdd(md, md.Name+"-"+md.Version+".tgz", "https://charts.example.com", ""); err != nil {
+			t.Fatalf("failed to add %s: %v", md.Version, err)
+		}
+	}
+
+	versions := chartVersionsFromIndex(index, "supabase")
+	if len(versions) != 2 || versions[0] != "0.1.10" || versions[1] != "0.1.2" {
+		t.Errorf("expected [0.1.10 0.1.2], got %v", versions)
+	}
+	if versions := chartVersionsFromIndex(index, "missing"); len(versions) != 0 {
+		t.Errorf("expected no versions of a missing chart, got %v", versions)
+	}
+}
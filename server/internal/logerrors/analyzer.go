@@ -0,0 +1,370 @@
+// Package logerrors summarizes the errors Supabase components log.
+//
+// An Analyzer periodically reads new log lines from the Kong, GoTrue and Postgres
+// containers of every running instance, matches them against known error patterns
+// (Kong 5xx responses, GoTrue error-level entries, Postgres FATAL and PANIC messages)
+// and keeps per-minute counts and the latest sample lines for a rolling window. The
+// summaries are held in memory, so every API replica runs its own analyzer.
+package logerrors
+
+import (
+	"bufio"
+	"context"
+	"log/slog"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/utils/ptr"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+	"github.com/qubitquilt/supacontrol/server/internal/k8s"
+)
+
+const (
+	// DefaultInterval is how often new log lines are read
+	DefaultInterval = 30 * time.Second
+
+	// DefaultWindow is how far back error counts reach
+	DefaultWindow = time.Hour
+
+	// maxSamples is the number of sample lines kept per component and error kind
+	maxSamples = 5
+
+	// maxSampleLength truncates long sample lines
+	maxSampleLength = 500
+
+	// maxLogBytes bounds how much of a container's log is read per interval
+	maxLogBytes = 1 << 20
+)
+
+// rule recognizes one kind of error in a component's log
+type rule struct {
+	component string
+	kind      string
+	pattern   *regexp.Regexp
+}
+
+// rules are the error patterns matched against component logs
+var rules = []rule{
+	// Kong's access log follows the nginx combined format: "GET /path HTTP/1.1" 502 ...
+	{component: apitypes.ComponentKong, kind: apitypes.ErrorKindHTTP5xx, pattern: regexp.MustCompile(`" 5\d\d \d`)},
+	// GoTrue logs JSON entries with a level field
+	{component: apitypes.ComponentGoTrue, kind: apitypes.ErrorKindError, pattern: regexp.MustCompile(`"level":"(error|fatal|panic)"`)},
+	{component: apitypes.ComponentPostgres, kind: apitypes.ErrorKindFatal, pattern: regexp.MustCompile(`\b(FATAL|PANIC):`)},
+}
+
+// watched reports whether a component's logs are analyzed
+func watched(component string) bool {
+	for _, r := range rules {
+		if r.component == component {
+			return true
+		}
+	}
+	return false
+}
+
+// InstanceLister lists SupabaseInstance resources
+type InstanceLister interface {
+	ListSupabaseInstances(ctx context.Context) (*supacontrolv1alpha1.SupabaseInstanceList, error)
+}
+
+// series holds the per-minute counts and latest samples of one component and error kind
+type series struct {
+	counts   map[int64]int // keyed by Unix minute
+	samples  []apitypes.ErrorSample
+	lastSeen time.Time
+}
+
+// instanceState is what the analyzer knows about one instance
+type instanceState struct {
+	series map[string]*series // keyed by component/kind
+	// read records the timestamp of the last line read per pod/container, so lines
+	// are not counted twice
+	read    map[string]time.Time
+	updated time.Time
+}
+
+// Analyzer reads instance logs and maintains their error summaries. It implements the
+// controller-runtime Runnable interface.
+type Analyzer struct {
+	clientset kubernetes.Interface
+	instances InstanceLister
+
+	Interval time.Duration
+	Window   time.Duration
+
+	now    func() time.Time
+	mu     sync.Mutex
+	states map[string]*instanceState
+}
+
+// NewAnalyzer creates an analyzer with the default interval and window
+func NewAnalyzer(clientset kubernetes.Interface, instances InstanceLister) *Analyzer {
+	return &Analyzer{
+		clientset: clientset,
+		instances: instances,
+		Interval:  DefaultInterval,
+		Window:    DefaultWindow,
+		now:       time.Now,
+		states:    make(map[string]*instanceState),
+	}
+}
+
+// NeedLeaderElection lets every replica analyze logs, as summaries are kept in memory
+func (a *Analyzer) NeedLeaderElection() bool {
+	return false
+}
+
+// Start reads logs every interval until ctx is cancelled
+func (a *Analyzer) Start(ctx context.Context) error {
+	ticker := time.NewTicker(a.Interval)
+	defer ticker.Stop()
+
+	for {
+		a.scan(ctx)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// scan reads the logs of every running instance and forgets instances that are gone
+func (a *Analyzer) scan(ctx context.Context) {
+	list, err := a.instances.ListSupabaseInstances(ctx)
+	if err != nil {
+		slog.Error("Failed to list instances for log analysis", "error", err)
+		return
+	}
+
+	present := make(map[string]bool, len(list.Items))
+	for i := range list.Items {
+		instance := &list.Items[i]
+		present[instance.Name] = true
+		if instance.Status.Phase != supacontrolv1alpha1.PhaseRunning {
+			continue
+		}
+		if err := a.scanInstance(ctx, instance); err != nil {
+			slog.Warn("Failed to analyze instance logs", "instance", instance.Name, "error", err)
+		}
+	}
+
+	a.mu.Lock()
+	for name := range a.states {
+		if !present[name] {
+			delete(a.states, name)
+		}
+	}
+	a.mu.Unlock()
+}
+
+// instanceNamespace returns the namespace an instance runs in
+func instanceNamespace(instance *supacontrolv1alpha1.SupabaseInstance) string {
+	if instance.Status.Namespace != "" {
+		return instance.Status.Namespace
+	}
+	return "supa-" + instance.Spec.ProjectName
+}
+
+// scanInstance reads the new log lines of the instance's watched containers
+func (a *Analyzer) scanInstance(ctx context.Context, instance *supacontrolv1alpha1.SupabaseInstance) error {
+	namespace := instanceNamespace(instance)
+	pods, err := a.clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+
+	current := map[string]bool{}
+	for _, pod := range pods.Items {
+		if pod.Status.Phase != corev1.PodRunning {
+			continue
+		}
+		for _, container := range pod.Spec.Containers {
+			component, ok := k8s.ComponentForImage(container.Image)
+			if !ok || !watched(component) {
+				continue
+			}
+			current[pod.Name+"/"+container.Name] = true
+			if err := a.readContainer(ctx, instance.Name, namespace, pod.Name, container.Name, component); err != nil {
+				slog.Warn("Failed to read container logs", "instance", instance.Name, "pod", pod.Name, "container", container.Name, "error", err)
+			}
+		}
+	}
+
+	a.mu.Lock()
+	state := a.state(instance.Name)
+	state.updated = a.now()
+	// Read positions of containers that are gone are no longer needed
+	for key := range state.read {
+		if !current[key] {
+			delete(state.read, key)
+		}
+	}
+	a.mu.Unlock()
+	return nil
+}
+
+// readContainer reads a container's log lines written since the last read. The first read
+// of a container only looks one interval back, so restarts don't recount old errors.
+func (a *Analyzer) readContainer(ctx context.Context, instance, namespace, pod, container, component string) error {
+	key := pod + "/" + container
+	a.mu.Lock()
+	since, seen := a.state(instance).read[key]
+	a.mu.Unlock()
+	if !seen {
+		since = a.now().Add(-a.Interval)
+	}
+
+	opts := &corev1.PodLogOptions{
+		Container:  container,
+		Timestamps: true,
+		SinceTime:  &metav1.Time{Time: since},
+		LimitBytes: ptr.To(int64(maxLogBytes)),
+	}
+	stream, err := a.clientset.CoreV1().Pods(namespace).GetLogs(pod, opts).Stream(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = stream.Close() }()
+
+	last := since
+	scanner := bufio.NewScanner(stream)
+	scanner.Buffer(make([]byte, 64<<10), maxLogBytes)
+	for scanner.Scan() {
+		at, line := splitTimestamp(scanner.Text())
+		if at.IsZero() {
+			at = a.now()
+		} else if !at.After(since) {
+			// SinceTime has second precision, so the previous read's last lines come again
+			continue
+		}
+		if at.After(last) {
+			last = at
+		}
+		a.Record(instance, component, pod, line, at)
+	}
+
+	a.mu.Lock()
+	a.state(instance).read[key] = last
+	a.mu.Unlock()
+	return scanner.Err()
+}
+
+// splitTimestamp splits the RFC 3339 timestamp the Kubernetes API prefixes log lines with
+func splitTimestamp(line string) (time.Time, string) {
+	stamp, rest, ok := strings.Cut(line, " ")
+	if !ok {
+		return time.Time{}, line
+	}
+	at, err := time.Parse(time.RFC3339Nano, stamp)
+	if err != nil {
+		return time.Time{}, line
+	}
+	return at, rest
+}
+
+// state returns the state of an instance, creating it if needed. mu must be held.
+func (a *Analyzer) state(instance string) *instanceState {
+	state, ok := a.states[instance]
+	if !ok {
+		state = &instanceState{series: map[string]*series{}, read: map[string]time.Time{}}
+		a.states[instance] = state
+	}
+	return state
+}
+
+// Record matches a log line of an instance's component against the error rules and
+// counts it when it is an error
+func (a *Analyzer) Record(instance, component, pod, line string, at time.Time) {
+	for _, r := range rules {
+		if r.component != component || !r.pattern.MatchString(line) {
+			continue
+		}
+
+		a.mu.Lock()
+		key := r.component + "/" + r.kind
+		state := a.state(instance)
+		s, ok := state.series[key]
+		if !ok {
+			s = &series{counts: map[int64]int{}}
+			state.series[key] = s
+		}
+		s.counts[at.Unix()/60]++
+		if at.After(s.lastSeen) {
+			s.lastSeen = at
+		}
+		if len(line) > maxSampleLength {
+			line = line[:maxSampleLength]
+		}
+		s.samples = append(s.samples, apitypes.ErrorSample{Time: at, Pod: pod, Line: line})
+		if len(s.samples) > maxSamples {
+			s.samples = s.samples[len(s.samples)-maxSamples:]
+		}
+		a.mu.Unlock()
+		return
+	}
+}
+
+// Summary returns an instance's error counts and samples within the window, or nil if
+// its logs have not been analyzed yet
+func (a *Analyzer) Summary(instance string) *apitypes.InstanceErrorSummary {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	state, ok := a.states[instance]
+	if !ok {
+		return nil
+	}
+	summary := &apitypes.InstanceErrorSummary{
+		Instance:      instance,
+		WindowSeconds: int(a.Window.Seconds()),
+		Components:    []apitypes.ComponentErrors{},
+	}
+	if !state.updated.IsZero() {
+		updated := state.updated
+		summary.UpdatedAt = &updated
+	}
+
+	cutoff := a.now().Add(-a.Window)
+	oldest := cutoff.Unix() / 60
+	for key, s := range state.series {
+		component, kind, _ := strings.Cut(key, "/")
+		errors := apitypes.ComponentErrors{Component: component, Kind: kind}
+		for minute, count := range s.counts {
+			if minute < oldest {
+				// Expired buckets are dropped as they are encountered
+				delete(s.counts, minute)
+				continue
+			}
+			errors.Count += count
+		}
+		if errors.Count == 0 {
+			continue
+		}
+		lastSeen := s.lastSeen
+		errors.LastSeen = &lastSeen
+		for _, sample := range s.samples {
+			if sample.Time.After(cutoff) {
+				errors.Samples = append(errors.Samples, sample)
+			}
+		}
+		summary.Components = append(summary.Components, errors)
+		summary.TotalErrors += errors.Count
+	}
+	sort.Slice(summary.Components, func(i, j int) bool {
+		if summary.Components[i].Count != summary.Components[j].Count {
+			return summary.Components[i].Count > summary.Components[j].Count
+		}
+		return summary.Components[i].Component < summary.Components[j].Component
+	})
+	return summary
+}
@@ -0,0 +1,152 @@
+package logerrors
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+)
+
+// staticInstances lists a fixed set of instances
+type staticInstances []supacontrolv1alpha1.SupabaseInstance
+
+func (s staticInstances) ListSupabaseInstances(context.Context) (*supacontrolv1alpha1.SupabaseInstanceList, error) {
+	return &supacontrolv1alpha1.SupabaseInstanceList{Items: s}, nil
+}
+
+// newTestAnalyzer returns an analyzer whose clock is fixed at now
+func newTestAnalyzer(now time.Time, instances staticInstances, objects ...*corev1.Pod) *Analyzer {
+	clientset := fake.NewSimpleClientset()
+	for _, pod := range objects {
+		_, _ = clientset.CoreV1().Pods(pod.Namespace).Create(context.Background(), pod, metav1.CreateOptions{})
+	}
+	a := NewAnalyzer(clientset, instances)
+	a.now = func() time.Time { return now }
+	return a
+}
+
+// TestRecord tests that only lines matching a component's error rules are counted
+func TestRecord(t *testing.T) {
+	now := time.Date(2025, 1, 15, 10, 30, 0, 0, time.UTC)
+	a := newTestAnalyzer(now, nil)
+
+	lines := []struct {
+		component string
+		line      string
+	}{
+		{apitypes.ComponentKong, `10.0.0.1 - - [15/Jan/2025:10:29:00 +0000] "GET /rest/v1/todos HTTP/1.1" 502 120 "-" "curl/8.0"`},
+		{apitypes.ComponentKong, `10.0.0.1 - - [15/Jan/2025:10:29:01 +0000] "GET /rest/v1/todos HTTP/1.1" 503 120 "-" "curl/8.0"`},
+		{apitypes.ComponentKong, `10.0.0.1 - - [15/Jan/2025:10:29:02 +0000] "GET /rest/v1/todos HTTP/1.1" 200 512 "-" "curl/8.0"`},
+		{apitypes.ComponentGoTrue, `{"level":"error","msg":"failed to send email","time":"2025-01-15T10:29:00Z"}`},
+		{apitypes.ComponentGoTrue, `{"level":"info","msg":"request completed"}`},
+		{apitypes.ComponentPostgres, `2025-01-15 10:29:00.000 UTC [42] FATAL:  password authentication failed for user "app"`},
+		{apitypes.ComponentPostgres, `2025-01-15 10:29:00.000 UTC [42] LOG:  checkpoint starting: time`},
+		// Patterns only apply to their own component
+		{apitypes.ComponentPostgres, `{"level":"error"}`},
+	}
+	for _, l := range lines {
+		a.Record("my-app", l.component, "pod-1", l.line, now.Add(-time.Minute))
+	}
+
+	summary := a.Summary("my-app")
+	if summary == nil {
+		t.Fatal("expected a summary")
+	}
+	if summary.TotalErrors != 4 {
+		t.Errorf("expected 4 errors, got %d", summary.TotalErrors)
+	}
+	counts := map[string]int{}
+	for _, c := range summary.Components {
+		counts[c.Component+"/"+c.Kind] = c.Count
+	}
+	want := map[string]int{"kong/http_5xx": 2, "gotrue/error": 1, "postgres/fatal": 1}
+	for key, count := range want {
+		if counts[key] != count {
+			t.Errorf("expected %d %s errors, got %d", count, key, counts[key])
+		}
+	}
+	if summary.Components[0].Component != apitypes.ComponentKong {
+		t.Errorf("expected the most frequent component first, got %s", summary.Components[0].Component)
+	}
+}
+
+// TestSummary_Window tests that errors and samples older than the window are dropped
+func TestSummary_Window(t *testing.T) {
+	now := time.Date(2025, 1, 15, 10, 30, 0, 0, time.UTC)
+	a := newTestAnalyzer(now, nil)
+
+	fatal := "FATAL:  the database system is shutting down"
+	a.Record("my-app", apitypes.ComponentPostgres, "db", fatal, now.Add(-2*time.Hour))
+	for i := 0; i < maxSamples+2; i++ {
+		a.Record("my-app", apitypes.ComponentPostgres, "db", fmt.Sprintf("%s %d", fatal, i), now.Add(-time.Duration(i)*time.Second))
+	}
+
+	summary := a.Summary("my-app")
+	if len(summary.Components) != 1 {
+		t.Fatalf("expected one component, got %+v", summary.Components)
+	}
+	errors := summary.Components[0]
+	if errors.Count != maxSamples+2 {
+		t.Errorf("expected %d errors within the window, got %d", maxSamples+2, errors.Count)
+	}
+	if len(errors.Samples) != maxSamples {
+		t.Errorf("expected %d samples, got %d", maxSamples, len(errors.Samples))
+	}
+	if errors.LastSeen == nil || !errors.LastSeen.Equal(now) {
+		t.Errorf("expected last seen %v, got %v", now, errors.LastSeen)
+	}
+
+	if a.Summary("other-app") != nil {
+		t.Error("expected no summary for an instance that was never analyzed")
+	}
+}
+
+// TestScan tests that running instances are analyzed and deleted ones forgotten
+func TestScan(t *testing.T) {
+	now := time.Now()
+	running := supacontrolv1alpha1.SupabaseInstance{
+		ObjectMeta: metav1.ObjectMeta{Name: "my-app"},
+		Spec:       supacontrolv1alpha1.SupabaseInstanceSpec{ProjectName: "my-app"},
+		Status:     supacontrolv1alpha1.SupabaseInstanceStatus{Phase: supacontrolv1alpha1.PhaseRunning},
+	}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "kong-0", Namespace: "supa-my-app"},
+		Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "kong", Image: "kong:2.8.1"}}},
+		Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+	}
+
+	a := newTestAnalyzer(now, staticInstances{running}, pod)
+	a.Record("deleted-app", apitypes.ComponentKong, "kong-0", `"GET / HTTP/1.1" 500 0`, now)
+	a.scan(context.Background())
+
+	summary := a.Summary("my-app")
+	if summary == nil || summary.UpdatedAt == nil {
+		t.Fatalf("expected the running instance to be analyzed, got %+v", summary)
+	}
+	if _, ok := a.states["my-app"].read["kong-0/kong"]; !ok {
+		t.Error("expected the read position of the kong container to be recorded")
+	}
+	if a.Summary("deleted-app") != nil {
+		t.Error("expected the deleted instance to be forgotten")
+	}
+}
+
+// TestSplitTimestamp tests parsing of the timestamps Kubernetes prefixes log lines with
+func TestSplitTimestamp(t *testing.T) {
+	at, line := splitTimestamp("2025-01-15T10:29:00.123456789Z FATAL:  out of memory")
+	if at.IsZero() || line != "FATAL:  out of memory" {
+		t.Errorf("unexpected split %v %q", at, line)
+	}
+
+	at, line = splitTimestamp("no timestamp here")
+	if !at.IsZero() || line != "no timestamp here" {
+		t.Errorf("expected the line unchanged, got %v %q", at, line)
+	}
+}
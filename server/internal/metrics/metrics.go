@@ -4,6 +4,7 @@ package metrics
 import (
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
+	ctrlmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
 )
 
 var (
@@ -28,6 +29,15 @@ var (
 		[]string{"endpoint", "method"},
 	)
 
+	// APIRequestsShedTotal counts API requests rejected by load shedding by budget (default/expensive)
+	APIRequestsShedTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "supacontrol_api_requests_shed_total",
+			Help: "Total number of API requests rejected because the server was saturated, by budget",
+		},
+		[]string{"budget"},
+	)
+
 	// Instance State Metrics
 
 	// InstancesTotal tracks the total number of instances
@@ -104,8 +114,33 @@ var (
 		},
 		[]string{"phase"},
 	)
+
+	// ReconciliationRequeuesTotal counts reconciliations that asked to run again, by phase and reason
+	ReconciliationRequeuesTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "supacontrol_reconciliation_requeues_total",
+			Help: "Total number of requeued reconciliations by phase and reason",
+		},
+		[]string{"phase", "reason"}, // reason: error/phase_change/poll/resync
+	)
+
+	// PhaseDuration tracks how long instances spend in a phase before moving to the next one
+	PhaseDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "supacontrol_phase_duration_seconds",
+			Help:    "Time instances spent in a phase before transitioning to the next phase in seconds",
+			Buckets: []float64{1, 5, 15, 30, 60, 120, 300, 600, 1800, 3600}, // 1s to 1h
+		},
+		[]string{"phase", "next_phase"},
+	)
 )
 
+// Gatherer returns the metrics served at /metrics: SupaControl's own and the controller
+// runtime's, which include the reconcile work queue depth and latency
+func Gatherer() prometheus.Gatherer {
+	return prometheus.Gatherers{prometheus.DefaultGatherer, ctrlmetrics.Registry}
+}
+
 // SetInstanceStatus sets the status for a specific instance
 // This helper ensures only one phase is set to 1, all others to 0
 func SetInstanceStatus(projectName, currentPhase string, allPhases []string) {
@@ -6,6 +6,7 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
+	ctrlmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
 )
 
 func TestAPIMetrics(t *testing.T) {
@@ -188,6 +189,8 @@ func TestMetricsRegistration(t *testing.T) {
 		assert.NotNil(t, ReconciliationTotal, "ReconciliationTotal should be registered")
 		assert.NotNil(t, ReconciliationDuration, "ReconciliationDuration should be registered")
 		assert.NotNil(t, ReconciliationErrorsTotal, "ReconciliationErrorsTotal should be registered")
+		assert.NotNil(t, ReconciliationRequeuesTotal, "ReconciliationRequeuesTotal should be registered")
+		assert.NotNil(t, PhaseDuration, "PhaseDuration should be registered")
 	})
 
 	t.Run("metrics implement correct prometheus types", func(t *testing.T) {
@@ -199,3 +202,22 @@ func TestMetricsRegistration(t *testing.T) {
 		assert.Implements(t, (*prometheus.Collector)(nil), InstanceCreationDuration)
 	})
 }
+
+func TestGatherer(t *testing.T) {
+	t.Run("includes SupaControl and controller runtime metrics", func(t *testing.T) {
+		PhaseDuration.WithLabelValues("Provisioning", "Running").Observe(30)
+		queueDepth := prometheus.NewGauge(prometheus.GaugeOpts{Name: "test_workqueue_depth", Help: "Test queue depth"})
+		ctrlmetrics.Registry.MustRegister(queueDepth)
+		defer ctrlmetrics.Registry.Unregister(queueDepth)
+
+		families, err := Gatherer().Gather()
+		assert.NoError(t, err)
+
+		names := make(map[string]bool, len(families))
+		for _, family := range families {
+			names[family.GetName()] = true
+		}
+		assert.True(t, names["supacontrol_phase_duration_seconds"], "SupaControl metrics should be gathered")
+		assert.True(t, names["test_workqueue_depth"], "controller runtime metrics should be gathered")
+	})
+}
@@ -0,0 +1,181 @@
+// Package namespaces names the namespaces instances run in and validates the labels and
+// annotations platform operators and instance owners set on them.
+//
+// Namespace names come from a template holding the {name} placeholder, which is replaced
+// by the instance's project name. Names of system namespaces are refused, so an instance
+// can never be installed into, or delete, kube-system and the like.
+package namespaces
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+const (
+	// Placeholder is replaced by the project name in namespace templates
+	Placeholder = "{name}"
+
+	// DefaultTemplate is the namespace template used when none is configured
+	DefaultTemplate = "supa-{name}"
+)
+
+var (
+	// ErrInvalidTemplate is returned for namespace templates that do not yield valid
+	// namespace names
+	ErrInvalidTemplate = errors.New("invalid namespace template")
+
+	// ErrSystemNamespace is returned for namespace names reserved for the cluster or
+	// the platform
+	ErrSystemNamespace = errors.New("namespace is reserved for the system")
+
+	// ErrReservedKey is returned for labels and annotations that SupaControl or
+	// Kubernetes manage themselves
+	ErrReservedKey = errors.New("key is reserved")
+)
+
+// systemNamespaces are namespaces that exist in every cluster
+var systemNamespaces = []string{"default", "kube-system", "kube-public", "kube-node-lease"}
+
+// systemPrefixes start the names of namespaces Kubernetes distributions create for
+// themselves
+var systemPrefixes = []string{"kube-", "openshift"}
+
+// reservedDomains are label and annotation prefixes managed by Kubernetes, which include
+// the Pod Security admission labels, and by SupaControl
+var reservedDomains = []string{"kubernetes.io", "k8s.io", "supacontrol.io"}
+
+// templateOrDefault returns template, or DefaultTemplate when it is empty
+func templateOrDefault(template string) string {
+	if template == "" {
+		return DefaultTemplate
+	}
+	return template
+}
+
+// ValidateTemplate checks that a namespace template holds the {name} placeholder exactly
+// once and yields valid names that are not system namespaces
+func ValidateTemplate(template string) error {
+	if strings.Count(template, Placeholder) != 1 {
+		return fmt.Errorf("%w: %q must contain %s exactly once", ErrInvalidTemplate, template, Placeholder)
+	}
+	// A one-letter project name yields the shortest name the template can produce
+	sample := Name(template, "a")
+	if errs := validation.IsDNS1123Label(sample); len(errs) > 0 {
+		return fmt.Errorf("%w: %q yields invalid names such as %q: %s", ErrInvalidTemplate, template, sample, strings.Join(errs, "; "))
+	}
+	if IsSystem(sample) {
+		return fmt.Errorf("%w: %q yields system namespaces such as %q", ErrInvalidTemplate, template, sample)
+	}
+	return nil
+}
+
+// Name returns the namespace of the project in template (empty uses DefaultTemplate)
+func Name(template, project string) string {
+	return strings.Replace(templateOrDefault(template), Placeholder, project, 1)
+}
+
+// Project returns the project whose namespace in template (empty uses DefaultTemplate) is
+// namespace, or false when the namespace does not match the template
+func Project(template, namespace string) (string, bool) {
+	prefix, suffix, _ := strings.Cut(templateOrDefault(template), Placeholder)
+	project, ok := strings.CutPrefix(namespace, prefix)
+	if !ok {
+		return "", false
+	}
+	project, ok = strings.CutSuffix(project, suffix)
+	if !ok || project == "" {
+		return "", false
+	}
+	return project, true
+}
+
+// IsSystem reports whether a namespace belongs to the cluster rather than to an instance
+func IsSystem(namespace string) bool {
+	for _, name := range systemNamespaces {
+		if namespace == name {
+			return true
+		}
+	}
+	for _, prefix := range systemPrefixes {
+		if strings.HasPrefix(namespace, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate checks that namespace can hold an instance: it must be a valid name and neither
+// a system namespace nor one of reserved, such as the platform's own namespaces
+func Validate(namespace string, reserved ...string) error {
+	if errs := validation.IsDNS1123Label(namespace); len(errs) > 0 {
+		return fmt.Errorf("invalid namespace %q: %s", namespace, strings.Join(errs, "; "))
+	}
+	if IsSystem(namespace) {
+		return fmt.Errorf("%w: %s", ErrSystemNamespace, namespace)
+	}
+	for _, name := range reserved {
+		if namespace == name {
+			return fmt.Errorf("%w: %s", ErrSystemNamespace, namespace)
+		}
+	}
+	return nil
+}
+
+// isReservedKey reports whether a label or annotation key is in a reserved domain
+func isReservedKey(key string) bool {
+	domain, _, ok := strings.Cut(key, "/")
+	if !ok {
+		return false
+	}
+	for _, reserved := range reservedDomains {
+		if domain == reserved || strings.HasSuffix(domain, "."+reserved) {
+			return true
+		}
+	}
+	return false
+}
+
+// sortedKeys returns the keys of m in order, so validation errors are deterministic
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// ValidateLabels checks namespace labels: keys and values must be valid and keys must not
+// be in a domain Kubernetes or SupaControl manage
+func ValidateLabels(labels map[string]string) error {
+	for _, key := range sortedKeys(labels) {
+		if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+			return fmt.Errorf("invalid label key %q: %s", key, strings.Join(errs, "; "))
+		}
+		if isReservedKey(key) {
+			return fmt.Errorf("%w: label %s", ErrReservedKey, key)
+		}
+		if errs := validation.IsValidLabelValue(labels[key]); len(errs) > 0 {
+			return fmt.Errorf("invalid value of label %q: %s", key, strings.Join(errs, "; "))
+		}
+	}
+	return nil
+}
+
+// ValidateAnnotations checks namespace annotations: keys must be valid and not in a domain
+// Kubernetes or SupaControl manage
+func ValidateAnnotations(annotations map[string]string) error {
+	for _, key := range sortedKeys(annotations) {
+		if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+			return fmt.Errorf("invalid annotation key %q: %s", key, strings.Join(errs, "; "))
+		}
+		if isReservedKey(key) {
+			return fmt.Errorf("%w: annotation %s", ErrReservedKey, key)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package namespaces
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidateTemplate(t *testing.T) {
+	tests := []struct {
+		template string
+		valid    bool
+	}{
+		{template: DefaultTemplate, valid: true},
+		{template: "team-a-{name}-db", valid: true},
+		{template: "{name}", valid: true},
+		{template: "supa-", valid: false},
+		{template: "{name}-{name}", valid: false},
+		{template: "Supa-{name}", valid: false},
+		{template: "supa_{name}", valid: false},
+		{template: "kube-{name}", valid: false},
+		{template: "openshift-{name}", valid: false},
+	}
+
+	for _, tt := range tests {
+		err := ValidateTemplate(tt.template)
+		if tt.valid && err != nil {
+			t.Errorf("ValidateTemplate(%q) = %v, want nil", tt.template, err)
+		}
+		if !tt.valid && !errors.Is(err, ErrInvalidTemplate) {
+			t.Errorf("ValidateTemplate(%q) = %v, want ErrInvalidTemplate", tt.template, err)
+		}
+	}
+}
+
+func TestNameAndProject(t *testing.T) {
+	tests := []struct {
+		template  string
+		project   string
+		namespace string
+	}{
+		{template: "", project: "my-app", namespace: "supa-my-app"},
+		{template: "team-a-{name}-db", project: "my-app", namespace: "team-a-my-app-db"},
+		{template: "{name}", project: "my-app", namespace: "my-app"},
+	}
+
+	for _, tt := range tests {
+		if got := Name(tt.template, tt.project); got != tt.namespace {
+			t.Errorf("Name(%q, %q) = %q, want %q", tt.template, tt.project, got, tt.namespace)
+		}
+		if got, ok := Project(tt.template, tt.namespace); !ok || got != tt.project {
+			t.Errorf("Project(%q, %q) = %q, %v, want %q", tt.template, tt.namespace, got, ok, tt.project)
+		}
+	}
+
+	for _, namespace := range []string{"kube-system", "team-a--db", "team-a-my-app"} {
+		if project, ok := Project("team-a-{name}-db", namespace); ok {
+			t.Errorf("Project(%q) = %q, want no match", namespace, project)
+		}
+	}
+	for _, namespace := range []string{"kube-system", "supa-"} {
+		if project, ok := Project("", namespace); ok {
+			t.Errorf("Project(%q) = %q, want no match of the default template", namespace, project)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		namespace string
+		system    bool
+		invalid   bool
+	}{
+		{namespace: "supa-my-app"},
+		{namespace: "default", system: true},
+		{namespace: "kube-system", system: true},
+		{namespace: "kube-anything", system: true},
+		{namespace: "openshift-monitoring", system: true},
+		{namespace: "supacontrol-system", system: true},
+		{namespace: "My-App", invalid: true},
+	}
+
+	for _, tt := range tests {
+		err := Validate(tt.namespace, "supacontrol-system")
+		switch {
+		case tt.system && !errors.Is(err, ErrSystemNamespace):
+			t.Errorf("Validate(%q) = %v, want ErrSystemNamespace", tt.namespace, err)
+		case tt.invalid && err == nil:
+			t.Errorf("Validate(%q) = nil, want an error", tt.namespace)
+		case !tt.system && !tt.invalid && err != nil:
+			t.Errorf("Validate(%q) = %v, want nil", tt.namespace, err)
+		}
+	}
+}
+
+func TestValidateLabels(t *testing.T) {
+	tests := []struct {
+		name     string
+		labels   map[string]string
+		reserved bool
+		invalid  bool
+	}{
+		{name: "cost center", labels: map[string]string{"cost-center": "cc-1234", "example.com/team": "payments"}},
+		{name: "pod security", labels: map[string]string{"pod-security.kubernetes.io/enforce": "privileged"}, reserved: true},
+		{name: "managed by", labels: map[string]string{"app.kubernetes.io/managed-by": "someone"}, reserved: true},
+		{name: "instance", labels: map[string]string{"supacontrol.io/instance": "other"}, reserved: true},
+		{name: "invalid key", labels: map[string]string{"cost center": "x"}, invalid: true},
+		{name: "invalid value", labels: map[string]string{"team": "a team"}, invalid: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateLabels(tt.labels)
+			switch {
+			case tt.reserved && !errors.Is(err, ErrReservedKey):
+				t.Errorf("ValidateLabels() = %v, want ErrReservedKey", err)
+			case tt.invalid && err == nil:
+				t.Error("ValidateLabels() = nil, want an error")
+			case !tt.reserved && !tt.invalid && err != nil:
+				t.Errorf("ValidateLabels() = %v, want nil", err)
+			}
+		})
+	}
+
+	if err := ValidateAnnotations(map[string]string{"example.com/owner": "Payments team <payments@example.com>"}); err != nil {
+		t.Errorf("ValidateAnnotations() = %v, want nil", err)
+	}
+	if err := ValidateAnnotations(map[string]string{"kubernetes.io/description": "x"}); !errors.Is(err, ErrReservedKey) {
+		t.Errorf("ValidateAnnotations() = %v, want ErrReservedKey", err)
+	}
+}
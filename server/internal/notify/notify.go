@@ -0,0 +1,98 @@
+// Package notify sends notifications about instances and team invitations to an
+// external webhook.
+//
+// Events are POSTed as JSON. When a secret is configured, the body is signed with
+// HMAC-SHA256 in the X-SupaControl-Signature header as "sha256=<hex>", the same scheme
+// the billing webhook verifies, so receivers can authenticate the sender.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const (
+	// SignatureHeader carries the HMAC-SHA256 signature of a notification body
+	SignatureHeader = "X-SupaControl-Signature"
+
+	// EventInstanceExpiring announces that an ephemeral instance will soon be deleted
+	EventInstanceExpiring = "instance.expiring"
+
+	// EventTeamInvitation carries a team invitation link for the receiver to deliver to
+	// the invitee, e.g. by email
+	EventTeamInvitation = "team.invitation"
+
+	// requestTimeout bounds delivering a notification
+	requestTimeout = 10 * time.Second
+)
+
+// Event is a notification about an instance, or a team invitation without one
+type Event struct {
+	Type     string            `json:"type"`
+	Instance string            `json:"instance,omitempty"`
+	Message  string            `json:"message"`
+	Time     time.Time         `json:"time"`
+	Data     map[string]string `json:"data,omitempty"`
+}
+
+// Notifier delivers events
+type Notifier interface {
+	Notify(ctx context.Context, event Event) error
+}
+
+// Webhook delivers events to an HTTP endpoint
+type Webhook struct {
+	url    string
+	secret string
+	client *http.Client
+}
+
+// NewWebhook creates a notifier posting to url, signing bodies with secret (empty
+// leaves them unsigned)
+func NewWebhook(url, secret string) *Webhook {
+	return &Webhook{
+		url:    url,
+		secret: secret,
+		client: &http.Client{Timeout: requestTimeout},
+	}
+}
+
+// Notify posts the event, failing unless the endpoint answers with a 2xx status
+func (w *Webhook) Notify(ctx context.Context, event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal notification: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("invalid notification webhook URL: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if w.secret != "" {
+		req.Header.Set(SignatureHeader, Sign(w.secret, body))
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send notification: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("failed to send notification: unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Sign returns the "sha256=<hex>" HMAC-SHA256 signature of body
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
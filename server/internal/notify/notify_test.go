@@ -0,0 +1,61 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWebhookNotify(t *testing.T) {
+	var received Event
+	var body []byte
+	var signature string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		if err := json.Unmarshal(body, &received); err != nil {
+			t.Errorf("invalid body: %v", err)
+		}
+		signature = r.Header.Get(SignatureHeader)
+	}))
+	defer server.Close()
+
+	event := Event{
+		Type:     EventInstanceExpiring,
+		Instance: "demo",
+		Message:  "Instance expires soon",
+		Time:     time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+		Data:     map[string]string{"expires_at": "2026-01-02T04:04:05Z"},
+	}
+	if err := NewWebhook(server.URL, "secret").Notify(context.Background(), event); err != nil {
+		t.Fatalf("Notify() error = %v", err)
+	}
+	if received.Type != event.Type || received.Instance != event.Instance || !received.Time.Equal(event.Time) ||
+		received.Data["expires_at"] != event.Data["expires_at"] {
+		t.Errorf("received %+v, want %+v", received, event)
+	}
+	if signature != Sign("secret", body) {
+		t.Errorf("signature %q does not match the body", signature)
+	}
+
+	if err := NewWebhook(server.URL, "").Notify(context.Background(), event); err != nil {
+		t.Fatalf("Notify() without a secret error = %v", err)
+	}
+	if signature != "" {
+		t.Errorf("expected no signature without a secret, got %q", signature)
+	}
+}
+
+func TestWebhookNotifyFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	if err := NewWebhook(server.URL, "").Notify(context.Background(), Event{Type: EventInstanceExpiring}); err == nil {
+		t.Error("Notify() succeeded on an error status")
+	}
+}
@@ -0,0 +1,280 @@
+package objectstore
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ProviderAzure stores objects as block blobs in an Azure Storage container.
+// The container is configured as the bucket, the storage account name as the
+// access key and the account key as the secret key.
+const ProviderAzure = "azure"
+
+// azureAPIVersion is the Blob service REST API version requests are made against
+const azureAPIVersion = "2021-08-06"
+
+func init() {
+	Register(ProviderAzure, Provider{
+		Validate: func(cfg Config) error {
+			err := requireFields(ProviderAzure, map[string]string{
+				"bucket (container)":       cfg.Bucket,
+				"access key (account)":     cfg.AccessKey,
+				"secret key (account key)": cfg.SecretKey,
+			})
+			if err != nil {
+				return err
+			}
+			if _, err := base64.StdEncoding.DecodeString(cfg.SecretKey); err != nil {
+				return fmt.Errorf("azure account key must be base64 encoded")
+			}
+			if cfg.Endpoint != "" {
+				return validateEndpoint(cfg.Endpoint)
+			}
+			return nil
+		},
+		New: func(cfg Config) (Backend, error) {
+			endpoint := cfg.Endpoint
+			if endpoint == "" {
+				endpoint = fmt.Sprintf("https://%s.blob.core.windows.net", cfg.AccessKey)
+			}
+			base, err := url.Parse(strings.TrimSuffix(endpoint, "/") + "/" + cfg.Bucket)
+			if err != nil {
+				return nil, fmt.Errorf("invalid object storage endpoint: %w", err)
+			}
+			key, err := base64.StdEncoding.DecodeString(cfg.SecretKey)
+			if err != nil {
+				return nil, fmt.Errorf("azure account key must be base64 encoded")
+			}
+			return &azureBackend{
+				client:  &http.Client{Timeout: 5 * time.Minute},
+				base:    base,
+				account: cfg.AccessKey,
+				key:     key,
+				now:     time.Now,
+			}, nil
+		},
+	})
+}
+
+// azureBackend talks to the Azure Blob service, signing requests with Shared Key authorization
+type azureBackend struct {
+	client  *http.Client
+	base    *url.URL // container URL
+	account string
+	key     []byte
+
+	// now is replaced in tests to produce deterministic signatures
+	now func() time.Time
+}
+
+// do signs and sends a request for the blob key (empty for the container itself)
+func (b *azureBackend) do(ctx context.Context, method, key string, query url.Values, header http.Header, body io.Reader, size int64) (*http.Response, error) {
+	u := *b.base
+	if key != "" {
+		u.Path += "/" + key
+	}
+	u.RawPath = canonicalPath(u.Path)
+	u.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range header {
+		for _, value := range values {
+			req.Header.Add(name, value)
+		}
+	}
+	if body != nil {
+		req.ContentLength = size
+	}
+	b.sign(req, query)
+	return b.client.Do(req)
+}
+
+// sign adds a Shared Key Authorization header to the request
+func (b *azureBackend) sign(req *http.Request, query url.Values) {
+	req.Header.Set("x-ms-date", b.now().UTC().Format(http.TimeFormat))
+	req.Header.Set("x-ms-version", azureAPIVersion)
+
+	contentLength := ""
+	if req.ContentLength > 0 {
+		contentLength = strconv.FormatInt(req.ContentLength, 10)
+	}
+
+	var msHeaders []string
+	for name, values := range req.Header {
+		if lower := strings.ToLower(name); strings.HasPrefix(lower, "x-ms-") {
+			msHeaders = append(msHeaders, lower+":"+strings.TrimSpace(strings.Join(values, ",")))
+		}
+	}
+	sort.Strings(msHeaders)
+
+	resource := "/" + b.account + req.URL.EscapedPath()
+	params := make([]string, 0, len(query))
+	for name, values := range query {
+		sorted := append([]string(nil), values...)
+		sort.Strings(sorted)
+		params = append(params, strings.ToLower(name)+":"+strings.Join(sorted, ","))
+	}
+	sort.Strings(params)
+	for _, p := range params {
+		resource += "\n" + p
+	}
+
+	stringToSign := strings.Join([]string{
+		req.Method,
+		req.Header.Get("Content-Encoding"),
+		req.Header.Get("Content-Language"),
+		contentLength,
+		req.Header.Get("Content-MD5"),
+		req.Header.Get("Content-Type"),
+		"", // Date (x-ms-date is used instead)
+		req.Header.Get("If-Modified-Since"),
+		req.Header.Get("If-Match"),
+		req.Header.Get("If-None-Match"),
+		req.Header.Get("If-Unmodified-Since"),
+		req.Header.Get("Range"),
+		strings.Join(msHeaders, "\n"),
+		resource,
+	}, "\n")
+
+	mac := hmac.New(sha256.New, b.key)
+	mac.Write([]byte(stringToSign))
+	req.Header.Set("Authorization", "SharedKey "+b.account+":"+base64.StdEncoding.EncodeToString(mac.Sum(nil)))
+}
+
+func (b *azureBackend) Put(ctx context.Context, key string, body io.Reader, size int64) error {
+	if err := validateKey(key); err != nil {
+		return err
+	}
+	header := http.Header{"x-ms-blob-type": {"BlockBlob"}}
+	resp, err := b.do(ctx, http.MethodPut, key, nil, header, body, size)
+	if err != nil {
+		return fmt.Errorf("failed to upload %s: %w", key, err)
+	}
+	defer closeBody(resp)
+	if resp.StatusCode != http.StatusCreated {
+		return fmt.Errorf("failed to upload %s: %w", key, azureError(resp))
+	}
+	return nil
+}
+
+func (b *azureBackend) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	if err := validateKey(key); err != nil {
+		return nil, err
+	}
+	resp, err := b.do(ctx, http.MethodGet, key, nil, nil, nil, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", key, err)
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return resp.Body, nil
+	case http.StatusNotFound:
+		closeBody(resp)
+		return nil, ErrNotFound
+	default:
+		defer closeBody(resp)
+		return nil, fmt.Errorf("failed to download %s: %w", key, azureError(resp))
+	}
+}
+
+func (b *azureBackend) Delete(ctx context.Context, key string) error {
+	if err := validateKey(key); err != nil {
+		return err
+	}
+	resp, err := b.do(ctx, http.MethodDelete, key, nil, nil, nil, 0)
+	if err != nil {
+		return fmt.Errorf("failed to delete %s: %w", key, err)
+	}
+	defer closeBody(resp)
+	if resp.StatusCode != http.StatusAccepted && resp.StatusCode != http.StatusNotFound {
+		return fmt.Errorf("failed to delete %s: %w", key, azureError(resp))
+	}
+	return nil
+}
+
+// enumerationResults is the List Blobs response
+type enumerationResults struct {
+	Blobs []struct {
+		Name       string `xml:"Name"`
+		Properties struct {
+			ContentLength int64  `xml:"Content-Length"`
+			LastModified  string `xml:"Last-Modified"`
+		} `xml:"Properties"`
+	} `xml:"Blobs>Blob"`
+	NextMarker string `xml:"NextMarker"`
+}
+
+func (b *azureBackend) List(ctx context.Context, prefix string) ([]Object, error) {
+	var objects []Object
+	query := url.Values{"restype": {"container"}, "comp": {"list"}}
+	if prefix != "" {
+		query.Set("prefix", prefix)
+	}
+	for {
+		resp, err := b.do(ctx, http.MethodGet, "", query, nil, nil, 0)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list objects: %w", err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			err := azureError(resp)
+			closeBody(resp)
+			return nil, fmt.Errorf("failed to list objects: %w", err)
+		}
+		var result enumerationResults
+		err = xml.NewDecoder(resp.Body).Decode(&result)
+		closeBody(resp)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode object list: %w", err)
+		}
+
+		for _, blob := range result.Blobs {
+			modified, _ := time.Parse(http.TimeFormat, blob.Properties.LastModified)
+			objects = append(objects, Object{Key: blob.Name, Size: blob.Properties.ContentLength, LastModified: modified})
+		}
+		if result.NextMarker == "" {
+			break
+		}
+		query.Set("marker", result.NextMarker)
+	}
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Key < objects[j].Key })
+	return objects, nil
+}
+
+// Check verifies the container exists and the account key can access it
+func (b *azureBackend) Check(ctx context.Context) error {
+	resp, err := b.do(ctx, http.MethodHead, "", url.Values{"restype": {"container"}}, nil, nil, 0)
+	if err != nil {
+		return fmt.Errorf("object storage unreachable: %w", err)
+	}
+	defer closeBody(resp)
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("object storage container check failed: %s", resp.Status)
+	}
+	return nil
+}
+
+// azureError extracts the error code and message from a Blob service error response
+func azureError(resp *http.Response) error {
+	var body struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+	if err := xml.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&body); err != nil || body.Code == "" {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return fmt.Errorf("%s: %s (%s)", body.Code, strings.TrimSpace(strings.SplitN(body.Message, "\n", 2)[0]), resp.Status)
+}
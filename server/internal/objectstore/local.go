@@ -0,0 +1,149 @@
+package objectstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ProviderLocal stores objects as files on a mounted volume (e.g. a PersistentVolume)
+const ProviderLocal = "local"
+
+func init() {
+	Register(ProviderLocal, Provider{
+		Validate: func(cfg Config) error {
+			if err := requireFields(ProviderLocal, map[string]string{"path": cfg.Path}); err != nil {
+				return err
+			}
+			if !filepath.IsAbs(cfg.Path) {
+				return fmt.Errorf("object storage path %q must be absolute", cfg.Path)
+			}
+			return nil
+		},
+		New: func(cfg Config) (Backend, error) {
+			return &localBackend{root: filepath.Clean(cfg.Path)}, nil
+		},
+	})
+}
+
+// localBackend stores each object as a file below root
+type localBackend struct {
+	root string
+}
+
+// path returns the file path of an object
+func (b *localBackend) path(key string) (string, error) {
+	if err := validateKey(key); err != nil {
+		return "", err
+	}
+	return filepath.Join(b.root, filepath.FromSlash(key)), nil
+}
+
+func (b *localBackend) Put(ctx context.Context, key string, body io.Reader, size int64) error {
+	path, err := b.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", key, err)
+	}
+
+	// Write to a temporary file and rename it so readers never see partial objects
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", key, err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if _, err := io.Copy(tmp, body); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to write %s: %w", key, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", key, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to store %s: %w", key, err)
+	}
+	return nil
+}
+
+func (b *localBackend) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	path, err := b.path(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to open %s: %w", key, err)
+	}
+	return f, nil
+}
+
+func (b *localBackend) Delete(ctx context.Context, key string) error {
+	path, err := b.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to delete %s: %w", key, err)
+	}
+	return nil
+}
+
+func (b *localBackend) List(ctx context.Context, prefix string) ([]Object, error) {
+	var objects []Object
+	err := filepath.WalkDir(b.root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || strings.HasPrefix(d.Name(), ".upload-") {
+			return nil
+		}
+		rel, err := filepath.Rel(b.root, path)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if !strings.HasPrefix(key, prefix) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		objects = append(objects, Object{Key: key, Size: info.Size(), LastModified: info.ModTime()})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list objects: %w", err)
+	}
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Key < objects[j].Key })
+	return objects, nil
+}
+
+// Check verifies the root directory exists and is writable
+func (b *localBackend) Check(ctx context.Context) error {
+	info, err := os.Stat(b.root)
+	if err != nil {
+		return fmt.Errorf("object storage path unavailable: %w", err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("object storage path %s is not a directory", b.root)
+	}
+	f, err := os.CreateTemp(b.root, ".upload-check-*")
+	if err != nil {
+		return fmt.Errorf("object storage path is not writable: %w", err)
+	}
+	_ = f.Close()
+	return os.Remove(f.Name())
+}
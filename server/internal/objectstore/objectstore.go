@@ -0,0 +1,204 @@
+// Package objectstore abstracts the object storage that holds SupaControl's
+// artifacts (backups, support bundles, function bundles) behind a single
+// Backend interface, so those features work the same on S3, GCS, Azure Blob,
+// MinIO or a local persistent volume.
+//
+// Providers register themselves by name with a config validator and a
+// constructor. The server builds the configured backend at startup and
+// reports its health in /readyz.
+package objectstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrNotFound is returned when an object does not exist
+var ErrNotFound = errors.New("object not found")
+
+// Object describes a stored object
+type Object struct {
+	Key          string
+	Size         int64
+	LastModified time.Time
+}
+
+// Backend stores artifacts under slash-separated keys
+type Backend interface {
+	// Put stores an object, replacing any existing object with the same key
+	Put(ctx context.Context, key string, body io.Reader, size int64) error
+
+	// Get opens an object for reading; callers must close it
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+
+	// Delete removes an object; deleting a missing object is not an error
+	Delete(ctx context.Context, key string) error
+
+	// List returns the objects whose keys start with prefix, sorted by key
+	List(ctx context.Context, prefix string) ([]Object, error)
+
+	// Check verifies the backend is reachable and the bucket usable
+	Check(ctx context.Context) error
+}
+
+// Config holds the object storage settings. Which fields apply depends on the provider.
+type Config struct {
+	Provider string
+
+	// Bucket is the bucket (S3, GCS, MinIO) or container (Azure) holding the objects
+	Bucket string
+
+	// Prefix is prepended to every key, so several installations can share a bucket
+	Prefix string
+
+	// Endpoint overrides the provider's default service URL
+	Endpoint string
+	Region   string
+
+	// AccessKey and SecretKey authenticate to the provider: an access key pair for S3
+	// and MinIO, HMAC keys for GCS, and the account name and key for Azure
+	AccessKey string
+	SecretKey string
+
+	// Path is the root directory of the local provider
+	Path string
+}
+
+// Provider is an object storage implementation
+type Provider struct {
+	// Validate reports configuration errors before the backend is built
+	Validate func(cfg Config) error
+
+	// New builds a backend from a validated config
+	New func(cfg Config) (Backend, error)
+}
+
+var (
+	providersMu sync.RWMutex
+	providers   = map[string]Provider{}
+)
+
+// Register makes a provider available under the given name. It panics if the
+// name is already registered, as that is a programming error.
+func Register(name string, provider Provider) {
+	providersMu.Lock()
+	defer providersMu.Unlock()
+	if _, exists := providers[name]; exists {
+		panic(fmt.Sprintf("objectstore: provider %q registered twice", name))
+	}
+	providers[name] = provider
+}
+
+// Providers returns the names of the registered providers
+func Providers() []string {
+	providersMu.RLock()
+	defer providersMu.RUnlock()
+	names := make([]string, 0, len(providers))
+	for name := range providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// lookup returns the provider registered under name
+func lookup(name string) (Provider, error) {
+	providersMu.RLock()
+	provider, ok := providers[name]
+	providersMu.RUnlock()
+	if !ok {
+		return Provider{}, fmt.Errorf("unknown object storage provider %q (supported: %s)", name, strings.Join(Providers(), ", "))
+	}
+	return provider, nil
+}
+
+// Validate checks the config against its provider's requirements
+func (c Config) Validate() error {
+	provider, err := lookup(c.Provider)
+	if err != nil {
+		return err
+	}
+	return provider.Validate(c)
+}
+
+// New validates the config and builds the backend of its provider
+func New(cfg Config) (Backend, error) {
+	provider, err := lookup(cfg.Provider)
+	if err != nil {
+		return nil, err
+	}
+	if err := provider.Validate(cfg); err != nil {
+		return nil, err
+	}
+	backend, err := provider.New(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Prefix != "" {
+		backend = &prefixed{Backend: backend, prefix: strings.Trim(cfg.Prefix, "/") + "/"}
+	}
+	return backend, nil
+}
+
+// requireFields returns an error naming the empty fields among the given ones
+func requireFields(provider string, fields map[string]string) error {
+	var missing []string
+	for name, value := range fields {
+		if value == "" {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	sort.Strings(missing)
+	return fmt.Errorf("object storage provider %q requires %s", provider, strings.Join(missing, ", "))
+}
+
+// validateKey rejects keys that could escape the bucket or local root
+func validateKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") {
+		return fmt.Errorf("invalid object key %q", key)
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return fmt.Errorf("invalid object key %q", key)
+		}
+	}
+	return nil
+}
+
+// prefixed scopes a backend to a key prefix
+type prefixed struct {
+	Backend
+	prefix string
+}
+
+func (p *prefixed) Put(ctx context.Context, key string, body io.Reader, size int64) error {
+	return p.Backend.Put(ctx, p.prefix+key, body, size)
+}
+
+func (p *prefixed) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	return p.Backend.Get(ctx, p.prefix+key)
+}
+
+func (p *prefixed) Delete(ctx context.Context, key string) error {
+	return p.Backend.Delete(ctx, p.prefix+key)
+}
+
+func (p *prefixed) List(ctx context.Context, prefix string) ([]Object, error) {
+	objects, err := p.Backend.List(ctx, p.prefix+prefix)
+	if err != nil {
+		return nil, err
+	}
+	for i := range objects {
+		objects[i].Key = strings.TrimPrefix(objects[i].Key, p.prefix)
+	}
+	return objects, nil
+}
@@ -0,0 +1,148 @@
+package objectstore
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr string
+	}{
+		{name: "unknown provider", cfg: Config{Provider: "ftp"}, wantErr: "unknown object storage provider"},
+		{name: "local", cfg: Config{Provider: ProviderLocal, Path: "/var/lib/supacontrol"}},
+		{name: "local missing path", cfg: Config{Provider: ProviderLocal}, wantErr: "requires path"},
+		{name: "local relative path", cfg: Config{Provider: ProviderLocal, Path: "data"}, wantErr: "must be absolute"},
+		{
+			name: "s3",
+			cfg:  Config{Provider: ProviderS3, Bucket: "b", Region: "eu-west-1", AccessKey: "a", SecretKey: "s"},
+		},
+		{
+			name:    "s3 missing region and keys",
+			cfg:     Config{Provider: ProviderS3, Bucket: "b"},
+			wantErr: "requires access key, region, secret key",
+		},
+		{
+			name:    "minio missing endpoint",
+			cfg:     Config{Provider: ProviderMinIO, Bucket: "b", AccessKey: "a", SecretKey: "s"},
+			wantErr: "requires endpoint",
+		},
+		{
+			name:    "minio invalid endpoint",
+			cfg:     Config{Provider: ProviderMinIO, Bucket: "b", AccessKey: "a", SecretKey: "s", Endpoint: "minio:9000"},
+			wantErr: "must be an http(s) URL",
+		},
+		{name: "gcs", cfg: Config{Provider: ProviderGCS, Bucket: "b", AccessKey: "a", SecretKey: "s"}},
+		{
+			name: "azure",
+			cfg:  Config{Provider: ProviderAzure, Bucket: "backups", AccessKey: "account", SecretKey: "c2VjcmV0"},
+		},
+		{
+			name:    "azure key not base64",
+			cfg:     Config{Provider: ProviderAzure, Bucket: "backups", AccessKey: "account", SecretKey: "not base64!"},
+			wantErr: "base64",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.cfg.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Validate() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Validate() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestProviders(t *testing.T) {
+	got := strings.Join(Providers(), ",")
+	if want := "azure,gcs,local,minio,s3"; got != want {
+		t.Errorf("Providers() = %s, want %s", got, want)
+	}
+}
+
+func TestLocalBackend(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	backend, err := New(Config{Provider: ProviderLocal, Path: root, Prefix: "cluster-a"})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	if err := backend.Check(ctx); err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	for _, key := range []string{"backups/one.tar.gz", "backups/two.tar.gz", "bundles/fn.zip"} {
+		if err := backend.Put(ctx, key, strings.NewReader("data:"+key), -1); err != nil {
+			t.Fatalf("Put(%s) error = %v", key, err)
+		}
+	}
+
+	// Objects are stored below the configured prefix
+	if _, err := os.Stat(filepath.Join(root, "cluster-a", "backups", "one.tar.gz")); err != nil {
+		t.Errorf("expected object file on disk: %v", err)
+	}
+
+	r, err := backend.Get(ctx, "backups/two.tar.gz")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	data, _ := io.ReadAll(r)
+	_ = r.Close()
+	if string(data) != "data:backups/two.tar.gz" {
+		t.Errorf("Get() = %q", data)
+	}
+
+	objects, err := backend.List(ctx, "backups/")
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(objects) != 2 || objects[0].Key != "backups/one.tar.gz" || objects[1].Key != "backups/two.tar.gz" {
+		t.Errorf("List() = %+v", objects)
+	}
+
+	if err := backend.Delete(ctx, "backups/one.tar.gz"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if err := backend.Delete(ctx, "backups/one.tar.gz"); err != nil {
+		t.Errorf("Delete() of a missing object error = %v", err)
+	}
+	if _, err := backend.Get(ctx, "backups/one.tar.gz"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() of a deleted object error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestLocalBackend_RejectsEscapingKeys(t *testing.T) {
+	backend, err := New(Config{Provider: ProviderLocal, Path: t.TempDir()})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	for _, key := range []string{"", "/etc/passwd", "../outside", "a//b", "a/./b"} {
+		if err := backend.Put(context.Background(), key, strings.NewReader("x"), 1); err == nil {
+			t.Errorf("Put(%q) succeeded, want an error", key)
+		}
+	}
+}
+
+func TestLocalBackend_CheckMissingRoot(t *testing.T) {
+	backend, err := New(Config{Provider: ProviderLocal, Path: filepath.Join(t.TempDir(), "missing")})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if err := backend.Check(context.Background()); err == nil {
+		t.Error("Check() succeeded for a missing root directory")
+	}
+}
@@ -0,0 +1,364 @@
+package objectstore
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// S3-compatible providers. MinIO and GCS (through its XML API with HMAC keys)
+// speak the S3 protocol and differ only in their defaults.
+const (
+	ProviderS3    = "s3"
+	ProviderMinIO = "minio"
+	ProviderGCS   = "gcs"
+)
+
+// gcsEndpoint is the S3-interoperable endpoint of Google Cloud Storage
+const gcsEndpoint = "https://storage.googleapis.com"
+
+// unsignedPayload skips payload hashing so uploads can be streamed
+const unsignedPayload = "UNSIGNED-PAYLOAD"
+
+func init() {
+	Register(ProviderS3, Provider{
+		Validate: func(cfg Config) error {
+			return validateS3(ProviderS3, cfg, map[string]string{"region": cfg.Region})
+		},
+		New: func(cfg Config) (Backend, error) {
+			if cfg.Endpoint == "" {
+				// Virtual-hosted-style addressing, as path-style is deprecated on AWS
+				return newS3Backend(cfg, fmt.Sprintf("https://%s.s3.%s.amazonaws.com", cfg.Bucket, cfg.Region), false)
+			}
+			return newS3Backend(cfg, cfg.Endpoint, true)
+		},
+	})
+	Register(ProviderMinIO, Provider{
+		Validate: func(cfg Config) error {
+			return validateS3(ProviderMinIO, cfg, map[string]string{"endpoint": cfg.Endpoint})
+		},
+		New: func(cfg Config) (Backend, error) {
+			if cfg.Region == "" {
+				cfg.Region = "us-east-1"
+			}
+			return newS3Backend(cfg, cfg.Endpoint, true)
+		},
+	})
+	Register(ProviderGCS, Provider{
+		Validate: func(cfg Config) error {
+			return validateS3(ProviderGCS, cfg, nil)
+		},
+		New: func(cfg Config) (Backend, error) {
+			endpoint := cfg.Endpoint
+			if endpoint == "" {
+				endpoint = gcsEndpoint
+			}
+			if cfg.Region == "" {
+				cfg.Region = "auto"
+			}
+			return newS3Backend(cfg, endpoint, true)
+		},
+	})
+}
+
+// validateS3 checks the settings shared by S3-compatible providers plus the given extra ones
+func validateS3(provider string, cfg Config, extra map[string]string) error {
+	fields := map[string]string{
+		"bucket":     cfg.Bucket,
+		"access key": cfg.AccessKey,
+		"secret key": cfg.SecretKey,
+	}
+	for name, value := range extra {
+		fields[name] = value
+	}
+	if err := requireFields(provider, fields); err != nil {
+		return err
+	}
+	if cfg.Endpoint != "" {
+		if err := validateEndpoint(cfg.Endpoint); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateEndpoint requires an absolute http(s) URL
+func validateEndpoint(endpoint string) error {
+	u, err := url.Parse(endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("object storage endpoint %q must be an http(s) URL", endpoint)
+	}
+	return nil
+}
+
+// s3Backend talks to an S3-compatible API, signing requests with AWS Signature V4
+type s3Backend struct {
+	client    *http.Client
+	base      *url.URL // bucket URL: the endpoint, plus /<bucket> for path-style addressing
+	region    string
+	accessKey string
+	secretKey string
+
+	// now is replaced in tests to produce deterministic signatures
+	now func() time.Time
+}
+
+// newS3Backend builds a backend for the bucket reachable at endpoint
+func newS3Backend(cfg Config, endpoint string, pathStyle bool) (*s3Backend, error) {
+	base, err := url.Parse(strings.TrimSuffix(endpoint, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid object storage endpoint: %w", err)
+	}
+	if pathStyle {
+		base.Path += "/" + cfg.Bucket
+	}
+	return &s3Backend{
+		client:    &http.Client{Timeout: 5 * time.Minute},
+		base:      base,
+		region:    cfg.Region,
+		accessKey: cfg.AccessKey,
+		secretKey: cfg.SecretKey,
+		now:       time.Now,
+	}, nil
+}
+
+// do signs and sends a request for key (empty for the bucket itself)
+func (b *s3Backend) do(ctx context.Context, method, key string, query url.Values, body io.Reader, size int64) (*http.Response, error) {
+	u := *b.base
+	if key != "" {
+		u.Path += "/" + key
+	}
+	u.RawPath = canonicalPath(u.Path)
+	u.RawQuery = canonicalQuery(query)
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.ContentLength = size
+	}
+	b.sign(req)
+	return b.client.Do(req)
+}
+
+// sign adds an AWS Signature V4 Authorization header to the request
+func (b *s3Backend) sign(req *http.Request) {
+	now := b.now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", unsignedPayload)
+
+	signedHeaders := "host;x-amz-content-sha256;x-amz-date"
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		canonicalPath(req.URL.Path),
+		req.URL.RawQuery,
+		"host:" + req.URL.Host,
+		"x-amz-content-sha256:" + unsignedPayload,
+		"x-amz-date:" + amzDate,
+		"",
+		signedHeaders,
+		unsignedPayload,
+	}, "\n")
+
+	scope := date + "/" + b.region + "/s3/aws4_request"
+	hash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(hash[:])
+
+	key := hmacSHA256([]byte("AWS4"+b.secretKey), date)
+	key = hmacSHA256(key, b.region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		b.accessKey, scope, signedHeaders, signature))
+}
+
+func (b *s3Backend) Put(ctx context.Context, key string, body io.Reader, size int64) error {
+	if err := validateKey(key); err != nil {
+		return err
+	}
+	resp, err := b.do(ctx, http.MethodPut, key, nil, body, size)
+	if err != nil {
+		return fmt.Errorf("failed to upload %s: %w", key, err)
+	}
+	defer closeBody(resp)
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to upload %s: %w", key, s3Error(resp))
+	}
+	return nil
+}
+
+func (b *s3Backend) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	if err := validateKey(key); err != nil {
+		return nil, err
+	}
+	resp, err := b.do(ctx, http.MethodGet, key, nil, nil, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", key, err)
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return resp.Body, nil
+	case http.StatusNotFound:
+		closeBody(resp)
+		return nil, ErrNotFound
+	default:
+		defer closeBody(resp)
+		return nil, fmt.Errorf("failed to download %s: %w", key, s3Error(resp))
+	}
+}
+
+func (b *s3Backend) Delete(ctx context.Context, key string) error {
+	if err := validateKey(key); err != nil {
+		return err
+	}
+	resp, err := b.do(ctx, http.MethodDelete, key, nil, nil, 0)
+	if err != nil {
+		return fmt.Errorf("failed to delete %s: %w", key, err)
+	}
+	defer closeBody(resp)
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+		return fmt.Errorf("failed to delete %s: %w", key, s3Error(resp))
+	}
+	return nil
+}
+
+// listBucketResult is the ListObjectsV2 response
+type listBucketResult struct {
+	Contents []struct {
+		Key          string    `xml:"Key"`
+		Size         int64     `xml:"Size"`
+		LastModified time.Time `xml:"LastModified"`
+	} `xml:"Contents"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+func (b *s3Backend) List(ctx context.Context, prefix string) ([]Object, error) {
+	var objects []Object
+	query := url.Values{"list-type": {"2"}, "prefix": {prefix}}
+	for {
+		resp, err := b.do(ctx, http.MethodGet, "", query, nil, 0)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list objects: %w", err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			err := s3Error(resp)
+			closeBody(resp)
+			return nil, fmt.Errorf("failed to list objects: %w", err)
+		}
+		var result listBucketResult
+		err = xml.NewDecoder(resp.Body).Decode(&result)
+		closeBody(resp)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode object list: %w", err)
+		}
+
+		for _, c := range result.Contents {
+			objects = append(objects, Object{Key: c.Key, Size: c.Size, LastModified: c.LastModified})
+		}
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			break
+		}
+		query.Set("continuation-token", result.NextContinuationToken)
+	}
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Key < objects[j].Key })
+	return objects, nil
+}
+
+// Check verifies the bucket exists and the credentials can access it
+func (b *s3Backend) Check(ctx context.Context) error {
+	resp, err := b.do(ctx, http.MethodHead, "", nil, nil, 0)
+	if err != nil {
+		return fmt.Errorf("object storage unreachable: %w", err)
+	}
+	defer closeBody(resp)
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("object storage bucket check failed: %s", resp.Status)
+	}
+	return nil
+}
+
+// s3Error extracts the error code and message from an S3 error response
+func s3Error(resp *http.Response) error {
+	var body struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+	if err := xml.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&body); err != nil || body.Code == "" {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return fmt.Errorf("%s: %s (%s)", body.Code, body.Message, resp.Status)
+}
+
+// closeBody drains and closes a response body so the connection can be reused
+func closeBody(resp *http.Response) {
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	_ = resp.Body.Close()
+}
+
+// canonicalPath URI-encodes each path segment as Signature V4 requires
+func canonicalPath(path string) string {
+	if path == "" {
+		return "/"
+	}
+	segments := strings.Split(path, "/")
+	for i, s := range segments {
+		segments[i] = uriEncode(s)
+	}
+	return strings.Join(segments, "/")
+}
+
+// canonicalQuery encodes query parameters sorted by name, as Signature V4 requires
+func canonicalQuery(query url.Values) string {
+	if len(query) == 0 {
+		return ""
+	}
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var parts []string
+	for _, k := range keys {
+		for _, v := range query[k] {
+			parts = append(parts, uriEncode(k)+"="+uriEncode(v))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// uriEncode percent-encodes everything except RFC 3986 unreserved characters
+func uriEncode(s string) string {
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') ||
+			c == '-' || c == '_' || c == '.' || c == '~' {
+			sb.WriteByte(c)
+		} else {
+			fmt.Fprintf(&sb, "%%%02X", c)
+		}
+	}
+	return sb.String()
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,165 @@
+// Package pgcron manages the scheduled jobs of instance databases through the pg_cron
+// extension.
+//
+// The package builds psql invocations for the instance's database container: the script
+// is sent on stdin and every user-provided value is passed as a psql variable, so psql
+// quotes it rather than the value being spliced into SQL. Scripts print a single line
+// that the Parse functions read back.
+package pgcron
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+)
+
+// ErrJobExists is returned when a job with the requested name is already scheduled
+var ErrJobExists = errors.New("cron job already exists")
+
+var (
+	// namePattern restricts job names to what is safe to show and audit
+	namePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,62}$`)
+
+	// cronFieldPattern matches one field of a cron expression, including ranges, steps,
+	// lists, names of months and weekdays, and "$" for the last day of the month
+	cronFieldPattern = regexp.MustCompile(`^[0-9A-Za-z*,/$-]+$`)
+
+	// secondsPattern matches pg_cron's sub-minute interval schedules
+	secondsPattern = regexp.MustCompile(`^([1-9]|[1-5][0-9]) seconds?$`)
+)
+
+const (
+	// listScript prints the jobs as a JSON array, or null when pg_cron is not installed
+	listScript = `SELECT EXISTS (SELECT 1 FROM pg_extension WHERE extname = 'pg_cron') AS enabled \gset
+\if :enabled
+SELECT coalesce(json_agg(json_build_object(
+  'id', j.jobid, 'name', j.jobname, 'schedule', j.schedule, 'command', j.command, 'active', j.active,
+  'last_run_status', r.status, 'last_run_at', r.start_time
+) ORDER BY j.jobid), '[]') FROM cron.job j
+LEFT JOIN LATERAL (
+  SELECT d.status, d.start_time FROM cron.job_run_details d WHERE d.jobid = j.jobid ORDER BY d.start_time DESC LIMIT 1
+) r ON true;
+\else
+SELECT 'null';
+\endif
+`
+
+	// scheduleScript installs pg_cron if needed and schedules the job, printing its ID, or
+	// "exists" when a job of that name is already scheduled
+	scheduleScript = `CREATE EXTENSION IF NOT EXISTS pg_cron;
+SELECT EXISTS (SELECT 1 FROM cron.job WHERE jobname = :'name') AS exists \gset
+\if :exists
+SELECT 'exists';
+\else
+SELECT cron.schedule(:'name', :'schedule', :'command');
+\endif
+`
+
+	// unscheduleScript removes the job, printing how many jobs were removed
+	unscheduleScript = `SELECT EXISTS (SELECT 1 FROM pg_extension WHERE extname = 'pg_cron') AS enabled \gset
+\if :enabled
+SELECT count(cron.unschedule(jobid)) FROM cron.job WHERE jobname = :'name';
+\else
+SELECT 0;
+\endif
+`
+)
+
+// Invocation is a psql command and the script to write to its stdin
+type Invocation struct {
+	Command []string
+	Script  string
+}
+
+// psql returns the invocation of a script against the instance's postgres database,
+// passing vars as psql variables. Connecting over the loopback interface lets the
+// chart's trust rule authenticate the postgres user without a password.
+func psql(script string, vars map[string]string) Invocation {
+	command := []string{"psql", "-X", "-q", "-t", "-A", "-v", "ON_ERROR_STOP=1",
+		"-h", "127.0.0.1", "-U", "postgres", "-d", "postgres"}
+	for _, key := range []string{"name", "schedule", "command"} {
+		if value, ok := vars[key]; ok {
+			command = append(command, "-v", key+"="+value)
+		}
+	}
+	return Invocation{Command: command, Script: script}
+}
+
+// List returns the invocation listing the jobs, whose output ParseList reads
+func List() Invocation {
+	return psql(listScript, nil)
+}
+
+// Schedule returns the invocation scheduling a job, whose output ParseSchedule reads
+func Schedule(req apitypes.CreateCronJobRequest) Invocation {
+	return psql(scheduleScript, map[string]string{"name": req.Name, "schedule": req.Schedule, "command": req.Command})
+}
+
+// Unschedule returns the invocation removing a job, whose output ParseUnschedule reads
+func Unschedule(name string) Invocation {
+	return psql(unscheduleScript, map[string]string{"name": name})
+}
+
+// ValidName reports whether a job name consists of up to 63 lowercase letters, digits,
+// hyphens and underscores
+func ValidName(name string) bool {
+	return namePattern.MatchString(name)
+}
+
+// ValidSchedule reports whether a schedule is a five-field cron expression or an interval
+// of 1-59 seconds
+func ValidSchedule(schedule string) bool {
+	if secondsPattern.MatchString(schedule) {
+		return true
+	}
+	fields := strings.Fields(schedule)
+	if len(fields) != 5 || strings.Join(fields, " ") != schedule {
+		return false
+	}
+	for _, field := range fields {
+		if !cronFieldPattern.MatchString(field) {
+			return false
+		}
+	}
+	return true
+}
+
+// ParseList reads the jobs listed by List, reporting whether pg_cron is installed
+func ParseList(output string) (bool, []*apitypes.CronJob, error) {
+	output = strings.TrimSpace(output)
+	if output == "null" {
+		return false, []*apitypes.CronJob{}, nil
+	}
+	jobs := []*apitypes.CronJob{}
+	if err := json.Unmarshal([]byte(output), &jobs); err != nil {
+		return false, nil, fmt.Errorf("unexpected cron job list: %w", err)
+	}
+	return true, jobs, nil
+}
+
+// ParseSchedule reads the ID of the job scheduled by Schedule
+func ParseSchedule(output string) (int64, error) {
+	output = strings.TrimSpace(output)
+	if output == "exists" {
+		return 0, ErrJobExists
+	}
+	id, err := strconv.ParseInt(output, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("unexpected cron job ID %q", output)
+	}
+	return id, nil
+}
+
+// ParseUnschedule reads whether Unschedule removed a job
+func ParseUnschedule(output string) (bool, error) {
+	count, err := strconv.Atoi(strings.TrimSpace(output))
+	if err != nil {
+		return false, fmt.Errorf("unexpected cron job count %q", strings.TrimSpace(output))
+	}
+	return count > 0, nil
+}
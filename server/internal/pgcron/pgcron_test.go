@@ -0,0 +1,88 @@
+package pgcron
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+)
+
+func TestValidSchedule(t *testing.T) {
+	for _, schedule := range []string{"0 3 * * *", "*/5 * * * *", "0 0 $ * *", "30 9 * * mon-fri", "1-30/2 * 1,15 jan *", "30 seconds", "1 second"} {
+		if !ValidSchedule(schedule) {
+			t.Errorf("ValidSchedule(%q) = false, want true", schedule)
+		}
+	}
+	for _, schedule := range []string{"", "* * * *", "0  3 * * *", "0 3 * * * *", "60 seconds", "0 3 * * *;", "@daily", "0 3 * * '"} {
+		if ValidSchedule(schedule) {
+			t.Errorf("ValidSchedule(%q) accepted an invalid schedule", schedule)
+		}
+	}
+}
+
+func TestValidName(t *testing.T) {
+	if !ValidName("nightly-vacuum_2") {
+		t.Error("ValidName() rejected a valid name")
+	}
+	for _, name := range []string{"", "Nightly", "-vacuum", "vacuum job", strings.Repeat("a", 64)} {
+		if ValidName(name) {
+			t.Errorf("ValidName(%q) accepted an invalid name", name)
+		}
+	}
+}
+
+func TestSchedule(t *testing.T) {
+	invocation := Schedule(apitypes.CreateCronJobRequest{Name: "cleanup", Schedule: "0 3 * * *", Command: "DELETE FROM logs WHERE at < now() - interval '7 days'"})
+	for _, arg := range []string{"name=cleanup", "schedule=0 3 * * *", "command=DELETE FROM logs WHERE at < now() - interval '7 days'"} {
+		if !slices.Contains(invocation.Command, arg) {
+			t.Errorf("expected psql variable %q in %v", arg, invocation.Command)
+		}
+	}
+	if strings.Contains(invocation.Script, "cleanup") || !strings.Contains(invocation.Script, ":'command'") {
+		t.Errorf("expected values to be passed as psql variables, got script:\n%s", invocation.Script)
+	}
+}
+
+func TestParseList(t *testing.T) {
+	enabled, jobs, err := ParseList("null\n")
+	if err != nil || enabled || len(jobs) != 0 {
+		t.Errorf("ParseList(null) = %v, %v, %v", enabled, jobs, err)
+	}
+
+	output := `[{"id" : 1, "name" : "cleanup", "schedule" : "0 3 * * *", "command" : "VACUUM", "active" : true, "last_run_status" : "succeeded", "last_run_at" : "2026-01-02T03:00:00.012345+00:00"},
+ {"id" : 2, "name" : "report", "schedule" : "30 seconds", "command" : "SELECT 1", "active" : false, "last_run_status" : null, "last_run_at" : null}]
+`
+	enabled, jobs, err = ParseList(output)
+	if err != nil || !enabled || len(jobs) != 2 {
+		t.Fatalf("ParseList() = %v, %v, %v", enabled, jobs, err)
+	}
+	if jobs[0].LastRunStatus != "succeeded" || jobs[0].LastRunAt == nil || !jobs[0].LastRunAt.Equal(time.Date(2026, 1, 2, 3, 0, 0, 12345000, time.UTC)) {
+		t.Errorf("unexpected first job %+v", jobs[0])
+	}
+	if jobs[1].Active || jobs[1].LastRunAt != nil {
+		t.Errorf("unexpected second job %+v", jobs[1])
+	}
+
+	if _, _, err := ParseList("ERROR"); err == nil {
+		t.Error("ParseList() accepted unexpected output")
+	}
+}
+
+func TestParseScheduleAndUnschedule(t *testing.T) {
+	if id, err := ParseSchedule("42\n"); id != 42 || err != nil {
+		t.Errorf("ParseSchedule() = %d, %v", id, err)
+	}
+	if _, err := ParseSchedule("exists\n"); !errors.Is(err, ErrJobExists) {
+		t.Errorf("ParseSchedule(exists) error = %v, want ErrJobExists", err)
+	}
+
+	if removed, err := ParseUnschedule("1\n"); !removed || err != nil {
+		t.Errorf("ParseUnschedule(1) = %v, %v", removed, err)
+	}
+	if removed, err := ParseUnschedule("0\n"); removed || err != nil {
+		t.Errorf("ParseUnschedule(0) = %v, %v", removed, err)
+	}
+}
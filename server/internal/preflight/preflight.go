@@ -0,0 +1,230 @@
+// Package preflight runs the startup checks that must pass before SupaControl
+// serves requests: a strong JWT secret, a reachable database, an installed
+// SupabaseInstance CRD and the RBAC permissions the controller relies on.
+//
+// Every check runs even when an earlier one fails, so a misconfigured
+// installation reports all of its problems at once instead of one per restart.
+package preflight
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+)
+
+// MinJWTSecretLength is the shortest JWT secret accepted, matching the 256-bit
+// key HS256 signing wants
+const MinJWTSecretLength = 32
+
+// minJWTSecretChars is the fewest distinct characters a JWT secret may use
+const minJWTSecretChars = 8
+
+// publishedJWTSecrets are example secrets shipped with SupaControl, which are
+// long enough but public
+var publishedJWTSecrets = []string{
+	"your-super-secret-jwt-key-minimum-32-characters",
+	"f59b0603f47248f02d4f723b2991f0d344f3e2279395817d7f11cad23af066ea",
+}
+
+// Check is a named startup check
+type Check struct {
+	Name string
+	Run  func(ctx context.Context) error
+}
+
+// Failure is a failed check
+type Failure struct {
+	Check string
+	Err   error
+}
+
+// Error reports every failed check
+type Error struct {
+	Failures []Failure
+}
+
+// Error implements the error interface
+func (e *Error) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d preflight check(s) failed:", len(e.Failures))
+	for _, f := range e.Failures {
+		fmt.Fprintf(&b, "\n  - %s: %v", f.Check, f.Err)
+	}
+	return b.String()
+}
+
+// Run runs all checks in order and returns an *Error listing the failed ones,
+// or nil when all of them pass
+func Run(ctx context.Context, checks ...Check) error {
+	var failures []Failure
+	for _, check := range checks {
+		if err := check.Run(ctx); err != nil {
+			failures = append(failures, Failure{Check: check.Name, Err: err})
+		}
+	}
+	if len(failures) > 0 {
+		return &Error{Failures: failures}
+	}
+	return nil
+}
+
+// Backoff controls how often and how patiently a check is retried
+type Backoff struct {
+	Attempts     int
+	InitialDelay time.Duration
+	MaxDelay     time.Duration
+}
+
+// DefaultBackoff retries for about a minute, long enough for a database
+// started alongside SupaControl to come up
+var DefaultBackoff = Backoff{Attempts: 6, InitialDelay: 2 * time.Second, MaxDelay: 30 * time.Second}
+
+// Retry wraps fn so it is retried with exponential backoff until it succeeds,
+// the attempts are used up or ctx is done. The last error is returned.
+func Retry(backoff Backoff, fn func(ctx context.Context) error) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		delay := backoff.InitialDelay
+		var err error
+		for attempt := 1; ; attempt++ {
+			if err = fn(ctx); err == nil {
+				return nil
+			}
+			if attempt >= backoff.Attempts {
+				return fmt.Errorf("failed after %d attempts: %w", attempt, err)
+			}
+			select {
+			case <-ctx.Done():
+				return fmt.Errorf("%w (last error: %v)", ctx.Err(), err)
+			case <-time.After(delay):
+			}
+			delay *= 2
+			if backoff.MaxDelay > 0 && delay > backoff.MaxDelay {
+				delay = backoff.MaxDelay
+			}
+		}
+	}
+}
+
+// JWTSecret checks that the JWT secret is long, varied and not one of the
+// published example secrets
+func JWTSecret(secret string) Check {
+	return Check{Name: "jwt_secret", Run: func(context.Context) error {
+		if len(secret) < MinJWTSecretLength {
+			return fmt.Errorf("JWT_SECRET must be at least %d characters, got %d", MinJWTSecretLength, len(secret))
+		}
+		for _, published := range publishedJWTSecrets {
+			if secret == published {
+				return fmt.Errorf("JWT_SECRET is a published example value; generate a random one (e.g. openssl rand -hex 32)")
+			}
+		}
+		distinct := map[rune]bool{}
+		for _, r := range secret {
+			distinct[r] = true
+		}
+		if len(distinct) < minJWTSecretChars {
+			return fmt.Errorf("JWT_SECRET uses only %d distinct characters; generate a random one (e.g. openssl rand -hex 32)", len(distinct))
+		}
+		return nil
+	}}
+}
+
+// Database checks database connectivity with connect, retrying with backoff
+func Database(backoff Backoff, connect func(ctx context.Context) error) Check {
+	return Check{Name: "database", Run: Retry(backoff, connect)}
+}
+
+// CRD checks that the SupabaseInstance CRD is installed and served
+func CRD(clientset kubernetes.Interface) Check {
+	return Check{Name: "crd", Run: func(context.Context) error {
+		groupVersion := supacontrolv1alpha1.GroupVersion.String()
+		resources, err := clientset.Discovery().ServerResourcesForGroupVersion(groupVersion)
+		if err != nil {
+			return fmt.Errorf("%s is not served (is the SupabaseInstance CRD installed?): %w", groupVersion, err)
+		}
+		for _, resource := range resources.APIResources {
+			if resource.Name == "supabaseinstances" {
+				return nil
+			}
+		}
+		return fmt.Errorf("supabaseinstances is not served by %s; install deploy/crds", groupVersion)
+	}}
+}
+
+// Permission is an action the control plane must be allowed to perform cluster-wide
+type Permission struct {
+	Group       string
+	Resource    string
+	Subresource string
+	Verb        string
+}
+
+// String renders the permission like kubectl auth can-i
+func (p Permission) String() string {
+	resource := p.Resource
+	if p.Subresource != "" {
+		resource += "/" + p.Subresource
+	}
+	if p.Group != "" {
+		resource += "." + p.Group
+	}
+	return p.Verb + " " + resource
+}
+
+// RequiredPermissions are the permissions the API and controller cannot work
+// without. Optional features (metrics, isolation detection) degrade on their own.
+var RequiredPermissions = []Permission{
+	{Group: supacontrolv1alpha1.GroupVersion.Group, Resource: "supabaseinstances", Verb: "list"},
+	{Group: supacontrolv1alpha1.GroupVersion.Group, Resource: "supabaseinstances", Verb: "watch"},
+	{Group: supacontrolv1alpha1.GroupVersion.Group, Resource: "supabaseinstances", Verb: "create"},
+	{Group: supacontrolv1alpha1.GroupVersion.Group, Resource: "supabaseinstances", Verb: "update"},
+	{Group: supacontrolv1alpha1.GroupVersion.Group, Resource: "supabaseinstances", Verb: "delete"},
+	{Group: supacontrolv1alpha1.GroupVersion.Group, Resource: "supabaseinstances", Subresource: "status", Verb: "update"},
+	{Resource: "namespaces", Verb: "create"},
+	{Resource: "namespaces", Verb: "delete"},
+	{Resource: "secrets", Verb: "create"},
+	{Resource: "secrets", Verb: "get"},
+	{Resource: "pods", Verb: "list"},
+	{Resource: "pods", Subresource: "log", Verb: "get"},
+	{Group: "batch", Resource: "jobs", Verb: "create"},
+	{Group: "apps", Resource: "deployments", Verb: "update"},
+	{Group: "apps", Resource: "statefulsets", Verb: "update"},
+	{Group: "networking.k8s.io", Resource: "ingresses", Verb: "update"},
+}
+
+// RBAC checks with SelfSubjectAccessReviews that the control plane's identity
+// holds every permission
+func RBAC(clientset kubernetes.Interface, permissions []Permission) Check {
+	return Check{Name: "rbac", Run: func(ctx context.Context) error {
+		var denied []string
+		for _, permission := range permissions {
+			review := &authorizationv1.SelfSubjectAccessReview{
+				Spec: authorizationv1.SelfSubjectAccessReviewSpec{
+					ResourceAttributes: &authorizationv1.ResourceAttributes{
+						Group:       permission.Group,
+						Resource:    permission.Resource,
+						Subresource: permission.Subresource,
+						Verb:        permission.Verb,
+					},
+				},
+			}
+			result, err := clientset.AuthorizationV1().SelfSubjectAccessReviews().Create(ctx, review, metav1.CreateOptions{})
+			if err != nil {
+				return fmt.Errorf("failed to review permission %q: %w", permission, err)
+			}
+			if !result.Status.Allowed {
+				denied = append(denied, permission.String())
+			}
+		}
+		if len(denied) > 0 {
+			return fmt.Errorf("missing permissions: %s", strings.Join(denied, ", "))
+		}
+		return nil
+	}}
+}
@@ -0,0 +1,159 @@
+package preflight
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+)
+
+// TestRunReportsAllFailures tests that every check runs and all failures are reported
+func TestRunReportsAllFailures(t *testing.T) {
+	ran := 0
+	check := func(name string, err error) Check {
+		return Check{Name: name, Run: func(context.Context) error {
+			ran++
+			return err
+		}}
+	}
+
+	err := Run(context.Background(),
+		check("first", errors.New("broken")),
+		check("second", nil),
+		check("third", errors.New("also broken")),
+	)
+
+	if ran != 3 {
+		t.Errorf("expected all 3 checks to run, ran %d", ran)
+	}
+	var preflightErr *Error
+	if !errors.As(err, &preflightErr) {
+		t.Fatalf("expected *Error, got %T (%v)", err, err)
+	}
+	if len(preflightErr.Failures) != 2 || preflightErr.Failures[0].Check != "first" || preflightErr.Failures[1].Check != "third" {
+		t.Errorf("unexpected failures %+v", preflightErr.Failures)
+	}
+	if !strings.Contains(err.Error(), "third: also broken") {
+		t.Errorf("expected the message to list each failure, got %q", err.Error())
+	}
+
+	if err := Run(context.Background(), check("ok", nil)); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+}
+
+// TestRetry tests that a failing function is retried until it succeeds or runs out of attempts
+func TestRetry(t *testing.T) {
+	backoff := Backoff{Attempts: 3, InitialDelay: time.Millisecond, MaxDelay: 2 * time.Millisecond}
+
+	calls := 0
+	err := Retry(backoff, func(context.Context) error {
+		calls++
+		if calls < 3 {
+			return errors.New("not yet")
+		}
+		return nil
+	})(context.Background())
+	if err != nil || calls != 3 {
+		t.Errorf("expected success on the third attempt, got %v after %d calls", err, calls)
+	}
+
+	calls = 0
+	err = Retry(backoff, func(context.Context) error {
+		calls++
+		return errors.New("down")
+	})(context.Background())
+	if err == nil || calls != 3 {
+		t.Errorf("expected failure after 3 attempts, got %v after %d calls", err, calls)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = Retry(Backoff{Attempts: 5, InitialDelay: time.Hour}, func(context.Context) error {
+		return errors.New("down")
+	})(ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected the cancellation to stop retries, got %v", err)
+	}
+}
+
+// TestJWTSecret tests JWT secret strength validation
+func TestJWTSecret(t *testing.T) {
+	tests := []struct {
+		name      string
+		secret    string
+		expectErr bool
+	}{
+		{name: "random hex", secret: "3f9c1e7a5b2d8046f1e3a7c9b5d20486e1f3a5c7b9d0e2f4a6c8e0b2d4f6a8c0"},
+		{name: "too short", secret: "short-secret", expectErr: true},
+		{name: "env example", secret: "your-super-secret-jwt-key-minimum-32-characters", expectErr: true},
+		{name: "chart example", secret: "f59b0603f47248f02d4f723b2991f0d344f3e2279395817d7f11cad23af066ea", expectErr: true},
+		{name: "repeated characters", secret: strings.Repeat("ab", 20), expectErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := JWTSecret(tt.secret).Run(context.Background())
+			if tt.expectErr != (err != nil) {
+				t.Errorf("expected error %v, got %v", tt.expectErr, err)
+			}
+		})
+	}
+}
+
+// TestCRD tests detection of the SupabaseInstance CRD through discovery
+func TestCRD(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	if err := CRD(clientset).Run(context.Background()); err == nil {
+		t.Error("expected an error without the CRD")
+	}
+
+	clientset.Discovery().(*fakediscovery.FakeDiscovery).Resources = []*metav1.APIResourceList{{
+		GroupVersion: supacontrolv1alpha1.GroupVersion.String(),
+		APIResources: []metav1.APIResource{{Name: "supabaseinstances", Kind: "SupabaseInstance"}},
+	}}
+	if err := CRD(clientset).Run(context.Background()); err != nil {
+		t.Errorf("expected the CRD to be found, got %v", err)
+	}
+}
+
+// TestRBAC tests that denied permissions are all reported
+func TestRBAC(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	clientset.PrependReactor("create", "selfsubjectaccessreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		review := action.(k8stesting.CreateAction).GetObject().(*authorizationv1.SelfSubjectAccessReview)
+		review.Status.Allowed = review.Spec.ResourceAttributes.Resource != "namespaces"
+		return true, review, nil
+	})
+
+	err := RBAC(clientset, RequiredPermissions).Run(context.Background())
+	if err == nil {
+		t.Fatal("expected missing namespace permissions to be reported")
+	}
+	if !strings.Contains(err.Error(), "create namespaces, delete namespaces") {
+		t.Errorf("expected both namespace permissions in %q", err.Error())
+	}
+
+	permissions := []Permission{{Group: "batch", Resource: "jobs", Verb: "create"}}
+	if err := RBAC(clientset, permissions).Run(context.Background()); err != nil {
+		t.Errorf("expected allowed permissions to pass, got %v", err)
+	}
+}
+
+// TestPermissionString tests the kubectl-style rendering of permissions
+func TestPermissionString(t *testing.T) {
+	permission := Permission{Group: "supacontrol.qubitquilt.com", Resource: "supabaseinstances", Subresource: "status", Verb: "update"}
+	if got := permission.String(); got != "update supabaseinstances/status.supacontrol.qubitquilt.com" {
+		t.Errorf("unexpected rendering %q", got)
+	}
+}
@@ -0,0 +1,330 @@
+// Package profiles defines admin-managed shared service profiles (SMTP relays,
+// S3 buckets, OAuth apps) that instances reference by name instead of each
+// project configuring the same service by hand.
+//
+// Profiles are stored as Secrets in the controller namespace. Non-sensitive
+// settings and credentials are kept under separate key prefixes so the API can
+// return settings while never echoing credentials back. The controller renders
+// the profiles an instance references into Helm chart values, except for OAuth
+// client secrets, which the auth service reads from a Secret instead.
+package profiles
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// Namespace is where profile Secrets live, alongside the provisioning Jobs
+	Namespace = "supacontrol-system"
+
+	// TypeLabel is the label key holding a profile Secret's profile type
+	TypeLabel = "supacontrol.io/profile-type"
+
+	// secretPrefix prefixes profile names to form their Secret names
+	secretPrefix = "supacontrol-profile-"
+
+	// settingPrefix and credentialPrefix separate the two kinds of Secret data keys
+	settingPrefix    = "setting."
+	credentialPrefix = "secret."
+)
+
+// Profile types
+const (
+	TypeSMTP  = "smtp"
+	TypeS3    = "s3"
+	TypeOAuth = "oauth"
+)
+
+// Field describes one setting or credential of a profile type
+type Field struct {
+	Key      string
+	Required bool
+
+	// Choices restricts the value to a fixed set when non-empty
+	Choices []string
+}
+
+// Definition describes the settings and credentials a profile type accepts
+type Definition struct {
+	Settings []Field
+	Secrets  []Field
+}
+
+// OAuthProviders are the external providers supported by Supabase Auth
+var OAuthProviders = []string{
+	"apple", "azure", "bitbucket", "discord", "facebook", "github", "gitlab",
+	"google", "keycloak", "linkedin", "slack", "spotify", "twitch", "twitter", "zoom",
+}
+
+var definitions = map[string]Definition{
+	TypeSMTP: {
+		Settings: []Field{
+			{Key: "host", Required: true},
+			{Key: "port", Required: true},
+			{Key: "sender_email", Required: true},
+			{Key: "sender_name"},
+		},
+		Secrets: []Field{
+			{Key: "username", Required: true},
+			{Key: "password", Required: true},
+		},
+	},
+	TypeS3: {
+		Settings: []Field{
+			{Key: "bucket", Required: true},
+			{Key: "region", Required: true},
+			{Key: "endpoint"},
+			{Key: "force_path_style", Choices: []string{"true", "false"}},
+		},
+		Secrets: []Field{
+			{Key: "access_key_id", Required: true},
+			{Key: "secret_access_key", Required: true},
+		},
+	},
+	TypeOAuth: {
+		Settings: []Field{
+			{Key: "provider", Required: true, Choices: OAuthProviders},
+			{Key: "client_id", Required: true},
+		},
+		Secrets: []Field{
+			{Key: "client_secret", Required: true},
+		},
+	},
+}
+
+// Types returns the supported profile types in sorted order
+func Types() []string {
+	types := make([]string, 0, len(definitions))
+	for t := range definitions {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// Lookup returns the definition of a profile type
+func Lookup(profileType string) (Definition, bool) {
+	def, ok := definitions[profileType]
+	return def, ok
+}
+
+// Profile is a named set of shared service settings and credentials
+type Profile struct {
+	Name      string
+	Type      string
+	Settings  map[string]string
+	Secrets   map[string]string
+	CreatedAt time.Time
+}
+
+// SecretName returns the name of the Secret storing a profile
+func SecretName(name string) string {
+	return secretPrefix + name
+}
+
+// Slot identifies what a profile configures on an instance. An instance can
+// attach at most one profile per slot: one SMTP relay, one S3 bucket and one
+// OAuth app per provider.
+func (p *Profile) Slot() string {
+	if p.Type == TypeOAuth {
+		return p.Type + ":" + p.Settings["provider"]
+	}
+	return p.Type
+}
+
+// SecretKeys returns the sorted names of the profile's credentials
+func (p *Profile) SecretKeys() []string {
+	keys := make([]string, 0, len(p.Secrets))
+	for k := range p.Secrets {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// ToSecret converts a profile into the Secret that stores it
+func ToSecret(p *Profile) *corev1.Secret {
+	data := make(map[string][]byte, len(p.Settings)+len(p.Secrets))
+	for k, v := range p.Settings {
+		data[settingPrefix+k] = []byte(v)
+	}
+	for k, v := range p.Secrets {
+		data[credentialPrefix+k] = []byte(v)
+	}
+
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      SecretName(p.Name),
+			Namespace: Namespace,
+			Labels: map[string]string{
+				"app.kubernetes.io/managed-by": "supacontrol",
+				TypeLabel:                      p.Type,
+			},
+		},
+		Type: corev1.SecretTypeOpaque,
+		Data: data,
+	}
+}
+
+// FromSecret reads a profile back from its Secret
+func FromSecret(secret *corev1.Secret) (*Profile, error) {
+	profileType := secret.Labels[TypeLabel]
+	if profileType == "" || !strings.HasPrefix(secret.Name, secretPrefix) {
+		return nil, fmt.Errorf("secret %s is not a shared service profile", secret.Name)
+	}
+
+	p := &Profile{
+		Name:      strings.TrimPrefix(secret.Name, secretPrefix),
+		Type:      profileType,
+		Settings:  make(map[string]string),
+		Secrets:   make(map[string]string),
+		CreatedAt: secret.CreationTimestamp.Time,
+	}
+	for k, v := range secret.Data {
+		switch {
+		case strings.HasPrefix(k, settingPrefix):
+			p.Settings[strings.TrimPrefix(k, settingPrefix)] = string(v)
+		case strings.HasPrefix(k, credentialPrefix):
+			p.Secrets[strings.TrimPrefix(k, credentialPrefix)] = string(v)
+		}
+	}
+	return p, nil
+}
+
+// Values builds the Supabase chart values that apply the given profiles. apiURL is
+// the instance's public API URL, used for OAuth callback URLs. OAuth client secrets
+// are left out; see OAuthSecrets.
+func Values(profiles []*Profile, apiURL string) map[string]interface{} {
+	values := map[string]interface{}{}
+	for _, p := range profiles {
+		switch p.Type {
+		case TypeSMTP:
+			setEnv(values, "auth", map[string]string{
+				"SMTP_HOST":        p.Settings["host"],
+				"SMTP_PORT":        p.Settings["port"],
+				"SMTP_ADMIN_EMAIL": p.Settings["sender_email"],
+				"SMTP_SENDER_NAME": p.Settings["sender_name"],
+			})
+			setSecret(values, "smtp", map[string]string{
+				"username": p.Secrets["username"],
+				"password": p.Secrets["password"],
+			})
+		case TypeS3:
+			setEnv(values, "storage", map[string]string{
+				"STORAGE_BACKEND":            "s3",
+				"GLOBAL_S3_BUCKET":           p.Settings["bucket"],
+				"REGION":                     p.Settings["region"],
+				"GLOBAL_S3_ENDPOINT":         p.Settings["endpoint"],
+				"GLOBAL_S3_FORCE_PATH_STYLE": p.Settings["force_path_style"],
+			})
+			setSecret(values, "s3", map[string]string{
+				"keyId":     p.Secrets["access_key_id"],
+				"accessKey": p.Secrets["secret_access_key"],
+			})
+		case TypeOAuth:
+			prefix := oauthEnvPrefix(p.Settings["provider"])
+			setEnv(values, "auth", map[string]string{
+				prefix + "ENABLED":      "true",
+				prefix + "CLIENT_ID":    p.Settings["client_id"],
+				prefix + "REDIRECT_URI": apiURL + "/auth/v1/callback",
+			})
+		}
+	}
+	return values
+}
+
+// OAuthSecretEnvName returns the auth service variable holding a provider's client secret
+func OAuthSecretEnvName(provider string) string {
+	return oauthEnvPrefix(provider) + "SECRET"
+}
+
+// oauthEnvPrefix returns the prefix of a provider's auth service variables
+func oauthEnvPrefix(provider string) string {
+	return "GOTRUE_EXTERNAL_" + strings.ToUpper(provider) + "_"
+}
+
+// OAuthSecrets returns the client secrets of the OAuth profiles, keyed by the auth
+// service variable that holds each one
+func OAuthSecrets(profiles []*Profile) map[string]string {
+	secrets := map[string]string{}
+	for _, p := range profiles {
+		if p.Type == TypeOAuth && p.Secrets["client_secret"] != "" {
+			secrets[OAuthSecretEnvName(p.Settings["provider"])] = p.Secrets["client_secret"]
+		}
+	}
+	return secrets
+}
+
+// Checksum returns a checksum of the profiles' names, settings and credentials, or an
+// empty string when there are none, so that editing an attached profile changes it
+func Checksum(profiles []*Profile) string {
+	if len(profiles) == 0 {
+		return ""
+	}
+	sorted := append([]*Profile(nil), profiles...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+
+	sum := sha256.New()
+	writeMap := func(m map[string]string) {
+		keys := make([]string, 0, len(m))
+		for k := range m {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			fmt.Fprintf(sum, "%s=%s\x00", k, m[k])
+		}
+	}
+	for _, p := range sorted {
+		fmt.Fprintf(sum, "%s\x00%s\x00", p.Name, p.Type)
+		writeMap(p.Settings)
+		writeMap(p.Secrets)
+	}
+	return hex.EncodeToString(sum.Sum(nil))[:16]
+}
+
+// Render returns the chart values for the given profiles as a YAML document
+func Render(profiles []*Profile, apiURL string) ([]byte, error) {
+	data, err := yaml.Marshal(Values(profiles, apiURL))
+	if err != nil {
+		return nil, fmt.Errorf("failed to render profile values: %w", err)
+	}
+	return data, nil
+}
+
+// setEnv merges non-empty variables into <component>.environment
+func setEnv(values map[string]interface{}, component string, env map[string]string) {
+	merge(values, []string{component, "environment"}, env)
+}
+
+// setSecret merges non-empty entries into secret.<name>
+func setSecret(values map[string]interface{}, name string, entries map[string]string) {
+	merge(values, []string{"secret", name}, entries)
+}
+
+// merge writes non-empty entries into the nested map at path, creating it as needed
+func merge(values map[string]interface{}, path []string, entries map[string]string) {
+	node := values
+	for _, key := range path {
+		child, ok := node[key].(map[string]interface{})
+		if !ok {
+			child = map[string]interface{}{}
+			node[key] = child
+		}
+		node = child
+	}
+	for k, v := range entries {
+		if v != "" {
+			node[k] = v
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package profiles
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestSecretRoundTrip tests that a profile survives conversion to and from its Secret
+func TestSecretRoundTrip(t *testing.T) {
+	profile := &Profile{
+		Name:     "mailgun",
+		Type:     TypeSMTP,
+		Settings: map[string]string{"host": "smtp.mailgun.org", "port": "587", "sender_email": "no-reply@example.com"},
+		Secrets:  map[string]string{"username": "postmaster", "password": "hunter2"},
+	}
+
+	secret := ToSecret(profile)
+	if secret.Name != "supacontrol-profile-mailgun" || secret.Namespace != Namespace {
+		t.Errorf("unexpected secret %s/%s", secret.Namespace, secret.Name)
+	}
+
+	got, err := FromSecret(secret)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.Name != profile.Name || got.Type != profile.Type {
+		t.Errorf("expected %s/%s, got %s/%s", profile.Name, profile.Type, got.Name, got.Type)
+	}
+	if !reflect.DeepEqual(got.Settings, profile.Settings) {
+		t.Errorf("expected settings %v, got %v", profile.Settings, got.Settings)
+	}
+	if !reflect.DeepEqual(got.Secrets, profile.Secrets) {
+		t.Errorf("expected secrets %v, got %v", profile.Secrets, got.Secrets)
+	}
+	if keys := got.SecretKeys(); !reflect.DeepEqual(keys, []string{"password", "username"}) {
+		t.Errorf("unexpected secret keys %v", keys)
+	}
+}
+
+// TestFromSecret_RejectsUnlabeled tests that unrelated Secrets are not read as profiles
+func TestFromSecret_RejectsUnlabeled(t *testing.T) {
+	secret := ToSecret(&Profile{Name: "mailgun", Type: TypeSMTP})
+	delete(secret.Labels, TypeLabel)
+
+	if _, err := FromSecret(secret); err == nil {
+		t.Error("expected error for secret without profile type label")
+	}
+}
+
+// TestSlot tests that OAuth profiles occupy one slot per provider
+func TestSlot(t *testing.T) {
+	tests := []struct {
+		profile  *Profile
+		expected string
+	}{
+		{&Profile{Type: TypeSMTP}, "smtp"},
+		{&Profile{Type: TypeS3}, "s3"},
+		{&Profile{Type: TypeOAuth, Settings: map[string]string{"provider": "github"}}, "oauth:github"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.profile.Slot(); got != tt.expected {
+			t.Errorf("expected slot %q, got %q", tt.expected, got)
+		}
+	}
+}
+
+// TestValues tests the chart values generated for each profile type
+func TestValues(t *testing.T) {
+	values := Values([]*Profile{
+		{
+			Type:     TypeSMTP,
+			Settings: map[string]string{"host": "smtp.example.com", "port": "587", "sender_email": "no-reply@example.com"},
+			Secrets:  map[string]string{"username": "user", "password": "pass"},
+		},
+		{
+			Type:     TypeS3,
+			Settings: map[string]string{"bucket": "assets", "region": "eu-west-1"},
+			Secrets:  map[string]string{"access_key_id": "AKIA", "secret_access_key": "s3cret"},
+		},
+		{
+			Type:     TypeOAuth,
+			Settings: map[string]string{"provider": "github", "client_id": "abc"},
+			Secrets:  map[string]string{"client_secret": "xyz"},
+		},
+	}, "https://my-app-api.example.com")
+
+	authEnv := values["auth"].(map[string]interface{})["environment"].(map[string]interface{})
+	expectedAuth := map[string]interface{}{
+		"SMTP_HOST":                           "smtp.example.com",
+		"SMTP_PORT":                           "587",
+		"SMTP_ADMIN_EMAIL":                    "no-reply@example.com",
+		"GOTRUE_EXTERNAL_GITHUB_ENABLED":      "true",
+		"GOTRUE_EXTERNAL_GITHUB_CLIENT_ID":    "abc",
+		"GOTRUE_EXTERNAL_GITHUB_REDIRECT_URI": "https://my-app-api.example.com/auth/v1/callback",
+	}
+	if !reflect.DeepEqual(authEnv, expectedAuth) {
+		t.Errorf("expected auth environment %v, got %v", expectedAuth, authEnv)
+	}
+
+	storageEnv := values["storage"].(map[string]interface{})["environment"].(map[string]interface{})
+	if storageEnv["STORAGE_BACKEND"] != "s3" || storageEnv["GLOBAL_S3_BUCKET"] != "assets" {
+		t.Errorf("unexpected storage environment %v", storageEnv)
+	}
+	if _, ok := storageEnv["GLOBAL_S3_ENDPOINT"]; ok {
+		t.Error("expected empty settings to be omitted")
+	}
+
+	secrets := values["secret"].(map[string]interface{})
+	expectedSecrets := map[string]interface{}{
+		"smtp": map[string]interface{}{"username": "user", "password": "pass"},
+		"s3":   map[string]interface{}{"keyId": "AKIA", "accessKey": "s3cret"},
+	}
+	if !reflect.DeepEqual(secrets, expectedSecrets) {
+		t.Errorf("expected secrets %v, got %v", expectedSecrets, secrets)
+	}
+}
+
+// TestOAuthSecrets tests that OAuth client secrets are returned by the variable that holds them
+func TestOAuthSecrets(t *testing.T) {
+	secrets := OAuthSecrets([]*Profile{
+		{Type: TypeSMTP, Secrets: map[string]string{"password": "pass"}},
+		{Type: TypeOAuth, Settings: map[string]string{"provider": "github"}, Secrets: map[string]string{"client_secret": "xyz"}},
+	})
+	expected := map[string]string{"GOTRUE_EXTERNAL_GITHUB_SECRET": "xyz"}
+	if !reflect.DeepEqual(secrets, expected) {
+		t.Errorf("expected secrets %v, got %v", expected, secrets)
+	}
+}
+
+// TestChecksum tests that the checksum ignores profile order and changes with their contents
+func TestChecksum(t *testing.T) {
+	smtp := &Profile{Name: "mail", Type: TypeSMTP, Settings: map[string]string{"host": "smtp.example.com"}}
+	oauth := &Profile{Name: "github", Type: TypeOAuth, Secrets: map[string]string{"client_secret": "xyz"}}
+
+	if Checksum(nil) != "" {
+		t.Error("expected no checksum without profiles")
+	}
+	if Checksum([]*Profile{smtp, oauth}) != Checksum([]*Profile{oauth, smtp}) {
+		t.Error("expected the checksum not to depend on profile order")
+	}
+	rotated := &Profile{Name: "github", Type: TypeOAuth, Secrets: map[string]string{"client_secret": "abc"}}
+	if Checksum([]*Profile{smtp, oauth}) == Checksum([]*Profile{smtp, rotated}) {
+		t.Error("expected a new credential to change the checksum")
+	}
+}
+
+// TestRender_NoProfiles tests that an instance without profiles gets an empty values document
+func TestRender_NoProfiles(t *testing.T) {
+	data, err := Render(nil, "https://my-app-api.example.com")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.TrimSpace(string(data)) != "{}" {
+		t.Errorf("expected empty document, got %q", data)
+	}
+}
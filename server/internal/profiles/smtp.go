@@ -0,0 +1,276 @@
+package profiles
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"net/textproto"
+	"strings"
+	"time"
+)
+
+// SMTPTestTimeout bounds the whole test email conversation
+const SMTPTestTimeout = 30 * time.Second
+
+// implicitTLSPort is the SMTPS port, where TLS starts before the SMTP greeting
+const implicitTLSPort = "465"
+
+// SendTestEmail sends a test email to recipient through an SMTP profile and returns
+// the SMTP transcript. Credentials are redacted from the transcript. The transcript
+// is returned even when sending fails, so callers can show where it went wrong.
+func SendTestEmail(ctx context.Context, p *Profile, recipient string) ([]string, error) {
+	if p.Type != TypeSMTP {
+		return nil, fmt.Errorf("profile %s is not an SMTP profile", p.Name)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, SMTPTestTimeout)
+	defer cancel()
+
+	host := p.Settings["host"]
+	port := p.Settings["port"]
+	s := &smtpSession{host: host}
+
+	err := s.run(ctx, port, func() error {
+		if err := s.auth(p.Secrets["username"], p.Secrets["password"]); err != nil {
+			return err
+		}
+		return s.send(p.Settings["sender_email"], p.Settings["sender_name"], recipient)
+	})
+	return s.transcript, err
+}
+
+// smtpSession is a minimal SMTP client that records the conversation
+type smtpSession struct {
+	host       string
+	conn       net.Conn
+	text       *textproto.Conn
+	tls        bool
+	extensions map[string]string
+	transcript []string
+}
+
+// run connects, greets the server, upgrades to TLS and runs fn before quitting
+func (s *smtpSession) run(ctx context.Context, port string, fn func() error) error {
+	addr := net.JoinHostPort(s.host, port)
+	s.logf("*", "connecting to %s", addr)
+
+	dialer := &net.Dialer{}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return s.fail(fmt.Errorf("failed to connect: %w", err))
+	}
+	defer func() { _ = conn.Close() }()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	if port == implicitTLSPort {
+		conn, err = s.handshake(conn)
+		if err != nil {
+			return s.fail(err)
+		}
+	}
+	s.setConn(conn)
+
+	if _, err := s.read(220); err != nil {
+		return s.fail(err)
+	}
+	if err := s.hello(); err != nil {
+		return s.fail(err)
+	}
+
+	if !s.tls {
+		if _, ok := s.extensions["STARTTLS"]; ok {
+			if _, err := s.cmd(220, "STARTTLS"); err != nil {
+				return s.fail(err)
+			}
+			conn, err := s.handshake(s.conn)
+			if err != nil {
+				return s.fail(err)
+			}
+			s.setConn(conn)
+			if err := s.hello(); err != nil {
+				return s.fail(err)
+			}
+		}
+	}
+
+	if err := fn(); err != nil {
+		return s.fail(err)
+	}
+
+	// A failed QUIT does not make the test fail: the message was accepted
+	_, _ = s.cmd(221, "QUIT")
+	return nil
+}
+
+// handshake starts TLS on conn, verifying the server certificate against the host name
+func (s *smtpSession) handshake(conn net.Conn) (net.Conn, error) {
+	tlsConn := tls.Client(conn, &tls.Config{ServerName: s.host, MinVersion: tls.VersionTLS12})
+	if err := tlsConn.Handshake(); err != nil {
+		return nil, fmt.Errorf("TLS handshake failed: %w", err)
+	}
+	s.tls = true
+	s.logf("*", "TLS established (%s)", tls.VersionName(tlsConn.ConnectionState().Version))
+	return tlsConn, nil
+}
+
+// setConn switches the session to conn
+func (s *smtpSession) setConn(conn net.Conn) {
+	s.conn = conn
+	s.text = textproto.NewConn(conn)
+}
+
+// hello sends EHLO and records the advertised extensions
+func (s *smtpSession) hello() error {
+	msg, err := s.cmd(250, "EHLO supacontrol")
+	if err != nil {
+		return err
+	}
+
+	s.extensions = make(map[string]string)
+	lines := strings.Split(msg, "\n")
+	for _, line := range lines[1:] {
+		keyword, params, _ := strings.Cut(line, " ")
+		s.extensions[strings.ToUpper(keyword)] = params
+	}
+	return nil
+}
+
+// auth authenticates with PLAIN or LOGIN. Credentials are only sent over TLS,
+// except to loopback servers.
+func (s *smtpSession) auth(username, password string) error {
+	if username == "" {
+		return nil
+	}
+	if !s.tls && !isLoopback(s.host) {
+		return errors.New("server does not support STARTTLS; refusing to send credentials unencrypted")
+	}
+
+	mechanisms := strings.Fields(strings.ToUpper(s.extensions["AUTH"]))
+	switch {
+	case containsMechanism(mechanisms, "PLAIN"):
+		token := base64.StdEncoding.EncodeToString([]byte("\x00" + username + "\x00" + password))
+		_, err := s.secretCmd(235, "AUTH PLAIN "+token, "AUTH PLAIN ********")
+		return err
+	case containsMechanism(mechanisms, "LOGIN"):
+		if _, err := s.cmd(334, "AUTH LOGIN"); err != nil {
+			return err
+		}
+		if _, err := s.secretCmd(334, base64.StdEncoding.EncodeToString([]byte(username)), "********"); err != nil {
+			return err
+		}
+		_, err := s.secretCmd(235, base64.StdEncoding.EncodeToString([]byte(password)), "********")
+		return err
+	default:
+		return errors.New("server offers no supported authentication mechanism (PLAIN or LOGIN)")
+	}
+}
+
+// send delivers the test message
+func (s *smtpSession) send(from, fromName, to string) error {
+	if _, err := s.cmd(250, "MAIL FROM:<%s>", from); err != nil {
+		return err
+	}
+	if _, err := s.cmd(25, "RCPT TO:<%s>", to); err != nil {
+		return err
+	}
+	if _, err := s.cmd(354, "DATA"); err != nil {
+		return err
+	}
+
+	sender := from
+	if fromName != "" {
+		sender = fmt.Sprintf("%q <%s>", fromName, from)
+	}
+	message := strings.Join([]string{
+		"From: " + sender,
+		"To: <" + to + ">",
+		"Subject: SupaControl SMTP test",
+		"Date: " + time.Now().UTC().Format(time.RFC1123Z),
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=utf-8",
+		"",
+		"This is a test email sent by SupaControl to verify an SMTP profile.",
+		"Supabase instances using this profile can send authentication emails.",
+	}, "\r\n")
+
+	w := s.text.DotWriter()
+	if _, err := w.Write([]byte(message)); err != nil {
+		return fmt.Errorf("failed to write message: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("failed to write message: %w", err)
+	}
+	s.logf("C", "<message body, %d bytes>", len(message))
+
+	_, err := s.read(250)
+	return err
+}
+
+// cmd sends a command and reads the response
+func (s *smtpSession) cmd(expectCode int, format string, args ...interface{}) (string, error) {
+	line := fmt.Sprintf(format, args...)
+	return s.secretCmd(expectCode, line, line)
+}
+
+// secretCmd sends a command, recording logged in the transcript instead of line
+func (s *smtpSession) secretCmd(expectCode int, line, logged string) (string, error) {
+	s.logf("C", "%s", logged)
+	if err := s.text.PrintfLine("%s", line); err != nil {
+		return "", fmt.Errorf("failed to send command: %w", err)
+	}
+	return s.read(expectCode)
+}
+
+// read reads a response, recording every line of it
+func (s *smtpSession) read(expectCode int) (string, error) {
+	code, msg, err := s.text.ReadResponse(expectCode)
+	if code != 0 {
+		for _, line := range strings.Split(msg, "\n") {
+			s.logf("S", "%d %s", code, line)
+		}
+	}
+	if err != nil {
+		var protoErr *textproto.Error
+		if errors.As(err, &protoErr) {
+			return msg, fmt.Errorf("server rejected command: %d %s", protoErr.Code, protoErr.Msg)
+		}
+		return msg, fmt.Errorf("failed to read response: %w", err)
+	}
+	return msg, nil
+}
+
+// fail records err in the transcript and returns it
+func (s *smtpSession) fail(err error) error {
+	s.logf("!", "%v", err)
+	return err
+}
+
+// logf appends a transcript line with a direction marker (C client, S server, * info, ! error)
+func (s *smtpSession) logf(marker, format string, args ...interface{}) {
+	s.transcript = append(s.transcript, marker+": "+fmt.Sprintf(format, args...))
+}
+
+// containsMechanism reports whether mechanisms includes name
+func containsMechanism(mechanisms []string, name string) bool {
+	for _, m := range mechanisms {
+		if m == name {
+			return true
+		}
+	}
+	return false
+}
+
+// isLoopback reports whether host refers to the local machine
+func isLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
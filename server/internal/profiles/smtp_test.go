@@ -0,0 +1,157 @@
+package profiles
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strings"
+	"testing"
+)
+
+// fakeSMTPServer accepts one connection and replays a minimal SMTP dialogue.
+// rcptReply is the response to RCPT TO; received collects the client's lines.
+func fakeSMTPServer(t *testing.T, rcptReply string) (string, string, chan []string) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+
+	received := make(chan []string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer func() { _ = conn.Close() }()
+
+		var lines []string
+		r := bufio.NewReader(conn)
+		reply := func(s string) { _, _ = conn.Write([]byte(s + "\r\n")) }
+		reply("220 fake ESMTP")
+		inData := false
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				break
+			}
+			line = strings.TrimRight(line, "\r\n")
+			lines = append(lines, line)
+			if inData {
+				if line == "." {
+					inData = false
+					reply("250 queued")
+				}
+				continue
+			}
+			switch {
+			case strings.HasPrefix(line, "EHLO"):
+				reply("250-fake")
+				reply("250 AUTH PLAIN LOGIN")
+			case strings.HasPrefix(line, "AUTH PLAIN"):
+				reply("235 authenticated")
+			case strings.HasPrefix(line, "MAIL FROM"):
+				reply("250 ok")
+			case strings.HasPrefix(line, "RCPT TO"):
+				reply(rcptReply)
+			case line == "DATA":
+				inData = true
+				reply("354 go ahead")
+			case line == "QUIT":
+				reply("221 bye")
+				received <- lines
+				return
+			default:
+				reply("500 unknown command")
+			}
+		}
+		received <- lines
+	}()
+
+	host, port, _ := net.SplitHostPort(listener.Addr().String())
+	return host, port, received
+}
+
+// smtpProfile returns an SMTP profile pointing at host:port
+func smtpProfile(host, port string) *Profile {
+	return &Profile{
+		Name:     "test",
+		Type:     TypeSMTP,
+		Settings: map[string]string{"host": host, "port": port, "sender_email": "no-reply@example.com"},
+		Secrets:  map[string]string{"username": "user", "password": "hunter2"},
+	}
+}
+
+// TestSendTestEmail tests a successful test email conversation
+func TestSendTestEmail(t *testing.T) {
+	host, port, received := fakeSMTPServer(t, "250 ok")
+
+	transcript, err := SendTestEmail(context.Background(), smtpProfile(host, port), "admin@example.com")
+	if err != nil {
+		t.Fatalf("unexpected error: %v\n%s", err, strings.Join(transcript, "\n"))
+	}
+
+	joined := strings.Join(transcript, "\n")
+	for _, want := range []string{"C: EHLO supacontrol", "C: AUTH PLAIN ********", "C: RCPT TO:<admin@example.com>", "S: 250 queued", "S: 221 bye"} {
+		if !strings.Contains(joined, want) {
+			t.Errorf("transcript missing %q:\n%s", want, joined)
+		}
+	}
+	if strings.Contains(joined, "hunter2") || strings.Contains(joined, "AHVzZXIAaHVudGVyMg==") {
+		t.Errorf("transcript leaks credentials:\n%s", joined)
+	}
+
+	lines := <-received
+	if !containsLine(lines, "Subject: SupaControl SMTP test") {
+		t.Errorf("server did not receive the test message: %v", lines)
+	}
+}
+
+// TestSendTestEmail_Rejected tests that a rejected recipient is reported with the transcript
+func TestSendTestEmail_Rejected(t *testing.T) {
+	host, port, _ := fakeSMTPServer(t, "550 mailbox unavailable")
+
+	transcript, err := SendTestEmail(context.Background(), smtpProfile(host, port), "nobody@example.com")
+	if err == nil {
+		t.Fatal("expected error for rejected recipient")
+	}
+	if !strings.Contains(err.Error(), "550 mailbox unavailable") {
+		t.Errorf("expected server reply in error, got %v", err)
+	}
+	if last := transcript[len(transcript)-1]; !strings.HasPrefix(last, "!: ") {
+		t.Errorf("expected transcript to end with the error, got %q", last)
+	}
+}
+
+// TestSendTestEmail_ConnectionRefused tests that connection failures are reported
+func TestSendTestEmail_ConnectionRefused(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	host, port, _ := net.SplitHostPort(listener.Addr().String())
+	_ = listener.Close()
+
+	if _, err := SendTestEmail(context.Background(), smtpProfile(host, port), "admin@example.com"); err == nil {
+		t.Fatal("expected error when the server is unreachable")
+	}
+}
+
+// TestSendTestEmail_RefusesPlaintextCredentials tests that credentials are not sent without TLS to remote hosts
+func TestSendTestEmail_RefusesPlaintextCredentials(t *testing.T) {
+	s := &smtpSession{host: "smtp.example.com", extensions: map[string]string{"AUTH": "PLAIN"}}
+	if err := s.auth("user", "pass"); err == nil || !strings.Contains(err.Error(), "STARTTLS") {
+		t.Errorf("expected plaintext credentials to be refused, got %v", err)
+	}
+}
+
+// containsLine reports whether lines includes want
+func containsLine(lines []string, want string) bool {
+	for _, line := range lines {
+		if line == want {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,129 @@
+// Package proxy propagates HTTP(S) proxy settings to the control plane's
+// outbound calls and to the Jobs it runs, for clusters that can only reach
+// chart repositories and feeds through a proxy.
+//
+// Go's HTTP clients, including Helm's and client-go's, read HTTP_PROXY,
+// HTTPS_PROXY and NO_PROXY from the environment. Because client-go honors
+// them too, in-cluster destinations are always added to NO_PROXY so calls to
+// the Kubernetes API never go through the proxy.
+package proxy
+
+import (
+	"os"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// clusterNoProxy are in-cluster destinations that must bypass the proxy
+var clusterNoProxy = []string{
+	"localhost",
+	"127.0.0.1",
+	".svc",
+	".cluster.local",
+	"kubernetes.default",
+}
+
+// Config holds proxy settings
+type Config struct {
+	HTTPProxy  string
+	HTTPSProxy string
+	NoProxy    string
+}
+
+// Enabled reports whether a proxy is configured
+func (c Config) Enabled() bool {
+	return c.HTTPProxy != "" || c.HTTPSProxy != ""
+}
+
+// WithClusterDefaults returns the config with in-cluster destinations, including
+// the Kubernetes API server address, appended to NoProxy. It is a no-op when no
+// proxy is configured.
+func (c Config) WithClusterDefaults() Config {
+	if !c.Enabled() {
+		return c
+	}
+
+	entries := splitList(c.NoProxy)
+	seen := make(map[string]bool, len(entries))
+	for _, e := range entries {
+		seen[e] = true
+	}
+
+	defaults := clusterNoProxy
+	if host := os.Getenv("KUBERNETES_SERVICE_HOST"); host != "" {
+		defaults = append(append([]string{}, defaults...), host)
+	}
+	for _, e := range defaults {
+		if !seen[e] {
+			entries = append(entries, e)
+			seen[e] = true
+		}
+	}
+
+	c.NoProxy = strings.Join(entries, ",")
+	return c
+}
+
+// Apply exports the settings to the process environment so Go HTTP clients pick
+// them up. It must run before the first outbound request, as Go reads the proxy
+// environment only once.
+func (c Config) Apply() error {
+	for _, v := range c.vars() {
+		for _, name := range []string{v.name, strings.ToLower(v.name)} {
+			if err := os.Setenv(name, v.value); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// EnvVars returns the settings as container environment variables, in both the
+// upper- and lowercase forms since tools disagree on which one they read
+func (c Config) EnvVars() []corev1.EnvVar {
+	if !c.Enabled() {
+		return nil
+	}
+
+	var env []corev1.EnvVar
+	for _, v := range c.vars() {
+		env = append(env,
+			corev1.EnvVar{Name: v.name, Value: v.value},
+			corev1.EnvVar{Name: strings.ToLower(v.name), Value: v.value},
+		)
+	}
+	return env
+}
+
+// proxyVar is a named proxy setting
+type proxyVar struct {
+	name  string
+	value string
+}
+
+// vars returns the non-empty proxy variables
+func (c Config) vars() []proxyVar {
+	var vars []proxyVar
+	for _, v := range []proxyVar{
+		{"HTTP_PROXY", c.HTTPProxy},
+		{"HTTPS_PROXY", c.HTTPSProxy},
+		{"NO_PROXY", c.NoProxy},
+	} {
+		if v.value != "" {
+			vars = append(vars, v)
+		}
+	}
+	return vars
+}
+
+// splitList splits a comma-separated NO_PROXY value, dropping blanks
+func splitList(s string) []string {
+	var entries []string
+	for _, e := range strings.Split(s, ",") {
+		if e = strings.TrimSpace(e); e != "" {
+			entries = append(entries, e)
+		}
+	}
+	return entries
+}
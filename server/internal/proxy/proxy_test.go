@@ -0,0 +1,85 @@
+package proxy
+
+import (
+	"os"
+	"testing"
+)
+
+// TestWithClusterDefaults tests that in-cluster destinations are added to NO_PROXY
+func TestWithClusterDefaults(t *testing.T) {
+	t.Setenv("KUBERNETES_SERVICE_HOST", "10.96.0.1")
+
+	tests := []struct {
+		name     string
+		config   Config
+		expected string
+	}{
+		{
+			name:     "no proxy configured",
+			config:   Config{NoProxy: "example.com"},
+			expected: "example.com",
+		},
+		{
+			name:     "appends cluster destinations",
+			config:   Config{HTTPSProxy: "http://proxy:3128", NoProxy: "corp.example.com, .internal"},
+			expected: "corp.example.com,.internal,localhost,127.0.0.1,.svc,.cluster.local,kubernetes.default,10.96.0.1",
+		},
+		{
+			name:     "does not duplicate entries",
+			config:   Config{HTTPProxy: "http://proxy:3128", NoProxy: ".svc,10.96.0.1"},
+			expected: ".svc,10.96.0.1,localhost,127.0.0.1,.cluster.local,kubernetes.default",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.config.WithClusterDefaults().NoProxy; got != tt.expected {
+				t.Errorf("expected NoProxy %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+// TestEnvVars tests the environment injected into Jobs
+func TestEnvVars(t *testing.T) {
+	if env := (Config{}).EnvVars(); env != nil {
+		t.Errorf("expected no env without a proxy, got %v", env)
+	}
+
+	env := Config{HTTPSProxy: "http://proxy:3128", NoProxy: ".svc"}.EnvVars()
+	got := make(map[string]string, len(env))
+	for _, e := range env {
+		got[e.Name] = e.Value
+	}
+	expected := map[string]string{
+		"HTTPS_PROXY": "http://proxy:3128",
+		"https_proxy": "http://proxy:3128",
+		"NO_PROXY":    ".svc",
+		"no_proxy":    ".svc",
+	}
+	if len(got) != len(expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+	for k, v := range expected {
+		if got[k] != v {
+			t.Errorf("expected %s=%q, got %q", k, v, got[k])
+		}
+	}
+}
+
+// TestApply tests that settings are exported to the process environment
+func TestApply(t *testing.T) {
+	for _, name := range []string{"HTTPS_PROXY", "https_proxy", "NO_PROXY", "no_proxy"} {
+		t.Setenv(name, "")
+	}
+
+	if err := (Config{HTTPSProxy: "http://proxy:3128", NoProxy: ".svc"}).Apply(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if os.Getenv("HTTPS_PROXY") != "http://proxy:3128" || os.Getenv("https_proxy") != "http://proxy:3128" {
+		t.Error("expected HTTPS_PROXY to be exported in both cases")
+	}
+	if os.Getenv("no_proxy") != ".svc" {
+		t.Error("expected NO_PROXY to be exported")
+	}
+}
@@ -0,0 +1,173 @@
+// Package schedule stops and starts instances at the times their schedules give.
+//
+// Schedules hold five-field cron expressions for stopping and starting an instance. A
+// Runner on the elected leader applies each scheduled change once by flipping the
+// instance's Paused flag, so stopping or starting an instance by hand in between is kept
+// until the next scheduled change.
+package schedule
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidExpression is returned for cron expressions that cannot be parsed
+var ErrInvalidExpression = errors.New("invalid cron expression")
+
+// maxSearch bounds how far ahead Next looks, so expressions that never match (such as
+// February 30th) do not loop forever
+const maxSearch = 5 * 366 * 24 * time.Hour
+
+// field describes one of the five fields of a cron expression
+type field struct {
+	name     string
+	min, max int
+}
+
+var fields = [5]field{
+	{name: "minute", min: 0, max: 59},
+	{name: "hour", min: 0, max: 23},
+	{name: "day of month", min: 1, max: 31},
+	{name: "month", min: 1, max: 12},
+	{name: "day of week", min: 0, max: 7},
+}
+
+// Expression is a parsed five-field cron expression: minute, hour, day of month, month and
+// day of week (0 or 7 is Sunday). Fields hold *, values, ranges and lists of them, each
+// optionally with a /step.
+type Expression struct {
+	minutes, hours, days, months, weekdays uint64
+
+	// anyDay and anyWeekday record unrestricted day fields: as in cron, a day matches when
+	// either restricted day field matches it
+	anyDay, anyWeekday bool
+}
+
+// Parse parses a five-field cron expression
+func Parse(expr string) (Expression, error) {
+	parts := strings.Fields(expr)
+	if len(parts) != len(fields) {
+		return Expression{}, fmt.Errorf("%w: %q must have %d fields", ErrInvalidExpression, expr, len(fields))
+	}
+
+	var sets [5]uint64
+	for i, part := range parts {
+		set, err := parseField(part, fields[i])
+		if err != nil {
+			return Expression{}, fmt.Errorf("%w: %q: %v", ErrInvalidExpression, expr, err)
+		}
+		sets[i] = set
+	}
+
+	// Sunday may be written as 7
+	weekdays := sets[4]
+	if weekdays&(1<<7) != 0 {
+		weekdays = weekdays&^(1<<7) | 1
+	}
+	return Expression{
+		minutes:    sets[0],
+		hours:      sets[1],
+		days:       sets[2],
+		months:     sets[3],
+		weekdays:   weekdays,
+		anyDay:     parts[2] == "*",
+		anyWeekday: parts[4] == "*",
+	}, nil
+}
+
+// parseField parses one field into a bit set of the values it matches
+func parseField(part string, f field) (uint64, error) {
+	var set uint64
+	for _, item := range strings.Split(part, ",") {
+		rng, stepText, hasStep := strings.Cut(item, "/")
+		step := 1
+		if hasStep {
+			var err error
+			if step, err = strconv.Atoi(stepText); err != nil || step < 1 {
+				return 0, fmt.Errorf("invalid step %q in %s field", stepText, f.name)
+			}
+		}
+
+		low, high := f.min, f.max
+		if rng != "*" {
+			lowText, highText, isRange := strings.Cut(rng, "-")
+			var err error
+			if low, err = parseValue(lowText, f); err != nil {
+				return 0, err
+			}
+			high = low
+			if isRange {
+				if high, err = parseValue(highText, f); err != nil {
+					return 0, err
+				}
+				if high < low {
+					return 0, fmt.Errorf("range %q in %s field is reversed", rng, f.name)
+				}
+			} else if hasStep {
+				// As in cron, "5/15" means from 5 to the maximum in steps of 15
+				high = f.max
+			}
+		}
+
+		for value := low; value <= high; value += step {
+			set |= 1 << value
+		}
+	}
+	return set, nil
+}
+
+// parseValue parses a single value of a field and checks its bounds
+func parseValue(text string, f field) (int, error) {
+	value, err := strconv.Atoi(text)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q in %s field", text, f.name)
+	}
+	if value < f.min || value > f.max {
+		return 0, fmt.Errorf("%s %d is not between %d and %d", f.name, value, f.min, f.max)
+	}
+	return value, nil
+}
+
+// dayMatches reports whether the expression's day fields match t's date
+func (e Expression) dayMatches(t time.Time) bool {
+	day := e.days&(1<<t.Day()) != 0
+	weekday := e.weekdays&(1<<int(t.Weekday())) != 0
+	switch {
+	case e.anyDay && e.anyWeekday:
+		return true
+	case e.anyDay:
+		return weekday
+	case e.anyWeekday:
+		return day
+	default:
+		return day || weekday
+	}
+}
+
+// Next returns the first time after t the expression matches, in t's location, or the zero
+// time when it does not match within five years
+func (e Expression) Next(t time.Time) time.Time {
+	loc := t.Location()
+	limit := t.Add(maxSearch)
+	t = t.Truncate(time.Minute).Add(time.Minute)
+
+	for t.Before(limit) {
+		switch {
+		case e.months&(1<<int(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+		case !e.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+		case e.hours&(1<<t.Hour()) == 0:
+			// Truncating to the hour would be wrong in zones with half-hour offsets
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+		case e.minutes&(1<<t.Minute()) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
@@ -0,0 +1,65 @@
+package schedule
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	valid := []string{"* * * * *", "0 19 * * 1-5", "*/15 8-18 * * 1,3,5", "30 6 1 */3 *", "0 0 * * 7", "5/20 * * * *"}
+	for _, expr := range valid {
+		if _, err := Parse(expr); err != nil {
+			t.Errorf("Parse(%q) = %v, want nil", expr, err)
+		}
+	}
+
+	invalid := []string{"", "* * * *", "* * * * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "* * * 13 *", "* * * * 8", "5-1 * * * *", "*/0 * * * *", "a * * * *", "@daily"}
+	for _, expr := range invalid {
+		if _, err := Parse(expr); !errors.Is(err, ErrInvalidExpression) {
+			t.Errorf("Parse(%q) = %v, want ErrInvalidExpression", expr, err)
+		}
+	}
+}
+
+func TestNext(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("time zone data unavailable: %v", err)
+	}
+
+	tests := []struct {
+		expr     string
+		after    time.Time
+		expected time.Time
+	}{
+		// Friday evening is followed by Monday evening
+		{expr: "0 19 * * 1-5", after: time.Date(2026, 10, 16, 19, 0, 0, 0, time.UTC), expected: time.Date(2026, 10, 19, 19, 0, 0, 0, time.UTC)},
+		{expr: "0 19 * * 1-5", after: time.Date(2026, 10, 16, 18, 59, 30, 0, time.UTC), expected: time.Date(2026, 10, 16, 19, 0, 0, 0, time.UTC)},
+		{expr: "*/15 * * * *", after: time.Date(2026, 10, 16, 10, 7, 0, 0, time.UTC), expected: time.Date(2026, 10, 16, 10, 15, 0, 0, time.UTC)},
+		{expr: "0 0 1 1 *", after: time.Date(2026, 10, 16, 10, 7, 0, 0, time.UTC), expected: time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)},
+		// Restricted day of month and day of week match either: the 1st or Sundays
+		{expr: "0 12 1 * 0", after: time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC), expected: time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)},
+		{expr: "0 12 * * 7", after: time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC), expected: time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)},
+		// Local times keep across the change from summer time
+		{expr: "0 7 * * *", after: time.Date(2026, 10, 24, 12, 0, 0, 0, berlin), expected: time.Date(2026, 10, 25, 7, 0, 0, 0, berlin)},
+		{expr: "0 7 * * *", after: time.Date(2026, 10, 25, 12, 0, 0, 0, berlin), expected: time.Date(2026, 10, 26, 7, 0, 0, 0, berlin)},
+		// Times skipped by the change to summer time are never matched
+		{expr: "30 2 * * *", after: time.Date(2026, 3, 28, 12, 0, 0, 0, berlin), expected: time.Date(2026, 3, 30, 2, 30, 0, 0, berlin)},
+	}
+
+	for _, tt := range tests {
+		expr, err := Parse(tt.expr)
+		if err != nil {
+			t.Fatalf("Parse(%q) = %v", tt.expr, err)
+		}
+		if got := expr.Next(tt.after); !got.Equal(tt.expected) {
+			t.Errorf("Next(%q, %s) = %s, want %s", tt.expr, tt.after, got, tt.expected)
+		}
+	}
+
+	never, _ := Parse("0 0 30 2 *")
+	if got := never.Next(time.Now()); !got.IsZero() {
+		t.Errorf("Next of February 30th = %s, want the zero time", got)
+	}
+}
@@ -0,0 +1,140 @@
+package schedule
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+)
+
+const (
+	// DefaultScanInterval is how often the runner looks for scheduled changes
+	DefaultScanInterval = time.Minute
+
+	// MaxCatchUp is how far back the runner looks for scheduled changes it has not applied,
+	// such as ones due while no replica was leading
+	MaxCatchUp = 15 * time.Minute
+)
+
+// errNothingDue aborts a patch when the scheduled change was applied concurrently
+var errNothingDue = errors.New("no scheduled change due")
+
+// InstanceClient lists and patches SupabaseInstance resources
+type InstanceClient interface {
+	ListSupabaseInstances(ctx context.Context) (*supacontrolv1alpha1.SupabaseInstanceList, error)
+	PatchSupabaseInstance(ctx context.Context, name string, mutate func(*supacontrolv1alpha1.SupabaseInstance) error) (*supacontrolv1alpha1.SupabaseInstance, error)
+}
+
+// Runner applies the schedules of instances. It implements the controller-runtime Runnable
+// interface and only runs on the elected leader.
+type Runner struct {
+	instances InstanceClient
+
+	ScanInterval time.Duration
+
+	// now returns the current time; replaced in tests
+	now func() time.Time
+}
+
+// NewRunner creates a runner with the default scan interval
+func NewRunner(instances InstanceClient) *Runner {
+	return &Runner{
+		instances:    instances,
+		ScanInterval: DefaultScanInterval,
+		now:          time.Now,
+	}
+}
+
+// NeedLeaderElection ensures only one replica applies schedules
+func (r *Runner) NeedLeaderElection() bool {
+	return true
+}
+
+// Start applies scheduled changes until ctx is cancelled
+func (r *Runner) Start(ctx context.Context) error {
+	ticker := time.NewTicker(r.ScanInterval)
+	defer ticker.Stop()
+
+	for {
+		r.scan(ctx)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// scan applies the changes due on every instance with a schedule
+func (r *Runner) scan(ctx context.Context) {
+	list, err := r.instances.ListSupabaseInstances(ctx)
+	if err != nil {
+		slog.Error("Failed to list instances for schedules", "error", err)
+		return
+	}
+
+	now := r.now()
+	for i := range list.Items {
+		instance := &list.Items[i]
+		if action, _ := due(instance, now); action == ActionNone {
+			continue
+		}
+		_, err := r.instances.PatchSupabaseInstance(ctx, instance.Name, func(instance *supacontrolv1alpha1.SupabaseInstance) error {
+			return apply(instance, now)
+		})
+		if err != nil && !errors.Is(err, errNothingDue) {
+			slog.Error("Failed to apply instance schedule", "instance", instance.Name, "error", err)
+		}
+	}
+}
+
+// due returns the scheduled change due on an instance and when it was scheduled. Changes
+// applied before, and instances being deleted or suspended, have none due.
+func due(instance *supacontrolv1alpha1.SupabaseInstance, now time.Time) (Action, time.Time) {
+	if instance.Spec.Schedule == nil || instance.DeletionTimestamp != nil ||
+		instance.Spec.PendingDeletion != nil || instance.Spec.Suspension != nil {
+		return ActionNone, time.Time{}
+	}
+
+	from := now.Add(-MaxCatchUp)
+	if appliedAt, err := time.Parse(time.RFC3339, instance.Annotations[supacontrolv1alpha1.AnnotationScheduleAppliedAt]); err == nil && appliedAt.After(from) {
+		from = appliedAt
+	}
+	action, at, err := Due(instance.Spec.Schedule, from, now)
+	if err != nil {
+		// The API validates schedules; ones written to the resource directly may not be
+		slog.Warn("Ignoring invalid instance schedule", "instance", instance.Name, "error", err)
+		return ActionNone, time.Time{}
+	}
+	return action, at
+}
+
+// apply makes the scheduled change due on an instance and records it as applied. Only
+// running instances are stopped, so provisioning is never interrupted; the change is
+// recorded regardless, so a later manual start is not undone.
+func apply(instance *supacontrolv1alpha1.SupabaseInstance, now time.Time) error {
+	action, at := due(instance, now)
+	switch action {
+	case ActionNone:
+		return errNothingDue
+	case ActionStop:
+		if !instance.Spec.Paused && instance.Status.Phase == supacontrolv1alpha1.PhaseRunning {
+			slog.Info("Stopping instance on schedule", "instance", instance.Name, "scheduledAt", at)
+			instance.Spec.Paused = true
+		}
+	case ActionStart:
+		if instance.Spec.Paused {
+			slog.Info("Starting instance on schedule", "instance", instance.Name, "scheduledAt", at)
+			instance.Spec.Paused = false
+		}
+	}
+
+	if instance.Annotations == nil {
+		instance.Annotations = map[string]string{}
+	}
+	instance.Annotations[supacontrolv1alpha1.AnnotationScheduleAppliedAt] = at.UTC().Format(time.RFC3339)
+	return nil
+}
@@ -0,0 +1,159 @@
+package schedule
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+)
+
+// fakeInstances keeps instances in memory and counts patches
+type fakeInstances struct {
+	instances map[string]*supacontrolv1alpha1.SupabaseInstance
+	patches   int
+}
+
+func (f *fakeInstances) ListSupabaseInstances(context.Context) (*supacontrolv1alpha1.SupabaseInstanceList, error) {
+	list := &supacontrolv1alpha1.SupabaseInstanceList{}
+	for _, instance := range f.instances {
+		list.Items = append(list.Items, *instance.DeepCopy())
+	}
+	return list, nil
+}
+
+func (f *fakeInstances) PatchSupabaseInstance(_ context.Context, name string, mutate func(*supacontrolv1alpha1.SupabaseInstance) error) (*supacontrolv1alpha1.SupabaseInstance, error) {
+	instance := f.instances[name].DeepCopy()
+	if err := mutate(instance); err != nil {
+		return nil, err
+	}
+	f.patches++
+	f.instances[name] = instance
+	return instance, nil
+}
+
+// weekdays stops instances at 19:00 and starts them at 7:00 on weekdays
+var weekdays = &supacontrolv1alpha1.Schedule{Stop: "0 19 * * 1-5", Start: "0 7 * * 1-5"}
+
+func newScheduledInstance(name string, phase supacontrolv1alpha1.SupabaseInstancePhase, paused bool) *supacontrolv1alpha1.SupabaseInstance {
+	return &supacontrolv1alpha1.SupabaseInstance{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec:       supacontrolv1alpha1.SupabaseInstanceSpec{ProjectName: name, Paused: paused, Schedule: weekdays},
+		Status:     supacontrolv1alpha1.SupabaseInstanceStatus{Phase: phase},
+	}
+}
+
+func TestDue(t *testing.T) {
+	friday := time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		from     time.Time
+		now      time.Time
+		action   Action
+		expected time.Time
+	}{
+		{name: "nothing due", from: friday.Add(12 * time.Hour), now: friday.Add(13 * time.Hour)},
+		{name: "stop", from: friday.Add(18*time.Hour + 50*time.Minute), now: friday.Add(19*time.Hour + time.Minute), action: ActionStop, expected: friday.Add(19 * time.Hour)},
+		{name: "start", from: friday.Add(6 * time.Hour), now: friday.Add(7 * time.Hour), action: ActionStart, expected: friday.Add(7 * time.Hour)},
+		{name: "latest wins", from: friday.Add(6 * time.Hour), now: friday.Add(20 * time.Hour), action: ActionStop, expected: friday.Add(19 * time.Hour)},
+		{name: "change at from is applied", from: friday.Add(19 * time.Hour), now: friday.Add(19*time.Hour + 5*time.Minute)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			action, at, err := Due(weekdays, tt.from, tt.now)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if action != tt.action || !at.Equal(tt.expected) {
+				t.Errorf("Due() = %q at %s, want %q at %s", action, at, tt.action, tt.expected)
+			}
+		})
+	}
+
+	stop, start := Upcoming(weekdays, friday.Add(20*time.Hour))
+	if !stop.Equal(time.Date(2026, 10, 19, 19, 0, 0, 0, time.UTC)) || !start.Equal(time.Date(2026, 10, 19, 7, 0, 0, 0, time.UTC)) {
+		t.Errorf("Upcoming() = %s, %s, want Monday's stop and start", stop, start)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		schedule supacontrolv1alpha1.Schedule
+		valid    bool
+	}{
+		{name: "weekdays", schedule: *weekdays, valid: true},
+		{name: "time zone", schedule: supacontrolv1alpha1.Schedule{Stop: "0 19 * * *", Start: "0 7 * * *", TimeZone: "UTC"}, valid: true},
+		{name: "invalid stop", schedule: supacontrolv1alpha1.Schedule{Stop: "0 25 * * *", Start: "0 7 * * *"}},
+		{name: "invalid start", schedule: supacontrolv1alpha1.Schedule{Stop: "0 19 * * *", Start: "tomorrow"}},
+		{name: "same times", schedule: supacontrolv1alpha1.Schedule{Stop: "0 7 * * *", Start: "0  7 * * *"}},
+		{name: "unknown time zone", schedule: supacontrolv1alpha1.Schedule{Stop: "0 19 * * *", Start: "0 7 * * *", TimeZone: "Mars/Olympus"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(&tt.schedule)
+			if tt.valid && err != nil {
+				t.Errorf("Validate() = %v, want nil", err)
+			}
+			if !tt.valid && err == nil {
+				t.Error("Validate() = nil, want an error")
+			}
+		})
+	}
+}
+
+func TestRunnerScan(t *testing.T) {
+	stopTime := time.Date(2026, 10, 16, 19, 0, 0, 0, time.UTC)
+	suspended := newScheduledInstance("suspended", supacontrolv1alpha1.PhaseSuspended, false)
+	suspended.Spec.Suspension = &supacontrolv1alpha1.Suspension{Reason: supacontrolv1alpha1.SuspensionReasonBilling}
+	unscheduled := newScheduledInstance("unscheduled", supacontrolv1alpha1.PhaseRunning, false)
+	unscheduled.Spec.Schedule = nil
+
+	fake := &fakeInstances{instances: map[string]*supacontrolv1alpha1.SupabaseInstance{
+		"running":      newScheduledInstance("running", supacontrolv1alpha1.PhaseRunning, false),
+		"provisioning": newScheduledInstance("provisioning", supacontrolv1alpha1.PhaseProvisioningInProgress, false),
+		"suspended":    suspended,
+		"unscheduled":  unscheduled,
+	}}
+	runner := NewRunner(fake)
+	now := stopTime.Add(time.Minute)
+	runner.now = func() time.Time { return now }
+
+	runner.scan(context.Background())
+	if !fake.instances["running"].Spec.Paused {
+		t.Error("Expected the running instance to be stopped")
+	}
+	if fake.instances["provisioning"].Spec.Paused {
+		t.Error("Expected the provisioning instance to be left running")
+	}
+	if fake.instances["provisioning"].Annotations[supacontrolv1alpha1.AnnotationScheduleAppliedAt] != "2026-10-16T19:00:00Z" {
+		t.Error("Expected the skipped stop to be recorded")
+	}
+	if fake.instances["suspended"].Spec.Paused || fake.instances["unscheduled"].Spec.Paused {
+		t.Error("Expected suspended and unscheduled instances to be left alone")
+	}
+	if fake.patches != 2 {
+		t.Errorf("Expected 2 patches, got %d", fake.patches)
+	}
+
+	// A manual start after the scheduled stop is kept until the next scheduled change
+	fake.instances["running"].Spec.Paused = false
+	now = stopTime.Add(5 * time.Minute)
+	runner.scan(context.Background())
+	if fake.instances["running"].Spec.Paused || fake.patches != 2 {
+		t.Errorf("Expected the manual start to be kept, paused %v after %d patches", fake.instances["running"].Spec.Paused, fake.patches)
+	}
+
+	// Monday morning starts the instance stopped on Friday evening
+	fake.instances["running"].Spec.Paused = true
+	now = time.Date(2026, 10, 19, 7, 0, 30, 0, time.UTC)
+	runner.scan(context.Background())
+	if fake.instances["running"].Spec.Paused {
+		t.Error("Expected the instance to be started on Monday morning")
+	}
+}
@@ -0,0 +1,100 @@
+package schedule
+
+import (
+	"fmt"
+	"time"
+
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+)
+
+// Action is a change a schedule makes to an instance
+type Action string
+
+const (
+	// ActionNone means no scheduled change is due
+	ActionNone Action = ""
+
+	// ActionStop stops the instance
+	ActionStop Action = "stop"
+
+	// ActionStart starts the instance
+	ActionStart Action = "start"
+)
+
+// parsed is a schedule with its expressions parsed and its time zone loaded
+type parsed struct {
+	stop, start Expression
+	location    *time.Location
+}
+
+// parse parses a schedule's expressions and loads its time zone
+func parse(s *supacontrolv1alpha1.Schedule) (*parsed, error) {
+	stop, err := Parse(s.Stop)
+	if err != nil {
+		return nil, fmt.Errorf("stop: %w", err)
+	}
+	start, err := Parse(s.Start)
+	if err != nil {
+		return nil, fmt.Errorf("start: %w", err)
+	}
+	location := time.UTC
+	if s.TimeZone != "" {
+		if location, err = time.LoadLocation(s.TimeZone); err != nil {
+			return nil, fmt.Errorf("unknown time zone %q", s.TimeZone)
+		}
+	}
+	return &parsed{stop: stop, start: start, location: location}, nil
+}
+
+// Validate checks that a schedule's expressions parse, that they differ and that its time
+// zone exists
+func Validate(s *supacontrolv1alpha1.Schedule) error {
+	p, err := parse(s)
+	if err != nil {
+		return err
+	}
+	if p.stop == p.start {
+		return fmt.Errorf("stop and start must be at different times")
+	}
+	return nil
+}
+
+// Upcoming returns the next scheduled stop and start after now, or zero times for an
+// invalid schedule or changes that never come
+func Upcoming(s *supacontrolv1alpha1.Schedule, now time.Time) (stop, start time.Time) {
+	p, err := parse(s)
+	if err != nil {
+		return time.Time{}, time.Time{}
+	}
+	now = now.In(p.location)
+	return p.stop.Next(now), p.start.Next(now)
+}
+
+// Due returns the latest scheduled change after from and up to now, and when it was
+// scheduled. A stop and a start scheduled at the same time leave the instance running.
+func Due(s *supacontrolv1alpha1.Schedule, from, now time.Time) (Action, time.Time, error) {
+	p, err := parse(s)
+	if err != nil {
+		return ActionNone, time.Time{}, err
+	}
+	from = from.In(p.location)
+	stop := latest(p.stop, from, now)
+	start := latest(p.start, from, now)
+	switch {
+	case stop.IsZero() && start.IsZero():
+		return ActionNone, time.Time{}, nil
+	case stop.After(start):
+		return ActionStop, stop, nil
+	default:
+		return ActionStart, start, nil
+	}
+}
+
+// latest returns the last time after from and up to now that e matches, or the zero time
+func latest(e Expression, from, now time.Time) time.Time {
+	var last time.Time
+	for t := e.Next(from); !t.IsZero() && !t.After(now); t = e.Next(t) {
+		last = t
+	}
+	return last
+}
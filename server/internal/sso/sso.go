@@ -0,0 +1,280 @@
+// Package sso implements SAML 2.0 single sign-on, with SupaControl acting as the
+// service provider of an enterprise identity provider (IdP).
+package sso
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/crewjam/saml"
+	dsig "github.com/russellhaering/goxmldsig"
+)
+
+const (
+	// MetadataPath, LoginPath and ACSPath are the routes of the service provider
+	MetadataPath = "/api/v1/auth/saml/metadata"
+	LoginPath    = "/api/v1/auth/saml/login"
+	ACSPath      = "/api/v1/auth/saml/acs"
+
+	// metadataFetchTimeout bounds fetching the IdP metadata from a URL
+	metadataFetchTimeout = 30 * time.Second
+)
+
+// RoleMapping assigns a role to users whose role attribute holds a value
+type RoleMapping struct {
+	Value string
+	Role  string
+}
+
+// Config configures the service provider
+type Config struct {
+	// PublicURL is the externally reachable base URL of SupaControl
+	PublicURL string
+
+	// EntityID identifies the service provider to the IdP (defaults to the metadata URL)
+	EntityID string
+
+	// IDPMetadata is the http(s) URL or file path of the IdP's metadata
+	IDPMetadata string
+
+	// CertFile and KeyFile hold the PEM certificate and key the service provider signs
+	// authentication requests and decrypts assertions with (both empty disables this)
+	CertFile string
+	KeyFile  string
+
+	// UsernameAttribute names the attribute holding the username (empty uses the NameID)
+	UsernameAttribute string
+
+	// RoleAttribute names the attribute the role is mapped from (empty leaves roles unmanaged)
+	RoleAttribute string
+
+	// RoleMappings are checked in order; the first one matching a value of the role
+	// attribute wins
+	RoleMappings []RoleMapping
+
+	// DefaultRole is given to users no mapping matches, and to new users when roles are unmanaged
+	DefaultRole string
+
+	// AllowIDPInitiated accepts assertions the IdP sends without a preceding request
+	AllowIDPInitiated bool
+}
+
+// Identity is the user an assertion authenticated
+type Identity struct {
+	Username string
+
+	// Role is the mapped role, empty when roles are not managed by the IdP
+	Role string
+}
+
+// ServiceProvider authenticates users against the configured IdP
+type ServiceProvider struct {
+	sp  *saml.ServiceProvider
+	cfg Config
+}
+
+// NewServiceProvider loads the IdP metadata and the service provider's key pair
+func NewServiceProvider(ctx context.Context, cfg Config) (*ServiceProvider, error) {
+	base, err := url.Parse(strings.TrimSuffix(cfg.PublicURL, "/"))
+	if err != nil || base.Scheme == "" || base.Host == "" {
+		return nil, fmt.Errorf("invalid public URL %q", cfg.PublicURL)
+	}
+	metadataURL := *base.JoinPath(MetadataPath)
+	acsURL := *base.JoinPath(ACSPath)
+
+	data, err := readMetadata(ctx, cfg.IDPMetadata)
+	if err != nil {
+		return nil, err
+	}
+	idpMetadata, err := ParseMetadata(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse IdP metadata: %w", err)
+	}
+
+	sp := &saml.ServiceProvider{
+		EntityID:          cfg.EntityID,
+		MetadataURL:       metadataURL,
+		AcsURL:            acsURL,
+		IDPMetadata:       idpMetadata,
+		AllowIDPInitiated: cfg.AllowIDPInitiated,
+		AuthnNameIDFormat: saml.UnspecifiedNameIDFormat,
+	}
+	if sp.EntityID == "" {
+		sp.EntityID = metadataURL.String()
+	}
+	if cfg.CertFile != "" || cfg.KeyFile != "" {
+		keyPair, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load service provider key pair: %w", err)
+		}
+		signer, ok := keyPair.PrivateKey.(crypto.Signer)
+		if !ok {
+			return nil, fmt.Errorf("service provider key cannot sign")
+		}
+		switch signer.(type) {
+		case *rsa.PrivateKey:
+			sp.SignatureMethod = dsig.RSASHA256SignatureMethod
+		case *ecdsa.PrivateKey:
+			sp.SignatureMethod = dsig.ECDSASHA256SignatureMethod
+		}
+		if sp.Certificate, err = x509.ParseCertificate(keyPair.Certificate[0]); err != nil {
+			return nil, fmt.Errorf("failed to parse service provider certificate: %w", err)
+		}
+		sp.Key = signer
+	}
+
+	return &ServiceProvider{sp: sp, cfg: cfg}, nil
+}
+
+// readMetadata reads the IdP metadata from an http(s) URL or a file
+func readMetadata(ctx context.Context, source string) ([]byte, error) {
+	if !strings.HasPrefix(source, "http://") && !strings.HasPrefix(source, "https://") {
+		data, err := os.ReadFile(source)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read IdP metadata: %w", err)
+		}
+		return data, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, metadataFetchTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, source, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid IdP metadata URL: %w", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch IdP metadata: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch IdP metadata: unexpected status %d", resp.StatusCode)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+}
+
+// ParseMetadata parses IdP metadata, which is either an EntityDescriptor or an
+// EntitiesDescriptor holding the IdP's descriptor
+func ParseMetadata(data []byte) (*saml.EntityDescriptor, error) {
+	var entities saml.EntitiesDescriptor
+	if err := xml.Unmarshal(data, &entities); err == nil {
+		for i := range entities.EntityDescriptors {
+			if len(entities.EntityDescriptors[i].IDPSSODescriptors) > 0 {
+				return &entities.EntityDescriptors[i], nil
+			}
+		}
+		return nil, errors.New("no entity with an IDPSSODescriptor found")
+	}
+
+	var entity saml.EntityDescriptor
+	if err := xml.Unmarshal(data, &entity); err != nil {
+		return nil, err
+	}
+	if len(entity.IDPSSODescriptors) == 0 {
+		return nil, errors.New("metadata has no IDPSSODescriptor")
+	}
+	return &entity, nil
+}
+
+// Metadata returns the service provider's metadata document
+func (p *ServiceProvider) Metadata() ([]byte, error) {
+	data, err := xml.MarshalIndent(p.sp.Metadata(), "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal metadata: %w", err)
+	}
+	return append([]byte(xml.Header), data...), nil
+}
+
+// AuthenticationRequest creates a request to authenticate at the IdP, returning the
+// URL to redirect the user to and the request's ID, which the response must refer to
+func (p *ServiceProvider) AuthenticationRequest() (*url.URL, string, error) {
+	location := p.sp.GetSSOBindingLocation(saml.HTTPRedirectBinding)
+	if location == "" {
+		return nil, "", errors.New("IdP has no HTTP-Redirect single sign-on endpoint")
+	}
+	req, err := p.sp.MakeAuthenticationRequest(location, saml.HTTPRedirectBinding, saml.HTTPPostBinding)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create authentication request: %w", err)
+	}
+	redirect, err := req.Redirect("", p.sp)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to encode authentication request: %w", err)
+	}
+	return redirect, req.ID, nil
+}
+
+// ParseResponse validates the IdP's response posted to the assertion consumer service
+// and returns the authenticated identity. requestIDs are the IDs of the authentication
+// requests the response may answer.
+func (p *ServiceProvider) ParseResponse(r *http.Request, requestIDs []string) (*Identity, error) {
+	assertion, err := p.sp.ParseResponse(r, requestIDs)
+	if err != nil {
+		var invalid *saml.InvalidResponseError
+		if errors.As(err, &invalid) {
+			return nil, fmt.Errorf("invalid SAML response: %w", invalid.PrivateErr)
+		}
+		return nil, fmt.Errorf("invalid SAML response: %w", err)
+	}
+	return p.identity(assertion)
+}
+
+// identity maps the attributes of an assertion to a user
+func (p *ServiceProvider) identity(assertion *saml.Assertion) (*Identity, error) {
+	identity := &Identity{}
+	if p.cfg.UsernameAttribute == "" {
+		if assertion.Subject != nil && assertion.Subject.NameID != nil {
+			identity.Username = strings.TrimSpace(assertion.Subject.NameID.Value)
+		}
+	} else if values := attributeValues(assertion, p.cfg.UsernameAttribute); len(values) > 0 {
+		identity.Username = strings.TrimSpace(values[0])
+	}
+	if identity.Username == "" {
+		return nil, errors.New("assertion has no username")
+	}
+
+	if p.cfg.RoleAttribute != "" {
+		identity.Role = mapRole(attributeValues(assertion, p.cfg.RoleAttribute), p.cfg.RoleMappings, p.cfg.DefaultRole)
+	}
+	return identity, nil
+}
+
+// mapRole returns the role of the first mapping matching one of the values, or the
+// default role if none does
+func mapRole(values []string, mappings []RoleMapping, defaultRole string) string {
+	for _, mapping := range mappings {
+		if slices.Contains(values, mapping.Value) {
+			return mapping.Role
+		}
+	}
+	return defaultRole
+}
+
+// attributeValues returns the values of the attributes with the given name or friendly name
+func attributeValues(assertion *saml.Assertion, name string) []string {
+	var values []string
+	for _, statement := range assertion.AttributeStatements {
+		for _, attribute := range statement.Attributes {
+			if attribute.Name != name && attribute.FriendlyName != name {
+				continue
+			}
+			for _, value := range attribute.Values {
+				values = append(values, value.Value)
+			}
+		}
+	}
+	return values
+}
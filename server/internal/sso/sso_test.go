@@ -0,0 +1,127 @@
+package sso
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/crewjam/saml"
+)
+
+const idpMetadata = `<EntityDescriptor xmlns="urn:oasis:names:tc:SAML:2.0:metadata" entityID="https://idp.example.com/metadata">
+  <IDPSSODescriptor protocolSupportEnumeration="urn:oasis:names:tc:SAML:2.0:protocol">
+    <SingleSignOnService Binding="urn:oasis:names:tc:SAML:2.0:bindings:HTTP-Redirect" Location="https://idp.example.com/sso"/>
+  </IDPSSODescriptor>
+</EntityDescriptor>`
+
+func writeMetadata(t *testing.T, data string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "idp.xml")
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatalf("failed to write metadata: %v", err)
+	}
+	return path
+}
+
+func TestParseMetadata(t *testing.T) {
+	entities := `<EntitiesDescriptor xmlns="urn:oasis:names:tc:SAML:2.0:metadata">
+  <EntityDescriptor entityID="https://sp.example.com">
+    <SPSSODescriptor protocolSupportEnumeration="urn:oasis:names:tc:SAML:2.0:protocol"/>
+  </EntityDescriptor>
+  <EntityDescriptor entityID="https://idp.example.com/metadata">
+    <IDPSSODescriptor protocolSupportEnumeration="urn:oasis:names:tc:SAML:2.0:protocol">
+      <SingleSignOnService Binding="urn:oasis:names:tc:SAML:2.0:bindings:HTTP-Redirect" Location="https://idp.example.com/sso"/>
+    </IDPSSODescriptor>
+  </EntityDescriptor>
+</EntitiesDescriptor>`
+
+	for name, data := range map[string]string{"entity": idpMetadata, "entities": entities} {
+		t.Run(name, func(t *testing.T) {
+			entity, err := ParseMetadata([]byte(data))
+			if err != nil {
+				t.Fatalf("ParseMetadata() error = %v", err)
+			}
+			if entity.EntityID != "https://idp.example.com/metadata" {
+				t.Errorf("EntityID = %q, want the IdP", entity.EntityID)
+			}
+		})
+	}
+
+	if _, err := ParseMetadata([]byte(`<EntityDescriptor xmlns="urn:oasis:names:tc:SAML:2.0:metadata" entityID="sp"/>`)); err == nil {
+		t.Error("ParseMetadata() accepted metadata without an IdP")
+	}
+}
+
+func TestServiceProvider(t *testing.T) {
+	provider, err := NewServiceProvider(context.Background(), Config{
+		PublicURL:   "https://supacontrol.example.com/",
+		IDPMetadata: writeMetadata(t, idpMetadata),
+	})
+	if err != nil {
+		t.Fatalf("NewServiceProvider() error = %v", err)
+	}
+
+	metadata, err := provider.Metadata()
+	if err != nil {
+		t.Fatalf("Metadata() error = %v", err)
+	}
+	for _, want := range []string{
+		`entityID="https://supacontrol.example.com/api/v1/auth/saml/metadata"`,
+		`Location="https://supacontrol.example.com/api/v1/auth/saml/acs"`,
+	} {
+		if !strings.Contains(string(metadata), want) {
+			t.Errorf("metadata lacks %s:\n%s", want, metadata)
+		}
+	}
+
+	redirect, requestID, err := provider.AuthenticationRequest()
+	if err != nil {
+		t.Fatalf("AuthenticationRequest() error = %v", err)
+	}
+	if redirect.Host != "idp.example.com" || redirect.Query().Get("SAMLRequest") == "" || requestID == "" {
+		t.Errorf("AuthenticationRequest() = %s, %q", redirect, requestID)
+	}
+
+	if _, err := NewServiceProvider(context.Background(), Config{PublicURL: "https://supacontrol.example.com", IDPMetadata: "/missing.xml"}); err == nil {
+		t.Error("NewServiceProvider() succeeded without IdP metadata")
+	}
+}
+
+func TestIdentity(t *testing.T) {
+	assertion := &saml.Assertion{
+		Subject: &saml.Subject{NameID: &saml.NameID{Value: "jane@example.com"}},
+		AttributeStatements: []saml.AttributeStatement{{Attributes: []saml.Attribute{
+			{Name: "urn:oid:0.9.2342.19200300.100.1.1", FriendlyName: "uid", Values: []saml.AttributeValue{{Value: "jane"}}},
+			{Name: "groups", Values: []saml.AttributeValue{{Value: "staff"}, {Value: "ops"}}},
+		}}},
+	}
+	mappings := []RoleMapping{{Value: "admins", Role: "admin"}, {Value: "ops", Role: "operator"}, {Value: "staff", Role: "readonly"}}
+
+	tests := []struct {
+		name     string
+		cfg      Config
+		expected Identity
+	}{
+		{name: "NameID without roles", expected: Identity{Username: "jane@example.com"}},
+		{name: "attribute by friendly name", cfg: Config{UsernameAttribute: "uid"}, expected: Identity{Username: "jane"}},
+		{name: "first matching mapping wins", cfg: Config{RoleAttribute: "groups", RoleMappings: mappings, DefaultRole: "user"}, expected: Identity{Username: "jane@example.com", Role: "operator"}},
+		{name: "default role", cfg: Config{RoleAttribute: "groups", RoleMappings: mappings[:1], DefaultRole: "user"}, expected: Identity{Username: "jane@example.com", Role: "user"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			identity, err := (&ServiceProvider{cfg: tt.cfg}).identity(assertion)
+			if err != nil {
+				t.Fatalf("identity() error = %v", err)
+			}
+			if *identity != tt.expected {
+				t.Errorf("identity() = %+v, want %+v", *identity, tt.expected)
+			}
+		})
+	}
+
+	if _, err := (&ServiceProvider{cfg: Config{UsernameAttribute: "mail"}}).identity(assertion); err == nil {
+		t.Error("identity() accepted an assertion without the username attribute")
+	}
+}
@@ -0,0 +1,156 @@
+// Package storageapi manages the buckets of Supabase instances through their storage API.
+//
+// Requests authenticate with the instance's service-role key, so they bypass the row
+// level security policies of the storage schema.
+package storageapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const (
+	// requestTimeout bounds a single call to an instance's storage API
+	requestTimeout = 30 * time.Second
+
+	// maxResponseSize caps the responses read from an instance's storage API
+	maxResponseSize = 4 << 20
+)
+
+// Bucket is a storage bucket of an instance
+type Bucket struct {
+	ID               string     `json:"id"`
+	Name             string     `json:"name"`
+	Public           bool       `json:"public"`
+	FileSizeLimit    *int64     `json:"file_size_limit,omitempty"`
+	AllowedMimeTypes []string   `json:"allowed_mime_types,omitempty"`
+	CreatedAt        *time.Time `json:"created_at,omitempty"`
+	UpdatedAt        *time.Time `json:"updated_at,omitempty"`
+}
+
+// Error is an error response of the storage API
+type Error struct {
+	// StatusCode is the HTTP status the storage API reports for the error
+	StatusCode int
+
+	// Message explains the error
+	Message string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("storage API returned %d: %s", e.StatusCode, e.Message)
+}
+
+// Client calls the storage APIs of instances
+type Client struct {
+	httpClient *http.Client
+}
+
+// NewClient creates a storage API client
+func NewClient() *Client {
+	return &Client{httpClient: &http.Client{Timeout: requestTimeout}}
+}
+
+// ListBuckets returns the buckets of the storage API at endpoint
+func (c *Client) ListBuckets(ctx context.Context, endpoint, serviceRoleKey string) ([]Bucket, error) {
+	var buckets []Bucket
+	if err := c.do(ctx, http.MethodGet, endpoint+"/bucket", serviceRoleKey, nil, &buckets); err != nil {
+		return nil, err
+	}
+	return buckets, nil
+}
+
+// CreateBucket creates a bucket; its ID is its name
+func (c *Client) CreateBucket(ctx context.Context, endpoint, serviceRoleKey string, bucket Bucket) error {
+	bucket.ID = bucket.Name
+	return c.do(ctx, http.MethodPost, endpoint+"/bucket", serviceRoleKey, bucket, nil)
+}
+
+// DeleteBucket deletes an empty bucket
+func (c *Client) DeleteBucket(ctx context.Context, endpoint, serviceRoleKey, id string) error {
+	return c.do(ctx, http.MethodDelete, endpoint+"/bucket/"+url.PathEscape(id), serviceRoleKey, nil, nil)
+}
+
+// do sends a request to the storage API and decodes its response into out, if given
+func (c *Client) do(ctx context.Context, method, target, serviceRoleKey string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+serviceRoleKey)
+	req.Header.Set("apikey", serviceRoleKey)
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach storage API: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return fmt.Errorf("failed to read storage API response: %w", err)
+	}
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return parseError(resp.StatusCode, data)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("failed to decode storage API response: %w", err)
+	}
+	return nil
+}
+
+// parseError reads an error response. The storage API reports some errors, such as
+// missing buckets, with HTTP status 400 and the actual status in the body.
+func parseError(status int, data []byte) error {
+	var body struct {
+		StatusCode string `json:"statusCode"`
+		Error      string `json:"error"`
+		Message    string `json:"message"`
+	}
+	apiErr := &Error{StatusCode: status, Message: http.StatusText(status)}
+	if json.Unmarshal(data, &body) != nil {
+		return apiErr
+	}
+	if code, err := strconv.Atoi(body.StatusCode); err == nil && code >= http.StatusBadRequest {
+		apiErr.StatusCode = code
+	}
+	switch {
+	case body.Message != "":
+		apiErr.Message = body.Message
+	case body.Error != "":
+		apiErr.Message = body.Error
+	}
+	return apiErr
+}
+
+// StatusCode returns the status the storage API reported for err, or 0 when err is not
+// a storage API error response
+func StatusCode(err error) int {
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode
+	}
+	return 0
+}
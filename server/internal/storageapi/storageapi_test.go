@@ -0,0 +1,91 @@
+package storageapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestBuckets(t *testing.T) {
+	var created Bucket
+	var deleted string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer service-key" || r.Header.Get("apikey") != "service-key" {
+			t.Errorf("request %s %s not authenticated with the service-role key", r.Method, r.URL.Path)
+		}
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/bucket":
+			_, _ = w.Write([]byte(`[{"id":"avatars","name":"avatars","public":true,"file_size_limit":1048576,"created_at":"2026-01-02T03:04:05Z"}]`))
+		case r.Method == http.MethodPost && r.URL.Path == "/bucket":
+			if err := json.NewDecoder(r.Body).Decode(&created); err != nil {
+				t.Errorf("invalid body: %v", err)
+			}
+			_, _ = w.Write([]byte(`{"name":"docs"}`))
+		case r.Method == http.MethodDelete:
+			deleted = r.URL.Path
+			_, _ = w.Write([]byte(`{"message":"Successfully deleted"}`))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient()
+	ctx := context.Background()
+	buckets, err := client.ListBuckets(ctx, server.URL, "service-key")
+	if err != nil {
+		t.Fatalf("ListBuckets() error = %v", err)
+	}
+	if len(buckets) != 1 || buckets[0].ID != "avatars" || !buckets[0].Public ||
+		buckets[0].FileSizeLimit == nil || *buckets[0].FileSizeLimit != 1048576 || buckets[0].CreatedAt == nil {
+		t.Errorf("unexpected buckets %+v", buckets)
+	}
+
+	if err := client.CreateBucket(ctx, server.URL, "service-key", Bucket{Name: "docs", AllowedMimeTypes: []string{"application/pdf"}}); err != nil {
+		t.Fatalf("CreateBucket() error = %v", err)
+	}
+	if created.ID != "docs" || created.Name != "docs" || len(created.AllowedMimeTypes) != 1 {
+		t.Errorf("unexpected created bucket %+v", created)
+	}
+
+	if err := client.DeleteBucket(ctx, server.URL, "service-key", "docs"); err != nil {
+		t.Fatalf("DeleteBucket() error = %v", err)
+	}
+	if deleted != "/bucket/docs" {
+		t.Errorf("deleted %q, want /bucket/docs", deleted)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	tests := []struct {
+		name           string
+		status         int
+		body           string
+		expectedStatus int
+		expectedMsg    string
+	}{
+		{name: "status in body", status: http.StatusBadRequest, body: `{"statusCode":"404","error":"Bucket not found","message":"Bucket not found"}`, expectedStatus: http.StatusNotFound, expectedMsg: "Bucket not found"},
+		{name: "error only", status: http.StatusConflict, body: `{"error":"Duplicate"}`, expectedStatus: http.StatusConflict, expectedMsg: "Duplicate"},
+		{name: "no JSON", status: http.StatusBadGateway, body: `upstream down`, expectedStatus: http.StatusBadGateway, expectedMsg: "Bad Gateway"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			err := NewClient().DeleteBucket(context.Background(), server.URL, "service-key", "docs")
+			if StatusCode(err) != tt.expectedStatus {
+				t.Errorf("StatusCode() = %d, want %d (error %v)", StatusCode(err), tt.expectedStatus, err)
+			}
+			if apiErr, ok := err.(*Error); !ok || apiErr.Message != tt.expectedMsg {
+				t.Errorf("error = %v, want message %q", err, tt.expectedMsg)
+			}
+		})
+	}
+}
@@ -0,0 +1,343 @@
+// Package upgrades rolls Supabase chart versions across a fleet of instances.
+//
+// An upgrade run is stored in the database by the API and driven by a Runner that
+// executes on the elected leader. Canary targets are upgraded one at a time first;
+// the remaining targets follow with bounded concurrency. A failed canary, or more
+// failures than the run tolerates, halts the run and skips targets not yet started.
+package upgrades
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/client-go/util/retry"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+)
+
+const (
+	// DefaultScanInterval is how often the runner looks for upgrade runs to drive
+	DefaultScanInterval = 5 * time.Second
+
+	// DefaultPollInterval is how often an instance is checked while it upgrades
+	DefaultPollInterval = 10 * time.Second
+
+	// DefaultInstanceTimeout bounds how long a single instance upgrade may take
+	DefaultInstanceTimeout = 20 * time.Minute
+)
+
+// Store persists upgrade runs and their progress
+type Store interface {
+	ListRunningUpgrades() ([]*apitypes.Upgrade, error)
+	ListUpgradeTargets(upgradeID int64) ([]*apitypes.UpgradeTarget, error)
+	UpdateUpgradeTarget(target *apitypes.UpgradeTarget) error
+	FinishUpgrade(id int64, status, haltReason string) error
+}
+
+// InstanceClient reads and updates SupabaseInstance resources
+type InstanceClient interface {
+	GetSupabaseInstance(ctx context.Context, name string) (*supacontrolv1alpha1.SupabaseInstance, error)
+	UpdateSupabaseInstance(ctx context.Context, instance *supacontrolv1alpha1.SupabaseInstance) error
+}
+
+// Runner drives running upgrades to completion. It implements the controller-runtime
+// Runnable interface and only runs on the elected leader.
+type Runner struct {
+	store     Store
+	instances InstanceClient
+
+	ScanInterval    time.Duration
+	PollInterval    time.Duration
+	InstanceTimeout time.Duration
+
+	mu     sync.Mutex
+	active map[int64]bool
+	wg     sync.WaitGroup
+}
+
+// NewRunner creates a runner with default intervals
+func NewRunner(store Store, instances InstanceClient) *Runner {
+	return &Runner{
+		store:           store,
+		instances:       instances,
+		ScanInterval:    DefaultScanInterval,
+		PollInterval:    DefaultPollInterval,
+		InstanceTimeout: DefaultInstanceTimeout,
+		active:          make(map[int64]bool),
+	}
+}
+
+// NeedLeaderElection ensures only one replica drives upgrades
+func (r *Runner) NeedLeaderElection() bool {
+	return true
+}
+
+// Start picks up running upgrades, including ones left behind by a previous leader,
+// until ctx is cancelled
+func (r *Runner) Start(ctx context.Context) error {
+	ticker := time.NewTicker(r.ScanInterval)
+	defer ticker.Stop()
+
+	for {
+		r.scan(ctx)
+
+		select {
+		case <-ctx.Done():
+			r.wg.Wait()
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// scan starts a goroutine for each running upgrade not already being driven
+func (r *Runner) scan(ctx context.Context) {
+	upgrades, err := r.store.ListRunningUpgrades()
+	if err != nil {
+		slog.Error("Failed to list running upgrades", "error", err)
+		return
+	}
+
+	for _, upgrade := range upgrades {
+		r.mu.Lock()
+		if r.active[upgrade.ID] {
+			r.mu.Unlock()
+			continue
+		}
+		r.active[upgrade.ID] = true
+		r.mu.Unlock()
+
+		r.wg.Add(1)
+		go func(upgrade *apitypes.Upgrade) {
+			defer r.wg.Done()
+			defer func() {
+				r.mu.Lock()
+				delete(r.active, upgrade.ID)
+				r.mu.Unlock()
+			}()
+
+			if err := r.Run(ctx, upgrade); err != nil && !errors.Is(err, context.Canceled) {
+				slog.Error("Upgrade run failed", "upgrade_id", upgrade.ID, "error", err)
+			}
+		}(upgrade)
+	}
+}
+
+// Run drives one upgrade to completion. Targets already finished are left alone and
+// targets in progress are resumed, so a run can be picked up after a restart.
+// If ctx is cancelled the run is left running for the next leader.
+func (r *Runner) Run(ctx context.Context, upgrade *apitypes.Upgrade) error {
+	targets, err := r.store.ListUpgradeTargets(upgrade.ID)
+	if err != nil {
+		return err
+	}
+
+	slog.Info("Driving upgrade", "upgrade_id", upgrade.ID, "chart_version", upgrade.ChartVersion, "targets", len(targets))
+
+	failures := 0
+	var canaries, rest []*apitypes.UpgradeTarget
+	for _, target := range targets {
+		switch {
+		case target.Status == apitypes.UpgradeTargetFailed:
+			failures++
+		case target.Status != apitypes.UpgradeTargetPending && target.Status != apitypes.UpgradeTargetInProgress:
+			// already finished
+		case target.Canary:
+			canaries = append(canaries, target)
+		default:
+			rest = append(rest, target)
+		}
+	}
+
+	// Canaries go first, one at a time; any canary failure halts the run
+	for _, target := range canaries {
+		if err := r.upgradeTarget(ctx, upgrade.ChartVersion, target); err != nil {
+			return err
+		}
+		if target.Status == apitypes.UpgradeTargetFailed {
+			return r.store.FinishUpgrade(upgrade.ID, apitypes.UpgradeStatusHalted,
+				fmt.Sprintf("canary %s failed", target.InstanceName))
+		}
+	}
+
+	// The remaining targets run with bounded concurrency until the failure threshold is exceeded
+	concurrency := upgrade.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		firstErr error
+	)
+	exceeded := func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return failures > upgrade.MaxFailures || firstErr != nil
+	}
+
+	slots := make(chan struct{}, concurrency)
+	for _, target := range rest {
+		slots <- struct{}{}
+		if exceeded() || ctx.Err() != nil {
+			<-slots
+			break
+		}
+
+		wg.Add(1)
+		go func(target *apitypes.UpgradeTarget) {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			err := r.upgradeTarget(ctx, upgrade.ChartVersion, target)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil && firstErr == nil {
+				firstErr = err
+			}
+			if target.Status == apitypes.UpgradeTargetFailed {
+				failures++
+			}
+		}(target)
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if failures > upgrade.MaxFailures {
+		return r.store.FinishUpgrade(upgrade.ID, apitypes.UpgradeStatusHalted,
+			fmt.Sprintf("failure threshold exceeded: %d instances failed, %d allowed", failures, upgrade.MaxFailures))
+	}
+
+	slog.Info("Upgrade completed", "upgrade_id", upgrade.ID, "failures", failures)
+	return r.store.FinishUpgrade(upgrade.ID, apitypes.UpgradeStatusCompleted, "")
+}
+
+// upgradeTarget upgrades one instance and records the outcome on its target.
+// An error is returned only when progress could not be saved or ctx was cancelled.
+func (r *Runner) upgradeTarget(ctx context.Context, chartVersion string, target *apitypes.UpgradeTarget) error {
+	if target.Status == apitypes.UpgradeTargetPending {
+		now := time.Now()
+		target.Status = apitypes.UpgradeTargetInProgress
+		target.StartedAt = &now
+		if err := r.store.UpdateUpgradeTarget(target); err != nil {
+			return err
+		}
+	}
+
+	status, errMsg := r.upgradeInstance(ctx, chartVersion, target)
+	if ctx.Err() != nil {
+		// Leave the target in progress so the next leader resumes it
+		return ctx.Err()
+	}
+
+	now := time.Now()
+	target.Status = status
+	target.CompletedAt = &now
+	if errMsg != "" {
+		target.ErrorMessage = &errMsg
+	}
+	slog.Info("Instance upgrade finished", "instance", target.InstanceName, "status", status, "error", errMsg)
+	return r.store.UpdateUpgradeTarget(target)
+}
+
+// upgradeInstance sets the instance's chart version and waits for the controller to
+// finish the upgrade. It returns the target status and an error message on failure.
+func (r *Runner) upgradeInstance(ctx context.Context, chartVersion string, target *apitypes.UpgradeTarget) (string, string) {
+	instance, err := r.instances.GetSupabaseInstance(ctx, target.InstanceName)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return apitypes.UpgradeTargetFailed, "instance not found"
+		}
+		return apitypes.UpgradeTargetFailed, fmt.Sprintf("failed to get instance: %v", err)
+	}
+
+	if target.FromVersion == nil {
+		fromVersion := instance.Status.ChartVersion
+		target.FromVersion = &fromVersion
+	}
+
+	switch {
+	case instance.Spec.PendingDeletion != nil || instance.Status.Phase == supacontrolv1alpha1.PhasePendingDeletion:
+		return apitypes.UpgradeTargetSkipped, "instance is pending deletion"
+	case instance.Spec.Paused || instance.Status.Phase == supacontrolv1alpha1.PhaseStopped:
+		return apitypes.UpgradeTargetSkipped, "instance is stopped"
+	case instance.Spec.Suspension != nil || instance.Status.Phase == supacontrolv1alpha1.PhaseSuspended:
+		return apitypes.UpgradeTargetSkipped, "instance is suspended"
+	case instance.Status.Phase == supacontrolv1alpha1.PhaseRunning && instance.Status.ChartVersion == chartVersion &&
+		instance.Status.ObservedGeneration == instance.Generation:
+		return apitypes.UpgradeTargetSucceeded, ""
+	case instance.Status.Phase != supacontrolv1alpha1.PhaseRunning && instance.Status.Phase != supacontrolv1alpha1.PhaseUpgrading &&
+		!supacontrolv1alpha1.IsDatabaseUpgradePhase(instance.Status.Phase):
+		return apitypes.UpgradeTargetFailed, fmt.Sprintf("instance is %s", instance.Status.Phase)
+	}
+
+	if instance.Spec.ChartVersion != chartVersion {
+		err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+			current, err := r.instances.GetSupabaseInstance(ctx, target.InstanceName)
+			if err != nil {
+				return err
+			}
+			current.Spec.ChartVersion = chartVersion
+			if err := r.instances.UpdateSupabaseInstance(ctx, current); err != nil {
+				return err
+			}
+			instance = current
+			return nil
+		})
+		if err != nil {
+			return apitypes.UpgradeTargetFailed, fmt.Sprintf("failed to update instance: %v", err)
+		}
+	}
+
+	return r.waitForUpgrade(ctx, chartVersion, target.InstanceName, instance.Generation)
+}
+
+// waitForUpgrade polls the instance until the controller has reconciled generation
+func (r *Runner) waitForUpgrade(ctx context.Context, chartVersion, name string, generation int64) (string, string) {
+	ctx, cancel := context.WithTimeout(ctx, r.InstanceTimeout)
+	defer cancel()
+
+	ticker := time.NewTicker(r.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		instance, err := r.instances.GetSupabaseInstance(ctx, name)
+		switch {
+		case apierrors.IsNotFound(err):
+			return apitypes.UpgradeTargetFailed, "instance was deleted during the upgrade"
+		case err != nil:
+			slog.Warn("Failed to check instance upgrade", "instance", name, "error", err)
+		case instance.Status.Phase == supacontrolv1alpha1.PhaseFailed:
+			return apitypes.UpgradeTargetFailed, instance.Status.ErrorMessage
+		case instance.Status.ObservedGeneration >= generation && instance.Status.Phase == supacontrolv1alpha1.PhaseRunning:
+			if instance.Status.ChartVersion == chartVersion {
+				return apitypes.UpgradeTargetSucceeded, ""
+			}
+			msg := "upgrade did not complete"
+			if condition := meta.FindStatusCondition(instance.Status.Conditions, supacontrolv1alpha1.ConditionTypeUpgraded); condition != nil && condition.Message != "" {
+				msg = condition.Message
+			}
+			return apitypes.UpgradeTargetFailed, msg
+		}
+
+		select {
+		case <-ctx.Done():
+			return apitypes.UpgradeTargetFailed, "timed out waiting for the upgrade to finish"
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,267 @@
+package upgrades
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
+	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+)
+
+// fakeStore keeps upgrade runs in memory
+type fakeStore struct {
+	mu         sync.Mutex
+	targets    []*apitypes.UpgradeTarget
+	status     string
+	haltReason string
+}
+
+func (s *fakeStore) ListRunningUpgrades() ([]*apitypes.Upgrade, error) {
+	return nil, nil
+}
+
+func (s *fakeStore) ListUpgradeTargets(int64) ([]*apitypes.UpgradeTarget, error) {
+	return s.targets, nil
+}
+
+func (s *fakeStore) UpdateUpgradeTarget(*apitypes.UpgradeTarget) error {
+	return nil
+}
+
+func (s *fakeStore) FinishUpgrade(_ int64, status, haltReason string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status = status
+	s.haltReason = haltReason
+	for _, target := range s.targets {
+		if target.Status == apitypes.UpgradeTargetPending {
+			target.Status = apitypes.UpgradeTargetSkipped
+		}
+	}
+	return nil
+}
+
+// fakeInstances simulates the controller: updating the chart version immediately
+// completes the upgrade, unless the instance is listed in failing
+type fakeInstances struct {
+	mu        sync.Mutex
+	instances map[string]*supacontrolv1alpha1.SupabaseInstance
+	failing   map[string]bool
+	order     []string
+	inFlight  int
+	maxFlight int
+}
+
+func newFakeInstances(names ...string) *fakeInstances {
+	f := &fakeInstances{
+		instances: make(map[string]*supacontrolv1alpha1.SupabaseInstance),
+		failing:   make(map[string]bool),
+	}
+	for _, name := range names {
+		f.instances[name] = &supacontrolv1alpha1.SupabaseInstance{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Generation: 1},
+			Spec:       supacontrolv1alpha1.SupabaseInstanceSpec{ProjectName: name, ChartVersion: "0.1.0"},
+			Status: supacontrolv1alpha1.SupabaseInstanceStatus{
+				Phase:              supacontrolv1alpha1.PhaseRunning,
+				ChartVersion:       "0.1.0",
+				ObservedGeneration: 1,
+			},
+		}
+	}
+	return f
+}
+
+func (f *fakeInstances) GetSupabaseInstance(_ context.Context, name string) (*supacontrolv1alpha1.SupabaseInstance, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	instance, ok := f.instances[name]
+	if !ok {
+		return nil, apierrors.NewNotFound(schema.GroupResource{Resource: "supabaseinstances"}, name)
+	}
+
+	// Upgrades take one poll to finish so concurrent upgrades overlap
+	if instance.Status.Phase == supacontrolv1alpha1.PhaseUpgrading {
+		f.inFlight--
+		instance.Status.Phase = supacontrolv1alpha1.PhaseRunning
+		instance.Status.ObservedGeneration = instance.Generation
+		if f.failing[name] {
+			meta.SetStatusCondition(&instance.Status.Conditions, metav1.Condition{
+				Type:    supacontrolv1alpha1.ConditionTypeUpgraded,
+				Status:  metav1.ConditionFalse,
+				Reason:  "UpgradeFailed",
+				Message: "helm upgrade failed",
+			})
+		} else {
+			instance.Status.ChartVersion = instance.Spec.ChartVersion
+		}
+	}
+	return instance.DeepCopy(), nil
+}
+
+func (f *fakeInstances) UpdateSupabaseInstance(_ context.Context, updated *supacontrolv1alpha1.SupabaseInstance) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	instance := f.instances[updated.Name]
+	instance.Spec = updated.Spec
+	instance.Generation++
+	instance.Status.Phase = supacontrolv1alpha1.PhaseUpgrading
+	f.order = append(f.order, updated.Name)
+	f.inFlight++
+	if f.inFlight > f.maxFlight {
+		f.maxFlight = f.inFlight
+	}
+	return nil
+}
+
+func newTargets(canaries int, names ...string) []*apitypes.UpgradeTarget {
+	targets := make([]*apitypes.UpgradeTarget, 0, len(names))
+	for i, name := range names {
+		targets = append(targets, &apitypes.UpgradeTarget{
+			ID:           int64(i + 1),
+			InstanceName: name,
+			Position:     i,
+			Canary:       i < canaries,
+			Status:       apitypes.UpgradeTargetPending,
+		})
+	}
+	return targets
+}
+
+func newTestRunner(store Store, instances InstanceClient) *Runner {
+	runner := NewRunner(store, instances)
+	runner.PollInterval = time.Millisecond
+	runner.InstanceTimeout = time.Second
+	return runner
+}
+
+func targetStatuses(targets []*apitypes.UpgradeTarget) map[string]string {
+	statuses := make(map[string]string, len(targets))
+	for _, target := range targets {
+		statuses[target.InstanceName] = target.Status
+	}
+	return statuses
+}
+
+func TestRunner_Run(t *testing.T) {
+	names := []string{"canary", "a", "b", "c", "d"}
+
+	tests := []struct {
+		name             string
+		failing          []string
+		maxFailures      int
+		expectedStatus   string
+		expectedTargets  map[string]string
+		expectedHaltText string
+	}{
+		{
+			name:           "upgrades every instance",
+			expectedStatus: apitypes.UpgradeStatusCompleted,
+			expectedTargets: map[string]string{
+				"canary": apitypes.UpgradeTargetSucceeded,
+				"a":      apitypes.UpgradeTargetSucceeded,
+				"b":      apitypes.UpgradeTargetSucceeded,
+				"c":      apitypes.UpgradeTargetSucceeded,
+				"d":      apitypes.UpgradeTargetSucceeded,
+			},
+		},
+		{
+			name:             "failed canary halts before the rest",
+			failing:          []string{"canary"},
+			maxFailures:      3,
+			expectedStatus:   apitypes.UpgradeStatusHalted,
+			expectedHaltText: "canary canary failed",
+			expectedTargets: map[string]string{
+				"canary": apitypes.UpgradeTargetFailed,
+				"a":      apitypes.UpgradeTargetSkipped,
+				"d":      apitypes.UpgradeTargetSkipped,
+			},
+		},
+		{
+			name:           "failures within threshold complete",
+			failing:        []string{"b"},
+			maxFailures:    1,
+			expectedStatus: apitypes.UpgradeStatusCompleted,
+			expectedTargets: map[string]string{
+				"b": apitypes.UpgradeTargetFailed,
+				"d": apitypes.UpgradeTargetSucceeded,
+			},
+		},
+		{
+			name:             "failures over threshold halt",
+			failing:          []string{"a", "b"},
+			maxFailures:      1,
+			expectedStatus:   apitypes.UpgradeStatusHalted,
+			expectedHaltText: "failure threshold exceeded",
+			expectedTargets: map[string]string{
+				"a": apitypes.UpgradeTargetFailed,
+				"b": apitypes.UpgradeTargetFailed,
+				"d": apitypes.UpgradeTargetSkipped,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			instances := newFakeInstances(names...)
+			for _, name := range tt.failing {
+				instances.failing[name] = true
+			}
+			store := &fakeStore{targets: newTargets(1, names...)}
+			upgrade := &apitypes.Upgrade{ID: 1, ChartVersion: "0.2.0", Concurrency: 1, MaxFailures: tt.maxFailures}
+
+			err := newTestRunner(store, instances).Run(context.Background(), upgrade)
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.expectedStatus, store.status)
+			assert.Contains(t, store.haltReason, tt.expectedHaltText)
+			statuses := targetStatuses(store.targets)
+			for name, expected := range tt.expectedTargets {
+				assert.Equal(t, expected, statuses[name], "target %s", name)
+			}
+			require.NotEmpty(t, instances.order)
+			assert.Equal(t, "canary", instances.order[0], "canary must be upgraded first")
+		})
+	}
+}
+
+func TestRunner_RespectsConcurrency(t *testing.T) {
+	names := make([]string, 0, 8)
+	for i := 0; i < 8; i++ {
+		names = append(names, fmt.Sprintf("instance-%d", i))
+	}
+	instances := newFakeInstances(names...)
+	store := &fakeStore{targets: newTargets(0, names...)}
+	upgrade := &apitypes.Upgrade{ID: 1, ChartVersion: "0.2.0", Concurrency: 3}
+
+	require.NoError(t, newTestRunner(store, instances).Run(context.Background(), upgrade))
+
+	assert.Equal(t, apitypes.UpgradeStatusCompleted, store.status)
+	assert.LessOrEqual(t, instances.maxFlight, 3)
+}
+
+func TestRunner_SkipsStoppedAndMissingInstances(t *testing.T) {
+	instances := newFakeInstances("running", "stopped")
+	instances.instances["stopped"].Status.Phase = supacontrolv1alpha1.PhaseStopped
+	store := &fakeStore{targets: newTargets(0, "running", "stopped", "missing")}
+	upgrade := &apitypes.Upgrade{ID: 1, ChartVersion: "0.2.0", Concurrency: 1, MaxFailures: 1}
+
+	require.NoError(t, newTestRunner(store, instances).Run(context.Background(), upgrade))
+
+	statuses := targetStatuses(store.targets)
+	assert.Equal(t, apitypes.UpgradeTargetSucceeded, statuses["running"])
+	assert.Equal(t, apitypes.UpgradeTargetSkipped, statuses["stopped"])
+	assert.Equal(t, apitypes.UpgradeTargetFailed, statuses["missing"])
+	assert.Equal(t, "0.1.0", *store.targets[0].FromVersion)
+}
@@ -0,0 +1,107 @@
+// Package validate checks the fields of API requests.
+//
+// A Validator collects every problem it finds instead of stopping at the first,
+// so a client learns about all invalid fields of a request at once. Messages are
+// i18n catalog messages, translated when the error response is written.
+package validate
+
+import (
+	"net/mail"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	"github.com/qubitquilt/supacontrol/server/internal/i18n"
+)
+
+// FieldError is a problem with one request field. Field is the field's JSON name;
+// elements of lists are addressed as name[index].
+type FieldError struct {
+	Field   string
+	Message *i18n.Message
+}
+
+// Errors are the field errors found in a request
+type Errors []FieldError
+
+// Error lists the field errors in the default locale
+func (e Errors) Error() string {
+	parts := make([]string, len(e))
+	for i, fe := range e {
+		parts[i] = fe.Field + ": " + fe.Message.String()
+	}
+	return strings.Join(parts, "; ")
+}
+
+// Validator collects field errors
+type Validator struct {
+	errs Errors
+}
+
+// Add records a field error with a catalog message
+func (v *Validator) Add(field, key string, args ...interface{}) {
+	v.errs = append(v.errs, FieldError{Field: field, Message: i18n.Msg(key, args...)})
+}
+
+// Err returns the collected field errors, or nil when there are none
+func (v *Validator) Err() error {
+	if len(v.errs) == 0 {
+		return nil
+	}
+	return v.errs
+}
+
+// Required records an error when value is empty or blank, and reports whether it is set
+func (v *Validator) Required(field, value string) bool {
+	if strings.TrimSpace(value) == "" {
+		v.Add(field, "is required")
+		return false
+	}
+	return true
+}
+
+// MaxLength records an error when value is longer than max characters
+func (v *Validator) MaxLength(field, value string, max int) {
+	if utf8.RuneCountInString(value) > max {
+		v.Add(field, "must be at most %d characters", max)
+	}
+}
+
+// DNSLabel records an error unless a set value is a DNS-1123 label of at most max
+// characters: lowercase letters, digits and hyphens, starting and ending with a letter
+// or digit. An empty value is left to Required.
+func (v *Validator) DNSLabel(field, value string, max int) {
+	if value == "" {
+		return
+	}
+	if len(value) > max || len(validation.IsDNS1123Label(value)) > 0 {
+		v.Add(field, "must be a DNS-1123 label of at most %d lowercase letters, digits and hyphens", max)
+	}
+}
+
+// Email records an error unless a set value is a plain email address
+func (v *Validator) Email(field, value string) {
+	if value == "" {
+		return
+	}
+	addr, err := mail.ParseAddress(value)
+	if err != nil || addr.Address != value {
+		v.Add(field, "must be a valid email address")
+	}
+}
+
+// NonNegative records an error when value is below zero
+func (v *Validator) NonNegative(field string, value int) {
+	if value < 0 {
+		v.Add(field, "must not be negative")
+	}
+}
+
+// Future records an error when a set time is not after now
+func (v *Validator) Future(field string, t *time.Time, now time.Time) {
+	if t != nil && !t.After(now) {
+		v.Add(field, "must be in the future")
+	}
+}
@@ -0,0 +1,91 @@
+package validate
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/qubitquilt/supacontrol/server/internal/i18n"
+)
+
+// TestValidator tests each check and that problems are collected rather than stopping at the first
+func TestValidator(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	past := now.Add(-time.Hour)
+	future := now.Add(time.Hour)
+
+	tests := []struct {
+		name       string
+		check      func(v *Validator)
+		wantFields []string
+	}{
+		{name: "required set", check: func(v *Validator) { v.Required("name", "app") }},
+		{name: "required blank", check: func(v *Validator) { v.Required("name", "  ") }, wantFields: []string{"name"}},
+		{name: "max length counts characters", check: func(v *Validator) { v.MaxLength("name", "äöü", 3) }},
+		{name: "too long", check: func(v *Validator) { v.MaxLength("name", "abcd", 3) }, wantFields: []string{"name"}},
+		{name: "dns label", check: func(v *Validator) { v.DNSLabel("name", "my-app-1", 58) }},
+		{name: "dns label empty is left to required", check: func(v *Validator) { v.DNSLabel("name", "", 58) }},
+		{name: "dns label uppercase", check: func(v *Validator) { v.DNSLabel("name", "MyApp", 58) }, wantFields: []string{"name"}},
+		{name: "dns label trailing hyphen", check: func(v *Validator) { v.DNSLabel("name", "app-", 58) }, wantFields: []string{"name"}},
+		{name: "dns label too long", check: func(v *Validator) { v.DNSLabel("name", strings.Repeat("a", 59), 58) }, wantFields: []string{"name"}},
+		{name: "email", check: func(v *Validator) { v.Email("email", "jane@example.com") }},
+		{name: "email with display name", check: func(v *Validator) { v.Email("email", "Jane <jane@example.com>") }, wantFields: []string{"email"}},
+		{name: "email without domain", check: func(v *Validator) { v.Email("email", "jane") }, wantFields: []string{"email"}},
+		{name: "negative", check: func(v *Validator) { v.NonNegative("count", -1) }, wantFields: []string{"count"}},
+		{name: "zero is not negative", check: func(v *Validator) { v.NonNegative("count", 0) }},
+		{name: "future", check: func(v *Validator) { v.Future("expires_at", &future, now) }},
+		{name: "past", check: func(v *Validator) { v.Future("expires_at", &past, now) }, wantFields: []string{"expires_at"}},
+		{name: "unset time", check: func(v *Validator) { v.Future("expires_at", nil, now) }},
+		{
+			name: "collects every problem",
+			check: func(v *Validator) {
+				v.Required("name", "")
+				v.Email("email", "nope")
+				v.NonNegative("count", -2)
+			},
+			wantFields: []string{"name", "email", "count"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var v Validator
+			tt.check(&v)
+			err := v.Err()
+
+			if len(tt.wantFields) == 0 {
+				if err != nil {
+					t.Fatalf("expected no error, got %v", err)
+				}
+				return
+			}
+			errs, ok := err.(Errors)
+			if !ok {
+				t.Fatalf("expected Errors, got %T", err)
+			}
+			if len(errs) != len(tt.wantFields) {
+				t.Fatalf("expected %d field errors, got %v", len(tt.wantFields), errs)
+			}
+			for i, field := range tt.wantFields {
+				if errs[i].Field != field {
+					t.Errorf("error %d: expected field %q, got %q", i, field, errs[i].Field)
+				}
+				if !i18n.Default().Has(errs[i].Message.Key) {
+					t.Errorf("message %q is missing from the i18n catalog", errs[i].Message.Key)
+				}
+			}
+		})
+	}
+}
+
+// TestErrorsError tests that field errors render as one readable line
+func TestErrorsError(t *testing.T) {
+	var v Validator
+	v.Required("name", "")
+	v.MaxLength("team", "abcdef", 5)
+
+	want := "name: is required; team: must be at most 5 characters"
+	if got := v.Err().Error(); got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
@@ -3,6 +3,7 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"log"
 	"os"
@@ -16,6 +17,7 @@ import (
 
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
+	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
 	appsv1 "k8s.io/api/apps/v1"
 	autoscalingv1 "k8s.io/api/autoscaling/v1"
 	batchv1 "k8s.io/api/batch/v1"
@@ -26,15 +28,36 @@ import (
 	schedulingv1 "k8s.io/api/scheduling/v1"
 	storagev1 "k8s.io/api/storage/v1"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
 
+	apitypes "github.com/qubitquilt/supacontrol/pkg/api-types"
 	"github.com/qubitquilt/supacontrol/server/api"
 	supacontrolv1alpha1 "github.com/qubitquilt/supacontrol/server/api/v1alpha1"
+	supacontrolv1beta1 "github.com/qubitquilt/supacontrol/server/api/v1beta1"
 	"github.com/qubitquilt/supacontrol/server/controllers"
+	"github.com/qubitquilt/supacontrol/server/internal/advisories"
+	"github.com/qubitquilt/supacontrol/server/internal/apikeyusage"
 	"github.com/qubitquilt/supacontrol/server/internal/auth"
+	"github.com/qubitquilt/supacontrol/server/internal/backup"
+	"github.com/qubitquilt/supacontrol/server/internal/benchmarks"
+	"github.com/qubitquilt/supacontrol/server/internal/bootstrap"
+	"github.com/qubitquilt/supacontrol/server/internal/cabundle"
+	"github.com/qubitquilt/supacontrol/server/internal/chartindex"
 	"github.com/qubitquilt/supacontrol/server/internal/config"
 	"github.com/qubitquilt/supacontrol/server/internal/db"
+	"github.com/qubitquilt/supacontrol/server/internal/inventory"
 	"github.com/qubitquilt/supacontrol/server/internal/k8s"
+	"github.com/qubitquilt/supacontrol/server/internal/logerrors"
+	"github.com/qubitquilt/supacontrol/server/internal/notify"
+	"github.com/qubitquilt/supacontrol/server/internal/objectstore"
+	"github.com/qubitquilt/supacontrol/server/internal/preflight"
+	"github.com/qubitquilt/supacontrol/server/internal/proxy"
+	"github.com/qubitquilt/supacontrol/server/internal/schedule"
+	"github.com/qubitquilt/supacontrol/server/internal/sso"
+	"github.com/qubitquilt/supacontrol/server/internal/storageapi"
+	"github.com/qubitquilt/supacontrol/server/internal/upgrades"
 )
 
 func main() {
@@ -43,52 +66,158 @@ func main() {
 	}
 }
 
+// migrateDatabase applies pending migrations, or reverts the last down migrations
+func migrateDatabase(dsn string, down int) error {
+	dbClient, err := db.NewClient(dsn)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := dbClient.Close(); closeErr != nil {
+			log.Printf("Error closing database client: %v", closeErr)
+		}
+	}()
+
+	if down > 0 {
+		reverted, err := dbClient.RollbackMigrations(down)
+		if err != nil {
+			return err
+		}
+		log.Printf("Reverted %d database migrations", reverted)
+		return nil
+	}
+	applied, err := dbClient.RunMigrations()
+	if err != nil {
+		return err
+	}
+	log.Printf("Applied %d database migrations", applied)
+	return nil
+}
+
 func run() error {
+	migrateOnly := flag.Bool("migrate-only", false, "apply pending database migrations and exit")
+	migrateDown := flag.Int("migrate-down", 0, "revert the last `n` applied database migrations and exit")
+	flag.Parse()
+
 	// Load configuration
 	cfg, err := config.Load()
 	if err != nil {
 		return fmt.Errorf("failed to load config: %w", err)
 	}
 
+	// Migrate the database without starting the server, e.g. from a pre-upgrade hook
+	if *migrateOnly || *migrateDown > 0 {
+		return migrateDatabase(cfg.GetDSN(), *migrateDown)
+	}
+
 	log.Println("Starting SupaControl server...")
 
-	// Initialize database
-	dbClient, err := db.NewClient(cfg.GetDSN())
-	if err != nil {
-		return fmt.Errorf("failed to connect to database: %w", err)
-	}
-	defer func() {
-		if closeErr := dbClient.Close(); closeErr != nil {
-			log.Printf("Error closing database client: %v", closeErr)
+	// Trust the custom CA bundle before any outbound TLS connection is made
+	if cfg.CABundleFile != "" {
+		bundle, err := cabundle.Install(cfg.CABundleFile)
+		if err != nil {
+			return fmt.Errorf("failed to install CA bundle: %w", err)
 		}
-	}()
-
-	log.Println("Connected to database")
+		log.Printf("Trusting custom CA bundle %s (merged into %s)", cfg.CABundleFile, bundle)
+	}
 
-	// Run migrations
-	migrationsPath := filepath.Join("internal", "db", "migrations")
-	if err := dbClient.RunMigrations(migrationsPath); err != nil {
-		log.Printf("Warning: failed to run migrations: %v", err)
-		log.Println("If this is the first run, ensure migrations are available")
+	// Route outbound calls through the proxy, keeping in-cluster traffic direct
+	proxyCfg := proxy.Config{
+		HTTPProxy:  cfg.HTTPProxy,
+		HTTPSProxy: cfg.HTTPSProxy,
+		NoProxy:    cfg.NoProxy,
+	}.WithClusterDefaults()
+	if proxyCfg.Enabled() {
+		if err := proxyCfg.Apply(); err != nil {
+			return fmt.Errorf("failed to apply proxy settings: %w", err)
+		}
+		log.Printf("Using outbound proxy (NO_PROXY: %s)", proxyCfg.NoProxy)
 	}
 
-	// Initialize authentication service
-	authService := auth.NewService(cfg.JWTSecret)
-	log.Println("Initialized authentication service")
+	// Refuse to start with a provisioner image Jobs could not run
+	provisionerImage := cfg.ProvisionerImage
+	if provisionerImage == "" {
+		provisionerImage = controllers.ProvisionerImage
+	}
+	if err := controllers.ValidateImage(provisionerImage, cfg.ProvisionerImageRequireDigest); err != nil {
+		return fmt.Errorf("invalid PROVISIONER_IMAGE: %w", err)
+	}
+	log.Printf("Provisioning Jobs run %s", provisionerImage)
+
+	// Initialize object storage for backups and bundles
+	var objectStore objectstore.Backend
+	if cfg.ObjectStorageProvider != "" {
+		objectStore, err = objectstore.New(cfg.ObjectStorage())
+		if err != nil {
+			return fmt.Errorf("failed to initialize object storage: %w", err)
+		}
+		log.Printf("Using %s object storage", cfg.ObjectStorageProvider)
+	}
 
 	// Initialize Kubernetes client
 	k8sClient, err := k8s.NewClient(cfg.KubeConfig)
 	if err != nil {
 		return fmt.Errorf("failed to create kubernetes client: %w", err)
 	}
-	log.Println("Connected to Kubernetes cluster")
 
-	// Initialize CR client for API handlers
-	crClient, err := k8s.NewCRClient(k8sClient.GetConfig())
+	// Log fetches and pod exec sessions get a client with their own rate limit, so
+	// observability traffic cannot starve provisioning and other API calls
+	logClient, err := k8sClient.WithRateLimit(float32(cfg.ObservabilityClientQPS), cfg.ObservabilityClientBurst)
 	if err != nil {
-		return fmt.Errorf("failed to create CR client: %w", err)
+		return fmt.Errorf("failed to create log client: %w", err)
+	}
+
+	// Check everything SupaControl depends on, reporting all failures together
+	var dbClient *db.Client
+	log.Println("Running preflight checks...")
+	err = preflight.Run(context.Background(),
+		preflight.JWTSecret(cfg.JWTSecret),
+		preflight.Database(preflight.DefaultBackoff, func(context.Context) error {
+			client, err := db.NewClient(cfg.GetDSN())
+			if err != nil {
+				log.Printf("Database not reachable yet: %v", err)
+				return err
+			}
+			dbClient = client
+			return nil
+		}),
+		preflight.CRD(k8sClient.GetClientset()),
+		preflight.RBAC(k8sClient.GetClientset(), preflight.RequiredPermissions),
+	)
+	if dbClient != nil {
+		defer func() {
+			if closeErr := dbClient.Close(); closeErr != nil {
+				log.Printf("Error closing database client: %v", closeErr)
+			}
+		}()
+	}
+	if err != nil {
+		return err
+	}
+	log.Println("Preflight checks passed")
+	log.Println("Connected to database and Kubernetes cluster")
+
+	// Run migrations
+	applied, err := dbClient.RunMigrations()
+	if err != nil {
+		return err
+	}
+	log.Printf("Applied %d database migrations", applied)
+
+	// Initialize authentication service
+	authService := auth.NewService(cfg.JWTSecret)
+	log.Println("Initialized authentication service")
+
+	// Create the first admin account of a new installation
+	secretNamespace, secretName := cfg.InitialAdminSecretRef()
+	if _, err := bootstrap.Admin(context.Background(), dbClient, authService, bootstrap.Options{
+		Username:        cfg.InitialAdminUsername,
+		Clientset:       k8sClient.GetClientset(),
+		SecretNamespace: secretNamespace,
+		SecretName:      secretName,
+	}); err != nil {
+		log.Printf("Warning: failed to create the first admin account: %v", err)
 	}
-	log.Println("Initialized CR client")
 
 	// Set up controller manager
 	ctrl.SetLogger(zap.New(zap.UseDevMode(true)))
@@ -108,35 +237,173 @@ func run() error {
 	utilruntime.Must(schedulingv1.AddToScheme(ctrlScheme))
 	utilruntime.Must(storagev1.AddToScheme(ctrlScheme))
 
+	// Prometheus Operator types, for the ServiceMonitors of monitored instances
+	utilruntime.Must(monitoringv1.AddToScheme(ctrlScheme))
+
 	// Custom Resource Definitions
 	utilruntime.Must(supacontrolv1alpha1.AddToScheme(ctrlScheme))
+	utilruntime.Must(supacontrolv1beta1.AddToScheme(ctrlScheme))
 
+	cacheSyncPeriod := time.Duration(cfg.CacheSyncPeriodMinutes) * time.Minute
 	mgr, err := ctrl.NewManager(k8sClient.GetConfig(), ctrl.Options{
 		Scheme: ctrlScheme,
 		// LeaderElection for HA deployments (configured via LEADER_ELECTION_ENABLED env var)
 		LeaderElection:   cfg.LeaderElectionEnabled,
 		LeaderElectionID: "supacontrol-leader-election",
+		Cache:            cache.Options{SyncPeriod: &cacheSyncPeriod},
+		WebhookServer: webhook.NewServer(webhook.Options{
+			Port:    cfg.WebhookPort,
+			CertDir: cfg.WebhookCertDir,
+		}),
 	})
 	if err != nil {
 		return fmt.Errorf("failed to create controller manager: %w", err)
 	}
 
+	// Initialize CR client for API handlers. Its reads are served from the manager's
+	// watch-backed cache, which the controller keeps current, instead of the API server.
+	var crClient *k8s.CRClient
+	if cfg.APICacheEnabled {
+		crClient = k8s.NewCachedCRClient(mgr.GetClient(), mgr.GetAPIReader(), mgr.GetScheme())
+	} else {
+		crClient, err = k8s.NewCRClient(k8sClient.GetConfig())
+		if err != nil {
+			return fmt.Errorf("failed to create CR client: %w", err)
+		}
+	}
+	log.Println("Initialized CR client")
+
 	// Set up the controller
 	reconciler := &controllers.SupabaseInstanceReconciler{
-		Client:               mgr.GetClient(),
-		Scheme:               mgr.GetScheme(),
-		ChartRepo:            cfg.SupabaseChartRepo,
-		ChartName:            cfg.SupabaseChartName,
-		ChartVersion:         cfg.SupabaseChartVersion,
-		DefaultIngressClass:  cfg.DefaultIngressClass,
-		DefaultIngressDomain: cfg.DefaultIngressDomain,
-		CertManagerIssuer:    cfg.CertManagerIssuer,
+		Client:                     mgr.GetClient(),
+		Scheme:                     mgr.GetScheme(),
+		ChartRepo:                  cfg.SupabaseChartRepo,
+		ChartName:                  cfg.SupabaseChartName,
+		ChartVersion:               cfg.SupabaseChartVersion,
+		DefaultIngressClass:        cfg.DefaultIngressClass,
+		DefaultIngressDomain:       cfg.DefaultIngressDomain,
+		IngressControllerNamespace: cfg.IngressControllerNamespace,
+		MonitoringNamespace:        cfg.MonitoringNamespace,
+		MonitoringLabels:           cfg.MonitoringLabels,
+		NamespaceTemplate:          cfg.NamespaceTemplate,
+		NamespaceLabels:            cfg.NamespaceLabels,
+		NamespaceAnnotations:       cfg.NamespaceAnnotations,
+		CertManagerIssuer:          cfg.CertManagerIssuer,
+		ExternalDNSMode:            cfg.ExternalDNSMode,
+		DNSReadyTimeout:            time.Duration(cfg.ExternalDNSReadyTimeoutSeconds) * time.Second,
+		CABundleConfigMap:          cfg.CABundleConfigMap,
+		ChartRepoCredentialsSecret: cfg.ChartRepoCredentialsSecret,
+		ProvisionerImage:           cfg.ProvisionerImage,
+		RequireImageDigest:         cfg.ProvisionerImageRequireDigest,
+		Proxy:                      proxyCfg,
+		KataRuntimeClass:           cfg.KataRuntimeClass,
+		VClusterChartRepo:          cfg.VClusterChartRepo,
+		VClusterChartVersion:       cfg.VClusterChartVersion,
+		SuspendedPageURL:           cfg.SuspendedPageURL,
+		PostgresImageTags:          cfg.PostgresImageTags,
+		PostgresUpgradeImage:       cfg.PostgresUpgradeImage,
+
+		MaxConcurrentReconciles:       cfg.ControllerMaxConcurrentReconciles,
+		MaxConcurrentProvisioningJobs: cfg.ControllerMaxConcurrentProvisioningJobs,
+		RateLimiter: controllers.NewRateLimiter(
+			time.Duration(cfg.ControllerRateLimitBaseDelayMS)*time.Millisecond,
+			time.Duration(cfg.ControllerRateLimitMaxDelaySeconds)*time.Second,
+			cfg.ControllerRateLimitQPS, cfg.ControllerRateLimitBurst,
+		),
+		JobPollInterval:       time.Duration(cfg.ControllerJobPollIntervalSeconds) * time.Second,
+		ResyncInterval:        time.Duration(cfg.ControllerResyncIntervalSeconds) * time.Second,
+		FailedRequeueInterval: time.Duration(cfg.ControllerFailedRequeueIntervalSeconds) * time.Second,
+
+		HealthSuccessThreshold: int32(cfg.ControllerHealthSuccessThreshold),
+		HealthFailureThreshold: int32(cfg.ControllerHealthFailureThreshold),
+		APIReader:              mgr.GetAPIReader(),
+		Recorder:               mgr.GetEventRecorderFor("supacontrol"),
+
+		ConnectionRotationInterval: time.Duration(cfg.ConnectionRotationDays) * 24 * time.Hour,
+		SecretMaxAge:               time.Duration(cfg.SecretMaxAgeDays) * 24 * time.Hour,
+	}
+	if cfg.NotificationWebhookURL != "" {
+		reconciler.Notifier = notify.NewWebhook(cfg.NotificationWebhookURL, cfg.NotificationWebhookSecret)
+		log.Println("Notification webhook enabled")
 	}
 
 	if err := reconciler.SetupWithManager(mgr); err != nil {
 		return fmt.Errorf("failed to setup controller: %w", err)
 	}
 
+	// Serve the conversion webhook that lets clients use SupabaseInstance v1beta1 while
+	// instances are stored as v1alpha1. It needs a serving certificate, so it is opt-in.
+	if cfg.WebhookEnabled {
+		if err := ctrl.NewWebhookManagedBy(mgr).For(&supacontrolv1beta1.SupabaseInstance{}).Complete(); err != nil {
+			return fmt.Errorf("failed to setup conversion webhook: %w", err)
+		}
+		log.Printf("Serving the conversion webhook on port %d", cfg.WebhookPort)
+	}
+
+	// Drive fleet upgrades from the elected leader
+	if err := mgr.Add(upgrades.NewRunner(dbClient, crClient)); err != nil {
+		return fmt.Errorf("failed to add upgrade runner: %w", err)
+	}
+
+	// Stop and start instances on their schedules from the elected leader
+	if err := mgr.Add(schedule.NewRunner(crClient)); err != nil {
+		return fmt.Errorf("failed to add schedule runner: %w", err)
+	}
+
+	// Record benchmark results from the elected leader
+	if err := mgr.Add(benchmarks.NewRunner(dbClient, k8sClient.GetClientset())); err != nil {
+		return fmt.Errorf("failed to add benchmark runner: %w", err)
+	}
+
+	// Mirror instances into the database from the elected leader
+	instanceSyncer := inventory.NewSyncer(crClient, dbClient)
+	instanceSyncer.Interval = time.Duration(cfg.InstanceSyncIntervalSeconds) * time.Second
+	if err := mgr.Add(instanceSyncer); err != nil {
+		return fmt.Errorf("failed to add instance syncer: %w", err)
+	}
+
+	// Batch API key last-used updates on every replica, so API key traffic does not write
+	// to the database on every request
+	apiKeyUsage := apikeyusage.NewRecorder(dbClient)
+	apiKeyUsage.Interval = time.Duration(cfg.APIKeyUsageFlushSeconds) * time.Second
+	if err := mgr.Add(apiKeyUsage); err != nil {
+		return fmt.Errorf("failed to add API key usage recorder: %w", err)
+	}
+
+	// Summarize errors in instance logs on every replica
+	var errorAnalyzer *logerrors.Analyzer
+	if cfg.LogErrorAnalysisEnabled {
+		errorAnalyzer = logerrors.NewAnalyzer(logClient.GetClientset(), crClient)
+		if err := mgr.Add(errorAnalyzer); err != nil {
+			return fmt.Errorf("failed to add log error analyzer: %w", err)
+		}
+	}
+
+	// Index the chart repository on every replica, to flag instances with upgrades available
+	chartInspector := k8s.NewChartInspector(cfg.SupabaseChartRepo, cfg.SupabaseChartName, cfg.SupabaseChartVersion).
+		WithCredentials(cfg.ChartRepoUsername, cfg.ChartRepoPassword)
+	chartIndexer := chartindex.NewIndexer(chartInspector, dbClient)
+	if err := mgr.Add(chartIndexer); err != nil {
+		return fmt.Errorf("failed to add chart indexer: %w", err)
+	}
+
+	// Refresh the security advisory feed on every replica, so requests read the cached copy
+	var advisoryFeed *advisories.Feed
+	if cfg.AdvisoryFeed != "" {
+		advisoryFeed = advisories.NewFeed(cfg.AdvisoryFeed, advisories.DefaultRefreshInterval)
+		if err := mgr.Add(advisoryFeed); err != nil {
+			return fmt.Errorf("failed to add advisory feed: %w", err)
+		}
+	}
+
+	// Apply backup retention policies from the elected leader; backups are enabled by
+	// configuring encryption keys, which config validation ties to object storage
+	if len(cfg.BackupEncryptionKeys) > 0 {
+		if err := mgr.Add(backup.NewPruner(dbClient, objectStore)); err != nil {
+			return fmt.Errorf("failed to add backup pruner: %w", err)
+		}
+	}
+
 	log.Println("Initialized controller manager")
 
 	// Channel for internal errors that should trigger shutdown
@@ -165,7 +432,97 @@ func run() error {
 	e.HideBanner = true
 
 	// Initialize handler with CR client and k8s client
-	handler := api.NewHandler(authService, dbClient, crClient, k8sClient)
+	handlerOpts := []api.HandlerOption{
+		api.WithPublicURL(cfg.PublicURL),
+		api.WithRequestTimeout(time.Duration(cfg.RequestTimeoutSeconds) * time.Second),
+		api.WithLoadShedding(api.LoadSheddingConfig{
+			MaxRequests:          cfg.LoadSheddingMaxRequests,
+			MaxExpensiveRequests: cfg.LoadSheddingMaxExpensiveRequests,
+			QueueTimeout:         time.Duration(cfg.LoadSheddingQueueTimeoutMS) * time.Millisecond,
+		}),
+		api.WithQuotaDefaults(
+			apitypes.QuotaLimits{MaxInstances: cfg.QuotaMaxInstancesPerUser, MaxStorageGB: cfg.QuotaMaxStorageGBPerUser},
+			apitypes.QuotaLimits{MaxInstances: cfg.QuotaMaxTotalInstances, MaxStorageGB: cfg.QuotaMaxTotalStorageGB},
+		),
+		api.WithSandboxPolicy(api.SandboxPolicy{
+			Role:         cfg.SandboxRole,
+			Team:         cfg.SandboxTeam,
+			MaxInstances: cfg.SandboxMaxInstances,
+			MaxStorageGB: cfg.SandboxMaxStorageGB,
+			TTL:          time.Duration(cfg.SandboxTTLHours) * time.Hour,
+		}),
+		api.WithRequireImageDigest(cfg.ProvisionerImageRequireDigest),
+		api.WithNamespaceTemplate(cfg.NamespaceTemplate),
+		api.WithPostgresImageTags(cfg.PostgresImageTags),
+		api.WithChartResolver(chartInspector),
+		api.WithChartCatalog(chartIndexer),
+		api.WithReleasePreviewer(k8s.NewOrchestrator(k8sClient, cfg.SupabaseChartRepo, cfg.SupabaseChartName,
+			cfg.SupabaseChartVersion, cfg.DefaultIngressClass, cfg.DefaultIngressDomain).
+			WithChartRepoCredentials(cfg.ChartRepoUsername, cfg.ChartRepoPassword)),
+		api.WithPodExecutor(k8s.NewPodExecutor(logClient)),
+		api.WithPortForwarder(k8s.NewPortForwarder(logClient)),
+		api.WithStorageAPI(storageapi.NewClient()),
+		api.WithLogClient(logClient),
+		api.WithAPIKeyUsageRecorder(apiKeyUsage),
+	}
+	if advisoryFeed != nil {
+		handlerOpts = append(handlerOpts, api.WithAdvisorySource(advisoryFeed))
+		log.Printf("Security advisory matching enabled (feed: %s)", cfg.AdvisoryFeed)
+	}
+	if cfg.PrometheusURL != "" {
+		handlerOpts = append(handlerOpts, api.WithUsageMetricsSource(k8s.NewPrometheusSource(cfg.PrometheusURL)))
+		log.Printf("Reading instance usage metrics from Prometheus (%s)", cfg.PrometheusURL)
+	} else {
+		handlerOpts = append(handlerOpts, api.WithUsageMetricsSource(k8s.NewMetricsServerSource(k8sClient.GetClientset())))
+	}
+	if errorAnalyzer != nil {
+		handlerOpts = append(handlerOpts, api.WithErrorSummarySource(errorAnalyzer))
+	}
+	if cfg.DeletionGracePeriodHours > 0 {
+		handlerOpts = append(handlerOpts, api.WithDeletionGracePeriod(time.Duration(cfg.DeletionGracePeriodHours)*time.Hour))
+		log.Printf("Deleted instances are kept in the trash for %d hours", cfg.DeletionGracePeriodHours)
+	}
+	handlerOpts = append(handlerOpts, api.WithAPIKeyRotationOverlap(time.Duration(cfg.APIKeyRotationOverlapHours)*time.Hour))
+	if cfg.NotificationWebhookURL != "" {
+		handlerOpts = append(handlerOpts, api.WithNotifier(notify.NewWebhook(cfg.NotificationWebhookURL, cfg.NotificationWebhookSecret)))
+	}
+	if cfg.BillingWebhookSecret != "" {
+		handlerOpts = append(handlerOpts, api.WithBillingWebhookSecret(cfg.BillingWebhookSecret))
+		log.Println("Billing webhook enabled")
+	}
+	if cfg.SAMLEnabled {
+		samlProvider, err := sso.NewServiceProvider(ctx, cfg.SAML())
+		if err != nil {
+			return fmt.Errorf("failed to initialize SAML single sign-on: %w", err)
+		}
+		handlerOpts = append(handlerOpts, api.WithSAML(samlProvider, cfg.SAMLDefaultRole))
+		log.Printf("SAML single sign-on enabled (IdP metadata: %s)", cfg.SAMLIDPMetadata)
+	}
+	handlerOpts = append(handlerOpts, api.WithReadinessCheck("database", func(ctx context.Context) error {
+		return dbClient.Ping()
+	}))
+	handlerOpts = append(handlerOpts, api.WithReadinessCheck("controller_cache", func(ctx context.Context) error {
+		if !mgr.GetCache().WaitForCacheSync(ctx) {
+			return fmt.Errorf("controller cache not synced")
+		}
+		return nil
+	}))
+	if cfg.LeaderElectionEnabled {
+		handlerOpts = append(handlerOpts, api.WithLeaderElection(mgr.Elected()))
+	}
+	if objectStore != nil {
+		handlerOpts = append(handlerOpts, api.WithReadinessCheck("object_storage", objectStore.Check))
+	}
+	// Backups are only taken encrypted, so they are enabled by configuring keys
+	if len(cfg.BackupEncryptionKeys) > 0 {
+		backupKeys, err := backup.NewKeyring(cfg.BackupEncryptionKeys, cfg.BackupEncryptionKeyID)
+		if err != nil {
+			return fmt.Errorf("invalid BACKUP_ENCRYPTION_KEYS: %w", err)
+		}
+		handlerOpts = append(handlerOpts, api.WithBackups(objectStore, backupKeys))
+		log.Printf("Backups are encrypted with key %s", backupKeys.ActiveKeyID())
+	}
+	handler := api.NewHandler(authService, dbClient, crClient, k8sClient, handlerOpts...)
 
 	// Setup routes
 	api.SetupRouter(e, handler, authService, dbClient)
@@ -19,8 +19,13 @@ function App() {
       }
 
       try {
-        await authAPI.getMe();
-        setIsAuthenticated(true);
+        const response = await authAPI.getMe();
+        // A one-time password must be replaced on the login page first
+        if (response.data?.user?.must_change_password) {
+          localStorage.removeItem('token');
+        } else {
+          setIsAuthenticated(true);
+        }
       } catch (error) {
         localStorage.removeItem('token');
         setIsAuthenticated(false);
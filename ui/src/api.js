@@ -33,6 +33,8 @@ export const authAPI = {
   login: (username, password) =>
     axios.post('/api/v1/auth/login', { username, password }),
   getMe: () => api.get('/auth/me'),
+  changePassword: (currentPassword, newPassword) =>
+    api.put('/auth/password', { current_password: currentPassword, new_password: newPassword }),
   createAPIKey: (name, expiresAt = null) =>
     api.post('/auth/api-keys', { name, expires_at: expiresAt }),
   listAPIKeys: () => api.get('/auth/api-keys'),
@@ -45,6 +47,13 @@ export const instancesAPI = {
   list: () => api.get('/instances'),
   get: (name) => api.get(`/instances/${name}`),
   delete: (name) => api.delete(`/instances/${name}`),
+  createStudioSession: (name) => api.post(`/instances/${name}/studio-session`),
+};
+
+// Preferences API
+export const preferencesAPI = {
+  get: () => api.get('/me/preferences'),
+  update: (preferences) => api.put('/me/preferences', { preferences }),
 };
 
 export default api;
@@ -11,6 +11,7 @@ vi.mock('axios', () => {
         },
         get: vi.fn(),
         post: vi.fn(),
+        put: vi.fn(),
         delete: vi.fn(),
       })),
       post: vi.fn(),
@@ -35,5 +36,13 @@ describe('API Configuration', () => {
     expect(instancesAPI).toBeDefined();
     expect(instancesAPI.create).toBeDefined();
     expect(instancesAPI.list).toBeDefined();
+    expect(instancesAPI.createStudioSession).toBeDefined();
+  });
+
+  it('should export preferencesAPI', async () => {
+    const { preferencesAPI } = await import('./api');
+    expect(preferencesAPI).toBeDefined();
+    expect(preferencesAPI.get).toBeDefined();
+    expect(preferencesAPI.update).toBeDefined();
   });
 });
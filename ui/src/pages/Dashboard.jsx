@@ -6,6 +6,7 @@ import './Dashboard.css';
 function Dashboard({ onLogout }) {
   const navigate = useNavigate();
   const [instances, setInstances] = useState([]);
+  const [affectedInstances, setAffectedInstances] = useState(0);
   const [loading, setLoading] = useState(true);
   const [error, setError] = useState('');
   const [showCreateModal, setShowCreateModal] = useState(false);
@@ -17,6 +18,7 @@ function Dashboard({ onLogout }) {
     try {
       const response = await instancesAPI.list();
       setInstances(response.data.instances || []);
+      setAffectedInstances(response.data.affected_instances || 0);
     } catch (err) {
       setError('Failed to load instances');
     } finally {
@@ -58,6 +60,24 @@ function Dashboard({ onLogout }) {
     }
   };
 
+  // Open Studio through a one-time link that signs in with the instance's keys. The
+  // window is opened before the request so popup blockers allow it.
+  const handleOpenStudio = async (name) => {
+    const studio = window.open('', '_blank');
+    try {
+      const response = await instancesAPI.createStudioSession(name);
+      if (studio) {
+        studio.opener = null;
+        studio.location.href = response.data.url;
+      } else {
+        window.location.href = response.data.url;
+      }
+    } catch (err) {
+      studio?.close();
+      setError(err.response?.data?.message || 'Failed to open Studio');
+    }
+  };
+
   const getStatusBadge = (status) => {
     const classes = {
       RUNNING: 'status-running',
@@ -87,6 +107,13 @@ function Dashboard({ onLogout }) {
 
       <main className="dashboard-main">
         {error && <div className="error-banner">{error}</div>}
+        {affectedInstances > 0 && (
+          <div className="advisory-banner">
+            {affectedInstances === 1
+              ? '1 instance is affected by security advisories'
+              : `${affectedInstances} instances are affected by security advisories`}
+          </div>
+        )}
 
         <div className="instances-header">
           <h2>Instances ({instances.length})</h2>
@@ -117,12 +144,22 @@ function Dashboard({ onLogout }) {
               {instances.map((instance) => (
                 <tr key={instance.id}>
                   <td>{instance.project_name}</td>
-                  <td>{getStatusBadge(instance.status)}</td>
+                  <td>
+                    {getStatusBadge(instance.status)}
+                    {instance.advisories?.length > 0 && (
+                      <span
+                        className="advisory-badge"
+                        title={instance.advisories.map((a) => `${a.id}: ${a.summary}`).join('\n')}
+                      >
+                        {instance.advisories.length} advisor{instance.advisories.length === 1 ? 'y' : 'ies'}
+                      </span>
+                    )}
+                  </td>
                   <td>
                     {instance.studio_url ? (
-                      <a href={instance.studio_url} target="_blank" rel="noopener noreferrer">
+                      <button onClick={() => handleOpenStudio(instance.project_name)} className="btn-link">
                         Open Studio
-                      </a>
+                      </button>
                     ) : (
                       '-'
                     )}
@@ -82,6 +82,29 @@ describe('Dashboard Component', () => {
         expect(screen.getByText('Failed to load instances')).toBeInTheDocument();
       });
     });
+
+    it('should flag instances affected by security advisories', async () => {
+      api.instancesAPI.list.mockResolvedValue({
+        data: {
+          instances: [
+            {
+              project_name: 'old-app',
+              status: 'running',
+              created_at: '2024-01-01T00:00:00Z',
+              advisories: [{ id: 'SA-2024-01', component: 'postgres', summary: 'privilege escalation' }],
+            },
+          ],
+          affected_instances: 1,
+        },
+      });
+
+      renderDashboard();
+
+      await waitFor(() => {
+        expect(screen.getByText('1 instance is affected by security advisories')).toBeInTheDocument();
+      });
+      expect(screen.getByText('1 advisory')).toBeInTheDocument();
+    });
   });
 
   describe('Navigation', () => {
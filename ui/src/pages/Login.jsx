@@ -1,4 +1,4 @@
-import { useState } from 'react';
+import { useState, useEffect } from 'react';
 import { authAPI } from '../api';
 import './Login.css';
 
@@ -7,6 +7,28 @@ function Login({ onLogin }) {
   const [password, setPassword] = useState('');
   const [error, setError] = useState('');
   const [loading, setLoading] = useState(false);
+  // Set after logging in with a one-time password, which must be replaced before going on
+  const [mustChangePassword, setMustChangePassword] = useState(false);
+  const [newPassword, setNewPassword] = useState('');
+  const [confirmPassword, setConfirmPassword] = useState('');
+
+  // Single sign-on redirects back here with the token or an error in the URL fragment
+  useEffect(() => {
+    const params = new URLSearchParams(window.location.hash.slice(1));
+    const token = params.get('token');
+    const ssoError = params.get('error');
+    if (!token && !ssoError) {
+      return;
+    }
+
+    window.history.replaceState(null, '', window.location.pathname + window.location.search);
+    if (token) {
+      localStorage.setItem('token', token);
+      onLogin();
+    } else {
+      setError(ssoError);
+    }
+  }, [onLogin]);
 
   const handleSubmit = async (e) => {
     e.preventDefault();
@@ -24,6 +46,10 @@ function Login({ onLogin }) {
       }
 
       localStorage.setItem('token', token);
+      if (response.data.user?.must_change_password) {
+        setMustChangePassword(true);
+        return;
+      }
       onLogin();
     } catch (err) {
       setError(err.response?.data?.message || 'Login failed. Please try again.');
@@ -32,6 +58,71 @@ function Login({ onLogin }) {
     }
   };
 
+  const handleChangePassword = async (e) => {
+    e.preventDefault();
+    setError('');
+    if (newPassword !== confirmPassword) {
+      setError('The new passwords do not match.');
+      return;
+    }
+    setLoading(true);
+
+    try {
+      await authAPI.changePassword(password, newPassword);
+      onLogin();
+    } catch (err) {
+      setError(err.response?.data?.message || 'Failed to change password. Please try again.');
+    } finally {
+      setLoading(false);
+    }
+  };
+
+  if (mustChangePassword) {
+    return (
+      <div className="login-container">
+        <div className="login-box">
+          <h1>SupaControl</h1>
+          <p className="subtitle">Choose a new password to replace your one-time password</p>
+
+          <form onSubmit={handleChangePassword}>
+            {error && <div className="error-message">{error}</div>}
+
+            <div className="form-group">
+              <label htmlFor="new-password">New password</label>
+              <input
+                id="new-password"
+                type="password"
+                value={newPassword}
+                onChange={(e) => setNewPassword(e.target.value)}
+                placeholder="At least 8 characters"
+                minLength={8}
+                required
+                autoFocus
+              />
+            </div>
+
+            <div className="form-group">
+              <label htmlFor="confirm-password">Confirm password</label>
+              <input
+                id="confirm-password"
+                type="password"
+                value={confirmPassword}
+                onChange={(e) => setConfirmPassword(e.target.value)}
+                placeholder="Repeat the new password"
+                minLength={8}
+                required
+              />
+            </div>
+
+            <button type="submit" disabled={loading}>
+              {loading ? 'Saving...' : 'Change password'}
+            </button>
+          </form>
+        </div>
+      </div>
+    );
+  }
+
   return (
     <div className="login-container">
       <div className="login-box">
@@ -72,8 +163,8 @@ function Login({ onLogin }) {
         </form>
 
         <div className="login-footer">
-          <p>Default credentials: admin / admin</p>
-          <p className="warning">Change default password after first login!</p>
+          <p>First login? Sign in as admin with the one-time password from the</p>
+          <p>supacontrol-initial-admin Secret or the server log.</p>
         </div>
       </div>
     </div>
@@ -1,4 +1,4 @@
-import { describe, it, expect, vi, beforeEach } from 'vitest';
+import { describe, it, expect, vi, beforeEach, afterEach } from 'vitest';
 import { render, screen, waitFor } from '@testing-library/react';
 import userEvent from '@testing-library/user-event';
 import Login from './Login';
@@ -8,6 +8,7 @@ import * as api from '../api';
 vi.mock('../api', () => ({
   authAPI: {
     login: vi.fn(),
+    changePassword: vi.fn(),
   },
 }));
 
@@ -432,4 +433,81 @@ describe('Login Component', () => {
       });
     });
   });
+
+  describe('Single Sign-On', () => {
+    afterEach(() => {
+      window.history.replaceState(null, '', '/');
+    });
+
+    it('should store the token passed back by single sign-on', async () => {
+      window.history.replaceState(null, '', '/login#token=sso-jwt-token');
+
+      renderLogin();
+
+      await waitFor(() => {
+        expect(localStorage.getItem('token')).toBe('sso-jwt-token');
+        expect(mockOnLogin).toHaveBeenCalledTimes(1);
+      });
+      expect(window.location.hash).toBe('');
+    });
+
+    it('should show a single sign-on error', async () => {
+      window.history.replaceState(null, '', '/login#error=single+sign-on+failed');
+
+      renderLogin();
+
+      expect(await screen.findByText('single sign-on failed')).toBeInTheDocument();
+      expect(mockOnLogin).not.toHaveBeenCalled();
+      expect(localStorage.getItem('token')).toBeNull();
+    });
+  });
+
+  describe('Password Change', () => {
+    const loginWithOneTimePassword = async (user) => {
+      api.authAPI.login.mockResolvedValue({
+        data: { token: 'test-jwt-token', user: { username: 'admin', must_change_password: true } }
+      });
+
+      renderLogin();
+
+      await user.type(screen.getByLabelText(/username/i), 'admin');
+      await user.type(screen.getByLabelText(/password/i), 'one-time-password');
+      await user.click(screen.getByRole('button', { name: /login/i }));
+
+      await waitFor(() => {
+        expect(screen.getByLabelText('New password')).toBeInTheDocument();
+      });
+    };
+
+    it('should ask for a new password before calling onLogin', async () => {
+      const user = userEvent.setup();
+      api.authAPI.changePassword.mockResolvedValue({});
+
+      await loginWithOneTimePassword(user);
+      expect(mockOnLogin).not.toHaveBeenCalled();
+
+      await user.type(screen.getByLabelText('New password'), 'a-much-better-password');
+      await user.type(screen.getByLabelText('Confirm password'), 'a-much-better-password');
+      await user.click(screen.getByRole('button', { name: /change password/i }));
+
+      await waitFor(() => {
+        expect(api.authAPI.changePassword).toHaveBeenCalledWith('one-time-password', 'a-much-better-password');
+        expect(mockOnLogin).toHaveBeenCalledTimes(1);
+      });
+    });
+
+    it('should reject new passwords that do not match', async () => {
+      const user = userEvent.setup();
+
+      await loginWithOneTimePassword(user);
+
+      await user.type(screen.getByLabelText('New password'), 'a-much-better-password');
+      await user.type(screen.getByLabelText('Confirm password'), 'a-different-password');
+      await user.click(screen.getByRole('button', { name: /change password/i }));
+
+      expect(screen.getByText('The new passwords do not match.')).toBeInTheDocument();
+      expect(api.authAPI.changePassword).not.toHaveBeenCalled();
+      expect(mockOnLogin).not.toHaveBeenCalled();
+    });
+  });
 });